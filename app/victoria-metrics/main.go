@@ -23,6 +23,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/pushmetrics"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
@@ -40,8 +41,14 @@ var (
 		"The saved data survives unclean shutdowns such as OOM crash, hardware reset, SIGKILL, etc. "+
 		"Bigger intervals may help increase the lifetime of flash storage with limited write cycles (e.g. Raspberry PI). "+
 		"Smaller intervals increase disk IO load. Minimum supported value is 1s")
-	maxIngestionRate = flag.Int("maxIngestionRate", 0, "The maximum number of samples vmsingle can receive per second. Data ingestion is paused when the limit is exceeded. "+
-		"By default there are no limits on samples ingestion rate.")
+	inmemoryDataFlushClassMetricNameRegex = flagutil.NewArrayString("inmemoryDataFlushClass.metricNameRegex", "Optional regular expressions for metric names, "+
+		"which must be flushed from memory to disk with the interval set via the corresponding -inmemoryDataFlushClass.interval instead of -inmemoryDataFlushInterval. "+
+		"The first matching regular expression is used for every time series. See https://docs.victoriametrics.com/#data-flush-classes")
+	inmemoryDataFlushClassInterval = flagutil.NewArrayDuration("inmemoryDataFlushClass.interval", 5*time.Second, "The interval for guaranteed saving of in-memory data to disk "+
+		"for time series matching the corresponding -inmemoryDataFlushClass.metricNameRegex. Minimum supported value is 1s. "+
+		"See https://docs.victoriametrics.com/#data-flush-classes")
+	maxIngestionRate = flagutil.NewTunableInt("maxIngestionRate", 0, "The maximum number of samples vmsingle can receive per second. Data ingestion is paused when the limit is exceeded. "+
+		"By default there are no limits on samples ingestion rate. The limit can be changed at runtime via /-/flags endpoint", nil)
 	finalDedupScheduleInterval = flag.Duration("storage.finalDedupScheduleCheckInterval", time.Hour, "The interval for checking when final deduplication process should be started."+
 		"Storage unconditionally adds 25% jitter to the interval value on each check evaluation."+
 		" Changing the interval to the bigger values may delay downsampling, deduplication for historical data."+
@@ -90,13 +97,17 @@ func main() {
 	startTime := time.Now()
 	storage.SetDedupInterval(*minScrapeInterval)
 	storage.SetDataFlushInterval(*inmemoryDataFlushInterval)
+	storage.SetDataFlushClasses(mustGetDataFlushClasses())
 	if *finalDedupScheduleInterval < time.Hour {
 		logger.Fatalf("-dedup.finalDedupScheduleCheckInterval cannot be smaller than 1 hour; got %s", *finalDedupScheduleInterval)
 	}
 	storage.SetFinalDedupScheduleInterval(*finalDedupScheduleInterval)
 	vmstorage.Init(promql.ResetRollupResultCacheIfNeeded)
 	vmselect.Init()
-	vminsertcommon.StartIngestionRateLimiter(*maxIngestionRate)
+	vminsertcommon.StartIngestionRateLimiter(maxIngestionRate.Get())
+	maxIngestionRate.OnChange(func() {
+		vminsertcommon.SetIngestionRateLimit(maxIngestionRate.Get())
+	})
 	vminsert.Init()
 
 	startSelfScraper()
@@ -166,6 +177,21 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+func mustGetDataFlushClasses() []storage.DataFlushClass {
+	var classes []storage.DataFlushClass
+	for i, expr := range *inmemoryDataFlushClassMetricNameRegex {
+		re, err := regexutil.NewPromRegex(expr)
+		if err != nil {
+			logger.Fatalf("cannot parse -inmemoryDataFlushClass.metricNameRegex=%q: %s", expr, err)
+		}
+		classes = append(classes, storage.DataFlushClass{
+			MetricNameRegex: re,
+			FlushInterval:   inmemoryDataFlushClassInterval.GetOptionalArg(i),
+		})
+	}
+	return classes
+}
+
 func usage() {
 	const s = `
 victoria-metrics is a time series database and monitoring solution.
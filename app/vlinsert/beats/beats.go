@@ -0,0 +1,352 @@
+package beats
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	listenAddr = flagutil.NewArrayString("beats.listenAddr", "Comma-separated list of TCP addresses to listen to for logs sent by Beats over Lumberjack v2 protocol "+
+		"(e.g. Filebeat or Winlogbeat with 'output.logstash'). See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/")
+
+	streamFields = flagutil.NewArrayString("beats.streamFields", "Fields to use as log stream labels for logs ingested via the corresponding -beats.listenAddr. "+
+		`Fields must be passed as JSON array. By default '["host.name","agent.type","log.file.path"]' is used. `+
+		`See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#stream-fields`)
+	ignoreFields = flagutil.NewArrayString("beats.ignoreFields", "Fields to ignore at logs ingested via the corresponding -beats.listenAddr. "+
+		`Fields must be passed as JSON array. See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#dropping-fields`)
+	extraFields = flagutil.NewArrayString("beats.extraFields", "Fields to add to logs ingested via the corresponding -beats.listenAddr. "+
+		`Fields must be passed as JSON object. See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#adding-extra-fields`)
+
+	tenantID = flagutil.NewArrayString("beats.tenantID", "TenantID for logs ingested via the corresponding -beats.listenAddr. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#multitenancy")
+
+	tlsEnable = flagutil.NewArrayBool("beats.tls", "Whether to enable TLS for receiving logs from Beats at the corresponding -beats.listenAddr. "+
+		"The corresponding -beats.tlsCertFile and -beats.tlsKeyFile must be set if -beats.tls is set. See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#security")
+	tlsCertFile = flagutil.NewArrayString("beats.tlsCertFile", "Path to file with TLS certificate for the corresponding -beats.listenAddr if the corresponding -beats.tls is set. "+
+		"Prefer ECDSA certs instead of RSA certs as RSA certs are slower. The provided certificate file is automatically re-read every second, so it can be dynamically updated. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#security")
+	tlsKeyFile = flagutil.NewArrayString("beats.tlsKeyFile", "Path to file with TLS key for the corresponding -beats.listenAddr if the corresponding -beats.tls is set. "+
+		"The provided key file is automatically re-read every second, so it can be dynamically updated. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#security")
+	tlsCipherSuites = flagutil.NewArrayString("beats.tlsCipherSuites", "Optional list of TLS cipher suites for -beats.listenAddr if -beats.tls is set. "+
+		"See the list of supported cipher suites at https://pkg.go.dev/crypto/tls#pkg-constants . "+
+		"See also https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#security")
+	tlsMinVersion = flag.String("beats.tlsMinVersion", "TLS13", "The minimum TLS version to use for -beats.listenAddr if -beats.tls is set. "+
+		"Supported values: TLS10, TLS11, TLS12, TLS13. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#security")
+
+	keepAliveInterval = flag.Duration("beats.keepAliveInterval", 5*time.Second, "Interval for sending keep-alive acknowledgements to Beats while the received batch of logs "+
+		"is still being processed. This prevents Beats from timing out and re-sending the batch when VictoriaLogs slows down the ingestion. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/beats/#backpressure")
+)
+
+// MustInit initializes Beats receiver at the given -beats.listenAddr ports.
+//
+// This function must be called after flag.Parse().
+//
+// MustStop() must be called in order to free up resources occupied by the initialized Beats receiver.
+func MustInit() {
+	if workersStopCh != nil {
+		logger.Panicf("BUG: MustInit() called twice without MustStop() call")
+	}
+	workersStopCh = make(chan struct{})
+
+	for argIdx, addr := range *listenAddr {
+		workersWG.Add(1)
+		go func(addr string, argIdx int) {
+			runTCPListener(addr, argIdx)
+			workersWG.Done()
+		}(addr, argIdx)
+	}
+}
+
+var (
+	workersWG     sync.WaitGroup
+	workersStopCh chan struct{}
+)
+
+// MustStop stops Beats receiver initialized via MustInit()
+func MustStop() {
+	close(workersStopCh)
+	workersWG.Wait()
+	workersStopCh = nil
+}
+
+func runTCPListener(addr string, argIdx int) {
+	var tlsConfig *tls.Config
+	if tlsEnable.GetOptionalArg(argIdx) {
+		certFile := tlsCertFile.GetOptionalArg(argIdx)
+		keyFile := tlsKeyFile.GetOptionalArg(argIdx)
+		tc, err := netutil.GetServerTLSConfig(certFile, keyFile, *tlsMinVersion, *tlsCipherSuites)
+		if err != nil {
+			logger.Fatalf("cannot load TLS cert from -beats.tlsCertFile=%q, -beats.tlsKeyFile=%q, -beats.tlsMinVersion=%q, -beats.tlsCipherSuites=%q: %s",
+				certFile, keyFile, *tlsMinVersion, *tlsCipherSuites, err)
+		}
+		tlsConfig = tc
+	}
+	ln, err := netutil.NewTCPListener("beats", addr, false, tlsConfig)
+	if err != nil {
+		logger.Fatalf("beats: cannot start TCP listener at %s: %s", addr, err)
+	}
+
+	tenantIDStr := tenantID.GetOptionalArg(argIdx)
+	tid, err := logstorage.ParseTenantID(tenantIDStr)
+	if err != nil {
+		logger.Fatalf("cannot parse -beats.tenantID=%q for -beats.listenAddr=%q: %s", tenantIDStr, addr, err)
+	}
+
+	streamFieldsStr := streamFields.GetOptionalArg(argIdx)
+	sfs, err := parseFieldsList(streamFieldsStr)
+	if err != nil {
+		logger.Fatalf("cannot parse -beats.streamFields=%q for -beats.listenAddr=%q: %s", streamFieldsStr, addr, err)
+	}
+
+	ignoreFieldsStr := ignoreFields.GetOptionalArg(argIdx)
+	ifs, err := parseFieldsList(ignoreFieldsStr)
+	if err != nil {
+		logger.Fatalf("cannot parse -beats.ignoreFields=%q for -beats.listenAddr=%q: %s", ignoreFieldsStr, addr, err)
+	}
+
+	extraFieldsStr := extraFields.GetOptionalArg(argIdx)
+	efs, err := parseExtraFields(extraFieldsStr)
+	if err != nil {
+		logger.Fatalf("cannot parse -beats.extraFields=%q for -beats.listenAddr=%q: %s", extraFieldsStr, addr, err)
+	}
+
+	cp := getCommonParams(tid, sfs, ifs, efs)
+
+	doneCh := make(chan struct{})
+	go func() {
+		serveTCP(ln, cp)
+		close(doneCh)
+	}()
+
+	logger.Infof("started accepting logs from Beats at -beats.listenAddr=%q", addr)
+	<-workersStopCh
+	if err := ln.Close(); err != nil {
+		logger.Fatalf("beats: cannot close TCP listener at %s: %s", addr, err)
+	}
+	<-doneCh
+	logger.Infof("finished accepting logs from Beats at -beats.listenAddr=%q", addr)
+}
+
+// getCommonParams returns common params needed for storing logs received from Beats.
+func getCommonParams(tenantID logstorage.TenantID, streamFields, ignoreFields []string, extraFields []logstorage.Field) *insertutil.CommonParams {
+	if streamFields == nil {
+		streamFields = []string{
+			"host.name",
+			"agent.type",
+			"log.file.path",
+		}
+	}
+	return &insertutil.CommonParams{
+		TenantID:  tenantID,
+		TimeField: "@timestamp",
+		MsgFields: []string{
+			"message",
+		},
+		StreamFields: streamFields,
+		IgnoreFields: ignoreFields,
+		ExtraFields:  extraFields,
+	}
+}
+
+func serveTCP(ln net.Listener, cp *insertutil.CommonParams) {
+	var cm ingestserver.ConnsMap
+	cm.Init("beats")
+
+	var wg sync.WaitGroup
+	addr := ln.Addr()
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) {
+				if ne.Temporary() {
+					logger.Errorf("beats: temporary error when listening for TCP addr %q: %s", addr, err)
+					time.Sleep(time.Second)
+					continue
+				}
+				if strings.Contains(err.Error(), "use of closed network connection") {
+					break
+				}
+				logger.Fatalf("beats: unrecoverable error when accepting TCP connections at %q: %s", addr, err)
+			}
+			logger.Fatalf("beats: unexpected error when accepting TCP connections at %q: %s", addr, err)
+		}
+		if !cm.Add(c) {
+			_ = c.Close()
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			if err := processConn(c, cp); err != nil {
+				logger.Errorf("beats: cannot process data from %s at %q: %s", c.RemoteAddr(), addr, err)
+			}
+
+			cm.Delete(c)
+			_ = c.Close()
+			wg.Done()
+		}()
+	}
+
+	cm.CloseAll(0)
+	wg.Wait()
+}
+
+// processConn reads batches of logs sent by Beats over c, ingests them into vlstorage and acknowledges them.
+func processConn(c net.Conn, cp *insertutil.CommonParams) error {
+	wcr := writeconcurrencylimiter.GetReader(c)
+	defer writeconcurrencylimiter.PutReader(wcr)
+
+	br := getBatchReader(wcr)
+	defer putBatchReader(br)
+
+	aw := &ackWriter{
+		w: c,
+	}
+	for {
+		if err := vlstorage.CanWriteData(); err != nil {
+			return err
+		}
+
+		windowSize, err := br.readWindowSize()
+		wcr.DecConcurrency()
+		if err != nil {
+			if err == io.EOF {
+				// The client closed the connection.
+				return nil
+			}
+			return err
+		}
+
+		stopKeepAlive := aw.startKeepAlive(*keepAliveInterval)
+		lmp := cp.NewLogMessageProcessor("beats", false)
+		seq, err := br.readBatch(windowSize, lmp)
+		// Flush the ingested logs to the storage before sending the acknowledgement to the client.
+		lmp.MustClose()
+		wcr.DecConcurrency()
+		stopKeepAlive()
+		if err != nil {
+			errorsTotal.Inc()
+			return err
+		}
+
+		if err := aw.writeACK(seq); err != nil {
+			return fmt.Errorf("cannot send acknowledgement for sequence number %d: %w", seq, err)
+		}
+		batchesTotal.Inc()
+	}
+}
+
+// ackWriter sends acknowledgements to Beats client.
+type ackWriter struct {
+	// mu protects w from concurrent writes from keep-alive goroutine.
+	mu sync.Mutex
+	w  io.Writer
+
+	buf [6]byte
+}
+
+// writeACK sends acknowledgement for all the events with sequence numbers up to seq.
+func (aw *ackWriter) writeACK(seq uint32) error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	b := marshalACK(aw.buf[:0], seq)
+	_, err := aw.w.Write(b)
+	return err
+}
+
+// startKeepAlive starts sending empty acknowledgements to the client every interval until the returned func is called.
+//
+// Beats treat acknowledgements with zero sequence number as a signal that the batch is still being processed.
+func (aw *ackWriter) startKeepAlive(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := aw.writeACK(0); err != nil {
+					// The error is returned to the caller on the next writeACK call.
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+var (
+	batchesTotal = metrics.NewCounter(`vl_beats_batches_total`)
+	errorsTotal  = metrics.NewCounter(`vl_errors_total{type="beats"}`)
+
+	rowsDroppedTooLong = metrics.NewCounter(`vl_rows_dropped_total{reason="beats_too_long_event"}`)
+	rowsDroppedInvalid = metrics.NewCounter(`vl_rows_dropped_total{reason="beats_invalid_event"}`)
+)
+
+func parseFieldsList(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var a []string
+	err := json.Unmarshal([]byte(s), &a)
+	return a, err
+}
+
+func parseExtraFields(s string) ([]logstorage.Field, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, err
+	}
+	fields := make([]logstorage.Field, 0, len(m))
+	for k, v := range m {
+		fields = append(fields, logstorage.Field{
+			Name:  k,
+			Value: v,
+		})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+	return fields, nil
+}
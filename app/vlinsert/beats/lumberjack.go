@@ -0,0 +1,263 @@
+package beats
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
+)
+
+// Lumberjack v2 protocol frames.
+//
+// See https://github.com/elastic/go-lumber
+const (
+	protocolVersion = '2'
+
+	frameTypeWindowSize = 'W'
+	frameTypeJSON       = 'J'
+	frameTypeCompressed = 'C'
+	frameTypeACK        = 'A'
+)
+
+// maxCompressedFrameSize is the maximum size of compressed frame, which can be sent by the client.
+const maxCompressedFrameSize = 64 * 1024 * 1024
+
+var msgFields = []string{"message"}
+
+// batchReader reads batches of events sent by Beats over Lumberjack v2 protocol.
+type batchReader struct {
+	br *bufio.Reader
+
+	// zr and zbr are used for reading compressed frames.
+	zr  io.ReadCloser
+	zbr *bufio.Reader
+
+	buf []byte
+}
+
+func (r *batchReader) reset(src io.Reader) {
+	r.br.Reset(src)
+	r.buf = r.buf[:0]
+}
+
+// readWindowSize reads the window size frame, which starts every batch of events.
+//
+// io.EOF is returned if the client closed the connection before sending the next batch.
+func (r *batchReader) readWindowSize() (uint32, error) {
+	frameType, err := readFrameHeader(r.br)
+	if err != nil {
+		if err == io.EOF {
+			return 0, err
+		}
+		return 0, fmt.Errorf("cannot read window size frame: %w", err)
+	}
+	if frameType != frameTypeWindowSize {
+		return 0, fmt.Errorf("unexpected frame type %q; want window size frame %q", frameType, frameTypeWindowSize)
+	}
+	windowSize, err := readUint32(r.br)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read window size: %w", err)
+	}
+	return windowSize, nil
+}
+
+// readBatch reads windowSize events from r and sends them to lmp.
+//
+// It returns the sequence number of the last read event, which must be acknowledged.
+func (r *batchReader) readBatch(windowSize uint32, lmp insertutil.LogMessageProcessor) (uint32, error) {
+	var seq uint32
+	n := uint32(0)
+	for n < windowSize {
+		frameType, err := readFrameHeader(r.br)
+		if err != nil {
+			return 0, fmt.Errorf("cannot read frame #%d out of %d frames in the batch: %w", n, windowSize, unexpectedEOF(err))
+		}
+		switch frameType {
+		case frameTypeJSON:
+			seq, err = r.readJSONFrame(r.br, lmp)
+			if err != nil {
+				return 0, err
+			}
+			n++
+		case frameTypeCompressed:
+			m, lastSeq, err := r.readCompressedFrame(windowSize-n, lmp)
+			if err != nil {
+				return 0, err
+			}
+			if m > 0 {
+				seq = lastSeq
+			}
+			n += m
+		default:
+			return 0, fmt.Errorf("unexpected frame type %q in the batch; supported frame types: %q, %q", frameType, frameTypeJSON, frameTypeCompressed)
+		}
+	}
+	return seq, nil
+}
+
+// readCompressedFrame reads zlib-compressed frame from r and sends the events from it to lmp.
+//
+// It returns the number of read events and the sequence number of the last read event.
+func (r *batchReader) readCompressedFrame(maxEvents uint32, lmp insertutil.LogMessageProcessor) (uint32, uint32, error) {
+	size, err := readUint32(r.br)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot read compressed frame size: %w", unexpectedEOF(err))
+	}
+	if size > maxCompressedFrameSize {
+		return 0, 0, fmt.Errorf("too big compressed frame size: %d bytes; mustn't exceed %d bytes", size, maxCompressedFrameSize)
+	}
+	lr := io.LimitReader(r.br, int64(size))
+	if r.zr == nil {
+		zr, err := zlib.NewReader(lr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cannot initialize zlib reader for compressed frame: %w", err)
+		}
+		r.zr = zr
+	} else if err := r.zr.(zlib.Resetter).Reset(lr, nil); err != nil {
+		return 0, 0, fmt.Errorf("cannot initialize zlib reader for compressed frame: %w", err)
+	}
+	if r.zbr == nil {
+		r.zbr = bufio.NewReaderSize(r.zr, 64*1024)
+	} else {
+		r.zbr.Reset(r.zr)
+	}
+
+	var seq uint32
+	n := uint32(0)
+	for {
+		frameType, err := readFrameHeader(r.zbr)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, 0, fmt.Errorf("cannot read frame #%d in compressed frame: %w", n, err)
+		}
+		if frameType != frameTypeJSON {
+			return 0, 0, fmt.Errorf("unexpected frame type %q in compressed frame; want %q", frameType, frameTypeJSON)
+		}
+		if n >= maxEvents {
+			return 0, 0, fmt.Errorf("compressed frame contains more than %d events left in the current window", maxEvents)
+		}
+		seq, err = r.readJSONFrame(r.zbr, lmp)
+		if err != nil {
+			return 0, 0, err
+		}
+		n++
+	}
+
+	// Drain the remaining data of the compressed frame, such as zlib checksum.
+	if _, err := io.Copy(io.Discard, lr); err != nil {
+		return 0, 0, fmt.Errorf("cannot read the remaining data of compressed frame: %w", err)
+	}
+	return n, seq, nil
+}
+
+// readJSONFrame reads JSON-encoded event from br and sends it to lmp.
+//
+// It returns the sequence number of the read event.
+func (r *batchReader) readJSONFrame(br *bufio.Reader, lmp insertutil.LogMessageProcessor) (uint32, error) {
+	seq, err := readUint32(br)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read sequence number for JSON frame: %w", unexpectedEOF(err))
+	}
+	size, err := readUint32(br)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read JSON frame size: %w", unexpectedEOF(err))
+	}
+	if maxSize := insertutil.MaxLineSizeBytes.IntN(); size > uint32(maxSize) {
+		// Skip too long event instead of returning error, since otherwise the client will re-send it infinitely.
+		if _, err := br.Discard(int(size)); err != nil {
+			return 0, fmt.Errorf("cannot skip JSON frame with size %d bytes: %w", size, unexpectedEOF(err))
+		}
+		logger.Warnf("beats: skipping event with sequence number %d, since its size exceeds -insert.maxLineSizeBytes=%d; size=%d bytes", seq, maxSize, size)
+		rowsDroppedTooLong.Inc()
+		return seq, nil
+	}
+	r.buf = slicesutil.SetLength(r.buf, int(size))
+	if _, err := io.ReadFull(br, r.buf); err != nil {
+		return 0, fmt.Errorf("cannot read JSON frame with size %d bytes: %w", size, unexpectedEOF(err))
+	}
+	if err := processEvent(r.buf, lmp); err != nil {
+		// Skip invalid event instead of returning error, since otherwise the client will re-send it infinitely.
+		logger.Warnf("beats: skipping event with sequence number %d: %s", seq, err)
+		rowsDroppedInvalid.Inc()
+	}
+	return seq, nil
+}
+
+func processEvent(data []byte, lmp insertutil.LogMessageProcessor) error {
+	p := logstorage.GetJSONParser()
+	defer logstorage.PutJSONParser(p)
+
+	if err := p.ParseLogMessage(data); err != nil {
+		return fmt.Errorf("cannot parse JSON-encoded event: %w", err)
+	}
+	ts, err := insertutil.ExtractTimestampFromFields("@timestamp", p.Fields)
+	if err != nil {
+		return err
+	}
+	logstorage.RenameField(p.Fields, msgFields, "_msg")
+	lmp.AddRow(ts, p.Fields, nil)
+	return nil
+}
+
+// readFrameHeader reads protocol version and frame type from br.
+//
+// io.EOF is returned if br has no more data.
+func readFrameHeader(br *bufio.Reader) (byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return 0, err
+	}
+	if header[0] != protocolVersion {
+		return 0, fmt.Errorf("unsupported protocol version %q; only Lumberjack v2 protocol is supported", header[0])
+	}
+	return header[1], nil
+}
+
+func readUint32(br *bufio.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(br, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// marshalACK appends acknowledgement frame for the given seq to dst and returns the result.
+func marshalACK(dst []byte, seq uint32) []byte {
+	dst = append(dst, protocolVersion, frameTypeACK)
+	return binary.BigEndian.AppendUint32(dst, seq)
+}
+
+func getBatchReader(src io.Reader) *batchReader {
+	v := batchReaderPool.Get()
+	if v == nil {
+		return &batchReader{
+			br: bufio.NewReaderSize(src, 64*1024),
+		}
+	}
+	r := v.(*batchReader)
+	r.reset(src)
+	return r
+}
+
+func putBatchReader(r *batchReader) {
+	r.reset(nil)
+	batchReaderPool.Put(r)
+}
+
+var batchReaderPool sync.Pool
@@ -0,0 +1,170 @@
+package beats
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+)
+
+func TestBatchReaderSuccess(t *testing.T) {
+	f := func(data []byte, seqExpected uint32, timestampsExpected []int64, resultExpected string) {
+		t.Helper()
+
+		br := getBatchReader(bytes.NewReader(data))
+		defer putBatchReader(br)
+
+		windowSize, err := br.readWindowSize()
+		if err != nil {
+			t.Fatalf("cannot read window size: %s", err)
+		}
+		tlp := &insertutil.TestLogMessageProcessor{}
+		seq, err := br.readBatch(windowSize, tlp)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if seq != seqExpected {
+			t.Fatalf("unexpected sequence number; got %d; want %d", seq, seqExpected)
+		}
+		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := br.readWindowSize(); err == nil {
+			t.Fatalf("expecting non-nil error at the end of the stream")
+		}
+	}
+
+	event1 := `{"@timestamp":"2024-12-01T10:20:30.123Z","@metadata":{"beat":"filebeat"},"message":"foo bar","host":{"name":"h1"}}`
+	event2 := `{"@timestamp":"2024-12-01T10:20:31Z","message":"baz","log":{"file":{"path":"/var/log/app.log"}}}`
+	resultExpected := `{"@metadata.beat":"filebeat","_msg":"foo bar","host.name":"h1"}
+{"_msg":"baz","log.file.path":"/var/log/app.log"}`
+	timestampsExpected := []int64{1733048430123000000, 1733048431000000000}
+
+	// empty window
+	f(marshalWindowSize(nil, 0), 0, nil, "")
+
+	// uncompressed events
+	data := marshalWindowSize(nil, 2)
+	data = marshalJSONFrame(data, 1, event1)
+	data = marshalJSONFrame(data, 2, event2)
+	f(data, 2, timestampsExpected, resultExpected)
+
+	// compressed events
+	data = marshalWindowSize(nil, 2)
+	data = marshalCompressedFrame(data, marshalJSONFrame(marshalJSONFrame(nil, 1, event1), 2, event2))
+	f(data, 2, timestampsExpected, resultExpected)
+
+	// events split between multiple compressed frames
+	data = marshalWindowSize(nil, 2)
+	data = marshalCompressedFrame(data, marshalJSONFrame(nil, 1, event1))
+	data = marshalCompressedFrame(data, marshalJSONFrame(nil, 2, event2))
+	f(data, 2, timestampsExpected, resultExpected)
+
+	// invalid event is skipped, but its sequence number is acknowledged
+	data = marshalWindowSize(nil, 3)
+	data = marshalJSONFrame(data, 1, event1)
+	data = marshalJSONFrame(data, 2, event2)
+	data = marshalJSONFrame(data, 3, `{"message":`)
+	f(data, 3, timestampsExpected, resultExpected)
+}
+
+func TestBatchReaderFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+
+		br := getBatchReader(bytes.NewReader(data))
+		defer putBatchReader(br)
+
+		windowSize, err := br.readWindowSize()
+		if err != nil {
+			return
+		}
+		tlp := &insertutil.TestLogMessageProcessor{}
+		if _, err := br.readBatch(windowSize, tlp); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	event := `{"message":"foo"}`
+
+	// unsupported protocol version
+	f([]byte("1W\x00\x00\x00\x01"))
+
+	// missing window size frame
+	f(marshalJSONFrame(nil, 1, event))
+
+	// incomplete window size frame
+	f([]byte("2W\x00\x00"))
+
+	// missing events
+	f(marshalWindowSize(nil, 2))
+	f(marshalJSONFrame(marshalWindowSize(nil, 2), 1, event))
+
+	// incomplete event
+	data := marshalJSONFrame(marshalWindowSize(nil, 1), 1, event)
+	f(data[:len(data)-1])
+
+	// unexpected frame type
+	f(append(marshalWindowSize(nil, 1), "2X"...))
+	f(marshalWindowSize(marshalWindowSize(nil, 1), 1))
+
+	// invalid compressed frame
+	data = marshalWindowSize(nil, 1)
+	data = append(data, "2C"...)
+	data = binary.BigEndian.AppendUint32(data, 3)
+	data = append(data, "foo"...)
+	f(data)
+
+	// compressed frame with more events than the window size
+	data = marshalWindowSize(nil, 1)
+	data = marshalCompressedFrame(data, marshalJSONFrame(marshalJSONFrame(nil, 1, event), 2, event))
+	f(data)
+
+	// nested compressed frame
+	data = marshalWindowSize(nil, 1)
+	data = marshalCompressedFrame(data, marshalCompressedFrame(nil, marshalJSONFrame(nil, 1, event)))
+	f(data)
+}
+
+func TestMarshalACK(t *testing.T) {
+	f := func(seq uint32, resultExpected string) {
+		t.Helper()
+
+		result := marshalACK(nil, seq)
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f(0, "2A\x00\x00\x00\x00")
+	f(1, "2A\x00\x00\x00\x01")
+	f(0x01020304, "2A\x01\x02\x03\x04")
+}
+
+func marshalWindowSize(dst []byte, windowSize uint32) []byte {
+	dst = append(dst, protocolVersion, frameTypeWindowSize)
+	return binary.BigEndian.AppendUint32(dst, windowSize)
+}
+
+func marshalJSONFrame(dst []byte, seq uint32, data string) []byte {
+	dst = append(dst, protocolVersion, frameTypeJSON)
+	dst = binary.BigEndian.AppendUint32(dst, seq)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(data)))
+	return append(dst, data...)
+}
+
+func marshalCompressedFrame(dst, frames []byte) []byte {
+	var bb bytes.Buffer
+	zw := zlib.NewWriter(&bb)
+	if _, err := zw.Write(frames); err != nil {
+		panic(err)
+	}
+	if err := zw.Close(); err != nil {
+		panic(err)
+	}
+	dst = append(dst, protocolVersion, frameTypeCompressed)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(bb.Len()))
+	return append(dst, bb.Bytes()...)
+}
@@ -9,13 +9,13 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
@@ -23,6 +23,8 @@ import (
 
 var (
 	elasticsearchVersion = flag.String("elasticsearch.version", "8.9.0", "Elasticsearch version to report to client")
+	maxRequestSize       = flagutil.NewBytes("elasticsearch.maxRequestSize", 64*1024*1024, "The maximum size in bytes of a single Elasticsearch bulk API request after decompression. "+
+		"The request is processed in a streaming manner, so the limit doesn't affect memory usage")
 )
 
 // RequestHandler processes Elasticsearch insert requests
@@ -59,6 +61,11 @@ func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
 		fmt.Fprintf(w, `{}`)
 		return true
 	}
+	if r.Method == http.MethodHead && isIndexPath(path) {
+		// Return empty response for index existence request, since VictoriaLogs accepts logs for any index.
+		// See https://www.elastic.co/guide/en/elasticsearch/reference/current/indices-exists.html
+		return true
+	}
 	switch path {
 	case "/", "":
 		switch r.Method {
@@ -99,20 +106,27 @@ func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
-		lmp := cp.NewLogMessageProcessor("elasticsearch_bulk", true)
 		encoding := r.Header.Get("Content-Encoding")
+		if encoding == "" && r.ContentLength > maxRequestSize.N {
+			// Reject too big request before ingesting documents from it.
+			bulkErrorsTotal.Inc()
+			httpserver.Errorf(w, r, "cannot process /_bulk request: the request size %d bytes exceeds -elasticsearch.maxRequestSize=%d bytes", r.ContentLength, maxRequestSize.N)
+			return true
+		}
+		lmp := cp.NewLogMessageProcessor("elasticsearch_bulk", true)
 		streamName := fmt.Sprintf("remoteAddr=%s, requestURI=%q", httpserver.GetQuotedRemoteAddr(r), r.RequestURI)
-		n, err := readBulkRequest(streamName, r.Body, encoding, cp.TimeField, cp.MsgFields, lmp)
+		items, err := readBulkRequest(streamName, r.Body, encoding, cp.TimeField, cp.MsgFields, lmp)
 		lmp.MustClose()
 		if err != nil {
-			logger.Warnf("cannot decode log message #%d in /_bulk request: %s, stream fields: %s", n, err, cp.StreamFields)
+			bulkErrorsTotal.Inc()
+			httpserver.Errorf(w, r, "cannot process /_bulk request after processing %d documents: %s; stream fields: %s", len(items), err, cp.StreamFields)
 			return true
 		}
 
 		tookMs := time.Since(startTime).Milliseconds()
 		bw := bufferedwriter.Get(w)
 		defer bufferedwriter.Put(bw)
-		WriteBulkResponse(bw, n, tookMs)
+		WriteBulkResponse(bw, items, tookMs)
 		_ = bw.Flush()
 
 		// update bulkRequestDuration only for successfully parsed requests
@@ -128,82 +142,182 @@ func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
 
 var (
 	bulkRequestsTotal   = metrics.NewCounter(`vl_http_requests_total{path="/insert/elasticsearch/_bulk"}`)
+	bulkErrorsTotal     = metrics.NewCounter(`vl_http_errors_total{path="/insert/elasticsearch/_bulk"}`)
 	bulkRequestDuration = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/elasticsearch/_bulk"}`)
+
+	bulkRejectedDocsTotal = metrics.NewCounter(`vl_rows_dropped_total{reason="elasticsearch_bulk_invalid_document"}`)
 )
 
-func readBulkRequest(streamName string, r io.Reader, encoding string, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) (int, error) {
+// isIndexPath returns true if path refers to Elasticsearch index, e.g. /index-name
+func isIndexPath(path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	return path != "" && !strings.HasPrefix(path, "_") && !strings.Contains(path, "/")
+}
+
+// bulkItem contains the result of processing a single document from the /_bulk request.
+//
+// See https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html#bulk-api-response-body
+type bulkItem struct {
+	// op is the bulk operation for the document - "create" or "index".
+	op string
+
+	// index is the _index value from the bulk operation.
+	index string
+
+	// status is HTTP status code for the document.
+	status int
+
+	// errorReason is the reason for the document rejection. It is empty for successfully processed documents.
+	errorReason string
+}
+
+func (bi *bulkItem) setError(err error) {
+	bi.status = http.StatusBadRequest
+	bi.errorReason = err.Error()
+	bulkRejectedDocsTotal.Inc()
+}
+
+// hasBulkItemErrors returns true if at least a single item in items has been rejected.
+func hasBulkItemErrors(items []bulkItem) bool {
+	for i := range items {
+		if items[i].errorReason != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// readBulkRequest reads the /_bulk request from r and returns the results for the processed documents.
+//
+// Invalid documents are rejected and the error reason is stored at the corresponding item,
+// while the remaining documents are processed as usual.
+// An error is returned only if the request cannot be parsed further, e.g. on invalid bulk operations
+// or if the request size exceeds -elasticsearch.maxRequestSize. The returned items contain the documents processed before the error.
+func readBulkRequest(streamName string, r io.Reader, encoding string, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) ([]bulkItem, error) {
 	// See https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
 
 	reader, err := protoparserutil.GetUncompressedReader(r, encoding)
 	if err != nil {
-		return 0, fmt.Errorf("cannot decode Elasticsearch protocol data: %w", err)
+		return nil, fmt.Errorf("cannot decode Elasticsearch protocol data: %w", err)
 	}
 	defer protoparserutil.PutUncompressedReader(reader)
 
 	wcr := writeconcurrencylimiter.GetReader(reader)
 	defer writeconcurrencylimiter.PutReader(wcr)
 
-	lr := insertutil.NewLineReader(streamName, wcr)
+	msr := &maxSizeReader{
+		r: wcr,
+		n: maxRequestSize.N + 1,
+	}
+	lr := insertutil.NewLineReader(streamName, msr)
 
-	n := 0
+	var items []bulkItem
 	for {
-		ok, err := readBulkLine(lr, timeField, msgFields, lmp)
+		item, ok, err := readBulkLine(lr, timeField, msgFields, lmp)
 		wcr.DecConcurrency()
 		if err != nil || !ok {
-			return n, err
+			return items, err
 		}
-		n++
+		items = append(items, item)
+	}
+}
+
+// maxSizeReader returns an error if more than -elasticsearch.maxRequestSize bytes are read from r.
+type maxSizeReader struct {
+	r io.Reader
+
+	// n is the number of bytes left to read from r until the limit is exceeded.
+	n int64
+}
+
+func (msr *maxSizeReader) Read(p []byte) (int, error) {
+	if msr.n <= 0 {
+		return 0, fmt.Errorf("the request size exceeds -elasticsearch.maxRequestSize=%d bytes", maxRequestSize.N)
 	}
+	if int64(len(p)) > msr.n {
+		p = p[:msr.n]
+	}
+	n, err := msr.r.Read(p)
+	msr.n -= int64(n)
+	return n, err
 }
 
-func readBulkLine(lr *insertutil.LineReader, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) (bool, error) {
+func readBulkLine(lr *insertutil.LineReader, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) (bulkItem, bool, error) {
 	var line []byte
 
 	// Read the command, must be "create" or "index"
 	for len(line) == 0 {
 		if !lr.NextLine() {
 			err := lr.Err()
-			return false, err
+			return bulkItem{}, false, err
 		}
 		line = lr.Line
 	}
-	lineStr := bytesutil.ToUnsafeString(line)
-	if !strings.Contains(lineStr, `"create"`) && !strings.Contains(lineStr, `"index"`) {
-		return false, fmt.Errorf(`unexpected command %q; expecting "create" or "index"`, line)
+	op, index, err := parseBulkAction(line)
+	if err != nil {
+		return bulkItem{}, false, err
+	}
+	item := bulkItem{
+		op:     op,
+		index:  index,
+		status: http.StatusCreated,
 	}
 
 	// Decode log message
 	if !lr.NextLine() {
 		if err := lr.Err(); err != nil {
-			return false, err
+			return item, false, err
 		}
-		return false, fmt.Errorf(`missing log message after the "create" or "index" command`)
+		return item, false, fmt.Errorf(`missing log message after the "create" or "index" command`)
 	}
 	line = lr.Line
 	if len(line) == 0 {
 		// Special case - the line could be too long, so it was skipped.
-		// Continue parsing next lines.
-		return true, nil
+		// Reject the document and continue parsing next lines.
+		item.setError(fmt.Errorf("the log entry is too long or empty"))
+		return item, true, nil
 	}
 	p := logstorage.GetJSONParser()
+	defer logstorage.PutJSONParser(p)
 	if err := p.ParseLogMessage(line); err != nil {
-		return false, fmt.Errorf("cannot parse json-encoded log entry: %w", err)
+		item.setError(fmt.Errorf("cannot parse json-encoded log entry: %w", err))
+		return item, true, nil
 	}
 
 	ts, err := extractTimestampFromFields(timeField, p.Fields)
 	if err != nil {
-		return false, fmt.Errorf("cannot parse timestamp: %w", err)
+		item.setError(fmt.Errorf("cannot parse timestamp: %w", err))
+		return item, true, nil
 	}
 	if ts == 0 {
 		ts = time.Now().UnixNano()
 	}
 	logstorage.RenameField(p.Fields, msgFields, "_msg")
 	lmp.AddRow(ts, p.Fields, nil)
-	logstorage.PutJSONParser(p)
 
-	return true, nil
+	return item, true, nil
 }
 
+// parseBulkAction parses bulk action line and returns the operation name and the _index value from it.
+func parseBulkAction(line []byte) (string, string, error) {
+	p := bulkActionParserPool.Get()
+	defer bulkActionParserPool.Put(p)
+
+	v, err := p.ParseBytes(line)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot parse bulk action %q: %w", line, err)
+	}
+	for _, op := range []string{"create", "index"} {
+		if av := v.Get(op); av != nil {
+			index := string(av.GetStringBytes("_index"))
+			return op, index, nil
+		}
+	}
+	return "", "", fmt.Errorf(`unexpected command %q; expecting "create" or "index"`, line)
+}
+
+var bulkActionParserPool fastjson.ParserPool
+
 func extractTimestampFromFields(timeField string, fields []logstorage.Field) (int64, error) {
 	for i := range fields {
 		f := &fields[i]
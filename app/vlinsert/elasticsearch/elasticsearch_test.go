@@ -14,26 +14,105 @@ import (
 )
 
 func TestReadBulkRequest_Failure(t *testing.T) {
-	f := func(data string) {
+	f := func(data string, timestampsExpected []int64, resultExpected string) {
 		t.Helper()
 
 		tlp := &insertutil.TestLogMessageProcessor{}
 		r := bytes.NewBufferString(data)
-		rows, err := readBulkRequest("test", r, "", "_time", []string{"_msg"}, tlp)
+		items, err := readBulkRequest("test", r, "", "_time", []string{"_msg"}, tlp)
 		if err == nil {
 			t.Fatalf("expecting non-empty error")
 		}
-		if rows != 0 {
-			t.Fatalf("unexpected non-zero rows=%d", rows)
+		if len(items) != len(timestampsExpected) {
+			t.Fatalf("unexpected items; got %d; want %d", len(items), len(timestampsExpected))
+		}
+		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
 		}
 	}
-	f("foobar")
-	f(`{}`)
-	f(`{"create":{}}`)
+	f("foobar", nil, "")
+	f(`{}`, nil, "")
+	f(`{"create":{}}`, nil, "")
 	f(`{"creat":{}}
-{}`)
+{}`, nil, "")
+
+	// The request is processed in a streaming manner, so valid documents before the invalid command are ingested
+	f(`{"create":{}}
+{"_msg":"a","_time":"1686026893"}
+{"creat":{}}
+{"_msg":"b","_time":"1686026894"}`, []int64{1686026893000000000}, `{"_msg":"a"}`)
+
+	// valid documents followed by missing document
 	f(`{"create":{}}
-foobar`)
+{"_msg":"a","_time":"1686026893"}
+{"create":{}}`, []int64{1686026893000000000}, `{"_msg":"a"}`)
+}
+
+func TestReadBulkRequest_MaxRequestSize(t *testing.T) {
+	origMaxRequestSize := maxRequestSize.N
+	defer func() {
+		maxRequestSize.N = origMaxRequestSize
+	}()
+
+	data := `{"create":{}}
+{"_msg":"a","_time":"1686026893"}
+`
+	f := func(maxSize int64, resultExpected bool) {
+		t.Helper()
+
+		maxRequestSize.N = maxSize
+		tlp := &insertutil.TestLogMessageProcessor{}
+		_, err := readBulkRequest("test", bytes.NewBufferString(data), "", "_time", []string{"_msg"}, tlp)
+		if resultExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !resultExpected && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(int64(len(data)), true)
+	f(int64(len(data))-1, false)
+	f(10, false)
+}
+
+func TestReadBulkRequest_PartialSuccess(t *testing.T) {
+	f := func(data string, timestampsExpected []int64, resultExpected, responseExpected string) {
+		t.Helper()
+
+		tlp := &insertutil.TestLogMessageProcessor{}
+		r := bytes.NewBufferString(data)
+		items, err := readBulkRequest("test", r, "", "_time", []string{"_msg"}, tlp)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
+		}
+		response := BulkResponse(items, 0)
+		if response != responseExpected {
+			t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", response, responseExpected)
+		}
+	}
+
+	// invalid log entry
+	f(`{"create":{"_index":"foo"}}
+foobar`, nil, "", `{"took":0,"errors":true,"items":[{"create":{"_index":"foo","status":400,"error":{"type":"document_parsing_exception","reason":"cannot parse json-encoded log entry: cannot parse json: cannot parse JSON: unexpected value found: \"foobar\"; unparsed tail: \"foobar\""}}}]}`)
+
+	// invalid log entries mixed with valid log entries
+	f(`{"create":{"_index":"foo"}}
+{"_msg":"a","_time":"1686026893"}
+{"index":{"_index":"bar"}}
+{"_msg":"b","_time":"foobar"}
+{"index":{}}
+{"_msg":"c","_time":"1686026894"}
+`, []int64{1686026893000000000, 1686026894000000000}, `{"_msg":"a"}
+{"_msg":"c"}`, `{"took":0,"errors":true,"items":[{"create":{"_index":"foo","status":201}},{"index":{"_index":"bar","status":400,"error":{"type":"document_parsing_exception","reason":"cannot parse timestamp: cannot parse unix timestamp from \"foobar\": strconv.ParseInt: parsing \"foobar\": invalid syntax"}}},{"index":{"_index":"","status":201}}]}`)
+
+	// valid log entries
+	f(`{"create":{"_index":"foo"}}
+{"_msg":"a","_time":"1686026893"}
+`, []int64{1686026893000000000}, `{"_msg":"a"}`, `{"took":0,"errors":false,"items":[{"create":{"_index":"foo","status":201}}]}`)
 }
 
 func TestReadBulkRequest_Success(t *testing.T) {
@@ -45,12 +124,15 @@ func TestReadBulkRequest_Success(t *testing.T) {
 
 		// Read the request without compression
 		r := bytes.NewBufferString(data)
-		rows, err := readBulkRequest("test", r, "", timeField, msgFields, tlp)
+		items, err := readBulkRequest("test", r, "", timeField, msgFields, tlp)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
-		if rows != len(timestampsExpected) {
-			t.Fatalf("unexpected rows read; got %d; want %d", rows, len(timestampsExpected))
+		if len(items) != len(timestampsExpected) {
+			t.Fatalf("unexpected rows read; got %d; want %d", len(items), len(timestampsExpected))
+		}
+		if hasBulkItemErrors(items) {
+			t.Fatalf("unexpected errors in items: %v", items)
 		}
 		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
 			t.Fatal(err)
@@ -62,12 +144,12 @@ func TestReadBulkRequest_Success(t *testing.T) {
 			data = compressData(data, encoding)
 		}
 		r = bytes.NewBufferString(data)
-		rows, err = readBulkRequest("test", r, encoding, timeField, msgFields, tlp)
+		items, err = readBulkRequest("test", r, encoding, timeField, msgFields, tlp)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
-		if rows != len(timestampsExpected) {
-			t.Fatalf("unexpected rows read; got %d; want %d", rows, len(timestampsExpected))
+		if len(items) != len(timestampsExpected) {
+			t.Fatalf("unexpected rows read; got %d; want %d", len(items), len(timestampsExpected))
 		}
 		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
 			t.Fatalf("verification failure after compression: %s", err)
@@ -125,3 +207,22 @@ func compressData(s string, encoding string) string {
 	}
 	return bb.String()
 }
+
+func TestIsIndexPath(t *testing.T) {
+	f := func(path string, resultExpected bool) {
+		t.Helper()
+
+		result := isIndexPath(path)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for isIndexPath(%q); got %v; want %v", path, result, resultExpected)
+		}
+	}
+
+	f("/filebeat-8.8.0", true)
+	f("logs", true)
+	f("", false)
+	f("/", false)
+	f("/_bulk", false)
+	f("/_cat/indices", false)
+	f("/logs/_count", false)
+}
@@ -0,0 +1,198 @@
+package heroku
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
+)
+
+var (
+	maxRequestSize = flagutil.NewBytes("heroku.maxRequestSize", 64*1024*1024, "The maximum size in bytes of a single Heroku Logplex drain request")
+)
+
+// defaultStreamFields contains stream fields for logs ingested via Heroku Logplex drains if _stream_fields aren't set in the request.
+var defaultStreamFields = []string{
+	"logplex_drain_token",
+	"app_name",
+	"proc_id",
+}
+
+// RequestHandler processes Heroku Logplex drain requests.
+//
+// See https://devcenter.heroku.com/articles/log-drains#https-drains
+func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
+	switch path {
+	case "/logplex":
+		handleLogplex(w, r)
+		return true
+	default:
+		return false
+	}
+}
+
+func handleLogplex(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	logplexRequestsTotal.Inc()
+
+	cp, err := insertutil.GetCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if len(cp.StreamFields) == 0 {
+		cp.StreamFields = defaultStreamFields
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	drainToken := r.Header.Get("Logplex-Drain-Token")
+	encoding := r.Header.Get("Content-Encoding")
+	err = protoparserutil.ReadUncompressedData(r.Body, encoding, maxRequestSize, func(data []byte) error {
+		lmp := cp.NewLogMessageProcessor("heroku", false)
+		err := readLogplexFrames(data, drainToken, lmp)
+		lmp.MustClose()
+		return err
+	})
+	if err != nil {
+		logplexErrorsTotal.Inc()
+		httpserver.Errorf(w, r, "cannot read Heroku Logplex frames: %s", err)
+		return
+	}
+
+	// update logplexRequestDuration only for successfully parsed requests
+	// There is no need in updating logplexRequestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	logplexRequestDuration.UpdateDuration(startTime)
+
+	// Logplex expects 2xx status code for successfully delivered frames.
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var (
+	logplexRequestsTotal   = metrics.NewCounter(`vl_http_requests_total{path="/insert/heroku/logplex"}`)
+	logplexErrorsTotal     = metrics.NewCounter(`vl_http_errors_total{path="/insert/heroku/logplex"}`)
+	logplexRequestDuration = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/heroku/logplex"}`)
+)
+
+// readLogplexFrames reads octet-counted syslog frames from data in application/logplex-1 format.
+//
+// Every frame has the following format:
+//
+//	<length> <priority>1 <timestamp> <hostname> <app_name> <proc_id> <msg_id> <message>
+//
+// The length is the number of bytes in the frame after the space following the length.
+func readLogplexFrames(data []byte, drainToken string, lmp insertutil.LogMessageProcessor) error {
+	var fields []logstorage.Field
+	for {
+		data = bytes.TrimLeft(data, " \r\n")
+		if len(data) == 0 {
+			return nil
+		}
+		n := bytes.IndexByte(data, ' ')
+		if n < 0 {
+			return fmt.Errorf("missing frame length in %q", data)
+		}
+		frameLen, err := strconv.Atoi(bytesutil.ToUnsafeString(data[:n]))
+		if err != nil || frameLen < 0 {
+			return fmt.Errorf("cannot parse frame length %q", data[:n])
+		}
+		data = data[n+1:]
+		if frameLen > len(data) {
+			return fmt.Errorf("too big frame length %d; the remaining data size is %d bytes", frameLen, len(data))
+		}
+		frame := bytesutil.ToUnsafeString(data[:frameLen])
+		data = data[frameLen:]
+
+		frame = strings.TrimSuffix(frame, "\n")
+		frame = strings.TrimSuffix(frame, "\r")
+		fields, err = parseLogplexFrame(fields[:0], frame)
+		if err != nil {
+			return fmt.Errorf("cannot parse frame %q: %w", frame, err)
+		}
+		if drainToken != "" {
+			fields = append(fields, logstorage.Field{
+				Name:  "logplex_drain_token",
+				Value: drainToken,
+			})
+		}
+		ts, err := insertutil.ExtractTimestampFromFields("timestamp", fields)
+		if err != nil {
+			return fmt.Errorf("cannot get timestamp from frame %q: %w", frame, err)
+		}
+		lmp.AddRow(ts, fields, nil)
+	}
+}
+
+// parseLogplexFrame parses RFC5424 syslog message without structured data from frame and appends the parsed fields to dst.
+//
+// Logplex frames differ from RFC5424 messages by the missing STRUCTURED-DATA part,
+// so they cannot be parsed with logstorage.SyslogParser.
+func parseLogplexFrame(dst []logstorage.Field, frame string) ([]logstorage.Field, error) {
+	if !strings.HasPrefix(frame, "<") {
+		return dst, fmt.Errorf("missing priority")
+	}
+	n := strings.IndexByte(frame, '>')
+	if n < 0 {
+		return dst, fmt.Errorf("missing '>' after priority")
+	}
+	priorityStr := frame[1:n]
+	priority, err := strconv.ParseUint(priorityStr, 10, 64)
+	if err != nil {
+		return dst, fmt.Errorf("cannot parse priority %q: %w", priorityStr, err)
+	}
+	s := frame[n+1:]
+	if !strings.HasPrefix(s, "1 ") {
+		return dst, fmt.Errorf("unsupported syslog version; want 1")
+	}
+	s = s[2:]
+
+	dst = append(dst, logstorage.Field{
+		Name:  "priority",
+		Value: priorityStr,
+	}, logstorage.Field{
+		Name:  "facility",
+		Value: strconv.FormatUint(priority/8, 10),
+	}, logstorage.Field{
+		Name:  "severity",
+		Value: strconv.FormatUint(priority%8, 10),
+	})
+
+	for _, name := range []string{"timestamp", "hostname", "app_name", "proc_id", "msg_id"} {
+		n := strings.IndexByte(s, ' ')
+		if n < 0 {
+			return dst, fmt.Errorf("missing %s", name)
+		}
+		if v := s[:n]; v != "-" {
+			dst = append(dst, logstorage.Field{
+				Name:  name,
+				Value: v,
+			})
+		}
+		s = s[n+1:]
+	}
+	dst = append(dst, logstorage.Field{
+		Name:  "_msg",
+		Value: s,
+	})
+	return dst, nil
+}
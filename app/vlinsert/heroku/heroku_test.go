@@ -0,0 +1,69 @@
+package heroku
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+)
+
+func TestReadLogplexFramesFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		lmp := &insertutil.TestLogMessageProcessor{}
+		if err := readLogplexFrames([]byte(data), "", lmp); err == nil {
+			t.Fatalf("expecting non-empty error")
+		}
+	}
+
+	// missing frame length
+	f("foobar")
+
+	// invalid frame length
+	f("abc <40>1 2012-11-30T06:45:29+00:00 host app web.3 - foo")
+
+	// too big frame length
+	f("1000 <40>1 2012-11-30T06:45:29+00:00 host app web.3 - foo")
+
+	// missing priority
+	f("46 2012-11-30T06:45:29+00:00 host app web.3 - foo")
+
+	// unsupported syslog version
+	f("52 <40>2 2012-11-30T06:45:29+00:00 host app web.3 - foo")
+
+	// missing msg_id
+	f("46 <40>1 2012-11-30T06:45:29+00:00 host app web.3")
+
+	// invalid timestamp
+	f("33 <40>1 foobar host app web.3 - foo")
+}
+
+func TestReadLogplexFramesSuccess(t *testing.T) {
+	f := func(data, drainToken string, timestampsExpected []int64, resultExpected string) {
+		t.Helper()
+
+		lmp := &insertutil.TestLogMessageProcessor{}
+		if err := readLogplexFrames([]byte(data), drainToken, lmp); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := lmp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// empty data
+	f("", "", nil, "")
+
+	// multiple frames with drain token
+	data := `83 <40>1 2012-11-30T06:45:29+00:00 host app web.3 - State changed from starting to up
+119 <40>1 2012-11-30T06:45:26+00:00 host app web.3 - Starting process with command ` + "`bundle exec rackup config.ru -p 24405`" + `
+`
+	f(data, "d.8bf6b2e3-9a5f-4e5a-a7a4-2c2c7d4d1d3e", []int64{1354257929000000000, 1354257926000000000},
+		`{"priority":"40","facility":"5","severity":"0","hostname":"host","app_name":"app","proc_id":"web.3","_msg":"State changed from starting to up","logplex_drain_token":"d.8bf6b2e3-9a5f-4e5a-a7a4-2c2c7d4d1d3e"}
+{"priority":"40","facility":"5","severity":"0","hostname":"host","app_name":"app","proc_id":"web.3","_msg":"Starting process with command `+"`bundle exec rackup config.ru -p 24405`"+`","logplex_drain_token":"d.8bf6b2e3-9a5f-4e5a-a7a4-2c2c7d4d1d3e"}`)
+
+	// frame without trailing newline and with msg_id
+	data = `66 <158>1 2012-11-30T06:45:29.123456+00:00 host heroku router abc foo`
+	f(data, "", []int64{1354257929123456000},
+		`{"priority":"158","facility":"19","severity":"6","hostname":"host","app_name":"heroku","proc_id":"router","msg_id":"abc","_msg":"foo"}`)
+}
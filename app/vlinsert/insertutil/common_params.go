@@ -38,6 +38,10 @@ type CommonParams struct {
 	Debug           bool
 	DebugRequestURI string
 	DebugRemoteAddr string
+
+	// SkipIngestion is set to true for duplicate requests with already seen X-VL-Request-ID header.
+	// Logs for such requests are parsed, but aren't stored.
+	SkipIngestion bool
 }
 
 // GetCommonParams returns CommonParams from r.
@@ -86,6 +90,8 @@ func GetCommonParams(r *http.Request) (*CommonParams, error) {
 		Debug:           debug,
 		DebugRequestURI: debugRequestURI,
 		DebugRemoteAddr: debugRemoteAddr,
+
+		SkipIngestion: isIngestionSkipped(r),
 	}
 
 	return cp, nil
@@ -157,6 +163,9 @@ type logMessageProcessor struct {
 	cp *CommonParams
 	lr *logstorage.LogRows
 
+	// fieldsBuf is used for normalizing severity in the added rows. See severityNormalizer.
+	fieldsBuf []logstorage.Field
+
 	rowsIngestedTotal  *metrics.Counter
 	bytesIngestedTotal *metrics.Counter
 }
@@ -191,6 +200,11 @@ func (lmp *logMessageProcessor) initPeriodicFlush() {
 //
 // If streamFields is non-nil, then it is used as log stream fields instead of the pre-configured stream fields.
 func (lmp *logMessageProcessor) AddRow(timestamp int64, fields, streamFields []logstorage.Field) {
+	if lmp.cp.SkipIngestion {
+		rowsDroppedTotalDuplicateRequest.Inc()
+		return
+	}
+
 	lmp.rowsIngestedTotal.Inc()
 	n := logstorage.EstimatedJSONRowLen(fields)
 	lmp.bytesIngestedTotal.Add(n)
@@ -205,6 +219,10 @@ func (lmp *logMessageProcessor) AddRow(timestamp int64, fields, streamFields []l
 	lmp.mu.Lock()
 	defer lmp.mu.Unlock()
 
+	if sn := severityNormalizerGlobal; sn != nil {
+		lmp.fieldsBuf = sn.normalize(lmp.fieldsBuf[:0], fields)
+		fields = lmp.fieldsBuf
+	}
 	lmp.lr.MustAdd(lmp.cp.TenantID, timestamp, fields, streamFields)
 
 	if lmp.cp.Debug {
@@ -227,6 +245,11 @@ type InsertRowProcessor interface {
 
 // AddInsertRow adds r to lmp.
 func (lmp *logMessageProcessor) AddInsertRow(r *logstorage.InsertRow) {
+	if lmp.cp.SkipIngestion {
+		rowsDroppedTotalDuplicateRequest.Inc()
+		return
+	}
+
 	lmp.rowsIngestedTotal.Inc()
 	n := logstorage.EstimatedJSONRowLen(r.Fields)
 	lmp.bytesIngestedTotal.Add(n)
@@ -299,4 +322,6 @@ func (cp *CommonParams) NewLogMessageProcessor(protocolName string, isStreamMode
 var (
 	rowsDroppedTotalDebug         = metrics.NewCounter(`vl_rows_dropped_total{reason="debug"}`)
 	rowsDroppedTotalTooManyFields = metrics.NewCounter(`vl_rows_dropped_total{reason="too_many_fields"}`)
+
+	rowsDroppedTotalDuplicateRequest = metrics.NewCounter(`vl_rows_dropped_total{reason="duplicate_request"}`)
 )
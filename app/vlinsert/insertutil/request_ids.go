@@ -0,0 +1,428 @@
+package insertutil
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+var (
+	maxRequestIDsPerTenant = flag.Int("insert.maxRequestIDsPerTenant", 100_000, "The maximum number of recently seen X-VL-Request-ID header values to remember per tenant "+
+		"for skipping duplicate ingestion requests. Set to 0 for disabling deduplication of ingestion requests. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries")
+	maxRequestIDs = flag.Int("insert.maxRequestIDs", 1_000_000, "The maximum number of recently seen X-VL-Request-ID header values to remember across all the tenants. "+
+		"The least recently seen values are evicted first when the limit is reached. See also -insert.maxRequestIDsPerTenant. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries")
+	requestIDsPath = flag.String("insert.requestIDsPath", "", "Path to file for persisting recently seen X-VL-Request-ID header values across restarts. "+
+		"By default, they are persisted to <-storageDataPath>/cache/request_ids.json if VictoriaLogs stores data locally, and aren't persisted otherwise. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries")
+	requestIDsSaveInterval = flag.Duration("insert.requestIDsSaveInterval", time.Minute, "The interval for persisting recently seen X-VL-Request-ID header values to -insert.requestIDsPath. "+
+		"The values are persisted only if they have been changed since the previous save. They are also persisted on graceful shutdown. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries")
+)
+
+// requestIDHeader is the name of the request header with the unique id for the ingestion request.
+//
+// See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries
+const requestIDHeader = "X-VL-Request-ID"
+
+// maxRequestIDLen is the maximum length of X-VL-Request-ID header value.
+const maxRequestIDLen = 256
+
+var (
+	rids *requestIDs
+
+	requestIDsSaverStopCh chan struct{}
+	requestIDsSaverWG     sync.WaitGroup
+)
+
+// MustInitRequestIDs initializes tracking of ingestion requests by X-VL-Request-ID header.
+//
+// It must be called after vlstorage.Init, since it may store request ids at -storageDataPath.
+func MustInitRequestIDs() {
+	if *maxRequestIDsPerTenant <= 0 || *maxRequestIDs <= 0 {
+		return
+	}
+	path := *requestIDsPath
+	if path == "" {
+		if dataPath := vlstorage.GetLocalStorageDataPath(); dataPath != "" {
+			path = filepath.Join(dataPath, "cache", "request_ids.json")
+		}
+	}
+	rids = mustLoadRequestIDs(path, *maxRequestIDsPerTenant, *maxRequestIDs)
+	_ = metrics.NewGauge(`vl_insert_request_ids`, func() float64 {
+		return float64(rids.itemsCount())
+	})
+
+	if path == "" {
+		return
+	}
+	requestIDsSaverStopCh = make(chan struct{})
+	requestIDsSaverWG.Add(1)
+	go func() {
+		defer requestIDsSaverWG.Done()
+		rids.runSaver(requestIDsSaverStopCh, *requestIDsSaveInterval)
+	}()
+}
+
+// MustStopRequestIDs persists the seen request ids to disk.
+//
+// It must be called after all the ingestion requests are processed.
+func MustStopRequestIDs() {
+	if rids == nil {
+		return
+	}
+	if requestIDsSaverStopCh != nil {
+		close(requestIDsSaverStopCh)
+		requestIDsSaverWG.Wait()
+		requestIDsSaverStopCh = nil
+	}
+	rids.mustSave()
+}
+
+// StartRequest starts tracking the ingestion request r by X-VL-Request-ID header.
+//
+// It returns false if r cannot be processed now. In this case the response is already sent to w.
+//
+// Otherwise the returned RequestTracker and request must be used instead of w and r for processing r,
+// and RequestTracker.Finish must be called after r is processed.
+// If r is a duplicate of the previously processed request, then the returned request is marked for skipping ingestion,
+// so the protocol handler parses it and sends the usual response without storing the parsed logs. See CommonParams.SkipIngestion.
+func StartRequest(w http.ResponseWriter, r *http.Request) (*RequestTracker, *http.Request, bool) {
+	rt := &RequestTracker{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+	requestID := r.Header.Get(requestIDHeader)
+	if rids == nil || requestID == "" {
+		return rt, r, true
+	}
+	if len(requestID) > maxRequestIDLen {
+		httpserver.Errorf(w, r, "too long %s header value; got %d bytes; mustn't exceed %d bytes", requestIDHeader, len(requestID), maxRequestIDLen)
+		return nil, nil, false
+	}
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		// Let the request handler report the error.
+		return rt, r, true
+	}
+
+	switch rids.start(tenantID, requestID) {
+	case requestIDStateDone:
+		// Let the request handler send the usual response for the duplicate request, since clients may expect protocol-specific response.
+		duplicateRequests.Inc()
+		ctx := context.WithValue(r.Context(), skipIngestionKey{}, true)
+		return rt, r.WithContext(ctx), true
+	case requestIDStateInProgress:
+		err := &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("the request with %s=%q is already in progress; retry it later", requestIDHeader, requestID),
+			StatusCode: http.StatusConflict,
+		}
+		httpserver.Errorf(w, r, "%s", err)
+		return nil, nil, false
+	}
+	rt.tenantID = tenantID
+	rt.requestID = requestID
+	return rt, r, true
+}
+
+var duplicateRequests = metrics.NewCounter(`vl_insert_duplicate_requests_total`)
+
+// skipIngestionKey is the request context key for marking duplicate requests, which mustn't be ingested.
+type skipIngestionKey struct{}
+
+// isIngestionSkipped returns true if logs from r mustn't be ingested, since r is a duplicate of the previously processed request.
+func isIngestionSkipped(r *http.Request) bool {
+	v, _ := r.Context().Value(skipIngestionKey{}).(bool)
+	return v
+}
+
+// RequestTracker tracks the status of the ingestion request with X-VL-Request-ID header.
+type RequestTracker struct {
+	http.ResponseWriter
+
+	tenantID   logstorage.TenantID
+	requestID  string
+	statusCode int
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (rt *RequestTracker) WriteHeader(statusCode int) {
+	rt.statusCode = statusCode
+	rt.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap returns the original http.ResponseWriter.
+//
+// It is used by http.ResponseController.
+func (rt *RequestTracker) Unwrap() http.ResponseWriter {
+	return rt.ResponseWriter
+}
+
+// Finish must be called after the request is processed.
+//
+// The request id is remembered only if the request has been successfully processed, so failed requests can be retried.
+func (rt *RequestTracker) Finish(ok bool) {
+	if rt.requestID == "" {
+		return
+	}
+	ok = ok && rt.statusCode >= 200 && rt.statusCode < 300
+	rids.finish(rt.tenantID, rt.requestID, ok)
+}
+
+type requestIDState int
+
+const (
+	requestIDStateNew requestIDState = iota
+	requestIDStateInProgress
+	requestIDStateDone
+)
+
+// requestIDs holds recently seen request ids per tenant.
+//
+// Every tenant may have up to maxPerTenant request ids, while all the tenants may have up to maxTotal request ids.
+// The least recently seen request ids are evicted first.
+type requestIDs struct {
+	path         string
+	maxPerTenant int
+	maxTotal     int
+
+	mu sync.Mutex
+
+	// ll contains *requestIDEntry items for all the tenants ordered from the most recently seen to the least recently seen.
+	ll *list.List
+
+	// m contains per-tenant request ids.
+	m map[logstorage.TenantID]*tenantRequestIDs
+
+	// changes is incremented on every change of the remembered request ids.
+	// It is used for persisting request ids only if they have been changed.
+	changes uint64
+
+	// savedChanges contains the value of changes at the last save.
+	savedChanges uint64
+}
+
+type tenantRequestIDs struct {
+	// ll contains *requestIDEntry items for the tenant ordered from the most recently seen to the least recently seen.
+	ll *list.List
+
+	// m maps request ids to the corresponding items in ll.
+	m map[string]*list.Element
+}
+
+type requestIDEntry struct {
+	tenantID   logstorage.TenantID
+	id         string
+	inProgress bool
+
+	// e is the corresponding item in requestIDs.ll.
+	e *list.Element
+}
+
+// requestIDsFileEntry is an entry in the file with persisted request ids.
+//
+// Entries in the file are ordered from the least recently seen to the most recently seen across all the tenants,
+// so the same tenant may have multiple entries.
+type requestIDsFileEntry struct {
+	AccountID uint32 `json:"accountID"`
+	ProjectID uint32 `json:"projectID"`
+
+	// RequestIDs contains request ids ordered from the least recently seen to the most recently seen.
+	RequestIDs []string `json:"requestIDs"`
+}
+
+func mustLoadRequestIDs(path string, maxPerTenant, maxTotal int) *requestIDs {
+	rids := &requestIDs{
+		path:         path,
+		maxPerTenant: maxPerTenant,
+		maxTotal:     maxTotal,
+		ll:           list.New(),
+		m:            make(map[logstorage.TenantID]*tenantRequestIDs),
+	}
+	if path == "" || !fs.IsPathExist(path) {
+		return rids
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read request ids from %q: %s", path, err)
+	}
+	var entries []requestIDsFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Errorf("cannot parse request ids from %q; resetting them: %s", path, err)
+		return rids
+	}
+	for _, e := range entries {
+		tenantID := logstorage.TenantID{
+			AccountID: e.AccountID,
+			ProjectID: e.ProjectID,
+		}
+		for _, id := range e.RequestIDs {
+			rids.start(tenantID, id)
+			rids.finish(tenantID, id, true)
+		}
+	}
+	rids.savedChanges = rids.changes
+	return rids
+}
+
+// runSaver persists request ids every interval until stopCh is closed.
+func (rids *requestIDs) runSaver(stopCh <-chan struct{}, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			rids.mustSave()
+		}
+	}
+}
+
+// mustSave persists request ids to rids.path if they have been changed since the last save.
+func (rids *requestIDs) mustSave() {
+	if rids.path == "" {
+		return
+	}
+
+	rids.mu.Lock()
+	if rids.changes == rids.savedChanges {
+		rids.mu.Unlock()
+		return
+	}
+	changes := rids.changes
+	var entries []requestIDsFileEntry
+	for e := rids.ll.Back(); e != nil; e = e.Prev() {
+		rie := e.Value.(*requestIDEntry)
+		if rie.inProgress {
+			continue
+		}
+		if len(entries) == 0 || entries[len(entries)-1].AccountID != rie.tenantID.AccountID || entries[len(entries)-1].ProjectID != rie.tenantID.ProjectID {
+			entries = append(entries, requestIDsFileEntry{
+				AccountID: rie.tenantID.AccountID,
+				ProjectID: rie.tenantID.ProjectID,
+			})
+		}
+		fe := &entries[len(entries)-1]
+		fe.RequestIDs = append(fe.RequestIDs, rie.id)
+	}
+	rids.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal request ids: %s", err)
+	}
+	fs.MustMkdirIfNotExist(filepath.Dir(rids.path))
+	fs.MustWriteAtomic(rids.path, data, true)
+
+	rids.mu.Lock()
+	rids.savedChanges = changes
+	rids.mu.Unlock()
+}
+
+// start registers the start of the request with the given id for the given tenantID.
+//
+// It returns the state of the previously seen request with the same id.
+// requestIDStateNew is returned if the request id hasn't been seen yet.
+func (rids *requestIDs) start(tenantID logstorage.TenantID, id string) requestIDState {
+	rids.mu.Lock()
+	defer rids.mu.Unlock()
+
+	trids := rids.m[tenantID]
+	if trids == nil {
+		trids = &tenantRequestIDs{
+			ll: list.New(),
+			m:  make(map[string]*list.Element),
+		}
+		rids.m[tenantID] = trids
+	}
+	if e := trids.m[id]; e != nil {
+		rie := e.Value.(*requestIDEntry)
+		trids.ll.MoveToFront(e)
+		rids.ll.MoveToFront(rie.e)
+		rids.changes++
+		if rie.inProgress {
+			return requestIDStateInProgress
+		}
+		return requestIDStateDone
+	}
+
+	rie := &requestIDEntry{
+		tenantID:   tenantID,
+		id:         id,
+		inProgress: true,
+	}
+	rie.e = rids.ll.PushFront(rie)
+	trids.m[id] = trids.ll.PushFront(rie)
+	rids.changes++
+
+	for trids.ll.Len() > rids.maxPerTenant {
+		rids.removeLocked(trids.ll.Back().Value.(*requestIDEntry))
+	}
+	for rids.ll.Len() > rids.maxTotal {
+		rids.removeLocked(rids.ll.Back().Value.(*requestIDEntry))
+	}
+	return requestIDStateNew
+}
+
+// finish registers the end of the request with the given id for the given tenantID.
+//
+// The request id is forgotten if ok is false, so the request could be retried.
+func (rids *requestIDs) finish(tenantID logstorage.TenantID, id string, ok bool) {
+	rids.mu.Lock()
+	defer rids.mu.Unlock()
+
+	trids := rids.m[tenantID]
+	if trids == nil {
+		return
+	}
+	e := trids.m[id]
+	if e == nil {
+		// The request id has been evicted while the request was in progress.
+		return
+	}
+	rie := e.Value.(*requestIDEntry)
+	if !ok {
+		rids.removeLocked(rie)
+		return
+	}
+	rie.inProgress = false
+	rids.changes++
+}
+
+// removeLocked removes rie from rids.
+//
+// rids.mu must be locked by the caller.
+func (rids *requestIDs) removeLocked(rie *requestIDEntry) {
+	trids := rids.m[rie.tenantID]
+	trids.ll.Remove(trids.m[rie.id])
+	delete(trids.m, rie.id)
+	if trids.ll.Len() == 0 {
+		delete(rids.m, rie.tenantID)
+	}
+	rids.ll.Remove(rie.e)
+	rids.changes++
+}
+
+func (rids *requestIDs) itemsCount() int {
+	rids.mu.Lock()
+	n := rids.ll.Len()
+	rids.mu.Unlock()
+	return n
+}
@@ -0,0 +1,216 @@
+package insertutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestRequestIDsStartFinish(t *testing.T) {
+	rids := mustLoadRequestIDs("", 2, 100)
+
+	tenant1 := logstorage.TenantID{AccountID: 1}
+	tenant2 := logstorage.TenantID{AccountID: 2}
+
+	f := func(tenantID logstorage.TenantID, id string, stateExpected requestIDState) {
+		t.Helper()
+		state := rids.start(tenantID, id)
+		if state != stateExpected {
+			t.Fatalf("unexpected state for tenant=%v, id=%q; got %d; want %d", tenantID, id, state, stateExpected)
+		}
+	}
+
+	// new request
+	f(tenant1, "foo", requestIDStateNew)
+
+	// duplicate request while the original request is in progress
+	f(tenant1, "foo", requestIDStateInProgress)
+
+	// the same request id for another tenant
+	f(tenant2, "foo", requestIDStateNew)
+	rids.finish(tenant2, "foo", true)
+
+	// duplicate request after successful processing
+	rids.finish(tenant1, "foo", true)
+	f(tenant1, "foo", requestIDStateDone)
+
+	// retry after failed processing
+	f(tenant1, "bar", requestIDStateNew)
+	rids.finish(tenant1, "bar", false)
+	f(tenant1, "bar", requestIDStateNew)
+	rids.finish(tenant1, "bar", true)
+
+	// the least recently seen request id is evicted
+	f(tenant1, "baz", requestIDStateNew)
+	rids.finish(tenant1, "baz", true)
+	f(tenant1, "bar", requestIDStateDone)
+	f(tenant1, "baz", requestIDStateDone)
+	f(tenant1, "foo", requestIDStateNew)
+	rids.finish(tenant1, "foo", true)
+
+	if n := rids.itemsCount(); n != 3 {
+		t.Fatalf("unexpected number of items; got %d; want 3", n)
+	}
+}
+
+func TestStartRequest(t *testing.T) {
+	ridsOrig := rids
+	rids = mustLoadRequestIDs("", 10, 100)
+	defer func() {
+		rids = ridsOrig
+	}()
+
+	f := func(id string, okExpected, skipIngestionExpected bool, statusCodeExpected int) {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodPost, "/insert/jsonline", nil)
+		r.Header.Set(requestIDHeader, id)
+		w := httptest.NewRecorder()
+		rt, r, ok := StartRequest(w, r)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok; got %v; want %v", ok, okExpected)
+		}
+		if !ok {
+			if w.Code != statusCodeExpected {
+				t.Fatalf("unexpected status code; got %d; want %d", w.Code, statusCodeExpected)
+			}
+			return
+		}
+		if skipIngestion := isIngestionSkipped(r); skipIngestion != skipIngestionExpected {
+			t.Fatalf("unexpected skipIngestion; got %v; want %v", skipIngestion, skipIngestionExpected)
+		}
+		rt.WriteHeader(statusCodeExpected)
+		rt.Finish(true)
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", w.Code, statusCodeExpected)
+		}
+	}
+
+	// new request
+	f("foo", true, false, http.StatusNoContent)
+
+	// duplicate request is passed to the request handler, which must send the usual response without ingesting logs
+	f("foo", true, true, http.StatusNoContent)
+
+	// request without id
+	f("", true, false, http.StatusOK)
+}
+
+func TestRequestIDsSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "request_ids.json")
+	tenantID := logstorage.TenantID{AccountID: 1, ProjectID: 2}
+
+	rids := mustLoadRequestIDs(path, 10, 100)
+	for _, id := range []string{"foo", "bar", "baz"} {
+		rids.start(tenantID, id)
+	}
+	rids.finish(tenantID, "foo", true)
+	rids.finish(tenantID, "bar", true)
+	rids.mustSave()
+
+	// Load the saved request ids. In-progress request ids mustn't be persisted.
+	rids = mustLoadRequestIDs(path, 10, 100)
+	if n := rids.itemsCount(); n != 2 {
+		t.Fatalf("unexpected number of items after load; got %d; want 2", n)
+	}
+	if state := rids.start(tenantID, "foo"); state != requestIDStateDone {
+		t.Fatalf("unexpected state for foo after load; got %d; want %d", state, requestIDStateDone)
+	}
+	if state := rids.start(tenantID, "baz"); state != requestIDStateNew {
+		t.Fatalf("unexpected state for baz after load; got %d; want %d", state, requestIDStateNew)
+	}
+
+	// Load the request ids with smaller limit. The most recently seen request ids must be kept.
+	rids.finish(tenantID, "baz", true)
+	rids.mustSave()
+	rids = mustLoadRequestIDs(path, 1, 100)
+	if n := rids.itemsCount(); n != 1 {
+		t.Fatalf("unexpected number of items after load; got %d; want 1", n)
+	}
+	if state := rids.start(tenantID, "baz"); state != requestIDStateDone {
+		t.Fatalf("unexpected state for baz after load; got %d; want %d", state, requestIDStateDone)
+	}
+}
+
+func TestRequestIDsMaxTotal(t *testing.T) {
+	rids := mustLoadRequestIDs("", 10, 3)
+
+	tenant1 := logstorage.TenantID{AccountID: 1}
+	tenant2 := logstorage.TenantID{AccountID: 2}
+
+	f := func(tenantID logstorage.TenantID, id string, stateExpected requestIDState) {
+		t.Helper()
+		state := rids.start(tenantID, id)
+		if state != stateExpected {
+			t.Fatalf("unexpected state for tenant=%v, id=%q; got %d; want %d", tenantID, id, state, stateExpected)
+		}
+		rids.finish(tenantID, id, true)
+	}
+
+	f(tenant1, "foo", requestIDStateNew)
+	f(tenant1, "bar", requestIDStateNew)
+	f(tenant2, "foo", requestIDStateNew)
+
+	// touch tenant1/foo, so tenant1/bar becomes the least recently seen request id across all the tenants
+	f(tenant1, "foo", requestIDStateDone)
+
+	// the least recently seen request id across all the tenants is evicted
+	f(tenant2, "bar", requestIDStateNew)
+	if n := rids.itemsCount(); n != 3 {
+		t.Fatalf("unexpected number of items; got %d; want 3", n)
+	}
+	f(tenant1, "foo", requestIDStateDone)
+	f(tenant2, "foo", requestIDStateDone)
+	f(tenant2, "bar", requestIDStateDone)
+	f(tenant1, "bar", requestIDStateNew)
+
+	// tenants without request ids are removed
+	for _, id := range []string{"a", "b", "c"} {
+		f(tenant2, id, requestIDStateNew)
+	}
+	if _, ok := rids.m[tenant1]; ok {
+		t.Fatalf("unexpected entry for tenant1 after evicting all its request ids")
+	}
+}
+
+func TestRequestIDsSaveLoadOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "request_ids.json")
+	tenant1 := logstorage.TenantID{AccountID: 1}
+	tenant2 := logstorage.TenantID{AccountID: 2}
+
+	rids := mustLoadRequestIDs(path, 10, 10)
+	for _, id := range []string{"a", "b", "c"} {
+		rids.start(tenant1, id)
+		rids.finish(tenant1, id, true)
+		rids.start(tenant2, id)
+		rids.finish(tenant2, id, true)
+	}
+	rids.mustSave()
+
+	// The request ids mustn't be saved if they weren't changed since the last save.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	rids.mustSave()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expecting missing %q after saving unchanged request ids; got %v", path, err)
+	}
+	rids.start(tenant1, "a")
+	rids.mustSave()
+
+	// The least recently seen order across tenants must be preserved after load.
+	rids = mustLoadRequestIDs(path, 10, 2)
+	if n := rids.itemsCount(); n != 2 {
+		t.Fatalf("unexpected number of items after load; got %d; want 2", n)
+	}
+	if state := rids.start(tenant1, "a"); state != requestIDStateDone {
+		t.Fatalf("unexpected state for tenant1/a after load; got %d; want %d", state, requestIDStateDone)
+	}
+	if state := rids.start(tenant2, "c"); state != requestIDStateDone {
+		t.Fatalf("unexpected state for tenant2/c after load; got %d; want %d", state, requestIDStateDone)
+	}
+}
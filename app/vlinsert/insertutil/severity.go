@@ -0,0 +1,220 @@
+package insertutil
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+var (
+	severityFields = flagutil.NewArrayString("insert.severityFields", "Optional list of log fields to read log severity from in the given order of priority; "+
+		"the first field with recognized severity value is normalized into -insert.severityTargetField. Severity normalization is disabled if empty. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#severity-normalization")
+	severityTargetField = flag.String("insert.severityTargetField", "severity", "Log field for storing the normalized severity if -insert.severityFields is set. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#severity-normalization")
+	severityMapping = flagutil.NewArrayString("insert.severityMapping", "Optional list of value=level pairs, which extend or override the built-in mapping of severity values "+
+		"to canonical severity levels if -insert.severityFields is set. For example, -insert.severityMapping='crit=critical,60=critical'. "+
+		"See https://docs.victoriametrics.com/victorialogs/data-ingestion/#severity-normalization")
+)
+
+// canonicalSeverityLevels contains canonical severity levels sorted from the most to the least severe.
+var canonicalSeverityLevels = []string{
+	"emergency",
+	"alert",
+	"critical",
+	"error",
+	"warning",
+	"notice",
+	"info",
+	"debug",
+	"trace",
+}
+
+// defaultSeverityMapping maps lowercase severity values to canonical severity levels.
+var defaultSeverityMapping = map[string]string{
+	// Numeric syslog severities. See https://datatracker.ietf.org/doc/html/rfc5424#section-6.2.1
+	"0": "emergency",
+	"1": "alert",
+	"2": "critical",
+	"3": "error",
+	"4": "warning",
+	"5": "notice",
+	"6": "info",
+	"7": "debug",
+
+	// Numeric Python logging levels. See https://docs.python.org/3/library/logging.html#logging-levels
+	"10": "debug",
+	"20": "info",
+	"30": "warning",
+	"40": "error",
+	"50": "critical",
+
+	"emergency": "emergency",
+	"emerg":     "emergency",
+	"panic":     "emergency",
+
+	"alert": "alert",
+
+	"critical": "critical",
+	"crit":     "critical",
+	"fatal":    "critical",
+	"ftl":      "critical",
+
+	"error": "error",
+	"err":   "error",
+	"eror":  "error",
+	"erro":  "error",
+
+	"warning": "warning",
+	"warn":    "warning",
+	"wrn":     "warning",
+
+	"notice": "notice",
+
+	"informational": "info",
+	"information":   "info",
+	"info":          "info",
+	"inf":           "info",
+
+	"debug": "debug",
+	"dbg":   "debug",
+
+	"trace": "trace",
+	"trc":   "trace",
+}
+
+var severityNormalizerGlobal *severityNormalizer
+
+// MustInitSeverityNormalization initializes severity normalization according to -insert.severity* command-line flags.
+func MustInitSeverityNormalization() {
+	sn, err := newSeverityNormalizer(*severityFields, *severityTargetField, *severityMapping)
+	if err != nil {
+		logger.Fatalf("cannot initialize severity normalization: %s", err)
+	}
+	severityNormalizerGlobal = sn
+}
+
+// severityNormalizer normalizes severity values in log entries.
+type severityNormalizer struct {
+	sourceFields []string
+	targetField  string
+	mapping      map[string]string
+}
+
+// newSeverityNormalizer returns severityNormalizer for the given sourceFields, targetField and mapping in the form value=level.
+//
+// nil is returned if sourceFields is empty.
+func newSeverityNormalizer(sourceFields []string, targetField string, mapping []string) (*severityNormalizer, error) {
+	if len(sourceFields) == 0 {
+		if len(mapping) > 0 {
+			return nil, fmt.Errorf("-insert.severityMapping cannot be used without -insert.severityFields")
+		}
+		return nil, nil
+	}
+	if targetField == "" {
+		return nil, fmt.Errorf("-insert.severityTargetField cannot be empty")
+	}
+
+	m := make(map[string]string, len(defaultSeverityMapping)+len(mapping))
+	for k, v := range defaultSeverityMapping {
+		m[k] = v
+	}
+	for _, s := range mapping {
+		n := strings.LastIndexByte(s, '=')
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid -insert.severityMapping entry %q; it must be in the form value=level", s)
+		}
+		value := strings.ToLower(strings.TrimSpace(s[:n]))
+		level := strings.TrimSpace(s[n+1:])
+		if !slices.Contains(canonicalSeverityLevels, level) {
+			return nil, fmt.Errorf("unexpected level %q at -insert.severityMapping entry %q; supported levels: %s", level, s, strings.Join(canonicalSeverityLevels, ", "))
+		}
+		m[value] = level
+	}
+
+	return &severityNormalizer{
+		sourceFields: sourceFields,
+		targetField:  targetField,
+		mapping:      m,
+	}, nil
+}
+
+// getLevel returns canonical severity level for the given severity value.
+//
+// An empty string is returned if the value isn't recognized.
+func (sn *severityNormalizer) getLevel(value string) string {
+	if level, ok := sn.mapping[value]; ok {
+		// Fast path - the value is already in lowercase.
+		return level
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if level, ok := sn.mapping[value]; ok {
+		return level
+	}
+
+	// Remove numeric suffix from OpenTelemetry severity texts such as Trace2 or Error3.
+	// See https://opentelemetry.io/docs/specs/otel/logs/data-model/#displaying-severity
+	valueTrimmed := strings.TrimRight(value, "0123456789")
+	if valueTrimmed == value || valueTrimmed == "" {
+		return ""
+	}
+	return sn.mapping[valueTrimmed]
+}
+
+// normalize appends fields with the normalized severity to dst and returns the result.
+//
+// The severity is taken from the first of sn.sourceFields with recognized severity value.
+// fields are appended to dst as is if they do not contain recognized severity value.
+func (sn *severityNormalizer) normalize(dst, fields []logstorage.Field) []logstorage.Field {
+	level := ""
+	hasSeverity := false
+	for _, name := range sn.sourceFields {
+		value := getFieldValue(fields, name)
+		if value == "" {
+			continue
+		}
+		hasSeverity = true
+		level = sn.getLevel(value)
+		if level != "" {
+			break
+		}
+	}
+
+	dstLen := len(dst)
+	dst = append(dst, fields...)
+	if level == "" {
+		if hasSeverity {
+			rowsUnknownSeverityTotal.Inc()
+		}
+		return dst
+	}
+	for i := range dst[dstLen:] {
+		f := &dst[dstLen+i]
+		if f.Name == sn.targetField {
+			f.Value = level
+			return dst
+		}
+	}
+	return append(dst, logstorage.Field{
+		Name:  sn.targetField,
+		Value: level,
+	})
+}
+
+var rowsUnknownSeverityTotal = metrics.NewCounter(`vl_rows_unknown_severity_total`)
+
+func getFieldValue(fields []logstorage.Field, name string) string {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
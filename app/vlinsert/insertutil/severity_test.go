@@ -0,0 +1,93 @@
+package insertutil
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestNewSeverityNormalizer_Failure(t *testing.T) {
+	f := func(sourceFields []string, targetField string, mapping []string) {
+		t.Helper()
+
+		if _, err := newSeverityNormalizer(sourceFields, targetField, mapping); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// mapping without source fields
+	f(nil, "severity", []string{"crit=critical"})
+
+	// empty target field
+	f([]string{"level"}, "", nil)
+
+	// invalid mapping
+	f([]string{"level"}, "severity", []string{"crit"})
+	f([]string{"level"}, "severity", []string{"=critical"})
+
+	// unknown level
+	f([]string{"level"}, "severity", []string{"crit=foo"})
+}
+
+func TestSeverityNormalizerNormalize(t *testing.T) {
+	f := func(sourceFields, mapping []string, fields []logstorage.Field, resultExpected string) {
+		t.Helper()
+
+		sn, err := newSeverityNormalizer(sourceFields, "severity", mapping)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := sn.normalize(nil, fields)
+		if s := string(logstorage.MarshalFieldsToJSON(nil, result)); s != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", s, resultExpected)
+		}
+	}
+
+	newFields := func(kvs ...string) []logstorage.Field {
+		var fields []logstorage.Field
+		for i := 0; i < len(kvs); i += 2 {
+			fields = append(fields, logstorage.Field{
+				Name:  kvs[i],
+				Value: kvs[i+1],
+			})
+		}
+		return fields
+	}
+
+	// missing severity
+	f([]string{"level"}, nil, newFields("_msg", "foo"), `{"_msg":"foo"}`)
+
+	// unknown severity
+	f([]string{"level"}, nil, newFields("_msg", "foo", "level", "bar"), `{"_msg":"foo","level":"bar"}`)
+
+	// text severity
+	f([]string{"level"}, nil, newFields("_msg", "foo", "level", "WARN"), `{"_msg":"foo","level":"WARN","severity":"warning"}`)
+	f([]string{"level"}, nil, newFields("level", " Err "), `{"level":" Err ","severity":"error"}`)
+	f([]string{"level"}, nil, newFields("level", "fatal"), `{"level":"fatal","severity":"critical"}`)
+
+	// OpenTelemetry severity text
+	f([]string{"severity"}, nil, newFields("severity", "Trace2"), `{"severity":"trace"}`)
+	f([]string{"severity"}, nil, newFields("severity", "Error4"), `{"severity":"error"}`)
+	f([]string{"severity"}, nil, newFields("severity", "Unspecified"), `{"severity":"Unspecified"}`)
+
+	// numeric syslog severity
+	f([]string{"severity"}, nil, newFields("severity", "3"), `{"severity":"error"}`)
+	f([]string{"PRIORITY"}, nil, newFields("PRIORITY", "6"), `{"PRIORITY":"6","severity":"info"}`)
+
+	// numeric Python level
+	f([]string{"levelno"}, nil, newFields("levelno", "30"), `{"levelno":"30","severity":"warning"}`)
+
+	// unknown numeric level
+	f([]string{"levelno"}, nil, newFields("levelno", "35"), `{"levelno":"35"}`)
+
+	// the first field with recognized severity is used
+	f([]string{"level", "severity"}, nil, newFields("level", "foo", "severity", "debug"), `{"level":"foo","severity":"debug"}`)
+	f([]string{"level", "severity"}, nil, newFields("level", "info", "severity", "3"), `{"level":"info","severity":"info"}`)
+
+	// custom mapping
+	f([]string{"level"}, []string{"60=critical", "Verbose=trace"}, newFields("level", "60"), `{"level":"60","severity":"critical"}`)
+	f([]string{"level"}, []string{"60=critical", "Verbose=trace"}, newFields("level", "VERBOSE"), `{"level":"VERBOSE","severity":"trace"}`)
+
+	// custom mapping overrides the built-in mapping
+	f([]string{"level"}, []string{"0=trace"}, newFields("level", "0"), `{"level":"0","severity":"trace"}`)
+}
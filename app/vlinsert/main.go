@@ -5,24 +5,33 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/beats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/datadog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/elasticsearch"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/heroku"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/internalinsert"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/journald"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/jsonline"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/loki"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/opentelemetry"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/syslog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/vector"
 )
 
 // Init initializes vlinsert
 func Init() {
+	insertutil.MustInitRequestIDs()
+	insertutil.MustInitSeverityNormalization()
 	syslog.MustInit()
+	beats.MustInit()
 }
 
 // Stop stops vlinsert
 func Stop() {
+	beats.MustStop()
 	syslog.MustStop()
+	insertutil.MustStopRequestIDs()
 }
 
 // RequestHandler handles insert requests for VictoriaLogs
@@ -41,17 +50,29 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	path = strings.TrimPrefix(path, "/insert")
 	path = strings.ReplaceAll(path, "//", "/")
 
-	switch path {
-	case "/jsonline":
-		jsonline.RequestHandler(w, r)
-		return true
-	case "/ready":
+	if path == "/ready" {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(200)
 		fmt.Fprintf(w, `{"status":"ok"}`)
 		return true
 	}
+
+	// Skip duplicate requests with the same X-VL-Request-ID header.
+	// See https://docs.victoriametrics.com/victorialogs/data-ingestion/#idempotent-retries
+	rt, r, ok := insertutil.StartRequest(w, r)
+	if !ok {
+		return true
+	}
+	ok = processInsertRequest(path, rt, r)
+	rt.Finish(ok)
+	return ok
+}
+
+func processInsertRequest(path string, w http.ResponseWriter, r *http.Request) bool {
 	switch {
+	case path == "/jsonline":
+		jsonline.RequestHandler(w, r)
+		return true
 	case strings.HasPrefix(path, "/elasticsearch"):
 		// some clients may omit trailing slash
 		// see https://github.com/VictoriaMetrics/VictoriaMetrics/issues/8353
@@ -69,6 +90,12 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	case strings.HasPrefix(path, "/datadog/"):
 		path = strings.TrimPrefix(path, "/datadog")
 		return datadog.RequestHandler(path, w, r)
+	case strings.HasPrefix(path, "/heroku/"):
+		path = strings.TrimPrefix(path, "/heroku")
+		return heroku.RequestHandler(path, w, r)
+	case strings.HasPrefix(path, "/vector/"):
+		path = strings.TrimPrefix(path, "/vector")
+		return vector.RequestHandler(path, w, r)
 	default:
 		return false
 	}
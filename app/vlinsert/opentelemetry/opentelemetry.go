@@ -25,7 +25,7 @@ func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
 	// https://opentelemetry.io/docs/specs/otlp/#otlphttp-request
 	case "/v1/logs":
 		if r.Header.Get("Content-Type") == "application/json" {
-			httpserver.Errorf(w, r, "json encoding isn't supported for opentelemetry format. Use protobuf encoding")
+			handleJSON(r, w)
 			return true
 		}
 		handleProtobuf(r, w)
@@ -68,11 +68,48 @@ func handleProtobuf(r *http.Request, w http.ResponseWriter) {
 	requestProtobufDuration.UpdateDuration(startTime)
 }
 
+func handleJSON(r *http.Request, w http.ResponseWriter) {
+	startTime := time.Now()
+	requestsJSONTotal.Inc()
+
+	cp, err := insertutil.GetCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse common params from request: %s", err)
+		return
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	encoding := r.Header.Get("Content-Encoding")
+	err = protoparserutil.ReadUncompressedData(r.Body, encoding, maxRequestSize, func(data []byte) error {
+		lmp := cp.NewLogMessageProcessor("opentelemetry_json", false)
+		useDefaultStreamFields := len(cp.StreamFields) == 0
+		err := pushJSONRequest(data, lmp, useDefaultStreamFields)
+		lmp.MustClose()
+		return err
+	})
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot read OpenTelemetry protocol data: %s", err)
+		return
+	}
+
+	// update requestJSONDuration only for successfully parsed requests
+	// See the comment for requestProtobufDuration in handleProtobuf.
+	requestJSONDuration.UpdateDuration(startTime)
+}
+
 var (
 	requestsProtobufTotal = metrics.NewCounter(`vl_http_requests_total{path="/insert/opentelemetry/v1/logs",format="protobuf"}`)
 	errorsTotal           = metrics.NewCounter(`vl_http_errors_total{path="/insert/opentelemetry/v1/logs",format="protobuf"}`)
 
 	requestProtobufDuration = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/opentelemetry/v1/logs",format="protobuf"}`)
+
+	requestsJSONTotal = metrics.NewCounter(`vl_http_requests_total{path="/insert/opentelemetry/v1/logs",format="json"}`)
+	errorsJSONTotal   = metrics.NewCounter(`vl_http_errors_total{path="/insert/opentelemetry/v1/logs",format="json"}`)
+
+	requestJSONDuration = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/opentelemetry/v1/logs",format="json"}`)
 )
 
 func pushProtobufRequest(data []byte, lmp insertutil.LogMessageProcessor, useDefaultStreamFields bool) error {
@@ -81,7 +118,21 @@ func pushProtobufRequest(data []byte, lmp insertutil.LogMessageProcessor, useDef
 		errorsTotal.Inc()
 		return fmt.Errorf("cannot unmarshal request from %d bytes: %w", len(data), err)
 	}
+	pushRequest(&req, lmp, useDefaultStreamFields)
+	return nil
+}
 
+func pushJSONRequest(data []byte, lmp insertutil.LogMessageProcessor, useDefaultStreamFields bool) error {
+	var req pb.ExportLogsServiceRequest
+	if err := req.UnmarshalJSON(data); err != nil {
+		errorsJSONTotal.Inc()
+		return fmt.Errorf("cannot unmarshal JSON request from %d bytes: %w", len(data), err)
+	}
+	pushRequest(&req, lmp, useDefaultStreamFields)
+	return nil
+}
+
+func pushRequest(req *pb.ExportLogsServiceRequest, lmp insertutil.LogMessageProcessor, useDefaultStreamFields bool) {
 	var commonFields []logstorage.Field
 	for _, rl := range req.ResourceLogs {
 		attributes := rl.Resource.Attributes
@@ -90,16 +141,41 @@ func pushProtobufRequest(data []byte, lmp insertutil.LogMessageProcessor, useDef
 			commonFields[i].Name = attr.Key
 			commonFields[i].Value = attr.Value.FormatString(true)
 		}
-		commonFieldsLen := len(commonFields)
+		resourceFieldsLen := len(commonFields)
 		for _, sc := range rl.ScopeLogs {
-			commonFields = pushFieldsFromScopeLogs(&sc, commonFields[:commonFieldsLen], lmp, useDefaultStreamFields)
+			commonFields = appendScopeFields(commonFields[:resourceFieldsLen], &sc.Scope)
+			commonFields = pushFieldsFromScopeLogs(&sc, commonFields, resourceFieldsLen, lmp, useDefaultStreamFields)
 		}
 	}
+}
 
-	return nil
+// appendScopeFields appends fields for the given instrumentation scope to dst and returns the result.
+func appendScopeFields(dst []logstorage.Field, scope *pb.InstrumentationScope) []logstorage.Field {
+	if scope.Name != "" {
+		dst = append(dst, logstorage.Field{
+			Name:  "scope.name",
+			Value: scope.Name,
+		})
+	}
+	if scope.Version != "" {
+		dst = append(dst, logstorage.Field{
+			Name:  "scope.version",
+			Value: scope.Version,
+		})
+	}
+	for _, attr := range scope.Attributes {
+		dst = append(dst, logstorage.Field{
+			Name:  attr.Key,
+			Value: attr.Value.FormatString(true),
+		})
+	}
+	return dst
 }
 
-func pushFieldsFromScopeLogs(sc *pb.ScopeLogs, commonFields []logstorage.Field, lmp insertutil.LogMessageProcessor, useDefaultStreamFields bool) []logstorage.Field {
+// pushFieldsFromScopeLogs pushes log records from sc to lmp.
+//
+// The first resourceFieldsLen items in commonFields are used as stream fields if useDefaultStreamFields is set.
+func pushFieldsFromScopeLogs(sc *pb.ScopeLogs, commonFields []logstorage.Field, resourceFieldsLen int, lmp insertutil.LogMessageProcessor, useDefaultStreamFields bool) []logstorage.Field {
 	fields := commonFields
 	for _, lr := range sc.LogRecords {
 		fields = fields[:len(commonFields)]
@@ -132,7 +208,7 @@ func pushFieldsFromScopeLogs(sc *pb.ScopeLogs, commonFields []logstorage.Field,
 
 		var streamFields []logstorage.Field
 		if useDefaultStreamFields {
-			streamFields = commonFields
+			streamFields = commonFields[:resourceFieldsLen]
 		}
 		lmp.AddRow(lr.ExtractTimestampNano(), fields, streamFields)
 	}
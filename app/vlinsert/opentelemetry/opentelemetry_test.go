@@ -126,6 +126,142 @@ func TestPushProtoOk(t *testing.T) {
 	)
 }
 
+func TestPushProtoScopeFields(t *testing.T) {
+	lr := pb.ExportLogsServiceRequest{
+		ResourceLogs: []pb.ResourceLogs{
+			{
+				Resource: pb.Resource{
+					Attributes: []*pb.KeyValue{
+						{Key: "service.name", Value: &pb.AnyValue{StringValue: ptrTo("foo")}},
+					},
+				},
+				ScopeLogs: []pb.ScopeLogs{
+					{
+						Scope: pb.InstrumentationScope{
+							Name:    "my.lib",
+							Version: "1.2.3",
+							Attributes: []*pb.KeyValue{
+								{Key: "scope_attr", Value: &pb.AnyValue{BoolValue: ptrTo(true)}},
+							},
+						},
+						LogRecords: []pb.LogRecord{
+							{TimeUnixNano: 1234, SeverityText: "WARN", Body: pb.AnyValue{StringValue: ptrTo("first")}},
+						},
+					},
+					{
+						LogRecords: []pb.LogRecord{
+							{TimeUnixNano: 1235, SeverityNumber: 9, Body: pb.AnyValue{StringValue: ptrTo("second")}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	pData := lr.MarshalProtobuf(nil)
+	tlp := &insertutil.TestLogMessageProcessor{}
+	if err := pushProtobufRequest(pData, tlp, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := `{"service.name":"foo","scope.name":"my.lib","scope.version":"1.2.3","scope_attr":"true","_msg":"first","severity":"WARN"}
+{"service.name":"foo","_msg":"second","severity":"Info"}`
+	if err := tlp.Verify([]int64{1234, 1235}, resultExpected); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushJSONOk(t *testing.T) {
+	f := func(data string, timestampsExpected []int64, resultExpected string) {
+		t.Helper()
+
+		tlp := &insertutil.TestLogMessageProcessor{}
+		if err := pushJSONRequest([]byte(data), tlp, false); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := tlp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// empty request
+	f(`{}`, nil, ``)
+
+	// single line without resource attributes
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"1234","severityNumber":1,"body":{"stringValue":"log-line-message"}}]}]}]}`,
+		[]int64{1234},
+		`{"_msg":"log-line-message","severity":"Trace"}`,
+	)
+
+	// resource and scope attributes
+	f(`{
+  "resourceLogs": [
+    {
+      "resource": {
+        "attributes": [
+          {"key": "service.name", "value": {"stringValue": "my.service"}},
+          {"key": "instance_id", "value": {"intValue": "10"}}
+        ]
+      },
+      "scopeLogs": [
+        {
+          "scope": {
+            "name": "my.library",
+            "version": "1.0.0",
+            "attributes": [{"key": "my.scope.attribute", "value": {"stringValue": "some scope attribute"}}]
+          },
+          "logRecords": [
+            {
+              "timeUnixNano": "1544712660300000000",
+              "observedTimeUnixNano": "1544712660300000001",
+              "severityNumber": 10,
+              "severityText": "Information",
+              "traceId": "5B8EFFF798038103D269B633813FC60C",
+              "spanId": "EEE19B7EC3C1B174",
+              "body": {"stringValue": "Example log record"},
+              "attributes": [
+                {"key": "string.attribute", "value": {"stringValue": "some string"}},
+                {"key": "boolean.attribute", "value": {"boolValue": true}},
+                {"key": "int.attribute", "value": {"intValue": 10}},
+                {"key": "double.attribute", "value": {"doubleValue": 637.704}},
+                {"key": "array.attribute", "value": {"arrayValue": {"values": [{"stringValue": "many"}, {"stringValue": "values"}]}}},
+                {"key": "map.attribute", "value": {"kvlistValue": {"values": [{"key": "some.map.key", "value": {"stringValue": "some value"}}]}}},
+                {"key": "bytes.attribute", "value": {"bytesValue": "Zm9v"}}
+              ]
+            },
+            {
+              "observedTimeUnixNano": 1544712660300000002,
+              "body": {"stringValue": "second"}
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`,
+		[]int64{1544712660300000000, 1544712660300000002},
+		`{"service.name":"my.service","instance_id":"10","scope.name":"my.library","scope.version":"1.0.0","my.scope.attribute":"some scope attribute","_msg":"Example log record","string.attribute":"some string","boolean.attribute":"true","int.attribute":"10","double.attribute":"637.704","array.attribute":"[\"many\",\"values\"]","map.attribute":"{\"some.map.key\":\"some value\"}","bytes.attribute":"Zm9v","trace_id":"5b8efff798038103d269b633813fc60c","span_id":"eee19b7ec3c1b174","severity":"Information"}
+{"service.name":"my.service","instance_id":"10","scope.name":"my.library","scope.version":"1.0.0","my.scope.attribute":"some scope attribute","_msg":"second","severity":"Unspecified"}`,
+	)
+}
+
+func TestPushJSONFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		tlp := &insertutil.TestLogMessageProcessor{}
+		if err := pushJSONRequest([]byte(data), tlp, false); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(``)
+	f(`[]`)
+	f(`{"resourceLogs":{}}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"foo"}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"intValue":"bar"}}]}]}]}`)
+	f(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"bytesValue":"!!!"}}]}]}]}`)
+}
+
 func ptrTo[T any](s T) *T {
 	return &s
 }
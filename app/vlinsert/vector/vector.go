@@ -0,0 +1,180 @@
+package vector
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
+)
+
+var (
+	maxRequestSize = flagutil.NewBytes("vector.maxRequestSize", 64*1024*1024, "The maximum size in bytes of a single request sent by Vector http sink")
+)
+
+var (
+	parserPool fastjson.ParserPool
+	bbPool     bytesutil.ByteBufferPool
+)
+
+// RequestHandler processes requests sent by Vector http sink.
+//
+// See https://vector.dev/docs/reference/configuration/sinks/http/
+func RequestHandler(path string, w http.ResponseWriter, r *http.Request) bool {
+	switch path {
+	case "/logs":
+		handleLogs(w, r)
+		return true
+	default:
+		return false
+	}
+}
+
+func handleLogs(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	logsRequestsTotal.Inc()
+
+	cp, err := insertutil.GetCommonParams(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	// Vector stores log timestamp and log message in the timestamp and message fields by default.
+	// See https://vector.dev/docs/reference/configuration/global-options/#log_schema
+	if httputil.GetRequestValue(r, "_time_field", "VL-Time-Field") == "" {
+		cp.TimeField = "timestamp"
+	}
+	if len(cp.MsgFields) == 0 {
+		cp.MsgFields = []string{"message"}
+	}
+	if err := vlstorage.CanWriteData(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	encoding := r.Header.Get("Content-Encoding")
+	err = protoparserutil.ReadUncompressedData(r.Body, encoding, maxRequestSize, func(data []byte) error {
+		lmp := cp.NewLogMessageProcessor("vector", false)
+		err := readLogsRequest(data, cp.TimeField, cp.MsgFields, lmp)
+		lmp.MustClose()
+		return err
+	})
+	if err != nil {
+		logsErrorsTotal.Inc()
+		httpserver.Errorf(w, r, "cannot read Vector logs: %s", err)
+		return
+	}
+
+	// update logsRequestDuration only for successfully parsed requests
+	// There is no need in updating logsRequestDuration for request errors,
+	// since their timings are usually much smaller than the timing for successful request parsing.
+	logsRequestDuration.UpdateDuration(startTime)
+}
+
+var (
+	logsRequestsTotal   = metrics.NewCounter(`vl_http_requests_total{path="/insert/vector/logs"}`)
+	logsErrorsTotal     = metrics.NewCounter(`vl_http_errors_total{path="/insert/vector/logs"}`)
+	logsRequestDuration = metrics.NewHistogram(`vl_http_request_duration_seconds{path="/insert/vector/logs"}`)
+)
+
+// readLogsRequest reads logs sent by Vector http sink from data.
+//
+// data may contain either JSON array of events (the default framing for json and native_json codecs)
+// or newline-delimited JSON events. Every event may be either a plain log object (json codec)
+// or an object wrapped into {"log":{...}} (native_json codec).
+func readLogsRequest(data []byte, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) error {
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		v, err := p.ParseBytes(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse JSON array of events: %w", err)
+		}
+		events, err := v.Array()
+		if err != nil {
+			return fmt.Errorf("cannot extract array of events: %w", err)
+		}
+		for i, event := range events {
+			if err := readEvent(event, timeField, msgFields, lmp); err != nil {
+				return fmt.Errorf("cannot read event #%d: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	n := 0
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i]
+			data = data[i+1:]
+		} else {
+			line = data
+			data = nil
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		v, err := p.ParseBytes(line)
+		if err != nil {
+			return fmt.Errorf("cannot parse JSON event #%d: %w", n, err)
+		}
+		if err := readEvent(v, timeField, msgFields, lmp); err != nil {
+			return fmt.Errorf("cannot read event #%d: %w", n, err)
+		}
+		n++
+	}
+	return nil
+}
+
+func readEvent(v *fastjson.Value, timeField string, msgFields []string, lmp insertutil.LogMessageProcessor) error {
+	o, err := v.Object()
+	if err != nil {
+		return fmt.Errorf("event must be JSON object; got %s", v.Type())
+	}
+	if o.Len() == 1 {
+		// native_json codec wraps events into {"log":{...}}, {"metric":{...}} or {"trace":{...}}
+		if logEvent := o.Get("log"); logEvent != nil && logEvent.Type() == fastjson.TypeObject {
+			v = logEvent
+		} else if o.Get("metric") != nil || o.Get("trace") != nil {
+			return fmt.Errorf("only log events are supported")
+		}
+	}
+
+	bb := bbPool.Get()
+	bb.B = v.MarshalTo(bb.B[:0])
+	defer bbPool.Put(bb)
+
+	jp := logstorage.GetJSONParser()
+	defer logstorage.PutJSONParser(jp)
+
+	if err := jp.ParseLogMessage(bb.B); err != nil {
+		return err
+	}
+	ts, err := insertutil.ExtractTimestampFromFields(timeField, jp.Fields)
+	if err != nil {
+		return err
+	}
+	logstorage.RenameField(jp.Fields, msgFields, "_msg")
+	lmp.AddRow(ts, jp.Fields, nil)
+	return nil
+}
@@ -0,0 +1,68 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutil"
+)
+
+func TestReadLogsRequestFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		lmp := &insertutil.TestLogMessageProcessor{}
+		if err := readLogsRequest([]byte(data), "timestamp", []string{"message"}, lmp); err == nil {
+			t.Fatalf("expecting non-empty error")
+		}
+	}
+
+	// invalid json
+	f("foobar")
+	f(`[{"message":"foo"`)
+
+	// non-object event
+	f(`["foo"]`)
+	f(`123`)
+
+	// non-log native event
+	f(`{"metric":{"name":"foo","counter":{"value":1}}}`)
+
+	// invalid timestamp
+	f(`{"message":"foo","timestamp":"bar"}`)
+}
+
+func TestReadLogsRequestSuccess(t *testing.T) {
+	f := func(data string, timestampsExpected []int64, resultExpected string) {
+		t.Helper()
+
+		lmp := &insertutil.TestLogMessageProcessor{}
+		if err := readLogsRequest([]byte(data), "timestamp", []string{"message"}, lmp); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := lmp.Verify(timestampsExpected, resultExpected); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// empty data
+	f("", nil, "")
+	f("[]", nil, "")
+
+	// json codec with json array framing
+	f(`[{"message":"foo","timestamp":"2024-01-02T03:04:05.123Z","host":"h1","kubernetes":{"pod_name":"p1"}},{"message":"bar","timestamp":"2024-01-02T03:04:06Z","host":"h2"}]`,
+		[]int64{1704164645123000000, 1704164646000000000},
+		`{"_msg":"foo","host":"h1","kubernetes.pod_name":"p1"}
+{"_msg":"bar","host":"h2"}`)
+
+	// native_json codec with newline-delimited framing
+	f(`{"log":{"message":"foo","timestamp":"2024-01-02T03:04:05.123Z","source_type":"file"}}
+
+{"log":{"message":"bar","timestamp":"2024-01-02T03:04:06Z"}}
+`, []int64{1704164645123000000, 1704164646000000000},
+		`{"_msg":"foo","source_type":"file"}
+{"_msg":"bar"}`)
+
+	// native_json codec with json array framing
+	f(`[{"log":{"message":"foo","timestamp":"2024-01-02T03:04:05Z"}}]`, []int64{1704164645000000000}, `{"_msg":"foo"}`)
+
+}
@@ -0,0 +1,163 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+// indexName is the name of the index reported to Elasticsearch clients.
+//
+// VictoriaLogs stores all the logs for the given tenant together, so all the logs for the tenant are reported as a single index.
+// See https://docs.victoriametrics.com/victorialogs/querying/#elasticsearch-read-apis
+const indexName = "victorialogs"
+
+// RequestHandler processes Elasticsearch read requests at /select/elasticsearch/*
+//
+// path must contain the request path without /select/elasticsearch prefix.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#elasticsearch-read-apis
+func RequestHandler(ctx context.Context, path string, w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Content-Type", "application/json")
+	// This header is needed for Elasticsearch clients
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+
+	startTime := time.Now()
+	switch {
+	case path == "/_count" || strings.HasSuffix(path, "/_count") && isIndexPath(strings.TrimSuffix(path, "/_count")):
+		countRequestsTotal.Inc()
+		processCountRequest(ctx, w, r)
+		countRequestDuration.UpdateDuration(startTime)
+		return true
+	case path == "/_cat/indices" || strings.HasPrefix(path, "/_cat/indices/"):
+		catIndicesRequestsTotal.Inc()
+		processCatIndicesRequest(ctx, w, r)
+		catIndicesRequestDuration.UpdateDuration(startTime)
+		return true
+	default:
+		return false
+	}
+}
+
+// processCountRequest processes Elasticsearch count request.
+//
+// See https://www.elastic.co/guide/en/elasticsearch/reference/current/search-count.html
+func processCountRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	n, err := getLogsCount(ctx, tenantID)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain the number of logs: %s", err)
+		return
+	}
+	fmt.Fprintf(w, `{"count":%d,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0}}`, n)
+}
+
+// processCatIndicesRequest processes Elasticsearch cat indices request.
+//
+// See https://www.elastic.co/guide/en/elasticsearch/reference/current/cat-indices.html
+func processCatIndicesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	n, err := getLogsCount(ctx, tenantID)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain the number of logs: %s", err)
+		return
+	}
+	if r.FormValue("format") == "json" {
+		fmt.Fprintf(w, `[{"health":"green","status":"open","index":%q,"uuid":%q,"pri":"1","rep":"0","docs.count":"%d","docs.deleted":"0"}]`,
+			indexName, tenantID.String(), n)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	if isCatVerbose(r) {
+		fmt.Fprintf(w, "health status index uuid pri rep docs.count docs.deleted\n")
+	}
+	fmt.Fprintf(w, "green open %s %s 1 0 %d 0\n", indexName, tenantID.String(), n)
+}
+
+// isCatVerbose returns true if the `v` query arg is set for the cat request.
+//
+// Elasticsearch treats `?v` without value as `?v=true`.
+func isCatVerbose(r *http.Request) bool {
+	args := r.URL.Query()
+	if !args.Has("v") {
+		return false
+	}
+	v := args.Get("v")
+	if v == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+// isIndexPath returns true if path refers to Elasticsearch index, e.g. /index-name
+func isIndexPath(path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	return path != "" && !strings.HasPrefix(path, "_") && !strings.Contains(path, "/")
+}
+
+// getLogsCount returns the number of logs stored for the given tenantID.
+func getLogsCount(ctx context.Context, tenantID logstorage.TenantID) (uint64, error) {
+	tenantIDs := []logstorage.TenantID{tenantID}
+	if n, ok := vlstorage.GetRowsCount(tenantIDs); ok {
+		return n, nil
+	}
+
+	// Fall back to the count query when the logs are stored at remote -storageNode nodes.
+	q, err := logstorage.ParseQuery("* | count() as count")
+	if err != nil {
+		return 0, fmt.Errorf("BUG: cannot parse count query: %w", err)
+	}
+
+	var n atomic.Uint64
+	var parseErr atomic.Pointer[error]
+	writeBlock := func(_ uint, db *logstorage.DataBlock) {
+		for _, c := range db.Columns {
+			if c.Name != "count" {
+				continue
+			}
+			for _, v := range c.Values {
+				x, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					err = fmt.Errorf("cannot parse count %q: %w", v, err)
+					parseErr.Store(&err)
+					return
+				}
+				n.Add(x)
+			}
+		}
+	}
+	if err := vlstorage.RunQuery(ctx, nil, tenantIDs, q, writeBlock); err != nil {
+		return 0, err
+	}
+	if errp := parseErr.Load(); errp != nil {
+		return 0, *errp
+	}
+	return n.Load(), nil
+}
+
+var (
+	countRequestsTotal   = metrics.NewCounter(`vl_http_requests_total{path="/select/elasticsearch/_count"}`)
+	countRequestDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/elasticsearch/_count"}`)
+
+	catIndicesRequestsTotal   = metrics.NewCounter(`vl_http_requests_total{path="/select/elasticsearch/_cat/indices"}`)
+	catIndicesRequestDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/elasticsearch/_cat/indices"}`)
+)
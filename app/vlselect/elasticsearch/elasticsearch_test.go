@@ -0,0 +1,47 @@
+package elasticsearch
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestIsIndexPath(t *testing.T) {
+	f := func(path string, resultExpected bool) {
+		t.Helper()
+
+		result := isIndexPath(path)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for isIndexPath(%q); got %v; want %v", path, result, resultExpected)
+		}
+	}
+
+	f("/filebeat-8.8.0", true)
+	f("logs", true)
+	f("", false)
+	f("/", false)
+	f("/_count", false)
+	f("/_cat/indices", false)
+	f("/logs/_count", false)
+}
+
+func TestIsCatVerbose(t *testing.T) {
+	f := func(requestURI string, resultExpected bool) {
+		t.Helper()
+
+		r, err := http.NewRequest(http.MethodGet, requestURI, nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		result := isCatVerbose(r)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for isCatVerbose(%q); got %v; want %v", requestURI, result, resultExpected)
+		}
+	}
+
+	f("/_cat/indices", false)
+	f("/_cat/indices?v", true)
+	f("/_cat/indices?v=true", true)
+	f("/_cat/indices?v=1", true)
+	f("/_cat/indices?v=false", false)
+	f("/_cat/indices?format=json", false)
+}
@@ -18,8 +18,10 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 var disableSelect = flag.Bool("internalselect.disable", false, "Whether to disable /internal/select/* HTTP endpoints")
@@ -125,7 +127,7 @@ func processQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	if err := vlstorage.RunQuery(ctx, cp.TenantIDs, cp.Query, writeBlock); err != nil {
+	if err := vlstorage.RunQuery(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query, writeBlock); err != nil {
 		return err
 	}
 	if errGlobal != nil {
@@ -139,7 +141,17 @@ func processQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	return nil
+	if !cp.QueryTracer.Enabled() {
+		return nil
+	}
+
+	// Send the query trace after the zero-length block, which cannot be sent by sendBuf.
+	cp.QueryTracer.Done()
+	var bb bytesutil.ByteBuffer
+	bb.B = encoding.MarshalUint64(bb.B, 0)
+	bb.B = marshalQueryTrace(bb.B, cp.QueryTracer)
+	_, err = w.Write(bb.B)
+	return err
 }
 
 func processFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -148,12 +160,12 @@ func processFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *htt
 		return err
 	}
 
-	fieldNames, err := vlstorage.GetFieldNames(ctx, cp.TenantIDs, cp.Query)
+	fieldNames, err := vlstorage.GetFieldNames(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query)
 	if err != nil {
 		return fmt.Errorf("cannot obtain field names: %w", err)
 	}
 
-	return writeValuesWithHits(w, fieldNames, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, fieldNames)
 }
 
 func processFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -169,12 +181,12 @@ func processFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *ht
 		return err
 	}
 
-	fieldValues, err := vlstorage.GetFieldValues(ctx, cp.TenantIDs, cp.Query, fieldName, uint64(limit))
+	fieldValues, err := vlstorage.GetFieldValues(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query, fieldName, uint64(limit))
 	if err != nil {
 		return fmt.Errorf("cannot obtain field values: %w", err)
 	}
 
-	return writeValuesWithHits(w, fieldValues, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, fieldValues)
 }
 
 func processStreamFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -183,12 +195,12 @@ func processStreamFieldNamesRequest(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
-	fieldNames, err := vlstorage.GetStreamFieldNames(ctx, cp.TenantIDs, cp.Query)
+	fieldNames, err := vlstorage.GetStreamFieldNames(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query)
 	if err != nil {
 		return fmt.Errorf("cannot obtain stream field names: %w", err)
 	}
 
-	return writeValuesWithHits(w, fieldNames, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, fieldNames)
 }
 
 func processStreamFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -204,12 +216,12 @@ func processStreamFieldValuesRequest(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
-	fieldValues, err := vlstorage.GetStreamFieldValues(ctx, cp.TenantIDs, cp.Query, fieldName, uint64(limit))
+	fieldValues, err := vlstorage.GetStreamFieldValues(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query, fieldName, uint64(limit))
 	if err != nil {
 		return fmt.Errorf("cannot obtain stream field values: %w", err)
 	}
 
-	return writeValuesWithHits(w, fieldValues, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, fieldValues)
 }
 
 func processStreamsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -223,12 +235,12 @@ func processStreamsRequest(ctx context.Context, w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	streams, err := vlstorage.GetStreams(ctx, cp.TenantIDs, cp.Query, uint64(limit))
+	streams, err := vlstorage.GetStreams(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query, uint64(limit))
 	if err != nil {
 		return fmt.Errorf("cannot obtain streams: %w", err)
 	}
 
-	return writeValuesWithHits(w, streams, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, streams)
 }
 
 func processStreamIDsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -242,12 +254,12 @@ func processStreamIDsRequest(ctx context.Context, w http.ResponseWriter, r *http
 		return err
 	}
 
-	streamIDs, err := vlstorage.GetStreamIDs(ctx, cp.TenantIDs, cp.Query, uint64(limit))
+	streamIDs, err := vlstorage.GetStreamIDs(ctx, cp.QueryTracer, cp.TenantIDs, cp.Query, uint64(limit))
 	if err != nil {
 		return fmt.Errorf("cannot obtain streams: %w", err)
 	}
 
-	return writeValuesWithHits(w, streamIDs, cp.DisableCompression)
+	return writeValuesWithHits(w, cp, streamIDs)
 }
 
 type commonParams struct {
@@ -255,6 +267,9 @@ type commonParams struct {
 	Query     *logstorage.Query
 
 	DisableCompression bool
+
+	// QueryTracer is enabled when the request contains trace=1 arg.
+	QueryTracer *querytracer.Tracer
 }
 
 func getCommonParams(r *http.Request, expectedProtocolVersion string) (*commonParams, error) {
@@ -286,23 +301,33 @@ func getCommonParams(r *http.Request, expectedProtocolVersion string) (*commonPa
 		return nil, fmt.Errorf("cannot parse disable_compression=%q: %w", s, err)
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "%s: query=%s", r.URL.Path, q)
+
 	cp := &commonParams{
 		TenantIDs: tenantIDs,
 		Query:     q,
 
 		DisableCompression: disableCompression,
+
+		QueryTracer: qt,
 	}
 	return cp, nil
 }
 
-func writeValuesWithHits(w http.ResponseWriter, vhs []logstorage.ValueWithHits, disableCompression bool) error {
-	var b []byte
+func writeValuesWithHits(w http.ResponseWriter, cp *commonParams, vhs []logstorage.ValueWithHits) error {
+	var data []byte
 	for i := range vhs {
-		b = vhs[i].Marshal(b)
+		data = vhs[i].Marshal(data)
 	}
 
-	if !disableCompression {
-		b = zstd.CompressLevel(nil, b, 1)
+	// The response starts with uncompressed query trace, which is empty if the tracing is disabled.
+	cp.QueryTracer.Done()
+	b := marshalQueryTrace(nil, cp.QueryTracer)
+
+	if cp.DisableCompression {
+		b = append(b, data...)
+	} else {
+		b = zstd.CompressLevel(b, data, 1)
 	}
 
 	w.Header().Set("Content-Type", "application/octet-stream")
@@ -314,6 +339,13 @@ func writeValuesWithHits(w http.ResponseWriter, vhs []logstorage.ValueWithHits,
 	return nil
 }
 
+// marshalQueryTrace appends the length-prefixed JSON representation of qt to dst and returns the result.
+func marshalQueryTrace(dst []byte, qt *querytracer.Tracer) []byte {
+	trace := qt.ToJSON()
+	dst = encoding.MarshalUint64(dst, uint64(len(trace)))
+	return append(dst, trace...)
+}
+
 func getInt64FromRequest(r *http.Request, argName string) (int64, error) {
 	s := r.FormValue(argName)
 	n, err := strconv.ParseInt(s, 10, 64)
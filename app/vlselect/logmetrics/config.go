@@ -0,0 +1,125 @@
+package logmetrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+)
+
+// Config represents the config file for -logMetrics.config
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule represents a rule for converting the results of LogsQL stats query to metrics.
+type Rule struct {
+	// Name is the rule name. It is used for identifying the rule in logs and metrics.
+	Name string `yaml:"name"`
+
+	// Query is LogsQL query, which must end with `| stats ...` pipe.
+	Query string `yaml:"query"`
+
+	// Tenant is an optional tenant to query logs from in the form accountID:projectID.
+	Tenant string `yaml:"tenant,omitempty"`
+
+	// Interval is an optional interval for evaluating the rule. By default -logMetrics.evaluationInterval is used.
+	Interval *promutil.Duration `yaml:"interval,omitempty"`
+
+	// Labels are optional labels to add to the generated metrics.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	q        *logstorage.Query
+	tenantID logstorage.TenantID
+	interval time.Duration
+	labels   []prompbmarshal.Label
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := fscore.ReadFileOrHTTP(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(cfg.Rules))
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("cannot initialize rule #%d: %w", i+1, err)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+	}
+	return &cfg, nil
+}
+
+func (r *Rule) init() error {
+	if r.Name == "" {
+		return fmt.Errorf("missing `name` option")
+	}
+	if r.Query == "" {
+		return fmt.Errorf("missing `query` option at rule %q", r.Name)
+	}
+	q, err := logstorage.ParseQuery(r.Query)
+	if err != nil {
+		return fmt.Errorf("cannot parse query [%s] at rule %q: %w", r.Query, r.Name, err)
+	}
+	if _, err := q.GetStatsByFields(); err != nil {
+		return fmt.Errorf("unsupported query [%s] at rule %q: %w", r.Query, r.Name, err)
+	}
+	r.q = q
+
+	tenantID, err := logstorage.ParseTenantID(r.Tenant)
+	if err != nil {
+		return fmt.Errorf("cannot parse `tenant` at rule %q: %w", r.Name, err)
+	}
+	r.tenantID = tenantID
+
+	r.interval = *evaluationInterval
+	if r.Interval != nil {
+		r.interval = r.Interval.Duration()
+	}
+	if r.interval <= 0 {
+		return fmt.Errorf("`interval` must be positive at rule %q; got %s", r.Name, r.interval)
+	}
+
+	r.labels = r.labels[:0]
+	for name, value := range r.Labels {
+		if name == "" || name == "__name__" {
+			return fmt.Errorf("unsupported label name %q at rule %q", name, r.Name)
+		}
+		r.labels = append(r.labels, prompbmarshal.Label{
+			Name:  name,
+			Value: value,
+		})
+	}
+	sort.Slice(r.labels, func(i, j int) bool {
+		return r.labels[i].Name < r.labels[j].Name
+	})
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package logmetrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestParseConfigFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		if _, err := parseConfig([]byte(s)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown option
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+  foo: bar
+`)
+
+	// missing name
+	f(`
+rules:
+- query: '* | stats count() rows'
+`)
+
+	// missing query
+	f(`
+rules:
+- name: foo
+`)
+
+	// invalid query
+	f(`
+rules:
+- name: foo
+  query: 'foo | bar'
+`)
+
+	// missing stats pipe
+	f(`
+rules:
+- name: foo
+  query: 'error'
+`)
+
+	// invalid tenant
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+  tenant: 'a:b'
+`)
+
+	// invalid interval
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+  interval: foo
+`)
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+  interval: 0s
+`)
+
+	// invalid label name
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+  labels:
+    __name__: bar
+`)
+
+	// duplicate rule names
+	f(`
+rules:
+- name: foo
+  query: '* | stats count() rows'
+- name: foo
+  query: '* | stats count() errors'
+`)
+}
+
+func TestParseConfigSuccess(t *testing.T) {
+	data := `
+rules:
+- name: errors
+  query: '_time:5m error | stats by (service) count() errors'
+  tenant: '12:34'
+  interval: 5m
+  labels:
+    source: logs
+    env: prod
+- name: rows
+  query: '_time:1m | stats count() rows'
+`
+	cfg, err := parseConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cfg.Rules) != 2 {
+		t.Fatalf("unexpected number of rules; got %d; want 2", len(cfg.Rules))
+	}
+
+	r := &cfg.Rules[0]
+	if r.Name != "errors" {
+		t.Fatalf("unexpected rule name; got %q; want %q", r.Name, "errors")
+	}
+	tenantIDExpected := logstorage.TenantID{
+		AccountID: 12,
+		ProjectID: 34,
+	}
+	if r.tenantID != tenantIDExpected {
+		t.Fatalf("unexpected tenantID; got %v; want %v", r.tenantID, tenantIDExpected)
+	}
+	if r.interval != 5*time.Minute {
+		t.Fatalf("unexpected interval; got %s; want %s", r.interval, 5*time.Minute)
+	}
+	labelsExpected := []prompbmarshal.Label{
+		{
+			Name:  "env",
+			Value: "prod",
+		},
+		{
+			Name:  "source",
+			Value: "logs",
+		},
+	}
+	if !reflect.DeepEqual(r.labels, labelsExpected) {
+		t.Fatalf("unexpected labels; got %v; want %v", r.labels, labelsExpected)
+	}
+
+	r = &cfg.Rules[1]
+	if r.tenantID != (logstorage.TenantID{}) {
+		t.Fatalf("unexpected tenantID; got %v; want zero tenantID", r.tenantID)
+	}
+	if r.interval != *evaluationInterval {
+		t.Fatalf("unexpected interval; got %s; want %s", r.interval, *evaluationInterval)
+	}
+	if len(r.labels) != 0 {
+		t.Fatalf("unexpected labels; got %v; want empty labels", r.labels)
+	}
+}
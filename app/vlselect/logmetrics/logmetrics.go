@@ -0,0 +1,246 @@
+package logmetrics
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+var (
+	configPath = flag.String("logMetrics.config", "", "Optional path to a file with rules for periodic conversion of LogsQL stats query results to metrics, "+
+		"which are sent to -logMetrics.remoteWrite.url. See https://docs.victoriametrics.com/victorialogs/querying/#log-to-metric-conversion")
+	evaluationInterval = flag.Duration("logMetrics.evaluationInterval", time.Minute, "The default interval for evaluating rules from -logMetrics.config. "+
+		"It can be overridden via interval option at the rule")
+	remoteWriteURL = flag.String("logMetrics.remoteWrite.url", "", "Prometheus remote write compatible url for sending metrics generated by rules from -logMetrics.config. "+
+		"For example, http://victoria-metrics:8428/api/v1/write")
+	remoteWriteSendTimeout = flag.Duration("logMetrics.remoteWrite.sendTimeout", 30*time.Second, "Timeout for sending metrics to -logMetrics.remoteWrite.url")
+)
+
+var (
+	stopCtx    context.Context
+	stopCancel func()
+	rulesWG    sync.WaitGroup
+
+	httpClient *http.Client
+)
+
+// Init starts evaluating rules from -logMetrics.config if it is set.
+func Init() {
+	if *configPath == "" {
+		return
+	}
+	if *remoteWriteURL == "" {
+		logger.Fatalf("missing -logMetrics.remoteWrite.url command-line flag; it must be set when -logMetrics.config is set")
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("cannot load -logMetrics.config: %s", err)
+	}
+
+	httpClient = &http.Client{
+		Timeout: *remoteWriteSendTimeout,
+	}
+	stopCtx, stopCancel = context.WithCancel(context.Background())
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		rulesWG.Add(1)
+		go func() {
+			defer rulesWG.Done()
+			r.run(stopCtx)
+		}()
+	}
+	logger.Infof("started %d rules from -logMetrics.config=%q", len(cfg.Rules), *configPath)
+}
+
+// Stop stops evaluating rules from -logMetrics.config.
+func Stop() {
+	if stopCancel == nil {
+		return
+	}
+	stopCancel()
+	rulesWG.Wait()
+}
+
+// run evaluates r every r.interval until ctx is canceled.
+//
+// Evaluations are aligned to r.interval, so the generated samples have consistent timestamps.
+func (r *Rule) run(ctx context.Context) {
+	evaluationsTotal := metrics.GetOrCreateCounter(fmt.Sprintf(`vl_log_metrics_evaluations_total{rule=%q}`, r.Name))
+	evaluationErrorsTotal := metrics.GetOrCreateCounter(fmt.Sprintf(`vl_log_metrics_evaluation_errors_total{rule=%q}`, r.Name))
+	evaluationsMissedTotal := metrics.GetOrCreateCounter(fmt.Sprintf(`vl_log_metrics_evaluations_missed_total{rule=%q}`, r.Name))
+	samplesTotal := metrics.GetOrCreateCounter(fmt.Sprintf(`vl_log_metrics_samples_total{rule=%q}`, r.Name))
+
+	next := time.Now().Truncate(r.interval).Add(r.interval)
+	t := time.NewTimer(time.Until(next))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		evaluationsTotal.Inc()
+		n, err := r.evalAndSend(ctx, next)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			evaluationErrorsTotal.Inc()
+			logger.Errorf("cannot evaluate rule %q from -logMetrics.config: %s", r.Name, err)
+		}
+		samplesTotal.Add(n)
+
+		next = next.Add(r.interval)
+		if now := time.Now(); next.Before(now) {
+			// The evaluation took more than r.interval. Skip the missed evaluations.
+			missed := now.Sub(next)/r.interval + 1
+			evaluationsMissedTotal.Add(int(missed))
+			next = next.Add(missed * r.interval)
+		}
+		t.Reset(time.Until(next))
+	}
+}
+
+// evalAndSend evaluates r at the given t and sends the results to -logMetrics.remoteWrite.url.
+//
+// It returns the number of sent samples.
+func (r *Rule) evalAndSend(ctx context.Context, t time.Time) (int, error) {
+	// The evaluation must fit the interval between evaluations.
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	tss, err := r.eval(ctx, t.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	if len(tss) == 0 {
+		return 0, nil
+	}
+	if err := sendTimeSeries(ctx, tss); err != nil {
+		remoteWriteErrors.Inc()
+		return 0, err
+	}
+	return len(tss), nil
+}
+
+var remoteWriteErrors = metrics.NewCounter(`vl_log_metrics_remote_write_errors_total`)
+
+// eval evaluates r at the given timestamp in nanoseconds and returns the results as time series.
+//
+// Every non-`by (...)` field from the `stats` pipe results is converted to a time series with the field name as a metric name
+// and `by (...)` fields plus r.Labels as labels. Non-numeric results are skipped.
+func (r *Rule) eval(ctx context.Context, timestamp int64) ([]prompbmarshal.TimeSeries, error) {
+	// Decrease timestamp by one nanosecond in order to avoid capturing logs belonging
+	// to the first nanosecond at the next period of time, like /select/logsql/stats_query does.
+	q := r.q.Clone(timestamp - 1)
+	byFields, err := q.GetStatsByFields()
+	if err != nil {
+		return nil, err
+	}
+
+	timestampMsecs := timestamp / 1e6
+
+	var tss []prompbmarshal.TimeSeries
+	var tssLock sync.Mutex
+
+	writeBlock := func(_ uint, db *logstorage.DataBlock) {
+		rowsCount := db.RowsCount()
+		columns := db.Columns
+		for i := 0; i < rowsCount; i++ {
+			labels := make([]prompbmarshal.Label, 0, len(byFields)+len(r.labels))
+			for _, c := range columns {
+				if slices.Contains(byFields, c.Name) && !r.hasLabel(c.Name) {
+					labels = append(labels, prompbmarshal.Label{
+						Name:  strings.Clone(c.Name),
+						Value: strings.Clone(c.Values[i]),
+					})
+				}
+			}
+			labels = append(labels, r.labels...)
+
+			for _, c := range columns {
+				if slices.Contains(byFields, c.Name) {
+					continue
+				}
+				v, err := strconv.ParseFloat(c.Values[i], 64)
+				if err != nil {
+					continue
+				}
+				ts := prompbmarshal.TimeSeries{
+					Labels: append([]prompbmarshal.Label{{
+						Name:  "__name__",
+						Value: strings.Clone(c.Name),
+					}}, labels...),
+					Samples: []prompbmarshal.Sample{{
+						Value:     v,
+						Timestamp: timestampMsecs,
+					}},
+				}
+
+				tssLock.Lock()
+				tss = append(tss, ts)
+				tssLock.Unlock()
+			}
+		}
+	}
+
+	tenantIDs := []logstorage.TenantID{r.tenantID}
+	if err := vlstorage.RunQuery(ctx, nil, tenantIDs, q, writeBlock); err != nil {
+		return nil, fmt.Errorf("cannot execute query [%s]: %w", q, err)
+	}
+	return tss, nil
+}
+
+// hasLabel returns true if r.Labels contain the label with the given name.
+//
+// Labels from r.Labels override labels with the same names from query results.
+func (r *Rule) hasLabel(name string) bool {
+	_, ok := r.Labels[name]
+	return ok
+}
+
+// sendTimeSeries sends tss to -logMetrics.remoteWrite.url via Prometheus remote write protocol.
+func sendTimeSeries(ctx context.Context, tss []prompbmarshal.TimeSeries) error {
+	wr := &prompbmarshal.WriteRequest{
+		Timeseries: tss,
+	}
+	data := snappy.Encode(nil, wr.MarshalProtobuf(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *remoteWriteURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot create request to -logMetrics.remoteWrite.url: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "victoria-logs")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send %d series to -logMetrics.remoteWrite.url: %w", len(tss), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code %d when sending %d series to -logMetrics.remoteWrite.url; response body: %q", resp.StatusCode, len(tss), body)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
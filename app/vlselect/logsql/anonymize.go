@@ -0,0 +1,121 @@
+package logsql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+var (
+	anonymizeHashFields = flagutil.NewArrayString("search.anonymizeHashFields", "Fields to replace with salted hashes of their values when /select/logsql/query is called with anonymize=1 query arg. "+
+		"Equal values are replaced with equal hashes, so anonymized logs can still be grouped and joined by these fields. "+
+		"Field names ending with '*' match all the fields with the given prefix. See also -search.anonymizeSalt and -search.anonymizeMaskFields. "+
+		"See https://docs.victoriametrics.com/victorialogs/querying/#anonymized-export")
+	anonymizeMaskFields = flagutil.NewArrayString("search.anonymizeMaskFields", "Fields to replace with '***' when /select/logsql/query is called with anonymize=1 query arg. "+
+		"Field names ending with '*' match all the fields with the given prefix. See also -search.anonymizeHashFields. "+
+		"See https://docs.victoriametrics.com/victorialogs/querying/#anonymized-export")
+	anonymizeSalt = flagutil.NewPassword("search.anonymizeSalt", "Secret salt for hashing field values listed in -search.anonymizeHashFields. "+
+		"It must be set for hashing field values, since unsalted hashes of well-known values such as user ids and emails can be easily reversed. "+
+		"See https://docs.victoriametrics.com/victorialogs/querying/#anonymized-export")
+)
+
+// maskedValue is the value for fields listed in -search.anonymizeMaskFields
+const maskedValue = "***"
+
+// anonymizer replaces values for the configured fields with salted hashes or masks them.
+type anonymizer struct {
+	hashFields []string
+	maskFields []string
+	salt       []byte
+}
+
+// getAnonymizer returns anonymizer for the given r.
+//
+// nil is returned if r doesn't contain anonymize=1 query arg.
+func getAnonymizer(r *http.Request) (*anonymizer, error) {
+	if !httputil.GetBool(r, "anonymize") {
+		return nil, nil
+	}
+	hashFields := append([]string{}, *anonymizeHashFields...)
+	hashFields = appendFieldNames(hashFields, r.Form["anonymize_hash_fields"])
+	maskFields := append([]string{}, *anonymizeMaskFields...)
+	maskFields = appendFieldNames(maskFields, r.Form["anonymize_mask_fields"])
+	return newAnonymizer(hashFields, maskFields, anonymizeSalt.Get())
+}
+
+func appendFieldNames(dst, args []string) []string {
+	for _, arg := range args {
+		for _, name := range strings.Split(arg, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				dst = append(dst, name)
+			}
+		}
+	}
+	return dst
+}
+
+func newAnonymizer(hashFields, maskFields []string, salt string) (*anonymizer, error) {
+	if len(hashFields) == 0 && len(maskFields) == 0 {
+		return nil, fmt.Errorf("missing fields to anonymize; set them via -search.anonymizeHashFields and -search.anonymizeMaskFields command-line flags " +
+			"or via anonymize_hash_fields and anonymize_mask_fields query args")
+	}
+	if len(hashFields) > 0 && salt == "" {
+		return nil, fmt.Errorf("-search.anonymizeSalt command-line flag must be set for hashing %q fields", hashFields)
+	}
+	a := &anonymizer{
+		hashFields: hashFields,
+		maskFields: maskFields,
+		salt:       []byte(salt),
+	}
+	return a, nil
+}
+
+// anonymizeFields replaces values for the configured fields in place.
+//
+// Masking has priority over hashing if the field matches both -search.anonymizeMaskFields and -search.anonymizeHashFields.
+func (a *anonymizer) anonymizeFields(fields []logstorage.Field) {
+	for i := range fields {
+		f := &fields[i]
+		if f.Value == "" {
+			// Preserve fields without values, since they are skipped in the response.
+			continue
+		}
+		if matchFieldName(a.maskFields, f.Name) {
+			f.Value = maskedValue
+			continue
+		}
+		if matchFieldName(a.hashFields, f.Name) {
+			f.Value = a.hash(f.Value)
+		}
+	}
+}
+
+func (a *anonymizer) hash(v string) string {
+	h := hmac.New(sha256.New, a.salt)
+	_, _ = h.Write([]byte(v))
+	var buf [sha256.Size]byte
+	sum := h.Sum(buf[:0])
+	// 128 bits are enough for avoiding collisions.
+	return hex.EncodeToString(sum[:16])
+}
+
+func matchFieldName(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if p == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package logsql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestNewAnonymizerFailure(t *testing.T) {
+	f := func(hashFields, maskFields []string, salt string) {
+		t.Helper()
+
+		if _, err := newAnonymizer(hashFields, maskFields, salt); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// missing fields
+	f(nil, nil, "")
+	f(nil, nil, "secret")
+
+	// missing salt
+	f([]string{"user_id"}, nil, "")
+	f([]string{"user_id"}, []string{"email"}, "")
+}
+
+func TestAnonymizerAnonymizeFields(t *testing.T) {
+	a, err := newAnonymizer([]string{"user_id", "client.*"}, []string{"email", "client.ip"}, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(fields, fieldsExpected []logstorage.Field) {
+		t.Helper()
+
+		a.anonymizeFields(fields)
+		if !reflect.DeepEqual(fields, fieldsExpected) {
+			t.Fatalf("unexpected fields\ngot\n%v\nwant\n%v", fields, fieldsExpected)
+		}
+	}
+
+	hashFoo := a.hash("foo")
+	hashBar := a.hash("bar")
+	if hashFoo == hashBar {
+		t.Fatalf("unexpected equal hashes for distinct values: %q", hashFoo)
+	}
+	if len(hashFoo) != 32 {
+		t.Fatalf("unexpected hash length; got %d; want 32", len(hashFoo))
+	}
+
+	// hashed and masked fields
+	f([]logstorage.Field{
+		{Name: "_msg", Value: "user logged in"},
+		{Name: "user_id", Value: "foo"},
+		{Name: "email", Value: "foo@example.com"},
+		{Name: "client.name", Value: "bar"},
+		{Name: "client.ip", Value: "1.2.3.4"},
+		{Name: "clientid", Value: "baz"},
+	}, []logstorage.Field{
+		{Name: "_msg", Value: "user logged in"},
+		{Name: "user_id", Value: hashFoo},
+		{Name: "email", Value: maskedValue},
+		{Name: "client.name", Value: hashBar},
+		{Name: "client.ip", Value: maskedValue},
+		{Name: "clientid", Value: "baz"},
+	})
+
+	// equal values are replaced with equal hashes across fields and rows
+	f([]logstorage.Field{
+		{Name: "user_id", Value: "bar"},
+		{Name: "client.id", Value: "foo"},
+	}, []logstorage.Field{
+		{Name: "user_id", Value: hashBar},
+		{Name: "client.id", Value: hashFoo},
+	})
+
+	// empty values are preserved
+	f([]logstorage.Field{
+		{Name: "user_id", Value: ""},
+		{Name: "email", Value: ""},
+	}, []logstorage.Field{
+		{Name: "user_id", Value: ""},
+		{Name: "email", Value: ""},
+	})
+
+	// hashes depend on salt
+	a2, err := newAnonymizer([]string{"user_id"}, nil, "another-secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h := a2.hash("foo"); h == hashFoo {
+		t.Fatalf("unexpected equal hashes for distinct salts: %q", h)
+	}
+}
@@ -7,172 +7,181 @@ package logsql
 //line app/vlselect/logsql/facets_response.qtpl:1
 import (
 	"slices"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
-//line app/vlselect/logsql/facets_response.qtpl:7
+//line app/vlselect/logsql/facets_response.qtpl:9
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vlselect/logsql/facets_response.qtpl:7
+//line app/vlselect/logsql/facets_response.qtpl:9
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vlselect/logsql/facets_response.qtpl:7
-func StreamFacetsResponse(qw422016 *qt422016.Writer, m map[string][]facetEntry) {
-//line app/vlselect/logsql/facets_response.qtpl:7
+//line app/vlselect/logsql/facets_response.qtpl:9
+func StreamFacetsResponse(qw422016 *qt422016.Writer, m map[string][]facetEntry, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/facets_response.qtpl:9
 	qw422016.N().S(`{`)
-//line app/vlselect/logsql/facets_response.qtpl:10
+//line app/vlselect/logsql/facets_response.qtpl:12
 	sortedKeys := make([]string, 0, len(m))
 	for k := range m {
 		sortedKeys = append(sortedKeys, k)
 	}
 	slices.Sort(sortedKeys)
 
-//line app/vlselect/logsql/facets_response.qtpl:15
-	qw422016.N().S(`"facets":[`)
 //line app/vlselect/logsql/facets_response.qtpl:17
+	qw422016.N().S(`"facets":[`)
+//line app/vlselect/logsql/facets_response.qtpl:19
 	if len(sortedKeys) > 0 {
-//line app/vlselect/logsql/facets_response.qtpl:18
+//line app/vlselect/logsql/facets_response.qtpl:20
 		streamfacetsLine(qw422016, m, sortedKeys[0])
-//line app/vlselect/logsql/facets_response.qtpl:19
+//line app/vlselect/logsql/facets_response.qtpl:21
 		for _, k := range sortedKeys[1:] {
-//line app/vlselect/logsql/facets_response.qtpl:19
+//line app/vlselect/logsql/facets_response.qtpl:21
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/facets_response.qtpl:20
+//line app/vlselect/logsql/facets_response.qtpl:22
 			streamfacetsLine(qw422016, m, k)
-//line app/vlselect/logsql/facets_response.qtpl:21
+//line app/vlselect/logsql/facets_response.qtpl:23
 		}
-//line app/vlselect/logsql/facets_response.qtpl:22
+//line app/vlselect/logsql/facets_response.qtpl:24
 	}
-//line app/vlselect/logsql/facets_response.qtpl:22
-	qw422016.N().S(`]}`)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:24
+	qw422016.N().S(`]`)
+//line app/vlselect/logsql/facets_response.qtpl:26
+	qt.Done()
+
+//line app/vlselect/logsql/facets_response.qtpl:27
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vlselect/logsql/facets_response.qtpl:27
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/facets_response.qtpl:29
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:25
-func WriteFacetsResponse(qq422016 qtio422016.Writer, m map[string][]facetEntry) {
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
+func WriteFacetsResponse(qq422016 qtio422016.Writer, m map[string][]facetEntry, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/facets_response.qtpl:29
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/facets_response.qtpl:25
-	StreamFacetsResponse(qw422016, m)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
+	StreamFacetsResponse(qw422016, m, qt)
+//line app/vlselect/logsql/facets_response.qtpl:29
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:25
-func FacetsResponse(m map[string][]facetEntry) string {
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
+func FacetsResponse(m map[string][]facetEntry, qt *querytracer.Tracer) string {
+//line app/vlselect/logsql/facets_response.qtpl:29
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/facets_response.qtpl:25
-	WriteFacetsResponse(qb422016, m)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
+	WriteFacetsResponse(qb422016, m, qt)
+//line app/vlselect/logsql/facets_response.qtpl:29
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
 	return qs422016
-//line app/vlselect/logsql/facets_response.qtpl:25
+//line app/vlselect/logsql/facets_response.qtpl:29
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:27
+//line app/vlselect/logsql/facets_response.qtpl:31
 func streamfacetsLine(qw422016 *qt422016.Writer, m map[string][]facetEntry, k string) {
-//line app/vlselect/logsql/facets_response.qtpl:27
+//line app/vlselect/logsql/facets_response.qtpl:31
 	qw422016.N().S(`{"field_name":`)
-//line app/vlselect/logsql/facets_response.qtpl:29
+//line app/vlselect/logsql/facets_response.qtpl:33
 	qw422016.N().Q(k)
-//line app/vlselect/logsql/facets_response.qtpl:29
+//line app/vlselect/logsql/facets_response.qtpl:33
 	qw422016.N().S(`,"values":[`)
-//line app/vlselect/logsql/facets_response.qtpl:31
+//line app/vlselect/logsql/facets_response.qtpl:35
 	fes := m[k]
 
-//line app/vlselect/logsql/facets_response.qtpl:32
+//line app/vlselect/logsql/facets_response.qtpl:36
 	if len(fes) > 0 {
-//line app/vlselect/logsql/facets_response.qtpl:33
+//line app/vlselect/logsql/facets_response.qtpl:37
 		streamfacetLine(qw422016, fes[0])
-//line app/vlselect/logsql/facets_response.qtpl:34
+//line app/vlselect/logsql/facets_response.qtpl:38
 		for _, fe := range fes[1:] {
-//line app/vlselect/logsql/facets_response.qtpl:34
+//line app/vlselect/logsql/facets_response.qtpl:38
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/facets_response.qtpl:35
+//line app/vlselect/logsql/facets_response.qtpl:39
 			streamfacetLine(qw422016, fe)
-//line app/vlselect/logsql/facets_response.qtpl:36
+//line app/vlselect/logsql/facets_response.qtpl:40
 		}
-//line app/vlselect/logsql/facets_response.qtpl:37
+//line app/vlselect/logsql/facets_response.qtpl:41
 	}
-//line app/vlselect/logsql/facets_response.qtpl:37
+//line app/vlselect/logsql/facets_response.qtpl:41
 	qw422016.N().S(`]}`)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 func writefacetsLine(qq422016 qtio422016.Writer, m map[string][]facetEntry, k string) {
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	streamfacetsLine(qw422016, m, k)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 func facetsLine(m map[string][]facetEntry, k string) string {
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	writefacetsLine(qb422016, m, k)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 	return qs422016
-//line app/vlselect/logsql/facets_response.qtpl:40
+//line app/vlselect/logsql/facets_response.qtpl:44
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:42
+//line app/vlselect/logsql/facets_response.qtpl:46
 func streamfacetLine(qw422016 *qt422016.Writer, fe facetEntry) {
-//line app/vlselect/logsql/facets_response.qtpl:42
+//line app/vlselect/logsql/facets_response.qtpl:46
 	qw422016.N().S(`{"field_value":`)
-//line app/vlselect/logsql/facets_response.qtpl:44
+//line app/vlselect/logsql/facets_response.qtpl:48
 	qw422016.N().Q(fe.value)
-//line app/vlselect/logsql/facets_response.qtpl:44
+//line app/vlselect/logsql/facets_response.qtpl:48
 	qw422016.N().S(`,"hits":`)
-//line app/vlselect/logsql/facets_response.qtpl:45
+//line app/vlselect/logsql/facets_response.qtpl:49
 	qw422016.N().S(fe.hits)
-//line app/vlselect/logsql/facets_response.qtpl:45
+//line app/vlselect/logsql/facets_response.qtpl:49
 	qw422016.N().S(`}`)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 func writefacetLine(qq422016 qtio422016.Writer, fe facetEntry) {
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	streamfacetLine(qw422016, fe)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 }
 
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 func facetLine(fe facetEntry) string {
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	writefacetLine(qb422016, fe)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 	return qs422016
-//line app/vlselect/logsql/facets_response.qtpl:47
+//line app/vlselect/logsql/facets_response.qtpl:51
 }
@@ -9,215 +9,223 @@ import (
 	"slices"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 // FieldsForHits formats labels for /select/logsql/hits response
 
-//line app/vlselect/logsql/hits_response.qtpl:10
+//line app/vlselect/logsql/hits_response.qtpl:11
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vlselect/logsql/hits_response.qtpl:10
+//line app/vlselect/logsql/hits_response.qtpl:11
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vlselect/logsql/hits_response.qtpl:10
+//line app/vlselect/logsql/hits_response.qtpl:11
 func StreamFieldsForHits(qw422016 *qt422016.Writer, columns []logstorage.BlockColumn, rowIdx int) {
-//line app/vlselect/logsql/hits_response.qtpl:10
+//line app/vlselect/logsql/hits_response.qtpl:11
 	qw422016.N().S(`{`)
-//line app/vlselect/logsql/hits_response.qtpl:12
-	if len(columns) > 0 {
 //line app/vlselect/logsql/hits_response.qtpl:13
+	if len(columns) > 0 {
+//line app/vlselect/logsql/hits_response.qtpl:14
 		qw422016.N().Q(columns[0].Name)
-//line app/vlselect/logsql/hits_response.qtpl:13
+//line app/vlselect/logsql/hits_response.qtpl:14
 		qw422016.N().S(`:`)
-//line app/vlselect/logsql/hits_response.qtpl:13
-		qw422016.N().Q(columns[0].Values[rowIdx])
 //line app/vlselect/logsql/hits_response.qtpl:14
+		qw422016.N().Q(columns[0].Values[rowIdx])
+//line app/vlselect/logsql/hits_response.qtpl:15
 		for _, c := range columns[1:] {
-//line app/vlselect/logsql/hits_response.qtpl:14
-			qw422016.N().S(`,`)
 //line app/vlselect/logsql/hits_response.qtpl:15
+			qw422016.N().S(`,`)
+//line app/vlselect/logsql/hits_response.qtpl:16
 			qw422016.N().Q(c.Name)
-//line app/vlselect/logsql/hits_response.qtpl:15
+//line app/vlselect/logsql/hits_response.qtpl:16
 			qw422016.N().S(`:`)
-//line app/vlselect/logsql/hits_response.qtpl:15
-			qw422016.N().Q(c.Values[rowIdx])
 //line app/vlselect/logsql/hits_response.qtpl:16
-		}
+			qw422016.N().Q(c.Values[rowIdx])
 //line app/vlselect/logsql/hits_response.qtpl:17
+		}
+//line app/vlselect/logsql/hits_response.qtpl:18
 	}
-//line app/vlselect/logsql/hits_response.qtpl:17
+//line app/vlselect/logsql/hits_response.qtpl:18
 	qw422016.N().S(`}`)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 func WriteFieldsForHits(qq422016 qtio422016.Writer, columns []logstorage.BlockColumn, rowIdx int) {
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	StreamFieldsForHits(qw422016, columns, rowIdx)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 func FieldsForHits(columns []logstorage.BlockColumn, rowIdx int) string {
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	WriteFieldsForHits(qb422016, columns, rowIdx)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 	return qs422016
-//line app/vlselect/logsql/hits_response.qtpl:19
+//line app/vlselect/logsql/hits_response.qtpl:20
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:21
-func StreamHitsSeries(qw422016 *qt422016.Writer, m map[string]*hitsSeries) {
-//line app/vlselect/logsql/hits_response.qtpl:21
+//line app/vlselect/logsql/hits_response.qtpl:22
+func StreamHitsSeries(qw422016 *qt422016.Writer, m map[string]*hitsSeries, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/hits_response.qtpl:22
 	qw422016.N().S(`{`)
-//line app/vlselect/logsql/hits_response.qtpl:24
+//line app/vlselect/logsql/hits_response.qtpl:25
 	sortedKeys := make([]string, 0, len(m))
 	for k := range m {
 		sortedKeys = append(sortedKeys, k)
 	}
 	slices.Sort(sortedKeys)
 
-//line app/vlselect/logsql/hits_response.qtpl:29
+//line app/vlselect/logsql/hits_response.qtpl:30
 	qw422016.N().S(`"hits":[`)
-//line app/vlselect/logsql/hits_response.qtpl:31
-	if len(sortedKeys) > 0 {
 //line app/vlselect/logsql/hits_response.qtpl:32
-		streamhitsSeriesLine(qw422016, m, sortedKeys[0])
+	if len(sortedKeys) > 0 {
 //line app/vlselect/logsql/hits_response.qtpl:33
+		streamhitsSeriesLine(qw422016, m, sortedKeys[0])
+//line app/vlselect/logsql/hits_response.qtpl:34
 		for _, k := range sortedKeys[1:] {
-//line app/vlselect/logsql/hits_response.qtpl:33
-			qw422016.N().S(`,`)
 //line app/vlselect/logsql/hits_response.qtpl:34
-			streamhitsSeriesLine(qw422016, m, k)
+			qw422016.N().S(`,`)
 //line app/vlselect/logsql/hits_response.qtpl:35
-		}
+			streamhitsSeriesLine(qw422016, m, k)
 //line app/vlselect/logsql/hits_response.qtpl:36
+		}
+//line app/vlselect/logsql/hits_response.qtpl:37
 	}
-//line app/vlselect/logsql/hits_response.qtpl:36
-	qw422016.N().S(`]}`)
+//line app/vlselect/logsql/hits_response.qtpl:37
+	qw422016.N().S(`]`)
 //line app/vlselect/logsql/hits_response.qtpl:39
+	qt.Done()
+
+//line app/vlselect/logsql/hits_response.qtpl:40
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vlselect/logsql/hits_response.qtpl:40
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/hits_response.qtpl:42
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:39
-func WriteHitsSeries(qq422016 qtio422016.Writer, m map[string]*hitsSeries) {
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
+func WriteHitsSeries(qq422016 qtio422016.Writer, m map[string]*hitsSeries, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/hits_response.qtpl:42
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/hits_response.qtpl:39
-	StreamHitsSeries(qw422016, m)
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
+	StreamHitsSeries(qw422016, m, qt)
+//line app/vlselect/logsql/hits_response.qtpl:42
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:39
-func HitsSeries(m map[string]*hitsSeries) string {
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
+func HitsSeries(m map[string]*hitsSeries, qt *querytracer.Tracer) string {
+//line app/vlselect/logsql/hits_response.qtpl:42
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/hits_response.qtpl:39
-	WriteHitsSeries(qb422016, m)
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
+	WriteHitsSeries(qb422016, m, qt)
+//line app/vlselect/logsql/hits_response.qtpl:42
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
 	return qs422016
-//line app/vlselect/logsql/hits_response.qtpl:39
+//line app/vlselect/logsql/hits_response.qtpl:42
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:41
+//line app/vlselect/logsql/hits_response.qtpl:44
 func streamhitsSeriesLine(qw422016 *qt422016.Writer, m map[string]*hitsSeries, k string) {
-//line app/vlselect/logsql/hits_response.qtpl:41
-	qw422016.N().S(`{`)
 //line app/vlselect/logsql/hits_response.qtpl:44
+	qw422016.N().S(`{`)
+//line app/vlselect/logsql/hits_response.qtpl:47
 	hs := m[k]
 	hs.sort()
 	timestamps := hs.timestamps
 	hits := hs.hits
 
-//line app/vlselect/logsql/hits_response.qtpl:48
+//line app/vlselect/logsql/hits_response.qtpl:51
 	qw422016.N().S(`"fields":`)
-//line app/vlselect/logsql/hits_response.qtpl:49
+//line app/vlselect/logsql/hits_response.qtpl:52
 	qw422016.N().S(k)
-//line app/vlselect/logsql/hits_response.qtpl:49
+//line app/vlselect/logsql/hits_response.qtpl:52
 	qw422016.N().S(`,"timestamps":[`)
-//line app/vlselect/logsql/hits_response.qtpl:51
+//line app/vlselect/logsql/hits_response.qtpl:54
 	if len(timestamps) > 0 {
-//line app/vlselect/logsql/hits_response.qtpl:52
+//line app/vlselect/logsql/hits_response.qtpl:55
 		qw422016.N().Q(timestamps[0])
-//line app/vlselect/logsql/hits_response.qtpl:53
+//line app/vlselect/logsql/hits_response.qtpl:56
 		for _, ts := range timestamps[1:] {
-//line app/vlselect/logsql/hits_response.qtpl:53
+//line app/vlselect/logsql/hits_response.qtpl:56
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/hits_response.qtpl:54
+//line app/vlselect/logsql/hits_response.qtpl:57
 			qw422016.N().Q(ts)
-//line app/vlselect/logsql/hits_response.qtpl:55
+//line app/vlselect/logsql/hits_response.qtpl:58
 		}
-//line app/vlselect/logsql/hits_response.qtpl:56
+//line app/vlselect/logsql/hits_response.qtpl:59
 	}
-//line app/vlselect/logsql/hits_response.qtpl:56
-	qw422016.N().S(`],"values":[`)
 //line app/vlselect/logsql/hits_response.qtpl:59
+	qw422016.N().S(`],"values":[`)
+//line app/vlselect/logsql/hits_response.qtpl:62
 	if len(hits) > 0 {
-//line app/vlselect/logsql/hits_response.qtpl:60
+//line app/vlselect/logsql/hits_response.qtpl:63
 		qw422016.N().DUL(hits[0])
-//line app/vlselect/logsql/hits_response.qtpl:61
+//line app/vlselect/logsql/hits_response.qtpl:64
 		for _, v := range hits[1:] {
-//line app/vlselect/logsql/hits_response.qtpl:61
+//line app/vlselect/logsql/hits_response.qtpl:64
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/hits_response.qtpl:62
+//line app/vlselect/logsql/hits_response.qtpl:65
 			qw422016.N().DUL(v)
-//line app/vlselect/logsql/hits_response.qtpl:63
+//line app/vlselect/logsql/hits_response.qtpl:66
 		}
-//line app/vlselect/logsql/hits_response.qtpl:64
+//line app/vlselect/logsql/hits_response.qtpl:67
 	}
-//line app/vlselect/logsql/hits_response.qtpl:64
+//line app/vlselect/logsql/hits_response.qtpl:67
 	qw422016.N().S(`],"total":`)
-//line app/vlselect/logsql/hits_response.qtpl:66
+//line app/vlselect/logsql/hits_response.qtpl:69
 	qw422016.N().DUL(hs.hitsTotal)
-//line app/vlselect/logsql/hits_response.qtpl:66
+//line app/vlselect/logsql/hits_response.qtpl:69
 	qw422016.N().S(`}`)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 func writehitsSeriesLine(qq422016 qtio422016.Writer, m map[string]*hitsSeries, k string) {
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	streamhitsSeriesLine(qw422016, m, k)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 }
 
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 func hitsSeriesLine(m map[string]*hitsSeries, k string) string {
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	writehitsSeriesLine(qb422016, m, k)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 	return qs422016
-//line app/vlselect/logsql/hits_response.qtpl:68
+//line app/vlselect/logsql/hits_response.qtpl:71
 }
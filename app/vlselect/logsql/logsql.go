@@ -25,6 +25,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
 )
 
@@ -58,6 +59,8 @@ func ProcessFacetsRequest(ctx context.Context, w http.ResponseWriter, r *http.Re
 	q.DropAllPipes()
 	q.AddFacetsPipe(limit, maxValuesPerField, maxValueLen, keepConstFields)
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/facets: query=%s", q)
+
 	var mLock sync.Mutex
 	m := make(map[string][]facetEntry)
 	writeBlock := func(_ uint, db *logstorage.DataBlock) {
@@ -92,14 +95,14 @@ func ProcessFacetsRequest(ctx context.Context, w http.ResponseWriter, r *http.Re
 	}
 
 	// Execute the query
-	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
 		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
 		return
 	}
 
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
-	WriteFacetsResponse(w, m)
+	WriteFacetsResponse(w, m, qt)
 }
 
 type facetEntry struct {
@@ -107,6 +110,83 @@ type facetEntry struct {
 	hits  string
 }
 
+// ProcessFieldStatsRequest handles /select/logsql/field_stats request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-field-stats
+func ProcessFieldStatsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	limit, err := httputil.GetInt(r, "limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	maxValueLen, err := httputil.GetInt(r, "max_value_len")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	q.DropAllPipes()
+	q.AddFieldStatsPipe(limit, maxValueLen)
+
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/field_stats: query=%s", q)
+
+	var fssLock sync.Mutex
+	var fss []fieldStatsEntry
+	writeBlock := func(_ uint, db *logstorage.DataBlock) {
+		rowsCount := db.RowsCount()
+		if rowsCount == 0 {
+			return
+		}
+
+		columns := db.Columns
+		if len(columns) != 5 {
+			logger.Panicf("BUG: expecting 5 columns; got %d columns", len(columns))
+		}
+
+		for i := 0; i < rowsCount; i++ {
+			fs := fieldStatsEntry{
+				name:           strings.Clone(columns[0].Values[i]),
+				hits:           strings.Clone(columns[1].Values[i]),
+				nullRatio:      strings.Clone(columns[2].Values[i]),
+				distinctValues: strings.Clone(columns[3].Values[i]),
+				topValues:      strings.Clone(columns[4].Values[i]),
+			}
+
+			fssLock.Lock()
+			fss = append(fss, fs)
+			fssLock.Unlock()
+		}
+	}
+
+	// Execute the query
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+
+	sort.Slice(fss, func(i, j int) bool {
+		return fss[i].name < fss[j].name
+	})
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	WriteFieldStatsResponse(w, fss, qt)
+}
+
+type fieldStatsEntry struct {
+	name           string
+	hits           string
+	nullRatio      string
+	distinctValues string
+	topValues      string
+}
+
 // ProcessHitsRequest handles /select/logsql/hits request.
 //
 // See https://docs.victoriametrics.com/victorialogs/querying/#querying-hits-stats
@@ -160,6 +240,8 @@ func ProcessHitsRequest(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	q.DropAllPipes()
 	q.AddCountByTimePipe(int64(step), int64(offset), fields)
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/hits: query=%s", q)
+
 	var mLock sync.Mutex
 	m := make(map[string]*hitsSeries)
 	writeBlock := func(_ uint, db *logstorage.DataBlock) {
@@ -201,7 +283,7 @@ func ProcessHitsRequest(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	}
 
 	// Execute the query
-	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
 		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
 		return
 	}
@@ -210,7 +292,7 @@ func ProcessHitsRequest(ctx context.Context, w http.ResponseWriter, r *http.Requ
 
 	// Write response
 	w.Header().Set("Content-Type", "application/json")
-	WriteHitsSeries(w, m)
+	WriteHitsSeries(w, m, qt)
 }
 
 var blockResultPool bytesutil.ByteBufferPool
@@ -280,6 +362,201 @@ func (hs *hitsSeries) Less(i, j int) bool {
 	return hs.timestamps[i] < hs.timestamps[j]
 }
 
+// ProcessHeatmapRequest handles /select/logsql/heatmap request.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#querying-heatmaps
+func ProcessHeatmapRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseCommonArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	// Obtain the field with numeric values
+	fieldName := r.FormValue("field")
+	if fieldName == "" {
+		httpserver.Errorf(w, r, "missing 'field' query arg")
+		return
+	}
+
+	// Obtain step
+	stepStr := r.FormValue("step")
+	if stepStr == "" {
+		stepStr = "1d"
+	}
+	step, err := timeutil.ParseDuration(stepStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse 'step' arg: %s", err)
+		return
+	}
+	if step <= 0 {
+		httpserver.Errorf(w, r, "'step' must be bigger than zero")
+		return
+	}
+
+	// Obtain offset
+	offsetStr := r.FormValue("offset")
+	if offsetStr == "" {
+		offsetStr = "0s"
+	}
+	offset, err := timeutil.ParseDuration(offsetStr)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse 'offset' arg: %s", err)
+		return
+	}
+
+	// Prepare the query for histogram buckets.
+	q.DropAllPipes()
+	q.AddHistogramByTimePipe(int64(step), int64(offset), fieldName)
+
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/heatmap: query=%s", q)
+
+	hm := newHeatmap(fieldName)
+	var parseErr atomic.Pointer[error]
+	writeBlock := func(_ uint, db *logstorage.DataBlock) {
+		rowsCount := db.RowsCount()
+		if rowsCount == 0 {
+			return
+		}
+
+		columns := db.Columns
+		if len(columns) != 2 {
+			logger.Panicf("BUG: expecting 2 columns; got %d columns", len(columns))
+		}
+		timestampValues := columns[0].Values
+		bucketsValues := columns[1].Values
+
+		for i := 0; i < rowsCount; i++ {
+			if err := hm.addBuckets(timestampValues[i], bucketsValues[i]); err != nil {
+				parseErr.CompareAndSwap(nil, &err)
+				return
+			}
+		}
+	}
+
+	// Execute the query
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+	if errP := parseErr.Load(); errP != nil {
+		httpserver.Errorf(w, r, "cannot build heatmap for query [%s]: %s", q, *errP)
+		return
+	}
+
+	// Write response
+	w.Header().Set("Content-Type", "application/json")
+	WriteHeatmapResponse(w, hm, qt)
+}
+
+// heatmap contains time x bucket matrix for the values of the given field.
+type heatmap struct {
+	field string
+
+	mu sync.Mutex
+
+	// timestamps contains sorted unique timestamps after finalize() call.
+	timestamps []string
+
+	// vmranges contains sorted unique vmrange buckets after finalize() call.
+	vmranges []string
+
+	// hits contains hits per every (timestamp, vmrange) pair.
+	hits map[string]map[string]uint64
+}
+
+func newHeatmap(field string) *heatmap {
+	return &heatmap{
+		field: field,
+		hits:  make(map[string]map[string]uint64),
+	}
+}
+
+func (hm *heatmap) addBuckets(timestamp, buckets string) error {
+	p := heatmapParserPool.Get()
+	defer heatmapParserPool.Put(p)
+
+	v, err := p.Parse(buckets)
+	if err != nil {
+		return fmt.Errorf("cannot parse histogram buckets %q: %w", buckets, err)
+	}
+	a, err := v.Array()
+	if err != nil {
+		return fmt.Errorf("unexpected histogram buckets %q: %w", buckets, err)
+	}
+
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	m := hm.hits[timestamp]
+	if m == nil {
+		m = make(map[string]uint64)
+		hm.hits[strings.Clone(timestamp)] = m
+	}
+	for _, b := range a {
+		vmrange := b.GetStringBytes("vmrange")
+		if len(vmrange) == 0 {
+			return fmt.Errorf("missing vmrange in histogram bucket %s", b)
+		}
+		n, err := b.Get("hits").Uint64()
+		if err != nil {
+			return fmt.Errorf("cannot parse hits in histogram bucket %s: %w", b, err)
+		}
+		m[string(vmrange)] += n
+	}
+	return nil
+}
+
+// finalize prepares hm for writing the response.
+func (hm *heatmap) finalize() {
+	timestamps := make([]string, 0, len(hm.hits))
+	vmrangesSeen := make(map[string]struct{})
+	for timestamp, m := range hm.hits {
+		timestamps = append(timestamps, timestamp)
+		for vmrange := range m {
+			vmrangesSeen[vmrange] = struct{}{}
+		}
+	}
+	sort.Strings(timestamps)
+
+	vmranges := make([]string, 0, len(vmrangesSeen))
+	for vmrange := range vmrangesSeen {
+		vmranges = append(vmranges, vmrange)
+	}
+	sort.Slice(vmranges, func(i, j int) bool {
+		return getVMRangeStart(vmranges[i]) < getVMRangeStart(vmranges[j])
+	})
+
+	hm.timestamps = timestamps
+	hm.vmranges = vmranges
+}
+
+// bucketHits returns hits for the given vmrange bucket across all the hm.timestamps.
+func (hm *heatmap) bucketHits(vmrange string) ([]uint64, uint64) {
+	hits := make([]uint64, len(hm.timestamps))
+	total := uint64(0)
+	for i, timestamp := range hm.timestamps {
+		n := hm.hits[timestamp][vmrange]
+		hits[i] = n
+		total += n
+	}
+	return hits, total
+}
+
+func getVMRangeStart(vmrange string) float64 {
+	n := strings.Index(vmrange, "...")
+	if n < 0 {
+		return 0
+	}
+	f, err := strconv.ParseFloat(vmrange[:n], 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+var heatmapParserPool fastjson.ParserPool
+
 // ProcessFieldNamesRequest handles /select/logsql/field_names request.
 //
 // See https://docs.victoriametrics.com/victorialogs/querying/#querying-field-names
@@ -290,8 +567,10 @@ func ProcessFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *htt
 		return
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/field_names: query=%s", q)
+
 	// Obtain field names for the given query
-	fieldNames, err := vlstorage.GetFieldNames(ctx, tenantIDs, q)
+	fieldNames, err := vlstorage.GetFieldNames(ctx, qt, tenantIDs, q)
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain field names: %s", err)
 		return
@@ -299,7 +578,7 @@ func ProcessFieldNamesRequest(ctx context.Context, w http.ResponseWriter, r *htt
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, fieldNames)
+	WriteValuesWithHitsJSON(w, fieldNames, qt)
 }
 
 // ProcessFieldValuesRequest handles /select/logsql/field_values request.
@@ -329,8 +608,10 @@ func ProcessFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *ht
 		limit = 0
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/field_values: query=%s", q)
+
 	// Obtain unique values for the given field
-	values, err := vlstorage.GetFieldValues(ctx, tenantIDs, q, fieldName, uint64(limit))
+	values, err := vlstorage.GetFieldValues(ctx, qt, tenantIDs, q, fieldName, uint64(limit))
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain values for field %q: %s", fieldName, err)
 		return
@@ -338,7 +619,7 @@ func ProcessFieldValuesRequest(ctx context.Context, w http.ResponseWriter, r *ht
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, values)
+	WriteValuesWithHitsJSON(w, values, qt)
 }
 
 // ProcessStreamFieldNamesRequest processes /select/logsql/stream_field_names request.
@@ -351,15 +632,17 @@ func ProcessStreamFieldNamesRequest(ctx context.Context, w http.ResponseWriter,
 		return
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/stream_field_names: query=%s", q)
+
 	// Obtain stream field names for the given query
-	names, err := vlstorage.GetStreamFieldNames(ctx, tenantIDs, q)
+	names, err := vlstorage.GetStreamFieldNames(ctx, qt, tenantIDs, q)
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain stream field names: %s", err)
 	}
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, names)
+	WriteValuesWithHitsJSON(w, names, qt)
 }
 
 // ProcessStreamFieldValuesRequest processes /select/logsql/stream_field_values request.
@@ -389,15 +672,17 @@ func ProcessStreamFieldValuesRequest(ctx context.Context, w http.ResponseWriter,
 		limit = 0
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/stream_field_values: query=%s", q)
+
 	// Obtain stream field values for the given query and the given fieldName
-	values, err := vlstorage.GetStreamFieldValues(ctx, tenantIDs, q, fieldName, uint64(limit))
+	values, err := vlstorage.GetStreamFieldValues(ctx, qt, tenantIDs, q, fieldName, uint64(limit))
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain stream field values: %s", err)
 	}
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, values)
+	WriteValuesWithHitsJSON(w, values, qt)
 }
 
 // ProcessStreamIDsRequest processes /select/logsql/stream_ids request.
@@ -420,15 +705,17 @@ func ProcessStreamIDsRequest(ctx context.Context, w http.ResponseWriter, r *http
 		limit = 0
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/stream_ids: query=%s", q)
+
 	// Obtain streamIDs for the given query
-	streamIDs, err := vlstorage.GetStreamIDs(ctx, tenantIDs, q, uint64(limit))
+	streamIDs, err := vlstorage.GetStreamIDs(ctx, qt, tenantIDs, q, uint64(limit))
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain stream_ids: %s", err)
 	}
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, streamIDs)
+	WriteValuesWithHitsJSON(w, streamIDs, qt)
 }
 
 // ProcessStreamsRequest processes /select/logsql/streams request.
@@ -451,15 +738,17 @@ func ProcessStreamsRequest(ctx context.Context, w http.ResponseWriter, r *http.R
 		limit = 0
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/streams: query=%s", q)
+
 	// Obtain streams for the given query
-	streams, err := vlstorage.GetStreams(ctx, tenantIDs, q, uint64(limit))
+	streams, err := vlstorage.GetStreams(ctx, qt, tenantIDs, q, uint64(limit))
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot obtain streams: %s", err)
 	}
 
 	// Write results
 	w.Header().Set("Content-Type", "application/json")
-	WriteValuesWithHitsJSON(w, streams)
+	WriteValuesWithHitsJSON(w, streams, qt)
 }
 
 // ProcessLiveTailRequest processes live tailing request to /select/logsq/tail
@@ -517,7 +806,7 @@ func ProcessLiveTailRequest(ctx context.Context, w http.ResponseWriter, r *http.
 	qOrig := q
 	for {
 		q = qOrig.CloneWithTimeFilter(end, start, end)
-		if err := vlstorage.RunQuery(ctxWithCancel, tenantIDs, q, tp.writeBlock); err != nil {
+		if err := vlstorage.RunQuery(ctxWithCancel, nil, tenantIDs, q, tp.writeBlock); err != nil {
 			httpserver.Errorf(w, r, "cannot execute tail query [%s]: %s", q, err)
 			return
 		}
@@ -658,7 +947,7 @@ func (tp *tailProcessor) getTailRows() ([][]logstorage.Field, error) {
 //
 // See https://docs.victoriametrics.com/victorialogs/querying/#querying-log-range-stats
 func ProcessStatsQueryRangeRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	q, tenantIDs, err := parseCommonArgs(r)
+	q, tenantIDs, start, end, err := parseCommonArgsInternal(r, false)
 	if err != nil {
 		httpserver.SendPrometheusError(w, r, err)
 		return
@@ -681,15 +970,66 @@ func ProcessStatsQueryRangeRequest(ctx context.Context, w http.ResponseWriter, r
 		return
 	}
 
-	// Obtain `by(...)` fields from the last `| stats` pipe in q.
-	// Add `_time:step` to the `by(...)` list.
-	byFields, err := q.GetStatsByFieldsAddGroupingByTime(int64(step))
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/stats_query_range: query=%s, start=%d, end=%d, step=%s", q, start, end, step)
+
+	m, err := getStatsQueryRangeSeries(ctx, qt, tenantIDs, q, int64(step), start, end)
 	if err != nil {
 		httpserver.SendPrometheusError(w, r, err)
 		return
 	}
 
-	m := make(map[string]*statsSeries)
+	// Sort the collected stats by time
+	rows := make([]*statsSeries, 0, len(m))
+	for _, ss := range m {
+		points := ss.Points
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Timestamp < points[j].Timestamp
+		})
+		rows = append(rows, ss)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].key < rows[j].key
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	WriteStatsQueryRangeResponse(w, rows, qt)
+}
+
+// getStatsQueryRangeSeries returns stats series for q on the [start, end] time range with the given step.
+//
+// q mustn't contain the global time filter for [start, end] time range.
+//
+// The results for historical time buckets are cached if -search.statsQueryRangeCacheSize is set.
+func getStatsQueryRangeSeries(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, step, start, end int64) (map[string]*statsSeries, error) {
+	c := statsQueryRangeCacheV
+	if c == nil || start == math.MinInt64 || !q.CanCacheStatsByTime() {
+		if start != math.MinInt64 || end != math.MaxInt64 {
+			q.AddTimeFilter(start, end)
+		}
+		m := make(map[string]*statsSeries)
+		if err := runStatsQueryRange(ctx, qt, tenantIDs, q, step, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	keyPrefix := getStatsQueryRangeCacheKeyPrefix(tenantIDs, q, step)
+	fetchSeries := func(start, end int64, m map[string]*statsSeries) error {
+		qRange := q.CloneWithTimeFilter(q.GetTimestamp(), start, end)
+		return runStatsQueryRange(ctx, qt, tenantIDs, qRange, step, m)
+	}
+	return c.getSeries(keyPrefix, step, start, end, fetchSeries)
+}
+
+// runStatsQueryRange runs q with the given step and puts the results into m.
+func runStatsQueryRange(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, step int64, m map[string]*statsSeries) error {
+	// Obtain `by(...)` fields from the last `| stats` pipe in q.
+	// Add `_time:step` to the `by(...)` list.
+	byFields, err := q.GetStatsByFieldsAddGroupingByTime(step)
+	if err != nil {
+		return err
+	}
+
 	var mLock sync.Mutex
 
 	writeBlock := func(_ uint, db *logstorage.DataBlock) {
@@ -752,27 +1092,10 @@ func ProcessStatsQueryRangeRequest(ctx context.Context, w http.ResponseWriter, r
 		}
 	}
 
-	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
-		err = fmt.Errorf("cannot execute query [%s]: %s", q, err)
-		httpserver.SendPrometheusError(w, r, err)
-		return
-	}
-
-	// Sort the collected stats by time
-	rows := make([]*statsSeries, 0, len(m))
-	for _, ss := range m {
-		points := ss.Points
-		sort.Slice(points, func(i, j int) bool {
-			return points[i].Timestamp < points[j].Timestamp
-		})
-		rows = append(rows, ss)
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
+		return fmt.Errorf("cannot execute query [%s]: %s", q, err)
 	}
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i].key < rows[j].key
-	})
-
-	w.Header().Set("Content-Type", "application/json")
-	WriteStatsQueryRangeResponse(w, rows)
+	return nil
 }
 
 type statsSeries struct {
@@ -805,6 +1128,8 @@ func ProcessStatsQueryRequest(ctx context.Context, w http.ResponseWriter, r *htt
 		return
 	}
 
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/stats_query: query=%s", q)
+
 	var rows []statsRow
 	var rowsLock sync.Mutex
 
@@ -844,14 +1169,14 @@ func ProcessStatsQueryRequest(ctx context.Context, w http.ResponseWriter, r *htt
 		}
 	}
 
-	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
 		err = fmt.Errorf("cannot execute query [%s]: %s", q, err)
 		httpserver.SendPrometheusError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	WriteStatsQueryResponse(w, rows)
+	WriteStatsQueryResponse(w, rows, qt)
 }
 
 type statsRow struct {
@@ -878,6 +1203,15 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Parse anonymize query args
+	a, err := getAnonymizer(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	qt := querytracer.New(httputil.GetBool(r, "trace"), "/select/logsql/query: query=%s, limit=%d", q, limit)
+
 	sw := &syncWriter{
 		w: w,
 	}
@@ -886,30 +1220,35 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 	bwShards.Init = func(shard *bufferedWriter) {
 		shard.sw = sw
 	}
-	defer func() {
+	flushShards := func() {
 		shards := bwShards.GetSlice()
 		for _, shard := range shards {
 			shard.FlushIgnoreErrors()
 		}
-	}()
+	}
+	defer flushShards()
 
 	w.Header().Set("Content-Type", "application/stream+json")
 
 	if limit > 0 {
 		if q.CanReturnLastNResults() {
-			rows, err := getLastNQueryResults(ctx, tenantIDs, q, limit)
+			rows, err := getLastNQueryResults(ctx, qt, tenantIDs, q, limit)
 			if err != nil {
 				httpserver.Errorf(w, r, "%s", err)
 				return
 			}
 			bw := bwShards.Get(0)
 			for i := range rows {
+				if a != nil {
+					a.anonymizeFields(rows[i].fields)
+				}
 				bw.buf = logstorage.MarshalFieldsToJSON(bw.buf, rows[i].fields)
 				bw.buf = append(bw.buf, '\n')
 				if len(bw.buf) > 16*1024 {
 					bw.FlushIgnoreErrors()
 				}
 			}
+			writeQueryTrace(bw, qt)
 			return
 		}
 
@@ -924,6 +1263,10 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 		columns := db.Columns
 
 		bw := bwShards.Get(workerID)
+		if a != nil {
+			writeAnonymizedRows(bw, a, columns, rowsCount)
+			return
+		}
 		for i := 0; i < rowsCount; i++ {
 			WriteJSONRow(bw, columns, i)
 			if len(bw.buf) > 16*1024 {
@@ -932,10 +1275,48 @@ func ProcessQueryRequest(ctx context.Context, w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	if err := vlstorage.RunQuery(ctx, tenantIDs, q, writeBlock); err != nil {
+	if err := vlstorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock); err != nil {
 		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
 		return
 	}
+
+	if qt.Enabled() {
+		// The trace must be written after all the query results.
+		flushShards()
+		writeQueryTrace(bwShards.Get(0), qt)
+	}
+}
+
+// writeQueryTrace finishes qt and writes it to bw as the last JSON line of /select/logsql/query response.
+//
+// It is no-op if qt is disabled.
+func writeQueryTrace(bw *bufferedWriter, qt *querytracer.Tracer) {
+	if !qt.Enabled() {
+		return
+	}
+	qt.Done()
+	bw.buf = append(bw.buf, `{"trace":`...)
+	bw.buf = append(bw.buf, qt.ToJSON()...)
+	bw.buf = append(bw.buf, "}\n"...)
+	bw.FlushIgnoreErrors()
+}
+
+func writeAnonymizedRows(bw *bufferedWriter, a *anonymizer, columns []logstorage.BlockColumn, rowsCount int) {
+	fields := make([]logstorage.Field, len(columns))
+	for i := 0; i < rowsCount; i++ {
+		for j := range columns {
+			fields[j] = logstorage.Field{
+				Name:  columns[j].Name,
+				Value: columns[j].Values[i],
+			}
+		}
+		a.anonymizeFields(fields)
+		bw.buf = logstorage.MarshalFieldsToJSON(bw.buf, fields)
+		bw.buf = append(bw.buf, '\n')
+		if len(bw.buf) > 16*1024 {
+			bw.FlushIgnoreErrors()
+		}
+	}
 }
 
 type syncWriter struct {
@@ -968,11 +1349,11 @@ func (bw *bufferedWriter) FlushIgnoreErrors() {
 	bw.buf = bw.buf[:0]
 }
 
-func getLastNQueryResults(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit int) ([]logRow, error) {
+func getLastNQueryResults(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit int) ([]logRow, error) {
 	limitUpper := 2 * limit
 	q.AddPipeLimit(uint64(limitUpper))
 
-	rows, err := getQueryResultsWithLimit(ctx, tenantIDs, q, limitUpper)
+	rows, err := getQueryResultsWithLimit(ctx, qt, tenantIDs, q, limitUpper)
 	if err != nil {
 		return nil, err
 	}
@@ -991,7 +1372,7 @@ func getLastNQueryResults(ctx context.Context, tenantIDs []logstorage.TenantID,
 	for {
 		timestamp := qOrig.GetTimestamp()
 		q = qOrig.CloneWithTimeFilter(timestamp, start, end)
-		rows, err := getQueryResultsWithLimit(ctx, tenantIDs, q, limitUpper)
+		rows, err := getQueryResultsWithLimit(ctx, qt, tenantIDs, q, limitUpper)
 		if err != nil {
 			return nil, err
 		}
@@ -1047,7 +1428,7 @@ func getLastNRows(rows []logRow, limit int) []logRow {
 	return rows
 }
 
-func getQueryResultsWithLimit(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit int) ([]logRow, error) {
+func getQueryResultsWithLimit(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit int) ([]logRow, error) {
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -1092,7 +1473,7 @@ func getQueryResultsWithLimit(ctx context.Context, tenantIDs []logstorage.Tenant
 			cancel()
 		}
 	}
-	err := vlstorage.RunQuery(ctxWithCancel, tenantIDs, q, writeBlock)
+	err := vlstorage.RunQuery(ctxWithCancel, qt, tenantIDs, q, writeBlock)
 
 	if missingTimeColumn.Load() {
 		return nil, fmt.Errorf("missing _time column in the result for the query [%s]", q)
@@ -1102,27 +1483,35 @@ func getQueryResultsWithLimit(ctx context.Context, tenantIDs []logstorage.Tenant
 }
 
 func parseCommonArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID, error) {
+	q, tenantIDs, _, _, err := parseCommonArgsInternal(r, true)
+	return q, tenantIDs, err
+}
+
+// parseCommonArgsInternal parses common args from r.
+//
+// It returns [start, end] time range from the request args. The returned time range is added to the returned query if addTimeFilter is set.
+func parseCommonArgsInternal(r *http.Request, addTimeFilter bool) (*logstorage.Query, []logstorage.TenantID, int64, int64, error) {
 	// Extract tenantID
 	tenantID, err := logstorage.GetTenantIDFromRequest(r)
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot obtain tenanID: %w", err)
+		return nil, nil, 0, 0, fmt.Errorf("cannot obtain tenanID: %w", err)
 	}
 	tenantIDs := []logstorage.TenantID{tenantID}
 
 	// Parse optional start and end args
 	start, okStart, err := getTimeNsec(r, "start")
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 	end, okEnd, err := getTimeNsec(r, "end")
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 
 	// Parse optional time arg
 	timestamp, okTime, err := getTimeNsec(r, "time")
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 	if !okTime {
 		// If time arg is missing, then evaluate query either at the end timestamp (if it is set)
@@ -1142,17 +1531,17 @@ func parseCommonArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID,
 	qStr := r.FormValue("query")
 	q, err := logstorage.ParseQueryAtTimestamp(qStr, timestamp)
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot parse query [%s]: %s", qStr, err)
+		return nil, nil, 0, 0, fmt.Errorf("cannot parse query [%s]: %s", qStr, err)
 	}
 
-	if okStart || okEnd {
+	if !okStart {
+		start = math.MinInt64
+	}
+	if !okEnd {
+		end = math.MaxInt64
+	}
+	if addTimeFilter && (okStart || okEnd) {
 		// Add _time:[start, end] filter if start or end args were set.
-		if !okStart {
-			start = math.MinInt64
-		}
-		if !okEnd {
-			end = math.MaxInt64
-		}
 		q.AddTimeFilter(start, end)
 	}
 
@@ -1160,7 +1549,7 @@ func parseCommonArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID,
 	extraFiltersStr := r.FormValue("extra_filters")
 	extraFilters, err := parseExtraFilters(extraFiltersStr)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 	q.AddExtraFilters(extraFilters)
 
@@ -1168,11 +1557,11 @@ func parseCommonArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID,
 	extraStreamFiltersStr := r.FormValue("extra_stream_filters")
 	extraStreamFilters, err := parseExtraStreamFilters(extraStreamFiltersStr)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, 0, err
 	}
 	q.AddExtraFilters(extraStreamFilters)
 
-	return q, tenantIDs, nil
+	return q, tenantIDs, start, end, nil
 }
 
 func getTimeNsec(r *http.Request, argName string) (int64, bool, error) {
@@ -7,146 +7,194 @@ package logsql
 //line app/vlselect/logsql/logsql.qtpl:1
 import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 // ValuesWithHitsJSON generates JSON from the given values.
 
-//line app/vlselect/logsql/logsql.qtpl:8
+//line app/vlselect/logsql/logsql.qtpl:9
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vlselect/logsql/logsql.qtpl:8
+//line app/vlselect/logsql/logsql.qtpl:9
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vlselect/logsql/logsql.qtpl:8
-func StreamValuesWithHitsJSON(qw422016 *qt422016.Writer, values []logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:8
+//line app/vlselect/logsql/logsql.qtpl:9
+func StreamValuesWithHitsJSON(qw422016 *qt422016.Writer, values []logstorage.ValueWithHits, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/logsql.qtpl:9
 	qw422016.N().S(`{"values":`)
-//line app/vlselect/logsql/logsql.qtpl:10
+//line app/vlselect/logsql/logsql.qtpl:11
 	streamvaluesWithHitsJSONArray(qw422016, values)
-//line app/vlselect/logsql/logsql.qtpl:10
-	qw422016.N().S(`}`)
 //line app/vlselect/logsql/logsql.qtpl:12
+	qt.Done()
+
+//line app/vlselect/logsql/logsql.qtpl:13
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vlselect/logsql/logsql.qtpl:13
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/logsql.qtpl:15
 }
 
-//line app/vlselect/logsql/logsql.qtpl:12
-func WriteValuesWithHitsJSON(qq422016 qtio422016.Writer, values []logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
+func WriteValuesWithHitsJSON(qq422016 qtio422016.Writer, values []logstorage.ValueWithHits, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/logsql.qtpl:15
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/logsql.qtpl:12
-	StreamValuesWithHitsJSON(qw422016, values)
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
+	StreamValuesWithHitsJSON(qw422016, values, qt)
+//line app/vlselect/logsql/logsql.qtpl:15
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
 }
 
-//line app/vlselect/logsql/logsql.qtpl:12
-func ValuesWithHitsJSON(values []logstorage.ValueWithHits) string {
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
+func ValuesWithHitsJSON(values []logstorage.ValueWithHits, qt *querytracer.Tracer) string {
+//line app/vlselect/logsql/logsql.qtpl:15
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/logsql.qtpl:12
-	WriteValuesWithHitsJSON(qb422016, values)
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
+	WriteValuesWithHitsJSON(qb422016, values, qt)
+//line app/vlselect/logsql/logsql.qtpl:15
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
 	return qs422016
-//line app/vlselect/logsql/logsql.qtpl:12
+//line app/vlselect/logsql/logsql.qtpl:15
 }
 
-//line app/vlselect/logsql/logsql.qtpl:14
+//line app/vlselect/logsql/logsql.qtpl:17
 func streamvaluesWithHitsJSONArray(qw422016 *qt422016.Writer, values []logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:14
+//line app/vlselect/logsql/logsql.qtpl:17
 	qw422016.N().S(`[`)
-//line app/vlselect/logsql/logsql.qtpl:16
+//line app/vlselect/logsql/logsql.qtpl:19
 	if len(values) > 0 {
-//line app/vlselect/logsql/logsql.qtpl:17
+//line app/vlselect/logsql/logsql.qtpl:20
 		streamvalueWithHitsJSON(qw422016, values[0])
-//line app/vlselect/logsql/logsql.qtpl:18
+//line app/vlselect/logsql/logsql.qtpl:21
 		for _, v := range values[1:] {
-//line app/vlselect/logsql/logsql.qtpl:18
+//line app/vlselect/logsql/logsql.qtpl:21
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/logsql.qtpl:19
+//line app/vlselect/logsql/logsql.qtpl:22
 			streamvalueWithHitsJSON(qw422016, v)
-//line app/vlselect/logsql/logsql.qtpl:20
+//line app/vlselect/logsql/logsql.qtpl:23
 		}
-//line app/vlselect/logsql/logsql.qtpl:21
+//line app/vlselect/logsql/logsql.qtpl:24
 	}
-//line app/vlselect/logsql/logsql.qtpl:21
+//line app/vlselect/logsql/logsql.qtpl:24
 	qw422016.N().S(`]`)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 }
 
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 func writevaluesWithHitsJSONArray(qq422016 qtio422016.Writer, values []logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	streamvaluesWithHitsJSONArray(qw422016, values)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 }
 
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 func valuesWithHitsJSONArray(values []logstorage.ValueWithHits) string {
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	writevaluesWithHitsJSONArray(qb422016, values)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 	return qs422016
-//line app/vlselect/logsql/logsql.qtpl:23
+//line app/vlselect/logsql/logsql.qtpl:26
 }
 
-//line app/vlselect/logsql/logsql.qtpl:25
+//line app/vlselect/logsql/logsql.qtpl:28
 func streamvalueWithHitsJSON(qw422016 *qt422016.Writer, v logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:25
+//line app/vlselect/logsql/logsql.qtpl:28
 	qw422016.N().S(`{"value":`)
-//line app/vlselect/logsql/logsql.qtpl:27
+//line app/vlselect/logsql/logsql.qtpl:30
 	qw422016.N().Q(v.Value)
-//line app/vlselect/logsql/logsql.qtpl:27
+//line app/vlselect/logsql/logsql.qtpl:30
 	qw422016.N().S(`,"hits":`)
-//line app/vlselect/logsql/logsql.qtpl:28
+//line app/vlselect/logsql/logsql.qtpl:31
 	qw422016.N().DUL(v.Hits)
-//line app/vlselect/logsql/logsql.qtpl:28
+//line app/vlselect/logsql/logsql.qtpl:31
 	qw422016.N().S(`}`)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 }
 
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 func writevalueWithHitsJSON(qq422016 qtio422016.Writer, v logstorage.ValueWithHits) {
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	streamvalueWithHitsJSON(qw422016, v)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 }
 
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 func valueWithHitsJSON(v logstorage.ValueWithHits) string {
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	writevalueWithHitsJSON(qb422016, v)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
 	return qs422016
-//line app/vlselect/logsql/logsql.qtpl:30
+//line app/vlselect/logsql/logsql.qtpl:33
+}
+
+//line app/vlselect/logsql/logsql.qtpl:35
+func streamdumpQueryTrace(qw422016 *qt422016.Writer, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/logsql.qtpl:36
+	traceJSON := qt.ToJSON()
+
+//line app/vlselect/logsql/logsql.qtpl:37
+	if traceJSON != "" {
+//line app/vlselect/logsql/logsql.qtpl:37
+		qw422016.N().S(`,"trace":`)
+//line app/vlselect/logsql/logsql.qtpl:37
+		qw422016.N().S(traceJSON)
+//line app/vlselect/logsql/logsql.qtpl:37
+	}
+//line app/vlselect/logsql/logsql.qtpl:38
+}
+
+//line app/vlselect/logsql/logsql.qtpl:38
+func writedumpQueryTrace(qq422016 qtio422016.Writer, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/logsql.qtpl:38
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vlselect/logsql/logsql.qtpl:38
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vlselect/logsql/logsql.qtpl:38
+	qt422016.ReleaseWriter(qw422016)
+//line app/vlselect/logsql/logsql.qtpl:38
+}
+
+//line app/vlselect/logsql/logsql.qtpl:38
+func dumpQueryTrace(qt *querytracer.Tracer) string {
+//line app/vlselect/logsql/logsql.qtpl:38
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vlselect/logsql/logsql.qtpl:38
+	writedumpQueryTrace(qb422016, qt)
+//line app/vlselect/logsql/logsql.qtpl:38
+	qs422016 := string(qb422016.B)
+//line app/vlselect/logsql/logsql.qtpl:38
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vlselect/logsql/logsql.qtpl:38
+	return qs422016
+//line app/vlselect/logsql/logsql.qtpl:38
 }
@@ -2,6 +2,8 @@ package logsql
 
 import (
 	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 )
 
 func TestParseExtraFilters_Success(t *testing.T) {
@@ -101,3 +103,34 @@ func TestParseExtraStreamFilters_Failure(t *testing.T) {
 	// excess pipe
 	f(`foo | count()`)
 }
+
+func TestHeatmap(t *testing.T) {
+	hm := newHeatmap("duration")
+
+	add := func(timestamp, buckets string) {
+		t.Helper()
+		if err := hm.addBuckets(timestamp, buckets); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	add("2024-01-01T01:00:00Z", `[{"vmrange":"1.000e+01...1.136e+01","hits":3}]`)
+	add("2024-01-01T00:00:00Z", `[{"vmrange":"2.154e+00...2.448e+00","hits":1},{"vmrange":"1.000e+01...1.136e+01","hits":5}]`)
+	add("2024-01-01T02:00:00Z", `[]`)
+
+	var bb bytesutil.ByteBuffer
+	WriteHeatmapResponse(&bb, hm, nil)
+	resultExpected := `{"field":"duration","timestamps":["2024-01-01T00:00:00Z","2024-01-01T01:00:00Z","2024-01-01T02:00:00Z"],` +
+		`"buckets":[{"vmrange":"2.154e+00...2.448e+00","values":[1,0,0],"total":1},{"vmrange":"1.000e+01...1.136e+01","values":[5,3,0],"total":8}]}`
+	if string(bb.B) != resultExpected {
+		t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", bb.B, resultExpected)
+	}
+
+	// invalid buckets
+	if err := hm.addBuckets("2024-01-01T00:00:00Z", `]`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if err := hm.addBuckets("2024-01-01T00:00:00Z", `[{"hits":3}]`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
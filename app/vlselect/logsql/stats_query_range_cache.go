@@ -0,0 +1,391 @@
+package logsql
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+var (
+	statsQueryRangeCacheSize = flagutil.NewBytes("search.statsQueryRangeCacheSize", 0, "The maximum size of the cache for /select/logsql/stats_query_range results "+
+		"on historical time buckets. This may speed up repeated dashboard queries. The cache is disabled by default; "+
+		"see https://docs.victoriametrics.com/victorialogs/querying/#stats-query-range-cache ; see also -search.statsQueryRangeCacheLag")
+	statsQueryRangeCacheLag = flag.Duration("search.statsQueryRangeCacheLag", 5*time.Minute, "Only time buckets ending before now-search.statsQueryRangeCacheLag "+
+		"are cached in the cache enabled via -search.statsQueryRangeCacheSize. Logs with timestamps older than now-search.statsQueryRangeCacheLag "+
+		"are detected as backfilled data, which invalidates the affected cache entries")
+)
+
+const (
+	// statsQueryRangeCacheMaxEntryAge is the maximum age for the cached entry.
+	//
+	// This limits the staleness of the cached results for logs deleted by retention or sampled during background merges.
+	statsQueryRangeCacheMaxEntryAge = time.Hour
+
+	// statsQueryRangeCacheMaxBuckets is the maximum number of time buckets per query, which can be cached.
+	statsQueryRangeCacheMaxBuckets = 10_000
+
+	// statsQueryRangeCacheBackfillsRetention is the duration for keeping information about the detected backfills.
+	//
+	// The results of queries, which take longer than this duration, aren't cached, since they could miss backfilled logs.
+	statsQueryRangeCacheBackfillsRetention = 5 * time.Minute
+)
+
+// InitStatsQueryRangeCache initializes the cache for /select/logsql/stats_query_range results.
+//
+// It must be called after vlstorage.Init().
+func InitStatsQueryRangeCache() {
+	maxSizeBytes := statsQueryRangeCacheSize.IntN()
+	if maxSizeBytes <= 0 {
+		return
+	}
+	if _, ok := vlstorage.TakeMinIngestedTimestamp(); !ok {
+		logger.Warnf("-search.statsQueryRangeCacheSize is ignored, since the cache can be used only for the local storage; i.e. when -storageNode isn't set")
+		return
+	}
+	statsQueryRangeCacheV = newStatsQueryRangeCache(maxSizeBytes, *statsQueryRangeCacheLag, vlstorage.TakeMinIngestedTimestamp)
+}
+
+// MustStopStatsQueryRangeCache stops the cache initialized via InitStatsQueryRangeCache.
+func MustStopStatsQueryRangeCache() {
+	statsQueryRangeCacheV = nil
+}
+
+var statsQueryRangeCacheV *statsQueryRangeCache
+
+var (
+	statsQueryRangeCacheRequests              = metrics.NewCounter(`vl_cache_requests_total{type="stats_query_range"}`)
+	statsQueryRangeCacheMisses                = metrics.NewCounter(`vl_cache_misses_total{type="stats_query_range"}`)
+	statsQueryRangeCacheBackfillInvalidations = metrics.NewCounter(`vl_cache_backfill_invalidations_total{type="stats_query_range"}`)
+
+	_ = metrics.NewGauge(`vl_cache_entries{type="stats_query_range"}`, func() float64 {
+		entries, _, _ := statsQueryRangeCacheV.stats()
+		return float64(entries)
+	})
+	_ = metrics.NewGauge(`vl_cache_size_bytes{type="stats_query_range"}`, func() float64 {
+		_, sizeBytes, _ := statsQueryRangeCacheV.stats()
+		return float64(sizeBytes)
+	})
+	_ = metrics.NewGauge(`vl_cache_size_max_bytes{type="stats_query_range"}`, func() float64 {
+		_, _, maxSizeBytes := statsQueryRangeCacheV.stats()
+		return float64(maxSizeBytes)
+	})
+)
+
+// statsQueryRangeCache caches stats_query_range results per every time bucket.
+//
+// Only time buckets ending before now-lag are cached, since the logs for these buckets are unlikely to change.
+// The cached buckets are invalidated when logs with timestamps belonging to them are ingested (aka backfilling).
+type statsQueryRangeCache struct {
+	maxSizeBytes int
+	lag          time.Duration
+
+	// takeMinIngestedTimestamp must return the minimum timestamp across logs ingested since the previous call.
+	takeMinIngestedTimestamp func() (int64, bool)
+
+	mu sync.Mutex
+
+	m         map[string]*statsQueryRangeCacheEntry
+	sizeBytes int
+
+	// maxBucketEnd is the maximum bucket end timestamp across entries at m.
+	//
+	// It isn't decreased when entries are deleted from m.
+	maxBucketEnd int64
+
+	// backfills contains backfills detected during the last statsQueryRangeCacheBackfillsRetention.
+	backfills []statsQueryRangeCacheBackfill
+}
+
+type statsQueryRangeCacheEntry struct {
+	// bucketEnd is the end timestamp for the cached time bucket
+	bucketEnd int64
+
+	// series contains series with points for the cached time bucket
+	series []*statsSeries
+
+	// createdAt is the entry creation time in nanoseconds
+	createdAt int64
+
+	sizeBytes int
+}
+
+type statsQueryRangeCacheBackfill struct {
+	// detectedAt is the time in nanoseconds when the backfill has been detected
+	detectedAt int64
+
+	// minTimestamp is the minimum timestamp across the backfilled logs
+	minTimestamp int64
+}
+
+func newStatsQueryRangeCache(maxSizeBytes int, lag time.Duration, takeMinIngestedTimestamp func() (int64, bool)) *statsQueryRangeCache {
+	return &statsQueryRangeCache{
+		maxSizeBytes:             maxSizeBytes,
+		lag:                      lag,
+		takeMinIngestedTimestamp: takeMinIngestedTimestamp,
+
+		m: make(map[string]*statsQueryRangeCacheEntry),
+	}
+}
+
+func (c *statsQueryRangeCache) stats() (int, int, int) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.m), c.sizeBytes, c.maxSizeBytes
+}
+
+// getSeries returns series for [start, end] time range with the given step.
+//
+// keyPrefix must uniquely identify the query, its tenants and the step.
+// fetchSeries must put series for the given time range into m. It is called only for time ranges, which are missing in the cache.
+func (c *statsQueryRangeCache) getSeries(keyPrefix string, step, start, end int64, fetchSeries func(start, end int64, m map[string]*statsSeries) error) (map[string]*statsSeries, error) {
+	requestStart := time.Now().UnixNano()
+
+	c.mu.Lock()
+	c.checkBackfillsLocked(requestStart)
+	c.mu.Unlock()
+
+	m := make(map[string]*statsSeries)
+
+	// Only full time buckets ending before now-lag can be cached.
+	bucketsStart := truncateTimestampToStep(start, step)
+	if bucketsStart < start {
+		bucketsStart += step
+	}
+	bucketsEnd := requestStart - c.lag.Nanoseconds()
+	if end < bucketsEnd {
+		bucketsEnd = end + 1
+	}
+	bucketsCount := int64(0)
+	if bucketsEnd > bucketsStart {
+		bucketsCount = (bucketsEnd - bucketsStart) / step
+	}
+	if bucketsCount <= 0 || bucketsCount > statsQueryRangeCacheMaxBuckets {
+		if err := fetchSeries(start, end, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	entries := make([]*statsQueryRangeCacheEntry, bucketsCount)
+	c.mu.Lock()
+	for i := range entries {
+		key := keyPrefix + strconv.FormatInt(bucketsStart+int64(i)*step, 10)
+		entries[i] = c.getLocked(key, requestStart)
+	}
+	c.mu.Unlock()
+
+	// Fetch series for time ranges missing in the cache.
+	rangeStart := start
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+		bucketStart := bucketsStart + int64(i)*step
+		if rangeStart < bucketStart {
+			if err := fetchSeries(rangeStart, bucketStart-1, m); err != nil {
+				return nil, err
+			}
+		}
+		rangeStart = bucketStart + step
+	}
+	if rangeStart <= end {
+		if err := fetchSeries(rangeStart, end, m); err != nil {
+			return nil, err
+		}
+	}
+
+	// Store the fetched time buckets in the cache.
+	missingEntries := make(map[int64]*statsQueryRangeCacheEntry)
+	for i, e := range entries {
+		if e == nil {
+			bucketStart := bucketsStart + int64(i)*step
+			missingEntries[bucketStart] = &statsQueryRangeCacheEntry{
+				bucketEnd: bucketStart + step - 1,
+			}
+		}
+	}
+	for _, ss := range m {
+		for _, p := range ss.Points {
+			e := missingEntries[truncateTimestampToStep(p.Timestamp, step)]
+			if e == nil {
+				continue
+			}
+			if n := len(e.series); n > 0 && e.series[n-1].key == ss.key {
+				e.series[n-1].Points = append(e.series[n-1].Points, p)
+				continue
+			}
+			e.series = append(e.series, &statsSeries{
+				key:    ss.key,
+				Name:   ss.Name,
+				Labels: ss.Labels,
+				Points: []statsPoint{p},
+			})
+		}
+	}
+	c.setEntries(keyPrefix, missingEntries, requestStart)
+
+	// Merge the cached time buckets with the fetched series.
+	for _, e := range entries {
+		if e == nil {
+			continue
+		}
+		for _, ss := range e.series {
+			dst := m[ss.key]
+			if dst == nil {
+				dst = &statsSeries{
+					key:    ss.key,
+					Name:   ss.Name,
+					Labels: ss.Labels,
+				}
+				m[ss.key] = dst
+			}
+			dst.Points = append(dst.Points, ss.Points...)
+		}
+	}
+
+	return m, nil
+}
+
+func (c *statsQueryRangeCache) getLocked(key string, currentTime int64) *statsQueryRangeCacheEntry {
+	statsQueryRangeCacheRequests.Inc()
+
+	e := c.m[key]
+	if e == nil {
+		statsQueryRangeCacheMisses.Inc()
+		return nil
+	}
+	if currentTime-e.createdAt > statsQueryRangeCacheMaxEntryAge.Nanoseconds() {
+		statsQueryRangeCacheMisses.Inc()
+		c.deleteLocked(key, e)
+		return nil
+	}
+	return e
+}
+
+func (c *statsQueryRangeCache) setEntries(keyPrefix string, entries map[int64]*statsQueryRangeCacheEntry, requestStart int64) {
+	currentTime := time.Now().UnixNano()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkBackfillsLocked(currentTime)
+	if currentTime-requestStart > statsQueryRangeCacheBackfillsRetention.Nanoseconds() {
+		// Cannot verify whether the fetched series miss backfilled logs.
+		return
+	}
+
+	for bucketStart, e := range entries {
+		if c.hasBackfillSinceLocked(requestStart, e.bucketEnd) {
+			// The fetched series may miss the backfilled logs.
+			continue
+		}
+		key := keyPrefix + strconv.FormatInt(bucketStart, 10)
+		e.createdAt = currentTime
+		e.sizeBytes = len(key) + e.getSizeBytes()
+		if e.sizeBytes > c.maxSizeBytes {
+			continue
+		}
+
+		if eOld := c.m[key]; eOld != nil {
+			c.deleteLocked(key, eOld)
+		}
+		c.m[key] = e
+		c.sizeBytes += e.sizeBytes
+		if e.bucketEnd > c.maxBucketEnd {
+			c.maxBucketEnd = e.bucketEnd
+		}
+	}
+
+	// Evict entries if the cache size exceeds the limit.
+	for key, e := range c.m {
+		if c.sizeBytes <= c.maxSizeBytes {
+			break
+		}
+		c.deleteLocked(key, e)
+	}
+}
+
+func (c *statsQueryRangeCache) deleteLocked(key string, e *statsQueryRangeCacheEntry) {
+	delete(c.m, key)
+	c.sizeBytes -= e.sizeBytes
+}
+
+// checkBackfillsLocked invalidates cached entries for time buckets with the logs ingested since the previous call.
+func (c *statsQueryRangeCache) checkBackfillsLocked(currentTime int64) {
+	minTimestamp, ok := c.takeMinIngestedTimestamp()
+	if ok && minTimestamp <= c.maxBucketEnd {
+		for key, e := range c.m {
+			if e.bucketEnd >= minTimestamp {
+				c.deleteLocked(key, e)
+				statsQueryRangeCacheBackfillInvalidations.Inc()
+			}
+		}
+	}
+	if ok && minTimestamp < currentTime-c.lag.Nanoseconds() {
+		c.backfills = append(c.backfills, statsQueryRangeCacheBackfill{
+			detectedAt:   currentTime,
+			minTimestamp: minTimestamp,
+		})
+	}
+
+	// Remove outdated backfills
+	deadline := currentTime - statsQueryRangeCacheBackfillsRetention.Nanoseconds()
+	n := 0
+	for n < len(c.backfills) && c.backfills[n].detectedAt < deadline {
+		n++
+	}
+	c.backfills = append(c.backfills[:0], c.backfills[n:]...)
+}
+
+// hasBackfillSinceLocked returns true if logs with timestamps smaller or equal to bucketEnd were detected after the given startTime.
+func (c *statsQueryRangeCache) hasBackfillSinceLocked(startTime, bucketEnd int64) bool {
+	for _, b := range c.backfills {
+		if b.detectedAt > startTime && b.minTimestamp <= bucketEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *statsQueryRangeCacheEntry) getSizeBytes() int {
+	n := int(unsafe.Sizeof(*e))
+	for _, ss := range e.series {
+		n += int(unsafe.Sizeof(*ss)) + len(ss.key) + len(ss.Name)
+		for _, label := range ss.Labels {
+			n += int(unsafe.Sizeof(label)) + len(label.Name) + len(label.Value)
+		}
+		for _, p := range ss.Points {
+			n += int(unsafe.Sizeof(p)) + len(p.Value)
+		}
+	}
+	return n
+}
+
+func getStatsQueryRangeCacheKeyPrefix(tenantIDs []logstorage.TenantID, q *logstorage.Query, step int64) string {
+	var tenants []byte
+	for i := range tenantIDs {
+		tenants = fmt.Appendf(tenants, "%s,", &tenantIDs[i])
+	}
+	return fmt.Sprintf("%s|%d|%s|", tenants, step, q)
+}
+
+func truncateTimestampToStep(ts, step int64) int64 {
+	r := ts % step
+	if r < 0 {
+		r += step
+	}
+	return ts - r
+}
@@ -0,0 +1,130 @@
+package logsql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStatsQueryRangeCache(t *testing.T) {
+	const step = int64(time.Hour)
+	const lag = 5 * time.Minute
+
+	minIngestedTimestamp := int64(math.MaxInt64)
+	takeMinIngestedTimestamp := func() (int64, bool) {
+		ts := minIngestedTimestamp
+		minIngestedTimestamp = math.MaxInt64
+		return ts, true
+	}
+	c := newStatsQueryRangeCache(1024*1024, lag, takeMinIngestedTimestamp)
+
+	// fetchSeries returns a single series with a point per every time bucket on the given time range.
+	// The point value is the number of nanoseconds covered by the time range at the time bucket.
+	var fetchedRanges [][2]int64
+	fetchSeries := func(start, end int64, m map[string]*statsSeries) error {
+		fetchedRanges = append(fetchedRanges, [2]int64{start, end})
+		ss := m["rows"]
+		if ss == nil {
+			ss = &statsSeries{
+				key:  "rows",
+				Name: "rows",
+			}
+			m["rows"] = ss
+		}
+		for bucketStart := truncateTimestampToStep(start, step); bucketStart <= end; bucketStart += step {
+			n := min(end, bucketStart+step-1) - max(start, bucketStart) + 1
+			ss.Points = append(ss.Points, statsPoint{
+				Timestamp: bucketStart,
+				Value:     fmt.Sprintf("%d", n),
+			})
+		}
+		return nil
+	}
+
+	f := func(start, end int64, fetchedRangesExpected [][2]int64) {
+		t.Helper()
+
+		fetchedRanges = nil
+		m, err := c.getSeries("foo|", step, start, end, fetchSeries)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(fetchedRanges, fetchedRangesExpected) {
+			t.Fatalf("unexpected fetched ranges\ngot\n%v\nwant\n%v", fetchedRanges, fetchedRangesExpected)
+		}
+
+		mExpected := make(map[string]*statsSeries)
+		if err := fetchSeries(start, end, mExpected); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, ss := range m {
+			sort.Slice(ss.Points, func(i, j int) bool {
+				return ss.Points[i].Timestamp < ss.Points[j].Timestamp
+			})
+		}
+		if !reflect.DeepEqual(m, mExpected) {
+			t.Fatalf("unexpected series\ngot\n%v\nwant\n%v", m["rows"], mExpected["rows"])
+		}
+	}
+
+	now := time.Now().UnixNano()
+	bucketsStart := truncateTimestampToStep(now-lag.Nanoseconds(), step) - 10*step
+
+	// the first request fetches the whole time range
+	start := bucketsStart - step/2
+	end := bucketsStart + 3*step + step/2
+	f(start, end, [][2]int64{{start, end}})
+
+	// the second request fetches only partial time buckets
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 3*step, end}})
+
+	// the request for wider time range fetches only the missing time buckets
+	end = bucketsStart + 5*step
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 3*step, end}})
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 5*step, end}})
+
+	// time buckets ending after now-lag aren't cached
+	end = now
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 5*step, end}})
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 10*step, end}})
+
+	// backfilling invalidates the affected time buckets
+	minIngestedTimestamp = bucketsStart + 2*step + 123
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 2*step, end}})
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 10*step, end}})
+
+	// ingestion of recent logs doesn't invalidate the cached time buckets
+	minIngestedTimestamp = now
+	f(start, end, [][2]int64{{start, bucketsStart - 1}, {bucketsStart + 10*step, end}})
+
+	// the results of requests running during backfilling aren't cached
+	fetchSeriesOrig := fetchSeries
+	fetchSeries = func(start, end int64, m map[string]*statsSeries) error {
+		minIngestedTimestamp = bucketsStart - 3*step
+		return fetchSeriesOrig(start, end, m)
+	}
+	start = bucketsStart - 5*step
+	end = bucketsStart
+	f(start, end, [][2]int64{{start, end}})
+	fetchSeries = fetchSeriesOrig
+	f(start, end, [][2]int64{{bucketsStart - 3*step, end}})
+	f(start, end, [][2]int64{{end, end}})
+
+	// the cache size is limited
+	entries, sizeBytes, maxSizeBytes := c.stats()
+	if entries == 0 || sizeBytes > maxSizeBytes {
+		t.Fatalf("unexpected cache stats; entries=%d, sizeBytes=%d, maxSizeBytes=%d", entries, sizeBytes, maxSizeBytes)
+	}
+	c.maxSizeBytes = 1
+	c.setEntries("bar|", map[int64]*statsQueryRangeCacheEntry{
+		bucketsStart: {
+			bucketEnd: bucketsStart + step - 1,
+		},
+	}, now)
+	if entries, sizeBytes, _ := c.stats(); entries != 0 || sizeBytes != 0 {
+		t.Fatalf("unexpected cache stats after exceeding the size limit; entries=%d, sizeBytes=%d", entries, sizeBytes)
+	}
+}
@@ -1,188 +1,200 @@
 // Code generated by qtc from "stats_query_range_response.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-// StatsQueryRangeResponse generates response for /select/logsql/stats_query_range
-
-//line app/vlselect/logsql/stats_query_range_response.qtpl:4
+//line app/vlselect/logsql/stats_query_range_response.qtpl:1
 package logsql
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:4
+//line app/vlselect/logsql/stats_query_range_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// StatsQueryRangeResponse generates response for /select/logsql/stats_query_range
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:4
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:4
-func StreamStatsQueryRangeResponse(qw422016 *qt422016.Writer, rows []*statsSeries) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:4
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
+func StreamStatsQueryRangeResponse(qw422016 *qt422016.Writer, rows []*statsSeries, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:8
 	qw422016.N().S(`{"status":"success","data":{"resultType":"matrix","result":[`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:10
+//line app/vlselect/logsql/stats_query_range_response.qtpl:14
 	if len(rows) > 0 {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:11
+//line app/vlselect/logsql/stats_query_range_response.qtpl:15
 		streamformatStatsSeries(qw422016, rows[0])
-//line app/vlselect/logsql/stats_query_range_response.qtpl:12
+//line app/vlselect/logsql/stats_query_range_response.qtpl:16
 		rows = rows[1:]
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:13
+//line app/vlselect/logsql/stats_query_range_response.qtpl:17
 		for i := range rows {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:13
+//line app/vlselect/logsql/stats_query_range_response.qtpl:17
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:14
+//line app/vlselect/logsql/stats_query_range_response.qtpl:18
 			streamformatStatsSeries(qw422016, rows[i])
-//line app/vlselect/logsql/stats_query_range_response.qtpl:15
+//line app/vlselect/logsql/stats_query_range_response.qtpl:19
 		}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:16
+//line app/vlselect/logsql/stats_query_range_response.qtpl:20
 	}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:16
-	qw422016.N().S(`]}}`)
 //line app/vlselect/logsql/stats_query_range_response.qtpl:20
+	qw422016.N().S(`]}`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:23
+	qt.Done()
+
+//line app/vlselect/logsql/stats_query_range_response.qtpl:24
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:24
+	qw422016.N().S(`}`)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
-func WriteStatsQueryRangeResponse(qq422016 qtio422016.Writer, rows []*statsSeries) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+func WriteStatsQueryRangeResponse(qq422016 qtio422016.Writer, rows []*statsSeries, qt *querytracer.Tracer) {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
-	StreamStatsQueryRangeResponse(qw422016, rows)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+	StreamStatsQueryRangeResponse(qw422016, rows, qt)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
-func StatsQueryRangeResponse(rows []*statsSeries) string {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+func StatsQueryRangeResponse(rows []*statsSeries, qt *querytracer.Tracer) string {
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
-	WriteStatsQueryRangeResponse(qb422016, rows)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+	WriteStatsQueryRangeResponse(qb422016, rows, qt)
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 	return qs422016
-//line app/vlselect/logsql/stats_query_range_response.qtpl:20
+//line app/vlselect/logsql/stats_query_range_response.qtpl:26
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:22
+//line app/vlselect/logsql/stats_query_range_response.qtpl:28
 func streamformatStatsSeries(qw422016 *qt422016.Writer, ss *statsSeries) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:22
+//line app/vlselect/logsql/stats_query_range_response.qtpl:28
 	qw422016.N().S(`{"metric":{"__name__":`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:25
+//line app/vlselect/logsql/stats_query_range_response.qtpl:31
 	qw422016.N().Q(ss.Name)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:26
+//line app/vlselect/logsql/stats_query_range_response.qtpl:32
 	if len(ss.Labels) > 0 {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:27
+//line app/vlselect/logsql/stats_query_range_response.qtpl:33
 		for _, label := range ss.Labels {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:27
+//line app/vlselect/logsql/stats_query_range_response.qtpl:33
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:28
+//line app/vlselect/logsql/stats_query_range_response.qtpl:34
 			qw422016.N().Q(label.Name)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:28
+//line app/vlselect/logsql/stats_query_range_response.qtpl:34
 			qw422016.N().S(`:`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:28
+//line app/vlselect/logsql/stats_query_range_response.qtpl:34
 			qw422016.N().Q(label.Value)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:29
+//line app/vlselect/logsql/stats_query_range_response.qtpl:35
 		}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+//line app/vlselect/logsql/stats_query_range_response.qtpl:36
 	}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:30
+//line app/vlselect/logsql/stats_query_range_response.qtpl:36
 	qw422016.N().S(`},"values":[`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:33
+//line app/vlselect/logsql/stats_query_range_response.qtpl:39
 	points := ss.Points
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:34
+//line app/vlselect/logsql/stats_query_range_response.qtpl:40
 	if len(points) > 0 {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:35
+//line app/vlselect/logsql/stats_query_range_response.qtpl:41
 		streamformatStatsPoint(qw422016, &points[0])
-//line app/vlselect/logsql/stats_query_range_response.qtpl:36
+//line app/vlselect/logsql/stats_query_range_response.qtpl:42
 		points = points[1:]
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:37
+//line app/vlselect/logsql/stats_query_range_response.qtpl:43
 		for i := range points {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:37
+//line app/vlselect/logsql/stats_query_range_response.qtpl:43
 			qw422016.N().S(`,`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:38
+//line app/vlselect/logsql/stats_query_range_response.qtpl:44
 			streamformatStatsPoint(qw422016, &points[i])
-//line app/vlselect/logsql/stats_query_range_response.qtpl:39
+//line app/vlselect/logsql/stats_query_range_response.qtpl:45
 		}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:40
+//line app/vlselect/logsql/stats_query_range_response.qtpl:46
 	}
-//line app/vlselect/logsql/stats_query_range_response.qtpl:40
+//line app/vlselect/logsql/stats_query_range_response.qtpl:46
 	qw422016.N().S(`]}`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 func writeformatStatsSeries(qq422016 qtio422016.Writer, ss *statsSeries) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	streamformatStatsSeries(qw422016, ss)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 func formatStatsSeries(ss *statsSeries) string {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	writeformatStatsSeries(qb422016, ss)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 	return qs422016
-//line app/vlselect/logsql/stats_query_range_response.qtpl:43
+//line app/vlselect/logsql/stats_query_range_response.qtpl:49
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:45
+//line app/vlselect/logsql/stats_query_range_response.qtpl:51
 func streamformatStatsPoint(qw422016 *qt422016.Writer, p *statsPoint) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:45
+//line app/vlselect/logsql/stats_query_range_response.qtpl:51
 	qw422016.N().S(`[`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:47
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
 	qw422016.N().F(float64(p.Timestamp) / 1e9)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:47
+//line app/vlselect/logsql/stats_query_range_response.qtpl:53
 	qw422016.N().S(`,`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:48
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
 	qw422016.N().Q(p.Value)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:48
+//line app/vlselect/logsql/stats_query_range_response.qtpl:54
 	qw422016.N().S(`]`)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 func writeformatStatsPoint(qq422016 qtio422016.Writer, p *statsPoint) {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	streamformatStatsPoint(qw422016, p)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	qt422016.ReleaseWriter(qw422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 }
 
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 func formatStatsPoint(p *statsPoint) string {
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	writeformatStatsPoint(qb422016, p)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	qs422016 := string(qb422016.B)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 	return qs422016
-//line app/vlselect/logsql/stats_query_range_response.qtpl:50
+//line app/vlselect/logsql/stats_query_range_response.qtpl:56
 }
@@ -0,0 +1,147 @@
+package loki
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// logQLToLogsQL translates LogQL log query s into LogsQL query.
+//
+// Only stream selector with optional line filters is supported, e.g. `{app="nginx",env=~"prod|dev"} |= "error" != "timeout"`.
+// See https://grafana.com/docs/loki/latest/query/log_queries/
+func logQLToLogsQL(s string) (string, error) {
+	sOrig := s
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return "", fmt.Errorf("unsupported LogQL query %q; only log queries starting with stream selector are supported", sOrig)
+	}
+
+	var filters []string
+	selector, tail, err := parseStreamSelector(s)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse stream selector in %q: %w", sOrig, err)
+	}
+	if selector != "{}" {
+		filters = append(filters, selector)
+	}
+
+	for {
+		tail = strings.TrimSpace(tail)
+		if tail == "" {
+			break
+		}
+		if len(tail) < 2 {
+			return "", fmt.Errorf("unexpected tail %q in %q", tail, sOrig)
+		}
+		op := tail[:2]
+		switch op {
+		case "|=", "!=", "|~", "!~":
+		default:
+			return "", fmt.Errorf("unsupported LogQL expression %q in %q; only line filters |=, !=, |~ and !~ are supported", tail, sOrig)
+		}
+		v, tailNext, err := parseQuotedString(tail[2:])
+		if err != nil {
+			return "", fmt.Errorf("cannot parse value for %q line filter in %q: %w", op, sOrig, err)
+		}
+		tail = tailNext
+
+		if op == "|=" && v == "" {
+			// `|= ""` matches all the lines. It is frequently used by Grafana when the search box is empty.
+			continue
+		}
+		if op == "|=" || op == "!=" {
+			v = regexp.QuoteMeta(v)
+		}
+		if _, err := regexp.Compile(v); err != nil {
+			return "", fmt.Errorf("invalid regexp %q in %q: %w", v, sOrig, err)
+		}
+		if op[0] == '!' {
+			filters = append(filters, "!~"+strconv.Quote(v))
+		} else {
+			filters = append(filters, "~"+strconv.Quote(v))
+		}
+	}
+
+	if len(filters) == 0 {
+		return "*", nil
+	}
+	return strings.Join(filters, " "), nil
+}
+
+// parseStreamSelector parses LogQL stream selector at the start of s and returns it in LogsQL format together with the remaining tail.
+func parseStreamSelector(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "{") {
+		return "", s, fmt.Errorf("missing '{'")
+	}
+	s = s[1:]
+
+	var matchers []string
+	for {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "}") {
+			s = s[1:]
+			break
+		}
+		if len(matchers) > 0 {
+			if !strings.HasPrefix(s, ",") {
+				return "", s, fmt.Errorf("missing ',' or '}' in front of %q", s)
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+
+		n := 0
+		for n < len(s) && isLabelNameChar(s[n]) {
+			n++
+		}
+		if n == 0 {
+			return "", s, fmt.Errorf("missing label name in front of %q", s)
+		}
+		name := s[:n]
+		s = strings.TrimSpace(s[n:])
+
+		var op string
+		switch {
+		case strings.HasPrefix(s, "=~"), strings.HasPrefix(s, "!~"), strings.HasPrefix(s, "!="):
+			op = s[:2]
+		case strings.HasPrefix(s, "="):
+			op = "="
+		default:
+			return "", s, fmt.Errorf("missing matching operator after label %q", name)
+		}
+		v, tail, err := parseQuotedString(s[len(op):])
+		if err != nil {
+			return "", s, fmt.Errorf("cannot parse value for label %q: %w", name, err)
+		}
+		s = tail
+		if op == "=~" || op == "!~" {
+			if _, err := regexp.Compile(v); err != nil {
+				return "", s, fmt.Errorf("invalid regexp %q for label %q: %w", v, name, err)
+			}
+		}
+		matchers = append(matchers, name+op+strconv.Quote(v))
+	}
+	return "{" + strings.Join(matchers, ",") + "}", s, nil
+}
+
+// parseQuotedString parses double-quoted or backtick-quoted string at the start of s and returns the unquoted string and the remaining tail.
+func parseQuotedString(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 || (s[0] != '"' && s[0] != '`') {
+		return "", s, fmt.Errorf("missing quoted string in front of %q", s)
+	}
+	qs, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", s, fmt.Errorf("cannot parse quoted string in front of %q: %w", s, err)
+	}
+	v, err := strconv.Unquote(qs)
+	if err != nil {
+		return "", s, fmt.Errorf("cannot unquote %s: %w", qs, err)
+	}
+	return v, s[len(qs):], nil
+}
+
+func isLabelNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
@@ -0,0 +1,85 @@
+package loki
+
+import (
+	"testing"
+)
+
+func TestLogQLToLogsQL_Success(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+
+		result, err := logQLToLogsQL(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q\ngot\n%s\nwant\n%s", s, result, resultExpected)
+		}
+	}
+
+	// stream selectors
+	f(`{}`, `*`)
+	f(` { } `, `*`)
+	f(`{app="nginx"}`, `{app="nginx"}`)
+	f(`{app = "nginx", env=~"prod|dev", host!="foo", job!~"bar.*"}`, `{app="nginx",env=~"prod|dev",host!="foo",job!~"bar.*"}`)
+	f("{app=`ngi\"nx`}", `{app="ngi\"nx"}`)
+	f(`{k8s.namespace="default"}`, `{k8s.namespace="default"}`)
+
+	// line filters
+	f(`{app="nginx"} |= ""`, `{app="nginx"}`)
+	f(`{app="nginx"} |= "error"`, `{app="nginx"} ~"error"`)
+	f(`{app="nginx"} |= "a.b" != "timeout"`, `{app="nginx"} ~"a\\.b" !~"timeout"`)
+	f(`{app="nginx"} |~ "err(or)?" !~ "GET|POST"`, `{app="nginx"} ~"err(or)?" !~"GET|POST"`)
+	f("{} |~ `\\d+`", `~"\\d+"`)
+}
+
+func TestLogQLToLogsQL_Failure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		result, err := logQLToLogsQL(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q; got %q", s, result)
+		}
+	}
+
+	f(``)
+	f(`foo`)
+	f(`rate({app="nginx"}[5m])`)
+
+	// invalid stream selectors
+	f(`{`)
+	f(`{app}`)
+	f(`{app=nginx}`)
+	f(`{app="nginx" env="prod"}`)
+	f(`{app=~"("}`)
+	f(`{="nginx"}`)
+
+	// unsupported pipes
+	f(`{app="nginx"} | json`)
+	f(`{app="nginx"} |= "error" | logfmt`)
+
+	// invalid line filters
+	f(`{app="nginx"} |=`)
+	f(`{app="nginx"} |= error`)
+	f(`{app="nginx"} |~ "("`)
+	f(`{app="nginx"} |= "error`)
+}
+
+func TestParseStreamLabels(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+
+		labels := parseStreamLabels(s)
+		result := marshalLabels(labels)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %s; want %s", s, result, resultExpected)
+		}
+	}
+
+	f(``, ``)
+	f(`{}`, ``)
+	f(`{app="nginx"}`, `app"nginx"`)
+	f(`{app="nginx",msg="a=\"b\",c"}`, `app"nginx"msg"a=\"b\",c"`)
+	f(`{app="nginx",broken}`, `app"nginx"`)
+}
@@ -0,0 +1,358 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
+)
+
+var (
+	lokiQueryRangeRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/loki/api/v1/query_range"}`)
+	lokiQueryRangeDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/loki/api/v1/query_range"}`)
+
+	lokiLabelsRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/loki/api/v1/labels"}`)
+	lokiLabelsDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/loki/api/v1/labels"}`)
+
+	lokiLabelValuesRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/loki/api/v1/label/{}/values"}`)
+	lokiLabelValuesDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/loki/api/v1/label/{}/values"}`)
+)
+
+// RequestHandler processes Grafana Loki-compatible query requests.
+//
+// path must have `/select/loki` prefix stripped.
+//
+// See https://docs.victoriametrics.com/victorialogs/querying/#loki-compatible-api
+func RequestHandler(ctx context.Context, path string, w http.ResponseWriter, r *http.Request) bool {
+	startTime := time.Now()
+	switch {
+	case path == "/api/v1/query_range":
+		lokiQueryRangeRequests.Inc()
+		processQueryRangeRequest(ctx, w, r)
+		lokiQueryRangeDuration.UpdateDuration(startTime)
+		return true
+	case path == "/api/v1/labels":
+		lokiLabelsRequests.Inc()
+		processLabelsRequest(ctx, w, r)
+		lokiLabelsDuration.UpdateDuration(startTime)
+		return true
+	case strings.HasPrefix(path, "/api/v1/label/") && strings.HasSuffix(path, "/values"):
+		lokiLabelValuesRequests.Inc()
+		labelName := strings.TrimSuffix(strings.TrimPrefix(path, "/api/v1/label/"), "/values")
+		processLabelValuesRequest(ctx, w, r, labelName)
+		lokiLabelValuesDuration.UpdateDuration(startTime)
+		return true
+	default:
+		return false
+	}
+}
+
+// lokiStream is a stream in /loki/api/v1/query_range response.
+type lokiStream struct {
+	labels  []logstorage.Field
+	entries []lokiEntry
+}
+
+// lokiEntry is a log entry in /loki/api/v1/query_range response.
+type lokiEntry struct {
+	// timestamp is the unix timestamp in nanoseconds.
+	timestamp string
+
+	// line is the log message.
+	line string
+
+	// timestampNsecs is used for sorting entries.
+	timestampNsecs int64
+}
+
+// processQueryRangeRequest processes /loki/api/v1/query_range request.
+//
+// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-logs-within-a-range-of-time
+func processQueryRangeRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	tenantIDs, start, end, err := parseCommonArgs(r, time.Hour)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	limit, err := httputil.GetInt(r, "limit")
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	if limit <= 0 {
+		// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-logs-within-a-range-of-time
+		limit = 100
+	}
+
+	sortOrder := "desc"
+	switch direction := r.FormValue("direction"); direction {
+	case "", "backward":
+	case "forward":
+		sortOrder = "asc"
+	default:
+		httpserver.Errorf(w, r, "unsupported direction=%q; supported values: backward, forward", direction)
+		return
+	}
+
+	logQL := r.FormValue("query")
+	qStr, err := logQLToLogsQL(logQL)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	qStr = fmt.Sprintf("%s | fields _time, _stream, _msg | sort by (_time %s) limit %d", qStr, sortOrder, limit)
+	q, err := logstorage.ParseQueryAtTimestamp(qStr, end)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot parse query [%s] obtained from LogQL query %q: %s", qStr, logQL, err)
+		return
+	}
+	q.AddTimeFilter(start, end)
+
+	var streamsLock sync.Mutex
+	streams := make(map[string]*lokiStream)
+	writeBlock := func(_ uint, db *logstorage.DataBlock) {
+		timestamps, ok := db.GetTimestamps()
+		if !ok {
+			return
+		}
+		var streamValues, msgValues []string
+		for _, c := range db.Columns {
+			switch c.Name {
+			case "_stream":
+				streamValues = c.Values
+			case "_msg":
+				msgValues = c.Values
+			}
+		}
+
+		streamsLock.Lock()
+		defer streamsLock.Unlock()
+		for i, timestampStr := range timestamps {
+			timestamp, ok := logstorage.TryParseTimestampRFC3339Nano(timestampStr)
+			if !ok {
+				continue
+			}
+			streamStr := ""
+			if streamValues != nil {
+				streamStr = streamValues[i]
+			}
+			s := streams[streamStr]
+			if s == nil {
+				s = &lokiStream{
+					labels: parseStreamLabels(streamStr),
+				}
+				streams[strings.Clone(streamStr)] = s
+			}
+			line := ""
+			if msgValues != nil {
+				line = strings.Clone(msgValues[i])
+			}
+			s.entries = append(s.entries, lokiEntry{
+				timestamp:      strconv.FormatInt(timestamp, 10),
+				line:           line,
+				timestampNsecs: timestamp,
+			})
+		}
+	}
+	if err := vlstorage.RunQuery(ctx, nil, tenantIDs, q, writeBlock); err != nil {
+		httpserver.Errorf(w, r, "cannot execute query [%s]: %s", q, err)
+		return
+	}
+
+	result := make([]*lokiStream, 0, len(streams))
+	for _, s := range streams {
+		sort.Slice(s.entries, func(i, j int) bool {
+			if sortOrder == "asc" {
+				return s.entries[i].timestampNsecs < s.entries[j].timestampNsecs
+			}
+			return s.entries[i].timestampNsecs > s.entries[j].timestampNsecs
+		})
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return marshalLabels(result[i].labels) < marshalLabels(result[j].labels)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	WriteQueryRangeResponse(w, result)
+}
+
+// processLabelsRequest processes /loki/api/v1/labels request.
+//
+// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-labels
+func processLabelsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	q, tenantIDs, err := parseLabelsArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	names, err := vlstorage.GetStreamFieldNames(ctx, nil, tenantIDs, q)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain stream field names: %s", err)
+		return
+	}
+	sortValues(names)
+
+	w.Header().Set("Content-Type", "application/json")
+	WriteLabelsResponse(w, names)
+}
+
+// processLabelValuesRequest processes /loki/api/v1/label/<labelName>/values request.
+//
+// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-label-values
+func processLabelValuesRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, labelName string) {
+	q, tenantIDs, err := parseLabelsArgs(r)
+	if err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+
+	values, err := vlstorage.GetStreamFieldValues(ctx, nil, tenantIDs, q, labelName, 0)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot obtain values for stream field %q: %s", labelName, err)
+		return
+	}
+	sortValues(values)
+
+	w.Header().Set("Content-Type", "application/json")
+	WriteLabelsResponse(w, values)
+}
+
+func parseLabelsArgs(r *http.Request) (*logstorage.Query, []logstorage.TenantID, error) {
+	// Loki uses 6 hours lookback for labels by default.
+	// See https://grafana.com/docs/loki/latest/reference/loki-http-api/#query-labels
+	tenantIDs, start, end, err := parseCommonArgs(r, 6*time.Hour)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qStr := "*"
+	if logQL := r.FormValue("query"); logQL != "" {
+		qStr, err = logQLToLogsQL(logQL)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	q, err := logstorage.ParseQueryAtTimestamp(qStr, end)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse query [%s]: %w", qStr, err)
+	}
+	q.AddTimeFilter(start, end)
+	return q, tenantIDs, nil
+}
+
+// parseCommonArgs returns tenantIDs and [start, end] time range in nanoseconds from r.
+//
+// The time range defaults to the lookback duration until the current time.
+func parseCommonArgs(r *http.Request, lookback time.Duration) ([]logstorage.TenantID, int64, int64, error) {
+	tenantID, err := getTenantID(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	currentTimestamp := time.Now().UnixNano()
+	end := currentTimestamp
+	if s := r.FormValue("end"); s != "" {
+		end, err = timeutil.ParseTimeAt(s, currentTimestamp)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("cannot parse end=%q: %w", s, err)
+		}
+	}
+	start := end - lookback.Nanoseconds()
+	if s := r.FormValue("since"); s != "" {
+		d, err := timeutil.ParseDuration(s)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("cannot parse since=%q: %w", s, err)
+		}
+		start = end - d.Nanoseconds()
+	}
+	if s := r.FormValue("start"); s != "" {
+		start, err = timeutil.ParseTimeAt(s, currentTimestamp)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("cannot parse start=%q: %w", s, err)
+		}
+	}
+	if start > end {
+		return nil, 0, 0, fmt.Errorf("start=%d cannot exceed end=%d", start, end)
+	}
+	return []logstorage.TenantID{tenantID}, start, end, nil
+}
+
+// getTenantID returns tenantID from r.
+//
+// Loki clients pass the tenant via X-Scope-OrgID header, so it is used if AccountID and ProjectID headers are missing.
+func getTenantID(r *http.Request) (logstorage.TenantID, error) {
+	tenantID, err := logstorage.GetTenantIDFromRequest(r)
+	if err != nil {
+		return tenantID, fmt.Errorf("cannot obtain tenantID: %w", err)
+	}
+	if tenantID.AccountID == 0 && tenantID.ProjectID == 0 {
+		if org := r.Header.Get("X-Scope-OrgID"); org != "" {
+			tenantID, err = logstorage.ParseTenantID(org)
+			if err != nil {
+				return tenantID, fmt.Errorf("cannot parse X-Scope-OrgID header: %w", err)
+			}
+		}
+	}
+	return tenantID, nil
+}
+
+// parseStreamLabels parses labels from _stream field value in the form {name1="value1",...,nameN="valueN"}.
+//
+// Invalid labels are skipped.
+func parseStreamLabels(s string) []logstorage.Field {
+	s = strings.TrimPrefix(s, "{")
+	s = strings.TrimSuffix(s, "}")
+
+	var labels []logstorage.Field
+	for s != "" {
+		n := strings.Index(s, `="`)
+		if n < 0 {
+			break
+		}
+		name := strings.TrimLeft(s[:n], ",")
+		qs, err := strconv.QuotedPrefix(s[n+1:])
+		if err != nil {
+			break
+		}
+		value, err := strconv.Unquote(qs)
+		if err != nil {
+			break
+		}
+		labels = append(labels, logstorage.Field{
+			Name:  strings.Clone(name),
+			Value: value,
+		})
+		s = s[n+1+len(qs):]
+	}
+	return labels
+}
+
+func marshalLabels(labels []logstorage.Field) string {
+	var b []byte
+	for _, label := range labels {
+		b = append(b, label.Name...)
+		b = strconv.AppendQuote(b, label.Value)
+	}
+	return string(b)
+}
+
+func sortValues(values []logstorage.ValueWithHits) {
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Value < values[j].Value
+	})
+}
@@ -9,8 +9,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/elasticsearch"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/internalselect"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/logmetrics"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/logsql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlselect/loki"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
@@ -44,10 +47,14 @@ func getDefaultMaxConcurrentRequests() int {
 // Init initializes vlselect
 func Init() {
 	concurrencyLimitCh = make(chan struct{}, *maxConcurrentRequests)
+	logsql.InitStatsQueryRangeCache()
+	logmetrics.Init()
 }
 
 // Stop stops vlselect
 func Stop() {
+	logmetrics.Stop()
+	logsql.MustStopStatsQueryRangeCache()
 }
 
 var concurrencyLimitCh chan struct{}
@@ -197,6 +204,12 @@ func decRequestConcurrency() {
 
 func processSelectRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, path string) bool {
 	httpserver.EnableCORS(w, r)
+	if strings.HasPrefix(path, "/select/loki/") {
+		return loki.RequestHandler(ctx, strings.TrimPrefix(path, "/select/loki"), w, r)
+	}
+	if strings.HasPrefix(path, "/select/elasticsearch/") {
+		return elasticsearch.RequestHandler(ctx, strings.TrimPrefix(path, "/select/elasticsearch"), w, r)
+	}
 	startTime := time.Now()
 	switch path {
 	case "/select/logsql/facets":
@@ -209,11 +222,21 @@ func processSelectRequest(ctx context.Context, w http.ResponseWriter, r *http.Re
 		logsql.ProcessFieldNamesRequest(ctx, w, r)
 		logsqlFieldNamesDuration.UpdateDuration(startTime)
 		return true
+	case "/select/logsql/field_stats":
+		logsqlFieldStatsRequests.Inc()
+		logsql.ProcessFieldStatsRequest(ctx, w, r)
+		logsqlFieldStatsDuration.UpdateDuration(startTime)
+		return true
 	case "/select/logsql/field_values":
 		logsqlFieldValuesRequests.Inc()
 		logsql.ProcessFieldValuesRequest(ctx, w, r)
 		logsqlFieldValuesDuration.UpdateDuration(startTime)
 		return true
+	case "/select/logsql/heatmap":
+		logsqlHeatmapRequests.Inc()
+		logsql.ProcessHeatmapRequest(ctx, w, r)
+		logsqlHeatmapDuration.UpdateDuration(startTime)
+		return true
 	case "/select/logsql/hits":
 		logsqlHitsRequests.Inc()
 		logsql.ProcessHitsRequest(ctx, w, r)
@@ -279,9 +302,15 @@ var (
 	logsqlFieldNamesRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/field_names"}`)
 	logsqlFieldNamesDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/logsql/field_names"}`)
 
+	logsqlFieldStatsRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/field_stats"}`)
+	logsqlFieldStatsDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/logsql/field_stats"}`)
+
 	logsqlFieldValuesRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/field_values"}`)
 	logsqlFieldValuesDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/logsql/field_values"}`)
 
+	logsqlHeatmapRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/heatmap"}`)
+	logsqlHeatmapDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/logsql/heatmap"}`)
+
 	logsqlHitsRequests = metrics.NewCounter(`vl_http_requests_total{path="/select/logsql/hits"}`)
 	logsqlHitsDuration = metrics.NewSummary(`vl_http_request_duration_seconds{path="/select/logsql/hits"}`)
 
@@ -2,22 +2,30 @@ package vlstorage
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/metrics"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netinsert"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlstorage/netselect"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 var (
@@ -40,12 +48,33 @@ var (
 		"see https://docs.victoriametrics.com/victorialogs/data-ingestion/ ; see also -logNewStreams")
 	minFreeDiskSpaceBytes = flagutil.NewBytes("storage.minFreeDiskSpaceBytes", 10e6, "The minimum free disk space at -storageDataPath after which "+
 		"the storage stops accepting new data")
-
-	forceMergeAuthKey = flagutil.NewPassword("forceMergeAuthKey", "authKey, which must be passed in query string to /internal/force_merge pages. It overrides -httpAuth.*")
+	maxInmemoryParts = flag.Int("insert.maxInmemoryParts", 0, "The maximum number of in-memory parts at -storageDataPath, which wait for being merged or flushed to disk. "+
+		"Data ingestion requests are rejected with '429 Too Many Requests' status code and Retry-After header if this limit is exceeded, "+
+		"so clients could retry them later instead of waiting for the overloaded storage. Zero value disables the limit. "+
+		"See https://docs.victoriametrics.com/victorialogs/#backpressure ; see also -insert.maxSmallParts and -insert.retryAfter")
+	maxSmallParts = flag.Int("insert.maxSmallParts", 0, "The maximum number of small parts at -storageDataPath, which wait for being merged into bigger parts. "+
+		"Data ingestion requests are rejected with '429 Too Many Requests' status code and Retry-After header if this limit is exceeded, "+
+		"so clients could retry them later instead of waiting for the overloaded storage. Zero value disables the limit. "+
+		"See https://docs.victoriametrics.com/victorialogs/#backpressure ; see also -insert.maxInmemoryParts and -insert.retryAfter")
+	retryAfter = flag.Duration("insert.retryAfter", 10*time.Second, "The value for Retry-After header in responses for data ingestion requests rejected "+
+		"because of -insert.maxInmemoryParts or -insert.maxSmallParts limits")
+	samplingConfigFile = flag.String("storage.samplingConfig", "", "Optional path to config file with rules for sampling aged log entries during background merges, "+
+		"e.g. for keeping only 10% of debug logs older than 7 days; see https://docs.victoriametrics.com/victorialogs/#log-sampling")
+	encryptionConfigFile = flag.String("storage.encryptionConfig", "", "Optional path to config file with per-tenant keys for AES-GCM encryption of stored log blocks; "+
+		"see https://docs.victoriametrics.com/victorialogs/#encryption-at-rest")
+	bloomFilterAutoTuning = flag.Bool("storage.bloomFilterAutoTuning", false, "Whether to automatically tune the size of bloom filters for new per-day partitions "+
+		"based on the bloom filter stats collected during queries over the previous partitions; "+
+		"see https://docs.victoriametrics.com/victorialogs/#bloom-filter-auto-tuning")
+
+	forceMergeAuthKey     = flagutil.NewPassword("forceMergeAuthKey", "authKey, which must be passed in query string to /internal/force_merge pages. It overrides -httpAuth.*")
+	partitionStatsAuthKey = flagutil.NewPassword("partitionStatsAuthKey", "authKey, which must be passed in query string to /internal/partition_stats pages. It overrides -httpAuth.*")
 
 	storageNodeAddrs = flagutil.NewArrayString("storageNode", "Comma-separated list of TCP addresses for storage nodes to route the ingested logs to and to send select queries to. "+
 		"If the list is empty, then the ingested logs are stored and queried locally from -storageDataPath")
-	insertConcurrency        = flag.Int("insert.concurrency", 2, "The average number of concurrent data ingestion requests, which can be sent to every -storageNode")
+	replicationFactor = flag.Int("replicationFactor", 1, "Replication factor for the ingested logs. The -storageNode list is split into replicationFactor groups of consecutive nodes, "+
+		"and every group holds a full copy of the ingested logs. Queries are sent to a single group and are re-sent to the next group if the group is unavailable. "+
+		"The number of -storageNode items must be divisible by -replicationFactor. See https://docs.victoriametrics.com/victorialogs/cluster/#replication")
+	insertConcurrency        = flag.Int("insert.concurrency", 2, "The maximum number of concurrent data ingestion requests, which can be sent to every -storageNode")
 	insertDisableCompression = flag.Bool("insert.disableCompression", false, "Whether to disable compression when sending the ingested data to -storageNode nodes. "+
 		"Disabled compression reduces CPU usage at the cost of higher network usage")
 	selectDisableCompression = flag.Bool("select.disableCompression", false, "Whether to disable compression for select query responses received from -storageNode nodes. "+
@@ -96,6 +125,14 @@ func initLocalStorage() {
 	if retentionPeriod.Duration() < 24*time.Hour {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
 	}
+	samplingCfg, err := loadSamplingConfig()
+	if err != nil {
+		logger.Fatalf("%s", err)
+	}
+	encryptionCfg, err := loadEncryptionConfig()
+	if err != nil {
+		logger.Fatalf("%s", err)
+	}
 	cfg := &logstorage.StorageConfig{
 		Retention:              retentionPeriod.Duration(),
 		MaxDiskSpaceUsageBytes: maxDiskSpaceUsageBytes.N,
@@ -104,10 +141,14 @@ func initLocalStorage() {
 		LogNewStreams:          *logNewStreams,
 		LogIngestedRows:        *logIngestedRows,
 		MinFreeDiskSpaceBytes:  minFreeDiskSpaceBytes.N,
+		Sampling:               samplingCfg,
+		BloomFilterAutoTuning:  *bloomFilterAutoTuning,
+		Encryption:             encryptionCfg,
 	}
 	logger.Infof("opening storage at -storageDataPath=%s", *storageDataPath)
 	startTime := time.Now()
 	localStorage = logstorage.MustOpenStorage(*storageDataPath, cfg)
+	minIngestedTimestamp.Store(math.MaxInt64)
 
 	var ss logstorage.StorageStats
 	localStorage.UpdateStats(&ss)
@@ -124,11 +165,56 @@ func initLocalStorage() {
 	metrics.RegisterSet(localStorageMetrics)
 }
 
+func loadSamplingConfig() (*logstorage.SamplingConfig, error) {
+	if *samplingConfigFile == "" {
+		return nil, nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*samplingConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -storage.samplingConfig=%q: %w", *samplingConfigFile, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at -storage.samplingConfig=%q: %w", *samplingConfigFile, err)
+	}
+	cfg, err := logstorage.ParseSamplingConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -storage.samplingConfig=%q: %w", *samplingConfigFile, err)
+	}
+	return cfg, nil
+}
+
+func loadEncryptionConfig() (*logstorage.EncryptionConfig, error) {
+	if *encryptionConfigFile == "" {
+		return nil, nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*encryptionConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -storage.encryptionConfig=%q: %w", *encryptionConfigFile, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at -storage.encryptionConfig=%q: %w", *encryptionConfigFile, err)
+	}
+	cfg, err := logstorage.ParseEncryptionConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -storage.encryptionConfig=%q: %w", *encryptionConfigFile, err)
+	}
+	return cfg, nil
+}
+
 func initNetworkStorage() {
 	if netstorageInsert != nil || netstorageSelect != nil {
 		logger.Panicf("BUG: initNetworkStorage() has been already called")
 	}
 
+	if *replicationFactor < 1 {
+		logger.Fatalf("-replicationFactor must be positive; got %d", *replicationFactor)
+	}
+	if len(*storageNodeAddrs)%*replicationFactor != 0 {
+		logger.Fatalf("the number of -storageNode items (%d) must be divisible by -replicationFactor=%d", len(*storageNodeAddrs), *replicationFactor)
+	}
+
 	authCfgs := make([]*promauth.Config, len(*storageNodeAddrs))
 	isTLSs := make([]bool, len(*storageNodeAddrs))
 	for i := range authCfgs {
@@ -137,10 +223,10 @@ func initNetworkStorage() {
 	}
 
 	logger.Infof("starting insert service for nodes %s", *storageNodeAddrs)
-	netstorageInsert = netinsert.NewStorage(*storageNodeAddrs, authCfgs, isTLSs, *insertConcurrency, *insertDisableCompression)
+	netstorageInsert = netinsert.NewStorage(*storageNodeAddrs, authCfgs, isTLSs, *insertConcurrency, *insertDisableCompression, *replicationFactor)
 
 	logger.Infof("initializing select service for nodes %s", *storageNodeAddrs)
-	netstorageSelect = netselect.NewStorage(*storageNodeAddrs, authCfgs, isTLSs, *selectDisableCompression)
+	netstorageSelect = netselect.NewStorage(*storageNodeAddrs, authCfgs, isTLSs, *selectDisableCompression, *replicationFactor)
 
 	logger.Infof("initialized all the network services")
 }
@@ -183,6 +269,16 @@ func newAuthConfigForStorageNode(argIdx int) *promauth.Config {
 	return ac
 }
 
+// GetLocalStorageDataPath returns -storageDataPath if vlstorage stores data locally.
+//
+// An empty string is returned if vlstorage stores data at -storageNode.
+func GetLocalStorageDataPath() string {
+	if localStorage == nil {
+		return ""
+	}
+	return *storageDataPath
+}
+
 // Stop stops vlstorage.
 func Stop() {
 	if localStorage != nil {
@@ -203,8 +299,11 @@ func Stop() {
 // RequestHandler is a storage request handler.
 func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	path := r.URL.Path
-	if path == "/internal/force_merge" {
+	switch path {
+	case "/internal/force_merge":
 		return processForceMerge(w, r)
+	case "/internal/partition_stats":
+		return processPartitionStats(w, r)
 	}
 	return false
 }
@@ -232,6 +331,56 @@ func processForceMerge(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// partitionStats is a JSON representation of per-day partition stats returned by /internal/partition_stats
+type partitionStats struct {
+	Name                      string `json:"name"`
+	Rows                      uint64 `json:"rows"`
+	CompressedSizeBytes       uint64 `json:"compressed_size_bytes"`
+	BloomFilterBitsPerItem    int    `json:"bloom_filter_bits_per_item"`
+	BloomFilterChecks         uint64 `json:"bloom_filter_checks"`
+	BloomFilterMisses         uint64 `json:"bloom_filter_misses"`
+	BloomFilterBlocksChecked  uint64 `json:"bloom_filter_blocks_checked"`
+	BloomFilterBlocksPassed   uint64 `json:"bloom_filter_blocks_passed"`
+	BloomFilterFalsePositives uint64 `json:"bloom_filter_false_positives"`
+}
+
+func processPartitionStats(w http.ResponseWriter, r *http.Request) bool {
+	if localStorage == nil {
+		// Partition stats aren't supported by non-local storage
+		return false
+	}
+
+	if !httpserver.CheckAuthFlag(w, r, partitionStatsAuthKey) {
+		return true
+	}
+
+	pis := localStorage.GetPartitionInfos()
+	pss := make([]partitionStats, len(pis))
+	for i := range pis {
+		pi := &pis[i]
+		pss[i] = partitionStats{
+			Name:                      pi.Name,
+			Rows:                      pi.RowsCount(),
+			CompressedSizeBytes:       pi.CompressedInmemorySize + pi.CompressedSmallPartSize + pi.CompressedBigPartSize,
+			BloomFilterBitsPerItem:    pi.BloomFilterBitsPerItem,
+			BloomFilterChecks:         pi.BloomFilterChecks,
+			BloomFilterMisses:         pi.BloomFilterMisses,
+			BloomFilterBlocksChecked:  pi.BloomFilterBlocksChecked,
+			BloomFilterBlocksPassed:   pi.BloomFilterBlocksPassed,
+			BloomFilterFalsePositives: pi.BloomFilterFalsePositives,
+		}
+	}
+	data, err := json.Marshal(map[string]any{
+		"partitions": pss,
+	})
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal partition stats to JSON: %s", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+	return true
+}
+
 // CanWriteData returns non-nil error if it cannot write data to vlstorage
 func CanWriteData() error {
 	if localStorage == nil {
@@ -246,7 +395,68 @@ func CanWriteData() error {
 			StatusCode: http.StatusTooManyRequests,
 		}
 	}
-	return nil
+	return getBackpressureError()
+}
+
+// backpressureErr holds the error returned by getBackpressureError until backpressureErrDeadline.
+//
+// backpressureRejectedRequests is the counter for requests rejected because of backpressureErr.
+var (
+	backpressureErrLock          sync.Mutex
+	backpressureErr              error
+	backpressureRejectedRequests *metrics.Counter
+	backpressureErrDeadline      uint64
+
+	inmemoryPartsRejectedRequests = metrics.NewCounter(`vl_insert_requests_rejected_total{reason="too_many_inmemory_parts"}`)
+	smallPartsRejectedRequests    = metrics.NewCounter(`vl_insert_requests_rejected_total{reason="too_many_small_parts"}`)
+)
+
+// getBackpressureError returns non-nil error if the local storage has too many parts waiting for being merged.
+//
+// See -insert.maxInmemoryParts and -insert.maxSmallParts
+func getBackpressureError() error {
+	if *maxInmemoryParts <= 0 && *maxSmallParts <= 0 {
+		return nil
+	}
+
+	backpressureErrLock.Lock()
+	defer backpressureErrLock.Unlock()
+
+	// Obtaining storage stats is relatively expensive, so update the error at most once per second.
+	currentTime := fasttime.UnixTimestamp()
+	if currentTime >= backpressureErrDeadline {
+		var ss logstorage.StorageStats
+		localStorage.UpdateStats(&ss)
+		backpressureRejectedRequests, backpressureErr = newBackpressureError(&ss, *maxInmemoryParts, *maxSmallParts, *retryAfter)
+		backpressureErrDeadline = currentTime + 1
+	}
+
+	if backpressureErr != nil {
+		backpressureRejectedRequests.Inc()
+	}
+	return backpressureErr
+}
+
+func newBackpressureError(ss *logstorage.StorageStats, maxInmemoryParts, maxSmallParts int, retryAfter time.Duration) (*metrics.Counter, error) {
+	var err error
+	var rejectedRequests *metrics.Counter
+	switch {
+	case maxInmemoryParts > 0 && ss.InmemoryParts > uint64(maxInmemoryParts):
+		err = fmt.Errorf("cannot add rows into storage, since it has %d in-memory parts waiting for being merged, which exceeds -insert.maxInmemoryParts=%d; "+
+			"retry the request later", ss.InmemoryParts, maxInmemoryParts)
+		rejectedRequests = inmemoryPartsRejectedRequests
+	case maxSmallParts > 0 && ss.SmallParts > uint64(maxSmallParts):
+		err = fmt.Errorf("cannot add rows into storage, since it has %d small parts waiting for being merged, which exceeds -insert.maxSmallParts=%d; "+
+			"retry the request later", ss.SmallParts, maxSmallParts)
+		rejectedRequests = smallPartsRejectedRequests
+	default:
+		return nil, nil
+	}
+	return rejectedRequests, &httpserver.ErrorWithStatusCode{
+		Err:        err,
+		StatusCode: http.StatusTooManyRequests,
+		RetryAfter: retryAfter,
+	}
 }
 
 // MustAddRows adds lr to vlstorage
@@ -256,74 +466,127 @@ func MustAddRows(lr *logstorage.LogRows) {
 	if localStorage != nil {
 		// Store lr in the local storage.
 		localStorage.MustAddRows(lr)
+
+		// Update minIngestedTimestamp after the rows become visible for search.
+		updateMinIngestedTimestamp(lr.MinTimestamp())
 	} else {
 		// Store lr across the remote storage nodes.
 		lr.ForEachRow(netstorageInsert.AddRow)
 	}
 }
 
+// minIngestedTimestamp holds the minimum timestamp across logs stored in the local storage since the last TakeMinIngestedTimestamp call.
+var minIngestedTimestamp atomic.Int64
+
+func updateMinIngestedTimestamp(timestamp int64) {
+	for {
+		n := minIngestedTimestamp.Load()
+		if timestamp >= n || minIngestedTimestamp.CompareAndSwap(n, timestamp) {
+			return
+		}
+	}
+}
+
+// TakeMinIngestedTimestamp returns the minimum timestamp across logs stored in the local storage since the previous call.
+//
+// It returns math.MaxInt64 if no logs were stored since the previous call.
+// It returns false if the ingested logs cannot be tracked, since they are stored at remote -storageNode.
+//
+// This allows detecting backfilling of historical data, which may invalidate cached query results.
+func TakeMinIngestedTimestamp() (int64, bool) {
+	if localStorage == nil {
+		return 0, false
+	}
+	return minIngestedTimestamp.Swap(math.MaxInt64), true
+}
+
 // RunQuery runs the given q and calls writeBlock for the returned data blocks
-func RunQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+//
+// Query execution stats are added to qt if it is enabled.
+func RunQuery(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
 	if localStorage != nil {
-		return localStorage.RunQuery(ctx, tenantIDs, q, writeBlock)
+		return localStorage.RunQuery(ctx, qt, tenantIDs, q, writeBlock)
 	}
-	return netstorageSelect.RunQuery(ctx, tenantIDs, q, writeBlock)
+	return netstorageSelect.RunQuery(ctx, qt, tenantIDs, q, writeBlock)
+}
+
+// GetRowsCount returns the number of rows stored for the given tenantIDs.
+//
+// The number is obtained from per-part stats without scanning the stored logs.
+// It returns false if the local storage isn't available, e.g. when the storage is accessed via -storageNode.
+func GetRowsCount(tenantIDs []logstorage.TenantID) (uint64, bool) {
+	if localStorage == nil {
+		return 0, false
+	}
+	return localStorage.GetRowsCount(tenantIDs), true
 }
 
 // GetFieldNames executes q and returns field names seen in results.
-func GetFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetFieldNames(ctx, tenantIDs, q)
+		return localStorage.GetFieldNames(ctx, qt, tenantIDs, q)
 	}
-	return netstorageSelect.GetFieldNames(ctx, tenantIDs, q)
+	return netstorageSelect.GetFieldNames(ctx, qt, tenantIDs, q)
 }
 
 // GetFieldValues executes q and returns unique values for the fieldName seen in results.
 //
 // If limit > 0, then up to limit unique values are returned.
-func GetFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetFieldValues(ctx, tenantIDs, q, fieldName, limit)
+		return localStorage.GetFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 	}
-	return netstorageSelect.GetFieldValues(ctx, tenantIDs, q, fieldName, limit)
+	return netstorageSelect.GetFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 }
 
 // GetStreamFieldNames executes q and returns stream field names seen in results.
-func GetStreamFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetStreamFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetStreamFieldNames(ctx, tenantIDs, q)
+		return localStorage.GetStreamFieldNames(ctx, qt, tenantIDs, q)
 	}
-	return netstorageSelect.GetStreamFieldNames(ctx, tenantIDs, q)
+	return netstorageSelect.GetStreamFieldNames(ctx, qt, tenantIDs, q)
 }
 
 // GetStreamFieldValues executes q and returns stream field values for the given fieldName seen in results.
 //
 // If limit > 0, then up to limit unique stream field values are returned.
-func GetStreamFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetStreamFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetStreamFieldValues(ctx, tenantIDs, q, fieldName, limit)
+		return localStorage.GetStreamFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 	}
-	return netstorageSelect.GetStreamFieldValues(ctx, tenantIDs, q, fieldName, limit)
+	return netstorageSelect.GetStreamFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 }
 
 // GetStreams executes q and returns streams seen in query results.
 //
 // If limit > 0, then up to limit unique streams are returned.
-func GetStreams(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetStreams(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetStreams(ctx, tenantIDs, q, limit)
+		return localStorage.GetStreams(ctx, qt, tenantIDs, q, limit)
 	}
-	return netstorageSelect.GetStreams(ctx, tenantIDs, q, limit)
+	return netstorageSelect.GetStreams(ctx, qt, tenantIDs, q, limit)
 }
 
 // GetStreamIDs executes q and returns streamIDs seen in query results.
 //
 // If limit > 0, then up to limit unique streamIDs are returned.
-func GetStreamIDs(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+//
+// Query execution stats are added to qt if it is enabled.
+func GetStreamIDs(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
 	if localStorage != nil {
-		return localStorage.GetStreamIDs(ctx, tenantIDs, q, limit)
+		return localStorage.GetStreamIDs(ctx, qt, tenantIDs, q, limit)
 	}
-	return netstorageSelect.GetStreamIDs(ctx, tenantIDs, q, limit)
+	return netstorageSelect.GetStreamIDs(ctx, qt, tenantIDs, q, limit)
 }
 
 func writeStorageMetrics(w io.Writer, strg *logstorage.Storage) {
@@ -376,8 +639,16 @@ func writeStorageMetrics(w io.Writer, strg *logstorage.Storage) {
 	metrics.WriteGaugeUint64(w, `vl_uncompressed_data_size_bytes{type="storage/small"}`, ss.UncompressedSmallPartSize)
 	metrics.WriteGaugeUint64(w, `vl_uncompressed_data_size_bytes{type="storage/big"}`, ss.UncompressedBigPartSize)
 
+	metrics.WriteCounterUint64(w, `vl_bloom_filter_checks_total`, ss.BloomFilterChecks)
+	metrics.WriteCounterUint64(w, `vl_bloom_filter_misses_total`, ss.BloomFilterMisses)
+	metrics.WriteCounterUint64(w, `vl_bloom_filter_blocks_checked_total`, ss.BloomFilterBlocksChecked)
+	metrics.WriteCounterUint64(w, `vl_bloom_filter_blocks_passed_total`, ss.BloomFilterBlocksPassed)
+	metrics.WriteCounterUint64(w, `vl_bloom_filter_false_positives_total`, ss.BloomFilterFalsePositives)
+
 	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="too_big_timestamp"}`, ss.RowsDroppedTooBigTimestamp)
 	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="too_small_timestamp"}`, ss.RowsDroppedTooSmallTimestamp)
+	metrics.WriteCounterUint64(w, `vl_rows_dropped_total{reason="sampling"}`, ss.RowsDroppedBySampling)
+	metrics.WriteCounterUint64(w, `vl_encrypted_parts_skipped_total`, ss.EncryptedPartsSkipped)
 }
 
 var activeForceMerges = metrics.NewCounter("vl_active_force_merges")
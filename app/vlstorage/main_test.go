@@ -0,0 +1,59 @@
+package vlstorage
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+)
+
+func TestNewBackpressureError(t *testing.T) {
+	f := func(inmemoryParts, smallParts uint64, maxInmemoryParts, maxSmallParts int, errStrExpected string) {
+		t.Helper()
+
+		var ss logstorage.StorageStats
+		ss.InmemoryParts = inmemoryParts
+		ss.SmallParts = smallParts
+		rejectedRequests, err := newBackpressureError(&ss, maxInmemoryParts, maxSmallParts, 5*time.Second)
+		if errStrExpected == "" {
+			if err != nil || rejectedRequests != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+		if err == nil || rejectedRequests == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !strings.Contains(err.Error(), errStrExpected) {
+			t.Fatalf("missing %q in the returned error %q", errStrExpected, err)
+		}
+		var esc *httpserver.ErrorWithStatusCode
+		if !errors.As(err, &esc) {
+			t.Fatalf("expecting ErrorWithStatusCode; got %T", err)
+		}
+		if esc.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("unexpected status code; got %d; want %d", esc.StatusCode, http.StatusTooManyRequests)
+		}
+		if esc.RetryAfter != 5*time.Second {
+			t.Fatalf("unexpected RetryAfter; got %s; want %s", esc.RetryAfter, 5*time.Second)
+		}
+	}
+
+	// limits are disabled
+	f(100, 1000, 0, 0, "")
+
+	// limits aren't exceeded
+	f(10, 100, 10, 100, "")
+
+	// too many in-memory parts
+	f(11, 100, 10, 100, "-insert.maxInmemoryParts=10")
+	f(11, 1000, 10, 0, "-insert.maxInmemoryParts=10")
+
+	// too many small parts
+	f(10, 101, 10, 100, "-insert.maxSmallParts=100")
+	f(1000, 101, 0, 100, "-insert.maxSmallParts=100")
+}
@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/valyala/fastrand"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -35,12 +36,15 @@ const ProtocolVersion = "v1"
 type Storage struct {
 	sns []*storageNode
 
+	// groups contains storage nodes split into replicationFactor groups.
+	//
+	// Every group holds a full copy of the ingested data.
+	groups [][]*storageNode
+
 	disableCompression bool
 
 	srt *streamRowsTracker
 
-	pendingDataBuffers chan *bytesutil.ByteBuffer
-
 	stopCh chan struct{}
 	wg     sync.WaitGroup
 }
@@ -55,12 +59,23 @@ type storageNode struct {
 	// s is a storage, which holds the given storageNode
 	s *Storage
 
+	// group contains storage nodes from the same replica group as the given storageNode.
+	//
+	// Data blocks, which cannot be sent to the given storageNode, are re-routed only to nodes from the group,
+	// so every group continues holding a full copy of the ingested data.
+	group []*storageNode
+
 	// c is an http client used for sending data blocks to addr.
 	c *http.Client
 
 	// ac is auth config used for setting request headers such as Authorization and Host.
 	ac *promauth.Config
 
+	// pendingDataBuffers contains free buffers for pendingData.
+	//
+	// Every storage node has its own buffers, so an unavailable storage node cannot exhaust buffers for the remaining nodes.
+	pendingDataBuffers chan *bytesutil.ByteBuffer
+
 	// pendingData contains pending data, which must be sent to the storage node at the addr.
 	pendingDataMu        sync.Mutex
 	pendingData          *bytesutil.ByteBuffer
@@ -70,7 +85,7 @@ type storageNode struct {
 	disabledUntil atomic.Uint64
 }
 
-func newStorageNode(s *Storage, addr string, ac *promauth.Config, isTLS bool) *storageNode {
+func newStorageNode(s *Storage, addr string, ac *promauth.Config, isTLS bool, concurrency int) *storageNode {
 	tr := httputil.NewTransport(false, "vlinsert_backend")
 	tr.TLSHandshakeTimeout = 20 * time.Second
 	tr.DisableCompression = true
@@ -89,7 +104,11 @@ func newStorageNode(s *Storage, addr string, ac *promauth.Config, isTLS bool) *s
 		},
 		ac: ac,
 
-		pendingData: &bytesutil.ByteBuffer{},
+		pendingDataBuffers: make(chan *bytesutil.ByteBuffer, concurrency),
+		pendingData:        &bytesutil.ByteBuffer{},
+	}
+	for i := 0; i < cap(sn.pendingDataBuffers); i++ {
+		sn.pendingDataBuffers <- &bytesutil.ByteBuffer{}
 	}
 
 	s.wg.Add(1)
@@ -129,17 +148,7 @@ func (sn *storageNode) flushPendingData() {
 	sn.mustSendInsertRequest(pendingData)
 }
 
-func (sn *storageNode) addRow(r *logstorage.InsertRow) {
-	bb := bbPool.Get()
-	b := bb.B
-
-	b = r.Marshal(b)
-
-	if len(b) > maxInsertBlockSize {
-		logger.Warnf("skipping too long log entry, since its length exceeds %d bytes; the actual log entry length is %d bytes; log entry contents: %s", maxInsertBlockSize, len(b), b)
-		return
-	}
-
+func (sn *storageNode) addData(b []byte) {
 	var pendingData *bytesutil.ByteBuffer
 	sn.pendingDataMu.Lock()
 	if sn.pendingData.Len()+len(b) > maxInsertBlockSize {
@@ -148,9 +157,6 @@ func (sn *storageNode) addRow(r *logstorage.InsertRow) {
 	sn.pendingData.MustWrite(b)
 	sn.pendingDataMu.Unlock()
 
-	bb.B = b
-	bbPool.Put(bb)
-
 	if pendingData != nil {
 		sn.mustSendInsertRequest(pendingData)
 	}
@@ -161,7 +167,7 @@ var bbPool bytesutil.ByteBufferPool
 func (sn *storageNode) grabPendingDataForFlushLocked() *bytesutil.ByteBuffer {
 	sn.pendingDataLastFlush = time.Now()
 	pendingData := sn.pendingData
-	sn.pendingData = <-sn.s.pendingDataBuffers
+	sn.pendingData = <-sn.pendingDataBuffers
 
 	return pendingData
 }
@@ -169,7 +175,7 @@ func (sn *storageNode) grabPendingDataForFlushLocked() *bytesutil.ByteBuffer {
 func (sn *storageNode) mustSendInsertRequest(pendingData *bytesutil.ByteBuffer) {
 	defer func() {
 		pendingData.Reset()
-		sn.s.pendingDataBuffers <- pendingData
+		sn.pendingDataBuffers <- pendingData
 	}()
 
 	err := sn.sendInsertRequest(pendingData)
@@ -180,7 +186,16 @@ func (sn *storageNode) mustSendInsertRequest(pendingData *bytesutil.ByteBuffer)
 	if !errors.Is(err, errTemporarilyDisabled) {
 		logger.Warnf("%s; re-routing the data block to the remaining nodes", err)
 	}
-	for !sn.s.sendInsertRequestToAnyNode(pendingData) {
+	reroutedBlocksTotal.Inc()
+	for !sendInsertRequestToAnyNode(sn.group, pendingData) {
+		if sn.s.hasAvailableGroupExcept(sn.group) {
+			// Drop the data block instead of blocking the ingestion until the group becomes available,
+			// since the remaining replica groups hold a copy of the data block.
+			logger.Errorf("dropping %d bytes of data, since all the storage nodes in the replica group are unavailable; "+
+				"the data is available at the remaining replica groups", pendingData.Len())
+			droppedBlocksTotal.Inc()
+			return
+		}
 		logger.Errorf("cannot send pending data to all storage nodes, since all of them are unavailable; re-trying to send the data in a second")
 
 		t := timerpool.Get(time.Second)
@@ -202,7 +217,7 @@ func (sn *storageNode) sendInsertRequest(pendingData *bytesutil.ByteBuffer) erro
 		return nil
 	}
 
-	if sn.disabledUntil.Load() > fasttime.UnixTimestamp() {
+	if !sn.isAvailable() {
 		return errTemporarilyDisabled
 	}
 
@@ -263,32 +278,64 @@ func (sn *storageNode) getRequestURL(path string) string {
 
 var zstdBufPool bytesutil.ByteBufferPool
 
+var (
+	reroutedBlocksTotal = metrics.NewCounter(`vl_insert_rerouted_blocks_total`)
+	droppedBlocksTotal  = metrics.NewCounter(`vl_insert_dropped_blocks_total`)
+)
+
+// hasAvailableGroupExcept returns true if s contains a replica group other than the given group with at least a single available storage node.
+func (s *Storage) hasAvailableGroupExcept(group []*storageNode) bool {
+	for _, g := range s.groups {
+		if g[0] == group[0] {
+			continue
+		}
+		for _, sn := range g {
+			if sn.isAvailable() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (sn *storageNode) isAvailable() bool {
+	return sn.disabledUntil.Load() <= fasttime.UnixTimestamp()
+}
+
 // NewStorage returns new Storage for the given addrs with the given authCfgs.
 //
-// The concurrency is the average number of concurrent connections per every addr.
+// The concurrency is the maximum number of concurrent connections per every addr.
 //
 // If disableCompression is set, then the data is sent uncompressed to the remote storage.
 //
+// The addrs are split into replicationFactor groups of consecutive addrs. Every ingested log entry is sent to a single node per each group,
+// so every group holds a full copy of the ingested data. The number of addrs must be divisible by replicationFactor.
+//
 // Call MustStop on the returned storage when it is no longer needed.
-func NewStorage(addrs []string, authCfgs []*promauth.Config, isTLSs []bool, concurrency int, disableCompression bool) *Storage {
-	pendingDataBuffers := make(chan *bytesutil.ByteBuffer, concurrency*len(addrs))
-	for i := 0; i < cap(pendingDataBuffers); i++ {
-		pendingDataBuffers <- &bytesutil.ByteBuffer{}
+func NewStorage(addrs []string, authCfgs []*promauth.Config, isTLSs []bool, concurrency int, disableCompression bool, replicationFactor int) *Storage {
+	if replicationFactor <= 0 || len(addrs)%replicationFactor != 0 {
+		logger.Panicf("BUG: the number of addrs=%d must be divisible by replicationFactor=%d", len(addrs), replicationFactor)
 	}
 
 	s := &Storage{
 		disableCompression: disableCompression,
-		pendingDataBuffers: pendingDataBuffers,
 		stopCh:             make(chan struct{}),
 	}
 
 	sns := make([]*storageNode, len(addrs))
 	for i, addr := range addrs {
-		sns[i] = newStorageNode(s, addr, authCfgs[i], isTLSs[i])
+		sns[i] = newStorageNode(s, addr, authCfgs[i], isTLSs[i], concurrency)
 	}
 	s.sns = sns
 
-	s.srt = newStreamRowsTracker(len(sns))
+	s.groups = splitStorageNodesToGroups(sns, replicationFactor)
+	for _, group := range s.groups {
+		for _, sn := range group {
+			sn.group = group
+		}
+	}
+
+	s.srt = newStreamRowsTracker(len(sns) / replicationFactor)
 
 	return s
 }
@@ -298,20 +345,44 @@ func (s *Storage) MustStop() {
 	close(s.stopCh)
 	s.wg.Wait()
 	s.sns = nil
+	s.groups = nil
+}
+
+func splitStorageNodesToGroups(sns []*storageNode, groupsCount int) [][]*storageNode {
+	groupSize := len(sns) / groupsCount
+	groups := make([][]*storageNode, groupsCount)
+	for i := range groups {
+		groups[i] = sns[i*groupSize : (i+1)*groupSize : (i+1)*groupSize]
+	}
+	return groups
 }
 
 // AddRow adds the given log row into s.
+//
+// The row is sent to a single storage node per every replica group.
 func (s *Storage) AddRow(streamHash uint64, r *logstorage.InsertRow) {
+	bb := bbPool.Get()
+	b := r.Marshal(bb.B)
+
+	if len(b) > maxInsertBlockSize {
+		logger.Warnf("skipping too long log entry, since its length exceeds %d bytes; the actual log entry length is %d bytes; log entry contents: %s", maxInsertBlockSize, len(b), b)
+		return
+	}
+
 	idx := s.srt.getNodeIdx(streamHash)
-	sn := s.sns[idx]
-	sn.addRow(r)
+	for _, group := range s.groups {
+		group[idx].addData(b)
+	}
+
+	bb.B = b
+	bbPool.Put(bb)
 }
 
-func (s *Storage) sendInsertRequestToAnyNode(pendingData *bytesutil.ByteBuffer) bool {
-	startIdx := int(fastrand.Uint32n(uint32(len(s.sns))))
-	for i := range s.sns {
-		idx := (startIdx + i) % len(s.sns)
-		sn := s.sns[idx]
+func sendInsertRequestToAnyNode(sns []*storageNode, pendingData *bytesutil.ByteBuffer) bool {
+	startIdx := int(fastrand.Uint32n(uint32(len(sns))))
+	for i := range sns {
+		idx := (startIdx + i) % len(sns)
+		sn := sns[idx]
 		err := sn.sendInsertRequest(pendingData)
 		if err == nil {
 			return true
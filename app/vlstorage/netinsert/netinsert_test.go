@@ -7,6 +7,9 @@ import (
 	"testing"
 
 	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 )
 
 func TestStreamRowsTracker(t *testing.T) {
@@ -55,3 +58,96 @@ func TestStreamRowsTracker(t *testing.T) {
 	nodesCount = 9
 	f(rowsCount, streamsCount, nodesCount)
 }
+
+func TestStorageAddRowReplication(t *testing.T) {
+	f := func(nodesCount, replicationFactor int) {
+		t.Helper()
+
+		addrs := make([]string, nodesCount)
+		authCfgs := make([]*promauth.Config, nodesCount)
+		isTLSs := make([]bool, nodesCount)
+		for i := range addrs {
+			addrs[i] = fmt.Sprintf("127.0.0.%d:1", i+1)
+		}
+		s := NewStorage(addrs, authCfgs, isTLSs, 1, true, replicationFactor)
+		defer s.MustStop()
+
+		if len(s.groups) != replicationFactor {
+			t.Fatalf("unexpected number of replica groups; got %d; want %d", len(s.groups), replicationFactor)
+		}
+
+		r := &logstorage.InsertRow{
+			Timestamp: 123,
+			Fields: []logstorage.Field{
+				{
+					Name:  "_msg",
+					Value: "foo bar",
+				},
+			},
+		}
+		rowLen := len(r.Marshal(nil))
+
+		const rowsCount = 100
+		for i := 0; i < rowsCount; i++ {
+			s.AddRow(uint64(i), r)
+		}
+
+		// Verify that every replica group received all the rows.
+		for groupIdx, group := range s.groups {
+			n := 0
+			for _, sn := range group {
+				if len(sn.group) != len(group) {
+					t.Fatalf("unexpected group size for the node %q; got %d; want %d", sn.addr, len(sn.group), len(group))
+				}
+				sn.pendingDataMu.Lock()
+				n += sn.pendingData.Len()
+				sn.pendingDataMu.Unlock()
+			}
+			if n != rowsCount*rowLen {
+				t.Fatalf("unexpected data length at replica group #%d; got %d bytes; want %d bytes", groupIdx, n, rowsCount*rowLen)
+			}
+		}
+	}
+
+	f(1, 1)
+	f(3, 1)
+	f(2, 2)
+	f(6, 3)
+}
+
+func TestStorageDropBlockForUnavailableGroup(t *testing.T) {
+	addrs := []string{"127.0.0.1:1", "127.0.0.2:1", "127.0.0.3:1", "127.0.0.4:1"}
+	authCfgs := make([]*promauth.Config, len(addrs))
+	isTLSs := make([]bool, len(addrs))
+	s := NewStorage(addrs, authCfgs, isTLSs, 1, true, 2)
+	defer s.MustStop()
+
+	// The first group is unavailable, while the second group is available.
+	for _, sn := range s.groups[0] {
+		sn.disabledUntil.Store(math.MaxUint64)
+	}
+	if !s.hasAvailableGroupExcept(s.groups[0]) {
+		t.Fatalf("expecting available group other than the first group")
+	}
+	if s.hasAvailableGroupExcept(s.groups[1]) {
+		t.Fatalf("unexpected available group other than the second group")
+	}
+
+	sn := s.groups[0][0]
+	sn.addData([]byte("foobar"))
+	sn.pendingDataMu.Lock()
+	pendingData := sn.grabPendingDataForFlushLocked()
+	sn.pendingDataMu.Unlock()
+
+	// The data block must be dropped instead of blocking until the first group becomes available.
+	droppedBlocks := droppedBlocksTotal.Get()
+	sn.mustSendInsertRequest(pendingData)
+	if n := droppedBlocksTotal.Get() - droppedBlocks; n != 1 {
+		t.Fatalf("unexpected number of dropped blocks; got %d; want 1", n)
+	}
+
+	// The buffer must be returned to the storage node.
+	if n := len(sn.pendingDataBuffers); n != cap(sn.pendingDataBuffers) {
+		t.Fatalf("unexpected number of free buffers; got %d; want %d", n, cap(sn.pendingDataBuffers))
+	}
+}
@@ -10,16 +10,23 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+	"github.com/valyala/fastrand"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/contextutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
 )
 
@@ -27,32 +34,32 @@ const (
 	// FieldNamesProtocolVersion is the version of the protocol used for /internal/select/field_names HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	FieldNamesProtocolVersion = "v1"
+	FieldNamesProtocolVersion = "v2"
 
 	// FieldValuesProtocolVersion is the version of the protocol used for /internal/select/field_values HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	FieldValuesProtocolVersion = "v1"
+	FieldValuesProtocolVersion = "v2"
 
 	// StreamFieldNamesProtocolVersion is the version of the protocol used for /internal/select/stream_field_names HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	StreamFieldNamesProtocolVersion = "v1"
+	StreamFieldNamesProtocolVersion = "v2"
 
 	// StreamFieldValuesProtocolVersion is the version of the protocol used for /internal/select/stream_field_values HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	StreamFieldValuesProtocolVersion = "v1"
+	StreamFieldValuesProtocolVersion = "v2"
 
 	// StreamsProtocolVersion is the version of the protocol used for /internal/select/streams HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	StreamsProtocolVersion = "v1"
+	StreamsProtocolVersion = "v2"
 
 	// StreamIDsProtocolVersion is the version of the protocol used for /internal/select/stream_ids HTTP endpoint.
 	//
 	// It must be updated every time the protocol changes.
-	StreamIDsProtocolVersion = "v1"
+	StreamIDsProtocolVersion = "v2"
 
 	// QueryProtocolVersion is the version of the protocol used for /internal/select/query HTTP endpoint.
 	//
@@ -64,6 +71,11 @@ const (
 type Storage struct {
 	sns []*storageNode
 
+	// groups contains storage nodes split into replica groups.
+	//
+	// Every group holds a full copy of the ingested data, so queries are sent to a single group.
+	groups [][]*storageNode
+
 	disableCompression bool
 }
 
@@ -82,6 +94,9 @@ type storageNode struct {
 
 	// ac is auth config used for setting request headers such as Authorization and Host.
 	ac *promauth.Config
+
+	// the unix timestamp until the storageNode is considered unavailable for querying.
+	disabledUntil atomic.Uint64
 }
 
 func newStorageNode(s *Storage, addr string, ac *promauth.Config, isTLS bool) *storageNode {
@@ -106,8 +121,8 @@ func newStorageNode(s *Storage, addr string, ac *promauth.Config, isTLS bool) *s
 	return sn
 }
 
-func (sn *storageNode) runQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, processBlock func(db *logstorage.DataBlock)) error {
-	args := sn.getCommonArgs(QueryProtocolVersion, tenantIDs, q)
+func (sn *storageNode) runQuery(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, processBlock func(db *logstorage.DataBlock)) error {
+	args := sn.getCommonArgs(QueryProtocolVersion, qt, tenantIDs, q)
 
 	reqURL := sn.getRequestURL("/internal/select/query", args)
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
@@ -121,6 +136,7 @@ func (sn *storageNode) runQuery(ctx context.Context, tenantIDs []logstorage.Tena
 	// send the request to the storage node
 	resp, err := sn.c.Do(req)
 	if err != nil {
+		sn.markUnavailable(ctx)
 		return err
 	}
 	defer resp.Body.Close()
@@ -147,6 +163,10 @@ func (sn *storageNode) runQuery(ctx context.Context, tenantIDs []logstorage.Tena
 			return fmt.Errorf("cannot read block size from %q: %w", reqURL, err)
 		}
 		blockLen := encoding.UnmarshalUint64(dataLenBuf[:])
+		if blockLen == 0 {
+			// The zero-length block is followed by uncompressed query trace from the storage node.
+			return sn.readQueryTrace(qt, resp.Body, reqURL)
+		}
 		if blockLen > math.MaxInt {
 			return fmt.Errorf("too big data block: %d bytes; mustn't exceed %v bytes", blockLen, math.MaxInt)
 		}
@@ -182,67 +202,92 @@ func (sn *storageNode) runQuery(ctx context.Context, tenantIDs []logstorage.Tena
 	}
 }
 
-func (sn *storageNode) getFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(FieldNamesProtocolVersion, tenantIDs, q)
+func (sn *storageNode) readQueryTrace(qt *querytracer.Tracer, r io.Reader, reqURL string) error {
+	var traceLenBuf [8]byte
+	if _, err := io.ReadFull(r, traceLenBuf[:]); err != nil {
+		return fmt.Errorf("cannot read query trace size from %q: %w", reqURL, err)
+	}
+	traceLen := encoding.UnmarshalUint64(traceLenBuf[:])
+	if traceLen > maxQueryTraceSize {
+		return fmt.Errorf("too big query trace received from %q: %d bytes; mustn't exceed %d bytes", reqURL, traceLen, maxQueryTraceSize)
+	}
+	trace := make([]byte, traceLen)
+	if _, err := io.ReadFull(r, trace); err != nil {
+		return fmt.Errorf("cannot read query trace with size of %d bytes from %q: %w", traceLen, reqURL, err)
+	}
+	if err := qt.AddJSON(trace); err != nil {
+		return fmt.Errorf("cannot add query trace received from %q: %w", reqURL, err)
+	}
+	return nil
+}
+
+// maxQueryTraceSize is the maximum size of query trace, which can be received from a storage node.
+const maxQueryTraceSize = 64 * 1024 * 1024
 
-	return sn.getValuesWithHits(ctx, "/internal/select/field_names", args)
+func (sn *storageNode) getFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(FieldNamesProtocolVersion, qt, tenantIDs, q)
+
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/field_names", args)
 }
 
-func (sn *storageNode) getFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(FieldValuesProtocolVersion, tenantIDs, q)
+func (sn *storageNode) getFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(FieldValuesProtocolVersion, qt, tenantIDs, q)
 	args.Set("field", fieldName)
 	args.Set("limit", fmt.Sprintf("%d", limit))
 
-	return sn.getValuesWithHits(ctx, "/internal/select/field_values", args)
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/field_values", args)
 }
 
-func (sn *storageNode) getStreamFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(StreamFieldNamesProtocolVersion, tenantIDs, q)
+func (sn *storageNode) getStreamFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(StreamFieldNamesProtocolVersion, qt, tenantIDs, q)
 
-	return sn.getValuesWithHits(ctx, "/internal/select/stream_field_names", args)
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/stream_field_names", args)
 }
 
-func (sn *storageNode) getStreamFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(StreamFieldValuesProtocolVersion, tenantIDs, q)
+func (sn *storageNode) getStreamFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(StreamFieldValuesProtocolVersion, qt, tenantIDs, q)
 	args.Set("field", fieldName)
 	args.Set("limit", fmt.Sprintf("%d", limit))
 
-	return sn.getValuesWithHits(ctx, "/internal/select/stream_field_values", args)
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/stream_field_values", args)
 }
 
-func (sn *storageNode) getStreams(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(StreamsProtocolVersion, tenantIDs, q)
+func (sn *storageNode) getStreams(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(StreamsProtocolVersion, qt, tenantIDs, q)
 	args.Set("limit", fmt.Sprintf("%d", limit))
 
-	return sn.getValuesWithHits(ctx, "/internal/select/streams", args)
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/streams", args)
 }
 
-func (sn *storageNode) getStreamIDs(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
-	args := sn.getCommonArgs(StreamIDsProtocolVersion, tenantIDs, q)
+func (sn *storageNode) getStreamIDs(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+	args := sn.getCommonArgs(StreamIDsProtocolVersion, qt, tenantIDs, q)
 	args.Set("limit", fmt.Sprintf("%d", limit))
 
-	return sn.getValuesWithHits(ctx, "/internal/select/stream_ids", args)
+	return sn.getValuesWithHits(ctx, qt, "/internal/select/stream_ids", args)
 }
 
-func (sn *storageNode) getCommonArgs(version string, tenantIDs []logstorage.TenantID, q *logstorage.Query) url.Values {
+func (sn *storageNode) getCommonArgs(version string, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) url.Values {
 	args := url.Values{}
 	args.Set("version", version)
 	args.Set("tenant_ids", string(logstorage.MarshalTenantIDs(nil, tenantIDs)))
 	args.Set("query", q.String())
 	args.Set("timestamp", fmt.Sprintf("%d", q.GetTimestamp()))
 	args.Set("disable_compression", fmt.Sprintf("%v", sn.s.disableCompression))
+	if qt.Enabled() {
+		args.Set("trace", "1")
+	}
 	return args
 }
 
-func (sn *storageNode) getValuesWithHits(ctx context.Context, path string, args url.Values) ([]logstorage.ValueWithHits, error) {
-	data, err := sn.executeRequestAt(ctx, path, args)
+func (sn *storageNode) getValuesWithHits(ctx context.Context, qt *querytracer.Tracer, path string, args url.Values) ([]logstorage.ValueWithHits, error) {
+	data, err := sn.executeRequestAt(ctx, qt, path, args)
 	if err != nil {
 		return nil, err
 	}
 	return unmarshalValuesWithHits(data)
 }
 
-func (sn *storageNode) executeRequestAt(ctx context.Context, path string, args url.Values) ([]byte, error) {
+func (sn *storageNode) executeRequestAt(ctx context.Context, qt *querytracer.Tracer, path string, args url.Values) ([]byte, error) {
 	reqURL := sn.getRequestURL(path, args)
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
@@ -252,6 +297,7 @@ func (sn *storageNode) executeRequestAt(ctx context.Context, path string, args u
 	// send the request to the storage node
 	resp, err := sn.c.Do(req)
 	if err != nil {
+		sn.markUnavailable(ctx)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -264,7 +310,11 @@ func (sn *storageNode) executeRequestAt(ctx context.Context, path string, args u
 		return nil, fmt.Errorf("unexpected status code for the request to %q: %d; want %d; response: %q", reqURL, resp.StatusCode, http.StatusOK, responseBody)
 	}
 
-	// read the response
+	// read the response, which starts with uncompressed query trace
+	if err := sn.readQueryTrace(qt, resp.Body, reqURL); err != nil {
+		return nil, err
+	}
+
 	var bb bytesutil.ByteBuffer
 	if _, err := bb.ReadFrom(resp.Body); err != nil {
 		return nil, fmt.Errorf("cannot read response from %q: %w", reqURL, err)
@@ -286,12 +336,33 @@ func (sn *storageNode) getRequestURL(path string, args url.Values) string {
 	return fmt.Sprintf("%s://%s%s?%s", sn.scheme, sn.addr, path, args.Encode())
 }
 
+// markUnavailable marks sn as unavailable for querying for 10 seconds, so queries are sent to other replica groups during this time.
+func (sn *storageNode) markUnavailable(ctx context.Context) {
+	if ctx.Err() != nil {
+		// The request has been canceled. This isn't an issue with sn.
+		return
+	}
+	sn.disabledUntil.Store(fasttime.UnixTimestamp() + 10)
+}
+
+func (sn *storageNode) isAvailable() bool {
+	return sn.disabledUntil.Load() <= fasttime.UnixTimestamp()
+}
+
 // NewStorage returns new Storage for the given addrs and the given authCfgs.
 //
 // If disableCompression is set, then uncompressed responses are received from storage nodes.
 //
+// The addrs are split into replicationFactor groups of consecutive addrs. Every group must hold a full copy of the data,
+// so queries are sent to a single group. If the group is unavailable, then queries are re-sent to the next group.
+// The number of addrs must be divisible by replicationFactor.
+//
 // Call MustStop on the returned storage when it is no longer needed.
-func NewStorage(addrs []string, authCfgs []*promauth.Config, isTLSs []bool, disableCompression bool) *Storage {
+func NewStorage(addrs []string, authCfgs []*promauth.Config, isTLSs []bool, disableCompression bool, replicationFactor int) *Storage {
+	if replicationFactor <= 0 || len(addrs)%replicationFactor != 0 {
+		logger.Panicf("BUG: the number of addrs=%d must be divisible by replicationFactor=%d", len(addrs), replicationFactor)
+	}
+
 	s := &Storage{
 		disableCompression: disableCompression,
 	}
@@ -302,44 +373,238 @@ func NewStorage(addrs []string, authCfgs []*promauth.Config, isTLSs []bool, disa
 	}
 	s.sns = sns
 
+	groupSize := len(sns) / replicationFactor
+	s.groups = make([][]*storageNode, replicationFactor)
+	for i := range s.groups {
+		s.groups[i] = sns[i*groupSize : (i+1)*groupSize]
+	}
+
 	return s
 }
 
 // MustStop stops the s.
 func (s *Storage) MustStop() {
 	s.sns = nil
+	s.groups = nil
+}
+
+// getGroupsForQuery returns replica groups in the order they must be queried.
+//
+// Groups with all the available nodes go first. The first group is selected at random among them
+// in order to spread the query load evenly among replica groups.
+func (s *Storage) getGroupsForQuery() [][]*storageNode {
+	if len(s.groups) == 1 {
+		// Fast path - there is no replication.
+		return s.groups
+	}
+
+	startIdx := int(fastrand.Uint32n(uint32(len(s.groups))))
+	groups := make([][]*storageNode, 0, len(s.groups))
+	var unavailableGroups [][]*storageNode
+	for i := range s.groups {
+		group := s.groups[(startIdx+i)%len(s.groups)]
+		if isGroupAvailable(group) {
+			groups = append(groups, group)
+		} else {
+			unavailableGroups = append(unavailableGroups, group)
+		}
+	}
+	return append(groups, unavailableGroups...)
 }
 
+func isGroupAvailable(group []*storageNode) bool {
+	for _, sn := range group {
+		if !sn.isAvailable() {
+			return false
+		}
+	}
+	return true
+}
+
+var replicaFailoversTotal = metrics.NewCounter(`vl_select_replica_failovers_total`)
+
 // RunQuery runs the given q and calls writeBlock for the returned data blocks
-func (s *Storage) RunQuery(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
-	nqr, err := logstorage.NewNetQueryRunner(ctx, tenantIDs, q, s.RunQuery, writeBlock)
+//
+// Query traces from storage nodes are added to qt if it is enabled.
+func (s *Storage) RunQuery(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+	// Subqueries may be executed concurrently, e.g. by union pipes, so their traces are registered at qt under the lock.
+	var qtLock sync.Mutex
+	runSubquery := func(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+		qtLock.Lock()
+		qtChild := querytracer.NewOrphan(qt, "run subquery %s", q)
+		qtLock.Unlock()
+
+		err := s.RunQuery(ctx, qtChild, tenantIDs, q, writeBlock)
+		qtChild.Done()
+
+		qtLock.Lock()
+		qt.AddChild(qtChild)
+		qtLock.Unlock()
+
+		return err
+	}
+	nqr, err := logstorage.NewNetQueryRunner(ctx, tenantIDs, q, runSubquery, writeBlock)
 	if err != nil {
 		return err
 	}
 
-	search := func(stopCh <-chan struct{}, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
-		return s.runQuery(stopCh, tenantIDs, q, writeBlock)
+	search := func(stopCh <-chan struct{}, qt *querytracer.Tracer, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+		return s.runQuery(stopCh, qt, tenantIDs, q, writeBlock)
 	}
 
 	concurrency := q.GetConcurrency()
-	return nqr.Run(ctx, concurrency, search)
+	return nqr.Run(ctx, qt, concurrency, search)
+}
+
+func (s *Storage) runQuery(stopCh <-chan struct{}, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+	// The query results cannot be re-requested from another replica group after some data blocks were passed to writeBlock,
+	// since this would result in duplicate logs.
+	var blocksWritten atomic.Bool
+	writeBlockTracked := func(workerID uint, db *logstorage.DataBlock) {
+		blocksWritten.Store(true)
+		writeBlock(workerID, db)
+	}
+
+	if len(s.groups) > 1 {
+		// Drop duplicate log entries, which may appear in replica groups because of re-routing at vlinsert.
+		rd := newRowsDeduplicator(maxDedupRows)
+		writeBlockTracked = rd.newWriteBlock(writeBlockTracked)
+	}
+
+	groups := s.getGroupsForQuery()
+	var err error
+	for i, group := range groups {
+		err = runQueryAtGroup(stopCh, qt, group, tenantIDs, q, writeBlockTracked)
+		if err == nil || blocksWritten.Load() || i+1 >= len(groups) {
+			return err
+		}
+		select {
+		case <-stopCh:
+			return err
+		default:
+		}
+		logger.Warnf("cannot execute query at replica group #%d: %s; re-trying the query at the next replica group", i, err)
+		qt.Printf("cannot execute query at replica group #%d: %s; re-trying the query at the next replica group", i, err)
+		replicaFailoversTotal.Inc()
+	}
+	return err
+}
+
+// maxDedupRows is the maximum number of log entries per query, which are tracked by rowsDeduplicator.
+const maxDedupRows = 1_000_000
+
+// rowsDeduplicator drops duplicate log entries from query results.
+//
+// vlinsert may store the same data block at two storage nodes of a replica group if the data block is re-routed to another node
+// after it has been already stored at the original node - for example, when the original node times out on returning the response.
+//
+// Only log entries with _time and _stream_id fields are de-duplicated, since it isn't possible to distinguish duplicate log entries
+// from distinct log entries with identical selected fields or from aggregated results otherwise.
+type rowsDeduplicator struct {
+	mu       sync.Mutex
+	maxItems int
+	seen     map[uint64]struct{}
+}
+
+func newRowsDeduplicator(maxItems int) *rowsDeduplicator {
+	return &rowsDeduplicator{
+		maxItems: maxItems,
+		seen:     make(map[uint64]struct{}),
+	}
+}
+
+// newWriteBlock returns writeBlock wrapper, which drops duplicate log entries before passing them to writeBlock.
+func (rd *rowsDeduplicator) newWriteBlock(writeBlock logstorage.WriteDataBlockFunc) logstorage.WriteDataBlockFunc {
+	return func(workerID uint, db *logstorage.DataBlock) {
+		db = rd.filterBlock(db)
+		if db.RowsCount() > 0 {
+			writeBlock(workerID, db)
+		}
+	}
 }
 
-func (s *Storage) runQuery(stopCh <-chan struct{}, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
+// filterBlock returns db without the log entries seen before.
+func (rd *rowsDeduplicator) filterBlock(db *logstorage.DataBlock) *logstorage.DataBlock {
+	if !hasColumn(db, "_time") || !hasColumn(db, "_stream_id") {
+		return db
+	}
+
+	rowsCount := db.RowsCount()
+	columns := db.Columns
+	keep := make([]bool, rowsCount)
+	keptRows := 0
+
+	var b []byte
+	rd.mu.Lock()
+	for i := 0; i < rowsCount; i++ {
+		b = b[:0]
+		for _, c := range columns {
+			b = encoding.MarshalBytes(b, bytesutil.ToUnsafeBytes(c.Name))
+			b = encoding.MarshalBytes(b, bytesutil.ToUnsafeBytes(c.Values[i]))
+		}
+		h := xxhash.Sum64(b)
+		if _, ok := rd.seen[h]; ok {
+			dedupedRowsTotal.Inc()
+			continue
+		}
+		if len(rd.seen) < rd.maxItems {
+			rd.seen[h] = struct{}{}
+		}
+		keep[i] = true
+		keptRows++
+	}
+	rd.mu.Unlock()
+
+	if keptRows == rowsCount {
+		return db
+	}
+
+	dbNew := &logstorage.DataBlock{
+		Columns: make([]logstorage.BlockColumn, len(columns)),
+	}
+	for j, c := range columns {
+		values := make([]string, 0, keptRows)
+		for i, v := range c.Values {
+			if keep[i] {
+				values = append(values, v)
+			}
+		}
+		dbNew.Columns[j] = logstorage.BlockColumn{
+			Name:   c.Name,
+			Values: values,
+		}
+	}
+	return dbNew
+}
+
+func hasColumn(db *logstorage.DataBlock, name string) bool {
+	for _, c := range db.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var dedupedRowsTotal = metrics.NewCounter(`vl_select_deduplicated_rows_total`)
+
+func runQueryAtGroup(stopCh <-chan struct{}, qt *querytracer.Tracer, sns []*storageNode, tenantIDs []logstorage.TenantID, q *logstorage.Query, writeBlock logstorage.WriteDataBlockFunc) error {
 	ctxWithCancel, cancel := contextutil.NewStopChanContext(stopCh)
 	defer cancel()
 
-	errs := make([]error, len(s.sns))
+	errs := make([]error, len(sns))
 
 	var wg sync.WaitGroup
-	for i := range s.sns {
+	for i := range sns {
+		qtChild := qt.NewChild("run query at storage node %s", sns[i].addr)
 		wg.Add(1)
 		go func(nodeIdx int) {
 			defer wg.Done()
-			sn := s.sns[nodeIdx]
-			err := sn.runQuery(ctxWithCancel, tenantIDs, q, func(db *logstorage.DataBlock) {
+			sn := sns[nodeIdx]
+			err := sn.runQuery(ctxWithCancel, qtChild, tenantIDs, q, func(db *logstorage.DataBlock) {
 				writeBlock(uint(nodeIdx), db)
 			})
+			qtChild.Done()
 			if err != nil {
 				// Cancel the remaining parallel queries
 				cancel()
@@ -354,72 +619,95 @@ func (s *Storage) runQuery(stopCh <-chan struct{}, tenantIDs []logstorage.Tenant
 }
 
 // GetFieldNames executes q and returns field names seen in results.
-func (s *Storage) GetFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, 0, false, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getFieldNames(ctx, tenantIDs, q)
+func (s *Storage) GetFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, 0, false, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getFieldNames(ctx, qt, tenantIDs, q)
 	})
 }
 
 // GetFieldValues executes q and returns unique values for the fieldName seen in results.
 //
 // If limit > 0, then up to limit unique values are returned.
-func (s *Storage) GetFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, limit, true, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getFieldValues(ctx, tenantIDs, q, fieldName, limit)
+func (s *Storage) GetFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, limit, true, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 	})
 }
 
 // GetStreamFieldNames executes q and returns stream field names seen in results.
-func (s *Storage) GetStreamFieldNames(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, 0, false, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getStreamFieldNames(ctx, tenantIDs, q)
+func (s *Storage) GetStreamFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, 0, false, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getStreamFieldNames(ctx, qt, tenantIDs, q)
 	})
 }
 
 // GetStreamFieldValues executes q and returns stream field values for the given fieldName seen in results.
 //
 // If limit > 0, then up to limit unique stream field values are returned.
-func (s *Storage) GetStreamFieldValues(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, limit, true, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getStreamFieldValues(ctx, tenantIDs, q, fieldName, limit)
+func (s *Storage) GetStreamFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, fieldName string, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, limit, true, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getStreamFieldValues(ctx, qt, tenantIDs, q, fieldName, limit)
 	})
 }
 
 // GetStreams executes q and returns streams seen in query results.
 //
 // If limit > 0, then up to limit unique streams are returned.
-func (s *Storage) GetStreams(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, limit, true, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getStreams(ctx, tenantIDs, q, limit)
+func (s *Storage) GetStreams(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, limit, true, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getStreams(ctx, qt, tenantIDs, q, limit)
 	})
 }
 
 // GetStreamIDs executes q and returns streamIDs seen in query results.
 //
 // If limit > 0, then up to limit unique streamIDs are returned.
-func (s *Storage) GetStreamIDs(ctx context.Context, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
-	return s.getValuesWithHits(ctx, limit, true, func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error) {
-		return sn.getStreamIDs(ctx, tenantIDs, q, limit)
+func (s *Storage) GetStreamIDs(ctx context.Context, qt *querytracer.Tracer, tenantIDs []logstorage.TenantID, q *logstorage.Query, limit uint64) ([]logstorage.ValueWithHits, error) {
+	return s.getValuesWithHits(ctx, qt, limit, true, func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error) {
+		return sn.getStreamIDs(ctx, qt, tenantIDs, q, limit)
 	})
 }
 
-func (s *Storage) getValuesWithHits(ctx context.Context, limit uint64, resetHitsOnLimitExceeded bool,
-	callback func(ctx context.Context, sn *storageNode) ([]logstorage.ValueWithHits, error)) ([]logstorage.ValueWithHits, error) {
+func (s *Storage) getValuesWithHits(ctx context.Context, qt *querytracer.Tracer, limit uint64, resetHitsOnLimitExceeded bool,
+	callback func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error)) ([]logstorage.ValueWithHits, error) {
+
+	groups := s.getGroupsForQuery()
+	var err error
+	for i, group := range groups {
+		var vhs []logstorage.ValueWithHits
+		vhs, err = getValuesWithHitsAtGroup(ctx, qt, group, limit, resetHitsOnLimitExceeded, callback)
+		if err == nil {
+			return vhs, nil
+		}
+		if i+1 >= len(groups) || ctx.Err() != nil {
+			break
+		}
+		logger.Warnf("cannot execute query at replica group #%d: %s; re-trying the query at the next replica group", i, err)
+		qt.Printf("cannot execute query at replica group #%d: %s; re-trying the query at the next replica group", i, err)
+		replicaFailoversTotal.Inc()
+	}
+	return nil, err
+}
+
+func getValuesWithHitsAtGroup(ctx context.Context, qt *querytracer.Tracer, sns []*storageNode, limit uint64, resetHitsOnLimitExceeded bool,
+	callback func(ctx context.Context, qt *querytracer.Tracer, sn *storageNode) ([]logstorage.ValueWithHits, error)) ([]logstorage.ValueWithHits, error) {
 
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	results := make([][]logstorage.ValueWithHits, len(s.sns))
-	errs := make([]error, len(s.sns))
+	results := make([][]logstorage.ValueWithHits, len(sns))
+	errs := make([]error, len(sns))
 
 	var wg sync.WaitGroup
-	for i := range s.sns {
+	for i := range sns {
+		qtChild := qt.NewChild("run query at storage node %s", sns[i].addr)
 		wg.Add(1)
 		go func(nodeIdx int) {
 			defer wg.Done()
 
-			sn := s.sns[nodeIdx]
-			vhs, err := callback(ctxWithCancel, sn)
+			sn := sns[nodeIdx]
+			vhs, err := callback(ctxWithCancel, qtChild, sn)
+			qtChild.Done()
 			results[nodeIdx] = vhs
 			errs[nodeIdx] = err
 
@@ -436,6 +724,7 @@ func (s *Storage) getValuesWithHits(ctx context.Context, limit uint64, resetHits
 	}
 
 	vhs := logstorage.MergeValuesWithHits(results, limit, resetHitsOnLimitExceeded)
+	qt.Printf("merge results from %d storage nodes into %d values", len(sns), len(vhs))
 
 	return vhs, nil
 }
@@ -0,0 +1,296 @@
+package netselect
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+func TestStorageGetGroupsForQuery(t *testing.T) {
+	f := func(nodesCount, replicationFactor int, unavailableNodes []int, availableGroupsExpected int) {
+		t.Helper()
+
+		addrs := make([]string, nodesCount)
+		for i := range addrs {
+			addrs[i] = "node" + strings.Repeat("x", i)
+		}
+		s := NewStorage(addrs, make([]*promauth.Config, nodesCount), make([]bool, nodesCount), true, replicationFactor)
+		defer s.MustStop()
+
+		for _, idx := range unavailableNodes {
+			s.sns[idx].markUnavailable(context.Background())
+		}
+
+		for i := 0; i < 10; i++ {
+			groups := s.getGroupsForQuery()
+			if len(groups) != replicationFactor {
+				t.Fatalf("unexpected number of groups; got %d; want %d", len(groups), replicationFactor)
+			}
+			availableGroups := 0
+			for j, group := range groups {
+				if len(group) != nodesCount/replicationFactor {
+					t.Fatalf("unexpected group size; got %d; want %d", len(group), nodesCount/replicationFactor)
+				}
+				if !isGroupAvailable(group) {
+					continue
+				}
+				if j != availableGroups {
+					t.Fatalf("available group #%d must go before unavailable groups", j)
+				}
+				availableGroups++
+			}
+			if availableGroups != availableGroupsExpected {
+				t.Fatalf("unexpected number of available groups; got %d; want %d", availableGroups, availableGroupsExpected)
+			}
+		}
+	}
+
+	// no replication
+	f(1, 1, nil, 1)
+	f(3, 1, nil, 1)
+	f(3, 1, []int{1}, 0)
+
+	// replication
+	f(2, 2, nil, 2)
+	f(2, 2, []int{0}, 1)
+	f(6, 3, []int{1, 4}, 1)
+	f(6, 3, []int{0, 2, 5}, 0)
+}
+
+func TestStorageGetFieldNamesReplicaFailover(t *testing.T) {
+	vh := logstorage.ValueWithHits{
+		Value: "foo",
+		Hits:  10,
+	}
+
+	// The response starts with empty query trace.
+	data := encoding.MarshalUint64(nil, 0)
+	data = vh.Marshal(data)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	// Obtain an address without a listener in order to simulate unavailable storage node.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot create listener: %s", err)
+	}
+	unavailableAddr := ln.Addr().String()
+	_ = ln.Close()
+
+	srvAddr := strings.TrimPrefix(srv.URL, "http://")
+	addrs := []string{unavailableAddr, srvAddr}
+	s := NewStorage(addrs, make([]*promauth.Config, len(addrs)), make([]bool, len(addrs)), true, 2)
+	defer s.MustStop()
+
+	q, err := logstorage.ParseQuery("*")
+	if err != nil {
+		t.Fatalf("cannot parse query: %s", err)
+	}
+	tenantIDs := []logstorage.TenantID{{}}
+
+	// Run the query multiple times, so it hits the unavailable group at least once.
+	for i := 0; i < 5; i++ {
+		vhs, err := s.GetFieldNames(context.Background(), nil, tenantIDs, q)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		vhsExpected := []logstorage.ValueWithHits{vh}
+		if !reflect.DeepEqual(vhs, vhsExpected) {
+			t.Fatalf("unexpected result; got %v; want %v", vhs, vhsExpected)
+		}
+	}
+}
+
+func TestStorageQueryTrace(t *testing.T) {
+	remoteQT := querytracer.New(true, "remote trace")
+	remoteQT.Printf("remote message")
+	remoteQT.Done()
+	remoteTrace := remoteQT.ToJSON()
+
+	vh := logstorage.ValueWithHits{
+		Value: "foo",
+		Hits:  10,
+	}
+	db := &logstorage.DataBlock{
+		Columns: []logstorage.BlockColumn{
+			{
+				Name:   "foo",
+				Values: []string{"bar"},
+			},
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace := ""
+		if r.FormValue("trace") == "1" {
+			trace = remoteTrace
+		}
+		var data []byte
+		switch r.URL.Path {
+		case "/internal/select/query":
+			blockData := db.Marshal(nil)
+			data = encoding.MarshalUint64(data, uint64(len(blockData)))
+			data = append(data, blockData...)
+			if trace != "" {
+				data = encoding.MarshalUint64(data, 0)
+				data = encoding.MarshalUint64(data, uint64(len(trace)))
+				data = append(data, trace...)
+			}
+		case "/internal/select/field_names":
+			data = encoding.MarshalUint64(data, uint64(len(trace)))
+			data = append(data, trace...)
+			data = vh.Marshal(data)
+		default:
+			t.Errorf("unexpected path requested: %q", r.URL.Path)
+		}
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	var opts promauth.Options
+	ac, err := opts.NewConfig()
+	if err != nil {
+		t.Fatalf("cannot create auth config: %s", err)
+	}
+	addrs := []string{strings.TrimPrefix(srv.URL, "http://")}
+	s := NewStorage(addrs, []*promauth.Config{ac}, make([]bool, len(addrs)), true, 1)
+	defer s.MustStop()
+
+	q, err := logstorage.ParseQuery("*")
+	if err != nil {
+		t.Fatalf("cannot parse query: %s", err)
+	}
+	tenantIDs := []logstorage.TenantID{{}}
+
+	f := func(traceEnabled bool) {
+		t.Helper()
+
+		// verify GetFieldNames
+		qt := querytracer.New(traceEnabled, "test")
+		vhs, err := s.GetFieldNames(context.Background(), qt, tenantIDs, q)
+		if err != nil {
+			t.Fatalf("unexpected error in GetFieldNames: %s", err)
+		}
+		vhsExpected := []logstorage.ValueWithHits{vh}
+		if !reflect.DeepEqual(vhs, vhsExpected) {
+			t.Fatalf("unexpected result; got %v; want %v", vhs, vhsExpected)
+		}
+		qt.Done()
+		if hasMessage := strings.Contains(qt.String(), "remote message"); hasMessage != traceEnabled {
+			t.Fatalf("unexpected presence of the remote trace in GetFieldNames trace; got %v; want %v; trace:\n%s", hasMessage, traceEnabled, qt)
+		}
+
+		// verify RunQuery
+		qt = querytracer.New(traceEnabled, "test")
+		var rowsCount atomic.Int64
+		writeBlock := func(_ uint, db *logstorage.DataBlock) {
+			rowsCount.Add(int64(db.RowsCount()))
+		}
+		if err := s.RunQuery(context.Background(), qt, tenantIDs, q, writeBlock); err != nil {
+			t.Fatalf("unexpected error in RunQuery: %s", err)
+		}
+		if n := rowsCount.Load(); n != 1 {
+			t.Fatalf("unexpected number of rows; got %d; want 1", n)
+		}
+		qt.Done()
+		if hasMessage := strings.Contains(qt.String(), "remote message"); hasMessage != traceEnabled {
+			t.Fatalf("unexpected presence of the remote trace in RunQuery trace; got %v; want %v; trace:\n%s", hasMessage, traceEnabled, qt)
+		}
+	}
+
+	f(false)
+	f(true)
+}
+
+func TestRowsDeduplicator(t *testing.T) {
+	f := func(maxItems int, blocks []*logstorage.DataBlock, resultExpected [][]logstorage.BlockColumn) {
+		t.Helper()
+
+		rd := newRowsDeduplicator(maxItems)
+		var result [][]logstorage.BlockColumn
+		writeBlock := rd.newWriteBlock(func(_ uint, db *logstorage.DataBlock) {
+			result = append(result, db.Columns)
+		})
+		for _, db := range blocks {
+			writeBlock(0, db)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+	}
+
+	newBlock := func(columns ...logstorage.BlockColumn) *logstorage.DataBlock {
+		return &logstorage.DataBlock{
+			Columns: columns,
+		}
+	}
+	col := func(name string, values ...string) logstorage.BlockColumn {
+		return logstorage.BlockColumn{
+			Name:   name,
+			Values: values,
+		}
+	}
+
+	// no duplicates
+	f(10, []*logstorage.DataBlock{
+		newBlock(col("_time", "1", "2"), col("_stream_id", "a", "a"), col("_msg", "foo", "foo")),
+		newBlock(col("_time", "1"), col("_stream_id", "b"), col("_msg", "foo")),
+	}, [][]logstorage.BlockColumn{
+		{col("_time", "1", "2"), col("_stream_id", "a", "a"), col("_msg", "foo", "foo")},
+		{col("_time", "1"), col("_stream_id", "b"), col("_msg", "foo")},
+	})
+
+	// duplicates across blocks
+	f(10, []*logstorage.DataBlock{
+		newBlock(col("_time", "1", "2"), col("_stream_id", "a", "a"), col("_msg", "foo", "bar")),
+		newBlock(col("_time", "2", "3"), col("_stream_id", "a", "a"), col("_msg", "bar", "baz")),
+		newBlock(col("_time", "1"), col("_stream_id", "a"), col("_msg", "foo")),
+	}, [][]logstorage.BlockColumn{
+		{col("_time", "1", "2"), col("_stream_id", "a", "a"), col("_msg", "foo", "bar")},
+		{col("_time", "3"), col("_stream_id", "a"), col("_msg", "baz")},
+	})
+
+	// distinct log entries with the same _time and _stream_id
+	f(10, []*logstorage.DataBlock{
+		newBlock(col("_time", "1", "1"), col("_stream_id", "a", "a"), col("_msg", "foo", "bar")),
+	}, [][]logstorage.BlockColumn{
+		{col("_time", "1", "1"), col("_stream_id", "a", "a"), col("_msg", "foo", "bar")},
+	})
+
+	// missing _stream_id - rows aren't de-duplicated
+	f(10, []*logstorage.DataBlock{
+		newBlock(col("_time", "1", "1"), col("_msg", "foo", "foo")),
+	}, [][]logstorage.BlockColumn{
+		{col("_time", "1", "1"), col("_msg", "foo", "foo")},
+	})
+
+	// aggregated results aren't de-duplicated
+	f(10, []*logstorage.DataBlock{
+		newBlock(col("count(*)", "5")),
+		newBlock(col("count(*)", "5")),
+	}, [][]logstorage.BlockColumn{
+		{col("count(*)", "5")},
+		{col("count(*)", "5")},
+	})
+
+	// the limit on the number of tracked rows is exceeded
+	f(1, []*logstorage.DataBlock{
+		newBlock(col("_time", "1", "2"), col("_stream_id", "a", "a")),
+		newBlock(col("_time", "1", "2"), col("_stream_id", "a", "a")),
+	}, [][]logstorage.BlockColumn{
+		{col("_time", "1", "2"), col("_stream_id", "a", "a")},
+		{col("_time", "2"), col("_stream_id", "a")},
+	})
+}
@@ -0,0 +1,265 @@
+package clientcertauth
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/metrics"
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+)
+
+var configPath = flag.String("mtls.authConfig", "", "Optional path to file with rules for authorizing requests by client certificate SPIFFE IDs and DNS names per request path. "+
+	"The file is re-read on SIGHUP signal. Client certificates must be verified via -mtls command-line flag. "+
+	"See https://docs.victoriametrics.com/vmagent/#client-certificate-authorization")
+
+// Config represents the contents of -mtls.authConfig file.
+type Config struct {
+	// Rules contains authorization rules. The first rule matching the request path is applied to the request.
+	//
+	// Requests with paths not matching any rule are allowed.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule contains authorization rule for requests with the path matching Paths.
+type Rule struct {
+	// Paths contains regexps for request paths the rule is applied to.
+	Paths []*Regex `yaml:"paths"`
+
+	// SPIFFEIDs contains regexps for SPIFFE IDs allowed to access Paths.
+	//
+	// SPIFFE ID is obtained from spiffe:// URI SAN of the client certificate.
+	SPIFFEIDs []*Regex `yaml:"spiffe_ids,omitempty"`
+
+	// DNSNames contains regexps for DNS SANs of the client certificate allowed to access Paths.
+	DNSNames []*Regex `yaml:"dns_names,omitempty"`
+}
+
+// Regex is an anchored regex.
+type Regex struct {
+	re *regexp.Regexp
+
+	sOriginal string
+}
+
+func (r *Regex) match(s string) bool {
+	prefix, ok := r.re.LiteralPrefix()
+	if ok {
+		// Fast path - literal match
+		return s == prefix
+	}
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	return r.re.MatchString(s)
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (r *Regex) UnmarshalYAML(f func(any) error) error {
+	var s string
+	if err := f(&s); err != nil {
+		return err
+	}
+	r.sOriginal = s
+
+	sAnchored := "^(?:" + s + ")$"
+	re, err := regexp.Compile(sAnchored)
+	if err != nil {
+		return fmt.Errorf("cannot build regexp from %q: %w", s, err)
+	}
+	r.re = re
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (r *Regex) MarshalYAML() (any, error) {
+	return r.sOriginal, nil
+}
+
+var (
+	configGlobal atomic.Pointer[Config]
+
+	configReloaderStopCh = make(chan struct{})
+	configReloaderWG     sync.WaitGroup
+)
+
+// Init initializes client certificate authorization according to -mtls.authConfig.
+//
+// Stop must be called when the authorization is no longer needed.
+func Init() {
+	if *configPath == "" {
+		return
+	}
+
+	// Register SIGHUP handler for config reload before loadConfig.
+	// This guarantees that the config will be re-read if the signal arrives just after loadConfig.
+	sighupCh := procutil.NewSighupChan()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Fatalf("cannot load -mtls.authConfig: %s", err)
+	}
+	configGlobal.Store(cfg)
+
+	configReloaderWG.Add(1)
+	go func() {
+		defer configReloaderWG.Done()
+		for {
+			select {
+			case <-configReloaderStopCh:
+				return
+			case <-sighupCh:
+			}
+			reloadConfig()
+		}
+	}()
+}
+
+// Stop stops client certificate authorization.
+func Stop() {
+	close(configReloaderStopCh)
+	configReloaderWG.Wait()
+}
+
+func reloadConfig() {
+	logger.Infof("reloading -mtls.authConfig=%q", *configPath)
+	cfg, err := loadConfig()
+	if err != nil {
+		configReloadErrors.Inc()
+		logger.Errorf("cannot reload -mtls.authConfig; preserving the previous config; error: %s", err)
+		return
+	}
+	configGlobal.Store(cfg)
+	logger.Infof("successfully reloaded -mtls.authConfig=%q", *configPath)
+}
+
+// CheckConfig checks -mtls.authConfig.
+func CheckConfig() error {
+	_, err := loadConfig()
+	return err
+}
+
+func loadConfig() (*Config, error) {
+	if *configPath == "" {
+		return nil, nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*configPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars in %q: %w", *configPath, err)
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", *configPath, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		if len(r.Paths) == 0 {
+			return nil, fmt.Errorf("missing `paths` in the rule #%d", i+1)
+		}
+		if len(r.SPIFFEIDs) == 0 && len(r.DNSNames) == 0 {
+			return nil, fmt.Errorf("missing `spiffe_ids` and `dns_names` in the rule #%d", i+1)
+		}
+	}
+	return &cfg, nil
+}
+
+// CheckRequest verifies whether r with the given path is authorized according to -mtls.authConfig.
+//
+// It returns an error with http.StatusForbidden status code if r isn't authorized.
+func CheckRequest(r *http.Request, path string) error {
+	cfg := configGlobal.Load()
+	if cfg == nil {
+		return nil
+	}
+	rule := cfg.getRule(path)
+	if rule == nil {
+		return nil
+	}
+	var cert *x509.Certificate
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cert = r.TLS.VerifiedChains[0][0]
+	}
+	if cert == nil {
+		deniedRequests.Inc()
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("access to %q requires verified client certificate; see -mtls and -mtls.authConfig command-line flags", path),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	if !rule.isAllowed(cert) {
+		deniedRequests.Inc()
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("client certificate with SPIFFE IDs %q and DNS names %q isn't allowed to access %q", getSPIFFEIDs(cert), cert.DNSNames, path),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) getRule(path string) *Rule {
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		for _, re := range r.Paths {
+			if re.match(path) {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) isAllowed(cert *x509.Certificate) bool {
+	for _, id := range getSPIFFEIDs(cert) {
+		for _, re := range r.SPIFFEIDs {
+			if re.match(id) {
+				return true
+			}
+		}
+	}
+	for _, name := range cert.DNSNames {
+		for _, re := range r.DNSNames {
+			if re.match(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func getSPIFFEIDs(cert *x509.Certificate) []string {
+	var ids []string
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			ids = append(ids, u.String())
+		}
+	}
+	return ids
+}
+
+var (
+	deniedRequests     = metrics.NewCounter(`vmagent_mtls_auth_denied_requests_total`)
+	configReloadErrors = metrics.NewCounter(`vmagent_mtls_auth_config_reload_errors_total`)
+)
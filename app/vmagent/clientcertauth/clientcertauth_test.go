@@ -0,0 +1,120 @@
+package clientcertauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+)
+
+func TestParseConfig_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := parseConfig([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown field
+	f(`foo: bar`)
+
+	// missing paths
+	f(`
+rules:
+- spiffe_ids: ["spiffe://example.org/.+"]
+`)
+
+	// missing spiffe_ids and dns_names
+	f(`
+rules:
+- paths: ["/api/v1/write"]
+`)
+
+	// invalid regexp
+	f(`
+rules:
+- paths: ["/api/v1/write"]
+  spiffe_ids: ["spiffe://example.org/("]
+`)
+}
+
+func TestCheckRequest(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+rules:
+- paths: ["/api/v1/write", "/insert/.+"]
+  spiffe_ids: ["spiffe://example.org/ns/prod/.+"]
+  dns_names: ["agent\\.prod\\.svc"]
+- paths: ["/influx/.+"]
+  dns_names: [".+\\.svc"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	configGlobal.Store(cfg)
+	defer configGlobal.Store(nil)
+
+	newCert := func(spiffeID string, dnsNames ...string) *x509.Certificate {
+		cert := &x509.Certificate{
+			DNSNames: dnsNames,
+		}
+		if spiffeID != "" {
+			u, err := url.Parse(spiffeID)
+			if err != nil {
+				t.Fatalf("cannot parse %q: %s", spiffeID, err)
+			}
+			cert.URIs = []*url.URL{u}
+		}
+		return cert
+	}
+
+	f := func(path string, cert *x509.Certificate, allowedExpected bool) {
+		t.Helper()
+
+		r := &http.Request{}
+		if cert != nil {
+			r.TLS = &tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{cert}},
+			}
+		}
+		err := CheckRequest(r, path)
+		if allowedExpected {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+		var esc *httpserver.ErrorWithStatusCode
+		if !errors.As(err, &esc) {
+			t.Fatalf("expecting ErrorWithStatusCode; got %v", err)
+		}
+		if esc.StatusCode != http.StatusForbidden {
+			t.Fatalf("unexpected status code; got %d; want %d", esc.StatusCode, http.StatusForbidden)
+		}
+	}
+
+	// paths without rules
+	f("/metrics", nil, true)
+	f("/api/v1/import", newCert("spiffe://example.org/ns/dev/sa/foo"), true)
+
+	// missing client certificate
+	f("/api/v1/write", nil, false)
+
+	// SPIFFE ID match
+	f("/api/v1/write", newCert("spiffe://example.org/ns/prod/sa/foo"), true)
+	f("/insert/0/prometheus/api/v1/write", newCert("spiffe://example.org/ns/prod/sa/foo"), true)
+	f("/api/v1/write", newCert("spiffe://example.org/ns/dev/sa/foo"), false)
+
+	// non-SPIFFE URI SAN is ignored
+	f("/api/v1/write", newCert("https://example.org/ns/prod/sa/foo"), false)
+
+	// DNS name match
+	f("/api/v1/write", newCert("", "agent.prod.svc"), true)
+	f("/api/v1/write", newCert("", "agent.dev.svc"), false)
+	f("/influx/write", newCert("", "agent.dev.svc"), true)
+	f("/influx/write", newCert("spiffe://example.org/ns/prod/sa/foo"), false)
+}
@@ -90,6 +90,9 @@ func insertRows(at *auth.Token, series []datadogv2.Series, extraLabels []prompbm
 	ctx.WriteRequest.Timeseries = tssDst
 	ctx.Labels = labels
 	ctx.Samples = samples
+	if err := remotewrite.CheckTenantLimits(at, ctx.WriteRequest.Timeseries); err != nil {
+		return err
+	}
 	if !remotewrite.TryPush(at, &ctx.WriteRequest) {
 		return remotewrite.ErrQueueFullHTTPRetry
 	}
@@ -130,6 +130,9 @@ func insertRows(at *auth.Token, db string, rows []influx.Row, extraLabels []prom
 	ctx.ctx.Labels = labels
 	ctx.ctx.Samples = samples
 	ctx.commonLabels = commonLabels
+	if err := remotewrite.CheckTenantLimits(at, ctx.ctx.WriteRequest.Timeseries); err != nil {
+		return err
+	}
 	if !remotewrite.TryPush(at, &ctx.ctx.WriteRequest) {
 		return remotewrite.ErrQueueFullHTTPRetry
 	}
@@ -0,0 +1,426 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/graphite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/influx"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/prometheusimport"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/promremotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/vmimport"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+var (
+	topics = flagutil.NewArrayString("kafka.consumer.topic", "Kafka topic names for data consumption. "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	brokers = flagutil.NewArrayString("kafka.consumer.topic.brokers", "List of brokers to connect for the corresponding -kafka.consumer.topic, "+
+		"e.g. -kafka.consumer.topic.brokers='host-1:9092;host-2:9092' . See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	groupIDs = flagutil.NewArrayString("kafka.consumer.topic.groupID", "Defines group.id for the corresponding -kafka.consumer.topic. "+
+		"By default, 'vmagent' group.id is used. See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	formats = flagutil.NewArrayString("kafka.consumer.topic.format", "Data format for the corresponding -kafka.consumer.topic. "+
+		"Valid formats: influx, prometheus, promremotewrite, graphite, jsonline. See also -kafka.consumer.topic.defaultFormat . "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	defaultFormat = flag.String("kafka.consumer.topic.defaultFormat", "promremotewrite", "Expected data format in the topic if -kafka.consumer.topic.format is skipped. "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	concurrency = flagutil.NewArrayInt("kafka.consumer.topic.concurrency", 1, "The number of concurrent consumers for the corresponding -kafka.consumer.topic. "+
+		"Every consumer joins the consumer group from -kafka.consumer.topic.groupID, so topic partitions are distributed among the consumers. "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	isGzipped = flagutil.NewArrayBool("kafka.consumer.topic.isGzipped", "Whether messages in the corresponding -kafka.consumer.topic are gzipped. "+
+		"Only prometheus, jsonline, graphite and influx formats accept gzipped messages. See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	basicAuthUsername = flagutil.NewArrayString("kafka.consumer.topic.basicAuth.username", "Optional SASL username for the corresponding -kafka.consumer.topic. "+
+		"Must be used in conjunction with SASL security protocol specified via -kafka.consumer.topic.options='security.protocol=SASL_SSL;sasl.mechanisms=PLAIN' . "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	basicAuthPassword = flagutil.NewArrayString("kafka.consumer.topic.basicAuth.password", "Optional SASL password for the corresponding -kafka.consumer.topic. "+
+		"Must be used in conjunction with SASL security protocol specified via -kafka.consumer.topic.options='security.protocol=SASL_SSL;sasl.mechanisms=PLAIN' . "+
+		"See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+	options = flagutil.NewArrayString("kafka.consumer.topic.options", "Optional key=value;key1=value1 settings for the consumer of the corresponding -kafka.consumer.topic. "+
+		"Supported settings: security.protocol, sasl.mechanisms, ssl.ca.location, ssl.certificate.location, ssl.key.location, "+
+		"enable.ssl.certificate.verification, client.id, auto.offset.reset. See https://docs.victoriametrics.com/vmagent/#reading-metrics-from-kafka")
+)
+
+// maxPollRecords is the maximum number of messages to process before committing their offsets.
+const maxPollRecords = 1000
+
+// retryInterval is the interval between attempts to push the message when remote storage systems cannot keep up with the data ingestion rate.
+const retryInterval = time.Second
+
+var (
+	consumers []*consumer
+
+	consumersCancel context.CancelFunc
+	consumersWG     sync.WaitGroup
+)
+
+// CheckConfig checks -kafka.consumer.topic* command-line flags.
+func CheckConfig() error {
+	_, err := parseConsumerConfigs()
+	return err
+}
+
+// Init starts consuming data from Kafka topics specified via -kafka.consumer.topic command-line flag.
+//
+// Stop must be called for stopping the started consumers.
+func Init() {
+	if len(*topics) == 0 {
+		return
+	}
+	cfgs, err := parseConsumerConfigs()
+	if err != nil {
+		logger.Fatalf("invalid Kafka consumer config: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumersCancel = cancel
+	for _, cfg := range cfgs {
+		for i := 0; i < cfg.concurrency; i++ {
+			c, err := newConsumer(cfg)
+			if err != nil {
+				logger.Fatalf("cannot start Kafka consumer for -kafka.consumer.topic=%q: %s", cfg.topic, err)
+			}
+			consumers = append(consumers, c)
+			consumersWG.Add(1)
+			go func() {
+				defer consumersWG.Done()
+				c.run(ctx)
+			}()
+		}
+		logger.Infof("started %d Kafka consumers for -kafka.consumer.topic=%q in %s format at brokers %q with group.id=%q",
+			cfg.concurrency, cfg.topic, cfg.format, cfg.brokers, cfg.groupID)
+	}
+}
+
+// Stop stops consumers started via Init.
+func Stop() {
+	if consumersCancel == nil {
+		return
+	}
+	consumersCancel()
+	consumersWG.Wait()
+	for _, c := range consumers {
+		c.client.Close()
+	}
+	consumers = nil
+	consumersCancel = nil
+}
+
+type consumerConfig struct {
+	topic       string
+	brokers     []string
+	groupID     string
+	format      string
+	isGzipped   bool
+	concurrency int
+
+	// opts contains additional options for Kafka client obtained from -kafka.consumer.topic.options and -kafka.consumer.topic.basicAuth.*
+	opts []kgo.Opt
+}
+
+func parseConsumerConfigs() ([]*consumerConfig, error) {
+	var cfgs []*consumerConfig
+	for i := range *topics {
+		cfg, err := parseConsumerConfig(i)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse config for -kafka.consumer.topic=%q: %w", (*topics)[i], err)
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+func parseConsumerConfig(argIdx int) (*consumerConfig, error) {
+	topic := (*topics)[argIdx]
+	if topic == "" {
+		return nil, fmt.Errorf("topic name cannot be empty")
+	}
+
+	var brokerAddrs []string
+	for _, addr := range strings.Split(brokers.GetOptionalArg(argIdx), ";") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			brokerAddrs = append(brokerAddrs, addr)
+		}
+	}
+	if len(brokerAddrs) == 0 {
+		return nil, fmt.Errorf("missing -kafka.consumer.topic.brokers")
+	}
+
+	groupID := groupIDs.GetOptionalArg(argIdx)
+	if groupID == "" {
+		groupID = "vmagent"
+	}
+
+	format := formats.GetOptionalArg(argIdx)
+	if format == "" {
+		format = *defaultFormat
+	}
+	switch format {
+	case "promremotewrite", "influx", "prometheus", "graphite", "jsonline":
+	default:
+		return nil, fmt.Errorf("unsupported format %q; supported formats: influx, prometheus, promremotewrite, graphite, jsonline", format)
+	}
+
+	gzipped := isGzipped.GetOptionalArg(argIdx)
+	if gzipped && format == "promremotewrite" {
+		return nil, fmt.Errorf("-kafka.consumer.topic.isGzipped cannot be used with promremotewrite format, since its messages are already compressed")
+	}
+
+	n := concurrency.GetOptionalArg(argIdx)
+	if n <= 0 {
+		return nil, fmt.Errorf("-kafka.consumer.topic.concurrency must be positive; got %d", n)
+	}
+
+	opts, err := getClientOpts(options.GetOptionalArg(argIdx), basicAuthUsername.GetOptionalArg(argIdx), basicAuthPassword.GetOptionalArg(argIdx))
+	if err != nil {
+		return nil, fmt.Errorf("invalid -kafka.consumer.topic.options: %w", err)
+	}
+
+	cfg := &consumerConfig{
+		topic:       topic,
+		brokers:     brokerAddrs,
+		groupID:     groupID,
+		format:      format,
+		isGzipped:   gzipped,
+		concurrency: n,
+		opts:        opts,
+	}
+	return cfg, nil
+}
+
+// getClientOpts returns Kafka client options for the given s in the form key1=value1;...;keyN=valueN and the given SASL credentials.
+//
+// Option names match librdkafka config option names. See https://github.com/confluentinc/librdkafka/blob/master/CONFIGURATION.md
+func getClientOpts(s, username, password string) ([]kgo.Opt, error) {
+	securityProtocol := "plaintext"
+	saslMechanism := "PLAIN"
+	var caFile, certFile, keyFile string
+	verifyCert := true
+	var opts []kgo.Opt
+	for _, kv := range strings.Split(s, ";") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		n := strings.IndexByte(kv, '=')
+		if n < 0 {
+			return nil, fmt.Errorf("missing '=' in %q", kv)
+		}
+		key, value := strings.TrimSpace(kv[:n]), strings.TrimSpace(kv[n+1:])
+		switch key {
+		case "security.protocol":
+			securityProtocol = strings.ToLower(value)
+			switch securityProtocol {
+			case "plaintext", "ssl", "sasl_plaintext", "sasl_ssl":
+			default:
+				return nil, fmt.Errorf("unsupported security.protocol=%q; supported values: PLAINTEXT, SSL, SASL_PLAINTEXT, SASL_SSL", value)
+			}
+		case "sasl.mechanisms", "sasl.mechanism":
+			saslMechanism = strings.ToUpper(value)
+			switch saslMechanism {
+			case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+			default:
+				return nil, fmt.Errorf("unsupported %s=%q; supported values: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512", key, value)
+			}
+		case "ssl.ca.location":
+			caFile = value
+		case "ssl.certificate.location":
+			certFile = value
+		case "ssl.key.location":
+			keyFile = value
+		case "enable.ssl.certificate.verification":
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %s=%q: %w", key, value, err)
+			}
+			verifyCert = v
+		case "client.id":
+			opts = append(opts, kgo.ClientID(value))
+		case "auto.offset.reset":
+			switch value {
+			case "earliest", "smallest", "beginning":
+				opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+			case "latest", "largest", "end":
+				opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+			default:
+				return nil, fmt.Errorf("unsupported auto.offset.reset=%q; supported values: earliest, latest", value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported option %q", key)
+		}
+	}
+
+	if securityProtocol == "ssl" || securityProtocol == "sasl_ssl" {
+		tlsCfg, err := promauth.NewTLSConfig(certFile, keyFile, caFile, "", !verifyCert)
+		if err != nil {
+			return nil, fmt.Errorf("cannot initialize TLS config: %w", err)
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsCfg))
+	} else if caFile != "" || certFile != "" || keyFile != "" {
+		return nil, fmt.Errorf("ssl.* options require security.protocol=SSL or security.protocol=SASL_SSL")
+	}
+
+	if securityProtocol == "sasl_plaintext" || securityProtocol == "sasl_ssl" {
+		if username == "" {
+			return nil, fmt.Errorf("missing -kafka.consumer.topic.basicAuth.username for security.protocol=%s", strings.ToUpper(securityProtocol))
+		}
+		switch saslMechanism {
+		case "PLAIN":
+			opts = append(opts, kgo.SASL(plain.Auth{User: username, Pass: password}.AsMechanism()))
+		case "SCRAM-SHA-256":
+			opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha256Mechanism()))
+		case "SCRAM-SHA-512":
+			opts = append(opts, kgo.SASL(scram.Auth{User: username, Pass: password}.AsSha512Mechanism()))
+		}
+	} else if username != "" {
+		return nil, fmt.Errorf("-kafka.consumer.topic.basicAuth.username requires security.protocol=SASL_PLAINTEXT or security.protocol=SASL_SSL")
+	}
+
+	return opts, nil
+}
+
+// consumer reads messages from Kafka topic and pushes the parsed data to the configured -remoteWrite.url.
+//
+// Message offsets are committed after the messages are pushed, so the messages are delivered at least once.
+type consumer struct {
+	cfg    *consumerConfig
+	client *kgo.Client
+
+	messagesRead  *metrics.Counter
+	messageErrors *metrics.Counter
+	fetchErrors   *metrics.Counter
+	commitErrors  *metrics.Counter
+}
+
+func newConsumer(cfg *consumerConfig) (*consumer, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.brokers...),
+		kgo.ClientID("vmagent"),
+		kgo.ConsumerGroup(cfg.groupID),
+		kgo.ConsumeTopics(cfg.topic),
+		kgo.DisableAutoCommit(),
+		kgo.BlockRebalanceOnPoll(),
+	}
+	opts = append(opts, cfg.opts...)
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := &consumer{
+		cfg:    cfg,
+		client: client,
+
+		messagesRead:  metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_kafka_consumer_messages_read_total{topic=%q}`, cfg.topic)),
+		messageErrors: metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_kafka_consumer_message_errors_total{topic=%q}`, cfg.topic)),
+		fetchErrors:   metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_kafka_consumer_fetch_errors_total{topic=%q}`, cfg.topic)),
+		commitErrors:  metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_kafka_consumer_commit_errors_total{topic=%q}`, cfg.topic)),
+	}
+	return c, nil
+}
+
+func (c *consumer) run(ctx context.Context) {
+	for {
+		fetches := c.client.PollRecords(ctx, maxPollRecords)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			c.fetchErrors.Inc()
+			logger.Errorf("cannot fetch messages from Kafka topic %q, partition %d: %s", topic, partition, err)
+		})
+
+		records := fetches.Records()
+		n := 0
+		for _, r := range records {
+			if !c.processRecord(ctx, r) {
+				break
+			}
+			n++
+		}
+		if n > 0 {
+			c.commitRecords(records[:n])
+		}
+		c.client.AllowRebalance()
+	}
+}
+
+// processRecord pushes data from r to the configured -remoteWrite.url.
+//
+// It waits until remote storage systems can accept the data. It returns false if ctx is canceled while waiting.
+func (c *consumer) processRecord(ctx context.Context, r *kgo.Record) bool {
+	c.messagesRead.Inc()
+	for {
+		err := c.insertMessage(r.Value)
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, remotewrite.ErrQueueFullHTTPRetry) {
+			c.messageErrors.Inc()
+			logger.Errorf("skipping message at offset %d from Kafka topic %q, partition %d: %s", r.Offset, r.Topic, r.Partition, err)
+			return true
+		}
+
+		// Suspend consuming data from Kafka until remote storage systems can accept the data.
+		t := time.NewTimer(retryInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		case <-t.C:
+		}
+	}
+}
+
+func (c *consumer) insertMessage(data []byte) error {
+	var r io.Reader = bytes.NewReader(data)
+	if c.cfg.isGzipped {
+		zr, err := protoparserutil.GetUncompressedReader(r, "gzip")
+		if err != nil {
+			return fmt.Errorf("cannot decompress gzipped message: %w", err)
+		}
+		defer protoparserutil.PutUncompressedReader(zr)
+		r = zr
+	}
+
+	switch c.cfg.format {
+	case "promremotewrite":
+		return promremotewrite.InsertHandlerForReader(nil, r, false)
+	case "influx":
+		return influx.InsertHandlerForReader(nil, r, "")
+	case "prometheus":
+		return prometheusimport.InsertHandlerForReader(nil, r)
+	case "graphite":
+		return graphite.InsertHandler(r)
+	case "jsonline":
+		return vmimport.InsertHandlerForReader(nil, r)
+	default:
+		logger.Panicf("BUG: unexpected format %q", c.cfg.format)
+		return nil
+	}
+}
+
+func (c *consumer) commitRecords(records []*kgo.Record) {
+	// Use a separate context, so the offsets for the processed messages are committed during graceful shutdown.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.client.CommitRecords(ctx, records...); err != nil {
+		c.commitErrors.Inc()
+		logger.Errorf("cannot commit offsets for messages from Kafka topic %q: %s; these messages may be consumed again", c.cfg.topic, err)
+	}
+}
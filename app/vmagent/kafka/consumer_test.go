@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+)
+
+func TestGetClientOpts_Success(t *testing.T) {
+	f := func(s, username string, optsLenExpected int) {
+		t.Helper()
+
+		opts, err := getClientOpts(s, username, "password")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(opts) != optsLenExpected {
+			t.Fatalf("unexpected number of options; got %d; want %d", len(opts), optsLenExpected)
+		}
+	}
+
+	f("", "", 0)
+	f("security.protocol=PLAINTEXT", "", 0)
+	f("client.id=foo; auto.offset.reset=earliest", "", 2)
+	f("auto.offset.reset=latest;", "", 1)
+	f("security.protocol=SSL;enable.ssl.certificate.verification=false", "", 1)
+	f("security.protocol=SASL_PLAINTEXT", "user", 1)
+	f("security.protocol=SASL_SSL;sasl.mechanisms=SCRAM-SHA-512", "user", 2)
+	f("security.protocol=sasl_plaintext;sasl.mechanism=scram-sha-256", "user", 1)
+}
+
+func TestGetClientOpts_Failure(t *testing.T) {
+	f := func(s, username string) {
+		t.Helper()
+
+		_, err := getClientOpts(s, username, "password")
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// missing value
+	f("client.id", "")
+
+	// unsupported option
+	f("foo=bar", "")
+
+	// invalid values
+	f("security.protocol=foo", "")
+	f("sasl.mechanisms=GSSAPI", "")
+	f("auto.offset.reset=foo", "")
+	f("enable.ssl.certificate.verification=foo", "")
+
+	// ssl options without ssl security protocol
+	f("ssl.ca.location=/path/to/ca", "")
+
+	// missing file for ssl option
+	f("security.protocol=SSL;ssl.ca.location=/non/existing/ca", "")
+
+	// missing username for sasl security protocol
+	f("security.protocol=SASL_SSL", "")
+
+	// username without sasl security protocol
+	f("security.protocol=SSL", "user")
+}
+
+func TestParseConsumerConfigs(t *testing.T) {
+	defer func() {
+		*topics = nil
+		*brokers = nil
+		*formats = nil
+		*isGzipped = nil
+	}()
+
+	f := func(topicsValue, brokersValue, formatsValue []string, isGzippedValue []bool, resultExpected []*consumerConfig) {
+		t.Helper()
+
+		*topics = flagutil.ArrayString(topicsValue)
+		*brokers = flagutil.ArrayString(brokersValue)
+		*formats = flagutil.ArrayString(formatsValue)
+		*isGzipped = flagutil.ArrayBool(isGzippedValue)
+		cfgs, err := parseConsumerConfigs()
+		if resultExpected == nil {
+			if err == nil {
+				t.Fatalf("expecting non-nil error")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, cfg := range cfgs {
+			cfg.opts = nil
+		}
+		if !reflect.DeepEqual(cfgs, resultExpected) {
+			t.Fatalf("unexpected configs;\ngot\n%+v\nwant\n%+v", cfgs, resultExpected)
+		}
+	}
+
+	// the default format and group.id
+	f([]string{"foo"}, []string{"host1:9092;host2:9092"}, nil, nil, []*consumerConfig{{
+		topic:       "foo",
+		brokers:     []string{"host1:9092", "host2:9092"},
+		groupID:     "vmagent",
+		format:      "promremotewrite",
+		concurrency: 1,
+	}})
+
+	// per-topic settings
+	f([]string{"foo", "bar"}, []string{"host1:9092", "host2:9092"}, []string{"influx", "jsonline"}, []bool{false, true}, []*consumerConfig{
+		{
+			topic:       "foo",
+			brokers:     []string{"host1:9092"},
+			groupID:     "vmagent",
+			format:      "influx",
+			concurrency: 1,
+		},
+		{
+			topic:       "bar",
+			brokers:     []string{"host2:9092"},
+			groupID:     "vmagent",
+			format:      "jsonline",
+			isGzipped:   true,
+			concurrency: 1,
+		},
+	})
+
+	// missing brokers
+	f([]string{"foo"}, nil, nil, nil, nil)
+
+	// unsupported format
+	f([]string{"foo"}, []string{"host:9092"}, []string{"csv"}, nil, nil)
+
+	// gzipped promremotewrite messages
+	f([]string{"foo"}, []string{"host:9092"}, nil, []bool{true}, nil)
+}
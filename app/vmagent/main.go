@@ -12,12 +12,15 @@ import (
 
 	"github.com/VictoriaMetrics/metrics"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/clientcertauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/csvimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/datadogsketches"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/datadogv1"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/datadogv2"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/influx"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/kafka"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/namespaces"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/native"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/newrelic"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/opentelemetry"
@@ -25,7 +28,9 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/opentsdbhttp"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/prometheusimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/promremotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/pushgateway"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/selfprofile"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/vmimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
@@ -61,6 +66,10 @@ var (
 		"See also -influxListenAddr.useProxyProtocol")
 	influxUseProxyProtocol = flag.Bool("influxListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -influxListenAddr . "+
 		"See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
+	influxUnixSocketPath = flag.String("influxUnixSocketPath", "", "Path to unix stream socket to listen for InfluxDB line protocol data. Doesn't work if empty. "+
+		"This socket can be used with unix:// address at Telegraf socket_writer output. See also -influxUnixgramSocketPath")
+	influxUnixgramSocketPath = flag.String("influxUnixgramSocketPath", "", "Path to unix datagram socket to listen for InfluxDB line protocol data. Doesn't work if empty. "+
+		"This socket can be used with unixgram:// address at Telegraf socket_writer output. See also -influxUnixSocketPath, -influx.datagramBatchSize and -influx.datagramFlushInterval")
 	graphiteListenAddr = flag.String("graphiteListenAddr", "", "TCP and UDP address to listen for Graphite plaintext data. Usually :2003 must be set. Doesn't work if empty. "+
 		"See also -graphiteListenAddr.useProxyProtocol")
 	graphiteUseProxyProtocol = flag.Bool("graphiteListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -graphiteListenAddr . "+
@@ -77,7 +86,7 @@ var (
 	configAuthKey = flagutil.NewPassword("configAuthKey", "Authorization key for accessing /config page. It must be passed via authKey query arg. It overrides -httpAuth.*")
 	reloadAuthKey = flagutil.NewPassword("reloadAuthKey", "Auth key for /-/reload http endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*")
 	dryRun        = flag.Bool("dryRun", false, "Whether to check config files without running vmagent. The following files are checked: "+
-		"-promscrape.config, -remoteWrite.relabelConfig, -remoteWrite.urlRelabelConfig, -remoteWrite.streamAggr.config . "+
+		"-promscrape.config, -remoteWrite.relabelConfig, -remoteWrite.urlRelabelConfig, -remoteWrite.streamAggr.config, -remoteWrite.tenantLimitsFile, -mtls.authConfig, -namespaces.config . "+
 		"Unknown config entries aren't allowed in -promscrape.config by default. This can be changed by passing -promscrape.config.strictParse=false command-line flag")
 	maxLabelsPerTimeseries = flag.Int("maxLabelsPerTimeseries", 0, "The maximum number of labels per time series to be accepted. Series with superfluous labels are ignored. In this case the vm_rows_ignored_total{reason=\"too_many_labels\"} metric at /metrics page is incremented")
 	maxLabelNameLen        = flag.Int("maxLabelNameLen", 0, "The maximum length of label names in the accepted time series. Series with longer label name are ignored. In this case the vm_rows_ignored_total{reason=\"too_long_label_name\"} metric at /metrics page is incremented")
@@ -85,10 +94,12 @@ var (
 )
 
 var (
-	influxServer       *influxserver.Server
-	graphiteServer     *graphiteserver.Server
-	opentsdbServer     *opentsdbserver.Server
-	opentsdbhttpServer *opentsdbhttpserver.Server
+	influxServer         *influxserver.Server
+	influxUnixServer     *influxserver.Server
+	influxUnixgramServer *influxserver.Server
+	graphiteServer       *graphiteserver.Server
+	opentsdbServer       *opentsdbserver.Server
+	opentsdbhttpServer   *opentsdbhttpserver.Server
 )
 
 var (
@@ -133,6 +144,18 @@ func main() {
 		if err := remotewrite.CheckStreamAggrConfigs(); err != nil {
 			logger.Fatalf("error when checking -streamAggr.config and -remoteWrite.streamAggr.config: %s", err)
 		}
+		if err := remotewrite.CheckTenantLimitsConfig(); err != nil {
+			logger.Fatalf("error when checking -remoteWrite.tenantLimitsFile: %s", err)
+		}
+		if err := clientcertauth.CheckConfig(); err != nil {
+			logger.Fatalf("error when checking -mtls.authConfig: %s", err)
+		}
+		if err := namespaces.CheckConfig(); err != nil {
+			logger.Fatalf("error when checking -namespaces.config: %s", err)
+		}
+		if err := kafka.CheckConfig(); err != nil {
+			logger.Fatalf("error when checking -kafka.consumer.topic* flags: %s", err)
+		}
 		logger.Infof("all the configs are ok; exiting with 0 status code")
 		return
 	}
@@ -145,12 +168,23 @@ func main() {
 	startTime := time.Now()
 	remotewrite.StartIngestionRateLimiter()
 	remotewrite.Init()
+	clientcertauth.Init()
 	protoparserutil.StartUnmarshalWorkers()
 	if len(*influxListenAddr) > 0 {
 		influxServer = influxserver.MustStart(*influxListenAddr, *influxUseProxyProtocol, func(r io.Reader) error {
 			return influx.InsertHandlerForReader(nil, r, "")
 		})
 	}
+	if len(*influxUnixSocketPath) > 0 {
+		influxUnixServer = influxserver.MustStartUnix(*influxUnixSocketPath, func(r io.Reader) error {
+			return influx.InsertHandlerForReader(nil, r, "")
+		})
+	}
+	if len(*influxUnixgramSocketPath) > 0 {
+		influxUnixgramServer = influxserver.MustStartUnixgram(*influxUnixgramSocketPath, func(r io.Reader) error {
+			return influx.InsertHandlerForReader(nil, r, "")
+		})
+	}
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer = graphiteserver.MustStart(*graphiteListenAddr, *graphiteUseProxyProtocol, graphite.InsertHandler)
 	}
@@ -164,6 +198,9 @@ func main() {
 	}
 
 	promscrape.Init(remotewrite.PushDropSamplesOnFailure)
+	namespaces.Init()
+	kafka.Init()
+	selfprofile.Init(remotewrite.GetMaxQueueUtilization)
 
 	go httpserver.Serve(listenAddrs, useProxyProtocol, requestHandler)
 	logger.Infof("started vmagent in %.3f seconds", time.Since(startTime).Seconds())
@@ -181,11 +218,20 @@ func main() {
 	}
 	logger.Infof("successfully shut down the webservice in %.3f seconds", time.Since(startTime).Seconds())
 
+	selfprofile.Stop()
+	kafka.Stop()
+	namespaces.Stop()
 	promscrape.Stop()
 
 	if len(*influxListenAddr) > 0 {
 		influxServer.MustStop()
 	}
+	if len(*influxUnixSocketPath) > 0 {
+		influxUnixServer.MustStop()
+	}
+	if len(*influxUnixgramSocketPath) > 0 {
+		influxUnixgramServer.MustStop()
+	}
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer.MustStop()
 	}
@@ -196,6 +242,7 @@ func main() {
 		opentsdbhttpServer.MustStop()
 	}
 	protoparserutil.StopUnmarshalWorkers()
+	clientcertauth.Stop()
 	remotewrite.Stop()
 
 	logger.Infof("successfully stopped vmagent in %.3f seconds", time.Since(startTime).Seconds())
@@ -258,6 +305,20 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 	}
 
 	path := strings.Replace(r.URL.Path, "//", "/", -1)
+	if err := clientcertauth.CheckRequest(r, path); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	if strings.HasPrefix(path, "/metrics/job") || strings.HasPrefix(path, "/prometheus/metrics/job") {
+		pushgatewayRequests.Inc()
+		if err := pushgateway.InsertHandler(nil, r); err != nil {
+			pushgatewayErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		writePushgatewayResponse(w, r)
+		return true
+	}
 	if strings.HasPrefix(path, "/prometheus/api/v1/import/prometheus") || strings.HasPrefix(path, "/api/v1/import/prometheus") {
 		prometheusimportRequests.Inc()
 		if err := prometheusimport.InsertHandler(nil, r); err != nil {
@@ -483,6 +544,10 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		procutil.SelfSIGHUP()
 		w.WriteHeader(http.StatusOK)
 		return true
+	case "/prometheus/api/v1/status/self_profiles", "/api/v1/status/self_profiles",
+		"/prometheus/api/v1/status/self_profiles/download", "/api/v1/status/self_profiles/download":
+		selfprofile.RequestHandler(w, r, path)
+		return true
 	case "/ready":
 		if rdy := promscrape.PendingScrapeConfigs.Load(); rdy > 0 {
 			errMsg := fmt.Sprintf("waiting for scrapes to init, left: %d", rdy)
@@ -520,6 +585,16 @@ func processMultitenantRequest(w http.ResponseWriter, r *http.Request, path stri
 		httpserver.Errorf(w, r, "cannot obtain auth token: %s", err)
 		return true
 	}
+	if strings.HasPrefix(p.Suffix, "prometheus/metrics/job") {
+		pushgatewayRequests.Inc()
+		if err := pushgateway.InsertHandler(at, r); err != nil {
+			pushgatewayErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		writePushgatewayResponse(w, r)
+		return true
+	}
 	if strings.HasPrefix(p.Suffix, "prometheus/api/v1/import/prometheus") {
 		prometheusimportRequests.Inc()
 		if err := prometheusimport.InsertHandler(at, r); err != nil {
@@ -686,6 +761,15 @@ func processMultitenantRequest(w http.ResponseWriter, r *http.Request, path stri
 	}
 }
 
+// writePushgatewayResponse writes the response status code in the same way as Pushgateway does.
+func writePushgatewayResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 var (
 	prometheusWriteRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/write", protocol="promremotewrite"}`)
 	prometheusWriteErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/write", protocol="promremotewrite"}`)
@@ -699,6 +783,9 @@ var (
 	prometheusimportRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/import/prometheus", protocol="prometheusimport"}`)
 	prometheusimportErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/import/prometheus", protocol="prometheusimport"}`)
 
+	pushgatewayRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/metrics/job", protocol="pushgateway"}`)
+	pushgatewayErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/metrics/job", protocol="pushgateway"}`)
+
 	nativeimportRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 	nativeimportErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 
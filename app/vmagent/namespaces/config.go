@@ -0,0 +1,179 @@
+package namespaces
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
+)
+
+// Config represents -namespaces.config file.
+type Config struct {
+	Namespaces []*NamespaceConfig `yaml:"namespaces"`
+
+	// baseDir is the directory where the config has been loaded from.
+	baseDir string
+}
+
+// NamespaceConfig represents a single namespace at -namespaces.config file.
+type NamespaceConfig struct {
+	// Name is the namespace name. It must be unique across -namespaces.config.
+	Name string `yaml:"name"`
+
+	// ScrapeConfig is the path to Prometheus-compatible config file with scrape_configs section.
+	ScrapeConfig string `yaml:"scrape_config"`
+
+	// RelabelConfigs are applied to all the scraped samples before sending them to RemoteWrite.
+	RelabelConfigs []promrelabel.RelabelConfig `yaml:"relabel_configs,omitempty"`
+
+	// RemoteWrite contains remote storage systems to send scraped samples to.
+	RemoteWrite []*RemoteWriteConfig `yaml:"remote_write"`
+
+	// MaxHourlySeries limits the number of unique series sent to RemoteWrite per hour.
+	MaxHourlySeries int `yaml:"max_hourly_series,omitempty"`
+
+	scrapeConfigPath string
+	relabelConfigs   *promrelabel.ParsedConfigs
+}
+
+// RemoteWriteConfig represents remote storage system for the namespace.
+type RemoteWriteConfig struct {
+	URL      string `yaml:"url"`
+	ProxyURL string `yaml:"proxy_url,omitempty"`
+
+	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
+
+	authConfig *promauth.Config
+}
+
+var namespaceNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func loadConfig(path string) (*Config, error) {
+	data, err := promscrape.ReadVerifiedConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain abs path for %q: %w", path, err)
+	}
+	cfg, err := parseConfig(data, filepath.Dir(absPath))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func parseConfig(data []byte, baseDir string) (*Config, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.baseDir = baseDir
+
+	names := make(map[string]struct{}, len(cfg.Namespaces))
+	for i, nc := range cfg.Namespaces {
+		if nc == nil {
+			return nil, fmt.Errorf("namespace #%d cannot be empty", i+1)
+		}
+		if _, ok := names[nc.Name]; ok {
+			return nil, fmt.Errorf("duplicate namespace name %q", nc.Name)
+		}
+		names[nc.Name] = struct{}{}
+		if err := nc.init(baseDir); err != nil {
+			return nil, fmt.Errorf("invalid namespace #%d: %w", i+1, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// names returns names for all the namespaces in cfg.
+func (cfg *Config) names() []string {
+	names := make([]string, len(cfg.Namespaces))
+	for i, nc := range cfg.Namespaces {
+		names[i] = nc.Name
+	}
+	return names
+}
+
+func (nc *NamespaceConfig) init(baseDir string) error {
+	if !namespaceNameRegexp.MatchString(nc.Name) {
+		return fmt.Errorf("namespace name %q must match %s", nc.Name, namespaceNameRegexp)
+	}
+	if nc.ScrapeConfig == "" {
+		return fmt.Errorf("missing `scrape_config` for namespace %q", nc.Name)
+	}
+	nc.scrapeConfigPath = fscore.GetFilepath(baseDir, nc.ScrapeConfig)
+
+	pcs, err := promrelabel.ParseRelabelConfigs(nc.RelabelConfigs)
+	if err != nil {
+		return fmt.Errorf("cannot parse `relabel_configs` for namespace %q: %w", nc.Name, err)
+	}
+	nc.relabelConfigs = pcs
+
+	if len(nc.RemoteWrite) == 0 {
+		return fmt.Errorf("missing `remote_write` for namespace %q", nc.Name)
+	}
+	for i, rw := range nc.RemoteWrite {
+		if rw == nil {
+			return fmt.Errorf("`remote_write` #%d for namespace %q cannot be empty", i+1, nc.Name)
+		}
+		if err := rw.init(baseDir); err != nil {
+			return fmt.Errorf("invalid `remote_write` #%d for namespace %q: %w", i+1, nc.Name, err)
+		}
+	}
+	if nc.MaxHourlySeries < 0 {
+		return fmt.Errorf("`max_hourly_series` for namespace %q cannot be negative; got %d", nc.Name, nc.MaxHourlySeries)
+	}
+	return nil
+}
+
+func (rw *RemoteWriteConfig) init(baseDir string) error {
+	u, err := url.Parse(rw.URL)
+	if err != nil {
+		return fmt.Errorf("cannot parse `url`: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme in `url`=%q; want `http` or `https`", rw.URL)
+	}
+	if rw.ProxyURL != "" {
+		if !strings.Contains(rw.ProxyURL, "://") {
+			return fmt.Errorf("`proxy_url`=%q must start with `http://`, `https://` or `socks5://`", rw.ProxyURL)
+		}
+		if _, err := url.Parse(rw.ProxyURL); err != nil {
+			return fmt.Errorf("cannot parse `proxy_url`: %w", err)
+		}
+	}
+	ac, err := rw.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return fmt.Errorf("cannot initialize auth config: %w", err)
+	}
+	rw.authConfig = ac
+	return nil
+}
+
+// marshalJSON returns JSON representation of nc, which can be used for detecting changes in nc.
+//
+// JSON is used instead of YAML, since YAML representation hides secrets.
+func (nc *NamespaceConfig) marshalJSON() string {
+	data, err := json.Marshal(nc)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal namespace config: %s", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,210 @@
+package namespaces
+
+import (
+	"testing"
+)
+
+func TestParseConfigSuccess(t *testing.T) {
+	f := func(data string, namesExpected []string) {
+		t.Helper()
+
+		cfg, err := parseConfig([]byte(data), "/etc/vmagent")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(cfg.Namespaces) != len(namesExpected) {
+			t.Fatalf("unexpected number of namespaces; got %d; want %d", len(cfg.Namespaces), len(namesExpected))
+		}
+		for i, nc := range cfg.Namespaces {
+			if nc.Name != namesExpected[i] {
+				t.Fatalf("unexpected name for namespace #%d; got %q; want %q", i+1, nc.Name, namesExpected[i])
+			}
+		}
+	}
+
+	f(`namespaces: []`, nil)
+	f(`
+namespaces:
+- name: team-a
+  scrape_config: team-a/scrape.yml
+  remote_write:
+  - url: http://vminsert:8480/insert/1/prometheus/api/v1/write
+- name: team_b
+  scrape_config: /etc/team-b/scrape.yml
+  relabel_configs:
+  - target_label: team
+    replacement: b
+  remote_write:
+  - url: https://vm-b:8428/api/v1/write
+    basic_auth:
+      username: foo
+      password: bar
+    proxy_url: http://proxy:3128
+  - url: https://vm-b-replica:8428/api/v1/write
+    bearer_token: secret
+  max_hourly_series: 1000
+`, []string{"team-a", "team_b"})
+}
+
+func TestParseConfigFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		_, err := parseConfig([]byte(data), "/etc/vmagent")
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown field
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+  unknown: bar
+`)
+
+	// missing name
+	f(`
+namespaces:
+- scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+`)
+
+	// invalid name
+	f(`
+namespaces:
+- name: foo/bar
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+`)
+
+	// duplicate names
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+- name: foo
+  scrape_config: bar.yml
+  remote_write:
+  - url: http://bar/api/v1/write
+`)
+
+	// missing scrape_config
+	f(`
+namespaces:
+- name: foo
+  remote_write:
+  - url: http://foo/api/v1/write
+`)
+
+	// missing remote_write
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+`)
+
+	// unsupported url scheme
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: ftp://foo/api/v1/write
+`)
+
+	// invalid proxy_url
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+    proxy_url: proxy:3128
+`)
+
+	// invalid auth config
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+    bearer_token: foo
+    bearer_token_file: bar
+`)
+
+	// invalid relabel_configs
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  relabel_configs:
+  - action: foobar
+  remote_write:
+  - url: http://foo/api/v1/write
+`)
+
+	// negative max_hourly_series
+	f(`
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+  max_hourly_series: -1
+`)
+}
+
+func TestNamespaceConfigMarshalJSON(t *testing.T) {
+	f := func(a, b string, equalExpected bool) {
+		t.Helper()
+
+		cfgA, err := parseConfig([]byte(a), "/etc/vmagent")
+		if err != nil {
+			t.Fatalf("cannot parse config a: %s", err)
+		}
+		cfgB, err := parseConfig([]byte(b), "/etc/vmagent")
+		if err != nil {
+			t.Fatalf("cannot parse config b: %s", err)
+		}
+		isEqual := cfgA.Namespaces[0].marshalJSON() == cfgB.Namespaces[0].marshalJSON()
+		if isEqual != equalExpected {
+			t.Fatalf("unexpected equality; got %v; want %v", isEqual, equalExpected)
+		}
+	}
+
+	a := `
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+    basic_auth:
+      username: foo
+      password: bar
+`
+	f(a, a, true)
+
+	// changed password must be detected, since it is hidden in YAML representation.
+	f(a, `
+namespaces:
+- name: foo
+  scrape_config: foo.yml
+  remote_write:
+  - url: http://foo/api/v1/write
+    basic_auth:
+      username: foo
+      password: baz
+`, false)
+}
@@ -0,0 +1,256 @@
+package namespaces
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
+)
+
+var (
+	namespacesConfig = flag.String("namespaces.config", "", "Optional path to config file with vmagent namespaces. "+
+		"Every namespace has its own scrape configs, relabeling rules, remote storage systems and series limits, which are isolated from other namespaces "+
+		"and from -promscrape.config. See https://docs.victoriametrics.com/vmagent/#namespaces")
+	configCheckInterval = flag.Duration("namespaces.configCheckInterval", 0, "Interval for checking for changes in -namespaces.config file "+
+		"and in scrape config files referred by it. By default, the checking is disabled. Send SIGHUP signal in order to force config check for changes")
+)
+
+var (
+	configReloads      = metrics.NewCounter(`vmagent_namespaces_config_reloads_total`)
+	configReloadErrors = metrics.NewCounter(`vmagent_namespaces_config_reloads_errors_total`)
+	configSuccess      = metrics.NewGauge(`vmagent_namespaces_config_last_reload_successful`, nil)
+	configTimestamp    = metrics.NewCounter(`vmagent_namespaces_config_last_reload_success_timestamp_seconds`)
+
+	namespaceStartErrors = metrics.NewCounter(`vmagent_namespaces_start_errors_total`)
+
+	_ = metrics.NewGauge(`vmagent_namespaces_active`, func() float64 {
+		nssLock.Lock()
+		n := len(nss)
+		nssLock.Unlock()
+		return float64(n)
+	})
+)
+
+var (
+	// nss contains the running namespaces keyed by their names.
+	nss     map[string]*namespace
+	nssLock sync.Mutex
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+)
+
+// namespace is a running vmagent namespace.
+type namespace struct {
+	cfgData string
+
+	rw      *remotewrite.Namespace
+	scraper *promscrape.NamespaceScraper
+}
+
+// CheckConfig checks -namespaces.config and scrape config files referred by it.
+func CheckConfig() error {
+	if *namespacesConfig == "" {
+		return nil
+	}
+	cfg, err := loadConfig(*namespacesConfig)
+	if err != nil {
+		return err
+	}
+	for _, nc := range cfg.Namespaces {
+		if err := promscrape.CheckNamespaceConfig(nc.Name, nc.scrapeConfigPath); err != nil {
+			return fmt.Errorf("invalid `scrape_config` for namespace %q: %w", nc.Name, err)
+		}
+	}
+	return nil
+}
+
+// Init starts namespaces from -namespaces.config.
+//
+// Stop must be called when namespaces are no longer needed.
+func Init() {
+	if *namespacesConfig == "" {
+		return
+	}
+
+	// Register SIGHUP handler for config reload before loadConfig.
+	// This guarantees that the config will be re-read if the signal arrives just after loadConfig.
+	sighupCh := procutil.NewSighupChan()
+
+	cfg, err := loadConfig(*namespacesConfig)
+	if err != nil {
+		logger.Fatalf("cannot load -namespaces.config=%q: %s", *namespacesConfig, err)
+	}
+	nss = make(map[string]*namespace)
+	for _, nc := range cfg.Namespaces {
+		ns, err := startNamespace(nc)
+		if err != nil {
+			logger.Fatalf("cannot start namespace from -namespaces.config=%q: %s", *namespacesConfig, err)
+		}
+		nss[nc.Name] = ns
+	}
+	remotewrite.DropDanglingNamespaceQueues(cfg.names())
+	configSuccess.Set(1)
+	configTimestamp.Set(fasttime.UnixTimestamp())
+	logger.Infof("started %d namespaces from -namespaces.config=%q", len(nss), *namespacesConfig)
+
+	stopCh = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runConfigReloader(sighupCh)
+	}()
+}
+
+// Stop stops all the namespaces started by Init.
+func Stop() {
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	wg.Wait()
+	stopCh = nil
+
+	nssLock.Lock()
+	for name, ns := range nss {
+		ns.mustStop()
+		delete(nss, name)
+	}
+	nssLock.Unlock()
+}
+
+func runConfigReloader(sighupCh <-chan os.Signal) {
+	var tickerCh <-chan time.Time
+	if *configCheckInterval > 0 {
+		ticker := time.NewTicker(*configCheckInterval)
+		defer ticker.Stop()
+		tickerCh = ticker.C
+	}
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-sighupCh:
+			logger.Infof("SIGHUP received; reloading -namespaces.config=%q", *namespacesConfig)
+		case <-tickerCh:
+		}
+		reloadConfig()
+	}
+}
+
+func reloadConfig() {
+	cfg, err := loadConfig(*namespacesConfig)
+	if err != nil {
+		configReloadErrors.Inc()
+		configSuccess.Set(0)
+		logger.Errorf("cannot load -namespaces.config=%q: %s; continuing with the previous config", *namespacesConfig, err)
+		return
+	}
+	configSuccess.Set(1)
+	if updateNamespaces(cfg) {
+		configReloads.Inc()
+		configTimestamp.Set(fasttime.UnixTimestamp())
+	}
+}
+
+// updateNamespaces starts, restarts and stops namespaces according to cfg.
+//
+// Unchanged namespaces are kept running, while their scrape config files are re-read.
+// It returns true if at least a single namespace has been started, restarted or stopped.
+func updateNamespaces(cfg *Config) bool {
+	nssLock.Lock()
+	defer nssLock.Unlock()
+
+	var started, stopped, restarted int
+	current := make(map[string]struct{}, len(cfg.Namespaces))
+	for _, nc := range cfg.Namespaces {
+		current[nc.Name] = struct{}{}
+		nsPrev := nss[nc.Name]
+		if nsPrev != nil && nsPrev.cfgData == nc.marshalJSON() {
+			// The namespace didn't change. Re-read its scrape config file, since it could be changed.
+			nsPrev.scraper.Reload()
+			continue
+		}
+		// Verify the scrape config before stopping the previous namespace,
+		// so the previous namespace continues working on invalid config.
+		if err := promscrape.CheckNamespaceConfig(nc.Name, nc.scrapeConfigPath); err != nil {
+			namespaceStartErrors.Inc()
+			logger.Errorf("invalid `scrape_config` for namespace %q: %s; continuing with the previous namespace config", nc.Name, err)
+			continue
+		}
+		if nsPrev != nil {
+			// The previous namespace must be stopped before starting the new one, since they share persistent queues.
+			nsPrev.mustStop()
+			delete(nss, nc.Name)
+		}
+		ns, err := startNamespace(nc)
+		if err != nil {
+			namespaceStartErrors.Inc()
+			logger.Errorf("cannot start namespace: %s", err)
+			continue
+		}
+		nss[nc.Name] = ns
+		if nsPrev != nil {
+			restarted++
+		} else {
+			started++
+		}
+	}
+	for name, ns := range nss {
+		if _, ok := current[name]; !ok {
+			ns.mustStop()
+			delete(nss, name)
+			stopped++
+		}
+	}
+	if stopped > 0 {
+		remotewrite.DropDanglingNamespaceQueues(cfg.names())
+	}
+	updated := started + stopped + restarted
+	if updated == 0 {
+		return false
+	}
+	logger.Infof("updated %d namespaces from -namespaces.config=%q; started=%d, stopped=%d, restarted=%d",
+		updated, *namespacesConfig, started, stopped, restarted)
+	return true
+}
+
+func startNamespace(nc *NamespaceConfig) (*namespace, error) {
+	targets := make([]remotewrite.NamespaceTarget, len(nc.RemoteWrite))
+	for i, rw := range nc.RemoteWrite {
+		targets[i] = remotewrite.NamespaceTarget{
+			URL:        rw.URL,
+			AuthConfig: rw.authConfig,
+			ProxyURL:   rw.ProxyURL,
+		}
+	}
+	rw, err := remotewrite.NewNamespace(nc.Name, targets, nc.relabelConfigs, nc.MaxHourlySeries)
+	if err != nil {
+		return nil, err
+	}
+	scraper, err := promscrape.StartNamespaceScraper(nc.Name, nc.scrapeConfigPath, rw.Push)
+	if err != nil {
+		rw.MustStop()
+		return nil, fmt.Errorf("cannot start scraper for namespace %q: %w", nc.Name, err)
+	}
+	return &namespace{
+		cfgData: nc.marshalJSON(),
+		rw:      rw,
+		scraper: scraper,
+	}, nil
+}
+
+func (ns *namespace) mustStop() {
+	// The scraper must be stopped before the remote write, since it pushes the scraped data to the remote write.
+	ns.scraper.MustStop()
+	ns.rw.MustStop()
+}
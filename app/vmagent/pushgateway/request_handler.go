@@ -0,0 +1,314 @@
+package pushgateway
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus/stream"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/tenantmetrics"
+)
+
+var maxGroups = flag.Int("pushgateway.maxGroups", 10_000, "The maximum number of groups, which can be pushed via Pushgateway protocol. "+
+	"Pushes for new groups are rejected when the limit is reached. See https://docs.victoriametrics.com/vmagent/#pushgateway-protocol")
+
+var (
+	rowsInserted       = metrics.NewCounter(`vmagent_rows_inserted_total{type="pushgateway"}`)
+	rowsTenantInserted = tenantmetrics.NewCounterMap(`vmagent_tenant_inserted_rows_total{type="pushgateway"}`)
+	rowsPerInsert      = metrics.NewHistogram(`vmagent_rows_per_insert{type="pushgateway"}`)
+	staleSeries        = metrics.NewCounter(`vmagent_pushgateway_stale_series_total`)
+
+	_ = metrics.NewGauge(`vmagent_pushgateway_groups`, func() float64 {
+		return float64(gs.groupsCount())
+	})
+)
+
+// gs holds the series for all the groups pushed via Pushgateway protocol.
+var gs = newGroupStore()
+
+// InsertHandler processes Pushgateway-compatible `PUT`, `POST` and `DELETE` requests to `/metrics/job/...`.
+//
+// The grouping key from the request path is converted to labels, which are added to all the pushed series.
+// `PUT` replaces all the previously pushed series for the group, `POST` replaces the previously pushed series
+// with the same metric names, while `DELETE` removes all the series for the group.
+// Staleness markers are sent for the replaced and removed series.
+//
+// See https://github.com/prometheus/pushgateway#api
+func InsertHandler(at *auth.Token, req *http.Request) error {
+	switch req.Method {
+	case http.MethodPut, http.MethodPost, http.MethodDelete:
+	default:
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("unsupported method %q; supported methods: PUT, POST, DELETE", req.Method),
+			StatusCode: http.StatusMethodNotAllowed,
+		}
+	}
+	groupingLabels, err := protoparserutil.GetExtraLabels(req)
+	if err != nil {
+		return err
+	}
+	if len(groupingLabels) == 0 {
+		return fmt.Errorf("missing grouping key in the request path %q; it must start with /metrics/job/<job_name>", req.URL.Path)
+	}
+	sortLabels(groupingLabels)
+
+	var ss []*series
+	if req.Method != http.MethodDelete {
+		ss, err = readSeries(req, groupingLabels)
+		if err != nil {
+			return err
+		}
+	}
+
+	key := at.String() + "/" + marshalLabels(groupingLabels)
+	return gs.process(key, req.Method, ss, func(ss []*series, stale [][]prompbmarshal.Label) error {
+		return pushSeries(at, ss, stale)
+	})
+}
+
+// series is a single series pushed via Pushgateway protocol.
+type series struct {
+	key        string
+	metricName string
+	labels     []prompbmarshal.Label
+	value      float64
+	timestamp  int64
+}
+
+func readSeries(req *http.Request, groupingLabels []prompbmarshal.Label) ([]*series, error) {
+	defaultTimestamp, err := protoparserutil.GetTimestamp(req)
+	if err != nil {
+		return nil, err
+	}
+	if defaultTimestamp <= 0 {
+		defaultTimestamp = time.Now().UnixMilli()
+	}
+
+	var ssLock sync.Mutex
+	var ss []*series
+	encoding := req.Header.Get("Content-Encoding")
+	err = stream.Parse(req.Body, defaultTimestamp, encoding, true, func(rows []prometheus.Row) error {
+		// rows are re-used after returning from the callback, so they must be copied.
+		rowsSeries := make([]*series, 0, len(rows))
+		for i := range rows {
+			rowsSeries = append(rowsSeries, newSeries(&rows[i], groupingLabels))
+		}
+		ssLock.Lock()
+		ss = append(ss, rowsSeries...)
+		ssLock.Unlock()
+		return nil
+	}, func(s string) {
+		httpserver.LogError(req, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func newSeries(r *prometheus.Row, groupingLabels []prompbmarshal.Label) *series {
+	labels := make([]prompbmarshal.Label, 0, 1+len(r.Tags)+len(groupingLabels))
+	labels = append(labels, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: strings.Clone(r.Metric),
+	})
+	for _, tag := range r.Tags {
+		if hasLabel(groupingLabels, tag.Key) {
+			// Grouping labels have priority over the pushed labels like in Pushgateway.
+			continue
+		}
+		labels = append(labels, prompbmarshal.Label{
+			Name:  strings.Clone(tag.Key),
+			Value: strings.Clone(tag.Value),
+		})
+	}
+	labels = append(labels, groupingLabels...)
+	sortLabels(labels)
+	return &series{
+		key:        marshalLabels(labels),
+		metricName: labels[0].Value,
+		labels:     labels,
+		value:      r.Value,
+		timestamp:  r.Timestamp,
+	}
+}
+
+func hasLabel(labels []prompbmarshal.Label, name string) bool {
+	for _, label := range labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortLabels sorts labels by name, while keeping __name__ label at the first place.
+func sortLabels(labels []prompbmarshal.Label) {
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Name == "__name__" {
+			return labels[j].Name != "__name__"
+		}
+		if labels[j].Name == "__name__" {
+			return false
+		}
+		return labels[i].Name < labels[j].Name
+	})
+}
+
+func marshalLabels(labels []prompbmarshal.Label) string {
+	var b []byte
+	for _, label := range labels {
+		b = append(b, label.Name...)
+		b = append(b, 0)
+		b = append(b, label.Value...)
+		b = append(b, 0)
+	}
+	return string(b)
+}
+
+func pushSeries(at *auth.Token, ss []*series, stale [][]prompbmarshal.Label) error {
+	ctx := common.GetPushCtx()
+	defer common.PutPushCtx(ctx)
+
+	tssDst := ctx.WriteRequest.Timeseries[:0]
+	samples := ctx.Samples[:0]
+	for _, s := range ss {
+		samples = append(samples, prompbmarshal.Sample{
+			Value:     s.value,
+			Timestamp: s.timestamp,
+		})
+		tssDst = append(tssDst, prompbmarshal.TimeSeries{
+			Labels:  s.labels,
+			Samples: samples[len(samples)-1:],
+		})
+	}
+	now := time.Now().UnixMilli()
+	for _, labels := range stale {
+		samples = append(samples, prompbmarshal.Sample{
+			Value:     decimal.StaleNaN,
+			Timestamp: now,
+		})
+		tssDst = append(tssDst, prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: samples[len(samples)-1:],
+		})
+	}
+	ctx.WriteRequest.Timeseries = tssDst
+	ctx.Samples = samples
+	defer func() {
+		// Do not hold references to series labels in the re-used ctx.
+		clear(ctx.WriteRequest.Timeseries)
+	}()
+	if len(tssDst) == 0 {
+		return nil
+	}
+	if err := remotewrite.CheckTenantLimits(at, ctx.WriteRequest.Timeseries); err != nil {
+		return err
+	}
+	if !remotewrite.TryPush(at, &ctx.WriteRequest) {
+		return remotewrite.ErrQueueFullHTTPRetry
+	}
+	if len(ss) > 0 {
+		rowsInserted.Add(len(ss))
+		if at != nil {
+			rowsTenantInserted.Get(at).Add(len(ss))
+		}
+		rowsPerInsert.Update(float64(len(ss)))
+	}
+	staleSeries.Add(len(stale))
+	return nil
+}
+
+// groupStore holds labels for the previously pushed series per every group.
+type groupStore struct {
+	mu sync.Mutex
+
+	// m maps group key to the previously pushed series labels keyed by series key.
+	m map[string]map[string][]prompbmarshal.Label
+}
+
+func newGroupStore() *groupStore {
+	return &groupStore{
+		m: make(map[string]map[string][]prompbmarshal.Label),
+	}
+}
+
+func (gs *groupStore) groupsCount() int {
+	gs.mu.Lock()
+	n := len(gs.m)
+	gs.mu.Unlock()
+	return n
+}
+
+// process applies ss to the group with the given key according to the given Pushgateway method.
+//
+// It calls pushFunc with ss and labels for the series, which must be marked as stale.
+// The group is updated only if pushFunc returns nil, so the failed push could be retried.
+func (gs *groupStore) process(key, method string, ss []*series, pushFunc func(ss []*series, stale [][]prompbmarshal.Label) error) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	prev, ok := gs.m[key]
+	if !ok && method != http.MethodDelete && len(gs.m) >= *maxGroups {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("cannot add new group, since the number of groups reached -pushgateway.maxGroups=%d", *maxGroups),
+			StatusCode: http.StatusTooManyRequests,
+		}
+	}
+
+	curr := make(map[string][]prompbmarshal.Label, len(ss))
+	for _, s := range ss {
+		curr[s.key] = s.labels
+	}
+
+	var stale [][]prompbmarshal.Label
+	switch method {
+	case http.MethodPost:
+		// Replace only the series with the pushed metric names.
+		metricNames := make(map[string]struct{})
+		for _, s := range ss {
+			metricNames[s.metricName] = struct{}{}
+		}
+		for k, labels := range prev {
+			if _, ok := curr[k]; ok {
+				continue
+			}
+			if _, ok := metricNames[labels[0].Value]; ok {
+				stale = append(stale, labels)
+				continue
+			}
+			curr[k] = labels
+		}
+	default:
+		// PUT and DELETE replace all the series in the group.
+		for k, labels := range prev {
+			if _, ok := curr[k]; !ok {
+				stale = append(stale, labels)
+			}
+		}
+	}
+
+	if err := pushFunc(ss, stale); err != nil {
+		return err
+	}
+	if len(curr) == 0 {
+		delete(gs.m, key)
+	} else {
+		gs.m[key] = curr
+	}
+	return nil
+}
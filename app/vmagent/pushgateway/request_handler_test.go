@@ -0,0 +1,133 @@
+package pushgateway
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+func TestGroupStoreProcess(t *testing.T) {
+	groupingLabels := []prompbmarshal.Label{
+		{
+			Name:  "job",
+			Value: "backup",
+		},
+		{
+			Name:  "instance",
+			Value: "db1",
+		},
+	}
+	sortLabels(groupingLabels)
+
+	parseSeries := func(s string) []*series {
+		t.Helper()
+		var rows prometheus.Rows
+		rows.UnmarshalWithErrLogger(s, func(errStr string) {
+			t.Fatalf("unexpected error when parsing %q: %s", s, errStr)
+		})
+		var ss []*series
+		for i := range rows.Rows {
+			ss = append(ss, newSeries(&rows.Rows[i], groupingLabels))
+		}
+		return ss
+	}
+	seriesKeys := func(ss []*series) []string {
+		var a []string
+		for _, s := range ss {
+			a = append(a, labelsString(s.labels))
+		}
+		sort.Strings(a)
+		return a
+	}
+
+	gs := newGroupStore()
+	f := func(method, data string, pushedExpected, staleExpected []string) {
+		t.Helper()
+
+		var pushed, stale []string
+		ss := parseSeries(data)
+		err := gs.process("group", method, ss, func(ss []*series, staleLabels [][]prompbmarshal.Label) error {
+			pushed = seriesKeys(ss)
+			for _, labels := range staleLabels {
+				stale = append(stale, labelsString(labels))
+			}
+			sort.Strings(stale)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(pushed, pushedExpected) {
+			t.Fatalf("unexpected pushed series\ngot\n%q\nwant\n%q", pushed, pushedExpected)
+		}
+		if !reflect.DeepEqual(stale, staleExpected) {
+			t.Fatalf("unexpected stale series\ngot\n%q\nwant\n%q", stale, staleExpected)
+		}
+	}
+
+	// The first push doesn't produce stale series.
+	f(http.MethodPut, "foo 1\nbar{x=\"y\"} 2\nbaz 3", []string{
+		`bar{instance="db1",job="backup",x="y"}`,
+		`baz{instance="db1",job="backup"}`,
+		`foo{instance="db1",job="backup"}`,
+	}, nil)
+
+	// PUT replaces all the series in the group. Grouping labels override the pushed labels.
+	f(http.MethodPut, "foo{job=\"other\"} 1\nbar{x=\"z\"} 2", []string{
+		`bar{instance="db1",job="backup",x="z"}`,
+		`foo{instance="db1",job="backup"}`,
+	}, []string{
+		`bar{instance="db1",job="backup",x="y"}`,
+		`baz{instance="db1",job="backup"}`,
+	})
+
+	// POST replaces only the series with the pushed metric names.
+	f(http.MethodPost, "bar{x=\"q\"} 3\nqux 4", []string{
+		`bar{instance="db1",job="backup",x="q"}`,
+		`qux{instance="db1",job="backup"}`,
+	}, []string{
+		`bar{instance="db1",job="backup",x="z"}`,
+	})
+
+	// DELETE removes all the series in the group.
+	f(http.MethodDelete, "", nil, []string{
+		`bar{instance="db1",job="backup",x="q"}`,
+		`foo{instance="db1",job="backup"}`,
+		`qux{instance="db1",job="backup"}`,
+	})
+	if n := gs.groupsCount(); n != 0 {
+		t.Fatalf("unexpected number of groups after DELETE; got %d; want 0", n)
+	}
+
+	// DELETE for the missing group is no-op.
+	f(http.MethodDelete, "", nil, nil)
+}
+
+func TestGroupStoreProcessPushFailure(t *testing.T) {
+	gs := newGroupStore()
+	ss := []*series{newSeries(&prometheus.Row{Metric: "foo", Value: 1}, []prompbmarshal.Label{{Name: "job", Value: "x"}})}
+
+	if err := gs.process("group", http.MethodPut, ss, func([]*series, [][]prompbmarshal.Label) error {
+		return http.ErrHandlerTimeout
+	}); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if n := gs.groupsCount(); n != 0 {
+		t.Fatalf("the group mustn't be registered after failed push; got %d groups", n)
+	}
+}
+
+func labelsString(labels []prompbmarshal.Label) string {
+	s := labels[0].Value + "{"
+	for i, label := range labels[1:] {
+		if i > 0 {
+			s += ","
+		}
+		s += label.Name + "=" + `"` + label.Value + `"`
+	}
+	return s + "}"
+}
@@ -105,6 +105,9 @@ type client struct {
 
 	rl *ratelimiter.RateLimiter
 
+	// at is set if -remoteWrite.adaptiveThrottling is enabled for remoteWriteURL.
+	at *adaptiveThrottler
+
 	bytesSent       *metrics.Counter
 	blocksSent      *metrics.Counter
 	requestDuration *metrics.Histogram
@@ -128,7 +131,15 @@ func newHTTPClient(argIdx int, remoteWriteURL, sanitizedURL string, fq *persiste
 	if err != nil {
 		logger.Fatalf("cannot initialize AWS Config for -remoteWrite.url=%q: %s", remoteWriteURL, err)
 	}
+	pURL := proxyURL.GetOptionalArg(argIdx)
+	return newHTTPClientWithAuth(argIdx, remoteWriteURL, sanitizedURL, fq, concurrency, authCfg, awsCfg, pURL)
+}
 
+// newHTTPClientWithAuth returns new client for the given remoteWriteURL with the given authCfg, awsCfg and pURL proxy url.
+//
+// The rest of client settings are obtained from -remoteWrite.* command-line flags at argIdx.
+func newHTTPClientWithAuth(argIdx int, remoteWriteURL, sanitizedURL string, fq *persistentqueue.FastQueue, concurrency int,
+	authCfg *promauth.Config, awsCfg *awsapi.Config, pURL string) *client {
 	tr := httputil.NewTransport(false, "vmagent_remotewrite")
 	tr.TLSHandshakeTimeout = tlsHandshakeTimeout.GetOptionalArg(argIdx)
 	tr.MaxConnsPerHost = 2 * concurrency
@@ -136,7 +147,6 @@ func newHTTPClient(argIdx int, remoteWriteURL, sanitizedURL string, fq *persiste
 	tr.IdleConnTimeout = time.Minute
 	tr.WriteBufferSize = 64 * 1024
 
-	pURL := proxyURL.GetOptionalArg(argIdx)
 	if len(pURL) > 0 {
 		if !strings.Contains(pURL, "://") {
 			logger.Fatalf("cannot parse -remoteWrite.proxyURL=%q: it must start with `http://`, `https://` or `socks5://`", pURL)
@@ -199,6 +209,20 @@ func (c *client) init(argIdx, concurrency int, sanitizedURL string) {
 	metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_queues{url=%q}`, c.sanitizedURL), func() float64 {
 		return float64(*queues)
 	})
+	if adaptiveThrottling.GetOptionalArg(argIdx) {
+		logger.Infof("enabling adaptive throttling for -remoteWrite.url=%q", sanitizedURL)
+		at := newAdaptiveThrottler(concurrency, c.retryMinInterval, c.retryMaxTime)
+		c.at = at
+		metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_adaptive_throttling_concurrency{url=%q}`, c.sanitizedURL), func() float64 {
+			return float64(at.getConcurrency())
+		})
+		metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_adaptive_throttling_paused{url=%q}`, c.sanitizedURL), func() float64 {
+			if at.isPaused() {
+				return 1
+			}
+			return 0
+		})
+	}
 	for i := 0; i < concurrency; i++ {
 		c.wg.Add(1)
 		go func() {
@@ -409,9 +433,16 @@ func (c *client) sendBlockHTTP(block []byte) bool {
 	retriesCount := 0
 
 again:
+	if c.at != nil && !c.at.acquire(c.stopCh) {
+		return false
+	}
 	startTime := time.Now()
 	resp, err := c.doRequest(c.remoteWriteURL, block)
 	c.requestDuration.UpdateDuration(startTime)
+	if c.at != nil && c.releaseThrottler(resp, err, len(block)) {
+		c.retriesCount.Inc()
+		goto again
+	}
 	if err != nil {
 		c.errorsCount.Inc()
 		retryDuration *= 2
@@ -510,6 +541,37 @@ again:
 
 var remoteWriteRejectedLogger = logger.WithThrottler("remoteWriteRejected", 5*time.Second)
 
+// releaseThrottler releases c.at after the request to remote storage, which returned resp and err.
+//
+// It returns true if remote storage responded with '429 Too Many Requests'. In this case resp is closed
+// and the block with the given size must be re-sent after c.at allows sending data again.
+func (c *client) releaseThrottler(resp *http.Response, err error, blockSize int) bool {
+	if err != nil {
+		c.at.release(false, false, 0)
+		return false
+	}
+	statusCode := resp.StatusCode
+	if statusCode != http.StatusTooManyRequests {
+		c.at.release(false, statusCode/100 == 2, 0)
+		return false
+	}
+
+	retryAfter := parseRetryAfterHeader(resp.Header.Get("Retry-After"))
+	c.at.release(true, false, retryAfter)
+	_ = resp.Body.Close()
+	metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_requests_total{url=%q, status_code="%d"}`, c.sanitizedURL, statusCode)).Inc()
+
+	// Move pending in-memory data to the on-disk queue, since it cannot be sent soon.
+	// This frees memory and protects the data from loss on restart while remote storage throttles requests.
+	c.fq.FlushInmemoryBlocksToFile()
+
+	remoteWriteThrottledLogger.Warnf("remote storage at %q responded with '429 Too Many Requests' to a block with size %d bytes; "+
+		"decreasing the number of concurrent requests to %d and re-sending the block after the throttling pause", c.sanitizedURL, blockSize, c.at.getConcurrency())
+	return true
+}
+
+var remoteWriteThrottledLogger = logger.WithThrottler("remoteWriteThrottled", 5*time.Second)
+
 // getRetryDuration returns retry duration.
 // retryAfterDuration has the highest priority.
 // If retryAfterDuration is not specified, retryDuration gets doubled.
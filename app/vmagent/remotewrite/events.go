@@ -0,0 +1,274 @@
+package remotewrite
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/quicktemplate"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+var (
+	eventsURL = flag.String("remoteWrite.eventsURL", "", "Optional VictoriaLogs URL for JSON lines ingestion, where series matching -remoteWrite.eventsSelector "+
+		"must be sent as log entries instead of sending them to -remoteWrite.url. "+
+		"Example url: http://<victorialogs-host>:9428/insert/jsonline?_stream_fields=__name__ . See https://docs.victoriametrics.com/vmagent/#sending-events-to-victorialogs")
+	eventsSelector = flag.String("remoteWrite.eventsSelector", "", "Series selector for series, which must be sent to -remoteWrite.eventsURL as log entries. "+
+		`For example, -remoteWrite.eventsSelector='{__name__=~"events_.*"}'. See https://docs.victoriametrics.com/vmagent/#sending-events-to-victorialogs`)
+	eventsMaxPendingBytes = flagutil.NewBytes("remoteWrite.eventsMaxPendingBytes", 32*1024*1024, "The maximum size of in-memory buffer for log entries, "+
+		"which aren't sent to -remoteWrite.eventsURL yet. New log entries are dropped when the buffer is full")
+)
+
+var (
+	eventsRowsSent      = metrics.NewCounter(`vmagent_remotewrite_events_rows_sent_total`)
+	eventsRowsDropped   = metrics.NewCounter(`vmagent_remotewrite_events_rows_dropped_total`)
+	eventsRequestErrors = metrics.NewCounter(`vmagent_remotewrite_events_request_errors_total`)
+)
+
+// eventsWriterGlobal sends series matching -remoteWrite.eventsSelector to -remoteWrite.eventsURL.
+//
+// It is nil if -remoteWrite.eventsURL isn't set.
+var eventsWriterGlobal *eventsWriter
+
+func initEventsWriter() {
+	if *eventsURL == "" {
+		return
+	}
+	if *eventsSelector == "" {
+		logger.Fatalf("-remoteWrite.eventsSelector must be set when -remoteWrite.eventsURL is set")
+	}
+	var selector promrelabel.IfExpression
+	if err := selector.Parse(*eventsSelector); err != nil {
+		logger.Fatalf("cannot parse -remoteWrite.eventsSelector=%q: %s", *eventsSelector, err)
+	}
+	ew := newEventsWriter(*eventsURL, &selector, eventsMaxPendingBytes.IntN(), *flushInterval)
+	_ = metrics.NewGauge(`vmagent_remotewrite_events_pending_bytes`, func() float64 {
+		return float64(ew.pendingBytes())
+	})
+	eventsWriterGlobal = ew
+}
+
+func stopEventsWriter() {
+	if eventsWriterGlobal == nil {
+		return
+	}
+	eventsWriterGlobal.MustStop()
+	eventsWriterGlobal = nil
+}
+
+// eventsWriter converts samples for series matching the selector into JSON lines and sends them to VictoriaLogs.
+//
+// See https://docs.victoriametrics.com/victorialogs/data-ingestion/#json-stream-api
+type eventsWriter struct {
+	url             string
+	selector        *promrelabel.IfExpression
+	maxPendingBytes int
+	c               *http.Client
+
+	mu  sync.Mutex
+	buf []byte
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func newEventsWriter(url string, selector *promrelabel.IfExpression, maxPendingBytes int, flushInterval time.Duration) *eventsWriter {
+	ew := &eventsWriter{
+		url:             url,
+		selector:        selector,
+		maxPendingBytes: maxPendingBytes,
+		c: &http.Client{
+			Timeout: time.Minute,
+		},
+		stopCh: make(chan struct{}),
+	}
+	ew.wg.Add(1)
+	go func() {
+		defer ew.wg.Done()
+		ew.runFlusher(flushInterval)
+	}()
+	return ew
+}
+
+// MustStop stops ew after sending the pending log entries.
+func (ew *eventsWriter) MustStop() {
+	close(ew.stopCh)
+	ew.wg.Wait()
+}
+
+// push converts series from tss matching ew.selector into log entries and returns the remaining series.
+//
+// tss isn't modified, since it may be used by the caller after the call.
+func (ew *eventsWriter) push(tss []prompbmarshal.TimeSeries) []prompbmarshal.TimeSeries {
+	var dst []prompbmarshal.TimeSeries
+	var buf []byte
+	rows := 0
+	for i, ts := range tss {
+		if !ew.selector.Match(ts.Labels) {
+			if dst != nil {
+				dst = append(dst, ts)
+			}
+			continue
+		}
+		if dst == nil {
+			// Fast path is over - copy the preceding series, which didn't match the selector.
+			dst = make([]prompbmarshal.TimeSeries, i, len(tss))
+			copy(dst, tss[:i])
+		}
+		for _, s := range ts.Samples {
+			if decimal.IsStaleNaN(s.Value) {
+				// Staleness markers aren't events.
+				continue
+			}
+			buf = appendEventJSON(buf, ts.Labels, s.Value, s.Timestamp)
+			rows++
+		}
+	}
+	if dst == nil {
+		// Fast path - tss has no series matching the selector.
+		return tss
+	}
+
+	if len(buf) > 0 {
+		ew.mu.Lock()
+		if len(ew.buf)+len(buf) > ew.maxPendingBytes {
+			eventsRowsDropped.Add(rows)
+		} else {
+			ew.buf = append(ew.buf, buf...)
+		}
+		ew.mu.Unlock()
+	}
+	return dst
+}
+
+func (ew *eventsWriter) pendingBytes() int {
+	ew.mu.Lock()
+	n := len(ew.buf)
+	ew.mu.Unlock()
+	return n
+}
+
+func (ew *eventsWriter) runFlusher(flushInterval time.Duration) {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+
+	var data []byte
+	retryInterval := time.Second
+	for {
+		select {
+		case <-ew.stopCh:
+			data = ew.getPendingData(data[:0])
+			if err := ew.send(data); err != nil {
+				eventsRowsDropped.Add(bytes.Count(data, []byte("\n")))
+				logger.Errorf("cannot send pending log entries to -remoteWrite.eventsURL during shutdown; dropping them: %s", err)
+				return
+			}
+			ew.removePendingData(len(data))
+			eventsRowsSent.Add(bytes.Count(data, []byte("\n")))
+			return
+		case <-t.C:
+		}
+		data = ew.getPendingData(data[:0])
+		if err := ew.send(data); err != nil {
+			eventsRequestErrors.Inc()
+			logger.Warnf("cannot send log entries to -remoteWrite.eventsURL; retrying in %s: %s", retryInterval, err)
+			select {
+			case <-ew.stopCh:
+			case <-time.After(retryInterval):
+			}
+			retryInterval = min(2*retryInterval, time.Minute)
+			continue
+		}
+		ew.removePendingData(len(data))
+		eventsRowsSent.Add(bytes.Count(data, []byte("\n")))
+		retryInterval = time.Second
+	}
+}
+
+// getPendingData appends the pending log entries to dst and returns the result.
+//
+// The pending log entries remain in ew until they are removed via removePendingData after successful sending,
+// so they are taken into account when limiting the buffer size.
+func (ew *eventsWriter) getPendingData(dst []byte) []byte {
+	ew.mu.Lock()
+	dst = append(dst, ew.buf...)
+	ew.mu.Unlock()
+	return dst
+}
+
+// removePendingData removes the first n bytes from the pending log entries.
+func (ew *eventsWriter) removePendingData(n int) {
+	ew.mu.Lock()
+	ew.buf = ew.buf[:copy(ew.buf, ew.buf[n:])]
+	ew.mu.Unlock()
+}
+
+func (ew *eventsWriter) send(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodPost, ew.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/stream+json")
+	resp, err := ew.c.Do(req)
+	if err != nil {
+		return err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response status code %d; response body: %q", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// appendEventJSON appends JSON line for the sample with the given labels, value and timestamp in milliseconds to dst.
+//
+// The metric name is stored in `_msg` field, while the sample value is stored in `value` field.
+// Labels are stored as log fields. Labels with `_msg`, `_time` and `value` names are ignored.
+func appendEventJSON(dst []byte, labels []prompbmarshal.Label, value float64, timestamp int64) []byte {
+	metricName := ""
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			metricName = label.Value
+			break
+		}
+	}
+	dst = append(dst, `{"_time":"`...)
+	dst = time.UnixMilli(timestamp).UTC().AppendFormat(dst, time.RFC3339Nano)
+	dst = append(dst, `","_msg":`...)
+	dst = quicktemplate.AppendJSONString(dst, metricName, true)
+	dst = append(dst, `,"value":`...)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		// JSON doesn't support NaN and Inf numbers
+		dst = strconv.AppendQuote(dst, strconv.FormatFloat(value, 'g', -1, 64))
+	} else {
+		dst = strconv.AppendFloat(dst, value, 'g', -1, 64)
+	}
+	for _, label := range labels {
+		switch label.Name {
+		case "_msg", "_time", "value":
+			continue
+		}
+		dst = append(dst, ',')
+		dst = quicktemplate.AppendJSONString(dst, label.Name, true)
+		dst = append(dst, ':')
+		dst = quicktemplate.AppendJSONString(dst, label.Value, true)
+	}
+	dst = append(dst, "}\n"...)
+	return dst
+}
@@ -0,0 +1,109 @@
+package remotewrite
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+func TestAppendEventJSON(t *testing.T) {
+	f := func(labels []prompbmarshal.Label, value float64, timestamp int64, resultExpected string) {
+		t.Helper()
+
+		result := appendEventJSON(nil, labels, value, timestamp)
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f([]prompbmarshal.Label{{Name: "__name__", Value: "events_deploy"}, {Name: "app", Value: "foo \"bar\""}}, 1, 1700000000123,
+		`{"_time":"2023-11-14T22:13:20.123Z","_msg":"events_deploy","value":1,"__name__":"events_deploy","app":"foo \"bar\""}`+"\n")
+
+	// missing metric name
+	f([]prompbmarshal.Label{{Name: "job", Value: "x"}}, -1.5e-3, 0,
+		`{"_time":"1970-01-01T00:00:00Z","_msg":"","value":-0.0015,"job":"x"}`+"\n")
+
+	// reserved label names are ignored
+	f([]prompbmarshal.Label{{Name: "__name__", Value: "e"}, {Name: "_msg", Value: "a"}, {Name: "_time", Value: "b"}, {Name: "value", Value: "c"}}, 2, 0,
+		`{"_time":"1970-01-01T00:00:00Z","_msg":"e","value":2,"__name__":"e"}`+"\n")
+
+	// non-finite values
+	f(nil, math.Inf(1), 0, `{"_time":"1970-01-01T00:00:00Z","_msg":"","value":"+Inf"}`+"\n")
+	f(nil, math.NaN(), 0, `{"_time":"1970-01-01T00:00:00Z","_msg":"","value":"NaN"}`+"\n")
+}
+
+func TestEventsWriter(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	failRequests := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failRequests {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/stream+json" {
+			t.Errorf("unexpected Content-Type: %q", ct)
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+		}
+		received = append(received, string(data))
+	}))
+	defer srv.Close()
+
+	var selector promrelabel.IfExpression
+	if err := selector.Parse(`{__name__=~"events_.*"}`); err != nil {
+		t.Fatalf("cannot parse selector: %s", err)
+	}
+	ew := newEventsWriter(srv.URL, &selector, 200, 10*time.Millisecond)
+
+	newSeries := func(name string, samples ...prompbmarshal.Sample) prompbmarshal.TimeSeries {
+		return prompbmarshal.TimeSeries{
+			Labels:  []prompbmarshal.Label{{Name: "__name__", Value: name}},
+			Samples: samples,
+		}
+	}
+	tss := []prompbmarshal.TimeSeries{
+		newSeries("foo", prompbmarshal.Sample{Value: 1}),
+		newSeries("events_a", prompbmarshal.Sample{Value: 2}, prompbmarshal.Sample{Value: decimal.StaleNaN}),
+		newSeries("bar", prompbmarshal.Sample{Value: 3}),
+	}
+	tssRemaining := ew.push(tss)
+	if len(tssRemaining) != 2 || tssRemaining[0].Labels[0].Value != "foo" || tssRemaining[1].Labels[0].Value != "bar" {
+		t.Fatalf("unexpected remaining series: %v", tssRemaining)
+	}
+	if len(tss) != 3 || tss[1].Labels[0].Value != "events_a" || tss[2].Labels[0].Value != "bar" {
+		t.Fatalf("push mustn't modify the original series: %v", tss)
+	}
+
+	// the buffer is full, so the entry must be dropped
+	ew.push([]prompbmarshal.TimeSeries{newSeries("events_b", prompbmarshal.Sample{Value: 4}, prompbmarshal.Sample{Value: 5}, prompbmarshal.Sample{Value: 6})})
+
+	// the failed requests must be retried
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	failRequests = false
+	mu.Unlock()
+
+	ew.MustStop()
+	if pb := ew.pendingBytes(); pb != 0 {
+		t.Fatalf("unexpected pending bytes after stop: %d", pb)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	resultExpected := `{"_time":"1970-01-01T00:00:00Z","_msg":"events_a","value":2,"__name__":"events_a"}` + "\n"
+	if len(received) != 1 || received[0] != resultExpected {
+		t.Fatalf("unexpected requests received\ngot\n%q\nwant\n%q", received, resultExpected)
+	}
+}
@@ -0,0 +1,311 @@
+package remotewrite
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/persistentqueue"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/golang/snappy"
+)
+
+// labelsDictCodec encodes blocks stored in the persistent queue, so label sets repeated across consecutive blocks
+// are stored only once per chunk file. Subsequent occurrences of the label set refer to it by id in the dictionary.
+//
+// This reduces disk usage and disk read bandwidth when millions of blocks with the same series accumulate
+// in the persistent queue during long unavailability of the remote storage.
+//
+// Encoded blocks are converted back to the original compression format (snappy or zstd) when they are read from the queue.
+type labelsDictCodec struct{}
+
+var _ persistentqueue.BlockCodec = labelsDictCodec{}
+
+// NewEncoder implements persistentqueue.BlockCodec interface.
+func (labelsDictCodec) NewEncoder() persistentqueue.BlockEncoder {
+	return &labelsDictEncoder{
+		ids:       make(map[string]uint64),
+		needReset: true,
+	}
+}
+
+// NewDecoder implements persistentqueue.BlockCodec interface.
+func (labelsDictCodec) NewDecoder() persistentqueue.BlockDecoder {
+	return &labelsDictDecoder{}
+}
+
+const (
+	// labelsDictFlagReset instructs the decoder to reset the dictionary before decoding the block.
+	labelsDictFlagReset = 1 << 0
+
+	// labelsDictFlagZstd means the original block was compressed with zstd instead of snappy.
+	labelsDictFlagZstd = 1 << 1
+
+	labelsDictFlagsMask = labelsDictFlagReset | labelsDictFlagZstd
+)
+
+// maxLabelsDictSize is the maximum size in bytes of label sets stored in the dictionary.
+//
+// The dictionary is reset when it reaches this size in order to limit memory usage.
+const maxLabelsDictSize = 64 * 1024 * 1024
+
+// labelsDictCompressLevel is the zstd compression level for encoded blocks.
+const labelsDictCompressLevel = 1
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+type labelsDictEncoder struct {
+	// ids maps marshaled label sets to their ids in the dictionary.
+	ids map[string]uint64
+
+	// dictSize is the size in bytes of label sets in ids.
+	dictSize int
+
+	// needReset is set to true if the next encoded block must reset the dictionary at the decoder.
+	needReset bool
+
+	// addedKeys contains label sets added to ids during the current EncodeBlock call.
+	addedKeys []string
+
+	wr       prompb.WriteRequest
+	plainBuf []byte
+	dataBuf  []byte
+	keyBuf   []byte
+}
+
+// EncodeBlock implements persistentqueue.BlockEncoder interface.
+func (e *labelsDictEncoder) EncodeBlock(dst, block []byte) ([]byte, bool) {
+	isZstd := bytes.HasPrefix(block, zstdMagic)
+	var err error
+	if isZstd {
+		e.plainBuf, err = zstd.Decompress(e.plainBuf[:0], block)
+	} else {
+		e.plainBuf, err = snappy.Decode(e.plainBuf[:cap(e.plainBuf)], block)
+	}
+	if err != nil {
+		return dst, false
+	}
+	if err := e.wr.UnmarshalProtobuf(e.plainBuf); err != nil {
+		return dst, false
+	}
+	if len(e.wr.Metadata) > 0 {
+		// Metadata isn't supported by the encoding.
+		return dst, false
+	}
+
+	if e.dictSize > maxLabelsDictSize {
+		clear(e.ids)
+		e.dictSize = 0
+		e.needReset = true
+	}
+
+	flags := byte(0)
+	if e.needReset {
+		flags |= labelsDictFlagReset
+	}
+	if isZstd {
+		flags |= labelsDictFlagZstd
+	}
+
+	e.addedKeys = e.addedKeys[:0]
+	data := encoding.MarshalVarUint64(e.dataBuf[:0], uint64(len(e.wr.Timeseries)))
+	for i := range e.wr.Timeseries {
+		ts := &e.wr.Timeseries[i]
+		e.keyBuf = marshalLabelsDictKey(e.keyBuf[:0], ts.Labels)
+		if id, ok := e.ids[string(e.keyBuf)]; ok {
+			data = encoding.MarshalVarUint64(data, id+1)
+		} else {
+			data = encoding.MarshalVarUint64(data, 0)
+			data = append(data, e.keyBuf...)
+			key := string(e.keyBuf)
+			e.ids[key] = uint64(len(e.ids))
+			e.addedKeys = append(e.addedKeys, key)
+			e.dictSize += len(key)
+		}
+		data = encoding.MarshalVarUint64(data, uint64(len(ts.Samples)))
+		prevTimestamp := int64(0)
+		for _, s := range ts.Samples {
+			data = encoding.MarshalVarInt64(data, s.Timestamp-prevTimestamp)
+			data = encoding.MarshalUint64(data, math.Float64bits(s.Value))
+			prevTimestamp = s.Timestamp
+		}
+	}
+	e.dataBuf = data
+
+	dstLen := len(dst)
+	dst = append(dst, flags)
+	dst = zstd.CompressLevel(dst, data, labelsDictCompressLevel)
+	if len(dst)-dstLen >= len(block) {
+		// The encoding doesn't reduce the block size. Roll back the dictionary changes.
+		for _, key := range e.addedKeys {
+			delete(e.ids, key)
+			e.dictSize -= len(key)
+		}
+		return dst[:dstLen], false
+	}
+	e.needReset = false
+	return dst, true
+}
+
+func marshalLabelsDictKey(dst []byte, labels []prompb.Label) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(labels)))
+	for _, label := range labels {
+		dst = encoding.MarshalVarUint64(dst, uint64(len(label.Name)))
+		dst = append(dst, label.Name...)
+		dst = encoding.MarshalVarUint64(dst, uint64(len(label.Value)))
+		dst = append(dst, label.Value...)
+	}
+	return dst
+}
+
+type labelsDictDecoder struct {
+	// dict contains label sets in the order they were added by the encoder.
+	dict [][]prompbmarshal.Label
+
+	wr       prompbmarshal.WriteRequest
+	samples  []prompbmarshal.Sample
+	dataBuf  []byte
+	plainBuf []byte
+	zbuf     []byte
+}
+
+// DecodeBlock implements persistentqueue.BlockDecoder interface.
+func (d *labelsDictDecoder) DecodeBlock(dst, src []byte) ([]byte, error) {
+	if len(src) < 1 {
+		return dst, fmt.Errorf("missing flags")
+	}
+	flags := src[0]
+	if flags&^labelsDictFlagsMask != 0 {
+		return dst, fmt.Errorf("unexpected flags: %d", flags)
+	}
+	data, err := zstd.Decompress(d.dataBuf[:0], src[1:])
+	if err != nil {
+		return dst, fmt.Errorf("cannot decompress block: %w", err)
+	}
+	d.dataBuf = data
+
+	if flags&labelsDictFlagReset != 0 {
+		clear(d.dict)
+		d.dict = d.dict[:0]
+	}
+
+	seriesCount, n := encoding.UnmarshalVarUint64(data)
+	if n <= 0 {
+		return dst, fmt.Errorf("cannot read the number of series")
+	}
+	data = data[n:]
+	if seriesCount > uint64(len(data)) {
+		return dst, fmt.Errorf("too big number of series: %d", seriesCount)
+	}
+
+	d.wr.Reset()
+	d.samples = d.samples[:0]
+	sampleOffsets := make([]int, 0, seriesCount+1)
+	for i := uint64(0); i < seriesCount; i++ {
+		ref, n := encoding.UnmarshalVarUint64(data)
+		if n <= 0 {
+			return dst, fmt.Errorf("cannot read labels ref for series #%d", i)
+		}
+		data = data[n:]
+		var labels []prompbmarshal.Label
+		if ref == 0 {
+			labels, data, err = unmarshalLabelsDictKey(data)
+			if err != nil {
+				return dst, fmt.Errorf("cannot read labels for series #%d: %w", i, err)
+			}
+			d.dict = append(d.dict, labels)
+		} else {
+			if ref > uint64(len(d.dict)) {
+				return dst, fmt.Errorf("labels ref=%d for series #%d exceeds dictionary size %d", ref, i, len(d.dict))
+			}
+			labels = d.dict[ref-1]
+		}
+
+		samplesCount, n := encoding.UnmarshalVarUint64(data)
+		if n <= 0 {
+			return dst, fmt.Errorf("cannot read the number of samples for series #%d", i)
+		}
+		data = data[n:]
+		if samplesCount > uint64(len(data)) {
+			return dst, fmt.Errorf("too big number of samples for series #%d: %d", i, samplesCount)
+		}
+		sampleOffsets = append(sampleOffsets, len(d.samples))
+		prevTimestamp := int64(0)
+		for j := uint64(0); j < samplesCount; j++ {
+			delta, n := encoding.UnmarshalVarInt64(data)
+			if n <= 0 || len(data)-n < 8 {
+				return dst, fmt.Errorf("cannot read sample #%d for series #%d", j, i)
+			}
+			data = data[n:]
+			timestamp := prevTimestamp + delta
+			value := math.Float64frombits(encoding.UnmarshalUint64(data))
+			data = data[8:]
+			d.samples = append(d.samples, prompbmarshal.Sample{
+				Value:     value,
+				Timestamp: timestamp,
+			})
+			prevTimestamp = timestamp
+		}
+		d.wr.Timeseries = append(d.wr.Timeseries, prompbmarshal.TimeSeries{
+			Labels: labels,
+		})
+	}
+	if len(data) > 0 {
+		return dst, fmt.Errorf("unexpected tail left after decoding the block; len(tail)=%d", len(data))
+	}
+	sampleOffsets = append(sampleOffsets, len(d.samples))
+	for i := range d.wr.Timeseries {
+		d.wr.Timeseries[i].Samples = d.samples[sampleOffsets[i]:sampleOffsets[i+1]]
+	}
+
+	d.plainBuf = d.wr.MarshalProtobuf(d.plainBuf[:0])
+	d.wr.Reset()
+	if flags&labelsDictFlagZstd != 0 {
+		return zstd.CompressLevel(dst, d.plainBuf, *vmProtoCompressLevel), nil
+	}
+	d.zbuf = snappy.Encode(d.zbuf[:cap(d.zbuf)], d.plainBuf)
+	return append(dst, d.zbuf...), nil
+}
+
+func unmarshalLabelsDictKey(src []byte) ([]prompbmarshal.Label, []byte, error) {
+	labelsCount, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return nil, src, fmt.Errorf("cannot read the number of labels")
+	}
+	src = src[n:]
+	if labelsCount > uint64(len(src)) {
+		return nil, src, fmt.Errorf("too big number of labels: %d", labelsCount)
+	}
+	labels := make([]prompbmarshal.Label, labelsCount)
+	for i := range labels {
+		name, tail, err := unmarshalLabelsDictString(src)
+		if err != nil {
+			return nil, src, fmt.Errorf("cannot read name for label #%d: %w", i, err)
+		}
+		value, tail, err := unmarshalLabelsDictString(tail)
+		if err != nil {
+			return nil, src, fmt.Errorf("cannot read value for label #%d: %w", i, err)
+		}
+		labels[i] = prompbmarshal.Label{
+			Name:  name,
+			Value: value,
+		}
+		src = tail
+	}
+	return labels, src, nil
+}
+
+func unmarshalLabelsDictString(src []byte) (string, []byte, error) {
+	size, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return "", src, fmt.Errorf("cannot read string size")
+	}
+	src = src[n:]
+	if size > uint64(len(src)) {
+		return "", src, fmt.Errorf("string size %d exceeds the remaining data size %d", size, len(src))
+	}
+	return string(src[:size]), src[size:], nil
+}
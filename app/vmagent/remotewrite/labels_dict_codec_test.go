@@ -0,0 +1,136 @@
+package remotewrite
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/golang/snappy"
+)
+
+func TestLabelsDictCodec(t *testing.T) {
+	f := func(isVMRemoteWrite bool) {
+		t.Helper()
+
+		var codec labelsDictCodec
+		enc := codec.NewEncoder()
+		dec := codec.NewDecoder()
+
+		var plainBlocks, encodedBlocks [][]byte
+		encodedSize := 0
+		for i := 0; i < 10; i++ {
+			wr := newTestWriteRequest(100, 10)
+			for j := range wr.Timeseries {
+				samples := wr.Timeseries[j].Samples
+				samples[0].Timestamp += int64(i) * 15000
+				samples[0].Value += float64(i)
+			}
+			plainBlock := wr.MarshalProtobuf(nil)
+			var block []byte
+			if isVMRemoteWrite {
+				block = zstd.CompressLevel(nil, plainBlock, 0)
+			} else {
+				block = snappy.Encode(nil, plainBlock)
+			}
+			encodedBlock, ok := enc.EncodeBlock(nil, block)
+			if !ok {
+				t.Fatalf("cannot encode block #%d", i)
+			}
+			if len(encodedBlock) >= len(block) {
+				t.Fatalf("encoded block #%d must be smaller than the original block; got %d bytes; want less than %d bytes", i, len(encodedBlock), len(block))
+			}
+			if i > 0 {
+				encodedSize += len(encodedBlock)
+			}
+			plainBlocks = append(plainBlocks, plainBlock)
+			encodedBlocks = append(encodedBlocks, encodedBlock)
+		}
+
+		// Blocks after the first one must refer to label sets in the dictionary
+		if firstSize := len(encodedBlocks[0]); encodedSize/(len(encodedBlocks)-1) >= firstSize/2 {
+			t.Fatalf("too big average size for encoded blocks referring the dictionary: %d bytes; the first block size: %d bytes",
+				encodedSize/(len(encodedBlocks)-1), firstSize)
+		}
+
+		for i, encodedBlock := range encodedBlocks {
+			block, err := dec.DecodeBlock(nil, encodedBlock)
+			if err != nil {
+				t.Fatalf("cannot decode block #%d: %s", i, err)
+			}
+			var plainBlock []byte
+			if isVMRemoteWrite {
+				plainBlock, err = zstd.Decompress(nil, block)
+			} else {
+				plainBlock, err = snappy.Decode(nil, block)
+			}
+			if err != nil {
+				t.Fatalf("cannot decompress decoded block #%d: %s", i, err)
+			}
+			if !bytes.Equal(plainBlock, plainBlocks[i]) {
+				t.Fatalf("unexpected decoded block #%d", i)
+			}
+		}
+
+		// The decoder with empty dictionary must reject blocks referring the dictionary
+		if _, err := codec.NewDecoder().DecodeBlock(nil, encodedBlocks[1]); err == nil {
+			t.Fatalf("expecting non-nil error when decoding block with missing dictionary")
+		}
+	}
+
+	// Prometheus remote write
+	f(false)
+
+	// VictoriaMetrics remote write
+	f(true)
+}
+
+func TestLabelsDictCodecRollback(t *testing.T) {
+	var codec labelsDictCodec
+	enc := codec.NewEncoder()
+	dec := codec.NewDecoder()
+
+	// The encoder must reject invalid blocks
+	if _, ok := enc.EncodeBlock(nil, []byte("foobar")); ok {
+		t.Fatalf("expecting invalid block to be rejected")
+	}
+
+	// The encoder must reject blocks, which cannot be reduced in size. Such blocks mustn't change the dictionary.
+	wr := &prompbmarshal.WriteRequest{
+		Timeseries: []prompbmarshal.TimeSeries{
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "__name__",
+						Value: "a",
+					},
+				},
+				Samples: []prompbmarshal.Sample{
+					{
+						Value:     1,
+						Timestamp: 2,
+					},
+				},
+			},
+		},
+	}
+	block := snappy.Encode(nil, wr.MarshalProtobuf(nil))
+	if _, ok := enc.EncodeBlock(nil, block); ok {
+		t.Fatalf("expecting too small block to be rejected")
+	}
+
+	// The next encoded block must be decoded with the fresh decoder.
+	wr = newTestWriteRequest(100, 10)
+	block = snappy.Encode(nil, wr.MarshalProtobuf(nil))
+	encodedBlock, ok := enc.EncodeBlock(nil, block)
+	if !ok {
+		t.Fatalf("cannot encode block")
+	}
+	decodedBlock, err := dec.DecodeBlock(nil, encodedBlock)
+	if err != nil {
+		t.Fatalf("cannot decode block: %s", err)
+	}
+	if !bytes.Equal(decodedBlock, block) {
+		t.Fatalf("unexpected decoded block")
+	}
+}
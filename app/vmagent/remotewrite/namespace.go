@@ -0,0 +1,270 @@
+package remotewrite
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bloomfilter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/persistentqueue"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+// namespacesQueueDirname is the directory inside -remoteWrite.tmpDataPath for persistent queues of namespaces.
+//
+// It differs from persistentQueueDirname, so the queues aren't removed by dropDanglingQueues.
+const namespacesQueueDirname = "namespaces"
+
+// namespaceArgIdx is the index of -remoteWrite.* command-line flags used for remote storage systems owned by namespaces.
+//
+// It exceeds the number of -remoteWrite.url items, so per-URL flags apply to namespaces only if they contain a single value,
+// which is applied to all the remote storage systems.
+const namespaceArgIdx = math.MaxInt32
+
+// NamespaceTarget is a remote storage system owned by vmagent namespace.
+type NamespaceTarget struct {
+	// URL is the remote write url for the remote storage system.
+	URL string
+
+	// AuthConfig is the auth config for sending data to URL.
+	AuthConfig *promauth.Config
+
+	// ProxyURL is an optional proxy url for sending data to URL.
+	ProxyURL string
+}
+
+// Namespace sends data collected by vmagent namespace to remote storage systems owned by the namespace.
+//
+// The data doesn't go through -remoteWrite.relabelConfig, stream aggregation and series limits configured via command-line flags.
+//
+// See https://docs.victoriametrics.com/vmagent/#namespaces
+type Namespace struct {
+	name string
+
+	rwctxs        []*remoteWriteCtx
+	queuePaths    []string
+	sanitizedURLs []string
+
+	relabelConfigs      *promrelabel.ParsedConfigs
+	hourlySeriesLimiter *bloomfilter.Limiter
+
+	ms                        *metrics.Set
+	rowsPushedBeforeRelabel   *metrics.Counter
+	rowsDroppedByRelabel      *metrics.Counter
+	hourlySeriesLimitRowsDrop *metrics.Counter
+}
+
+// NewNamespace returns new Namespace with the given name, which sends data to the given targets.
+//
+// relabelConfigs are applied to all the data before sending it to targets.
+// The number of unique series sent to targets per hour is limited by maxHourlySeries if it is positive.
+//
+// MustStop must be called when the returned Namespace is no longer needed.
+func NewNamespace(name string, targets []NamespaceTarget, relabelConfigs *promrelabel.ParsedConfigs, maxHourlySeries int) (*Namespace, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("missing remote write targets for namespace %q", name)
+	}
+	urls := make([]*url.URL, len(targets))
+	for i, t := range targets {
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote write url #%d for namespace %q: %w", i+1, name, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported scheme %q in remote write url #%d for namespace %q; want `http` or `https`", u.Scheme, i+1, name)
+		}
+		urls[i] = u
+	}
+
+	ms := metrics.NewSet()
+	ns := &Namespace{
+		name:           name,
+		relabelConfigs: relabelConfigs,
+
+		ms:                        ms,
+		rowsPushedBeforeRelabel:   ms.NewCounter(fmt.Sprintf(`vmagent_namespace_rows_pushed_before_relabel_total{namespace=%q}`, name)),
+		rowsDroppedByRelabel:      ms.NewCounter(fmt.Sprintf(`vmagent_namespace_relabel_metrics_dropped_total{namespace=%q}`, name)),
+		hourlySeriesLimitRowsDrop: ms.NewCounter(fmt.Sprintf(`vmagent_namespace_hourly_series_limit_rows_dropped_total{namespace=%q}`, name)),
+	}
+	if maxHourlySeries > 0 {
+		sl := bloomfilter.NewLimiter(maxHourlySeries, time.Hour)
+		ns.hourlySeriesLimiter = sl
+		_ = ms.NewGauge(fmt.Sprintf(`vmagent_namespace_hourly_series_limit_max_series{namespace=%q}`, name), func() float64 {
+			return float64(sl.MaxItems())
+		})
+		_ = ms.NewGauge(fmt.Sprintf(`vmagent_namespace_hourly_series_limit_current_series{namespace=%q}`, name), func() float64 {
+			return float64(sl.CurrentItems())
+		})
+	}
+
+	maxInmemoryBlocks := getMaxInmemoryBlocks(len(*remoteWriteURLs) + len(targets))
+	for i, u := range urls {
+		t := targets[i]
+		sanitizedURL := fmt.Sprintf("%s:%d:secret-url", name, i+1)
+		if *showRemoteWriteURL {
+			sanitizedURL = fmt.Sprintf("%s:%d:%s", name, i+1, u)
+		}
+
+		// strip query params, otherwise changing params resets pq
+		pqURL := *u
+		pqURL.RawQuery = ""
+		pqURL.Fragment = ""
+		h := xxhash.Sum64([]byte(pqURL.String()))
+		queuePath := filepath.Join(getNamespaceQueuesDir(name), fmt.Sprintf("%d_%016X", i+1, h))
+
+		newClient := func(fq *persistentqueue.FastQueue) *client {
+			return newHTTPClientWithAuth(namespaceArgIdx, u.String(), sanitizedURL, fq, *queues, t.AuthConfig, nil, t.ProxyURL)
+		}
+		rwctx := newRemoteWriteCtxWithClient(namespaceArgIdx, u, queuePath, maxInmemoryBlocks, sanitizedURL, newClient)
+		ns.rwctxs = append(ns.rwctxs, rwctx)
+		ns.queuePaths = append(ns.queuePaths, queuePath)
+		ns.sanitizedURLs = append(ns.sanitizedURLs, sanitizedURL)
+	}
+	metrics.RegisterSet(ms)
+	ns.dropDanglingQueues()
+	logger.Infof("started remote write for namespace %q with %d remote storage systems", name, len(ns.rwctxs))
+	return ns, nil
+}
+
+// dropDanglingQueues removes persistent queues for remote storage systems, which are no longer owned by ns.
+func (ns *Namespace) dropDanglingQueues() {
+	if *keepDanglingQueues {
+		return
+	}
+	existingQueues := make(map[string]struct{}, len(ns.queuePaths))
+	for _, queuePath := range ns.queuePaths {
+		existingQueues[filepath.Base(queuePath)] = struct{}{}
+	}
+	queuesDir := getNamespaceQueuesDir(ns.name)
+	for _, de := range fs.MustReadDir(queuesDir) {
+		dirname := de.Name()
+		if _, ok := existingQueues[dirname]; !ok {
+			logger.Infof("removing dangling queue %q for namespace %q", dirname, ns.name)
+			fs.MustRemoveAll(filepath.Join(queuesDir, dirname))
+		}
+	}
+}
+
+// DropDanglingNamespaceQueues removes persistent queues for namespaces missing in names.
+//
+// It must be called after namespaces missing in names are stopped.
+func DropDanglingNamespaceQueues(names []string) {
+	if *keepDanglingQueues {
+		return
+	}
+	queuesDir := filepath.Join(*tmpDataPath, namespacesQueueDirname)
+	if !fs.IsPathExist(queuesDir) {
+		return
+	}
+	existingNamespaces := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		existingNamespaces[name] = struct{}{}
+	}
+	for _, de := range fs.MustReadDir(queuesDir) {
+		name := de.Name()
+		if _, ok := existingNamespaces[name]; !ok {
+			logger.Infof("removing dangling queues for namespace %q", name)
+			fs.MustRemoveAll(filepath.Join(queuesDir, name))
+		}
+	}
+}
+
+func getNamespaceQueuesDir(name string) string {
+	return filepath.Join(*tmpDataPath, namespacesQueueDirname, name)
+}
+
+// Push sends wr to remote storage systems owned by ns.
+//
+// Samples from wr are dropped if they cannot be sent to remote storage systems.
+//
+// Push can modify wr contents.
+func (ns *Namespace) Push(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+	tss := wr.Timeseries
+	if at != nil {
+		// Convert at to (vm_account_id, vm_project_id) labels.
+		tenantRctx := getRelabelCtx()
+		defer putRelabelCtx(tenantRctx)
+		tenantRctx.tenantToLabels(tss, at.AccountID, at.ProjectID)
+	}
+
+	rowsCount := getRowsCount(tss)
+	ns.rowsPushedBeforeRelabel.Add(rowsCount)
+	if ns.relabelConfigs.Len() > 0 || *usePromCompatibleNaming {
+		rctx := getRelabelCtx()
+		defer putRelabelCtx(rctx)
+		tss = rctx.applyRelabeling(tss, ns.relabelConfigs)
+		ns.rowsDroppedByRelabel.Add(rowsCount - getRowsCount(tss))
+	}
+	sortLabelsIfNeeded(tss)
+	tss = ns.limitSeriesCardinality(tss)
+	if len(tss) == 0 {
+		return
+	}
+	_ = replicateBlockAmongRemoteStorages(ns.rwctxs, tss, true)
+}
+
+func (ns *Namespace) limitSeriesCardinality(tss []prompbmarshal.TimeSeries) []prompbmarshal.TimeSeries {
+	sl := ns.hourlySeriesLimiter
+	if sl == nil {
+		return tss
+	}
+	dst := tss[:0]
+	for i := range tss {
+		labels := tss[i].Labels
+		h := getLabelsHash(labels)
+		if !sl.Add(h) {
+			ns.hourlySeriesLimitRowsDrop.Add(len(tss[i].Samples))
+			logSkippedSeries(labels, "max_hourly_series", sl.MaxItems())
+			continue
+		}
+		dst = append(dst, tss[i])
+	}
+	return dst
+}
+
+// MustStop stops ns and unregisters its metrics.
+//
+// Pending data is kept in persistent queues, so it is sent after the namespace with the same name and remote write urls is started again.
+// Call DropDanglingNamespaceQueues for removing persistent queues of namespaces, which won't be started again.
+func (ns *Namespace) MustStop() {
+	for _, rwctx := range ns.rwctxs {
+		rwctx.MustStop()
+	}
+	ns.rwctxs = nil
+	if sl := ns.hourlySeriesLimiter; sl != nil {
+		sl.MustStop()
+	}
+	ns.unregisterQueueMetrics()
+	metrics.UnregisterSet(ns.ms, true)
+	logger.Infof("stopped remote write for namespace %q", ns.name)
+}
+
+// unregisterQueueMetrics unregisters metrics for remote storage systems and persistent queues owned by ns.
+//
+// These metrics are registered by remoteWriteCtx, client and persistentqueue at the default metrics set,
+// so they are unregistered by their `path` and `url` labels, which are unique per namespace.
+func (ns *Namespace) unregisterQueueMetrics() {
+	labels := make([]string, 0, 2*len(ns.queuePaths))
+	for i, queuePath := range ns.queuePaths {
+		labels = append(labels, fmt.Sprintf(`path=%q`, queuePath), fmt.Sprintf(`url=%q`, ns.sanitizedURLs[i]))
+	}
+	for _, name := range metrics.ListMetricNames() {
+		for _, label := range labels {
+			if strings.Contains(name, label) {
+				metrics.UnregisterMetric(name)
+				break
+			}
+		}
+	}
+}
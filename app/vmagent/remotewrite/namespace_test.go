@@ -0,0 +1,72 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestNamespaceMustStop(t *testing.T) {
+	tmpDataPathOrig := *tmpDataPath
+	*tmpDataPath = t.TempDir()
+	defer func() {
+		*tmpDataPath = tmpDataPathOrig
+	}()
+
+	// Create dangling queues for the namespace and for the removed namespace.
+	danglingQueuePath := filepath.Join(getNamespaceQueuesDir("foo"), "2_0000000000000000")
+	fs.MustMkdirIfNotExist(danglingQueuePath)
+	removedNamespaceDir := getNamespaceQueuesDir("bar")
+	fs.MustMkdirIfNotExist(removedNamespaceDir)
+
+	ns, err := NewNamespace("foo", []NamespaceTarget{{URL: "http://localhost:8428/api/v1/write"}}, nil, 100)
+	if err != nil {
+		t.Fatalf("cannot create namespace: %s", err)
+	}
+	if fs.IsPathExist(danglingQueuePath) {
+		t.Fatalf("dangling queue %q must be removed", danglingQueuePath)
+	}
+	queuePath := ns.queuePaths[0]
+	if !fs.IsPathExist(queuePath) {
+		t.Fatalf("missing queue %q", queuePath)
+	}
+
+	getNamespaceMetrics := func() []string {
+		var names []string
+		for _, name := range metrics.ListMetricNames() {
+			if strings.Contains(name, fmt.Sprintf("path=%q", queuePath)) || strings.Contains(name, `"foo:1:secret-url"`) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	if names := getNamespaceMetrics(); len(names) == 0 {
+		t.Fatalf("missing metrics for namespace")
+	}
+
+	ns.MustStop()
+	if names := getNamespaceMetrics(); len(names) > 0 {
+		t.Fatalf("unexpected metrics left after stopping the namespace: %q", names)
+	}
+
+	// Queues must be kept for the namespace, which may be started again.
+	DropDanglingNamespaceQueues([]string{"foo"})
+	if !fs.IsPathExist(queuePath) {
+		t.Fatalf("queue %q must be kept", queuePath)
+	}
+	if fs.IsPathExist(removedNamespaceDir) {
+		t.Fatalf("queues for the removed namespace at %q must be removed", removedNamespaceDir)
+	}
+
+	// Queues must be removed for the removed namespace.
+	DropDanglingNamespaceQueues(nil)
+	if _, err := os.Stat(getNamespaceQueuesDir("foo")); !os.IsNotExist(err) {
+		t.Fatalf("queues for the removed namespace must be removed; got err=%v", err)
+	}
+}
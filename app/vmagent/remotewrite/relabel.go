@@ -118,8 +118,11 @@ func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, pcs *pro
 	return tssDst
 }
 
-func (rctx *relabelCtx) appendExtraLabels(tss []prompbmarshal.TimeSeries, extraLabels []prompbmarshal.Label) {
-	if len(extraLabels) == 0 {
+// appendExtraLabels adds resourceLabels and extraLabels to tss.
+//
+// resourceLabels are added only if they are missing in the time series, while extraLabels override labels with the same names.
+func (rctx *relabelCtx) appendExtraLabels(tss []prompbmarshal.TimeSeries, resourceLabels, extraLabels []prompbmarshal.Label) {
+	if len(resourceLabels) == 0 && len(extraLabels) == 0 {
 		return
 	}
 	rctx.reset()
@@ -128,6 +131,12 @@ func (rctx *relabelCtx) appendExtraLabels(tss []prompbmarshal.TimeSeries, extraL
 		ts := &tss[i]
 		labelsLen := len(labels)
 		labels = append(labels, ts.Labels...)
+		for j := range resourceLabels {
+			resourceLabel := resourceLabels[j]
+			if promrelabel.GetLabelByName(labels[labelsLen:], resourceLabel.Name) == nil {
+				labels = append(labels, resourceLabel)
+			}
+		}
 		for j := range extraLabels {
 			extraLabel := extraLabels[j]
 			tmp := promrelabel.GetLabelByName(labels[labelsLen:], extraLabel.Name)
@@ -142,6 +151,42 @@ func (rctx *relabelCtx) appendExtraLabels(tss []prompbmarshal.TimeSeries, extraL
 	rctx.labels = labels
 }
 
+// dropOldSamples drops samples with timestamps smaller than minTimestamp from tss.
+//
+// Series without samples are dropped too.
+func (rctx *relabelCtx) dropOldSamples(tss []prompbmarshal.TimeSeries, minTimestamp int64) []prompbmarshal.TimeSeries {
+	tssDst := tss[:0]
+	samples := rctx.samples[:0]
+	for _, ts := range tss {
+		samplesLen := len(samples)
+		for _, s := range ts.Samples {
+			if s.Timestamp >= minTimestamp {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == samplesLen {
+			continue
+		}
+		ts.Samples = samples[samplesLen:len(samples):len(samples)]
+		tssDst = append(tssDst, ts)
+	}
+	clear(tss[len(tssDst):])
+	rctx.samples = samples
+	return tssDst
+}
+
+// hasOldSamples returns true if tss contain samples with timestamps smaller than minTimestamp.
+func hasOldSamples(tss []prompbmarshal.TimeSeries, minTimestamp int64) bool {
+	for _, ts := range tss {
+		for _, s := range ts.Samples {
+			if s.Timestamp < minTimestamp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (rctx *relabelCtx) tenantToLabels(tss []prompbmarshal.TimeSeries, accountID, projectID uint32) {
 	rctx.reset()
 	accountIDStr := strconv.FormatUint(uint64(accountID), 10)
@@ -173,6 +218,9 @@ func (rctx *relabelCtx) tenantToLabels(tss []prompbmarshal.TimeSeries, accountID
 type relabelCtx struct {
 	// pool for labels, which are used during the relabeling.
 	labels []prompbmarshal.Label
+
+	// pool for samples, which are left after dropping old samples.
+	samples []prompbmarshal.Sample
 }
 
 func (rctx *relabelCtx) reset() {
@@ -192,6 +240,7 @@ func getRelabelCtx() *relabelCtx {
 
 func putRelabelCtx(rctx *relabelCtx) {
 	rctx.reset()
+	rctx.samples = rctx.samples[:0]
 	relabelCtxPool.Put(rctx)
 }
 
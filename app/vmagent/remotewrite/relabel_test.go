@@ -43,7 +43,7 @@ func TestAppendExtraLabels(t *testing.T) {
 		t.Helper()
 		rctx := &relabelCtx{}
 		tss, expTss := parseSeries(sTss), parseSeries(sExpTss)
-		rctx.appendExtraLabels(tss, extraLabels)
+		rctx.appendExtraLabels(tss, nil, extraLabels)
 		if !reflect.DeepEqual(tss, expTss) {
 			t.Fatalf("expected to have: \n%v;\ngot: \n%v", expTss, tss)
 		}
@@ -60,6 +60,88 @@ func TestAppendExtraLabels(t *testing.T) {
 	*usePromCompatibleNaming = oldVal
 }
 
+func TestAppendExtraLabelsWithResourceLabels(t *testing.T) {
+	f := func(resourceLabels, extraLabels []prompbmarshal.Label, sTss, sExpTss string) {
+		t.Helper()
+		rctx := &relabelCtx{}
+		tss, expTss := parseSeries(sTss), parseSeries(sExpTss)
+		rctx.appendExtraLabels(tss, resourceLabels, extraLabels)
+		if !reflect.DeepEqual(tss, expTss) {
+			t.Fatalf("expected to have: \n%v;\ngot: \n%v", expTss, tss)
+		}
+	}
+
+	resourceLabels := []prompbmarshal.Label{{Name: "cloud_region", Value: "us-east-1"}, {Name: "host_name", Value: "vmagent-host"}}
+
+	// resource labels are added to series without such labels
+	f(resourceLabels, nil, `up{foo="bar"}`, `up{foo="bar",cloud_region="us-east-1",host_name="vmagent-host"}`)
+
+	// resource labels do not override the existing labels
+	f(resourceLabels, nil, `up{host_name="exporter-host"}`, `up{host_name="exporter-host",cloud_region="us-east-1"}`)
+
+	// extra labels override resource labels and the existing labels
+	f(resourceLabels, []prompbmarshal.Label{{Name: "host_name", Value: "foo"}}, `up{host_name="exporter-host"}`, `up{host_name="foo",cloud_region="us-east-1"}`)
+	f(resourceLabels, []prompbmarshal.Label{{Name: "cloud_region", Value: "eu"}}, `up`, `up{cloud_region="eu",host_name="vmagent-host"}`)
+}
+
+func TestDropOldSamples(t *testing.T) {
+	f := func(tss []prompbmarshal.TimeSeries, minTimestamp int64, tssExpected []prompbmarshal.TimeSeries) {
+		t.Helper()
+
+		if hasOld := hasOldSamples(tss, minTimestamp); hasOld != (getRowsCount(tss) != getRowsCount(tssExpected)) {
+			t.Fatalf("unexpected hasOldSamples result: %v", hasOld)
+		}
+		rctx := &relabelCtx{}
+		tss = rctx.dropOldSamples(tss, minTimestamp)
+		if len(tss) == 0 && len(tssExpected) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(tss, tssExpected) {
+			t.Fatalf("unexpected series;\ngot\n%v\nwant\n%v", tss, tssExpected)
+		}
+	}
+
+	newSeries := func(name string, timestamps ...int64) prompbmarshal.TimeSeries {
+		ts := prompbmarshal.TimeSeries{
+			Labels: []prompbmarshal.Label{{Name: "__name__", Value: name}},
+		}
+		for _, timestamp := range timestamps {
+			ts.Samples = append(ts.Samples, prompbmarshal.Sample{
+				Value:     float64(timestamp),
+				Timestamp: timestamp,
+			})
+		}
+		return ts
+	}
+
+	f(nil, 100, nil)
+
+	// no old samples
+	f([]prompbmarshal.TimeSeries{
+		newSeries("foo", 100, 200),
+		newSeries("bar", 300),
+	}, 100, []prompbmarshal.TimeSeries{
+		newSeries("foo", 100, 200),
+		newSeries("bar", 300),
+	})
+
+	// drop old samples and series without samples
+	f([]prompbmarshal.TimeSeries{
+		newSeries("foo", 50, 150, 99, 200),
+		newSeries("bar", 10, 20),
+		newSeries("baz", 300),
+	}, 100, []prompbmarshal.TimeSeries{
+		newSeries("foo", 150, 200),
+		newSeries("baz", 300),
+	})
+
+	// all the samples are old
+	f([]prompbmarshal.TimeSeries{
+		newSeries("foo", 50, 60),
+		newSeries("bar", 10),
+	}, 100, nil)
+}
+
 func parseSeries(data string) []prompbmarshal.TimeSeries {
 	var tss []prompbmarshal.TimeSeries
 	tss = append(tss, prompbmarshal.TimeSeries{
@@ -67,7 +67,17 @@ var (
 	maxPendingBytesPerURL = flagutil.NewArrayBytes("remoteWrite.maxDiskUsagePerURL", 0, "The maximum file-based buffer size in bytes at -remoteWrite.tmpDataPath "+
 		"for each -remoteWrite.url. When buffer size reaches the configured maximum, then old data is dropped when adding new data to the buffer. "+
 		"Buffered data is stored in ~500MB chunks. It is recommended to set the value for this flag to a multiple of the block size 500MB. "+
-		"Disk usage is unlimited if the value is set to 0")
+		"Disk usage is unlimited if the value is set to 0. See also -remoteWrite.dropPolicy")
+	dropPolicy = flagutil.NewArrayString("remoteWrite.dropPolicy", "Which data to drop from the file-based buffer at -remoteWrite.tmpDataPath "+
+		"when its size reaches -remoteWrite.maxDiskUsagePerURL for the corresponding -remoteWrite.url. Supported values: "+
+		"drop-oldest - drop the oldest buffered data; drop-newest - drop new data; "+
+		"block-with-timeout - block data ingestion for up to -remoteWrite.dropPolicy.blockTimeout and then drop new data. "+
+		"By default, the oldest data is dropped. See https://docs.victoriametrics.com/vmagent/#drop-policy-for-on-disk-persistence")
+	dropPolicyBlockTimeout = flagutil.NewArrayDuration("remoteWrite.dropPolicy.blockTimeout", 10*time.Second, "The maximum duration to block data ingestion "+
+		"when the file-based buffer for the corresponding -remoteWrite.url is full and -remoteWrite.dropPolicy=block-with-timeout is set")
+	maxSampleAge = flagutil.NewArrayDuration("remoteWrite.maxSampleAge", 0, "Optional maximum age for samples sent to the corresponding -remoteWrite.url. "+
+		"Older samples are dropped before being queued for sending to this -remoteWrite.url, while other -remoteWrite.url systems still receive them. "+
+		"This may be useful when the remote storage rejects old samples anyway. By default samples of any age are sent")
 	significantFigures = flagutil.NewArrayInt("remoteWrite.significantFigures", 0, "The number of significant figures to leave in metric values before writing them "+
 		"to remote storage. See https://en.wikipedia.org/wiki/Significant_figures . Zero value saves all the significant figures. "+
 		"This option may be used for improving data compression for the stored metrics. See also -remoteWrite.roundDigits")
@@ -84,14 +94,17 @@ var (
 		"Excess series are logged and dropped. This can be useful for limiting series cardinality. See https://docs.victoriametrics.com/vmagent/#cardinality-limiter")
 	maxDailySeries = flag.Int("remoteWrite.maxDailySeries", 0, "The maximum number of unique series vmagent can send to remote storage systems during the last 24 hours. "+
 		"Excess series are logged and dropped. This can be useful for limiting series churn rate. See https://docs.victoriametrics.com/vmagent/#cardinality-limiter")
-	maxIngestionRate = flag.Int("maxIngestionRate", 0, "The maximum number of samples vmagent can receive per second. Data ingestion is paused when the limit is exceeded. "+
-		"By default there are no limits on samples ingestion rate. See also -remoteWrite.rateLimit")
+	maxIngestionRate = flagutil.NewTunableInt("maxIngestionRate", 0, "The maximum number of samples vmagent can receive per second. Data ingestion is paused when the limit is exceeded. "+
+		"By default there are no limits on samples ingestion rate. The limit can be changed at runtime via /-/flags endpoint. See also -remoteWrite.rateLimit", nil)
 
 	disableOnDiskQueue = flagutil.NewArrayBool("remoteWrite.disableOnDiskQueue", "Whether to disable storing pending data to -remoteWrite.tmpDataPath "+
 		"when the remote storage system at the corresponding -remoteWrite.url cannot keep up with the data ingestion rate. "+
 		"See https://docs.victoriametrics.com/vmagent#disabling-on-disk-persistence . See also -remoteWrite.dropSamplesOnOverload")
 	dropSamplesOnOverload = flag.Bool("remoteWrite.dropSamplesOnOverload", false, "Whether to drop samples when -remoteWrite.disableOnDiskQueue is set and if the samples "+
 		"cannot be pushed into the configured -remoteWrite.url systems in a timely manner. See https://docs.victoriametrics.com/vmagent#disabling-on-disk-persistence")
+	persistentQueueDedupLabels = flag.Bool("remoteWrite.persistentQueue.dedupLabels", false, "Whether to store repeated label sets only once per chunk file "+
+		"in the persistent queue at -remoteWrite.tmpDataPath. This reduces disk space usage, but the persistent queue cannot be read by vmagent "+
+		"versions without this flag or when this flag is disabled. See https://docs.victoriametrics.com/vmagent/#on-disk-persistence")
 )
 
 var (
@@ -189,6 +202,7 @@ func Init() {
 	shardByURLIgnoreLabelsMap = newMapFromStrings(*shardByURLIgnoreLabels)
 
 	initLabelsGlobal()
+	initResourceLabels()
 
 	// Register SIGHUP handler for config reload before loadRelabelConfigs.
 	// This guarantees that the config will be re-read if the signal arrives just after loadRelabelConfig.
@@ -203,9 +217,14 @@ func Init() {
 	relabelConfigSuccess.Set(1)
 	relabelConfigTimestamp.Set(fasttime.UnixTimestamp())
 
+	initTenantLimits()
+
 	initStreamAggrConfigGlobal()
 
+	initEventsWriter()
+
 	rwctxsGlobal = newRemoteWriteCtxs(*remoteWriteURLs)
+	initTopology()
 
 	disableOnDiskQueues := []bool(*disableOnDiskQueue)
 	disableOnDiskQueueAny = slices.Contains(disableOnDiskQueues, true)
@@ -229,6 +248,7 @@ func Init() {
 			case <-sighupCh:
 			}
 			reloadRelabelConfigs()
+			reloadTenantLimits()
 			reloadStreamAggrConfigs()
 		}
 	}()
@@ -295,16 +315,7 @@ func newRemoteWriteCtxs(urls []string) []*remoteWriteCtx {
 		logger.Panicf("BUG: urls must be non-empty")
 	}
 
-	maxInmemoryBlocks := memory.Allowed() / len(urls) / *maxRowsPerBlock / 100
-	if maxInmemoryBlocks / *queues > 100 {
-		// There is no much sense in keeping higher number of blocks in memory,
-		// since this means that the producer outperforms consumer and the queue
-		// will continue growing. It is better storing the queue to file.
-		maxInmemoryBlocks = 100 * *queues
-	}
-	if maxInmemoryBlocks < 2 {
-		maxInmemoryBlocks = 2
-	}
+	maxInmemoryBlocks := getMaxInmemoryBlocks(len(urls))
 	rwctxs := make([]*remoteWriteCtx, len(urls))
 	for i, remoteWriteURLRaw := range urls {
 		remoteWriteURL, err := url.Parse(remoteWriteURLRaw)
@@ -320,6 +331,21 @@ func newRemoteWriteCtxs(urls []string) []*remoteWriteCtx {
 	return rwctxs
 }
 
+// getMaxInmemoryBlocks returns the maximum number of in-memory blocks per each remote storage system out of urlsCount systems.
+func getMaxInmemoryBlocks(urlsCount int) int {
+	maxInmemoryBlocks := memory.Allowed() / urlsCount / *maxRowsPerBlock / 100
+	if maxInmemoryBlocks / *queues > 100 {
+		// There is no much sense in keeping higher number of blocks in memory,
+		// since this means that the producer outperforms consumer and the queue
+		// will continue growing. It is better storing the queue to file.
+		maxInmemoryBlocks = 100 * *queues
+	}
+	if maxInmemoryBlocks < 2 {
+		maxInmemoryBlocks = 2
+	}
+	return maxInmemoryBlocks
+}
+
 var (
 	configReloaderStopCh = make(chan struct{})
 	configReloaderWG     sync.WaitGroup
@@ -332,12 +358,12 @@ var (
 // StopIngestionRateLimiter must be called before Stop() call in order to unblock all the callers
 // to ingestion rate limiter. Otherwise deadlock may occur at Stop() call.
 func StartIngestionRateLimiter() {
-	if *maxIngestionRate <= 0 {
-		return
-	}
 	ingestionRateLimitReached := metrics.NewCounter(`vmagent_max_ingestion_rate_limit_reached_total`)
 	ingestionRateLimiterStopCh = make(chan struct{})
-	ingestionRateLimiter = ratelimiter.New(int64(*maxIngestionRate), ingestionRateLimitReached, ingestionRateLimiterStopCh)
+	ingestionRateLimiter = ratelimiter.New(int64(maxIngestionRate.Get()), ingestionRateLimitReached, ingestionRateLimiterStopCh)
+	maxIngestionRate.OnChange(func() {
+		ingestionRateLimiter.SetLimit(int64(maxIngestionRate.Get()))
+	})
 }
 
 // StopIngestionRateLimiter stops ingestion rate limiter.
@@ -361,12 +387,17 @@ func Stop() {
 	close(configReloaderStopCh)
 	configReloaderWG.Wait()
 
+	stopTenantLimits()
+
+	stopEventsWriter()
+
 	sasGlobal.Load().MustStop()
 	if deduplicatorGlobal != nil {
 		deduplicatorGlobal.MustStop()
 		deduplicatorGlobal = nil
 	}
 
+	stopTopology()
 	for _, rwctx := range rwctxsGlobal {
 		rwctx.MustStop()
 	}
@@ -380,6 +411,16 @@ func Stop() {
 	}
 }
 
+// GetMaxQueueUtilization returns the maximum fraction of in-memory queue capacity occupied by pending blocks
+// across queues for -remoteWrite.url.
+func GetMaxQueueUtilization() float64 {
+	var n float64
+	for _, rwctx := range rwctxsGlobal {
+		n = max(n, rwctx.fq.GetInmemoryQueueUtilization())
+	}
+	return n
+}
+
 // PushDropSamplesOnFailure pushes wr to the configured remote storage systems set via -remoteWrite.url
 //
 // PushDropSamplesOnFailure drops wr samples if they cannot be sent to -remoteWrite.url by any reason.
@@ -480,6 +521,9 @@ func tryPush(at *auth.Token, wr *prompbmarshal.WriteRequest, forceDropSamplesOnF
 			}
 			tssBlock = tmpBlock
 		}
+		if eventsWriterGlobal != nil {
+			tssBlock = eventsWriterGlobal.push(tssBlock)
+		}
 		sortLabelsIfNeeded(tssBlock)
 		tssBlock = limitSeriesCardinality(tssBlock)
 		if sas.IsEnabled() {
@@ -555,6 +599,10 @@ func tryPushBlockToRemoteStorages(rwctxs []*remoteWriteCtx, tssBlock []prompbmar
 		return tryShardingBlockAmongRemoteStorages(rwctxs, tssBlock, replicas, forceDropSamplesOnFailure)
 	}
 
+	return replicateBlockAmongRemoteStorages(rwctxs, tssBlock, forceDropSamplesOnFailure)
+}
+
+func replicateBlockAmongRemoteStorages(rwctxs []*remoteWriteCtx, tssBlock []prompbmarshal.TimeSeries, forceDropSamplesOnFailure bool) bool {
 	// Replicate tssBlock samples among rwctxs.
 	// Push tssBlock to remote storage systems in parallel in order to reduce
 	// the time needed for sending the data to multiple remote storage systems.
@@ -578,6 +626,15 @@ func tryShardingBlockAmongRemoteStorages(rwctxs []*remoteWriteCtx, tssBlock []pr
 	defer putTSSShards(x)
 
 	shards := x.shards
+
+	// Route series according to the topology advertised at -remoteWrite.shardByURL.topologyURL if it is available.
+	// Fall back to the default sharding if some of remote storage systems are excluded from rwctxs,
+	// since shards are indexed by position in rwctxs.
+	st := topologyGlobal.Load()
+	if st != nil && len(rwctxs) != len(rwctxsGlobal) {
+		st = nil
+	}
+
 	tmpLabels := promutil.GetLabels()
 	for _, ts := range tssBlock {
 		hashLabels := ts.Labels
@@ -599,6 +656,10 @@ func tryShardingBlockAmongRemoteStorages(rwctxs []*remoteWriteCtx, tssBlock []pr
 			tmpLabels.Labels = hashLabels
 		}
 		h := getLabelsHash(hashLabels)
+		if st != nil {
+			st.appendSeries(shards, ts, h, replicas)
+			continue
+		}
 		idx := h % uint64(len(shards))
 		i := 0
 		for {
@@ -748,6 +809,15 @@ type remoteWriteCtx struct {
 	streamAggrKeepInput bool
 	streamAggrDropInput bool
 
+	// maxSampleAge is the maximum age for samples pushed to the remote storage. Older samples are dropped.
+	maxSampleAge time.Duration
+
+	// resourceLabels contains labels from -remoteWrite.resourceDetection, which must be added to the pushed metrics without such labels.
+	resourceLabels []prompbmarshal.Label
+
+	// extraLabels contains labels from -remoteWrite.label, which must be added to all the pushed metrics.
+	extraLabels []prompbmarshal.Label
+
 	pss        []*pendingSeries
 	pssNextIdx atomic.Uint64
 
@@ -756,6 +826,7 @@ type remoteWriteCtx struct {
 
 	pushFailures             *metrics.Counter
 	rowsDroppedOnPushFailure *metrics.Counter
+	rowsDroppedByAge         *metrics.Counter
 }
 
 func newRemoteWriteCtx(argIdx int, remoteWriteURL *url.URL, maxInmemoryBlocks int, sanitizedURL string) *remoteWriteCtx {
@@ -765,6 +836,20 @@ func newRemoteWriteCtx(argIdx int, remoteWriteURL *url.URL, maxInmemoryBlocks in
 	pqURL.Fragment = ""
 	h := xxhash.Sum64([]byte(pqURL.String()))
 	queuePath := filepath.Join(*tmpDataPath, persistentQueueDirname, fmt.Sprintf("%d_%016X", argIdx+1, h))
+	newClient := func(fq *persistentqueue.FastQueue) *client {
+		return newHTTPClient(argIdx, remoteWriteURL.String(), sanitizedURL, fq, *queues)
+	}
+	rwctx := newRemoteWriteCtxWithClient(argIdx, remoteWriteURL, queuePath, maxInmemoryBlocks, sanitizedURL, newClient)
+	rwctx.initStreamAggrConfig()
+
+	return rwctx
+}
+
+// newRemoteWriteCtxWithClient returns remoteWriteCtx, which stores pending data at queuePath and sends it via the client returned by newClient.
+//
+// Settings for the returned remoteWriteCtx are obtained from -remoteWrite.* command-line flags at argIdx.
+func newRemoteWriteCtxWithClient(argIdx int, remoteWriteURL *url.URL, queuePath string, maxInmemoryBlocks int, sanitizedURL string,
+	newClient func(fq *persistentqueue.FastQueue) *client) *remoteWriteCtx {
 	maxPendingBytes := maxPendingBytesPerURL.GetOptionalArg(argIdx)
 	if maxPendingBytes != 0 && maxPendingBytes < persistentqueue.DefaultChunkFileSize {
 		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/4195
@@ -773,7 +858,16 @@ func newRemoteWriteCtx(argIdx int, remoteWriteURL *url.URL, maxInmemoryBlocks in
 	}
 
 	isPQDisabled := disableOnDiskQueue.GetOptionalArg(argIdx)
-	fq := persistentqueue.MustOpenFastQueue(queuePath, sanitizedURL, maxInmemoryBlocks, maxPendingBytes, isPQDisabled)
+	dp, err := persistentqueue.ParseDropPolicy(dropPolicy.GetOptionalArg(argIdx))
+	if err != nil {
+		logger.Fatalf("invalid -remoteWrite.dropPolicy for -remoteWrite.url=%q: %s", sanitizedURL, err)
+	}
+	blockTimeout := dropPolicyBlockTimeout.GetOptionalArg(argIdx)
+	var codec persistentqueue.BlockCodec
+	if *persistentQueueDedupLabels {
+		codec = labelsDictCodec{}
+	}
+	fq := persistentqueue.MustOpenFastQueue(queuePath, sanitizedURL, maxInmemoryBlocks, maxPendingBytes, isPQDisabled, dp, blockTimeout, codec)
 	_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_pending_data_bytes{path=%q, url=%q}`, queuePath, sanitizedURL), func() float64 {
 		return float64(fq.GetPendingBytes())
 	})
@@ -790,7 +884,7 @@ func newRemoteWriteCtx(argIdx int, remoteWriteURL *url.URL, maxInmemoryBlocks in
 	var c *client
 	switch remoteWriteURL.Scheme {
 	case "http", "https":
-		c = newHTTPClient(argIdx, remoteWriteURL.String(), sanitizedURL, fq, *queues)
+		c = newClient(fq)
 	default:
 		logger.Fatalf("unsupported scheme: %s for remoteWriteURL: %s, want `http`, `https`", remoteWriteURL.Scheme, sanitizedURL)
 	}
@@ -816,14 +910,17 @@ func newRemoteWriteCtx(argIdx int, remoteWriteURL *url.URL, maxInmemoryBlocks in
 		c:   c,
 		pss: pss,
 
+		maxSampleAge:   maxSampleAge.GetOptionalArg(argIdx),
+		resourceLabels: getResourceLabels(argIdx),
+		extraLabels:    labelsGlobal,
+
 		rowsPushedAfterRelabel: metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_rows_pushed_after_relabel_total{path=%q,url=%q}`, queuePath, sanitizedURL)),
 		rowsDroppedByRelabel:   metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_relabel_metrics_dropped_total{path=%q,url=%q}`, queuePath, sanitizedURL)),
 
 		pushFailures:             metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_push_failures_total{path=%q,url=%q}`, queuePath, sanitizedURL)),
 		rowsDroppedOnPushFailure: metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_samples_dropped_total{path=%q,url=%q}`, queuePath, sanitizedURL)),
+		rowsDroppedByAge:         metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_old_samples_dropped_total{path=%q,url=%q}`, queuePath, sanitizedURL)),
 	}
-	rwctx.initStreamAggrConfig()
-
 	return rwctx
 }
 
@@ -871,7 +968,11 @@ func (rwctx *remoteWriteCtx) TryPush(tss []prompbmarshal.TimeSeries, forceDropSa
 
 	// Apply relabeling
 	rcs := allRelabelConfigs.Load()
-	pcs := rcs.perURL[rwctx.idx]
+	var pcs *promrelabel.ParsedConfigs
+	if rwctx.idx < len(rcs.perURL) {
+		// rwctx.idx may exceed the number of -remoteWrite.url items for remote storage systems owned by namespaces.
+		pcs = rcs.perURL[rwctx.idx]
+	}
 	if pcs.Len() > 0 {
 		rctx = getRelabelCtx()
 		// Make a copy of tss before applying relabeling in order to prevent
@@ -965,13 +1066,29 @@ func (rwctx *remoteWriteCtx) tryPushInternal(tss []prompbmarshal.TimeSeries) boo
 		putRelabelCtx(rctx)
 	}()
 
-	if len(labelsGlobal) > 0 {
-		// Make a copy of tss before adding extra labels in order to prevent
-		// from affecting time series for other remoteWrite.url configs.
-		rctx = getRelabelCtx()
-		v = tssPool.Get().(*[]prompbmarshal.TimeSeries)
-		tss = append(*v, tss...)
-		rctx.appendExtraLabels(tss, labelsGlobal)
+	if rwctx.maxSampleAge > 0 {
+		minTimestamp := int64(fasttime.UnixTimestamp())*1000 - rwctx.maxSampleAge.Milliseconds()
+		if hasOldSamples(tss, minTimestamp) {
+			// Make a copy of tss before dropping old samples in order to prevent
+			// from affecting time series for other remoteWrite.url configs.
+			rctx = getRelabelCtx()
+			v = tssPool.Get().(*[]prompbmarshal.TimeSeries)
+			tss = append(*v, tss...)
+			rowsCountBefore := getRowsCount(tss)
+			tss = rctx.dropOldSamples(tss, minTimestamp)
+			rwctx.rowsDroppedByAge.Add(rowsCountBefore - getRowsCount(tss))
+		}
+	}
+
+	if len(rwctx.resourceLabels) > 0 || len(rwctx.extraLabels) > 0 {
+		if rctx == nil {
+			// Make a copy of tss before adding extra labels in order to prevent
+			// from affecting time series for other remoteWrite.url configs.
+			rctx = getRelabelCtx()
+			v = tssPool.Get().(*[]prompbmarshal.TimeSeries)
+			tss = append(*v, tss...)
+		}
+		rctx.appendExtraLabels(tss, rwctx.resourceLabels, rwctx.extraLabels)
 	}
 
 	pss := rwctx.pss
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/persistentqueue"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
@@ -68,17 +69,25 @@ func TestRemoteWriteContext_TryPush_ImmutableTimeseries(t *testing.T) {
 		}
 		allRelabelConfigs.Store(rcs)
 
+		// pendingSeries flushes the pushed samples to fq in background, so fq must be non-nil.
+		fq := persistentqueue.MustOpenFastQueue(t.TempDir(), "test", 100, 0, false, persistentqueue.DropOldest, 0, nil)
 		pss := make([]*pendingSeries, 1)
 		isVMProto := &atomic.Bool{}
 		isVMProto.Store(true)
-		pss[0] = newPendingSeries(nil, isVMProto, 0, 100)
+		pss[0] = newPendingSeries(fq, isVMProto, 0, 100)
+		defer func() {
+			pss[0].MustStop()
+			fq.MustClose()
+		}()
 		rwctx := &remoteWriteCtx{
 			idx:                    0,
 			streamAggrKeepInput:    keepInput,
 			streamAggrDropInput:    dropInput,
 			pss:                    pss,
+			maxSampleAge:           time.Hour,
 			rowsPushedAfterRelabel: metrics.GetOrCreateCounter(`foo`),
 			rowsDroppedByRelabel:   metrics.GetOrCreateCounter(`bar`),
+			rowsDroppedByAge:       metrics.GetOrCreateCounter(`baz`),
 		}
 		if dedupInterval > 0 {
 			rwctx.deduplicator = streamaggr.NewDeduplicator(nil, enableWindows, dedupInterval, nil, "dedup-global")
@@ -172,5 +181,11 @@ metric{env="dev"} 10
 metric{env="test"} 20
 metric{env="dev"} 15
 metric{env="bar"} 25
+`)
+	f(``, ``, false, 0, false, false, `
+metric{env="dev"} 10 -7200000
+metric{env="test"} 20
+metric{env="dev"} 15 -7200000
+metric{env="bar"} 25
 `)
 }
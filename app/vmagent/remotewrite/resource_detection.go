@@ -0,0 +1,502 @@
+package remotewrite
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+var (
+	resourceDetection = flagutil.NewArrayBool("remoteWrite.resourceDetection", "Whether to add labels with host and cloud metadata such as instance id, region, "+
+		"availability zone and Kubernetes node name to all the metrics sent to the corresponding -remoteWrite.url. "+
+		"See https://docs.victoriametrics.com/vmagent/#resource-detection")
+	resourceDetectors = flagutil.NewArrayString("remoteWrite.resourceDetection.detectors", "Optional list of detectors to use for -remoteWrite.resourceDetection. "+
+		"Supported detectors: host, ec2, gce, azure, kubernetes. By default all the detectors are used. "+
+		"See https://docs.victoriametrics.com/vmagent/#resource-detection")
+	resourceDetectionTimeout = flag.Duration("remoteWrite.resourceDetection.timeout", 5*time.Second, "Timeout for obtaining metadata from cloud metadata servers "+
+		"and Kubernetes API server for -remoteWrite.resourceDetection. See https://docs.victoriametrics.com/vmagent/#resource-detection")
+)
+
+// resourceLabels contains labels detected by resourceDetectors.
+//
+// It is initialized by initResourceLabels.
+var resourceLabels []prompbmarshal.Label
+
+// These URLs are overridden in tests.
+var (
+	ec2MetadataURL   = "http://169.254.169.254"
+	gceMetadataURL   = "http://metadata.google.internal"
+	azureMetadataURL = "http://169.254.169.254"
+)
+
+// resourceDetector returns labels for the resource where vmagent runs.
+type resourceDetector func(c *http.Client) ([]prompbmarshal.Label, error)
+
+var allResourceDetectors = map[string]resourceDetector{
+	"host":       detectHostResource,
+	"ec2":        detectEC2Resource,
+	"gce":        detectGCEResource,
+	"azure":      detectAzureResource,
+	"kubernetes": detectKubernetesResource,
+}
+
+// cloudResourceDetectors contains detectors, which are mutually exclusive, since vmagent can run only at a single cloud.
+var cloudResourceDetectors = []string{"ec2", "gce", "azure"}
+
+// initResourceLabels initializes resourceLabels if -remoteWrite.resourceDetection is set for at least a single -remoteWrite.url.
+//
+// It must be called after parsing command-line flags.
+func initResourceLabels() {
+	resourceLabels = nil
+	if !slices.Contains(*resourceDetection, true) {
+		return
+	}
+	names, err := getResourceDetectorNames(*resourceDetectors)
+	if err != nil {
+		logger.Fatalf("invalid -remoteWrite.resourceDetection.detectors: %s", err)
+	}
+	c := &http.Client{
+		Timeout: *resourceDetectionTimeout,
+	}
+	resourceLabels = detectResourceLabels(c, names)
+	logger.Infof("detected the following resource labels for -remoteWrite.resourceDetection: %s", formatLabels(resourceLabels))
+}
+
+func getResourceDetectorNames(names []string) ([]string, error) {
+	var result []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := allResourceDetectors[name]; !ok {
+			return nil, fmt.Errorf("unsupported detector %q; supported detectors: host, ec2, gce, azure, kubernetes", name)
+		}
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		result = []string{"host", "ec2", "gce", "azure", "kubernetes"}
+	}
+	return result, nil
+}
+
+// detectResourceLabels runs detectors with the given names in parallel and returns the detected labels sorted by name.
+//
+// Labels from cloud detectors are taken only from the first successful detector in the cloudResourceDetectors order.
+// Labels with duplicate names are taken from the cloud detector.
+func detectResourceLabels(c *http.Client, names []string) []prompbmarshal.Label {
+	results := make([][]prompbmarshal.Label, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			labels, err := allResourceDetectors[name](c)
+			if err != nil {
+				logger.Infof("skipping %q detector for -remoteWrite.resourceDetection: %s", name, err)
+				return
+			}
+			results[i] = labels
+		}(i, name)
+	}
+	wg.Wait()
+
+	var labels []prompbmarshal.Label
+	addLabels := func(src []prompbmarshal.Label) {
+		for _, label := range src {
+			if !slices.ContainsFunc(labels, func(l prompbmarshal.Label) bool { return l.Name == label.Name }) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	for _, name := range cloudResourceDetectors {
+		idx := slices.Index(names, name)
+		if idx < 0 || results[idx] == nil {
+			continue
+		}
+		addLabels(results[idx])
+		break
+	}
+	// Labels from the remaining detectors cannot override labels from the cloud metadata server,
+	// since the latter are more precise than the labels obtained from Kubernetes node.
+	for i, name := range names {
+		if !slices.Contains(cloudResourceDetectors, name) {
+			addLabels(results[i])
+		}
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func detectHostResource(_ *http.Client) ([]prompbmarshal.Label, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain hostname: %w", err)
+	}
+	return newResourceLabels("host_name", hostname), nil
+}
+
+func detectKubernetesResource(c *http.Client) ([]prompbmarshal.Label, error) {
+	// The node name can be passed to vmagent pod via Kubernetes downward API.
+	// See https://kubernetes.io/docs/concepts/workloads/pods/downward-api/
+	nodeName := os.Getenv("K8S_NODE_NAME")
+
+	kc, err := newKubernetesAPIClient(c)
+	if err != nil {
+		if nodeName == "" {
+			return nil, fmt.Errorf("missing K8S_NODE_NAME environment variable and cannot access Kubernetes API server: %w", err)
+		}
+		return newResourceLabels("k8s_node_name", nodeName), nil
+	}
+	if nodeName == "" {
+		nodeName, err = kc.getPodNodeName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Obtain the metadata for the node from the Node object registered by the kubelet.
+	// See https://kubernetes.io/docs/reference/labels-annotations-taints/
+	var node struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			ProviderID string `json:"providerID"`
+		} `json:"spec"`
+	}
+	if err := kc.getJSON("/api/v1/nodes/"+url.PathEscape(nodeName), &node); err != nil {
+		logger.Infof("cannot obtain metadata for Kubernetes node %q for -remoteWrite.resourceDetection: %s; "+
+			"make sure vmagent has permissions for `get` requests to `nodes`", nodeName, err)
+		return newResourceLabels("k8s_node_name", nodeName), nil
+	}
+	getNodeLabel := func(names ...string) string {
+		for _, name := range names {
+			if v := node.Metadata.Labels[name]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	cloudProvider, hostID := parseKubernetesProviderID(node.Spec.ProviderID)
+	return newResourceLabels(
+		"k8s_node_name", nodeName,
+		"cloud_provider", cloudProvider,
+		"cloud_region", getNodeLabel("topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"),
+		"cloud_availability_zone", getNodeLabel("topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"),
+		"host_id", hostID,
+		"host_type", getNodeLabel("node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"),
+	), nil
+}
+
+// parseKubernetesProviderID returns cloud provider and instance id from the given Kubernetes node providerID.
+//
+// For example, `aws:///us-east-1a/i-1234567890abcdef0` is parsed into `aws` and `i-1234567890abcdef0`.
+func parseKubernetesProviderID(providerID string) (string, string) {
+	scheme, path, ok := strings.Cut(providerID, "://")
+	if !ok {
+		return "", ""
+	}
+	hostID := path[strings.LastIndexByte(path, '/')+1:]
+	switch scheme {
+	case "aws":
+		return "aws", hostID
+	case "gce":
+		return "gcp", hostID
+	case "azure":
+		return "azure", hostID
+	default:
+		return "", hostID
+	}
+}
+
+// These vars are overridden in tests.
+var (
+	kubernetesAPIServer         = ""
+	kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// kubernetesAPIClient is a client for Kubernetes API server, which uses vmagent pod service account.
+type kubernetesAPIClient struct {
+	apiServer string
+	ac        *promauth.Config
+	c         *http.Client
+}
+
+func newKubernetesAPIClient(c *http.Client) (*kubernetesAPIClient, error) {
+	// See https://kubernetes.io/docs/tasks/run-application/access-api-from-pod/
+	apiServer := kubernetesAPIServer
+	if apiServer == "" {
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		port := os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("missing KUBERNETES_SERVICE_HOST or KUBERNETES_SERVICE_PORT environment variables; probably, vmagent doesn't run in Kubernetes")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+	opts := &promauth.Options{
+		BearerTokenFile: filepath.Join(kubernetesServiceAccountDir, "token"),
+	}
+	if strings.HasPrefix(apiServer, "https://") {
+		opts.TLSConfig = &promauth.TLSConfig{
+			CAFile: filepath.Join(kubernetesServiceAccountDir, "ca.crt"),
+		}
+	}
+	ac, err := opts.NewConfig()
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize service account auth: %w", err)
+	}
+	return &kubernetesAPIClient{
+		apiServer: apiServer,
+		ac:        ac,
+		c: &http.Client{
+			Timeout:   c.Timeout,
+			Transport: ac.NewRoundTripper(&http.Transport{}),
+		},
+	}, nil
+}
+
+// getPodNodeName returns the name of the node where vmagent pod runs.
+func (kc *kubernetesAPIClient) getPodNodeName() (string, error) {
+	namespace, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace"))
+	if err != nil {
+		return "", fmt.Errorf("cannot read pod namespace: %w", err)
+	}
+	// Pod hostname equals to pod name by default.
+	podName := os.Getenv("POD_NAME")
+	if podName == "" {
+		podName, err = os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("cannot obtain pod name: %w", err)
+		}
+	}
+	var pod struct {
+		Spec struct {
+			NodeName string `json:"nodeName"`
+		} `json:"spec"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", url.PathEscape(strings.TrimSpace(string(namespace))), url.PathEscape(podName))
+	if err := kc.getJSON(path, &pod); err != nil {
+		return "", fmt.Errorf("cannot obtain node name for pod %q: %w", podName, err)
+	}
+	if pod.Spec.NodeName == "" {
+		return "", fmt.Errorf("missing spec.nodeName for pod %q", podName)
+	}
+	return pod.Spec.NodeName, nil
+}
+
+func (kc *kubernetesAPIClient) getJSON(path string, dst any) error {
+	apiURL := kc.apiServer + path
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create request to %q: %w", apiURL, err)
+	}
+	if err := kc.ac.SetHeaders(req, true); err != nil {
+		return fmt.Errorf("cannot set request headers for %q: %w", apiURL, err)
+	}
+	data, err := readMetadataResponse(kc.c, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("cannot parse response from %q: %w", apiURL, err)
+	}
+	return nil
+}
+
+func detectEC2Resource(c *http.Client) ([]prompbmarshal.Label, error) {
+	// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/configuring-instance-metadata-service.html
+	tokenURL := ec2MetadataURL + "/latest/api/token"
+	req, err := http.NewRequest(http.MethodPut, tokenURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request for IMDSv2 session token at %q: %w", tokenURL, err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := readMetadataResponse(c, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-identity-documents.html
+	docURL := ec2MetadataURL + "/latest/dynamic/instance-identity/document"
+	req, err = http.NewRequest(http.MethodGet, docURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %w", docURL, err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	data, err := readMetadataResponse(c, req)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		AccountID        string `json:"accountId"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse instance identity document from %q: %w", docURL, err)
+	}
+	return newResourceLabels(
+		"cloud_provider", "aws",
+		"cloud_platform", "aws_ec2",
+		"cloud_account_id", doc.AccountID,
+		"cloud_region", doc.Region,
+		"cloud_availability_zone", doc.AvailabilityZone,
+		"host_id", doc.InstanceID,
+		"host_type", doc.InstanceType,
+	), nil
+}
+
+func detectGCEResource(c *http.Client) ([]prompbmarshal.Label, error) {
+	// See https://cloud.google.com/compute/docs/metadata/predefined-metadata-keys
+	getMetadata := func(path string) (string, error) {
+		metadataURL := gceMetadataURL + "/computeMetadata/v1/" + path
+		req, err := http.NewRequest(http.MethodGet, metadataURL, nil)
+		if err != nil {
+			return "", fmt.Errorf("cannot create request to %q: %w", metadataURL, err)
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		data, err := readMetadataResponse(c, req)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	projectID, err := getMetadata("project/project-id")
+	if err != nil {
+		return nil, err
+	}
+	instanceID, err := getMetadata("instance/id")
+	if err != nil {
+		return nil, err
+	}
+	// The zone and the machine type are returned in the form `projects/<project_number>/zones/<zone>`
+	// and `projects/<project_number>/machineTypes/<machine_type>`.
+	zone, err := getMetadata("instance/zone")
+	if err != nil {
+		return nil, err
+	}
+	zone = zone[strings.LastIndexByte(zone, '/')+1:]
+	machineType, err := getMetadata("instance/machine-type")
+	if err != nil {
+		return nil, err
+	}
+	machineType = machineType[strings.LastIndexByte(machineType, '/')+1:]
+
+	// The region is the zone without the last `-<suffix>` part. For example, the region for `us-central1-a` zone is `us-central1`.
+	region := zone
+	if n := strings.LastIndexByte(zone, '-'); n > 0 {
+		region = zone[:n]
+	}
+	return newResourceLabels(
+		"cloud_provider", "gcp",
+		"cloud_platform", "gcp_compute_engine",
+		"cloud_account_id", projectID,
+		"cloud_region", region,
+		"cloud_availability_zone", zone,
+		"host_id", instanceID,
+		"host_type", machineType,
+	), nil
+}
+
+func detectAzureResource(c *http.Client) ([]prompbmarshal.Label, error) {
+	// See https://learn.microsoft.com/en-us/azure/virtual-machines/instance-metadata-service
+	metadataURL := azureMetadataURL + "/metadata/instance/compute?api-version=2021-02-01&format=json"
+	req, err := http.NewRequest(http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %w", metadataURL, err)
+	}
+	req.Header.Set("Metadata", "true")
+	data, err := readMetadataResponse(c, req)
+	if err != nil {
+		return nil, err
+	}
+	var compute struct {
+		Location       string `json:"location"`
+		SubscriptionID string `json:"subscriptionId"`
+		VMID           string `json:"vmId"`
+		VMSize         string `json:"vmSize"`
+		Zone           string `json:"zone"`
+	}
+	if err := json.Unmarshal(data, &compute); err != nil {
+		return nil, fmt.Errorf("cannot parse instance metadata from %q: %w", metadataURL, err)
+	}
+	return newResourceLabels(
+		"cloud_provider", "azure",
+		"cloud_platform", "azure_vm",
+		"cloud_account_id", compute.SubscriptionID,
+		"cloud_region", compute.Location,
+		"cloud_availability_zone", compute.Zone,
+		"host_id", compute.VMID,
+		"host_type", compute.VMSize,
+	), nil
+}
+
+func readMetadataResponse(c *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain response from %q: %w", req.URL, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %q: %w", req.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for %q; got %d; want %d; response body: %q", req.URL, resp.StatusCode, http.StatusOK, data)
+	}
+	return data, nil
+}
+
+// newResourceLabels returns labels for the given name-value pairs. Labels with empty values are skipped.
+func newResourceLabels(nameValues ...string) []prompbmarshal.Label {
+	labels := make([]prompbmarshal.Label, 0, len(nameValues)/2)
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		if nameValues[i+1] == "" {
+			continue
+		}
+		labels = append(labels, prompbmarshal.Label{
+			Name:  nameValues[i],
+			Value: nameValues[i+1],
+		})
+	}
+	return labels
+}
+
+func formatLabels(labels []prompbmarshal.Label) string {
+	a := make([]string, len(labels))
+	for i, label := range labels {
+		a[i] = fmt.Sprintf("%s=%q", label.Name, label.Value)
+	}
+	return "{" + strings.Join(a, ",") + "}"
+}
+
+// getResourceLabels returns labels detected via -remoteWrite.resourceDetection for -remoteWrite.url with the given argIdx.
+func getResourceLabels(argIdx int) []prompbmarshal.Label {
+	if !resourceDetection.GetOptionalArg(argIdx) {
+		return nil
+	}
+	return resourceLabels
+}
@@ -0,0 +1,309 @@
+package remotewrite
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestGetResourceDetectorNames(t *testing.T) {
+	f := func(names, resultExpected []string) {
+		t.Helper()
+
+		result, err := getResourceDetectorNames(names)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f(nil, []string{"host", "ec2", "gce", "azure", "kubernetes"})
+	f([]string{""}, []string{"host", "ec2", "gce", "azure", "kubernetes"})
+	f([]string{"gce"}, []string{"gce"})
+	f([]string{"ec2", "host", "ec2"}, []string{"ec2", "host"})
+
+	// unsupported detector
+	if _, err := getResourceDetectorNames([]string{"host", "foo"}); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestDetectResourceLabels(t *testing.T) {
+	ec2Srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write([]byte("secret-token"))
+		case "/latest/dynamic/instance-identity/document":
+			if r.Header.Get("X-aws-ec2-metadata-token") != "secret-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"accountId":"123456789012","availabilityZone":"us-east-1a","instanceId":"i-1234567890abcdef0","instanceType":"t3.micro","region":"us-east-1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ec2Srv.Close()
+
+	gceSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		m := map[string]string{
+			"/computeMetadata/v1/project/project-id":    "my-project",
+			"/computeMetadata/v1/instance/id":           "4567",
+			"/computeMetadata/v1/instance/zone":         "projects/123/zones/europe-west1-b",
+			"/computeMetadata/v1/instance/machine-type": "projects/123/machineTypes/e2-medium",
+		}
+		v, ok := m[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(v))
+	}))
+	defer gceSrv.Close()
+
+	azureSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Path != "/metadata/instance/compute" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"location":"westeurope","subscriptionId":"sub-1","vmId":"vm-1","vmSize":"Standard_B2s","zone":""}`))
+	}))
+	defer azureSrv.Close()
+
+	ec2MetadataURLOrig, gceMetadataURLOrig, azureMetadataURLOrig := ec2MetadataURL, gceMetadataURL, azureMetadataURL
+	defer func() {
+		ec2MetadataURL, gceMetadataURL, azureMetadataURL = ec2MetadataURLOrig, gceMetadataURLOrig, azureMetadataURLOrig
+	}()
+
+	t.Setenv("K8S_NODE_NAME", "node-1")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	f := func(names []string, labelsExpected []prompbmarshal.Label) {
+		t.Helper()
+
+		labels := detectResourceLabels(http.DefaultClient, names)
+		if !reflect.DeepEqual(labels, labelsExpected) {
+			t.Fatalf("unexpected labels\ngot\n%s\nwant\n%s", formatLabels(labels), formatLabels(labelsExpected))
+		}
+	}
+
+	ec2MetadataURL = ec2Srv.URL
+	gceMetadataURL = gceSrv.URL
+	azureMetadataURL = azureSrv.URL
+
+	// ec2
+	f([]string{"ec2", "kubernetes"}, []prompbmarshal.Label{
+		{Name: "cloud_account_id", Value: "123456789012"},
+		{Name: "cloud_availability_zone", Value: "us-east-1a"},
+		{Name: "cloud_platform", Value: "aws_ec2"},
+		{Name: "cloud_provider", Value: "aws"},
+		{Name: "cloud_region", Value: "us-east-1"},
+		{Name: "host_id", Value: "i-1234567890abcdef0"},
+		{Name: "host_type", Value: "t3.micro"},
+		{Name: "k8s_node_name", Value: "node-1"},
+	})
+
+	// gce
+	f([]string{"gce"}, []prompbmarshal.Label{
+		{Name: "cloud_account_id", Value: "my-project"},
+		{Name: "cloud_availability_zone", Value: "europe-west1-b"},
+		{Name: "cloud_platform", Value: "gcp_compute_engine"},
+		{Name: "cloud_provider", Value: "gcp"},
+		{Name: "cloud_region", Value: "europe-west1"},
+		{Name: "host_id", Value: "4567"},
+		{Name: "host_type", Value: "e2-medium"},
+	})
+
+	// azure without availability zone
+	f([]string{"azure"}, []prompbmarshal.Label{
+		{Name: "cloud_account_id", Value: "sub-1"},
+		{Name: "cloud_platform", Value: "azure_vm"},
+		{Name: "cloud_provider", Value: "azure"},
+		{Name: "cloud_region", Value: "westeurope"},
+		{Name: "host_id", Value: "vm-1"},
+		{Name: "host_type", Value: "Standard_B2s"},
+	})
+
+	// only the first successful cloud detector is used
+	ec2MetadataURL = azureSrv.URL
+	f([]string{"ec2", "azure", "gce"}, []prompbmarshal.Label{
+		{Name: "cloud_account_id", Value: "my-project"},
+		{Name: "cloud_availability_zone", Value: "europe-west1-b"},
+		{Name: "cloud_platform", Value: "gcp_compute_engine"},
+		{Name: "cloud_provider", Value: "gcp"},
+		{Name: "cloud_region", Value: "europe-west1"},
+		{Name: "host_id", Value: "4567"},
+		{Name: "host_type", Value: "e2-medium"},
+	})
+
+	// all the detectors fail
+	ec2MetadataURL = gceSrv.URL
+	gceMetadataURL = azureSrv.URL
+	azureMetadataURL = ec2Srv.URL
+	t.Setenv("K8S_NODE_NAME", "")
+	f([]string{"ec2", "gce", "azure", "kubernetes"}, nil)
+}
+
+func TestDetectKubernetesResource(t *testing.T) {
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/namespaces/monitoring/pods/vmagent-0":
+			_, _ = w.Write([]byte(`{"spec":{"nodeName":"node-1"}}`))
+		case "/api/v1/nodes/node-1":
+			_, _ = w.Write([]byte(`{"metadata":{"labels":{"topology.kubernetes.io/region":"us-east-1","topology.kubernetes.io/zone":"us-east-1a",` +
+				`"node.kubernetes.io/instance-type":"t3.micro"}},"spec":{"providerID":"aws:///us-east-1a/i-1234567890abcdef0"}}`))
+		case "/api/v1/nodes/node-2":
+			_, _ = w.Write([]byte(`{"metadata":{"labels":{"failure-domain.beta.kubernetes.io/region":"europe-west1"}},"spec":{"providerID":"gce://my-project/europe-west1-b/node-2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiSrv.Close()
+
+	serviceAccountDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(serviceAccountDir, "token"), []byte("secret-token"), 0o600); err != nil {
+		t.Fatalf("cannot write token: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(serviceAccountDir, "namespace"), []byte("monitoring\n"), 0o600); err != nil {
+		t.Fatalf("cannot write namespace: %s", err)
+	}
+
+	kubernetesAPIServerOrig, kubernetesServiceAccountDirOrig := kubernetesAPIServer, kubernetesServiceAccountDir
+	defer func() {
+		kubernetesAPIServer, kubernetesServiceAccountDir = kubernetesAPIServerOrig, kubernetesServiceAccountDirOrig
+	}()
+	kubernetesAPIServer = apiSrv.URL
+	kubernetesServiceAccountDir = serviceAccountDir
+
+	f := func(nodeName, podName string, labelsExpected []prompbmarshal.Label) {
+		t.Helper()
+
+		t.Setenv("K8S_NODE_NAME", nodeName)
+		t.Setenv("POD_NAME", podName)
+		labels, err := detectKubernetesResource(http.DefaultClient)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(labels, labelsExpected) {
+			t.Fatalf("unexpected labels\ngot\n%s\nwant\n%s", formatLabels(labels), formatLabels(labelsExpected))
+		}
+	}
+
+	// node name is obtained from the pod
+	f("", "vmagent-0", []prompbmarshal.Label{
+		{Name: "k8s_node_name", Value: "node-1"},
+		{Name: "cloud_provider", Value: "aws"},
+		{Name: "cloud_region", Value: "us-east-1"},
+		{Name: "cloud_availability_zone", Value: "us-east-1a"},
+		{Name: "host_id", Value: "i-1234567890abcdef0"},
+		{Name: "host_type", Value: "t3.micro"},
+	})
+
+	// node name is obtained from K8S_NODE_NAME; deprecated node labels are supported
+	f("node-2", "", []prompbmarshal.Label{
+		{Name: "k8s_node_name", Value: "node-2"},
+		{Name: "cloud_provider", Value: "gcp"},
+		{Name: "cloud_region", Value: "europe-west1"},
+		{Name: "host_id", Value: "node-2"},
+	})
+
+	// missing access to node
+	f("node-3", "", []prompbmarshal.Label{
+		{Name: "k8s_node_name", Value: "node-3"},
+	})
+
+	// missing pod
+	t.Setenv("K8S_NODE_NAME", "")
+	t.Setenv("POD_NAME", "missing-pod")
+	if _, err := detectKubernetesResource(http.DefaultClient); err == nil {
+		t.Fatalf("expecting non-nil error for missing pod")
+	}
+
+	// cloud metadata server labels take precedence over Kubernetes node labels
+	ec2Labels := []prompbmarshal.Label{{Name: "cloud_region", Value: "eu-west-1"}}
+	allResourceDetectorsOrig := allResourceDetectors
+	defer func() {
+		allResourceDetectors = allResourceDetectorsOrig
+	}()
+	allResourceDetectors = map[string]resourceDetector{
+		"ec2": func(_ *http.Client) ([]prompbmarshal.Label, error) {
+			return ec2Labels, nil
+		},
+		"kubernetes": detectKubernetesResource,
+	}
+	t.Setenv("K8S_NODE_NAME", "node-1")
+	labels := detectResourceLabels(http.DefaultClient, []string{"kubernetes", "ec2"})
+	labelsExpected := []prompbmarshal.Label{
+		{Name: "cloud_availability_zone", Value: "us-east-1a"},
+		{Name: "cloud_provider", Value: "aws"},
+		{Name: "cloud_region", Value: "eu-west-1"},
+		{Name: "host_id", Value: "i-1234567890abcdef0"},
+		{Name: "host_type", Value: "t3.micro"},
+		{Name: "k8s_node_name", Value: "node-1"},
+	}
+	if !reflect.DeepEqual(labels, labelsExpected) {
+		t.Fatalf("unexpected labels\ngot\n%s\nwant\n%s", formatLabels(labels), formatLabels(labelsExpected))
+	}
+}
+
+func TestParseKubernetesProviderID(t *testing.T) {
+	f := func(providerID, cloudProviderExpected, hostIDExpected string) {
+		t.Helper()
+
+		cloudProvider, hostID := parseKubernetesProviderID(providerID)
+		if cloudProvider != cloudProviderExpected || hostID != hostIDExpected {
+			t.Fatalf("unexpected result for %q; got %q, %q; want %q, %q", providerID, cloudProvider, hostID, cloudProviderExpected, hostIDExpected)
+		}
+	}
+
+	f("", "", "")
+	f("foo", "", "")
+	f("aws:///us-east-1a/i-1234567890abcdef0", "aws", "i-1234567890abcdef0")
+	f("gce://my-project/europe-west1-b/node-2", "gcp", "node-2")
+	f("azure:///subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm-1", "azure", "vm-1")
+	f("kind://docker/kind/kind-control-plane", "", "kind-control-plane")
+}
+
+func TestGetResourceLabels(t *testing.T) {
+	resourceLabelsOrig, resourceDetectionOrig := resourceLabels, *resourceDetection
+	defer func() {
+		resourceLabels, *resourceDetection = resourceLabelsOrig, resourceDetectionOrig
+	}()
+
+	resourceLabels = []prompbmarshal.Label{{Name: "cloud_region", Value: "us-east-1"}}
+	*resourceDetection = []bool{false, true}
+
+	f := func(argIdx int, labelsExpected []prompbmarshal.Label) {
+		t.Helper()
+
+		labels := getResourceLabels(argIdx)
+		if !reflect.DeepEqual(labels, labelsExpected) {
+			t.Fatalf("unexpected labels for argIdx=%d\ngot\n%s\nwant\n%s", argIdx, formatLabels(labels), formatLabels(labelsExpected))
+		}
+	}
+
+	f(0, nil)
+	f(1, resourceLabels)
+
+	// missing -remoteWrite.resourceDetection for the given -remoteWrite.url
+	f(2, nil)
+}
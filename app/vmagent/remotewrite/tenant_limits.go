@@ -0,0 +1,409 @@
+package remotewrite
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bloomfilter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/tenantmetrics"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var tenantLimitsFile = flag.String("remoteWrite.tenantLimitsFile", "", "Optional path to file with per-tenant limits on the ingestion rate and on the number of unique series "+
+	"for data pushed via multitenant insert handlers. The file is re-read on SIGHUP signal. "+
+	"The path can point either to local file or to http url. See https://docs.victoriametrics.com/vmagent/#per-tenant-limits")
+
+// TenantLimits contains limits for a single tenant.
+type TenantLimits struct {
+	// MaxSamplesPerSecond is the maximum number of samples the tenant can push per second.
+	MaxSamplesPerSecond int `yaml:"max_samples_per_second,omitempty"`
+
+	// MaxHourlySeries is the maximum number of unique series the tenant can push during the last hour.
+	MaxHourlySeries int `yaml:"max_hourly_series,omitempty"`
+}
+
+// TenantLimitsConfig represents the contents of -remoteWrite.tenantLimitsFile.
+type TenantLimitsConfig struct {
+	// Default contains limits for tenants missing in Tenants.
+	Default *TenantLimits `yaml:"default,omitempty"`
+
+	// Tenants contains limits per tenant in the form `accountID` or `accountID:projectID`.
+	Tenants map[string]*TenantLimits `yaml:"tenants,omitempty"`
+}
+
+// tenantLimiter enforces TenantLimits for a single tenant.
+type tenantLimiter struct {
+	limits TenantLimits
+
+	// isDefault is set to true if the limiter is created on demand for the tenant with default limits.
+	//
+	// Such limiters are removed after tenantLimiterIdleTimeout of inactivity.
+	isDefault bool
+
+	// lastAccessTime is the last unix timestamp in seconds when the limiter was accessed.
+	lastAccessTime atomic.Uint64
+
+	// mu protects samplesBudget and samplesBudgetTime
+	mu sync.Mutex
+
+	// samplesBudget is the number of samples the tenant can push at samplesBudgetTime.
+	//
+	// It is refilled at limits.MaxSamplesPerSecond rate up to limits.MaxSamplesPerSecond, so the tenant can push bursts of samples
+	// up to the per-second limit. It becomes negative after accepting more samples than the budget contains,
+	// so requests with more samples than the per-second limit are accepted and are paid by the subsequent requests.
+	samplesBudget float64
+
+	// samplesBudgetTime is the last time samplesBudget was updated.
+	samplesBudgetTime time.Time
+
+	// hourlySeriesLimiter is nil if limits.MaxHourlySeries isn't set.
+	hourlySeriesLimiter *bloomfilter.Limiter
+}
+
+func newTenantLimiter(limits *TenantLimits, isDefault bool) *tenantLimiter {
+	tl := &tenantLimiter{
+		limits:            *limits,
+		isDefault:         isDefault,
+		samplesBudget:     float64(limits.MaxSamplesPerSecond),
+		samplesBudgetTime: time.Now(),
+	}
+	tl.lastAccessTime.Store(fasttime.UnixTimestamp())
+	if limits.MaxHourlySeries > 0 {
+		tl.hourlySeriesLimiter = bloomfilter.NewLimiter(limits.MaxHourlySeries, time.Hour)
+	}
+	return tl
+}
+
+func (tl *tenantLimiter) mustStop() {
+	if tl.hourlySeriesLimiter != nil {
+		tl.hourlySeriesLimiter.MustStop()
+	}
+}
+
+// tryAddSamples returns false if the tenant exceeds the samples rate limit at the given time.
+//
+// The returned duration contains the time to wait before the tenant can push samples again if false is returned.
+func (tl *tenantLimiter) tryAddSamples(n int, now time.Time) (bool, time.Duration) {
+	limit := float64(tl.limits.MaxSamplesPerSecond)
+	if limit <= 0 {
+		return true, 0
+	}
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	if d := now.Sub(tl.samplesBudgetTime); d > 0 {
+		tl.samplesBudget = min(tl.samplesBudget+d.Seconds()*limit, limit)
+		tl.samplesBudgetTime = now
+	}
+	if tl.samplesBudget <= 0 {
+		retryAfter := time.Duration((1 - tl.samplesBudget) / limit * float64(time.Second))
+		return false, retryAfter
+	}
+	tl.samplesBudget -= float64(n)
+	return true, 0
+}
+
+// tryAddSeries returns false if the tenant exceeds the limit on the number of unique hourly series after adding tss.
+//
+// Series from tss aren't registered in the limiter if false is returned, so rejected requests do not consume the limit.
+func (tl *tenantLimiter) tryAddSeries(tss []prompbmarshal.TimeSeries) bool {
+	sl := tl.hourlySeriesLimiter
+	if sl == nil {
+		return true
+	}
+	hs := make([]uint64, len(tss))
+	for i := range tss {
+		hs[i] = getLabelsHash(tss[i].Labels)
+	}
+	slices.Sort(hs)
+	hs = slices.Compact(hs)
+	return sl.AddAll(hs)
+}
+
+// tenantLimiters contains the state for tenant limits loaded from -remoteWrite.tenantLimitsFile.
+type tenantLimiters struct {
+	// defaultLimits is applied to tenants missing in limitersByTenant.
+	defaultLimits *TenantLimits
+
+	// mu protects limitersByTenant, next and stopped.
+	mu sync.Mutex
+
+	// limitersByTenant contains limiters for tenants.
+	//
+	// Limiters for tenants with defaultLimits are created on demand and are removed after tenantLimiterIdleTimeout of inactivity.
+	limitersByTenant map[auth.Token]*tenantLimiter
+
+	// next is set when the limiters are replaced by newTenantLimiters on config reload.
+	//
+	// Requests for limiters are forwarded to next after that, so limiters aren't created in the replaced limitersByTenant.
+	next *tenantLimiters
+
+	// stopped is set to true after mustStop call.
+	stopped bool
+
+	// lastCleanupTime is the last unix timestamp in seconds when idle limiters were removed from limitersByTenant.
+	lastCleanupTime uint64
+}
+
+// tenantLimiterIdleTimeout is the duration in seconds after which idle limiters for tenants with default limits are removed.
+//
+// It mustn't be smaller than the hourly series limiter window, so the removal doesn't reset the limits for active tenants.
+const tenantLimiterIdleTimeout = 3600
+
+func (tls *tenantLimiters) getLimiter(at *auth.Token) *tenantLimiter {
+	currentTime := fasttime.UnixTimestamp()
+
+	tls.mu.Lock()
+	if next := tls.next; next != nil {
+		tls.mu.Unlock()
+		return next.getLimiter(at)
+	}
+	defer tls.mu.Unlock()
+
+	if tls.stopped {
+		return nil
+	}
+	if currentTime-tls.lastCleanupTime >= 60 {
+		tls.removeIdleLimitersLocked(currentTime)
+		tls.lastCleanupTime = currentTime
+	}
+
+	tl := tls.limitersByTenant[*at]
+	if tl == nil {
+		if tls.defaultLimits == nil {
+			return nil
+		}
+		tl = newTenantLimiter(tls.defaultLimits, true)
+		tls.limitersByTenant[*at] = tl
+	}
+	tl.lastAccessTime.Store(currentTime)
+	return tl
+}
+
+// removeIdleLimitersLocked removes limiters for tenants with default limits, which weren't accessed during tenantLimiterIdleTimeout.
+//
+// tls.mu must be locked when calling this function.
+func (tls *tenantLimiters) removeIdleLimitersLocked(currentTime uint64) {
+	for at, tl := range tls.limitersByTenant {
+		if !tl.isDefault || tl.lastAccessTime.Load()+tenantLimiterIdleTimeout > currentTime {
+			continue
+		}
+		delete(tls.limitersByTenant, at)
+		tl.mustStop()
+	}
+}
+
+func (tls *tenantLimiters) mustStop() {
+	tls.mu.Lock()
+	// limitersByTenant is nil if the limiters have been moved to tls.next by newTenantLimiters.
+	limiters := tls.limitersByTenant
+	tls.limitersByTenant = nil
+	tls.stopped = true
+	tls.mu.Unlock()
+
+	for _, tl := range limiters {
+		tl.mustStop()
+	}
+}
+
+var tenantLimitersGlobal atomic.Pointer[tenantLimiters]
+
+// CheckTenantLimitsConfig checks -remoteWrite.tenantLimitsFile.
+func CheckTenantLimitsConfig() error {
+	_, err := loadTenantLimitsConfig()
+	return err
+}
+
+func loadTenantLimitsConfig() (*TenantLimitsConfig, error) {
+	if *tenantLimitsFile == "" {
+		return nil, nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*tenantLimitsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -remoteWrite.tenantLimitsFile=%q: %w", *tenantLimitsFile, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at -remoteWrite.tenantLimitsFile=%q: %w", *tenantLimitsFile, err)
+	}
+	cfg, err := parseTenantLimitsConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -remoteWrite.tenantLimitsFile=%q: %w", *tenantLimitsFile, err)
+	}
+	return cfg, nil
+}
+
+func parseTenantLimitsConfig(data []byte) (*TenantLimitsConfig, error) {
+	var cfg TenantLimitsConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	for tenant, limits := range cfg.Tenants {
+		if _, err := auth.NewToken(tenant); err != nil {
+			return nil, fmt.Errorf("invalid tenant %q: %w", tenant, err)
+		}
+		if limits == nil {
+			return nil, fmt.Errorf("missing limits for tenant %q", tenant)
+		}
+	}
+	return &cfg, nil
+}
+
+// newTenantLimiters creates limiters for the given cfg.
+//
+// Limiters from prev are re-used for tenants with unchanged limits, so their state is preserved across config reloads.
+// The remaining limiters from prev are stopped, while requests for prev limiters are forwarded to the returned limiters.
+// prev may be nil.
+func newTenantLimiters(cfg *TenantLimitsConfig, prev *tenantLimiters) *tenantLimiters {
+	if prev != nil {
+		// Hold the lock during the whole replacement, so concurrent getLimiter calls
+		// do not create limiters in prev.limitersByTenant, which would be lost.
+		prev.mu.Lock()
+		defer prev.mu.Unlock()
+	}
+	var prevLimiters map[auth.Token]*tenantLimiter
+	if prev != nil && !prev.stopped {
+		prevLimiters = prev.limitersByTenant
+	}
+	reuseLimiter := func(at *auth.Token, limits *TenantLimits, isDefault bool) *tenantLimiter {
+		tl := prevLimiters[*at]
+		if tl == nil || tl.limits != *limits || tl.isDefault != isDefault {
+			return nil
+		}
+		delete(prevLimiters, *at)
+		return tl
+	}
+
+	tls := &tenantLimiters{
+		defaultLimits:    cfg.Default,
+		limitersByTenant: make(map[auth.Token]*tenantLimiter, len(cfg.Tenants)),
+	}
+	for tenant, limits := range cfg.Tenants {
+		at, err := auth.NewToken(tenant)
+		if err != nil {
+			logger.Panicf("BUG: tenant %q must be already validated; got error: %s", tenant, err)
+		}
+		tl := reuseLimiter(at, limits, false)
+		if tl == nil {
+			tl = newTenantLimiter(limits, false)
+		}
+		tls.limitersByTenant[*at] = tl
+	}
+	if cfg.Default != nil {
+		for at, tl := range prevLimiters {
+			if !tl.isDefault {
+				continue
+			}
+			if _, ok := tls.limitersByTenant[at]; ok {
+				continue
+			}
+			if reuseLimiter(&at, cfg.Default, true) != nil {
+				tls.limitersByTenant[at] = tl
+			}
+		}
+	}
+
+	// Stop the remaining limiters, which cannot be re-used.
+	for _, tl := range prevLimiters {
+		tl.mustStop()
+	}
+	if prev != nil {
+		prev.limitersByTenant = nil
+		prev.next = tls
+	}
+	return tls
+}
+
+func initTenantLimits() {
+	cfg, err := loadTenantLimitsConfig()
+	if err != nil {
+		logger.Fatalf("cannot load tenant limits: %s", err)
+	}
+	if cfg != nil {
+		tenantLimitersGlobal.Store(newTenantLimiters(cfg, nil))
+	}
+}
+
+func reloadTenantLimits() {
+	if *tenantLimitsFile == "" {
+		return
+	}
+	logger.Infof("reloading tenant limits pointed by -remoteWrite.tenantLimitsFile")
+	cfg, err := loadTenantLimitsConfig()
+	if err != nil {
+		tenantLimitsReloadErrors.Inc()
+		logger.Errorf("cannot reload tenant limits; preserving the previous limits; error: %s", err)
+		return
+	}
+	tenantLimitersGlobal.Store(newTenantLimiters(cfg, tenantLimitersGlobal.Load()))
+	logger.Infof("successfully reloaded tenant limits")
+}
+
+func stopTenantLimits() {
+	if tls := tenantLimitersGlobal.Swap(nil); tls != nil {
+		tls.mustStop()
+	}
+}
+
+// CheckTenantLimits checks whether the given tenant at can push tss according to -remoteWrite.tenantLimitsFile.
+//
+// An error with http.StatusTooManyRequests status code is returned if the tenant exceeds the configured limits.
+// The limits are applied only to data pushed via multitenant insert handlers, e.g. when at isn't nil.
+func CheckTenantLimits(at *auth.Token, tss []prompbmarshal.TimeSeries) error {
+	if at == nil {
+		return nil
+	}
+	tls := tenantLimitersGlobal.Load()
+	if tls == nil {
+		return nil
+	}
+	tl := tls.getLimiter(at)
+	if tl == nil {
+		return nil
+	}
+	rowsCount := getRowsCount(tss)
+
+	// Check the series limit before counting samples, so samples rejected because of the series limit
+	// do not consume the samples rate limit.
+	if !tl.tryAddSeries(tss) {
+		tenantSeriesLimitExceeded.Get(at).Add(rowsCount)
+		return &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("tenant %s exceeds the limit on the number of unique series during the last hour: %d; retry the request later",
+				at, tl.limits.MaxHourlySeries),
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: time.Minute,
+		}
+	}
+	if ok, retryAfter := tl.tryAddSamples(rowsCount, time.Now()); !ok {
+		tenantRateLimitedRows.Get(at).Add(rowsCount)
+		return &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("tenant %s exceeds the limit on the number of samples per second: %d; retry the request in %.3f seconds",
+				at, tl.limits.MaxSamplesPerSecond, retryAfter.Seconds()),
+			StatusCode: http.StatusTooManyRequests,
+			RetryAfter: retryAfter,
+		}
+	}
+	return nil
+}
+
+var (
+	tenantLimitsReloadErrors = metrics.NewCounter(`vmagent_tenant_limits_reloads_errors_total`)
+
+	tenantRateLimitedRows     = tenantmetrics.NewCounterMap(`vmagent_tenant_rate_limited_rows_total`)
+	tenantSeriesLimitExceeded = tenantmetrics.NewCounterMap(`vmagent_tenant_series_limit_exceeded_total`)
+)
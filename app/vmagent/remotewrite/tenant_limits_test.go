@@ -0,0 +1,343 @@
+package remotewrite
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestParseTenantLimitsConfig_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := parseTenantLimitsConfig([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown field
+	f(`foo: bar`)
+	f(`
+default:
+  max_series: 10
+`)
+
+	// invalid tenant
+	f(`
+tenants:
+  "foo":
+    max_samples_per_second: 10
+`)
+	f(`
+tenants:
+  "1:2:3":
+    max_samples_per_second: 10
+`)
+
+	// missing limits
+	f(`
+tenants:
+  "1:2":
+`)
+}
+
+func TestParseTenantLimitsConfig_Success(t *testing.T) {
+	data := `
+default:
+  max_samples_per_second: 100
+tenants:
+  "1":
+    max_hourly_series: 10
+  "2:3":
+    max_samples_per_second: 20
+    max_hourly_series: 5
+`
+	cfg, err := parseTenantLimitsConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Default == nil || cfg.Default.MaxSamplesPerSecond != 100 {
+		t.Fatalf("unexpected default limits: %+v", cfg.Default)
+	}
+	tls := newTenantLimiters(cfg, nil)
+	defer tls.mustStop()
+
+	f := func(tenant string, limitsExpected TenantLimits) {
+		t.Helper()
+
+		at, err := auth.NewToken(tenant)
+		if err != nil {
+			t.Fatalf("cannot parse tenant: %s", err)
+		}
+		tl := tls.getLimiter(at)
+		if tl == nil {
+			t.Fatalf("missing limiter for tenant %q", tenant)
+		}
+		if tl.limits != limitsExpected {
+			t.Fatalf("unexpected limits for tenant %q; got %+v; want %+v", tenant, tl.limits, limitsExpected)
+		}
+	}
+
+	f("1", TenantLimits{MaxHourlySeries: 10})
+	f("1:0", TenantLimits{MaxHourlySeries: 10})
+	f("2:3", TenantLimits{MaxSamplesPerSecond: 20, MaxHourlySeries: 5})
+	f("4", TenantLimits{MaxSamplesPerSecond: 100})
+}
+
+func TestCheckTenantLimits(t *testing.T) {
+	cfg, err := parseTenantLimitsConfig([]byte(`
+tenants:
+  "1":
+    max_samples_per_second: 5
+  "2":
+    max_hourly_series: 2
+  "4":
+    max_samples_per_second: 10
+    max_hourly_series: 2
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tenantLimitersGlobal.Store(newTenantLimiters(cfg, nil))
+	defer stopTenantLimits()
+
+	newTimeseries := func(seriesCount, samplesPerSeries int) []prompbmarshal.TimeSeries {
+		tss := make([]prompbmarshal.TimeSeries, seriesCount)
+		for i := range tss {
+			tss[i].Labels = []prompbmarshal.Label{{Name: "__name__", Value: fmt.Sprintf("metric_%d", i)}}
+			tss[i].Samples = make([]prompbmarshal.Sample, samplesPerSeries)
+		}
+		return tss
+	}
+
+	f := func(tenant string, tss []prompbmarshal.TimeSeries, retryAfterExpected string) {
+		t.Helper()
+
+		var at *auth.Token
+		if tenant != "" {
+			at, err = auth.NewToken(tenant)
+			if err != nil {
+				t.Fatalf("cannot parse tenant: %s", err)
+			}
+		}
+		err := CheckTenantLimits(at, tss)
+		if retryAfterExpected == "" {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+		var esc *httpserver.ErrorWithStatusCode
+		if !errors.As(err, &esc) {
+			t.Fatalf("expecting ErrorWithStatusCode; got %v", err)
+		}
+		if esc.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("unexpected status code; got %d; want %d", esc.StatusCode, http.StatusTooManyRequests)
+		}
+		if retryAfter := esc.RetryAfter.Round(time.Second).String(); retryAfter != retryAfterExpected {
+			t.Fatalf("unexpected Retry-After; got %s; want %s", retryAfter, retryAfterExpected)
+		}
+	}
+
+	// data without tenant isn't limited
+	f("", newTimeseries(10, 10), "")
+
+	// tenant without limits
+	f("3", newTimeseries(10, 10), "")
+
+	// samples rate limit allows requests with more samples than the per-second limit,
+	// while the subsequent requests must wait until the limit is paid off
+	f("1", newTimeseries(2, 10), "")
+	f("1", newTimeseries(1, 1), "3s")
+
+	// hourly series limit
+	f("2", newTimeseries(2, 10), "")
+	f("2", newTimeseries(3, 1), "1m0s")
+
+	// samples rejected because of the series limit do not consume the samples rate limit
+	f("4", newTimeseries(3, 2), "1m0s")
+	f("4", newTimeseries(2, 5), "")
+}
+
+func TestTenantLimiterTryAddSamples(t *testing.T) {
+	tl := newTenantLimiter(&TenantLimits{MaxSamplesPerSecond: 10}, false)
+	defer tl.mustStop()
+
+	now := tl.samplesBudgetTime
+	f := func(n int, d time.Duration, okExpected bool, retryAfterExpected time.Duration) {
+		t.Helper()
+
+		now = now.Add(d)
+		ok, retryAfter := tl.tryAddSamples(n, now)
+		if ok != okExpected {
+			t.Fatalf("unexpected result; got %v; want %v", ok, okExpected)
+		}
+		if retryAfter != retryAfterExpected {
+			t.Fatalf("unexpected retryAfter; got %s; want %s", retryAfter, retryAfterExpected)
+		}
+	}
+
+	// burst up to the limit
+	f(6, 0, true, 0)
+	f(4, 0, true, 0)
+	f(1, 0, false, 100*time.Millisecond)
+
+	// the budget is refilled over time
+	f(5, 500*time.Millisecond, true, 0)
+	f(5, 0, false, 100*time.Millisecond)
+
+	// the budget isn't refilled above the limit
+	f(10, time.Hour, true, 0)
+	f(1, 0, false, 100*time.Millisecond)
+
+	// requests with more samples than the limit are accepted and must be paid off by the subsequent requests
+	f(25, time.Second, true, 0)
+	f(1, time.Second, false, 600*time.Millisecond)
+	f(1, 600*time.Millisecond, true, 0)
+}
+
+func TestTenantLimiterTryAddSeries(t *testing.T) {
+	tl := newTenantLimiter(&TenantLimits{MaxHourlySeries: 3}, false)
+	defer tl.mustStop()
+
+	f := func(names []string, okExpected bool) {
+		t.Helper()
+
+		var tss []prompbmarshal.TimeSeries
+		for _, name := range names {
+			tss = append(tss, prompbmarshal.TimeSeries{
+				Labels: []prompbmarshal.Label{{Name: "__name__", Value: name}},
+			})
+		}
+		if ok := tl.tryAddSeries(tss); ok != okExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", names, ok, okExpected)
+		}
+	}
+
+	// duplicate series are counted once
+	f([]string{"a", "b", "a"}, true)
+
+	// rejected series do not consume the limit
+	f([]string{"c", "d"}, false)
+	f([]string{"a", "c"}, true)
+	f([]string{"d"}, false)
+	f([]string{"a", "b", "c"}, true)
+}
+
+func TestReloadTenantLimitsPreservesLimiters(t *testing.T) {
+	cfg, err := parseTenantLimitsConfig([]byte(`
+default:
+  max_samples_per_second: 10
+tenants:
+  "1":
+    max_samples_per_second: 5
+  "2":
+    max_samples_per_second: 5
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tls := newTenantLimiters(cfg, nil)
+
+	getLimiter := func(tls *tenantLimiters, tenant string) *tenantLimiter {
+		t.Helper()
+		at, err := auth.NewToken(tenant)
+		if err != nil {
+			t.Fatalf("cannot parse tenant: %s", err)
+		}
+		return tls.getLimiter(at)
+	}
+	tl1 := getLimiter(tls, "1")
+	tl2 := getLimiter(tls, "2")
+	tl3 := getLimiter(tls, "3")
+
+	cfg, err = parseTenantLimitsConfig([]byte(`
+default:
+  max_samples_per_second: 10
+tenants:
+  "1":
+    max_samples_per_second: 5
+  "2":
+    max_samples_per_second: 20
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tlsNew := newTenantLimiters(cfg, tls)
+	defer tlsNew.mustStop()
+	tls.mustStop()
+
+	if getLimiter(tlsNew, "1") != tl1 {
+		t.Fatalf("expecting the limiter for tenant with unchanged limits to be preserved")
+	}
+	if getLimiter(tlsNew, "3") != tl3 {
+		t.Fatalf("expecting the limiter for tenant with unchanged default limits to be preserved")
+	}
+	if tl := getLimiter(tlsNew, "2"); tl == tl2 || tl.limits.MaxSamplesPerSecond != 20 {
+		t.Fatalf("expecting new limiter for tenant with changed limits")
+	}
+
+	// limiters requested via the replaced limiters must be created in the new limiters
+	if tl := getLimiter(tls, "4"); tl == nil || tl != getLimiter(tlsNew, "4") {
+		t.Fatalf("expecting the limiter requested via the replaced limiters to be created in the new limiters")
+	}
+}
+
+func TestTenantLimitersRemoveIdleLimiters(t *testing.T) {
+	cfg, err := parseTenantLimitsConfig([]byte(`
+default:
+  max_hourly_series: 10
+tenants:
+  "1":
+    max_hourly_series: 5
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tls := newTenantLimiters(cfg, nil)
+	defer tls.mustStop()
+
+	getLimiter := func(tenant string) *tenantLimiter {
+		t.Helper()
+		at, err := auth.NewToken(tenant)
+		if err != nil {
+			t.Fatalf("cannot parse tenant: %s", err)
+		}
+		return tls.getLimiter(at)
+	}
+
+	tl1 := getLimiter("1")
+	tl2 := getLimiter("2")
+	if len(tls.limitersByTenant) != 2 {
+		t.Fatalf("unexpected number of limiters; got %d; want 2", len(tls.limitersByTenant))
+	}
+
+	// Limiters accessed recently must be preserved.
+	currentTime := tl2.lastAccessTime.Load()
+	tls.mu.Lock()
+	tls.removeIdleLimitersLocked(currentTime + tenantLimiterIdleTimeout - 1)
+	tls.mu.Unlock()
+	if len(tls.limitersByTenant) != 2 {
+		t.Fatalf("unexpected number of limiters after removing recently accessed limiters; got %d; want 2", len(tls.limitersByTenant))
+	}
+
+	// Idle limiters for tenants with default limits must be removed, while limiters for explicitly configured tenants must be preserved.
+	tls.mu.Lock()
+	tls.removeIdleLimitersLocked(currentTime + tenantLimiterIdleTimeout)
+	tls.mu.Unlock()
+	if len(tls.limitersByTenant) != 1 {
+		t.Fatalf("unexpected number of limiters after removing idle limiters; got %d; want 1", len(tls.limitersByTenant))
+	}
+	if tl := getLimiter("1"); tl != tl1 {
+		t.Fatalf("the limiter for explicitly configured tenant mustn't be removed")
+	}
+	if tl := getLimiter("2"); tl == tl2 {
+		t.Fatalf("the idle limiter for tenant with default limits must be re-created")
+	}
+}
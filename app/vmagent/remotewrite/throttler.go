@@ -0,0 +1,147 @@
+package remotewrite
+
+import (
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
+)
+
+var adaptiveThrottling = flagutil.NewArrayBool("remoteWrite.adaptiveThrottling", "Whether to adapt sending data to the corresponding -remoteWrite.url "+
+	"when it responds with '429 Too Many Requests'. In this mode all the -remoteWrite.queues workers pause sending data until the delay from 'Retry-After' response header "+
+	"or until the shared retry backoff expires, the number of concurrent requests is halved on every 429 response and is gradually restored after successful requests, "+
+	"while pending in-memory data is flushed to the on-disk queue. See https://docs.victoriametrics.com/vmagent/#adaptive-throttling")
+
+// adaptiveThrottler limits the rate of requests to remote storage, which responds with '429 Too Many Requests'.
+//
+// All the workers of the client share the same throttler, so they pause sending data at once
+// instead of retrying every rejected request independently.
+type adaptiveThrottler struct {
+	maxConcurrency   int
+	retryMinInterval time.Duration
+	retryMaxTime     time.Duration
+
+	mu sync.Mutex
+
+	// concurrency is the current limit on the number of concurrent requests.
+	concurrency int
+
+	// active is the number of in-flight requests.
+	active int
+
+	// successes is the number of successful requests since the last change of concurrency.
+	successes int
+
+	// pauseUntil is the time until requests are paused.
+	pauseUntil time.Time
+
+	// backoff is the pause duration for the next 429 response without 'Retry-After' header.
+	backoff time.Duration
+
+	// wakeupCh is closed when waiting workers may proceed.
+	wakeupCh chan struct{}
+}
+
+func newAdaptiveThrottler(maxConcurrency int, retryMinInterval, retryMaxTime time.Duration) *adaptiveThrottler {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &adaptiveThrottler{
+		maxConcurrency:   maxConcurrency,
+		retryMinInterval: retryMinInterval,
+		retryMaxTime:     retryMaxTime,
+
+		concurrency: maxConcurrency,
+		backoff:     retryMinInterval,
+		wakeupCh:    make(chan struct{}),
+	}
+}
+
+// acquire waits until a request to remote storage is allowed.
+//
+// It returns false if stopCh is closed while waiting.
+// release must be called after the request is complete if acquire returns true.
+func (at *adaptiveThrottler) acquire(stopCh <-chan struct{}) bool {
+	for {
+		at.mu.Lock()
+		d := time.Until(at.pauseUntil)
+		if d <= 0 && at.active < at.concurrency {
+			at.active++
+			at.mu.Unlock()
+			return true
+		}
+		wakeupCh := at.wakeupCh
+		at.mu.Unlock()
+
+		if d <= 0 {
+			select {
+			case <-stopCh:
+				return false
+			case <-wakeupCh:
+			}
+			continue
+		}
+		t := timerpool.Get(d)
+		select {
+		case <-stopCh:
+			timerpool.Put(t)
+			return false
+		case <-t.C:
+			timerpool.Put(t)
+		}
+	}
+}
+
+// release must be called after the request acquired via acquire is complete.
+//
+// isThrottled must be set to true if remote storage responded with '429 Too Many Requests',
+// while retryAfter must contain the delay from 'Retry-After' response header.
+// isSuccess must be set to true if the request has been successfully accepted by remote storage.
+func (at *adaptiveThrottler) release(isThrottled, isSuccess bool, retryAfter time.Duration) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.active--
+	switch {
+	case isThrottled:
+		// Multiplicative decrease of concurrency.
+		at.concurrency = max(at.concurrency/2, 1)
+		at.successes = 0
+
+		pause := retryAfter
+		if pause <= 0 {
+			pause = at.backoff
+			at.backoff = min(at.backoff*2, at.retryMaxTime)
+		}
+		pauseUntil := time.Now().Add(timeutil.AddJitterToDuration(pause))
+		if pauseUntil.After(at.pauseUntil) {
+			at.pauseUntil = pauseUntil
+		}
+	case isSuccess:
+		at.backoff = at.retryMinInterval
+		at.successes++
+		if at.successes >= at.concurrency && at.concurrency < at.maxConcurrency {
+			// Additive increase of concurrency after the whole window of requests succeeds.
+			at.concurrency++
+			at.successes = 0
+		}
+	}
+	close(at.wakeupCh)
+	at.wakeupCh = make(chan struct{})
+}
+
+// getConcurrency returns the current limit on the number of concurrent requests.
+func (at *adaptiveThrottler) getConcurrency() int {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	return at.concurrency
+}
+
+// isPaused returns true if requests are paused because of '429 Too Many Requests' responses.
+func (at *adaptiveThrottler) isPaused() bool {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	return time.Now().Before(at.pauseUntil)
+}
@@ -0,0 +1,104 @@
+package remotewrite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveThrottlerConcurrency(t *testing.T) {
+	at := newAdaptiveThrottler(8, time.Millisecond, time.Millisecond)
+	stopCh := make(chan struct{})
+
+	f := func(isThrottled, isSuccess bool, concurrencyExpected int) {
+		t.Helper()
+
+		if !at.acquire(stopCh) {
+			t.Fatalf("acquire must return true")
+		}
+		at.release(isThrottled, isSuccess, 0)
+		if n := at.getConcurrency(); n != concurrencyExpected {
+			t.Fatalf("unexpected concurrency; got %d; want %d", n, concurrencyExpected)
+		}
+	}
+
+	// the concurrency is halved on every 429 response
+	f(true, false, 4)
+	f(true, false, 2)
+	f(true, false, 1)
+	f(true, false, 1)
+
+	// errors do not change the concurrency
+	f(false, false, 1)
+
+	// the concurrency is increased by one after the concurrency number of successful requests
+	f(false, true, 2)
+	f(false, true, 2)
+	f(false, true, 3)
+	f(false, true, 3)
+	f(false, true, 3)
+	f(false, true, 4)
+
+	// the concurrency cannot exceed the maximum
+	for i := 0; i < 100; i++ {
+		if !at.acquire(stopCh) {
+			t.Fatalf("acquire must return true")
+		}
+		at.release(false, true, 0)
+	}
+	f(false, true, 8)
+}
+
+func TestAdaptiveThrottlerPause(t *testing.T) {
+	at := newAdaptiveThrottler(2, time.Millisecond, time.Millisecond)
+	stopCh := make(chan struct{})
+
+	if !at.acquire(stopCh) {
+		t.Fatalf("acquire must return true")
+	}
+	if at.isPaused() {
+		t.Fatalf("the throttler mustn't be paused before 429 response")
+	}
+	at.release(true, false, 200*time.Millisecond)
+	if !at.isPaused() {
+		t.Fatalf("the throttler must be paused after 429 response")
+	}
+
+	// acquire must wait until the end of the pause from Retry-After header
+	startTime := time.Now()
+	if !at.acquire(stopCh) {
+		t.Fatalf("acquire must return true")
+	}
+	if d := time.Since(startTime); d < 200*time.Millisecond {
+		t.Fatalf("acquire must wait for the pause; waited for %s", d)
+	}
+
+	// acquire must wait for release if the concurrency limit is reached
+	doneCh := make(chan struct{})
+	go func() {
+		if at.acquire(stopCh) {
+			at.release(false, true, 0)
+		}
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+		t.Fatalf("acquire must wait for release")
+	case <-time.After(50 * time.Millisecond):
+	}
+	at.release(false, true, 0)
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("acquire must proceed after release")
+	}
+
+	// acquire must return false after stopCh is closed
+	if !at.acquire(stopCh) {
+		t.Fatalf("acquire must return true")
+	}
+	at.release(true, false, time.Hour)
+	close(stopCh)
+	if at.acquire(stopCh) {
+		t.Fatalf("acquire must return false after stopCh is closed")
+	}
+}
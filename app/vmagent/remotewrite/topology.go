@@ -0,0 +1,302 @@
+package remotewrite
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+var (
+	shardByURLTopologyURL = flag.String("remoteWrite.shardByURL.topologyURL", "", "Optional URL of the topology hint API such as http://vminsert:8480/api/v1/status/topology . "+
+		"If set, then series are sharded among -remoteWrite.url systems listed at the topology API via jump consistent hash in the advertised order, "+
+		"so the same series is always routed to the same vminsert node. -remoteWrite.url entries are matched to topology nodes by host:port. "+
+		"The sharding falls back to the default scheme if the topology cannot be obtained. "+
+		"See https://docs.victoriametrics.com/vmagent/#sharding-among-remote-storages")
+	shardByURLTopologyCheckInterval = flag.Duration("remoteWrite.shardByURL.topologyCheckInterval", time.Minute, "Interval for re-reading the topology "+
+		"from -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyMaxResponseSize = flagutil.NewBytes("remoteWrite.shardByURL.topologyMaxResponseSize", 1024*1024, "The maximum size of the response "+
+		"from -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyHeaders = flag.String("remoteWrite.shardByURL.topologyHeaders", "", "Optional HTTP headers to send with each request to -remoteWrite.shardByURL.topologyURL. "+
+		"Multiple headers must be delimited by '^^': -remoteWrite.shardByURL.topologyHeaders='header1:value1^^header2:value2'")
+	shardByURLTopologyBasicAuthUsername = flag.String("remoteWrite.shardByURL.topologyBasicAuth.username", "", "Optional basic auth username to use for -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyBasicAuthPassword = flagutil.NewPassword("remoteWrite.shardByURL.topologyBasicAuth.password", "Optional basic auth password to use for -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyBearerToken       = flagutil.NewPassword("remoteWrite.shardByURL.topologyBearerToken", "Optional bearer auth token to use for -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyProxyURL          = flag.String("remoteWrite.shardByURL.topologyProxyURL", "", "Optional proxy URL for reading -remoteWrite.shardByURL.topologyURL. "+
+		"Supported proxies: http, https, socks5. Example: -remoteWrite.shardByURL.topologyProxyURL=socks5://proxy:1234")
+
+	shardByURLTopologyTLSInsecureSkipVerify = flag.Bool("remoteWrite.shardByURL.topologyTLSInsecureSkipVerify", false, "Whether to skip tls verification when connecting to -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyTLSCertFile           = flag.String("remoteWrite.shardByURL.topologyTLSCertFile", "", "Optional path to client-side TLS certificate file to use when connecting "+
+		"to -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyTLSKeyFile = flag.String("remoteWrite.shardByURL.topologyTLSKeyFile", "", "Optional path to client-side TLS certificate key to use when connecting "+
+		"to -remoteWrite.shardByURL.topologyURL")
+	shardByURLTopologyTLSCAFile = flag.String("remoteWrite.shardByURL.topologyTLSCAFile", "", "Optional path to TLS CA file to use for verifying connections "+
+		"to -remoteWrite.shardByURL.topologyURL. By default, system CA is used")
+	shardByURLTopologyTLSServerName = flag.String("remoteWrite.shardByURL.topologyTLSServerName", "", "Optional TLS server name to use for connections "+
+		"to -remoteWrite.shardByURL.topologyURL. By default, the server name from -remoteWrite.shardByURL.topologyURL is used")
+)
+
+var (
+	topologyGlobal atomic.Pointer[shardingTopology]
+
+	topologyReloads          = metrics.NewCounter(`vmagent_remotewrite_topology_reloads_total`)
+	topologyReloadErrors     = metrics.NewCounter(`vmagent_remotewrite_topology_reloads_errors_total`)
+	topologyLastReloadStatus = metrics.NewGauge(`vmagent_remotewrite_topology_last_reload_successful`, nil)
+	topologyLastReloadTime   = metrics.NewGauge(`vmagent_remotewrite_topology_last_reload_success_timestamp_seconds`, nil)
+
+	_ = metrics.NewGauge(`vmagent_remotewrite_topology_nodes`, func() float64 {
+		st := topologyGlobal.Load()
+		if st == nil {
+			return 0
+		}
+		return float64(len(st.rwctxIdxs))
+	})
+
+	topologyStopCh chan struct{}
+	topologyWG     sync.WaitGroup
+
+	topologyClient     *http.Client
+	topologyAuthConfig *promauth.Config
+)
+
+// shardingTopology contains -remoteWrite.url indexes in the order advertised by the topology API.
+type shardingTopology struct {
+	rwctxIdxs []int
+}
+
+// topologyResponse is the response of the topology API exposed by vminsert at /api/v1/status/topology.
+type topologyResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		HashAlgorithm string   `json:"hashAlgorithm"`
+		Nodes         []string `json:"nodes"`
+	} `json:"data"`
+}
+
+func initTopology() {
+	if *shardByURLTopologyURL == "" {
+		return
+	}
+	if !*shardByURL {
+		logger.Fatalf("-remoteWrite.shardByURL.topologyURL requires -remoteWrite.shardByURL command-line flag")
+	}
+	if *shardByURLTopologyCheckInterval <= 0 {
+		logger.Fatalf("-remoteWrite.shardByURL.topologyCheckInterval must be positive; got %s", *shardByURLTopologyCheckInterval)
+	}
+	hc, ac, err := newTopologyClient()
+	if err != nil {
+		logger.Fatalf("cannot initialize client for -remoteWrite.shardByURL.topologyURL=%q: %s", *shardByURLTopologyURL, err)
+	}
+	topologyClient = hc
+	topologyAuthConfig = ac
+	reloadTopology()
+
+	topologyStopCh = make(chan struct{})
+	topologyWG.Add(1)
+	go func() {
+		defer topologyWG.Done()
+		t := time.NewTicker(*shardByURLTopologyCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-topologyStopCh:
+				return
+			case <-t.C:
+				reloadTopology()
+			}
+		}
+	}()
+}
+
+func stopTopology() {
+	if topologyStopCh == nil {
+		return
+	}
+	close(topologyStopCh)
+	topologyWG.Wait()
+	topologyStopCh = nil
+	topologyGlobal.Store(nil)
+}
+
+func reloadTopology() {
+	topologyReloads.Inc()
+	st, err := readTopology(topologyClient, topologyAuthConfig, *shardByURLTopologyURL, *remoteWriteURLs)
+	if err != nil {
+		topologyReloadErrors.Inc()
+		topologyLastReloadStatus.Set(0)
+		logger.Errorf("cannot read topology from -remoteWrite.shardByURL.topologyURL=%q: %s; keeping the previous topology", *shardByURLTopologyURL, err)
+		return
+	}
+	topologyLastReloadStatus.Set(1)
+	topologyLastReloadTime.Set(float64(fasttime.UnixTimestamp()))
+	topologyGlobal.Store(st)
+}
+
+// newTopologyClient returns http client and auth config for reading -remoteWrite.shardByURL.topologyURL.
+func newTopologyClient() (*http.Client, *promauth.Config, error) {
+	opts := &promauth.Options{
+		BearerToken: shardByURLTopologyBearerToken.Get(),
+		TLSConfig: &promauth.TLSConfig{
+			CAFile:             *shardByURLTopologyTLSCAFile,
+			CertFile:           *shardByURLTopologyTLSCertFile,
+			KeyFile:            *shardByURLTopologyTLSKeyFile,
+			ServerName:         *shardByURLTopologyTLSServerName,
+			InsecureSkipVerify: *shardByURLTopologyTLSInsecureSkipVerify,
+		},
+	}
+	if *shardByURLTopologyBasicAuthUsername != "" || shardByURLTopologyBasicAuthPassword.Get() != "" {
+		opts.BasicAuth = &promauth.BasicAuthConfig{
+			Username: *shardByURLTopologyBasicAuthUsername,
+			Password: promauth.NewSecret(shardByURLTopologyBasicAuthPassword.Get()),
+		}
+	}
+	if *shardByURLTopologyHeaders != "" {
+		opts.Headers = strings.Split(*shardByURLTopologyHeaders, "^^")
+	}
+	ac, err := opts.NewConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot initialize auth config: %w", err)
+	}
+
+	tr := httputil.NewTransport(false, "vmagent_remotewrite_topology")
+	if *shardByURLTopologyProxyURL != "" {
+		pu, err := url.Parse(*shardByURLTopologyProxyURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot parse -remoteWrite.shardByURL.topologyProxyURL=%q: %w", *shardByURLTopologyProxyURL, err)
+		}
+		tr.Proxy = http.ProxyURL(pu)
+	}
+	hc := &http.Client{
+		Transport: ac.NewRoundTripper(tr),
+		Timeout:   10 * time.Second,
+	}
+	return hc, ac, nil
+}
+
+func readTopology(hc *http.Client, ac *promauth.Config, topologyURL string, remoteWriteURLs []string) (*shardingTopology, error) {
+	req, err := http.NewRequest(http.MethodGet, topologyURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	if err := ac.SetHeaders(req, true); err != nil {
+		return nil, fmt.Errorf("cannot set request headers: %w", err)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := shardByURLTopologyMaxResponseSize.N
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("too big response; it mustn't exceed -remoteWrite.shardByURL.topologyMaxResponseSize=%d bytes", maxSize)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d; response body: %q", resp.StatusCode, data)
+	}
+	var tr topologyResponse
+	if err := json.Unmarshal(data, &tr); err != nil {
+		return nil, fmt.Errorf("cannot parse response %q: %w", data, err)
+	}
+	if tr.Status != "success" {
+		return nil, fmt.Errorf("unexpected status %q in response %q", tr.Status, data)
+	}
+	if tr.Data.HashAlgorithm != "jump" {
+		return nil, fmt.Errorf("unsupported hashAlgorithm %q; supported value: jump", tr.Data.HashAlgorithm)
+	}
+	return newShardingTopology(tr.Data.Nodes, remoteWriteURLs)
+}
+
+// newShardingTopology maps the given topology nodes to remoteWriteURLs indexes by host:port.
+func newShardingTopology(nodes, remoteWriteURLs []string) (*shardingTopology, error) {
+	urlIdxs := make(map[string]int, len(remoteWriteURLs))
+	for i, u := range remoteWriteURLs {
+		host, err := getTopologyHost(u)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse -remoteWrite.url #%d: %w", i+1, err)
+		}
+		urlIdxs[host] = i
+	}
+
+	rwctxIdxs := make([]int, 0, len(nodes))
+	seen := make(map[int]struct{}, len(nodes))
+	for _, node := range nodes {
+		host, err := getTopologyHost(node)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse topology node %q: %w", node, err)
+		}
+		idx, ok := urlIdxs[host]
+		if !ok {
+			return nil, fmt.Errorf("topology node %q doesn't match any -remoteWrite.url", node)
+		}
+		if _, ok := seen[idx]; ok {
+			return nil, fmt.Errorf("duplicate topology node %q", node)
+		}
+		seen[idx] = struct{}{}
+		rwctxIdxs = append(rwctxIdxs, idx)
+	}
+	if len(rwctxIdxs) == 0 {
+		return nil, fmt.Errorf("topology doesn't contain nodes")
+	}
+	return &shardingTopology{
+		rwctxIdxs: rwctxIdxs,
+	}, nil
+}
+
+func getTopologyHost(s string) (string, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	return strings.ToLower(u.Host), nil
+}
+
+// jumpHash returns the bucket in the range [0 ... numBuckets) for the given key.
+//
+// See https://arxiv.org/abs/1406.2294
+func jumpHash(key uint64, numBuckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// appendSeries appends ts with the given labels hash h to shards for replicas distinct topology nodes.
+//
+// The first node is selected via jump consistent hash, while the remaining replicas go to the next nodes in the topology order.
+func (st *shardingTopology) appendSeries(shards [][]prompbmarshal.TimeSeries, ts prompbmarshal.TimeSeries, h uint64, replicas int) {
+	nodes := st.rwctxIdxs
+	n := min(replicas, len(nodes))
+	bucket := jumpHash(h, len(nodes))
+	for i := 0; i < n; i++ {
+		idx := nodes[(bucket+i)%len(nodes)]
+		shards[idx] = append(shards[idx], ts)
+	}
+}
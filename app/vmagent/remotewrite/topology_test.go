@@ -0,0 +1,224 @@
+package remotewrite
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestJumpHash(t *testing.T) {
+	f := func(numBuckets int) {
+		t.Helper()
+
+		itemsCount := 1_000 * numBuckets
+		m := make([]int, numBuckets)
+		moved := 0
+		for i := 0; i < itemsCount; i++ {
+			h := xxhash.Sum64(uint64ToBytes(uint64(i)))
+			bucket := jumpHash(h, numBuckets)
+			if bucket < 0 || bucket >= numBuckets {
+				t.Fatalf("unexpected bucket for %d buckets: %d", numBuckets, bucket)
+			}
+			m[bucket]++
+
+			// Adding a bucket must move items only to the new bucket.
+			bucketNext := jumpHash(h, numBuckets+1)
+			if bucketNext != bucket {
+				if bucketNext != numBuckets {
+					t.Fatalf("item moved from bucket %d to bucket %d instead of the new bucket %d", bucket, bucketNext, numBuckets)
+				}
+				moved++
+			}
+		}
+
+		// Verify that the distribution is even
+		expectedItemsPerBucket := itemsCount / numBuckets
+		for _, n := range m {
+			if math.Abs(1-float64(n)/float64(expectedItemsPerBucket)) > 0.1 {
+				t.Fatalf("unexpected items in the bucket for %d buckets; got %d; want around %d", numBuckets, n, expectedItemsPerBucket)
+			}
+		}
+
+		// Verify that only 1/(numBuckets+1) share of items is moved to the new bucket.
+		expectedMoved := itemsCount / (numBuckets + 1)
+		if math.Abs(1-float64(moved)/float64(expectedMoved)) > 0.1 {
+			t.Fatalf("unexpected number of moved items when adding a bucket to %d buckets; got %d; want around %d", numBuckets, moved, expectedMoved)
+		}
+	}
+
+	f(1)
+	f(2)
+	f(3)
+	f(5)
+	f(10)
+}
+
+func uint64ToBytes(n uint64) []byte {
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = byte(n >> (8 * i))
+	}
+	return b
+}
+
+func TestNewShardingTopologySuccess(t *testing.T) {
+	f := func(nodes, remoteWriteURLs []string, rwctxIdxsExpected []int) {
+		t.Helper()
+
+		st, err := newShardingTopology(nodes, remoteWriteURLs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(st.rwctxIdxs, rwctxIdxsExpected) {
+			t.Fatalf("unexpected rwctxIdxs; got %d; want %d", st.rwctxIdxs, rwctxIdxsExpected)
+		}
+	}
+
+	urls := []string{
+		"http://vminsert-1:8480/insert/0/prometheus/api/v1/write",
+		"http://vminsert-2:8480/insert/0/prometheus/api/v1/write",
+		"https://VMINSERT-3:8480/insert/0/prometheus/api/v1/write",
+	}
+
+	// nodes in the same order as urls
+	f([]string{"vminsert-1:8480", "vminsert-2:8480", "vminsert-3:8480"}, urls, []int{0, 1, 2})
+
+	// nodes in another order
+	f([]string{"vminsert-3:8480", "vminsert-1:8480", "vminsert-2:8480"}, urls, []int{2, 0, 1})
+
+	// nodes with scheme
+	f([]string{"http://vminsert-2:8480", "https://vminsert-1:8480/"}, urls, []int{1, 0})
+}
+
+func TestNewShardingTopologyFailure(t *testing.T) {
+	f := func(nodes, remoteWriteURLs []string) {
+		t.Helper()
+
+		if _, err := newShardingTopology(nodes, remoteWriteURLs); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	urls := []string{
+		"http://vminsert-1:8480/insert/0/prometheus/api/v1/write",
+		"http://vminsert-2:8480/insert/0/prometheus/api/v1/write",
+	}
+
+	// empty topology
+	f(nil, urls)
+
+	// unknown node
+	f([]string{"vminsert-1:8480", "vminsert-3:8480"}, urls)
+
+	// duplicate node
+	f([]string{"vminsert-1:8480", "http://vminsert-1:8480"}, urls)
+}
+
+func TestReadTopology(t *testing.T) {
+	f := func(response string, isErrorExpected bool) {
+		t.Helper()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(response))
+		}))
+		defer srv.Close()
+
+		hc, ac, err := newTopologyClient()
+		if err != nil {
+			t.Fatalf("cannot create topology client: %s", err)
+		}
+		urls := []string{"http://vminsert-1:8480/", "http://vminsert-2:8480/"}
+		_, err = readTopology(hc, ac, srv.URL, urls)
+		if isErrorExpected && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !isErrorExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	f(`{"status":"success","data":{"hashAlgorithm":"jump","nodes":["vminsert-2:8480","vminsert-1:8480"]}}`, false)
+
+	// invalid json
+	f(`foobar`, true)
+
+	// unexpected status
+	f(`{"status":"error"}`, true)
+
+	// unsupported hash algorithm
+	f(`{"status":"success","data":{"hashAlgorithm":"modulo","nodes":["vminsert-1:8480"]}}`, true)
+
+	// too big response
+	f(`{"status":"success","data":{"hashAlgorithm":"jump","nodes":["vminsert-2:8480","vminsert-1:8480"]}}`+strings.Repeat(" ", 1024*1024), true)
+}
+
+func TestReadTopologyAuth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer foo" || r.Header.Get("X-Foo") != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":{"hashAlgorithm":"jump","nodes":["vminsert-1:8480"]}}`))
+	}))
+	defer srv.Close()
+
+	bearerTokenOrig := shardByURLTopologyBearerToken.Get()
+	headersOrig := *shardByURLTopologyHeaders
+	defer func() {
+		if err := shardByURLTopologyBearerToken.Set(bearerTokenOrig); err != nil {
+			t.Fatalf("cannot restore bearer token: %s", err)
+		}
+		*shardByURLTopologyHeaders = headersOrig
+	}()
+	if err := shardByURLTopologyBearerToken.Set("foo"); err != nil {
+		t.Fatalf("cannot set bearer token: %s", err)
+	}
+	*shardByURLTopologyHeaders = "X-Foo:bar"
+
+	hc, ac, err := newTopologyClient()
+	if err != nil {
+		t.Fatalf("cannot create topology client: %s", err)
+	}
+	st, err := readTopology(hc, ac, srv.URL, []string{"http://vminsert-1:8480/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(st.rwctxIdxs, []int{0}) {
+		t.Fatalf("unexpected rwctxIdxs; got %d; want [0]", st.rwctxIdxs)
+	}
+}
+
+func TestShardingTopologyAppendSeries(t *testing.T) {
+	st := &shardingTopology{
+		rwctxIdxs: []int{2, 0, 1},
+	}
+	h := uint64(12345)
+	bucket := jumpHash(h, len(st.rwctxIdxs))
+
+	shards := make([][]prompbmarshal.TimeSeries, 4)
+	st.appendSeries(shards, prompbmarshal.TimeSeries{}, h, 2)
+	for i, idx := range []int{st.rwctxIdxs[bucket], st.rwctxIdxs[(bucket+1)%3]} {
+		if len(shards[idx]) != 1 {
+			t.Fatalf("missing series in shard %d for replica #%d", idx, i)
+		}
+	}
+	if len(shards[3]) != 0 {
+		t.Fatalf("unexpected series in the shard, which isn't in the topology")
+	}
+
+	// The number of replicas cannot exceed the number of topology nodes.
+	shards = make([][]prompbmarshal.TimeSeries, 4)
+	st.appendSeries(shards, prompbmarshal.TimeSeries{}, h, 10)
+	for _, idx := range st.rwctxIdxs {
+		if len(shards[idx]) != 1 {
+			t.Fatalf("unexpected number of series in shard %d; got %d; want 1", idx, len(shards[idx]))
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package selfprofile
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	vmmetrics "github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	snapshotsDir = flag.String("selfProfile.dir", "", "Path to directory for storing profiling snapshots, which are captured automatically when vmagent is overloaded. "+
+		"Automatic self-profiling is disabled if empty. See https://docs.victoriametrics.com/vmagent/#automatic-self-profiling")
+	maxSnapshots = flag.Int("selfProfile.maxSnapshots", 10, "The maximum number of profiling snapshots to keep at -selfProfile.dir. "+
+		"The oldest snapshots are deleted when this limit is exceeded")
+	checkInterval = flag.Duration("selfProfile.checkInterval", 10*time.Second, "Interval for checking whether vmagent is overloaded. "+
+		"See -selfProfile.gcCPUThreshold and -selfProfile.queueUtilizationThreshold")
+	minInterval = flag.Duration("selfProfile.minInterval", 30*time.Minute, "The minimum interval between profiling snapshots. "+
+		"This prevents from additional overhead and from overwriting the first snapshot for the incident during prolonged overload")
+	cpuProfileDuration = flag.Duration("selfProfile.cpuProfileDuration", 10*time.Second, "The duration of CPU profile capturing for every profiling snapshot. "+
+		"CPU profile isn't captured if zero")
+	gcCPUThreshold = flag.Float64("selfProfile.gcCPUThreshold", 0.5, "Profiling snapshot is captured when the fraction of CPU time spent in Go garbage collector "+
+		"over -selfProfile.checkInterval exceeds this value. The check is disabled if zero")
+	queueUtilizationThreshold = flag.Float64("selfProfile.queueUtilizationThreshold", 0.9, "Profiling snapshot is captured when the in-memory queue for any -remoteWrite.url "+
+		"is filled by more than the given fraction of its capacity. The check is disabled if zero")
+	authKey = flagutil.NewPassword("selfProfile.authKey", "Auth key for /api/v1/status/self_profiles endpoints. It must be passed via authKey query arg. It overrides -httpAuth.*")
+)
+
+const snapshotFileSuffix = ".zip"
+
+var (
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+)
+
+// Init starts automatic self-profiling if -selfProfile.dir is set.
+//
+// getQueueUtilization must return the maximum utilization of remote write queues in the range [0..1].
+//
+// Stop must be called when self-profiling is no longer needed.
+func Init(getQueueUtilization func() float64) {
+	if *snapshotsDir == "" {
+		return
+	}
+	fs.MustMkdirIfNotExist(*snapshotsDir)
+	logger.Infof("automatic self-profiling is enabled; profiling snapshots are stored at -selfProfile.dir=%q", *snapshotsDir)
+
+	stopCh = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runProfiler(getQueueUtilization)
+	}()
+}
+
+// Stop stops automatic self-profiling.
+func Stop() {
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	wg.Wait()
+	stopCh = nil
+}
+
+func runProfiler(getQueueUtilization func() float64) {
+	ticker := time.NewTicker(*checkInterval)
+	defer ticker.Stop()
+
+	var gcs gcCPUSampler
+	gcs.sample()
+	var lastSnapshotTime time.Time
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+		gcCPUFraction := gcs.sample()
+		queueUtilization := getQueueUtilization()
+		reason := getOverloadReason(gcCPUFraction, queueUtilization)
+		if reason == "" || time.Since(lastSnapshotTime) < *minInterval {
+			continue
+		}
+		lastSnapshotTime = time.Now()
+		logger.Warnf("vmagent is overloaded (%s: gcCPUFraction=%.3f, queueUtilization=%.3f); capturing profiling snapshot at -selfProfile.dir=%q",
+			reason, gcCPUFraction, queueUtilization, *snapshotsDir)
+		si := &snapshotInfo{
+			Reason:           reason,
+			Timestamp:        lastSnapshotTime,
+			GCCPUFraction:    gcCPUFraction,
+			QueueUtilization: queueUtilization,
+		}
+		name, err := captureSnapshot(*snapshotsDir, si, *cpuProfileDuration, stopCh)
+		if err != nil {
+			snapshotErrors.Inc()
+			logger.Errorf("cannot capture profiling snapshot: %s", err)
+			continue
+		}
+		vmmetrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_self_profile_snapshots_total{reason=%q}`, reason)).Inc()
+		logger.Infof("stored profiling snapshot %q", name)
+		mustRemoveOldSnapshots(*snapshotsDir, *maxSnapshots)
+	}
+}
+
+var snapshotErrors = vmmetrics.NewCounter(`vmagent_self_profile_snapshot_errors_total`)
+
+// getOverloadReason returns the reason for capturing profiling snapshot for the given gcCPUFraction and queueUtilization.
+//
+// An empty string is returned if vmagent isn't overloaded.
+func getOverloadReason(gcCPUFraction, queueUtilization float64) string {
+	if *gcCPUThreshold > 0 && gcCPUFraction >= *gcCPUThreshold {
+		return "gc_cpu"
+	}
+	if *queueUtilizationThreshold > 0 && queueUtilization >= *queueUtilizationThreshold {
+		return "queue_utilization"
+	}
+	return ""
+}
+
+// gcCPUSampler calculates the fraction of CPU time spent in Go garbage collector between sample calls.
+type gcCPUSampler struct {
+	samples []metrics.Sample
+
+	prevGCCPUSeconds    float64
+	prevTotalCPUSeconds float64
+}
+
+// sample returns the fraction of CPU time spent in Go garbage collector since the previous call.
+func (gcs *gcCPUSampler) sample() float64 {
+	if gcs.samples == nil {
+		gcs.samples = []metrics.Sample{
+			{Name: "/cpu/classes/gc/total:cpu-seconds"},
+			{Name: "/cpu/classes/total:cpu-seconds"},
+		}
+	}
+	metrics.Read(gcs.samples)
+	gcCPUSeconds := getSampleFloat64(&gcs.samples[0])
+	totalCPUSeconds := getSampleFloat64(&gcs.samples[1])
+
+	deltaGC := gcCPUSeconds - gcs.prevGCCPUSeconds
+	deltaTotal := totalCPUSeconds - gcs.prevTotalCPUSeconds
+	gcs.prevGCCPUSeconds = gcCPUSeconds
+	gcs.prevTotalCPUSeconds = totalCPUSeconds
+	if deltaTotal <= 0 {
+		return 0
+	}
+	return deltaGC / deltaTotal
+}
+
+func getSampleFloat64(s *metrics.Sample) float64 {
+	if s.Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return s.Value.Float64()
+}
+
+// snapshotInfo contains information about the captured profiling snapshot.
+type snapshotInfo struct {
+	Reason           string    `json:"reason"`
+	Timestamp        time.Time `json:"timestamp"`
+	Version          string    `json:"version"`
+	GCCPUFraction    float64   `json:"gcCPUFraction"`
+	QueueUtilization float64   `json:"queueUtilization"`
+}
+
+// captureSnapshot captures profiling snapshot for si and stores it as zip archive at dir.
+//
+// CPU profile is captured during cpuDuration or until stopCh is closed. It is skipped if cpuDuration is zero.
+//
+// The name of the stored snapshot is returned.
+func captureSnapshot(dir string, si *snapshotInfo, cpuDuration time.Duration, stopCh <-chan struct{}) (string, error) {
+	si.Version = buildinfo.Version
+
+	var bb bytesutil.ByteBuffer
+	zw := zip.NewWriter(&bb)
+	writeFile := func(name string, f func(w io.Writer) error) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: si.Timestamp,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create %s in the snapshot: %w", name, err)
+		}
+		if err := f(w); err != nil {
+			return fmt.Errorf("cannot write %s to the snapshot: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := writeFile("info.json", func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(si)
+	}); err != nil {
+		return "", err
+	}
+	if cpuDuration > 0 {
+		// Capture CPU profile into a separate buffer, since zip writer doesn't allow writing multiple files concurrently.
+		var cpuProfile bytesutil.ByteBuffer
+		if err := pprof.StartCPUProfile(&cpuProfile); err != nil {
+			// CPU profile may be already captured via /debug/pprof/profile. Continue with other profiles.
+			logger.Warnf("skipping CPU profile in the profiling snapshot: %s", err)
+		} else {
+			t := time.NewTimer(cpuDuration)
+			select {
+			case <-t.C:
+			case <-stopCh:
+				t.Stop()
+			}
+			pprof.StopCPUProfile()
+			if err := writeFile("cpu.pprof", func(w io.Writer) error {
+				_, err := w.Write(cpuProfile.B)
+				return err
+			}); err != nil {
+				return "", err
+			}
+		}
+	}
+	for _, name := range []string{"heap", "goroutine", "allocs", "mutex", "block"} {
+		p := pprof.Lookup(name)
+		if p == nil {
+			continue
+		}
+		if err := writeFile(name+".pprof", func(w io.Writer) error {
+			return p.WriteTo(w, 0)
+		}); err != nil {
+			return "", err
+		}
+	}
+	// Store process and Go runtime metrics, since they may help interpreting the captured profiles.
+	if err := writeFile("metrics.txt", func(w io.Writer) error {
+		vmmetrics.WritePrometheus(w, true)
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("cannot finalize the snapshot: %w", err)
+	}
+
+	name := si.Timestamp.UTC().Format("20060102T150405Z") + "_" + si.Reason + snapshotFileSuffix
+	fs.MustWriteAtomic(filepath.Join(dir, name), bb.B, true)
+	return name, nil
+}
+
+// mustRemoveOldSnapshots removes the oldest snapshots at dir, so no more than maxSnapshots snapshots remain.
+func mustRemoveOldSnapshots(dir string, maxSnapshots int) {
+	names := mustListSnapshots(dir)
+	for len(names) > maxSnapshots {
+		path := filepath.Join(dir, names[0])
+		if err := os.Remove(path); err != nil {
+			logger.Panicf("FATAL: cannot remove old profiling snapshot: %s", err)
+		}
+		names = names[1:]
+	}
+}
+
+// mustListSnapshots returns names of snapshots stored at dir sorted from the oldest to the newest.
+func mustListSnapshots(dir string) []string {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read profiling snapshots dir: %s", err)
+	}
+	var names []string
+	for _, de := range des {
+		name := de.Name()
+		if !de.Type().IsRegular() || !strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		names = append(names, name)
+	}
+	// Snapshot names start with the capture timestamp, so lexicographical order matches the capture order.
+	slices.Sort(names)
+	return names
+}
+
+// RequestHandler handles requests to /api/v1/status/self_profiles endpoints.
+func RequestHandler(w http.ResponseWriter, r *http.Request, path string) {
+	if !httpserver.CheckAuthFlag(w, r, authKey) {
+		return
+	}
+	selfProfilesRequests.Inc()
+	if *snapshotsDir == "" {
+		httpserver.Errorf(w, r, "automatic self-profiling is disabled; set -selfProfile.dir command-line flag in order to enable it")
+		return
+	}
+	if strings.HasSuffix(path, "/download") {
+		serveSnapshot(w, r)
+		return
+	}
+	writeSnapshotsList(w)
+}
+
+var selfProfilesRequests = vmmetrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/status/self_profiles"}`)
+
+func writeSnapshotsList(w http.ResponseWriter) {
+	type snapshotEntry struct {
+		Name string `json:"name"`
+		Size uint64 `json:"size"`
+	}
+	names := mustListSnapshots(*snapshotsDir)
+	entries := make([]snapshotEntry, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		entries = append(entries, snapshotEntry{
+			Name: names[i],
+			Size: fs.MustFileSize(filepath.Join(*snapshotsDir, names[i])),
+		})
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal profiling snapshots list: %s", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":%s}`, data)
+}
+
+func serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if !slices.Contains(mustListSnapshots(*snapshotsDir), name) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "cannot find profiling snapshot %q", name)
+		return
+	}
+	f, err := os.Open(filepath.Join(*snapshotsDir, name))
+	if err != nil {
+		// The snapshot may be deleted after the listing above.
+		httpserver.Errorf(w, r, "cannot open profiling snapshot %q: %s", name, err)
+		return
+	}
+	defer fs.MustClose(f)
+	fi, err := f.Stat()
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot stat profiling snapshot %q: %s", name, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, fi.ModTime(), f)
+}
@@ -0,0 +1,147 @@
+package selfprofile
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetOverloadReason(t *testing.T) {
+	f := func(gcCPUFraction, queueUtilization float64, reasonExpected string) {
+		t.Helper()
+
+		reason := getOverloadReason(gcCPUFraction, queueUtilization)
+		if reason != reasonExpected {
+			t.Fatalf("unexpected reason; got %q; want %q", reason, reasonExpected)
+		}
+	}
+
+	f(0, 0, "")
+	f(0.1, 0.5, "")
+	f(0.6, 0.5, "gc_cpu")
+	f(0.1, 1, "queue_utilization")
+	f(0.6, 1, "gc_cpu")
+}
+
+func TestCaptureSnapshot(t *testing.T) {
+	f := func(cpuDuration time.Duration, filesExpected []string) {
+		t.Helper()
+
+		dir := t.TempDir()
+		si := &snapshotInfo{
+			Reason:    "gc_cpu",
+			Timestamp: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		}
+		name, err := captureSnapshot(dir, si, cpuDuration, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if nameExpected := "20240506T070809Z_gc_cpu.zip"; name != nameExpected {
+			t.Fatalf("unexpected snapshot name; got %q; want %q", name, nameExpected)
+		}
+		zr, err := zip.OpenReader(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("cannot open snapshot: %s", err)
+		}
+		defer zr.Close()
+		var files []string
+		for _, zf := range zr.File {
+			files = append(files, zf.Name)
+		}
+		if !reflect.DeepEqual(files, filesExpected) {
+			t.Fatalf("unexpected files in the snapshot\ngot\n%q\nwant\n%q", files, filesExpected)
+		}
+	}
+
+	// without CPU profile
+	f(0, []string{"info.json", "heap.pprof", "goroutine.pprof", "allocs.pprof", "mutex.pprof", "block.pprof", "metrics.txt"})
+
+	// with CPU profile
+	f(10*time.Millisecond, []string{"info.json", "cpu.pprof", "heap.pprof", "goroutine.pprof", "allocs.pprof", "mutex.pprof", "block.pprof", "metrics.txt"})
+}
+
+func TestMustRemoveOldSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("20240506T07080%dZ_gc_cpu.zip", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("foo"), 0600); err != nil {
+			t.Fatalf("cannot create snapshot: %s", err)
+		}
+	}
+	// Files without .zip suffix must be ignored
+	if err := os.WriteFile(filepath.Join(dir, "foo.txt"), []byte("foo"), 0600); err != nil {
+		t.Fatalf("cannot create file: %s", err)
+	}
+
+	mustRemoveOldSnapshots(dir, 2)
+	names := mustListSnapshots(dir)
+	namesExpected := []string{"20240506T070803Z_gc_cpu.zip", "20240506T070804Z_gc_cpu.zip"}
+	if !reflect.DeepEqual(names, namesExpected) {
+		t.Fatalf("unexpected snapshots\ngot\n%q\nwant\n%q", names, namesExpected)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "foo.txt")); err != nil {
+		t.Fatalf("unexpected error for the file without .zip suffix: %s", err)
+	}
+}
+
+func TestRequestHandler(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "20240506T070809Z_gc_cpu.zip"), []byte("foobar"), 0600); err != nil {
+		t.Fatalf("cannot create snapshot: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "foo.txt"), []byte("secret"), 0600); err != nil {
+		t.Fatalf("cannot create file: %s", err)
+	}
+
+	origDir := *snapshotsDir
+	*snapshotsDir = dir
+	defer func() {
+		*snapshotsDir = origDir
+	}()
+
+	f := func(path string, statusCodeExpected int, bodyExpected string) {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		RequestHandler(w, r, r.URL.Path)
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", w.Code, statusCodeExpected)
+		}
+		body := strings.TrimSpace(w.Body.String())
+		if body != bodyExpected {
+			t.Fatalf("unexpected response body\ngot\n%s\nwant\n%s", body, bodyExpected)
+		}
+	}
+
+	f("/api/v1/status/self_profiles", http.StatusOK, `{"status":"success","data":[{"name":"20240506T070809Z_gc_cpu.zip","size":6}]}`)
+	f("/api/v1/status/self_profiles/download?name=20240506T070809Z_gc_cpu.zip", http.StatusOK, "foobar")
+
+	// Only snapshots can be downloaded
+	f("/api/v1/status/self_profiles/download?name=foo.txt", http.StatusNotFound, `cannot find profiling snapshot "foo.txt"`)
+	f("/api/v1/status/self_profiles/download?name=../20240506T070809Z_gc_cpu.zip", http.StatusNotFound, `cannot find profiling snapshot "../20240506T070809Z_gc_cpu.zip"`)
+}
+
+func TestGCCPUSampler(t *testing.T) {
+	var gcs gcCPUSampler
+	gcs.sample()
+
+	// Generate some garbage
+	var bb bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		bb.Write(make([]byte, 64*1024))
+		bb.Reset()
+	}
+	n := gcs.sample()
+	if n < 0 || n > 1 {
+		t.Fatalf("unexpected GC CPU fraction: %v; it must be in the range [0..1]", n)
+	}
+}
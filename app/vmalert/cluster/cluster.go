@@ -0,0 +1,279 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	members = flagutil.NewArrayString("cluster.members", "Comma-separated list of URLs for all the vmalert instances in the cluster, including the current instance, "+
+		"e.g. http://vmalert-0:8880,http://vmalert-1:8880. If set, then rule groups are sharded among alive cluster members, "+
+		"so every group is evaluated by a single vmalert instance. See https://docs.victoriametrics.com/vmalert/#cluster-mode")
+	memberNum = flag.String("cluster.memberNum", "0", "The index of the current vmalert instance in -cluster.members list. "+
+		"It may also be a name ending with the index, e.g. vmalert-1, which is convenient for running vmalert as StatefulSet in Kubernetes. "+
+		"See https://docs.victoriametrics.com/vmalert/#cluster-mode")
+	healthCheckInterval = flag.Duration("cluster.healthCheckInterval", 5*time.Second, "Interval for checking the health of other -cluster.members. "+
+		"See https://docs.victoriametrics.com/vmalert/#cluster-mode")
+	memberDownTimeout = flag.Duration("cluster.memberDownTimeout", 15*time.Second, "The duration after the last successful health check, "+
+		"when the member of -cluster.members is considered down, so its rule groups are taken over by the remaining members. "+
+		"See https://docs.victoriametrics.com/vmalert/#cluster-mode")
+	tlsInsecureSkipVerify = flag.Bool("cluster.tlsInsecureSkipVerify", false, "Whether to skip TLS verification when checking the health of -cluster.members")
+)
+
+var (
+	ms     *membership
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+)
+
+// Init initializes cluster mode if -cluster.members is set.
+//
+// Stop must be called when cluster mode is no longer needed.
+func Init() error {
+	if len(*members) == 0 {
+		return nil
+	}
+	idx, err := parseMemberNum(*memberNum, len(*members))
+	if err != nil {
+		return err
+	}
+	if *healthCheckInterval <= 0 {
+		return fmt.Errorf("-cluster.healthCheckInterval must be positive; got %s", *healthCheckInterval)
+	}
+	if *memberDownTimeout < *healthCheckInterval {
+		return fmt.Errorf("-cluster.memberDownTimeout=%s cannot be smaller than -cluster.healthCheckInterval=%s", *memberDownTimeout, *healthCheckInterval)
+	}
+	ms = newMembership(*members, idx)
+	registerMetrics(ms)
+
+	stopCh = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ms.runHealthChecks(stopCh, *healthCheckInterval, *memberDownTimeout)
+	}()
+	logger.Infof("started cluster mode as member #%d (%s) of %d members", idx, ms.members[idx], len(ms.members))
+	return nil
+}
+
+// Stop stops cluster mode initialized via Init.
+func Stop() {
+	if ms == nil {
+		return
+	}
+	close(stopCh)
+	wg.Wait()
+}
+
+// IsEnabled returns true if cluster mode is enabled via -cluster.members.
+func IsEnabled() bool {
+	return ms != nil
+}
+
+// IsGroupOwner returns true if the current vmalert instance must evaluate the rule group with the given groupID.
+//
+// It always returns true if cluster mode is disabled.
+func IsGroupOwner(groupID uint64) bool {
+	if ms == nil {
+		return true
+	}
+	return ms.isOwner(groupID)
+}
+
+// parseMemberNum parses the index of the current instance from s.
+//
+// s may contain a name ending with the index, e.g. vmalert-1.
+func parseMemberNum(s string, membersCount int) (int, error) {
+	if idx := strings.LastIndexByte(s, '-'); idx >= 0 {
+		s = s[idx+1:]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse -cluster.memberNum=%q: %w", *memberNum, err)
+	}
+	if n < 0 || n >= membersCount {
+		return 0, fmt.Errorf("-cluster.memberNum must be in the range [0..%d] according to the number of -cluster.members; got %d", membersCount-1, n)
+	}
+	return n, nil
+}
+
+// membership tracks the health of cluster members.
+type membership struct {
+	// members contains URLs of all the cluster members.
+	members []string
+
+	// self is the index of the current instance in members.
+	self int
+
+	// lastSeen contains unix timestamps in nanoseconds for the last successful health check per each member.
+	lastSeen []atomic.Int64
+
+	// alive contains the current health state per each member.
+	alive []atomic.Bool
+}
+
+func newMembership(members []string, self int) *membership {
+	ms := &membership{
+		members:  make([]string, len(members)),
+		self:     self,
+		lastSeen: make([]atomic.Int64, len(members)),
+		alive:    make([]atomic.Bool, len(members)),
+	}
+	// Consider all the members alive at start, so groups aren't evaluated twice during rolling restarts.
+	now := time.Now().UnixNano()
+	for i, member := range members {
+		ms.members[i] = strings.TrimSuffix(member, "/")
+		ms.lastSeen[i].Store(now)
+		ms.alive[i].Store(true)
+	}
+	return ms
+}
+
+// isOwner returns true if the current instance must evaluate the group with the given groupID.
+//
+// Groups are assigned to alive members via rendezvous hashing,
+// so only groups owned by the failed member are moved to the remaining members.
+func (ms *membership) isOwner(groupID uint64) bool {
+	return ms.getOwner(groupID) == ms.self
+}
+
+func (ms *membership) getOwner(groupID uint64) int {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], groupID)
+
+	owner := -1
+	var maxScore uint64
+	for i, member := range ms.members {
+		if i != ms.self && !ms.alive[i].Load() {
+			continue
+		}
+		d := xxhash.New()
+		_, _ = d.WriteString(member)
+		_, _ = d.Write(buf[:])
+		score := d.Sum64()
+		if owner < 0 || score > maxScore {
+			owner = i
+			maxScore = score
+		}
+	}
+	return owner
+}
+
+// aliveMembers returns the number of alive members including the current instance.
+func (ms *membership) aliveMembers() int {
+	n := 0
+	for i := range ms.alive {
+		if i == ms.self || ms.alive[i].Load() {
+			n++
+		}
+	}
+	return n
+}
+
+func (ms *membership) runHealthChecks(stopCh <-chan struct{}, interval, downTimeout time.Duration) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if *tlsInsecureSkipVerify {
+		tr.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+	c := &http.Client{
+		Transport: tr,
+		Timeout:   interval,
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+		}
+		var wg sync.WaitGroup
+		for i := range ms.members {
+			if i == ms.self {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ms.checkMember(c, i, downTimeout)
+			}(i)
+		}
+		wg.Wait()
+	}
+}
+
+func (ms *membership) checkMember(c *http.Client, i int, downTimeout time.Duration) {
+	member := ms.members[i]
+	err := checkHealth(c, member)
+	if err == nil {
+		ms.lastSeen[i].Store(time.Now().UnixNano())
+		if !ms.alive[i].Swap(true) {
+			logger.Infof("cluster member %s is up; taking back its rule groups", member)
+		}
+		return
+	}
+	if time.Since(time.Unix(0, ms.lastSeen[i].Load())) < downTimeout {
+		return
+	}
+	if ms.alive[i].Swap(false) {
+		logger.Warnf("cluster member %s is down for more than -cluster.memberDownTimeout=%s; taking over its rule groups: %s", member, downTimeout, err)
+	}
+}
+
+func checkHealth(c *http.Client, member string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, member+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("cannot create health check request: %w", err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot perform health check: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code for health check: %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+	return nil
+}
+
+func registerMetrics(ms *membership) {
+	_ = metrics.NewGauge(`vmalert_cluster_members`, func() float64 {
+		return float64(len(ms.members))
+	})
+	_ = metrics.NewGauge(`vmalert_cluster_members_alive`, func() float64 {
+		return float64(ms.aliveMembers())
+	})
+	for i, member := range ms.members {
+		if i == ms.self {
+			continue
+		}
+		_ = metrics.NewGauge(fmt.Sprintf(`vmalert_cluster_member_up{member=%q}`, member), func() float64 {
+			if ms.alive[i].Load() {
+				return 1
+			}
+			return 0
+		})
+	}
+}
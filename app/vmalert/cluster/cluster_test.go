@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestParseMemberNumSuccess(t *testing.T) {
+	f := func(s string, membersCount, nExpected int) {
+		t.Helper()
+		n, err := parseMemberNum(s, membersCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != nExpected {
+			t.Fatalf("unexpected member num; got %d; want %d", n, nExpected)
+		}
+	}
+
+	f("0", 1, 0)
+	f("2", 3, 2)
+	f("vmalert-1", 2, 1)
+	f("vmalert-cluster-0", 2, 0)
+}
+
+func TestParseMemberNumFailure(t *testing.T) {
+	f := func(s string, membersCount int) {
+		t.Helper()
+		if _, err := parseMemberNum(s, membersCount); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("", 2)
+	f("foo", 2)
+	f("vmalert-", 2)
+	f("2", 2)
+	f("vmalert-3", 2)
+}
+
+func TestMembershipGetOwner(t *testing.T) {
+	members := []string{"http://vmalert-0:8880", "http://vmalert-1:8880/", "http://vmalert-2:8880"}
+	const groupsCount = 1000
+
+	newMemberships := func() []*membership {
+		mss := make([]*membership, len(members))
+		for i := range members {
+			mss[i] = newMembership(members, i)
+		}
+		return mss
+	}
+
+	// Every group must be owned by exactly one member
+	mss := newMemberships()
+	owners := make([]int, groupsCount)
+	ownedGroups := make([]int, len(members))
+	for groupID := range owners {
+		ownersCount := 0
+		for i, ms := range mss {
+			if ms.isOwner(uint64(groupID)) {
+				owners[groupID] = i
+				ownersCount++
+			}
+		}
+		if ownersCount != 1 {
+			t.Fatalf("unexpected number of owners for group %d; got %d; want 1", groupID, ownersCount)
+		}
+		ownedGroups[owners[groupID]]++
+	}
+	for i, n := range ownedGroups {
+		if n < groupsCount/len(members)/2 {
+			t.Fatalf("too small number of groups owned by member #%d: %d", i, n)
+		}
+	}
+
+	// Groups of the failed member must be taken over by the remaining members,
+	// while groups of the remaining members must stay at the same members.
+	const failedMember = 1
+	for _, ms := range mss {
+		ms.alive[failedMember].Store(false)
+	}
+	for groupID, prevOwner := range owners {
+		ownersCount := 0
+		owner := -1
+		for i, ms := range mss {
+			if i == failedMember {
+				continue
+			}
+			if ms.isOwner(uint64(groupID)) {
+				owner = i
+				ownersCount++
+			}
+		}
+		if ownersCount != 1 {
+			t.Fatalf("unexpected number of owners for group %d after member failure; got %d; want 1", groupID, ownersCount)
+		}
+		if prevOwner != failedMember && owner != prevOwner {
+			t.Fatalf("group %d moved from alive member #%d to member #%d", groupID, prevOwner, owner)
+		}
+	}
+
+	// The failed member must own all the groups if it considers other members down
+	ms := mss[failedMember]
+	for i := range members {
+		ms.alive[i].Store(i == failedMember)
+	}
+	for groupID := range owners {
+		if !ms.isOwner(uint64(groupID)) {
+			t.Fatalf("isolated member must own group %d", groupID)
+		}
+	}
+	if n := ms.aliveMembers(); n != 1 {
+		t.Fatalf("unexpected number of alive members; got %d; want 1", n)
+	}
+}
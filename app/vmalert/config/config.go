@@ -50,6 +50,18 @@ type Group struct {
 	NotifierHeaders []Header `yaml:"notifier_headers,omitempty"`
 	// EvalAlignment will make the timestamp of group query requests be aligned with interval
 	EvalAlignment *bool `yaml:"eval_alignment,omitempty"`
+	// DashboardURL is an optional template for the link to the dashboard, which is set for every alerting rule in the group.
+	// It can be overridden on the rule level.
+	DashboardURL string `yaml:"dashboard_url,omitempty"`
+	// MuteTimeIntervals contains names of time intervals, when notifications for the group alerts mustn't be sent.
+	// Time intervals are defined in the file specified via -rule.timeIntervals command-line flag.
+	MuteTimeIntervals []string `yaml:"mute_time_intervals,omitempty"`
+	// ActiveTimeIntervals contains names of time intervals, when notifications for the group alerts may be sent.
+	// Notifications aren't sent outside these time intervals if they are set.
+	ActiveTimeIntervals []string `yaml:"active_time_intervals,omitempty"`
+	// SuppressFirstEvalNotifications disables notifications for alerts, which are already active
+	// on the first evaluation of the rule after vmalert start or config reload.
+	SuppressFirstEvalNotifications *bool `yaml:"suppress_first_eval_notifications,omitempty"`
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]any `yaml:",inline"`
 }
@@ -97,6 +109,21 @@ func (g *Group) Validate(validateTplFn ValidateTplFn, validateExpressions bool)
 	if g.Concurrency < 0 {
 		return fmt.Errorf("invalid concurrency %d, shouldn't be less than 0", g.Concurrency)
 	}
+	if validateTplFn != nil {
+		if err := validateTplFn(map[string]string{"dashboard_url": g.DashboardURL}); err != nil {
+			return fmt.Errorf("invalid dashboard_url: %w", err)
+		}
+	}
+	for _, name := range g.MuteTimeIntervals {
+		if !hasTimeInterval(name) {
+			return fmt.Errorf("unknown time interval %q in mute_time_intervals; see -rule.timeIntervals command-line flag", name)
+		}
+	}
+	for _, name := range g.ActiveTimeIntervals {
+		if !hasTimeInterval(name) {
+			return fmt.Errorf("unknown time interval %q in active_time_intervals; see -rule.timeIntervals command-line flag", name)
+		}
+	}
 
 	uniqueRules := map[uint64]struct{}{}
 	for _, r := range g.Rules {
@@ -120,6 +147,9 @@ func (g *Group) Validate(validateTplFn ValidateTplFn, validateExpressions bool)
 			}
 		}
 		if validateTplFn != nil {
+			if err := validateTplFn(map[string]string{"dashboard_url": r.DashboardURL}); err != nil {
+				return fmt.Errorf("invalid dashboard_url for rule  %q: %w", ruleName, err)
+			}
 			if err := validateTplFn(r.Annotations); err != nil {
 				return fmt.Errorf("invalid annotations for rule  %q: %w", ruleName, err)
 			}
@@ -131,6 +161,133 @@ func (g *Group) Validate(validateTplFn ValidateTplFn, validateExpressions bool)
 	return checkOverflow(g.XXX, fmt.Sprintf("group %q", g.Name))
 }
 
+// GroupDefaults contains default values for groups within a single config file.
+//
+// Every group in the file inherits the values, which aren't set on the group level.
+// Labels, params and headers are merged with the group ones, while the group values have priority.
+type GroupDefaults struct {
+	Interval        *promutil.Duration `yaml:"interval,omitempty"`
+	EvalOffset      *promutil.Duration `yaml:"eval_offset,omitempty"`
+	EvalDelay       *promutil.Duration `yaml:"eval_delay,omitempty"`
+	Limit           int                `yaml:"limit,omitempty"`
+	Concurrency     int                `yaml:"concurrency,omitempty"`
+	Labels          map[string]string  `yaml:"labels,omitempty"`
+	Params          url.Values         `yaml:"params,omitempty"`
+	Headers         []Header           `yaml:"headers,omitempty"`
+	NotifierHeaders []Header           `yaml:"notifier_headers,omitempty"`
+	EvalAlignment   *bool              `yaml:"eval_alignment,omitempty"`
+	DashboardURL    string             `yaml:"dashboard_url,omitempty"`
+	// MuteTimeIntervals and ActiveTimeIntervals are applied to groups without their own time intervals.
+	MuteTimeIntervals              []string `yaml:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals            []string `yaml:"active_time_intervals,omitempty"`
+	SuppressFirstEvalNotifications *bool    `yaml:"suppress_first_eval_notifications,omitempty"`
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// applyTo sets the group fields, which aren't set on the group level, to values from gd.
+//
+// checksum is the checksum of gd. It is mixed into the group checksum, so the group is updated on gd changes.
+func (gd *GroupDefaults) applyTo(g *Group, checksum string) {
+	if g.Interval == nil {
+		g.Interval = gd.Interval
+	}
+	if g.EvalOffset == nil {
+		g.EvalOffset = gd.EvalOffset
+	}
+	if g.EvalDelay == nil {
+		g.EvalDelay = gd.EvalDelay
+	}
+	if g.Limit == 0 {
+		g.Limit = gd.Limit
+	}
+	if g.Concurrency == 0 {
+		g.Concurrency = gd.Concurrency
+	}
+	if g.EvalAlignment == nil {
+		g.EvalAlignment = gd.EvalAlignment
+	}
+	if g.DashboardURL == "" {
+		g.DashboardURL = gd.DashboardURL
+	}
+	if g.MuteTimeIntervals == nil {
+		g.MuteTimeIntervals = gd.MuteTimeIntervals
+	}
+	if g.ActiveTimeIntervals == nil {
+		g.ActiveTimeIntervals = gd.ActiveTimeIntervals
+	}
+	if g.SuppressFirstEvalNotifications == nil {
+		g.SuppressFirstEvalNotifications = gd.SuppressFirstEvalNotifications
+	}
+	g.Labels = mergeLabels(gd.Labels, g.Labels)
+	g.Params = mergeParams(gd.Params, g.Params)
+	g.Headers = mergeHeaders(gd.Headers, g.Headers)
+	g.NotifierHeaders = mergeHeaders(gd.NotifierHeaders, g.NotifierHeaders)
+
+	h := md5.New()
+	h.Write([]byte(g.Checksum))
+	h.Write([]byte(checksum))
+	g.Checksum = fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func (gd *GroupDefaults) checksum() (string, error) {
+	b, err := yaml.Marshal(gd)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal defaults configuration for checksum: %w", err)
+	}
+	h := md5.New()
+	h.Write(b)
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func mergeLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return labels
+	}
+	m := make(map[string]string, len(defaults)+len(labels))
+	for k, v := range defaults {
+		m[k] = v
+	}
+	for k, v := range labels {
+		m[k] = v
+	}
+	return m
+}
+
+func mergeParams(defaults, params url.Values) url.Values {
+	if len(defaults) == 0 {
+		return params
+	}
+	m := make(url.Values, len(defaults)+len(params))
+	for k, vs := range defaults {
+		m[k] = append([]string{}, vs...)
+	}
+	for k, vs := range params {
+		m[k] = append([]string{}, vs...)
+	}
+	return m
+}
+
+func mergeHeaders(defaults, headers []Header) []Header {
+	if len(defaults) == 0 {
+		return headers
+	}
+	result := make([]Header, 0, len(defaults)+len(headers))
+	for _, dh := range defaults {
+		overridden := false
+		for _, h := range headers {
+			if strings.EqualFold(h.Key, dh.Key) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			result = append(result, dh)
+		}
+	}
+	return append(result, headers...)
+}
+
 // Rule describes entity that represent either
 // recording rule or alerting rule.
 type Rule struct {
@@ -147,6 +304,9 @@ type Rule struct {
 	// UpdateEntriesLimit defines max number of rule's state updates stored in memory.
 	// Overrides `-rule.updateEntriesLimit`.
 	UpdateEntriesLimit *int `yaml:"update_entries_limit,omitempty"`
+	// DashboardURL is an optional template for the link to the dashboard for alerts generated by the rule.
+	// It overrides the group's DashboardURL.
+	DashboardURL string `yaml:"dashboard_url,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]any `yaml:",inline"`
@@ -225,6 +385,9 @@ func (r *Rule) Validate() error {
 	if r.Expr == "" {
 		return fmt.Errorf("expression can't be empty")
 	}
+	if r.Record != "" && r.DashboardURL != "" {
+		return fmt.Errorf("dashboard_url can be set only for alerting rules")
+	}
 	return checkOverflow(r.XXX, "rule")
 }
 
@@ -308,7 +471,9 @@ func parseConfig(data []byte) ([]Group, error) {
 
 	var result []Group
 	type cfgFile struct {
-		Groups []Group `yaml:"groups"`
+		// Defaults contains default values for all the groups in the file.
+		Defaults *GroupDefaults `yaml:"defaults"`
+		Groups   []Group        `yaml:"groups"`
 		// Catches all undefined fields and must be empty after parsing.
 		XXX map[string]any `yaml:",inline"`
 	}
@@ -325,6 +490,18 @@ func parseConfig(data []byte) ([]Group, error) {
 		if err = checkOverflow(cf.XXX, "config"); err != nil {
 			return nil, err
 		}
+		if gd := cf.Defaults; gd != nil {
+			if err = checkOverflow(gd.XXX, "defaults"); err != nil {
+				return nil, err
+			}
+			checksum, err := gd.checksum()
+			if err != nil {
+				return nil, err
+			}
+			for i := range cf.Groups {
+				gd.applyTo(&cf.Groups[i], checksum)
+			}
+		}
 		result = append(result, cf.Groups...)
 	}
 
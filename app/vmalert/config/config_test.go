@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +20,9 @@ func TestMain(m *testing.M) {
 	if err := templates.Load([]string{"testdata/templates/*good.tmpl"}, url.URL{}); err != nil {
 		os.Exit(1)
 	}
+	if err := LoadTimeIntervals("testdata/time_intervals/time-intervals-good.yaml"); err != nil {
+		os.Exit(1)
+	}
 	os.Exit(m.Run())
 }
 
@@ -126,6 +130,9 @@ func TestParse_Failure(t *testing.T) {
 	f([]string{"testdata/dir/rules6-bad.rules"}, "missing ':' in header")
 	f([]string{"testdata/rules/rules-multi-doc-bad.rules"}, "unknown fields")
 	f([]string{"testdata/rules/rules-multi-doc-duplicates-bad.rules"}, "duplicate")
+	f([]string{"testdata/rules/rules-dashboard-url-bad.rules"}, "invalid dashboard_url")
+	f([]string{"testdata/rules/rules-defaults-bad.rules"}, "unknown fields in defaults")
+	f([]string{"testdata/rules/rules-time-intervals-bad.rules"}, "unknown time interval")
 	f([]string{"http://unreachable-url"}, "failed to")
 }
 
@@ -343,6 +350,16 @@ func TestGroupValidate_Failure(t *testing.T) {
 		},
 	}, true, "bad prometheus expr")
 
+	f(&Group{
+		Name: "test dashboard_url for recording rule",
+		Rules: []Rule{
+			{
+				Record:       "r1",
+				Expr:         "sum(up == 0 ) by (host)",
+				DashboardURL: "https://grafana.example.com/d/foo",
+			},
+		},
+	}, false, "dashboard_url can be set only for alerting rules")
 }
 
 func TestGroupValidate_Success(t *testing.T) {
@@ -708,3 +725,199 @@ rules:
 `, url.Values{"nocache": {"1"}, "denyPartialResponse": {"true"}})
 	})
 }
+
+func TestParseConfigDefaults(t *testing.T) {
+	f := func(data string, groupsExpected []Group) {
+		t.Helper()
+
+		groups, err := parseConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(groups) != len(groupsExpected) {
+			t.Fatalf("unexpected number of groups; got %d; want %d", len(groups), len(groupsExpected))
+		}
+		for i := range groups {
+			g, gExpected := groups[i], groupsExpected[i]
+			if g.Interval.Duration() != gExpected.Interval.Duration() {
+				t.Fatalf("unexpected interval for group %q; got %s; want %s", g.Name, g.Interval.Duration(), gExpected.Interval.Duration())
+			}
+			if g.Concurrency != gExpected.Concurrency {
+				t.Fatalf("unexpected concurrency for group %q; got %d; want %d", g.Name, g.Concurrency, gExpected.Concurrency)
+			}
+			if !reflect.DeepEqual(g.Labels, gExpected.Labels) {
+				t.Fatalf("unexpected labels for group %q; got %v; want %v", g.Name, g.Labels, gExpected.Labels)
+			}
+			if g.Params.Encode() != gExpected.Params.Encode() {
+				t.Fatalf("unexpected params for group %q; got %q; want %q", g.Name, g.Params.Encode(), gExpected.Params.Encode())
+			}
+			if !reflect.DeepEqual(g.NotifierHeaders, gExpected.NotifierHeaders) {
+				t.Fatalf("unexpected notifier_headers for group %q; got %v; want %v", g.Name, g.NotifierHeaders, gExpected.NotifierHeaders)
+			}
+			if !reflect.DeepEqual(g.SuppressFirstEvalNotifications, gExpected.SuppressFirstEvalNotifications) {
+				t.Fatalf("unexpected suppress_first_eval_notifications for group %q; got %v; want %v",
+					g.Name, g.SuppressFirstEvalNotifications, gExpected.SuppressFirstEvalNotifications)
+			}
+		}
+	}
+
+	// no defaults
+	f(`
+groups:
+  - name: TestGroup
+    concurrency: 2
+    rules:
+      - alert: foo
+        expr: up == 0
+`, []Group{{
+		Concurrency: 2,
+	}})
+
+	// defaults are inherited and overridden by groups
+	f(`
+defaults:
+  interval: 30s
+  concurrency: 2
+  labels:
+    team: infra
+    env: prod
+  params:
+    nocache: ["1"]
+  notifier_headers:
+    - "TenantID: infra"
+    - "X-Env: prod"
+groups:
+  - name: Inherited
+    rules:
+      - alert: foo
+        expr: up == 0
+  - name: Overridden
+    interval: 1m
+    concurrency: 4
+    labels:
+      team: db
+    params:
+      nocache: ["0"]
+      extra_label: ["env=dev"]
+    notifier_headers:
+      - "tenantid: db"
+    rules:
+      - alert: foo
+        expr: up == 0
+`, []Group{
+		{
+			Interval:        promutil.NewDuration(30 * time.Second),
+			Concurrency:     2,
+			Labels:          map[string]string{"team": "infra", "env": "prod"},
+			Params:          url.Values{"nocache": {"1"}},
+			NotifierHeaders: []Header{{Key: "TenantID", Value: "infra"}, {Key: "X-Env", Value: "prod"}},
+		},
+		{
+			Interval:        promutil.NewDuration(time.Minute),
+			Concurrency:     4,
+			Labels:          map[string]string{"team": "db", "env": "prod"},
+			Params:          url.Values{"nocache": {"0"}, "extra_label": {"env=dev"}},
+			NotifierHeaders: []Header{{Key: "X-Env", Value: "prod"}, {Key: "tenantid", Value: "db"}},
+		},
+	})
+
+	// defaults are applied only to groups in the same document
+	f(`
+defaults:
+  concurrency: 2
+groups:
+  - name: First
+    rules:
+      - alert: foo
+        expr: up == 0
+---
+groups:
+  - name: Second
+    rules:
+      - alert: foo
+        expr: up == 0
+`, []Group{
+		{
+			Concurrency: 2,
+		},
+		{
+			Concurrency: 0,
+		},
+	})
+
+	// suppress_first_eval_notifications is inherited and can be disabled on the group level
+	enabled, disabled := true, false
+	f(`
+defaults:
+  suppress_first_eval_notifications: true
+groups:
+  - name: Inherited
+    rules:
+      - alert: foo
+        expr: up == 0
+  - name: Overridden
+    suppress_first_eval_notifications: false
+    rules:
+      - alert: foo
+        expr: up == 0
+`, []Group{
+		{
+			SuppressFirstEvalNotifications: &enabled,
+		},
+		{
+			SuppressFirstEvalNotifications: &disabled,
+		},
+	})
+}
+
+func TestParseConfigDefaultsChecksum(t *testing.T) {
+	f := func(data, newData string) {
+		t.Helper()
+
+		groups, err := parseConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		newGroups, err := parseConfig([]byte(newData))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if groups[0].Checksum == newGroups[0].Checksum {
+			t.Fatalf("expected to get different checksums")
+		}
+	}
+
+	f(`
+defaults:
+  concurrency: 2
+groups:
+  - name: TestGroup
+    rules:
+      - alert: foo
+        expr: up == 0
+`, `
+defaults:
+  concurrency: 3
+groups:
+  - name: TestGroup
+    rules:
+      - alert: foo
+        expr: up == 0
+`)
+	f(`
+groups:
+  - name: TestGroup
+    rules:
+      - alert: foo
+        expr: up == 0
+`, `
+defaults:
+  labels:
+    team: infra
+groups:
+  - name: TestGroup
+    rules:
+      - alert: foo
+        expr: up == 0
+`)
+}
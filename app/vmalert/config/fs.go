@@ -1,15 +1,29 @@
 package config
 
 import (
+	"flag"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config/fsgcs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config/fslocal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config/fss3"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config/fsurl"
 )
 
+var (
+	s3CredsFilePath = flag.String("s3.credsFilePath", "", "Path to file with GCS or S3 credentials. Credentials are loaded from default locations if not set.\n"+
+		"See https://cloud.google.com/iam/docs/creating-managing-service-account-keys and https://docs.aws.amazon.com/general/latest/gr/aws-security-credentials.html")
+	s3ConfigFilePath = flag.String("s3.configFilePath", "", "Path to file with S3 configs. Configs are loaded from default location if not set.\n"+
+		"See https://docs.aws.amazon.com/general/latest/gr/aws-security-credentials.html")
+	s3ConfigProfile = flag.String("s3.configProfile", "", "Profile name for S3 configs. If no set, the value of the environment variable will be loaded (AWS_PROFILE or AWS_DEFAULT_PROFILE), "+
+		"or if both not set, DefaultSharedConfigProfile is used")
+	s3CustomEndpoint = flag.String("s3.customEndpoint", "", "Custom S3 endpoint for use with S3-compatible storages (e.g. MinIO). S3 is used if not set")
+	s3ForcePathStyle = flag.Bool("s3.forcePathStyle", true, "Prefixing endpoint with bucket name when set false, true by default.")
+)
+
 // FS represent a file system abstract for reading files.
 type FS interface {
 	// Init initializes FS.
@@ -88,7 +102,7 @@ func ReadFromFS(paths []string) (map[string][]byte, error) {
 }
 
 // newFS creates FS based on the give path.
-// Supported file systems are: fs
+// Supported file systems are: fs, http, https, s3, gs
 func newFS(originPath string) (FS, error) {
 	scheme := "fs"
 	path := originPath
@@ -105,7 +119,40 @@ func newFS(originPath string) (FS, error) {
 		return &fslocal.FS{Pattern: path}, nil
 	case "http", "https":
 		return &fsurl.FS{Path: originPath}, nil
+	case "s3":
+		bucket, prefix, err := parseBucketPath(path)
+		if err != nil {
+			return nil, err
+		}
+		return &fss3.FS{
+			Bucket:         bucket,
+			Prefix:         prefix,
+			CredsFilePath:  *s3CredsFilePath,
+			ConfigFilePath: *s3ConfigFilePath,
+			ProfileName:    *s3ConfigProfile,
+			CustomEndpoint: *s3CustomEndpoint,
+			ForcePathStyle: *s3ForcePathStyle,
+		}, nil
+	case "gs", "gcs":
+		bucket, prefix, err := parseBucketPath(path)
+		if err != nil {
+			return nil, err
+		}
+		return &fsgcs.FS{
+			Bucket:        bucket,
+			Prefix:        prefix,
+			CredsFilePath: *s3CredsFilePath,
+		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported scheme %q", scheme)
 	}
 }
+
+// parseBucketPath splits path in form `bucket/prefix` into bucket and prefix.
+func parseBucketPath(path string) (string, string, error) {
+	n := strings.Index(path, "/")
+	if n <= 0 {
+		return "", "", fmt.Errorf("missing bucket name or prefix in path %q; expecting `bucket/prefix`", path)
+	}
+	return path[:n], path[n+1:], nil
+}
@@ -19,6 +19,10 @@ func TestNewFS(t *testing.T) {
 
 	f("/foo/bar", "Local FS{MatchPattern: \"/foo/bar\"}")
 	f("fs:///foo/bar", "Local FS{MatchPattern: \"/foo/bar\"}")
+	f("http://foo/bar", "URL {Path: \"http://foo/bar\"}")
+	f("s3://bucket/dir/rule_", "S3{bucket: \"bucket\", prefix: \"dir/rule_\"}")
+	f("gs://bucket/dir/", "GCS{bucket: \"bucket\", prefix: \"dir/\"}")
+	f("gcs://bucket/rules", "GCS{bucket: \"bucket\", prefix: \"rules\"}")
 }
 
 func TestNewFSNegative(t *testing.T) {
@@ -36,4 +40,6 @@ func TestNewFSNegative(t *testing.T) {
 	f("", "path cannot be empty")
 	f("fs://", "path cannot be empty")
 	f("foobar://baz", `unsupported scheme "foobar"`)
+	f("s3://bucket", "missing bucket name or prefix")
+	f("gs:///prefix", "missing bucket name or prefix")
 }
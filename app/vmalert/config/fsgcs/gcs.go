@@ -0,0 +1,96 @@
+package fsgcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// FS represents a struct which can read files from GCS bucket
+// with names matching the given prefix.
+//
+// Init must be called before calling other FS methods.
+type FS struct {
+	// Bucket is the GCS bucket to read files from.
+	Bucket string
+
+	// Prefix is used for matching files in the Bucket.
+	Prefix string
+
+	// CredsFilePath is the path to GCP credentials file.
+	// Default credentials are used if empty.
+	CredsFilePath string
+
+	bkt *storage.BucketHandle
+}
+
+// Init initializes GCS client for fs.
+func (fs *FS) Init() error {
+	fs.Prefix = strings.TrimPrefix(fs.Prefix, "/")
+
+	var opts []option.ClientOption
+	if len(fs.CredsFilePath) > 0 {
+		opts = append(opts, option.WithCredentialsFile(fs.CredsFilePath))
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("cannot create gcs client: %w", err)
+	}
+	fs.bkt = client.Bucket(fs.Bucket)
+	return nil
+}
+
+// String implements Stringer interface
+func (fs *FS) String() string {
+	return fmt.Sprintf("GCS{bucket: %q, prefix: %q}", fs.Bucket, fs.Prefix)
+}
+
+// List returns the list of object names in the Bucket matching the Prefix
+func (fs *FS) List() ([]string, error) {
+	q := &storage.Query{
+		Prefix: fs.Prefix,
+	}
+	if err := q.SetAttrSelection([]string{"Name"}); err != nil {
+		return nil, fmt.Errorf("error in SetAttrSelection: %w", err)
+	}
+	var names []string
+	it := fs.bkt.Objects(context.Background(), q)
+	for {
+		attr, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot list objects at %s: %w", fs, err)
+		}
+		if strings.HasSuffix(attr.Name, "/") {
+			// skip directory placeholders
+			continue
+		}
+		names = append(names, attr.Name)
+	}
+}
+
+// Read returns a map of read files where
+// key is the file URL and value is file's content.
+func (fs *FS) Read(files []string) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, name := range files {
+		r, err := fs.bkt.Object(name).NewReader(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("cannot open %q at %s: %w", name, fs, err)
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %q at %s: %w", name, fs, err)
+		}
+		result[fmt.Sprintf("gs://%s/%s", fs.Bucket, name)] = data
+	}
+	return result, nil
+}
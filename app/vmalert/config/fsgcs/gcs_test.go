@@ -0,0 +1,90 @@
+package fsgcs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFSListRead(t *testing.T) {
+	objects := map[string]string{
+		"rules/alerts.yml":  "groups: []",
+		"rules/records.yml": "groups: [{name: foo}]",
+		"rules/dir/":        "",
+		"other/foo.yml":     "groups: []",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Listing goes via JSON API, while objects are read via XML API.
+		if r.URL.Path == "/storage/v1/b/bucket/o" {
+			prefix := r.URL.Query().Get("prefix")
+			type item struct {
+				Name string `json:"name"`
+			}
+			var items []item
+			for name := range objects {
+				if strings.HasPrefix(name, prefix) {
+					items = append(items, item{Name: name})
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"kind":  "storage#objects",
+				"items": items,
+			})
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+		data, exists := objects[name]
+		if !ok || !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	defer srv.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", srv.URL)
+
+	fs := &FS{
+		Bucket: "bucket",
+		Prefix: "/rules/",
+	}
+	if err := fs.Init(); err != nil {
+		t.Fatalf("cannot init fs: %s", err)
+	}
+
+	names, err := fs.List()
+	if err != nil {
+		t.Fatalf("cannot list objects: %s", err)
+	}
+	namesMap := make(map[string]bool)
+	for _, name := range names {
+		namesMap[name] = true
+	}
+	namesExpected := map[string]bool{
+		"rules/alerts.yml":  true,
+		"rules/records.yml": true,
+	}
+	if !reflect.DeepEqual(namesMap, namesExpected) {
+		t.Fatalf("unexpected names; got %q; want %v", names, namesExpected)
+	}
+
+	result, err := fs.Read(names)
+	if err != nil {
+		t.Fatalf("cannot read objects: %s", err)
+	}
+	resultExpected := map[string][]byte{
+		"gs://bucket/rules/alerts.yml":  []byte("groups: []"),
+		"gs://bucket/rules/records.yml": []byte("groups: [{name: foo}]"),
+	}
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+	}
+
+	// missing object
+	if _, err := fs.Read([]string{"rules/missing.yml"}); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
@@ -0,0 +1,137 @@
+package fss3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+)
+
+// FS represents a struct which can read files from S3 bucket
+// with names matching the given prefix.
+//
+// Init must be called before calling other FS methods.
+type FS struct {
+	// Bucket is the S3 bucket to read files from.
+	Bucket string
+
+	// Prefix is used for matching files in the Bucket.
+	Prefix string
+
+	// CredsFilePath is the path to S3 credentials file.
+	CredsFilePath string
+
+	// ConfigFilePath is the path to S3 configs file.
+	ConfigFilePath string
+
+	// ProfileName is the name of S3 config profile to use.
+	ProfileName string
+
+	// CustomEndpoint is set for using S3-compatible endpoint such as MinIO.
+	CustomEndpoint string
+
+	// ForcePathStyle defines whether to use path style for S3-compatible endpoint.
+	ForcePathStyle bool
+
+	s3 *s3.Client
+}
+
+// Init initializes S3 client for fs.
+func (fs *FS) Init() error {
+	fs.Prefix = strings.TrimPrefix(fs.Prefix, "/")
+
+	var configOpts []func(*config.LoadOptions) error
+	configOpts = append(configOpts, config.WithDefaultRegion("us-east-1"))
+	if len(fs.ProfileName) > 0 {
+		configOpts = append(configOpts, config.WithSharedConfigProfile(fs.ProfileName))
+	}
+	if len(fs.ConfigFilePath) > 0 {
+		configOpts = append(configOpts, config.WithSharedConfigFiles([]string{fs.ConfigFilePath}))
+	}
+	if len(fs.CredsFilePath) > 0 {
+		configOpts = append(configOpts, config.WithSharedCredentialsFiles([]string{fs.CredsFilePath}))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), configOpts...)
+	if err != nil {
+		return fmt.Errorf("cannot load S3 config: %w", err)
+	}
+	cfg.HTTPClient = &http.Client{
+		Transport: httputil.NewTransport(false, "vmalert_s3_rules"),
+	}
+
+	var region string
+	if len(fs.CustomEndpoint) == 0 {
+		region, err = manager.GetBucketRegion(context.Background(), s3.NewFromConfig(cfg), fs.Bucket)
+		if err != nil {
+			return fmt.Errorf("cannot determine region for bucket %q: %w", fs.Bucket, err)
+		}
+	}
+	fs.s3 = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if len(fs.CustomEndpoint) > 0 {
+			o.UsePathStyle = fs.ForcePathStyle
+			o.BaseEndpoint = aws.String(fs.CustomEndpoint)
+			return
+		}
+		o.Region = region
+	})
+	return nil
+}
+
+// String implements Stringer interface
+func (fs *FS) String() string {
+	return fmt.Sprintf("S3{bucket: %q, prefix: %q}", fs.Bucket, fs.Prefix)
+}
+
+// List returns the list of object keys in the Bucket matching the Prefix
+func (fs *FS) List() ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(fs.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(fs.Bucket),
+		Prefix: aws.String(fs.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("cannot list objects at %s: %w", fs, err)
+		}
+		for _, o := range page.Contents {
+			key := *o.Key
+			if strings.HasSuffix(key, "/") {
+				// skip directory placeholders
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Read returns a map of read files where
+// key is the file URL and value is file's content.
+func (fs *FS) Read(files []string) (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	for _, key := range files {
+		o, err := fs.s3.GetObject(context.Background(), &s3.GetObjectInput{
+			Bucket: aws.String(fs.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot open %q at %s: %w", key, fs, err)
+		}
+		data, err := io.ReadAll(o.Body)
+		_ = o.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %q at %s: %w", key, fs, err)
+		}
+		result[fmt.Sprintf("s3://%s/%s", fs.Bucket, key)] = data
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package fss3
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFSListRead(t *testing.T) {
+	objects := map[string]string{
+		"rules/alerts.yml":  "groups: []",
+		"rules/records.yml": "groups: [{name: foo}]",
+		"rules/dir/":        "",
+		"other/foo.yml":     "groups: []",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket" || r.URL.Path == "/bucket/" {
+			if r.URL.Query().Get("list-type") != "2" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			prefix := r.URL.Query().Get("prefix")
+			var keys []string
+			for key := range objects {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			var sb strings.Builder
+			sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>bucket</Name>`)
+			fmt.Fprintf(&sb, `<Prefix>%s</Prefix><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>`, prefix, len(keys))
+			for _, key := range keys {
+				fmt.Fprintf(&sb, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, key, len(objects[key]))
+			}
+			sb.WriteString(`</ListBucketResult>`)
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(sb.String()))
+			return
+		}
+		key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+		data, exists := objects[key]
+		if !ok || !exists {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	// Use static credentials in order to avoid looking for credentials outside the test.
+	t.Setenv("AWS_ACCESS_KEY_ID", "foo")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "bar")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	fs := &FS{
+		Bucket:         "bucket",
+		Prefix:         "/rules/",
+		CustomEndpoint: srv.URL,
+		ForcePathStyle: true,
+	}
+	if err := fs.Init(); err != nil {
+		t.Fatalf("cannot init fs: %s", err)
+	}
+
+	keys, err := fs.List()
+	if err != nil {
+		t.Fatalf("cannot list objects: %s", err)
+	}
+	keysExpected := []string{"rules/alerts.yml", "rules/records.yml"}
+	if !reflect.DeepEqual(keys, keysExpected) {
+		t.Fatalf("unexpected keys; got %q; want %q", keys, keysExpected)
+	}
+
+	result, err := fs.Read(keys)
+	if err != nil {
+		t.Fatalf("cannot read objects: %s", err)
+	}
+	resultExpected := map[string][]byte{
+		"s3://bucket/rules/alerts.yml":  []byte("groups: []"),
+		"s3://bucket/rules/records.yml": []byte("groups: [{name: foo}]"),
+	}
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+	}
+
+	// missing object
+	if _, err := fs.Read([]string{"rules/missing.yml"}); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
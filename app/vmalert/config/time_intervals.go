@@ -0,0 +1,417 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"gopkg.in/yaml.v2"
+)
+
+// TimeIntervals contains named time intervals, which can be referenced
+// by groups via `mute_time_intervals` and `active_time_intervals` params.
+//
+// The format of time intervals is compatible with Alertmanager's `time_intervals`,
+// see https://prometheus.io/docs/alerting/latest/configuration/#time_interval
+type TimeIntervals map[string][]TimeInterval
+
+// NamedTimeInterval is a named list of time intervals.
+type NamedTimeInterval struct {
+	Name          string         `yaml:"name"`
+	TimeIntervals []TimeInterval `yaml:"time_intervals"`
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// TimeInterval describes times, which match all the set conditions.
+//
+// Unset conditions match any time.
+type TimeInterval struct {
+	Times       []timeRange
+	Weekdays    []inclusiveRange
+	DaysOfMonth []inclusiveRange
+	Months      []inclusiveRange
+	Years       []inclusiveRange
+	Location    *time.Location
+}
+
+// timeRange represents [startMinute, endMinute) range of minutes within a day.
+type timeRange struct {
+	startMinute int
+	endMinute   int
+}
+
+type inclusiveRange struct {
+	begin int
+	end   int
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ti *TimeInterval) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw struct {
+		Times []struct {
+			StartTime string `yaml:"start_time"`
+			EndTime   string `yaml:"end_time"`
+		} `yaml:"times,omitempty"`
+		Weekdays    []string `yaml:"weekdays,omitempty"`
+		DaysOfMonth []string `yaml:"days_of_month,omitempty"`
+		Months      []string `yaml:"months,omitempty"`
+		Years       []string `yaml:"years,omitempty"`
+		Location    string   `yaml:"location,omitempty"`
+		// Catches all undefined fields and must be empty after parsing.
+		XXX map[string]any `yaml:",inline"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	if err := checkOverflow(raw.XXX, "time interval"); err != nil {
+		return err
+	}
+
+	for _, t := range raw.Times {
+		start, err := parseTimeOfDay(t.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid start_time: %w", err)
+		}
+		end, err := parseTimeOfDay(t.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid end_time: %w", err)
+		}
+		if start >= end {
+			return fmt.Errorf("start_time=%q must be smaller than end_time=%q", t.StartTime, t.EndTime)
+		}
+		ti.Times = append(ti.Times, timeRange{
+			startMinute: start,
+			endMinute:   end,
+		})
+	}
+
+	var err error
+	ti.Weekdays, err = parseRanges(raw.Weekdays, "weekdays", 0, 6, weekdayNames)
+	if err != nil {
+		return err
+	}
+	ti.DaysOfMonth, err = parseRanges(raw.DaysOfMonth, "days_of_month", -31, 31, nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range ti.DaysOfMonth {
+		if r.begin == 0 || r.end == 0 {
+			return fmt.Errorf("invalid days_of_month range %d:%d; days of month cannot be zero", r.begin, r.end)
+		}
+	}
+	ti.Months, err = parseRanges(raw.Months, "months", 1, 12, monthNames)
+	if err != nil {
+		return err
+	}
+	ti.Years, err = parseRanges(raw.Years, "years", 1, 9999, nil)
+	if err != nil {
+		return err
+	}
+
+	if raw.Location != "" {
+		loc, err := time.LoadLocation(raw.Location)
+		if err != nil {
+			return fmt.Errorf("invalid location %q: %w", raw.Location, err)
+		}
+		ti.Location = loc
+	}
+	return nil
+}
+
+var weekdayNames = map[string]int{
+	"sunday":    0,
+	"monday":    1,
+	"tuesday":   2,
+	"wednesday": 3,
+	"thursday":  4,
+	"friday":    5,
+	"saturday":  6,
+}
+
+var monthNames = map[string]int{
+	"january":   1,
+	"february":  2,
+	"march":     3,
+	"april":     4,
+	"may":       5,
+	"june":      6,
+	"july":      7,
+	"august":    8,
+	"september": 9,
+	"october":   10,
+	"november":  11,
+	"december":  12,
+}
+
+// parseTimeOfDay parses HH:MM string into the number of minutes since the start of the day.
+//
+// 24:00 is allowed for denoting the end of the day.
+func parseTimeOfDay(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok || len(hh) != 2 || len(mm) != 2 {
+		return 0, fmt.Errorf("cannot parse %q; want HH:MM", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse hours in %q: %w", s, err)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse minutes in %q: %w", s, err)
+	}
+	if h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q is out of the allowed range 00:00-24:00", s)
+	}
+	return h*60 + m, nil
+}
+
+// parseRanges parses ss items in the form `v` or `begin:end`.
+//
+// Values may be either integers in the range [minValue, maxValue] or names from the given names map.
+func parseRanges(ss []string, field string, minValue, maxValue int, names map[string]int) ([]inclusiveRange, error) {
+	var rs []inclusiveRange
+	for _, s := range ss {
+		beginStr, endStr, ok := strings.Cut(s, ":")
+		if !ok {
+			endStr = beginStr
+		}
+		begin, err := parseRangeValue(beginStr, minValue, maxValue, names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s range %q: %w", field, s, err)
+		}
+		end, err := parseRangeValue(endStr, minValue, maxValue, names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s range %q: %w", field, s, err)
+		}
+		// Ranges such as `1:-1` are allowed for days of month. They mean from the first till the last day of month.
+		if begin < 0 && end >= 0 {
+			return nil, fmt.Errorf("invalid %s range %q: end must be negative if start is negative", field, s)
+		}
+		if (begin < 0) == (end < 0) && begin > end {
+			return nil, fmt.Errorf("invalid %s range %q: start cannot be bigger than end", field, s)
+		}
+		rs = append(rs, inclusiveRange{
+			begin: begin,
+			end:   end,
+		})
+	}
+	return rs, nil
+}
+
+func parseRangeValue(s string, minValue, maxValue int, names map[string]int) (int, error) {
+	s = strings.TrimSpace(s)
+	if n, ok := names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q", s)
+	}
+	if n < minValue || n > maxValue {
+		return 0, fmt.Errorf("%d is out of the allowed range [%d...%d]", n, minValue, maxValue)
+	}
+	return n, nil
+}
+
+// ContainsTime returns true if t matches ti.
+func (ti *TimeInterval) ContainsTime(t time.Time) bool {
+	if ti.Location != nil {
+		t = t.In(ti.Location)
+	}
+	if len(ti.Times) > 0 {
+		minute := t.Hour()*60 + t.Minute()
+		ok := false
+		for _, tr := range ti.Times {
+			if minute >= tr.startMinute && minute < tr.endMinute {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(ti.Weekdays) > 0 && !rangesContain(ti.Weekdays, int(t.Weekday())) {
+		return false
+	}
+	if len(ti.DaysOfMonth) > 0 {
+		daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		day := t.Day()
+		ok := false
+		for _, r := range ti.DaysOfMonth {
+			begin, end := r.begin, r.end
+			if begin < 0 {
+				begin += daysInMonth + 1
+			}
+			if end < 0 {
+				end += daysInMonth + 1
+			}
+			if day >= begin && day <= end {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(ti.Months) > 0 && !rangesContain(ti.Months, int(t.Month())) {
+		return false
+	}
+	if len(ti.Years) > 0 && !rangesContain(ti.Years, t.Year()) {
+		return false
+	}
+	return true
+}
+
+func rangesContain(rs []inclusiveRange, n int) bool {
+	for _, r := range rs {
+		if n >= r.begin && n <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsTime returns true if t matches at least a single time interval with the given name.
+func (tis TimeIntervals) ContainsTime(name string, t time.Time) bool {
+	for i := range tis[name] {
+		if tis[name][i].ContainsTime(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTimeIntervals parses time intervals from data.
+//
+// data must contain `time_intervals` list in Alertmanager format.
+func ParseTimeIntervals(data []byte) (TimeIntervals, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var cf struct {
+		TimeIntervals []NamedTimeInterval `yaml:"time_intervals"`
+		// Catches all undefined fields and must be empty after parsing.
+		XXX map[string]any `yaml:",inline"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&cf); err != nil {
+		return nil, err
+	}
+	if err := checkOverflow(cf.XXX, "time intervals config"); err != nil {
+		return nil, err
+	}
+	tis := make(TimeIntervals, len(cf.TimeIntervals))
+	for _, nti := range cf.TimeIntervals {
+		if nti.Name == "" {
+			return nil, fmt.Errorf("time interval name must be set")
+		}
+		if err := checkOverflow(nti.XXX, fmt.Sprintf("time interval %q", nti.Name)); err != nil {
+			return nil, err
+		}
+		if _, ok := tis[nti.Name]; ok {
+			return nil, fmt.Errorf("time interval name %q is duplicated", nti.Name)
+		}
+		tis[nti.Name] = nti.TimeIntervals
+	}
+	return tis, nil
+}
+
+type timeIntervalsHolder struct {
+	current     TimeIntervals
+	replacement TimeIntervals
+	loaded      bool
+}
+
+var (
+	timeIntervalsMu     sync.RWMutex
+	sharedTimeIntervals timeIntervalsHolder
+)
+
+// LoadTimeIntervals loads time intervals from the file at the given path and either
+// sets them directly as current time intervals if it's the first load;
+// or sets replacement time intervals and waits for ReloadTimeIntervals() to replace current ones with replacement.
+//
+// path may point to http or https url. Empty path resets time intervals.
+func LoadTimeIntervals(path string) error {
+	var tis TimeIntervals
+	if path != "" {
+		data, err := fscore.ReadFileOrHTTP(path)
+		if err != nil {
+			return fmt.Errorf("cannot read time intervals from %q: %w", path, err)
+		}
+		tis, err = ParseTimeIntervals(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse time intervals from %q: %w", path, err)
+		}
+	}
+
+	timeIntervalsMu.Lock()
+	defer timeIntervalsMu.Unlock()
+	if !sharedTimeIntervals.loaded {
+		sharedTimeIntervals.current = tis
+		sharedTimeIntervals.loaded = true
+	} else {
+		sharedTimeIntervals.replacement = tis
+	}
+	return nil
+}
+
+// ReloadTimeIntervals replaces current time intervals with replacement time intervals set by LoadTimeIntervals.
+func ReloadTimeIntervals() {
+	timeIntervalsMu.Lock()
+	defer timeIntervalsMu.Unlock()
+	if sharedTimeIntervals.replacement != nil {
+		sharedTimeIntervals.current = sharedTimeIntervals.replacement
+		sharedTimeIntervals.replacement = nil
+	}
+}
+
+// hasTimeInterval returns true if time interval with the given name is loaded.
+//
+// The replacement time intervals are checked if they are set, since they will become current after successful config reload.
+func hasTimeInterval(name string) bool {
+	timeIntervalsMu.RLock()
+	defer timeIntervalsMu.RUnlock()
+	tis := sharedTimeIntervals.current
+	if sharedTimeIntervals.replacement != nil {
+		tis = sharedTimeIntervals.replacement
+	}
+	_, ok := tis[name]
+	return ok
+}
+
+// IsMuted returns true if notifications must not be sent at t
+// according to the given mute and active time intervals names.
+//
+// Notifications are muted if t matches any of muteTimeIntervals
+// or if activeTimeIntervals are set and t doesn't match any of them.
+func IsMuted(muteTimeIntervals, activeTimeIntervals []string, t time.Time) bool {
+	if len(muteTimeIntervals) == 0 && len(activeTimeIntervals) == 0 {
+		return false
+	}
+	timeIntervalsMu.RLock()
+	defer timeIntervalsMu.RUnlock()
+	tis := sharedTimeIntervals.current
+	for _, name := range muteTimeIntervals {
+		if tis.ContainsTime(name, t) {
+			return true
+		}
+	}
+	if len(activeTimeIntervals) == 0 {
+		return false
+	}
+	for _, name := range activeTimeIntervals {
+		if tis.ContainsTime(name, t) {
+			return false
+		}
+	}
+	return true
+}
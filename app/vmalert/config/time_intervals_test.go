@@ -0,0 +1,170 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeIntervals_Failure(t *testing.T) {
+	f := func(data, errStrExpected string) {
+		t.Helper()
+
+		_, err := ParseTimeIntervals([]byte(data))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !strings.Contains(err.Error(), errStrExpected) {
+			t.Fatalf("expected err to contain %q; got %q instead", errStrExpected, err)
+		}
+	}
+
+	f(`
+time_intervals:
+- time_intervals: [{}]
+`, "name must be set")
+	f(`
+time_intervals:
+- name: foo
+- name: foo
+`, "duplicated")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - times: [{start_time: "10:00", end_time: "09:00"}]
+`, "must be smaller than end_time")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - times: [{start_time: "10:00", end_time: "25:00"}]
+`, "out of the allowed range")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - weekdays: ["friday:monday"]
+`, "start cannot be bigger than end")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - weekdays: ["funday"]
+`, "cannot parse")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - days_of_month: ["-1:5"]
+`, "end must be negative")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - days_of_month: ["0"]
+`, "cannot be zero")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - months: ["13"]
+`, "out of the allowed range")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - location: "Unknown/Location"
+`, "invalid location")
+	f(`
+time_intervals:
+- name: foo
+  time_intervals:
+  - foo: bar
+`, "unknown fields")
+}
+
+func TestTimeIntervalsContainsTime(t *testing.T) {
+	data := `
+time_intervals:
+- name: weekend-nights
+  time_intervals:
+  - times: [{start_time: "22:00", end_time: "24:00"}, {start_time: "00:00", end_time: "06:00"}]
+    weekdays: ["saturday", "sunday"]
+- name: end-of-month
+  time_intervals:
+  - days_of_month: ["-3:-1"]
+    months: ["january:march"]
+    years: ["2024"]
+- name: berlin-morning
+  time_intervals:
+  - times: [{start_time: "09:00", end_time: "10:00"}]
+    location: "Europe/Berlin"
+- name: always
+  time_intervals:
+  - {}
+`
+	tis, err := ParseTimeIntervals([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(name, ts string, resultExpected bool) {
+		t.Helper()
+
+		tm, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", ts, err)
+		}
+		if result := tis.ContainsTime(name, tm); result != resultExpected {
+			t.Fatalf("unexpected result for %q at %s; got %v; want %v", name, ts, result, resultExpected)
+		}
+	}
+
+	// Saturday
+	f("weekend-nights", "2024-06-01T23:30:00Z", true)
+	f("weekend-nights", "2024-06-01T05:59:00Z", true)
+	f("weekend-nights", "2024-06-01T06:00:00Z", false)
+	// Friday
+	f("weekend-nights", "2024-05-31T23:30:00Z", false)
+
+	f("end-of-month", "2024-02-27T12:00:00Z", true)
+	f("end-of-month", "2024-02-29T12:00:00Z", true)
+	f("end-of-month", "2024-02-26T12:00:00Z", false)
+	f("end-of-month", "2024-04-30T12:00:00Z", false)
+	f("end-of-month", "2025-01-31T12:00:00Z", false)
+
+	// Europe/Berlin is UTC+2 in summer
+	f("berlin-morning", "2024-06-01T07:30:00Z", true)
+	f("berlin-morning", "2024-06-01T09:30:00Z", false)
+
+	f("always", "2024-06-01T09:30:00Z", true)
+	f("unknown", "2024-06-01T09:30:00Z", false)
+}
+
+func TestIsMuted(t *testing.T) {
+	// TestMain loads testdata/time_intervals/time-intervals-good.yaml
+	f := func(mute, active []string, ts string, resultExpected bool) {
+		t.Helper()
+
+		tm, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", ts, err)
+		}
+		if result := IsMuted(mute, active, tm); result != resultExpected {
+			t.Fatalf("unexpected result for mute=%q, active=%q at %s; got %v; want %v", mute, active, ts, result, resultExpected)
+		}
+	}
+
+	// Saturday night
+	f(nil, nil, "2024-06-01T23:00:00Z", false)
+	f([]string{"maintenance"}, nil, "2024-06-01T23:00:00Z", true)
+	f([]string{"maintenance"}, nil, "2024-06-01T21:00:00Z", false)
+
+	// Monday
+	f(nil, []string{"business-hours"}, "2024-06-03T10:00:00Z", false)
+	f(nil, []string{"business-hours"}, "2024-06-03T18:00:00Z", true)
+	// Saturday
+	f(nil, []string{"business-hours"}, "2024-06-01T10:00:00Z", true)
+	f([]string{"maintenance"}, []string{"business-hours"}, "2024-06-01T23:00:00Z", true)
+}
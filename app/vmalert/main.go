@@ -13,6 +13,7 @@ import (
 
 	"github.com/VictoriaMetrics/metrics"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/cluster"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
@@ -40,7 +41,7 @@ Examples:
  -rule="dir/**/*.yaml". Includes all the .yaml files in "dir" subfolders recursively.
 Rule files support YAML multi-document. Files may contain %{ENV_VAR} placeholders, which are substituted by the corresponding env vars.
 
-Enterprise version of vmalert supports S3 and GCS paths to rules.
+S3 and GCS paths to rules are supported as well.
 For example: gs://bucket/path/to/rules, s3://bucket/path/to/rules
 S3 and GCS paths support only matching by prefix, e.g. s3://bucket/dir/rule_ matches
 all files with prefix rule_ in folder dir.
@@ -54,8 +55,14 @@ Examples:
  -rule.templates="dir/*.tpl" -rule.templates="/*.tpl". Relative path to all .tpl files in "dir" folder,
 absolute path to all .tpl files in root.
  -rule.templates="dir/**/*.tpl". Includes all the .tpl files in "dir" subfolders recursively.
+ -rule.templates="https://host/path/to/file#sha256=<hex>". Templates served via http or https url. The #sha256=<hex> suffix is optional.
+See https://docs.victoriametrics.com/vmalert/#remote-templates
 `)
 
+	ruleTimeIntervalsPath = flag.String("rule.timeIntervals", "", "Path or http url to the file with named time intervals in Alertmanager format. "+
+		"The time intervals can be referenced by groups via mute_time_intervals and active_time_intervals params "+
+		"in order to skip sending notifications during planned maintenance windows. See https://docs.victoriametrics.com/vmalert/#time-intervals")
+
 	configCheckInterval = flag.Duration("configCheckInterval", 0, "Interval for checking for changes in '-rule' or '-notifier.config' files. "+
 		"By default, the checking is disabled. Send SIGHUP signal in order to force config check for changes.")
 
@@ -109,6 +116,10 @@ func main() {
 		logger.Fatalf("failed to load template %q: %s", *ruleTemplatesPath, err)
 	}
 
+	if err := config.LoadTimeIntervals(*ruleTimeIntervalsPath); err != nil {
+		logger.Fatalf("failed to load time intervals: %s", err)
+	}
+
 	if *dryRun {
 		groups, err := config.Parse(*rulePath, notifier.ValidateTemplates, true)
 		if err != nil {
@@ -155,6 +166,10 @@ func main() {
 		return
 	}
 
+	if err := cluster.Init(); err != nil {
+		logger.Fatalf("failed to init cluster mode: %s", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	manager, err := newManager(ctx)
 	if err != nil {
@@ -194,6 +209,7 @@ func main() {
 	}
 	cancel()
 	manager.close()
+	cluster.Stop()
 }
 
 var (
@@ -286,6 +302,20 @@ func getHostnameAsExternalURL(addr string, isSecure bool) (*url.URL, error) {
 }
 
 func getAlertURLGenerator(externalURL *url.URL, externalAlertSource string, validateTemplate bool) (notifier.AlertURLGenerator, error) {
+	fn, err := getDefaultAlertURLGenerator(externalURL, externalAlertSource, validateTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return func(a notifier.Alert) string {
+		// dashboard_url set on group or rule level has priority over -external.alert.source
+		if dashboardURL := a.Annotations[notifier.DashboardURLAnnotation]; dashboardURL != "" {
+			return dashboardURL
+		}
+		return fn(a)
+	}, nil
+}
+
+func getDefaultAlertURLGenerator(externalURL *url.URL, externalAlertSource string, validateTemplate bool) (notifier.AlertURLGenerator, error) {
 	if externalAlertSource == "" {
 		return func(a notifier.Alert) string {
 			gID, aID := strconv.FormatUint(a.GroupID, 10), strconv.FormatUint(a.ID, 10)
@@ -369,6 +399,11 @@ func configReload(ctx context.Context, m *manager, groupsCfg []config.Group, sig
 			logger.Errorf("failed to load new templates: %s", err)
 			continue
 		}
+		if err := config.LoadTimeIntervals(*ruleTimeIntervalsPath); err != nil {
+			setConfigError(err)
+			logger.Errorf("failed to load new time intervals: %s", err)
+			continue
+		}
 		newGroupsCfg, err := parseFn(*rulePath, validateTplFn, *validateExpressions)
 		if err != nil {
 			setConfigError(err)
@@ -377,6 +412,7 @@ func configReload(ctx context.Context, m *manager, groupsCfg []config.Group, sig
 		}
 		if configsEqual(newGroupsCfg, groupsCfg) {
 			templates.Reload()
+			config.ReloadTimeIntervals()
 			// set success to 1 since previous reload could have been unsuccessful
 			// do not update configTimestamp as config version remains old.
 			configSuccess.Set(1)
@@ -391,6 +427,7 @@ func configReload(ctx context.Context, m *manager, groupsCfg []config.Group, sig
 			continue
 		}
 		templates.Reload()
+		config.ReloadTimeIntervals()
 		groupsCfg = newGroupsCfg
 		setConfigSuccessAt(fasttime.UnixTimestamp())
 		logger.Infof("Rules reloaded successfully from %q", *rulePath)
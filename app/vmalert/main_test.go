@@ -70,6 +70,12 @@ func TestGetAlertURLGenerator(t *testing.T) {
 	if exp := "https://victoriametrics.com/path/foo?query=4&ds=baz"; exp != fn(testAlert) {
 		t.Fatalf("unexpected url want %s, got %s", exp, fn(testAlert))
 	}
+
+	// dashboard_url annotation has priority over -external.alert.source
+	testAlert.Annotations = map[string]string{notifier.DashboardURLAnnotation: "https://grafana.example.com/d/foo?var-tenant=baz"}
+	if exp := "https://grafana.example.com/d/foo?var-tenant=baz"; exp != fn(testAlert) {
+		t.Fatalf("unexpected url want %s, got %s", exp, fn(testAlert))
+	}
 }
 
 func TestConfigReload(t *testing.T) {
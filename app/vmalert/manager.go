@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
@@ -46,6 +49,101 @@ func (m *manager) ruleAPI(gID, rID uint64) (apiRule, error) {
 	return apiRule{}, fmt.Errorf("can't find rule with id %d in group %q", rID, g.Name)
 }
 
+// pauseRule pauses the rule via admin API until it is explicitly resumed
+func (m *manager) pauseRule(gID, rID uint64, reason string) (bool, error) {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	g, ok := m.groups[gID]
+	if !ok {
+		return false, fmt.Errorf("can't find group with id %d", gID)
+	}
+	for _, r := range g.Rules {
+		if r.ID() == rID {
+			return rule.PauseRule(r, reason), nil
+		}
+	}
+	return false, fmt.Errorf("can't find rule with id %d in group %q", rID, g.Name)
+}
+
+// resumeRule resumes the rule paused via admin API or because of exceeding evaluation budget
+func (m *manager) resumeRule(gID, rID uint64) (bool, error) {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	g, ok := m.groups[gID]
+	if !ok {
+		return false, fmt.Errorf("can't find group with id %d", gID)
+	}
+	for _, r := range g.Rules {
+		if r.ID() == rID {
+			return rule.ResumeRule(r), nil
+		}
+	}
+	return false, fmt.Errorf("can't find rule with id %d in group %q", rID, g.Name)
+}
+
+// pauseGroup pauses all the rules in the group via admin API until they are explicitly resumed
+func (m *manager) pauseGroup(gID uint64, reason string) (bool, error) {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	g, ok := m.groups[gID]
+	if !ok {
+		return false, fmt.Errorf("can't find group with id %d", gID)
+	}
+	return rule.PauseGroup(g, reason), nil
+}
+
+// resumeGroup resumes all the paused rules in the group
+func (m *manager) resumeGroup(gID uint64) (bool, error) {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	g, ok := m.groups[gID]
+	if !ok {
+		return false, fmt.Errorf("can't find group with id %d", gID)
+	}
+	return rule.ResumeGroup(g), nil
+}
+
+// pausedRulesAPI returns all the paused rules sorted by group and rule names.
+func (m *manager) pausedRulesAPI() []apiPausedRule {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	var result []apiPausedRule
+	for _, g := range m.groups {
+		for _, r := range g.Rules {
+			ps := rule.GetPauseState(r)
+			if !ps.Paused {
+				continue
+			}
+			pr := apiPausedRule{
+				GroupID:   fmt.Sprintf("%d", g.GetID()),
+				GroupName: g.Name,
+				File:      g.File,
+				RuleID:    fmt.Sprintf("%d", r.ID()),
+				RuleName:  ruleToAPI(r).Name,
+				PausedBy:  string(ps.Reason),
+				Reason:    ps.Message,
+				Since:     ps.Since,
+			}
+			if !ps.Until.IsZero() {
+				pr.Until = &ps.Until
+			}
+			result = append(result, pr)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].GroupName != result[j].GroupName {
+			return result[i].GroupName < result[j].GroupName
+		}
+		return result[i].RuleName < result[j].RuleName
+	})
+	return result
+}
+
 // alertAPI generates apiAlert object from alert by its ID(hash)
 func (m *manager) alertAPI(gID, aID uint64) (*apiAlert, error) {
 	m.groupsMu.RLock()
@@ -67,6 +165,152 @@ func (m *manager) alertAPI(gID, aID uint64) (*apiAlert, error) {
 	return nil, fmt.Errorf("can't find alert with id %d in group %q", aID, g.Name)
 }
 
+// exportState returns alerts state of all the alerting rules
+func (m *manager) exportState() []apiRuleState {
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	rss := make([]apiRuleState, 0)
+	for _, g := range m.groups {
+		for _, r := range g.Rules {
+			ar, ok := r.(*rule.AlertingRule)
+			if !ok {
+				continue
+			}
+			alerts := ar.ExportAlerts()
+			if len(alerts) == 0 {
+				continue
+			}
+			rs := apiRuleState{
+				// encode as strings to avoid rounding
+				GroupID:   fmt.Sprintf("%d", g.GetID()),
+				GroupName: g.Name,
+				File:      g.File,
+				RuleID:    fmt.Sprintf("%d", ar.RuleID),
+				Name:      ar.Name,
+				Alerts:    make([]apiAlertState, 0, len(alerts)),
+			}
+			sort.Slice(alerts, func(i, j int) bool {
+				return alerts[i].ID < alerts[j].ID
+			})
+			for i := range alerts {
+				rs.Alerts = append(rs.Alerts, newAlertStateAPI(&alerts[i]))
+			}
+			rss = append(rss, rs)
+		}
+	}
+	// sort for deterministic output
+	sort.Slice(rss, func(i, j int) bool {
+		if rss[i].GroupID != rss[j].GroupID {
+			return rss[i].GroupID < rss[j].GroupID
+		}
+		return rss[i].RuleID < rss[j].RuleID
+	})
+	return rss
+}
+
+// importState imports alerts state for alerting rules.
+// Rules are matched by group and rule IDs, so the importing vmalert must run the same rules config.
+//
+// It returns the number of imported alerts and the number of rules, which couldn't be found.
+func (m *manager) importState(rss []apiRuleState) (int, int, error) {
+	type ruleAlerts struct {
+		ar     *rule.AlertingRule
+		alerts []notifier.Alert
+	}
+
+	m.groupsMu.RLock()
+	defer m.groupsMu.RUnlock()
+
+	// validate the whole state before applying it
+	var toImport []ruleAlerts
+	skipped := 0
+	for _, rs := range rss {
+		ar := m.findAlertingRule(rs.GroupID, rs.RuleID)
+		if ar == nil {
+			logger.Warnf("skipping state import for alerting rule %q from group %q: cannot find rule with group_id=%s and rule_id=%s",
+				rs.Name, rs.GroupName, rs.GroupID, rs.RuleID)
+			skipped++
+			continue
+		}
+		alerts := make([]notifier.Alert, 0, len(rs.Alerts))
+		for i := range rs.Alerts {
+			a, err := rs.Alerts[i].toAlert()
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid alert state for rule %q from group %q: %w", rs.Name, rs.GroupName, err)
+			}
+			alerts = append(alerts, a)
+		}
+		toImport = append(toImport, ruleAlerts{
+			ar:     ar,
+			alerts: alerts,
+		})
+	}
+
+	imported := 0
+	for _, ra := range toImport {
+		ra.ar.ImportAlerts(ra.alerts)
+		imported += len(ra.alerts)
+	}
+	return imported, skipped, nil
+}
+
+func (m *manager) findAlertingRule(groupID, ruleID string) *rule.AlertingRule {
+	gID, err := strconv.ParseUint(groupID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	rID, err := strconv.ParseUint(ruleID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	g, ok := m.groups[gID]
+	if !ok {
+		return nil
+	}
+	for _, r := range g.Rules {
+		if ar, ok := r.(*rule.AlertingRule); ok && ar.RuleID == rID {
+			return ar
+		}
+	}
+	return nil
+}
+
+// sendTestAlert sends a synthetic firing alert built from tr to all the configured notifiers.
+//
+// If dryRun is set, then the alert isn't sent. Instead, the request body for every AlertManager notifier is returned.
+func (m *manager) sendTestAlert(ctx context.Context, tr *testAlertRequest, dryRun bool) ([]testAlertNotifierResult, error) {
+	var nts []notifier.Notifier
+	if m.notifiers != nil {
+		nts = m.notifiers()
+	}
+	if len(nts) == 0 {
+		return nil, fmt.Errorf("no notifiers configured; see -notifier.url, -notifier.config and -notifier.blackhole command-line flags")
+	}
+	alerts := []notifier.Alert{tr.toAlert(m.labels, time.Now())}
+
+	results := make([]testAlertNotifierResult, len(nts))
+	var wg sync.WaitGroup
+	for i, nt := range nts {
+		results[i].Addr = nt.Addr()
+		if dryRun {
+			if am, ok := nt.(*notifier.AlertManager); ok {
+				results[i].Payload = am.Payload(alerts)
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(nt notifier.Notifier, res *testAlertNotifierResult) {
+			defer wg.Done()
+			if err := nt.Send(ctx, alerts, nil); err != nil {
+				res.Error = err.Error()
+			}
+		}(nt, &results[i])
+	}
+	wg.Wait()
+	return results, nil
+}
+
 func (m *manager) start(ctx context.Context, groupsCfg []config.Group) error {
 	return m.update(ctx, groupsCfg, true)
 }
@@ -76,15 +76,23 @@ func (as AlertState) String() string {
 	return "inactive"
 }
 
+// DashboardURLAnnotation is the annotation name for the link to the dashboard
+// set via `dashboard_url` param on group or rule level.
+//
+// If set, it is used as the Source link for alerts sent to AlertManager.
+const DashboardURLAnnotation = "dashboard_url"
+
 // AlertTplData is used to execute templating
 type AlertTplData struct {
-	Labels   map[string]string
-	Value    float64
-	Expr     string
-	AlertID  uint64
-	GroupID  uint64
-	ActiveAt time.Time
-	For      time.Duration
+	Labels    map[string]string
+	Value     float64
+	Expr      string
+	AlertID   uint64
+	GroupID   uint64
+	GroupName string
+	AlertName string
+	ActiveAt  time.Time
+	For       time.Duration
 }
 
 var tplHeaders = []string{
@@ -95,6 +103,8 @@ var tplHeaders = []string{
 	"{{ $externalURL := .ExternalURL }}",
 	"{{ $alertID := .AlertID }}",
 	"{{ $groupID := .GroupID }}",
+	"{{ $groupName := .GroupName }}",
+	"{{ $alertName := .AlertName }}",
 	"{{ $activeAt := .ActiveAt }}",
 	"{{ $for := .For }}",
 }
@@ -105,13 +115,14 @@ var tplHeaders = []string{
 // requires a queryFunction as an argument.
 func (a *Alert) ExecTemplate(q templates.QueryFn, labels, annotations map[string]string) (map[string]string, error) {
 	tplData := AlertTplData{
-		Value:    a.Value,
-		Labels:   labels,
-		Expr:     a.Expr,
-		AlertID:  a.ID,
-		GroupID:  a.GroupID,
-		ActiveAt: a.ActiveAt,
-		For:      a.For,
+		Value:     a.Value,
+		Labels:    labels,
+		Expr:      a.Expr,
+		AlertID:   a.ID,
+		GroupID:   a.GroupID,
+		AlertName: a.Name,
+		ActiveAt:  a.ActiveAt,
+		For:       a.For,
 	}
 	return ExecTemplate(q, annotations, tplData)
 }
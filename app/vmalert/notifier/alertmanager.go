@@ -7,8 +7,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/VictoriaMetrics/metrics"
 
@@ -31,6 +33,7 @@ type AlertManager struct {
 	// stores already parsed RelabelConfigs object
 	relabelConfigs *promrelabel.ParsedConfigs
 
+	status  *deliveryStatus
 	metrics *notifierMetrics
 }
 
@@ -39,21 +42,51 @@ type notifierMetrics struct {
 
 	alertsSent         *metrics.Counter
 	alertsSendErrors   *metrics.Counter
+	alertsTruncated    *metrics.Counter
+	alertsDropped      *metrics.Counter
 	alertsSendDuration *metrics.Histogram
+
+	requests      *metrics.Counter
+	requestErrors *metrics.Counter
 }
 
-func newNotifierMetrics(addr string) *notifierMetrics {
+func newNotifierMetrics(addr string, ds *deliveryStatus) *notifierMetrics {
 	set := metrics.NewSet()
 	metrics.RegisterSet(set)
 
+	_ = set.NewGauge(fmt.Sprintf("vmalert_notifier_last_success_timestamp_seconds{addr=%q}", addr), func() float64 {
+		return unixTimestamp(ds.get().LastSuccess)
+	})
+	_ = set.NewGauge(fmt.Sprintf("vmalert_notifier_last_error_timestamp_seconds{addr=%q}", addr), func() float64 {
+		return unixTimestamp(ds.get().LastErrorTime)
+	})
+	_ = set.NewGauge(fmt.Sprintf("vmalert_notifier_failing_duration_seconds{addr=%q}", addr), func() float64 {
+		failingSince := ds.get().FailingSince
+		if failingSince.IsZero() {
+			return 0
+		}
+		return time.Since(failingSince).Seconds()
+	})
+
 	return &notifierMetrics{
 		set:                set,
 		alertsSent:         set.NewCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
 		alertsSendErrors:   set.NewCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
+		alertsTruncated:    set.NewCounter(fmt.Sprintf("vmalert_alerts_truncated_total{addr=%q}", addr)),
+		alertsDropped:      set.NewCounter(fmt.Sprintf("vmalert_alerts_dropped_total{addr=%q}", addr)),
 		alertsSendDuration: set.NewHistogram(fmt.Sprintf("vmalert_alerts_send_duration_seconds{addr=%q}", addr)),
+		requests:           set.NewCounter(fmt.Sprintf("vmalert_notifier_requests_total{addr=%q}", addr)),
+		requestErrors:      set.NewCounter(fmt.Sprintf("vmalert_notifier_request_errors_total{addr=%q}", addr)),
 	}
 }
 
+func unixTimestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
 func (nm *notifierMetrics) close() {
 	metrics.UnregisterSet(nm.set, true)
 }
@@ -71,31 +104,69 @@ func (am AlertManager) Addr() string {
 	return am.addr.Redacted()
 }
 
+// AlertRelabelConfigs returns relabeling rules applied to alert labels before sending them to am.
+func (am *AlertManager) AlertRelabelConfigs() *promrelabel.ParsedConfigs {
+	return am.relabelConfigs
+}
+
+// DeliveryStatus returns the status of the last deliveries to am.
+func (am *AlertManager) DeliveryStatus() DeliveryStatus {
+	return am.status.get()
+}
+
 // Send an alert or resolve message
 func (am *AlertManager) Send(ctx context.Context, alerts []Alert, headers map[string]string) error {
 	am.metrics.alertsSent.Add(len(alerts))
+	am.metrics.requests.Inc()
 	startTime := time.Now()
 	err := am.send(ctx, alerts, headers)
 	am.metrics.alertsSendDuration.UpdateDuration(startTime)
 	if err != nil {
 		am.metrics.alertsSendErrors.Add(len(alerts))
+		am.metrics.requestErrors.Inc()
+	}
+	am.status.update(err)
+	if fallback != nil {
+		fallback.check(ctx, am)
 	}
 	return err
 }
 
-func (am *AlertManager) send(ctx context.Context, alerts []Alert, headers map[string]string) error {
+// Payload returns the request body, which would be sent to AlertManager for the given alerts.
+//
+// It is used for rendering test alerts without sending them.
+func (am *AlertManager) Payload(alerts []Alert) []byte {
 	b := &bytes.Buffer{}
+	am.writeRequest(b, alerts)
+	return b.Bytes()
+}
+
+func (am *AlertManager) writeRequest(b *bytes.Buffer, alerts []Alert) (truncated, dropped int) {
 	alertsToSend := make([]Alert, 0, len(alerts))
 	lblss := make([][]prompbmarshal.Label, 0, len(alerts))
 	for _, a := range alerts {
 		lbls := a.applyRelabelingIfNeeded(am.relabelConfigs)
 		if len(lbls) == 0 {
+			dropped++
 			continue
 		}
+		if annotations, ok := truncateAnnotations(a.Annotations, lbls, maxAlertSize.IntN()); ok {
+			// a is a copy of the alert, so it is safe to replace its annotations
+			a.Annotations = annotations
+			truncated++
+		}
 		alertsToSend = append(alertsToSend, a)
 		lblss = append(lblss, lbls)
 	}
 	writeamRequest(b, alertsToSend, am.argFunc, lblss)
+	return truncated, dropped
+}
+
+func (am *AlertManager) send(ctx context.Context, alerts []Alert, headers map[string]string) error {
+	b := &bytes.Buffer{}
+	truncated, dropped := am.writeRequest(b, alerts)
+	am.metrics.alertsTruncated.Add(truncated)
+	am.metrics.alertsDropped.Add(dropped)
 
 	req, err := http.NewRequest(http.MethodPost, am.addr.String(), b)
 	if err != nil {
@@ -143,6 +214,75 @@ func (am *AlertManager) send(ctx context.Context, alerts []Alert, headers map[st
 	return nil
 }
 
+// truncatedAnnotationMarker is appended to annotation values truncated by truncateAnnotations.
+const truncatedAnnotationMarker = "...(truncated)"
+
+// truncateAnnotations truncates annotations if the size of the alert with the given lbls exceeds maxSize bytes.
+//
+// The size of the alert is calculated as the sum of lengths of label and annotation names and values.
+// The longest annotation values are truncated at first, while labels are never modified,
+// since they are used for alert identification.
+//
+// It returns a copy of annotations with truncated values and true if annotations were truncated.
+// Otherwise the original annotations and false are returned.
+func truncateAnnotations(annotations map[string]string, lbls []prompbmarshal.Label, maxSize int) (map[string]string, bool) {
+	if maxSize <= 0 || len(annotations) == 0 {
+		return annotations, false
+	}
+	size := 0
+	for _, l := range lbls {
+		size += len(l.Name) + len(l.Value)
+	}
+	keys := make([]string, 0, len(annotations))
+	for k, v := range annotations {
+		size += len(k) + len(v)
+		keys = append(keys, k)
+	}
+	excess := size - maxSize
+	if excess <= 0 {
+		return annotations, false
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		vi, vj := annotations[keys[i]], annotations[keys[j]]
+		if len(vi) != len(vj) {
+			return len(vi) > len(vj)
+		}
+		return keys[i] < keys[j]
+	})
+
+	truncated := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		truncated[k] = v
+	}
+	ok := false
+	for _, k := range keys {
+		if excess <= 0 {
+			break
+		}
+		v := truncated[k]
+		n := len(v) - excess - len(truncatedAnnotationMarker)
+		if n < 0 {
+			n = 0
+		}
+		// do not cut multi-byte chars in the middle
+		for n > 0 && !utf8.RuneStart(v[n]) {
+			n--
+		}
+		vNew := v[:n] + truncatedAnnotationMarker
+		if len(vNew) >= len(v) {
+			// the value is too short for truncation
+			continue
+		}
+		truncated[k] = vNew
+		excess -= len(v) - len(vNew)
+		ok = true
+	}
+	if !ok {
+		return annotations, false
+	}
+	return truncated, true
+}
+
 // AlertURLGenerator returns URL to single alert by given name
 type AlertURLGenerator func(Alert) string
 
@@ -193,6 +333,7 @@ func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg proma
 	if !*showNotifierURL {
 		alertManagerURL = amURL.Redacted()
 	}
+	ds := &deliveryStatus{}
 	return &AlertManager{
 		addr:           amURL,
 		argFunc:        fn,
@@ -202,6 +343,7 @@ func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg proma
 			Transport: tr,
 		},
 		timeout: timeout,
-		metrics: newNotifierMetrics(alertManagerURL),
+		status:  ds,
+		metrics: newNotifierMetrics(alertManagerURL, ds),
 	}, nil
 }
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 )
 
@@ -195,3 +198,68 @@ func TestAlertManager_Send(t *testing.T) {
 		t.Fatalf("expected 4 calls(count from zero) to server got %d", c)
 	}
 }
+
+func TestTruncateAnnotations(t *testing.T) {
+	f := func(annotations map[string]string, maxSize int, resultExpected map[string]string, truncatedExpected bool) {
+		t.Helper()
+
+		lbls := []prompbmarshal.Label{
+			{Name: "alertname", Value: "foo"},
+			{Name: "job", Value: "bar"},
+		}
+		annotationsOrig := make(map[string]string, len(annotations))
+		for k, v := range annotations {
+			annotationsOrig[k] = v
+		}
+		result, truncated := truncateAnnotations(annotations, lbls, maxSize)
+		if truncated != truncatedExpected {
+			t.Fatalf("unexpected truncated; got %v; want %v", truncated, truncatedExpected)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+		if !reflect.DeepEqual(annotations, annotationsOrig) {
+			t.Fatalf("original annotations must be left untouched; got %v; want %v", annotations, annotationsOrig)
+		}
+	}
+
+	// disabled limit
+	f(map[string]string{"description": strings.Repeat("x", 100)}, 0, map[string]string{"description": strings.Repeat("x", 100)}, false)
+
+	// alert fits the limit
+	f(map[string]string{"summary": "foo"}, 100, map[string]string{"summary": "foo"}, false)
+
+	// the longest annotation is truncated
+	f(map[string]string{
+		"summary":     "foo",
+		"description": strings.Repeat("x", 100),
+	}, 100, map[string]string{
+		"summary":     "foo",
+		"description": strings.Repeat("x", 47) + truncatedAnnotationMarker,
+	}, true)
+
+	// multiple annotations are truncated
+	f(map[string]string{
+		"a": strings.Repeat("x", 50),
+		"b": strings.Repeat("y", 40),
+	}, 50, map[string]string{
+		"a": truncatedAnnotationMarker,
+		"b": strings.Repeat("y", 2) + truncatedAnnotationMarker,
+	}, true)
+
+	// multi-byte chars aren't cut in the middle
+	f(map[string]string{
+		"description": strings.Repeat("ж", 50),
+	}, 80, map[string]string{
+		"description": strings.Repeat("ж", 18) + truncatedAnnotationMarker,
+	}, true)
+
+	// labels exceed the limit, so annotations are truncated as much as possible
+	f(map[string]string{
+		"summary":     "foo",
+		"description": strings.Repeat("x", 100),
+	}, 10, map[string]string{
+		"summary":     "foo",
+		"description": truncatedAnnotationMarker,
+	}, true)
+}
@@ -70,6 +70,12 @@ type StaticConfig struct {
 	Targets []string `yaml:"targets"`
 	// HTTPClientConfig contains HTTP configuration for the Targets
 	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
+	// AlertRelabelConfigs contains list of relabeling rules for alert labels
+	// applied only to the Targets after the global AlertRelabelConfigs.
+	AlertRelabelConfigs []promrelabel.RelabelConfig `yaml:"alert_relabel_configs,omitempty"`
+
+	// stores already parsed AlertRelabelConfigs object, which includes global alert_relabel_configs
+	parsedAlertRelabelConfigs *promrelabel.ParsedConfigs
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -94,6 +100,20 @@ func (cfg *Config) UnmarshalYAML(unmarshal func(any) error) error {
 		return fmt.Errorf("failed to parse alert relabeling config: %w", err)
 	}
 	cfg.parsedAlertRelabelConfigs = arCfg
+	for i := range cfg.StaticConfigs {
+		sc := &cfg.StaticConfigs[i]
+		if len(sc.AlertRelabelConfigs) == 0 {
+			sc.parsedAlertRelabelConfigs = arCfg
+			continue
+		}
+		rcs := append([]promrelabel.RelabelConfig{}, cfg.AlertRelabelConfigs...)
+		rcs = append(rcs, sc.AlertRelabelConfigs...)
+		scCfg, err := promrelabel.ParseRelabelConfigs(rcs)
+		if err != nil {
+			return fmt.Errorf("failed to parse alert relabeling config for static_configs targets %s: %w", sc.Targets, err)
+		}
+		sc.parsedAlertRelabelConfigs = scCfg
+	}
 
 	b, err := yaml.Marshal(cfg)
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 )
 
 func TestConfigWatcherReload(t *testing.T) {
@@ -99,6 +100,63 @@ consul_sd_configs:
 	}
 }
 
+func TestConfigWatcherStaticAlertRelabelConfigs(t *testing.T) {
+	f, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	writeToFile(t, f.Name(), `
+alert_relabel_configs:
+  - target_label: env
+    replacement: prod
+static_configs:
+  - targets:
+      - localhost:9093
+  - targets:
+      - localhost:9094
+    alert_relabel_configs:
+      - source_labels: [severity]
+        regex: critical
+        action: keep
+`)
+	cw, err := newWatcher(f.Name(), nil)
+	if err != nil {
+		t.Fatalf("failed to start config watcher: %s", err)
+	}
+	defer cw.mustStop()
+	ns := cw.notifiers()
+	if len(ns) != 2 {
+		t.Fatalf("expected to have 2 notifiers; got %d %#v", len(ns), ns)
+	}
+
+	f2 := func(addr string, a Alert, labelsExpected string) {
+		t.Helper()
+		for _, n := range ns {
+			if n.Addr() != addr {
+				continue
+			}
+			am := n.(*AlertManager)
+			labels := a.applyRelabelingIfNeeded(am.AlertRelabelConfigs())
+			result := promrelabel.LabelsToString(labels)
+			if result != labelsExpected {
+				t.Fatalf("unexpected labels for %q; got %s; want %s", addr, result, labelsExpected)
+			}
+			return
+		}
+		t.Fatalf("cannot find notifier with addr %q", addr)
+	}
+
+	warning := Alert{Labels: map[string]string{"alertname": "foo", "severity": "warning"}}
+	critical := Alert{Labels: map[string]string{"alertname": "foo", "severity": "critical"}}
+
+	f2("http://localhost:9093/api/v2/alerts", warning, `{alertname="foo",env="prod",severity="warning"}`)
+	f2("http://localhost:9093/api/v2/alerts", critical, `{alertname="foo",env="prod",severity="critical"}`)
+	f2("http://localhost:9094/api/v2/alerts", warning, `{}`)
+	f2("http://localhost:9094/api/v2/alerts", critical, `{alertname="foo",env="prod",severity="critical"}`)
+}
+
 // TestConfigWatcherReloadConcurrent supposed to test concurrent
 // execution of configuration update.
 // Should be executed with -race flag
@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+)
+
+// DeliveryStatus contains the status of alert deliveries to a notifier.
+type DeliveryStatus struct {
+	// LastSuccess is the time of the last successful delivery.
+	LastSuccess time.Time
+	// LastError is the error for the last failed delivery.
+	LastError string
+	// LastErrorTime is the time of the last failed delivery.
+	LastErrorTime time.Time
+	// FailingSince is the time of the first failed delivery after the last successful delivery.
+	// It is zero if the last delivery was successful.
+	FailingSince time.Time
+}
+
+// deliveryStatus tracks DeliveryStatus for a notifier.
+type deliveryStatus struct {
+	mu sync.Mutex
+	ds DeliveryStatus
+}
+
+func (s *deliveryStatus) update(err error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		s.ds.LastSuccess = now
+		s.ds.FailingSince = time.Time{}
+		return
+	}
+	s.ds.LastError = err.Error()
+	s.ds.LastErrorTime = now
+	if s.ds.FailingSince.IsZero() {
+		s.ds.FailingSince = now
+	}
+}
+
+func (s *deliveryStatus) get() DeliveryStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ds
+}
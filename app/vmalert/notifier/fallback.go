@@ -0,0 +1,140 @@
+package notifier
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+var (
+	fallbackURL = flag.String("notifier.fallbackURL", "", "Optional Alertmanager URL for sending VMAlertNotifierFailing alert "+
+		"when alerts delivery to some of the configured notifiers keeps failing for longer than -notifier.fallbackAfter. "+
+		"The Alertmanager at this URL should route alerts via a channel independent of the configured notifiers. "+
+		"See https://docs.victoriametrics.com/vmalert/#notifier-delivery-monitoring")
+	fallbackAfter = flag.Duration("notifier.fallbackAfter", 5*time.Minute, "The duration of continuous delivery failures to a notifier, "+
+		"after which VMAlertNotifierFailing alert is sent to -notifier.fallbackURL")
+)
+
+const (
+	// fallbackAlertName is the name of the alert sent to -notifier.fallbackURL.
+	fallbackAlertName = "VMAlertNotifierFailing"
+
+	// fallbackResendInterval is the interval for re-sending the firing fallback alert,
+	// so it doesn't expire at Alertmanager while the notifier keeps failing.
+	fallbackResendInterval = time.Minute
+)
+
+// fallback is set only if -notifier.fallbackURL is set.
+var fallback *fallbackNotifier
+
+// fallbackNotifier sends fallbackAlertName alert to nt when delivery to some notifier keeps failing for longer than after.
+type fallbackNotifier struct {
+	nt    Notifier
+	after time.Duration
+
+	mu sync.Mutex
+	// lastSent contains the last time the firing alert was sent per every failing notifier address.
+	lastSent map[string]time.Time
+}
+
+func initFallback() error {
+	if *fallbackURL == "" {
+		return nil
+	}
+	if *blackHole {
+		return fmt.Errorf("-notifier.fallbackURL cannot be used together with -notifier.blackhole")
+	}
+	addr := strings.TrimSuffix(*fallbackURL, "/") + alertManagerPath
+	gen := func(Alert) string {
+		return strings.TrimSuffix(externalURL, "/") + "/vmalert/notifiers"
+	}
+	am, err := NewAlertManager(addr, gen, promauth.HTTPClientConfig{}, nil, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("cannot initialize notifier for -notifier.fallbackURL: %w", err)
+	}
+	fallback = newFallbackNotifier(am, *fallbackAfter)
+	return nil
+}
+
+func newFallbackNotifier(nt Notifier, after time.Duration) *fallbackNotifier {
+	return &fallbackNotifier{
+		nt:       nt,
+		after:    after,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// check sends the firing fallback alert if delivery to am keeps failing for longer than fn.after,
+// and sends the resolved fallback alert after delivery to am recovers.
+func (fn *fallbackNotifier) check(ctx context.Context, am *AlertManager) {
+	if Notifier(am) == fn.nt {
+		return
+	}
+	ds := am.DeliveryStatus()
+	addr := am.Addr()
+	now := time.Now()
+
+	fn.mu.Lock()
+	lastSent, notified := fn.lastSent[addr]
+	var a Alert
+	switch {
+	case ds.FailingSince.IsZero():
+		if !notified {
+			fn.mu.Unlock()
+			return
+		}
+		delete(fn.lastSent, addr)
+		a = newFallbackAlert(addr, ds, now)
+		a.State = StateInactive
+		a.End = now
+	case now.Sub(ds.FailingSince) < fn.after:
+		fn.mu.Unlock()
+		return
+	case notified && now.Sub(lastSent) < fallbackResendInterval:
+		fn.mu.Unlock()
+		return
+	default:
+		fn.lastSent[addr] = now
+		a = newFallbackAlert(addr, ds, now)
+	}
+	fn.mu.Unlock()
+
+	if err := fn.nt.Send(ctx, []Alert{a}, nil); err != nil {
+		logger.Errorf("cannot send %s alert for notifier %q to -notifier.fallbackURL: %s", fallbackAlertName, addr, err)
+	}
+}
+
+func newFallbackAlert(addr string, ds DeliveryStatus, now time.Time) Alert {
+	labels := make(map[string]string, len(externalLabels)+3)
+	for k, v := range externalLabels {
+		labels[k] = v
+	}
+	labels["alertname"] = fallbackAlertName
+	labels["notifier"] = addr
+	labels["severity"] = "critical"
+
+	start := ds.FailingSince
+	if start.IsZero() {
+		start = now
+	}
+	return Alert{
+		Name:   fallbackAlertName,
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("vmalert fails to deliver alerts to notifier %s", addr),
+			"description": fmt.Sprintf("vmalert fails to deliver alerts to notifier %s since %s. The last error: %s",
+				addr, start.Format(time.RFC3339), ds.LastError),
+		},
+		State:    StateFiring,
+		ActiveAt: start,
+		Start:    start,
+		// Make sure the alert doesn't expire at Alertmanager between re-sends.
+		End: now.Add(3 * fallbackResendInterval),
+	}
+}
@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+func TestFallbackNotifier(t *testing.T) {
+	var primaryFailing atomic.Bool
+	primarySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if primaryFailing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer primarySrv.Close()
+
+	type fallbackRequest struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		EndsAt      time.Time         `json:"endsAt"`
+	}
+	reqsCh := make(chan fallbackRequest, 10)
+	fallbackSrv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var reqs []fallbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			t.Errorf("cannot decode fallback request: %s", err)
+			return
+		}
+		for _, req := range reqs {
+			reqsCh <- req
+		}
+	}))
+	defer fallbackSrv.Close()
+
+	primary, err := NewAlertManager(primarySrv.URL, func(Alert) string { return "" }, promauth.HTTPClientConfig{}, nil, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer primary.Close()
+	fallbackAM, err := NewAlertManager(fallbackSrv.URL, func(Alert) string { return "" }, promauth.HTTPClientConfig{}, nil, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer fallbackAM.Close()
+
+	fallback = newFallbackNotifier(fallbackAM, 0)
+	defer func() {
+		fallback = nil
+	}()
+
+	send := func() error {
+		return primary.Send(context.Background(), []Alert{{Name: "foo", Labels: map[string]string{"alertname": "foo"}}}, nil)
+	}
+	expectNoFallbackRequests := func() {
+		t.Helper()
+		select {
+		case req := <-reqsCh:
+			t.Fatalf("unexpected request to fallback notifier: %v", req)
+		default:
+		}
+	}
+	getFallbackRequest := func() fallbackRequest {
+		t.Helper()
+		select {
+		case req := <-reqsCh:
+			return req
+		default:
+			t.Fatalf("missing request to fallback notifier")
+		}
+		return fallbackRequest{}
+	}
+
+	// Successful delivery doesn't trigger the fallback alert.
+	if err := send(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectNoFallbackRequests()
+
+	// Failed delivery triggers the firing fallback alert.
+	primaryFailing.Store(true)
+	if err := send(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	req := getFallbackRequest()
+	if req.Labels["alertname"] != fallbackAlertName || req.Labels["notifier"] != primary.Addr() {
+		t.Fatalf("unexpected labels for the fallback alert: %v", req.Labels)
+	}
+	if !req.EndsAt.After(time.Now()) {
+		t.Fatalf("firing fallback alert must end in the future; got endsAt=%s", req.EndsAt)
+	}
+	ds := primary.DeliveryStatus()
+	if ds.FailingSince.IsZero() || ds.LastError == "" {
+		t.Fatalf("unexpected delivery status: %+v", ds)
+	}
+
+	// The firing fallback alert isn't re-sent until fallbackResendInterval passes.
+	if err := send(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	expectNoFallbackRequests()
+
+	// Successful delivery resolves the fallback alert.
+	primaryFailing.Store(false)
+	if err := send(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req = getFallbackRequest()
+	if req.EndsAt.After(time.Now()) {
+		t.Fatalf("resolved fallback alert mustn't end in the future; got endsAt=%s", req.EndsAt)
+	}
+	ds = primary.DeliveryStatus()
+	if !ds.FailingSince.IsZero() || ds.LastSuccess.IsZero() {
+		t.Fatalf("unexpected delivery status: %+v", ds)
+	}
+
+	// Subsequent successful deliveries don't trigger the fallback alert.
+	if err := send(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectNoFallbackRequests()
+}
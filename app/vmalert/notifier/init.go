@@ -55,6 +55,11 @@ var (
 	oauth2Scopes = flagutil.NewArrayString("notifier.oauth2.scopes", "Optional OAuth2 scopes to use for -notifier.url. Scopes must be delimited by ';'. "+
 		"If multiple args are set, then they are applied independently for the corresponding -notifier.url")
 	sendTimeout = flagutil.NewArrayDuration("notifier.sendTimeout", 10*time.Second, "Timeout when sending alerts to the corresponding -notifier.url")
+
+	maxAlertSize = flagutil.NewBytes("notifier.maxAlertSize", 0, "The maximum size of a single alert sent to notifiers. "+
+		"The size is calculated as the sum of lengths of label and annotation names and values. "+
+		"The longest annotation values are truncated if the alert exceeds the limit, while labels are left untouched. "+
+		"By default, alerts aren't truncated. See https://docs.victoriametrics.com/vmalert/#alert-size-limit")
 )
 
 // cw holds a configWatcher for configPath configuration file
@@ -97,6 +102,9 @@ func Init(gen AlertURLGenerator, extLabels map[string]string, extURL string) (fu
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse external URL: %w", err)
 	}
+	if err := initFallback(); err != nil {
+		return nil, err
+	}
 
 	if *blackHole {
 		if len(*addrs) > 0 || *configPath != "" {
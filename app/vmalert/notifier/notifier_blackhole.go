@@ -30,6 +30,6 @@ func newBlackHoleNotifier() *blackHoleNotifier {
 	address := "blackhole"
 	return &blackHoleNotifier{
 		addr:    address,
-		metrics: newNotifierMetrics(address),
+		metrics: newNotifierMetrics(address, &deliveryStatus{}),
 	}
 }
@@ -43,6 +43,11 @@ type AlertingRule struct {
 	alertsMu sync.RWMutex
 	// stores list of active alerts
 	alerts map[uint64]*notifier.Alert
+	// evaluated is set after the first successful evaluation of the rule.
+	evaluated bool
+	// suppressed contains ActiveAt for alerts, which were active on the first evaluation of the rule,
+	// so notifications aren't sent for them. See markEvaluated.
+	suppressed map[uint64]time.Time
 
 	// state stores recent state changes
 	// during evaluations
@@ -57,6 +62,7 @@ type alertingRuleMetrics struct {
 	active        *vmalertutil.Gauge
 	samples       *vmalertutil.Gauge
 	seriesFetched *vmalertutil.Gauge
+	paused        *vmalertutil.Gauge
 }
 
 func newAlertingRuleMetrics(set *metrics.Set, ar *AlertingRule) *alertingRuleMetrics {
@@ -108,6 +114,13 @@ func newAlertingRuleMetrics(set *metrics.Set, ar *AlertingRule) *alertingRuleMet
 			}
 			return seriesFetched
 		})
+	arm.paused = vmalertutil.NewGauge(set, fmt.Sprintf(`vmalert_alerting_rules_paused{%s}`, labels),
+		func() float64 {
+			if isRulePaused(ar, time.Now()) {
+				return 1
+			}
+			return 0
+		})
 	return arm
 }
 
@@ -120,6 +133,7 @@ func (arm *alertingRuleMetrics) close() {
 	arm.pending.Unregister()
 	arm.samples.Unregister()
 	arm.seriesFetched.Unregister()
+	arm.paused.Unregister()
 }
 
 // NewAlertingRule creates a new AlertingRule
@@ -132,7 +146,7 @@ func NewAlertingRule(qb datasource.QuerierBuilder, group *Group, cfg config.Rule
 		For:           cfg.For.Duration(),
 		KeepFiringFor: cfg.KeepFiringFor.Duration(),
 		Labels:        cfg.Labels,
-		Annotations:   cfg.Annotations,
+		Annotations:   withDashboardURL(cfg.Annotations, cfg.DashboardURL),
 		GroupID:       group.GetID(),
 		GroupName:     group.Name,
 		File:          group.File,
@@ -203,6 +217,135 @@ func (ar *AlertingRule) GetAlert(id uint64) *notifier.Alert {
 	return ar.alerts[id]
 }
 
+// ExportAlerts returns copies of all the alerts of rule, including inactive alerts
+// kept for sending resolve notifications.
+// It is used for transferring the rule state to another vmalert instance.
+func (ar *AlertingRule) ExportAlerts() []notifier.Alert {
+	ar.alertsMu.RLock()
+	defer ar.alertsMu.RUnlock()
+	alerts := make([]notifier.Alert, 0, len(ar.alerts))
+	for _, a := range ar.alerts {
+		alerts = append(alerts, *a)
+	}
+	return alerts
+}
+
+// alertsToSendCount returns the number of alerts, which would be returned by alertsToSend.
+// Isn't concurrent safe.
+func (ar *AlertingRule) alertsToSendCount(resendDelay time.Duration) int {
+	currentTime := time.Now()
+	n := 0
+	for _, a := range ar.alerts {
+		if ar.isSuppressed(a) {
+			continue
+		}
+		if needsSending(a, resendDelay, currentTime) {
+			n++
+		}
+	}
+	return n
+}
+
+// markEvaluated must be called after every successful evaluation of ar.
+//
+// If suppressFirstEval is set, then alerts active on the first evaluation of ar are marked as suppressed,
+// so notifications aren't sent for them until they become active again. The number of suppressed alerts is returned.
+func (ar *AlertingRule) markEvaluated(suppressFirstEval bool) int {
+	ar.alertsMu.Lock()
+	defer ar.alertsMu.Unlock()
+
+	if ar.evaluated {
+		return 0
+	}
+	ar.evaluated = true
+	if !suppressFirstEval {
+		return 0
+	}
+	for id, a := range ar.alerts {
+		if a.State == notifier.StateInactive {
+			continue
+		}
+		if ar.suppressed == nil {
+			ar.suppressed = make(map[uint64]time.Time)
+		}
+		ar.suppressed[id] = a.ActiveAt
+	}
+	return len(ar.suppressed)
+}
+
+// isSuppressed returns true if notifications for a mustn't be sent because a was active on the first evaluation of ar.
+//
+// The suppression is lifted when a becomes active again after being resolved.
+// It is also lifted when a is restored via remote read, since it was active before vmalert restart,
+// so notifications for it were already sent.
+func (ar *AlertingRule) isSuppressed(a *notifier.Alert) bool {
+	activeAt, ok := ar.suppressed[a.ID]
+	if !ok {
+		return false
+	}
+	if a.Restored || !a.ActiveAt.Equal(activeAt) {
+		delete(ar.suppressed, a.ID)
+		return false
+	}
+	return true
+}
+
+// cleanupSuppressed removes the deleted alerts from ar.suppressed.
+func (ar *AlertingRule) cleanupSuppressed() {
+	for id := range ar.suppressed {
+		if _, ok := ar.alerts[id]; !ok {
+			delete(ar.suppressed, id)
+		}
+	}
+}
+
+func needsSending(a *notifier.Alert, resendDelay time.Duration, currentTime time.Time) bool {
+	if a.State == notifier.StatePending {
+		return false
+	}
+	if a.State == notifier.StateFiring && a.End.Before(a.LastSent) {
+		return true
+	}
+	if a.State == notifier.StateInactive && a.ResolvedAt.After(a.LastSent) {
+		return true
+	}
+	return a.LastSent.Add(resendDelay).Before(currentTime)
+}
+
+// ImportAlerts adds the given alerts to the rule state, replacing alerts with the same labels.
+// It is used for taking over the rule state from another vmalert instance,
+// so pending alerts keep their `for` timers and firing alerts remain firing.
+//
+// Imported alerts are marked as restored, so they aren't overridden by restoring via remote read.
+func (ar *AlertingRule) ImportAlerts(alerts []notifier.Alert) {
+	ar.alertsMu.Lock()
+	defer ar.alertsMu.Unlock()
+	for i := range alerts {
+		a := alerts[i]
+		a.GroupID = ar.GroupID
+		a.Name = ar.Name
+		a.Expr = ar.Expr
+		a.For = ar.For
+		if a.Labels == nil {
+			a.Labels = make(map[string]string)
+		}
+		if a.Annotations == nil {
+			a.Annotations = make(map[string]string)
+		}
+		a.ID = hash(a.Labels)
+		a.Restored = true
+		ar.alerts[a.ID] = &a
+		logger.Infof("alert %q (%d) imported in state %s active since %v", a.Name, a.ID, a.State, a.ActiveAt)
+	}
+}
+
+// resetAlerts removes all the alerts of rule.
+func (ar *AlertingRule) resetAlerts() {
+	ar.alertsMu.Lock()
+	ar.alerts = make(map[uint64]*notifier.Alert)
+	ar.alertsMu.Unlock()
+}
+
 func (ar *AlertingRule) logDebugf(at time.Time, a *notifier.Alert, format string, args ...any) {
 	if !ar.Debug {
 		return
@@ -245,6 +388,10 @@ func (ar *AlertingRule) updateWith(r Rule) error {
 	ar.EvalInterval = nr.EvalInterval
 	ar.Debug = nr.Debug
 	ar.q = nr.q
+	if ar.state != nil && nr.state != nil {
+		// the rule remains paused after config reload, since its ID isn't changed
+		nr.state.pause.copyFrom(&ar.state.pause)
+	}
 	ar.state = nr.state
 	return nil
 }
@@ -536,13 +683,15 @@ func (ar *AlertingRule) expandTemplates(m datasource.Metric, qFn templates.Query
 	}
 
 	tplData := notifier.AlertTplData{
-		Value:    m.Values[0],
-		Labels:   ls.origin,
-		Expr:     ar.Expr,
-		AlertID:  hash(ls.processed),
-		GroupID:  ar.GroupID,
-		ActiveAt: ts,
-		For:      ar.For,
+		Value:     m.Values[0],
+		Labels:    ls.origin,
+		Expr:      ar.Expr,
+		AlertID:   hash(ls.processed),
+		GroupID:   ar.GroupID,
+		GroupName: ar.GroupName,
+		AlertName: ar.Name,
+		ActiveAt:  ts,
+		For:       ar.For,
 	}
 	as, err := notifier.ExecTemplate(qFn, ar.Annotations, tplData)
 	if err != nil {
@@ -551,6 +700,24 @@ func (ar *AlertingRule) expandTemplates(m datasource.Metric, qFn templates.Query
 	return ls, as, nil
 }
 
+// withDashboardURL returns a copy of annotations with notifier.DashboardURLAnnotation set to dashboardURL.
+//
+// The annotation explicitly set in annotations has priority over dashboardURL.
+func withDashboardURL(annotations map[string]string, dashboardURL string) map[string]string {
+	if dashboardURL == "" {
+		return annotations
+	}
+	if _, ok := annotations[notifier.DashboardURLAnnotation]; ok {
+		return annotations
+	}
+	as := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		as[k] = v
+	}
+	as[notifier.DashboardURLAnnotation] = dashboardURL
+	return as
+}
+
 // toTimeSeries creates `ALERTS` and `ALERTS_FOR_STATE` for active alerts
 func (ar *AlertingRule) toTimeSeries(timestamp int64) []prompbmarshal.TimeSeries {
 	var tss []prompbmarshal.TimeSeries
@@ -777,22 +944,13 @@ func (ar *AlertingRule) restore(ctx context.Context, q datasource.Querier, ts ti
 // Isn't concurrent safe.
 func (ar *AlertingRule) alertsToSend(resolveDuration, resendDelay time.Duration) []notifier.Alert {
 	currentTime := time.Now()
-	needsSending := func(a *notifier.Alert) bool {
-		if a.State == notifier.StatePending {
-			return false
-		}
-		if a.State == notifier.StateFiring && a.End.Before(a.LastSent) {
-			return true
-		}
-		if a.State == notifier.StateInactive && a.ResolvedAt.After(a.LastSent) {
-			return true
-		}
-		return a.LastSent.Add(resendDelay).Before(currentTime)
-	}
-
 	var alerts []notifier.Alert
+	ar.cleanupSuppressed()
 	for _, a := range ar.alerts {
-		if !needsSending(a) {
+		if ar.isSuppressed(a) {
+			continue
+		}
+		if !needsSending(a, resendDelay, currentTime) {
 			continue
 		}
 		a.End = currentTime.Add(resolveDuration)
@@ -1200,6 +1200,56 @@ func TestAlertingRule_Template(t *testing.T) {
 			},
 		},
 	})
+	f(&AlertingRule{
+		Name:      "DashboardURL",
+		GroupName: "Testing",
+		Annotations: withDashboardURL(nil,
+			`https://grafana.example.com/d/foo?var-group={{ $groupName }}&var-alert={{ $alertName }}&var-instance={{ $labels.instance }}`),
+		alerts: make(map[uint64]*notifier.Alert),
+	}, []datasource.Metric{
+		metricWithValueAndLabels(t, 1, "instance", "foo"),
+	}, map[uint64]*notifier.Alert{
+		hash(map[string]string{alertNameLabel: "DashboardURL", alertGroupNameLabel: "Testing", "instance": "foo"}): {
+			Labels: map[string]string{
+				alertNameLabel:      "DashboardURL",
+				alertGroupNameLabel: "Testing",
+				"instance":          "foo",
+			},
+			Annotations: map[string]string{
+				notifier.DashboardURLAnnotation: "https://grafana.example.com/d/foo?var-group=Testing&var-alert=DashboardURL&var-instance=foo",
+			},
+		},
+	})
+}
+
+func TestNewGroup_DashboardURL(t *testing.T) {
+	g := NewGroup(config.Group{
+		Name:         "test",
+		DashboardURL: "https://grafana.example.com/d/group",
+		Rules: []config.Rule{
+			{Alert: "group", Expr: "up == 0"},
+			{Alert: "rule", Expr: "up == 0", DashboardURL: "https://grafana.example.com/d/rule"},
+			{
+				Alert:       "annotation",
+				Expr:        "up == 0",
+				Annotations: map[string]string{notifier.DashboardURLAnnotation: "https://grafana.example.com/d/annotation"},
+			},
+			{Record: "record", Expr: "up == 0"},
+		},
+	}, &datasource.FakeQuerier{}, time.Second, nil)
+
+	f := func(idx int, dashboardURLExpected string) {
+		t.Helper()
+
+		ar := g.Rules[idx].(*AlertingRule)
+		if dashboardURL := ar.Annotations[notifier.DashboardURLAnnotation]; dashboardURL != dashboardURLExpected {
+			t.Fatalf("unexpected dashboard_url for rule %q; got %q; want %q", ar.Name, dashboardURL, dashboardURLExpected)
+		}
+	}
+
+	f(0, "https://grafana.example.com/d/group")
+	f(1, "https://grafana.example.com/d/rule")
+	f(2, "https://grafana.example.com/d/annotation")
 }
 
 func TestAlertsToSend(t *testing.T) {
@@ -1368,3 +1418,50 @@ func TestAlertingRuleExec_Partial(t *testing.T) {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
+
+func TestAlertingRule_ExportImportAlerts(t *testing.T) {
+	fq := &datasource.FakeQuerier{}
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+
+	ar := newTestAlertingRule("test", 5*time.Minute)
+	ar.q = fq
+
+	ts := time.Now().Truncate(time.Second)
+	if _, err := ar.exec(context.TODO(), ts, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	alerts := ar.ExportAlerts()
+	if len(alerts) != 1 {
+		t.Fatalf("unexpected number of exported alerts; got %d; want 1", len(alerts))
+	}
+	if alerts[0].State != notifier.StatePending {
+		t.Fatalf("unexpected state of exported alert; got %s; want %s", alerts[0].State, notifier.StatePending)
+	}
+
+	// the alert must keep its `for` timer after the import to another rule
+	arNew := newTestAlertingRule("test", 5*time.Minute)
+	arNew.q = fq
+	arNew.ImportAlerts(alerts)
+	a := arNew.GetAlert(alerts[0].ID)
+	if a == nil {
+		t.Fatalf("cannot find imported alert")
+	}
+	if !a.Restored {
+		t.Fatalf("imported alert must be marked as restored")
+	}
+	if !a.ActiveAt.Equal(ts) {
+		t.Fatalf("unexpected ActiveAt of imported alert; got %v; want %v", a.ActiveAt, ts)
+	}
+	if _, err := arNew.exec(context.TODO(), ts.Add(5*time.Minute), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.State != notifier.StateFiring {
+		t.Fatalf("unexpected state of imported alert; got %s; want %s", a.State, notifier.StateFiring)
+	}
+
+	// exported alerts must not share state with the rule
+	alerts[0].State = notifier.StateInactive
+	if a := ar.GetAlert(alerts[0].ID); a.State != notifier.StatePending {
+		t.Fatalf("unexpected state of the original alert; got %s; want %s", a.State, notifier.StatePending)
+	}
+}
@@ -0,0 +1,88 @@
+package rule
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	evalDurationBudget = flag.Duration("rule.evalDurationBudget", 0, "The maximum duration of a single rule evaluation. "+
+		"Rules exceeding this budget for -rule.evalBudgetViolations consecutive evaluations are paused for -rule.evalBudgetPauseDuration. "+
+		"By default, the budget isn't checked. See https://docs.victoriametrics.com/vmalert/#rule-evaluation-budget")
+	evalSeriesFetchedBudget = flag.Int("rule.evalSeriesFetchedBudget", 0, "The maximum number of time series a single rule evaluation may fetch from the datasource. "+
+		"Rules exceeding this budget for -rule.evalBudgetViolations consecutive evaluations are paused for -rule.evalBudgetPauseDuration. "+
+		"The number of fetched series is reported by VictoriaMetrics datasource only. "+
+		"By default, the budget isn't checked. See https://docs.victoriametrics.com/vmalert/#rule-evaluation-budget")
+	evalBudgetViolations = flag.Int("rule.evalBudgetViolations", 3, "The number of consecutive evaluations exceeding -rule.evalDurationBudget or -rule.evalSeriesFetchedBudget "+
+		"after which the rule is paused")
+	evalBudgetPauseDuration = flag.Duration("rule.evalBudgetPauseDuration", time.Hour, "How long to pause the rule after it exceeds -rule.evalDurationBudget or -rule.evalSeriesFetchedBudget. "+
+		"The paused rule can be resumed earlier via /api/v1/admin/rule/resume endpoint. "+
+		"Set to zero for keeping the rule paused until it is resumed via /api/v1/admin/rule/resume endpoint or until vmalert restart")
+)
+
+// check checks whether the evaluation e exceeds the given budget.
+//
+// It pauses the rule and returns true if the budget is exceeded for maxViolations consecutive evaluations.
+func (rp *rulePause) check(e StateEntry, maxDuration time.Duration, maxSeriesFetched, maxViolations int, pauseDuration time.Duration, now time.Time) bool {
+	reason := getBudgetViolation(e, maxDuration, maxSeriesFetched)
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if reason == "" || rp.state.Paused {
+		rp.violations = 0
+		return false
+	}
+	rp.violations++
+	if rp.violations < maxViolations {
+		return false
+	}
+	rp.violations = 0
+	rp.state = PauseState{
+		Paused:  true,
+		Reason:  PauseReasonBudget,
+		Message: fmt.Sprintf("%s for %d consecutive evaluations", reason, maxViolations),
+		Since:   now,
+	}
+	if pauseDuration > 0 {
+		rp.state.Until = now.Add(pauseDuration)
+	}
+	return true
+}
+
+// getBudgetViolation returns non-empty reason if e exceeds the given budget.
+func getBudgetViolation(e StateEntry, maxDuration time.Duration, maxSeriesFetched int) string {
+	if maxDuration > 0 && e.Duration > maxDuration {
+		return fmt.Sprintf("evaluation duration %.3fs exceeds -rule.evalDurationBudget=%s", e.Duration.Seconds(), maxDuration)
+	}
+	if maxSeriesFetched > 0 && e.SeriesFetched != nil && *e.SeriesFetched > maxSeriesFetched {
+		return fmt.Sprintf("the number of fetched series %d exceeds -rule.evalSeriesFetchedBudget=%d", *e.SeriesFetched, maxSeriesFetched)
+	}
+	return ""
+}
+
+// checkEvalBudget pauses r if its last evaluation exceeds -rule.evalDurationBudget or -rule.evalSeriesFetchedBudget
+// for -rule.evalBudgetViolations consecutive evaluations.
+func checkEvalBudget(r Rule) {
+	if *evalDurationBudget <= 0 && *evalSeriesFetchedBudget <= 0 {
+		return
+	}
+	s := getRuleState(r)
+	if s == nil {
+		return
+	}
+	maxViolations := max(*evalBudgetViolations, 1)
+	if !s.pause.check(s.getLast(), *evalDurationBudget, *evalSeriesFetchedBudget, maxViolations, *evalBudgetPauseDuration, time.Now()) {
+		return
+	}
+	rulesPausedTotal.Inc()
+	ps := s.pause.getState(time.Now())
+	if ps.Until.IsZero() {
+		logger.Warnf("rule %q is paused until it is resumed via /api/v1/admin/rule/resume: %s", r, ps.Message)
+	} else {
+		logger.Warnf("rule %q is paused until %s: %s", r, ps.Until.Format(time.RFC3339), ps.Message)
+	}
+}
@@ -0,0 +1,131 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+)
+
+func TestEvalBudgetCheck(t *testing.T) {
+	now := time.Unix(1000, 0)
+	seriesFetched := func(n int) *int {
+		return &n
+	}
+
+	f := func(entries []StateEntry, pauseDuration time.Duration, pausedExpected bool, reasonExpected string) {
+		t.Helper()
+
+		var b rulePause
+		for _, e := range entries {
+			b.check(e, time.Second, 100, 3, pauseDuration, now)
+		}
+		ps := b.getState(now)
+		if ps.Paused != pausedExpected {
+			t.Fatalf("unexpected paused state; got %v; want %v", ps.Paused, pausedExpected)
+		}
+		if !strings.Contains(ps.Message, reasonExpected) {
+			t.Fatalf("missing %q in the pause message %q", reasonExpected, ps.Message)
+		}
+		if !pausedExpected {
+			return
+		}
+		if ps.Reason != PauseReasonBudget {
+			t.Fatalf("unexpected pause reason; got %q; want %q", ps.Reason, PauseReasonBudget)
+		}
+		if pauseDuration == 0 {
+			if !ps.Until.IsZero() {
+				t.Fatalf("expecting zero pause deadline; got %s", ps.Until)
+			}
+			if !b.isPaused(now.Add(24 * time.Hour)) {
+				t.Fatalf("expecting the rule to remain paused")
+			}
+		} else {
+			if !ps.Until.Equal(now.Add(pauseDuration)) {
+				t.Fatalf("unexpected pause deadline; got %s; want %s", ps.Until, now.Add(pauseDuration))
+			}
+			if b.isPaused(now.Add(pauseDuration)) {
+				t.Fatalf("expecting the rule to be resumed after the pause deadline")
+			}
+		}
+	}
+
+	ok := StateEntry{Duration: time.Millisecond, SeriesFetched: seriesFetched(10)}
+	slow := StateEntry{Duration: 2 * time.Second}
+	heavy := StateEntry{Duration: time.Millisecond, SeriesFetched: seriesFetched(1000)}
+
+	// the budget isn't exceeded
+	f([]StateEntry{ok, ok, ok, ok}, time.Hour, false, "")
+
+	// the budget is exceeded not enough times in a row
+	f([]StateEntry{slow, slow, ok, heavy, heavy}, time.Hour, false, "")
+
+	// the budget is exceeded for 3 consecutive evaluations
+	f([]StateEntry{slow, slow, slow}, time.Hour, true, "-rule.evalDurationBudget=1s")
+	f([]StateEntry{ok, heavy, heavy, heavy}, time.Hour, true, "-rule.evalSeriesFetchedBudget=100")
+	f([]StateEntry{slow, heavy, slow}, 0, true, "exceeds -rule.evalDurationBudget")
+}
+
+func TestEvalBudgetResume(t *testing.T) {
+	var b rulePause
+	if b.resume() {
+		t.Fatalf("unexpected resume of not paused rule")
+	}
+	now := time.Now()
+	e := StateEntry{Duration: time.Minute}
+	if !b.check(e, time.Second, 0, 1, 0, now) {
+		t.Fatalf("expecting the rule to be paused")
+	}
+	if !b.resume() {
+		t.Fatalf("expecting the paused rule to be resumed")
+	}
+	if b.isPaused(now) {
+		t.Fatalf("unexpected paused state after resume")
+	}
+}
+
+func TestExecutorSkipsPausedRule(t *testing.T) {
+	origDurationBudget, origViolations := *evalDurationBudget, *evalBudgetViolations
+	*evalDurationBudget = time.Nanosecond
+	*evalBudgetViolations = 2
+	defer func() {
+		*evalDurationBudget = origDurationBudget
+		*evalBudgetViolations = origViolations
+	}()
+
+	fq := &datasource.FakeQuerier{}
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+	r := &RecordingRule{
+		Name:  "test",
+		q:     fq,
+		state: &ruleState{entries: make([]StateEntry, 10)},
+	}
+	e := &executor{}
+
+	execAndCheck := func(pausedExpected bool) {
+		t.Helper()
+		if err := e.exec(context.Background(), r, time.Now(), 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ps := GetPauseState(r); ps.Paused != pausedExpected {
+			t.Fatalf("unexpected paused state; got %v; want %v", ps.Paused, pausedExpected)
+		}
+	}
+
+	execAndCheck(false)
+	execAndCheck(true)
+
+	// the paused rule mustn't be evaluated
+	lastEvaluation := r.state.getLast().Time
+	execAndCheck(true)
+	if !r.state.getLast().Time.Equal(lastEvaluation) {
+		t.Fatalf("unexpected evaluation of the paused rule")
+	}
+
+	if !ResumeRule(r) {
+		t.Fatalf("expecting the paused rule to be resumed")
+	}
+	execAndCheck(false)
+}
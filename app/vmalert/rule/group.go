@@ -15,6 +15,7 @@ import (
 
 	"github.com/VictoriaMetrics/metrics"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/cluster"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
@@ -62,6 +63,13 @@ type Group struct {
 	Params          url.Values
 	Headers         map[string]string
 	NotifierHeaders map[string]string
+	// MuteTimeIntervals and ActiveTimeIntervals contain names of time intervals,
+	// which control when notifications for the group alerts are sent.
+	MuteTimeIntervals   []string
+	ActiveTimeIntervals []string
+	// SuppressFirstEvalNotifications disables notifications for alerts,
+	// which are already active on the first evaluation of the rule.
+	SuppressFirstEvalNotifications bool
 
 	doneCh     chan struct{}
 	finishedCh chan struct{}
@@ -107,18 +115,20 @@ func mergeLabels(groupName, ruleName string, set1, set2 map[string]string) map[s
 // NewGroup returns a new group
 func NewGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval time.Duration, labels map[string]string) *Group {
 	g := &Group{
-		Type:            cfg.Type,
-		Name:            cfg.Name,
-		File:            cfg.File,
-		Interval:        cfg.Interval.Duration(),
-		Limit:           cfg.Limit,
-		Concurrency:     cfg.Concurrency,
-		checksum:        cfg.Checksum,
-		Params:          cfg.Params,
-		Headers:         make(map[string]string),
-		NotifierHeaders: make(map[string]string),
-		Labels:          cfg.Labels,
-		evalAlignment:   cfg.EvalAlignment,
+		Type:                cfg.Type,
+		Name:                cfg.Name,
+		File:                cfg.File,
+		Interval:            cfg.Interval.Duration(),
+		Limit:               cfg.Limit,
+		Concurrency:         cfg.Concurrency,
+		checksum:            cfg.Checksum,
+		Params:              cfg.Params,
+		Headers:             make(map[string]string),
+		NotifierHeaders:     make(map[string]string),
+		Labels:              cfg.Labels,
+		MuteTimeIntervals:   cfg.MuteTimeIntervals,
+		ActiveTimeIntervals: cfg.ActiveTimeIntervals,
+		evalAlignment:       cfg.EvalAlignment,
 
 		doneCh:     make(chan struct{}),
 		finishedCh: make(chan struct{}),
@@ -130,6 +140,9 @@ func NewGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval ti
 	if g.Concurrency < 1 {
 		g.Concurrency = 1
 	}
+	if cfg.SuppressFirstEvalNotifications != nil {
+		g.SuppressFirstEvalNotifications = *cfg.SuppressFirstEvalNotifications
+	}
 	if cfg.EvalOffset != nil {
 		g.EvalOffset = &cfg.EvalOffset.D
 	}
@@ -158,6 +171,10 @@ func NewGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval ti
 		if len(extraLabels) > 0 {
 			r.Labels = mergeLabels(g.Name, r.Name(), extraLabels, r.Labels)
 		}
+		// apply group dashboard_url, rule's dashboard_url has priority
+		if r.Alert != "" && r.DashboardURL == "" {
+			r.DashboardURL = cfg.DashboardURL
+		}
 
 		rules[i] = g.newRule(qb, r)
 	}
@@ -223,6 +240,18 @@ func (g *Group) restore(ctx context.Context, qb datasource.QuerierBuilder, ts ti
 	return nil
 }
 
+// resetAlerts removes active alerts from alerting rules of the group.
+//
+// It is called when the group is taken over by another vmalert instance in cluster mode,
+// so stale alerts aren't shown by the current instance.
+func (g *Group) resetAlerts() {
+	for _, rule := range g.Rules {
+		if ar, ok := rule.(*AlertingRule); ok {
+			ar.resetAlerts()
+		}
+	}
+}
+
 // updateWith updates existing group with
 // passed group object. This function ignores group
 // evaluation interval change. It supposed to be updated
@@ -267,6 +296,9 @@ func (g *Group) updateWith(newGroup *Group) error {
 	g.Params = newGroup.Params
 	g.Headers = newGroup.Headers
 	g.NotifierHeaders = newGroup.NotifierHeaders
+	g.MuteTimeIntervals = newGroup.MuteTimeIntervals
+	g.ActiveTimeIntervals = newGroup.ActiveTimeIntervals
+	g.SuppressFirstEvalNotifications = newGroup.SuppressFirstEvalNotifications
 	g.Labels = newGroup.Labels
 	g.Limit = newGroup.Limit
 	g.checksum = newGroup.checksum
@@ -317,6 +349,14 @@ func (g *Group) Init() {
 		i := g.Interval.Seconds()
 		return i
 	})
+	if cluster.IsEnabled() {
+		g.metrics.set.NewGauge(fmt.Sprintf(`vmalert_group_owned{%s}`, labels), func() float64 {
+			if cluster.IsGroupOwner(g.GetID()) {
+				return 1
+			}
+			return 0
+		})
+	}
 	for i := range g.Rules {
 		g.Rules[i].registerMetrics(g.metrics.set)
 	}
@@ -364,6 +404,11 @@ func (g *Group) Start(ctx context.Context, nts func() []notifier.Notifier, rw re
 		Rw:              rw,
 		Notifiers:       nts,
 		notifierHeaders: g.NotifierHeaders,
+
+		muteTimeIntervals:   g.MuteTimeIntervals,
+		activeTimeIntervals: g.ActiveTimeIntervals,
+
+		suppressFirstEvalNotifications: g.SuppressFirstEvalNotifications,
 	}
 
 	g.infof("started")
@@ -398,20 +443,44 @@ func (g *Group) Start(ctx context.Context, nts func() []notifier.Notifier, rw re
 	g.mu.Unlock()
 	defer g.evalCancel()
 
-	eval(evalCtx, evalTS)
-
-	t := time.NewTicker(g.Interval)
-	defer t.Stop()
+	// isOwner is set to true if the group was evaluated by the current vmalert instance during the last iteration.
+	// The group may be evaluated by another instance in cluster mode.
+	// See https://docs.victoriametrics.com/vmalert/#cluster-mode
+	isOwner := false
+	evalIfOwner := func(evalCtx context.Context, ts time.Time) {
+		if !cluster.IsGroupOwner(g.GetID()) {
+			if isOwner {
+				g.infof("is evaluated by another cluster member; stopping evaluation")
+				g.resetAlerts()
+			}
+			isOwner = false
+			return
+		}
+		if isOwner {
+			eval(evalCtx, ts)
+			return
+		}
+		isOwner = true
+		if cluster.IsEnabled() {
+			g.infof("is evaluated by the current cluster member; starting evaluation")
+		}
+		eval(evalCtx, ts)
 
-	// restore the rules state after the first evaluation
-	// so only active alerts can be restored.
-	if rr != nil {
-		err := g.restore(ctx, rr, evalTS, *remoteReadLookBack)
-		if err != nil {
-			logger.Errorf("error while restoring ruleState for group %q: %s", g.Name, err)
+		// restore the rules state after the first evaluation
+		// so only active alerts can be restored.
+		if rr != nil {
+			err := g.restore(ctx, rr, ts, *remoteReadLookBack)
+			if err != nil {
+				logger.Errorf("error while restoring ruleState for group %q: %s", g.Name, err)
+			}
 		}
 	}
 
+	evalIfOwner(evalCtx, evalTS)
+
+	t := time.NewTicker(g.Interval)
+	defer t.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -438,6 +507,9 @@ func (g *Group) Start(ctx context.Context, nts func() []notifier.Notifier, rw re
 			}
 
 			e.notifierHeaders = g.NotifierHeaders
+			e.muteTimeIntervals = g.MuteTimeIntervals
+			e.activeTimeIntervals = g.ActiveTimeIntervals
+			e.suppressFirstEvalNotifications = g.SuppressFirstEvalNotifications
 			g.mu.Unlock()
 
 			g.infof("re-started")
@@ -453,7 +525,7 @@ func (g *Group) Start(ctx context.Context, nts func() []notifier.Notifier, rw re
 			}
 			evalTS = evalTS.Add((missed + 1) * g.Interval)
 
-			eval(evalCtx, evalTS)
+			evalIfOwner(evalCtx, evalTS)
 		}
 	}
 }
@@ -550,6 +622,9 @@ func (g *Group) ExecOnce(ctx context.Context, nts func() []notifier.Notifier, rw
 		Rw:              rw,
 		Notifiers:       nts,
 		notifierHeaders: g.NotifierHeaders,
+
+		muteTimeIntervals:   g.MuteTimeIntervals,
+		activeTimeIntervals: g.ActiveTimeIntervals,
 	}
 	if len(g.Rules) < 1 {
 		return nil
@@ -627,6 +702,16 @@ type executor struct {
 	Notifiers       func() []notifier.Notifier
 	notifierHeaders map[string]string
 
+	// muteTimeIntervals and activeTimeIntervals contain names of time intervals,
+	// which control when notifications are sent. See config.IsMuted.
+	muteTimeIntervals   []string
+	activeTimeIntervals []string
+
+	// suppressFirstEvalNotifications disables notifications for alerts,
+	// which are already active on the first evaluation of the rule.
+	// See AlertingRule.markEvaluated.
+	suppressFirstEvalNotifications bool
+
 	Rw remotewrite.RWClient
 }
 
@@ -662,15 +747,25 @@ func (e *executor) execConcurrently(ctx context.Context, rules []Rule, ts time.T
 
 var (
 	alertsFired = metrics.NewCounter(`vmalert_alerts_fired_total`)
+	alertsMuted = metrics.NewCounter(`vmalert_alerts_muted_total`)
 
 	execTotal  = metrics.NewCounter(`vmalert_execution_total`)
 	execErrors = metrics.NewCounter(`vmalert_execution_errors_total`)
 )
 
+var alertsFirstEvalSuppressed = metrics.NewCounter(`vmalert_alerts_first_eval_suppressed_total`)
+
 func (e *executor) exec(ctx context.Context, r Rule, ts time.Time, resolveDuration time.Duration, limit int) error {
+	if skipPausedRule(r) {
+		return nil
+	}
+
 	execTotal.Inc()
 
 	tss, err := r.exec(ctx, ts, limit)
+	if !errors.Is(err, context.Canceled) {
+		checkEvalBudget(r)
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			// the context can be cancelled on graceful shutdown
@@ -701,6 +796,20 @@ func (e *executor) exec(ctx context.Context, r Rule, ts time.Time, resolveDurati
 		return nil
 	}
 
+	if n := ar.markEvaluated(e.suppressFirstEvalNotifications); n > 0 {
+		// Alerts, which are already active on the first evaluation after vmalert start or config reload,
+		// may have been notified before. Do not send them in order to avoid notification storms.
+		alertsFirstEvalSuppressed.Add(n)
+	}
+
+	if config.IsMuted(e.muteTimeIntervals, e.activeTimeIntervals, ts) {
+		// Do not send notifications during mute time intervals.
+		// Alerts are sent on the first evaluation after the end of the mute time interval,
+		// since their LastSent isn't updated.
+		alertsMuted.Add(ar.alertsToSendCount(*resendDelay))
+		return nil
+	}
+
 	alerts := ar.alertsToSend(resolveDuration, *resendDelay)
 	if len(alerts) < 1 {
 		return nil
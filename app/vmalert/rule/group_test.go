@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
 	"testing"
 	"time"
@@ -441,6 +442,120 @@ func TestFaultyNotifier(t *testing.T) {
 	t.Fatalf("alive notifier didn't receive notification by %v", deadline)
 }
 
+func TestExecMuteTimeIntervals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "time_intervals.yaml")
+	data := `
+time_intervals:
+- name: always
+  time_intervals:
+  - {}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("cannot write time intervals file: %s", err)
+	}
+	if err := config.LoadTimeIntervals(path); err != nil {
+		t.Fatalf("cannot load time intervals: %s", err)
+	}
+	config.ReloadTimeIntervals()
+
+	fq := &datasource.FakeQuerier{}
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+
+	r := newTestAlertingRule("instant", 0)
+	r.q = fq
+
+	fn := &notifier.FakeNotifier{}
+	e := &executor{
+		Notifiers: func() []notifier.Notifier {
+			return []notifier.Notifier{fn}
+		},
+		muteTimeIntervals: []string{"always"},
+	}
+	mutedBefore := alertsMuted.Get()
+	if err := e.exec(context.Background(), r, time.Now(), 0, 10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := fn.GetCounter(); n != 0 {
+		t.Fatalf("unexpected number of sent alerts during mute time interval; got %d; want 0", n)
+	}
+	if n := alertsMuted.Get() - mutedBefore; n != 1 {
+		t.Fatalf("unexpected number of muted alerts; got %d; want 1", n)
+	}
+
+	// The alert must be sent after the end of the mute time interval.
+	e.muteTimeIntervals = nil
+	e.activeTimeIntervals = []string{"always"}
+	if err := e.exec(context.Background(), r, time.Now(), 0, 10); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := fn.GetCounter(); n != 1 {
+		t.Fatalf("unexpected number of sent alerts during active time interval; got %d; want 1", n)
+	}
+}
+
+func TestExecSuppressFirstEvalNotifications(t *testing.T) {
+	fq := &datasource.FakeQuerier{}
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+
+	r := newTestAlertingRule("instant", 0)
+	r.q = fq
+
+	fn := &notifier.FakeNotifier{}
+	e := &executor{
+		Notifiers: func() []notifier.Notifier {
+			return []notifier.Notifier{fn}
+		},
+		suppressFirstEvalNotifications: true,
+	}
+	ts := time.Now()
+	exec := func(sentExpected int) {
+		t.Helper()
+		ts = ts.Add(time.Second)
+		if err := e.exec(context.Background(), r, ts, 0, 10); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := fn.GetCounter(); n != sentExpected {
+			t.Fatalf("unexpected number of sent alerts; got %d; want %d", n, sentExpected)
+		}
+	}
+
+	// The alert active on the first evaluation mustn't be sent.
+	suppressedBefore := alertsFirstEvalSuppressed.Get()
+	exec(0)
+	if n := alertsFirstEvalSuppressed.Get() - suppressedBefore; n != 1 {
+		t.Fatalf("unexpected number of suppressed alerts; got %d; want 1", n)
+	}
+	exec(0)
+
+	// New alert must be sent immediately.
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "baz"))
+	exec(1)
+	if alerts := fn.GetAlerts(); len(alerts) != 1 || alerts[0].Labels["job"] != "baz" {
+		t.Fatalf("unexpected alerts sent: %v", alerts)
+	}
+
+	// The resolved notification mustn't be sent for the suppressed alert.
+	fq.Reset()
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "baz"))
+	exec(2)
+	if alerts := fn.GetAlerts(); len(alerts) != 1 || alerts[0].Labels["job"] != "baz" {
+		t.Fatalf("unexpected alerts sent: %v", alerts)
+	}
+
+	// The suppressed alert must be sent when it becomes active again.
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+	exec(4)
+
+	// Alerts mustn't be suppressed if the rule was already evaluated before enabling the option.
+	fn = &notifier.FakeNotifier{}
+	r = newTestAlertingRule("instant", 0)
+	r.q = fq
+	e.suppressFirstEvalNotifications = false
+	exec(2)
+	e.suppressFirstEvalNotifications = true
+	exec(4)
+}
+
 func TestFaultyRW(t *testing.T) {
 	fq := &datasource.FakeQuerier{}
 	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
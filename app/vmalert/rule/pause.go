@@ -0,0 +1,255 @@
+package rule
+
+import (
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	rulesPausedTotal   = metrics.NewCounter(`vmalert_rules_paused_total`)
+	rulesResumedTotal  = metrics.NewCounter(`vmalert_rules_resumed_total`)
+	groupsPausedTotal  = metrics.NewCounter(`vmalert_groups_paused_total`)
+	groupsResumedTotal = metrics.NewCounter(`vmalert_groups_resumed_total`)
+	pausedRulesSkipped = metrics.NewCounter(`vmalert_execution_skipped_total{reason="rule_paused"}`)
+)
+
+// PauseReason is the reason why the rule is paused.
+type PauseReason string
+
+const (
+	// PauseReasonBudget means that the rule is paused because of exceeding evaluation budget.
+	PauseReasonBudget PauseReason = "budget"
+
+	// PauseReasonManual means that the rule or its group is paused via admin API.
+	PauseReasonManual PauseReason = "manual"
+)
+
+// PauseState contains information about the paused rule.
+type PauseState struct {
+	// Paused is set to true if the rule is paused
+	Paused bool
+	// Reason is the reason why the rule is paused
+	Reason PauseReason
+	// Message contains human-readable description of the pause
+	Message string
+	// Since is the time when the rule was paused
+	Since time.Time
+	// Until is the time when the rule is automatically resumed.
+	// It is zero if the rule must be resumed via admin API.
+	Until time.Time
+}
+
+// rulePause contains the pause state for a single rule.
+//
+// The rule is paused either automatically because of exceeding evaluation budget or via admin API.
+type rulePause struct {
+	mu sync.Mutex
+
+	// violations is the number of consecutive evaluations exceeding the budget
+	violations int
+
+	// state contains the current pause state
+	state PauseState
+}
+
+// GetPauseState returns pause state for r.
+func GetPauseState(r Rule) PauseState {
+	s := getRuleState(r)
+	if s == nil {
+		return PauseState{}
+	}
+	return s.pause.getState(time.Now())
+}
+
+// PauseRule pauses evaluation of r via admin API until it is explicitly resumed.
+//
+// It returns false if r is already paused via admin API.
+func PauseRule(r Rule, reason string) bool {
+	msg := "the rule is paused via API"
+	if reason != "" {
+		msg += ": " + reason
+	}
+	if !pauseRule(r, msg) {
+		return false
+	}
+	logger.Infof("rule %q is paused via API; reason: %q", r, reason)
+	return true
+}
+
+// ResumeRule resumes r paused either via admin API or because of exceeding evaluation budget.
+//
+// It returns false if r isn't paused.
+func ResumeRule(r Rule) bool {
+	if !resumeRule(r) {
+		return false
+	}
+	logger.Infof("rule %q is resumed via API", r)
+	return true
+}
+
+// PauseGroup pauses evaluation of all the rules in g via admin API until they are explicitly resumed.
+//
+// It returns false if all the rules in g are already paused via admin API.
+func PauseGroup(g *Group, reason string) bool {
+	msg := "the group is paused via API"
+	if reason != "" {
+		msg += ": " + reason
+	}
+	paused := false
+	for _, r := range g.Rules {
+		if pauseRule(r, msg) {
+			paused = true
+		}
+	}
+	if !paused {
+		return false
+	}
+	groupsPausedTotal.Inc()
+	logger.Infof("group %q is paused via API; reason: %q", g.Name, reason)
+	return true
+}
+
+// ResumeGroup resumes all the paused rules in g.
+//
+// It returns false if g has no paused rules.
+func ResumeGroup(g *Group) bool {
+	resumed := false
+	for _, r := range g.Rules {
+		if resumeRule(r) {
+			resumed = true
+		}
+	}
+	if !resumed {
+		return false
+	}
+	groupsResumedTotal.Inc()
+	logger.Infof("group %q is resumed via API", g.Name)
+	return true
+}
+
+func pauseRule(r Rule, msg string) bool {
+	s := getRuleState(r)
+	if s == nil {
+		return false
+	}
+	if !s.pause.pauseManually(msg, time.Now()) {
+		return false
+	}
+	rulesPausedTotal.Inc()
+	return true
+}
+
+func resumeRule(r Rule) bool {
+	s := getRuleState(r)
+	if s == nil {
+		return false
+	}
+	if !s.pause.resume() {
+		return false
+	}
+	rulesResumedTotal.Inc()
+	return true
+}
+
+func getRuleState(r Rule) *ruleState {
+	if rule, ok := r.(*AlertingRule); ok {
+		return rule.state
+	}
+	if rule, ok := r.(*RecordingRule); ok {
+		return rule.state
+	}
+	return nil
+}
+
+// isRulePaused returns true if r is paused at the given time.
+func isRulePaused(r Rule, now time.Time) bool {
+	s := getRuleState(r)
+	return s != nil && s.pause.isPaused(now)
+}
+
+// skipPausedRule returns true if r is paused and mustn't be evaluated.
+func skipPausedRule(r Rule) bool {
+	if !isRulePaused(r, time.Now()) {
+		return false
+	}
+	pausedRulesSkipped.Inc()
+	return true
+}
+
+// isPaused returns true if the rule is paused at the given time.
+//
+// The rule is automatically resumed when its pause expires.
+func (rp *rulePause) isPaused(now time.Time) bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.expireLocked(now)
+	return rp.state.Paused
+}
+
+func (rp *rulePause) getState(now time.Time) PauseState {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.expireLocked(now)
+	return rp.state
+}
+
+// pauseManually pauses the rule until it is explicitly resumed.
+//
+// The manual pause overrides the pause because of exceeding evaluation budget.
+// It returns false if the rule is already paused manually.
+func (rp *rulePause) pauseManually(msg string, now time.Time) bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if rp.state.Paused && rp.state.Reason == PauseReasonManual {
+		return false
+	}
+	rp.violations = 0
+	rp.state = PauseState{
+		Paused:  true,
+		Reason:  PauseReasonManual,
+		Message: msg,
+		Since:   now,
+	}
+	return true
+}
+
+func (rp *rulePause) resume() bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if !rp.state.Paused {
+		return false
+	}
+	rp.resetLocked()
+	return true
+}
+
+// copyFrom copies the pause state from src to rp.
+func (rp *rulePause) copyFrom(src *rulePause) {
+	src.mu.Lock()
+	violations, state := src.violations, src.state
+	src.mu.Unlock()
+
+	rp.mu.Lock()
+	rp.violations = violations
+	rp.state = state
+	rp.mu.Unlock()
+}
+
+func (rp *rulePause) expireLocked(now time.Time) {
+	if rp.state.Paused && !rp.state.Until.IsZero() && !now.Before(rp.state.Until) {
+		rp.resetLocked()
+	}
+}
+
+func (rp *rulePause) resetLocked() {
+	rp.violations = 0
+	rp.state = PauseState{}
+}
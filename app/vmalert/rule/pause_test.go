@@ -0,0 +1,175 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+)
+
+func TestRulePause(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	f := func(rp *rulePause, reasonExpected PauseReason, messageExpected string) {
+		t.Helper()
+
+		ps := rp.getState(now)
+		if ps.Paused != (reasonExpected != "") {
+			t.Fatalf("unexpected paused state; got %v; want %v", ps.Paused, reasonExpected != "")
+		}
+		if ps.Reason != reasonExpected {
+			t.Fatalf("unexpected pause reason; got %q; want %q", ps.Reason, reasonExpected)
+		}
+		if ps.Message != messageExpected {
+			t.Fatalf("unexpected pause message; got %q; want %q", ps.Message, messageExpected)
+		}
+	}
+
+	var rp rulePause
+	f(&rp, "", "")
+
+	if !rp.pauseManually("noisy", now) {
+		t.Fatalf("expecting the rule to be paused")
+	}
+	if rp.pauseManually("noisy again", now) {
+		t.Fatalf("unexpected pause of already paused rule")
+	}
+	f(&rp, PauseReasonManual, "noisy")
+
+	// the manually paused rule mustn't be paused because of exceeding evaluation budget
+	e := StateEntry{Duration: time.Minute}
+	if rp.check(e, time.Second, 0, 1, time.Hour, now) {
+		t.Fatalf("unexpected pause because of exceeding evaluation budget")
+	}
+	f(&rp, PauseReasonManual, "noisy")
+
+	if !rp.resume() {
+		t.Fatalf("expecting the rule to be resumed")
+	}
+	if rp.resume() {
+		t.Fatalf("unexpected resume of not paused rule")
+	}
+	f(&rp, "", "")
+
+	// the manual pause overrides the pause because of exceeding evaluation budget
+	if !rp.check(e, time.Second, 0, 1, time.Hour, now) {
+		t.Fatalf("expecting the rule to be paused because of exceeding evaluation budget")
+	}
+	if !rp.pauseManually("maintenance", now) {
+		t.Fatalf("expecting the rule to be paused manually")
+	}
+	f(&rp, PauseReasonManual, "maintenance")
+	if !rp.getState(now).Until.IsZero() {
+		t.Fatalf("expecting zero pause deadline for the manually paused rule")
+	}
+
+	// the pause state is preserved when copied to the updated rule
+	var rpNew rulePause
+	rpNew.copyFrom(&rp)
+	f(&rpNew, PauseReasonManual, "maintenance")
+}
+
+func TestExecutorSkipsManuallyPausedRule(t *testing.T) {
+	fq := &datasource.FakeQuerier{}
+	fq.Add(metricWithValueAndLabels(t, 1, "__name__", "foo", "job", "bar"))
+	r := &RecordingRule{
+		Name:    "test",
+		RuleID:  123,
+		GroupID: 456,
+		q:       fq,
+		state:   &ruleState{entries: make([]StateEntry, 10)},
+	}
+	g := &Group{
+		Name:  "group",
+		Rules: []Rule{r},
+	}
+	e := &executor{}
+
+	execAndCheck := func(evaluatedExpected bool) {
+		t.Helper()
+		lastEvaluation := r.state.getLast().Time
+		time.Sleep(time.Millisecond)
+		if err := e.exec(context.Background(), r, time.Now(), 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		evaluated := !r.state.getLast().Time.Equal(lastEvaluation)
+		if evaluated != evaluatedExpected {
+			t.Fatalf("unexpected evaluation; got %v; want %v", evaluated, evaluatedExpected)
+		}
+	}
+
+	execAndCheck(true)
+
+	if !PauseRule(r, "noisy") {
+		t.Fatalf("expecting the rule to be paused")
+	}
+	ps := GetPauseState(r)
+	if !ps.Paused || ps.Reason != PauseReasonManual || !strings.Contains(ps.Message, "noisy") {
+		t.Fatalf("unexpected pause state: %+v", ps)
+	}
+	execAndCheck(false)
+
+	if PauseGroup(g, "") {
+		t.Fatalf("unexpected pause of the group with already paused rules")
+	}
+	if !ResumeGroup(g) {
+		t.Fatalf("expecting the group to be resumed")
+	}
+	if ps := GetPauseState(r); ps.Paused {
+		t.Fatalf("unexpected pause state after resume: %+v", ps)
+	}
+	execAndCheck(true)
+
+	if !PauseGroup(g, "maintenance") {
+		t.Fatalf("expecting the group to be paused")
+	}
+	if ps := GetPauseState(r); !strings.Contains(ps.Message, "the group is paused via API: maintenance") {
+		t.Fatalf("unexpected pause state: %+v", ps)
+	}
+	execAndCheck(false)
+
+	if !ResumeRule(r) {
+		t.Fatalf("expecting the rule to be resumed")
+	}
+	if ResumeGroup(g) {
+		t.Fatalf("unexpected resume of the group without paused rules")
+	}
+	execAndCheck(true)
+}
+
+func TestPauseSurvivesGroupUpdate(t *testing.T) {
+	qb := &datasource.FakeQuerier{}
+	rules := []config.Rule{
+		{Alert: "alert", Expr: "up == 0"},
+		{Record: "record", Expr: "sum(up)"},
+	}
+	newGroup := func() *Group {
+		g := &Group{
+			Name:    "test",
+			metrics: &groupMetrics{set: metrics.NewSet()},
+		}
+		for _, r := range rules {
+			r.ID = config.HashRule(r)
+			g.Rules = append(g.Rules, g.newRule(qb, r))
+		}
+		return g
+	}
+
+	g := newGroup()
+	if !PauseGroup(g, "maintenance") {
+		t.Fatalf("expecting the group to be paused")
+	}
+	if err := g.updateWith(newGroup()); err != nil {
+		t.Fatalf("cannot update group: %s", err)
+	}
+	for _, r := range g.Rules {
+		if ps := GetPauseState(r); !ps.Paused || ps.Reason != PauseReasonManual {
+			t.Fatalf("expecting rule %q to remain paused after the group update; got %+v", r, ps)
+		}
+	}
+}
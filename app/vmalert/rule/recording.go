@@ -46,6 +46,7 @@ type RecordingRule struct {
 type recordingRuleMetrics struct {
 	errors  *vmalertutil.Counter
 	samples *vmalertutil.Gauge
+	paused  *vmalertutil.Gauge
 }
 
 func newRecordingRuleMetrics(set *metrics.Set, rr *RecordingRule) *recordingRuleMetrics {
@@ -58,6 +59,13 @@ func newRecordingRuleMetrics(set *metrics.Set, rr *RecordingRule) *recordingRule
 			e := rr.state.getLast()
 			return float64(e.Samples)
 		})
+	rmr.paused = vmalertutil.NewGauge(set, fmt.Sprintf(`vmalert_recording_rules_paused{%s}`, labels),
+		func() float64 {
+			if isRulePaused(rr, time.Now()) {
+				return 1
+			}
+			return 0
+		})
 
 	return rmr
 }
@@ -68,6 +76,7 @@ func (m *recordingRuleMetrics) close() {
 	}
 	m.errors.Unregister()
 	m.samples.Unregister()
+	m.paused.Unregister()
 }
 
 // String implements Stringer interface
@@ -41,6 +41,10 @@ type ruleState struct {
 	sync.RWMutex
 	entries []StateEntry
 	cur     int
+
+	// pause contains the pause state of the rule and tracks evaluations exceeding
+	// -rule.evalDurationBudget and -rule.evalSeriesFetchedBudget
+	pause rulePause
 }
 
 // StateEntry stores rule's execution states
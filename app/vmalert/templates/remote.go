@@ -0,0 +1,203 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+var (
+	remoteMaxSize = flagutil.NewBytes("rule.templates.maxSize", 10*1024*1024, "The maximum size of templates, which can be fetched from http and https urls at -rule.templates")
+	remoteHeaders = flag.String("rule.templates.headers", "", "Optional HTTP headers to send with each request to http and https urls at -rule.templates. "+
+		"For example, -rule.templates.headers='My-Auth:foobar' would send 'My-Auth: foobar' HTTP header with every request. "+
+		"Multiple headers must be delimited by '^^': -rule.templates.headers='header1:value1^^header2:value2'")
+	remoteBasicAuthUsername = flag.String("rule.templates.basicAuth.username", "", "Optional basic auth username for http and https urls at -rule.templates")
+	remoteBasicAuthPassword = flagutil.NewPassword("rule.templates.basicAuth.password", "Optional basic auth password for http and https urls at -rule.templates")
+	remoteBearerToken       = flagutil.NewPassword("rule.templates.bearerToken", "Optional bearer auth token for http and https urls at -rule.templates")
+	remoteProxyURL          = flag.String("rule.templates.proxyURL", "", "Optional proxy URL for fetching templates from http and https urls at -rule.templates. "+
+		"For example, -rule.templates.proxyURL=http://proxy:1234")
+
+	remoteTLSInsecureSkipVerify = flag.Bool("rule.templates.tlsInsecureSkipVerify", false, "Whether to skip tls verification when fetching templates from https urls at -rule.templates")
+	remoteTLSCertFile           = flag.String("rule.templates.tlsCertFile", "", "Optional path to client-side TLS certificate file to use when fetching templates from https urls at -rule.templates")
+	remoteTLSKeyFile            = flag.String("rule.templates.tlsKeyFile", "", "Optional path to client-side TLS certificate key to use when fetching templates from https urls at -rule.templates")
+	remoteTLSCAFile             = flag.String("rule.templates.tlsCAFile", "", "Optional path to TLS CA file to use for verifying connections to https urls at -rule.templates. "+
+		"By default, system CA is used")
+	remoteTLSServerName = flag.String("rule.templates.tlsServerName", "", "Optional TLS server name to use for connections to https urls at -rule.templates. "+
+		"By default, the server name from the url is used")
+)
+
+// isRemotePath returns true if path points to templates served via http or https.
+func isRemotePath(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// remoteTemplate is a cached copy of templates fetched from remote url.
+type remoteTemplate struct {
+	// etag is the ETag header value returned by the server for data.
+	etag string
+
+	// data is the fetched templates
+	data []byte
+}
+
+var (
+	remoteTemplatesLock sync.Mutex
+	remoteTemplates     = make(map[string]*remoteTemplate)
+)
+
+var (
+	// remoteTemplatesClient and remoteTemplatesAuthConfig are initialized on the first call to readRemote
+	// under remoteTemplatesLock, since command-line flags must be parsed before their initialization.
+	remoteTemplatesClient     *http.Client
+	remoteTemplatesAuthConfig *promauth.Config
+)
+
+func initRemoteTemplatesClient() error {
+	opts := &promauth.Options{
+		BearerToken: remoteBearerToken.Get(),
+		TLSConfig: &promauth.TLSConfig{
+			CAFile:             *remoteTLSCAFile,
+			CertFile:           *remoteTLSCertFile,
+			KeyFile:            *remoteTLSKeyFile,
+			ServerName:         *remoteTLSServerName,
+			InsecureSkipVerify: *remoteTLSInsecureSkipVerify,
+		},
+	}
+	if *remoteBasicAuthUsername != "" || remoteBasicAuthPassword.Get() != "" {
+		opts.BasicAuth = &promauth.BasicAuthConfig{
+			Username: *remoteBasicAuthUsername,
+			Password: promauth.NewSecret(remoteBasicAuthPassword.Get()),
+		}
+	}
+	if *remoteHeaders != "" {
+		opts.Headers = strings.Split(*remoteHeaders, "^^")
+	}
+	ac, err := opts.NewConfig()
+	if err != nil {
+		return fmt.Errorf("cannot initialize auth config for fetching templates: %w", err)
+	}
+
+	tr := httputil.NewTransport(false, "vmalert_templates")
+	if *remoteProxyURL != "" {
+		pu, err := url.Parse(*remoteProxyURL)
+		if err != nil {
+			return fmt.Errorf("cannot parse -rule.templates.proxyURL=%q: %w", *remoteProxyURL, err)
+		}
+		tr.Proxy = http.ProxyURL(pu)
+	}
+	remoteTemplatesClient = &http.Client{
+		Transport: ac.NewRoundTripper(tr),
+		Timeout:   30 * time.Second,
+	}
+	remoteTemplatesAuthConfig = ac
+	return nil
+}
+
+// readRemote returns templates from the given path, which must start with http:// or https://
+//
+// The path may end with #sha256=<hex> suffix. In this case the fetched templates must have the given sha256 checksum.
+//
+// Templates are cached between calls. The cached templates are re-validated via If-None-Match request header
+// if the server returned ETag header. The cached templates are returned if the server is unavailable.
+func readRemote(path string) ([]byte, error) {
+	u, expectedChecksum, err := parseRemotePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteTemplatesLock.Lock()
+	defer remoteTemplatesLock.Unlock()
+
+	if remoteTemplatesClient == nil {
+		if err := initRemoteTemplatesClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	rt := remoteTemplates[path]
+	rtNew, err := fetchRemote(u, expectedChecksum, rt)
+	if err != nil {
+		if rt == nil {
+			return nil, err
+		}
+		logger.Warnf("using the previously fetched templates from %q because of error: %s", u, err)
+		return rt.data, nil
+	}
+	remoteTemplates[path] = rtNew
+	return rtNew.data, nil
+}
+
+func fetchRemote(u, expectedChecksum string, rt *remoteTemplate) (*remoteTemplate, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request for %q: %w", u, err)
+	}
+	if err := remoteTemplatesAuthConfig.SetHeaders(req, true); err != nil {
+		return nil, fmt.Errorf("cannot set request headers for %q: %w", u, err)
+	}
+	if rt != nil && rt.etag != "" {
+		req.Header.Set("If-None-Match", rt.etag)
+	}
+	resp, err := remoteTemplatesClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch templates from %q: %w", u, err)
+	}
+	maxSize := remoteMaxSize.N
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	_ = resp.Body.Close()
+	if rt != nil && resp.StatusCode == http.StatusNotModified {
+		return rt, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(data) > 4*1024 {
+			data = data[:4*1024]
+		}
+		return nil, fmt.Errorf("unexpected status code when fetching templates from %q: %d, expecting %d; response: %q",
+			u, resp.StatusCode, http.StatusOK, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read templates from %q: %w", u, err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("too big templates at %q; they mustn't exceed -rule.templates.maxSize=%d bytes", u, maxSize)
+	}
+	if expectedChecksum != "" {
+		checksum := sha256.Sum256(data)
+		if s := hex.EncodeToString(checksum[:]); s != expectedChecksum {
+			return nil, fmt.Errorf("unexpected sha256 checksum for templates fetched from %q; got %s; want %s", u, s, expectedChecksum)
+		}
+	}
+	rtNew := &remoteTemplate{
+		etag: resp.Header.Get("ETag"),
+		data: data,
+	}
+	return rtNew, nil
+}
+
+func parseRemotePath(path string) (string, string, error) {
+	u, fragment, ok := strings.Cut(path, "#")
+	if !ok {
+		return u, "", nil
+	}
+	checksum, ok := strings.CutPrefix(fragment, "sha256=")
+	if !ok {
+		return "", "", fmt.Errorf("unsupported suffix %q in %q; only #sha256=<hex> suffix is supported", "#"+fragment, path)
+	}
+	checksum = strings.ToLower(checksum)
+	if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+		return "", "", fmt.Errorf("invalid sha256 checksum %q in %q; it must contain %d hex chars", checksum, path, 2*sha256.Size)
+	}
+	return u, checksum, nil
+}
@@ -0,0 +1,219 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadRemote(t *testing.T) {
+	const data = `{{ define "remote.test" }}Hello{{ end }}`
+	var requests, notModified atomic.Int64
+	var unavailable atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if unavailable.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	path := srv.URL + "/templates.tpl"
+	defer func() {
+		remoteTemplatesLock.Lock()
+		delete(remoteTemplates, path)
+		remoteTemplatesLock.Unlock()
+	}()
+
+	readAndCheck := func() {
+		t.Helper()
+		result, err := readRemote(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != data {
+			t.Fatalf("unexpected data; got %q; want %q", result, data)
+		}
+	}
+
+	// the first read fetches the data
+	readAndCheck()
+	if n := notModified.Load(); n != 0 {
+		t.Fatalf("unexpected number of not modified responses; got %d; want 0", n)
+	}
+
+	// the subsequent read must re-validate the cached data via ETag
+	readAndCheck()
+	if n := notModified.Load(); n != 1 {
+		t.Fatalf("unexpected number of not modified responses; got %d; want 1", n)
+	}
+
+	// the cached data must be returned if the server is unavailable
+	unavailable.Store(true)
+	readAndCheck()
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("unexpected number of requests; got %d; want 3", n)
+	}
+
+	// the error must be returned if there is no cached data
+	if _, err := readRemote(srv.URL + "/missing.tpl"); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestReadRemoteChecksum(t *testing.T) {
+	const data = `{{ define "remote.test" }}Hello{{ end }}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	checksum := sha256.Sum256([]byte(data))
+	checksumHex := hex.EncodeToString(checksum[:])
+
+	// valid checksum
+	path := srv.URL + "/templates.tpl#sha256=" + strings.ToUpper(checksumHex)
+	result, err := readRemote(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(result) != data {
+		t.Fatalf("unexpected data; got %q; want %q", result, data)
+	}
+
+	f := func(path, errExpected string) {
+		t.Helper()
+
+		_, err := readRemote(path)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("missing %q in the error %q", errExpected, err)
+		}
+	}
+
+	// checksum mismatch
+	f(srv.URL+"/other.tpl#sha256="+strings.Repeat("0", 64), "unexpected sha256 checksum")
+
+	// invalid checksum
+	f(srv.URL+"/other.tpl#sha256=foo", "invalid sha256 checksum")
+
+	// unsupported suffix
+	f(srv.URL+"/other.tpl#md5=foo", "unsupported suffix")
+}
+
+func TestTemplatesLoadRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{{ define "remote.test" }}{{ printf "Hello %s!" externalURL }}{{ end }}`))
+	}))
+	defer srv.Close()
+
+	masterTmplOrig := masterTmpl
+	defer func() {
+		masterTmpl = masterTmplOrig
+	}()
+
+	if err := Load([]string{srv.URL + "/templates.tpl", "templates/test/good0-*.tpl"}, url.URL{}); err != nil {
+		t.Fatalf("cannot load templates: %s", err)
+	}
+	Reload()
+
+	for _, name := range []string{"remote.test", "test.0"} {
+		if masterTmpl.current.Lookup(name) == nil {
+			t.Fatalf("missing %q template", name)
+		}
+	}
+}
+
+func TestReadRemoteMaxSize(t *testing.T) {
+	data := strings.Repeat("x", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	maxSizeOrig := remoteMaxSize.N
+	defer func() {
+		remoteMaxSize.N = maxSizeOrig
+	}()
+
+	path := srv.URL + "/templates.tpl"
+	defer func() {
+		remoteTemplatesLock.Lock()
+		delete(remoteTemplates, path)
+		remoteTemplatesLock.Unlock()
+	}()
+
+	remoteMaxSize.N = int64(len(data)) - 1
+	if _, err := readRemote(path); err == nil {
+		t.Fatalf("expecting non-nil error for too big templates")
+	}
+
+	remoteMaxSize.N = int64(len(data))
+	result, err := readRemote(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(result) != data {
+		t.Fatalf("unexpected data; got %q; want %q", result, data)
+	}
+}
+
+func TestReadRemoteAuth(t *testing.T) {
+	const data = `{{ define "remote.test" }}Hello{{ end }}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer foo" || r.Header.Get("X-Foo") != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer srv.Close()
+
+	bearerTokenOrig := remoteBearerToken.Get()
+	headersOrig := *remoteHeaders
+	defer func() {
+		if err := remoteBearerToken.Set(bearerTokenOrig); err != nil {
+			t.Fatalf("cannot restore bearer token: %s", err)
+		}
+		*remoteHeaders = headersOrig
+		remoteTemplatesLock.Lock()
+		remoteTemplatesClient = nil
+		remoteTemplatesLock.Unlock()
+	}()
+	if err := remoteBearerToken.Set("foo"); err != nil {
+		t.Fatalf("cannot set bearer token: %s", err)
+	}
+	*remoteHeaders = "X-Foo:bar"
+	remoteTemplatesLock.Lock()
+	remoteTemplatesClient = nil
+	remoteTemplatesLock.Unlock()
+
+	path := srv.URL + "/templates.tpl"
+	defer func() {
+		remoteTemplatesLock.Lock()
+		delete(remoteTemplates, path)
+		remoteTemplatesLock.Unlock()
+	}()
+	result, err := readRemote(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(result) != data {
+		t.Fatalf("unexpected data; got %q; want %q", result, data)
+	}
+}
@@ -56,9 +56,22 @@ func newTemplate() *textTpl.Template {
 // Load func loads templates from multiple globs specified in pathPatterns and either
 // sets them directly to current template if it's the first init;
 // or sets replacement templates and wait for Reload() to replace current template with replacement.
+//
+// pathPatterns may contain http and https urls. Templates fetched from urls are cached
+// and re-validated via ETag on subsequent Load calls. See readRemote for details.
 func Load(pathPatterns []string, externalURL url.URL) error {
 	tmpl := newTemplate()
 	for _, tp := range pathPatterns {
+		if isRemotePath(tp) {
+			data, err := readRemote(tp)
+			if err != nil {
+				return err
+			}
+			if _, err := tmpl.New(tp).Parse(string(data)); err != nil {
+				return fmt.Errorf("failed to parse templates from %q: %w", tp, err)
+			}
+			continue
+		}
 		p, err := doublestar.FilepathGlob(tp)
 		if err != nil {
 			return fmt.Errorf("failed to retrieve a template glob %q: %w", tp, err)
@@ -17,9 +17,19 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
 )
 
-var reloadAuthKey = flagutil.NewPassword("reloadAuthKey", "Auth key for /-/reload http endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*")
+var (
+	reloadAuthKey      = flagutil.NewPassword("reloadAuthKey", "Auth key for /-/reload http endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*")
+	stateImportAuthKey = flagutil.NewPassword("stateImportAuthKey", "Auth key for /api/v1/state/import http endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*. "+
+		"See https://docs.victoriametrics.com/vmalert/#alerts-state-transfer")
+	testAlertAuthKey = flagutil.NewPassword("testAlertAuthKey", "Auth key for /api/v1/test_alert http endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*. "+
+		"See https://docs.victoriametrics.com/vmalert/#test-alerts")
+	adminAuthKey = flagutil.NewPassword("adminAuthKey", "Auth key for /api/v1/admin/* http endpoints, which pause and resume rules and groups. It must be passed via authKey query arg. It overrides -httpAuth.*. "+
+		"See https://docs.victoriametrics.com/vmalert/#pausing-rules-and-groups")
+)
 
 var (
 	apiLinks = [][2]string{
@@ -28,6 +38,9 @@ var (
 		{"api/v1/rules", "list all loaded groups and rules"},
 		{"api/v1/alerts", "list all active alerts"},
 		{fmt.Sprintf("api/v1/alert?%s=<int>&%s=<int>", paramGroupID, paramAlertID), "get alert status by group and alert ID"},
+		{"api/v1/state/export", "export alerts state for importing it into another vmalert instance"},
+		{"api/v1/admin/paused", "list paused rules"},
+		{"api/v1/admin/openapi.yaml", "OpenAPI specification for admin API"},
 	}
 	systemLinks = [][2]string{
 		{"flags", "command-line flags"},
@@ -52,6 +65,9 @@ var (
 	staticFiles   embed.FS
 	staticHandler = http.FileServer(http.FS(staticFiles))
 	staticServer  = http.StripPrefix("/vmalert", staticHandler)
+
+	//go:embed openapi.yaml
+	adminAPISpec []byte
 )
 
 func (rh *requestHandler) handler(w http.ResponseWriter, r *http.Request) bool {
@@ -96,6 +112,9 @@ func (rh *requestHandler) handler(w http.ResponseWriter, r *http.Request) bool {
 	case "/vmalert/notifiers":
 		WriteListTargets(w, r, notifier.GetTargets())
 		return true
+	case "/vmalert/alert-relabel-debug":
+		rh.writeAlertRelabelDebug(w, r)
+		return true
 
 	// special cases for Grafana requests,
 	// served without `vmalert` prefix:
@@ -166,6 +185,130 @@ func (rh *requestHandler) handler(w http.ResponseWriter, r *http.Request) bool {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(data)
 		return true
+	case "/vmalert/api/v1/admin/rule/pause", "/api/v1/admin/rule/pause",
+		"/vmalert/api/v1/admin/rule/resume", "/api/v1/admin/rule/resume",
+		"/vmalert/api/v1/admin/group/pause", "/api/v1/admin/group/pause",
+		"/vmalert/api/v1/admin/group/resume", "/api/v1/admin/group/resume":
+		if !httpserver.CheckAuthFlag(w, r, adminAuthKey) {
+			return true
+		}
+		if r.Method != http.MethodPost {
+			httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+			return true
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/vmalert")
+		action := "paused"
+		if strings.HasSuffix(path, "/resume") {
+			action = "resumed"
+		}
+		ok, err := rh.pauseOrResume(r, path)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{%q:%t}}`, action, ok)
+		return true
+	case "/vmalert/api/v1/admin/paused", "/api/v1/admin/paused":
+		if !httpserver.CheckAuthFlag(w, r, adminAuthKey) {
+			return true
+		}
+		var resp struct {
+			Status string `json:"status"`
+			Data   struct {
+				Paused []apiPausedRule `json:"paused"`
+			} `json:"data"`
+		}
+		resp.Status = "success"
+		resp.Data.Paused = rh.m.pausedRulesAPI()
+		data, err := json.Marshal(resp)
+		if err != nil {
+			httpserver.Errorf(w, r, "failed to marshal paused rules: %s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return true
+	case "/vmalert/api/v1/admin/openapi.yaml", "/api/v1/admin/openapi.yaml":
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(adminAPISpec)
+		return true
+	case "/vmalert/api/v1/state/export", "/api/v1/state/export":
+		var sr stateResponse
+		sr.Status = "success"
+		sr.Data.Rules = rh.m.exportState()
+		data, err := json.Marshal(sr)
+		if err != nil {
+			httpserver.Errorf(w, r, "failed to marshal alerts state: %s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return true
+	case "/vmalert/api/v1/state/import", "/api/v1/state/import":
+		if !httpserver.CheckAuthFlag(w, r, stateImportAuthKey) {
+			return true
+		}
+		if r.Method != http.MethodPost {
+			httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+			return true
+		}
+		var sr stateResponse
+		if err := json.NewDecoder(r.Body).Decode(&sr); err != nil {
+			httpserver.Errorf(w, r, "cannot parse alerts state: %s", err)
+			return true
+		}
+		imported, skipped, err := rh.m.importState(sr.Data.Rules)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot import alerts state: %s", err)
+			return true
+		}
+		logger.Infof("imported %d alerts via %s; skipped %d unknown rules", imported, r.URL.Path, skipped)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"importedAlerts":%d,"skippedRules":%d}}`, imported, skipped)
+		return true
+	case "/vmalert/api/v1/test_alert", "/api/v1/test_alert":
+		if !httpserver.CheckAuthFlag(w, r, testAlertAuthKey) {
+			return true
+		}
+		if r.Method != http.MethodPost {
+			httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+			return true
+		}
+		var tr testAlertRequest
+		if err := json.NewDecoder(r.Body).Decode(&tr); err != nil {
+			httpserver.Errorf(w, r, "cannot parse test alert: %s", err)
+			return true
+		}
+		dryRun := httputil.GetBool(r, "dry_run")
+		results, err := rh.m.sendTestAlert(r.Context(), &tr, dryRun)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot send test alert: %s", err)
+			return true
+		}
+		var resp testAlertResponse
+		resp.Status = "success"
+		resp.Data.Notifiers = results
+		for _, res := range results {
+			if res.Error != "" {
+				resp.Status = "error"
+				break
+			}
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			httpserver.Errorf(w, r, "failed to marshal test alert response: %s", err)
+			return true
+		}
+		if !dryRun {
+			logger.Infof("sent test alert via %s to %d notifiers; status: %s", r.URL.Path, len(results), resp.Status)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "success" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		w.Write(data)
+		return true
 	case "/-/reload":
 		if !httpserver.CheckAuthFlag(w, r, reloadAuthKey) {
 			return true
@@ -196,6 +339,44 @@ func (rh *requestHandler) getRule(r *http.Request) (apiRule, error) {
 	return obj, nil
 }
 
+// pauseOrResume pauses or resumes the rule or the group according to the given admin API path.
+//
+// It returns false if the rule or the group is already in the requested state.
+func (rh *requestHandler) pauseOrResume(r *http.Request, path string) (bool, error) {
+	groupID, err := strconv.ParseUint(r.FormValue(paramGroupID), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %q param: %w", paramGroupID, err)
+	}
+	reason := r.FormValue(paramReason)
+	if strings.HasPrefix(path, "/api/v1/admin/group/") {
+		var ok bool
+		if path == "/api/v1/admin/group/resume" {
+			ok, err = rh.m.resumeGroup(groupID)
+		} else {
+			ok, err = rh.m.pauseGroup(groupID, reason)
+		}
+		if err != nil {
+			return false, errResponse(err, http.StatusNotFound)
+		}
+		return ok, nil
+	}
+
+	ruleID, err := strconv.ParseUint(r.FormValue(paramRuleID), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %q param: %w", paramRuleID, err)
+	}
+	var ok bool
+	if path == "/api/v1/admin/rule/resume" {
+		ok, err = rh.m.resumeRule(groupID, ruleID)
+	} else {
+		ok, err = rh.m.pauseRule(groupID, ruleID, reason)
+	}
+	if err != nil {
+		return false, errResponse(err, http.StatusNotFound)
+	}
+	return ok, nil
+}
+
 func (rh *requestHandler) getAlert(r *http.Request) (*apiAlert, error) {
 	groupID, err := strconv.ParseUint(r.FormValue(paramGroupID), 10, 64)
 	if err != nil {
@@ -212,6 +393,56 @@ func (rh *requestHandler) getAlert(r *http.Request) (*apiAlert, error) {
 	return a, nil
 }
 
+// writeAlertRelabelDebug generates response for /vmalert/alert-relabel-debug page.
+//
+// If metric and relabel_configs args are missing, then they are populated
+// from the alert labels and the alert_relabel_configs of the given notifier.
+func (rh *requestHandler) writeAlertRelabelDebug(w http.ResponseWriter, r *http.Request) {
+	metric := r.FormValue("metric")
+	relabelConfigs := r.FormValue("relabel_configs")
+	format := r.FormValue("format")
+	var err error
+
+	if metric == "" && relabelConfigs == "" {
+		if r.FormValue(paramGroupID) != "" {
+			alert, aErr := rh.getAlert(r)
+			if aErr != nil {
+				err = aErr
+			} else {
+				metric = promutil.NewLabelsFromMap(alert.Labels).String()
+			}
+		}
+		if addr := r.FormValue(paramNotifier); addr != "" && err == nil {
+			pcs, ok := getNotifierAlertRelabelConfigs(addr)
+			if !ok {
+				err = fmt.Errorf("cannot find notifier with address %q", addr)
+			} else {
+				relabelConfigs = pcs.String()
+			}
+		}
+	}
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	promrelabel.WriteMetricRelabelDebug(w, "", metric, relabelConfigs, format, err)
+}
+
+func getNotifierAlertRelabelConfigs(addr string) (*promrelabel.ParsedConfigs, bool) {
+	for _, ts := range notifier.GetTargets() {
+		for _, t := range ts {
+			if t.Notifier.Addr() != addr {
+				continue
+			}
+			am, ok := t.Notifier.(*notifier.AlertManager)
+			if !ok {
+				return nil, false
+			}
+			return am.AlertRelabelConfigs(), true
+		}
+	}
+	return nil, false
+}
+
 type listGroupsResponse struct {
 	Status string `json:"status"`
 	Data   struct {
@@ -1,10 +1,10 @@
 // Code generated by qtc from "web.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-//line app/vmalert/web.qtpl:1
+//line web.qtpl:1
 package main
 
-//line app/vmalert/web.qtpl:3
+//line web.qtpl:3
 import (
 	"net/http"
 	"sort"
@@ -15,203 +15,203 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/vmalertutil"
 )
 
-//line app/vmalert/web.qtpl:14
+//line web.qtpl:14
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vmalert/web.qtpl:14
+//line web.qtpl:14
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vmalert/web.qtpl:14
+//line web.qtpl:14
 func StreamWelcome(qw422016 *qt422016.Writer, r *http.Request) {
-//line app/vmalert/web.qtpl:14
+//line web.qtpl:14
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:15
+//line web.qtpl:15
 	tpl.StreamHeader(qw422016, r, navItems, "vmalert", getLastConfigError())
-//line app/vmalert/web.qtpl:15
+//line web.qtpl:15
 	qw422016.N().S(`
     <p>
         API:<br>
         `)
-//line app/vmalert/web.qtpl:18
+//line web.qtpl:18
 	for _, p := range apiLinks {
-//line app/vmalert/web.qtpl:18
+//line web.qtpl:18
 		qw422016.N().S(`
             `)
-//line app/vmalert/web.qtpl:19
+//line web.qtpl:19
 		p, doc := p[0], p[1]
 
-//line app/vmalert/web.qtpl:19
+//line web.qtpl:19
 		qw422016.N().S(`
             <a href="`)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.E().S(p)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.E().S(p)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.N().S(`</a> - `)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.E().S(doc)
-//line app/vmalert/web.qtpl:20
+//line web.qtpl:20
 		qw422016.N().S(`<br/>
         `)
-//line app/vmalert/web.qtpl:21
+//line web.qtpl:21
 	}
-//line app/vmalert/web.qtpl:21
+//line web.qtpl:21
 	qw422016.N().S(`
         `)
-//line app/vmalert/web.qtpl:22
+//line web.qtpl:22
 	if r.Header.Get("X-Forwarded-For") == "" {
-//line app/vmalert/web.qtpl:22
+//line web.qtpl:22
 		qw422016.N().S(`
             System:<br>
             `)
-//line app/vmalert/web.qtpl:24
+//line web.qtpl:24
 		for _, p := range systemLinks {
-//line app/vmalert/web.qtpl:24
+//line web.qtpl:24
 			qw422016.N().S(`
                 `)
-//line app/vmalert/web.qtpl:25
+//line web.qtpl:25
 			p, doc := p[0], p[1]
 
-//line app/vmalert/web.qtpl:25
+//line web.qtpl:25
 			qw422016.N().S(`
                 <a href="`)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.E().S(p)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.E().S(p)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.N().S(`</a> - `)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.E().S(doc)
-//line app/vmalert/web.qtpl:26
+//line web.qtpl:26
 			qw422016.N().S(`<br/>
             `)
-//line app/vmalert/web.qtpl:27
+//line web.qtpl:27
 		}
-//line app/vmalert/web.qtpl:27
+//line web.qtpl:27
 		qw422016.N().S(`
         `)
-//line app/vmalert/web.qtpl:28
+//line web.qtpl:28
 	}
-//line app/vmalert/web.qtpl:28
+//line web.qtpl:28
 	qw422016.N().S(`
     </p>
     `)
-//line app/vmalert/web.qtpl:30
+//line web.qtpl:30
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:30
+//line web.qtpl:30
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 }
 
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 func WriteWelcome(qq422016 qtio422016.Writer, r *http.Request) {
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	StreamWelcome(qw422016, r)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 }
 
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 func Welcome(r *http.Request) string {
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	WriteWelcome(qb422016, r)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 	return qs422016
-//line app/vmalert/web.qtpl:31
+//line web.qtpl:31
 }
 
-//line app/vmalert/web.qtpl:33
+//line web.qtpl:33
 func streambuttonActive(qw422016 *qt422016.Writer, filter, expValue string) {
-//line app/vmalert/web.qtpl:33
+//line web.qtpl:33
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:34
+//line web.qtpl:34
 	if filter != expValue {
-//line app/vmalert/web.qtpl:34
+//line web.qtpl:34
 		qw422016.N().S(`
 btn-secondary
     `)
-//line app/vmalert/web.qtpl:36
+//line web.qtpl:36
 	} else {
-//line app/vmalert/web.qtpl:36
+//line web.qtpl:36
 		qw422016.N().S(`
 btn-primary
     `)
-//line app/vmalert/web.qtpl:38
+//line web.qtpl:38
 	}
-//line app/vmalert/web.qtpl:38
+//line web.qtpl:38
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 }
 
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 func writebuttonActive(qq422016 qtio422016.Writer, filter, expValue string) {
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	streambuttonActive(qw422016, filter, expValue)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 }
 
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 func buttonActive(filter, expValue string) string {
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	writebuttonActive(qb422016, filter, expValue)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 	return qs422016
-//line app/vmalert/web.qtpl:39
+//line web.qtpl:39
 }
 
-//line app/vmalert/web.qtpl:41
+//line web.qtpl:41
 func StreamListGroups(qw422016 *qt422016.Writer, r *http.Request, originGroups []apiGroup) {
-//line app/vmalert/web.qtpl:41
+//line web.qtpl:41
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:42
+//line web.qtpl:42
 	prefix := vmalertutil.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:42
+//line web.qtpl:42
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:43
+//line web.qtpl:43
 	tpl.StreamHeader(qw422016, r, navItems, "Groups", getLastConfigError())
-//line app/vmalert/web.qtpl:43
+//line web.qtpl:43
 	qw422016.N().S(`
         `)
-//line app/vmalert/web.qtpl:45
+//line web.qtpl:45
 	filter := r.URL.Query().Get("filter")
 	rOk := make(map[string]int)
 	rNotOk := make(map[string]int)
@@ -240,26 +240,26 @@ func StreamListGroups(qw422016 *qt422016.Writer, r *http.Request, originGroups [
 		}
 	}
 
-//line app/vmalert/web.qtpl:72
+//line web.qtpl:72
 	qw422016.N().S(`
         <div class="btn-toolbar mb-3" role="toolbar">
           <div>
             <a class="btn `)
-//line app/vmalert/web.qtpl:75
+//line web.qtpl:75
 	streambuttonActive(qw422016, filter, "")
-//line app/vmalert/web.qtpl:75
+//line web.qtpl:75
 	qw422016.N().S(`" role="button" onclick="window.location = window.location.pathname">All</a>
             <a class="btn btn-primary" role="button" onclick="collapseAll()">Collapse All</a>
             <a class="btn btn-primary" role="button" onclick="expandAll()">Expand All</a>
             <a class="btn `)
-//line app/vmalert/web.qtpl:78
+//line web.qtpl:78
 	streambuttonActive(qw422016, filter, "unhealthy")
-//line app/vmalert/web.qtpl:78
+//line web.qtpl:78
 	qw422016.N().S(`" role="button" onclick="location.href='?filter=unhealthy'" title="Show only rules with errors">Unhealthy</a>
             <a class="btn `)
-//line app/vmalert/web.qtpl:79
+//line web.qtpl:79
 	streambuttonActive(qw422016, filter, "noMatch")
-//line app/vmalert/web.qtpl:79
+//line web.qtpl:79
 	qw422016.N().S(`" role="button" onclick="location.href='?filter=noMatch'" title="Show only rules matching no time series during last evaluation">NoMatch</a>
           </div>
           <div class="col-md-4 col-lg-5">
@@ -274,156 +274,156 @@ func StreamListGroups(qw422016 *qt422016.Writer, r *http.Request, originGroups [
           </div>
         </div>
         `)
-//line app/vmalert/web.qtpl:92
+//line web.qtpl:92
 	if len(groups) > 0 {
-//line app/vmalert/web.qtpl:92
+//line web.qtpl:92
 		qw422016.N().S(`
             `)
-//line app/vmalert/web.qtpl:93
+//line web.qtpl:93
 		for _, g := range groups {
-//line app/vmalert/web.qtpl:93
+//line web.qtpl:93
 			qw422016.N().S(`
                   <div
                     class="group-heading`)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			if rNotOk[g.ID] > 0 {
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 				qw422016.N().S(` alert-danger`)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			}
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			qw422016.N().S(`" data-bs-target="rules-`)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			qw422016.N().S(`" data-group-name="`)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			qw422016.E().S(g.Name)
-//line app/vmalert/web.qtpl:95
+//line web.qtpl:95
 			qw422016.N().S(`">
                     <span class="anchor" id="group-`)
-//line app/vmalert/web.qtpl:96
+//line web.qtpl:96
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:96
+//line web.qtpl:96
 			qw422016.N().S(`"></span>
                     <a href="#group-`)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.E().S(g.Name)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			if g.Type != "prometheus" {
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 				qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 				qw422016.E().S(g.Type)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 				qw422016.N().S(`)`)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			}
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.N().S(` (every `)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.N().FPrec(g.Interval, 0)
-//line app/vmalert/web.qtpl:97
+//line web.qtpl:97
 			qw422016.N().S(`s) #</a>
                      `)
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 			if rNotOk[g.ID] > 0 {
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 				qw422016.N().S(`<span class="badge bg-danger" title="Number of rules with status Error">`)
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 				qw422016.N().D(rNotOk[g.ID])
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 				qw422016.N().S(`</span> `)
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 			}
-//line app/vmalert/web.qtpl:98
+//line web.qtpl:98
 			qw422016.N().S(`
                      `)
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 			if rNoMatch[g.ID] > 0 {
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 				qw422016.N().S(`<span class="badge bg-warning" title="Number of rules with status NoMatch">`)
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 				qw422016.N().D(rNoMatch[g.ID])
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 				qw422016.N().S(`</span> `)
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 			}
-//line app/vmalert/web.qtpl:99
+//line web.qtpl:99
 			qw422016.N().S(`
                     <span class="badge bg-success" title="Number of rules with status Ok">`)
-//line app/vmalert/web.qtpl:100
+//line web.qtpl:100
 			qw422016.N().D(rOk[g.ID])
-//line app/vmalert/web.qtpl:100
+//line web.qtpl:100
 			qw422016.N().S(`</span>
                     <p class="fs-6 fw-lighter">`)
-//line app/vmalert/web.qtpl:101
+//line web.qtpl:101
 			qw422016.E().S(g.File)
-//line app/vmalert/web.qtpl:101
+//line web.qtpl:101
 			qw422016.N().S(`</p>
                     `)
-//line app/vmalert/web.qtpl:102
+//line web.qtpl:102
 			if len(g.Params) > 0 {
-//line app/vmalert/web.qtpl:102
+//line web.qtpl:102
 				qw422016.N().S(`
                         <div class="fs-6 fw-lighter">Extra params
                         `)
-//line app/vmalert/web.qtpl:104
+//line web.qtpl:104
 				for _, param := range g.Params {
-//line app/vmalert/web.qtpl:104
+//line web.qtpl:104
 					qw422016.N().S(`
                                 <span class="float-left badge bg-primary">`)
-//line app/vmalert/web.qtpl:105
+//line web.qtpl:105
 					qw422016.E().S(param)
-//line app/vmalert/web.qtpl:105
+//line web.qtpl:105
 					qw422016.N().S(`</span>
                         `)
-//line app/vmalert/web.qtpl:106
+//line web.qtpl:106
 				}
-//line app/vmalert/web.qtpl:106
+//line web.qtpl:106
 				qw422016.N().S(`
                         </div>
                     `)
-//line app/vmalert/web.qtpl:108
+//line web.qtpl:108
 			}
-//line app/vmalert/web.qtpl:108
+//line web.qtpl:108
 			qw422016.N().S(`
                     `)
-//line app/vmalert/web.qtpl:109
+//line web.qtpl:109
 			if len(g.Headers) > 0 {
-//line app/vmalert/web.qtpl:109
+//line web.qtpl:109
 				qw422016.N().S(`
                         <div class="fs-6 fw-lighter">Extra headers
                         `)
-//line app/vmalert/web.qtpl:111
+//line web.qtpl:111
 				for _, header := range g.Headers {
-//line app/vmalert/web.qtpl:111
+//line web.qtpl:111
 					qw422016.N().S(`
                                 <span class="float-left badge bg-primary">`)
-//line app/vmalert/web.qtpl:112
+//line web.qtpl:112
 					qw422016.E().S(header)
-//line app/vmalert/web.qtpl:112
+//line web.qtpl:112
 					qw422016.N().S(`</span>
                         `)
-//line app/vmalert/web.qtpl:113
+//line web.qtpl:113
 				}
-//line app/vmalert/web.qtpl:113
+//line web.qtpl:113
 				qw422016.N().S(`
                         </div>
                     `)
-//line app/vmalert/web.qtpl:115
+//line web.qtpl:115
 			}
-//line app/vmalert/web.qtpl:115
+//line web.qtpl:115
 			qw422016.N().S(`
                 </div>
                 <div class="collapse rule-table" id="rules-`)
-//line app/vmalert/web.qtpl:117
+//line web.qtpl:117
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:117
+//line web.qtpl:117
 			qw422016.N().S(`">
                     <table class="table table-striped table-hover table-sm">
                         <thead>
@@ -435,251 +435,262 @@ func StreamListGroups(qw422016 *qt422016.Writer, r *http.Request, originGroups [
                         </thead>
                         <tbody>
                         `)
-//line app/vmalert/web.qtpl:127
+//line web.qtpl:127
 			for _, r := range g.Rules {
-//line app/vmalert/web.qtpl:127
+//line web.qtpl:127
 				qw422016.N().S(`
                             <tr class="rule`)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				if r.LastError != "" {
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 					qw422016.N().S(` alert-danger`)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				}
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				qw422016.N().S(`" data-rule-name="`)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				qw422016.N().S(`" data-bs-target="`)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:128
+//line web.qtpl:128
 				qw422016.N().S(`">
                                 <td>
                                     <div class="row">
                                         <div class="col-12 mb-2">
                                             `)
-//line app/vmalert/web.qtpl:132
+//line web.qtpl:132
 				if r.Type == "alerting" {
-//line app/vmalert/web.qtpl:132
+//line web.qtpl:132
 					qw422016.N().S(`
                                             `)
-//line app/vmalert/web.qtpl:133
+//line web.qtpl:133
 					if r.KeepFiringFor > 0 {
-//line app/vmalert/web.qtpl:133
+//line web.qtpl:133
 						qw422016.N().S(`
                                             <b>alert:</b> `)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.N().S(` (for: `)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.E().V(r.Duration)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.N().S(` seconds, keep_firing_for: `)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.E().V(r.KeepFiringFor)
-//line app/vmalert/web.qtpl:134
+//line web.qtpl:134
 						qw422016.N().S(` seconds)
                                             `)
-//line app/vmalert/web.qtpl:135
+//line web.qtpl:135
 					} else {
-//line app/vmalert/web.qtpl:135
+//line web.qtpl:135
 						qw422016.N().S(`
                                             <b>alert:</b> `)
-//line app/vmalert/web.qtpl:136
+//line web.qtpl:136
 						qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:136
+//line web.qtpl:136
 						qw422016.N().S(` (for: `)
-//line app/vmalert/web.qtpl:136
+//line web.qtpl:136
 						qw422016.E().V(r.Duration)
-//line app/vmalert/web.qtpl:136
+//line web.qtpl:136
 						qw422016.N().S(` seconds)
                                             `)
-//line app/vmalert/web.qtpl:137
+//line web.qtpl:137
 					}
-//line app/vmalert/web.qtpl:137
+//line web.qtpl:137
 					qw422016.N().S(`
                                             `)
-//line app/vmalert/web.qtpl:138
+//line web.qtpl:138
 				} else {
-//line app/vmalert/web.qtpl:138
+//line web.qtpl:138
 					qw422016.N().S(`
                                             <b>record:</b> `)
-//line app/vmalert/web.qtpl:139
+//line web.qtpl:139
 					qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:139
+//line web.qtpl:139
 					qw422016.N().S(`
                                             `)
-//line app/vmalert/web.qtpl:140
+//line web.qtpl:140
 				}
-//line app/vmalert/web.qtpl:140
+//line web.qtpl:140
 				qw422016.N().S(`
                                             |
                                             `)
-//line app/vmalert/web.qtpl:142
+//line web.qtpl:142
 				streamseriesFetchedWarn(qw422016, r)
-//line app/vmalert/web.qtpl:142
+//line web.qtpl:142
+				qw422016.N().S(`
+                                            `)
+//line web.qtpl:143
+				if r.Paused {
+//line web.qtpl:143
+					streambadgePaused(qw422016, r.PausedReason)
+//line web.qtpl:143
+					qw422016.N().S(` |`)
+//line web.qtpl:143
+				}
+//line web.qtpl:143
 				qw422016.N().S(`
                                             <span><a target="_blank" href="`)
-//line app/vmalert/web.qtpl:143
+//line web.qtpl:144
 				qw422016.E().S(prefix + r.WebLink())
-//line app/vmalert/web.qtpl:143
+//line web.qtpl:144
 				qw422016.N().S(`">Details</a></span>
                                         </div>
                                         <div class="col-12">
                                             <code><pre>`)
-//line app/vmalert/web.qtpl:146
+//line web.qtpl:147
 				qw422016.E().S(r.Query)
-//line app/vmalert/web.qtpl:146
+//line web.qtpl:147
 				qw422016.N().S(`</pre></code>
                                         </div>
                                         <div class="col-12 mb-2">
                                             `)
-//line app/vmalert/web.qtpl:149
+//line web.qtpl:150
 				if len(r.Labels) > 0 {
-//line app/vmalert/web.qtpl:149
+//line web.qtpl:150
 					qw422016.N().S(` <b>Labels:</b>`)
-//line app/vmalert/web.qtpl:149
+//line web.qtpl:150
 				}
-//line app/vmalert/web.qtpl:149
+//line web.qtpl:150
 				qw422016.N().S(`
                                             `)
-//line app/vmalert/web.qtpl:150
+//line web.qtpl:151
 				for k, v := range r.Labels {
-//line app/vmalert/web.qtpl:150
+//line web.qtpl:151
 					qw422016.N().S(`
                                                     <span class="ms-1 badge bg-primary label">`)
-//line app/vmalert/web.qtpl:151
+//line web.qtpl:152
 					qw422016.E().S(k)
-//line app/vmalert/web.qtpl:151
+//line web.qtpl:152
 					qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:151
+//line web.qtpl:152
 					qw422016.E().S(v)
-//line app/vmalert/web.qtpl:151
+//line web.qtpl:152
 					qw422016.N().S(`</span>
                                             `)
-//line app/vmalert/web.qtpl:152
+//line web.qtpl:153
 				}
-//line app/vmalert/web.qtpl:152
+//line web.qtpl:153
 				qw422016.N().S(`
                                         </div>
                                         `)
-//line app/vmalert/web.qtpl:154
+//line web.qtpl:155
 				if r.LastError != "" {
-//line app/vmalert/web.qtpl:154
+//line web.qtpl:155
 					qw422016.N().S(`
                                         <div class="col-12">
                                             <b>Error:</b>
                                             <div class="error-cell">
                                             `)
-//line app/vmalert/web.qtpl:158
+//line web.qtpl:159
 					qw422016.E().S(r.LastError)
-//line app/vmalert/web.qtpl:158
+//line web.qtpl:159
 					qw422016.N().S(`
                                             </div>
                                         </div>
                                         `)
-//line app/vmalert/web.qtpl:161
+//line web.qtpl:162
 				}
-//line app/vmalert/web.qtpl:161
+//line web.qtpl:162
 				qw422016.N().S(`
                                     </div>
                                 </td>
                                 <td class="text-center">`)
-//line app/vmalert/web.qtpl:164
+//line web.qtpl:165
 				qw422016.N().D(r.LastSamples)
-//line app/vmalert/web.qtpl:164
+//line web.qtpl:165
 				qw422016.N().S(`</td>
                                 <td class="text-center">`)
-//line app/vmalert/web.qtpl:165
+//line web.qtpl:166
 				qw422016.N().FPrec(time.Since(r.LastEvaluation).Seconds(), 3)
-//line app/vmalert/web.qtpl:165
+//line web.qtpl:166
 				qw422016.N().S(`s ago</td>
                             </tr>
                         `)
-//line app/vmalert/web.qtpl:167
+//line web.qtpl:168
 			}
-//line app/vmalert/web.qtpl:167
+//line web.qtpl:168
 			qw422016.N().S(`
                      </tbody>
                     </table>
                 </div>
             `)
-//line app/vmalert/web.qtpl:171
+//line web.qtpl:172
 		}
-//line app/vmalert/web.qtpl:171
+//line web.qtpl:172
 		qw422016.N().S(`
         `)
-//line app/vmalert/web.qtpl:172
+//line web.qtpl:173
 	} else {
-//line app/vmalert/web.qtpl:172
+//line web.qtpl:173
 		qw422016.N().S(`
             <div>
                 <p>No groups...</p>
             </div>
         `)
-//line app/vmalert/web.qtpl:176
+//line web.qtpl:177
 	}
-//line app/vmalert/web.qtpl:176
+//line web.qtpl:177
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:178
+//line web.qtpl:179
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:178
+//line web.qtpl:179
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 }
 
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 func WriteListGroups(qq422016 qtio422016.Writer, r *http.Request, originGroups []apiGroup) {
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	StreamListGroups(qw422016, r, originGroups)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 }
 
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 func ListGroups(r *http.Request, originGroups []apiGroup) string {
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	WriteListGroups(qb422016, r, originGroups)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 	return qs422016
-//line app/vmalert/web.qtpl:180
+//line web.qtpl:181
 }
 
-//line app/vmalert/web.qtpl:183
+//line web.qtpl:184
 func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []groupAlerts) {
-//line app/vmalert/web.qtpl:183
+//line web.qtpl:184
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:184
+//line web.qtpl:185
 	prefix := vmalertutil.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:184
+//line web.qtpl:185
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:185
+//line web.qtpl:186
 	tpl.StreamHeader(qw422016, r, navItems, "Alerts", getLastConfigError())
-//line app/vmalert/web.qtpl:185
+//line web.qtpl:186
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:186
+//line web.qtpl:187
 	if len(groupAlerts) > 0 {
-//line app/vmalert/web.qtpl:186
+//line web.qtpl:187
 		qw422016.N().S(`
          <div class="btn-toolbar mb-3" role="toolbar">
               <div>
@@ -698,63 +709,63 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
               </div>
           </div>
          `)
-//line app/vmalert/web.qtpl:203
+//line web.qtpl:204
 		for _, ga := range groupAlerts {
-//line app/vmalert/web.qtpl:203
+//line web.qtpl:204
 			qw422016.N().S(`
             `)
-//line app/vmalert/web.qtpl:204
+//line web.qtpl:205
 			g := ga.Group
 
-//line app/vmalert/web.qtpl:204
+//line web.qtpl:205
 			qw422016.N().S(`
             <div class="group-heading alert-danger" data-bs-target="rules-`)
-//line app/vmalert/web.qtpl:205
+//line web.qtpl:206
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:205
+//line web.qtpl:206
 			qw422016.N().S(`" data-group-name="`)
-//line app/vmalert/web.qtpl:205
+//line web.qtpl:206
 			qw422016.E().S(g.Name)
-//line app/vmalert/web.qtpl:205
+//line web.qtpl:206
 			qw422016.N().S(`">
                 <span class="anchor" id="group-`)
-//line app/vmalert/web.qtpl:206
+//line web.qtpl:207
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:206
+//line web.qtpl:207
 			qw422016.N().S(`"></span>
                 <a href="#group-`)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			qw422016.E().S(g.Name)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			if g.Type != "prometheus" {
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 				qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 				qw422016.E().S(g.Type)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 				qw422016.N().S(`)`)
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			}
-//line app/vmalert/web.qtpl:207
+//line web.qtpl:208
 			qw422016.N().S(`</a>
                 <span class="badge bg-danger" title="Number of active alerts">`)
-//line app/vmalert/web.qtpl:208
+//line web.qtpl:209
 			qw422016.N().D(len(ga.Alerts))
-//line app/vmalert/web.qtpl:208
+//line web.qtpl:209
 			qw422016.N().S(`</span>
                 <br>
                 <p class="fs-6 fw-lighter">`)
-//line app/vmalert/web.qtpl:210
+//line web.qtpl:211
 			qw422016.E().S(g.File)
-//line app/vmalert/web.qtpl:210
+//line web.qtpl:211
 			qw422016.N().S(`</p>
             </div>
             `)
-//line app/vmalert/web.qtpl:213
+//line web.qtpl:214
 			var keys []string
 			alertsByRule := make(map[string][]*apiAlert)
 			for _, alert := range ga.Alerts {
@@ -765,20 +776,20 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
 			}
 			sort.Strings(keys)
 
-//line app/vmalert/web.qtpl:222
+//line web.qtpl:223
 			qw422016.N().S(`
             <div class="collapse rule-table" id="rules-`)
-//line app/vmalert/web.qtpl:223
+//line web.qtpl:224
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:223
+//line web.qtpl:224
 			qw422016.N().S(`">
                 `)
-//line app/vmalert/web.qtpl:224
+//line web.qtpl:225
 			for _, ruleID := range keys {
-//line app/vmalert/web.qtpl:224
+//line web.qtpl:225
 				qw422016.N().S(`
                     `)
-//line app/vmalert/web.qtpl:226
+//line web.qtpl:227
 				defaultAR := alertsByRule[ruleID][0]
 				var labelKeys []string
 				for k := range defaultAR.Labels {
@@ -786,37 +797,37 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
 				}
 				sort.Strings(labelKeys)
 
-//line app/vmalert/web.qtpl:232
+//line web.qtpl:233
 				qw422016.N().S(`
                     <br>
                     <div class="rule" data-rule-name="`)
-//line app/vmalert/web.qtpl:234
+//line web.qtpl:235
 				qw422016.E().S(defaultAR.Name)
-//line app/vmalert/web.qtpl:234
+//line web.qtpl:235
 				qw422016.N().S(`" data-bs-target="`)
-//line app/vmalert/web.qtpl:234
+//line web.qtpl:235
 				qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:234
+//line web.qtpl:235
 				qw422016.N().S(`">
                       <b>alert:</b> `)
-//line app/vmalert/web.qtpl:235
+//line web.qtpl:236
 				qw422016.E().S(defaultAR.Name)
-//line app/vmalert/web.qtpl:235
+//line web.qtpl:236
 				qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:235
+//line web.qtpl:236
 				qw422016.N().D(len(alertsByRule[ruleID]))
-//line app/vmalert/web.qtpl:235
+//line web.qtpl:236
 				qw422016.N().S(`)
                        | <span><a target="_blank" href="`)
-//line app/vmalert/web.qtpl:236
+//line web.qtpl:237
 				qw422016.E().S(defaultAR.SourceLink)
-//line app/vmalert/web.qtpl:236
+//line web.qtpl:237
 				qw422016.N().S(`">Source</a></span>
                       <br>
                       <b>expr:</b><code><pre>`)
-//line app/vmalert/web.qtpl:238
+//line web.qtpl:239
 				qw422016.E().S(defaultAR.Expression)
-//line app/vmalert/web.qtpl:238
+//line web.qtpl:239
 				qw422016.N().S(`</pre></code>
                       <table class="table table-striped table-hover table-sm">
                           <thead>
@@ -830,336 +841,414 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
                           </thead>
                           <tbody>
                           `)
-//line app/vmalert/web.qtpl:250
+//line web.qtpl:251
 				for _, ar := range alertsByRule[ruleID] {
-//line app/vmalert/web.qtpl:250
+//line web.qtpl:251
 					qw422016.N().S(`
                               <tr>
                                   <td>
                                       `)
-//line app/vmalert/web.qtpl:253
+//line web.qtpl:254
 					for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:253
+//line web.qtpl:254
 						qw422016.N().S(`
                                           <span class="ms-1 badge bg-primary label">`)
-//line app/vmalert/web.qtpl:254
+//line web.qtpl:255
 						qw422016.E().S(k)
-//line app/vmalert/web.qtpl:254
+//line web.qtpl:255
 						qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:254
+//line web.qtpl:255
 						qw422016.E().S(ar.Labels[k])
-//line app/vmalert/web.qtpl:254
+//line web.qtpl:255
 						qw422016.N().S(`</span>
                                       `)
-//line app/vmalert/web.qtpl:255
+//line web.qtpl:256
 					}
-//line app/vmalert/web.qtpl:255
+//line web.qtpl:256
 					qw422016.N().S(`
                                   </td>
                                   <td>`)
-//line app/vmalert/web.qtpl:257
+//line web.qtpl:258
 					streambadgeState(qw422016, ar.State)
-//line app/vmalert/web.qtpl:257
+//line web.qtpl:258
 					qw422016.N().S(`</td>
                                   <td>
                                       `)
-//line app/vmalert/web.qtpl:259
+//line web.qtpl:260
 					qw422016.E().S(ar.ActiveAt.Format("2006-01-02T15:04:05Z07:00"))
-//line app/vmalert/web.qtpl:259
+//line web.qtpl:260
 					qw422016.N().S(`
                                       `)
-//line app/vmalert/web.qtpl:260
+//line web.qtpl:261
 					if ar.Restored {
-//line app/vmalert/web.qtpl:260
+//line web.qtpl:261
 						streambadgeRestored(qw422016)
-//line app/vmalert/web.qtpl:260
+//line web.qtpl:261
 					}
-//line app/vmalert/web.qtpl:260
+//line web.qtpl:261
 					qw422016.N().S(`
                                       `)
-//line app/vmalert/web.qtpl:261
+//line web.qtpl:262
 					if ar.Stabilizing {
-//line app/vmalert/web.qtpl:261
+//line web.qtpl:262
 						streambadgeStabilizing(qw422016)
-//line app/vmalert/web.qtpl:261
+//line web.qtpl:262
 					}
-//line app/vmalert/web.qtpl:261
+//line web.qtpl:262
 					qw422016.N().S(`
                                   </td>
                                   <td>`)
-//line app/vmalert/web.qtpl:263
+//line web.qtpl:264
 					qw422016.E().S(ar.Value)
-//line app/vmalert/web.qtpl:263
+//line web.qtpl:264
 					qw422016.N().S(`</td>
                                   <td>
                                       <a href="`)
-//line app/vmalert/web.qtpl:265
+//line web.qtpl:266
 					qw422016.E().S(prefix + ar.WebLink())
-//line app/vmalert/web.qtpl:265
+//line web.qtpl:266
 					qw422016.N().S(`">Details</a>
                                   </td>
                               </tr>
                           `)
-//line app/vmalert/web.qtpl:268
+//line web.qtpl:269
 				}
-//line app/vmalert/web.qtpl:268
+//line web.qtpl:269
 				qw422016.N().S(`
                        </tbody>
                       </table>
                     </div>
                 `)
-//line app/vmalert/web.qtpl:272
+//line web.qtpl:273
 			}
-//line app/vmalert/web.qtpl:272
+//line web.qtpl:273
 			qw422016.N().S(`
             </div>
         `)
-//line app/vmalert/web.qtpl:274
+//line web.qtpl:275
 		}
-//line app/vmalert/web.qtpl:274
+//line web.qtpl:275
 		qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:276
+//line web.qtpl:277
 	} else {
-//line app/vmalert/web.qtpl:276
+//line web.qtpl:277
 		qw422016.N().S(`
         <div>
             <p>No active alerts...</p>
         </div>
     `)
-//line app/vmalert/web.qtpl:280
+//line web.qtpl:281
 	}
-//line app/vmalert/web.qtpl:280
+//line web.qtpl:281
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:282
+//line web.qtpl:283
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:282
+//line web.qtpl:283
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 }
 
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 func WriteListAlerts(qq422016 qtio422016.Writer, r *http.Request, groupAlerts []groupAlerts) {
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	StreamListAlerts(qw422016, r, groupAlerts)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 }
 
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 func ListAlerts(r *http.Request, groupAlerts []groupAlerts) string {
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	WriteListAlerts(qb422016, r, groupAlerts)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 	return qs422016
-//line app/vmalert/web.qtpl:284
+//line web.qtpl:285
 }
 
-//line app/vmalert/web.qtpl:286
+//line web.qtpl:287
 func StreamListTargets(qw422016 *qt422016.Writer, r *http.Request, targets map[notifier.TargetType][]notifier.Target) {
-//line app/vmalert/web.qtpl:286
+//line web.qtpl:287
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:287
+//line web.qtpl:288
 	tpl.StreamHeader(qw422016, r, navItems, "Notifiers", getLastConfigError())
-//line app/vmalert/web.qtpl:287
+//line web.qtpl:288
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:288
+//line web.qtpl:289
 	if len(targets) > 0 {
-//line app/vmalert/web.qtpl:288
+//line web.qtpl:289
 		qw422016.N().S(`
          <a class="btn btn-primary" role="button" onclick="collapseAll()">Collapse All</a>
          <a class="btn btn-primary" role="button" onclick="expandAll()">Expand All</a>
 
          `)
-//line app/vmalert/web.qtpl:293
+//line web.qtpl:294
 		var keys []string
 		for key := range targets {
 			keys = append(keys, string(key))
 		}
 		sort.Strings(keys)
 
-//line app/vmalert/web.qtpl:298
+//line web.qtpl:299
 		qw422016.N().S(`
 
          `)
-//line app/vmalert/web.qtpl:300
+//line web.qtpl:301
 		for i := range keys {
-//line app/vmalert/web.qtpl:300
+//line web.qtpl:301
 			qw422016.N().S(`
            `)
-//line app/vmalert/web.qtpl:301
+//line web.qtpl:302
 			typeK, ns := keys[i], targets[notifier.TargetType(keys[i])]
 			count := len(ns)
 
-//line app/vmalert/web.qtpl:303
+//line web.qtpl:304
 			qw422016.N().S(`
            <div class="group-heading" data-bs-target="notifiers-`)
-//line app/vmalert/web.qtpl:304
+//line web.qtpl:305
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:304
+//line web.qtpl:305
 			qw422016.N().S(`">
              <span class="anchor" id="group-`)
-//line app/vmalert/web.qtpl:305
+//line web.qtpl:306
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:305
+//line web.qtpl:306
 			qw422016.N().S(`"></span>
              <a href="#group-`)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.N().D(count)
-//line app/vmalert/web.qtpl:306
+//line web.qtpl:307
 			qw422016.N().S(`)</a>
          </div>
          <div class="collapse show" id="notifiers-`)
-//line app/vmalert/web.qtpl:308
+//line web.qtpl:309
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:308
+//line web.qtpl:309
 			qw422016.N().S(`">
              <table class="table table-striped table-hover table-sm">
                  <thead>
                      <tr>
                          <th scope="col">Labels</th>
                          <th scope="col">Address</th>
+                         <th scope="col">Last success</th>
+                         <th scope="col">Last error</th>
                      </tr>
                  </thead>
                  <tbody>
                  `)
-//line app/vmalert/web.qtpl:317
+//line web.qtpl:320
 			for _, n := range ns {
-//line app/vmalert/web.qtpl:317
+//line web.qtpl:320
 				qw422016.N().S(`
                      <tr>
                          <td>
                               `)
-//line app/vmalert/web.qtpl:320
+//line web.qtpl:323
 				for _, l := range n.Labels.GetLabels() {
-//line app/vmalert/web.qtpl:320
+//line web.qtpl:323
 					qw422016.N().S(`
                                       <span class="ms-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:321
+//line web.qtpl:324
 					qw422016.E().S(l.Name)
-//line app/vmalert/web.qtpl:321
+//line web.qtpl:324
 					qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:321
+//line web.qtpl:324
 					qw422016.E().S(l.Value)
-//line app/vmalert/web.qtpl:321
+//line web.qtpl:324
 					qw422016.N().S(`</span>
                               `)
-//line app/vmalert/web.qtpl:322
+//line web.qtpl:325
 				}
-//line app/vmalert/web.qtpl:322
+//line web.qtpl:325
 				qw422016.N().S(`
                           </td>
                          <td>`)
-//line app/vmalert/web.qtpl:324
+//line web.qtpl:327
 				qw422016.E().S(n.Notifier.Addr())
-//line app/vmalert/web.qtpl:324
+//line web.qtpl:327
 				qw422016.N().S(`</td>
+                         `)
+//line web.qtpl:328
+				if am, ok := n.Notifier.(*notifier.AlertManager); ok {
+//line web.qtpl:328
+					qw422016.N().S(`
+                             `)
+//line web.qtpl:329
+					ds := am.DeliveryStatus()
+
+//line web.qtpl:329
+					qw422016.N().S(`
+                             <td>
+                                 `)
+//line web.qtpl:331
+					if !ds.LastSuccess.IsZero() {
+//line web.qtpl:331
+						qw422016.N().S(`
+                                     `)
+//line web.qtpl:332
+						qw422016.N().FPrec(time.Since(ds.LastSuccess).Seconds(), 3)
+//line web.qtpl:332
+						qw422016.N().S(`s ago
+                                 `)
+//line web.qtpl:333
+					}
+//line web.qtpl:333
+					qw422016.N().S(`
+                             </td>
+                             <td>
+                                 `)
+//line web.qtpl:336
+					if !ds.FailingSince.IsZero() {
+//line web.qtpl:336
+						qw422016.N().S(`
+                                     <span class="badge bg-danger">failing for `)
+//line web.qtpl:337
+						qw422016.N().FPrec(time.Since(ds.FailingSince).Seconds(), 3)
+//line web.qtpl:337
+						qw422016.N().S(`s</span>
+                                     <span class="ms-1">`)
+//line web.qtpl:338
+						qw422016.E().S(ds.LastError)
+//line web.qtpl:338
+						qw422016.N().S(`</span>
+                                 `)
+//line web.qtpl:339
+					} else if !ds.LastErrorTime.IsZero() {
+//line web.qtpl:339
+						qw422016.N().S(`
+                                     <span>`)
+//line web.qtpl:340
+						qw422016.N().FPrec(time.Since(ds.LastErrorTime).Seconds(), 3)
+//line web.qtpl:340
+						qw422016.N().S(`s ago: `)
+//line web.qtpl:340
+						qw422016.E().S(ds.LastError)
+//line web.qtpl:340
+						qw422016.N().S(`</span>
+                                 `)
+//line web.qtpl:341
+					}
+//line web.qtpl:341
+					qw422016.N().S(`
+                             </td>
+                         `)
+//line web.qtpl:343
+				} else {
+//line web.qtpl:343
+					qw422016.N().S(`
+                             <td></td>
+                             <td></td>
+                         `)
+//line web.qtpl:346
+				}
+//line web.qtpl:346
+				qw422016.N().S(`
                      </tr>
                  `)
-//line app/vmalert/web.qtpl:326
+//line web.qtpl:348
 			}
-//line app/vmalert/web.qtpl:326
+//line web.qtpl:348
 			qw422016.N().S(`
               </tbody>
              </table>
          </div>
      `)
-//line app/vmalert/web.qtpl:330
+//line web.qtpl:352
 		}
-//line app/vmalert/web.qtpl:330
+//line web.qtpl:352
 		qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:332
+//line web.qtpl:354
 	} else {
-//line app/vmalert/web.qtpl:332
+//line web.qtpl:354
 		qw422016.N().S(`
         <div>
             <p>No targets...</p>
         </div>
     `)
-//line app/vmalert/web.qtpl:336
+//line web.qtpl:358
 	}
-//line app/vmalert/web.qtpl:336
+//line web.qtpl:358
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:338
+//line web.qtpl:360
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:338
+//line web.qtpl:360
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 }
 
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 func WriteListTargets(qq422016 qtio422016.Writer, r *http.Request, targets map[notifier.TargetType][]notifier.Target) {
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	StreamListTargets(qw422016, r, targets)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 }
 
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 func ListTargets(r *http.Request, targets map[notifier.TargetType][]notifier.Target) string {
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	WriteListTargets(qb422016, r, targets)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 	return qs422016
-//line app/vmalert/web.qtpl:340
+//line web.qtpl:362
 }
 
-//line app/vmalert/web.qtpl:342
+//line web.qtpl:364
 func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
-//line app/vmalert/web.qtpl:342
+//line web.qtpl:364
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:343
+//line web.qtpl:365
 	prefix := vmalertutil.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:343
+//line web.qtpl:365
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:344
+//line web.qtpl:366
 	tpl.StreamHeader(qw422016, r, navItems, "", getLastConfigError())
-//line app/vmalert/web.qtpl:344
+//line web.qtpl:366
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:346
+//line web.qtpl:368
 	var labelKeys []string
 	for k := range alert.Labels {
 		labelKeys = append(labelKeys, k)
@@ -1172,28 +1261,28 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
 	}
 	sort.Strings(annotationKeys)
 
-//line app/vmalert/web.qtpl:357
+//line web.qtpl:379
 	qw422016.N().S(`
     <div class="display-6 pb-3 mb-3">Alert: `)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	qw422016.E().S(alert.Name)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	qw422016.N().S(`<span class="ms-2 badge `)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	if alert.State == "firing" {
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 		qw422016.N().S(`bg-danger`)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	} else {
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 		qw422016.N().S(` bg-warning text-dark`)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	}
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	qw422016.E().S(alert.State)
-//line app/vmalert/web.qtpl:358
+//line web.qtpl:380
 	qw422016.N().S(`</span></div>
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1202,9 +1291,9 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:365
+//line web.qtpl:387
 	qw422016.E().S(alert.ActiveAt.Format("2006-01-02T15:04:05Z07:00"))
-//line app/vmalert/web.qtpl:365
+//line web.qtpl:387
 	qw422016.N().S(`
         </div>
       </div>
@@ -1216,9 +1305,9 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
           <code><pre>`)
-//line app/vmalert/web.qtpl:375
+//line web.qtpl:397
 	qw422016.E().S(alert.Expression)
-//line app/vmalert/web.qtpl:375
+//line web.qtpl:397
 	qw422016.N().S(`</pre></code>
         </div>
       </div>
@@ -1230,23 +1319,23 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:385
+//line web.qtpl:407
 	for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:385
+//line web.qtpl:407
 		qw422016.N().S(`
                 <span class="m-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:386
+//line web.qtpl:408
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:386
+//line web.qtpl:408
 		qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:386
+//line web.qtpl:408
 		qw422016.E().S(alert.Labels[k])
-//line app/vmalert/web.qtpl:386
+//line web.qtpl:408
 		qw422016.N().S(`</span>
           `)
-//line app/vmalert/web.qtpl:387
+//line web.qtpl:409
 	}
-//line app/vmalert/web.qtpl:387
+//line web.qtpl:409
 	qw422016.N().S(`
         </div>
       </div>
@@ -1258,24 +1347,24 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:397
+//line web.qtpl:419
 	for _, k := range annotationKeys {
-//line app/vmalert/web.qtpl:397
+//line web.qtpl:419
 		qw422016.N().S(`
                 <b>`)
-//line app/vmalert/web.qtpl:398
+//line web.qtpl:420
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:398
+//line web.qtpl:420
 		qw422016.N().S(`:</b><br>
                 <p>`)
-//line app/vmalert/web.qtpl:399
+//line web.qtpl:421
 		qw422016.E().S(alert.Annotations[k])
-//line app/vmalert/web.qtpl:399
+//line web.qtpl:421
 		qw422016.N().S(`</p>
           `)
-//line app/vmalert/web.qtpl:400
+//line web.qtpl:422
 	}
-//line app/vmalert/web.qtpl:400
+//line web.qtpl:422
 	qw422016.N().S(`
         </div>
       </div>
@@ -1287,17 +1376,17 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.E().S(prefix)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.N().S(`groups#group-`)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.E().S(alert.GroupID)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.E().S(alert.GroupID)
-//line app/vmalert/web.qtpl:410
+//line web.qtpl:432
 	qw422016.N().S(`</a>
         </div>
       </div>
@@ -1309,66 +1398,132 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *apiAlert) {
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:420
+//line web.qtpl:442
 	qw422016.E().S(alert.SourceLink)
-//line app/vmalert/web.qtpl:420
+//line web.qtpl:442
 	qw422016.N().S(`">Link</a>
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:424
+//line web.qtpl:446
+	targets := notifier.GetTargets()
+
+//line web.qtpl:446
+	qw422016.N().S(`
+    `)
+//line web.qtpl:447
+	if len(targets) > 0 {
+//line web.qtpl:447
+		qw422016.N().S(`
+     <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          Notifiers relabeling
+        </div>
+        <div class="col">
+           `)
+//line web.qtpl:454
+		for _, ns := range targets {
+//line web.qtpl:454
+			qw422016.N().S(`
+               `)
+//line web.qtpl:455
+			for _, n := range ns {
+//line web.qtpl:455
+				qw422016.N().S(`
+                   <a target="_blank" href="`)
+//line web.qtpl:456
+				qw422016.E().S(prefix)
+//line web.qtpl:456
+				qw422016.N().S(`alert-relabel-debug?group_id=`)
+//line web.qtpl:456
+				qw422016.E().S(alert.GroupID)
+//line web.qtpl:456
+				qw422016.N().S(`&alert_id=`)
+//line web.qtpl:456
+				qw422016.E().S(alert.ID)
+//line web.qtpl:456
+				qw422016.N().S(`&notifier=`)
+//line web.qtpl:456
+				qw422016.N().U(n.Notifier.Addr())
+//line web.qtpl:456
+				qw422016.N().S(`">`)
+//line web.qtpl:456
+				qw422016.E().S(n.Notifier.Addr())
+//line web.qtpl:456
+				qw422016.N().S(`</a><br>
+               `)
+//line web.qtpl:457
+			}
+//line web.qtpl:457
+			qw422016.N().S(`
+           `)
+//line web.qtpl:458
+		}
+//line web.qtpl:458
+		qw422016.N().S(`
+        </div>
+      </div>
+    </div>
+    `)
+//line web.qtpl:462
+	}
+//line web.qtpl:462
+	qw422016.N().S(`
+    `)
+//line web.qtpl:463
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:424
+//line web.qtpl:463
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 }
 
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 func WriteAlert(qq422016 qtio422016.Writer, r *http.Request, alert *apiAlert) {
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	StreamAlert(qw422016, r, alert)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 }
 
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 func Alert(r *http.Request, alert *apiAlert) string {
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	WriteAlert(qb422016, r, alert)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 	return qs422016
-//line app/vmalert/web.qtpl:426
+//line web.qtpl:465
 }
 
-//line app/vmalert/web.qtpl:429
+//line web.qtpl:468
 func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule) {
-//line app/vmalert/web.qtpl:429
+//line web.qtpl:468
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:430
+//line web.qtpl:469
 	prefix := vmalertutil.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:430
+//line web.qtpl:469
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:431
+//line web.qtpl:470
 	tpl.StreamHeader(qw422016, r, navItems, "", getLastConfigError())
-//line app/vmalert/web.qtpl:431
+//line web.qtpl:470
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:433
+//line web.qtpl:472
 	var labelKeys []string
 	for k := range rule.Labels {
 		labelKeys = append(labelKeys, k)
@@ -1392,28 +1547,28 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
 		}
 	}
 
-//line app/vmalert/web.qtpl:456
+//line web.qtpl:495
 	qw422016.N().S(`
     <div class="display-6 pb-3 mb-3">Rule: `)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	qw422016.E().S(rule.Name)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	qw422016.N().S(`<span class="ms-2 badge `)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	if rule.Health != "ok" {
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 		qw422016.N().S(`bg-danger`)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	} else {
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 		qw422016.N().S(` bg-success text-dark`)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	}
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	qw422016.E().S(rule.Health)
-//line app/vmalert/web.qtpl:457
+//line web.qtpl:496
 	qw422016.N().S(`</span></div>
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1422,17 +1577,62 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
           <code><pre>`)
-//line app/vmalert/web.qtpl:464
+//line web.qtpl:503
 	qw422016.E().S(rule.Query)
-//line app/vmalert/web.qtpl:464
+//line web.qtpl:503
 	qw422016.N().S(`</pre></code>
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:468
+//line web.qtpl:507
+	if rule.Paused {
+//line web.qtpl:507
+		qw422016.N().S(`
+    <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          Paused
+        </div>
+        <div class="col">
+         `)
+//line web.qtpl:514
+		qw422016.E().S(rule.PausedReason)
+//line web.qtpl:514
+		qw422016.N().S(`.
+         `)
+//line web.qtpl:515
+		if rule.PausedUntil != nil {
+//line web.qtpl:515
+			qw422016.N().S(`
+         The rule is resumed automatically at `)
+//line web.qtpl:516
+			qw422016.E().S(rule.PausedUntil.Format(time.RFC3339))
+//line web.qtpl:516
+			qw422016.N().S(`.
+         `)
+//line web.qtpl:517
+		} else {
+//line web.qtpl:517
+			qw422016.N().S(`
+         The rule must be resumed via <code>/api/v1/admin/rule/resume</code> or <code>/api/v1/admin/group/resume</code> endpoint.
+         `)
+//line web.qtpl:519
+		}
+//line web.qtpl:519
+		qw422016.N().S(`
+        </div>
+      </div>
+    </div>
+    `)
+//line web.qtpl:523
+	}
+//line web.qtpl:523
+	qw422016.N().S(`
+    `)
+//line web.qtpl:524
 	if rule.Type == "alerting" {
-//line app/vmalert/web.qtpl:468
+//line web.qtpl:524
 		qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1441,17 +1641,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
          `)
-//line app/vmalert/web.qtpl:475
+//line web.qtpl:531
 		qw422016.E().V(rule.Duration)
-//line app/vmalert/web.qtpl:475
+//line web.qtpl:531
 		qw422016.N().S(` seconds
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:479
+//line web.qtpl:535
 		if rule.KeepFiringFor > 0 {
-//line app/vmalert/web.qtpl:479
+//line web.qtpl:535
 			qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1460,22 +1660,22 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
          `)
-//line app/vmalert/web.qtpl:486
+//line web.qtpl:542
 			qw422016.E().V(rule.KeepFiringFor)
-//line app/vmalert/web.qtpl:486
+//line web.qtpl:542
 			qw422016.N().S(` seconds
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:490
+//line web.qtpl:546
 		}
-//line app/vmalert/web.qtpl:490
+//line web.qtpl:546
 		qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:491
+//line web.qtpl:547
 	}
-//line app/vmalert/web.qtpl:491
+//line web.qtpl:547
 	qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1484,31 +1684,31 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:498
+//line web.qtpl:554
 	for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:498
+//line web.qtpl:554
 		qw422016.N().S(`
                 <span class="m-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:499
+//line web.qtpl:555
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:499
+//line web.qtpl:555
 		qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:499
+//line web.qtpl:555
 		qw422016.E().S(rule.Labels[k])
-//line app/vmalert/web.qtpl:499
+//line web.qtpl:555
 		qw422016.N().S(`</span>
           `)
-//line app/vmalert/web.qtpl:500
+//line web.qtpl:556
 	}
-//line app/vmalert/web.qtpl:500
+//line web.qtpl:556
 	qw422016.N().S(`
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:504
+//line web.qtpl:560
 	if rule.Type == "alerting" {
-//line app/vmalert/web.qtpl:504
+//line web.qtpl:560
 		qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1517,24 +1717,24 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:511
+//line web.qtpl:567
 		for _, k := range annotationKeys {
-//line app/vmalert/web.qtpl:511
+//line web.qtpl:567
 			qw422016.N().S(`
                 <b>`)
-//line app/vmalert/web.qtpl:512
+//line web.qtpl:568
 			qw422016.E().S(k)
-//line app/vmalert/web.qtpl:512
+//line web.qtpl:568
 			qw422016.N().S(`:</b><br>
                 <p>`)
-//line app/vmalert/web.qtpl:513
+//line web.qtpl:569
 			qw422016.E().S(rule.Annotations[k])
-//line app/vmalert/web.qtpl:513
+//line web.qtpl:569
 			qw422016.N().S(`</p>
           `)
-//line app/vmalert/web.qtpl:514
+//line web.qtpl:570
 		}
-//line app/vmalert/web.qtpl:514
+//line web.qtpl:570
 		qw422016.N().S(`
         </div>
       </div>
@@ -1546,17 +1746,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:524
+//line web.qtpl:580
 		qw422016.E().V(rule.Debug)
-//line app/vmalert/web.qtpl:524
+//line web.qtpl:580
 		qw422016.N().S(`
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:528
+//line web.qtpl:584
 	}
-//line app/vmalert/web.qtpl:528
+//line web.qtpl:584
 	qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1565,17 +1765,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.E().S(prefix)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.N().S(`groups#group-`)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.E().S(rule.GroupID)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.E().S(rule.GroupID)
-//line app/vmalert/web.qtpl:535
+//line web.qtpl:591
 	qw422016.N().S(`</a>
         </div>
       </div>
@@ -1583,9 +1783,9 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
 
     <br>
     `)
-//line app/vmalert/web.qtpl:541
+//line web.qtpl:597
 	if seriesFetchedWarning {
-//line app/vmalert/web.qtpl:541
+//line web.qtpl:597
 		qw422016.N().S(`
     <div class="alert alert-warning" role="alert">
        <strong>Warning:</strong> some of updates have "Series fetched" equal to 0.<br>
@@ -1599,18 +1799,18 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
        See more details about this detection <a target="_blank" href="https://github.com/VictoriaMetrics/VictoriaMetrics/issues/4039">here</a>.
     </div>
     `)
-//line app/vmalert/web.qtpl:553
+//line web.qtpl:609
 	}
-//line app/vmalert/web.qtpl:553
+//line web.qtpl:609
 	qw422016.N().S(`
     <div class="display-6 pb-3">Last `)
-//line app/vmalert/web.qtpl:554
+//line web.qtpl:610
 	qw422016.N().D(len(rule.Updates))
-//line app/vmalert/web.qtpl:554
+//line web.qtpl:610
 	qw422016.N().S(`/`)
-//line app/vmalert/web.qtpl:554
+//line web.qtpl:610
 	qw422016.N().D(rule.MaxUpdates)
-//line app/vmalert/web.qtpl:554
+//line web.qtpl:610
 	qw422016.N().S(` updates</span>:</div>
         <table class="table table-striped table-hover table-sm">
             <thead>
@@ -1618,13 +1818,13 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
                     <th scope="col" title="The time when event was created">Updated at</th>
                     <th scope="col" style="width: 10%" class="text-center" title="How many samples were returned">Samples</th>
                     `)
-//line app/vmalert/web.qtpl:560
+//line web.qtpl:616
 	if seriesFetchedEnabled {
-//line app/vmalert/web.qtpl:560
+//line web.qtpl:616
 		qw422016.N().S(`<th scope="col" style="width: 10%" class="text-center" title="How many series were scanned by datasource during the evaluation">Series fetched</th>`)
-//line app/vmalert/web.qtpl:560
+//line web.qtpl:616
 	}
-//line app/vmalert/web.qtpl:560
+//line web.qtpl:616
 	qw422016.N().S(`
                     <th scope="col" style="width: 10%" class="text-center" title="How many seconds request took">Duration</th>
                     <th scope="col" class="text-center" title="Time used for rule execution">Executed at</th>
@@ -1634,285 +1834,324 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule apiRule)
             <tbody>
 
      `)
-//line app/vmalert/web.qtpl:568
+//line web.qtpl:624
 	for _, u := range rule.Updates {
-//line app/vmalert/web.qtpl:568
+//line web.qtpl:624
 		qw422016.N().S(`
              <tr`)
-//line app/vmalert/web.qtpl:569
+//line web.qtpl:625
 		if u.Err != nil {
-//line app/vmalert/web.qtpl:569
+//line web.qtpl:625
 			qw422016.N().S(` class="alert-danger"`)
-//line app/vmalert/web.qtpl:569
+//line web.qtpl:625
 		}
-//line app/vmalert/web.qtpl:569
+//line web.qtpl:625
 		qw422016.N().S(`>
                  <td>
                     <span class="badge bg-primary rounded-pill me-3" title="Updated at">`)
-//line app/vmalert/web.qtpl:571
+//line web.qtpl:627
 		qw422016.E().S(u.Time.Format(time.RFC3339))
-//line app/vmalert/web.qtpl:571
+//line web.qtpl:627
 		qw422016.N().S(`</span>
                  </td>
                  <td class="text-center">`)
-//line app/vmalert/web.qtpl:573
+//line web.qtpl:629
 		qw422016.N().D(u.Samples)
-//line app/vmalert/web.qtpl:573
+//line web.qtpl:629
 		qw422016.N().S(`</td>
                  `)
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 		if seriesFetchedEnabled {
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 			qw422016.N().S(`<td class="text-center">`)
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 			if u.SeriesFetched != nil {
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 				qw422016.N().D(*u.SeriesFetched)
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 			}
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 			qw422016.N().S(`</td>`)
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 		}
-//line app/vmalert/web.qtpl:574
+//line web.qtpl:630
 		qw422016.N().S(`
                  <td class="text-center">`)
-//line app/vmalert/web.qtpl:575
+//line web.qtpl:631
 		qw422016.N().FPrec(u.Duration.Seconds(), 3)
-//line app/vmalert/web.qtpl:575
+//line web.qtpl:631
 		qw422016.N().S(`s</td>
                  <td class="text-center">`)
-//line app/vmalert/web.qtpl:576
+//line web.qtpl:632
 		qw422016.E().S(u.At.Format(time.RFC3339))
-//line app/vmalert/web.qtpl:576
+//line web.qtpl:632
 		qw422016.N().S(`</td>
                  <td>
                     <textarea class="curl-area" rows="1" onclick="this.focus();this.select()">`)
-//line app/vmalert/web.qtpl:578
+//line web.qtpl:634
 		qw422016.E().S(u.Curl)
-//line app/vmalert/web.qtpl:578
+//line web.qtpl:634
 		qw422016.N().S(`</textarea>
                 </td>
              </tr>
           </li>
           `)
-//line app/vmalert/web.qtpl:582
+//line web.qtpl:638
 		if u.Err != nil {
-//line app/vmalert/web.qtpl:582
+//line web.qtpl:638
 			qw422016.N().S(`
              <tr`)
-//line app/vmalert/web.qtpl:583
+//line web.qtpl:639
 			if u.Err != nil {
-//line app/vmalert/web.qtpl:583
+//line web.qtpl:639
 				qw422016.N().S(` class="alert-danger"`)
-//line app/vmalert/web.qtpl:583
+//line web.qtpl:639
 			}
-//line app/vmalert/web.qtpl:583
+//line web.qtpl:639
 			qw422016.N().S(`>
                <td colspan="`)
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 			if seriesFetchedEnabled {
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 				qw422016.N().S(`6`)
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 			} else {
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 				qw422016.N().S(`5`)
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 			}
-//line app/vmalert/web.qtpl:584
+//line web.qtpl:640
 			qw422016.N().S(`">
                    <span class="alert-danger">`)
-//line app/vmalert/web.qtpl:585
+//line web.qtpl:641
 			qw422016.E().V(u.Err)
-//line app/vmalert/web.qtpl:585
+//line web.qtpl:641
 			qw422016.N().S(`</span>
                </td>
              </tr>
           `)
-//line app/vmalert/web.qtpl:588
+//line web.qtpl:644
 		}
-//line app/vmalert/web.qtpl:588
+//line web.qtpl:644
 		qw422016.N().S(`
      `)
-//line app/vmalert/web.qtpl:589
+//line web.qtpl:645
 	}
-//line app/vmalert/web.qtpl:589
+//line web.qtpl:645
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:591
+//line web.qtpl:647
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:591
+//line web.qtpl:647
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 }
 
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 func WriteRuleDetails(qq422016 qtio422016.Writer, r *http.Request, rule apiRule) {
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	StreamRuleDetails(qw422016, r, rule)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 }
 
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 func RuleDetails(r *http.Request, rule apiRule) string {
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	WriteRuleDetails(qb422016, r, rule)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 	return qs422016
-//line app/vmalert/web.qtpl:592
+//line web.qtpl:648
 }
 
-//line app/vmalert/web.qtpl:596
+//line web.qtpl:652
 func streambadgeState(qw422016 *qt422016.Writer, state string) {
-//line app/vmalert/web.qtpl:596
+//line web.qtpl:652
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:598
+//line web.qtpl:654
 	badgeClass := "bg-warning text-dark"
 	if state == "firing" {
 		badgeClass = "bg-danger"
 	}
 
-//line app/vmalert/web.qtpl:602
+//line web.qtpl:658
 	qw422016.N().S(`
 <span class="badge `)
-//line app/vmalert/web.qtpl:603
+//line web.qtpl:659
 	qw422016.E().S(badgeClass)
-//line app/vmalert/web.qtpl:603
+//line web.qtpl:659
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:603
+//line web.qtpl:659
 	qw422016.E().S(state)
-//line app/vmalert/web.qtpl:603
+//line web.qtpl:659
 	qw422016.N().S(`</span>
 `)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 }
 
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 func writebadgeState(qq422016 qtio422016.Writer, state string) {
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	streambadgeState(qw422016, state)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 }
 
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 func badgeState(state string) string {
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	writebadgeState(qb422016, state)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 	return qs422016
-//line app/vmalert/web.qtpl:604
+//line web.qtpl:660
 }
 
-//line app/vmalert/web.qtpl:606
+//line web.qtpl:662
 func streambadgeRestored(qw422016 *qt422016.Writer) {
-//line app/vmalert/web.qtpl:606
+//line web.qtpl:662
 	qw422016.N().S(`
 <span class="badge bg-warning text-dark" title="Alert state was restored after the service restart from remote storage">restored</span>
 `)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 }
 
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 func writebadgeRestored(qq422016 qtio422016.Writer) {
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 	streambadgeRestored(qw422016)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 }
 
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 func badgeRestored() string {
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
 	writebadgeRestored(qb422016)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:664
+	qs422016 := string(qb422016.B)
+//line web.qtpl:664
+	qt422016.ReleaseByteBuffer(qb422016)
+//line web.qtpl:664
+	return qs422016
+//line web.qtpl:664
+}
+
+//line web.qtpl:666
+func streambadgePaused(qw422016 *qt422016.Writer, reason string) {
+//line web.qtpl:666
+	qw422016.N().S(`
+<span class="badge bg-warning text-dark" title="`)
+//line web.qtpl:667
+	qw422016.E().S(reason)
+//line web.qtpl:667
+	qw422016.N().S(`">paused</span>
+`)
+//line web.qtpl:668
+}
+
+//line web.qtpl:668
+func writebadgePaused(qq422016 qtio422016.Writer, reason string) {
+//line web.qtpl:668
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line web.qtpl:668
+	streambadgePaused(qw422016, reason)
+//line web.qtpl:668
+	qt422016.ReleaseWriter(qw422016)
+//line web.qtpl:668
+}
+
+//line web.qtpl:668
+func badgePaused(reason string) string {
+//line web.qtpl:668
+	qb422016 := qt422016.AcquireByteBuffer()
+//line web.qtpl:668
+	writebadgePaused(qb422016, reason)
+//line web.qtpl:668
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:668
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:668
 	return qs422016
-//line app/vmalert/web.qtpl:608
+//line web.qtpl:668
 }
 
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 func streambadgeStabilizing(qw422016 *qt422016.Writer) {
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 	qw422016.N().S(`
 <span class="badge bg-warning text-dark" title="This firing state is kept because of `)
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 	qw422016.N().S("`")
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 	qw422016.N().S(`keep_firing_for`)
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 	qw422016.N().S("`")
-//line app/vmalert/web.qtpl:610
+//line web.qtpl:670
 	qw422016.N().S(`">stabilizing</span>
 `)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 }
 
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 func writebadgeStabilizing(qq422016 qtio422016.Writer) {
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	streambadgeStabilizing(qw422016)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 }
 
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 func badgeStabilizing() string {
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	writebadgeStabilizing(qb422016)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 	return qs422016
-//line app/vmalert/web.qtpl:612
+//line web.qtpl:672
 }
 
-//line app/vmalert/web.qtpl:614
+//line web.qtpl:674
 func streamseriesFetchedWarn(qw422016 *qt422016.Writer, r apiRule) {
-//line app/vmalert/web.qtpl:614
+//line web.qtpl:674
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:615
+//line web.qtpl:675
 	if isNoMatch(r) {
-//line app/vmalert/web.qtpl:615
+//line web.qtpl:675
 		qw422016.N().S(`
 <svg xmlns="http://www.w3.org/2000/svg"
     data-bs-toggle="tooltip"
@@ -1923,41 +2162,41 @@ func streamseriesFetchedWarn(qw422016 *qt422016.Writer, r apiRule) {
        <path d="M8 16A8 8 0 1 0 8 0a8 8 0 0 0 0 16zm.93-9.412-1 4.705c-.07.34.029.533.304.533.194 0 .487-.07.686-.246l-.088.416c-.287.346-.92.598-1.465.598-.703 0-1.002-.422-.808-1.319l.738-3.468c.064-.293.006-.399-.287-.47l-.451-.081.082-.381 2.29-.287zM8 5.5a1 1 0 1 1 0-2 1 1 0 0 1 0 2z"/>
 </svg>
 `)
-//line app/vmalert/web.qtpl:624
+//line web.qtpl:684
 	}
-//line app/vmalert/web.qtpl:624
+//line web.qtpl:684
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 }
 
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 func writeseriesFetchedWarn(qq422016 qtio422016.Writer, r apiRule) {
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	streamseriesFetchedWarn(qw422016, r)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 }
 
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 func seriesFetchedWarn(r apiRule) string {
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	writeseriesFetchedWarn(qb422016, r)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 	return qs422016
-//line app/vmalert/web.qtpl:625
+//line web.qtpl:685
 }
 
-//line app/vmalert/web.qtpl:628
+//line web.qtpl:688
 func isNoMatch(r apiRule) bool {
 	return r.LastSamples == 0 && r.LastSeriesFetched != nil && *r.LastSeriesFetched == 0
 }
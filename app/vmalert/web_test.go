@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/rule"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
 )
 
 func TestHandler(t *testing.T) {
@@ -84,6 +87,13 @@ func TestHandler(t *testing.T) {
 			getResp(t, ts.URL+"/vmalert/"+a.WebLink(), nil, 200)
 		}
 	})
+	t.Run("/vmalert/alert-relabel-debug", func(t *testing.T) {
+		alerts := ruleToAPIAlert(ar)
+		for _, a := range alerts {
+			params := fmt.Sprintf("?%s=%s&%s=%s&format=json", paramGroupID, a.GroupID, paramAlertID, a.ID)
+			getResp(t, ts.URL+"/vmalert/alert-relabel-debug"+params, nil, 200)
+		}
+	})
 	t.Run("/vmalert/rule?badParam", func(t *testing.T) {
 		params := fmt.Sprintf("?%s=0&%s=1", paramGroupID, paramRuleID)
 		getResp(t, ts.URL+"/vmalert/rule"+params, nil, 404)
@@ -240,6 +250,88 @@ func TestHandler(t *testing.T) {
 	})
 }
 
+func TestHandlerStateExportImport(t *testing.T) {
+	fq := &datasource.FakeQuerier{}
+	fq.Add(datasource.Metric{
+		Values: []float64{1}, Timestamps: []int64{0},
+	})
+	newManager := func() (*manager, *rule.AlertingRule) {
+		g := rule.NewGroup(config.Group{
+			Name:        "group",
+			File:        "rules.yaml",
+			Concurrency: 1,
+			Rules: []config.Rule{
+				{ID: 0, Alert: "alert", For: promutil.NewDuration(time.Hour)},
+			},
+		}, fq, 1*time.Minute, nil)
+		m := &manager{groups: map[uint64]*rule.Group{
+			g.CreateID(): g,
+		}}
+		return m, g.Rules[0].(*rule.AlertingRule)
+	}
+
+	mSrc, arSrc := newManager()
+	ts := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for _, g := range mSrc.groups {
+		g.ExecOnce(context.Background(), func() []notifier.Notifier { return nil }, nil, ts)
+	}
+	mDst, arDst := newManager()
+
+	rhSrc := &requestHandler{m: mSrc}
+	tsSrc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rhSrc.handler(w, r) }))
+	defer tsSrc.Close()
+	rhDst := &requestHandler{m: mDst}
+	tsDst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rhDst.handler(w, r) }))
+	defer tsDst.Close()
+
+	postState := func(data string, code int) {
+		t.Helper()
+		resp, err := http.Post(tsDst.URL+"/api/v1/state/import", "application/json", strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected err %s", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Fatalf("unexpected status code %d want %d", resp.StatusCode, code)
+		}
+	}
+
+	resp, err := http.Get(tsSrc.URL + "/vmalert/api/v1/state/export")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	var sr stateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	_ = resp.Body.Close()
+	if len(sr.Data.Rules) != 1 || len(sr.Data.Rules[0].Alerts) != 1 {
+		t.Fatalf("expected 1 rule with 1 alert in exported state; got %v", sr.Data.Rules)
+	}
+	data, err := json.Marshal(sr)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+
+	// invalid requests mustn't change the state
+	postState("foobar", 400)
+	postState(strings.ReplaceAll(string(data), `"pending"`, `"foobar"`), 400)
+	if len(arDst.GetAlerts()) != 0 {
+		t.Fatalf("expected no alerts after invalid import; got %d", len(arDst.GetAlerts()))
+	}
+
+	postState(string(data), 200)
+	aSrc := arSrc.GetAlerts()[0]
+	aDst := arDst.GetAlert(aSrc.ID)
+	if aDst == nil {
+		t.Fatalf("cannot find imported alert")
+	}
+	if aDst.State != notifier.StatePending || !aDst.ActiveAt.Equal(aSrc.ActiveAt) || !aDst.Restored {
+		t.Fatalf("unexpected imported alert; got state=%s, activeAt=%v, restored=%v; want state=%s, activeAt=%v, restored=true",
+			aDst.State, aDst.ActiveAt, aDst.Restored, aSrc.State, aSrc.ActiveAt)
+	}
+}
+
 func TestEmptyResponse(t *testing.T) {
 	rhWithNoGroups := &requestHandler{m: &manager{groups: make(map[uint64]*rule.Group)}}
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rhWithNoGroups.handler(w, r) }))
@@ -316,3 +408,256 @@ func TestEmptyResponse(t *testing.T) {
 		}
 	})
 }
+
+func TestHandlerTestAlert(t *testing.T) {
+	fn := &notifier.FakeNotifier{}
+	am, err := notifier.NewAlertManager("http://localhost:9093/api/v2/alerts", func(_ notifier.Alert) string {
+		return "http://vmalert"
+	}, promauth.HTTPClientConfig{}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	defer am.Close()
+
+	var nts []notifier.Notifier
+	m := &manager{
+		groups:    make(map[uint64]*rule.Group),
+		notifiers: func() []notifier.Notifier { return nts },
+		labels:    map[string]string{"cluster": "prod", "env": "prod"},
+	}
+	rh := &requestHandler{m: m}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rh.handler(w, r) }))
+	defer ts.Close()
+
+	postTestAlert := func(path, data string, code int) testAlertResponse {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected err %s", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != code {
+			t.Fatalf("unexpected status code %d want %d", resp.StatusCode, code)
+		}
+		var tr testAlertResponse
+		if code == http.StatusOK || code == http.StatusBadGateway {
+			if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+				t.Fatalf("unexpected err %s", err)
+			}
+		}
+		return tr
+	}
+	reqBody := `{"name":"OnCallCheck","labels":{"severity":"critical","env":"test"},"annotations":{"summary":"test alert"}}`
+
+	// no notifiers configured
+	postTestAlert("/api/v1/test_alert", reqBody, http.StatusBadRequest)
+
+	nts = []notifier.Notifier{fn}
+
+	// invalid request body
+	postTestAlert("/api/v1/test_alert", "foobar", http.StatusBadRequest)
+	if fn.GetCounter() != 0 {
+		t.Fatalf("expected no alerts to be sent; got %d", fn.GetCounter())
+	}
+
+	// only POST is supported
+	resp, err := http.Get(ts.URL + "/api/v1/test_alert")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code %d want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	tr := postTestAlert("/vmalert/api/v1/test_alert", reqBody, http.StatusOK)
+	if tr.Status != "success" || len(tr.Data.Notifiers) != 1 || tr.Data.Notifiers[0].Error != "" {
+		t.Fatalf("unexpected response: %+v", tr)
+	}
+	alerts := fn.GetAlerts()
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert to be sent; got %d", len(alerts))
+	}
+	a := alerts[0]
+	expLabels := map[string]string{"alertname": "OnCallCheck", "cluster": "prod", "env": "test", "severity": "critical"}
+	if !reflect.DeepEqual(a.Labels, expLabels) {
+		t.Fatalf("unexpected alert labels; got %v; want %v", a.Labels, expLabels)
+	}
+	if a.State != notifier.StateFiring || a.Annotations["summary"] != "test alert" || a.End.Sub(a.Start) != testAlertDuration {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+
+	// failed notifier must be reported
+	nts = []notifier.Notifier{fn, &notifier.FaultyNotifier{}}
+	tr = postTestAlert("/api/v1/test_alert", reqBody, http.StatusBadGateway)
+	if tr.Status != "error" || len(tr.Data.Notifiers) != 2 || tr.Data.Notifiers[0].Error != "" || tr.Data.Notifiers[1].Error != "send failed" {
+		t.Fatalf("unexpected response: %+v", tr)
+	}
+	if fn.GetCounter() != 2 {
+		t.Fatalf("expected 2 alerts to be sent in total; got %d", fn.GetCounter())
+	}
+
+	// dry run mustn't send alerts
+	nts = []notifier.Notifier{fn, am}
+	tr = postTestAlert("/api/v1/test_alert?dry_run=1", reqBody, http.StatusOK)
+	if fn.GetCounter() != 2 {
+		t.Fatalf("expected no alerts to be sent on dry run; got %d in total", fn.GetCounter())
+	}
+	if len(tr.Data.Notifiers) != 2 || tr.Data.Notifiers[0].Payload != nil {
+		t.Fatalf("unexpected response: %+v", tr)
+	}
+	var payload []struct {
+		GeneratorURL string            `json:"generatorURL"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(tr.Data.Notifiers[1].Payload, &payload); err != nil {
+		t.Fatalf("cannot parse rendered payload %q: %s", tr.Data.Notifiers[1].Payload, err)
+	}
+	if len(payload) != 1 || payload[0].GeneratorURL != "http://vmalert" || !reflect.DeepEqual(payload[0].Labels, expLabels) ||
+		payload[0].Annotations["summary"] != "test alert" {
+		t.Fatalf("unexpected rendered payload: %s", tr.Data.Notifiers[1].Payload)
+	}
+}
+
+func TestHandlerAdminPauseResume(t *testing.T) {
+	fq := &datasource.FakeQuerier{}
+	newManager := func() (*manager, *rule.RecordingRule) {
+		g := rule.NewGroup(config.Group{
+			Name:        "admin-group",
+			File:        "rules.yaml",
+			Concurrency: 1,
+			Rules: []config.Rule{
+				{ID: 1, Record: "record"},
+			},
+		}, fq, 1*time.Minute, nil)
+		m := &manager{groups: map[uint64]*rule.Group{
+			g.CreateID(): g,
+		}}
+		return m, g.Rules[0].(*rule.RecordingRule)
+	}
+	m, rr := newManager()
+	rh := &requestHandler{m: m}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rh.handler(w, r) }))
+	defer ts.Close()
+
+	if err := adminAuthKey.Set("secret"); err != nil {
+		t.Fatalf("cannot set -adminAuthKey: %s", err)
+	}
+	defer func() {
+		_ = adminAuthKey.Set("")
+	}()
+
+	post := func(path string, statusCodeExpected int) map[string]bool {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected err %s", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code for %s; got %d; want %d", path, resp.StatusCode, statusCodeExpected)
+		}
+		if statusCodeExpected != http.StatusOK {
+			return nil
+		}
+		var res struct {
+			Status string          `json:"status"`
+			Data   map[string]bool `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("unexpected err %s", err)
+		}
+		return res.Data
+	}
+
+	a := ruleToAPI(rr)
+	ruleArgs := fmt.Sprintf("%s=%s&%s=%s", paramGroupID, a.GroupID, paramRuleID, a.ID)
+	groupArgs := fmt.Sprintf("%s=%s", paramGroupID, a.GroupID)
+
+	// missing or invalid authKey
+	post("/api/v1/admin/rule/pause?"+ruleArgs, http.StatusUnauthorized)
+	post("/api/v1/admin/rule/pause?authKey=foo&"+ruleArgs, http.StatusUnauthorized)
+
+	// unknown rule and group
+	post(fmt.Sprintf("/api/v1/admin/rule/pause?authKey=secret&%s=%s&%s=123", paramGroupID, a.GroupID, paramRuleID), http.StatusNotFound)
+	post(fmt.Sprintf("/api/v1/admin/group/pause?authKey=secret&%s=123", paramGroupID), http.StatusNotFound)
+
+	if data := post("/api/v1/admin/rule/pause?authKey=secret&reason=noisy&"+ruleArgs, http.StatusOK); !data["paused"] {
+		t.Fatalf("expecting the rule to be paused; got %v", data)
+	}
+	if data := post("/vmalert/api/v1/admin/rule/pause?authKey=secret&"+ruleArgs, http.StatusOK); data["paused"] {
+		t.Fatalf("unexpected pause of already paused rule")
+	}
+
+	a = ruleToAPI(rr)
+	if !a.Paused || a.PausedBy != "manual" || !strings.Contains(a.PausedReason, "noisy") {
+		t.Fatalf("unexpected pause state; got paused=%v, pausedBy=%q, reason=%q", a.Paused, a.PausedBy, a.PausedReason)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/admin/paused?authKey=secret")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	var lr struct {
+		Data struct {
+			Paused []apiPausedRule `json:"paused"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&lr); err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	_ = resp.Body.Close()
+	if len(lr.Data.Paused) != 1 {
+		t.Fatalf("unexpected number of paused rules; got %d; want 1", len(lr.Data.Paused))
+	}
+	if pr := lr.Data.Paused[0]; pr.GroupName != "admin-group" || pr.RuleName != "record" || pr.RuleID != a.ID || pr.PausedBy != "manual" || !strings.Contains(pr.Reason, "noisy") {
+		t.Fatalf("unexpected paused rule: %+v", pr)
+	}
+
+	if data := post("/api/v1/admin/rule/resume?authKey=secret&"+ruleArgs, http.StatusOK); !data["resumed"] {
+		t.Fatalf("expecting the rule to be resumed; got %v", data)
+	}
+	if data := post("/api/v1/admin/rule/resume?authKey=secret&"+ruleArgs, http.StatusOK); data["resumed"] {
+		t.Fatalf("unexpected resume of not paused rule")
+	}
+	if data := post("/api/v1/admin/group/pause?authKey=secret&"+groupArgs, http.StatusOK); !data["paused"] {
+		t.Fatalf("expecting the group to be paused; got %v", data)
+	}
+	if a := ruleToAPI(rr); !a.Paused || !strings.Contains(a.PausedReason, "the group is paused via API") {
+		t.Fatalf("expecting the rule to be paused together with its group; got paused=%v, reason=%q", a.Paused, a.PausedReason)
+	}
+	if data := post("/api/v1/admin/group/resume?authKey=secret&"+groupArgs, http.StatusOK); !data["resumed"] {
+		t.Fatalf("expecting the group to be resumed; got %v", data)
+	}
+	if data := post("/api/v1/admin/group/resume?authKey=secret&"+groupArgs, http.StatusOK); data["resumed"] {
+		t.Fatalf("unexpected resume of not paused group")
+	}
+	if a := ruleToAPI(rr); a.Paused {
+		t.Fatalf("unexpected paused rule after resume")
+	}
+
+	// resuming unknown rule and group
+	post(fmt.Sprintf("/api/v1/admin/rule/resume?authKey=secret&%s=%s&%s=123", paramGroupID, a.GroupID, paramRuleID), http.StatusNotFound)
+	post(fmt.Sprintf("/api/v1/admin/group/resume?authKey=secret&%s=123", paramGroupID), http.StatusNotFound)
+
+	// GET isn't supported
+	resp, err = http.Get(ts.URL + "/api/v1/admin/rule/pause?authKey=secret&" + ruleArgs)
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code %d want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	// the OpenAPI spec is available without authKey
+	resp, err = http.Get(ts.URL + "/api/v1/admin/openapi.yaml")
+	if err != nil {
+		t.Fatalf("unexpected err %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d want %d", resp.StatusCode, http.StatusOK)
+	}
+}
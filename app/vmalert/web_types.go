@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"sort"
@@ -16,10 +17,36 @@ const (
 	paramGroupID = "group_id"
 	// ParamAlertID is alert id key in url parameter
 	paramAlertID = "alert_id"
+	// ParamNotifier is notifier address key in url parameter
+	paramNotifier = "notifier"
 	// ParamRuleID is rule id key in url parameter
 	paramRuleID = "rule_id"
+	// ParamReason is pause reason key in url parameter
+	paramReason = "reason"
 )
 
+// apiPausedRule represents the rule paused via admin API or because of exceeding evaluation budget
+type apiPausedRule struct {
+	// GroupID is the ID of the group of the paused rule
+	GroupID string `json:"group_id"`
+	// GroupName is the group name
+	GroupName string `json:"group_name"`
+	// File is file name where the group is defined
+	File string `json:"file"`
+	// RuleID is the ID of the paused rule
+	RuleID string `json:"rule_id"`
+	// RuleName is the rule name
+	RuleName string `json:"rule_name"`
+	// PausedBy is the reason why the rule is paused: `manual` or `budget`
+	PausedBy string `json:"paused_by"`
+	// Reason contains the description of the pause
+	Reason string `json:"reason"`
+	// Since is the time when the rule was paused
+	Since time.Time `json:"since"`
+	// Until is the time when the paused rule is automatically resumed
+	Until *time.Time `json:"until,omitempty"`
+}
+
 // apiAlert represents a notifier.AlertingRule state
 // for WEB view
 // https://github.com/prometheus/compliance/blob/main/alert_generator/specification.md#get-apiv1rules
@@ -158,6 +185,15 @@ type apiRule struct {
 	// Debug shows whether debug mode is enabled
 	Debug bool `json:"debug"`
 
+	// Paused shows whether the rule is paused because of exceeding evaluation budget or via admin API
+	Paused bool `json:"paused,omitempty"`
+	// PausedBy is the reason why the rule is paused: `manual` or `budget`
+	PausedBy string `json:"pausedBy,omitempty"`
+	// PausedReason contains the description of the pause
+	PausedReason string `json:"pausedReason,omitempty"`
+	// PausedUntil is the time when the paused rule is automatically resumed
+	PausedUntil *time.Time `json:"pausedUntil,omitempty"`
+
 	// MaxUpdates is the max number of recorded ruleStateEntry objects
 	MaxUpdates int `json:"max_updates_entries"`
 	// Updates contains the ordered list of recorded ruleStateEntry objects
@@ -224,6 +260,7 @@ func recordingToAPI(rr *rule.RecordingRule) apiRule {
 		r.LastError = lastState.Err.Error()
 		r.Health = "err"
 	}
+	setPauseState(&r, rr)
 	return r
 }
 
@@ -260,6 +297,7 @@ func alertingToAPI(ar *rule.AlertingRule) apiRule {
 		r.LastError = lastState.Err.Error()
 		r.Health = "err"
 	}
+	setPauseState(&r, ar)
 	// satisfy apiRule.State logic
 	if len(r.Alerts) > 0 {
 		r.State = notifier.StatePending.String()
@@ -274,6 +312,20 @@ func alertingToAPI(ar *rule.AlertingRule) apiRule {
 	return r
 }
 
+// setPauseState sets pause state of r to dst
+func setPauseState(dst *apiRule, r rule.Rule) {
+	ps := rule.GetPauseState(r)
+	if !ps.Paused {
+		return
+	}
+	dst.Paused = true
+	dst.PausedBy = string(ps.Reason)
+	dst.PausedReason = ps.Message
+	if !ps.Until.IsZero() {
+		dst.PausedUntil = &ps.Until
+	}
+}
+
 // ruleToAPIAlert generates list of apiAlert objects from existing alerts
 func ruleToAPIAlert(ar *rule.AlertingRule) []*apiAlert {
 	var alerts []*apiAlert
@@ -392,3 +444,150 @@ func headersToStrings(headers map[string]string) []string {
 
 	return res
 }
+
+// apiRuleState represents alerts state of alerting rule
+// for transferring it between vmalert instances via /api/v1/state/export and /api/v1/state/import
+type apiRuleState struct {
+	// GroupID is an unique Group's ID
+	GroupID string `json:"group_id"`
+	// GroupName is the name of the Group the rule belongs to
+	GroupName string `json:"group"`
+	// File is the path to the file the Group was loaded from
+	File string `json:"file"`
+	// RuleID is an unique Rule's ID within a group
+	RuleID string `json:"rule_id"`
+	// Name is the name of the alerting rule
+	Name string `json:"name"`
+	// Alerts contains the alerts of the rule
+	Alerts []apiAlertState `json:"alerts"`
+}
+
+// apiAlertState represents the state of a single alert, including its `for` timer
+type apiAlertState struct {
+	State       string            `json:"state"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Value       string            `json:"value"`
+	// ActiveAt is the time when the alert has become active. It is used as `for` timer for pending alerts
+	ActiveAt time.Time `json:"activeAt"`
+	// Start is the time when the alert has become firing
+	Start time.Time `json:"start"`
+	// ResolvedAt is the time when the alert has become inactive
+	ResolvedAt time.Time `json:"resolvedAt"`
+	// LastSent is the time when the alert was sent to notifiers last time
+	LastSent time.Time `json:"lastSent"`
+	// KeepFiringSince is the time since the alert is kept firing because of `keep_firing_for`
+	KeepFiringSince time.Time `json:"keepFiringSince"`
+}
+
+// stateResponse is the response for /api/v1/state/export.
+// The same format is accepted by /api/v1/state/import.
+type stateResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Rules []apiRuleState `json:"rules"`
+	} `json:"data"`
+}
+
+// testAlertDuration is the duration after which the test alert is resolved by notifiers,
+// since vmalert never sends the resolve notification for it.
+const testAlertDuration = 5 * time.Minute
+
+// testAlertRequest is the request body for /api/v1/test_alert.
+type testAlertRequest struct {
+	// Name is the alert name. It is set to `alertname` label
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// toAlert returns firing alert for tr.
+//
+// extLabels are added to the alert labels unless they are overridden by tr.Labels.
+func (tr *testAlertRequest) toAlert(extLabels map[string]string, now time.Time) notifier.Alert {
+	name := tr.Name
+	if name == "" {
+		name = tr.Labels["alertname"]
+	}
+	if name == "" {
+		name = "TestAlert"
+	}
+	labels := make(map[string]string, len(extLabels)+len(tr.Labels)+1)
+	for k, v := range extLabels {
+		labels[k] = v
+	}
+	for k, v := range tr.Labels {
+		labels[k] = v
+	}
+	labels["alertname"] = name
+	return notifier.Alert{
+		Name:        name,
+		Labels:      labels,
+		Annotations: tr.Annotations,
+		State:       notifier.StateFiring,
+		ActiveAt:    now,
+		Start:       now,
+		End:         now.Add(testAlertDuration),
+		LastSent:    now,
+	}
+}
+
+// testAlertNotifierResult is the result of sending test alert to a single notifier.
+type testAlertNotifierResult struct {
+	Addr  string `json:"addr"`
+	Error string `json:"error,omitempty"`
+	// Payload is the request body, which would be sent to the notifier. It is set only for dry run
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// testAlertResponse is the response for /api/v1/test_alert.
+type testAlertResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Notifiers []testAlertNotifierResult `json:"notifiers"`
+	} `json:"data"`
+}
+
+func newAlertStateAPI(a *notifier.Alert) apiAlertState {
+	return apiAlertState{
+		State:           a.State.String(),
+		Labels:          a.Labels,
+		Annotations:     a.Annotations,
+		Value:           strconv.FormatFloat(a.Value, 'g', -1, 64),
+		ActiveAt:        a.ActiveAt,
+		Start:           a.Start,
+		ResolvedAt:      a.ResolvedAt,
+		LastSent:        a.LastSent,
+		KeepFiringSince: a.KeepFiringSince,
+	}
+}
+
+func (as *apiAlertState) toAlert() (notifier.Alert, error) {
+	a := notifier.Alert{
+		Labels:          as.Labels,
+		Annotations:     as.Annotations,
+		ActiveAt:        as.ActiveAt,
+		Start:           as.Start,
+		ResolvedAt:      as.ResolvedAt,
+		LastSent:        as.LastSent,
+		KeepFiringSince: as.KeepFiringSince,
+	}
+	switch as.State {
+	case "firing":
+		a.State = notifier.StateFiring
+	case "pending":
+		a.State = notifier.StatePending
+	case "inactive":
+		a.State = notifier.StateInactive
+	default:
+		return a, fmt.Errorf("unsupported alert state %q; want one of firing, pending or inactive", as.State)
+	}
+	if as.Value != "" {
+		v, err := strconv.ParseFloat(as.Value, 64)
+		if err != nil {
+			return a, fmt.Errorf("cannot parse alert value %q: %w", as.Value, err)
+		}
+		a.Value = v
+	}
+	return a, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"math"
@@ -40,7 +41,7 @@ var (
 	defaultRetryStatusCodes = flagutil.NewArrayInt("retryStatusCodes", 0, "Comma-separated list of default HTTP response status codes when vmauth re-tries the request on other backends. "+
 		"See https://docs.victoriametrics.com/vmauth/#load-balancing for details")
 	defaultLoadBalancingPolicy = flag.String("loadBalancingPolicy", "least_loaded", "The default load balancing policy to use for backend urls specified inside url_prefix section. "+
-		"Supported policies: least_loaded, first_available. See https://docs.victoriametrics.com/vmauth/#load-balancing")
+		"Supported policies: least_loaded, first_available, consistent_hashing. See https://docs.victoriametrics.com/vmauth/#load-balancing")
 	discoverBackendIPsGlobal = flag.Bool("discoverBackendIPs", false, "Whether to discover backend IPs via periodic DNS queries to hostnames specified in url_prefix. "+
 		"This may be useful when url_prefix points to a hostname with dynamically scaled instances behind it. See https://docs.victoriametrics.com/vmauth/#discovering-backend-ips")
 	discoverBackendIPsInterval = flag.Duration("discoverBackendIPsInterval", 10*time.Second, "The interval for re-discovering backend IPs if -discoverBackendIPs command-line flag is set. "+
@@ -81,9 +82,15 @@ type UserInfo struct {
 	TLSKeyFile             string      `yaml:"tls_key_file,omitempty"`
 	TLSServerName          string      `yaml:"tls_server_name,omitempty"`
 	TLSInsecureSkipVerify  *bool       `yaml:"tls_insecure_skip_verify,omitempty"`
+	TenantFromHeader       string      `yaml:"tenant_from_header,omitempty"`
+	TenantFromClaim        string      `yaml:"tenant_from_claim,omitempty"`
+	AllowedTenants         []string    `yaml:"allowed_tenants,omitempty"`
 
 	MetricLabels map[string]string `yaml:"metric_labels,omitempty"`
 
+	// allowedTenants contains normalized AllowedTenants in the form accountID:projectID.
+	allowedTenants map[string]struct{}
+
 	concurrencyLimitCh      chan struct{}
 	concurrencyLimitReached *metrics.Counter
 
@@ -184,6 +191,9 @@ type URLMap struct {
 
 	// DropSrcPathPrefixParts is the number of `/`-delimited request path prefix parts to drop before proxying the request to backend.
 	DropSrcPathPrefixParts *int `yaml:"drop_src_path_prefix_parts,omitempty"`
+
+	// Canary is an optional config for routing a share of requests to canary backends.
+	Canary *CanaryConfig `yaml:"canary,omitempty"`
 }
 
 // QueryArg represents HTTP query arg
@@ -264,11 +274,12 @@ func (up *URLPrefix) setLoadBalancingPolicy(loadBalancingPolicy string) error {
 	switch loadBalancingPolicy {
 	case "", // empty string is equivalent to least_loaded
 		"least_loaded",
-		"first_available":
+		"first_available",
+		"consistent_hashing":
 		up.loadBalancingPolicy = loadBalancingPolicy
 		return nil
 	default:
-		return fmt.Errorf("unexpected load_balancing_policy: %q; want least_loaded or first_available", loadBalancingPolicy)
+		return fmt.Errorf("unexpected load_balancing_policy: %q; want least_loaded, first_available or consistent_hashing", loadBalancingPolicy)
 	}
 }
 
@@ -277,6 +288,16 @@ type backendURL struct {
 	concurrentRequests atomic.Int32
 
 	url *url.URL
+
+	// urlHash is the hash of url. It is used for consistent_hashing load balancing policy.
+	urlHash uint64
+}
+
+func newBackendURL(u *url.URL) *backendURL {
+	return &backendURL{
+		url:     u,
+		urlHash: xxhash.Sum64String(u.String()),
+	}
 }
 
 func (bu *backendURL) isBroken() bool {
@@ -308,6 +329,17 @@ func (up *URLPrefix) getBackendsCount() int {
 //
 // backendURL.put() must be called on the returned backendURL after the request is complete.
 func (up *URLPrefix) getBackendURL() *backendURL {
+	return up.getBackendURLForKey("")
+}
+
+// getBackendURLForKey returns the backendURL depending on the load balance policy.
+//
+// Requests with the same stickyKey are routed to the same backend if consistent_hashing load balancing policy is used.
+//
+// It can return nil if there are no backend urls available at the moment.
+//
+// backendURL.put() must be called on the returned backendURL after the request is complete.
+func (up *URLPrefix) getBackendURLForKey(stickyKey string) *backendURL {
 	up.discoverBackendAddrsIfNeeded()
 
 	pbus := up.bus.Load()
@@ -316,10 +348,14 @@ func (up *URLPrefix) getBackendURL() *backendURL {
 		return nil
 	}
 
-	if up.loadBalancingPolicy == "first_available" {
+	switch up.loadBalancingPolicy {
+	case "first_available":
 		return getFirstAvailableBackendURL(bus)
+	case "consistent_hashing":
+		return getConsistentHashingBackendURL(bus, stickyKey)
+	default:
+		return getLeastLoadedBackendURL(bus, &up.n)
 	}
-	return getLeastLoadedBackendURL(bus, &up.n)
 }
 
 func (up *URLPrefix) discoverBackendAddrsIfNeeded() {
@@ -399,9 +435,7 @@ func (up *URLPrefix) discoverBackendAddrsIfNeeded() {
 		for _, addr := range hostToAddrs[host] {
 			buCopy := *bu
 			buCopy.Host = addr
-			busNew = append(busNew, &backendURL{
-				url: &buCopy,
-			})
+			busNew = append(busNew, newBackendURL(&buCopy))
 		}
 	}
 
@@ -449,6 +483,48 @@ func getFirstAvailableBackendURL(bus []*backendURL) *backendURL {
 	return bu
 }
 
+// getConsistentHashingBackendURL returns the backendURL for the given stickyKey.
+//
+// Requests with the same stickyKey are routed to the same backend while it is available.
+// Rendezvous hashing is used, so only requests routed to unavailable backends are re-routed to other backends,
+// and the routing for the remaining stickyKey values isn't changed when backends are added or removed.
+//
+// backendURL.put() must be called on the returned backendURL after the request is complete.
+func getConsistentHashingBackendURL(bus []*backendURL, stickyKey string) *backendURL {
+	keyHash := xxhash.Sum64String(stickyKey)
+	var buMax *backendURL
+	var hMax uint64
+	var buBrokenMax *backendURL
+	var hBrokenMax uint64
+	for _, bu := range bus {
+		h := getRendezvousHash(keyHash, bu.urlHash)
+		if bu.isBroken() {
+			if buBrokenMax == nil || h > hBrokenMax {
+				buBrokenMax = bu
+				hBrokenMax = h
+			}
+			continue
+		}
+		if buMax == nil || h > hMax {
+			buMax = bu
+			hMax = h
+		}
+	}
+	if buMax == nil {
+		// All the backends are temporarily unavailable. Fall back to the backend for the stickyKey.
+		buMax = buBrokenMax
+	}
+	buMax.get()
+	return buMax
+}
+
+func getRendezvousHash(keyHash, urlHash uint64) uint64 {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], keyHash)
+	binary.BigEndian.PutUint64(buf[8:], urlHash)
+	return xxhash.Sum64(buf[:])
+}
+
 // getLeastLoadedBackendURL returns the backendURL with the minimum number of concurrent requests.
 //
 // backendURL.put() must be called on the returned backendURL after the request is complete.
@@ -760,6 +836,7 @@ func parseAuthConfig(data []byte) (*AuthConfig, error) {
 			return nil, fmt.Errorf("cannot parse metric_labels for unauthorized_user: %w", err)
 		}
 		ui.requests = ac.ms.NewCounter(`vmauth_unauthorized_user_requests_total` + metricLabels)
+		ui.initCanaryMetrics(ac.ms, metricLabels)
 		ui.backendErrors = ac.ms.NewCounter(`vmauth_unauthorized_user_request_backend_errors_total` + metricLabels)
 		ui.requestsDuration = ac.ms.NewSummary(`vmauth_unauthorized_user_request_duration_seconds` + metricLabels)
 		ui.concurrencyLimitCh = make(chan struct{}, ui.getMaxConcurrentRequests())
@@ -808,6 +885,7 @@ func parseAuthConfigUsers(ac *AuthConfig) (map[string]*UserInfo, error) {
 			return nil, fmt.Errorf("cannot parse metric_labels: %w", err)
 		}
 		ui.requests = ac.ms.GetOrCreateCounter(`vmauth_user_requests_total` + metricLabels)
+		ui.initCanaryMetrics(ac.ms, metricLabels)
 		ui.backendErrors = ac.ms.GetOrCreateCounter(`vmauth_user_request_backend_errors_total` + metricLabels)
 		ui.requestsDuration = ac.ms.GetOrCreateSummary(`vmauth_user_request_duration_seconds` + metricLabels)
 		mcr := ui.getMaxConcurrentRequests()
@@ -856,7 +934,19 @@ func (ui *UserInfo) getMetricLabels() (string, error) {
 	return labelsStr, nil
 }
 
+func (ui *UserInfo) initCanaryMetrics(ms *metrics.Set, metricLabels string) {
+	for _, e := range ui.URLMaps {
+		if e.Canary != nil {
+			e.Canary.initMetrics(ms, metricLabels)
+		}
+	}
+}
+
 func (ui *UserInfo) initURLs() error {
+	if err := ui.validateTenantRewrite(); err != nil {
+		return err
+	}
+
 	retryStatusCodes := defaultRetryStatusCodes.Values()
 	loadBalancingPolicy := *defaultLoadBalancingPolicy
 	dropSrcPathPrefixParts := 0
@@ -922,6 +1012,18 @@ func (ui *UserInfo) initURLs() error {
 		}
 		e.URLPrefix.dropSrcPathPrefixParts = dsp
 		e.URLPrefix.discoverBackendIPs = dbd
+
+		if cc := e.Canary; cc != nil {
+			if err := cc.init(); err != nil {
+				return err
+			}
+			cc.URLPrefix.retryStatusCodes = rscs
+			if err := cc.URLPrefix.setLoadBalancingPolicy(lbp); err != nil {
+				return err
+			}
+			cc.URLPrefix.dropSrcPathPrefixParts = dsp
+			cc.URLPrefix.discoverBackendIPs = dbd
+		}
 	}
 	if len(ui.URLMaps) == 0 && ui.URLPrefix == nil {
 		return fmt.Errorf("missing `url_prefix` or `url_map`")
@@ -1033,9 +1135,7 @@ func (up *URLPrefix) sanitizeAndInitialize() error {
 	// Initialize up.bus
 	bus := make([]*backendURL, len(up.busOriginal))
 	for i, bu := range up.busOriginal {
-		bus[i] = &backendURL{
-			url: bu,
-		}
+		bus[i] = newBackendURL(bu)
 	}
 	up.bus.Store(&bus)
 
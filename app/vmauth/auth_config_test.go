@@ -36,6 +36,14 @@ users:
 - username: foo
 `)
 
+	// Invalid load_balancing_policy
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  load_balancing_policy: foobar
+`)
+
 	// Invalid url_prefix
 	f(`
 users:
@@ -277,6 +285,99 @@ users:
   metric_labels:
     not-prometheus-compatible: value
 `)
+
+	// Missing url_prefix in canary
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/api/v1/query']
+    url_prefix: http://foobar
+    canary:
+      percent: 5
+`)
+
+	// Invalid url_prefix in canary
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/api/v1/query']
+    url_prefix: http://foobar
+    canary:
+      url_prefix: ftp://foobar
+      percent: 5
+`)
+
+	// Invalid percent in canary
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/api/v1/query']
+    url_prefix: http://foobar
+    canary:
+      url_prefix: http://canary
+      percent: 105
+`)
+
+	// Invalid max_error_rate in canary
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/api/v1/query']
+    url_prefix: http://foobar
+    canary:
+      url_prefix: http://canary
+      percent: 5
+      max_error_rate: 2
+`)
+
+	// Both tenant_from_header and tenant_from_claim are set
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  tenant_from_header: X-Scope-OrgID
+  tenant_from_claim: tenant
+  allowed_tenants: ["1"]
+`)
+
+	// Missing allowed_tenants
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  tenant_from_header: X-Scope-OrgID
+`)
+
+	// Invalid allowed_tenants
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  tenant_from_header: X-Scope-OrgID
+  allowed_tenants: ["foo"]
+`)
+
+	// allowed_tenants without tenant_from_header and tenant_from_claim
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  allowed_tenants: ["1"]
+`)
+
+	// tenant_from_claim without bearer_token
+	f(`
+users:
+- username: a
+  password: b
+  url_prefix: http://foobar
+  tenant_from_claim: tenant
+  allowed_tenants: ["1"]
+`)
 }
 
 func TestParseAuthConfigSuccess(t *testing.T) {
@@ -801,6 +902,65 @@ func TestBrokenBackend(t *testing.T) {
 	}
 }
 
+func TestConsistentHashingBackend(t *testing.T) {
+	up := mustParseURLs([]string{
+		"http://node1:343",
+		"http://node2:343",
+		"http://node3:343",
+	})
+	up.loadBalancingPolicy = "consistent_hashing"
+
+	getBackend := func(stickyKey string) *backendURL {
+		t.Helper()
+		bu := up.getBackendURLForKey(stickyKey)
+		bu.put()
+		return bu
+	}
+
+	// Requests with the same key must be routed to the same backend.
+	keyToBackend := make(map[string]*backendURL)
+	backends := make(map[*backendURL]int)
+	for i := 0; i < 100; i++ {
+		stickyKey := fmt.Sprintf("user_%d", i)
+		bu := getBackend(stickyKey)
+		for j := 0; j < 10; j++ {
+			if buNew := getBackend(stickyKey); buNew != bu {
+				t.Fatalf("unexpected backend for stickyKey=%q; got %q; want %q", stickyKey, buNew.url, bu.url)
+			}
+		}
+		keyToBackend[stickyKey] = bu
+		backends[bu]++
+	}
+
+	// Requests with distinct keys must be spread among backends.
+	if len(backends) != 3 {
+		t.Fatalf("unexpected number of used backends; got %d; want 3", len(backends))
+	}
+
+	// Requests for the broken backend must be routed to other backends, while the remaining requests mustn't change their backends.
+	pbus := up.bus.Load()
+	bus := *pbus
+	bus[1].setBroken()
+	for stickyKey, bu := range keyToBackend {
+		buNew := getBackend(stickyKey)
+		if buNew.isBroken() {
+			t.Fatalf("unexpected broken backend %q for stickyKey=%q", buNew.url, stickyKey)
+		}
+		if bu != bus[1] && buNew != bu {
+			t.Fatalf("unexpected backend for stickyKey=%q; got %q; want %q", stickyKey, buNew.url, bu.url)
+		}
+	}
+
+	// The backend for the key must be returned if all the backends are broken.
+	bus[0].setBroken()
+	bus[2].setBroken()
+	for stickyKey, bu := range keyToBackend {
+		if buNew := getBackend(stickyKey); buNew != bu {
+			t.Fatalf("unexpected backend for stickyKey=%q when all the backends are broken; got %q; want %q", stickyKey, buNew.url, bu.url)
+		}
+	}
+}
+
 func TestDiscoverBackendIPsWithIPV6(t *testing.T) {
 	f := func(actualUrl, expectedUrl string) {
 		t.Helper()
@@ -911,9 +1071,7 @@ func mustParseURLs(us []string) *URLPrefix {
 		if err != nil {
 			panic(fmt.Errorf("BUG: cannot parse %q: %w", u, err))
 		}
-		bus[i] = &backendURL{
-			url: pu,
-		}
+		bus[i] = newBackendURL(pu)
 		urls[i] = pu
 	}
 	up := &URLPrefix{}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// CanaryConfig represents config for routing a share of requests to canary backends.
+//
+// See https://docs.victoriametrics.com/vmauth/#canary-routing
+type CanaryConfig struct {
+	// URLPrefix contains canary backend url prefixes.
+	URLPrefix *URLPrefix `yaml:"url_prefix"`
+
+	// Percent is the percentage of users, which requests are routed to URLPrefix.
+	Percent float64 `yaml:"percent"`
+
+	// MaxErrorRate is the maximum share of requests failed at URLPrefix before the canary is automatically rolled back.
+	//
+	// The canary is never rolled back if MaxErrorRate is zero.
+	MaxErrorRate float64 `yaml:"max_error_rate,omitempty"`
+
+	// MinRequests is the number of requests to URLPrefix to collect before checking the error rate.
+	MinRequests int `yaml:"min_requests,omitempty"`
+
+	// requests is the number of requests to URLPrefix since the last error rate check.
+	requests atomic.Uint64
+
+	// errors is the number of failed requests to URLPrefix since the last error rate check.
+	errors atomic.Uint64
+
+	// rolledBack is set to true when the canary is rolled back.
+	rolledBack atomic.Bool
+
+	requestsTotal *metrics.Counter
+	errorsTotal   *metrics.Counter
+}
+
+const defaultCanaryMinRequests = 100
+
+func (cc *CanaryConfig) init() error {
+	if cc.URLPrefix == nil {
+		return fmt.Errorf("missing `url_prefix` in `canary`")
+	}
+	if err := cc.URLPrefix.sanitizeAndInitialize(); err != nil {
+		return err
+	}
+	if cc.Percent < 0 || cc.Percent > 100 {
+		return fmt.Errorf("`percent` in `canary` must be in the range [0..100]; got %v", cc.Percent)
+	}
+	if cc.MaxErrorRate < 0 || cc.MaxErrorRate > 1 {
+		return fmt.Errorf("`max_error_rate` in `canary` must be in the range [0..1]; got %v", cc.MaxErrorRate)
+	}
+	if cc.MinRequests < 0 {
+		return fmt.Errorf("`min_requests` in `canary` cannot be negative; got %d", cc.MinRequests)
+	}
+	return nil
+}
+
+func (cc *CanaryConfig) initMetrics(ms *metrics.Set, metricLabels string) {
+	metricLabels = addMetricLabel(metricLabels, "url_prefix", fmt.Sprintf("%v", cc.URLPrefix.vOriginal))
+	cc.requestsTotal = ms.GetOrCreateCounter(`vmauth_canary_requests_total` + metricLabels)
+	cc.errorsTotal = ms.GetOrCreateCounter(`vmauth_canary_request_errors_total` + metricLabels)
+	_ = ms.GetOrCreateGauge(`vmauth_canary_rolled_back`+metricLabels, func() float64 {
+		if cc.rolledBack.Load() {
+			return 1
+		}
+		return 0
+	})
+}
+
+// addMetricLabel adds name="value" label to metricLabels obtained from UserInfo.getMetricLabels.
+func addMetricLabel(metricLabels, name, value string) string {
+	label := fmt.Sprintf(`%s=%q`, name, value)
+	if metricLabels == "" {
+		return "{" + label + "}"
+	}
+	return metricLabels[:len(metricLabels)-1] + "," + label + "}"
+}
+
+// isCanaryRequest returns true if the request with the given stickyKey must be routed to cc.URLPrefix.
+//
+// Requests with the same stickyKey are routed to the same backends until the canary is rolled back.
+func (cc *CanaryConfig) isCanaryRequest(stickyKey string) bool {
+	if cc.rolledBack.Load() {
+		return false
+	}
+	h := xxhash.Sum64String(stickyKey)
+	return float64(h%10000) < cc.Percent*100
+}
+
+// registerResponse registers the response with the given statusCode from cc.URLPrefix
+// and rolls back the canary if the error rate exceeds cc.MaxErrorRate.
+func (cc *CanaryConfig) registerResponse(statusCode int) {
+	if statusCode == 0 {
+		// The response wasn't sent to the client, e.g. because the client canceled the request.
+		return
+	}
+	if cc.requestsTotal != nil {
+		cc.requestsTotal.Inc()
+	}
+	requests := cc.requests.Add(1)
+	errors := cc.errors.Load()
+	if statusCode >= 500 {
+		if cc.errorsTotal != nil {
+			cc.errorsTotal.Inc()
+		}
+		errors = cc.errors.Add(1)
+	}
+
+	minRequests := uint64(cc.MinRequests)
+	if minRequests == 0 {
+		minRequests = defaultCanaryMinRequests
+	}
+	if cc.MaxErrorRate <= 0 || requests < minRequests {
+		return
+	}
+	if !cc.requests.CompareAndSwap(requests, 0) {
+		// Concurrent goroutine already checks the error rate.
+		return
+	}
+	cc.errors.Add(^(errors - 1))
+
+	errorRate := float64(errors) / float64(requests)
+	if errorRate > cc.MaxErrorRate && !cc.rolledBack.Swap(true) {
+		logger.Warnf("rolling back canary url_prefix=%v, since its error rate %.3f exceeds max_error_rate=%v at the last %d requests; "+
+			"the canary remains disabled until the next -auth.config reload", cc.URLPrefix.vOriginal, errorRate, cc.MaxErrorRate, requests)
+	}
+}
+
+// getStickyKey returns the key for sticky routing of requests from ui to the same backends.
+func getStickyKey(r *http.Request, ui *UserInfo) string {
+	if name := ui.name(); name != "" {
+		return name
+	}
+	// Fall back to client IP for unauthorized_user
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// canaryResponseWriter tracks the status code of the response sent to canary client.
+type canaryResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode int
+}
+
+func (crw *canaryResponseWriter) WriteHeader(statusCode int) {
+	if crw.statusCode == 0 {
+		crw.statusCode = statusCode
+	}
+	crw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (crw *canaryResponseWriter) Write(p []byte) (int, error) {
+	if crw.statusCode == 0 {
+		crw.statusCode = http.StatusOK
+	}
+	return crw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// This allows httpserver.Errorf aborting the client connection on errors after the response headers are sent.
+func (crw *canaryResponseWriter) Unwrap() http.ResponseWriter {
+	return crw.ResponseWriter
+}
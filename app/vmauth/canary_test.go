@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCanaryConfigIsCanaryRequest(t *testing.T) {
+	f := func(percent float64, canaryRequestsMin, canaryRequestsMax int) {
+		t.Helper()
+
+		cc := &CanaryConfig{
+			Percent: percent,
+		}
+		canaryRequests := 0
+		for i := 0; i < 1000; i++ {
+			stickyKey := fmt.Sprintf("user_%d", i)
+			isCanary := cc.isCanaryRequest(stickyKey)
+			if isCanary {
+				canaryRequests++
+			}
+			// Requests with the same stickyKey must be routed to the same backends
+			if cc.isCanaryRequest(stickyKey) != isCanary {
+				t.Fatalf("unexpected routing change for stickyKey=%q", stickyKey)
+			}
+		}
+		if canaryRequests < canaryRequestsMin || canaryRequests > canaryRequestsMax {
+			t.Fatalf("unexpected number of canary requests; got %d; want [%d..%d]", canaryRequests, canaryRequestsMin, canaryRequestsMax)
+		}
+	}
+
+	f(0, 0, 0)
+	f(5, 25, 75)
+	f(50, 450, 550)
+	f(100, 1000, 1000)
+}
+
+func TestCanaryConfigRegisterResponse(t *testing.T) {
+	cc := &CanaryConfig{
+		URLPrefix:    mustParseURL("http://canary"),
+		Percent:      100,
+		MaxErrorRate: 0.2,
+		MinRequests:  10,
+	}
+
+	// The error rate is below max_error_rate
+	for i := 0; i < 10; i++ {
+		statusCode := http.StatusOK
+		if i < 2 {
+			statusCode = http.StatusBadGateway
+		}
+		cc.registerResponse(statusCode)
+	}
+	if !cc.isCanaryRequest("foo") {
+		t.Fatalf("the canary mustn't be rolled back when the error rate doesn't exceed max_error_rate")
+	}
+
+	// Responses, which weren't sent to client, must be ignored
+	for i := 0; i < 100; i++ {
+		cc.registerResponse(0)
+	}
+	if !cc.isCanaryRequest("foo") {
+		t.Fatalf("the canary mustn't be rolled back on responses, which weren't sent to client")
+	}
+
+	// The error rate exceeds max_error_rate
+	for i := 0; i < 10; i++ {
+		statusCode := http.StatusOK
+		if i < 3 {
+			statusCode = http.StatusServiceUnavailable
+		}
+		cc.registerResponse(statusCode)
+	}
+	if cc.isCanaryRequest("foo") {
+		t.Fatalf("the canary must be rolled back when the error rate exceeds max_error_rate")
+	}
+}
@@ -229,7 +229,20 @@ func processUserRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 
 func processRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 	u := normalizeURL(r.URL)
-	up, hc := ui.getURLPrefixAndHeaders(u, r.Host, r.Header)
+	if ui.hasTenantRewrite() {
+		uNew, err := ui.rewriteTenantPath(u, r)
+		if err != nil {
+			tenantRewriteErrors.Inc()
+			err = &httpserver.ErrorWithStatusCode{
+				Err:        fmt.Errorf("cannot route request for user %q: %w", ui.name(), err),
+				StatusCode: http.StatusBadRequest,
+			}
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+		u = uNew
+	}
+	up, hc, cc := ui.getURLPrefixAndHeaders(u, r.Host, r.Header)
 	isDefault := false
 	if up == nil {
 		if ui.DefaultURL == nil {
@@ -252,12 +265,28 @@ func processRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 		isDefault = true
 	}
 
+	if cc != nil && cc.isCanaryRequest(getStickyKey(r, ui)) {
+		// Route the request to canary backends and track the response status code for automatic rollback.
+		up = cc.URLPrefix
+		crw := &canaryResponseWriter{
+			ResponseWriter: w,
+		}
+		w = crw
+		defer func() {
+			cc.registerResponse(crw.statusCode)
+		}()
+	}
+
 	rtb := newReadTrackingBody(r.Body, maxRequestBodySizeToRetry.IntN())
 	r.Body = rtb
 
+	var stickyKey string
+	if up.loadBalancingPolicy == "consistent_hashing" {
+		stickyKey = getBackendStickyKey(r, ui)
+	}
 	maxAttempts := up.getBackendsCount()
 	for i := 0; i < maxAttempts; i++ {
-		bu := up.getBackendURL()
+		bu := up.getBackendURLForKey(stickyKey)
 		if bu == nil {
 			break
 		}
@@ -293,6 +322,18 @@ func processRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 	ui.backendErrors.Inc()
 }
 
+// getBackendStickyKey returns the key for routing requests from ui to the same backend with consistent_hashing load balancing policy.
+//
+// Requests are routed by tenant if ui obtains the tenant from the request, so every backend serves its own subset of tenants.
+func getBackendStickyKey(r *http.Request, ui *UserInfo) string {
+	if ui.hasTenantRewrite() {
+		if tenant, err := ui.getTenant(r); err == nil {
+			return "tenant:" + tenant
+		}
+	}
+	return getStickyKey(r, ui)
+}
+
 func tryProcessingRequest(w http.ResponseWriter, r *http.Request, targetURL *url.URL, hc HeadersConf, retryStatusCodes []int, ui *UserInfo) (bool, bool) {
 	req := sanitizeRequestHeaders(r)
 
@@ -471,6 +512,7 @@ var (
 	configReloadRequests     = metrics.NewCounter(`vmauth_http_requests_total{path="/-/reload"}`)
 	invalidAuthTokenRequests = metrics.NewCounter(`vmauth_http_request_errors_total{reason="invalid_auth_token"}`)
 	missingRouteRequests     = metrics.NewCounter(`vmauth_http_request_errors_total{reason="missing_route"}`)
+	tenantRewriteErrors      = metrics.NewCounter(`vmauth_http_request_errors_total{reason="invalid_tenant"}`)
 )
 
 func newRoundTripper(caFileOpt, certFileOpt, keyFileOpt, serverNameOpt string, insecureSkipVerifyP *bool) (http.RoundTripper, error) {
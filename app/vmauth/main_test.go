@@ -181,6 +181,34 @@ statusCode=200
 requested_url={BACKEND}/foo/abc/def?bar=baz`
 	f(cfgStr, requestURL, backendHandler, responseExpected)
 
+	// canary routing
+	cfgStr = `
+unauthorized_user:
+  url_map:
+  - src_paths: ["/api/v1/query"]
+    url_prefix: "{BACKEND}/stable"
+    canary:
+      url_prefix: "{BACKEND}/canary"
+      percent: 100
+  - src_paths: ["/api/v1/write"]
+    url_prefix: "{BACKEND}/stable"
+    canary:
+      url_prefix: "{BACKEND}/canary"
+      percent: 0`
+	requestURL = "http://some-host.com/api/v1/query"
+	backendHandler = func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "requested_url=http://%s%s", r.Host, r.URL)
+	}
+	responseExpected = `
+statusCode=200
+requested_url={BACKEND}/canary/api/v1/query`
+	f(cfgStr, requestURL, backendHandler, responseExpected)
+	requestURL = "http://some-host.com/api/v1/write"
+	responseExpected = `
+statusCode=200
+requested_url={BACKEND}/stable/api/v1/write`
+	f(cfgStr, requestURL, backendHandler, responseExpected)
+
 	// /-/reload handler failure
 	origAuthKey := reloadAuthKey.Get()
 	if err := reloadAuthKey.Set("secret"); err != nil {
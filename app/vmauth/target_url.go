@@ -51,7 +51,10 @@ func dropPrefixParts(path string, parts int) string {
 	return path
 }
 
-func (ui *UserInfo) getURLPrefixAndHeaders(u *url.URL, host string, h http.Header) (*URLPrefix, HeadersConf) {
+// getURLPrefixAndHeaders returns URLPrefix and HeadersConf for the given request params.
+//
+// It also returns an optional CanaryConfig for the matching url_map entry.
+func (ui *UserInfo) getURLPrefixAndHeaders(u *url.URL, host string, h http.Header) (*URLPrefix, HeadersConf, *CanaryConfig) {
 	for _, e := range ui.URLMaps {
 		if !matchAnyRegex(e.SrcHosts, host) {
 			continue
@@ -66,12 +69,12 @@ func (ui *UserInfo) getURLPrefixAndHeaders(u *url.URL, host string, h http.Heade
 			continue
 		}
 
-		return e.URLPrefix, e.HeadersConf
+		return e.URLPrefix, e.HeadersConf, e.Canary
 	}
 	if ui.URLPrefix != nil {
-		return ui.URLPrefix, ui.HeadersConf
+		return ui.URLPrefix, ui.HeadersConf, nil
 	}
-	return nil, HeadersConf{}
+	return nil, HeadersConf{}, nil
 }
 
 func matchAnyRegex(rs []*Regex, s string) bool {
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// tenantPathRule is a rule for rewriting tenant-unaware request path to VictoriaMetrics cluster path.
+type tenantPathRule struct {
+	// prefix is the request path prefix the rule applies to.
+	prefix string
+
+	// component is the first part of the cluster path: insert, select or delete.
+	component string
+
+	// suffix is an optional part of the cluster path, which must be put between the tenant and the request path.
+	suffix string
+}
+
+// tenantPathRules contains rules for rewriting request paths to VictoriaMetrics cluster paths.
+//
+// The first matching rule is applied. Requests, which do not match any rule, are routed to /select/<tenant>/prometheus/<path>.
+//
+// See https://docs.victoriametrics.com/cluster-victoriametrics/#url-format
+var tenantPathRules = []tenantPathRule{
+	{prefix: "/api/v1/write", component: "insert", suffix: "/prometheus"},
+	{prefix: "/api/v1/import", component: "insert", suffix: "/prometheus"},
+	{prefix: "/opentelemetry/", component: "insert"},
+	{prefix: "/datadog/", component: "insert"},
+	{prefix: "/influx/", component: "insert"},
+	{prefix: "/newrelic/", component: "insert"},
+	{prefix: "/opentsdb/", component: "insert"},
+	{prefix: "/write", component: "insert", suffix: "/influx"},
+	{prefix: "/api/put", component: "insert", suffix: "/opentsdb"},
+	{prefix: "/api/v1/admin/tsdb/delete_series", component: "delete", suffix: "/prometheus"},
+	{prefix: "/render", component: "select", suffix: "/graphite"},
+	{prefix: "/metrics/find", component: "select", suffix: "/graphite"},
+	{prefix: "/metrics/expand", component: "select", suffix: "/graphite"},
+	{prefix: "/metrics/index.json", component: "select", suffix: "/graphite"},
+	{prefix: "/tags", component: "select", suffix: "/graphite"},
+	{prefix: "/vmui", component: "select"},
+}
+
+// tenantPathPrefixes contains cluster path prefixes, which cannot be requested directly when the tenant is obtained from the request,
+// since this would allow bypassing the tenant enforcement.
+var tenantPathPrefixes = []string{"/insert/", "/select/", "/delete/", "/admin/"}
+
+// hasTenantRewrite returns true if ui must obtain the tenant from the request and rewrite the request path accordingly.
+func (ui *UserInfo) hasTenantRewrite() bool {
+	return ui.TenantFromHeader != "" || ui.TenantFromClaim != ""
+}
+
+func (ui *UserInfo) validateTenantRewrite() error {
+	if !ui.hasTenantRewrite() {
+		if len(ui.AllowedTenants) > 0 {
+			return fmt.Errorf("`allowed_tenants` can be set only together with `tenant_from_header` or `tenant_from_claim`")
+		}
+		return nil
+	}
+	if ui.TenantFromHeader != "" && ui.TenantFromClaim != "" {
+		return fmt.Errorf("`tenant_from_header` and `tenant_from_claim` cannot be set simultaneously")
+	}
+	if ui.TenantFromClaim != "" && ui.BearerToken == "" {
+		// vmauth doesn't verify JWT signature, so the tenant can be trusted only if the whole JWT matches the configured `bearer_token`.
+		return fmt.Errorf("`tenant_from_claim` requires `bearer_token`, since vmauth doesn't verify JWT signature")
+	}
+	if len(ui.AllowedTenants) == 0 {
+		return fmt.Errorf("missing `allowed_tenants`; it must contain the list of tenants the user can access when `tenant_from_header` or `tenant_from_claim` is set")
+	}
+	allowedTenants := make(map[string]struct{}, len(ui.AllowedTenants))
+	for _, tenant := range ui.AllowedTenants {
+		tenantNormalized, err := normalizeTenant(tenant)
+		if err != nil {
+			return fmt.Errorf("invalid tenant at `allowed_tenants`: %w", err)
+		}
+		allowedTenants[tenantNormalized] = struct{}{}
+	}
+	ui.allowedTenants = allowedTenants
+	return nil
+}
+
+// rewriteTenantPath returns u with the path rewritten to VictoriaMetrics cluster path for the tenant obtained from r.
+//
+// See https://docs.victoriametrics.com/vmauth/#tenant-based-routing
+func (ui *UserInfo) rewriteTenantPath(u *url.URL, r *http.Request) (*url.URL, error) {
+	tenant, err := ui.getTenant(r)
+	if err != nil {
+		return nil, err
+	}
+	path, err := getTenantPath(u.Path, tenant)
+	if err != nil {
+		return nil, err
+	}
+	uNew := *u
+	uNew.Path = path
+	return &uNew, nil
+}
+
+func (ui *UserInfo) getTenant(r *http.Request) (string, error) {
+	tenant, err := ui.getRequestTenant(r)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := ui.allowedTenants[tenant]; !ok {
+		return "", fmt.Errorf("tenant %q isn't allowed; see `allowed_tenants` option", tenant)
+	}
+	return tenant, nil
+}
+
+func (ui *UserInfo) getRequestTenant(r *http.Request) (string, error) {
+	if ui.TenantFromHeader != "" {
+		tenant := r.Header.Get(ui.TenantFromHeader)
+		if tenant == "" {
+			return "", fmt.Errorf("missing tenant in %q request header", ui.TenantFromHeader)
+		}
+		tenantNormalized, err := normalizeTenant(tenant)
+		if err != nil {
+			return "", fmt.Errorf("invalid tenant in %q request header: %w", ui.TenantFromHeader, err)
+		}
+		return tenantNormalized, nil
+	}
+
+	tenant, err := getTenantFromJWTClaim(r, ui.TenantFromClaim)
+	if err != nil {
+		return "", err
+	}
+	tenantNormalized, err := normalizeTenant(tenant)
+	if err != nil {
+		return "", fmt.Errorf("invalid tenant in %q claim of JWT: %w", ui.TenantFromClaim, err)
+	}
+	return tenantNormalized, nil
+}
+
+// normalizeTenant verifies whether tenant has the `accountID` or `accountID:projectID` format
+// and returns it in the `accountID:projectID` format.
+func normalizeTenant(tenant string) (string, error) {
+	accountID, projectID, ok := strings.Cut(tenant, ":")
+	n, err := strconv.ParseUint(accountID, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse accountID from %q: it must be 32-bit unsigned integer", tenant)
+	}
+	m := uint64(0)
+	if ok {
+		m, err = strconv.ParseUint(projectID, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("cannot parse projectID from %q: it must be 32-bit unsigned integer", tenant)
+		}
+	}
+	return fmt.Sprintf("%d:%d", n, m), nil
+}
+
+// getTenantPath returns VictoriaMetrics cluster path for the given tenant-unaware path and the given tenant.
+func getTenantPath(path, tenant string) (string, error) {
+	for _, prefix := range tenantPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return "", fmt.Errorf("requests to %q are forbidden, since the tenant is obtained from the request", path)
+		}
+	}
+	for _, rule := range tenantPathRules {
+		if strings.HasPrefix(path, rule.prefix) {
+			return "/" + rule.component + "/" + tenant + rule.suffix + path, nil
+		}
+	}
+	return "/select/" + tenant + "/prometheus" + path, nil
+}
+
+// getTenantFromJWTClaim returns the value for the given claim from JWT passed in bearer token auth header of r.
+//
+// The JWT signature isn't verified, so `tenant_from_claim` is allowed only for users with `bearer_token` option,
+// which must match the whole JWT.
+func getTenantFromJWTClaim(r *http.Request, claim string) (string, error) {
+	token := ""
+	headerNames := *httpAuthHeader
+	if len(headerNames) == 0 {
+		headerNames = defaultHeaderNames
+	}
+	for _, headerName := range headerNames {
+		if s, ok := strings.CutPrefix(r.Header.Get(headerName), "Bearer "); ok {
+			token = s
+			break
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("missing JWT in bearer token auth header")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("cannot parse JWT: it must contain 3 dot-delimited parts; got %d parts", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", fmt.Errorf("cannot decode JWT payload: %w", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("cannot parse JWT payload: %w", err)
+	}
+
+	switch v := claims[claim].(type) {
+	case string:
+		if v == "" {
+			return "", fmt.Errorf("empty %q claim in JWT", claim)
+		}
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", fmt.Errorf("missing %q claim in JWT", claim)
+	default:
+		return "", fmt.Errorf("unexpected type for %q claim in JWT: %T; want string or number", claim, v)
+	}
+}
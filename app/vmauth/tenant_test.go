@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestGetTenantPath(t *testing.T) {
+	f := func(path, tenant, resultExpected string) {
+		t.Helper()
+
+		result, err := getTenantPath(path, tenant)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected path; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f("", "42", "/select/42/prometheus")
+	f("/api/v1/query", "42", "/select/42/prometheus/api/v1/query")
+	f("/api/v1/label/job/values", "1:2", "/select/1:2/prometheus/api/v1/label/job/values")
+	f("/api/v1/write", "42", "/insert/42/prometheus/api/v1/write")
+	f("/api/v1/import/native", "42", "/insert/42/prometheus/api/v1/import/native")
+	f("/opentelemetry/v1/metrics", "42", "/insert/42/opentelemetry/v1/metrics")
+	f("/influx/write", "42", "/insert/42/influx/write")
+	f("/write", "42", "/insert/42/influx/write")
+	f("/api/put", "42", "/insert/42/opentsdb/api/put")
+	f("/api/v1/admin/tsdb/delete_series", "42", "/delete/42/prometheus/api/v1/admin/tsdb/delete_series")
+	f("/render", "42", "/select/42/graphite/render")
+	f("/tags/autoComplete/tags", "42", "/select/42/graphite/tags/autoComplete/tags")
+	f("/vmui/", "42", "/select/42/vmui/")
+}
+
+func TestGetTenantPathFailure(t *testing.T) {
+	f := func(path string) {
+		t.Helper()
+
+		result, err := getTenantPath(path, "42")
+		if err == nil {
+			t.Fatalf("expecting non-nil error; got %q", result)
+		}
+	}
+
+	// Tenant-qualified paths mustn't bypass tenant enforcement
+	f("/select/1/prometheus/api/v1/query")
+	f("/insert/multitenant/prometheus/api/v1/write")
+	f("/delete/1/prometheus/api/v1/admin/tsdb/delete_series")
+	f("/admin/tenants")
+}
+
+func TestUserInfoRewriteTenantPath(t *testing.T) {
+	newJWT := func(payload string) string {
+		return "Bearer eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2ln"
+	}
+
+	f := func(ui *UserInfo, headers map[string]string, pathExpected string) {
+		t.Helper()
+
+		r, err := http.NewRequest(http.MethodGet, "http://vmauth/api/v1/query?query=up", nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		u, err := ui.rewriteTenantPath(r.URL, r)
+		if pathExpected == "" {
+			if err == nil {
+				t.Fatalf("expecting non-nil error; got %q", u)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if u.Path != pathExpected {
+			t.Fatalf("unexpected path; got %q; want %q", u.Path, pathExpected)
+		}
+		if u.RawQuery != "query=up" {
+			t.Fatalf("unexpected query args; got %q; want %q", u.RawQuery, "query=up")
+		}
+	}
+
+	newUserInfo := func(ui *UserInfo) *UserInfo {
+		t.Helper()
+
+		if err := ui.validateTenantRewrite(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return ui
+	}
+
+	uiHeader := newUserInfo(&UserInfo{
+		TenantFromHeader: "X-Scope-OrgID",
+		AllowedTenants:   []string{"12", "12:34", "0:56"},
+	})
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "12"}, "/select/12:0/prometheus/api/v1/query")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "12:0"}, "/select/12:0/prometheus/api/v1/query")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "12:34"}, "/select/12:34/prometheus/api/v1/query")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "00:56"}, "/select/0:56/prometheus/api/v1/query")
+
+	// tenant outside allowed_tenants
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "13"}, "")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "12:35"}, "")
+
+	// missing header
+	f(uiHeader, nil, "")
+
+	// invalid tenant
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "foo"}, "")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "1:bar"}, "")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "../../1"}, "")
+	f(uiHeader, map[string]string{"X-Scope-OrgID": "4294967296"}, "")
+
+	uiClaim := newUserInfo(&UserInfo{
+		BearerToken:     "foo",
+		TenantFromClaim: "vm_tenant",
+		AllowedTenants:  []string{"5:6", "7"},
+	})
+	f(uiClaim, map[string]string{"Authorization": newJWT(`{"sub":"foo","vm_tenant":"5:6"}`)}, "/select/5:6/prometheus/api/v1/query")
+	f(uiClaim, map[string]string{"Authorization": newJWT(`{"sub":"foo","vm_tenant":7}`)}, "/select/7:0/prometheus/api/v1/query")
+
+	// tenant outside allowed_tenants
+	f(uiClaim, map[string]string{"Authorization": newJWT(`{"sub":"foo","vm_tenant":"8"}`)}, "")
+
+	// missing claim
+	f(uiClaim, map[string]string{"Authorization": newJWT(`{"sub":"foo"}`)}, "")
+
+	// invalid claim type
+	f(uiClaim, map[string]string{"Authorization": newJWT(`{"vm_tenant":["1"]}`)}, "")
+
+	// missing JWT
+	f(uiClaim, nil, "")
+	f(uiClaim, map[string]string{"Authorization": "Basic Zm9vOmJhcg=="}, "")
+
+	// invalid JWT
+	f(uiClaim, map[string]string{"Authorization": "Bearer foobar"}, "")
+	f(uiClaim, map[string]string{"Authorization": "Bearer a.!!!.c"}, "")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/files"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+type filesProcessor struct {
+	// cl lists and reads CSV files
+	cl *files.Client
+	// importer performs import requests
+	// for timeseries data read from files
+	im *vm.Importer
+	// cc stands for concurrency
+	// and defines number of concurrently
+	// processed files
+	cc int
+
+	// isVerbose enables verbose output
+	isVerbose bool
+}
+
+func (fp *filesProcessor) run() error {
+	names, err := fp.cl.Explore()
+	if err != nil {
+		return fmt.Errorf("explore failed: %s", err)
+	}
+	if len(names) < 1 {
+		return fmt.Errorf("found no files to import at %s", fp.cl)
+	}
+	question := fmt.Sprintf("Found %d files to import. Continue?", len(names))
+	if !prompt(question) {
+		return nil
+	}
+
+	if err := fp.processFiles(names); err != nil {
+		return fmt.Errorf("migration failed: %s", err)
+	}
+
+	log.Println("Import finished!")
+	log.Println(fp.im.Stats())
+	return nil
+}
+
+func (fp *filesProcessor) do(name string) error {
+	return fp.cl.Read(name, fp.im.Input)
+}
+
+func (fp *filesProcessor) processFiles(names []string) error {
+	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing files"), len(names))
+	if err := barpool.Start(); err != nil {
+		return err
+	}
+	defer barpool.Stop()
+
+	if fp.cc < 1 {
+		fp.cc = 1
+	}
+	namesCh := make(chan string)
+	errCh := make(chan error, fp.cc)
+	fp.im.ResetStats()
+
+	var wg sync.WaitGroup
+	wg.Add(fp.cc)
+	for i := 0; i < fp.cc; i++ {
+		go func() {
+			defer wg.Done()
+			for name := range namesCh {
+				if err := fp.do(name); err != nil {
+					errCh <- fmt.Errorf("read failed for file %q: %s", name, err)
+					return
+				}
+				bar.Increment()
+			}
+		}()
+	}
+	// any error breaks the import
+	for _, name := range names {
+		select {
+		case filesErr := <-errCh:
+			close(namesCh)
+			return fmt.Errorf("files error: %s", filesErr)
+		case vmErr := <-fp.im.Errors():
+			close(namesCh)
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, fp.isVerbose))
+		case namesCh <- name:
+		}
+	}
+
+	close(namesCh)
+	wg.Wait()
+	// wait for all buffers to flush
+	fp.im.Close()
+	close(errCh)
+	// drain import errors channel
+	for vmErr := range fp.im.Errors() {
+		if vmErr.Err != nil {
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, fp.isVerbose))
+		}
+	}
+	for err := range errCh {
+		return fmt.Errorf("import process failed: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package files
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readCSV reads rows from CSV file r with the header line and passes them to sb.
+func readCSV(r io.Reader, delimiter rune, sb *seriesBuilder) error {
+	cr := csv.NewReader(r)
+	cr.Comma = delimiter
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("cannot read header line: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	getColumnIdx := func(name string) (int, error) {
+		idx, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("missing column %q in the header line", name)
+		}
+		return idx, nil
+	}
+
+	m := sb.mapping
+	timestampIdx, err := getColumnIdx(m.TimestampColumn)
+	if err != nil {
+		return err
+	}
+	labelIdxs := make([]int, len(m.LabelColumns))
+	for i, name := range m.LabelColumns {
+		if labelIdxs[i], err = getColumnIdx(name); err != nil {
+			return err
+		}
+	}
+	valueIdxs := make([]int, len(m.ValueColumns))
+	for i := range m.ValueColumns {
+		if valueIdxs[i], err = getColumnIdx(m.ValueColumns[i].Column); err != nil {
+			return err
+		}
+	}
+
+	labelValues := make([]string, len(labelIdxs))
+	values := make([]float64, len(valueIdxs))
+	hasValues := make([]bool, len(valueIdxs))
+	for {
+		record, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		timestamp, err := m.parseTimestamp(strings.TrimSpace(record[timestampIdx]))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		for i, idx := range labelIdxs {
+			// Copy the label value, since record is re-used by cr
+			labelValues[i] = strings.Clone(record[idx])
+		}
+		for i, idx := range valueIdxs {
+			s := strings.TrimSpace(record[idx])
+			if s == "" {
+				hasValues[i] = false
+				continue
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: cannot parse value %q for column %q: %w", line, s, m.ValueColumns[i].Column, err)
+			}
+			values[i] = v
+			hasValues[i] = true
+		}
+		if err := sb.addRow(timestamp, labelValues, values, hasValues); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package files
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+// Config contains a list of params needed
+// for reading CSV files
+type Config struct {
+	// Path is a path to a file or to a directory with files on local disk
+	// or s3://bucket/prefix for reading files from S3.
+	Path string
+
+	// Format is the format of files. Only csv is supported at the moment.
+	//
+	// By default, the format is detected from file extensions
+	// and files with unsupported extensions are skipped.
+	Format string
+
+	// CSVDelimiter is the delimiter for CSV files.
+	CSVDelimiter rune
+
+	// Mapping defines how file columns are mapped to time series.
+	Mapping *Mapping
+
+	// S3 contains params for reading files from S3.
+	S3 S3Config
+}
+
+// Client reads time series from CSV files
+type Client struct {
+	fs           filesystem
+	format       string
+	csvDelimiter rune
+	mapping      *Mapping
+}
+
+// NewClient creates and validates new Client
+// with given Config
+func NewClient(cfg Config) (*Client, error) {
+	switch cfg.Format {
+	case "", "csv":
+	default:
+		return nil, fmt.Errorf("unsupported format %q; supported formats: csv", cfg.Format)
+	}
+	if cfg.Mapping == nil {
+		return nil, fmt.Errorf("missing columns mapping")
+	}
+	csvDelimiter := cfg.CSVDelimiter
+	if csvDelimiter == 0 {
+		csvDelimiter = ','
+	}
+	fs, err := newFilesystem(cfg.Path, cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		fs:           fs,
+		format:       cfg.Format,
+		csvDelimiter: csvDelimiter,
+		mapping:      cfg.Mapping,
+	}, nil
+}
+
+// String returns human-readable representation of c.
+func (c *Client) String() string {
+	return c.fs.String()
+}
+
+// Explore returns names of files to import.
+func (c *Client) Explore() ([]string, error) {
+	names, err := c.fs.list()
+	if err != nil {
+		return nil, err
+	}
+	if c.format != "" {
+		return names, nil
+	}
+	var result []string
+	for _, name := range names {
+		if getFormatByName(name) != "" {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// Read reads time series from the file with the given name and passes them to callback.
+//
+// callback may be called multiple times for the same time series.
+func (c *Client) Read(name string, callback func(ts *vm.TimeSeries) error) error {
+	format := c.format
+	if format == "" {
+		format = getFormatByName(name)
+	}
+	f, err := c.fs.open(name)
+	if err != nil {
+		return fmt.Errorf("cannot open %q: %w", name, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sb := newSeriesBuilder(c.mapping, callback)
+	switch format {
+	case "csv":
+		err = readCSV(f, c.csvDelimiter, sb)
+	default:
+		return fmt.Errorf("cannot detect format for %q; set it explicitly", name)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read %q: %w", name, err)
+	}
+	return sb.flush()
+}
+
+func getFormatByName(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return "csv"
+	default:
+		return ""
+	}
+}
+
+// maxBatchRows is the maximum number of rows to collect before passing time series to callback.
+const maxBatchRows = 10000
+
+// seriesBuilder groups rows into time series according to mapping.
+type seriesBuilder struct {
+	mapping  *Mapping
+	callback func(ts *vm.TimeSeries) error
+
+	extraLabels []vm.LabelPair
+
+	// tss contains time series collected from the added rows in the order of their appearance.
+	tss []*vm.TimeSeries
+
+	// series maps time series keys to tss items.
+	series map[string]*vm.TimeSeries
+	rows   int
+
+	keyBuf []byte
+}
+
+func newSeriesBuilder(m *Mapping, callback func(ts *vm.TimeSeries) error) *seriesBuilder {
+	extraLabels := make([]vm.LabelPair, 0, len(m.ExtraLabels))
+	for name, value := range m.ExtraLabels {
+		extraLabels = append(extraLabels, vm.LabelPair{
+			Name:  name,
+			Value: value,
+		})
+	}
+	sort.Slice(extraLabels, func(i, j int) bool {
+		return extraLabels[i].Name < extraLabels[j].Name
+	})
+	return &seriesBuilder{
+		mapping:     m,
+		callback:    callback,
+		extraLabels: extraLabels,
+		series:      make(map[string]*vm.TimeSeries),
+	}
+}
+
+// addRow adds a row with the given timestamp in milliseconds, label values and sample values.
+//
+// labelValues must be in the order of mapping.LabelColumns, while values must be in the order of mapping.ValueColumns.
+// hasValues[i] must be false for missing values[i].
+func (sb *seriesBuilder) addRow(timestamp int64, labelValues []string, values []float64, hasValues []bool) error {
+	m := sb.mapping
+	for i := range m.ValueColumns {
+		if !hasValues[i] {
+			continue
+		}
+		vc := &m.ValueColumns[i]
+		key := sb.keyBuf[:0]
+		key = append(key, vc.metricName()...)
+		for _, v := range labelValues {
+			key = append(key, 0)
+			key = append(key, v...)
+		}
+		sb.keyBuf = key
+		ts := sb.series[string(key)]
+		if ts == nil {
+			ts = &vm.TimeSeries{
+				Name:       vc.metricName(),
+				LabelPairs: sb.newLabels(labelValues),
+			}
+			sb.series[string(key)] = ts
+			sb.tss = append(sb.tss, ts)
+		}
+		ts.Timestamps = append(ts.Timestamps, timestamp)
+		ts.Values = append(ts.Values, values[i])
+	}
+	sb.rows++
+	if sb.rows >= maxBatchRows {
+		return sb.flush()
+	}
+	return nil
+}
+
+func (sb *seriesBuilder) newLabels(labelValues []string) []vm.LabelPair {
+	m := sb.mapping
+	labels := make([]vm.LabelPair, 0, len(labelValues)+len(sb.extraLabels))
+	for i, v := range labelValues {
+		if v == "" {
+			// Empty labels are equivalent to missing labels
+			continue
+		}
+		labels = append(labels, vm.LabelPair{
+			Name:  m.LabelColumns[i],
+			Value: v,
+		})
+	}
+	return append(labels, sb.extraLabels...)
+}
+
+// flush passes all the collected time series to callback.
+func (sb *seriesBuilder) flush() error {
+	tss := sb.tss
+	sb.tss = nil
+	clear(sb.series)
+	sb.rows = 0
+	for _, ts := range tss {
+		if err := sb.callback(ts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+func TestParseMapping_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := parseMapping([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown field
+	f(`foo: bar`)
+
+	// missing timestamp_column
+	f(`
+value_columns:
+- column: cpu
+`)
+
+	// unsupported timestamp_format
+	f(`
+timestamp_column: ts
+timestamp_format: foo
+value_columns:
+- column: cpu
+`)
+
+	// missing value_columns
+	f(`timestamp_column: ts`)
+
+	// missing column in value_columns
+	f(`
+timestamp_column: ts
+value_columns:
+- metric_name: cpu
+`)
+}
+
+func TestMappingParseTimestamp(t *testing.T) {
+	f := func(format, s string, timestampExpected int64) {
+		t.Helper()
+
+		m := &Mapping{
+			TimestampFormat: format,
+		}
+		timestamp, err := m.parseTimestamp(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if timestamp != timestampExpected {
+			t.Fatalf("unexpected timestamp; got %d; want %d", timestamp, timestampExpected)
+		}
+	}
+
+	f("", "1700000000123", 1700000000123)
+	f("", "2023-11-14T22:13:20.123Z", 1700000000123)
+	f("unix_s", "1700000000.123", 1700000000123)
+	f("unix_ms", "1700000000123", 1700000000123)
+	f("unix_us", "1700000000123456", 1700000000123)
+	f("unix_ns", "1700000000123000000", 1700000000123)
+	f("rfc3339", "2023-11-14T23:13:20.123+01:00", 1700000000123)
+}
+
+func TestClientRead(t *testing.T) {
+	mapping, err := parseMapping([]byte(`
+timestamp_column: ts
+label_columns: [host, region]
+value_columns:
+- column: cpu
+  metric_name: cpu_usage
+- column: mem
+extra_labels:
+  job: backfill
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	dir := t.TempDir()
+	csvData := `ts,host,region,cpu,mem,other
+1700000000000,host1,eu,0.5,100,foo
+1700000001000,host1,eu,0.7,,bar
+1700000000000,host2,,0.1,200,baz
+`
+	if err := os.WriteFile(filepath.Join(dir, "data.csv"), []byte(csvData), 0o644); err != nil {
+		t.Fatalf("cannot write CSV file: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("foo"), 0o644); err != nil {
+		t.Fatalf("cannot write file: %s", err)
+	}
+
+	cl, err := NewClient(Config{
+		Path:    dir,
+		Mapping: mapping,
+	})
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	names, err := cl.Explore()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	namesExpected := []string{
+		filepath.Join(dir, "data.csv"),
+	}
+	if !reflect.DeepEqual(names, namesExpected) {
+		t.Fatalf("unexpected files; got %q; want %q", names, namesExpected)
+	}
+
+	tssExpected := []vm.TimeSeries{
+		{
+			Name: "cpu_usage",
+			LabelPairs: []vm.LabelPair{
+				{Name: "host", Value: "host1"},
+				{Name: "region", Value: "eu"},
+				{Name: "job", Value: "backfill"},
+			},
+			Timestamps: []int64{1700000000000, 1700000001000},
+			Values:     []float64{0.5, 0.7},
+		},
+		{
+			Name: "mem",
+			LabelPairs: []vm.LabelPair{
+				{Name: "host", Value: "host1"},
+				{Name: "region", Value: "eu"},
+				{Name: "job", Value: "backfill"},
+			},
+			Timestamps: []int64{1700000000000},
+			Values:     []float64{100},
+		},
+		{
+			Name: "cpu_usage",
+			LabelPairs: []vm.LabelPair{
+				{Name: "host", Value: "host2"},
+				{Name: "job", Value: "backfill"},
+			},
+			Timestamps: []int64{1700000000000},
+			Values:     []float64{0.1},
+		},
+		{
+			Name: "mem",
+			LabelPairs: []vm.LabelPair{
+				{Name: "host", Value: "host2"},
+				{Name: "job", Value: "backfill"},
+			},
+			Timestamps: []int64{1700000000000},
+			Values:     []float64{200},
+		},
+	}
+	for _, name := range names {
+		var tss []vm.TimeSeries
+		err := cl.Read(name, func(ts *vm.TimeSeries) error {
+			tss = append(tss, *ts)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", name, err)
+		}
+		if !reflect.DeepEqual(tss, tssExpected) {
+			t.Fatalf("unexpected time series read from %q\ngot\n%v\nwant\n%v", name, tss, tssExpected)
+		}
+	}
+}
+
+func TestClientRead_Failure(t *testing.T) {
+	f := func(csvData string) {
+		t.Helper()
+
+		mapping := &Mapping{
+			TimestampColumn: "ts",
+			ValueColumns: []ValueColumn{
+				{Column: "cpu"},
+			},
+		}
+		path := filepath.Join(t.TempDir(), "data.csv")
+		if err := os.WriteFile(path, []byte(csvData), 0o644); err != nil {
+			t.Fatalf("cannot write CSV file: %s", err)
+		}
+		cl, err := NewClient(Config{
+			Path:    path,
+			Mapping: mapping,
+		})
+		if err != nil {
+			t.Fatalf("cannot create client: %s", err)
+		}
+		err = cl.Read(path, func(_ *vm.TimeSeries) error {
+			return nil
+		})
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// missing column
+	f("ts,mem\n1700000000000,1\n")
+
+	// invalid timestamp
+	f("ts,cpu\nfoo,1\n")
+
+	// invalid value
+	f("ts,cpu\n1700000000000,foo\n")
+}
@@ -0,0 +1,193 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+)
+
+// S3Config contains params for reading files from S3.
+type S3Config struct {
+	// CredsFilePath is the path to S3 credentials file.
+	CredsFilePath string
+
+	// ConfigFilePath is the path to S3 configs file.
+	ConfigFilePath string
+
+	// ProfileName is the name of S3 config profile to use.
+	ProfileName string
+
+	// CustomEndpoint is set for using S3-compatible endpoint such as MinIO.
+	CustomEndpoint string
+
+	// ForcePathStyle defines whether to use path style for S3-compatible endpoint.
+	ForcePathStyle bool
+}
+
+// filesystem lists and opens files for import.
+type filesystem interface {
+	// String returns human-readable representation of the filesystem.
+	String() string
+
+	// list returns sorted names of all the files in the filesystem.
+	list() ([]string, error)
+
+	// open opens the file with the given name for reading.
+	open(name string) (io.ReadCloser, error)
+}
+
+func newFilesystem(path string, s3Cfg S3Config) (filesystem, error) {
+	if strings.HasPrefix(path, "s3://") {
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(path, "s3://"), "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("missing bucket name in %q", path)
+		}
+		fs := &s3FS{
+			cfg:    s3Cfg,
+			bucket: bucket,
+			prefix: prefix,
+		}
+		if err := fs.init(); err != nil {
+			return nil, err
+		}
+		return fs, nil
+	}
+	if n := strings.Index(path, "://"); n >= 0 {
+		return nil, fmt.Errorf("unsupported scheme %q in %q; supported schemes: `s3://` or local path", path[:n], path)
+	}
+	return &localFS{
+		path: path,
+	}, nil
+}
+
+// localFS reads files from local filesystem.
+type localFS struct {
+	// path is a path to a file or to a directory with files.
+	path string
+}
+
+func (lfs *localFS) String() string {
+	return fmt.Sprintf("local{path: %q}", lfs.path)
+}
+
+func (lfs *localFS) list() ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(lfs.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		names = append(names, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list files at %s: %w", lfs, err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (lfs *localFS) open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// s3FS reads files from S3 bucket with names matching the given prefix.
+type s3FS struct {
+	cfg    S3Config
+	bucket string
+	prefix string
+
+	s3 *s3.Client
+}
+
+func (sfs *s3FS) init() error {
+	var configOpts []func(*config.LoadOptions) error
+	configOpts = append(configOpts, config.WithDefaultRegion("us-east-1"))
+	if len(sfs.cfg.ProfileName) > 0 {
+		configOpts = append(configOpts, config.WithSharedConfigProfile(sfs.cfg.ProfileName))
+	}
+	if len(sfs.cfg.ConfigFilePath) > 0 {
+		configOpts = append(configOpts, config.WithSharedConfigFiles([]string{sfs.cfg.ConfigFilePath}))
+	}
+	if len(sfs.cfg.CredsFilePath) > 0 {
+		configOpts = append(configOpts, config.WithSharedCredentialsFiles([]string{sfs.cfg.CredsFilePath}))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), configOpts...)
+	if err != nil {
+		return fmt.Errorf("cannot load S3 config: %w", err)
+	}
+	cfg.HTTPClient = &http.Client{
+		Transport: httputil.NewTransport(false, "vmctl_files_s3"),
+	}
+
+	var region string
+	if len(sfs.cfg.CustomEndpoint) == 0 {
+		region, err = manager.GetBucketRegion(context.Background(), s3.NewFromConfig(cfg), sfs.bucket)
+		if err != nil {
+			return fmt.Errorf("cannot determine region for bucket %q: %w", sfs.bucket, err)
+		}
+	}
+	sfs.s3 = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if len(sfs.cfg.CustomEndpoint) > 0 {
+			o.UsePathStyle = sfs.cfg.ForcePathStyle
+			o.BaseEndpoint = aws.String(sfs.cfg.CustomEndpoint)
+			return
+		}
+		o.Region = region
+	})
+	return nil
+}
+
+func (sfs *s3FS) String() string {
+	return fmt.Sprintf("S3{bucket: %q, prefix: %q}", sfs.bucket, sfs.prefix)
+}
+
+func (sfs *s3FS) list() ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(sfs.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(sfs.bucket),
+		Prefix: aws.String(sfs.prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("cannot list objects at %s: %w", sfs, err)
+		}
+		for _, o := range page.Contents {
+			key := *o.Key
+			if strings.HasSuffix(key, "/") {
+				// skip directory placeholders
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (sfs *s3FS) open(name string) (io.ReadCloser, error) {
+	o, err := sfs.s3.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(sfs.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %q at %s: %w", name, sfs, err)
+	}
+	return o.Body, nil
+}
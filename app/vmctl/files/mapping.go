@@ -0,0 +1,136 @@
+package files
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Mapping defines how columns from CSV files are mapped to time series.
+type Mapping struct {
+	// TimestampColumn is the name of the column with sample timestamps.
+	TimestampColumn string `yaml:"timestamp_column"`
+
+	// TimestampFormat is the format of timestamps at TimestampColumn.
+	//
+	// Supported formats: unix_s, unix_ms, unix_us, unix_ns and rfc3339.
+	// By default, unix_ms is used for numeric values and rfc3339 is used for string values.
+	TimestampFormat string `yaml:"timestamp_format,omitempty"`
+
+	// LabelColumns contains names of columns, which must be used as labels for every value column.
+	LabelColumns []string `yaml:"label_columns,omitempty"`
+
+	// ValueColumns contains columns with sample values.
+	ValueColumns []ValueColumn `yaml:"value_columns"`
+
+	// ExtraLabels contains labels, which must be added to all the imported time series.
+	ExtraLabels map[string]string `yaml:"extra_labels,omitempty"`
+}
+
+// ValueColumn defines a column with sample values.
+type ValueColumn struct {
+	// Column is the name of the column with sample values.
+	Column string `yaml:"column"`
+
+	// MetricName is the name of the metric for values from the Column.
+	//
+	// By default, the Column is used as metric name.
+	MetricName string `yaml:"metric_name,omitempty"`
+}
+
+func (vc *ValueColumn) metricName() string {
+	if vc.MetricName != "" {
+		return vc.MetricName
+	}
+	return vc.Column
+}
+
+// LoadMapping reads and validates Mapping from the YAML file at the given path.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	m, err := parseMapping(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return m, nil
+}
+
+func parseMapping(data []byte) (*Mapping, error) {
+	var m Mapping
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, err
+	}
+	if m.TimestampColumn == "" {
+		return nil, fmt.Errorf("missing `timestamp_column`")
+	}
+	switch m.TimestampFormat {
+	case "", "unix_s", "unix_ms", "unix_us", "unix_ns", "rfc3339":
+	default:
+		return nil, fmt.Errorf("unsupported `timestamp_format: %q`; supported values: unix_s, unix_ms, unix_us, unix_ns, rfc3339", m.TimestampFormat)
+	}
+	if len(m.ValueColumns) == 0 {
+		return nil, fmt.Errorf("missing `value_columns`")
+	}
+	for i, vc := range m.ValueColumns {
+		if vc.Column == "" {
+			return nil, fmt.Errorf("missing `column` in `value_columns` #%d", i+1)
+		}
+	}
+	for i, lc := range m.LabelColumns {
+		if lc == "" {
+			return nil, fmt.Errorf("empty column name in `label_columns` #%d", i+1)
+		}
+	}
+	return &m, nil
+}
+
+// parseTimestamp parses timestamp from s according to m.TimestampFormat and returns it in milliseconds.
+func (m *Mapping) parseTimestamp(s string) (int64, error) {
+	switch m.TimestampFormat {
+	case "rfc3339":
+		return parseRFC3339Timestamp(s)
+	case "":
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return parseRFC3339Timestamp(s)
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse timestamp %q: %w", s, err)
+	}
+	return m.numericTimestamp(f)
+}
+
+// numericTimestamp converts f to milliseconds according to m.TimestampFormat.
+func (m *Mapping) numericTimestamp(f float64) (int64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid timestamp %v", f)
+	}
+	switch m.TimestampFormat {
+	case "unix_s":
+		return int64(math.Round(f * 1e3)), nil
+	case "", "unix_ms":
+		return int64(math.Round(f)), nil
+	case "unix_us":
+		return int64(math.Round(f / 1e3)), nil
+	case "unix_ns":
+		return int64(math.Round(f / 1e6)), nil
+	default:
+		return 0, fmt.Errorf("cannot convert numeric timestamp %v to `timestamp_format: %q`", f, m.TimestampFormat)
+	}
+}
+
+func parseRFC3339Timestamp(s string) (int64, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse timestamp %q in RFC3339 format: %w", s, err)
+	}
+	return t.UnixMilli(), nil
+}
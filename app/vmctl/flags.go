@@ -417,6 +417,72 @@ var (
 	}
 )
 
+const (
+	filesPath             = "files-path"
+	filesFormat           = "files-format"
+	filesMappingConfig    = "files-mapping-config"
+	filesCSVDelimiter     = "files-csv-delimiter"
+	filesConcurrency      = "files-concurrency"
+	filesS3CustomEndpoint = "files-s3-custom-endpoint"
+	filesS3ForcePathStyle = "files-s3-force-path-style"
+	filesS3CredsFilePath  = "files-s3-creds-file-path"
+	filesS3ConfigFilePath = "files-s3-config-file-path"
+	filesS3ConfigProfile  = "files-s3-config-profile"
+)
+
+var (
+	filesFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name: filesPath,
+			Usage: "Path to CSV file or to a directory with such files on local disk. " +
+				"Use s3://bucket/prefix for importing files from S3 bucket with names matching the given prefix",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name: filesFormat,
+			Usage: "Format of the imported files. Supported values: csv. Parquet files aren't supported. " +
+				"By default, the format is detected from file extensions and files with other extensions are skipped",
+		},
+		&cli.StringFlag{
+			Name:     filesMappingConfig,
+			Usage:    "Path to YAML file with mapping of file columns to time series. See https://docs.victoriametrics.com/vmctl/#columns-mapping",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  filesCSVDelimiter,
+			Usage: "Field delimiter for CSV files",
+			Value: ",",
+		},
+		&cli.IntFlag{
+			Name:  filesConcurrency,
+			Usage: "Number of concurrently processed files",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name:  filesS3CustomEndpoint,
+			Usage: "Custom S3 endpoint for use with S3-compatible storages (e.g. MinIO). S3 is used if not set",
+		},
+		&cli.BoolFlag{
+			Name:  filesS3ForcePathStyle,
+			Usage: "Prefixing endpoint with bucket name when set false, true by default.",
+			Value: true,
+		},
+		&cli.StringFlag{
+			Name: filesS3CredsFilePath,
+			Usage: "Path to file with S3 credentials. By default, credentials are loaded from default locations and environment variables. " +
+				"See https://docs.aws.amazon.com/sdk-for-go/v1/developer-guide/configuring-sdk.html",
+		},
+		&cli.StringFlag{
+			Name:  filesS3ConfigFilePath,
+			Usage: "Path to file with S3 configs. Configs are loaded from default location if not set",
+		},
+		&cli.StringFlag{
+			Name:  filesS3ConfigProfile,
+			Usage: "Profile name for S3 configs. If no set, the value of the environment variable will be loaded (AWS_PROFILE or AWS_DEFAULT_PROFILE), or if both not set, DefaultSharedConfigProfile is used",
+		},
+	}
+)
+
 const (
 	vmNativeFilterMatch       = "vm-native-filter-match"
 	vmNativeFilterTimeStart   = "vm-native-filter-time-start"
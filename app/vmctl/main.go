@@ -17,6 +17,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/backoff"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/files"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/native"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/remoteread"
 
@@ -271,6 +272,58 @@ func main() {
 					return pp.run()
 				},
 			},
+			{
+				Name:   "files",
+				Usage:  "Import time series from CSV files on local disk or S3",
+				Flags:  mergeFlags(globalFlags, filesFlags, vmFlags),
+				Before: beforeFn,
+				Action: func(c *cli.Context) error {
+					fmt.Println("Files import mode")
+
+					mapping, err := files.LoadMapping(c.String(filesMappingConfig))
+					if err != nil {
+						return fmt.Errorf("failed to load -%s: %s", filesMappingConfig, err)
+					}
+					delimiter := []rune(c.String(filesCSVDelimiter))
+					if len(delimiter) != 1 {
+						return fmt.Errorf("-%s must contain a single char; got %q", filesCSVDelimiter, c.String(filesCSVDelimiter))
+					}
+					filesCfg := files.Config{
+						Path:         c.String(filesPath),
+						Format:       c.String(filesFormat),
+						CSVDelimiter: delimiter[0],
+						Mapping:      mapping,
+						S3: files.S3Config{
+							CredsFilePath:  c.String(filesS3CredsFilePath),
+							ConfigFilePath: c.String(filesS3ConfigFilePath),
+							ProfileName:    c.String(filesS3ConfigProfile),
+							CustomEndpoint: c.String(filesS3CustomEndpoint),
+							ForcePathStyle: c.Bool(filesS3ForcePathStyle),
+						},
+					}
+					cl, err := files.NewClient(filesCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create files client: %s", err)
+					}
+
+					vmCfg, err := initConfigVM(c)
+					if err != nil {
+						return fmt.Errorf("failed to init VM configuration: %s", err)
+					}
+					importer, err = vm.NewImporter(ctx, vmCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create VM importer: %s", err)
+					}
+
+					fp := filesProcessor{
+						cl:        cl,
+						im:        importer,
+						cc:        c.Int(filesConcurrency),
+						isVerbose: c.Bool(globalVerbose),
+					}
+					return fp.run()
+				},
+			},
 			{
 				Name:   "vm-native",
 				Usage:  "Migrate time series between VictoriaMetrics installations",
@@ -23,15 +23,21 @@ import (
 //
 // StopIngestionRateLimiter must be called before Stop() call in order to unblock all the callers
 // to ingestion rate limiter. Otherwise deadlock may occur at Stop() call.
+//
+// The rate limiting is disabled if maxIngestionRate <= 0. It can be enabled later via SetIngestionRateLimit.
 func StartIngestionRateLimiter(maxIngestionRate int) {
-	if maxIngestionRate <= 0 {
-		return
-	}
 	ingestionRateLimitReached := metrics.NewCounter(`vm_max_ingestion_rate_limit_reached_total`)
 	ingestionRateLimiterStopCh = make(chan struct{})
 	ingestionRateLimiter = ratelimiter.New(int64(maxIngestionRate), ingestionRateLimitReached, ingestionRateLimiterStopCh)
 }
 
+// SetIngestionRateLimit changes the limit for ingestion rate limiter started via StartIngestionRateLimiter.
+//
+// The rate limiting is disabled if maxIngestionRate <= 0.
+func SetIngestionRateLimit(maxIngestionRate int) {
+	ingestionRateLimiter.SetLimit(int64(maxIngestionRate))
+}
+
 // StopIngestionRateLimiter stops ingestion rate limiter.
 func StopIngestionRateLimiter() {
 	if ingestionRateLimiterStopCh == nil {
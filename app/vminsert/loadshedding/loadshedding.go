@@ -0,0 +1,311 @@
+package loadshedding
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	latencyBudgets = flagutil.NewArrayString("insert.latencyBudget", "Optional per-protocol latency budgets for insert requests in the form protocol:duration, "+
+		"e.g. promremotewrite:2s,influx:500ms . If the 99th percentile of request handling duration for some protocol exceeds its budget, "+
+		"then insert requests for protocols from -insert.loadSheddingOrder are rejected with 503 status code one protocol at a time until the latency returns within the budget. "+
+		"Supported protocols: "+strings.Join(supportedProtocols, ", ")+". See https://docs.victoriametrics.com/#ingestion-load-shedding")
+	loadSheddingOrder = flagutil.NewArrayString("insert.loadSheddingOrder", "The order for shedding insert protocols when -insert.latencyBudget is exceeded. "+
+		"The first protocol is shed first. Protocols missing in the list are never shed. "+
+		"By default: "+strings.Join(defaultLoadSheddingOrder, ","))
+	checkInterval = flag.Duration("insert.loadSheddingCheckInterval", 5*time.Second, "The interval for checking -insert.latencyBudget . "+
+		"The number of shed protocols is changed by one at every check")
+)
+
+// supportedProtocols contains protocols, which can be passed to -insert.latencyBudget and -insert.loadSheddingOrder.
+//
+// The names match the `protocol` label at vm_http_requests_total metrics.
+var supportedProtocols = []string{
+	"promremotewrite",
+	"vmimport",
+	"csvimport",
+	"prometheusimport",
+	"nativeimport",
+	"influx",
+	"opentelemetry",
+	"newrelic",
+	"datadog",
+}
+
+// defaultLoadSheddingOrder is used when -insert.loadSheddingOrder isn't set.
+//
+// Prometheus remote write isn't shed by default, since it is usually the most critical ingestion path.
+var defaultLoadSheddingOrder = []string{
+	"datadog",
+	"newrelic",
+	"influx",
+	"opentelemetry",
+	"csvimport",
+	"prometheusimport",
+	"vmimport",
+	"nativeimport",
+}
+
+var _ = metrics.NewGauge(`vm_insert_load_shedding_protocols`, func() float64 {
+	s := shedderGlobal
+	if s == nil {
+		return 0
+	}
+	return float64(s.level.Load())
+})
+
+var shedderGlobal *shedder
+
+// Init must be called after flag.Parse and before using the loadshedding package.
+func Init() {
+	if len(*latencyBudgets) == 0 {
+		return
+	}
+	budgets, err := parseLatencyBudgets(*latencyBudgets)
+	if err != nil {
+		logger.Fatalf("cannot parse -insert.latencyBudget: %s", err)
+	}
+	order := defaultLoadSheddingOrder
+	if len(*loadSheddingOrder) > 0 {
+		order = *loadSheddingOrder
+		if err := validateLoadSheddingOrder(order); err != nil {
+			logger.Fatalf("invalid -insert.loadSheddingOrder: %s", err)
+		}
+	}
+	if *checkInterval <= 0 {
+		logger.Fatalf("-insert.loadSheddingCheckInterval must be positive; got %s", *checkInterval)
+	}
+	shedderGlobal = newShedder(budgets, order)
+	shedderGlobal.start(*checkInterval)
+}
+
+// Stop stops the loadshedding package.
+func Stop() {
+	if shedderGlobal == nil {
+		return
+	}
+	shedderGlobal.stop()
+	shedderGlobal = nil
+}
+
+// CheckRequest returns an error with 503 status code if requests for the given protocol must be shed.
+//
+// Otherwise it returns a function, which must be called after the request is handled in order to track its latency.
+func CheckRequest(protocol string) (func(), error) {
+	s := shedderGlobal
+	if s == nil {
+		return noop, nil
+	}
+	if s.isShed(protocol) {
+		metrics.GetOrCreateCounter(fmt.Sprintf(`vm_insert_requests_shed_total{protocol=%q}`, protocol)).Inc()
+		return nil, &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("%s insert requests are temporarily rejected because -insert.latencyBudget is exceeded for insert requests; "+
+				"see https://docs.victoriametrics.com/#ingestion-load-shedding", protocol),
+			StatusCode: http.StatusServiceUnavailable,
+			RetryAfter: s.checkInterval,
+		}
+	}
+	lt := s.trackers[protocol]
+	if lt == nil {
+		return noop, nil
+	}
+	startTime := time.Now()
+	return func() {
+		lt.add(time.Since(startTime))
+	}, nil
+}
+
+func noop() {}
+
+func parseLatencyBudgets(a []string) (map[string]time.Duration, error) {
+	budgets := make(map[string]time.Duration, len(a))
+	for _, s := range a {
+		protocol, durationStr, ok := strings.Cut(s, ":")
+		if !ok {
+			return nil, fmt.Errorf("missing ':' in %q; it must have protocol:duration format", s)
+		}
+		if !isSupportedProtocol(protocol) {
+			return nil, fmt.Errorf("unsupported protocol %q in %q; supported protocols: %s", protocol, s, strings.Join(supportedProtocols, ", "))
+		}
+		if _, ok := budgets[protocol]; ok {
+			return nil, fmt.Errorf("duplicate latency budget for protocol %q", protocol)
+		}
+		d, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse duration in %q: %w", s, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("latency budget must be positive in %q", s)
+		}
+		budgets[protocol] = d
+	}
+	return budgets, nil
+}
+
+func validateLoadSheddingOrder(order []string) error {
+	seen := make(map[string]bool, len(order))
+	for _, protocol := range order {
+		if !isSupportedProtocol(protocol) {
+			return fmt.Errorf("unsupported protocol %q; supported protocols: %s", protocol, strings.Join(supportedProtocols, ", "))
+		}
+		if seen[protocol] {
+			return fmt.Errorf("duplicate protocol %q", protocol)
+		}
+		seen[protocol] = true
+	}
+	return nil
+}
+
+func isSupportedProtocol(protocol string) bool {
+	for _, p := range supportedProtocols {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// shedder sheds insert requests for the least important protocols when the latency budget is exceeded.
+type shedder struct {
+	budgets map[string]time.Duration
+	order   []string
+
+	// trackers contain latency trackers for all the supported protocols.
+	trackers map[string]*latencyTracker
+
+	// level is the number of protocols from the start of order, which are currently shed.
+	level atomic.Int32
+
+	checkInterval time.Duration
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+}
+
+func newShedder(budgets map[string]time.Duration, order []string) *shedder {
+	trackers := make(map[string]*latencyTracker, len(supportedProtocols))
+	for _, protocol := range supportedProtocols {
+		trackers[protocol] = &latencyTracker{}
+	}
+	return &shedder{
+		budgets:  budgets,
+		order:    order,
+		trackers: trackers,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (s *shedder) start(interval time.Duration) {
+	s.checkInterval = interval
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.stopCh:
+				return
+			case <-t.C:
+				s.check()
+			}
+		}
+	}()
+}
+
+func (s *shedder) stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+func (s *shedder) isShed(protocol string) bool {
+	level := int(s.level.Load())
+	for i := 0; i < level && i < len(s.order); i++ {
+		if s.order[i] == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// check adjusts the number of shed protocols according to the latency collected since the previous check.
+//
+// A single protocol is shed or restored per check, so the load is changed gradually.
+func (s *shedder) check() {
+	exceeded := ""
+	for protocol, budget := range s.budgets {
+		p99, ok := s.trackers[protocol].p99()
+		if ok && p99 > budget {
+			exceeded = protocol
+			break
+		}
+	}
+	level := int(s.level.Load())
+	switch {
+	case exceeded != "" && level < len(s.order):
+		level++
+		logger.Warnf("99th percentile latency for %s insert requests exceeds -insert.latencyBudget=%s; start shedding %s insert requests",
+			exceeded, s.budgets[exceeded], s.order[level-1])
+	case exceeded == "" && level > 0:
+		level--
+		logger.Infof("insert latency returned within -insert.latencyBudget; stop shedding %s insert requests", s.order[level])
+	default:
+		return
+	}
+	s.level.Store(int32(level))
+}
+
+// maxLatencySamples is the maximum number of the most recent request durations used for estimating the 99th percentile.
+const maxLatencySamples = 1024
+
+// latencyTracker tracks request durations since the last p99 call.
+type latencyTracker struct {
+	mu sync.Mutex
+
+	// durations contains up to maxLatencySamples of the most recent request durations.
+	durations []time.Duration
+
+	// n is the number of durations added since the last p99 call.
+	n int
+}
+
+func (lt *latencyTracker) add(d time.Duration) {
+	lt.mu.Lock()
+	if len(lt.durations) < maxLatencySamples {
+		lt.durations = append(lt.durations, d)
+	} else {
+		lt.durations[lt.n%maxLatencySamples] = d
+	}
+	lt.n++
+	lt.mu.Unlock()
+}
+
+// p99 returns the 99th percentile of request durations added since the previous call.
+//
+// false is returned if no requests were tracked since the previous call.
+func (lt *latencyTracker) p99() (time.Duration, bool) {
+	lt.mu.Lock()
+	a := append([]time.Duration{}, lt.durations...)
+	lt.durations = lt.durations[:0]
+	lt.n = 0
+	lt.mu.Unlock()
+
+	if len(a) == 0 {
+		return 0, false
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i] < a[j]
+	})
+	return a[(len(a)-1)*99/100], true
+}
@@ -0,0 +1,155 @@
+package loadshedding
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLatencyBudgets_Success(t *testing.T) {
+	f := func(a []string, resultExpected map[string]time.Duration) {
+		t.Helper()
+
+		result, err := parseLatencyBudgets(a)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(result) != len(resultExpected) {
+			t.Fatalf("unexpected number of budgets; got %d; want %d", len(result), len(resultExpected))
+		}
+		for protocol, d := range resultExpected {
+			if result[protocol] != d {
+				t.Fatalf("unexpected budget for %q; got %s; want %s", protocol, result[protocol], d)
+			}
+		}
+	}
+
+	f(nil, nil)
+	f([]string{"promremotewrite:2s"}, map[string]time.Duration{
+		"promremotewrite": 2 * time.Second,
+	})
+	f([]string{"promremotewrite:2s", "influx:500ms"}, map[string]time.Duration{
+		"promremotewrite": 2 * time.Second,
+		"influx":          500 * time.Millisecond,
+	})
+}
+
+func TestParseLatencyBudgets_Failure(t *testing.T) {
+	f := func(a []string) {
+		t.Helper()
+
+		_, err := parseLatencyBudgets(a)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f([]string{"2s"})
+	f([]string{"foo:2s"})
+	f([]string{"influx:bar"})
+	f([]string{"influx:0s"})
+	f([]string{"influx:-1s"})
+	f([]string{"influx:1s", "influx:2s"})
+}
+
+func TestValidateLoadSheddingOrder(t *testing.T) {
+	f := func(order []string, resultExpected bool) {
+		t.Helper()
+
+		err := validateLoadSheddingOrder(order)
+		if resultExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !resultExpected && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f(nil, true)
+	f(defaultLoadSheddingOrder, true)
+	f([]string{"influx", "promremotewrite"}, true)
+	f([]string{"influx", "foo"}, false)
+	f([]string{"influx", "datadog", "influx"}, false)
+}
+
+func TestShedderCheck(t *testing.T) {
+	budgets := map[string]time.Duration{
+		"promremotewrite": time.Second,
+	}
+	s := newShedder(budgets, []string{"datadog", "influx"})
+
+	f := func(d time.Duration, levelExpected int, shedExpected []string) {
+		t.Helper()
+
+		if d > 0 {
+			s.trackers["promremotewrite"].add(d)
+		}
+		s.check()
+		if level := int(s.level.Load()); level != levelExpected {
+			t.Fatalf("unexpected level; got %d; want %d", level, levelExpected)
+		}
+		shed := make(map[string]bool)
+		for _, protocol := range shedExpected {
+			shed[protocol] = true
+		}
+		for _, protocol := range supportedProtocols {
+			if s.isShed(protocol) != shed[protocol] {
+				t.Fatalf("unexpected isShed(%q); got %v; want %v", protocol, s.isShed(protocol), shed[protocol])
+			}
+		}
+	}
+
+	// The latency is within the budget
+	f(100*time.Millisecond, 0, nil)
+
+	// No requests since the previous check
+	f(0, 0, nil)
+
+	// The latency exceeds the budget, so protocols are shed one by one
+	f(2*time.Second, 1, []string{"datadog"})
+	f(2*time.Second, 2, []string{"datadog", "influx"})
+
+	// promremotewrite is never shed, since it is missing in the order
+	f(2*time.Second, 2, []string{"datadog", "influx"})
+
+	// The latency returns within the budget, so protocols are restored one by one
+	f(100*time.Millisecond, 1, []string{"datadog"})
+	f(100*time.Millisecond, 0, nil)
+	f(100*time.Millisecond, 0, nil)
+}
+
+func TestLatencyTrackerP99(t *testing.T) {
+	var lt latencyTracker
+
+	if _, ok := lt.p99(); ok {
+		t.Fatalf("expecting no p99 for empty tracker")
+	}
+
+	for i := 1; i <= 100; i++ {
+		lt.add(time.Duration(i) * time.Millisecond)
+	}
+	p99, ok := lt.p99()
+	if !ok {
+		t.Fatalf("expecting non-empty p99")
+	}
+	if p99 != 99*time.Millisecond {
+		t.Fatalf("unexpected p99; got %s; want %s", p99, 99*time.Millisecond)
+	}
+
+	// The durations must be reset after p99 call
+	if _, ok := lt.p99(); ok {
+		t.Fatalf("expecting no p99 after the reset")
+	}
+
+	// Only the most recent durations must be tracked
+	for i := 0; i < 2*maxLatencySamples; i++ {
+		d := time.Millisecond
+		if i >= maxLatencySamples {
+			d = time.Second
+		}
+		lt.add(d)
+	}
+	p99, _ = lt.p99()
+	if p99 != time.Second {
+		t.Fatalf("unexpected p99; got %s; want %s", p99, time.Second)
+	}
+}
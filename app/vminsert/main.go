@@ -17,6 +17,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/datadogv2"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/influx"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/loadshedding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/native"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/newrelic"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/opentelemetry"
@@ -26,7 +27,9 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/prompush"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/promremotewrite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/seriespolicy"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/vmimport"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
@@ -55,6 +58,10 @@ var (
 		"See also -influxListenAddr.useProxyProtocol")
 	influxUseProxyProtocol = flag.Bool("influxListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -influxListenAddr . "+
 		"See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
+	influxUnixSocketPath = flag.String("influxUnixSocketPath", "", "Path to unix stream socket to listen for InfluxDB line protocol data. Doesn't work if empty. "+
+		"This socket can be used with unix:// address at Telegraf socket_writer output. See also -influxUnixgramSocketPath")
+	influxUnixgramSocketPath = flag.String("influxUnixgramSocketPath", "", "Path to unix datagram socket to listen for InfluxDB line protocol data. Doesn't work if empty. "+
+		"This socket can be used with unixgram:// address at Telegraf socket_writer output. See also -influxUnixSocketPath, -influx.datagramBatchSize and -influx.datagramFlushInterval")
 	opentsdbListenAddr = flag.String("opentsdbListenAddr", "", "TCP and UDP address to listen for OpenTSDB metrics. "+
 		"Telnet put messages and HTTP /api/put messages are simultaneously served on TCP port. "+
 		"Usually :4242 must be set. Doesn't work if empty. "+
@@ -73,10 +80,12 @@ var (
 )
 
 var (
-	graphiteServer     *graphiteserver.Server
-	influxServer       *influxserver.Server
-	opentsdbServer     *opentsdbserver.Server
-	opentsdbhttpServer *opentsdbhttpserver.Server
+	graphiteServer       *graphiteserver.Server
+	influxServer         *influxserver.Server
+	influxUnixServer     *influxserver.Server
+	influxUnixgramServer *influxserver.Server
+	opentsdbServer       *opentsdbserver.Server
+	opentsdbhttpServer   *opentsdbhttpserver.Server
 )
 
 //go:embed static
@@ -87,6 +96,8 @@ var staticServer = http.FileServer(http.FS(staticFiles))
 // Init initializes vminsert.
 func Init() {
 	relabel.Init()
+	seriespolicy.Init(vmstorage.Storage.DropSeriesFromCache)
+	loadshedding.Init()
 	common.InitStreamAggr()
 	protoparserutil.StartUnmarshalWorkers()
 	if len(*graphiteListenAddr) > 0 {
@@ -95,6 +106,12 @@ func Init() {
 	if len(*influxListenAddr) > 0 {
 		influxServer = influxserver.MustStart(*influxListenAddr, *influxUseProxyProtocol, influx.InsertHandlerForReader)
 	}
+	if len(*influxUnixSocketPath) > 0 {
+		influxUnixServer = influxserver.MustStartUnix(*influxUnixSocketPath, influx.InsertHandlerForReader)
+	}
+	if len(*influxUnixgramSocketPath) > 0 {
+		influxUnixgramServer = influxserver.MustStartUnixgram(*influxUnixgramSocketPath, influx.InsertHandlerForReader)
+	}
 	if len(*opentsdbListenAddr) > 0 {
 		opentsdbServer = opentsdbserver.MustStart(*opentsdbListenAddr, *opentsdbUseProxyProtocol, opentsdb.InsertHandler, opentsdbhttp.InsertHandler)
 	}
@@ -116,6 +133,12 @@ func Stop() {
 	if len(*influxListenAddr) > 0 {
 		influxServer.MustStop()
 	}
+	if len(*influxUnixSocketPath) > 0 {
+		influxUnixServer.MustStop()
+	}
+	if len(*influxUnixgramSocketPath) > 0 {
+		influxUnixgramServer.MustStop()
+	}
 	if len(*opentsdbListenAddr) > 0 {
 		opentsdbServer.MustStop()
 	}
@@ -124,6 +147,8 @@ func Stop() {
 	}
 	protoparserutil.StopUnmarshalWorkers()
 	common.MustStopStreamAggr()
+	loadshedding.Stop()
+	seriespolicy.Stop()
 }
 
 // RequestHandler is a handler for Prometheus remote storage write API
@@ -141,6 +166,19 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		staticServer.ServeHTTP(w, r)
 		return true
 	}
+	if strings.HasPrefix(path, "/datadog/") {
+		// Trim suffix from paths starting from /datadog/ in order to support legacy DataDog agent.
+		// See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/2670
+		path = strings.TrimSuffix(path, "/")
+	}
+	if protocol := getInsertProtocol(path); protocol != "" {
+		done, err := loadshedding.CheckRequest(protocol)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		defer done()
+	}
 	if strings.HasPrefix(path, "/prometheus/api/v1/import/prometheus") || strings.HasPrefix(path, "/api/v1/import/prometheus") {
 		prometheusimportRequests.Inc()
 		if err := prometheusimport.InsertHandler(r); err != nil {
@@ -158,11 +196,6 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		w.WriteHeader(statusCode)
 		return true
 	}
-	if strings.HasPrefix(path, "/datadog/") {
-		// Trim suffix from paths starting from /datadog/ in order to support legacy DataDog agent.
-		// See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/2670
-		path = strings.TrimSuffix(path, "/")
-	}
 	switch path {
 	case "/prometheus/api/v1/write", "/api/v1/write", "/api/v1/push", "/prometheus/api/v1/push":
 		if protoparserutil.HandleVMProtoServerHandshake(w, r) {
@@ -341,6 +374,16 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		promscrape.WriteConfigData(w)
 		return true
+	case "/prometheus/api/v1/status/remote_write_clients", "/api/v1/status/remote_write_clients":
+		promremotewriteClientsStatsRequests.Inc()
+		if err := promremotewrite.WriteClientsStats(w, r); err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/prometheus/api/v1/status/topology", "/api/v1/status/topology":
+		topologyRequests.Inc()
+		writeTopology(w)
+		return true
 	case "/prometheus/api/v1/status/config", "/api/v1/status/config":
 		// See https://prometheus.io/docs/prometheus/latest/querying/api/#config
 		if !httpserver.CheckAuthFlag(w, r, configAuthKey) {
@@ -376,6 +419,36 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	}
 }
 
+// getInsertProtocol returns the ingestion protocol for the given path.
+//
+// An empty string is returned if path isn't used for data ingestion.
+func getInsertProtocol(path string) string {
+	path = strings.TrimPrefix(path, "/prometheus")
+	if strings.HasPrefix(path, "/api/v1/import/prometheus") {
+		return "prometheusimport"
+	}
+	switch path {
+	case "/api/v1/write", "/api/v1/push":
+		return "promremotewrite"
+	case "/api/v1/import":
+		return "vmimport"
+	case "/api/v1/import/csv":
+		return "csvimport"
+	case "/api/v1/import/native":
+		return "nativeimport"
+	case "/influx/write", "/influx/api/v2/write", "/write", "/api/v2/write":
+		return "influx"
+	case "/opentelemetry/api/v1/push", "/opentelemetry/v1/metrics":
+		return "opentelemetry"
+	case "/newrelic/infra/v2/metrics/events/bulk":
+		return "newrelic"
+	case "/datadog/api/v1/series", "/datadog/api/v2/series", "/datadog/api/beta/sketches":
+		return "datadog"
+	default:
+		return ""
+	}
+}
+
 func addInfluxResponseHeaders(w http.ResponseWriter) {
 	// This is needed for some clients, which expect InfluxDB version header.
 	// See, for example, https://github.com/ntop/ntopng/issues/5449#issuecomment-1005347597
@@ -440,5 +513,8 @@ var (
 	promscrapeConfigRequests       = metrics.NewCounter(`vm_http_requests_total{path="/config"}`)
 	promscrapeStatusConfigRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/config"}`)
 
+	promremotewriteClientsStatsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/remote_write_clients"}`)
+	topologyRequests                    = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/topology"}`)
+
 	promscrapeConfigReloadRequests = metrics.NewCounter(`vm_http_requests_total{path="/-/reload"}`)
 )
@@ -0,0 +1,160 @@
+package promremotewrite
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
+)
+
+var (
+	clientIdentityHeader = flag.String("promremotewrite.clientIdentityHeader", "", "Optional HTTP request header with the identity of Prometheus remote write client such as vmagent or Prometheus. "+
+		"If the header is missing, then the CommonName from client TLS certificate is used if -mtls is set. Otherwise the client ip address is used. "+
+		"See https://docs.victoriametrics.com/#remote-write-clients-stats")
+	maxTrackedClients = flag.Int("promremotewrite.maxTrackedClients", 1000, "The maximum number of Prometheus remote write clients to track stats for. "+
+		"Stats for the remaining clients are accounted under \"other\" client. See https://docs.victoriametrics.com/#remote-write-clients-stats")
+)
+
+// otherClient is the client identity for clients exceeding -promremotewrite.maxTrackedClients
+const otherClient = "other"
+
+type clientStats struct {
+	requests atomic.Uint64
+	bytes    atomic.Uint64
+	samples  atomic.Uint64
+
+	// lastRequestTime is the last request time in unix seconds
+	lastRequestTime atomic.Int64
+}
+
+var (
+	clientsStatsLock sync.Mutex
+	clientsStats     = make(map[string]*clientStats)
+)
+
+var _ = metrics.NewGauge(`vm_promremotewrite_tracked_clients`, func() float64 {
+	clientsStatsLock.Lock()
+	n := len(clientsStats)
+	clientsStatsLock.Unlock()
+	return float64(n)
+})
+
+// getClientIdentity returns identity for the client sent req.
+func getClientIdentity(req *http.Request) string {
+	if *clientIdentityHeader != "" {
+		if s := req.Header.Get(*clientIdentityHeader); s != "" {
+			return s
+		}
+	}
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		if cn := req.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func getClientStats(client string) *clientStats {
+	clientsStatsLock.Lock()
+	defer clientsStatsLock.Unlock()
+
+	cs := clientsStats[client]
+	if cs != nil {
+		return cs
+	}
+	if len(clientsStats) >= *maxTrackedClients {
+		client = otherClient
+		if cs := clientsStats[client]; cs != nil {
+			return cs
+		}
+	}
+	cs = &clientStats{}
+	clientsStats[client] = cs
+	return cs
+}
+
+// registerRequest registers a remote write request with the given number of bytes and samples from the given client.
+func registerRequest(client string, bytes, samples int) {
+	cs := getClientStats(client)
+	cs.requests.Add(1)
+	cs.bytes.Add(uint64(bytes))
+	cs.samples.Add(uint64(samples))
+	cs.lastRequestTime.Store(time.Now().Unix())
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+// WriteClientsStats writes stats for Prometheus remote write clients to w.
+//
+// The stats are sorted by the number of samples in descending order. The number of returned clients can be limited via topN query arg.
+func WriteClientsStats(w http.ResponseWriter, r *http.Request) error {
+	topN, err := httputil.GetInt(r, "topN")
+	if err != nil {
+		return err
+	}
+
+	type clientStatsEntry struct {
+		client          string
+		requests        uint64
+		bytes           uint64
+		samples         uint64
+		lastRequestTime int64
+	}
+	clientsStatsLock.Lock()
+	entries := make([]clientStatsEntry, 0, len(clientsStats))
+	for client, cs := range clientsStats {
+		entries = append(entries, clientStatsEntry{
+			client:          client,
+			requests:        cs.requests.Load(),
+			bytes:           cs.bytes.Load(),
+			samples:         cs.samples.Load(),
+			lastRequestTime: cs.lastRequestTime.Load(),
+		})
+	}
+	clientsStatsLock.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].samples != entries[j].samples {
+			return entries[i].samples > entries[j].samples
+		}
+		return entries[i].client < entries[j].client
+	})
+	if topN > 0 && len(entries) > topN {
+		entries = entries[:topN]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":[`)
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintf(w, `,`)
+		}
+		fmt.Fprintf(w, `{"client":%s,"requests":%d,"bytes":%d,"samples":%d,"lastRequestTimestamp":%d}`,
+			stringsutil.JSONString(e.client), e.requests, e.bytes, e.samples, e.lastRequestTime)
+	}
+	fmt.Fprintf(w, `]}`)
+	return nil
+}
@@ -0,0 +1,103 @@
+package promremotewrite
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetClientIdentity(t *testing.T) {
+	origHeader := *clientIdentityHeader
+	*clientIdentityHeader = "X-Client-Id"
+	defer func() {
+		*clientIdentityHeader = origHeader
+	}()
+
+	f := func(header, tlsCN, remoteAddr, identityExpected string) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/write", nil)
+		req.RemoteAddr = remoteAddr
+		if header != "" {
+			req.Header.Set("X-Client-Id", header)
+		}
+		if tlsCN != "" {
+			req.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{
+					Subject: pkix.Name{
+						CommonName: tlsCN,
+					},
+				}},
+			}
+		}
+		identity := getClientIdentity(req)
+		if identity != identityExpected {
+			t.Fatalf("unexpected client identity; got %q; want %q", identity, identityExpected)
+		}
+	}
+
+	// identity from header
+	f("vmagent-1", "vmagent-2", "1.2.3.4:1234", "vmagent-1")
+
+	// identity from TLS CN
+	f("", "vmagent-2", "1.2.3.4:1234", "vmagent-2")
+
+	// identity from remote address
+	f("", "", "1.2.3.4:1234", "1.2.3.4")
+	f("", "", "1.2.3.4", "1.2.3.4")
+}
+
+func TestWriteClientsStats(t *testing.T) {
+	origMaxTrackedClients := *maxTrackedClients
+	*maxTrackedClients = 2
+	defer func() {
+		*maxTrackedClients = origMaxTrackedClients
+		clientsStatsLock.Lock()
+		clientsStats = make(map[string]*clientStats)
+		clientsStatsLock.Unlock()
+	}()
+
+	registerRequest("foo", 100, 10)
+	registerRequest("bar", 200, 30)
+	registerRequest("foo", 100, 10)
+
+	// clients exceeding -promremotewrite.maxTrackedClients are accounted under "other" client
+	registerRequest("baz", 50, 5)
+	registerRequest("qwe", 50, 5)
+
+	f := func(requestURI string, clientsExpected []string, entryExpected string) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, requestURI, nil)
+		w := httptest.NewRecorder()
+		if err := WriteClientsStats(w, req); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp := w.Body.String()
+		prevIdx := -1
+		for _, client := range clientsExpected {
+			idx := strings.Index(resp, `"client":"`+client+`"`)
+			if idx < 0 {
+				t.Fatalf("missing client %q in the response %s", client, resp)
+			}
+			if idx < prevIdx {
+				t.Fatalf("unexpected order for client %q in the response %s", client, resp)
+			}
+			prevIdx = idx
+		}
+		if n := strings.Count(resp, `"client":`); n != len(clientsExpected) {
+			t.Fatalf("unexpected number of clients in the response; got %d; want %d; response: %s", n, len(clientsExpected), resp)
+		}
+		if !strings.Contains(resp, entryExpected) {
+			t.Fatalf("missing %q in the response %s", entryExpected, resp)
+		}
+	}
+
+	f("/api/v1/status/remote_write_clients", []string{"bar", "foo", "other"}, `{"client":"foo","requests":2,"bytes":200,"samples":20,`)
+	f("/api/v1/status/remote_write_clients?topN=1", []string{"bar"}, `{"client":"bar","requests":1,"bytes":200,"samples":30,`)
+	f("/api/v1/status/remote_write_clients", []string{"bar", "foo", "other"}, `{"client":"other","requests":2,"bytes":100,"samples":10,`)
+}
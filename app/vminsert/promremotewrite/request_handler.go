@@ -5,16 +5,20 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/promremotewrite/stream"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/protoparserutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
 	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="promremotewrite"}`)
 	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="promremotewrite"}`)
+
+	metadataInserted = metrics.NewCounter(`vm_metadata_rows_inserted_total{type="promremotewrite"}`)
 )
 
 // InsertHandler processes remote write for prometheus.
@@ -24,12 +28,39 @@ func InsertHandler(req *http.Request) error {
 		return err
 	}
 	isVMRemoteWrite := req.Header.Get("Content-Encoding") == "zstd"
-	return stream.Parse(req.Body, isVMRemoteWrite, func(tss []prompb.TimeSeries) error {
-		return insertRows(tss, extraLabels)
+	cr := &countingReader{
+		r: req.Body,
+	}
+	samples := 0
+	err = stream.Parse(cr, isVMRemoteWrite, func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error {
+		insertMetadata(mms)
+		n, err := insertRows(tss, extraLabels)
+		samples += n
+		return err
 	})
+	registerRequest(getClientIdentity(req), cr.n, samples)
+	return err
+}
+
+func insertMetadata(mms []prompb.MetricMetadata) {
+	if len(mms) == 0 {
+		return
+	}
+	mmsDst := make([]storage.MetricMetadata, len(mms))
+	for i := range mms {
+		mm := &mms[i]
+		mmsDst[i] = storage.MetricMetadata{
+			MetricFamilyName: mm.MetricFamilyName,
+			Type:             prompb.MetricTypeString(mm.Type),
+			Help:             mm.Help,
+			Unit:             mm.Unit,
+		}
+	}
+	vmstorage.AddMetricMetadata(mmsDst)
+	metadataInserted.Add(len(mms))
 }
 
-func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
+func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) (int, error) {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -63,11 +94,11 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 			r := &samples[i]
 			metricNameRaw, err = ctx.WriteDataPointExt(metricNameRaw, ctx.Labels, r.Timestamp, r.Value)
 			if err != nil {
-				return err
+				return rowsTotal, err
 			}
 		}
 	}
 	rowsInserted.Add(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
-	return ctx.FlushBufs()
+	return rowsTotal, ctx.FlushBufs()
 }
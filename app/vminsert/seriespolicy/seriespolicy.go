@@ -0,0 +1,409 @@
+package seriespolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/contextutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var (
+	policyURL = flag.String("seriesPolicy.url", "", "Optional URL of the external policy service for accepting or denying new time series during data ingestion. "+
+		"Batches of new series without cached decisions are sent to this URL in background. See https://docs.victoriametrics.com/#series-policy-webhook")
+	policyBearerToken = flagutil.NewPassword("seriesPolicy.bearerToken", "Optional bearer token to send in requests to -seriesPolicy.url")
+	policyTimeout     = flag.Duration("seriesPolicy.timeout", 5*time.Second, "Timeout for requests to -seriesPolicy.url")
+	cacheDuration     = flag.Duration("seriesPolicy.cacheDuration", time.Hour, "How long to cache the decision received from -seriesPolicy.url for every new time series. "+
+		"The series is sent to -seriesPolicy.url again if it is still missing in the storage after the cached decision expires")
+	maxCachedDecisions = flag.Int("seriesPolicy.maxCachedDecisions", 100_000, "The maximum number of decisions received from -seriesPolicy.url to cache. "+
+		"Older decisions are dropped when the limit is reached")
+	maxBatchSize      = flag.Int("seriesPolicy.maxBatchSize", 1000, "The maximum number of series to send in a single request to -seriesPolicy.url")
+	denyUntilDecision = flag.Bool("seriesPolicy.denyUntilDecision", false, "Whether to drop samples for new series until the decision is received from -seriesPolicy.url. "+
+		"By default such samples are accepted, so new series are created before the decision is received, and samples for denied series are dropped after the decision is received. "+
+		"The decision isn't cached on errors, so the series is sent to -seriesPolicy.url again on the next ingestion")
+)
+
+var (
+	requestsTotal        = metrics.NewCounter(`vm_series_policy_requests_total`)
+	requestErrorsTotal   = metrics.NewCounter(`vm_series_policy_request_errors_total`)
+	seriesChecked        = metrics.NewCounter(`vm_series_policy_series_checked_total`)
+	seriesDenied         = metrics.NewCounter(`vm_series_policy_series_denied_total`)
+	seriesQueueOverflows = metrics.NewCounter(`vm_series_policy_queue_overflows_total`)
+	rowsDenied           = metrics.NewCounter(`vm_rows_ignored_total{reason="series_policy_denied"}`)
+	rowsPending          = metrics.NewCounter(`vm_rows_ignored_total{reason="series_policy_pending"}`)
+
+	_ = metrics.NewGauge(`vm_series_policy_cached_decisions`, func() float64 {
+		p := policyGlobal
+		if p == nil {
+			return 0
+		}
+		p.mu.Lock()
+		n := len(p.decisions) + len(p.prevDecisions)
+		p.mu.Unlock()
+		return float64(n)
+	})
+)
+
+var policyGlobal *policy
+
+// Init must be called after flag.Parse and before using the seriespolicy package.
+//
+// New time series are checked at -seriesPolicy.url before they are created in the storage.
+// dropSeriesFromCache is called for series denied after their creation in the storage,
+// so their samples are checked again during data ingestion.
+func Init(dropSeriesFromCache func(metricNameRaw []byte)) {
+	if *policyURL == "" {
+		return
+	}
+	if *maxBatchSize <= 0 {
+		logger.Fatalf("-seriesPolicy.maxBatchSize must be positive; got %d", *maxBatchSize)
+	}
+	if *maxCachedDecisions <= 0 {
+		logger.Fatalf("-seriesPolicy.maxCachedDecisions must be positive; got %d", *maxCachedDecisions)
+	}
+	authHeader := ""
+	if token := policyBearerToken.Get(); token != "" {
+		authHeader = "Bearer " + token
+	}
+	policyGlobal = newPolicy(*policyURL, authHeader, *policyTimeout, *cacheDuration, *maxCachedDecisions, *maxBatchSize, *denyUntilDecision, dropSeriesFromCache)
+	storage.SetNewSeriesFilter(policyGlobal.isNewSeriesAllowed)
+	storage.SetDeniedSeriesFilter(policyGlobal.isSeriesDenied)
+}
+
+// Stop stops the seriespolicy package.
+func Stop() {
+	if policyGlobal == nil {
+		return
+	}
+	storage.SetNewSeriesFilter(nil)
+	storage.SetDeniedSeriesFilter(nil)
+	policyGlobal.stop()
+}
+
+type policy struct {
+	url                string
+	authHeader         string
+	client             *http.Client
+	cacheDuration      uint64
+	maxCachedDecisions int
+	maxBatchSize       int
+	denyUntilDecision  bool
+
+	// dropSeriesFromCache is called for series, which may be created in the storage before the decision is received.
+	dropSeriesFromCache func(metricNameRaw []byte)
+
+	// pendingCh contains new series, which must be checked at the policy service.
+	pendingCh chan string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	mu sync.Mutex
+
+	// decisions contains cached decisions per every metricNameRaw.
+	//
+	// It is moved to prevDecisions when it reaches maxCachedDecisions items, so the number of cached decisions is limited.
+	decisions     map[string]decision
+	prevDecisions map[string]decision
+
+	// pending contains metricNameRaw for series at pendingCh or at the request to the policy service.
+	pending map[string]struct{}
+}
+
+type decision struct {
+	denied   bool
+	deadline uint64
+}
+
+func newPolicy(url, authHeader string, timeout, cacheDuration time.Duration, maxCachedDecisions, maxBatchSize int, denyUntilDecision bool,
+	dropSeriesFromCache func(metricNameRaw []byte)) *policy {
+	tr := httputil.NewTransport(false, "vm_series_policy")
+	p := &policy{
+		url:        url,
+		authHeader: authHeader,
+		client: &http.Client{
+			Transport: tr,
+			Timeout:   timeout,
+		},
+		cacheDuration:      uint64(cacheDuration.Seconds()),
+		maxCachedDecisions: maxCachedDecisions,
+		maxBatchSize:       maxBatchSize,
+		denyUntilDecision:  denyUntilDecision,
+
+		dropSeriesFromCache: dropSeriesFromCache,
+
+		pendingCh: make(chan string, 10*maxBatchSize),
+		stopCh:    make(chan struct{}),
+
+		decisions:     make(map[string]decision),
+		prevDecisions: make(map[string]decision),
+		pending:       make(map[string]struct{}),
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.runChecker()
+	}()
+	return p
+}
+
+func (p *policy) stop() {
+	close(p.stopCh)
+	p.wg.Wait()
+	p.client.CloseIdleConnections()
+}
+
+// isNewSeriesAllowed returns false if samples for the new series with the given metricNameRaw must be dropped.
+//
+// It is called by the storage before creating new series, so it mustn't block.
+// Series without cached decisions are queued for checking at the policy service in background.
+func (p *policy) isNewSeriesAllowed(metricNameRaw []byte) bool {
+	key := bytesutil.ToUnsafeString(metricNameRaw)
+	currentTime := fasttime.UnixTimestamp()
+
+	p.mu.Lock()
+	d, ok := p.getDecisionLocked(key, currentTime)
+	if ok {
+		p.mu.Unlock()
+		if d.denied {
+			rowsDenied.Inc()
+			return false
+		}
+		return true
+	}
+	p.queueLocked(key)
+	p.mu.Unlock()
+
+	if p.denyUntilDecision {
+		rowsPending.Inc()
+		return false
+	}
+	return true
+}
+
+// isSeriesDenied returns true if samples for the existing series with the given metricNameRaw must be dropped.
+//
+// It is called by the storage for series missing in the TSID cache, so it mustn't block.
+// Series without cached decisions aren't sent to the policy service, since they have been already accepted.
+// Series with expired denials are sent to the policy service again, while their samples are dropped until the new decision is received.
+func (p *policy) isSeriesDenied(metricNameRaw []byte) bool {
+	key := bytesutil.ToUnsafeString(metricNameRaw)
+	currentTime := fasttime.UnixTimestamp()
+
+	p.mu.Lock()
+	d, ok := p.decisions[key]
+	if !ok {
+		d, ok = p.prevDecisions[key]
+	}
+	if !ok || !d.denied {
+		p.mu.Unlock()
+		return false
+	}
+	if currentTime >= d.deadline {
+		p.queueLocked(key)
+	}
+	p.mu.Unlock()
+
+	rowsDenied.Inc()
+	return true
+}
+
+// queueLocked queues the series with the given key for checking at the policy service if it isn't queued yet.
+func (p *policy) queueLocked(key string) {
+	if _, ok := p.pending[key]; ok {
+		return
+	}
+	key = strings.Clone(key)
+	select {
+	case p.pendingCh <- key:
+		p.pending[key] = struct{}{}
+	default:
+		// The queue is full. The series will be queued on the next ingestion.
+		seriesQueueOverflows.Inc()
+	}
+}
+
+func (p *policy) getDecisionLocked(key string, currentTime uint64) (decision, bool) {
+	d, ok := p.decisions[key]
+	if !ok {
+		d, ok = p.prevDecisions[key]
+	}
+	if !ok || currentTime >= d.deadline {
+		return decision{}, false
+	}
+	return d, true
+}
+
+func (p *policy) addDecisionLocked(key string, d decision) {
+	if len(p.decisions) >= p.maxCachedDecisions {
+		p.prevDecisions = p.decisions
+		p.decisions = make(map[string]decision)
+	}
+	p.decisions[key] = d
+}
+
+// runChecker checks the queued series at the policy service until stop is called.
+func (p *policy) runChecker() {
+	var batch []string
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case key := <-p.pendingCh:
+			batch = append(batch[:0], key)
+		}
+
+		// Collect the remaining queued series into the batch without waiting for new series.
+	collect:
+		for len(batch) < p.maxBatchSize {
+			select {
+			case key := <-p.pendingCh:
+				batch = append(batch, key)
+			default:
+				break collect
+			}
+		}
+
+		p.processBatch(batch)
+	}
+}
+
+func (p *policy) processBatch(batch []string) {
+	denied, err := p.checkSeries(batch)
+	if err != nil {
+		requestErrorsTotal.Inc()
+		errorLogger.Warnf("cannot check %d new series at -seriesPolicy.url: %s; %s", len(batch), err, p.getErrorAction())
+	}
+
+	deadline := fasttime.UnixTimestamp() + p.cacheDuration
+	p.mu.Lock()
+	for i, key := range batch {
+		delete(p.pending, key)
+		if err != nil {
+			continue
+		}
+		p.addDecisionLocked(key, decision{
+			denied:   denied[i],
+			deadline: deadline,
+		})
+		if denied[i] {
+			seriesDenied.Inc()
+		}
+	}
+	p.mu.Unlock()
+
+	if err != nil || p.dropSeriesFromCache == nil {
+		return
+	}
+	// The denied series may be already created in the storage, since their samples are accepted until the decision is received.
+	// Drop them from the TSID cache, so their samples are dropped via isSeriesDenied.
+	for i, key := range batch {
+		if denied[i] {
+			p.dropSeriesFromCache(bytesutil.ToUnsafeBytes(key))
+		}
+	}
+}
+
+func (p *policy) getErrorAction() string {
+	if p.denyUntilDecision {
+		return "dropping samples for these series according to -seriesPolicy.denyUntilDecision"
+	}
+	return "accepting samples for these series; set -seriesPolicy.denyUntilDecision for dropping them instead"
+}
+
+var errorLogger = logger.WithThrottler("series_policy_error", 5*time.Second)
+
+// policyRequest is the request body sent to the policy service.
+type policyRequest struct {
+	// Series contains labels per every checked series.
+	Series []map[string]string `json:"series"`
+}
+
+// policyResponse is the response body returned by the policy service.
+type policyResponse struct {
+	// Denied contains indexes of denied series at policyRequest.Series.
+	Denied []int `json:"denied"`
+}
+
+// checkSeries sends series with the given metricNameRaw to the policy service.
+//
+// It returns true per every denied series.
+func (p *policy) checkSeries(metricNamesRaw []string) ([]bool, error) {
+	seriesChecked.Add(len(metricNamesRaw))
+
+	var req policyRequest
+	mn := storage.GetMetricName()
+	for _, metricNameRaw := range metricNamesRaw {
+		if err := mn.UnmarshalRaw(bytesutil.ToUnsafeBytes(metricNameRaw)); err != nil {
+			storage.PutMetricName(mn)
+			return nil, fmt.Errorf("cannot unmarshal metric name: %w", err)
+		}
+		labels := make(map[string]string, len(mn.Tags)+1)
+		if len(mn.MetricGroup) > 0 {
+			labels["__name__"] = string(mn.MetricGroup)
+		}
+		for _, tag := range mn.Tags {
+			labels[string(tag.Key)] = string(tag.Value)
+		}
+		req.Series = append(req.Series, labels)
+	}
+	storage.PutMetricName(mn)
+
+	data, err := json.Marshal(&req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request: %w", err)
+	}
+
+	requestsTotal.Inc()
+	ctx, cancel := contextutil.NewStopChanContext(p.stopCh)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if p.authHeader != "" {
+		r.Header.Set("Authorization", p.authHeader)
+	}
+	resp, err := p.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d; response body: %q", resp.StatusCode, body)
+	}
+
+	var pr policyResponse
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return nil, fmt.Errorf("cannot parse response %q: %w", body, err)
+	}
+	denied := make([]bool, len(metricNamesRaw))
+	for _, idx := range pr.Denied {
+		if idx < 0 || idx >= len(denied) {
+			return nil, fmt.Errorf("unexpected index of denied series %d; it must be in the range [0..%d]", idx, len(denied)-1)
+		}
+		denied[idx] = true
+	}
+	return denied, nil
+}
@@ -0,0 +1,243 @@
+package seriespolicy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestPolicyIsNewSeriesAllowed(t *testing.T) {
+	var checkedSeries atomic.Int64
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method; got %q; want %q", r.Method, http.MethodPost)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer foo" {
+			t.Errorf("unexpected Authorization header; got %q; want %q", auth, "Bearer foo")
+		}
+		var req policyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot parse request: %s", err)
+		}
+		checkedSeries.Add(int64(len(req.Series)))
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		// Deny series with team="unknown" label
+		var resp policyResponse
+		for i, labels := range req.Series {
+			if labels["team"] == "unknown" {
+				resp.Denied = append(resp.Denied, i)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(&resp); err != nil {
+			t.Errorf("cannot write response: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	newMetricNameRaw := func(name string) []byte {
+		team := "dev"
+		if name == "bad" {
+			team = "unknown"
+		}
+		return storage.MarshalMetricNameRaw(nil, []prompbmarshal.Label{
+			{Name: "__name__", Value: name},
+			{Name: "team", Value: team},
+		})
+	}
+
+	// waitForChecks waits until all the queued series are checked at the policy service.
+	waitForChecks := func(p *policy) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			p.mu.Lock()
+			n := len(p.pending)
+			p.mu.Unlock()
+			if n == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout when waiting for %d pending series", n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	f := func(p *policy, series []string, allowedExpected []bool, checkedSeriesExpected int64) {
+		t.Helper()
+
+		checkedSeries.Store(0)
+		allowed := make([]bool, len(series))
+		for i, name := range series {
+			allowed[i] = p.isNewSeriesAllowed(newMetricNameRaw(name))
+		}
+		if !reflect.DeepEqual(allowed, allowedExpected) {
+			t.Fatalf("unexpected allowed series; got %v; want %v", allowed, allowedExpected)
+		}
+		waitForChecks(p)
+		if n := checkedSeries.Load(); n != checkedSeriesExpected {
+			t.Fatalf("unexpected number of checked series; got %d; want %d", n, checkedSeriesExpected)
+		}
+	}
+
+	p := newPolicy(srv.URL, "Bearer foo", time.Second, time.Hour, 100, 10, false, nil)
+
+	// new series are accepted until the decision is received
+	f(p, []string{"foo", "bad", "bar", "bad"}, []bool{true, true, true, true}, 3)
+
+	// decisions are cached
+	f(p, []string{"bad", "foo", "bar"}, []bool{false, true, true}, 0)
+	f(p, []string{"baz", "bad"}, []bool{true, false}, 1)
+
+	// decisions aren't cached on errors
+	fail.Store(true)
+	f(p, []string{"x"}, []bool{true}, 1)
+	fail.Store(false)
+	f(p, []string{"x", "bad"}, []bool{true, false}, 1)
+	f(p, []string{"x"}, []bool{true}, 0)
+	p.stop()
+
+	// new series are denied until the decision is received if denyUntilDecision is set
+	p = newPolicy(srv.URL, "Bearer foo", time.Second, time.Hour, 100, 10, true, nil)
+	f(p, []string{"foo", "bad"}, []bool{false, false}, 2)
+	f(p, []string{"foo", "bad"}, []bool{true, false}, 0)
+	p.stop()
+
+	// expired decisions are checked again
+	p = newPolicy(srv.URL, "Bearer foo", time.Second, 0, 100, 10, false, nil)
+	f(p, []string{"foo", "bad"}, []bool{true, true}, 2)
+	f(p, []string{"foo", "bad"}, []bool{true, true}, 2)
+	p.stop()
+}
+
+func TestPolicyMaxCachedDecisions(t *testing.T) {
+	p := newPolicy("http://127.0.0.1:1", "", time.Second, time.Hour, 2, 10, false, nil)
+	defer p.stop()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d := decision{
+		denied:   true,
+		deadline: fasttime.UnixTimestamp() + 3600,
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		p.addDecisionLocked(key, d)
+		if n := len(p.decisions) + len(p.prevDecisions); n > 2*p.maxCachedDecisions {
+			t.Fatalf("too many cached decisions: %d; mustn't exceed %d", n, 2*p.maxCachedDecisions)
+		}
+	}
+
+	// The most recent decisions must be cached.
+	for _, key := range []string{"d", "e"} {
+		if _, ok := p.getDecisionLocked(key, fasttime.UnixTimestamp()); !ok {
+			t.Fatalf("missing cached decision for %q", key)
+		}
+	}
+	// The oldest decisions must be dropped.
+	for _, key := range []string{"a", "b"} {
+		if _, ok := p.getDecisionLocked(key, fasttime.UnixTimestamp()); ok {
+			t.Fatalf("unexpected cached decision for %q", key)
+		}
+	}
+}
+
+func TestPolicyCheckSeriesFailure(t *testing.T) {
+	f := func(response string) {
+		t.Helper()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(response))
+		}))
+		defer srv.Close()
+
+		p := newPolicy(srv.URL, "", time.Second, time.Hour, 100, 10, false, nil)
+		defer p.stop()
+		metricNameRaw := storage.MarshalMetricNameRaw(nil, []prompbmarshal.Label{{Name: "__name__", Value: "foo"}})
+		if _, err := p.checkSeries([]string{string(metricNameRaw)}); err == nil {
+			t.Fatalf("expecting non-nil error for response %q", response)
+		}
+	}
+
+	// invalid json
+	f(`foo`)
+	f(`{"denied":"foo"}`)
+
+	// index out of range
+	f(`{"denied":[1]}`)
+	f(`{"denied":[-1]}`)
+}
+
+func TestPolicyDenyAfterSeriesCreation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Deny all the series
+		_, _ = w.Write([]byte(`{"denied":[0]}`))
+	}))
+	defer srv.Close()
+
+	s := storage.MustOpenStorage(t.TempDir(), storage.OpenOptions{})
+	defer s.MustClose()
+
+	p := newPolicy(srv.URL, "", time.Second, time.Hour, 100, 10, false, s.DropSeriesFromCache)
+	defer p.stop()
+	storage.SetNewSeriesFilter(p.isNewSeriesAllowed)
+	defer storage.SetNewSeriesFilter(nil)
+	storage.SetDeniedSeriesFilter(p.isSeriesDenied)
+	defer storage.SetDeniedSeriesFilter(nil)
+
+	metricNameRaw := storage.MarshalMetricNameRaw(nil, []prompbmarshal.Label{{Name: "__name__", Value: "foo"}})
+	timestamp := time.Now().UnixMilli()
+	addRow := func() {
+		t.Helper()
+		timestamp++
+		s.AddRows([]storage.MetricRow{{
+			MetricNameRaw: metricNameRaw,
+			Timestamp:     timestamp,
+			Value:         1,
+		}}, 64)
+	}
+	rowsAddedExpected := func(n uint64) {
+		t.Helper()
+		var m storage.Metrics
+		s.UpdateMetrics(&m)
+		if m.RowsAddedTotal != n {
+			t.Fatalf("unexpected number of added rows; got %d; want %d", m.RowsAddedTotal, n)
+		}
+	}
+
+	// The first sample is accepted and the series is created before the decision is received.
+	addRow()
+	rowsAddedExpected(1)
+
+	// Wait for the deny decision.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		_, ok := p.getDecisionLocked(string(metricNameRaw), fasttime.UnixTimestamp())
+		p.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout when waiting for the decision")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The next samples must be dropped, since the series is denied.
+	addRow()
+	addRow()
+	rowsAddedExpected(1)
+}
@@ -0,0 +1,30 @@
+package vminsert
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
+)
+
+var topologyNodes = flagutil.NewArrayString("topology.vminsertNodes", "Optional ordered list of vminsert addresses to advertise at /api/v1/status/topology . "+
+	"vmagent with -remoteWrite.shardByURL.topologyURL pointing to this endpoint routes every series to the same vminsert node "+
+	"via jump consistent hash over this list. The order of nodes must be identical across all the vminsert nodes. "+
+	"See https://docs.victoriametrics.com/vmagent/#sharding-among-remote-storages")
+
+// topologyHashAlgorithm is the hash algorithm, which must be used by clients for routing series among topologyNodes.
+const topologyHashAlgorithm = "jump"
+
+// writeTopology writes the ordered list of vminsert nodes from -topology.vminsertNodes to w in JSON format.
+func writeTopology(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":{"hashAlgorithm":%s,"nodes":[`, stringsutil.JSONString(topologyHashAlgorithm))
+	for i, node := range *topologyNodes {
+		if i > 0 {
+			fmt.Fprintf(w, `,`)
+		}
+		fmt.Fprintf(w, `%s`, stringsutil.JSONString(node))
+	}
+	fmt.Fprintf(w, `]}}`)
+}
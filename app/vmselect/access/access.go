@@ -0,0 +1,259 @@
+package access
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	corsAllowedOrigins = flagutil.NewArrayString("search.corsAllowedOrigins", "Optional list of origins allowed to send cross-origin requests to querying APIs from browsers. "+
+		"For example, -search.corsAllowedOrigins=https://grafana.example.com . By default cross-origin requests are allowed from any origin. "+
+		"See https://docs.victoriametrics.com/#cors-and-api-tokens")
+	authTokensFile = flag.String("search.authTokensFile", "", "Optional path to file with bearer tokens, which are allowed to access querying APIs. "+
+		"Every token can be restricted to the given list of API paths. By default tokens are restricted to read-only querying APIs. "+
+		"The path can point either to local file or to http url. The file is re-read on SIGHUP signal. "+
+		"See https://docs.victoriametrics.com/#cors-and-api-tokens")
+)
+
+// defaultAllowedPaths contains read-only querying APIs, which are allowed for tokens without allowed_paths.
+var defaultAllowedPaths = []string{
+	"/api/v1/query",
+	"/api/v1/query_range",
+	"/api/v1/series",
+	"/api/v1/labels",
+	"/api/v1/label/[^/]+/values",
+	"/api/v1/metadata",
+	"/api/v1/query_exemplars",
+	"/api/v1/status/buildinfo",
+}
+
+// Token contains settings for a single token at -search.authTokensFile.
+type Token struct {
+	// Token is the bearer token value.
+	Token string `yaml:"token"`
+
+	// AllowedPaths is an optional list of regexps for API paths allowed for the token.
+	//
+	// defaultAllowedPaths are used if AllowedPaths is empty.
+	AllowedPaths []string `yaml:"allowed_paths,omitempty"`
+}
+
+// AuthTokensConfig represents the contents of -search.authTokensFile.
+type AuthTokensConfig struct {
+	Tokens []Token `yaml:"tokens"`
+}
+
+// authTokens contains parsed AuthTokensConfig.
+type authTokens struct {
+	// allowedPaths contains allowed path regexp per every token.
+	allowedPaths map[string]*regexp.Regexp
+}
+
+// newAuthTokens returns authTokens for the given cfg.
+func newAuthTokens(cfg *AuthTokensConfig) (*authTokens, error) {
+	ats := &authTokens{
+		allowedPaths: make(map[string]*regexp.Regexp, len(cfg.Tokens)),
+	}
+	for i, t := range cfg.Tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("missing token value at tokens[%d]", i)
+		}
+		if _, ok := ats.allowedPaths[t.Token]; ok {
+			return nil, fmt.Errorf("duplicate token value at tokens[%d]", i)
+		}
+		paths := t.AllowedPaths
+		if len(paths) == 0 {
+			paths = defaultAllowedPaths
+		}
+		expr := "^(?:" + strings.Join(paths, "|") + ")$"
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse allowed_paths at tokens[%d]: %w", i, err)
+		}
+		ats.allowedPaths[t.Token] = re
+	}
+	return ats, nil
+}
+
+// checkAccess returns non-nil error if the given token cannot access the given path.
+func (ats *authTokens) checkAccess(token, path string) error {
+	if token == "" {
+		authErrorsMissingToken.Inc()
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("missing bearer token in Authorization header; it is required by -search.authTokensFile"),
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+	re, ok := ats.allowedPaths[token]
+	if !ok {
+		authErrorsUnknownToken.Inc()
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("unknown bearer token in Authorization header; see -search.authTokensFile"),
+			StatusCode: http.StatusUnauthorized,
+		}
+	}
+	if !re.MatchString(path) {
+		authErrorsForbiddenPath.Inc()
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("the bearer token isn't allowed to access %q; see allowed_paths at -search.authTokensFile", path),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	return nil
+}
+
+var authTokensGlobal atomic.Pointer[authTokens]
+
+var (
+	authErrorsMissingToken  = metrics.NewCounter(`vm_search_auth_errors_total{reason="missing_token"}`)
+	authErrorsUnknownToken  = metrics.NewCounter(`vm_search_auth_errors_total{reason="unknown_token"}`)
+	authErrorsForbiddenPath = metrics.NewCounter(`vm_search_auth_errors_total{reason="forbidden_path"}`)
+
+	configReloads      = metrics.NewCounter(`vm_search_auth_tokens_config_reloads_total`)
+	configReloadErrors = metrics.NewCounter(`vm_search_auth_tokens_config_reloads_errors_total`)
+	configSuccess      = metrics.NewGauge(`vm_search_auth_tokens_config_last_reload_successful`, nil)
+	configTimestamp    = metrics.NewCounter(`vm_search_auth_tokens_config_last_reload_success_timestamp_seconds`)
+)
+
+// Init must be called after flag.Parse and before using the access package.
+func Init() {
+	if *authTokensFile == "" {
+		return
+	}
+
+	// Register SIGHUP handler for config re-read just before loadAuthTokens call.
+	// This guarantees that the config will be re-read if the signal arrives during loadAuthTokens call.
+	sighupCh := procutil.NewSighupChan()
+
+	ats, err := loadAuthTokens()
+	if err != nil {
+		logger.Fatalf("cannot load -search.authTokensFile: %s", err)
+	}
+	authTokensGlobal.Store(ats)
+	configSuccess.Set(1)
+	configTimestamp.Set(fasttime.UnixTimestamp())
+
+	go func() {
+		for range sighupCh {
+			configReloads.Inc()
+			logger.Infof("received SIGHUP; reloading -search.authTokensFile=%q...", *authTokensFile)
+			ats, err := loadAuthTokens()
+			if err != nil {
+				configReloadErrors.Inc()
+				configSuccess.Set(0)
+				logger.Errorf("cannot load the updated -search.authTokensFile: %s; preserving the previous config", err)
+				continue
+			}
+			authTokensGlobal.Store(ats)
+			configSuccess.Set(1)
+			configTimestamp.Set(fasttime.UnixTimestamp())
+			logger.Infof("successfully reloaded -search.authTokensFile=%q", *authTokensFile)
+		}
+	}()
+}
+
+func loadAuthTokens() (*authTokens, error) {
+	data, err := fscore.ReadFileOrHTTP(*authTokensFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", *authTokensFile, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at %q: %w", *authTokensFile, err)
+	}
+	ats, err := parseAuthTokens(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", *authTokensFile, err)
+	}
+	return ats, nil
+}
+
+func parseAuthTokens(data []byte) (*authTokens, error) {
+	var cfg AuthTokensConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	return newAuthTokens(&cfg)
+}
+
+// CheckAuthToken checks whether r contains bearer token from -search.authTokensFile, which is allowed to access the given path.
+//
+// It returns true if -search.authTokensFile isn't set.
+// Otherwise it returns false and sends the error response to w if the access is denied.
+func CheckAuthToken(w http.ResponseWriter, r *http.Request, path string) bool {
+	ats := authTokensGlobal.Load()
+	if ats == nil {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = ""
+	}
+	if err := ats.checkAccess(token, path); err != nil {
+		var esc *httpserver.ErrorWithStatusCode
+		if errors.As(err, &esc) && esc.StatusCode == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="VictoriaMetrics"`)
+		}
+		EnableCORS(w, r)
+		httpserver.Errorf(w, r, "%s", err)
+		return false
+	}
+	return true
+}
+
+// EnableCORS enables https://developer.mozilla.org/en-US/docs/Web/HTTP/CORS on the response
+// for origins allowed by -search.corsAllowedOrigins.
+func EnableCORS(w http.ResponseWriter, r *http.Request) {
+	if len(*corsAllowedOrigins) == 0 {
+		httpserver.EnableCORS(w, r)
+		return
+	}
+	origin := r.Header.Get("Origin")
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if origin == "" || !isAllowedOrigin(origin) {
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func isAllowedOrigin(origin string) bool {
+	for _, allowedOrigin := range *corsAllowedOrigins {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleCORSPreflight responds to CORS preflight request r.
+//
+// It returns false if r isn't a CORS preflight request.
+func HandleCORSPreflight(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodOptions || r.Header.Get("Origin") == "" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	EnableCORS(w, r)
+	h := w.Header()
+	h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	h.Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
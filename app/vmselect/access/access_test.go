@@ -0,0 +1,164 @@
+package access
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+)
+
+func TestParseAuthTokens_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := parseAuthTokens([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid yaml
+	f(`foo`)
+
+	// unknown field
+	f(`
+tokens:
+- token: foo
+  foo: bar
+`)
+
+	// missing token
+	f(`
+tokens:
+- allowed_paths: ["/api/v1/query"]
+`)
+
+	// duplicate token
+	f(`
+tokens:
+- token: foo
+- token: foo
+`)
+
+	// invalid regexp
+	f(`
+tokens:
+- token: foo
+  allowed_paths: ["/api/v1/query("]
+`)
+}
+
+func TestAuthTokensCheckAccess(t *testing.T) {
+	ats, err := parseAuthTokens([]byte(`
+tokens:
+- token: read-only
+- token: export
+  allowed_paths: ["/api/v1/export", "/api/v1/export/.+"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(token, path string, statusCodeExpected int) {
+		t.Helper()
+
+		err := ats.checkAccess(token, path)
+		if statusCodeExpected == http.StatusOK {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+		var esc *httpserver.ErrorWithStatusCode
+		if !errors.As(err, &esc) {
+			t.Fatalf("expecting ErrorWithStatusCode; got %v", err)
+		}
+		if esc.StatusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", esc.StatusCode, statusCodeExpected)
+		}
+	}
+
+	// missing or unknown token
+	f("", "/api/v1/query", http.StatusUnauthorized)
+	f("foo", "/api/v1/query", http.StatusUnauthorized)
+
+	// default read-only paths
+	f("read-only", "/api/v1/query", http.StatusOK)
+	f("read-only", "/api/v1/query_range", http.StatusOK)
+	f("read-only", "/api/v1/label/job/values", http.StatusOK)
+	f("read-only", "/api/v1/export", http.StatusForbidden)
+	f("read-only", "/api/v1/admin/tsdb/delete_series", http.StatusForbidden)
+	f("read-only", "/api/v1/query/foo", http.StatusForbidden)
+
+	// explicitly allowed paths
+	f("export", "/api/v1/export", http.StatusOK)
+	f("export", "/api/v1/export/csv", http.StatusOK)
+	f("export", "/api/v1/query", http.StatusForbidden)
+}
+
+func TestEnableCORS(t *testing.T) {
+	f := func(allowedOrigins []string, origin, allowOriginExpected string) {
+		t.Helper()
+
+		origOrigins := *corsAllowedOrigins
+		*corsAllowedOrigins = allowedOrigins
+		defer func() {
+			*corsAllowedOrigins = origOrigins
+		}()
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		EnableCORS(w, r)
+		if allowOrigin := w.Header().Get("Access-Control-Allow-Origin"); allowOrigin != allowOriginExpected {
+			t.Fatalf("unexpected Access-Control-Allow-Origin; got %q; want %q", allowOrigin, allowOriginExpected)
+		}
+	}
+
+	// any origin is allowed by default
+	f(nil, "https://foo.com", "*")
+	f(nil, "", "*")
+
+	// only the given origins are allowed
+	f([]string{"https://foo.com", "https://bar.com"}, "https://bar.com", "https://bar.com")
+	f([]string{"https://foo.com", "https://bar.com"}, "https://baz.com", "")
+	f([]string{"https://foo.com"}, "", "")
+	f([]string{"*"}, "https://baz.com", "https://baz.com")
+}
+
+func TestHandleCORSPreflight(t *testing.T) {
+	f := func(method string, headers map[string]string, handledExpected bool) {
+		t.Helper()
+
+		r := httptest.NewRequest(method, "/api/v1/query", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handled := HandleCORSPreflight(w, r)
+		if handled != handledExpected {
+			t.Fatalf("unexpected result; got %v; want %v", handled, handledExpected)
+		}
+		if !handled {
+			return
+		}
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status code; got %d; want %d", w.Code, http.StatusNoContent)
+		}
+		if h := w.Header().Get("Access-Control-Allow-Headers"); h != "Authorization, Content-Type" {
+			t.Fatalf("unexpected Access-Control-Allow-Headers: %q", h)
+		}
+	}
+
+	preflightHeaders := map[string]string{
+		"Origin":                        "https://foo.com",
+		"Access-Control-Request-Method": "GET",
+	}
+	f(http.MethodOptions, preflightHeaders, true)
+	f(http.MethodGet, preflightHeaders, false)
+	f(http.MethodOptions, map[string]string{"Origin": "https://foo.com"}, false)
+	f(http.MethodOptions, nil, false)
+}
@@ -8,8 +8,10 @@ import (
 	nethttputil "net/http/httputil"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/access"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/prometheus"
@@ -34,9 +36,9 @@ var (
 	metricNamesStatsResetAuthKey = flagutil.NewPassword("metricNamesStatsResetAuthKey", "authKey for resetting metric names usage cache via /api/v1/admin/status/metric_names_stats/reset. It overrides -httpAuth.*. "+
 		"See https://docs.victoriametrics.com/#track-ingested-metrics-usage")
 
-	maxConcurrentRequests = flag.Int("search.maxConcurrentRequests", getDefaultMaxConcurrentRequests(), "The maximum number of concurrent search requests. "+
+	maxConcurrentRequests = flagutil.NewTunableInt("search.maxConcurrentRequests", getDefaultMaxConcurrentRequests(), "The maximum number of concurrent search requests. "+
 		"It shouldn't be high, since a single request can saturate all the CPU cores, while many concurrently executed requests may require high amounts of memory. "+
-		"See also -search.maxQueueDuration and -search.maxMemoryPerQuery")
+		"The limit can be changed at runtime via /-/flags endpoint. See also -search.maxQueueDuration and -search.maxMemoryPerQuery", flagutil.ValidatePositiveInt)
 	maxQueueDuration = flag.Duration("search.maxQueueDuration", 10*time.Second, "The maximum time the request waits for execution when -search.maxConcurrentRequests "+
 		"limit is reached; see also -search.maxQueryDuration")
 	resetCacheAuthKey    = flagutil.NewPassword("search.resetCacheAuthKey", "Optional authKey for resetting rollup cache via /internal/resetRollupResultCache call. It could be passed via authKey query arg. It overrides -httpAuth.*")
@@ -64,10 +66,14 @@ func Init() {
 	fs.RemoveDirContents(tmpDirPath)
 	netstorage.InitTmpBlocksDir(tmpDirPath)
 	promql.InitRollupResultCache(*vmstorage.DataPath + "/cache/rollupResult")
-	prometheus.InitMaxUniqueTimeseries(*maxConcurrentRequests)
+	prometheus.InitMaxUniqueTimeseries(maxConcurrentRequests.Get())
 
-	concurrencyLimitCh = make(chan struct{}, *maxConcurrentRequests)
+	setConcurrencyLimit(maxConcurrentRequests.Get())
+	maxConcurrentRequests.OnChange(func() {
+		setConcurrencyLimit(maxConcurrentRequests.Get())
+	})
 	initVMAlertProxy()
+	access.Init()
 }
 
 // Stop stops vmselect
@@ -75,17 +81,32 @@ func Stop() {
 	promql.StopRollupResultCache()
 }
 
-var concurrencyLimitCh chan struct{}
+// concurrencyLimitCh limits the number of concurrently executed requests.
+//
+// It is replaced with a new channel when -search.maxConcurrentRequests is changed at runtime.
+// Requests, which are already executed, release the channel they were admitted by.
+var concurrencyLimitCh atomic.Pointer[chan struct{}]
+
+func setConcurrencyLimit(n int) {
+	ch := make(chan struct{}, n)
+	concurrencyLimitCh.Store(&ch)
+}
 
 var (
 	concurrencyLimitReached = metrics.NewCounter(`vm_concurrent_select_limit_reached_total`)
 	concurrencyLimitTimeout = metrics.NewCounter(`vm_concurrent_select_limit_timeout_total`)
 
 	_ = metrics.NewGauge(`vm_concurrent_select_capacity`, func() float64 {
-		return float64(cap(concurrencyLimitCh))
+		if ch := concurrencyLimitCh.Load(); ch != nil {
+			return float64(cap(*ch))
+		}
+		return 0
 	})
 	_ = metrics.NewGauge(`vm_concurrent_select_current`, func() float64 {
-		return float64(len(concurrencyLimitCh))
+		if ch := concurrencyLimitCh.Load(); ch != nil {
+			return float64(len(*ch))
+		}
+		return 0
 	})
 	_ = metrics.NewGauge(`vm_search_max_unique_timeseries`, func() float64 {
 		return float64(prometheus.GetMaxUniqueTimeSeries())
@@ -111,6 +132,13 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		path = path[len("/graphite"):]
 	}
 
+	if access.HandleCORSPreflight(w, r) {
+		return true
+	}
+	if !isVMUIPath(path) && !isStoragePath(path) && !access.CheckAuthToken(w, r, path) {
+		return true
+	}
+
 	if handleStaticAndSimpleRequests(w, r, path) {
 		return true
 	}
@@ -122,9 +150,10 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	qt := querytracer.New(tracerEnabled, "%s", r.URL.Path)
 
 	// Limit the number of concurrent queries.
+	concurrencyCh := *concurrencyLimitCh.Load()
 	select {
-	case concurrencyLimitCh <- struct{}{}:
-		defer func() { <-concurrencyLimitCh }()
+	case concurrencyCh <- struct{}{}:
+		defer func() { <-concurrencyCh }()
 	default:
 		// Sleep for a while until giving up. This should resolve short bursts in requests.
 		concurrencyLimitReached.Inc()
@@ -134,10 +163,10 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		t := timerpool.Get(d)
 		select {
-		case concurrencyLimitCh <- struct{}{}:
+		case concurrencyCh <- struct{}{}:
 			timerpool.Put(t)
-			qt.Printf("wait in queue because -search.maxConcurrentRequests=%d concurrent requests are executed", *maxConcurrentRequests)
-			defer func() { <-concurrencyLimitCh }()
+			qt.Printf("wait in queue because -search.maxConcurrentRequests=%d concurrent requests are executed", cap(concurrencyCh))
+			defer func() { <-concurrencyCh }()
 		case <-r.Context().Done():
 			timerpool.Put(t)
 			remoteAddr := httpserver.GetQuotedRemoteAddr(r)
@@ -152,7 +181,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 				Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
 					"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
 					"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests",
-					d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
+					d.Seconds(), cap(concurrencyCh), maxQueueDuration),
 				StatusCode: http.StatusTooManyRequests,
 			}
 			w.Header().Add("Retry-After", "10")
@@ -187,7 +216,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		if strings.HasSuffix(s, "/values") {
 			labelValuesRequests.Inc()
 			labelName := s[:len(s)-len("/values")]
-			httpserver.EnableCORS(w, r)
+			access.EnableCORS(w, r)
 			if err := prometheus.LabelValuesHandler(qt, startTime, labelName, w, r); err != nil {
 				labelValuesErrors.Inc()
 				httpserver.SendPrometheusError(w, r, err)
@@ -210,7 +239,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	switch path {
 	case "/api/v1/query":
 		queryRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.QueryHandler(qt, startTime, w, r); err != nil {
 			queryErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
@@ -219,16 +248,43 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/api/v1/query_range":
 		queryRangeRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.QueryRangeHandler(qt, startTime, w, r); err != nil {
 			queryRangeErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
 			return true
 		}
 		return true
+	case "/api/v1/query_range_diff":
+		queryRangeDiffRequests.Inc()
+		access.EnableCORS(w, r)
+		if err := prometheus.QueryRangeDiffHandler(qt, startTime, w, r); err != nil {
+			queryRangeDiffErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/sql":
+		sqlQueryRequests.Inc()
+		access.EnableCORS(w, r)
+		if err := prometheus.SQLQueryHandler(qt, startTime, w, r); err != nil {
+			sqlQueryErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/graphql":
+		graphqlRequests.Inc()
+		access.EnableCORS(w, r)
+		if err := prometheus.GraphQLHandler(qt, startTime, w, r); err != nil {
+			graphqlErrors.Inc()
+			prometheus.SendGraphQLError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/series":
 		seriesRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.SeriesHandler(qt, startTime, w, r); err != nil {
 			seriesErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
@@ -237,7 +293,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/api/v1/series/count":
 		seriesCountRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.SeriesCountHandler(startTime, w, r); err != nil {
 			seriesCountErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
@@ -246,22 +302,40 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/api/v1/labels":
 		labelsRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.LabelsHandler(qt, startTime, w, r); err != nil {
 			labelsErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
 			return true
 		}
 		return true
+	case "/api/v1/metadata":
+		metadataRequests.Inc()
+		access.EnableCORS(w, r)
+		if err := prometheus.MetadataHandler(qt, startTime, w, r); err != nil {
+			metadataErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/status/tsdb":
 		statusTSDBRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.TSDBStatusHandler(qt, startTime, w, r); err != nil {
 			statusTSDBErrors.Inc()
 			httpserver.SendPrometheusError(w, r, err)
 			return true
 		}
 		return true
+	case "/api/v1/status/series_churn":
+		statusSeriesChurnRequests.Inc()
+		access.EnableCORS(w, r)
+		if err := prometheus.SeriesChurnHandler(qt, startTime, w, r); err != nil {
+			statusSeriesChurnErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/export":
 		exportRequests.Inc()
 		if err := prometheus.ExportHandler(startTime, w, r); err != nil {
@@ -296,7 +370,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/metrics/find", "/metrics/find/":
 		graphiteMetricsFindRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := graphite.MetricsFindHandler(startTime, w, r); err != nil {
 			graphiteMetricsFindErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
@@ -305,7 +379,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/metrics/expand", "/metrics/expand/":
 		graphiteMetricsExpandRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := graphite.MetricsExpandHandler(startTime, w, r); err != nil {
 			graphiteMetricsExpandErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
@@ -314,7 +388,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/metrics/index.json", "/metrics/index.json/":
 		graphiteMetricsIndexRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := graphite.MetricsIndexHandler(startTime, w, r); err != nil {
 			graphiteMetricsIndexErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
@@ -355,7 +429,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/tags/autoComplete/tags":
 		graphiteTagsAutoCompleteTagsRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := graphite.TagsAutoCompleteTagsHandler(startTime, w, r); err != nil {
 			graphiteTagsAutoCompleteTagsErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
@@ -364,7 +438,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/tags/autoComplete/values":
 		graphiteTagsAutoCompleteValuesRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := graphite.TagsAutoCompleteValuesHandler(startTime, w, r); err != nil {
 			graphiteTagsAutoCompleteValuesErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
@@ -410,6 +484,14 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/status/unused_metrics":
+		unusedMetricsRequests.Inc()
+		if err := stats.UnusedMetricsHandler(qt, w, r); err != nil {
+			unusedMetricsErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
 	case "/api/v1/admin/status/metric_names_stats/reset":
 		metricNamesStatsResetRequests.Inc()
 		if !httpserver.CheckAuthFlag(w, r, metricNamesStatsResetAuthKey) {
@@ -427,6 +509,23 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	}
 }
 
+// isVMUIPath returns true if the given path points to vmui static contents.
+func isVMUIPath(path string) bool {
+	return path == "/vmui" || path == "/graph" || strings.HasPrefix(path, "/vmui/") || strings.HasPrefix(path, "/graph/")
+}
+
+// isStoragePath returns true if the given path is handled by vmstorage.RequestHandler.
+//
+// Such paths are protected by their own authKeys, so they aren't checked against -search.authTokensFile.
+func isStoragePath(path string) bool {
+	switch path {
+	case "/internal/force_merge", "/internal/force_flush", "/api/v1/admin/tsdb/snapshot":
+		return true
+	default:
+		return strings.HasPrefix(path, "/snapshot/")
+	}
+}
+
 func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path string) bool {
 	// vmui access.
 	if path == "/vmui" || path == "/graph" {
@@ -450,8 +549,25 @@ func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path
 		}
 		return true
 	}
+	if path == "/vmui/saved-dashboards" {
+		if !httpserver.CheckAuthFlag(w, r, vmuiSavedDashboardsAuthKey) {
+			return true
+		}
+		if err := handleVMUISavedDashboards(w, r); err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
+	}
+	if path == "/vmui/custom-dashboards/grafana" {
+		if err := handleVMUIGrafanaDashboard(w, r); err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
+	}
 	if path == "/vmui/timezone" {
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := handleVMUITimezone(w); err != nil {
 			httpserver.Errorf(w, r, "%s", err)
 			return true
@@ -513,12 +629,12 @@ func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path
 	switch path {
 	case "/api/v1/status/active_queries":
 		statusActiveQueriesRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		promql.ActiveQueriesHandler(w, r)
 		return true
 	case "/api/v1/status/top_queries":
 		topQueriesRequests.Inc()
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		if err := prometheus.QueryStatsHandler(w, r); err != nil {
 			topQueriesErrors.Inc()
 			httpserver.SendPrometheusError(w, r, fmt.Errorf("cannot query status endpoint: %w", err))
@@ -561,12 +677,6 @@ func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path
 		w.Header().Set("Content-Type", "application/json")
 		fmt.Fprint(w, `{"status":"success","data":{"alerts":[]}}`)
 		return true
-	case "/api/v1/metadata":
-		// Return dumb placeholder for https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
-		metadataRequests.Inc()
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s", `{"status":"success","data":{}}`)
-		return true
 	case "/api/v1/status/buildinfo":
 		buildInfoRequests.Inc()
 		w.Header().Set("Content-Type", "application/json")
@@ -610,6 +720,15 @@ var (
 	queryRangeRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query_range"}`)
 	queryRangeErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/query_range"}`)
 
+	queryRangeDiffRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query_range_diff"}`)
+	queryRangeDiffErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/query_range_diff"}`)
+
+	sqlQueryRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/sql"}`)
+	sqlQueryErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/sql"}`)
+
+	graphqlRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/graphql"}`)
+	graphqlErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/graphql"}`)
+
 	seriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series"}`)
 	seriesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series"}`)
 
@@ -622,6 +741,9 @@ var (
 	statusTSDBRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/tsdb"}`)
 	statusTSDBErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/tsdb"}`)
 
+	statusSeriesChurnRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/series_churn"}`)
+	statusSeriesChurnErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/series_churn"}`)
+
 	statusActiveQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/active_queries"}`)
 
 	topQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/top_queries"}`)
@@ -694,13 +816,18 @@ var (
 	rulesRequests   = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/rules"}`)
 	alertsRequests  = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/alerts"}`)
 
-	metadataRequests       = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/metadata"}`)
+	metadataRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/metadata"}`)
+	metadataErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/metadata"}`)
+
 	buildInfoRequests      = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/buildinfo"}`)
 	queryExemplarsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query_exemplars"}`)
 
 	metricNamesStatsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/metric_names_stats"}`)
 	metricNamesStatsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/metric_names_stats"}`)
 
+	unusedMetricsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/unused_metrics"}`)
+	unusedMetricsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/unused_metrics"}`)
+
 	metricNamesStatsResetRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/status/metric_names_stats/reset"}`)
 	metricNamesStatsResetErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/status/metric_names_stats/reset"}`)
 )
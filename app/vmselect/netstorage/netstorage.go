@@ -47,17 +47,25 @@ type Result struct {
 	// Values are sorted by Timestamps.
 	Values     []float64
 	Timestamps []int64
+
+	// BlockSummaries contains summaries for blocks, which weren't unpacked to Values and Timestamps.
+	//
+	// BlockSummaries are sorted by MinTimestamp.
+	// It is filled only by Results.RunParallelWithBlockSummaries.
+	BlockSummaries []storage.BlockSummary
 }
 
 func (r *Result) reset() {
 	r.MetricName.Reset()
 	r.Values = r.Values[:0]
 	r.Timestamps = r.Timestamps[:0]
+	r.BlockSummaries = r.BlockSummaries[:0]
 }
 
 // Results holds results returned from ProcessSearchQuery.
 type Results struct {
 	tr       storage.TimeRange
+	ioClass  storage.IOClass
 	deadline searchutil.Deadline
 
 	packedTimeseries []packedTimeseries
@@ -83,11 +91,12 @@ func (rss *Results) mustClose() {
 }
 
 type timeseriesWork struct {
-	mustStop *atomic.Bool
-	rss      *Results
-	pts      *packedTimeseries
-	f        func(rs *Result, workerID uint) error
-	err      error
+	mustStop           *atomic.Bool
+	rss                *Results
+	pts                *packedTimeseries
+	canUseBlockSummary func(bs *storage.BlockSummary) bool
+	f                  func(rs *Result, workerID uint) error
+	err                error
 
 	rowsProcessed int
 }
@@ -101,12 +110,12 @@ func (tsw *timeseriesWork) do(r *Result, workerID uint) error {
 		tsw.mustStop.Store(true)
 		return fmt.Errorf("timeout exceeded during query execution: %s", rss.deadline.String())
 	}
-	if err := tsw.pts.Unpack(r, rss.tbf, rss.tr); err != nil {
+	if err := tsw.pts.unpackWithBlockSummaries(r, rss.tbf, rss.tr, rss.ioClass, tsw.canUseBlockSummary); err != nil {
 		tsw.mustStop.Store(true)
 		return fmt.Errorf("error during time series unpacking: %w", err)
 	}
 	tsw.rowsProcessed = len(r.Timestamps)
-	if len(r.Timestamps) > 0 {
+	if len(r.Timestamps) > 0 || len(r.BlockSummaries) > 0 {
 		if err := tsw.f(r, workerID); err != nil {
 			tsw.mustStop.Store(true)
 			return err
@@ -218,10 +227,24 @@ var defaultMaxWorkersPerQuery = func() int {
 //
 // rss becomes unusable after the call to RunParallel.
 func (rss *Results) RunParallel(qt *querytracer.Tracer, f func(rs *Result, workerID uint) error) error {
+	return rss.RunParallelWithBlockSummaries(qt, nil, f)
+}
+
+// RunParallelWithBlockSummaries is like RunParallel, but it doesn't unpack blocks if canUseBlockSummary returns true for their summaries.
+//
+// Summaries for such blocks are passed to f via rs.BlockSummaries instead of unpacking the blocks to rs.Values and rs.Timestamps.
+// canUseBlockSummary is called only for blocks with available summaries, which are fully located inside the selected time range.
+// Summaries aren't used if deduplication is enabled, since the deduplication needs raw samples.
+//
+// canUseBlockSummary may be called concurrently from multiple goroutines. It may be nil.
+//
+// rss becomes unusable after the call to RunParallelWithBlockSummaries.
+func (rss *Results) RunParallelWithBlockSummaries(qt *querytracer.Tracer, canUseBlockSummary func(bs *storage.BlockSummary) bool,
+	f func(rs *Result, workerID uint) error) error {
 	qt = qt.NewChild("parallel process of fetched data")
 	defer rss.mustClose()
 
-	rowsProcessedTotal, err := rss.runParallel(qt, f)
+	rowsProcessedTotal, err := rss.runParallel(qt, canUseBlockSummary, f)
 	seriesProcessedTotal := len(rss.packedTimeseries)
 	rss.packedTimeseries = rss.packedTimeseries[:0]
 
@@ -233,7 +256,7 @@ func (rss *Results) RunParallel(qt *querytracer.Tracer, f func(rs *Result, worke
 	return err
 }
 
-func (rss *Results) runParallel(qt *querytracer.Tracer, f func(rs *Result, workerID uint) error) (int, error) {
+func (rss *Results) runParallel(qt *querytracer.Tracer, canUseBlockSummary func(bs *storage.BlockSummary) bool, f func(rs *Result, workerID uint) error) (int, error) {
 	tswsLen := len(rss.packedTimeseries)
 	if tswsLen == 0 {
 		// Nothing to process
@@ -244,6 +267,7 @@ func (rss *Results) runParallel(qt *querytracer.Tracer, f func(rs *Result, worke
 	initTimeseriesWork := func(tsw *timeseriesWork, pts *packedTimeseries) {
 		tsw.rss = rss
 		tsw.pts = pts
+		tsw.canUseBlockSummary = canUseBlockSummary
 		tsw.f = f
 		tsw.mustStop = &mustStop
 	}
@@ -339,24 +363,26 @@ type packedTimeseries struct {
 }
 
 type unpackWork struct {
-	tbf *tmpBlocksFile
-	br  blockRef
-	tr  storage.TimeRange
-	sb  *sortBlock
-	err error
+	tbf     *tmpBlocksFile
+	br      blockRef
+	tr      storage.TimeRange
+	ioClass storage.IOClass
+	sb      *sortBlock
+	err     error
 }
 
 func (upw *unpackWork) reset() {
 	upw.tbf = nil
 	upw.br = blockRef{}
 	upw.tr = storage.TimeRange{}
+	upw.ioClass = storage.IOClassInteractive
 	upw.sb = nil
 	upw.err = nil
 }
 
 func (upw *unpackWork) unpack(tmpBlock *storage.Block) {
 	sb := getSortBlock()
-	if err := sb.unpackFrom(tmpBlock, upw.tbf, upw.br, upw.tr); err != nil {
+	if err := sb.unpackFrom(tmpBlock, upw.tbf, upw.br, upw.tr, upw.ioClass); err != nil {
 		putSortBlock(sb)
 		upw.err = fmt.Errorf("cannot unpack block: %w", err)
 		return
@@ -426,26 +452,65 @@ func putTmpStorageBlock(sb *storage.Block) {
 var tmpStorageBlockPool sync.Pool
 
 // Unpack unpacks pts to dst.
-func (pts *packedTimeseries) Unpack(dst *Result, tbf *tmpBlocksFile, tr storage.TimeRange) error {
+func (pts *packedTimeseries) Unpack(dst *Result, tbf *tmpBlocksFile, tr storage.TimeRange, ioClass storage.IOClass) error {
+	return pts.unpackWithBlockSummaries(dst, tbf, tr, ioClass, nil)
+}
+
+// unpackWithBlockSummaries unpacks pts to dst.
+//
+// Blocks with summaries, for which canUseBlockSummary returns true, aren't unpacked. Their summaries are put to dst.BlockSummaries instead.
+func (pts *packedTimeseries) unpackWithBlockSummaries(dst *Result, tbf *tmpBlocksFile, tr storage.TimeRange, ioClass storage.IOClass,
+	canUseBlockSummary func(bs *storage.BlockSummary) bool) error {
 	dst.reset()
 	if err := dst.MetricName.Unmarshal(bytesutil.ToUnsafeBytes(pts.metricName)); err != nil {
 		return fmt.Errorf("cannot unmarshal metricName %q: %w", pts.metricName, err)
 	}
+	dedupInterval := storage.GetDedupInterval()
+	if canUseBlockSummary != nil && dedupInterval <= 0 {
+		dst.BlockSummaries = pts.extractBlockSummaries(dst.BlockSummaries[:0], tbf, tr, canUseBlockSummary)
+	}
 	sbh := getSortBlocksHeap()
 	var err error
-	sbh.sbs, err = pts.unpackTo(sbh.sbs[:0], tbf, tr)
+	sbh.sbs, err = pts.unpackTo(sbh.sbs[:0], tbf, tr, ioClass)
 	pts.brs = pts.brs[:0]
 	if err != nil {
 		putSortBlocksHeap(sbh)
 		return err
 	}
-	dedupInterval := storage.GetDedupInterval()
 	mergeSortBlocks(dst, sbh, dedupInterval)
 	putSortBlocksHeap(sbh)
 	return nil
 }
 
-func (pts *packedTimeseries) unpackTo(dst []*sortBlock, tbf *tmpBlocksFile, tr storage.TimeRange) ([]*sortBlock, error) {
+// extractBlockSummaries appends summaries for pts blocks, which can be used instead of unpacking the blocks, to dst and returns the result.
+//
+// Blocks for the appended summaries are removed from pts.brs.
+func (pts *packedTimeseries) extractBlockSummaries(dst []storage.BlockSummary, tbf *tmpBlocksFile, tr storage.TimeRange,
+	canUseBlockSummary func(bs *storage.BlockSummary) bool) []storage.BlockSummary {
+	dstLen := len(dst)
+	brs := pts.brs[:0]
+	for _, br := range pts.brs {
+		brReal := tbf.MustReadBlockRefAt(br.partRef, br.addr)
+		bs, ok := brReal.Summary()
+		if !ok || bs.MinTimestamp < tr.MinTimestamp || bs.MaxTimestamp > tr.MaxTimestamp || !canUseBlockSummary(&bs) {
+			brs = append(brs, br)
+			continue
+		}
+		dst = append(dst, bs)
+	}
+	pts.brs = brs
+
+	summaries := dst[dstLen:]
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].MinTimestamp < summaries[j].MinTimestamp
+	})
+	blockSummariesUsed.Add(len(summaries))
+	return dst
+}
+
+var blockSummariesUsed = metrics.NewCounter(`vm_block_summaries_used_total`)
+
+func (pts *packedTimeseries) unpackTo(dst []*sortBlock, tbf *tmpBlocksFile, tr storage.TimeRange, ioClass storage.IOClass) ([]*sortBlock, error) {
 	upwsLen := len(pts.brs)
 	if upwsLen == 0 {
 		// Nothing to do
@@ -455,6 +520,7 @@ func (pts *packedTimeseries) unpackTo(dst []*sortBlock, tbf *tmpBlocksFile, tr s
 		upw.tbf = tbf
 		upw.br = br
 		upw.tr = tr
+		upw.ioClass = ioClass
 	}
 	if gomaxprocs == 1 || upwsLen <= 1000 {
 		// It is faster to unpack all the data in the current goroutine.
@@ -684,10 +750,10 @@ func (sb *sortBlock) reset() {
 	sb.NextIdx = 0
 }
 
-func (sb *sortBlock) unpackFrom(tmpBlock *storage.Block, tbf *tmpBlocksFile, br blockRef, tr storage.TimeRange) error {
+func (sb *sortBlock) unpackFrom(tmpBlock *storage.Block, tbf *tmpBlocksFile, br blockRef, tr storage.TimeRange, ioClass storage.IOClass) error {
 	tmpBlock.Reset()
 	brReal := tbf.MustReadBlockRefAt(br.partRef, br.addr)
-	brReal.MustReadBlock(tmpBlock)
+	brReal.MustReadBlockWithIOClass(tmpBlock, ioClass)
 	if err := tmpBlock.UnmarshalData(); err != nil {
 		return fmt.Errorf("cannot unmarshal block: %w", err)
 	}
@@ -959,6 +1025,18 @@ func SeriesCount(qt *querytracer.Tracer, deadline searchutil.Deadline) (uint64,
 	return n, nil
 }
 
+// MetricMetadata returns metric metadata for the given metricFamilyName grouped by metric family names.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+func MetricMetadata(qt *querytracer.Tracer, metricFamilyName string, limit, limitPerMetric int, deadline searchutil.Deadline) (map[string][]storage.MetricMetadata, error) {
+	qt = qt.NewChild("get metric metadata")
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting the query processing: %s", deadline.String())
+	}
+	return vmstorage.SearchMetricMetadata(qt, metricFamilyName, limit, limitPerMetric), nil
+}
+
 func getStorageSearch() *storage.Search {
 	v := ssPool.Get()
 	if v == nil {
@@ -1046,7 +1124,7 @@ func ExportBlocks(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline sear
 			return fmt.Errorf("cannot unmarshal metricName for block #%d: %w", blocksRead, err)
 		}
 		br := sr.MetricBlockRef.BlockRef
-		br.MustReadBlock(&xw.b)
+		br.MustReadBlockWithIOClass(&xw.b, sq.IOClass)
 		samples += br.RowsCount()
 		workCh <- xw
 	}
@@ -1115,6 +1193,19 @@ func SearchMetricNames(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline
 	return metricNames, nil
 }
 
+// GetIndexSearchPath returns the name of the index, which is used for searching series on the given tr.
+//
+// An empty string is returned if the storage isn't opened yet.
+func GetIndexSearchPath(tr storage.TimeRange) string {
+	if vmstorage.Storage == nil {
+		return ""
+	}
+	if vmstorage.Storage.IsGlobalIndexSearch(tr) {
+		return "global"
+	}
+	return "per-day"
+}
+
 // ProcessSearchQuery performs sq until the given deadline.
 //
 // Results.RunParallel or Results.Cancel must be called on the returned Results.
@@ -1277,6 +1368,7 @@ func ProcessSearchQuery(qt *querytracer.Tracer, sq *storage.SearchQuery, deadlin
 
 	var rss Results
 	rss.tr = tr
+	rss.ioClass = sq.IOClass
 	rss.deadline = deadline
 	pts := make([]packedTimeseries, len(orderedMetricNames))
 	for i, metricName := range orderedMetricNames {
@@ -1375,6 +1467,13 @@ func GetMetricNamesStats(qt *querytracer.Tracer, limit, le int, matchPattern str
 	return vmstorage.GetMetricNamesStats(qt, limit, le, matchPattern)
 }
 
+// GetUnusedMetricNamesStats returns statistic for timeseries metric names, which weren't queried since the given unusedSince unix timestamp in seconds.
+func GetUnusedMetricNamesStats(qt *querytracer.Tracer, limit int, unusedSince uint64, matchPattern string) (storage.MetricNamesStatsResponse, error) {
+	qt = qt.NewChild("get unused metric names statistics with limit: %d, unused since: %d, match pattern=%q", limit, unusedSince, matchPattern)
+	defer qt.Done()
+	return vmstorage.GetUnusedMetricNamesStats(qt, limit, unusedSince, matchPattern)
+}
+
 // ResetMetricNamesStats resets state of metric names usage
 func ResetMetricNamesStats(qt *querytracer.Tracer) error {
 	qt = qt.NewChild("reset metric names usage stats")
@@ -0,0 +1,155 @@
+package prometheus
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// defaultExportChunkDuration is the default duration of time chunks for resumable export.
+const defaultExportChunkDuration = 24 * time.Hour
+
+// exportContinuationTokenHeader is the name of the response header with the continuation token for the next time chunk to export.
+//
+// The token is sent in the header instead of the response body, so the exported data remains compatible with /api/v1/import.
+const exportContinuationTokenHeader = "VM-Export-Continuation-Token"
+
+// exportContinuationToken is the state of resumable export, which is sent to the client in exportContinuationTokenHeader.
+//
+// The client must pass the received token in `continuation_token` query arg in order to export the next time chunk.
+type exportContinuationToken struct {
+	// Start is the start timestamp in milliseconds for the next time chunk to export.
+	Start int64 `json:"start"`
+
+	// End is the end timestamp in milliseconds for the export.
+	//
+	// It is stored in the token, so the resumed export doesn't depend on the current time if the client didn't pass `end` query arg.
+	End int64 `json:"end"`
+
+	// ChunkDuration is the duration of time chunks in milliseconds.
+	ChunkDuration int64 `json:"chunk_duration"`
+
+	// FiltersHash is the hash of series filters for the export.
+	//
+	// It is used for detecting attempts to resume the export with another series filters.
+	FiltersHash uint64 `json:"filters_hash"`
+}
+
+func (ct *exportContinuationToken) marshal() string {
+	data, err := json.Marshal(ct)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal export continuation token: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (ct *exportContinuationToken) unmarshal(s string) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("cannot decode continuation token: %w", err)
+	}
+	if err := json.Unmarshal(data, ct); err != nil {
+		return fmt.Errorf("cannot parse continuation token: %w", err)
+	}
+	if ct.ChunkDuration <= 0 {
+		return fmt.Errorf("invalid chunk_duration in continuation token: %d; it must be positive", ct.ChunkDuration)
+	}
+	if ct.Start > ct.End+1 {
+		return fmt.Errorf("invalid time range in continuation token: start=%d cannot exceed end=%d", ct.Start, ct.End)
+	}
+	return nil
+}
+
+func getFiltersHash(filterss [][]storage.TagFilter) uint64 {
+	var b []byte
+	for _, tfs := range filterss {
+		for i := range tfs {
+			b = append(b, tfs[i].String()...)
+			b = append(b, ',')
+		}
+		b = append(b, '|')
+	}
+	return xxhash.Sum64(b)
+}
+
+// isResumableExport returns true if r requests resumable export.
+func isResumableExport(r *http.Request) bool {
+	return httputil.GetBool(r, "resumable") || r.FormValue("continuation_token") != ""
+}
+
+// exportResumableHandler exports a single time chunk for cp and sends continuation token for the next time chunk in exportContinuationTokenHeader.
+//
+// Every time chunk is exported in a separate request, so every chunk has its own deadline.
+//
+// See https://docs.victoriametrics.com/#resumable-export
+func exportResumableHandler(w http.ResponseWriter, r *http.Request, cp *commonParams, format string, maxRowsPerLine int, reduceMemUsage bool) error {
+	if format == "promapi" {
+		return fmt.Errorf("resumable export isn't supported for format=promapi")
+	}
+	filtersHash := getFiltersHash(cp.filterss)
+
+	var ct exportContinuationToken
+	if s := r.FormValue("continuation_token"); s != "" {
+		if err := ct.unmarshal(s); err != nil {
+			return err
+		}
+		if ct.FiltersHash != filtersHash {
+			return fmt.Errorf("continuation token was issued for another `match[]`, `extra_label` or `extra_filters[]` args")
+		}
+	} else {
+		if cp.start <= 0 {
+			return fmt.Errorf("missing `start` arg; it must be set for resumable export")
+		}
+		chunkDuration, err := httputil.GetDuration(r, "chunk_duration", defaultExportChunkDuration.Milliseconds())
+		if err != nil {
+			return err
+		}
+		if chunkDuration <= 0 {
+			return fmt.Errorf("`chunk_duration` must be positive; got %dms", chunkDuration)
+		}
+		ct = exportContinuationToken{
+			Start:         cp.start,
+			End:           cp.end,
+			ChunkDuration: chunkDuration,
+			FiltersHash:   filtersHash,
+		}
+	}
+	if ct.Start > ct.End {
+		// Nothing to export.
+		return nil
+	}
+
+	chunkStart, chunkEnd, ctNext := ct.nextChunk()
+	if ctNext != nil {
+		// The header must be set before exporting the data, since headers cannot be sent after the response body.
+		w.Header().Set(exportContinuationTokenHeader, ctNext.marshal())
+	}
+	cpChunk := *cp
+	cpChunk.start = chunkStart
+	cpChunk.end = chunkEnd
+	if err := exportHandler(nil, w, &cpChunk, format, maxRowsPerLine, reduceMemUsage); err != nil {
+		return fmt.Errorf("error when exporting data on the time range (start=%d, end=%d): %w", cpChunk.start, cpChunk.end, err)
+	}
+	return nil
+}
+
+// nextChunk returns the time range for the time chunk to export for ct and the continuation token for the next time chunk.
+//
+// nil token is returned if the returned time chunk is the last one.
+func (ct *exportContinuationToken) nextChunk() (int64, int64, *exportContinuationToken) {
+	chunkEnd := ct.Start + ct.ChunkDuration - 1
+	if chunkEnd >= ct.End {
+		return ct.Start, ct.End, nil
+	}
+	ctNext := *ct
+	ctNext.Start = chunkEnd + 1
+	return ct.Start, chunkEnd, &ctNext
+}
@@ -0,0 +1,132 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestExportContinuationTokenMarshalUnmarshal(t *testing.T) {
+	f := func(ct *exportContinuationToken) {
+		t.Helper()
+
+		s := ct.marshal()
+		var ct2 exportContinuationToken
+		if err := ct2.unmarshal(s); err != nil {
+			t.Fatalf("cannot unmarshal token %q: %s", s, err)
+		}
+		if ct2 != *ct {
+			t.Fatalf("unexpected token after unmarshal\ngot\n%+v\nwant\n%+v", ct2, *ct)
+		}
+	}
+
+	f(&exportContinuationToken{
+		Start:         1,
+		End:           1,
+		ChunkDuration: 1,
+	})
+	f(&exportContinuationToken{
+		Start:         1654543486000,
+		End:           1654629886000,
+		ChunkDuration: 3600000,
+		FiltersHash:   0xfedcba9876543210,
+	})
+
+	// The start may exceed the end by 1ms after the last chunk
+	f(&exportContinuationToken{
+		Start:         101,
+		End:           100,
+		ChunkDuration: 10,
+	})
+}
+
+func TestExportContinuationTokenUnmarshalFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		var ct exportContinuationToken
+		if err := ct.unmarshal(s); err == nil {
+			t.Fatalf("expecting non-nil error when unmarshaling %q", s)
+		}
+	}
+
+	// invalid base64
+	f("foo bar")
+
+	// invalid json
+	f("Zm9vYmFy")
+
+	// zero chunk_duration
+	f((&exportContinuationToken{Start: 1, End: 2}).marshal())
+
+	// start exceeds end
+	f((&exportContinuationToken{Start: 10, End: 2, ChunkDuration: 1}).marshal())
+}
+
+func TestExportContinuationTokenNextChunk(t *testing.T) {
+	f := func(ct *exportContinuationToken, chunkStartExpected, chunkEndExpected int64, ctNextExpected *exportContinuationToken) {
+		t.Helper()
+
+		chunkStart, chunkEnd, ctNext := ct.nextChunk()
+		if chunkStart != chunkStartExpected || chunkEnd != chunkEndExpected {
+			t.Fatalf("unexpected chunk; got [%d ... %d]; want [%d ... %d]", chunkStart, chunkEnd, chunkStartExpected, chunkEndExpected)
+		}
+		if ctNextExpected == nil {
+			if ctNext != nil {
+				t.Fatalf("unexpected token for the next chunk: %+v; want nil", *ctNext)
+			}
+			return
+		}
+		if ctNext == nil || *ctNext != *ctNextExpected {
+			t.Fatalf("unexpected token for the next chunk\ngot\n%+v\nwant\n%+v", ctNext, *ctNextExpected)
+		}
+	}
+
+	// the first chunk out of many
+	f(&exportContinuationToken{Start: 100, End: 1000, ChunkDuration: 300, FiltersHash: 123}, 100, 399,
+		&exportContinuationToken{Start: 400, End: 1000, ChunkDuration: 300, FiltersHash: 123})
+
+	// the chunk ending exactly at the end
+	f(&exportContinuationToken{Start: 701, End: 1000, ChunkDuration: 300}, 701, 1000, nil)
+
+	// the last chunk is shorter than chunk duration
+	f(&exportContinuationToken{Start: 900, End: 1000, ChunkDuration: 300}, 900, 1000, nil)
+
+	// single-millisecond time range
+	f(&exportContinuationToken{Start: 1000, End: 1000, ChunkDuration: 1}, 1000, 1000, nil)
+
+	// iterate over all the chunks
+	ct := &exportContinuationToken{Start: 0, End: 99, ChunkDuration: 10}
+	chunks := 0
+	for ct != nil {
+		_, _, ct = ct.nextChunk()
+		chunks++
+	}
+	if chunks != 10 {
+		t.Fatalf("unexpected number of chunks; got %d; want 10", chunks)
+	}
+}
+
+func TestGetFiltersHash(t *testing.T) {
+	newFilterss := func(values ...string) [][]storage.TagFilter {
+		var filterss [][]storage.TagFilter
+		for _, v := range values {
+			filterss = append(filterss, []storage.TagFilter{{
+				Key:   []byte("job"),
+				Value: []byte(v),
+			}})
+		}
+		return filterss
+	}
+
+	h1 := getFiltersHash(newFilterss("foo"))
+	if h := getFiltersHash(newFilterss("foo")); h != h1 {
+		t.Fatalf("unexpected hash for identical filters; got %d; want %d", h, h1)
+	}
+	if h := getFiltersHash(newFilterss("bar")); h == h1 {
+		t.Fatalf("expecting distinct hash for distinct filters")
+	}
+	if h := getFiltersHash(newFilterss("foo", "bar")); h == h1 {
+		t.Fatalf("expecting distinct hash for distinct number of filters")
+	}
+}
@@ -0,0 +1,825 @@
+package prometheus
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/quicktemplate"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
+)
+
+var (
+	maxGraphQLPageSize = flag.Int("search.maxGraphQLPageSize", 1000, "The maximum value of `first` argument for paginated fields at /api/v1/graphql. "+
+		"Every metric at the page may require additional queries to the storage for nested fields. See also -search.maxGraphQLStorageQueries "+
+		"and https://docs.victoriametrics.com/#graphql-api")
+	maxGraphQLStorageQueries = flag.Int("search.maxGraphQLStorageQueries", 5000, "The maximum number of queries to the storage, which can be performed "+
+		"for a single request to /api/v1/graphql. Nested fields may require a query to the storage per every item at the page. "+
+		"See https://docs.victoriametrics.com/#graphql-api")
+)
+
+// defaultGraphQLPageSize is the number of items returned from paginated GraphQL fields if `first` argument isn't set.
+const defaultGraphQLPageSize = 100
+
+// GraphQLHandler processes /api/v1/graphql request.
+//
+// It allows exploring metric names, labels, label values, series counts and metric metadata with a single GraphQL query.
+//
+// See https://docs.victoriametrics.com/#graphql-api
+func GraphQLHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer graphqlDuration.UpdateDuration(startTime)
+
+	gr, err := getGraphQLRequest(r)
+	if err != nil {
+		return err
+	}
+	if len(gr.Query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	if len(gr.Query) > maxQueryLen.IntN() {
+		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(gr.Query), maxQueryLen.N)
+	}
+	fields, err := parseGraphQLQuery(gr.Query, gr.OperationName, gr.Variables)
+	if err != nil {
+		return err
+	}
+	cp, err := getCommonParamsForLabelsAPI(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	etfs, err := searchutil.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	e := &graphqlExecutor{
+		b: &graphqlLimitedBackend{
+			b: &graphqlStorage{
+				qt:       qt,
+				deadline: cp.deadline,
+			},
+			maxQueries: *maxGraphQLStorageQueries,
+		},
+		defaultScope: graphqlScope{
+			filterss: cp.filterss,
+			start:    cp.start,
+			end:      cp.end,
+		},
+		etfs:        etfs,
+		maxPageSize: *maxGraphQLPageSize,
+	}
+	data, err := e.execQuery(nil, fields)
+	if err != nil {
+		return fmt.Errorf("error when executing GraphQL query: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	_, _ = bw.Write([]byte(`{"data":`))
+	_, _ = bw.Write(data)
+	_, _ = bw.Write([]byte("}"))
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send GraphQL response to remote client: %w", err)
+	}
+	return nil
+}
+
+var graphqlDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/graphql"}`)
+
+// SendGraphQLError sends err to the client in GraphQL response format.
+func SendGraphQLError(w http.ResponseWriter, r *http.Request, err error) {
+	logger.WarnfSkipframes(1, "error in %q: %s", httpserver.GetRequestURI(r), err)
+
+	w.Header().Set("Content-Type", "application/json")
+	statusCode := http.StatusUnprocessableEntity
+	var esc *httpserver.ErrorWithStatusCode
+	if errors.As(err, &esc) {
+		statusCode = esc.StatusCode
+	}
+	w.WriteHeader(statusCode)
+
+	var ure *httpserver.UserReadableError
+	if errors.As(err, &ure) {
+		err = ure
+	}
+	b := []byte(`{"errors":[{"message":`)
+	b = quicktemplate.AppendJSONString(b, err.Error(), true)
+	b = append(b, "}]}"...)
+	_, _ = w.Write(b)
+}
+
+// graphqlRequest is GraphQL request obtained either from query args or from JSON request body.
+//
+// See https://graphql.org/learn/serving-over-http/
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func getGraphQLRequest(r *http.Request) (*graphqlRequest, error) {
+	var gr graphqlRequest
+	if r.Method == http.MethodPost {
+		contentType := r.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "application/json"):
+			data, err := io.ReadAll(io.LimitReader(r.Body, int64(2*maxQueryLen.IntN())))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read request body: %w", err)
+			}
+			if err := json.Unmarshal(data, &gr); err != nil {
+				return nil, fmt.Errorf("cannot parse GraphQL request body: %w", err)
+			}
+			return &gr, nil
+		case strings.HasPrefix(contentType, "application/graphql"):
+			data, err := io.ReadAll(io.LimitReader(r.Body, int64(maxQueryLen.IntN())+1))
+			if err != nil {
+				return nil, fmt.Errorf("cannot read request body: %w", err)
+			}
+			gr.Query = string(data)
+			gr.OperationName = r.FormValue("operationName")
+			return &gr, nil
+		}
+	}
+	gr.Query = r.FormValue("query")
+	gr.OperationName = r.FormValue("operationName")
+	if s := r.FormValue("variables"); s != "" {
+		if err := json.Unmarshal([]byte(s), &gr.Variables); err != nil {
+			return nil, fmt.Errorf("cannot parse `variables` arg: %w", err)
+		}
+	}
+	return &gr, nil
+}
+
+// graphqlScope contains filters for the time series, which are inherited by nested GraphQL fields.
+type graphqlScope struct {
+	filterss [][]storage.TagFilter
+	start    int64
+	end      int64
+
+	// metricName is set for the fields nested into Metric.
+	metricName string
+}
+
+func (sc *graphqlScope) withMetricName(metricName string) *graphqlScope {
+	tf := storage.TagFilter{
+		Value: []byte(metricName),
+	}
+	scNew := *sc
+	scNew.filterss = searchutil.JoinTagFilterss(sc.filterss, [][]storage.TagFilter{{tf}})
+	scNew.metricName = metricName
+	return &scNew
+}
+
+// graphqlBackend provides the data for GraphQL queries.
+type graphqlBackend interface {
+	labelNames(sc *graphqlScope) ([]string, error)
+	labelValues(labelName string, sc *graphqlScope) ([]string, error)
+	seriesCount(sc *graphqlScope) (uint64, error)
+	totalSeriesCount() (uint64, error)
+	metricMetadata(metricName string) ([]storage.MetricMetadata, error)
+}
+
+// graphqlStorage is graphqlBackend, which obtains the data from the storage.
+type graphqlStorage struct {
+	qt       *querytracer.Tracer
+	deadline searchutil.Deadline
+}
+
+func (gs *graphqlStorage) labelNames(sc *graphqlScope) ([]string, error) {
+	sq := storage.NewSearchQuery(sc.start, sc.end, sc.filterss, *maxLabelsAPISeries)
+	labels, err := netstorage.LabelNames(gs.qt, sq, 0, gs.deadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain labels: %w", err)
+	}
+	return labels, nil
+}
+
+func (gs *graphqlStorage) labelValues(labelName string, sc *graphqlScope) ([]string, error) {
+	sq := storage.NewSearchQuery(sc.start, sc.end, sc.filterss, *maxLabelsAPISeries)
+	values, err := netstorage.LabelValues(gs.qt, labelName, sq, 0, gs.deadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain values for label %q: %w", labelName, err)
+	}
+	return values, nil
+}
+
+// seriesCount returns the number of series matching sc for the day containing sc.end.
+func (gs *graphqlStorage) seriesCount(sc *graphqlScope) (uint64, error) {
+	date := uint64(sc.end) / (secsPerDay * 1000)
+	start := int64(date*secsPerDay) * 1000
+	end := int64((date+1)*secsPerDay)*1000 - 1
+	sq := storage.NewSearchQuery(start, end, sc.filterss, *maxTSDBStatusSeries)
+	status, err := netstorage.TSDBStatus(gs.qt, sq, "", 1, gs.deadline)
+	if err != nil {
+		return 0, fmt.Errorf("cannot obtain series count: %w", err)
+	}
+	return status.TotalSeries, nil
+}
+
+func (gs *graphqlStorage) totalSeriesCount() (uint64, error) {
+	n, err := netstorage.SeriesCount(gs.qt, gs.deadline)
+	if err != nil {
+		return 0, fmt.Errorf("cannot obtain series count: %w", err)
+	}
+	return n, nil
+}
+
+func (gs *graphqlStorage) metricMetadata(metricName string) ([]storage.MetricMetadata, error) {
+	m, err := netstorage.MetricMetadata(gs.qt, metricName, 0, 0, gs.deadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain metadata for metric %q: %w", metricName, err)
+	}
+	return m[metricName], nil
+}
+
+// graphqlLimitedBackend limits the number of queries to b.
+type graphqlLimitedBackend struct {
+	b graphqlBackend
+
+	queries    int
+	maxQueries int
+}
+
+func (lb *graphqlLimitedBackend) registerQuery() error {
+	if lb.queries >= lb.maxQueries {
+		return fmt.Errorf("the query requires more than %d queries to the storage; reduce the page size or the number of nested fields; "+
+			"see -search.maxGraphQLStorageQueries command-line flag", lb.maxQueries)
+	}
+	lb.queries++
+	return nil
+}
+
+func (lb *graphqlLimitedBackend) labelNames(sc *graphqlScope) ([]string, error) {
+	if err := lb.registerQuery(); err != nil {
+		return nil, err
+	}
+	return lb.b.labelNames(sc)
+}
+
+func (lb *graphqlLimitedBackend) labelValues(labelName string, sc *graphqlScope) ([]string, error) {
+	if err := lb.registerQuery(); err != nil {
+		return nil, err
+	}
+	return lb.b.labelValues(labelName, sc)
+}
+
+func (lb *graphqlLimitedBackend) seriesCount(sc *graphqlScope) (uint64, error) {
+	if err := lb.registerQuery(); err != nil {
+		return 0, err
+	}
+	return lb.b.seriesCount(sc)
+}
+
+func (lb *graphqlLimitedBackend) totalSeriesCount() (uint64, error) {
+	if err := lb.registerQuery(); err != nil {
+		return 0, err
+	}
+	return lb.b.totalSeriesCount()
+}
+
+func (lb *graphqlLimitedBackend) metricMetadata(metricName string) ([]storage.MetricMetadata, error) {
+	if err := lb.registerQuery(); err != nil {
+		return nil, err
+	}
+	return lb.b.metricMetadata(metricName)
+}
+
+// graphqlExecutor executes GraphQL queries against the following schema:
+//
+//	type Query {
+//	  metrics(match: [String], start: String, end: String, first: Int, after: String): MetricConnection
+//	  metric(name: String!, match: [String], start: String, end: String): Metric
+//	  labels(match: [String], start: String, end: String, first: Int, after: String): LabelConnection
+//	  label(name: String!, match: [String], start: String, end: String): Label
+//	  seriesCount: Int
+//	}
+//	type Metric { name: String, seriesCount: Int, labels(first: Int, after: String): LabelConnection, metadata: [MetricMetadata] }
+//	type Label { name: String, values(first: Int, after: String): LabelValueConnection }
+//	type MetricMetadata { type: String, help: String, unit: String }
+//	type XConnection { totalCount: Int, pageInfo: PageInfo, nodes: [X], edges: [XEdge] }
+//	type XEdge { cursor: String, node: X }
+//	type PageInfo { hasNextPage: Boolean, endCursor: String }
+//
+// Nested fields inherit series filters from the parent fields.
+type graphqlExecutor struct {
+	b graphqlBackend
+
+	// defaultScope is the scope for root fields obtained from request query args.
+	defaultScope graphqlScope
+
+	// etfs contains extra filters from request query args, which must be applied to `match` args.
+	etfs [][]storage.TagFilter
+
+	maxPageSize int
+}
+
+func (e *graphqlExecutor) execQuery(dst []byte, fields []*graphqlField) ([]byte, error) {
+	return writeGraphQLObject(dst, "Query", fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+		switch f.name {
+		case "metrics":
+			if err := checkGraphQLArgs(f, "match", "start", "end", "first", "after"); err != nil {
+				return nil, err
+			}
+			sc, err := e.getScope(f)
+			if err != nil {
+				return nil, err
+			}
+			return e.writeMetrics(dst, f, sc)
+		case "metric":
+			if err := checkGraphQLArgs(f, "name", "match", "start", "end"); err != nil {
+				return nil, err
+			}
+			name, err := getGraphQLRequiredStringArg(f, "name")
+			if err != nil {
+				return nil, err
+			}
+			sc, err := e.getScope(f)
+			if err != nil {
+				return nil, err
+			}
+			sc = sc.withMetricName(name)
+			names, err := e.b.labelValues("__name__", sc)
+			if err != nil {
+				return nil, err
+			}
+			if len(names) == 0 {
+				return append(dst, "null"...), nil
+			}
+			return e.writeMetric(dst, f.fields, sc)
+		case "labels":
+			if err := checkGraphQLArgs(f, "match", "start", "end", "first", "after"); err != nil {
+				return nil, err
+			}
+			sc, err := e.getScope(f)
+			if err != nil {
+				return nil, err
+			}
+			return e.writeLabels(dst, f, sc)
+		case "label":
+			if err := checkGraphQLArgs(f, "name", "match", "start", "end"); err != nil {
+				return nil, err
+			}
+			name, err := getGraphQLRequiredStringArg(f, "name")
+			if err != nil {
+				return nil, err
+			}
+			sc, err := e.getScope(f)
+			if err != nil {
+				return nil, err
+			}
+			return e.writeLabel(dst, f.fields, name, sc)
+		case "seriesCount":
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			n, err := e.b.totalSeriesCount()
+			if err != nil {
+				return nil, err
+			}
+			return strconv.AppendUint(dst, n, 10), nil
+		default:
+			return nil, fmt.Errorf("unknown field %q at type Query", f.name)
+		}
+	})
+}
+
+// getScope returns the scope for root field f according to its `match`, `start` and `end` args.
+func (e *graphqlExecutor) getScope(f *graphqlField) (*graphqlScope, error) {
+	sc := e.defaultScope
+	if v := f.args["match"]; v != nil && v.kind != graphqlValueNull {
+		matches, err := getGraphQLStrings(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `match` arg for field %q: %w", f.name, err)
+		}
+		filterss, err := getTagFilterssFromMatches(matches)
+		if err != nil {
+			return nil, err
+		}
+		sc.filterss = searchutil.JoinTagFilterss(filterss, e.etfs)
+	}
+	start, err := getGraphQLTimeArg(f, "start", sc.start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := getGraphQLTimeArg(f, "end", sc.end)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		end = start
+	}
+	sc.start = start
+	sc.end = end
+	return &sc, nil
+}
+
+func (e *graphqlExecutor) writeMetrics(dst []byte, f *graphqlField, sc *graphqlScope) ([]byte, error) {
+	names, err := e.b.labelValues("__name__", sc)
+	if err != nil {
+		return nil, err
+	}
+	return e.writeConnection(dst, f, "Metric", names, func(dst []byte, fields []*graphqlField, name string) ([]byte, error) {
+		return e.writeMetric(dst, fields, sc.withMetricName(name))
+	})
+}
+
+func (e *graphqlExecutor) writeMetric(dst []byte, fields []*graphqlField, sc *graphqlScope) ([]byte, error) {
+	return writeGraphQLObject(dst, "Metric", fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+		switch f.name {
+		case "name":
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			return quicktemplate.AppendJSONString(dst, sc.metricName, true), nil
+		case "seriesCount":
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			n, err := e.b.seriesCount(sc)
+			if err != nil {
+				return nil, err
+			}
+			return strconv.AppendUint(dst, n, 10), nil
+		case "labels":
+			if err := checkGraphQLArgs(f, "first", "after"); err != nil {
+				return nil, err
+			}
+			return e.writeLabels(dst, f, sc)
+		case "metadata":
+			if err := checkGraphQLArgs(f); err != nil {
+				return nil, err
+			}
+			mms, err := e.b.metricMetadata(sc.metricName)
+			if err != nil {
+				return nil, err
+			}
+			return writeGraphQLMetricMetadata(dst, f, mms)
+		default:
+			return nil, fmt.Errorf("unknown field %q at type Metric", f.name)
+		}
+	})
+}
+
+func writeGraphQLMetricMetadata(dst []byte, f *graphqlField, mms []storage.MetricMetadata) ([]byte, error) {
+	if len(f.fields) == 0 {
+		return nil, fmt.Errorf("missing selection set for field %q", f.name)
+	}
+	dst = append(dst, '[')
+	for i := range mms {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		mm := &mms[i]
+		var err error
+		dst, err = writeGraphQLObject(dst, "MetricMetadata", f.fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			switch f.name {
+			case "type":
+				return quicktemplate.AppendJSONString(dst, mm.Type, true), nil
+			case "help":
+				return quicktemplate.AppendJSONString(dst, mm.Help, true), nil
+			case "unit":
+				return quicktemplate.AppendJSONString(dst, mm.Unit, true), nil
+			default:
+				return nil, fmt.Errorf("unknown field %q at type MetricMetadata", f.name)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	dst = append(dst, ']')
+	return dst, nil
+}
+
+func (e *graphqlExecutor) writeLabels(dst []byte, f *graphqlField, sc *graphqlScope) ([]byte, error) {
+	labels, err := e.b.labelNames(sc)
+	if err != nil {
+		return nil, err
+	}
+	return e.writeConnection(dst, f, "Label", labels, func(dst []byte, fields []*graphqlField, name string) ([]byte, error) {
+		return e.writeLabel(dst, fields, name, sc)
+	})
+}
+
+func (e *graphqlExecutor) writeLabel(dst []byte, fields []*graphqlField, labelName string, sc *graphqlScope) ([]byte, error) {
+	return writeGraphQLObject(dst, "Label", fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+		switch f.name {
+		case "name":
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			return quicktemplate.AppendJSONString(dst, labelName, true), nil
+		case "values":
+			if err := checkGraphQLArgs(f, "first", "after"); err != nil {
+				return nil, err
+			}
+			values, err := e.b.labelValues(labelName, sc)
+			if err != nil {
+				return nil, err
+			}
+			return e.writeConnection(dst, f, "LabelValue", values, nil)
+		default:
+			return nil, fmt.Errorf("unknown field %q at type Label", f.name)
+		}
+	})
+}
+
+// writeConnection writes a page of items for the paginated field f according to its `first` and `after` args.
+//
+// writeNode must write the node for the given item. Items are written as strings if writeNode is nil.
+func (e *graphqlExecutor) writeConnection(dst []byte, f *graphqlField, nodeType string, items []string,
+	writeNode func(dst []byte, fields []*graphqlField, item string) ([]byte, error)) ([]byte, error) {
+
+	first, err := getGraphQLIntArg(f, "first", min(defaultGraphQLPageSize, e.maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+	if first < 0 {
+		return nil, fmt.Errorf("`first` arg for field %q cannot be negative; got %d", f.name, first)
+	}
+	if first > e.maxPageSize {
+		return nil, fmt.Errorf("`first` arg for field %q cannot exceed %d; got %d; see -search.maxGraphQLPageSize command-line flag", f.name, e.maxPageSize, first)
+	}
+	sort.Strings(items)
+	offset := 0
+	if v := f.args["after"]; v != nil && v.kind != graphqlValueNull {
+		if v.kind != graphqlValueString {
+			return nil, fmt.Errorf("`after` arg for field %q must be string; got %s", f.name, v.kindString())
+		}
+		after, err := decodeGraphQLCursor(v.s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `after` arg for field %q: %w", f.name, err)
+		}
+		offset = sort.SearchStrings(items, after)
+		if offset < len(items) && items[offset] == after {
+			offset++
+		}
+	}
+	page := items[offset:]
+	if len(page) > first {
+		page = page[:first]
+	}
+	hasNextPage := offset+len(page) < len(items)
+
+	writeItem := func(dst []byte, f *graphqlField, item string) ([]byte, error) {
+		if writeNode == nil {
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			return quicktemplate.AppendJSONString(dst, item, true), nil
+		}
+		if err := checkGraphQLArgs(f); err != nil {
+			return nil, err
+		}
+		if len(f.fields) == 0 {
+			return nil, fmt.Errorf("missing selection set for field %q", f.name)
+		}
+		return writeNode(dst, f.fields, item)
+	}
+
+	if len(f.fields) == 0 {
+		return nil, fmt.Errorf("missing selection set for field %q", f.name)
+	}
+	return writeGraphQLObject(dst, nodeType+"Connection", f.fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+		switch f.name {
+		case "totalCount":
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			return strconv.AppendInt(dst, int64(len(items)), 10), nil
+		case "pageInfo":
+			if err := checkGraphQLArgs(f); err != nil {
+				return nil, err
+			}
+			if len(f.fields) == 0 {
+				return nil, fmt.Errorf("missing selection set for field %q", f.name)
+			}
+			return writeGraphQLObject(dst, "PageInfo", f.fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+				if err := checkGraphQLLeaf(f); err != nil {
+					return nil, err
+				}
+				switch f.name {
+				case "hasNextPage":
+					if hasNextPage {
+						return append(dst, "true"...), nil
+					}
+					return append(dst, "false"...), nil
+				case "endCursor":
+					if len(page) == 0 {
+						return append(dst, "null"...), nil
+					}
+					return quicktemplate.AppendJSONString(dst, encodeGraphQLCursor(page[len(page)-1]), true), nil
+				default:
+					return nil, fmt.Errorf("unknown field %q at type PageInfo", f.name)
+				}
+			})
+		case "nodes":
+			if err := checkGraphQLArgs(f); err != nil {
+				return nil, err
+			}
+			dst = append(dst, '[')
+			for i, item := range page {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				var err error
+				dst, err = writeItem(dst, f, item)
+				if err != nil {
+					return nil, err
+				}
+			}
+			dst = append(dst, ']')
+			return dst, nil
+		case "edges":
+			if err := checkGraphQLArgs(f); err != nil {
+				return nil, err
+			}
+			if len(f.fields) == 0 {
+				return nil, fmt.Errorf("missing selection set for field %q", f.name)
+			}
+			dst = append(dst, '[')
+			for i, item := range page {
+				if i > 0 {
+					dst = append(dst, ',')
+				}
+				var err error
+				dst, err = writeGraphQLObject(dst, nodeType+"Edge", f.fields, func(dst []byte, f *graphqlField) ([]byte, error) {
+					switch f.name {
+					case "cursor":
+						if err := checkGraphQLLeaf(f); err != nil {
+							return nil, err
+						}
+						return quicktemplate.AppendJSONString(dst, encodeGraphQLCursor(item), true), nil
+					case "node":
+						return writeItem(dst, f, item)
+					default:
+						return nil, fmt.Errorf("unknown field %q at type %sEdge", f.name, nodeType)
+					}
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			dst = append(dst, ']')
+			return dst, nil
+		default:
+			return nil, fmt.Errorf("unknown field %q at type %sConnection", f.name, nodeType)
+		}
+	})
+}
+
+// writeGraphQLObject writes JSON object with the given fields to dst.
+//
+// writeField must write the value for the given field. `__typename` field is handled by writeGraphQLObject.
+func writeGraphQLObject(dst []byte, typeName string, fields []*graphqlField, writeField func(dst []byte, f *graphqlField) ([]byte, error)) ([]byte, error) {
+	keys := make(map[string]struct{}, len(fields))
+	dst = append(dst, '{')
+	for i, f := range fields {
+		key := f.responseKey()
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("duplicate field %q at type %s; use aliases for requesting the same field multiple times", key, typeName)
+		}
+		keys[key] = struct{}{}
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = quicktemplate.AppendJSONString(dst, key, true)
+		dst = append(dst, ':')
+		var err error
+		if f.name == "__typename" {
+			if err := checkGraphQLLeaf(f); err != nil {
+				return nil, err
+			}
+			dst = quicktemplate.AppendJSONString(dst, typeName, true)
+			continue
+		}
+		dst, err = writeField(dst, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dst = append(dst, '}')
+	return dst, nil
+}
+
+// checkGraphQLArgs verifies that f contains only the allowed args.
+func checkGraphQLArgs(f *graphqlField, allowed ...string) error {
+	for name := range f.args {
+		if !slices.Contains(allowed, name) {
+			return fmt.Errorf("unknown argument %q for field %q", name, f.name)
+		}
+	}
+	return nil
+}
+
+// checkGraphQLLeaf verifies that f is a leaf field without args and selection set.
+func checkGraphQLLeaf(f *graphqlField) error {
+	if err := checkGraphQLArgs(f); err != nil {
+		return err
+	}
+	if len(f.fields) > 0 {
+		return fmt.Errorf("field %q cannot have selection set", f.name)
+	}
+	return nil
+}
+
+func getGraphQLRequiredStringArg(f *graphqlField, name string) (string, error) {
+	v := f.args[name]
+	if v == nil || v.kind == graphqlValueNull {
+		return "", fmt.Errorf("missing `%s` arg for field %q", name, f.name)
+	}
+	if v.kind != graphqlValueString {
+		return "", fmt.Errorf("`%s` arg for field %q must be string; got %s", name, f.name, v.kindString())
+	}
+	return v.s, nil
+}
+
+func getGraphQLIntArg(f *graphqlField, name string, defaultValue int) (int, error) {
+	v := f.args[name]
+	if v == nil || v.kind == graphqlValueNull {
+		return defaultValue, nil
+	}
+	if v.kind != graphqlValueInt {
+		return 0, fmt.Errorf("`%s` arg for field %q must be int; got %s", name, f.name, v.kindString())
+	}
+	return int(v.n), nil
+}
+
+// getGraphQLTimeArg returns timestamp in milliseconds from f arg with the given name.
+//
+// The arg may contain unix timestamp in seconds or a string in any format supported by Prometheus querying API.
+func getGraphQLTimeArg(f *graphqlField, name string, defaultValue int64) (int64, error) {
+	v := f.args[name]
+	if v == nil {
+		return defaultValue, nil
+	}
+	switch v.kind {
+	case graphqlValueNull:
+		return defaultValue, nil
+	case graphqlValueInt:
+		return v.n * 1000, nil
+	case graphqlValueFloat:
+		return int64(v.f * 1000), nil
+	case graphqlValueString:
+		msecs, err := timeutil.ParseTimeMsec(v.s)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse `%s` arg for field %q: %w", name, f.name, err)
+		}
+		return msecs, nil
+	default:
+		return 0, fmt.Errorf("`%s` arg for field %q must be string or number; got %s", name, f.name, v.kindString())
+	}
+}
+
+// getGraphQLStrings returns strings from v, which may contain either a string or a list of strings.
+func getGraphQLStrings(v *graphqlValue) ([]string, error) {
+	switch v.kind {
+	case graphqlValueString:
+		return []string{v.s}, nil
+	case graphqlValueList:
+		a := make([]string, 0, len(v.list))
+		for _, item := range v.list {
+			if item.kind != graphqlValueString {
+				return nil, fmt.Errorf("list items must be strings; got %s", item.kindString())
+			}
+			a = append(a, item.s)
+		}
+		return a, nil
+	default:
+		return nil, fmt.Errorf("want string or list of strings; got %s", v.kindString())
+	}
+}
+
+func encodeGraphQLCursor(item string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(item))
+}
+
+func decodeGraphQLCursor(cursor string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,584 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// graphqlField is a field from GraphQL selection set.
+//
+// The supported subset of GraphQL query language:
+//
+//	query [Name] [($var: Type [= default], ...)] { field(arg: value, ...) { ... } ... }
+//
+// Fields may have aliases. Argument values may be strings, numbers, booleans, null, enums, lists and $variables.
+// Fragments, directives, object values, mutations and subscriptions aren't supported.
+//
+// See https://docs.victoriametrics.com/#graphql-api
+type graphqlField struct {
+	// alias is the optional field alias.
+	alias string
+
+	// name is the field name.
+	name string
+
+	// args contains field arguments with already substituted variables.
+	args map[string]*graphqlValue
+
+	// fields contains the selection set for the field.
+	fields []*graphqlField
+}
+
+// responseKey returns the key for f in the response.
+func (f *graphqlField) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type graphqlValueKind int
+
+const (
+	graphqlValueNull graphqlValueKind = iota
+	graphqlValueString
+	graphqlValueInt
+	graphqlValueFloat
+	graphqlValueBool
+	graphqlValueList
+)
+
+// graphqlValue is an argument value.
+type graphqlValue struct {
+	kind graphqlValueKind
+
+	s    string
+	n    int64
+	f    float64
+	b    bool
+	list []*graphqlValue
+}
+
+func (v *graphqlValue) kindString() string {
+	switch v.kind {
+	case graphqlValueNull:
+		return "null"
+	case graphqlValueString:
+		return "string"
+	case graphqlValueInt:
+		return "int"
+	case graphqlValueFloat:
+		return "float"
+	case graphqlValueBool:
+		return "boolean"
+	default:
+		return "list"
+	}
+}
+
+// newGraphQLValueFromJSON converts v obtained from JSON `variables` to graphqlValue.
+func newGraphQLValueFromJSON(v any) (*graphqlValue, error) {
+	switch t := v.(type) {
+	case nil:
+		return &graphqlValue{}, nil
+	case string:
+		return &graphqlValue{kind: graphqlValueString, s: t}, nil
+	case bool:
+		return &graphqlValue{kind: graphqlValueBool, b: t}, nil
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return &graphqlValue{kind: graphqlValueInt, n: int64(t)}, nil
+		}
+		return &graphqlValue{kind: graphqlValueFloat, f: t}, nil
+	case []any:
+		gv := &graphqlValue{kind: graphqlValueList}
+		for _, item := range t {
+			itemV, err := newGraphQLValueFromJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			gv.list = append(gv.list, itemV)
+		}
+		return gv, nil
+	default:
+		return nil, fmt.Errorf("object values aren't supported")
+	}
+}
+
+// parseGraphQLQuery parses GraphQL query s and returns the selection set for the operation with the given operationName.
+//
+// operationName may be empty if s contains a single operation. variables are substituted into argument values.
+func parseGraphQLQuery(s, operationName string, variables map[string]any) ([]*graphqlField, error) {
+	tokens, err := tokenizeGraphQL(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse GraphQL query: %w", err)
+	}
+	p := &graphqlParser{
+		tokens:    tokens,
+		variables: variables,
+	}
+	ops, err := p.parseDocument()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse GraphQL query: %w", err)
+	}
+	if operationName == "" {
+		if len(ops) > 1 {
+			return nil, fmt.Errorf("`operationName` must be set when the query contains multiple operations")
+		}
+		return ops[0].fields, nil
+	}
+	for _, op := range ops {
+		if op.name == operationName {
+			return op.fields, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find operation %q in the query", operationName)
+}
+
+type graphqlOperation struct {
+	name   string
+	fields []*graphqlField
+}
+
+// maxGraphQLQueryDepth is the maximum nesting depth for selection sets, list values and variable types in GraphQL query.
+//
+// The deepest valid query for the supported schema has 9 nested selection sets,
+// so the limit protects from excess recursion on malicious queries without rejecting valid queries.
+const maxGraphQLQueryDepth = 16
+
+type graphqlParser struct {
+	tokens []graphqlToken
+	pos    int
+
+	// depth is the current nesting depth. It mustn't exceed maxGraphQLQueryDepth.
+	depth int
+
+	variables map[string]any
+
+	// defaults contains default values for variables of the currently parsed operation.
+	defaults map[string]*graphqlValue
+}
+
+type graphqlTokenKind int
+
+const (
+	graphqlTokenEOF graphqlTokenKind = iota
+	graphqlTokenName
+	graphqlTokenString
+	graphqlTokenNumber
+	graphqlTokenPunct
+)
+
+type graphqlToken struct {
+	kind graphqlTokenKind
+	s    string
+}
+
+func tokenizeGraphQL(s string) ([]graphqlToken, error) {
+	var tokens []graphqlToken
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			// Commas are insignificant in GraphQL.
+			s = s[1:]
+		case c == '#':
+			n := strings.IndexByte(s, '\n')
+			if n < 0 {
+				n = len(s)
+			}
+			s = s[n:]
+		case c == '"':
+			if strings.HasPrefix(s, `"""`) {
+				return nil, fmt.Errorf("block strings aren't supported")
+			}
+			v, tail, err := readGraphQLString(s)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlTokenString, s: v})
+			s = tail
+		case isGraphQLNameChar(c) && (c < '0' || c > '9'):
+			n := 1
+			for n < len(s) && isGraphQLNameChar(s[n]) {
+				n++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlTokenName, s: s[:n]})
+			s = s[n:]
+		case c >= '0' && c <= '9' || c == '-':
+			n := 1
+			for n < len(s) && (s[n] >= '0' && s[n] <= '9' || s[n] == '.' || s[n] == 'e' || s[n] == 'E' || s[n] == '+' || s[n] == '-') {
+				n++
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlTokenNumber, s: s[:n]})
+			s = s[n:]
+		case strings.HasPrefix(s, "..."):
+			tokens = append(tokens, graphqlToken{kind: graphqlTokenPunct, s: "..."})
+			s = s[3:]
+		default:
+			if !strings.ContainsRune("{}()[]:$!=@", rune(c)) {
+				return nil, fmt.Errorf("unexpected char %q", c)
+			}
+			tokens = append(tokens, graphqlToken{kind: graphqlTokenPunct, s: s[:1]})
+			s = s[1:]
+		}
+	}
+	tokens = append(tokens, graphqlToken{kind: graphqlTokenEOF})
+	return tokens, nil
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// readGraphQLString reads double-quoted string from the beginning of s.
+//
+// GraphQL string escape sequences are identical to JSON escape sequences, so the string is unquoted with JSON decoder.
+func readGraphQLString(s string) (string, string, error) {
+	n := 1
+	for n < len(s) {
+		switch s[n] {
+		case '\\':
+			n += 2
+			continue
+		case '\n', '\r':
+			return "", s, fmt.Errorf("unexpected newline in string %s", s[:n])
+		case '"':
+			var v string
+			if err := json.Unmarshal([]byte(s[:n+1]), &v); err != nil {
+				return "", s, fmt.Errorf("cannot unquote string %s: %w", s[:n+1], err)
+			}
+			return v, s[n+1:], nil
+		}
+		n++
+	}
+	return "", s, fmt.Errorf("missing closing quote in string %s", s)
+}
+
+// enterNested must be called when entering nested selection set, list value or variable type.
+//
+// leaveNested must be called when leaving it if enterNested returns nil error.
+func (p *graphqlParser) enterNested() error {
+	if p.depth >= maxGraphQLQueryDepth {
+		return fmt.Errorf("too deep nesting; it cannot exceed %d levels", maxGraphQLQueryDepth)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *graphqlParser) leaveNested() {
+	p.depth--
+}
+
+func (p *graphqlParser) peek() graphqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *graphqlParser) next() graphqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != graphqlTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *graphqlParser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == graphqlTokenPunct && t.s == s
+}
+
+func (p *graphqlParser) expectPunct(s string) error {
+	t := p.next()
+	if t.kind != graphqlTokenPunct || t.s != s {
+		return fmt.Errorf("unexpected %s; want %q", t.String(), s)
+	}
+	return nil
+}
+
+func (p *graphqlParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != graphqlTokenName {
+		return "", fmt.Errorf("unexpected %s; want name", t.String())
+	}
+	return t.s, nil
+}
+
+func (t graphqlToken) String() string {
+	switch t.kind {
+	case graphqlTokenEOF:
+		return "end of query"
+	case graphqlTokenString:
+		return strconv.Quote(t.s)
+	default:
+		return fmt.Sprintf("%q", t.s)
+	}
+}
+
+func (p *graphqlParser) parseDocument() ([]*graphqlOperation, error) {
+	var ops []*graphqlOperation
+	for p.peek().kind != graphqlTokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("missing operation")
+	}
+	return ops, nil
+}
+
+func (p *graphqlParser) parseOperation() (*graphqlOperation, error) {
+	op := &graphqlOperation{}
+	p.defaults = nil
+	if !p.isPunct("{") {
+		kind, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations aren't supported; only queries are allowed", kind)
+		case "fragment":
+			return nil, fmt.Errorf("fragments aren't supported")
+		default:
+			return nil, fmt.Errorf("unexpected %q; want `query` or `{`", kind)
+		}
+		if p.peek().kind == graphqlTokenName {
+			op.name = p.next().s
+		}
+		if p.isPunct("(") {
+			if err := p.parseVariableDefinitions(); err != nil {
+				return nil, fmt.Errorf("cannot parse variable definitions: %w", err)
+			}
+		}
+	}
+	fields, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.fields = fields
+	return op, nil
+}
+
+func (p *graphqlParser) parseVariableDefinitions() error {
+	if err := p.expectPunct("("); err != nil {
+		return err
+	}
+	for !p.isPunct(")") {
+		if err := p.expectPunct("$"); err != nil {
+			return err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return fmt.Errorf("cannot parse type for $%s: %w", name, err)
+		}
+		if p.isPunct("=") {
+			p.next()
+			v, err := p.parseValue(true)
+			if err != nil {
+				return fmt.Errorf("cannot parse default value for $%s: %w", name, err)
+			}
+			if p.defaults == nil {
+				p.defaults = make(map[string]*graphqlValue)
+			}
+			p.defaults[name] = v
+		}
+	}
+	p.next()
+	return nil
+}
+
+// skipType skips variable type such as `String`, `[String!]` or `Int!`.
+//
+// Variable types aren't validated, since every field argument is validated during query execution.
+func (p *graphqlParser) skipType() error {
+	if p.isPunct("[") {
+		if err := p.enterNested(); err != nil {
+			return err
+		}
+		defer p.leaveNested()
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *graphqlParser) parseSelectionSet() ([]*graphqlField, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	if err := p.enterNested(); err != nil {
+		return nil, err
+	}
+	defer p.leaveNested()
+	var fields []*graphqlField
+	for !p.isPunct("}") {
+		if p.isPunct("...") {
+			return nil, fmt.Errorf("fragments aren't supported")
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("selection set cannot be empty")
+	}
+	return fields, nil
+}
+
+func (p *graphqlParser) parseField() (*graphqlField, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	f := &graphqlField{
+		name: name,
+	}
+	if p.isPunct(":") {
+		p.next()
+		f.alias = name
+		f.name, err = p.expectName()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse field name for alias %q: %w", f.alias, err)
+		}
+	}
+	if p.isPunct("(") {
+		f.args, err = p.parseArguments()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse arguments for field %q: %w", f.name, err)
+		}
+	}
+	if p.isPunct("@") {
+		return nil, fmt.Errorf("directives aren't supported")
+	}
+	if p.isPunct("{") {
+		f.fields, err = p.parseSelectionSet()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse selection set for field %q: %w", f.name, err)
+		}
+	}
+	return f, nil
+}
+
+func (p *graphqlParser) parseArguments() (map[string]*graphqlValue, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]*graphqlValue)
+	for !p.isPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := args[name]; ok {
+			return nil, fmt.Errorf("duplicate argument %q", name)
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseValue(false)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse value for argument %q: %w", name, err)
+		}
+		args[name] = v
+	}
+	p.next()
+	return args, nil
+}
+
+// parseValue parses argument value. Variables aren't allowed if isConst is set.
+func (p *graphqlParser) parseValue(isConst bool) (*graphqlValue, error) {
+	t := p.next()
+	switch t.kind {
+	case graphqlTokenString:
+		return &graphqlValue{kind: graphqlValueString, s: t.s}, nil
+	case graphqlTokenNumber:
+		if n, err := strconv.ParseInt(t.s, 10, 64); err == nil {
+			return &graphqlValue{kind: graphqlValueInt, n: n}, nil
+		}
+		f, err := strconv.ParseFloat(t.s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse number %q: %w", t.s, err)
+		}
+		return &graphqlValue{kind: graphqlValueFloat, f: f}, nil
+	case graphqlTokenName:
+		switch t.s {
+		case "null":
+			return &graphqlValue{}, nil
+		case "true", "false":
+			return &graphqlValue{kind: graphqlValueBool, b: t.s == "true"}, nil
+		default:
+			// Enum values are treated as strings.
+			return &graphqlValue{kind: graphqlValueString, s: t.s}, nil
+		}
+	case graphqlTokenPunct:
+		switch t.s {
+		case "$":
+			if isConst {
+				return nil, fmt.Errorf("variables aren't allowed in default values")
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return p.getVariable(name)
+		case "[":
+			if err := p.enterNested(); err != nil {
+				return nil, err
+			}
+			defer p.leaveNested()
+			v := &graphqlValue{kind: graphqlValueList}
+			for !p.isPunct("]") {
+				item, err := p.parseValue(isConst)
+				if err != nil {
+					return nil, err
+				}
+				v.list = append(v.list, item)
+			}
+			p.next()
+			return v, nil
+		case "{":
+			return nil, fmt.Errorf("object values aren't supported")
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s; want value", t.String())
+}
+
+func (p *graphqlParser) getVariable(name string) (*graphqlValue, error) {
+	if v, ok := p.variables[name]; ok {
+		gv, err := newGraphQLValueFromJSON(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot use variable $%s: %w", name, err)
+		}
+		return gv, nil
+	}
+	if v, ok := p.defaults[name]; ok {
+		return v, nil
+	}
+	return &graphqlValue{}, nil
+}
@@ -0,0 +1,162 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseGraphQLQuerySuccess(t *testing.T) {
+	f := func(s, operationName, variables, resultExpected string) {
+		t.Helper()
+
+		var vars map[string]any
+		if variables != "" {
+			if err := json.Unmarshal([]byte(variables), &vars); err != nil {
+				t.Fatalf("cannot parse variables: %s", err)
+			}
+		}
+		fields, err := parseGraphQLQuery(s, operationName, vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := marshalGraphQLFields(fields)
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// shorthand query
+	f(`{ seriesCount }`, "", "", `{seriesCount}`)
+
+	// named query with aliases, args, nested fields and comments
+	f(`
+	# metric catalog
+	query Catalog {
+		top: metrics(match: "{job=\"api\"}", first: 10, after: "Zm9v") {
+			totalCount,
+			nodes { name labels { nodes { name } } }
+		}
+	}`, "", "", `{top:metrics(after:"Zm9v",first:10,match:"{job=\"api\"}"){totalCount nodes{name labels{nodes{name}}}}}`)
+
+	// list values, booleans, null and enums
+	f(`{ labels(match: ["up", "foo"], start: -1.5, end: null, x: true, y: FOO) { totalCount } }`, "", "",
+		`{labels(end:null,match:["up" "foo"],start:-1.5,x:true,y:"FOO"){totalCount}}`)
+
+	// variables with defaults
+	f(`query Q($match: [String!], $first: Int = 5, $after: String) { metrics(match: $match, first: $first, after: $after) { totalCount } }`,
+		"", `{"match":["up"]}`, `{metrics(after:null,first:5,match:["up"]){totalCount}}`)
+	f(`query Q($first: Int = 5) { metrics(first: $first) { totalCount } }`, "", `{"first":20}`, `{metrics(first:20){totalCount}}`)
+
+	// multiple operations
+	f(`query A { seriesCount } query B { labels { totalCount } }`, "B", "", `{labels{totalCount}}`)
+
+	// the deepest query for the supported schema
+	f(`{ metrics { edges { node { labels { edges { node { values { edges { node } } } } } } } } }`, "", "",
+		`{metrics{edges{node{labels{edges{node{values{edges{node}}}}}}}}}`)
+}
+
+func TestParseGraphQLQueryFailure(t *testing.T) {
+	f := func(s, operationName string) {
+		t.Helper()
+
+		_, err := parseGraphQLQuery(s, operationName, nil)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// empty query
+	f(``, "")
+	f(`{}`, "")
+
+	// unbalanced braces
+	f(`{ metrics { totalCount }`, "")
+	f(`{ metrics(first: 1 { totalCount } }`, "")
+
+	// unsupported operations
+	f(`mutation { foo }`, "")
+	f(`subscription { foo }`, "")
+
+	// unsupported features
+	f(`{ metrics { ...MetricFields } }`, "")
+	f(`fragment MetricFields on Metric { name }`, "")
+	f(`{ metrics @include(if: true) { totalCount } }`, "")
+	f(`{ metrics(match: {foo: "bar"}) { totalCount } }`, "")
+	f(`{ metrics(match: """foo""") { totalCount } }`, "")
+
+	// invalid strings
+	f(`{ metrics(match: "foo) { totalCount } }`, "")
+	f(`{ metrics(match: "\x") { totalCount } }`, "")
+
+	// duplicate args
+	f(`{ metrics(first: 1, first: 2) { totalCount } }`, "")
+
+	// unexpected char
+	f(`{ metrics; }`, "")
+
+	// multiple operations without operationName
+	f(`query A { seriesCount } query B { seriesCount }`, "")
+
+	// missing operation
+	f(`query A { seriesCount }`, "B")
+
+	// too deep nesting
+	f(strings.Repeat(`{ metrics `, 17)+`{ totalCount }`+strings.Repeat(` }`, 17), "")
+	f(`{ metrics(match: `+strings.Repeat(`[`, 17)+`"up"`+strings.Repeat(`]`, 17)+`) { totalCount } }`, "")
+	f(`query Q($match: `+strings.Repeat(`[`, 17)+`String`+strings.Repeat(`]`, 17)+`) { metrics(match: $match) { totalCount } }`, "")
+}
+
+func marshalGraphQLFields(fields []*graphqlField) string {
+	var a []string
+	for _, f := range fields {
+		s := f.name
+		if f.alias != "" {
+			s = f.alias + ":" + s
+		}
+		if len(f.args) > 0 {
+			s += "(" + marshalGraphQLArgs(f.args) + ")"
+		}
+		if len(f.fields) > 0 {
+			s += marshalGraphQLFields(f.fields)
+		}
+		a = append(a, s)
+	}
+	return "{" + strings.Join(a, " ") + "}"
+}
+
+func marshalGraphQLArgs(args map[string]*graphqlValue) string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	a := make([]string, len(names))
+	for i, name := range names {
+		a[i] = name + ":" + marshalGraphQLValue(args[name])
+	}
+	return strings.Join(a, ",")
+}
+
+func marshalGraphQLValue(v *graphqlValue) string {
+	switch v.kind {
+	case graphqlValueNull:
+		return "null"
+	case graphqlValueList:
+		a := make([]string, len(v.list))
+		for i, item := range v.list {
+			a[i] = marshalGraphQLValue(item)
+		}
+		return "[" + strings.Join(a, " ") + "]"
+	case graphqlValueString:
+		return strconv.Quote(v.s)
+	case graphqlValueInt:
+		return strconv.FormatInt(v.n, 10)
+	case graphqlValueFloat:
+		return strconv.FormatFloat(v.f, 'g', -1, 64)
+	default:
+		return strconv.FormatBool(v.b)
+	}
+}
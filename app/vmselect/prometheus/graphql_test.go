@@ -0,0 +1,240 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// graphqlTestBackend is graphqlBackend with static data.
+type graphqlTestBackend struct {
+	// labels contains label values per label name per metric name.
+	labels map[string]map[string][]string
+
+	seriesCounts map[string]uint64
+	metadata     map[string][]storage.MetricMetadata
+}
+
+func (tb *graphqlTestBackend) labelNames(sc *graphqlScope) ([]string, error) {
+	m := make(map[string]struct{})
+	for metricName, labels := range tb.labels {
+		if sc.metricName != "" && metricName != sc.metricName {
+			continue
+		}
+		m["__name__"] = struct{}{}
+		for labelName := range labels {
+			m[labelName] = struct{}{}
+		}
+	}
+	var a []string
+	for labelName := range m {
+		a = append(a, labelName)
+	}
+	return a, nil
+}
+
+func (tb *graphqlTestBackend) labelValues(labelName string, sc *graphqlScope) ([]string, error) {
+	m := make(map[string]struct{})
+	for metricName, labels := range tb.labels {
+		if sc.metricName != "" && metricName != sc.metricName {
+			continue
+		}
+		if labelName == "__name__" {
+			m[metricName] = struct{}{}
+			continue
+		}
+		for _, v := range labels[labelName] {
+			m[v] = struct{}{}
+		}
+	}
+	var a []string
+	for v := range m {
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func (tb *graphqlTestBackend) seriesCount(sc *graphqlScope) (uint64, error) {
+	return tb.seriesCounts[sc.metricName], nil
+}
+
+func (tb *graphqlTestBackend) totalSeriesCount() (uint64, error) {
+	n := uint64(0)
+	for _, v := range tb.seriesCounts {
+		n += v
+	}
+	return n, nil
+}
+
+func (tb *graphqlTestBackend) metricMetadata(metricName string) ([]storage.MetricMetadata, error) {
+	return tb.metadata[metricName], nil
+}
+
+func newGraphQLTestExecutor() *graphqlExecutor {
+	return &graphqlExecutor{
+		b: &graphqlTestBackend{
+			labels: map[string]map[string][]string{
+				"up": {
+					"job":      {"api", "db"},
+					"instance": {"host1", "host2", "host3"},
+				},
+				"http_requests_total": {
+					"job":  {"api"},
+					"path": {"/", "/api"},
+				},
+				"node_load1": {
+					"instance": {"host1"},
+				},
+			},
+			seriesCounts: map[string]uint64{
+				"up":                  3,
+				"http_requests_total": 2,
+				"node_load1":          1,
+			},
+			metadata: map[string][]storage.MetricMetadata{
+				"http_requests_total": {
+					{
+						MetricFamilyName: "http_requests_total",
+						Type:             "counter",
+						Help:             "The number of \"HTTP\" requests",
+					},
+				},
+			},
+		},
+		maxPageSize: 2,
+	}
+}
+
+func TestGraphQLExecutorSuccess(t *testing.T) {
+	f := func(query, resultExpected string) {
+		t.Helper()
+
+		fields, err := parseGraphQLQuery(query, "", nil)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		e := newGraphQLTestExecutor()
+		result, err := e.execQuery(nil, fields)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// total series count with __typename
+	f(`{ __typename seriesCount }`, `{"__typename":"Query","seriesCount":6}`)
+
+	// metrics with pagination
+	f(`{ metrics(first: 2) { totalCount pageInfo { hasNextPage endCursor } nodes { name } } }`,
+		`{"metrics":{"totalCount":3,"pageInfo":{"hasNextPage":true,"endCursor":"bm9kZV9sb2FkMQ"},"nodes":[{"name":"http_requests_total"},{"name":"node_load1"}]}}`)
+	f(`{ metrics(first: 2, after: "bm9kZV9sb2FkMQ") { pageInfo { hasNextPage endCursor } edges { cursor node { name } } } }`,
+		`{"metrics":{"pageInfo":{"hasNextPage":false,"endCursor":"dXA"},"edges":[{"cursor":"dXA","node":{"name":"up"}}]}}`)
+	f(`{ metrics(first: 2, after: "dXA") { pageInfo { hasNextPage endCursor } nodes { name } } }`,
+		`{"metrics":{"pageInfo":{"hasNextPage":false,"endCursor":null},"nodes":[]}}`)
+
+	// nested fields inherit the metric filter
+	f(`{ metric(name: "up") { name seriesCount labels(after: "X19uYW1lX18") { nodes { name values(first: 1) { totalCount nodes } } } } }`,
+		`{"metric":{"name":"up","seriesCount":3,"labels":{"nodes":[`+
+			`{"name":"instance","values":{"totalCount":3,"nodes":["host1"]}},`+
+			`{"name":"job","values":{"totalCount":2,"nodes":["api"]}}]}}}`)
+
+	// missing metric
+	f(`{ metric(name: "missing") { name } }`, `{"metric":null}`)
+
+	// metadata with aliases
+	f(`{ a: metric(name: "http_requests_total") { metadata { type help unit } } b: metric(name: "up") { metadata { type } } }`,
+		`{"a":{"metadata":[{"type":"counter","help":"The number of \"HTTP\" requests","unit":""}]},"b":{"metadata":[]}}`)
+
+	// label values across all the metrics
+	f(`{ label(name: "instance") { name values { totalCount nodes } } }`,
+		`{"label":{"name":"instance","values":{"totalCount":3,"nodes":["host1","host2"]}}}`)
+	f(`{ labels(first: 0) { totalCount pageInfo { hasNextPage } } }`,
+		`{"labels":{"totalCount":4,"pageInfo":{"hasNextPage":true}}}`)
+}
+
+func TestGraphQLExecutorFailure(t *testing.T) {
+	f := func(query string) {
+		t.Helper()
+
+		fields, err := parseGraphQLQuery(query, "", nil)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		e := newGraphQLTestExecutor()
+		if _, err := e.execQuery(nil, fields); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown fields
+	f(`{ foo }`)
+	f(`{ metrics { foo } }`)
+	f(`{ metrics { nodes { foo } } }`)
+	f(`{ label(name: "job") { values { nodes { foo } } } }`)
+
+	// unknown args
+	f(`{ metrics(foo: 1) { totalCount } }`)
+	f(`{ seriesCount(foo: 1) }`)
+
+	// missing selection set
+	f(`{ metrics }`)
+	f(`{ metric(name: "up") { labels } }`)
+
+	// unexpected selection set
+	f(`{ seriesCount { foo } }`)
+
+	// missing name
+	f(`{ metric { name } }`)
+	f(`{ label(name: 1) { name } }`)
+
+	// invalid pagination args
+	f(`{ metrics(first: -1) { totalCount } }`)
+	f(`{ metrics(first: 3) { totalCount } }`)
+	f(`{ metrics(first: "1") { totalCount } }`)
+	f(`{ metrics(after: "!!!") { totalCount } }`)
+
+	// invalid match and time args
+	f(`{ metrics(match: "{") { totalCount } }`)
+	f(`{ metrics(match: [1]) { totalCount } }`)
+	f(`{ metrics(start: "foo") { totalCount } }`)
+
+	// duplicate fields
+	f(`{ seriesCount seriesCount }`)
+}
+
+func TestGraphQLExecutorMaxStorageQueries(t *testing.T) {
+	f := func(query string, maxQueries int, isErrorExpected bool) {
+		t.Helper()
+
+		fields, err := parseGraphQLQuery(query, "", nil)
+		if err != nil {
+			t.Fatalf("cannot parse query: %s", err)
+		}
+		e := newGraphQLTestExecutor()
+		e.b = &graphqlLimitedBackend{
+			b:          e.b,
+			maxQueries: maxQueries,
+		}
+		_, err = e.execQuery(nil, fields)
+		if isErrorExpected {
+			if err == nil {
+				t.Fatalf("expecting non-nil error")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// a query for metric names and a query per every metric at the page for seriesCount
+	f(`{ metrics(first: 2) { nodes { name seriesCount } } }`, 3, false)
+	f(`{ metrics(first: 2) { nodes { name seriesCount } } }`, 2, true)
+
+	// nested fields multiply the number of queries
+	f(`{ metrics(first: 2) { nodes { seriesCount labels { nodes { name } } } } }`, 5, false)
+	f(`{ metrics(first: 2) { nodes { seriesCount labels { nodes { name } } } } }`, 4, true)
+	f(`{ metrics(first: 2) { nodes { labels { nodes { values { totalCount } } } } } }`, 6, true)
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,12 +17,14 @@ import (
 	"github.com/VictoriaMetrics/metricsql"
 	"github.com/valyala/fastjson/fastfloat"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/access"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
@@ -83,7 +86,7 @@ func ExpandWithExprs(w http.ResponseWriter, r *http.Request) {
 	defer bufferedwriter.Put(bw)
 	if format == "json" {
 		w.Header().Set("Content-Type", "application/json")
-		httpserver.EnableCORS(w, r)
+		access.EnableCORS(w, r)
 		WriteExpandWithExprsJSONResponse(bw, query)
 	} else {
 		WriteExpandWithExprsResponse(bw, query)
@@ -97,7 +100,7 @@ func PrettifyQuery(w http.ResponseWriter, r *http.Request) {
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
 	w.Header().Set("Content-Type", "application/json")
-	httpserver.EnableCORS(w, r)
+	access.EnableCORS(w, r)
 
 	prettyQuery, err := metricsql.Prettify(query)
 	if err != nil {
@@ -172,6 +175,7 @@ func ExportCSVHandler(startTime time.Time, w http.ResponseWriter, r *http.Reques
 	reduceMemUsage := httputil.GetBool(r, "reduce_mem_usage")
 
 	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	sq.IOClass = cp.ioClass
 	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
@@ -252,6 +256,7 @@ func ExportNativeHandler(startTime time.Time, w http.ResponseWriter, r *http.Req
 	}
 
 	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	sq.IOClass = cp.ioClass
 	w.Header().Set("Content-Type", "VictoriaMetrics/native")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
@@ -313,6 +318,9 @@ func ExportHandler(startTime time.Time, w http.ResponseWriter, r *http.Request)
 	format := r.FormValue("format")
 	maxRowsPerLine := int(fastfloat.ParseInt64BestEffort(r.FormValue("max_rows_per_line")))
 	reduceMemUsage := httputil.GetBool(r, "reduce_mem_usage")
+	if isResumableExport(r) {
+		return exportResumableHandler(w, r, cp, format, maxRowsPerLine, reduceMemUsage)
+	}
 	if err := exportHandler(nil, w, cp, format, maxRowsPerLine, reduceMemUsage); err != nil {
 		return fmt.Errorf("error when exporting data on the time range (start=%d, end=%d): %w", cp.start, cp.end, err)
 	}
@@ -397,6 +405,7 @@ func exportHandler(qt *querytracer.Tracer, w http.ResponseWriter, cp *commonPara
 	}
 
 	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	sq.IOClass = cp.ioClass
 	w.Header().Set("Content-Type", contentType)
 
 	doneCh := make(chan error, 1)
@@ -642,6 +651,44 @@ func LabelsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 
 var labelsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/labels"}`)
 
+// MetadataHandler processes /api/v1/metadata request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+func MetadataHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer metadataDuration.UpdateDuration(startTime)
+
+	deadline := searchutil.GetDeadlineForStatusRequest(r, startTime)
+	limit, err := httputil.GetInt(r, "limit")
+	if err != nil {
+		return err
+	}
+	limitPerMetric, err := httputil.GetInt(r, "limit_per_metric")
+	if err != nil {
+		return err
+	}
+	metricFamilyName := r.FormValue("metric")
+	m, err := netstorage.MetricMetadata(qt, metricFamilyName, limit, limitPerMetric, deadline)
+	if err != nil {
+		return fmt.Errorf("cannot obtain metric metadata: %w", err)
+	}
+	metricFamilyNames := make([]string, 0, len(m))
+	for name := range m {
+		metricFamilyNames = append(metricFamilyNames, name)
+	}
+	sort.Strings(metricFamilyNames)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteMetadataResponse(bw, metricFamilyNames, m, qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send metadata response to remote client: %w", err)
+	}
+	return nil
+}
+
+var metadataDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/metadata"}`)
+
 // SeriesCountHandler processes /api/v1/series/count request.
 func SeriesCountHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
 	defer seriesCountDuration.UpdateDuration(startTime)
@@ -739,7 +786,38 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	if err != nil {
 		return err
 	}
-	if childQuery, windowExpr, offsetExpr := promql.IsMetricSelectorWithRollup(query); childQuery != "" {
+	explainPlan, err := isExplainPlan(r)
+	if err != nil {
+		return err
+	}
+	ioClass, err := searchutil.GetIOClass(r, storage.IOClassInteractive)
+	if err != nil {
+		return err
+	}
+	if httputil.GetBool(r, "fast_last") && !explainPlan {
+		childQuery, offsetExpr := promql.IsMetricSelector(query)
+		if childQuery == "" {
+			return fmt.Errorf("`fast_last=1` query arg is supported only for series selectors; got query=%q", query)
+		}
+		end := start - offsetExpr.Duration(step)
+		tagFilterss, err := getTagFilterssFromMatches([]string{childQuery})
+		if err != nil {
+			return err
+		}
+		cp := &commonParams{
+			deadline: deadline,
+			// Do not include data point with a timestamp matching the lower boundary of the lookbehind window as Prometheus does.
+			start:    end - step + 1,
+			end:      end,
+			filterss: searchutil.JoinTagFilterss(tagFilterss, etfs),
+			ioClass:  ioClass,
+		}
+		if err := fastLastHandler(qt, startTime, w, cp, childQuery); err != nil {
+			return fmt.Errorf("error when selecting last values for query=%q on the time range (start=%d, end=%d): %w", childQuery, cp.start, cp.end, err)
+		}
+		return nil
+	}
+	if childQuery, windowExpr, offsetExpr := promql.IsMetricSelectorWithRollup(query); childQuery != "" && !explainPlan {
 		window, err := windowExpr.NonNegativeDuration(step)
 		if err != nil {
 			return fmt.Errorf("cannot parse lookbehind window in square brackets at %s: %w", query, err)
@@ -765,13 +843,14 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 			start:    start,
 			end:      end,
 			filterss: filterss,
+			ioClass:  ioClass,
 		}
 		if err := exportHandler(qt, w, cp, "promapi", 0, false); err != nil {
 			return fmt.Errorf("error when exporting data for query=%q on the time range (start=%d, end=%d): %w", childQuery, start, end, err)
 		}
 		return nil
 	}
-	if childQuery, windowExpr, stepExpr, offsetExpr := promql.IsRollup(query); childQuery != "" {
+	if childQuery, windowExpr, stepExpr, offsetExpr := promql.IsRollup(query); childQuery != "" && !explainPlan {
 		newStep, err := stepExpr.NonNegativeDuration(step)
 		if err != nil {
 			return fmt.Errorf("cannot parse step in square brackets at %s: %w", query, err)
@@ -819,12 +898,16 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 		LookbackDelta:       lookbackDelta,
 		RoundDigits:         getRoundDigits(r),
 		EnforcedTagFilterss: etfs,
+		IOClass:             ioClass,
 		GetRequestURI: func() string {
 			return httpserver.GetRequestURI(r)
 		},
 
 		QueryStats: qs,
 	}
+	if explainPlan {
+		return explainHandler(qt, w, ec, query)
+	}
 	result, err := promql.Exec(qt, ec, query, true)
 	if err != nil {
 		return fmt.Errorf("error when executing query=%q for (time=%d, step=%d): %w", query, start, step, err)
@@ -899,6 +982,10 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	if err != nil {
 		return err
 	}
+	ioClass, err := searchutil.GetIOClass(r, storage.IOClassInteractive)
+	if err != nil {
+		return err
+	}
 
 	// Validate input args.
 	if len(query) > maxQueryLen.IntN() {
@@ -927,12 +1014,20 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 		LookbackDelta:       lookbackDelta,
 		RoundDigits:         getRoundDigits(r),
 		EnforcedTagFilterss: etfs,
+		IOClass:             ioClass,
 		GetRequestURI: func() string {
 			return httpserver.GetRequestURI(r)
 		},
 
 		QueryStats: qs,
 	}
+	explainPlan, err := isExplainPlan(r)
+	if err != nil {
+		return err
+	}
+	if explainPlan {
+		return explainHandler(qt, w, ec, query)
+	}
 	result, err := promql.Exec(qt, ec, query, false)
 	if err != nil {
 		return err
@@ -951,19 +1046,129 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/153
 	result = removeEmptyValuesAndTimeseries(result)
 
-	w.Header().Set("Content-Type", "application/json")
+	var alerts []*rangeAlert
+	if httputil.GetBool(r, "alerts") {
+		alerts, err = getFiringAlerts(end)
+		if err != nil {
+			return err
+		}
+		qt.Printf("obtain %d firing alerts from vmalert", len(alerts))
+	}
+
+	rf := getQueryRangeResponseFormat(r)
+	w.Header().Set("Content-Type", rf.contentType())
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
 	qtDone := func() {
 		qt.Donef("start=%d, end=%d, step=%d, query=%q: series=%d", start, end, step, query, len(result))
 	}
-	WriteQueryRangeResponse(bw, result, qt, qtDone, qs)
+	switch rf {
+	case queryRangeResponseFormatProtobuf:
+		writeQueryRangeResponseProtobuf(bw, result, qs)
+		qtDone()
+	case queryRangeResponseFormatMsgpack:
+		writeQueryRangeResponseMsgpack(bw, result, qs)
+		qtDone()
+	default:
+		WriteQueryRangeResponse(bw, result, qt, qtDone, qs, alerts)
+	}
 	if err := bw.Flush(); err != nil {
 		return fmt.Errorf("cannot send query range response to remote client: %w", err)
 	}
 	return nil
 }
 
+// isExplainPlan returns true if r contains `explain=plan` query arg.
+func isExplainPlan(r *http.Request) (bool, error) {
+	explain := r.FormValue("explain")
+	switch explain {
+	case "":
+		return false, nil
+	case "plan":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported `explain` query arg value %q; supported value: `plan`", explain)
+	}
+}
+
+// explainHandler writes the execution plan for the given query and ec to w without executing the query.
+func explainHandler(qt *querytracer.Tracer, w http.ResponseWriter, ec *promql.EvalConfig, query string) error {
+	ep, err := promql.Explain(ec, query)
+	if err != nil {
+		return fmt.Errorf("cannot explain query=%q: %w", query, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteExplainResponse(bw, ep, ec.Start, ec.End, ec.Step, qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send explain response to remote client: %w", err)
+	}
+	return nil
+}
+
+// fastLastHandler writes the last raw sample per each series matching cp to w.
+//
+// The samples are selected directly from the storage without rollup evaluation.
+func fastLastHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, cp *commonParams, query string) error {
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, GetMaxUniqueTimeSeries())
+	sq.IOClass = cp.ioClass
+	rss, err := netstorage.ProcessSearchQuery(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch data for %q: %w", sq, err)
+	}
+	qs := &promql.QueryStats{}
+	qs.SeriesFetched.Add(int64(rss.Len()))
+
+	var resultLock sync.Mutex
+	var result []netstorage.Result
+	err = rss.RunParallel(qt, func(rs *netstorage.Result, _ uint) error {
+		v, ts, ok := getLastValue(rs.Values, rs.Timestamps)
+		if !ok {
+			return nil
+		}
+		resultLock.Lock()
+		result = append(result, netstorage.Result{})
+		r := &result[len(result)-1]
+		// rs is re-used after returning from the callback, so copy its contents.
+		r.MetricName.CopyFrom(&rs.MetricName)
+		r.Values = []float64{v}
+		r.Timestamps = []int64{ts}
+		resultLock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot select last values: %w", err)
+	}
+	qs.ExecutionTimeMsec.Store(time.Since(startTime).Milliseconds())
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("fast_last query=%s, start=%d, end=%d: series=%d", query, cp.start, cp.end, len(result))
+	}
+	WriteQueryResponse(bw, result, qt, qtDone, qs)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot flush query response to remote client: %w", err)
+	}
+	return nil
+}
+
+// getLastValue returns the last value and timestamp from the given values and timestamps.
+//
+// It returns false if there are no samples or if the last sample is a staleness marker.
+func getLastValue(values []float64, timestamps []int64) (float64, int64, bool) {
+	if len(values) == 0 {
+		return 0, 0, false
+	}
+	v := values[len(values)-1]
+	if decimal.IsStaleNaN(v) {
+		return 0, 0, false
+	}
+	return v, timestamps[len(timestamps)-1], true
+}
+
 func removeEmptyValuesAndTimeseries(tss []netstorage.Result) []netstorage.Result {
 	dst := tss[:0]
 	for i := range tss {
@@ -1128,6 +1333,7 @@ type commonParams struct {
 	end              int64
 	currentTimestamp int64
 	filterss         [][]storage.TagFilter
+	ioClass          storage.IOClass
 }
 
 func (cp *commonParams) IsDefaultTimeRange() bool {
@@ -1142,12 +1348,18 @@ func (cp *commonParams) IsDefaultTimeRange() bool {
 // - match[]
 // - extra_label
 // - extra_filters[]
+// - io_class
 func getExportParams(r *http.Request, startTime time.Time) (*commonParams, error) {
 	cp, err := getCommonParams(r, startTime, true)
 	if err != nil {
 		return nil, err
 	}
 	cp.deadline = searchutil.GetDeadlineForExport(r, startTime)
+	// Bulk exports mustn't evict page cache needed by interactive queries by default.
+	cp.ioClass, err = searchutil.GetIOClass(r, storage.IOClassBatch)
+	if err != nil {
+		return nil, err
+	}
 	return cp, nil
 }
 
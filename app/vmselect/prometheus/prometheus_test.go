@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 )
 
 func TestRemoveEmptyValuesAndTimeseries(t *testing.T) {
@@ -198,6 +199,29 @@ func TestAdjustLastPoints(t *testing.T) {
 	})
 }
 
+func TestGetLastValue(t *testing.T) {
+	f := func(values []float64, timestamps []int64, vExpected float64, tsExpected int64, okExpected bool) {
+		t.Helper()
+		v, ts, ok := getLastValue(values, timestamps)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok; got %v; want %v", ok, okExpected)
+		}
+		if v != vExpected || ts != tsExpected {
+			t.Fatalf("unexpected last sample; got (%v, %d); want (%v, %d)", v, ts, vExpected, tsExpected)
+		}
+	}
+
+	// no samples
+	f(nil, nil, 0, 0, false)
+
+	// the last sample is a staleness marker
+	f([]float64{1, decimal.StaleNaN}, []int64{100, 200}, 0, 0, false)
+
+	// the last sample is returned
+	f([]float64{1}, []int64{100}, 1, 100, true)
+	f([]float64{1, decimal.StaleNaN, 3}, []int64{100, 200, 300}, 3, 300, true)
+}
+
 func TestGetLatencyOffsetMillisecondsSuccess(t *testing.T) {
 	f := func(url string, expectedOffset int64) {
 		t.Helper()
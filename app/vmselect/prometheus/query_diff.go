@@ -0,0 +1,215 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// QueryRangeDiffHandler processes /api/v1/query_range_diff request.
+//
+// It evaluates `query` on the [start..end] time range and `query2` on the [start-offset..end-offset] time range,
+// and returns per-series differences or ratios between the results aligned by timestamps and labels.
+//
+// See https://docs.victoriametrics.com/#query-range-diff-api
+func QueryRangeDiffHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer queryRangeDiffDuration.UpdateDuration(startTime)
+
+	ct := startTime.UnixNano() / 1e6
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	query2 := r.FormValue("query2")
+	if len(query2) == 0 {
+		query2 = query
+	}
+	start, err := httputil.GetTime(r, "start", ct-defaultStep)
+	if err != nil {
+		return err
+	}
+	end, err := httputil.GetTime(r, "end", ct)
+	if err != nil {
+		return err
+	}
+	step, err := httputil.GetDuration(r, "step", defaultStep)
+	if err != nil {
+		return err
+	}
+	offset, err := httputil.GetDuration(r, "offset", 0)
+	if err != nil {
+		return err
+	}
+	if query2 == query && offset == 0 {
+		return fmt.Errorf("`query2` or `offset` arg must be set")
+	}
+	op := r.FormValue("op")
+	if op == "" {
+		op = "diff"
+	}
+	f, err := getQueryDiffFunc(op)
+	if err != nil {
+		return err
+	}
+	etfs, err := searchutil.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	if err := queryRangeDiffHandler(qt, startTime, w, query, query2, start, end, step, offset, f, r, ct, etfs); err != nil {
+		return fmt.Errorf("error when executing query=%q and query2=%q on the time range (start=%d, end=%d, step=%d, offset=%d): %w",
+			query, query2, start, end, step, offset, err)
+	}
+	return nil
+}
+
+var queryRangeDiffDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/query_range_diff"}`)
+
+func queryRangeDiffHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, query, query2 string,
+	start, end, step, offset int64, f queryDiffFunc, r *http.Request, ct int64, etfs [][]storage.TagFilter) error {
+	deadline := searchutil.GetDeadlineForQuery(r, startTime)
+	mayCache := !httputil.GetBool(r, "nocache")
+	lookbackDelta, err := getMaxLookback(r)
+	if err != nil {
+		return err
+	}
+	ioClass, err := searchutil.GetIOClass(r, storage.IOClassInteractive)
+	if err != nil {
+		return err
+	}
+
+	// Validate input args.
+	if n := max(len(query), len(query2)); n > maxQueryLen.IntN() {
+		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", n, maxQueryLen.N)
+	}
+	if start > end {
+		end = start + defaultStep
+	}
+	if err := promql.ValidateMaxPointsPerSeries(start, end, step, *maxPointsPerTimeseries); err != nil {
+		return fmt.Errorf("%w; (see -search.maxPointsPerTimeseries command-line flag)", err)
+	}
+	if mayCache {
+		start, end = promql.AdjustStartEnd(start, end, step)
+	}
+
+	qs := &promql.QueryStats{}
+	newEvalConfig := func(start, end int64) *promql.EvalConfig {
+		return &promql.EvalConfig{
+			Start:               start,
+			End:                 end,
+			Step:                step,
+			MaxPointsPerSeries:  *maxPointsPerTimeseries,
+			MaxSeries:           GetMaxUniqueTimeSeries(),
+			QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
+			Deadline:            deadline,
+			MayCache:            mayCache,
+			LookbackDelta:       lookbackDelta,
+			RoundDigits:         getRoundDigits(r),
+			EnforcedTagFilterss: etfs,
+			IOClass:             ioClass,
+			GetRequestURI: func() string {
+				return httpserver.GetRequestURI(r)
+			},
+
+			QueryStats: qs,
+		}
+	}
+	result, err := promql.Exec(qt, newEvalConfig(start, end), query, false)
+	if err != nil {
+		return err
+	}
+	if step < maxStepForPointsAdjustment.Milliseconds() {
+		queryOffset, err := getLatencyOffsetMilliseconds(r)
+		if err != nil {
+			return err
+		}
+		if ct-queryOffset < end {
+			result = adjustLastPoints(result, ct-queryOffset, ct+step)
+		}
+	}
+
+	result2, err := promql.Exec(qt, newEvalConfig(start-offset, end-offset), query2, false)
+	if err != nil {
+		return fmt.Errorf("cannot execute query2: %w", err)
+	}
+
+	result = diffQueryResults(result, result2, f)
+
+	// Remove NaN values as Prometheus does.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/153
+	result = removeEmptyValuesAndTimeseries(result)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("start=%d, end=%d, step=%d, offset=%d, query=%q, query2=%q: series=%d", start, end, step, offset, query, query2, len(result))
+	}
+	WriteQueryRangeResponse(bw, result, qt, qtDone, qs, nil)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send query range diff response to remote client: %w", err)
+	}
+	return nil
+}
+
+type queryDiffFunc func(a, b float64) float64
+
+func getQueryDiffFunc(op string) (queryDiffFunc, error) {
+	switch op {
+	case "diff":
+		return func(a, b float64) float64 {
+			return a - b
+		}, nil
+	case "ratio":
+		return func(a, b float64) float64 {
+			return a / b
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported `op`=%q; supported values: diff, ratio", op)
+	}
+}
+
+// diffQueryResults applies f to values from rs and rs2 series with identical labels.
+//
+// Series are matched by labels without metric names in the same way as binary operations do in PromQL,
+// while points are matched by their position, since both rs and rs2 are evaluated with identical step and the number of points.
+// Series without a match are dropped.
+func diffQueryResults(rs, rs2 []netstorage.Result, f queryDiffFunc) []netstorage.Result {
+	m := make(map[string]*netstorage.Result, len(rs2))
+	for i := range rs2 {
+		r := &rs2[i]
+		r.MetricName.ResetMetricGroup()
+		m[r.MetricName.String()] = r
+	}
+
+	dst := rs[:0]
+	for i := range rs {
+		r := &rs[i]
+		r.MetricName.ResetMetricGroup()
+		r2 := m[r.MetricName.String()]
+		if r2 == nil {
+			continue
+		}
+		values := r.Values
+		for j := range values {
+			if j >= len(r2.Values) || math.IsNaN(values[j]) || math.IsNaN(r2.Values[j]) {
+				values[j] = nan
+				continue
+			}
+			values[j] = f(values[j], r2.Values[j])
+		}
+		dst = append(dst, *r)
+	}
+	return dst
+}
@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+)
+
+func TestDiffQueryResults(t *testing.T) {
+	newResult := func(metricGroup string, tags map[string]string, values ...float64) netstorage.Result {
+		var r netstorage.Result
+		r.MetricName.MetricGroup = []byte(metricGroup)
+		for k, v := range tags {
+			r.MetricName.AddTag(k, v)
+		}
+		r.Values = values
+		for i := range values {
+			r.Timestamps = append(r.Timestamps, int64(i+1)*1000)
+		}
+		return r
+	}
+
+	f := func(op string, rs, rs2 []netstorage.Result, metricNamesExpected []string, valuesExpected [][]float64) {
+		t.Helper()
+
+		qdf, err := getQueryDiffFunc(op)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := diffQueryResults(rs, rs2, qdf)
+		if len(result) != len(metricNamesExpected) {
+			t.Fatalf("unexpected number of series; got %d; want %d", len(result), len(metricNamesExpected))
+		}
+		for i := range result {
+			r := &result[i]
+			if s := r.MetricName.String(); s != metricNamesExpected[i] {
+				t.Fatalf("unexpected metric name for series #%d; got %s; want %s", i, s, metricNamesExpected[i])
+			}
+			if len(r.Values) != len(valuesExpected[i]) {
+				t.Fatalf("unexpected number of values for series #%d; got %d; want %d", i, len(r.Values), len(valuesExpected[i]))
+			}
+			for j, v := range r.Values {
+				vExpected := valuesExpected[i][j]
+				if math.IsNaN(vExpected) {
+					if !math.IsNaN(v) {
+						t.Fatalf("unexpected value #%d for series #%d; got %v; want NaN", j, i, v)
+					}
+					continue
+				}
+				if v != vExpected {
+					t.Fatalf("unexpected value #%d for series #%d; got %v; want %v", j, i, v, vExpected)
+				}
+			}
+		}
+	}
+
+	// empty results
+	f("diff", nil, nil, nil, nil)
+	f("diff", []netstorage.Result{newResult("foo", nil, 1, 2)}, nil, nil, nil)
+
+	// series are matched by labels without metric names
+	f("diff", []netstorage.Result{
+		newResult("foo", map[string]string{"job": "a"}, 10, 20, nan),
+		newResult("foo", map[string]string{"job": "b"}, 1, 2, 3),
+		newResult("foo", map[string]string{"job": "c"}, 1, 2, 3),
+	}, []netstorage.Result{
+		newResult("bar", map[string]string{"job": "b"}, 1, 1, 1),
+		newResult("bar", map[string]string{"job": "a"}, 5, nan, 5),
+	}, []string{
+		`{job="a"}`,
+		`{job="b"}`,
+	}, [][]float64{
+		{5, nan, nan},
+		{0, 1, 2},
+	})
+
+	// ratio
+	f("ratio", []netstorage.Result{
+		newResult("foo", map[string]string{"job": "a", "instance": "x"}, 10, 20, 0),
+	}, []netstorage.Result{
+		newResult("foo", map[string]string{"instance": "x", "job": "a"}, 5, 10, 0),
+	}, []string{
+		`{job="a",instance="x"}`,
+	}, [][]float64{
+		{2, 2, nan},
+	})
+}
+
+func TestGetQueryDiffFuncFailure(t *testing.T) {
+	if _, err := getQueryDiffFunc("foobar"); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
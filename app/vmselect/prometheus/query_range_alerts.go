@@ -0,0 +1,189 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	vmalertAlertsURLs = flagutil.NewArrayString("vmalert.alertsURL", "Optional comma-separated list of vmalert URLs for fetching firing alerts. "+
+		"Firing alerts overlapping the requested time range are included into /api/v1/query_range responses if `alerts=1` query arg is set. "+
+		"See https://docs.victoriametrics.com/#alerts-in-range-query-responses")
+	vmalertAlertsCacheDuration = flag.Duration("vmalert.alertsCacheDuration", 15*time.Second, "The duration for caching firing alerts fetched from -vmalert.alertsURL")
+	vmalertAlertsTimeout       = flag.Duration("vmalert.alertsTimeout", 5*time.Second, "Timeout for fetching firing alerts from -vmalert.alertsURL")
+)
+
+// rangeAlert is a firing alert obtained from vmalert.
+//
+// See https://docs.victoriametrics.com/vmalert/#web
+type rangeAlert struct {
+	Name        string            `json:"name"`
+	State       string            `json:"state"`
+	Value       string            `json:"value"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	ActiveAt    time.Time         `json:"activeAt"`
+}
+
+// getFiringAlerts returns firing alerts from -vmalert.alertsURL, which overlap the time range ending at end in milliseconds.
+//
+// Firing alerts are active until now, so they overlap the time range if they became active before its end.
+// Alerts are cached for -vmalert.alertsCacheDuration. Errors from vmalert are logged and don't prevent the query from execution.
+func getFiringAlerts(end int64) ([]*rangeAlert, error) {
+	if len(*vmalertAlertsURLs) == 0 {
+		return nil, fmt.Errorf("`alerts` query arg requires -vmalert.alertsURL command-line flag to be set")
+	}
+	caches := getVMAlertAlertsCaches()
+	alertss := make([][]*rangeAlert, len(caches))
+	var wg sync.WaitGroup
+	for i, c := range caches {
+		wg.Add(1)
+		go func(i int, c *vmalertAlertsCache) {
+			defer wg.Done()
+			alertss[i] = c.getAlerts()
+		}(i, c)
+	}
+	wg.Wait()
+
+	alerts := make([]*rangeAlert, 0)
+	for _, as := range alertss {
+		alerts = appendOverlappingAlerts(alerts, as, end)
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if !alerts[i].ActiveAt.Equal(alerts[j].ActiveAt) {
+			return alerts[i].ActiveAt.Before(alerts[j].ActiveAt)
+		}
+		return alerts[i].Name < alerts[j].Name
+	})
+	return alerts, nil
+}
+
+func appendOverlappingAlerts(dst, alerts []*rangeAlert, end int64) []*rangeAlert {
+	for _, a := range alerts {
+		if a.State != "firing" {
+			continue
+		}
+		if a.ActiveAt.UnixMilli() > end {
+			continue
+		}
+		dst = append(dst, a)
+	}
+	return dst
+}
+
+// vmalertAlertsCache caches alerts obtained from a single vmalert.
+type vmalertAlertsCache struct {
+	url string
+
+	mu         sync.Mutex
+	alerts     []*rangeAlert
+	lastUpdate time.Time
+}
+
+func (c *vmalertAlertsCache) getAlerts() []*rangeAlert {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastUpdate) < *vmalertAlertsCacheDuration {
+		return c.alerts
+	}
+	// Update lastUpdate on errors too, so unavailable vmalert isn't queried on every request.
+	c.lastUpdate = time.Now()
+	alerts, err := fetchVMAlertAlerts(c.url)
+	if err != nil {
+		vmalertAlertsFetchErrors.Inc()
+		vmalertAlertsLogger.Warnf("cannot fetch alerts from -vmalert.alertsURL=%q; using the previously fetched alerts: %s", c.url, err)
+		return c.alerts
+	}
+	c.alerts = alerts
+	return alerts
+}
+
+var (
+	vmalertAlertsFetchErrors = metrics.NewCounter(`vm_vmalert_alerts_fetch_errors_total`)
+	vmalertAlertsLogger      = logger.WithThrottler("vmalertAlerts", 5*time.Second)
+)
+
+var (
+	vmalertAlertsCaches     []*vmalertAlertsCache
+	vmalertAlertsCachesOnce sync.Once
+
+	vmalertAlertsClient *http.Client
+)
+
+func getVMAlertAlertsCaches() []*vmalertAlertsCache {
+	vmalertAlertsCachesOnce.Do(func() {
+		for _, u := range *vmalertAlertsURLs {
+			vmalertAlertsCaches = append(vmalertAlertsCaches, &vmalertAlertsCache{
+				url: strings.TrimSuffix(u, "/") + "/api/v1/alerts",
+			})
+		}
+		vmalertAlertsClient = &http.Client{
+			Transport: httputil.NewTransport(false, "vm_vmalert_alerts"),
+			Timeout:   *vmalertAlertsTimeout,
+		}
+	})
+	return vmalertAlertsCaches
+}
+
+func fetchVMAlertAlerts(url string) ([]*rangeAlert, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *vmalertAlertsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	resp, err := vmalertAlertsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform request: %w", err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d; want %d; response body: %q", resp.StatusCode, http.StatusOK, data)
+	}
+	return parseVMAlertAlerts(data)
+}
+
+// parseVMAlertAlerts parses alerts from vmalert /api/v1/alerts response.
+func parseVMAlertAlerts(data []byte) ([]*rangeAlert, error) {
+	var r struct {
+		Status string `json:"status"`
+		Data   struct {
+			Alerts []*rangeAlert `json:"alerts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("cannot parse response: %w", err)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("unexpected status in response: %q; want %q", r.Status, "success")
+	}
+	return r.Data.Alerts, nil
+}
+
+func getSortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
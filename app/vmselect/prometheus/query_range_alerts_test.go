@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+func TestParseVMAlertAlertsFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		_, err := parseVMAlertAlerts([]byte(data))
+		if err == nil {
+			t.Fatalf("expecting non-nil error when parsing %q", data)
+		}
+	}
+
+	f(``)
+	f(`foo`)
+	f(`{"status":"error","errorType":"server_error","error":"foo"}`)
+	f(`{"status":"success","data":{"alerts":"foo"}}`)
+}
+
+func TestGetFiringAlertsResponse(t *testing.T) {
+	data := `{"status":"success","data":{"alerts":[
+{"state":"firing","name":"HighLatency","value":"1.5","labels":{"job":"api","alertname":"HighLatency"},"annotations":{"summary":"latency is \"high\""},"activeAt":"2024-01-01T00:10:00Z","id":"1"},
+{"state":"pending","name":"HighErrors","value":"0.1","labels":{"alertname":"HighErrors"},"annotations":{},"activeAt":"2024-01-01T00:05:00Z"},
+{"state":"firing","name":"Future","value":"1","labels":{"alertname":"Future"},"annotations":{},"activeAt":"2024-01-01T02:00:00Z"},
+{"state":"firing","name":"InstanceDown","value":"0","labels":{"alertname":"InstanceDown"},"annotations":{},"activeAt":"2023-12-31T23:00:00Z"}
+]}}`
+	alerts, err := parseVMAlertAlerts([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(alerts) != 4 {
+		t.Fatalf("unexpected number of alerts; got %d; want 4", len(alerts))
+	}
+
+	end := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC).UnixMilli()
+	alerts = appendOverlappingAlerts(nil, alerts, end)
+
+	qs := &promql.QueryStats{}
+	qt := querytracer.New(false, "")
+	result := QueryRangeResponse(nil, qt, func() {}, qs, alerts)
+	if !json.Valid([]byte(result)) {
+		t.Fatalf("invalid json response: %s", result)
+	}
+	resultExpected := `{"status":"success","data":{"resultType":"matrix","result":[]},"alerts":[` +
+		`{"name":"HighLatency","labels":{"alertname":"HighLatency","job":"api"},"annotations":{"summary":"latency is \"high\""},"value":"1.5","activeAt":1704067800},` +
+		`{"name":"InstanceDown","labels":{"alertname":"InstanceDown"},"annotations":{},"value":"0","activeAt":1704063600}` +
+		`],"stats":{"seriesFetched": "0","executionTimeMsec":0}}`
+	if result != resultExpected {
+		t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+
+	// Alerts must be missing in the response if they aren't requested.
+	result = QueryRangeResponse(nil, qt, func() {}, qs, nil)
+	resultExpected = `{"status":"success","data":{"resultType":"matrix","result":[]},"stats":{"seriesFetched": "0","executionTimeMsec":0}}`
+	if result != resultExpected {
+		t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
@@ -0,0 +1,235 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/VictoriaMetrics/easyproto"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// queryRangeResponseFormat is the response format for /api/v1/query_range.
+//
+// See https://docs.victoriametrics.com/#binary-responses-for-range-queries
+type queryRangeResponseFormat int
+
+const (
+	queryRangeResponseFormatJSON queryRangeResponseFormat = iota
+	queryRangeResponseFormatProtobuf
+	queryRangeResponseFormatMsgpack
+)
+
+func (f queryRangeResponseFormat) contentType() string {
+	switch f {
+	case queryRangeResponseFormatProtobuf:
+		return "application/x-protobuf"
+	case queryRangeResponseFormatMsgpack:
+		return "application/msgpack"
+	default:
+		return "application/json"
+	}
+}
+
+// getQueryRangeResponseFormat returns the response format requested via `Accept` header of r.
+//
+// The first supported media type from `Accept` header is used. JSON is returned if there are no supported media types.
+func getQueryRangeResponseFormat(r *http.Request) queryRangeResponseFormat {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return queryRangeResponseFormatJSON
+	}
+	for _, s := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(s, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			return queryRangeResponseFormatJSON
+		case "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf":
+			return queryRangeResponseFormatProtobuf
+		case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+			return queryRangeResponseFormatMsgpack
+		}
+	}
+	return queryRangeResponseFormatJSON
+}
+
+// writeQueryRangeResponseProtobuf writes rs to w in protobuf format according to the following schema:
+//
+//	message QueryRangeResponse {
+//	  repeated Series series = 1;
+//	  uint64 series_fetched = 2;
+//	  int64 execution_time_msec = 3;
+//	}
+//
+//	message Series {
+//	  repeated Label labels = 1;
+//	  repeated int64 timestamps = 2;  // in milliseconds
+//	  repeated double values = 3;
+//	}
+//
+//	message Label {
+//	  string name = 1;
+//	  string value = 2;
+//	}
+//
+// Series are marshaled one by one in order to avoid building the whole response in memory.
+func writeQueryRangeResponseProtobuf(w io.Writer, rs []netstorage.Result, qs *promql.QueryStats) {
+	m := protobufMarshalerPool.Get()
+	defer protobufMarshalerPool.Put(m)
+
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+
+	for i := range rs {
+		r := &rs[i]
+		m.Reset()
+		mm := m.MessageMarshaler()
+		if len(r.MetricName.MetricGroup) > 0 {
+			appendProtobufLabel(mm, "__name__", bytesutil.ToUnsafeString(r.MetricName.MetricGroup))
+		}
+		for j := range r.MetricName.Tags {
+			tag := &r.MetricName.Tags[j]
+			appendProtobufLabel(mm, bytesutil.ToUnsafeString(tag.Key), bytesutil.ToUnsafeString(tag.Value))
+		}
+		mm.AppendInt64s(2, r.Timestamps)
+		mm.AppendDoubles(3, r.Values)
+
+		// Append `series` field with length-delimited wire type.
+		bb.B = binary.AppendUvarint(bb.B[:0], 1<<3|2)
+		bb.B = m.MarshalWithLen(bb.B)
+		_, _ = w.Write(bb.B)
+	}
+
+	m.Reset()
+	mm := m.MessageMarshaler()
+	mm.AppendUint64(2, uint64(qs.SeriesFetched.Load()))
+	mm.AppendInt64(3, qs.ExecutionTimeMsec.Load())
+	bb.B = m.Marshal(bb.B[:0])
+	_, _ = w.Write(bb.B)
+}
+
+func appendProtobufLabel(mm *easyproto.MessageMarshaler, name, value string) {
+	label := mm.AppendMessage(1)
+	label.AppendString(1, name)
+	label.AppendString(2, value)
+}
+
+var protobufMarshalerPool easyproto.MarshalerPool
+
+// writeQueryRangeResponseMsgpack writes rs to w in msgpack format.
+//
+// The response has the same structure as JSON response for /api/v1/query_range,
+// except of timestamps and values, which are encoded as float64 numbers instead of strings.
+// Timestamps are in seconds like in JSON response.
+func writeQueryRangeResponseMsgpack(w io.Writer, rs []netstorage.Result, qs *promql.QueryStats) {
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+
+	dst := bb.B[:0]
+	dst = appendMsgpackMapHeader(dst, 3)
+	dst = appendMsgpackString(dst, "status")
+	dst = appendMsgpackString(dst, "success")
+	dst = appendMsgpackString(dst, "data")
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "resultType")
+	dst = appendMsgpackString(dst, "matrix")
+	dst = appendMsgpackString(dst, "result")
+	dst = appendMsgpackArrayHeader(dst, len(rs))
+	for i := range rs {
+		r := &rs[i]
+		dst = appendMsgpackMapHeader(dst, 2)
+		dst = appendMsgpackString(dst, "metric")
+		dst = appendMsgpackMetricName(dst, &r.MetricName)
+		dst = appendMsgpackString(dst, "values")
+		dst = appendMsgpackArrayHeader(dst, len(r.Values))
+		for j, v := range r.Values {
+			dst = appendMsgpackArrayHeader(dst, 2)
+			dst = appendMsgpackFloat64(dst, float64(r.Timestamps[j])/1e3)
+			dst = appendMsgpackFloat64(dst, v)
+		}
+		if len(dst) >= 64*1024 {
+			_, _ = w.Write(dst)
+			dst = dst[:0]
+		}
+	}
+	dst = appendMsgpackString(dst, "stats")
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "seriesFetched")
+	dst = appendMsgpackInt64(dst, qs.SeriesFetched.Load())
+	dst = appendMsgpackString(dst, "executionTimeMsec")
+	dst = appendMsgpackInt64(dst, qs.ExecutionTimeMsec.Load())
+	_, _ = w.Write(dst)
+	bb.B = dst
+}
+
+func appendMsgpackMetricName(dst []byte, mn *storage.MetricName) []byte {
+	n := len(mn.Tags)
+	if len(mn.MetricGroup) > 0 {
+		n++
+	}
+	dst = appendMsgpackMapHeader(dst, n)
+	if len(mn.MetricGroup) > 0 {
+		dst = appendMsgpackString(dst, "__name__")
+		dst = appendMsgpackString(dst, bytesutil.ToUnsafeString(mn.MetricGroup))
+	}
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		dst = appendMsgpackString(dst, bytesutil.ToUnsafeString(tag.Key))
+		dst = appendMsgpackString(dst, bytesutil.ToUnsafeString(tag.Value))
+	}
+	return dst
+}
+
+// See https://github.com/msgpack/msgpack/blob/master/spec.md for msgpack format details.
+
+func appendMsgpackMapHeader(dst []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(dst, 0x80|byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(dst, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(dst, 0xdf), uint32(n))
+	}
+}
+
+func appendMsgpackArrayHeader(dst []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(dst, 0x90|byte(n))
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16(append(dst, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(dst, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackString(dst []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		dst = append(dst, 0xa0|byte(n))
+	case n < 1<<8:
+		dst = append(dst, 0xd9, byte(n))
+	case n < 1<<16:
+		dst = binary.BigEndian.AppendUint16(append(dst, 0xda), uint16(n))
+	default:
+		dst = binary.BigEndian.AppendUint32(append(dst, 0xdb), uint32(n))
+	}
+	return append(dst, s...)
+}
+
+func appendMsgpackFloat64(dst []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(dst, 0xcb), math.Float64bits(f))
+}
+
+func appendMsgpackInt64(dst []byte, n int64) []byte {
+	return binary.BigEndian.AppendUint64(append(dst, 0xd3), uint64(n))
+}
@@ -0,0 +1,254 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/easyproto"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestGetQueryRangeResponseFormat(t *testing.T) {
+	f := func(accept string, formatExpected queryRangeResponseFormat) {
+		t.Helper()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost/api/v1/query_range", nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		format := getQueryRangeResponseFormat(r)
+		if format != formatExpected {
+			t.Fatalf("unexpected format for Accept=%q; got %d; want %d", accept, format, formatExpected)
+		}
+	}
+
+	f("", queryRangeResponseFormatJSON)
+	f("*/*", queryRangeResponseFormatJSON)
+	f("application/json", queryRangeResponseFormatJSON)
+	f("text/html", queryRangeResponseFormatJSON)
+	f("application/x-protobuf", queryRangeResponseFormatProtobuf)
+	f("application/vnd.google.protobuf;proto=QueryRangeResponse", queryRangeResponseFormatProtobuf)
+	f("application/msgpack", queryRangeResponseFormatMsgpack)
+	f("Application/X-Msgpack", queryRangeResponseFormatMsgpack)
+
+	// The first supported media type is used
+	f("text/html, application/msgpack, application/json", queryRangeResponseFormatMsgpack)
+	f("application/json, application/x-protobuf", queryRangeResponseFormatJSON)
+	f("application/x-protobuf;q=1, */*;q=0.1", queryRangeResponseFormatProtobuf)
+}
+
+func newTestQueryRangeResult() ([]netstorage.Result, *promql.QueryStats) {
+	rs := []netstorage.Result{
+		{
+			MetricName: storage.MetricName{
+				MetricGroup: []byte("foo"),
+				Tags: []storage.Tag{
+					{Key: []byte("job"), Value: []byte("bar")},
+				},
+			},
+			Values:     []float64{1, 2.5},
+			Timestamps: []int64{1000, 2500},
+		},
+		{
+			MetricName: storage.MetricName{
+				Tags: []storage.Tag{
+					{Key: []byte("instance"), Value: []byte("baz")},
+				},
+			},
+			Values:     []float64{-3},
+			Timestamps: []int64{3000},
+		},
+	}
+	qs := &promql.QueryStats{}
+	qs.SeriesFetched.Store(2)
+	qs.ExecutionTimeMsec.Store(15)
+	return rs, qs
+}
+
+func TestWriteQueryRangeResponseProtobuf(t *testing.T) {
+	rs, qs := newTestQueryRangeResult()
+
+	var bb bytes.Buffer
+	writeQueryRangeResponseProtobuf(&bb, rs, qs)
+
+	// Unmarshal the response into a human-readable form
+	var result []string
+	var fc easyproto.FieldContext
+	src := bb.Bytes()
+	for len(src) > 0 {
+		var err error
+		src, err = fc.NextField(src)
+		if err != nil {
+			t.Fatalf("cannot read next field: %s", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				t.Fatalf("cannot read series data")
+			}
+			s, err := unmarshalProtobufSeries(data)
+			if err != nil {
+				t.Fatalf("cannot unmarshal series: %s", err)
+			}
+			result = append(result, s)
+		case 2:
+			v, ok := fc.Uint64()
+			if !ok {
+				t.Fatalf("cannot read series_fetched")
+			}
+			result = append(result, fmt.Sprintf("series_fetched=%d", v))
+		case 3:
+			v, ok := fc.Int64()
+			if !ok {
+				t.Fatalf("cannot read execution_time_msec")
+			}
+			result = append(result, fmt.Sprintf("execution_time_msec=%d", v))
+		}
+	}
+	resultExpected := []string{
+		`{__name__="foo",job="bar"} [1000 2500] [1 2.5]`,
+		`{instance="baz"} [3000] [-3]`,
+		"series_fetched=2",
+		"execution_time_msec=15",
+	}
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected result\ngot\n%q\nwant\n%q", result, resultExpected)
+	}
+}
+
+func unmarshalProtobufSeries(src []byte) (string, error) {
+	var labels []string
+	var timestamps []int64
+	var values []float64
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		var err error
+		src, err = fc.NextField(src)
+		if err != nil {
+			return "", err
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return "", fmt.Errorf("cannot read label data")
+			}
+			var name, value string
+			var lfc easyproto.FieldContext
+			for len(data) > 0 {
+				data, err = lfc.NextField(data)
+				if err != nil {
+					return "", err
+				}
+				switch lfc.FieldNum {
+				case 1:
+					name, _ = lfc.String()
+				case 2:
+					value, _ = lfc.String()
+				}
+			}
+			labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+		case 2:
+			var ok bool
+			timestamps, ok = fc.UnpackInt64s(timestamps)
+			if !ok {
+				return "", fmt.Errorf("cannot read timestamps")
+			}
+		case 3:
+			var ok bool
+			values, ok = fc.UnpackDoubles(values)
+			if !ok {
+				return "", fmt.Errorf("cannot read values")
+			}
+		}
+	}
+	return fmt.Sprintf("{%s} %v %v", strings.Join(labels, ","), timestamps, values), nil
+}
+
+func TestWriteQueryRangeResponseMsgpack(t *testing.T) {
+	rs, qs := newTestQueryRangeResult()
+
+	var bb bytes.Buffer
+	writeQueryRangeResponseMsgpack(&bb, rs, qs)
+
+	var dst []byte
+	dst = appendMsgpackMapHeader(dst, 3)
+	dst = appendMsgpackString(dst, "status")
+	dst = appendMsgpackString(dst, "success")
+	dst = appendMsgpackString(dst, "data")
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "resultType")
+	dst = appendMsgpackString(dst, "matrix")
+	dst = appendMsgpackString(dst, "result")
+	dst = appendMsgpackArrayHeader(dst, 2)
+
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "metric")
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "__name__")
+	dst = appendMsgpackString(dst, "foo")
+	dst = appendMsgpackString(dst, "job")
+	dst = appendMsgpackString(dst, "bar")
+	dst = appendMsgpackString(dst, "values")
+	dst = appendMsgpackArrayHeader(dst, 2)
+	dst = appendMsgpackArrayHeader(dst, 2)
+	dst = appendMsgpackFloat64(dst, 1)
+	dst = appendMsgpackFloat64(dst, 1)
+	dst = appendMsgpackArrayHeader(dst, 2)
+	dst = appendMsgpackFloat64(dst, 2.5)
+	dst = appendMsgpackFloat64(dst, 2.5)
+
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "metric")
+	dst = appendMsgpackMapHeader(dst, 1)
+	dst = appendMsgpackString(dst, "instance")
+	dst = appendMsgpackString(dst, "baz")
+	dst = appendMsgpackString(dst, "values")
+	dst = appendMsgpackArrayHeader(dst, 1)
+	dst = appendMsgpackArrayHeader(dst, 2)
+	dst = appendMsgpackFloat64(dst, 3)
+	dst = appendMsgpackFloat64(dst, -3)
+
+	dst = appendMsgpackString(dst, "stats")
+	dst = appendMsgpackMapHeader(dst, 2)
+	dst = appendMsgpackString(dst, "seriesFetched")
+	dst = appendMsgpackInt64(dst, 2)
+	dst = appendMsgpackString(dst, "executionTimeMsec")
+	dst = appendMsgpackInt64(dst, 15)
+
+	if !bytes.Equal(bb.Bytes(), dst) {
+		t.Fatalf("unexpected response\ngot\n%X\nwant\n%X", bb.Bytes(), dst)
+	}
+}
+
+func TestAppendMsgpackHeaders(t *testing.T) {
+	f := func(dst, dstExpected []byte) {
+		t.Helper()
+		if !bytes.Equal(dst, dstExpected) {
+			t.Fatalf("unexpected result\ngot\n%X\nwant\n%X", dst, dstExpected)
+		}
+	}
+
+	f(appendMsgpackMapHeader(nil, 0), []byte{0x80})
+	f(appendMsgpackMapHeader(nil, 15), []byte{0x8f})
+	f(appendMsgpackMapHeader(nil, 16), []byte{0xde, 0x00, 0x10})
+	f(appendMsgpackMapHeader(nil, 1<<16), []byte{0xdf, 0x00, 0x01, 0x00, 0x00})
+	f(appendMsgpackArrayHeader(nil, 3), []byte{0x93})
+	f(appendMsgpackArrayHeader(nil, 300), []byte{0xdc, 0x01, 0x2c})
+	f(appendMsgpackArrayHeader(nil, 1<<16), []byte{0xdd, 0x00, 0x01, 0x00, 0x00})
+	f(appendMsgpackString(nil, "ab"), []byte{0xa2, 'a', 'b'})
+	f(appendMsgpackString(nil, string(make([]byte, 32)))[:2], []byte{0xd9, 0x20})
+	f(appendMsgpackString(nil, string(make([]byte, 256)))[:3], []byte{0xda, 0x01, 0x00})
+	f(appendMsgpackFloat64(nil, 1), []byte{0xcb, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0})
+	f(appendMsgpackInt64(nil, -1), []byte{0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+}
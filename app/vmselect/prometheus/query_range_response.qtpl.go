@@ -27,7 +27,7 @@ var (
 )
 
 //line app/vmselect/prometheus/query_range_response.qtpl:10
-func StreamQueryRangeResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats) {
+func StreamQueryRangeResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats, alerts []*rangeAlert) {
 //line app/vmselect/prometheus/query_range_response.qtpl:10
 	qw422016.N().S(`{`)
 //line app/vmselect/prometheus/query_range_response.qtpl:13
@@ -60,95 +60,228 @@ func StreamQueryRangeResponse(qw422016 *qt422016.Writer, rs []netstorage.Result,
 //line app/vmselect/prometheus/query_range_response.qtpl:28
 	}
 //line app/vmselect/prometheus/query_range_response.qtpl:28
-	qw422016.N().S(`]},"stats":{`)
+	qw422016.N().S(`]},`)
+//line app/vmselect/prometheus/query_range_response.qtpl:31
+	if alerts != nil {
 //line app/vmselect/prometheus/query_range_response.qtpl:33
+		// alerts are set only if `alerts=1` query arg is passed.
+		// See https://docs.victoriametrics.com/#alerts-in-range-query-responses
+
+//line app/vmselect/prometheus/query_range_response.qtpl:35
+		qw422016.N().S(`"alerts":[`)
+//line app/vmselect/prometheus/query_range_response.qtpl:37
+		for i, a := range alerts {
+//line app/vmselect/prometheus/query_range_response.qtpl:38
+			streamrangeAlertObject(qw422016, a)
+//line app/vmselect/prometheus/query_range_response.qtpl:38
+			if i+1 < len(alerts) {
+//line app/vmselect/prometheus/query_range_response.qtpl:38
+				qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_range_response.qtpl:38
+			}
+//line app/vmselect/prometheus/query_range_response.qtpl:39
+		}
+//line app/vmselect/prometheus/query_range_response.qtpl:39
+		qw422016.N().S(`],`)
+//line app/vmselect/prometheus/query_range_response.qtpl:41
+	}
+//line app/vmselect/prometheus/query_range_response.qtpl:41
+	qw422016.N().S(`"stats":{`)
+//line app/vmselect/prometheus/query_range_response.qtpl:44
 	// seriesFetched is string instead of int because of historical reasons.
 	// It cannot be converted to int without breaking backwards compatibility at vmalert :(
 
-//line app/vmselect/prometheus/query_range_response.qtpl:35
+//line app/vmselect/prometheus/query_range_response.qtpl:46
 	qw422016.N().S(`"seriesFetched": "`)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line app/vmselect/prometheus/query_range_response.qtpl:47
 	qw422016.N().DL(qs.SeriesFetched.Load())
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line app/vmselect/prometheus/query_range_response.qtpl:47
 	qw422016.N().S(`","executionTimeMsec":`)
-//line app/vmselect/prometheus/query_range_response.qtpl:37
+//line app/vmselect/prometheus/query_range_response.qtpl:48
 	qw422016.N().DL(qs.ExecutionTimeMsec.Load())
-//line app/vmselect/prometheus/query_range_response.qtpl:37
+//line app/vmselect/prometheus/query_range_response.qtpl:48
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:40
+//line app/vmselect/prometheus/query_range_response.qtpl:51
 	qt.Printf("generate /api/v1/query_range response for series=%d, points=%d", seriesCount, pointsCount)
 	qtDone()
 
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line app/vmselect/prometheus/query_range_response.qtpl:54
 	streamdumpQueryTrace(qw422016, qt)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line app/vmselect/prometheus/query_range_response.qtpl:54
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:45
-func WriteQueryRangeResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats) {
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
+func WriteQueryRangeResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats, alerts []*rangeAlert) {
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
-	StreamQueryRangeResponse(qw422016, rs, qt, qtDone, qs)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
+	StreamQueryRangeResponse(qw422016, rs, qt, qtDone, qs, alerts)
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:45
-func QueryRangeResponse(rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats) string {
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
+func QueryRangeResponse(rs []netstorage.Result, qt *querytracer.Tracer, qtDone func(), qs *promql.QueryStats, alerts []*rangeAlert) string {
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/query_range_response.qtpl:45
-	WriteQueryRangeResponse(qb422016, rs, qt, qtDone, qs)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
+	WriteQueryRangeResponse(qb422016, rs, qt, qtDone, qs, alerts)
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 	return qs422016
-//line app/vmselect/prometheus/query_range_response.qtpl:45
+//line app/vmselect/prometheus/query_range_response.qtpl:56
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:47
+//line app/vmselect/prometheus/query_range_response.qtpl:58
 func streamqueryRangeLine(qw422016 *qt422016.Writer, r *netstorage.Result) {
-//line app/vmselect/prometheus/query_range_response.qtpl:47
+//line app/vmselect/prometheus/query_range_response.qtpl:58
 	qw422016.N().S(`{"metric":`)
-//line app/vmselect/prometheus/query_range_response.qtpl:49
+//line app/vmselect/prometheus/query_range_response.qtpl:60
 	streammetricNameObject(qw422016, &r.MetricName)
-//line app/vmselect/prometheus/query_range_response.qtpl:49
+//line app/vmselect/prometheus/query_range_response.qtpl:60
 	qw422016.N().S(`,"values":`)
-//line app/vmselect/prometheus/query_range_response.qtpl:50
+//line app/vmselect/prometheus/query_range_response.qtpl:61
 	streamvaluesWithTimestamps(qw422016, r.Values, r.Timestamps)
-//line app/vmselect/prometheus/query_range_response.qtpl:50
+//line app/vmselect/prometheus/query_range_response.qtpl:61
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 func writequeryRangeLine(qq422016 qtio422016.Writer, r *netstorage.Result) {
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 	streamqueryRangeLine(qw422016, r)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 func queryRangeLine(r *netstorage.Result) string {
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
 	writequeryRangeLine(qb422016, r)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:63
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_range_response.qtpl:63
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:63
+	return qs422016
+//line app/vmselect/prometheus/query_range_response.qtpl:63
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:65
+func streamrangeAlertObject(qw422016 *qt422016.Writer, a *rangeAlert) {
+//line app/vmselect/prometheus/query_range_response.qtpl:65
+	qw422016.N().S(`{"name":`)
+//line app/vmselect/prometheus/query_range_response.qtpl:67
+	qw422016.N().Q(a.Name)
+//line app/vmselect/prometheus/query_range_response.qtpl:67
+	qw422016.N().S(`,"labels":`)
+//line app/vmselect/prometheus/query_range_response.qtpl:68
+	streamstringsMapObject(qw422016, a.Labels)
+//line app/vmselect/prometheus/query_range_response.qtpl:68
+	qw422016.N().S(`,"annotations":`)
+//line app/vmselect/prometheus/query_range_response.qtpl:69
+	streamstringsMapObject(qw422016, a.Annotations)
+//line app/vmselect/prometheus/query_range_response.qtpl:69
+	qw422016.N().S(`,"value":`)
+//line app/vmselect/prometheus/query_range_response.qtpl:70
+	qw422016.N().Q(a.Value)
+//line app/vmselect/prometheus/query_range_response.qtpl:70
+	qw422016.N().S(`,"activeAt":`)
+//line app/vmselect/prometheus/query_range_response.qtpl:71
+	qw422016.N().DL(a.ActiveAt.Unix())
+//line app/vmselect/prometheus/query_range_response.qtpl:71
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+func writerangeAlertObject(qq422016 qtio422016.Writer, a *rangeAlert) {
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	streamrangeAlertObject(qw422016, a)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+func rangeAlertObject(a *rangeAlert) string {
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	writerangeAlertObject(qb422016, a)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+	return qs422016
+//line app/vmselect/prometheus/query_range_response.qtpl:73
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:75
+func streamstringsMapObject(qw422016 *qt422016.Writer, m map[string]string) {
+//line app/vmselect/prometheus/query_range_response.qtpl:75
+	qw422016.N().S(`{`)
+//line app/vmselect/prometheus/query_range_response.qtpl:77
+	keys := getSortedKeys(m)
+
+//line app/vmselect/prometheus/query_range_response.qtpl:78
+	for i, k := range keys {
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+		qw422016.N().Q(k)
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+		qw422016.N().S(`:`)
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+		qw422016.N().Q(m[k])
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+		if i+1 < len(keys) {
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_range_response.qtpl:79
+		}
+//line app/vmselect/prometheus/query_range_response.qtpl:80
+	}
+//line app/vmselect/prometheus/query_range_response.qtpl:80
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+func writestringsMapObject(qq422016 qtio422016.Writer, m map[string]string) {
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+	streamstringsMapObject(qw422016, m)
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+}
+
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+func stringsMapObject(m map[string]string) string {
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_range_response.qtpl:82
+	writestringsMapObject(qb422016, m)
+//line app/vmselect/prometheus/query_range_response.qtpl:82
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:82
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:82
 	return qs422016
-//line app/vmselect/prometheus/query_range_response.qtpl:52
+//line app/vmselect/prometheus/query_range_response.qtpl:82
 }
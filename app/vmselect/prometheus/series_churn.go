@@ -0,0 +1,219 @@
+package prometheus
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var maxSeriesChurnSeries = flag.Int("search.maxSeriesChurnSeries", 1e6, "The maximum number of time series per each time window, which can be processed "+
+	"during the call to /api/v1/status/series_churn. This option allows limiting memory usage. See https://docs.victoriametrics.com/#series-churn-explorer")
+
+// SeriesChurnHandler processes /api/v1/status/series_churn request.
+//
+// It returns series, which exist on the [start..end] time range but are missing on the [start-offset..end-offset] time range (appeared series)
+// and vice versa (disappeared series), grouped by metric names and label=value pairs.
+//
+// See https://docs.victoriametrics.com/#series-churn-explorer
+func SeriesChurnHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer seriesChurnDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParamsForLabelsAPI(r, startTime, true)
+	if err != nil {
+		return err
+	}
+	cp.deadline = searchutil.GetDeadlineForStatusRequest(r, startTime)
+	offset, err := httputil.GetDuration(r, "offset", 0)
+	if err != nil {
+		return err
+	}
+	if offset <= 0 {
+		return fmt.Errorf("`offset` arg must be set to positive duration")
+	}
+	topN := 10
+	if topNStr := r.FormValue("topN"); len(topNStr) > 0 {
+		n, err := strconv.Atoi(topNStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `topN` arg %q: %w", topNStr, err)
+		}
+		topN = max(n, 1)
+		if topN > *maxTSDBStatusTopNSeries {
+			topN = *maxTSDBStatusTopNSeries
+		}
+	}
+
+	sqA := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxSeriesChurnSeries)
+	metricNamesA, err := netstorage.SearchMetricNames(qt, sqA, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch time series for %q: %w", sqA, err)
+	}
+	sqB := storage.NewSearchQuery(cp.start-offset, cp.end-offset, cp.filterss, *maxSeriesChurnSeries)
+	metricNamesB, err := netstorage.SearchMetricNames(qt, sqB, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch time series for %q: %w", sqB, err)
+	}
+
+	sc, err := getSeriesChurn(metricNamesA, metricNamesB, topN)
+	if err != nil {
+		return err
+	}
+	qt.Printf("found %d appeared and %d disappeared series", sc.Appeared.TotalSeries, sc.Disappeared.TotalSeries)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("start=%d, end=%d, offset=%d", cp.start, cp.end, offset)
+	}
+	WriteSeriesChurnResponse(bw, sc, qt, qtDone)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send series churn response to remote client: %w", err)
+	}
+	return nil
+}
+
+var seriesChurnDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/series_churn"}`)
+
+// seriesChurn contains the difference between series on two time windows.
+type seriesChurn struct {
+	// SeriesCountA is the number of series on the first time window.
+	SeriesCountA uint64
+
+	// SeriesCountB is the number of series on the second time window.
+	SeriesCountB uint64
+
+	// Appeared contains series, which exist on the first time window, but are missing on the second time window.
+	Appeared seriesChurnStats
+
+	// Disappeared contains series, which exist on the second time window, but are missing on the first time window.
+	Disappeared seriesChurnStats
+}
+
+// seriesChurnStats contains stats for appeared or disappeared series.
+type seriesChurnStats struct {
+	// TotalSeries is the total number of series.
+	TotalSeries uint64
+
+	// SeriesCountByLabelValuePair contains top label=value pairs across all the series.
+	SeriesCountByLabelValuePair []storage.TopHeapEntry
+
+	// Metrics contains top metric names by the number of series.
+	Metrics []seriesChurnMetric
+}
+
+// seriesChurnMetric contains stats for appeared or disappeared series with the given metric name.
+type seriesChurnMetric struct {
+	// Name is the metric name.
+	Name string
+
+	// SeriesCount is the number of series with the given metric name.
+	SeriesCount uint64
+
+	// SeriesCountByLabelValuePair contains top label=value pairs for series with the given metric name.
+	SeriesCountByLabelValuePair []storage.TopHeapEntry
+}
+
+// getSeriesChurn returns the difference between marshaled metric names on two time windows.
+//
+// Only topN metric names and topN label=value pairs are returned per each group.
+func getSeriesChurn(metricNamesA, metricNamesB []string, topN int) (*seriesChurn, error) {
+	sc := &seriesChurn{
+		SeriesCountA: uint64(len(metricNamesA)),
+		SeriesCountB: uint64(len(metricNamesB)),
+	}
+	if err := sc.Appeared.init(metricNamesA, metricNamesB, topN); err != nil {
+		return nil, err
+	}
+	if err := sc.Disappeared.init(metricNamesB, metricNamesA, topN); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// init initializes scs from metricNames missing in metricNamesExclude.
+func (scs *seriesChurnStats) init(metricNames, metricNamesExclude []string, topN int) error {
+	exclude := make(map[string]struct{}, len(metricNamesExclude))
+	for _, metricName := range metricNamesExclude {
+		exclude[metricName] = struct{}{}
+	}
+
+	type metricStats struct {
+		seriesCount uint64
+		labelValues map[string]uint64
+	}
+	byMetricName := make(map[string]*metricStats)
+	labelValues := make(map[string]uint64)
+	var mn storage.MetricName
+	for _, metricName := range metricNames {
+		if _, ok := exclude[metricName]; ok {
+			continue
+		}
+		if err := mn.UnmarshalString(metricName); err != nil {
+			return fmt.Errorf("cannot unmarshal metric name: %w", err)
+		}
+		scs.TotalSeries++
+
+		ms := byMetricName[string(mn.MetricGroup)]
+		if ms == nil {
+			ms = &metricStats{
+				labelValues: make(map[string]uint64),
+			}
+			byMetricName[string(mn.MetricGroup)] = ms
+		}
+		ms.seriesCount++
+		for _, tag := range mn.Tags {
+			labelValue := string(tag.Key) + "=" + string(tag.Value)
+			ms.labelValues[labelValue]++
+			labelValues[labelValue]++
+		}
+	}
+
+	scs.SeriesCountByLabelValuePair = getTopEntries(labelValues, topN)
+	metricNameCounts := make(map[string]uint64, len(byMetricName))
+	for name, ms := range byMetricName {
+		metricNameCounts[name] = ms.seriesCount
+	}
+	for _, e := range getTopEntries(metricNameCounts, topN) {
+		scs.Metrics = append(scs.Metrics, seriesChurnMetric{
+			Name:                        e.Name,
+			SeriesCount:                 e.Count,
+			SeriesCountByLabelValuePair: getTopEntries(byMetricName[e.Name].labelValues, topN),
+		})
+	}
+	return nil
+}
+
+// getTopEntries returns up to topN entries from m with the biggest counts.
+//
+// Entries with equal counts are sorted by name.
+func getTopEntries(m map[string]uint64, topN int) []storage.TopHeapEntry {
+	a := make([]storage.TopHeapEntry, 0, len(m))
+	for name, count := range m {
+		a = append(a, storage.TopHeapEntry{
+			Name:  name,
+			Count: count,
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].Count != a[j].Count {
+			return a[i].Count > a[j].Count
+		}
+		return a[i].Name < a[j].Name
+	})
+	if len(a) > topN {
+		a = a[:topN]
+	}
+	return a
+}
@@ -0,0 +1,79 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestGetSeriesChurn(t *testing.T) {
+	// newMetricNames returns marshaled metric names for the given series in the form of name, job, pod.
+	newMetricNames := func(series ...[3]string) []string {
+		var a []string
+		for _, labels := range series {
+			var mn storage.MetricName
+			mn.MetricGroup = []byte(labels[0])
+			mn.AddTag("job", labels[1])
+			mn.AddTag("pod", labels[2])
+			a = append(a, string(mn.Marshal(nil)))
+		}
+		return a
+	}
+
+	f := func(metricNamesA, metricNamesB []string, topN int, resultExpected string) {
+		t.Helper()
+
+		sc, err := getSeriesChurn(metricNamesA, metricNamesB, topN)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := SeriesChurnResponse(sc, nil, func() {})
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// empty windows
+	f(nil, nil, 10, `{"status":"success","data":{"seriesCountA":0,"seriesCountB":0,`+
+		`"appeared":{"totalSeries":0,"seriesCountByLabelValuePair":[],"seriesCountByMetricName":[]},`+
+		`"disappeared":{"totalSeries":0,"seriesCountByLabelValuePair":[],"seriesCountByMetricName":[]}}}`)
+
+	// identical windows
+	mns := newMetricNames(
+		[3]string{"foo", "a", "p1"},
+		[3]string{"bar", "a", "p1"},
+	)
+	f(mns, mns, 10, `{"status":"success","data":{"seriesCountA":2,"seriesCountB":2,`+
+		`"appeared":{"totalSeries":0,"seriesCountByLabelValuePair":[],"seriesCountByMetricName":[]},`+
+		`"disappeared":{"totalSeries":0,"seriesCountByLabelValuePair":[],"seriesCountByMetricName":[]}}}`)
+
+	// appeared and disappeared series
+	mnsA := newMetricNames(
+		[3]string{"foo", "a", "p1"},
+		[3]string{"foo", "a", "p3"},
+		[3]string{"foo", "a", "p4"},
+		[3]string{"bar", "b", "p3"},
+	)
+	mnsB := newMetricNames(
+		[3]string{"foo", "a", "p1"},
+		[3]string{"foo", "a", "p2"},
+	)
+	f(mnsA, mnsB, 10, `{"status":"success","data":{"seriesCountA":4,"seriesCountB":2,`+
+		`"appeared":{"totalSeries":3,`+
+		`"seriesCountByLabelValuePair":[{"name":"job=a","value":2},{"name":"pod=p3","value":2},{"name":"job=b","value":1},{"name":"pod=p4","value":1}],`+
+		`"seriesCountByMetricName":[`+
+		`{"name":"foo","value":2,"seriesCountByLabelValuePair":[{"name":"job=a","value":2},{"name":"pod=p3","value":1},{"name":"pod=p4","value":1}]},`+
+		`{"name":"bar","value":1,"seriesCountByLabelValuePair":[{"name":"job=b","value":1},{"name":"pod=p3","value":1}]}]},`+
+		`"disappeared":{"totalSeries":1,`+
+		`"seriesCountByLabelValuePair":[{"name":"job=a","value":1},{"name":"pod=p2","value":1}],`+
+		`"seriesCountByMetricName":[{"name":"foo","value":1,"seriesCountByLabelValuePair":[{"name":"job=a","value":1},{"name":"pod=p2","value":1}]}]}}}`)
+
+	// topN limits the number of returned entries
+	f(mnsA, mnsB, 1, `{"status":"success","data":{"seriesCountA":4,"seriesCountB":2,`+
+		`"appeared":{"totalSeries":3,`+
+		`"seriesCountByLabelValuePair":[{"name":"job=a","value":2}],`+
+		`"seriesCountByMetricName":[{"name":"foo","value":2,"seriesCountByLabelValuePair":[{"name":"job=a","value":2}]}]},`+
+		`"disappeared":{"totalSeries":1,`+
+		`"seriesCountByLabelValuePair":[{"name":"job=a","value":1}],`+
+		`"seriesCountByMetricName":[{"name":"foo","value":1,"seriesCountByLabelValuePair":[{"name":"job=a","value":1}]}]}}}`)
+}
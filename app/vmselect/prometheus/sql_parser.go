@@ -0,0 +1,892 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// sqlQuery is a parsed read-only SQL query over metrics.
+//
+// The supported dialect:
+//
+//	SELECT time_bucket('<duration>', time) [AS alias], label, ..., agg(value) [AS alias], ...
+//	FROM metrics
+//	WHERE name = '<metric_name>' AND time BETWEEN '<start>' AND '<end>' [AND <label_filter> ...]
+//	GROUP BY <time_bucket>, label, ...
+//	[ORDER BY column [ASC|DESC], ...]
+//	[LIMIT N]
+//
+// See https://docs.victoriametrics.com/#sql-query-api
+type sqlQuery struct {
+	columns []sqlColumn
+
+	metricName string
+	filters    []sqlFilter
+
+	// start and end are the time range in milliseconds. end is zero if the upper bound isn't set.
+	start int64
+	end   int64
+
+	// bucket is the time bucket duration in milliseconds.
+	bucket int64
+
+	// groupBy contains label names from GROUP BY clause.
+	groupBy []string
+
+	orderBy []sqlOrderBy
+
+	// limit is the maximum number of rows to return. Zero means no limit.
+	limit int
+}
+
+type sqlColumnKind int
+
+const (
+	sqlColumnTime sqlColumnKind = iota
+	sqlColumnLabel
+	sqlColumnAgg
+)
+
+// sqlColumn is a column from SELECT clause.
+type sqlColumn struct {
+	kind sqlColumnKind
+
+	// name is the name of the column in the response.
+	name string
+
+	// label is the label name for sqlColumnLabel.
+	label string
+
+	// aggFunc is the aggregate function name for sqlColumnAgg.
+	aggFunc string
+
+	// groupByIdx is the index of the label in GROUP BY clause for sqlColumnLabel.
+	groupByIdx int
+
+	// aggIdx is the index of the aggregate among all the aggregates in SELECT clause.
+	aggIdx int
+}
+
+func (c *sqlColumn) typ() string {
+	switch c.kind {
+	case sqlColumnTime:
+		return "timestamp"
+	case sqlColumnLabel:
+		return "string"
+	default:
+		return "float"
+	}
+}
+
+// sqlFilter is a label filter from WHERE clause.
+type sqlFilter struct {
+	label string
+
+	// op is MetricsQL label matching operator: =, !=, =~ or !~
+	op    string
+	value string
+}
+
+type sqlOrderBy struct {
+	columnIdx int
+	desc      bool
+}
+
+var sqlAggFuncs = map[string]struct{}{
+	"avg":   {},
+	"count": {},
+	"max":   {},
+	"min":   {},
+	"sum":   {},
+}
+
+// parseSQLQuery parses the given read-only SQL query. now is the current time in milliseconds.
+func parseSQLQuery(s string, now int64) (*sqlQuery, error) {
+	tokens, err := tokenizeSQL(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{
+		tokens: tokens,
+		now:    now,
+	}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse SQL query %q: %w", s, err)
+	}
+	return q, nil
+}
+
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+	now    int64
+}
+
+type sqlTokenKind int
+
+const (
+	sqlTokenEOF sqlTokenKind = iota
+	sqlTokenIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenPunct
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	s    string
+
+	// quoted is set to true for double-quoted identifiers, which mustn't be treated as keywords.
+	quoted bool
+}
+
+func tokenizeSQL(s string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			s = s[1:]
+		case strings.HasPrefix(s, "--"):
+			n := strings.IndexByte(s, '\n')
+			if n < 0 {
+				n = len(s)
+			}
+			s = s[n:]
+		case c == '\'' || c == '"':
+			v, tail, err := readSQLQuoted(s)
+			if err != nil {
+				return nil, err
+			}
+			if c == '\'' {
+				tokens = append(tokens, sqlToken{kind: sqlTokenString, s: v})
+			} else {
+				tokens = append(tokens, sqlToken{kind: sqlTokenIdent, s: v, quoted: true})
+			}
+			s = tail
+		case isSQLIdentChar(c) && (c < '0' || c > '9'):
+			n := 1
+			for n < len(s) && isSQLIdentChar(s[n]) {
+				n++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, s: s[:n]})
+			s = s[n:]
+		case c >= '0' && c <= '9' || c == '.':
+			n := 1
+			for n < len(s) && (s[n] >= '0' && s[n] <= '9' || s[n] == '.') {
+				n++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenNumber, s: s[:n]})
+			s = s[n:]
+		default:
+			n := 1
+			if len(s) > 1 {
+				switch s[:2] {
+				case "!=", "<>", ">=", "<=":
+					n = 2
+				}
+			}
+			if n == 1 && !strings.ContainsRune("(),*=<>;-", rune(c)) {
+				return nil, fmt.Errorf("unexpected char %q", c)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenPunct, s: s[:n]})
+			s = s[n:]
+		}
+	}
+	return tokens, nil
+}
+
+func isSQLIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// readSQLQuoted reads the quoted string at the start of s. The quote char is escaped by doubling it.
+func readSQLQuoted(s string) (string, string, error) {
+	quote := s[0]
+	var b []byte
+	s = s[1:]
+	for {
+		n := strings.IndexByte(s, quote)
+		if n < 0 {
+			return "", "", fmt.Errorf("missing closing %c quote", quote)
+		}
+		b = append(b, s[:n]...)
+		s = s[n+1:]
+		if len(s) == 0 || s[0] != quote {
+			return string(b), s, nil
+		}
+		b = append(b, quote)
+		s = s[1:]
+	}
+}
+
+func (p *sqlParser) peek() sqlToken {
+	if p.pos >= len(p.tokens) {
+		return sqlToken{kind: sqlTokenEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *sqlParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == sqlTokenIdent && !t.quoted && strings.EqualFold(t.s, keyword)
+}
+
+func (p *sqlParser) isPunct(punct string) bool {
+	t := p.peek()
+	return t.kind == sqlTokenPunct && t.s == punct
+}
+
+func (p *sqlParser) expectKeyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return fmt.Errorf("missing %s; got %s", strings.ToUpper(keyword), p.peekString())
+	}
+	p.next()
+	return nil
+}
+
+func (p *sqlParser) expectPunct(punct string) error {
+	if !p.isPunct(punct) {
+		return fmt.Errorf("missing %q; got %s", punct, p.peekString())
+	}
+	p.next()
+	return nil
+}
+
+func (p *sqlParser) peekString() string {
+	t := p.peek()
+	switch t.kind {
+	case sqlTokenEOF:
+		return "end of query"
+	case sqlTokenString:
+		return fmt.Sprintf("string %q", t.s)
+	default:
+		return fmt.Sprintf("%q", t.s)
+	}
+}
+
+func (p *sqlParser) parseIdent() (string, error) {
+	t := p.peek()
+	if t.kind != sqlTokenIdent {
+		return "", fmt.Errorf("expecting identifier; got %s", p.peekString())
+	}
+	p.next()
+	return t.s, nil
+}
+
+func (p *sqlParser) parseString() (string, error) {
+	t := p.peek()
+	if t.kind != sqlTokenString {
+		return "", fmt.Errorf("expecting quoted string; got %s", p.peekString())
+	}
+	p.next()
+	return t.s, nil
+}
+
+func (p *sqlParser) parseQuery() (*sqlQuery, error) {
+	var q sqlQuery
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+	if err := p.parseSelect(&q); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("from"); err != nil {
+		return nil, err
+	}
+	table, err := p.parseIdent()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse table name: %w", err)
+	}
+	if !strings.EqualFold(table, "metrics") {
+		return nil, fmt.Errorf("unsupported table %q; the only supported table is metrics", table)
+	}
+	if p.isKeyword("where") {
+		p.next()
+		if err := p.parseWhere(&q); err != nil {
+			return nil, fmt.Errorf("cannot parse WHERE clause: %w", err)
+		}
+	}
+	if err := p.expectKeyword("group"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("by"); err != nil {
+		return nil, err
+	}
+	if err := p.parseGroupBy(&q); err != nil {
+		return nil, fmt.Errorf("cannot parse GROUP BY clause: %w", err)
+	}
+	if p.isKeyword("order") {
+		p.next()
+		if err := p.expectKeyword("by"); err != nil {
+			return nil, err
+		}
+		if err := p.parseOrderBy(&q); err != nil {
+			return nil, fmt.Errorf("cannot parse ORDER BY clause: %w", err)
+		}
+	}
+	if p.isKeyword("limit") {
+		p.next()
+		t := p.next()
+		n, err := strconv.Atoi(t.s)
+		if t.kind != sqlTokenNumber || err != nil || n < 0 {
+			return nil, fmt.Errorf("cannot parse LIMIT value %q", t.s)
+		}
+		q.limit = n
+	}
+	if p.isPunct(";") {
+		p.next()
+	}
+	if t := p.peek(); t.kind != sqlTokenEOF {
+		return nil, fmt.Errorf("unexpected tail starting from %s", p.peekString())
+	}
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (p *sqlParser) parseSelect(q *sqlQuery) error {
+	aggsCount := 0
+	for {
+		c, err := p.parseColumn()
+		if err != nil {
+			return fmt.Errorf("cannot parse SELECT column #%d: %w", len(q.columns)+1, err)
+		}
+		switch c.kind {
+		case sqlColumnTime:
+			if q.bucket != 0 {
+				return fmt.Errorf("SELECT clause mustn't contain multiple time_bucket() columns")
+			}
+			q.bucket = c.bucket
+		case sqlColumnAgg:
+			c.aggIdx = aggsCount
+			aggsCount++
+		}
+		q.columns = append(q.columns, c.sqlColumn)
+		if !p.isPunct(",") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parsedColumn is an sqlColumn with the time bucket duration for time_bucket() column.
+type parsedColumn struct {
+	sqlColumn
+	bucket int64
+}
+
+func (p *sqlParser) parseColumn() (*parsedColumn, error) {
+	var c parsedColumn
+	if p.isKeyword("time_bucket") {
+		p.next()
+		bucket, err := p.parseTimeBucketArgs()
+		if err != nil {
+			return nil, err
+		}
+		c.kind = sqlColumnTime
+		c.name = "time_bucket"
+		c.bucket = bucket
+	} else {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if p.isPunct("(") {
+			funcName := strings.ToLower(name)
+			if _, ok := sqlAggFuncs[funcName]; !ok {
+				return nil, fmt.Errorf("unsupported function %q; supported aggregate functions: avg, count, max, min, sum", name)
+			}
+			p.next()
+			if funcName == "count" && p.isPunct("*") {
+				p.next()
+			} else if err := p.expectKeyword("value"); err != nil {
+				return nil, fmt.Errorf("cannot parse %s() arg: %w", funcName, err)
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			c.kind = sqlColumnAgg
+			c.aggFunc = funcName
+			c.name = funcName
+		} else {
+			switch strings.ToLower(name) {
+			case "value":
+				return nil, fmt.Errorf("value must be wrapped into an aggregate function such as avg(value)")
+			case "time":
+				return nil, fmt.Errorf("time must be wrapped into time_bucket('<duration>', time)")
+			}
+			c.kind = sqlColumnLabel
+			c.label = normalizeSQLLabel(name)
+			c.name = name
+		}
+	}
+	if p.isKeyword("as") {
+		p.next()
+		alias, err := p.parseIdent()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse alias: %w", err)
+		}
+		c.name = alias
+	}
+	return &c, nil
+}
+
+// parseTimeBucketArgs parses `('<duration>', time)` args for time_bucket() function and returns the duration in milliseconds.
+func (p *sqlParser) parseTimeBucketArgs() (int64, error) {
+	if err := p.expectPunct("("); err != nil {
+		return 0, err
+	}
+	s, err := p.parseString()
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse time_bucket() duration: %w", err)
+	}
+	bucket, err := metricsql.PositiveDurationValue(strings.ReplaceAll(s, " ", ""), 0)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse time_bucket() duration %q: %w", s, err)
+	}
+	if bucket <= 0 {
+		return 0, fmt.Errorf("time_bucket() duration must be positive; got %q", s)
+	}
+	if err := p.expectPunct(","); err != nil {
+		return 0, err
+	}
+	if err := p.expectKeyword("time"); err != nil {
+		return 0, fmt.Errorf("cannot parse time_bucket() arg: %w", err)
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return 0, err
+	}
+	return bucket, nil
+}
+
+func (p *sqlParser) parseWhere(q *sqlQuery) error {
+	for {
+		if err := p.parseCondition(q); err != nil {
+			return err
+		}
+		if !p.isKeyword("and") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *sqlParser) parseCondition(q *sqlQuery) error {
+	name, err := p.parseIdent()
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(name, "time") {
+		return p.parseTimeCondition(q)
+	}
+	label := normalizeSQLLabel(name)
+
+	negative := false
+	if p.isKeyword("not") {
+		p.next()
+		negative = true
+	}
+	switch {
+	case p.isKeyword("in"):
+		p.next()
+		values, err := p.parseStringList()
+		if err != nil {
+			return fmt.Errorf("cannot parse IN list for %q: %w", name, err)
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = regexp.QuoteMeta(v)
+		}
+		q.addFilter(label, negative, true, strings.Join(quoted, "|"))
+		return nil
+	case p.isKeyword("like"):
+		p.next()
+		pattern, err := p.parseString()
+		if err != nil {
+			return fmt.Errorf("cannot parse LIKE pattern for %q: %w", name, err)
+		}
+		q.addFilter(label, negative, true, sqlLikeToRegexp(pattern))
+		return nil
+	}
+	if negative {
+		return fmt.Errorf("NOT must be followed by IN or LIKE; got %s", p.peekString())
+	}
+
+	t := p.next()
+	if t.kind != sqlTokenPunct {
+		return fmt.Errorf("unexpected token %q after %q; supported operators: =, !=, <>, IN, NOT IN, LIKE, NOT LIKE", t.s, name)
+	}
+	switch t.s {
+	case "=":
+		negative = false
+	case "!=", "<>":
+		negative = true
+	default:
+		return fmt.Errorf("unsupported operator %q for %q; supported operators: =, !=, <>, IN, NOT IN, LIKE, NOT LIKE", t.s, name)
+	}
+	value, err := p.parseString()
+	if err != nil {
+		return fmt.Errorf("cannot parse value for %q: %w", name, err)
+	}
+	if label == "__name__" && !negative {
+		if q.metricName != "" && q.metricName != value {
+			return fmt.Errorf("conflicting metric names: %q and %q", q.metricName, value)
+		}
+		q.metricName = value
+		return nil
+	}
+	q.addFilter(label, negative, false, value)
+	return nil
+}
+
+func (q *sqlQuery) addFilter(label string, negative, isRegexp bool, value string) {
+	op := "="
+	switch {
+	case negative && isRegexp:
+		op = "!~"
+	case negative:
+		op = "!="
+	case isRegexp:
+		op = "=~"
+	}
+	q.filters = append(q.filters, sqlFilter{
+		label: label,
+		op:    op,
+		value: value,
+	})
+}
+
+func (p *sqlParser) parseTimeCondition(q *sqlQuery) error {
+	if p.isKeyword("between") {
+		p.next()
+		start, err := p.parseTimestamp()
+		if err != nil {
+			return err
+		}
+		if err := p.expectKeyword("and"); err != nil {
+			return err
+		}
+		end, err := p.parseTimestamp()
+		if err != nil {
+			return err
+		}
+		q.start = start
+		q.end = end
+		return nil
+	}
+	t := p.next()
+	if t.kind != sqlTokenPunct {
+		return fmt.Errorf("unexpected token %q after time; supported operators: BETWEEN, >, >=, <, <=", t.s)
+	}
+	ts, err := p.parseTimestamp()
+	if err != nil {
+		return err
+	}
+	switch t.s {
+	case ">", ">=":
+		q.start = ts
+	case "<", "<=":
+		q.end = ts
+	default:
+		return fmt.Errorf("unsupported operator %q for time; supported operators: BETWEEN, >, >=, <, <=", t.s)
+	}
+	return nil
+}
+
+// parseTimestamp parses timestamp in RFC3339 format or unix timestamp in seconds and returns it in milliseconds.
+//
+// now() and now() - '<duration>' are supported as well.
+func (p *sqlParser) parseTimestamp() (int64, error) {
+	if p.isKeyword("now") {
+		p.next()
+		if err := p.expectPunct("("); err != nil {
+			return 0, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return 0, err
+		}
+		if !p.isPunct("-") {
+			return p.now, nil
+		}
+		p.next()
+		s, err := p.parseString()
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse duration after now() - : %w", err)
+		}
+		d, err := metricsql.PositiveDurationValue(strings.ReplaceAll(s, " ", ""), 0)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse duration %q: %w", s, err)
+		}
+		return p.now - d, nil
+	}
+	t := p.next()
+	switch t.kind {
+	case sqlTokenString:
+		if tm, err := time.Parse(time.RFC3339Nano, t.s); err == nil {
+			return tm.UnixMilli(), nil
+		}
+		if tm, err := time.Parse(time.DateTime, t.s); err == nil {
+			return tm.UnixMilli(), nil
+		}
+		if tm, err := time.Parse(time.DateOnly, t.s); err == nil {
+			return tm.UnixMilli(), nil
+		}
+		return 0, fmt.Errorf("cannot parse timestamp %q; it must be in RFC3339 format, YYYY-MM-DD hh:mm:ss format or YYYY-MM-DD format", t.s)
+	case sqlTokenNumber:
+		f, err := strconv.ParseFloat(t.s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse unix timestamp %q: %w", t.s, err)
+		}
+		return int64(f * 1e3), nil
+	default:
+		return 0, fmt.Errorf("expecting timestamp; got %q", t.s)
+	}
+}
+
+func (p *sqlParser) parseStringList() ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		v, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (p *sqlParser) parseGroupBy(q *sqlQuery) error {
+	hasTimeBucket := false
+	for {
+		if p.isKeyword("time_bucket") {
+			p.next()
+			bucket, err := p.parseTimeBucketArgs()
+			if err != nil {
+				return err
+			}
+			if bucket != q.bucket {
+				return fmt.Errorf("time_bucket() at GROUP BY must be identical to time_bucket() at SELECT")
+			}
+			hasTimeBucket = true
+		} else {
+			idx, name, err := p.parseColumnRef(q)
+			if err != nil {
+				return err
+			}
+			label := normalizeSQLLabel(name)
+			if idx >= 0 {
+				c := &q.columns[idx]
+				switch c.kind {
+				case sqlColumnTime:
+					hasTimeBucket = true
+					label = ""
+				case sqlColumnAgg:
+					return fmt.Errorf("cannot group by aggregate function column %q", c.name)
+				default:
+					label = c.label
+				}
+			}
+			if label != "" && !slices.Contains(q.groupBy, label) {
+				q.groupBy = append(q.groupBy, label)
+			}
+		}
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if !hasTimeBucket {
+		return fmt.Errorf("GROUP BY must contain time_bucket() column")
+	}
+	return nil
+}
+
+func (p *sqlParser) parseOrderBy(q *sqlQuery) error {
+	for {
+		idx, name, err := p.parseColumnRef(q)
+		if err != nil {
+			return err
+		}
+		if idx < 0 {
+			return fmt.Errorf("unknown column %q; ORDER BY may refer only to SELECT columns", name)
+		}
+		ob := sqlOrderBy{
+			columnIdx: idx,
+		}
+		if p.isKeyword("desc") {
+			p.next()
+			ob.desc = true
+		} else if p.isKeyword("asc") {
+			p.next()
+		}
+		q.orderBy = append(q.orderBy, ob)
+		if !p.isPunct(",") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseColumnRef parses a reference to SELECT column by name or by 1-based position.
+//
+// It returns the index of the referred column and its name. The index is -1 if the name doesn't match SELECT columns.
+func (p *sqlParser) parseColumnRef(q *sqlQuery) (int, string, error) {
+	t := p.peek()
+	if t.kind == sqlTokenNumber {
+		p.next()
+		n, err := strconv.Atoi(t.s)
+		if err != nil || n <= 0 || n > len(q.columns) {
+			return -1, "", fmt.Errorf("invalid column position %q; it must be in the range [1..%d]", t.s, len(q.columns))
+		}
+		return n - 1, q.columns[n-1].name, nil
+	}
+	name, err := p.parseIdent()
+	if err != nil {
+		return -1, "", err
+	}
+	return q.getColumnIdx(name), name, nil
+}
+
+func (q *sqlQuery) getColumnIdx(name string) int {
+	for i := range q.columns {
+		if q.columns[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *sqlQuery) validate() error {
+	if q.metricName == "" {
+		return fmt.Errorf("WHERE clause must contain name = '<metric_name>' filter")
+	}
+	if q.start == 0 {
+		return fmt.Errorf("WHERE clause must contain time BETWEEN '<start>' AND '<end>' or time >= '<start>' filter")
+	}
+	if q.end != 0 && q.end < q.start {
+		return fmt.Errorf("the end of time range mustn't be smaller than the start")
+	}
+	if q.bucket == 0 {
+		return fmt.Errorf("SELECT clause must contain time_bucket('<duration>', time) column")
+	}
+	for _, f := range q.filters {
+		if !isSQLLabelName(f.label) {
+			return fmt.Errorf("invalid label name %q in WHERE clause", f.label)
+		}
+	}
+	for _, label := range q.groupBy {
+		if !isSQLLabelName(label) {
+			return fmt.Errorf("invalid label name %q in GROUP BY clause", label)
+		}
+	}
+	hasAgg := false
+	for i := range q.columns {
+		c := &q.columns[i]
+		switch c.kind {
+		case sqlColumnAgg:
+			hasAgg = true
+		case sqlColumnLabel:
+			c.groupByIdx = slices.Index(q.groupBy, c.label)
+			if c.groupByIdx < 0 {
+				return fmt.Errorf("column %q must be present in GROUP BY clause", c.name)
+			}
+		}
+	}
+	if !hasAgg {
+		return fmt.Errorf("SELECT clause must contain at least a single aggregate function such as avg(value)")
+	}
+	return nil
+}
+
+func isSQLLabelName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isSQLIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeSQLLabel converts name column to __name__ label.
+func normalizeSQLLabel(name string) string {
+	if strings.EqualFold(name, "name") {
+		return "__name__"
+	}
+	return name
+}
+
+// sqlLikeToRegexp converts SQL LIKE pattern to regular expression.
+func sqlLikeToRegexp(pattern string) string {
+	var b strings.Builder
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// metricsqlSelector returns MetricsQL series selector for q.
+func (q *sqlQuery) metricsqlSelector() string {
+	var b strings.Builder
+	b.WriteString("{__name__=")
+	b.WriteString(strconv.Quote(q.metricName))
+	for _, f := range q.filters {
+		b.WriteString(",")
+		b.WriteString(f.label)
+		b.WriteString(f.op)
+		b.WriteString(strconv.Quote(f.value))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// metricsqlQuery returns MetricsQL query for the aggregate function aggFunc over time buckets of q.
+func (q *sqlQuery) metricsqlQuery(aggFunc string) string {
+	window := fmt.Sprintf("[%dms]", q.bucket)
+	selector := q.metricsqlSelector() + window
+	by := "by (" + strings.Join(q.groupBy, ",") + ")"
+	switch aggFunc {
+	case "avg":
+		return fmt.Sprintf("sum(sum_over_time(%s)) %s / sum(count_over_time(%s)) %s", selector, by, selector, by)
+	case "count":
+		return fmt.Sprintf("sum(count_over_time(%s)) %s", selector, by)
+	default:
+		return fmt.Sprintf("%s(%s_over_time(%s)) %s", aggFunc, aggFunc, selector, by)
+	}
+}
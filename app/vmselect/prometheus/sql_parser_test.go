@@ -0,0 +1,149 @@
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSQLQuerySuccess(t *testing.T) {
+	now := int64(1_700_000_000_000)
+	f := func(s string, queriesExpected []string, start, end, bucket int64, groupByExpected []string) {
+		t.Helper()
+
+		sq, err := parseSQLQuery(s, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var queries []string
+		for _, c := range sq.columns {
+			if c.kind == sqlColumnAgg {
+				queries = append(queries, sq.metricsqlQuery(c.aggFunc))
+			}
+		}
+		if !reflect.DeepEqual(queries, queriesExpected) {
+			t.Fatalf("unexpected queries\ngot\n%q\nwant\n%q", queries, queriesExpected)
+		}
+		if sq.start != start {
+			t.Fatalf("unexpected start; got %d; want %d", sq.start, start)
+		}
+		if sq.end != end {
+			t.Fatalf("unexpected end; got %d; want %d", sq.end, end)
+		}
+		if sq.bucket != bucket {
+			t.Fatalf("unexpected bucket; got %d; want %d", sq.bucket, bucket)
+		}
+		if !reflect.DeepEqual(sq.groupBy, groupByExpected) {
+			t.Fatalf("unexpected groupBy; got %q; want %q", sq.groupBy, groupByExpected)
+		}
+	}
+
+	// the simplest query
+	f(`SELECT time_bucket('5m', time), sum(value) FROM metrics WHERE name = 'foo' AND time >= '2024-01-01T00:00:00Z' GROUP BY 1`, []string{
+		`sum(sum_over_time({__name__="foo"}[300000ms])) by ()`,
+	}, 1704067200000, 0, 300_000, nil)
+
+	// all the aggregate functions with labels, aliases and filters
+	f(`select time_bucket('1h', time) as t, job, "instance", avg(value) AS avg_value, count(*), min(value), max(value)
+		from metrics
+		where name = 'http_requests_total' and time between '2024-01-01 00:00:00' and '2024-01-02'
+			and job = 'api' and env != 'dev' and instance in ('a', 'b.c') and path not like '/debug/%' and zone <> 'x'
+		group by t, job, instance
+		order by t desc, job
+		limit 100;`, []string{
+		`sum(sum_over_time({__name__="http_requests_total",job="api",env!="dev",instance=~"a|b\\.c",path!~"/debug/.*",zone!="x"}[3600000ms])) by (job,instance) / ` +
+			`sum(count_over_time({__name__="http_requests_total",job="api",env!="dev",instance=~"a|b\\.c",path!~"/debug/.*",zone!="x"}[3600000ms])) by (job,instance)`,
+		`sum(count_over_time({__name__="http_requests_total",job="api",env!="dev",instance=~"a|b\\.c",path!~"/debug/.*",zone!="x"}[3600000ms])) by (job,instance)`,
+		`min(min_over_time({__name__="http_requests_total",job="api",env!="dev",instance=~"a|b\\.c",path!~"/debug/.*",zone!="x"}[3600000ms])) by (job,instance)`,
+		`max(max_over_time({__name__="http_requests_total",job="api",env!="dev",instance=~"a|b\\.c",path!~"/debug/.*",zone!="x"}[3600000ms])) by (job,instance)`,
+	}, 1704067200000, 1704153600000, 3_600_000, []string{"job", "instance"})
+
+	// relative time range, unix timestamps, quoted strings and positional GROUP BY
+	f(`SELECT time_bucket('30s', time), "job", sum(value) FROM metrics WHERE time > now() - '1h' AND time < 1700000000 AND name = 'a''b' GROUP BY 1, 2`, []string{
+		`sum(sum_over_time({__name__="a'b"}[30000ms])) by (job)`,
+	}, now-3_600_000, 1_700_000_000_000, 30_000, []string{"job"})
+
+	// time_bucket expression in GROUP BY
+	f(`SELECT time_bucket('1m', time), max(value) FROM metrics WHERE name = 'foo' AND time >= 1 GROUP BY time_bucket('1m', time), host`, []string{
+		`max(max_over_time({__name__="foo"}[60000ms])) by (host)`,
+	}, 1000, 0, 60_000, []string{"host"})
+}
+
+func TestParseSQLQueryFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		sq, err := parseSQLQuery(s, 0)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		if sq != nil {
+			t.Fatalf("expecting nil result for %q", s)
+		}
+	}
+
+	const where = ` WHERE name = 'foo' AND time >= 1`
+
+	// non-SELECT queries
+	f(`DELETE FROM metrics` + where)
+	f(`INSERT INTO metrics VALUES (1)`)
+
+	// unsupported table
+	f(`SELECT time_bucket('1m', time), sum(value) FROM users` + where + ` GROUP BY 1`)
+
+	// missing metric name
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics WHERE time >= 1 GROUP BY 1`)
+
+	// missing time range
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics WHERE name = 'foo' GROUP BY 1`)
+
+	// invalid time range
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics WHERE name = 'foo' AND time BETWEEN 10 AND 5 GROUP BY 1`)
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics WHERE name = 'foo' AND time >= 'yesterday' GROUP BY 1`)
+
+	// missing time_bucket
+	f(`SELECT job, sum(value) FROM metrics` + where + ` GROUP BY job`)
+
+	// missing GROUP BY
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where)
+
+	// GROUP BY without time_bucket
+	f(`SELECT time_bucket('1m', time), job, sum(value) FROM metrics` + where + ` GROUP BY job`)
+
+	// mismatched time_bucket at GROUP BY
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY time_bucket('5m', time)`)
+
+	// label column missing at GROUP BY
+	f(`SELECT time_bucket('1m', time), job, sum(value) FROM metrics` + where + ` GROUP BY 1`)
+
+	// missing aggregate function
+	f(`SELECT time_bucket('1m', time), job FROM metrics` + where + ` GROUP BY 1, 2`)
+
+	// raw values and time
+	f(`SELECT time_bucket('1m', time), value FROM metrics` + where + ` GROUP BY 1`)
+	f(`SELECT time, sum(value) FROM metrics` + where + ` GROUP BY 1`)
+
+	// unsupported function
+	f(`SELECT time_bucket('1m', time), median(value) FROM metrics` + where + ` GROUP BY 1`)
+
+	// invalid time_bucket
+	f(`SELECT time_bucket('foo', time), sum(value) FROM metrics` + where + ` GROUP BY 1`)
+	f(`SELECT time_bucket('1m', ts), sum(value) FROM metrics` + where + ` GROUP BY 1`)
+
+	// invalid label name
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` AND "foo-bar" = 'x' GROUP BY 1`)
+
+	// unsupported operator
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` AND job > 'x' GROUP BY 1`)
+
+	// invalid GROUP BY, ORDER BY and LIMIT
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY 3`)
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY 1, 2`)
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY 1 ORDER BY foo`)
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY 1 LIMIT -1`)
+
+	// unexpected tail
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics` + where + ` GROUP BY 1; DROP TABLE metrics`)
+
+	// unclosed quote
+	f(`SELECT time_bucket('1m, time), sum(value) FROM metrics`)
+}
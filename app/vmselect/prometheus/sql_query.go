@@ -0,0 +1,263 @@
+package prometheus
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// SQLQueryHandler processes /api/v1/sql request.
+//
+// It translates a read-only SQL query over metrics into MetricsQL range queries
+// and returns the result as a table with columns and rows.
+//
+// See https://docs.victoriametrics.com/#sql-query-api
+func SQLQueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer sqlQueryDuration.UpdateDuration(startTime)
+
+	ct := startTime.UnixNano() / 1e6
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	if len(query) > maxQueryLen.IntN() {
+		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(query), maxQueryLen.N)
+	}
+	sq, err := parseSQLQuery(query, ct)
+	if err != nil {
+		return err
+	}
+	etfs, err := searchutil.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	if err := sqlQueryHandler(qt, startTime, w, sq, r, ct, etfs); err != nil {
+		return fmt.Errorf("error when executing SQL query %q: %w", query, err)
+	}
+	return nil
+}
+
+var sqlQueryDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/sql"}`)
+
+func sqlQueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, sq *sqlQuery, r *http.Request, ct int64, etfs [][]storage.TagFilter) error {
+	deadline := searchutil.GetDeadlineForQuery(r, startTime)
+	ioClass, err := searchutil.GetIOClass(r, storage.IOClassInteractive)
+	if err != nil {
+		return err
+	}
+
+	// Align the time range to time buckets. Every bucket is evaluated at its end,
+	// so the rollup window [bucket] covers the samples in the bucket.
+	step := sq.bucket
+	end := sq.end
+	if end == 0 {
+		end = ct
+	}
+	start := sq.start - sq.start%step
+	if n := end % step; n != 0 {
+		end += step - n
+	}
+	if end <= start {
+		end = start + step
+	}
+	if err := promql.ValidateMaxPointsPerSeries(start+step, end, step, *maxPointsPerTimeseries); err != nil {
+		return fmt.Errorf("%w; (see -search.maxPointsPerTimeseries command-line flag)", err)
+	}
+
+	qs := &promql.QueryStats{}
+	newEvalConfig := func() *promql.EvalConfig {
+		return &promql.EvalConfig{
+			Start:               start + step,
+			End:                 end,
+			Step:                step,
+			MaxPointsPerSeries:  *maxPointsPerTimeseries,
+			MaxSeries:           GetMaxUniqueTimeSeries(),
+			QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
+			Deadline:            deadline,
+			MayCache:            !httputil.GetBool(r, "nocache"),
+			RoundDigits:         getRoundDigits(r),
+			EnforcedTagFilterss: etfs,
+			IOClass:             ioClass,
+			GetRequestURI: func() string {
+				return httpserver.GetRequestURI(r)
+			},
+
+			QueryStats: qs,
+		}
+	}
+
+	var aggs []string
+	for _, c := range sq.columns {
+		if c.kind == sqlColumnAgg {
+			aggs = append(aggs, c.aggFunc)
+		}
+	}
+	rt := newSQLRowsTable(sq, len(aggs))
+	for i, aggFunc := range aggs {
+		query := sq.metricsqlQuery(aggFunc)
+		result, err := promql.Exec(qt, newEvalConfig(), query, false)
+		if err != nil {
+			return fmt.Errorf("cannot execute MetricsQL query %q: %w", query, err)
+		}
+		rt.addResults(result, i)
+	}
+	rows := rt.getRows()
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteSQLQueryResponse(bw, sq.columns, rows)
+	qt.Donef("start=%d, end=%d, step=%d: rows=%d", start, end, step, len(rows))
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send SQL query response to remote client: %w", err)
+	}
+	return nil
+}
+
+// sqlRow is a single row of SQL query response.
+type sqlRow struct {
+	// timestamp is the start of the time bucket in milliseconds.
+	timestamp int64
+
+	// labels contains values for GROUP BY labels.
+	labels []string
+
+	// values contains values for aggregate functions in the order of their appearance in SELECT clause.
+	values []float64
+}
+
+// sqlRowsTable joins results of aggregate functions by time buckets and GROUP BY labels.
+type sqlRowsTable struct {
+	sq        *sqlQuery
+	aggsCount int
+
+	m map[string]*sqlRow
+}
+
+func newSQLRowsTable(sq *sqlQuery, aggsCount int) *sqlRowsTable {
+	return &sqlRowsTable{
+		sq:        sq,
+		aggsCount: aggsCount,
+		m:         make(map[string]*sqlRow),
+	}
+}
+
+// addResults adds rs for the aggregate function with the given aggIdx to rt.
+func (rt *sqlRowsTable) addResults(rs []netstorage.Result, aggIdx int) {
+	var key []byte
+	for i := range rs {
+		r := &rs[i]
+		labels := make([]string, len(rt.sq.groupBy))
+		for j, label := range rt.sq.groupBy {
+			if label == "__name__" {
+				// Metric name is dropped by rollup functions, while it is always known from WHERE clause.
+				labels[j] = rt.sq.metricName
+				continue
+			}
+			labels[j] = string(r.MetricName.GetTagValue(label))
+		}
+		for j, v := range r.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			ts := r.Timestamps[j] - rt.sq.bucket
+			key = fmt.Appendf(key[:0], "%d", ts)
+			for _, v := range labels {
+				key = append(key, 0)
+				key = append(key, v...)
+			}
+			row := rt.m[string(key)]
+			if row == nil {
+				values := make([]float64, rt.aggsCount)
+				for k := range values {
+					values[k] = math.NaN()
+				}
+				row = &sqlRow{
+					timestamp: ts,
+					labels:    labels,
+					values:    values,
+				}
+				rt.m[string(key)] = row
+			}
+			row.values[aggIdx] = v
+		}
+	}
+}
+
+// getRows returns rows sorted according to ORDER BY clause and limited by LIMIT clause.
+//
+// Rows are sorted by time buckets and then by labels if ORDER BY clause is missing.
+func (rt *sqlRowsTable) getRows() []*sqlRow {
+	rows := make([]*sqlRow, 0, len(rt.m))
+	for _, row := range rt.m {
+		rows = append(rows, row)
+	}
+	sq := rt.sq
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		for _, ob := range sq.orderBy {
+			n := compareSQLRows(a, b, &sq.columns[ob.columnIdx])
+			if n == 0 {
+				continue
+			}
+			if ob.desc {
+				return n > 0
+			}
+			return n < 0
+		}
+		if a.timestamp != b.timestamp {
+			return a.timestamp < b.timestamp
+		}
+		for k := range a.labels {
+			if n := strings.Compare(a.labels[k], b.labels[k]); n != 0 {
+				return n < 0
+			}
+		}
+		return false
+	})
+	if sq.limit > 0 && len(rows) > sq.limit {
+		rows = rows[:sq.limit]
+	}
+	return rows
+}
+
+func compareSQLRows(a, b *sqlRow, c *sqlColumn) int {
+	switch c.kind {
+	case sqlColumnTime:
+		return cmp.Compare(a.timestamp, b.timestamp)
+	case sqlColumnLabel:
+		return strings.Compare(a.labels[c.groupByIdx], b.labels[c.groupByIdx])
+	default:
+		va, vb := a.values[c.aggIdx], b.values[c.aggIdx]
+		switch {
+		case math.IsNaN(va) && math.IsNaN(vb):
+			return 0
+		case math.IsNaN(va):
+			// NaN values go last like NULL values in ascending order.
+			return 1
+		case math.IsNaN(vb):
+			return -1
+		case va < vb:
+			return -1
+		case va > vb:
+			return 1
+		default:
+			return 0
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package prometheus
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+)
+
+func TestSQLRowsTable(t *testing.T) {
+	newResult := func(tags map[string]string, values ...float64) netstorage.Result {
+		var r netstorage.Result
+		for k, v := range tags {
+			r.MetricName.AddTag(k, v)
+		}
+		r.Values = values
+		for i := range values {
+			r.Timestamps = append(r.Timestamps, int64(i+1)*60_000)
+		}
+		return r
+	}
+
+	f := func(query string, rss [][]netstorage.Result, responseExpected string) {
+		t.Helper()
+
+		sq, err := parseSQLQuery(query, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		rt := newSQLRowsTable(sq, len(rss))
+		for i, rs := range rss {
+			rt.addResults(rs, i)
+		}
+		var bb bytes.Buffer
+		WriteSQLQueryResponse(&bb, sq.columns, rt.getRows())
+		if response := bb.String(); response != responseExpected {
+			t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", response, responseExpected)
+		}
+	}
+
+	nan := math.NaN()
+
+	// rows are joined by time buckets and labels, while missing values are returned as null
+	f(`SELECT time_bucket('1m', time) AS t, job, name, sum(value), max(value) AS m FROM metrics WHERE name = 'foo' AND time >= 1 GROUP BY 1, 2, 3`, [][]netstorage.Result{
+		{
+			newResult(map[string]string{"job": "b"}, 1, 2),
+			newResult(map[string]string{"job": "a"}, nan, 3),
+		},
+		{
+			newResult(map[string]string{"job": "b"}, 10, nan),
+			newResult(map[string]string{"job": "a"}, 30, 40),
+		},
+	}, `{"status":"success","data":{"columns":[{"name":"t","type":"timestamp"},{"name":"job","type":"string"},{"name":"name","type":"string"},`+
+		`{"name":"sum","type":"float"},{"name":"m","type":"float"}],"rows":[`+
+		`["1970-01-01T00:00:00Z","a","foo",null,30],`+
+		`["1970-01-01T00:00:00Z","b","foo",1,10],`+
+		`["1970-01-01T00:01:00Z","a","foo",3,40],`+
+		`["1970-01-01T00:01:00Z","b","foo",2,null]]}}`)
+
+	// ORDER BY and LIMIT
+	f(`SELECT time_bucket('1m', time), job, sum(value) AS s FROM metrics WHERE name = 'foo' AND time >= 1 GROUP BY 1, 2 ORDER BY s DESC LIMIT 2`, [][]netstorage.Result{
+		{
+			newResult(map[string]string{"job": "b"}, 1, 2),
+			newResult(map[string]string{"job": "a"}, 5, 3),
+		},
+	}, `{"status":"success","data":{"columns":[{"name":"time_bucket","type":"timestamp"},{"name":"job","type":"string"},{"name":"s","type":"float"}],"rows":[`+
+		`["1970-01-01T00:00:00Z","a",5],`+
+		`["1970-01-01T00:01:00Z","a",3]]}}`)
+
+	// empty result
+	f(`SELECT time_bucket('1m', time), sum(value) FROM metrics WHERE name = 'foo' AND time >= 1 GROUP BY 1`, [][]netstorage.Result{
+		nil,
+	}, `{"status":"success","data":{"columns":[{"name":"time_bucket","type":"timestamp"},{"name":"sum","type":"float"}],"rows":[]}}`)
+}
@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metrics"
@@ -22,6 +24,7 @@ var aggrFuncs = map[string]aggrFunc{
 	"avg":            newAggrFunc(aggrFuncAvg),
 	"bottomk":        newAggrFuncTopK(true),
 	"bottomk_avg":    newAggrFuncRangeTopK(avgValue, true),
+	"bottomk_by":     newAggrFuncTopKBy(true),
 	"bottomk_max":    newAggrFuncRangeTopK(maxValue, true),
 	"bottomk_median": newAggrFuncRangeTopK(medianValue, true),
 	"bottomk_last":   newAggrFuncRangeTopK(lastValue, true),
@@ -50,6 +53,7 @@ var aggrFuncs = map[string]aggrFunc{
 	"sum2":           newAggrFunc(aggrFuncSum2),
 	"topk":           newAggrFuncTopK(false),
 	"topk_avg":       newAggrFuncRangeTopK(avgValue, false),
+	"topk_by":        newAggrFuncTopKBy(false),
 	"topk_max":       newAggrFuncRangeTopK(maxValue, false),
 	"topk_median":    newAggrFuncRangeTopK(medianValue, false),
 	"topk_last":      newAggrFuncRangeTopK(lastValue, false),
@@ -677,6 +681,98 @@ func newAggrFuncTopK(isReverse bool) aggrFunc {
 	}
 }
 
+func newAggrFuncTopKBy(isReverse bool) aggrFunc {
+	return func(afa *aggrFuncArg) ([]*timeseries, error) {
+		args := afa.args
+		if err := expectTransformArgsNum(args, 3); err != nil {
+			return nil, err
+		}
+		ks, err := getScalar(args[0], 0)
+		if err != nil {
+			return nil, err
+		}
+		labels, err := getString(args[2], 2)
+		if err != nil {
+			return nil, err
+		}
+		var entityTags []string
+		for _, label := range strings.Split(labels, ",") {
+			label = strings.TrimSpace(label)
+			if label != "" {
+				entityTags = append(entityTags, label)
+			}
+		}
+		if len(entityTags) == 0 {
+			return nil, fmt.Errorf("the third arg must contain at least a single label name")
+		}
+		afe := func(tss []*timeseries, _ *metricsql.ModifierExpr) []*timeseries {
+			return getTopKByTimeseries(tss, ks, entityTags, isReverse)
+		}
+		return aggrFuncExt(afe, args[1], &afa.ae.Modifier, afa.ae.Limit, true)
+	}
+}
+
+// getTopKByTimeseries returns all the series from tss for up to ks entities with the biggest sums at every point.
+//
+// Series belong to the same entity if they have identical values for entityTags.
+// If isReverse is set, then the entities with the smallest sums are returned.
+func getTopKByTimeseries(tss []*timeseries, ks []float64, entityTags []string, isReverse bool) []*timeseries {
+	type entity struct {
+		tss  []*timeseries
+		sums []float64
+	}
+	m := make(map[string]*entity)
+	var entities []*entity
+	var bb bytesutil.ByteBuffer
+	for _, ts := range tss {
+		bb.B = bb.B[:0]
+		for _, tag := range entityTags {
+			bb.B = encoding.MarshalBytes(bb.B, ts.MetricName.GetTagValue(tag))
+		}
+		e := m[string(bb.B)]
+		if e == nil {
+			e = &entity{
+				sums: make([]float64, len(ts.Values)),
+			}
+			for i := range e.sums {
+				e.sums[i] = nan
+			}
+			m[string(bb.B)] = e
+			entities = append(entities, e)
+		}
+		e.tss = append(e.tss, ts)
+		for i, v := range ts.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			if math.IsNaN(e.sums[i]) {
+				e.sums[i] = v
+			} else {
+				e.sums[i] += v
+			}
+		}
+	}
+
+	lessFunc := lessWithNaNs
+	if isReverse {
+		lessFunc = greaterWithNaNs
+	}
+	for n := range ks {
+		sort.Slice(entities, func(i, j int) bool {
+			a := entities[i].sums[n]
+			b := entities[j].sums[n]
+			return lessFunc(a, b)
+		})
+		kn := getIntK(ks[n], len(entities))
+		for _, e := range entities[:len(entities)-kn] {
+			for _, ts := range e.tss {
+				ts.Values[n] = nan
+			}
+		}
+	}
+	return removeEmptySeries(tss)
+}
+
 func newAggrFuncRangeTopK(f func(values []float64) float64, isReverse bool) aggrFunc {
 	return func(afa *aggrFuncArg) ([]*timeseries, error) {
 		args := afa.args
@@ -1,7 +1,9 @@
 package promql
 
 import (
+	"fmt"
 	"math"
+	"reflect"
 	"sort"
 	"testing"
 )
@@ -82,3 +84,67 @@ func TestModeNoNaNs(t *testing.T) {
 	f(1, []float64{2, 3, 3, 4, 4}, 3)
 	f(1, []float64{4, 3, 2, 3, 4}, 3)
 }
+
+func TestGetTopKByTimeseries(t *testing.T) {
+	newTimeseries := func(values []float64, tags ...string) *timeseries {
+		var ts timeseries
+		for i := 0; i < len(tags); i += 2 {
+			ts.MetricName.AddTag(tags[i], tags[i+1])
+		}
+		ts.Values = append([]float64{}, values...)
+		ts.Timestamps = make([]int64, len(values))
+		return &ts
+	}
+
+	f := func(k float64, entityTags []string, isReverse bool, resultExpected []string) {
+		t.Helper()
+
+		tss := []*timeseries{
+			newTimeseries([]float64{1, 1, 1}, "container", "x", "pod", "p1"),
+			newTimeseries([]float64{2, 2, 2}, "container", "y", "pod", "p1"),
+			newTimeseries([]float64{1.5, 2.5, 4}, "pod", "p2"),
+			newTimeseries([]float64{nan, 0, 5}, "pod", "p3"),
+		}
+		ks := []float64{k, k, k}
+		result := getTopKByTimeseries(tss, ks, entityTags, isReverse)
+		var results []string
+		for _, ts := range result {
+			results = append(results, fmt.Sprintf("%s %v", ts.MetricName.String(), ts.Values))
+		}
+		sort.Strings(results)
+		if !reflect.DeepEqual(results, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%q\nwant\n%q", results, resultExpected)
+		}
+	}
+
+	// top entities by sum of their series
+	f(1, []string{"pod"}, false, []string{
+		`{pod="p1",container="x"} [1 1 NaN]`,
+		`{pod="p1",container="y"} [2 2 NaN]`,
+		`{pod="p3"} [NaN NaN 5]`,
+	})
+	f(2, []string{"pod"}, false, []string{
+		`{pod="p1",container="x"} [1 1 NaN]`,
+		`{pod="p1",container="y"} [2 2 NaN]`,
+		`{pod="p2"} [1.5 2.5 4]`,
+		`{pod="p3"} [NaN NaN 5]`,
+	})
+
+	// bottom entities by sum of their series
+	f(1, []string{"pod"}, true, []string{
+		`{pod="p1",container="x"} [NaN NaN 1]`,
+		`{pod="p1",container="y"} [NaN NaN 2]`,
+		`{pod="p2"} [1.5 NaN NaN]`,
+		`{pod="p3"} [NaN 0 NaN]`,
+	})
+
+	// zero k
+	f(0, []string{"pod"}, false, nil)
+
+	// entities identified by multiple tags
+	f(1, []string{"pod", "container"}, false, []string{
+		`{pod="p1",container="y"} [2 NaN NaN]`,
+		`{pod="p2"} [NaN 2.5 NaN]`,
+		`{pod="p3"} [NaN NaN 5]`,
+	})
+}
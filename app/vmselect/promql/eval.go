@@ -139,6 +139,9 @@ type EvalConfig struct {
 	// EnforcedTagFilterss may contain additional label filters to use in the query.
 	EnforcedTagFilterss [][]storage.TagFilter
 
+	// IOClass is the class of disk IO for the query.
+	IOClass storage.IOClass
+
 	// The callback, which returns the request URI during logging.
 	// The request URI isn't stored here because its' construction may take non-trivial amounts of CPU.
 	GetRequestURI func() string
@@ -165,6 +168,7 @@ func copyEvalConfig(src *EvalConfig) *EvalConfig {
 	ec.LookbackDelta = src.LookbackDelta
 	ec.RoundDigits = src.RoundDigits
 	ec.EnforcedTagFilterss = src.EnforcedTagFilterss
+	ec.IOClass = src.IOClass
 	ec.GetRequestURI = src.GetRequestURI
 	ec.QueryStats = src.QueryStats
 
@@ -981,7 +985,7 @@ func evalRollupFuncWithSubquery(qt *querytracer.Tracer, ec *EvalConfig, funcName
 				continue
 			}
 			var ts timeseries
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, &ts, &tsSQ.MetricName, values, timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, nil, &ts, &tsSQ.MetricName, values, timestamps, nil, sharedTimestamps)
 			samplesScannedTotal.Add(samplesScanned)
 			seriesByWorkerID[workerID].tss = append(seriesByWorkerID[workerID].tss, &ts)
 		}
@@ -1163,12 +1167,16 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 			window, minWindowForInstantRollupOptimization.Milliseconds())
 		return evalAt(qt, timestamp, window)
 	}
+	aggrFuncName := ""
+	if iafc != nil {
+		aggrFuncName = iafc.ae.Name
+	}
+	if reason := getInstantRollupOptimizationSkipReason(funcName, aggrFuncName); reason != "" {
+		qt.Printf("do not apply instant rollup optimization: %s", reason)
+		return evalAt(qt, timestamp, window)
+	}
 	switch funcName {
 	case "avg_over_time":
-		if iafc != nil {
-			qt.Printf("do not apply instant rollup optimization for incremental aggregate %s()", iafc.ae.Name)
-			return evalAt(qt, timestamp, window)
-		}
 		qt.Printf("optimized calculation for instant rollup avg_over_time(m[d]) as (sum_over_time(m[d]) / count_over_time(m[d]))")
 		fe := expr.(*metricsql.FuncExpr)
 		feSum := *fe
@@ -1184,10 +1192,6 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 		return evalExpr(qt, ec, be)
 	case "rate":
 		if iafc != nil {
-			if !strings.EqualFold(iafc.ae.Name, "sum") {
-				qt.Printf("do not apply instant rollup optimization for incremental aggregate %s()", iafc.ae.Name)
-				return evalAt(qt, timestamp, window)
-			}
 			qt.Printf("optimized calculation for sum(rate(m[d])) as (sum(increase(m[d])) / d)")
 			afe := expr.(*metricsql.AggrFuncExpr)
 			fe := afe.Args[0].(*metricsql.FuncExpr)
@@ -1229,13 +1233,6 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 		}
 		return evalExpr(qt, ec, be)
 	case "max_over_time":
-		if iafc != nil {
-			if !strings.EqualFold(iafc.ae.Name, "max") {
-				qt.Printf("do not apply instant rollup optimization for non-max incremental aggregate %s()", iafc.ae.Name)
-				return evalAt(qt, timestamp, window)
-			}
-		}
-
 		// Calculate
 		//
 		// max_over_time(m[window] @ timestamp)
@@ -1291,13 +1288,6 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 		}
 		return tss, nil
 	case "min_over_time":
-		if iafc != nil {
-			if !strings.EqualFold(iafc.ae.Name, "min") {
-				qt.Printf("do not apply instant rollup optimization for non-min incremental aggregate %s()", iafc.ae.Name)
-				return evalAt(qt, timestamp, window)
-			}
-		}
-
 		// Calculate
 		//
 		//   min_over_time(m[window] @ timestamp)
@@ -1361,11 +1351,6 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 		"increase",
 		"increase_pure",
 		"sum_over_time":
-		if iafc != nil && !strings.EqualFold(iafc.ae.Name, "sum") {
-			qt.Printf("do not apply instant rollup optimization for non-sum incremental aggregate %s()", iafc.ae.Name)
-			return evalAt(qt, timestamp, window)
-		}
-
 		// Calculate
 		//
 		//   rf(m[window] @ timestamp)
@@ -1413,11 +1398,46 @@ func evalInstantRollup(qt *querytracer.Tracer, ec *EvalConfig, funcName string,
 		tss := getSumInstantValues(qtChild, tssCached, tssStart, tssEnd, timestamp)
 		return tss, nil
 	default:
-		qt.Printf("instant rollup optimization isn't implemented for %s()", funcName)
-		return evalAt(qt, timestamp, window)
+		logger.Panicf("BUG: missing instant rollup optimization for %s(), which is registered at instantRollupOptimizationFuncs", funcName)
+		return nil, nil
 	}
 }
 
+// instantRollupOptimizationFuncs contains rollup functions, which support instant rollup optimization at evalInstantRollup.
+//
+// The value is the incremental aggregate function, which can be applied on top of the rollup function
+// without disabling the optimization. An empty value means that the optimization is disabled for any incremental aggregate function.
+var instantRollupOptimizationFuncs = map[string]string{
+	"avg_over_time":      "",
+	"rate":               "sum",
+	"max_over_time":      "max",
+	"min_over_time":      "min",
+	"count_eq_over_time": "sum",
+	"count_gt_over_time": "sum",
+	"count_le_over_time": "sum",
+	"count_ne_over_time": "sum",
+	"count_over_time":    "sum",
+	"increase":           "sum",
+	"increase_pure":      "sum",
+	"sum_over_time":      "sum",
+}
+
+// getInstantRollupOptimizationSkipReason returns the reason why instant rollup optimization cannot be applied to funcName rollup
+// with the given incremental aggrFuncName on top of it.
+//
+// aggrFuncName must be empty if there is no incremental aggregate function on top of the rollup.
+// An empty string is returned if the optimization can be applied.
+func getInstantRollupOptimizationSkipReason(funcName, aggrFuncName string) string {
+	aggrFuncNameAllowed, ok := instantRollupOptimizationFuncs[funcName]
+	if !ok {
+		return fmt.Sprintf("instant rollup optimization isn't implemented for %s()", funcName)
+	}
+	if aggrFuncName != "" && !strings.EqualFold(aggrFuncName, aggrFuncNameAllowed) {
+		return fmt.Sprintf("instant rollup optimization isn't implemented for %s() over %s()", aggrFuncName, funcName)
+	}
+	return ""
+}
+
 func hasDuplicateSeries(tss []*timeseries) bool {
 	if len(tss) <= 1 {
 		return false
@@ -1717,6 +1737,7 @@ func evalRollupFuncNoCache(qt *querytracer.Tracer, ec *EvalConfig, funcName stri
 		minTimestamp -= ec.Step
 	}
 	sq := storage.NewSearchQuery(minTimestamp, ec.End, tfss, ec.MaxSeries)
+	sq.IOClass = ec.IOClass
 	rss, err := netstorage.ProcessSearchQuery(qt, sq, ec.Deadline)
 	if err != nil {
 		return nil, err
@@ -1783,10 +1804,11 @@ func evalRollupFuncNoCache(qt *querytracer.Tracer, ec *EvalConfig, funcName stri
 
 	// Evaluate rollup
 	keepMetricNames := getKeepMetricNames(expr)
+	bsr := newBlockSummaryRollup(funcName, rcs)
 	if iafc != nil {
-		return evalRollupWithIncrementalAggregate(qt, funcName, keepMetricNames, iafc, rss, rcs, preFunc, sharedTimestamps)
+		return evalRollupWithIncrementalAggregate(qt, funcName, keepMetricNames, iafc, rss, rcs, bsr, preFunc, sharedTimestamps)
 	}
-	return evalRollupNoIncrementalAggregate(qt, funcName, keepMetricNames, rss, rcs, preFunc, sharedTimestamps)
+	return evalRollupNoIncrementalAggregate(qt, funcName, keepMetricNames, rss, rcs, bsr, preFunc, sharedTimestamps)
 }
 
 var (
@@ -1810,12 +1832,12 @@ func maxSilenceInterval() int64 {
 }
 
 func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string, keepMetricNames bool,
-	iafc *incrementalAggrFuncContext, rss *netstorage.Results, rcs []*rollupConfig,
+	iafc *incrementalAggrFuncContext, rss *netstorage.Results, rcs []*rollupConfig, bsr *blockSummaryRollup,
 	preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64) ([]*timeseries, error) {
 	qt = qt.NewChild("rollup %s() with incremental aggregation %s() over %d series; rollupConfigs=%s", funcName, iafc.ae.Name, rss.Len(), rcs)
 	defer qt.Done()
 	var samplesScannedTotal atomic.Uint64
-	err := rss.RunParallel(qt, func(rs *netstorage.Result, workerID uint) error {
+	err := rss.RunParallelWithBlockSummaries(qt, bsr.getCanUseBlockSummaryFunc(), func(rs *netstorage.Result, workerID uint) error {
 		rs.Values, rs.Timestamps = dropStaleNaNs(funcName, rs.Values, rs.Timestamps)
 		preFunc(rs.Values, rs.Timestamps)
 		ts := getTimeseries()
@@ -1830,7 +1852,7 @@ func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string,
 				continue
 			}
 			ts.Reset()
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, bsr, ts, &rs.MetricName, rs.Values, rs.Timestamps, rs.BlockSummaries, sharedTimestamps)
 			samplesScannedTotal.Add(samplesScanned)
 			iafc.updateTimeseries(ts, workerID)
 
@@ -1850,7 +1872,7 @@ func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string,
 }
 
 func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, keepMetricNames bool, rss *netstorage.Results, rcs []*rollupConfig,
-	preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64) ([]*timeseries, error) {
+	bsr *blockSummaryRollup, preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64) ([]*timeseries, error) {
 	qt = qt.NewChild("rollup %s() over %d series; rollupConfigs=%s", funcName, rss.Len(), rcs)
 	defer qt.Done()
 
@@ -1858,7 +1880,7 @@ func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, k
 	tsw := getTimeseriesByWorkerID()
 	seriesByWorkerID := tsw.byWorkerID
 	seriesLen := rss.Len()
-	err := rss.RunParallel(qt, func(rs *netstorage.Result, workerID uint) error {
+	err := rss.RunParallelWithBlockSummaries(qt, bsr.getCanUseBlockSummaryFunc(), func(rs *netstorage.Result, workerID uint) error {
 		rs.Values, rs.Timestamps = dropStaleNaNs(funcName, rs.Values, rs.Timestamps)
 		preFunc(rs.Values, rs.Timestamps)
 		for _, rc := range rcs {
@@ -1869,7 +1891,7 @@ func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, k
 				continue
 			}
 			var ts timeseries
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, &ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, bsr, &ts, &rs.MetricName, rs.Values, rs.Timestamps, rs.BlockSummaries, sharedTimestamps)
 			samplesScannedTotal.Add(samplesScanned)
 			seriesByWorkerID[workerID].tss = append(seriesByWorkerID[workerID].tss, &ts)
 		}
@@ -1889,8 +1911,12 @@ func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, k
 	return tss, nil
 }
 
-func doRollupForTimeseries(funcName string, keepMetricNames bool, rc *rollupConfig, tsDst *timeseries, mnSrc *storage.MetricName,
-	valuesSrc []float64, timestampsSrc []int64, sharedTimestamps []int64) uint64 {
+// doRollupForTimeseries calculates rc over the given samples and stores the result in tsDst.
+//
+// summariesSrc must contain block summaries for blocks, which weren't unpacked to valuesSrc and timestampsSrc.
+// bsr is used for calculating the rollup if summariesSrc isn't empty.
+func doRollupForTimeseries(funcName string, keepMetricNames bool, rc *rollupConfig, bsr *blockSummaryRollup, tsDst *timeseries, mnSrc *storage.MetricName,
+	valuesSrc []float64, timestampsSrc []int64, summariesSrc []storage.BlockSummary, sharedTimestamps []int64) uint64 {
 	tsDst.MetricName.CopyFrom(mnSrc)
 	if len(rc.TagValue) > 0 {
 		tsDst.MetricName.AddTag("rollup", rc.TagValue)
@@ -1899,7 +1925,11 @@ func doRollupForTimeseries(funcName string, keepMetricNames bool, rc *rollupConf
 		tsDst.MetricName.ResetMetricGroup()
 	}
 	var samplesScanned uint64
-	tsDst.Values, samplesScanned = rc.Do(tsDst.Values[:0], valuesSrc, timestampsSrc)
+	if len(summariesSrc) > 0 {
+		tsDst.Values, samplesScanned = bsr.do(tsDst.Values[:0], valuesSrc, timestampsSrc, summariesSrc)
+	} else {
+		tsDst.Values, samplesScanned = rc.Do(tsDst.Values[:0], valuesSrc, timestampsSrc)
+	}
 	tsDst.Timestamps = sharedTimestamps
 	tsDst.denyReuse = true
 	return samplesScanned
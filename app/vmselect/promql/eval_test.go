@@ -1,7 +1,12 @@
 package promql
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"reflect"
+	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
@@ -170,3 +175,55 @@ func TestGetSumInstantValues(t *testing.T) {
 		[]*timeseries{ts("foo", 100, 1)},
 	)
 }
+
+func TestInstantRollupOptimizationFuncs(t *testing.T) {
+	// Make sure instantRollupOptimizationFuncs, which is used by explain, is in sync with the switch at evalInstantRollup.
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "eval.go", nil, 0)
+	if err != nil {
+		t.Fatalf("cannot parse eval.go: %s", err)
+	}
+	var funcNames []string
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != "evalInstantRollup" {
+			continue
+		}
+		for _, stmt := range fd.Body.List {
+			ss, ok := stmt.(*ast.SwitchStmt)
+			if !ok {
+				continue
+			}
+			if id, ok := ss.Tag.(*ast.Ident); !ok || id.Name != "funcName" {
+				continue
+			}
+			for _, stmt := range ss.Body.List {
+				for _, e := range stmt.(*ast.CaseClause).List {
+					bl, ok := e.(*ast.BasicLit)
+					if !ok || bl.Kind != token.STRING {
+						t.Fatalf("unexpected case expression at %s", fset.Position(e.Pos()))
+					}
+					funcName, err := strconv.Unquote(bl.Value)
+					if err != nil {
+						t.Fatalf("cannot unquote %s: %s", bl.Value, err)
+					}
+					funcNames = append(funcNames, funcName)
+				}
+			}
+		}
+	}
+	if len(funcNames) == 0 {
+		t.Fatalf("cannot find switch by funcName at evalInstantRollup")
+	}
+	sort.Strings(funcNames)
+
+	var funcNamesExpected []string
+	for funcName := range instantRollupOptimizationFuncs {
+		funcNamesExpected = append(funcNamesExpected, funcName)
+	}
+	sort.Strings(funcNamesExpected)
+
+	if !reflect.DeepEqual(funcNames, funcNamesExpected) {
+		t.Fatalf("evalInstantRollup and instantRollupOptimizationFuncs are out of sync\nevalInstantRollup: %q\ninstantRollupOptimizationFuncs: %q", funcNames, funcNamesExpected)
+	}
+}
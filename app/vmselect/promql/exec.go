@@ -33,7 +33,16 @@ var (
 )
 
 // Exec executes q for the given ec.
+//
+// Concurrently executed identical queries are collapsed into a single execution. See execCollapsed for details.
 func Exec(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly bool) ([]netstorage.Result, error) {
+	if *disableQueryCollapsing {
+		return execInternal(qt, ec, q, isFirstPointOnly)
+	}
+	return execCollapsed(qt, ec, q, isFirstPointOnly)
+}
+
+func execInternal(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly bool) ([]netstorage.Result, error) {
 	if querystats.Enabled() {
 		startTime := time.Now()
 		defer func() {
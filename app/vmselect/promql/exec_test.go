@@ -6506,6 +6506,72 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{r1}
 		f(q, resultExpected)
 	})
+	t.Run(`topk_by(1)`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort(topk_by(1, label_set(10, "pod", "a", "c", "x") or label_set(3, "pod", "a", "c", "y") or label_set(12, "pod", "b"), "pod"))`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{3, 3, 3, 3, 3, 3},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("c"),
+				Value: []byte("y"),
+			},
+			{
+				Key:   []byte("pod"),
+				Value: []byte("a"),
+			},
+		}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{10, 10, 10, 10, 10, 10},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("c"),
+				Value: []byte("x"),
+			},
+			{
+				Key:   []byte("pod"),
+				Value: []byte("a"),
+			},
+		}
+		resultExpected := []netstorage.Result{r1, r2}
+		f(q, resultExpected)
+	})
+	t.Run(`bottomk_by(1) by (pod)`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort(bottomk_by(1, label_set(10, "pod", "a", "c", "x") or label_set(3, "pod", "a", "c", "y") or label_set(12, "pod", "b"), "c") by (pod))`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{3, 3, 3, 3, 3, 3},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("c"),
+				Value: []byte("y"),
+			},
+			{
+				Key:   []byte("pod"),
+				Value: []byte("a"),
+			},
+		}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{12, 12, 12, 12, 12, 12},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{{
+			Key:   []byte("pod"),
+			Value: []byte("b"),
+		}}
+		resultExpected := []netstorage.Result{r1, r2}
+		f(q, resultExpected)
+	})
 	t.Run(`topk_max(1, remaining_sum)`, func(t *testing.T) {
 		t.Parallel()
 		q := `sort_desc(topk_max(1, label_set(10, "foo", "bar") or label_set(time()/150, "baz", "sss"), "remaining_sum=foo"))`
@@ -0,0 +1,312 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// ExplainPlan is the execution plan for MetricsQL query returned by Explain.
+type ExplainPlan struct {
+	// Query is the original query.
+	Query string
+
+	// ParsedExpr is the query after parsing and expanding WITH templates.
+	ParsedExpr string
+
+	// OptimizedExpr is the query after applying optimizations. This expression is used for the query execution.
+	OptimizedExpr string
+
+	// Root is the root node of the execution plan.
+	Root *ExplainNode
+}
+
+// ExplainNode is a single node in the execution plan.
+type ExplainNode struct {
+	// Type is the node type. It may be one of selector, rollup, subquery, aggregate, transform, binaryOp, number, string or duration.
+	Type string
+
+	// Expr is the expression for the node.
+	Expr string
+
+	// Func is the function or the operation name for the node.
+	Func string
+
+	// Filters contains series filters sent to the storage for selector node.
+	//
+	// The filters include label filters pushed down from the outer expressions
+	// and the filters from `extra_label` and `extra_filters[]` query args.
+	Filters []string
+
+	// MinTimestamp and MaxTimestamp contain the time range for the data fetched from the storage for selector node.
+	MinTimestamp int64
+	MaxTimestamp int64
+
+	// IndexPath is the index used for searching series for selector node - either per-day or global.
+	IndexPath string
+
+	// Cache contains the decision on the cache usage for rollup node.
+	Cache string
+
+	// Notes contains additional details on the node evaluation such as applied optimizations.
+	Notes []string
+
+	// Children contains child nodes.
+	Children []*ExplainNode
+}
+
+// Explain returns the execution plan for q with the given ec without executing the query.
+func Explain(ec *EvalConfig, q string) (*ExplainPlan, error) {
+	ec.validate()
+
+	e, err := metricsql.Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	eOptimized, err := parsePromQLWithCache(q)
+	if err != nil {
+		return nil, err
+	}
+	root, err := explainExpr(ec, eOptimized)
+	if err != nil {
+		return nil, err
+	}
+	ep := &ExplainPlan{
+		Query:         q,
+		ParsedExpr:    string(e.AppendString(nil)),
+		OptimizedExpr: string(eOptimized.AppendString(nil)),
+		Root:          root,
+	}
+	return ep, nil
+}
+
+func explainExpr(ec *EvalConfig, e metricsql.Expr) (*ExplainNode, error) {
+	switch t := e.(type) {
+	case *metricsql.MetricExpr:
+		re := &metricsql.RollupExpr{
+			Expr: t,
+		}
+		return explainRollup(ec, "default_rollup", e, re, "")
+	case *metricsql.RollupExpr:
+		return explainRollup(ec, "default_rollup", e, t, "")
+	case *metricsql.FuncExpr:
+		if getRollupFunc(t.Name) == nil {
+			en := newExplainNode("transform", t.Name, e)
+			if err := en.addChildren(ec, t.Args); err != nil {
+				return nil, err
+			}
+			return en, nil
+		}
+		return explainRollupFunc(ec, t, e, "")
+	case *metricsql.AggrFuncExpr:
+		if callbacks := getIncrementalAggrFuncCallbacks(t.Name); callbacks != nil {
+			if fe, _ := tryGetArgRollupFuncWithMetricExpr(t); fe != nil {
+				en, err := explainRollupFunc(ec, fe, e, t.Name)
+				if err != nil {
+					return nil, err
+				}
+				en.Type = "aggregate"
+				en.Func = t.Name
+				en.Notes = append(en.Notes, fmt.Sprintf("%s() is calculated incrementally over %s() results without keeping all the selected series in memory", t.Name, fe.Name))
+				return en, nil
+			}
+		}
+		en := newExplainNode("aggregate", t.Name, e)
+		if err := en.addChildren(ec, t.Args); err != nil {
+			return nil, err
+		}
+		return en, nil
+	case *metricsql.BinaryOpExpr:
+		en := newExplainNode("binaryOp", t.Op, e)
+		left, right := "left", "right"
+		exprFirst, exprSecond := t.Left, t.Right
+		switch strings.ToLower(t.Op) {
+		case "and", "if":
+			left, right = right, left
+			exprFirst, exprSecond = exprSecond, exprFirst
+		}
+		if canPushdownCommonFilters(t) {
+			en.Notes = append(en.Notes, fmt.Sprintf("the %s side is executed at first; common label filters from its results are pushed down to the %s side", left, right))
+		} else {
+			en.Notes = append(en.Notes, "the left and the right sides are executed in parallel without pushing down common label filters")
+		}
+		if err := en.addChildren(ec, []metricsql.Expr{exprFirst, exprSecond}); err != nil {
+			return nil, err
+		}
+		return en, nil
+	case *metricsql.NumberExpr:
+		return newExplainNode("number", "", e), nil
+	case *metricsql.StringExpr:
+		return newExplainNode("string", "", e), nil
+	case *metricsql.DurationExpr:
+		return newExplainNode("duration", "", e), nil
+	default:
+		return nil, fmt.Errorf("unexpected expression %q", e.AppendString(nil))
+	}
+}
+
+func explainRollupFunc(ec *EvalConfig, fe *metricsql.FuncExpr, expr metricsql.Expr, aggrFuncName string) (*ExplainNode, error) {
+	rollupArgIdx := metricsql.GetRollupArgIdx(fe)
+	if len(fe.Args) <= rollupArgIdx {
+		return nil, fmt.Errorf("expecting at least %d args to %q; got %d args; expr: %q", rollupArgIdx+1, fe.Name, len(fe.Args), fe.AppendString(nil))
+	}
+	re := getRollupExprArg(fe.Args[rollupArgIdx])
+	en, err := explainRollup(ec, fe.Name, expr, re, aggrFuncName)
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range fe.Args {
+		if i == rollupArgIdx {
+			continue
+		}
+		child, err := explainExpr(ec, arg)
+		if err != nil {
+			return nil, err
+		}
+		en.Children = append(en.Children, child)
+	}
+	return en, nil
+}
+
+func explainRollup(ec *EvalConfig, funcName string, expr metricsql.Expr, re *metricsql.RollupExpr, aggrFuncName string) (*ExplainNode, error) {
+	funcName = strings.ToLower(funcName)
+	en := newExplainNode("rollup", funcName, expr)
+	if re.At != nil {
+		en.Notes = append(en.Notes, "the `@` modifier is evaluated before the rollup; the time range below doesn't account for it")
+		child, err := explainExpr(ec, re.At)
+		if err != nil {
+			return nil, err
+		}
+		en.Children = append(en.Children, child)
+	}
+	ecNew := ec
+	if re.Offset != nil {
+		offset := re.Offset.Duration(ec.Step)
+		ecNew = copyEvalConfig(ecNew)
+		ecNew.Start -= offset
+		ecNew.End -= offset
+	}
+	if funcName == "rollup_candlestick" {
+		step := ecNew.Step
+		ecNew = copyEvalConfig(ecNew)
+		ecNew.Start += step
+		ecNew.End += step
+	}
+	window, err := re.Window.NonNegativeDuration(ec.Step)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse lookbehind window in square brackets at %s: %w", expr.AppendString(nil), err)
+	}
+
+	me, ok := re.Expr.(*metricsql.MetricExpr)
+	if !ok {
+		en.Type = "subquery"
+		en.Cache = "none: rollup results over subqueries aren't cached"
+		step, err := re.Step.NonNegativeDuration(ecNew.Step)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse step in square brackets at %s: %w", expr.AppendString(nil), err)
+		}
+		if step == 0 {
+			step = ecNew.Step
+		}
+		ecSQ := copyEvalConfig(ecNew)
+		ecSQ.Start -= window + step + maxSilenceInterval()
+		ecSQ.End += step
+		ecSQ.Step = step
+		ecSQ.Start, ecSQ.End = alignStartEnd(ecSQ.Start, ecSQ.End, ecSQ.Step)
+		child, err := explainExpr(ecSQ, re.Expr)
+		if err != nil {
+			return nil, err
+		}
+		en.Children = append(en.Children, child)
+		return en, nil
+	}
+
+	en.Cache = getRollupCacheDecision(ecNew, funcName, aggrFuncName, window)
+	selector := newExplainNode("selector", "", me)
+	if me.IsEmpty() {
+		selector.Notes = append(selector.Notes, "empty selector isn't sent to the storage")
+		en.Children = append(en.Children, selector)
+		return en, nil
+	}
+	tfss := searchutil.ToTagFilterss(me.LabelFilterss)
+	tfss = searchutil.JoinTagFilterss(tfss, ec.EnforcedTagFilterss)
+	for _, tfs := range tfss {
+		selector.Filters = append(selector.Filters, tagFiltersString(tfs))
+	}
+	minTimestamp := ecNew.Start
+	if needSilenceIntervalForRollupFunc[funcName] {
+		minTimestamp -= maxSilenceInterval()
+	}
+	if window > ecNew.Step {
+		minTimestamp -= window
+	} else {
+		minTimestamp -= ecNew.Step
+	}
+	selector.MinTimestamp = minTimestamp
+	selector.MaxTimestamp = ecNew.End
+	selector.IndexPath = netstorage.GetIndexSearchPath(storage.TimeRange{
+		MinTimestamp: minTimestamp,
+		MaxTimestamp: ecNew.End,
+	})
+	en.Children = append(en.Children, selector)
+	return en, nil
+}
+
+// getRollupCacheDecision returns the decision on the cache usage for funcName rollup with the given window over a series selector.
+//
+// The decision must be in sync with evalRollupFuncWithMetricExpr and evalInstantRollup.
+func getRollupCacheDecision(ec *EvalConfig, funcName, aggrFuncName string, window int64) string {
+	if !ec.mayCache() {
+		switch {
+		case *disableCache:
+			return "disabled: -search.disableCache command-line flag is set"
+		case !ec.MayCache:
+			return "disabled: nocache query arg is set"
+		default:
+			return "disabled: start and end aren't aligned to step"
+		}
+	}
+	if ec.Start != ec.End {
+		return "rollup result cache"
+	}
+	if window < minWindowForInstantRollupOptimization.Milliseconds() {
+		return fmt.Sprintf("none: lookbehind window=%dms is smaller than -search.minWindowForInstantRollupOptimization=%s",
+			window, minWindowForInstantRollupOptimization)
+	}
+	if reason := getInstantRollupOptimizationSkipReason(funcName, aggrFuncName); reason != "" {
+		return "none: " + reason
+	}
+	return "instant rollup cache"
+}
+
+func tagFiltersString(tfs []storage.TagFilter) string {
+	a := make([]string, len(tfs))
+	for i := range tfs {
+		a[i] = tfs[i].String()
+	}
+	return "{" + strings.Join(a, ",") + "}"
+}
+
+func newExplainNode(typ, funcName string, e metricsql.Expr) *ExplainNode {
+	return &ExplainNode{
+		Type: typ,
+		Func: funcName,
+		Expr: string(e.AppendString(nil)),
+	}
+}
+
+func (en *ExplainNode) addChildren(ec *EvalConfig, args []metricsql.Expr) error {
+	for _, arg := range args {
+		child, err := explainExpr(ec, arg)
+		if err != nil {
+			return err
+		}
+		en.Children = append(en.Children, child)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+)
+
+func TestExplain(t *testing.T) {
+	silenceInterval := maxSilenceInterval()
+
+	f := func(q string, start, end int64, mayCache bool, optimizedExprExpected string, rootExpected *ExplainNode) {
+		t.Helper()
+
+		ec := &EvalConfig{
+			Start:              start,
+			End:                end,
+			Step:               60e3,
+			MaxPointsPerSeries: 1e4,
+			MaxSeries:          1000,
+			Deadline:           searchutil.NewDeadline(time.Now(), time.Minute, ""),
+			MayCache:           mayCache,
+		}
+		ep, err := Explain(ec, q)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ep.OptimizedExpr != optimizedExprExpected {
+			t.Fatalf("unexpected optimized expr; got %s; want %s", ep.OptimizedExpr, optimizedExprExpected)
+		}
+		if !reflect.DeepEqual(ep.Root, rootExpected) {
+			t.Fatalf("unexpected plan\ngot\n%#v\nwant\n%#v", ep.Root, rootExpected)
+		}
+	}
+
+	// range query over series selector with the enabled cache
+	f(`foo{bar="baz"}`, 3600e3, 7200e3, true, `foo{bar="baz"}`, &ExplainNode{
+		Type:  "rollup",
+		Func:  "default_rollup",
+		Expr:  `foo{bar="baz"}`,
+		Cache: "rollup result cache",
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo{bar="baz"}`,
+				Filters:      []string{`{__name__="foo",bar="baz"}`},
+				MinTimestamp: 3600e3 - 60e3 - silenceInterval,
+				MaxTimestamp: 7200e3,
+			},
+		},
+	})
+
+	// instant query with disabled cache
+	f(`rate(foo[5m])`, 3600e3, 3600e3, false, `rate(foo[5m])`, &ExplainNode{
+		Type:  "rollup",
+		Func:  "rate",
+		Expr:  `rate(foo[5m])`,
+		Cache: "disabled: nocache query arg is set",
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 3600e3 - 300e3 - silenceInterval,
+				MaxTimestamp: 3600e3,
+			},
+		},
+	})
+
+	// instant query with too small lookbehind window
+	f(`sum(rate(foo[5m]))`, 3600e3, 3600e3, true, `sum(rate(foo[5m]))`, &ExplainNode{
+		Type:  "aggregate",
+		Func:  "sum",
+		Expr:  `sum(rate(foo[5m]))`,
+		Cache: "none: lookbehind window=300000ms is smaller than -search.minWindowForInstantRollupOptimization=3h0m0s",
+		Notes: []string{"sum() is calculated incrementally over rate() results without keeping all the selected series in memory"},
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 3600e3 - 300e3 - silenceInterval,
+				MaxTimestamp: 3600e3,
+			},
+		},
+	})
+
+	// instant rollup cache
+	f(`count_over_time(foo[1d])`, 100*3600e3, 100*3600e3, true, `count_over_time(foo[1d])`, &ExplainNode{
+		Type:  "rollup",
+		Func:  "count_over_time",
+		Expr:  `count_over_time(foo[1d])`,
+		Cache: "instant rollup cache",
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 100*3600e3 - 24*3600e3,
+				MaxTimestamp: 100 * 3600e3,
+			},
+		},
+	})
+
+	// instant rollup cache for incremental aggregate function over the matching rollup
+	f(`max(max_over_time(foo[1d]))`, 100*3600e3, 100*3600e3, true, `max(max_over_time(foo[1d]))`, &ExplainNode{
+		Type:  "aggregate",
+		Func:  "max",
+		Expr:  `max(max_over_time(foo[1d]))`,
+		Cache: "instant rollup cache",
+		Notes: []string{"max() is calculated incrementally over max_over_time() results without keeping all the selected series in memory"},
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 100*3600e3 - 24*3600e3,
+				MaxTimestamp: 100 * 3600e3,
+			},
+		},
+	})
+	f(`count_eq_over_time(foo[1d], 1)`, 100*3600e3, 100*3600e3, true, `count_eq_over_time(foo[1d], 1)`, &ExplainNode{
+		Type:  "rollup",
+		Func:  "count_eq_over_time",
+		Expr:  `count_eq_over_time(foo[1d], 1)`,
+		Cache: "instant rollup cache",
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 100*3600e3 - 24*3600e3,
+				MaxTimestamp: 100 * 3600e3,
+			},
+			{
+				Type: "number",
+				Expr: `1`,
+			},
+		},
+	})
+
+	// instant rollup optimization isn't implemented for the given incremental aggregate function
+	f(`avg(avg_over_time(foo[1d]))`, 100*3600e3, 100*3600e3, true, `avg(avg_over_time(foo[1d]))`, &ExplainNode{
+		Type:  "aggregate",
+		Func:  "avg",
+		Expr:  `avg(avg_over_time(foo[1d]))`,
+		Cache: "none: instant rollup optimization isn't implemented for avg() over avg_over_time()",
+		Notes: []string{"avg() is calculated incrementally over avg_over_time() results without keeping all the selected series in memory"},
+		Children: []*ExplainNode{
+			{
+				Type:         "selector",
+				Expr:         `foo`,
+				Filters:      []string{`{__name__="foo"}`},
+				MinTimestamp: 100*3600e3 - 24*3600e3,
+				MaxTimestamp: 100 * 3600e3,
+			},
+		},
+	})
+
+	// binary op with pushed down filters
+	f(`foo{a="b"} + bar`, 3600e3, 7200e3, true, `foo{a="b"} + bar{a="b"}`, &ExplainNode{
+		Type:  "binaryOp",
+		Func:  "+",
+		Expr:  `foo{a="b"} + bar{a="b"}`,
+		Notes: []string{"the left side is executed at first; common label filters from its results are pushed down to the right side"},
+		Children: []*ExplainNode{
+			{
+				Type:  "rollup",
+				Func:  "default_rollup",
+				Expr:  `foo{a="b"}`,
+				Cache: "rollup result cache",
+				Children: []*ExplainNode{
+					{
+						Type:         "selector",
+						Expr:         `foo{a="b"}`,
+						Filters:      []string{`{__name__="foo",a="b"}`},
+						MinTimestamp: 3600e3 - 60e3 - silenceInterval,
+						MaxTimestamp: 7200e3,
+					},
+				},
+			},
+			{
+				Type:  "rollup",
+				Func:  "default_rollup",
+				Expr:  `bar{a="b"}`,
+				Cache: "rollup result cache",
+				Children: []*ExplainNode{
+					{
+						Type:         "selector",
+						Expr:         `bar{a="b"}`,
+						Filters:      []string{`{__name__="bar",a="b"}`},
+						MinTimestamp: 3600e3 - 60e3 - silenceInterval,
+						MaxTimestamp: 7200e3,
+					},
+				},
+			},
+		},
+	})
+
+	// transform over number
+	f(`abs(-1)`, 3600e3, 7200e3, true, `abs(-1)`, &ExplainNode{
+		Type: "transform",
+		Func: "abs",
+		Expr: `abs(-1)`,
+		Children: []*ExplainNode{
+			{
+				Type: "number",
+				Expr: `-1`,
+			},
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestExtraMetricsQLFuncs(t *testing.T) {
+	f := func(q string, isAggr bool) {
+		t.Helper()
+
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", q, err)
+		}
+		if isAggr {
+			if _, ok := e.(*metricsql.AggrFuncExpr); !ok {
+				t.Fatalf("unexpected expression type for %q; got %T; want *metricsql.AggrFuncExpr", q, e)
+			}
+			return
+		}
+		fe, ok := e.(*metricsql.FuncExpr)
+		if !ok {
+			t.Fatalf("unexpected expression type for %q; got %T; want *metricsql.FuncExpr", q, e)
+		}
+		if idx := metricsql.GetRollupArgIdx(fe); idx != 0 {
+			t.Fatalf("unexpected rollup arg index for %q; got %d; want 0", q, idx)
+		}
+	}
+
+	f(`predict_seasonal(foo[1d], 3600, 86400)`, false)
+	f(`topk_by(3, rate(foo), "pod") by (namespace)`, true)
+	f(`bottomk_by(3, rate(foo), "pod")`, true)
+}
@@ -20,6 +20,28 @@ func IsRollup(s string) (childQuery string, window, step, offset *metricsql.Dura
 	return string(wrappedQuery), re.Window, re.Step, re.Offset
 }
 
+// IsMetricSelector verifies whether s contains PromQL metric selector with optional offset.
+//
+// It returns the metric selector without offset and the offset.
+func IsMetricSelector(s string) (childQuery string, offset *metricsql.DurationExpr) {
+	expr, err := parsePromQLWithCache(s)
+	if err != nil {
+		return
+	}
+	if re, ok := expr.(*metricsql.RollupExpr); ok {
+		if re.Window != nil || re.Step != nil || re.At != nil {
+			return
+		}
+		offset = re.Offset
+		expr = re.Expr
+	}
+	me, ok := expr.(*metricsql.MetricExpr)
+	if !ok || len(me.LabelFilterss) == 0 {
+		return "", nil
+	}
+	return string(me.AppendString(nil)), offset
+}
+
 // IsMetricSelectorWithRollup verifies whether s contains PromQL metric selector
 // wrapped into rollup.
 //
@@ -0,0 +1,154 @@
+package promql
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var disableQueryCollapsing = flag.Bool("search.disableQueryCollapsing", false, "Whether to disable collapsing of identical concurrently executed queries into a single execution. "+
+	"By default, identical queries with the same time range and step, which are received while the first such query is executing, "+
+	"wait for its result instead of being executed again. See https://docs.victoriametrics.com/#query-collapsing")
+
+var queriesCollapsed = metrics.NewCounter(`vm_queries_collapsed_total`)
+
+// inflightQuery is a query, which is currently executing.
+type inflightQuery struct {
+	// doneCh is closed when the query execution is finished.
+	doneCh chan struct{}
+
+	// waiters is the number of identical queries waiting for the result of the query.
+	//
+	// It is protected by inflightQueriesLock.
+	waiters int
+
+	// The following fields are set before closing doneCh.
+	result        []netstorage.Result
+	seriesFetched int64
+	err           error
+}
+
+var (
+	inflightQueriesLock sync.Mutex
+	inflightQueries     = make(map[string]*inflightQuery)
+)
+
+// execCollapsed executes q for the given ec.
+//
+// If an identical query is already executing, then execCollapsed waits for its result instead of executing q again.
+// Every caller receives its own copy of the result, so it can be modified by the caller.
+func execCollapsed(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly bool) ([]netstorage.Result, error) {
+	key := marshalInflightQueryKey(nil, ec, q, isFirstPointOnly)
+
+	inflightQueriesLock.Lock()
+	iq := inflightQueries[string(key)]
+	if iq != nil {
+		iq.waiters++
+		inflightQueriesLock.Unlock()
+
+		queriesCollapsed.Inc()
+		return iq.waitResult(qt, ec)
+	}
+	iq = &inflightQuery{
+		doneCh: make(chan struct{}),
+	}
+	inflightQueries[string(key)] = iq
+	inflightQueriesLock.Unlock()
+
+	result, err := execInternal(qt, ec, q, isFirstPointOnly)
+
+	inflightQueriesLock.Lock()
+	delete(inflightQueries, string(key))
+	waiters := iq.waiters
+	inflightQueriesLock.Unlock()
+
+	if waiters == 0 {
+		// Fast path - there are no identical queries waiting for the result.
+		close(iq.doneCh)
+		return result, err
+	}
+
+	// Slow path - share the result with the waiting queries.
+	// The original result mustn't be modified, since it is copied by the waiting queries.
+	iq.result = result
+	iq.err = err
+	if qs := ec.QueryStats; qs != nil {
+		iq.seriesFetched = qs.SeriesFetched.Load()
+	}
+	close(iq.doneCh)
+	qt.Printf("share the result with %d identical concurrently executed queries", waiters)
+
+	if err != nil {
+		return nil, err
+	}
+	return copyResults(result), nil
+}
+
+func (iq *inflightQuery) waitResult(qt *querytracer.Tracer, ec *EvalConfig) ([]netstorage.Result, error) {
+	qt.Printf("wait for the result of identical concurrently executed query")
+
+	timeout := time.Until(time.Unix(int64(ec.Deadline.Deadline()), 0))
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout exceeded before waiting for the result of identical concurrently executed query: %s", ec.Deadline.String())
+	}
+	t := timerpool.Get(timeout)
+	select {
+	case <-iq.doneCh:
+		timerpool.Put(t)
+	case <-t.C:
+		timerpool.Put(t)
+		return nil, fmt.Errorf("timeout exceeded while waiting for the result of identical concurrently executed query: %s", ec.Deadline.String())
+	}
+
+	if iq.err != nil {
+		return nil, iq.err
+	}
+	ec.QueryStats.addSeriesFetched(int(iq.seriesFetched))
+	qt.Printf("obtain %d series from identical concurrently executed query", len(iq.result))
+	return copyResults(iq.result), nil
+}
+
+// copyResults returns a copy of src, which can be modified by the caller.
+//
+// Values are copied, since callers may modify them in place. Metric names and timestamps
+// are shared with src, since callers never modify them in place.
+func copyResults(src []netstorage.Result) []netstorage.Result {
+	dst := make([]netstorage.Result, len(src))
+	for i := range src {
+		dst[i] = src[i]
+		dst[i].Values = append([]float64{}, src[i].Values...)
+	}
+	return dst
+}
+
+// marshalInflightQueryKey appends the key for identifying identical queries to dst and returns the result.
+//
+// The key contains all the args, which may affect the query result.
+func marshalInflightQueryKey(dst []byte, ec *EvalConfig, q string, isFirstPointOnly bool) []byte {
+	dst = encoding.MarshalBytes(dst, []byte(q))
+	dst = encoding.MarshalBool(dst, isFirstPointOnly)
+	dst = encoding.MarshalInt64(dst, ec.Start)
+	dst = encoding.MarshalInt64(dst, ec.End)
+	dst = encoding.MarshalInt64(dst, ec.Step)
+	dst = encoding.MarshalInt64(dst, int64(ec.MaxSeries))
+	dst = encoding.MarshalInt64(dst, int64(ec.MaxPointsPerSeries))
+	dst = encoding.MarshalBool(dst, ec.MayCache)
+	dst = encoding.MarshalInt64(dst, ec.LookbackDelta)
+	dst = encoding.MarshalInt64(dst, int64(ec.RoundDigits))
+	dst = append(dst, byte(ec.IOClass))
+	dst = encoding.MarshalVarUint64(dst, uint64(len(ec.EnforcedTagFilterss)))
+	for _, tfs := range ec.EnforcedTagFilterss {
+		dst = encoding.MarshalVarUint64(dst, uint64(len(tfs)))
+		for i := range tfs {
+			dst = tfs[i].Marshal(dst)
+		}
+	}
+	return dst
+}
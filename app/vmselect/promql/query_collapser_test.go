@@ -0,0 +1,189 @@
+package promql
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestExecCollapsedWaitsForInflightQuery(t *testing.T) {
+	ec := &EvalConfig{
+		Start:              1000e3,
+		End:                2000e3,
+		Step:               200e3,
+		MaxPointsPerSeries: 1e4,
+		MaxSeries:          1000,
+		Deadline:           searchutil.NewDeadline(time.Now(), time.Minute, ""),
+		RoundDigits:        100,
+		QueryStats:         &QueryStats{},
+	}
+	q := "collapsed_query_test"
+	key := marshalInflightQueryKey(nil, ec, q, false)
+
+	// Register the in-flight query, so the queries below wait for its result.
+	iq := &inflightQuery{
+		doneCh: make(chan struct{}),
+	}
+	inflightQueriesLock.Lock()
+	inflightQueries[string(key)] = iq
+	inflightQueriesLock.Unlock()
+	defer func() {
+		inflightQueriesLock.Lock()
+		delete(inflightQueries, string(key))
+		inflightQueriesLock.Unlock()
+	}()
+
+	const waiters = 5
+	collapsedBefore := queriesCollapsed.Get()
+	results := make([][]netstorage.Result, waiters)
+	errs := make([]error, waiters)
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = execCollapsed(nil, ec, q, false)
+		}(i)
+	}
+
+	// Wait until all the queries start waiting for the in-flight query.
+	for {
+		inflightQueriesLock.Lock()
+		n := iq.waiters
+		inflightQueriesLock.Unlock()
+		if n == waiters {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	resultExpected := []netstorage.Result{{
+		MetricName: storage.MetricName{
+			MetricGroup: []byte("foo"),
+		},
+		Values:     []float64{1, 2, 3},
+		Timestamps: []int64{1000e3, 1200e3, 1400e3},
+	}}
+	iq.result = resultExpected
+	iq.seriesFetched = 1
+	close(iq.doneCh)
+	wg.Wait()
+
+	for i := 0; i < waiters; i++ {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %s", errs[i])
+		}
+		testResultsEqual(t, results[i], resultExpected)
+	}
+
+	// Make sure the results can be modified independently.
+	results[0][0].Values[0] = 42
+	if v := resultExpected[0].Values[0]; v != 1 {
+		t.Fatalf("unexpected modification of the shared result; got %v; want 1", v)
+	}
+	if v := results[1][0].Values[0]; v != 1 {
+		t.Fatalf("unexpected modification of the result for another query; got %v; want 1", v)
+	}
+
+	if n := queriesCollapsed.Get() - collapsedBefore; n != waiters {
+		t.Fatalf("unexpected number of collapsed queries; got %d; want %d", n, waiters)
+	}
+	if n := ec.QueryStats.SeriesFetched.Load(); n != waiters {
+		t.Fatalf("unexpected number of fetched series; got %d; want %d", n, waiters)
+	}
+}
+
+func TestExecCollapsedError(t *testing.T) {
+	ec := &EvalConfig{
+		Start:              1000e3,
+		End:                2000e3,
+		Step:               200e3,
+		MaxPointsPerSeries: 1e4,
+		MaxSeries:          1000,
+		Deadline:           searchutil.NewDeadline(time.Now(), time.Minute, ""),
+		RoundDigits:        100,
+	}
+	q := "collapsed_query_error_test"
+	key := marshalInflightQueryKey(nil, ec, q, false)
+
+	iq := &inflightQuery{
+		doneCh: make(chan struct{}),
+		err:    fmt.Errorf("some error"),
+	}
+	close(iq.doneCh)
+	inflightQueriesLock.Lock()
+	inflightQueries[string(key)] = iq
+	inflightQueriesLock.Unlock()
+	defer func() {
+		inflightQueriesLock.Lock()
+		delete(inflightQueries, string(key))
+		inflightQueriesLock.Unlock()
+	}()
+
+	if _, err := execCollapsed(nil, ec, q, false); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestMarshalInflightQueryKey(t *testing.T) {
+	newEvalConfig := func() *EvalConfig {
+		return &EvalConfig{
+			Start:              1000e3,
+			End:                2000e3,
+			Step:               200e3,
+			MaxPointsPerSeries: 1e4,
+			MaxSeries:          1000,
+			MayCache:           true,
+			RoundDigits:        100,
+		}
+	}
+	keyOrig := string(marshalInflightQueryKey(nil, newEvalConfig(), "foo", false))
+
+	f := func(ec *EvalConfig, q string, isFirstPointOnly bool) {
+		t.Helper()
+		key := string(marshalInflightQueryKey(nil, ec, q, isFirstPointOnly))
+		if key == keyOrig {
+			t.Fatalf("unexpected key match for q=%q, isFirstPointOnly=%v, ec=%+v", q, isFirstPointOnly, ec)
+		}
+	}
+
+	// The same args must result in the same key.
+	if key := string(marshalInflightQueryKey(nil, newEvalConfig(), "foo", false)); key != keyOrig {
+		t.Fatalf("unexpected key mismatch for identical args")
+	}
+
+	f(newEvalConfig(), "bar", false)
+	f(newEvalConfig(), "foo", true)
+
+	ec := newEvalConfig()
+	ec.Start++
+	f(ec, "foo", false)
+
+	ec = newEvalConfig()
+	ec.End++
+	f(ec, "foo", false)
+
+	ec = newEvalConfig()
+	ec.Step++
+	f(ec, "foo", false)
+
+	ec = newEvalConfig()
+	ec.MayCache = false
+	f(ec, "foo", false)
+
+	ec = newEvalConfig()
+	ec.RoundDigits = 2
+	f(ec, "foo", false)
+
+	ec = newEvalConfig()
+	ec.EnforcedTagFilterss = [][]storage.TagFilter{{{
+		Key:   []byte("job"),
+		Value: []byte("foo"),
+	}}}
+	f(ec, "foo", false)
+}
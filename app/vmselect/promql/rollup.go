@@ -67,6 +67,7 @@ var rollupFuncs = map[string]newRollupFunc{
 	"mode_over_time":          newRollupFuncOneArg(rollupModeOverTime),
 	"outlier_iqr_over_time":   newRollupFuncOneArg(rollupOutlierIQR),
 	"predict_linear":          newRollupPredictLinear,
+	"predict_seasonal":        newRollupPredictSeasonal,
 	"present_over_time":       newRollupFuncOneArg(rollupPresent),
 	"quantile_over_time":      newRollupQuantile,
 	"quantiles_over_time":     newRollupQuantiles,
@@ -276,6 +277,7 @@ var rollupFuncsKeepMetricName = map[string]bool{
 	"min_over_time":         true,
 	"mode_over_time":        true,
 	"predict_linear":        true,
+	"predict_seasonal":      true,
 	"quantile_over_time":    true,
 	"quantiles_over_time":   true,
 	"rollup":                true,
@@ -1018,8 +1020,12 @@ func newRollupFuncOneOrTwoArgs(rf rollupFunc) newRollupFunc {
 }
 
 func newRollupHoltWinters(args []any) (rollupFunc, error) {
-	if err := expectRollupArgsNum(args, 3); err != nil {
-		return nil, err
+	if len(args) == 1 {
+		// The smoothing factor and the trend factor are omitted, so they are tuned automatically.
+		return rollupHoltWintersAutoTuned, nil
+	}
+	if len(args) != 3 {
+		return nil, fmt.Errorf("unexpected number of args; got %d; want 1 or 3", len(args))
 	}
 	sfs, err := getScalar(args[1], 1)
 	if err != nil {
@@ -1030,12 +1036,6 @@ func newRollupHoltWinters(args []any) (rollupFunc, error) {
 		return nil, err
 	}
 	rf := func(rfa *rollupFuncArg) float64 {
-		// There is no need in handling NaNs here, since they must be cleaned up
-		// before calling rollup funcs.
-		values := rfa.values
-		if len(values) == 0 {
-			return nan
-		}
 		sf := sfs[rfa.idx]
 		if sf < 0 || sf > 1 {
 			return nan
@@ -1044,27 +1044,65 @@ func newRollupHoltWinters(args []any) (rollupFunc, error) {
 		if tf < 0 || tf > 1 {
 			return nan
 		}
+		v, _ := holtWinters(rfa.prevValue, rfa.values, sf, tf)
+		return v
+	}
+	return rf, nil
+}
 
-		// See https://en.wikipedia.org/wiki/Exponential_smoothing#Double_exponential_smoothing .
-		// TODO: determine whether this shit really works.
-		s0 := rfa.prevValue
-		if math.IsNaN(s0) {
-			s0 = values[0]
-			values = values[1:]
-			if len(values) == 0 {
-				return s0
+func rollupHoltWintersAutoTuned(rfa *rollupFuncArg) float64 {
+	if len(rfa.values) == 0 {
+		return nan
+	}
+
+	// Select the smoothing factor and the trend factor with the minimum sum of squared one-step-ahead forecast errors
+	// over the [0.1 ... 0.9] grid with 0.1 step.
+	vBest := nan
+	sseMin := math.Inf(1)
+	for i := 1; i <= 9; i++ {
+		sf := float64(i) / 10
+		for j := 1; j <= 9; j++ {
+			tf := float64(j) / 10
+			v, sse := holtWinters(rfa.prevValue, rfa.values, sf, tf)
+			if sse < sseMin {
+				vBest = v
+				sseMin = sse
 			}
 		}
-		b0 := values[0] - s0
-		for _, v := range values {
-			s1 := sf*v + (1-sf)*(s0+b0)
-			b1 := tf*(s1-s0) + (1-tf)*b0
-			s0 = s1
-			b0 = b1
+	}
+	return vBest
+}
+
+// holtWinters returns Holt-Winters value for values with the given smoothing factor sf and trend factor tf.
+//
+// It also returns the sum of squared one-step-ahead forecast errors, which can be used for tuning sf and tf.
+func holtWinters(prevValue float64, values []float64, sf, tf float64) (float64, float64) {
+	// There is no need in handling NaNs here, since they must be cleaned up
+	// before calling rollup funcs.
+	if len(values) == 0 {
+		return nan, nan
+	}
+
+	// See https://en.wikipedia.org/wiki/Exponential_smoothing#Double_exponential_smoothing .
+	s0 := prevValue
+	if math.IsNaN(s0) {
+		s0 = values[0]
+		values = values[1:]
+		if len(values) == 0 {
+			return s0, 0
 		}
-		return s0
 	}
-	return rf, nil
+	b0 := values[0] - s0
+	sse := float64(0)
+	for _, v := range values {
+		d := v - (s0 + b0)
+		sse += d * d
+		s1 := sf*v + (1-sf)*(s0+b0)
+		b1 := tf*(s1-s0) + (1-tf)*b0
+		s0 = s1
+		b0 = b1
+	}
+	return s0, sse
 }
 
 func newRollupPredictLinear(args []any) (rollupFunc, error) {
@@ -1086,6 +1124,76 @@ func newRollupPredictLinear(args []any) (rollupFunc, error) {
 	return rf, nil
 }
 
+func newRollupPredictSeasonal(args []any) (rollupFunc, error) {
+	if err := expectRollupArgsNum(args, 3); err != nil {
+		return nil, err
+	}
+	secs, err := getScalar(args[1], 1)
+	if err != nil {
+		return nil, err
+	}
+	periods, err := getScalar(args[2], 2)
+	if err != nil {
+		return nil, err
+	}
+	rf := func(rfa *rollupFuncArg) float64 {
+		values := rfa.values
+		timestamps := rfa.timestamps
+		if len(values) < 2 {
+			return nan
+		}
+		period := int64(periods[rfa.idx] * 1e3)
+		if period <= 0 {
+			return nan
+		}
+		duration := timestamps[len(timestamps)-1] - timestamps[0]
+		if duration < period {
+			// The seasonal component cannot be detected if samples cover less than a single period.
+			return nan
+		}
+		v, k := linearRegression(values, timestamps, rfa.currTimestamp)
+		if math.IsNaN(v) {
+			return nan
+		}
+		sec := secs[rfa.idx]
+		seasonal := getSeasonalComponent(values, timestamps, v, k, rfa.currTimestamp, rfa.currTimestamp+int64(sec*1e3), period)
+		return v + k*sec + seasonal
+	}
+	return rf, nil
+}
+
+// getSeasonalComponent returns the average deviation of values from the linear trend v + k*t with the intercept at interceptTime
+// for samples with the same phase within the given period as the phase of targetTimestamp.
+//
+// Samples with the phase differing by up to a half of the average interval between samples are taken into account.
+func getSeasonalComponent(values []float64, timestamps []int64, v, k float64, interceptTime, targetTimestamp, period int64) float64 {
+	tolerance := (timestamps[len(timestamps)-1] - timestamps[0]) / int64(len(timestamps)-1) / 2
+	sum := float64(0)
+	n := 0
+	for i, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
+		phase := (timestamps[i] - targetTimestamp) % period
+		if phase < 0 {
+			phase += period
+		}
+		if phase > period/2 {
+			phase = period - phase
+		}
+		if phase > tolerance {
+			continue
+		}
+		trend := v + k*float64(timestamps[i]-interceptTime)/1e3
+		sum += value - trend
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
 func linearRegression(values []float64, timestamps []int64, interceptTime int64) (float64, float64) {
 	if len(values) == 0 {
 		return nan, nan
@@ -0,0 +1,178 @@
+package promql
+
+import (
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var disableBlockSummaries = flag.Bool("search.disableBlockSummaries", false, "Whether to disable calculating min_over_time(), max_over_time() and avg_over_time() "+
+	"from per-block summaries for blocks of raw samples, which are fully covered by lookbehind windows. "+
+	"By default, such blocks aren't unpacked, which speeds up queries over long time ranges with big step. See https://docs.victoriametrics.com/#block-summaries")
+
+// rollupFuncsWithBlockSummaries contains rollup functions, which can be calculated from block summaries.
+var rollupFuncsWithBlockSummaries = map[string]bool{
+	"avg_over_time": true,
+	"max_over_time": true,
+	"min_over_time": true,
+}
+
+// blockSummaryRollup calculates rollup function over raw samples and block summaries.
+type blockSummaryRollup struct {
+	funcName string
+	rc       *rollupConfig
+	window   int64
+}
+
+// newBlockSummaryRollup returns blockSummaryRollup for the given funcName and rcs.
+//
+// nil is returned if block summaries cannot be used for calculating the given rollup.
+func newBlockSummaryRollup(funcName string, rcs []*rollupConfig) *blockSummaryRollup {
+	if *disableBlockSummaries {
+		return nil
+	}
+	funcName = strings.ToLower(funcName)
+	if !rollupFuncsWithBlockSummaries[funcName] || len(rcs) != 1 {
+		return nil
+	}
+	rc := rcs[0]
+	window := rc.Window
+	if window <= 0 {
+		if rc.MayAdjustWindow {
+			// The window depends on the interval between raw samples, so it cannot be determined in advance.
+			return nil
+		}
+		window = rc.Step
+	}
+	return &blockSummaryRollup{
+		funcName: funcName,
+		rc:       rc,
+		window:   window,
+	}
+}
+
+// getCanUseBlockSummaryFunc returns the function for passing to netstorage.Results.RunParallelWithBlockSummaries.
+//
+// nil is returned if bsr is nil.
+func (bsr *blockSummaryRollup) getCanUseBlockSummaryFunc() func(bs *storage.BlockSummary) bool {
+	if bsr == nil {
+		return nil
+	}
+	return bsr.canUseBlockSummary
+}
+
+// canUseBlockSummary returns true if bs can be used instead of raw samples for the block.
+//
+// This is the case if every lookbehind window, which intersects with the block, covers the whole block.
+func (bsr *blockSummaryRollup) canUseBlockSummary(bs *storage.BlockSummary) bool {
+	timestamps := bsr.rc.Timestamps
+
+	// Lookbehind windows cover (tEnd-window ... tEnd] time ranges, so skip windows ending before the block start.
+	i := sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i] >= bs.MinTimestamp
+	})
+	for _, tEnd := range timestamps[i:] {
+		tStart := tEnd - bsr.window
+		if tStart >= bs.MaxTimestamp {
+			// This and the remaining windows do not intersect with the block.
+			break
+		}
+		if tStart >= bs.MinTimestamp || tEnd < bs.MaxTimestamp {
+			// The window covers only a part of the block.
+			return false
+		}
+	}
+	return true
+}
+
+// do calculates the rollup over raw samples with the given values and timestamps and the given summaries for blocks, which weren't unpacked.
+//
+// It is expected that every summary passed canUseBlockSummary check and summaries are sorted by MinTimestamp.
+//
+// It appends the calculated values to dstValues and returns the result alongside the number of scanned samples and summaries.
+func (bsr *blockSummaryRollup) do(dstValues []float64, values []float64, timestamps []int64, summaries []storage.BlockSummary) ([]float64, uint64) {
+	rc := bsr.rc
+	dstValues = decimal.ExtendFloat64sCapacity(dstValues, len(rc.Timestamps))
+
+	i := 0
+	j := 0
+	k := 0
+	samplesScanned := uint64(len(values) + len(summaries))
+	for _, tEnd := range rc.Timestamps {
+		tStart := tEnd - bsr.window
+		for i < len(timestamps) && timestamps[i] <= tStart {
+			i++
+		}
+		if j < i {
+			j = i
+		}
+		for j < len(timestamps) && timestamps[j] <= tEnd {
+			j++
+		}
+		for k < len(summaries) && summaries[k].MinTimestamp <= tStart {
+			k++
+		}
+
+		var bsa blockSummaryAggregate
+		for _, v := range values[i:j] {
+			bsa.addValue(v)
+		}
+		n := k
+		for n < len(summaries) && summaries[n].MinTimestamp <= tEnd {
+			bsa.addSummary(&summaries[n])
+			n++
+		}
+		samplesScanned += uint64(j - i + n - k)
+		dstValues = append(dstValues, bsa.result(bsr.funcName))
+	}
+	return dstValues, samplesScanned
+}
+
+// blockSummaryAggregate aggregates raw samples and block summaries on a single lookbehind window.
+type blockSummaryAggregate struct {
+	count int
+	min   float64
+	max   float64
+	sum   float64
+}
+
+func (bsa *blockSummaryAggregate) addValue(v float64) {
+	if bsa.count == 0 || v < bsa.min {
+		bsa.min = v
+	}
+	if bsa.count == 0 || v > bsa.max {
+		bsa.max = v
+	}
+	bsa.sum += v
+	bsa.count++
+}
+
+func (bsa *blockSummaryAggregate) addSummary(bs *storage.BlockSummary) {
+	if bsa.count == 0 || bs.Min < bsa.min {
+		bsa.min = bs.Min
+	}
+	if bsa.count == 0 || bs.Max > bsa.max {
+		bsa.max = bs.Max
+	}
+	bsa.sum += bs.Sum
+	bsa.count += bs.Count
+}
+
+func (bsa *blockSummaryAggregate) result(funcName string) float64 {
+	if bsa.count == 0 {
+		return nan
+	}
+	switch funcName {
+	case "min_over_time":
+		return bsa.min
+	case "max_over_time":
+		return bsa.max
+	case "avg_over_time":
+		return bsa.sum / float64(bsa.count)
+	default:
+		return nan
+	}
+}
@@ -0,0 +1,190 @@
+package promql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestNewBlockSummaryRollup(t *testing.T) {
+	f := func(funcName string, window int64, mayAdjustWindow bool, rcsCount int, windowExpected int64) {
+		t.Helper()
+
+		var rcs []*rollupConfig
+		for i := 0; i < rcsCount; i++ {
+			rcs = append(rcs, &rollupConfig{
+				Step:            10,
+				Window:          window,
+				MayAdjustWindow: mayAdjustWindow,
+			})
+		}
+		bsr := newBlockSummaryRollup(funcName, rcs)
+		if windowExpected < 0 {
+			if bsr != nil {
+				t.Fatalf("expecting nil blockSummaryRollup")
+			}
+			return
+		}
+		if bsr == nil {
+			t.Fatalf("expecting non-nil blockSummaryRollup")
+		}
+		if bsr.window != windowExpected {
+			t.Fatalf("unexpected window; got %d; want %d", bsr.window, windowExpected)
+		}
+	}
+
+	// supported functions
+	f("min_over_time", 20, false, 1, 20)
+	f("MAX_over_time", 20, false, 1, 20)
+	f("avg_over_time", 0, false, 1, 10)
+
+	// unsupported functions
+	f("sum_over_time", 20, false, 1, -1)
+	f("rate", 20, false, 1, -1)
+
+	// multiple rollup configs
+	f("min_over_time", 20, false, 2, -1)
+
+	// implicit window, which depends on samples
+	f("min_over_time", 0, true, 1, -1)
+}
+
+func TestBlockSummaryRollupCanUseBlockSummary(t *testing.T) {
+	f := func(window, minTimestamp, maxTimestamp int64, resultExpected bool) {
+		t.Helper()
+
+		bsr := &blockSummaryRollup{
+			rc: &rollupConfig{
+				Timestamps: []int64{100, 200, 300, 400},
+			},
+			window: window,
+		}
+		bs := &storage.BlockSummary{
+			MinTimestamp: minTimestamp,
+			MaxTimestamp: maxTimestamp,
+		}
+		result := bsr.canUseBlockSummary(bs)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for window=%d, block=[%d..%d]; got %v; want %v", window, minTimestamp, maxTimestamp, result, resultExpected)
+		}
+	}
+
+	// the block is fully covered by a single window
+	f(100, 101, 200, true)
+	f(100, 150, 160, true)
+
+	// the block spans multiple windows
+	f(100, 150, 250, false)
+
+	// the window start isn't included in the window
+	f(100, 100, 200, false)
+
+	// the block is fully covered by overlapping windows
+	f(300, 201, 210, true)
+
+	// the block is partially covered by overlapping windows
+	f(300, 90, 130, false)
+
+	// the block isn't covered by windows
+	f(50, 110, 140, true)
+	f(100, 401, 500, true)
+	f(100, -10, -5, true)
+
+	// the block is partially covered by the window with gaps between windows
+	f(50, 140, 160, false)
+}
+
+func TestBlockSummaryRollupDo(t *testing.T) {
+	f := func(funcName string, window int64, blockSize int) {
+		t.Helper()
+
+		// Generate raw samples and split them into blocks.
+		var timestamps []int64
+		var values []float64
+		for i := 0; i < 1000; i++ {
+			timestamps = append(timestamps, int64(i*7))
+			values = append(values, math.Sin(float64(i))*float64(i%17))
+		}
+
+		rc := &rollupConfig{
+			Start:              500,
+			End:                6500,
+			Step:               1000,
+			Window:             window,
+			MaxPointsPerSeries: 1e4,
+		}
+		rc.Timestamps = rc.getTimestamps()
+		switch funcName {
+		case "min_over_time":
+			rc.Func = rollupMin
+		case "max_over_time":
+			rc.Func = rollupMax
+		case "avg_over_time":
+			rc.Func = rollupAvg
+		}
+		bsr := newBlockSummaryRollup(funcName, []*rollupConfig{rc})
+
+		var timestampsRaw []int64
+		var valuesRaw []float64
+		var summaries []storage.BlockSummary
+		for i := 0; i < len(timestamps); i += blockSize {
+			n := i + blockSize
+			if n > len(timestamps) {
+				n = len(timestamps)
+			}
+			bs := storage.BlockSummary{
+				MinTimestamp: timestamps[i],
+				MaxTimestamp: timestamps[n-1],
+				Count:        n - i,
+				Min:          values[i],
+				Max:          values[i],
+			}
+			for _, v := range values[i:n] {
+				bs.Min = math.Min(bs.Min, v)
+				bs.Max = math.Max(bs.Max, v)
+				bs.Sum += v
+			}
+			if bsr.canUseBlockSummary(&bs) {
+				summaries = append(summaries, bs)
+			} else {
+				timestampsRaw = append(timestampsRaw, timestamps[i:n]...)
+				valuesRaw = append(valuesRaw, values[i:n]...)
+			}
+		}
+		if len(summaries) == 0 {
+			t.Fatalf("expecting non-empty block summaries")
+		}
+
+		valuesExpected, _ := rc.Do(nil, values, timestamps)
+		valuesResult, _ := bsr.do(nil, valuesRaw, timestampsRaw, summaries)
+		if len(valuesResult) != len(valuesExpected) {
+			t.Fatalf("unexpected number of values; got %d; want %d", len(valuesResult), len(valuesExpected))
+		}
+		for i, v := range valuesResult {
+			vExpected := valuesExpected[i]
+			if math.IsNaN(vExpected) {
+				if !math.IsNaN(v) {
+					t.Fatalf("unexpected value at position %d; got %v; want NaN", i, v)
+				}
+				continue
+			}
+			if math.Abs(v-vExpected) > 1e-9*math.Abs(vExpected) {
+				t.Fatalf("unexpected value at position %d; got %v; want %v", i, v, vExpected)
+			}
+		}
+	}
+
+	for _, funcName := range []string{"min_over_time", "max_over_time", "avg_over_time"} {
+		// window equals to step
+		f(funcName, 0, 10)
+		f(funcName, 1000, 10)
+		f(funcName, 1000, 100)
+
+		// window is smaller than step
+		f(funcName, 500, 10)
+
+		// window is bigger than step
+		f(funcName, 2000, 10)
+	}
+}
@@ -177,12 +177,17 @@ func InitRollupResultCache(cachePath string) {
 	})
 
 	rollupResultCacheV = &rollupResultCache{
-		c: c,
+		c:      c,
+		remote: mustInitRollupResultCacheRemote(),
 	}
 }
 
 // StopRollupResultCache closes the rollupResult cache.
 func StopRollupResultCache() {
+	if rollupResultCacheV.remote != nil {
+		rollupResultCacheV.remote.MustStop()
+		rollupResultCacheV.remote = nil
+	}
 	if len(rollupResultCachePath) == 0 {
 		rollupResultCacheV.c.Stop()
 		rollupResultCacheV.c = nil
@@ -205,6 +210,11 @@ func StopRollupResultCache() {
 
 type rollupResultCache struct {
 	c *workingsetcache.Cache
+
+	// remote is an optional remote cache shared among vmselect replicas.
+	//
+	// It is used for obtaining entries missing in c.
+	remote *rollupResultCacheRemote
 }
 
 var rollupResultCacheResets = metrics.NewCounter(`vm_cache_resets_total{type="promql/rollupResult"}`)
@@ -212,7 +222,12 @@ var rollupResultCacheResets = metrics.NewCounter(`vm_cache_resets_total{type="pr
 // ResetRollupResultCache resets rollup result cache.
 func ResetRollupResultCache() {
 	rollupResultCacheResets.Inc()
-	rollupResultCacheKeyPrefix.Add(1)
+	if rcr := rollupResultCacheV.remote; rcr != nil {
+		// Propagate the reset to other vmselect replicas sharing the remote cache.
+		rcr.resetKeyPrefix()
+	} else {
+		rollupResultCacheKeyPrefix.Add(1)
+	}
 	logger.Infof("rollupResult cache has been cleared")
 }
 
@@ -292,7 +307,7 @@ func (rrc *rollupResultCache) GetSeries(qt *querytracer.Tracer, ec *EvalConfig,
 	defer bbPool.Put(bb)
 
 	bb.B = marshalRollupResultCacheKeyForSeries(bb.B[:0], expr, window, ec.Step, ec.EnforcedTagFilterss)
-	metainfoBuf := rrc.c.Get(nil, bb.B)
+	metainfoBuf := rrc.get(nil, bb.B)
 	if len(metainfoBuf) == 0 {
 		qt.Printf("nothing found")
 		return nil, ec.Start
@@ -314,7 +329,7 @@ func (rrc *rollupResultCache) GetSeries(qt *querytracer.Tracer, ec *EvalConfig,
 		mi.RemoveKey(key)
 		metainfoBuf = mi.Marshal(metainfoBuf[:0])
 		bb.B = marshalRollupResultCacheKeyForSeries(bb.B[:0], expr, window, ec.Step, ec.EnforcedTagFilterss)
-		rrc.c.Set(bb.B, metainfoBuf)
+		rrc.set(bb.B, metainfoBuf)
 		return nil, ec.Start
 	}
 
@@ -420,7 +435,7 @@ func (rrc *rollupResultCache) PutSeries(qt *querytracer.Tracer, ec *EvalConfig,
 	defer bbPool.Put(metainfoBuf)
 
 	metainfoKey.B = marshalRollupResultCacheKeyForSeries(metainfoKey.B[:0], expr, window, ec.Step, ec.EnforcedTagFilterss)
-	metainfoBuf.B = rrc.c.Get(metainfoBuf.B[:0], metainfoKey.B)
+	metainfoBuf.B = rrc.get(metainfoBuf.B[:0], metainfoKey.B)
 	var mi rollupResultCacheMetainfo
 	if len(metainfoBuf.B) > 0 {
 		if err := mi.Unmarshal(metainfoBuf.B); err != nil {
@@ -452,7 +467,53 @@ func (rrc *rollupResultCache) PutSeries(qt *querytracer.Tracer, ec *EvalConfig,
 
 	mi.AddKey(key, timestamps[0], timestamps[len(timestamps)-1])
 	metainfoBuf.B = mi.Marshal(metainfoBuf.B[:0])
-	rrc.c.Set(metainfoKey.B, metainfoBuf.B)
+	rrc.set(metainfoKey.B, metainfoBuf.B)
+}
+
+// get appends the value for the given key to dst and returns the result.
+//
+// The value is obtained from the remote cache if it is missing in the local cache.
+func (rrc *rollupResultCache) get(dst, key []byte) []byte {
+	dstLen := len(dst)
+	dst = rrc.c.Get(dst, key)
+	if len(dst) > dstLen || rrc.remote == nil {
+		return dst
+	}
+	dst = rrc.remote.get(dst, key)
+	if len(dst) > dstLen {
+		rrc.c.Set(key, dst[dstLen:])
+	}
+	return dst
+}
+
+// set stores the given value under the given key in the local cache and in the remote cache.
+func (rrc *rollupResultCache) set(key, value []byte) {
+	rrc.c.Set(key, value)
+	if rrc.remote != nil {
+		rrc.remote.set(key, value)
+	}
+}
+
+// getBig is like get, but for values stored with setBig.
+func (rrc *rollupResultCache) getBig(dst, key []byte) []byte {
+	dstLen := len(dst)
+	dst = rrc.c.GetBig(dst, key)
+	if len(dst) > dstLen || rrc.remote == nil {
+		return dst
+	}
+	dst = rrc.remote.get(dst, key)
+	if len(dst) > dstLen {
+		rrc.c.SetBig(key, dst[dstLen:])
+	}
+	return dst
+}
+
+// setBig is like set, but for big values.
+func (rrc *rollupResultCache) setBig(key, value []byte) {
+	rrc.c.SetBig(key, value)
+	if rrc.remote != nil {
+		rrc.remote.set(key, value)
+	}
 }
 
 var (
@@ -466,7 +527,7 @@ var (
 
 func (rrc *rollupResultCache) getSeriesFromCache(qt *querytracer.Tracer, key []byte) ([]*timeseries, bool) {
 	compressedResultBuf := resultBufPool.Get()
-	compressedResultBuf.B = rrc.c.GetBig(compressedResultBuf.B[:0], key)
+	compressedResultBuf.B = rrc.getBig(compressedResultBuf.B[:0], key)
 	if len(compressedResultBuf.B) == 0 {
 		qt.Printf("nothing found in the cache")
 		resultBufPool.Put(compressedResultBuf)
@@ -505,7 +566,7 @@ func (rrc *rollupResultCache) putSeriesToCache(qt *querytracer.Tracer, key []byt
 	compressedResultBuf.B = encoding.CompressZSTDLevel(compressedResultBuf.B[:0], resultBuf.B, 1)
 	qt.Printf("compress %d bytes into %d bytes", len(resultBuf.B), len(compressedResultBuf.B))
 
-	rrc.c.SetBig(key, compressedResultBuf.B)
+	rrc.setBig(key, compressedResultBuf.B)
 	qt.Printf("store %d bytes in the cache", len(compressedResultBuf.B))
 	return true
 }
@@ -0,0 +1,276 @@
+package promql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/remotecache"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+)
+
+var (
+	rollupResultCacheRemoteURL = flag.String("search.rollupResultCache.remoteURL", "", "Optional url of memcached or Redis for sharing rollup result cache "+
+		"among vmselect replicas. For example, memcached://memcached:11211 or redis://redis:6379/0 . "+
+		"See https://docs.victoriametrics.com/#sharing-rollup-result-cache-among-replicas")
+	rollupResultCacheRemotePassword = flagutil.NewPassword("search.rollupResultCache.remotePassword", "Optional password for authenticating "+
+		"at Redis specified via -search.rollupResultCache.remoteURL")
+	rollupResultCacheRemoteTimeout = flag.Duration("search.rollupResultCache.remoteTimeout", 100*time.Millisecond, "Timeout for requests "+
+		"to -search.rollupResultCache.remoteURL . Requests exceeding the timeout are treated as cache misses")
+	rollupResultCacheRemoteTTL = flag.Duration("search.rollupResultCache.remoteTTL", time.Hour, "Lifetime for entries stored "+
+		"at -search.rollupResultCache.remoteURL")
+	rollupResultCacheRemoteNamespace = flag.String("search.rollupResultCache.remoteNamespace", "vm", "Prefix for keys stored "+
+		"at -search.rollupResultCache.remoteURL . vmselect replicas share cached results only if they use the same namespace. "+
+		"Use distinct namespaces for independent VictoriaMetrics installations sharing the same -search.rollupResultCache.remoteURL")
+)
+
+// rollupResultCacheRemoteWorkers is the number of workers, which store entries at the remote cache.
+const rollupResultCacheRemoteWorkers = 4
+
+// rollupResultCacheRemoteSyncInterval is the interval for synchronizing rollupResultCacheKeyPrefix with the remote cache.
+const rollupResultCacheRemoteSyncInterval = 5 * time.Second
+
+// rollupResultCacheRemoteKeyPrefixTTL is the lifetime for rollupResultCacheKeyPrefix at the remote cache.
+//
+// The key prefix is refreshed every rollupResultCacheRemoteSyncInterval, so it is re-created after expiration.
+const rollupResultCacheRemoteKeyPrefixTTL = 30 * 24 * time.Hour
+
+// rollupResultCacheRemote is the remote tier for rollupResultCache, which is shared among vmselect replicas.
+//
+// It is used when the requested entry is missing in the local cache.
+type rollupResultCacheRemote struct {
+	c         remotecache.Client
+	namespace string
+	ttl       time.Duration
+
+	// keyPrefixLock serializes updates of rollupResultCacheKeyPrefix with the remote cache.
+	keyPrefixLock sync.Mutex
+
+	setCh  chan *rollupResultCacheRemoteEntry
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type rollupResultCacheRemoteEntry struct {
+	key   string
+	value []byte
+}
+
+var (
+	rollupResultCacheRemoteGets        = metrics.NewCounter(`vm_rollup_result_cache_remote_requests_total{type="get"}`)
+	rollupResultCacheRemoteSets        = metrics.NewCounter(`vm_rollup_result_cache_remote_requests_total{type="set"}`)
+	rollupResultCacheRemoteHits        = metrics.NewCounter(`vm_rollup_result_cache_remote_hits_total`)
+	rollupResultCacheRemoteGetErrors   = metrics.NewCounter(`vm_rollup_result_cache_remote_errors_total{type="get"}`)
+	rollupResultCacheRemoteSetErrors   = metrics.NewCounter(`vm_rollup_result_cache_remote_errors_total{type="set"}`)
+	rollupResultCacheRemoteSetsDropped = metrics.NewCounter(`vm_rollup_result_cache_remote_sets_dropped_total`)
+)
+
+// mustInitRollupResultCacheRemote returns the remote tier for rollupResultCache according to -search.rollupResultCache.remote* flags.
+//
+// nil is returned if -search.rollupResultCache.remoteURL isn't set.
+func mustInitRollupResultCacheRemote() *rollupResultCacheRemote {
+	if *rollupResultCacheRemoteURL == "" {
+		return nil
+	}
+	c, err := remotecache.NewClient(*rollupResultCacheRemoteURL, rollupResultCacheRemotePassword.Get(), *rollupResultCacheRemoteTimeout)
+	if err != nil {
+		logger.Fatalf("cannot initialize -search.rollupResultCache.remoteURL: %s", err)
+	}
+	rcr := newRollupResultCacheRemote(c, *rollupResultCacheRemoteNamespace, *rollupResultCacheRemoteTTL)
+	rcr.syncKeyPrefix()
+	rcr.wg.Add(1)
+	go func() {
+		defer rcr.wg.Done()
+		rcr.runKeyPrefixSyncer()
+	}()
+	logger.Infof("using %s as remote rollupResult cache", *rollupResultCacheRemoteURL)
+	return rcr
+}
+
+func newRollupResultCacheRemote(c remotecache.Client, namespace string, ttl time.Duration) *rollupResultCacheRemote {
+	rcr := &rollupResultCacheRemote{
+		c:         c,
+		namespace: namespace,
+		ttl:       ttl,
+		setCh:     make(chan *rollupResultCacheRemoteEntry, 1024),
+		stopCh:    make(chan struct{}),
+	}
+	for i := 0; i < rollupResultCacheRemoteWorkers; i++ {
+		rcr.wg.Add(1)
+		go func() {
+			defer rcr.wg.Done()
+			rcr.runSetWorker()
+		}()
+	}
+	return rcr
+}
+
+// MustStop stops rcr.
+func (rcr *rollupResultCacheRemote) MustStop() {
+	close(rcr.stopCh)
+	rcr.wg.Wait()
+	rcr.c.MustStop()
+}
+
+// get appends the value for the given key from the remote cache to dst and returns the result.
+//
+// dst is returned unchanged if the value is missing or cannot be obtained.
+func (rcr *rollupResultCacheRemote) get(dst, key []byte) []byte {
+	rollupResultCacheRemoteGets.Inc()
+	k := rcr.getRemoteKey(key)
+	dstLen := len(dst)
+	dst, ok, err := rcr.c.Get(dst, k)
+	if err != nil {
+		rollupResultCacheRemoteGetErrors.Inc()
+		logger.WithThrottler("rollupResultCacheRemoteGet", 5*time.Second).Warnf("cannot obtain entry from -search.rollupResultCache.remoteURL: %s", err)
+		return dst[:dstLen]
+	}
+	if !ok {
+		return dst
+	}
+	value, ok := unwrapRollupResultCacheRemoteValue(dst[dstLen:], key)
+	if !ok {
+		// The entry is corrupted or it belongs to another key with the same hash.
+		rollupResultCacheRemoteGetErrors.Inc()
+		logger.WithThrottler("rollupResultCacheRemoteGet", 5*time.Second).Warnf("unexpected entry at -search.rollupResultCache.remoteURL; ignoring it")
+		return dst[:dstLen]
+	}
+	rollupResultCacheRemoteHits.Inc()
+	n := copy(dst[dstLen:], value)
+	return dst[:dstLen+n]
+}
+
+// set asynchronously stores the given value under the given key in the remote cache.
+//
+// The value is dropped if the remote cache cannot keep up with the incoming entries.
+func (rcr *rollupResultCacheRemote) set(key, value []byte) {
+	e := &rollupResultCacheRemoteEntry{
+		key:   rcr.getRemoteKey(key),
+		value: wrapRollupResultCacheRemoteValue(nil, key, value),
+	}
+	select {
+	case rcr.setCh <- e:
+	default:
+		rollupResultCacheRemoteSetsDropped.Inc()
+	}
+}
+
+func (rcr *rollupResultCacheRemote) runSetWorker() {
+	for {
+		select {
+		case <-rcr.stopCh:
+			return
+		case e := <-rcr.setCh:
+			rollupResultCacheRemoteSets.Inc()
+			if err := rcr.c.Set(e.key, e.value, rcr.ttl); err != nil {
+				rollupResultCacheRemoteSetErrors.Inc()
+				logger.WithThrottler("rollupResultCacheRemoteSet", 5*time.Second).Warnf("cannot store entry at -search.rollupResultCache.remoteURL: %s", err)
+			}
+		}
+	}
+}
+
+// getRemoteKey returns the key for the remote cache for the given local key.
+//
+// Local keys may be long and may contain arbitrary bytes, which aren't supported by memcached,
+// so they are hashed.
+func (rcr *rollupResultCacheRemote) getRemoteKey(key []byte) string {
+	h := sha256.Sum256(key)
+	return rcr.namespace + ":rollupResult:" + hex.EncodeToString(h[:16])
+}
+
+func (rcr *rollupResultCacheRemote) getKeyPrefixKey() string {
+	return rcr.namespace + ":rollupResult:keyPrefix"
+}
+
+// syncKeyPrefix synchronizes rollupResultCacheKeyPrefix with the remote cache,
+// so all the vmselect replicas use the same key prefix and observe cache resets made by other replicas.
+func (rcr *rollupResultCacheRemote) syncKeyPrefix() {
+	rcr.keyPrefixLock.Lock()
+	defer rcr.keyPrefixLock.Unlock()
+
+	k := rcr.getKeyPrefixKey()
+	buf, ok, err := rcr.c.Get(nil, k)
+	if err != nil {
+		logger.WithThrottler("rollupResultCacheRemoteKeyPrefix", 5*time.Second).Warnf("cannot obtain rollupResult cache key prefix "+
+			"from -search.rollupResultCache.remoteURL: %s", err)
+		return
+	}
+	if ok && len(buf) == 8 {
+		prefix := encoding.UnmarshalUint64(buf)
+		if rollupResultCacheKeyPrefix.Swap(prefix) != prefix {
+			logger.Infof("switched to rollupResult cache key prefix from -search.rollupResultCache.remoteURL")
+		}
+		return
+	}
+	// The key prefix is missing at the remote cache. Store the local key prefix there.
+	rcr.storeKeyPrefixLocked(rollupResultCacheKeyPrefix.Load())
+}
+
+// resetKeyPrefix increments rollupResultCacheKeyPrefix and propagates it to the remote cache.
+func (rcr *rollupResultCacheRemote) resetKeyPrefix() {
+	rcr.keyPrefixLock.Lock()
+	defer rcr.keyPrefixLock.Unlock()
+
+	prefix := rollupResultCacheKeyPrefix.Add(1)
+	rcr.storeKeyPrefixLocked(prefix)
+}
+
+func (rcr *rollupResultCacheRemote) storeKeyPrefixLocked(prefix uint64) {
+	buf := encoding.MarshalUint64(nil, prefix)
+	if err := rcr.c.Set(rcr.getKeyPrefixKey(), buf, rollupResultCacheRemoteKeyPrefixTTL); err != nil {
+		logger.WithThrottler("rollupResultCacheRemoteKeyPrefix", 5*time.Second).Warnf("cannot store rollupResult cache key prefix "+
+			"at -search.rollupResultCache.remoteURL: %s", err)
+	}
+}
+
+func (rcr *rollupResultCacheRemote) runKeyPrefixSyncer() {
+	t := time.NewTicker(rollupResultCacheRemoteSyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-rcr.stopCh:
+			return
+		case <-t.C:
+			rcr.syncKeyPrefix()
+		}
+	}
+}
+
+// wrapRollupResultCacheRemoteValue appends value to dst together with the checksum for the given key and value.
+//
+// The checksum protects from corrupted entries and from collisions of hashed keys at the remote cache.
+func wrapRollupResultCacheRemoteValue(dst, key, value []byte) []byte {
+	dst = encoding.MarshalUint64(dst, getRollupResultCacheRemoteChecksum(key, value))
+	return append(dst, value...)
+}
+
+// unwrapRollupResultCacheRemoteValue returns the value from buf created with wrapRollupResultCacheRemoteValue.
+//
+// false is returned if buf doesn't match the given key.
+func unwrapRollupResultCacheRemoteValue(buf, key []byte) ([]byte, bool) {
+	if len(buf) < 8 {
+		return nil, false
+	}
+	checksum := encoding.UnmarshalUint64(buf)
+	value := buf[8:]
+	if checksum != getRollupResultCacheRemoteChecksum(key, value) {
+		return nil, false
+	}
+	return value, true
+}
+
+func getRollupResultCacheRemoteChecksum(key, value []byte) uint64 {
+	var d xxhash.Digest
+	d.Reset()
+	_, _ = d.Write(encoding.MarshalUint64(nil, uint64(len(key))))
+	_, _ = d.Write(key)
+	_, _ = d.Write(value)
+	return d.Sum64()
+}
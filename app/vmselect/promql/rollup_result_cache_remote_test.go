@@ -0,0 +1,234 @@
+package promql
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/workingsetcache"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestRollupResultCacheRemoteValue(t *testing.T) {
+	f := func(key, value string) {
+		t.Helper()
+
+		buf := wrapRollupResultCacheRemoteValue(nil, []byte(key), []byte(value))
+		result, ok := unwrapRollupResultCacheRemoteValue(buf, []byte(key))
+		if !ok {
+			t.Fatalf("cannot unwrap value")
+		}
+		if string(result) != value {
+			t.Fatalf("unexpected value; got %q; want %q", result, value)
+		}
+
+		// Another key
+		if _, ok := unwrapRollupResultCacheRemoteValue(buf, []byte(key+"x")); ok {
+			t.Fatalf("expecting unwrap failure for another key")
+		}
+
+		// Corrupted value
+		buf[len(buf)-1]++
+		if _, ok := unwrapRollupResultCacheRemoteValue(buf, []byte(key)); ok {
+			t.Fatalf("expecting unwrap failure for corrupted value")
+		}
+	}
+
+	f("foo", "bar")
+	f("", "x")
+	f("foo", "some long value")
+
+	// too short value
+	if _, ok := unwrapRollupResultCacheRemoteValue([]byte("foo"), []byte("foo")); ok {
+		t.Fatalf("expecting unwrap failure for too short value")
+	}
+}
+
+func TestRollupResultCacheRemoteSharing(t *testing.T) {
+	fc := newFakeRemoteCacheClient()
+	rrcA := &rollupResultCache{
+		c:      workingsetcache.New(1024 * 1024),
+		remote: newRollupResultCacheRemote(fc, "test", time.Hour),
+	}
+	defer rrcA.c.Stop()
+	defer rrcA.remote.MustStop()
+	rrcB := &rollupResultCache{
+		c:      workingsetcache.New(1024 * 1024),
+		remote: newRollupResultCacheRemote(fc, "test", time.Hour),
+	}
+	defer rrcB.c.Stop()
+	defer rrcB.remote.MustStop()
+
+	window := int64(456)
+	ec := &EvalConfig{
+		Start:              1000,
+		End:                2000,
+		Step:               200,
+		MaxPointsPerSeries: 1e4,
+
+		MayCache: true,
+	}
+	fe := &metricsql.FuncExpr{
+		Name: "foo",
+		Args: []metricsql.Expr{
+			&metricsql.MetricExpr{
+				LabelFilterss: [][]metricsql.LabelFilter{
+					{
+						{
+							Label: "aaa",
+							Value: "xxx",
+						},
+					},
+				},
+			},
+		},
+	}
+	tss := []*timeseries{
+		{
+			Timestamps: []int64{1000, 1200, 1400, 1600, 1800, 2000},
+			Values:     []float64{1, 2, 3, 4, 5, 6},
+		},
+	}
+	rrcA.PutSeries(nil, ec, fe, window, tss)
+
+	// Wait until the metainfo and the series are stored at the remote cache.
+	deadline := time.Now().Add(5 * time.Second)
+	for fc.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout while waiting for entries at the remote cache; got %d entries; want 2", fc.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The series must be obtained by another replica from the remote cache.
+	tssResult, newStart := rrcB.GetSeries(nil, ec, fe, window)
+	if newStart != 2200 {
+		t.Fatalf("unexpected newStart; got %d; want %d", newStart, 2200)
+	}
+	testTimeseriesEqual(t, tssResult, tss)
+
+	// The series must be cached locally after obtaining them from the remote cache.
+	fc.Reset()
+	tssResult, newStart = rrcB.GetSeries(nil, ec, fe, window)
+	if newStart != 2200 {
+		t.Fatalf("unexpected newStart; got %d; want %d", newStart, 2200)
+	}
+	testTimeseriesEqual(t, tssResult, tss)
+
+	// Another namespace must not see the entries.
+	rrcC := &rollupResultCache{
+		c:      workingsetcache.New(1024 * 1024),
+		remote: newRollupResultCacheRemote(fc, "another", time.Hour),
+	}
+	defer rrcC.c.Stop()
+	defer rrcC.remote.MustStop()
+	rrcA.PutSeries(nil, ec, fe, window, tss)
+	tssResult, newStart = rrcC.GetSeries(nil, ec, fe, window)
+	if newStart != ec.Start {
+		t.Fatalf("unexpected newStart; got %d; want %d", newStart, ec.Start)
+	}
+	if len(tssResult) != 0 {
+		t.Fatalf("got %d timeseries, while expecting zero", len(tssResult))
+	}
+}
+
+func TestRollupResultCacheRemoteCorruptedEntry(t *testing.T) {
+	fc := newFakeRemoteCacheClient()
+	rcr := newRollupResultCacheRemote(fc, "test", time.Hour)
+	defer rcr.MustStop()
+
+	key := []byte("foo")
+	if err := fc.Set(rcr.getRemoteKey(key), []byte("corrupted value"), time.Hour); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := rcr.get([]byte("prefix"), key)
+	if string(result) != "prefix" {
+		t.Fatalf("unexpected result; got %q; want %q", result, "prefix")
+	}
+
+	if err := fc.Set(rcr.getRemoteKey(key), wrapRollupResultCacheRemoteValue(nil, key, []byte("bar")), time.Hour); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result = rcr.get([]byte("prefix"), key)
+	if string(result) != "prefixbar" {
+		t.Fatalf("unexpected result; got %q; want %q", result, "prefixbar")
+	}
+}
+
+func TestRollupResultCacheRemoteKeyPrefix(t *testing.T) {
+	origPrefix := rollupResultCacheKeyPrefix.Load()
+	defer rollupResultCacheKeyPrefix.Store(origPrefix)
+
+	fc := newFakeRemoteCacheClient()
+	rcr := newRollupResultCacheRemote(fc, "test", time.Hour)
+	defer rcr.MustStop()
+
+	// The local key prefix must be stored at the remote cache if it is missing there.
+	rollupResultCacheKeyPrefix.Store(123)
+	rcr.syncKeyPrefix()
+	if n := rollupResultCacheKeyPrefix.Load(); n != 123 {
+		t.Fatalf("unexpected key prefix; got %d; want %d", n, 123)
+	}
+
+	// The key prefix from the remote cache must be used, since it is shared among replicas.
+	rollupResultCacheKeyPrefix.Store(456)
+	rcr.syncKeyPrefix()
+	if n := rollupResultCacheKeyPrefix.Load(); n != 123 {
+		t.Fatalf("unexpected key prefix; got %d; want %d", n, 123)
+	}
+
+	// The reset must be propagated to the remote cache.
+	rcr.resetKeyPrefix()
+	if n := rollupResultCacheKeyPrefix.Load(); n != 124 {
+		t.Fatalf("unexpected key prefix; got %d; want %d", n, 124)
+	}
+	rollupResultCacheKeyPrefix.Store(456)
+	rcr.syncKeyPrefix()
+	if n := rollupResultCacheKeyPrefix.Load(); n != 124 {
+		t.Fatalf("unexpected key prefix; got %d; want %d", n, 124)
+	}
+}
+
+// fakeRemoteCacheClient is an in-memory implementation of remotecache.Client.
+type fakeRemoteCacheClient struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newFakeRemoteCacheClient() *fakeRemoteCacheClient {
+	return &fakeRemoteCacheClient{
+		m: make(map[string]string),
+	}
+}
+
+func (fc *fakeRemoteCacheClient) Get(dst []byte, key string) ([]byte, bool, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	value, ok := fc.m[key]
+	if !ok {
+		return dst, false, nil
+	}
+	return append(dst, value...), true, nil
+}
+
+func (fc *fakeRemoteCacheClient) Set(key string, value []byte, _ time.Duration) error {
+	fc.mu.Lock()
+	fc.m[key] = string(value)
+	fc.mu.Unlock()
+	return nil
+}
+
+func (fc *fakeRemoteCacheClient) MustStop() {}
+
+func (fc *fakeRemoteCacheClient) Len() int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.m)
+}
+
+func (fc *fakeRemoteCacheClient) Reset() {
+	fc.mu.Lock()
+	fc.m = make(map[string]string)
+	fc.mu.Unlock()
+}
@@ -585,6 +585,68 @@ func TestRollupHoltWinters(t *testing.T) {
 	f(0.9, 0.9, 33.99637566941818)
 }
 
+func TestRollupHoltWintersAutoTuned(t *testing.T) {
+	var me metricsql.MetricExpr
+	args := []any{&metricsql.RollupExpr{Expr: &me}}
+	testRollupFunc(t, "holt_winters", args, 33.21913263372957)
+}
+
+func TestRollupPredictSeasonal(t *testing.T) {
+	f := func(values []float64, timestamps []int64, sec, period, vExpected float64) {
+		t.Helper()
+		secs := []*timeseries{{
+			Values:     []float64{sec},
+			Timestamps: []int64{123},
+		}}
+		periods := []*timeseries{{
+			Values:     []float64{period},
+			Timestamps: []int64{123},
+		}}
+		var me metricsql.MetricExpr
+		args := []any{&metricsql.RollupExpr{Expr: &me}, secs, periods}
+		rf, err := getRollupFunc("predict_seasonal")(args)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		rfa := &rollupFuncArg{
+			prevValue:     nan,
+			values:        values,
+			timestamps:    timestamps,
+			currTimestamp: timestamps[len(timestamps)-1],
+		}
+		v := rf(rfa)
+		if err := compareValues([]float64{v}, []float64{vExpected}); err != nil {
+			t.Fatalf("unexpected value: %s", err)
+		}
+	}
+
+	// generate samples with 10s interval, which contain linear trend plus seasonal component with 60s period
+	season := []float64{0, 3, 6, 0, -6, -3}
+	var values []float64
+	var timestamps []int64
+	for i := 0; i < 36; i++ {
+		ts := int64(i) * 10e3
+		values = append(values, 100+float64(ts)/1e3+season[i%len(season)])
+		timestamps = append(timestamps, ts)
+	}
+
+	// too short lookbehind window
+	f(values[:5], timestamps[:5], 10, 60, nan)
+
+	// invalid period
+	f(values, timestamps, 10, 0, nan)
+
+	// forecasts for the next period must follow the seasonal pattern on top of the trend
+	f(values, timestamps, 0, 60, 446.44401544401546)
+	f(values, timestamps, 10, 60, 459.2216216216216)
+	f(values, timestamps, 20, 60, 472.2216216216216)
+	f(values, timestamps, 30, 60, 485.22162162162164)
+	f(values, timestamps, 70, 60, 518.9992277992278)
+
+	// linear series without seasonal component
+	f([]float64{1, 2, 3, 4, 5}, []int64{0, 1000, 2000, 3000, 4000}, 3, 2, 8)
+}
+
 func TestRollupHoeffdingBoundLower(t *testing.T) {
 	f := func(phi, vExpected float64) {
 		t.Helper()
@@ -705,6 +767,7 @@ func TestRollupNewRollupFuncError(t *testing.T) {
 	f("default_rollup", nil)
 	f("holt_winters", nil)
 	f("predict_linear", nil)
+	f("predict_seasonal", nil)
 	f("quantile_over_time", nil)
 	f("quantiles_over_time", nil)
 
@@ -714,11 +777,14 @@ func TestRollupNewRollupFuncError(t *testing.T) {
 		Timestamps: []int64{123},
 	}}
 	me := &metricsql.MetricExpr{}
+	f("holt_winters", []any{me, scalarTs})
 	f("holt_winters", []any{123, 123, 321})
 	f("holt_winters", []any{me, 123, 321})
 	f("holt_winters", []any{me, scalarTs, 321})
 	f("predict_linear", []any{123, 123})
 	f("predict_linear", []any{me, 123})
+	f("predict_seasonal", []any{me, scalarTs})
+	f("predict_seasonal", []any{me, scalarTs, 321})
 	f("quantile_over_time", []any{123, 123})
 	f("quantiles_over_time", []any{123, 123})
 }
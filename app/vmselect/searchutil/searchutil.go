@@ -35,6 +35,21 @@ func GetMaxQueryDuration(r *http.Request) time.Duration {
 	return d
 }
 
+// GetIOClass returns IO class for the given request r.
+//
+// The IO class is read from io_class query arg or from X-VM-IO-Class request header.
+// defaultClass is returned if r doesn't contain IO class.
+func GetIOClass(r *http.Request, defaultClass storage.IOClass) (storage.IOClass, error) {
+	s := r.FormValue("io_class")
+	if s == "" {
+		s = r.Header.Get("X-VM-IO-Class")
+	}
+	if s == "" {
+		return defaultClass, nil
+	}
+	return storage.ParseIOClass(s)
+}
+
 // GetDeadlineForQuery returns deadline for the given query r.
 func GetDeadlineForQuery(r *http.Request, startTime time.Time) Deadline {
 	dMax := maxQueryDuration.Milliseconds()
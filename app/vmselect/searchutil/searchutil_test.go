@@ -281,3 +281,45 @@ func TestGetDeadline(t *testing.T) {
 	f(GetDeadlineForStatusRequest(r, start), expDeadline(time.Second))
 	f(GetDeadlineForQuery(r, start), expDeadline(time.Second))
 }
+
+func TestGetIOClass(t *testing.T) {
+	f := func(requestURI, header string, defaultClass, classExpected storage.IOClass) {
+		t.Helper()
+
+		r, err := http.NewRequest(http.MethodGet, requestURI, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if header != "" {
+			r.Header.Set("X-VM-IO-Class", header)
+		}
+		ioClass, err := GetIOClass(r, defaultClass)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ioClass != classExpected {
+			t.Fatalf("unexpected IO class; got %s; want %s", ioClass, classExpected)
+		}
+	}
+
+	// default IO class
+	f("http://foo", "", storage.IOClassInteractive, storage.IOClassInteractive)
+	f("http://foo", "", storage.IOClassBatch, storage.IOClassBatch)
+
+	// IO class from query arg
+	f("http://foo?io_class=batch", "", storage.IOClassInteractive, storage.IOClassBatch)
+	f("http://foo?io_class=interactive", "", storage.IOClassBatch, storage.IOClassInteractive)
+
+	// IO class from header
+	f("http://foo", "background", storage.IOClassInteractive, storage.IOClassBatch)
+	f("http://foo", "interactive", storage.IOClassBatch, storage.IOClassInteractive)
+
+	// query arg has priority over header
+	f("http://foo?io_class=interactive", "batch", storage.IOClassBatch, storage.IOClassInteractive)
+
+	// invalid IO class
+	r, _ := http.NewRequest(http.MethodGet, "http://foo?io_class=foo", nil)
+	if _, err := GetIOClass(r, storage.IOClassInteractive); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
@@ -1,14 +1,22 @@
 package stats
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
+var unusedMetricsWindow = flag.Duration("search.unusedMetricsWindow", 4*7*24*time.Hour, "The default time window for /api/v1/status/unused_metrics. "+
+	"Metric names, which weren't queried during this window, are returned as unused. It can be overridden via window query arg. "+
+	"See https://docs.victoriametrics.com/#track-ingested-metrics-usage")
+
 // MetricNamesStatsHandler returns timeseries metric names usage statistics
 func MetricNamesStatsHandler(qt *querytracer.Tracer, w http.ResponseWriter, r *http.Request) error {
 	limit := 1000
@@ -41,6 +49,42 @@ func MetricNamesStatsHandler(qt *querytracer.Tracer, w http.ResponseWriter, r *h
 	return nil
 }
 
+// UnusedMetricsHandler returns timeseries metric names, which weren't queried during the time window specified via `window` query arg
+func UnusedMetricsHandler(qt *querytracer.Tracer, w http.ResponseWriter, r *http.Request) error {
+	limit := 1000
+	limitStr := r.FormValue("limit")
+	if len(limitStr) > 0 {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `limit` arg %q: %w", limitStr, err)
+		}
+		if n > 0 {
+			limit = n
+		}
+	}
+	windowMsecs, err := httputil.GetDuration(r, "window", unusedMetricsWindow.Milliseconds())
+	if err != nil {
+		return err
+	}
+	window := uint64(windowMsecs / 1e3)
+	matchPattern := r.FormValue("match_pattern")
+
+	ct := fasttime.UnixTimestamp()
+	unusedSince := uint64(0)
+	if ct > window {
+		unusedSince = ct - window
+	}
+	stats, err := netstorage.GetUnusedMetricNamesStats(qt, limit, unusedSince, matchPattern)
+	if err != nil {
+		return err
+	}
+	// The result is partial if metric names usage was collected during the smaller time range than the requested window,
+	// since metric names could be queried before the collection has been started.
+	isPartial := stats.CollectedSinceTs > unusedSince
+	WriteUnusedMetricsResponse(w, &stats, window, isPartial, qt)
+	return nil
+}
+
 // ResetMetricNamesStatsHandler resets metric names usage state
 func ResetMetricNamesStatsHandler(qt *querytracer.Tracer) error {
 	if err := netstorage.ResetMetricNamesStats(qt); err != nil {
@@ -26,6 +26,9 @@ type dashboardSettings struct {
 	Title    string         `json:"title,omitempty"`
 	Filename string         `json:"filename,omitempty"`
 	Rows     []dashboardRow `json:"rows"`
+
+	// Saved is set to true for dashboards stored at -vmui.savedDashboardsPath.
+	Saved bool `json:"saved,omitempty"`
 }
 
 // panelSettings represents fields which used to show graph.
@@ -55,16 +58,30 @@ type dashboardsData struct {
 }
 
 func handleVMUICustomDashboards(w http.ResponseWriter) error {
-	path := *vmuiCustomDashboardsPath
-	if path == "" {
-		writeSuccessResponse(w, []byte(`{"dashboardsSettings": []}`))
-		return nil
+	dss := []dashboardSettings{}
+	if path := *vmuiCustomDashboardsPath; path != "" {
+		settings, err := collectDashboardsSettings(path)
+		if err != nil {
+			return fmt.Errorf("cannot collect dashboards settings by -vmui.customDashboardsPath=%q: %w", path, err)
+		}
+		dss = append(dss, settings...)
 	}
-	settings, err := collectDashboardsSettings(path)
+	if path := *vmuiSavedDashboardsPath; path != "" && fs.IsPathExist(path) {
+		settings, err := collectDashboardsSettings(path)
+		if err != nil {
+			return fmt.Errorf("cannot collect dashboards settings by -vmui.savedDashboardsPath=%q: %w", path, err)
+		}
+		for i := range settings {
+			settings[i].Saved = true
+		}
+		dss = append(dss, settings...)
+	}
+	dd := dashboardsData{DashboardsSettings: dss}
+	data, err := json.Marshal(dd)
 	if err != nil {
-		return fmt.Errorf("cannot collect dashboards settings by -vmui.customDashboardsPath=%q: %w", path, err)
+		return fmt.Errorf("cannot marshal dashboards settings: %w", err)
 	}
-	writeSuccessResponse(w, settings)
+	writeSuccessResponse(w, data)
 	return nil
 }
 
@@ -84,7 +101,7 @@ func writeSuccessResponse(w http.ResponseWriter, data []byte) {
 	w.Write(data)
 }
 
-func collectDashboardsSettings(path string) ([]byte, error) {
+func collectDashboardsSettings(path string) ([]dashboardSettings, error) {
 	if !fs.IsPathExist(path) {
 		return nil, fmt.Errorf("cannot find folder %q", path)
 	}
@@ -107,6 +124,7 @@ func collectDashboardsSettings(path string) ([]byte, error) {
 		if err != nil {
 			return nil, fmt.Errorf("cannot parse file %s: %w", filePath, err)
 		}
+		ds.Filename = filename
 
 		for i := range ds.Rows {
 			for j := range ds.Rows[i].Panels {
@@ -123,6 +141,5 @@ func collectDashboardsSettings(path string) ([]byte, error) {
 		}
 	}
 
-	dd := dashboardsData{DashboardsSettings: dss}
-	return json.Marshal(dd)
+	return dss, nil
 }
@@ -0,0 +1,448 @@
+package vmselect
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+var (
+	vmuiSavedDashboardsPath = flag.String("vmui.savedDashboardsPath", "", "Optional path to a directory for storing dashboards assembled in vmui from queries. "+
+		"Saving dashboards from vmui is disabled if this flag isn't set. See https://docs.victoriametrics.com/#vmui-dashboards")
+	vmuiSavedDashboardsAuthKey = flagutil.NewPassword("vmui.savedDashboardsAuthKey", "authKey for saving and deleting dashboards via /vmui/saved-dashboards. "+
+		"It could be passed via authKey query arg. It overrides -httpAuth.*. See https://docs.victoriametrics.com/#vmui-dashboards")
+)
+
+// maxSavedDashboardSize is the maximum size of a dashboard, which can be saved via /vmui/saved-dashboards.
+const maxSavedDashboardSize = 1024 * 1024
+
+var dashboardFilenameRe = regexp.MustCompile(`^[a-zA-Z0-9_\-]+\.json$`)
+
+// handleVMUISavedDashboards saves or deletes dashboards at -vmui.savedDashboardsPath.
+func handleVMUISavedDashboards(w http.ResponseWriter, r *http.Request) error {
+	path := *vmuiSavedDashboardsPath
+	if path == "" {
+		return fmt.Errorf("saving dashboards is disabled; set -vmui.savedDashboardsPath command-line flag in order to enable it")
+	}
+	switch r.Method {
+	case http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxSavedDashboardSize+1))
+		if err != nil {
+			return fmt.Errorf("cannot read dashboard: %w", err)
+		}
+		if len(data) > maxSavedDashboardSize {
+			return fmt.Errorf("too big dashboard; it mustn't exceed %d bytes", maxSavedDashboardSize)
+		}
+		ds, err := saveDashboard(path, data)
+		if err != nil {
+			return err
+		}
+		response, err := json.Marshal(ds)
+		if err != nil {
+			return fmt.Errorf("cannot marshal dashboard: %w", err)
+		}
+		writeSuccessResponse(w, response)
+		return nil
+	case http.MethodDelete:
+		filename := r.FormValue("filename")
+		if !dashboardFilenameRe.MatchString(filename) {
+			return fmt.Errorf("invalid filename=%q; it must match %s", filename, dashboardFilenameRe)
+		}
+		filePath := filepath.Join(path, filename)
+		if err := os.Remove(filePath); err != nil {
+			return fmt.Errorf("cannot delete dashboard: %w", err)
+		}
+		writeSuccessResponse(w, []byte(`{"status":"success"}`))
+		return nil
+	default:
+		return fmt.Errorf("unsupported method %s; use POST for saving dashboard or DELETE for deleting dashboard", r.Method)
+	}
+}
+
+// saveDashboard saves dashboard settings from data to the given dir and returns the saved dashboard.
+//
+// The dashboard is stored in the file with the name from the filename field.
+// The filename is generated from the dashboard title if it is missing.
+// The existing dashboard with the same filename is overwritten.
+func saveDashboard(dir string, data []byte) (*dashboardSettings, error) {
+	var ds dashboardSettings
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("cannot parse dashboard: %w", err)
+	}
+	if err := validateDashboard(&ds); err != nil {
+		return nil, err
+	}
+	if ds.Filename == "" {
+		ds.Filename = dashboardFilenameFromTitle(ds.Title)
+	}
+	if !dashboardFilenameRe.MatchString(ds.Filename) {
+		return nil, fmt.Errorf("invalid filename=%q; it must match %s", ds.Filename, dashboardFilenameRe)
+	}
+	// The flag is set only when reading dashboards from disk.
+	ds.Saved = false
+
+	data, err := json.MarshalIndent(&ds, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal dashboard: %w", err)
+	}
+	fs.MustMkdirIfNotExist(dir)
+	filePath := filepath.Join(dir, ds.Filename)
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("cannot save dashboard: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("cannot save dashboard: %w", err)
+	}
+	ds.Saved = true
+	return &ds, nil
+}
+
+func validateDashboard(ds *dashboardSettings) error {
+	if ds.Title == "" && ds.Filename == "" {
+		return fmt.Errorf("missing dashboard title")
+	}
+	if len(ds.Rows) == 0 {
+		return fmt.Errorf("dashboard must contain at least a single row")
+	}
+	for i, row := range ds.Rows {
+		if len(row.Panels) == 0 {
+			return fmt.Errorf("row #%d must contain at least a single panel", i+1)
+		}
+		for j, p := range row.Panels {
+			if len(p.Expr) == 0 {
+				return fmt.Errorf("panel #%d at row #%d must contain at least a single query", j+1, i+1)
+			}
+			for _, expr := range p.Expr {
+				if strings.TrimSpace(expr) == "" {
+					return fmt.Errorf("panel #%d at row #%d mustn't contain empty queries", j+1, i+1)
+				}
+			}
+			if p.Width < 0 || p.Width > 12 {
+				return fmt.Errorf("panel #%d at row #%d has invalid width=%d; it must be in the range [1..12]", j+1, i+1, p.Width)
+			}
+		}
+	}
+	return nil
+}
+
+// dashboardFilenameFromTitle returns file name for the dashboard with the given title.
+func dashboardFilenameFromTitle(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(title) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+			b.WriteRune(c)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		name = "dashboard"
+	}
+	return name + ".json"
+}
+
+// handleVMUIGrafanaDashboard exports the dashboard with the filename from r as Grafana dashboard JSON.
+//
+// The dashboard is searched at -vmui.savedDashboardsPath and then at -vmui.customDashboardsPath.
+func handleVMUIGrafanaDashboard(w http.ResponseWriter, r *http.Request) error {
+	filename := r.FormValue("filename")
+	if !dashboardFilenameRe.MatchString(filename) {
+		return fmt.Errorf("invalid filename=%q; it must match %s", filename, dashboardFilenameRe)
+	}
+	var data []byte
+	for _, dir := range []string{*vmuiSavedDashboardsPath, *vmuiCustomDashboardsPath} {
+		if dir == "" {
+			continue
+		}
+		filePath := filepath.Join(dir, filename)
+		if !fs.IsPathExist(filePath) {
+			continue
+		}
+		b, err := os.ReadFile(filePath)
+		if err != nil {
+			// There is no need to add more context to the returned error, since os.ReadFile() adds enough context.
+			return err
+		}
+		data = b
+		break
+	}
+	if data == nil {
+		return fmt.Errorf("cannot find dashboard %q at -vmui.savedDashboardsPath and -vmui.customDashboardsPath", filename)
+	}
+	var ds dashboardSettings
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return fmt.Errorf("cannot parse dashboard %q: %w", filename, err)
+	}
+	gd := newGrafanaDashboard(&ds)
+	response, err := json.MarshalIndent(gd, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal Grafana dashboard: %w", err)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="grafana-%s"`, filename))
+	writeSuccessResponse(w, response)
+	return nil
+}
+
+// grafanaDatasourceVar is the name of Grafana dashboard variable with Prometheus datasource.
+const grafanaDatasourceVar = "ds"
+
+// grafanaGridWidth is the number of columns in Grafana dashboard grid.
+const grafanaGridWidth = 24
+
+// grafanaPanelHeight is the height of Grafana panels generated from vmui panels.
+const grafanaPanelHeight = 8
+
+// grafanaDashboard represents Grafana dashboard JSON model.
+//
+// See https://grafana.com/docs/grafana/latest/dashboards/build-dashboards/view-dashboard-json-model/
+type grafanaDashboard struct {
+	Title         string             `json:"title"`
+	Tags          []string           `json:"tags"`
+	Editable      bool               `json:"editable"`
+	SchemaVersion int                `json:"schemaVersion"`
+	Time          grafanaTimeRange   `json:"time"`
+	Templating    grafanaTemplating  `json:"templating"`
+	Panels        []grafanaPanel     `json:"panels"`
+	Annotations   grafanaAnnotations `json:"annotations"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type"`
+	Query   string   `json:"query"`
+	Current struct{} `json:"current"`
+	Hide    int      `json:"hide"`
+	Refresh int      `json:"refresh"`
+}
+
+type grafanaAnnotations struct {
+	List []any `json:"list"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	GridPos     grafanaGridPos      `json:"gridPos"`
+	Collapsed   *bool               `json:"collapsed,omitempty"`
+	Panels      []grafanaPanel      `json:"panels,omitempty"`
+	Datasource  *grafanaDatasource  `json:"datasource,omitempty"`
+	Targets     []grafanaTarget     `json:"targets,omitempty"`
+	FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+	Options     *grafanaOptions     `json:"options,omitempty"`
+}
+
+type grafanaTarget struct {
+	RefID        string            `json:"refId"`
+	Datasource   grafanaDatasource `json:"datasource"`
+	Expr         string            `json:"expr"`
+	LegendFormat string            `json:"legendFormat"`
+	Range        bool              `json:"range"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults  grafanaFieldDefaults `json:"defaults"`
+	Overrides []any                `json:"overrides"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+type grafanaOptions struct {
+	Legend  grafanaLegend  `json:"legend"`
+	Tooltip grafanaTooltip `json:"tooltip"`
+}
+
+type grafanaLegend struct {
+	ShowLegend  bool   `json:"showLegend"`
+	DisplayMode string `json:"displayMode"`
+	Placement   string `json:"placement"`
+}
+
+type grafanaTooltip struct {
+	Mode string `json:"mode"`
+	Sort string `json:"sort"`
+}
+
+// newGrafanaDashboard converts vmui dashboard ds to Grafana dashboard.
+//
+// Every vmui row is converted to Grafana row with timeseries panels. Queries are executed via Prometheus datasource
+// selected at grafanaDatasourceVar dashboard variable.
+func newGrafanaDashboard(ds *dashboardSettings) *grafanaDashboard {
+	title := ds.Title
+	if title == "" {
+		title = strings.TrimSuffix(ds.Filename, ".json")
+	}
+	datasource := grafanaDatasource{
+		Type: "prometheus",
+		UID:  "${" + grafanaDatasourceVar + "}",
+	}
+	gd := &grafanaDashboard{
+		Title:         title,
+		Tags:          []string{"victoriametrics", "vmui"},
+		Editable:      true,
+		SchemaVersion: 39,
+		Time: grafanaTimeRange{
+			From: "now-1h",
+			To:   "now",
+		},
+		Templating: grafanaTemplating{
+			List: []grafanaVariable{{
+				Name:    grafanaDatasourceVar,
+				Label:   "Datasource",
+				Type:    "datasource",
+				Query:   "prometheus",
+				Refresh: 1,
+			}},
+		},
+		Annotations: grafanaAnnotations{
+			List: []any{},
+		},
+	}
+
+	id := 0
+	y := 0
+	for _, row := range ds.Rows {
+		id++
+		collapsed := false
+		gd.Panels = append(gd.Panels, grafanaPanel{
+			ID:    id,
+			Type:  "row",
+			Title: row.Title,
+			GridPos: grafanaGridPos{
+				H: 1,
+				W: grafanaGridWidth,
+				Y: y,
+			},
+			Collapsed: &collapsed,
+		})
+		y++
+
+		x := 0
+		for _, p := range row.Panels {
+			w := grafanaGridWidth
+			if p.Width > 0 && p.Width < 12 {
+				w = p.Width * grafanaGridWidth / 12
+			}
+			if x+w > grafanaGridWidth {
+				x = 0
+				y += grafanaPanelHeight
+			}
+			id++
+			gd.Panels = append(gd.Panels, newGrafanaPanel(id, &p, datasource, grafanaGridPos{
+				H: grafanaPanelHeight,
+				W: w,
+				X: x,
+				Y: y,
+			}))
+			x += w
+		}
+		if x > 0 {
+			y += grafanaPanelHeight
+		}
+	}
+	return gd
+}
+
+func newGrafanaPanel(id int, p *panelSettings, datasource grafanaDatasource, gridPos grafanaGridPos) grafanaPanel {
+	targets := make([]grafanaTarget, 0, len(p.Expr))
+	for i, expr := range p.Expr {
+		legendFormat := "__auto"
+		if i < len(p.Alias) && p.Alias[i] != "" {
+			// vmui aliases use the same {{label}} templates as Grafana legend format.
+			legendFormat = p.Alias[i]
+		}
+		targets = append(targets, grafanaTarget{
+			RefID:        grafanaRefID(i),
+			Datasource:   datasource,
+			Expr:         expr,
+			LegendFormat: legendFormat,
+			Range:        true,
+		})
+	}
+	unit := ""
+	if p.Unit != "" {
+		// vmui units are arbitrary strings, so they are displayed as custom suffixes in Grafana.
+		unit = "suffix: " + p.Unit
+	}
+	showLegend := p.ShowLegend == nil || *p.ShowLegend
+	return grafanaPanel{
+		ID:          id,
+		Type:        "timeseries",
+		Title:       p.Title,
+		Description: p.Description,
+		GridPos:     gridPos,
+		Datasource:  &datasource,
+		Targets:     targets,
+		FieldConfig: &grafanaFieldConfig{
+			Defaults: grafanaFieldDefaults{
+				Unit: unit,
+			},
+			Overrides: []any{},
+		},
+		Options: &grafanaOptions{
+			Legend: grafanaLegend{
+				ShowLegend:  showLegend,
+				DisplayMode: "list",
+				Placement:   "bottom",
+			},
+			Tooltip: grafanaTooltip{
+				Mode: "multi",
+				Sort: "desc",
+			},
+		},
+	}
+}
+
+// grafanaRefID returns Grafana query refId for the query with the given index: A, B, ..., Z, AA, AB, ...
+func grafanaRefID(i int) string {
+	var b []byte
+	for {
+		b = append([]byte{byte('A' + i%26)}, b...)
+		i = i/26 - 1
+		if i < 0 {
+			return string(b)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package vmselect
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDashboardFilenameFromTitle(t *testing.T) {
+	f := func(title, resultExpected string) {
+		t.Helper()
+
+		result := dashboardFilenameFromTitle(title)
+		if result != resultExpected {
+			t.Fatalf("unexpected filename for title %q; got %q; want %q", title, result, resultExpected)
+		}
+	}
+
+	f("", "dashboard.json")
+	f("!!!", "dashboard.json")
+	f("foo", "foo.json")
+	f("Per-job CPU usage", "per-job-cpu-usage.json")
+	f("  disk_usage / 5m  ", "disk_usage-5m.json")
+	f("../../etc/passwd", "etc-passwd.json")
+}
+
+func TestSaveDashboard_Success(t *testing.T) {
+	dir := t.TempDir()
+
+	f := func(data, filenameExpected string) {
+		t.Helper()
+
+		ds, err := saveDashboard(dir, []byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ds.Filename != filenameExpected {
+			t.Fatalf("unexpected filename; got %q; want %q", ds.Filename, filenameExpected)
+		}
+		if !ds.Saved {
+			t.Fatalf("expecting saved dashboard")
+		}
+
+		dss, err := collectDashboardsSettings(dir)
+		if err != nil {
+			t.Fatalf("cannot collect saved dashboards: %s", err)
+		}
+		for _, x := range dss {
+			if x.Filename == filenameExpected {
+				if x.Title != ds.Title || len(x.Rows) != len(ds.Rows) {
+					t.Fatalf("unexpected saved dashboard; got %+v; want %+v", x, ds)
+				}
+				return
+			}
+		}
+		t.Fatalf("cannot find saved dashboard %q", filenameExpected)
+	}
+
+	f(`{"title":"CPU usage","rows":[{"panels":[{"expr":["rate(process_cpu_seconds_total)"]}]}]}`, "cpu-usage.json")
+	f(`{"title":"CPU usage","filename":"cpu.json","rows":[{"panels":[{"expr":["a","b"],"width":6}]}]}`, "cpu.json")
+
+	// Overwrite the existing dashboard
+	f(`{"title":"CPU usage","filename":"cpu.json","rows":[{"panels":[{"expr":["a"]}]},{"panels":[{"expr":["b"]}]}]}`, "cpu.json")
+}
+
+func TestSaveDashboard_Failure(t *testing.T) {
+	dir := t.TempDir()
+
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := saveDashboard(dir, []byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid json
+	f(`foo`)
+
+	// missing title
+	f(`{"rows":[{"panels":[{"expr":["a"]}]}]}`)
+
+	// missing rows, panels or queries
+	f(`{"title":"foo"}`)
+	f(`{"title":"foo","rows":[{"panels":[]}]}`)
+	f(`{"title":"foo","rows":[{"panels":[{"expr":[]}]}]}`)
+	f(`{"title":"foo","rows":[{"panels":[{"expr":[" "]}]}]}`)
+
+	// invalid width
+	f(`{"title":"foo","rows":[{"panels":[{"expr":["a"],"width":13}]}]}`)
+
+	// invalid filename
+	f(`{"title":"foo","filename":"../foo.json","rows":[{"panels":[{"expr":["a"]}]}]}`)
+	f(`{"title":"foo","filename":"foo.txt","rows":[{"panels":[{"expr":["a"]}]}]}`)
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read dir: %s", err)
+	}
+	if len(files) > 0 {
+		t.Fatalf("unexpected files created for invalid dashboards: %s", filepath.Join(dir, files[0].Name()))
+	}
+}
+
+func TestNewGrafanaDashboard(t *testing.T) {
+	showLegend := false
+	ds := &dashboardSettings{
+		Filename: "foo.json",
+		Rows: []dashboardRow{
+			{
+				Title: "row1",
+				Panels: []panelSettings{
+					{
+						Title: "panel1",
+						Unit:  "MB",
+						Expr:  []string{"a", "b"},
+						Alias: []string{"{{job}}"},
+						Width: 6,
+					},
+					{
+						Title:      "panel2",
+						Expr:       []string{"c"},
+						ShowLegend: &showLegend,
+						Width:      8,
+					},
+				},
+			},
+			{
+				Panels: []panelSettings{
+					{
+						Expr: []string{"d"},
+					},
+				},
+			},
+		},
+	}
+	gd := newGrafanaDashboard(ds)
+	data, err := json.Marshal(gd)
+	if err != nil {
+		t.Fatalf("cannot marshal Grafana dashboard: %s", err)
+	}
+	resultExpected := `{"title":"foo","tags":["victoriametrics","vmui"],"editable":true,"schemaVersion":39,"time":{"from":"now-1h","to":"now"},` +
+		`"templating":{"list":[{"name":"ds","label":"Datasource","type":"datasource","query":"prometheus","current":{},"hide":0,"refresh":1}]},` +
+		`"panels":[` +
+		`{"id":1,"type":"row","title":"row1","gridPos":{"h":1,"w":24,"x":0,"y":0},"collapsed":false},` +
+		`{"id":2,"type":"timeseries","title":"panel1","gridPos":{"h":8,"w":12,"x":0,"y":1},"datasource":{"type":"prometheus","uid":"${ds}"},` +
+		`"targets":[{"refId":"A","datasource":{"type":"prometheus","uid":"${ds}"},"expr":"a","legendFormat":"{{job}}","range":true},` +
+		`{"refId":"B","datasource":{"type":"prometheus","uid":"${ds}"},"expr":"b","legendFormat":"__auto","range":true}],` +
+		`"fieldConfig":{"defaults":{"unit":"suffix: MB"},"overrides":[]},"options":{"legend":{"showLegend":true,"displayMode":"list","placement":"bottom"},"tooltip":{"mode":"multi","sort":"desc"}}},` +
+		`{"id":3,"type":"timeseries","title":"panel2","gridPos":{"h":8,"w":16,"x":0,"y":9},"datasource":{"type":"prometheus","uid":"${ds}"},` +
+		`"targets":[{"refId":"A","datasource":{"type":"prometheus","uid":"${ds}"},"expr":"c","legendFormat":"__auto","range":true}],` +
+		`"fieldConfig":{"defaults":{},"overrides":[]},"options":{"legend":{"showLegend":false,"displayMode":"list","placement":"bottom"},"tooltip":{"mode":"multi","sort":"desc"}}},` +
+		`{"id":4,"type":"row","title":"","gridPos":{"h":1,"w":24,"x":0,"y":17},"collapsed":false},` +
+		`{"id":5,"type":"timeseries","title":"","gridPos":{"h":8,"w":24,"x":0,"y":18},"datasource":{"type":"prometheus","uid":"${ds}"},` +
+		`"targets":[{"refId":"A","datasource":{"type":"prometheus","uid":"${ds}"},"expr":"d","legendFormat":"__auto","range":true}],` +
+		`"fieldConfig":{"defaults":{},"overrides":[]},"options":{"legend":{"showLegend":true,"displayMode":"list","placement":"bottom"},"tooltip":{"mode":"multi","sort":"desc"}}}],` +
+		`"annotations":{"list":[]}}`
+	if string(data) != resultExpected {
+		t.Fatalf("unexpected Grafana dashboard\ngot\n%s\nwant\n%s", data, resultExpected)
+	}
+}
+
+func TestGrafanaRefID(t *testing.T) {
+	f := func(i int, resultExpected string) {
+		t.Helper()
+
+		result := grafanaRefID(i)
+		if result != resultExpected {
+			t.Fatalf("unexpected refId for %d; got %q; want %q", i, result, resultExpected)
+		}
+	}
+
+	f(0, "A")
+	f(1, "B")
+	f(25, "Z")
+	f(26, "AA")
+	f(27, "AB")
+	f(51, "AZ")
+	f(52, "BA")
+}
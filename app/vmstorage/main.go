@@ -1,6 +1,7 @@
 package vmstorage
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -20,6 +21,8 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/mergeset"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ratelimiter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/syncwg"
@@ -43,10 +46,17 @@ var (
 	_ = flag.Int("bigMergeConcurrency", 0, "Deprecated: this flag does nothing")
 	_ = flag.Int("smallMergeConcurrency", 0, "Deprecated: this flag does nothing")
 
+	retentionKeepForeverMetricNameRegex = flagutil.NewArrayString("retentionKeepForever.metricNameRegex", "Optional regular expressions for metric names, "+
+		"which must be excluded from -retentionPeriod. Samples for the matching series are stored in dedicated partitions, which are never deleted. "+
+		"See https://docs.victoriametrics.com/#retention-exceptions")
+
 	retentionTimezoneOffset = flag.Duration("retentionTimezoneOffset", 0, "The offset for performing indexdb rotation. "+
 		"If set to 0, then the indexdb rotation is performed at 4am UTC time per each -retentionPeriod. "+
 		"If set to 2h, then the indexdb rotation is performed at 4am EET time (the timezone with +2h offset)")
 
+	enableBlockSummaries = flag.Bool("storage.enableBlockSummaries", false, "Whether to store the minimum, the maximum and the sum of values per each block of raw samples "+
+		"for speeding up min_over_time(), max_over_time() and avg_over_time() over long time ranges. Data written with this flag cannot be read by releases "+
+		"without block summaries support, so downgrading isn't possible after enabling it. See https://docs.victoriametrics.com/#block-summaries")
 	logNewSeries = flag.Bool("logNewSeries", false, "Whether to log new series. This option is for debug purposes only. It can lead to performance issues "+
 		"when big number of new series are ingested into VictoriaMetrics")
 	denyQueriesOutsideRetention = flag.Bool("denyQueriesOutsideRetention", false, "Whether to deny queries outside the configured -retentionPeriod. "+
@@ -63,12 +73,12 @@ var (
 
 	cacheSizeStorageTSID = flagutil.NewBytes("storage.cacheSizeStorageTSID", 0, "Overrides max size for storage/tsid cache. "+
 		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
-	cacheSizeIndexDBIndexBlocks = flagutil.NewBytes("storage.cacheSizeIndexDBIndexBlocks", 0, "Overrides max size for indexdb/indexBlocks cache. "+
-		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
-	cacheSizeIndexDBDataBlocks = flagutil.NewBytes("storage.cacheSizeIndexDBDataBlocks", 0, "Overrides max size for indexdb/dataBlocks cache. "+
-		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
-	cacheSizeIndexDBDataBlocksSparse = flagutil.NewBytes("storage.cacheSizeIndexDBDataBlocksSparse", 0, "Overrides max size for indexdb/dataBlocksSparse cache. "+
-		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
+	cacheSizeIndexDBIndexBlocks = flagutil.NewTunableBytes("storage.cacheSizeIndexDBIndexBlocks", 0, "Overrides max size for indexdb/indexBlocks cache. "+
+		"The size can be changed at runtime via /-/flags endpoint. See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
+	cacheSizeIndexDBDataBlocks = flagutil.NewTunableBytes("storage.cacheSizeIndexDBDataBlocks", 0, "Overrides max size for indexdb/dataBlocks cache. "+
+		"The size can be changed at runtime via /-/flags endpoint. See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
+	cacheSizeIndexDBDataBlocksSparse = flagutil.NewTunableBytes("storage.cacheSizeIndexDBDataBlocksSparse", 0, "Overrides max size for indexdb/dataBlocksSparse cache. "+
+		"The size can be changed at runtime via /-/flags endpoint. See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
 	cacheSizeIndexDBTagFilters = flagutil.NewBytes("storage.cacheSizeIndexDBTagFilters", 0, "Overrides max size for indexdb/tagFiltersToMetricIDs cache. "+
 		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
 
@@ -79,8 +89,21 @@ var (
 	trackMetricNamesStats = flag.Bool("storage.trackMetricNamesStats", false, "Whether to track ingest and query requests for timeseries metric names. "+
 		"This feature allows to track metric names unused at query requests. "+
 		"See https://docs.victoriametrics.com/#track-ingested-metrics-usage")
+	ingestionDedupWindow = flag.Duration("dedup.ingestionWindow", 0, "Drop samples with the same series and timestamp as already received samples during the given window. "+
+		"The first received sample wins. This can be used for exact deduplication of samples pushed from redundant pipelines before they are stored. "+
+		"Ingestion-time deduplication is disabled if set to 0. See https://docs.victoriametrics.com/#ingestion-time-deduplication")
+	ingestionDedupMaxItems = flag.Int("dedup.ingestionMaxItems", 0, "The maximum number of samples tracked for -dedup.ingestionWindow. "+
+		"The oldest samples are evicted when the limit is reached. By default the limit is set to 5% of -memory.allowedPercent memory. "+
+		"See https://docs.victoriametrics.com/#ingestion-time-deduplication")
+	metricMetadataTTL = flag.Duration("storage.metricMetadataTTL", 24*time.Hour, "Metric metadata (TYPE, HELP and UNIT) received via Prometheus remote write protocol "+
+		"is deleted if it isn't received again during the given TTL. Metric metadata isn't stored if it is set to 0. "+
+		"See https://docs.victoriametrics.com/#metric-metadata")
 	cacheSizeMetricNamesStats = flagutil.NewBytes("storage.cacheSizeMetricNamesStats", 0, "Overrides max size for storage/metricNamesStatsTracker cache. "+
 		"See https://docs.victoriametrics.com/single-server-victoriametrics/#cache-tuning")
+
+	batchIOReadLimit = flagutil.NewBytes("search.batchIOReadLimit", 0, "The maximum number of bytes per second, which can be read from disk by queries with batch IO class. "+
+		"This prevents bulk exports from evicting page cache needed by interactive queries. By default, disk reads aren't throttled. "+
+		"See https://docs.victoriametrics.com/#io-classes")
 )
 
 // CheckTimeRange returns true if the given tr is denied for querying.
@@ -98,6 +121,18 @@ func CheckTimeRange(tr storage.TimeRange) error {
 	}
 }
 
+func getKeepForeverMetricNameRegexes() []*regexutil.PromRegex {
+	var regexes []*regexutil.PromRegex
+	for _, expr := range *retentionKeepForeverMetricNameRegex {
+		re, err := regexutil.NewPromRegex(expr)
+		if err != nil {
+			logger.Fatalf("cannot parse -retentionKeepForever.metricNameRegex=%q: %s", expr, err)
+		}
+		regexes = append(regexes, re)
+	}
+	return regexes
+}
+
 // Init initializes vmstorage.
 func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	if err := encoding.CheckPrecisionBits(uint8(*precisionBits)); err != nil {
@@ -106,6 +141,7 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 
 	resetResponseCacheIfNeeded = resetCacheIfNeeded
 	storage.SetLogNewSeries(*logNewSeries)
+	storage.SetBlockSummariesEnabled(*enableBlockSummaries)
 	storage.SetRetentionTimezoneOffset(*retentionTimezoneOffset)
 	storage.SetFreeDiskSpaceLimit(minFreeDiskSpaceBytes.N)
 	storage.SetTSIDCacheSize(cacheSizeStorageTSID.IntN())
@@ -114,6 +150,16 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	mergeset.SetIndexBlocksCacheSize(cacheSizeIndexDBIndexBlocks.IntN())
 	mergeset.SetDataBlocksCacheSize(cacheSizeIndexDBDataBlocks.IntN())
 	mergeset.SetDataBlocksSparseCacheSize(cacheSizeIndexDBDataBlocksSparse.IntN())
+	cacheSizeIndexDBIndexBlocks.OnChange(func() {
+		mergeset.SetIndexBlocksCacheSize(cacheSizeIndexDBIndexBlocks.IntN())
+	})
+	cacheSizeIndexDBDataBlocks.OnChange(func() {
+		mergeset.SetDataBlocksCacheSize(cacheSizeIndexDBDataBlocks.IntN())
+	})
+	cacheSizeIndexDBDataBlocksSparse.OnChange(func() {
+		mergeset.SetDataBlocksSparseCacheSize(cacheSizeIndexDBDataBlocksSparse.IntN())
+	})
+	startBatchIOReadLimiter()
 
 	if retentionPeriod.Duration() < 24*time.Hour {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
@@ -127,6 +173,12 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 		MaxDailySeries:        *maxDailySeries,
 		DisablePerDayIndex:    *disablePerDayIndex,
 		TrackMetricNamesStats: *trackMetricNamesStats,
+		IngestionDedupWindow:  *ingestionDedupWindow,
+		MetricMetadataTTL:     *metricMetadataTTL,
+
+		IngestionDedupMaxItems: *ingestionDedupMaxItems,
+
+		KeepForeverMetricNameRegexes: getKeepForeverMetricNameRegexes(),
 	}
 	strg := storage.MustOpenStorage(*DataPath, opts)
 	Storage = strg
@@ -189,6 +241,27 @@ func RegisterMetricNames(qt *querytracer.Tracer, mrs []storage.MetricRow) {
 	WG.Done()
 }
 
+// AddMetricMetadata adds mms to the storage.
+func AddMetricMetadata(mms []storage.MetricMetadata) {
+	WG.Add(1)
+	Storage.AddMetricMetadata(mms)
+	WG.Done()
+}
+
+// SearchMetricMetadata returns metric metadata for the given metricFamilyName grouped by metric family names.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+func SearchMetricMetadata(qt *querytracer.Tracer, metricFamilyName string, limit, limitPerMetric int) map[string][]storage.MetricMetadata {
+	qt = qt.NewChild("search metric metadata: metric=%q, limit=%d, limit_per_metric=%d", metricFamilyName, limit, limitPerMetric)
+	defer qt.Done()
+
+	WG.Add(1)
+	m := Storage.SearchMetricMetadata(metricFamilyName, limit, limitPerMetric)
+	WG.Done()
+	qt.Printf("found metadata for %d metric families", len(m))
+	return m
+}
+
 // DeleteSeries deletes series matching tfss.
 //
 // Returns the number of deleted series.
@@ -207,6 +280,14 @@ func GetMetricNamesStats(qt *querytracer.Tracer, limit, le int, matchPattern str
 	return r, nil
 }
 
+// GetUnusedMetricNamesStats returns stats for metric names, which weren't queried since the given unusedSince unix timestamp in seconds.
+func GetUnusedMetricNamesStats(qt *querytracer.Tracer, limit int, unusedSince uint64, matchPattern string) (storage.MetricNamesStatsResponse, error) {
+	WG.Add(1)
+	r := Storage.GetUnusedMetricNamesStats(qt, limit, unusedSince, matchPattern)
+	WG.Done()
+	return r, nil
+}
+
 // ResetMetricNamesStats resets state for metric names usage tracker
 func ResetMetricNamesStats(qt *querytracer.Tracer) {
 	WG.Add(1)
@@ -281,6 +362,8 @@ func Stop() {
 
 	logger.Infof("gracefully closing the storage at %s", *DataPath)
 	startTime := time.Now()
+	// Unblock throttled searches before waiting for them to finish.
+	stopBatchIOReadLimiter()
 	WG.WaitAndBlock()
 	stopStaleSnapshotsRemover()
 	Storage.MustClose()
@@ -289,6 +372,26 @@ func Stop() {
 	logger.Infof("the storage has been stopped")
 }
 
+func startBatchIOReadLimiter() {
+	if batchIOReadLimit.N <= 0 {
+		return
+	}
+	limitReached := metrics.GetOrCreateCounter(`vm_batch_io_read_limit_reached_total`)
+	batchIOReadLimiterStopCh = make(chan struct{})
+	storage.SetBatchIOReadLimiter(ratelimiter.New(batchIOReadLimit.N, limitReached, batchIOReadLimiterStopCh))
+}
+
+func stopBatchIOReadLimiter() {
+	if batchIOReadLimiterStopCh == nil {
+		return
+	}
+	storage.SetBatchIOReadLimiter(nil)
+	close(batchIOReadLimiterStopCh)
+	batchIOReadLimiterStopCh = nil
+}
+
+var batchIOReadLimiterStopCh chan struct{}
+
 // RequestHandler is a storage request handler.
 func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	path := r.URL.Path
@@ -356,6 +459,23 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		fmt.Fprintf(w, `]}`)
 		return true
+	case "/manifest":
+		snapshotsManifestTotal.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		snapshotName := r.FormValue("snapshot")
+		sm, err := Storage.GetSnapshotManifest(snapshotName)
+		if err != nil {
+			err = fmt.Errorf("cannot obtain manifest for snapshot %q: %w", snapshotName, err)
+			jsonResponseError(w, err)
+			snapshotsManifestErrorsTotal.Inc()
+			return true
+		}
+		data, err := json.Marshal(sm)
+		if err != nil {
+			logger.Panicf("BUG: cannot marshal snapshot manifest: %s", err)
+		}
+		fmt.Fprintf(w, `{"status":"ok","manifest":%s}`, data)
+		return true
 	case "/delete":
 		snapshotsDeleteTotal.Inc()
 		w.Header().Set("Content-Type", "application/json")
@@ -437,6 +557,9 @@ var (
 
 	snapshotsListTotal = metrics.NewCounter(`vm_http_requests_total{path="/snapshot/list"}`)
 
+	snapshotsManifestTotal       = metrics.NewCounter(`vm_http_requests_total{path="/snapshot/manifest"}`)
+	snapshotsManifestErrorsTotal = metrics.NewCounter(`vm_http_request_errors_total{path="/snapshot/manifest"}`)
+
 	snapshotsDeleteTotal       = metrics.NewCounter(`vm_http_requests_total{path="/snapshot/delete"}`)
 	snapshotsDeleteErrorsTotal = metrics.NewCounter(`vm_http_request_errors_total{path="/snapshot/delete"}`)
 
@@ -530,6 +653,14 @@ func writeStorageMetrics(w io.Writer, strg *storage.Storage) {
 	metrics.WriteCounterUint64(w, `vm_rows_received_by_storage_total`, m.RowsReceivedTotal)
 	metrics.WriteCounterUint64(w, `vm_rows_added_to_storage_total`, m.RowsAddedTotal)
 	metrics.WriteCounterUint64(w, `vm_deduplicated_samples_total{type="merge"}`, m.DedupsDuringMerge)
+	if *ingestionDedupWindow > 0 {
+		metrics.WriteCounterUint64(w, `vm_deduplicated_samples_total{type="ingestion"}`, m.IngestionDedupRowsDropped)
+		metrics.WriteGaugeUint64(w, `vm_ingestion_dedup_current_items`, m.IngestionDedupCurrentItems)
+		metrics.WriteCounterUint64(w, `vm_ingestion_dedup_evictions_total`, m.IngestionDedupEvictions)
+	}
+	if *metricMetadataTTL > 0 {
+		metrics.WriteGaugeUint64(w, `vm_metric_metadata_items`, m.MetricMetadataItems)
+	}
 	metrics.WriteGaugeUint64(w, `vm_snapshots`, m.SnapshotsCount)
 
 	metrics.WriteCounterUint64(w, `vm_rows_ignored_total{reason="big_timestamp"}`, m.TooBigTimestampRows)
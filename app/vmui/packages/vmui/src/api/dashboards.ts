@@ -0,0 +1,13 @@
+export const getSavedDashboardsUrl = (server: string, filename?: string): string => {
+  const params = new URLSearchParams();
+  if (filename) params.set("filename", filename);
+  // authKey from the vmui url is required by -vmui.savedDashboardsAuthKey
+  const authKey = new URLSearchParams(window.location.search).get("authKey");
+  if (authKey) params.set("authKey", authKey);
+  const query = params.toString();
+  return `${server}/vmui/saved-dashboards${query ? `?${query}` : ""}`;
+};
+
+export const getGrafanaDashboardUrl = (server: string, filename: string): string => (
+  `${server}/vmui/custom-dashboards/grafana?filename=${encodeURIComponent(filename)}`
+);
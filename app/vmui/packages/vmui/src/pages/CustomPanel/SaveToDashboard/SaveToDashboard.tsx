@@ -0,0 +1,175 @@
+import React, { FC, useEffect, useMemo, useState } from "preact/compat";
+import { PlusIcon } from "../../../components/Main/Icons";
+import Button from "../../../components/Main/Button/Button";
+import Tooltip from "../../../components/Main/Tooltip/Tooltip";
+import Modal from "../../../components/Main/Modal/Modal";
+import Select from "../../../components/Main/Select/Select";
+import TextField from "../../../components/Main/TextField/TextField";
+import Checkbox from "../../../components/Main/Checkbox/Checkbox";
+import Alert from "../../../components/Main/Alert/Alert";
+import useBoolean from "../../../hooks/useBoolean";
+import { useAppState } from "../../../state/common/StateContext";
+import { useQueryState } from "../../../state/query/QueryStateContext";
+import { useDashboardsDispatch, useDashboardsState } from "../../../state/dashboards/DashboardsStateContext";
+import { getQueriesFromStorage } from "../QueryHistory/utils";
+import { getSavedDashboardsUrl } from "../../../api/dashboards";
+import { DashboardSettings, PanelSettings } from "../../../types";
+import { Link } from "react-router-dom";
+import router from "../../../router";
+import "./style.scss";
+
+const NEW_DASHBOARD = "New dashboard";
+
+// The number of panels per row in vmui dashboards grid with 12 columns.
+const PANELS_PER_ROW = 2;
+
+const SaveToDashboard: FC = () => {
+  const { serverUrl } = useAppState();
+  const { query } = useQueryState();
+  const { dashboardsSettings } = useDashboardsState();
+  const dashboardsDispatch = useDashboardsDispatch();
+
+  const [dashboard, setDashboard] = useState(NEW_DASHBOARD);
+  const [title, setTitle] = useState("");
+  const [selected, setSelected] = useState<string[]>([]);
+  const [error, setError] = useState("");
+  const [savedTitle, setSavedTitle] = useState("");
+  const [isLoading, setIsLoading] = useState(false);
+
+  const {
+    value: openModal,
+    toggle: toggleOpen,
+    setFalse: handleClose,
+  } = useBoolean(false);
+
+  const savedDashboards = useMemo(() => dashboardsSettings.filter(d => d.saved), [dashboardsSettings]);
+  const dashboardsList = useMemo(() => [NEW_DASHBOARD, ...savedDashboards.map(d => d.title || d.filename)], [savedDashboards]);
+
+  const queries = useMemo(() => {
+    const favorites = getQueriesFromStorage("QUERY_FAVORITES").flat();
+    return Array.from(new Set([...query, ...favorites].filter(q => q.trim())));
+  }, [query, openModal]);
+
+  const createHandlerToggleQuery = (q: string) => (checked: boolean) => {
+    setSelected(prev => checked ? [...prev, q] : prev.filter(v => v !== q));
+  };
+
+  const handleSave = async () => {
+    if (!selected.length) {
+      setError("Select at least a single query");
+      return;
+    }
+    const existing = savedDashboards.find(d => (d.title || d.filename) === dashboard);
+    if (!existing && !title.trim()) {
+      setError("Enter the dashboard title");
+      return;
+    }
+
+    const panels: PanelSettings[] = selected.map(q => ({
+      title: q,
+      expr: [q],
+      width: 12 / PANELS_PER_ROW,
+    }));
+    const settings: DashboardSettings = existing
+      ? { ...existing, rows: [...existing.rows, { panels }] }
+      : { title: title.trim(), filename: "", rows: [{ panels }] };
+
+    setError("");
+    setIsLoading(true);
+    try {
+      const response = await fetch(getSavedDashboardsUrl(serverUrl), {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(settings),
+      });
+      if (!response.ok) {
+        setError(await response.text());
+        return;
+      }
+      const saved: DashboardSettings = await response.json();
+      const others = dashboardsSettings.filter(d => !(d.saved && d.filename === saved.filename));
+      dashboardsDispatch({ type: "SET_DASHBOARDS_SETTINGS", payload: [...others, saved] });
+      setSavedTitle(saved.title || saved.filename);
+    } catch (e) {
+      if (e instanceof Error) setError(`${e.name}: ${e.message}`);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    setError("");
+    setSavedTitle("");
+    setTitle("");
+    setDashboard(NEW_DASHBOARD);
+    setSelected(query.filter(q => q.trim()));
+  }, [openModal]);
+
+  return (
+    <>
+      <Tooltip title={"Add to dashboard"}>
+        <Button
+          variant="text"
+          startIcon={<PlusIcon/>}
+          onClick={toggleOpen}
+          ariaLabel="add to dashboard"
+        />
+      </Tooltip>
+      {openModal && (
+        <Modal
+          title={"Add to dashboard"}
+          onClose={handleClose}
+          isOpen={openModal}
+        >
+          <div className="vm-save-to-dashboard">
+            <Select
+              value={dashboard}
+              list={dashboardsList}
+              label="Dashboard"
+              onChange={setDashboard}
+            />
+            {dashboard === NEW_DASHBOARD && (
+              <TextField
+                label="Dashboard title"
+                value={title}
+                onChange={setTitle}
+              />
+            )}
+            <div className="vm-save-to-dashboard-queries">
+              <div className="vm-save-to-dashboard-queries__title">
+                Current and favorite queries. Every selected query is added as a separate panel.
+              </div>
+              {queries.map(q => (
+                <Checkbox
+                  key={q}
+                  checked={selected.includes(q)}
+                  onChange={createHandlerToggleQuery(q)}
+                  label={q}
+                />
+              ))}
+            </div>
+            {error && <Alert variant="error">{error}</Alert>}
+            {savedTitle && (
+              <Alert variant="success">
+                Dashboard <b>{savedTitle}</b> has been saved. See the <Link
+                  className="vm-link vm-link_underlined vm-link_colored"
+                  to={router.dashboards}
+                >Dashboards</Link> tab.
+              </Alert>
+            )}
+            <div className="vm-save-to-dashboard__buttons">
+              <Button
+                onClick={handleSave}
+                disabled={isLoading}
+              >
+                {isLoading ? "Saving..." : "Save"}
+              </Button>
+            </div>
+          </div>
+        </Modal>
+      )}
+    </>
+  );
+};
+
+export default SaveToDashboard;
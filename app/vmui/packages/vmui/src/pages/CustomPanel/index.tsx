@@ -19,6 +19,8 @@ import CustomPanelTabs from "./CustomPanelTabs";
 import { DisplayType } from "../../types";
 import DownloadReport from "./DownloadReport/DownloadReport";
 import WarningHeatmapToLine from "./WarningHeatmapToLine/WarningHeatmapToLine";
+import SaveToDashboard from "./SaveToDashboard/SaveToDashboard";
+import { APP_TYPE_VM } from "../../constants/appType";
 
 const CustomPanel: FC = () => {
   useSetQueryParams();
@@ -119,6 +121,7 @@ const CustomPanel: FC = () => {
             <DisplayTypeSwitch/>
           </div>
           {(graphData || liveData) && <DownloadReport fetchUrl={fetchUrl}/>}
+          {APP_TYPE_VM && <SaveToDashboard/>}
         </div>
         <CustomPanelTabs
           graphData={graphData}
@@ -0,0 +1,100 @@
+import React, { FC, useState } from "preact/compat";
+import Button from "../../../components/Main/Button/Button";
+import Alert from "../../../components/Main/Alert/Alert";
+import { DeleteIcon, DownloadIcon } from "../../../components/Main/Icons";
+import { useAppState } from "../../../state/common/StateContext";
+import { useDashboardsDispatch, useDashboardsState } from "../../../state/dashboards/DashboardsStateContext";
+import { getGrafanaDashboardUrl, getSavedDashboardsUrl } from "../../../api/dashboards";
+import { DashboardSettings } from "../../../types";
+import "./style.scss";
+
+interface Props {
+  dashboard: DashboardSettings;
+  onDelete: () => void;
+}
+
+const DashboardActions: FC<Props> = ({ dashboard, onDelete }) => {
+  const { serverUrl } = useAppState();
+  const { dashboardsSettings } = useDashboardsState();
+  const dispatch = useDashboardsDispatch();
+
+  const [error, setError] = useState("");
+  const [isLoading, setIsLoading] = useState(false);
+
+  const handleExportGrafana = async () => {
+    setError("");
+    setIsLoading(true);
+    try {
+      const response = await fetch(getGrafanaDashboardUrl(serverUrl, dashboard.filename));
+      if (!response.ok) {
+        setError(await response.text());
+        return;
+      }
+      const blob = await response.blob();
+      const href = URL.createObjectURL(blob);
+
+      const link = document.createElement("a");
+      link.href = href;
+      link.download = `grafana-${dashboard.filename}`;
+      document.body.appendChild(link);
+      link.click();
+
+      document.body.removeChild(link);
+      URL.revokeObjectURL(href);
+    } catch (e) {
+      if (e instanceof Error) setError(`${e.name}: ${e.message}`);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  const handleDelete = async () => {
+    setError("");
+    setIsLoading(true);
+    try {
+      const response = await fetch(getSavedDashboardsUrl(serverUrl, dashboard.filename), { method: "DELETE" });
+      if (!response.ok) {
+        setError(await response.text());
+        return;
+      }
+      const payload = dashboardsSettings.filter(d => !(d.saved && d.filename === dashboard.filename));
+      dispatch({ type: "SET_DASHBOARDS_SETTINGS", payload });
+      onDelete();
+    } catch (e) {
+      if (e instanceof Error) setError(`${e.name}: ${e.message}`);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <div className="vm-dashboard-actions">
+      {error && <Alert variant="error">{error}</Alert>}
+      <div className="vm-dashboard-actions__buttons">
+        <Button
+          variant="text"
+          size="small"
+          startIcon={<DownloadIcon/>}
+          onClick={handleExportGrafana}
+          disabled={isLoading}
+        >
+          Export to Grafana
+        </Button>
+        {dashboard.saved && (
+          <Button
+            variant="text"
+            size="small"
+            color="error"
+            startIcon={<DeleteIcon/>}
+            onClick={handleDelete}
+            disabled={isLoading}
+          >
+            Delete
+          </Button>
+        )}
+      </div>
+    </div>
+  );
+};
+
+export default DashboardActions;
@@ -7,6 +7,8 @@ import "./style.scss";
 import { useDashboardsState } from "../../state/dashboards/DashboardsStateContext";
 import Spinner from "../../components/Main/Spinner/Spinner";
 import useDeviceDetect from "../../hooks/useDeviceDetect";
+import DashboardActions from "./DashboardActions/DashboardActions";
+import { DashboardSettings } from "../../types";
 
 const DashboardsLayout: FC = () => {
   useSetQueryParams();
@@ -32,6 +34,10 @@ const DashboardsLayout: FC = () => {
     handleChangeDashboard(value);
   };
 
+  const handleDeleteDashboard = () => {
+    setDashboard(0);
+  };
+
   return <div className="vm-predefined-panels">
     {dashboardsLoading && <Spinner />}
     {!dashboardsSettings.length && dashboardsError && <Alert variant="error">{dashboardsError}</Alert>}
@@ -57,6 +63,12 @@ const DashboardsLayout: FC = () => {
         ))}
       </div>
     )}
+    {validDashboardRows && activeDashboard.filename && (
+      <DashboardActions
+        dashboard={activeDashboard as DashboardSettings}
+        onDelete={handleDeleteDashboard}
+      />
+    )}
     <div className="vm-predefined-panels__dashboards">
       {validDashboardRows && (
         rows.map((r,i) =>
@@ -74,6 +74,7 @@ export interface DashboardSettings {
   title?: string;
   filename: string;
   rows: DashboardRow[];
+  saved?: boolean;
 }
 
 export interface RelativeTimeOption {
@@ -71,6 +71,18 @@ func (l *Limiter) Add(h uint64) bool {
 	return lm.Add(h)
 }
 
+// AddAll adds all the hs to the limiter.
+//
+// It is safe calling AddAll from concurrent goroutines.
+//
+// True is returned if all the hs are added or already exist in l.
+// False is returned without adding hs to l if l cannot hold all the new items from hs, since it would exceed maxItems unique items.
+// hs mustn't contain duplicate items.
+func (l *Limiter) AddAll(hs []uint64) bool {
+	lm := l.v.Load()
+	return lm.AddAll(hs)
+}
+
 type limiter struct {
 	currentItems atomic.Uint64
 	f            *filter
@@ -92,3 +104,24 @@ func (l *limiter) Add(h uint64) bool {
 	}
 	return true
 }
+
+func (l *limiter) AddAll(hs []uint64) bool {
+	newItems := uint64(0)
+	for _, h := range hs {
+		if !l.f.Has(h) {
+			newItems++
+		}
+	}
+	if newItems == 0 {
+		return true
+	}
+	if l.currentItems.Load()+newItems > uint64(l.f.maxItems) {
+		return false
+	}
+	for _, h := range hs {
+		if l.f.Add(h) {
+			l.currentItems.Add(1)
+		}
+	}
+	return true
+}
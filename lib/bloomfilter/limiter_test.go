@@ -94,3 +94,29 @@ func TestLimiterConcurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestLimiterAddAll(t *testing.T) {
+	l := NewLimiter(10, time.Hour)
+	defer l.MustStop()
+
+	f := func(hs []uint64, resultExpected bool, currentItemsExpected int) {
+		t.Helper()
+		if result := l.AddAll(hs); result != resultExpected {
+			t.Fatalf("unexpected result for AddAll(%v); got %v; want %v", hs, result, resultExpected)
+		}
+		if n := l.CurrentItems(); n != currentItemsExpected {
+			t.Fatalf("unexpected number of items after AddAll(%v); got %d; want %d", hs, n, currentItemsExpected)
+		}
+	}
+
+	f(nil, true, 0)
+	f([]uint64{1, 2, 3, 4, 5, 6, 7, 8}, true, 8)
+
+	// Items exceeding the limit mustn't be added
+	f([]uint64{9, 10, 11}, false, 8)
+	f([]uint64{1, 9, 10}, true, 10)
+
+	// Existing items can be added after reaching the limit
+	f([]uint64{1, 2, 10}, true, 10)
+	f([]uint64{1, 12}, false, 10)
+}
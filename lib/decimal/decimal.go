@@ -415,6 +415,11 @@ func isSpecialValue(v int64) bool {
 	return v > vMax || v < vMin
 }
 
+// IsSpecialValueInt64 returns true if v represents Inf or Prometheus staleness mark.
+func IsSpecialValueInt64(v int64) bool {
+	return isSpecialValue(v)
+}
+
 // IsStaleNaN returns true if f represents Prometheus staleness mark.
 func IsStaleNaN(f float64) bool {
 	return math.Float64bits(f) == staleNaNBits
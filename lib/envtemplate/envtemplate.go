@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/valyala/fasttemplate"
@@ -33,6 +34,20 @@ func ReplaceString(s string) (string, error) {
 	return result, nil
 }
 
+// ReplaceAllowedBytes replaces `${ENV_VAR}` and `${ENV_VAR:-default}` placeholders in b with the corresponding ENV_VAR values
+// for ENV_VAR names from allowedNames.
+//
+// Placeholders with ENV_VAR names outside allowedNames are left as is, since they may be used for other purposes
+// such as `${1}` references in relabeling rules.
+// The default value is used if ENV_VAR isn't set. Error is returned if ENV_VAR isn't set and the default value is missing.
+func ReplaceAllowedBytes(b []byte, allowedNames []string) ([]byte, error) {
+	result, err := expandAllowed(envVars, string(b), allowedNames)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(result), nil
+}
+
 // LookupEnv returns the expanded environment variable value for the given name.
 //
 // The expanded means that `%{ENV_VAR}` placeholders in env var value are replaced
@@ -109,6 +124,31 @@ func expand(m map[string]string, s string) (string, error) {
 	return result, nil
 }
 
+func expandAllowed(m map[string]string, s string, allowedNames []string) (string, error) {
+	if len(allowedNames) == 0 || !strings.Contains(s, "${") {
+		// Fast path - nothing to expand
+		return s, nil
+	}
+	result, err := fasttemplate.ExecuteFuncStringWithErr(s, "${", "}", func(w io.Writer, tag string) (int, error) {
+		name, defaultValue, hasDefault := strings.Cut(tag, ":-")
+		if !isValidEnvVarName(name) || !slices.Contains(allowedNames, name) {
+			return fmt.Fprintf(w, "${%s}", tag)
+		}
+		v, ok := m[name]
+		if !ok {
+			if !hasDefault {
+				return 0, fmt.Errorf("missing %q env var; set it or specify the default value via ${%s:-default}", name, name)
+			}
+			v = defaultValue
+		}
+		return fmt.Fprintf(w, "%s", v)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
 func isValidEnvVarName(s string) bool {
 	return envVarNameRegex.MatchString(s)
 }
@@ -94,3 +94,53 @@ func TestReplaceFailure(t *testing.T) {
 	f("%{Foo-Bar-2}")
 	f("%{Foo.Baz.3}")
 }
+
+func TestReplaceAllowedSuccess(t *testing.T) {
+	envVars = map[string]string{
+		"foo":       "bar",
+		"foo.bar_1": "baz",
+		"empty":     "",
+	}
+	f := func(s string, allowedNames []string, resultExpected string) {
+		t.Helper()
+		result, err := ReplaceAllowedBytes([]byte(s), allowedNames)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result for ReplaceAllowedBytes(%q, %q);\ngot\n%q\nwant\n%q", s, allowedNames, result, resultExpected)
+		}
+	}
+	f("", nil, "")
+	f("${foo}", nil, "${foo}")
+	f("a ${foo}-x", []string{"foo"}, "a bar-x")
+	f("${foo.bar_1}", []string{"foo.bar_1"}, "baz")
+
+	// placeholders outside allowed names are left as is
+	f("${foo} ${1} ${foo.bar_1}", []string{"foo"}, "bar ${1} ${foo.bar_1}")
+	f("${missing}", []string{"foo"}, "${missing}")
+
+	// %{ENV_VAR} placeholders are ignored
+	f("%{foo}", []string{"foo"}, "%{foo}")
+
+	// default values
+	f("${missing:-default}", []string{"missing"}, "default")
+	f("${missing:-}", []string{"missing"}, "")
+	f("${missing:-http://localhost:8428}", []string{"missing"}, "http://localhost:8428")
+	f("${foo:-default}", []string{"foo"}, "bar")
+	f("${empty:-default}", []string{"empty"}, "")
+}
+
+func TestReplaceAllowedFailure(t *testing.T) {
+	envVars = map[string]string{
+		"foo": "bar",
+	}
+	f := func(s string, allowedNames []string) {
+		t.Helper()
+		if _, err := ReplaceAllowedBytes([]byte(s), allowedNames); err == nil {
+			t.Fatalf("expecting non-nil error for ReplaceAllowedBytes(%q, %q)", s, allowedNames)
+		}
+	}
+	f("${missing}", []string{"missing"})
+	f("${foo} ${missing}", []string{"foo", "missing"})
+}
@@ -0,0 +1,325 @@
+package flagutil
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// NewTunableInt returns new int flag with the given name, defaultValue and description.
+//
+// validate is called for every value passed to the flag. It may be nil.
+//
+// The flag value can be changed at runtime via SetRuntimeTunables.
+func NewTunableInt(name string, defaultValue int, description string, validate func(n int) error) *TunableInt {
+	ti := &TunableInt{
+		validate: validate,
+	}
+	ti.n.Store(int64(defaultValue))
+	flag.Var(ti, name, description)
+	registerRuntimeTunable(name, ti)
+	return ti
+}
+
+// TunableInt is an int flag, which can be changed at runtime via SetRuntimeTunables.
+//
+// It is safe to call its methods from concurrently running goroutines.
+type TunableInt struct {
+	n        atomic.Int64
+	validate func(n int) error
+
+	onChange atomic.Pointer[func()]
+}
+
+// Get returns the current flag value.
+func (ti *TunableInt) Get() int {
+	return int(ti.n.Load())
+}
+
+// OnChange registers f to be called after the flag value is changed via SetRuntimeTunables.
+func (ti *TunableInt) OnChange(f func()) {
+	ti.onChange.Store(&f)
+}
+
+// String implements flag.Value interface
+func (ti *TunableInt) String() string {
+	return strconv.FormatInt(ti.n.Load(), 10)
+}
+
+// Set implements flag.Value interface
+func (ti *TunableInt) Set(value string) error {
+	set, err := ti.prepare(value)
+	if err != nil {
+		return err
+	}
+	set()
+	return nil
+}
+
+func (ti *TunableInt) parse(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q as int: %w", value, err)
+	}
+	if ti.validate != nil {
+		if err := ti.validate(n); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (ti *TunableInt) prepare(value string) (func(), error) {
+	n, err := ti.parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		ti.n.Store(int64(n))
+	}, nil
+}
+
+func (ti *TunableInt) notifyChange() {
+	if f := ti.onChange.Load(); f != nil {
+		(*f)()
+	}
+}
+
+// NewTunableBytes returns new `bytes` flag with the given name, defaultValue and description.
+//
+// The flag value can be changed at runtime via SetRuntimeTunables.
+func NewTunableBytes(name string, defaultValue int64, description string) *TunableBytes {
+	description += "\nSupports the following optional suffixes for `size` values: KB, MB, GB, TB, KiB, MiB, GiB, TiB"
+	tb := &TunableBytes{}
+	tb.b.Store(&Bytes{
+		N:           defaultValue,
+		Name:        name,
+		valueString: fmt.Sprintf("%d", defaultValue),
+	})
+	flag.Var(tb, name, description)
+	registerRuntimeTunable(name, tb)
+	return tb
+}
+
+// TunableBytes is a flag for holding size in bytes, which can be changed at runtime via SetRuntimeTunables.
+//
+// It is safe to call its methods from concurrently running goroutines.
+type TunableBytes struct {
+	b atomic.Pointer[Bytes]
+
+	onChange atomic.Pointer[func()]
+}
+
+// IntN returns the current flag value capped by int type.
+func (tb *TunableBytes) IntN() int {
+	return tb.b.Load().IntN()
+}
+
+// OnChange registers f to be called after the flag value is changed via SetRuntimeTunables.
+func (tb *TunableBytes) OnChange(f func()) {
+	tb.onChange.Store(&f)
+}
+
+// String implements flag.Value interface
+func (tb *TunableBytes) String() string {
+	return tb.b.Load().String()
+}
+
+// Set implements flag.Value interface
+func (tb *TunableBytes) Set(value string) error {
+	set, err := tb.prepare(value)
+	if err != nil {
+		return err
+	}
+	set()
+	return nil
+}
+
+func (tb *TunableBytes) prepare(value string) (func(), error) {
+	b := &Bytes{
+		Name: tb.b.Load().Name,
+	}
+	if err := b.Set(value); err != nil {
+		return nil, err
+	}
+	return func() {
+		tb.b.Store(b)
+	}, nil
+}
+
+func (tb *TunableBytes) notifyChange() {
+	if f := tb.onChange.Load(); f != nil {
+		(*f)()
+	}
+}
+
+// NewTunableString returns new string flag with the given name, defaultValue and description.
+//
+// validate is called for every value passed to the flag. It may be nil.
+//
+// The flag value can be changed at runtime via SetRuntimeTunables.
+func NewTunableString(name, defaultValue, description string, validate func(value string) error) *TunableString {
+	ts := &TunableString{
+		validate: validate,
+	}
+	ts.s.Store(&defaultValue)
+	flag.Var(ts, name, description)
+	registerRuntimeTunable(name, ts)
+	return ts
+}
+
+// TunableString is a string flag, which can be changed at runtime via SetRuntimeTunables.
+//
+// It is safe to call its methods from concurrently running goroutines.
+type TunableString struct {
+	s        atomic.Pointer[string]
+	validate func(value string) error
+
+	onChange atomic.Pointer[func()]
+}
+
+// Get returns the current flag value.
+func (ts *TunableString) Get() string {
+	return ts.String()
+}
+
+// OnChange registers f to be called after the flag value is changed via SetRuntimeTunables.
+func (ts *TunableString) OnChange(f func()) {
+	ts.onChange.Store(&f)
+}
+
+// String implements flag.Value interface
+func (ts *TunableString) String() string {
+	p := ts.s.Load()
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// Set implements flag.Value interface
+func (ts *TunableString) Set(value string) error {
+	set, err := ts.prepare(value)
+	if err != nil {
+		return err
+	}
+	set()
+	return nil
+}
+
+func (ts *TunableString) prepare(value string) (func(), error) {
+	if ts.validate != nil {
+		if err := ts.validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		ts.s.Store(&value)
+	}, nil
+}
+
+func (ts *TunableString) notifyChange() {
+	if f := ts.onChange.Load(); f != nil {
+		(*f)()
+	}
+}
+
+type runtimeTunable interface {
+	flag.Value
+
+	// prepare validates the value and returns a function for setting it to the flag.
+	//
+	// The returned function cannot fail, so it can be called after all the values are validated.
+	prepare(value string) (func(), error)
+
+	notifyChange()
+}
+
+var (
+	runtimeTunablesLock sync.Mutex
+	runtimeTunables     = make(map[string]runtimeTunable)
+)
+
+// registerRuntimeTunable registers rt under the given flag name.
+//
+// It must be called after flag.Var, which panics on duplicate flag names.
+func registerRuntimeTunable(name string, rt runtimeTunable) {
+	runtimeTunablesLock.Lock()
+	runtimeTunables[name] = rt
+	runtimeTunablesLock.Unlock()
+}
+
+// IsRuntimeTunable returns true if the flag with the given name can be changed via SetRuntimeTunables.
+func IsRuntimeTunable(name string) bool {
+	runtimeTunablesLock.Lock()
+	_, ok := runtimeTunables[name]
+	runtimeTunablesLock.Unlock()
+	return ok
+}
+
+// SetRuntimeTunables sets the runtime-tunable flags from values, which contains flag values per every flag name.
+//
+// All the values are validated before changing the flags, so either all the flags are changed or none of them is changed.
+//
+// It returns the previous flag values.
+func SetRuntimeTunables(values map[string]string) (map[string]string, error) {
+	// Serialize flag changes in order to guarantee that the returned previous values are correct
+	// and that onChange callbacks observe the last values.
+	runtimeTunablesLock.Lock()
+	defer runtimeTunablesLock.Unlock()
+
+	names := make([]string, 0, len(values))
+	setters := make(map[string]func(), len(values))
+	for name, value := range values {
+		rt, ok := runtimeTunables[name]
+		if !ok {
+			return nil, fmt.Errorf("-%s cannot be changed at runtime", name)
+		}
+		set, err := rt.prepare(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot set -%s=%q: %w", name, value, err)
+		}
+		names = append(names, name)
+		setters[name] = set
+	}
+	sort.Strings(names)
+
+	prevValues := make(map[string]string, len(values))
+	for _, name := range names {
+		prevValues[name] = runtimeTunables[name].String()
+		setters[name]()
+	}
+	for _, name := range names {
+		runtimeTunables[name].notifyChange()
+	}
+	return prevValues, nil
+}
+
+// WriteRuntimeTunables writes all the runtime-tunable flags with their current values to w.
+func WriteRuntimeTunables(w io.Writer) {
+	runtimeTunablesLock.Lock()
+	defer runtimeTunablesLock.Unlock()
+
+	names := make([]string, 0, len(runtimeTunables))
+	for name := range runtimeTunables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "-%s=%q\n", name, runtimeTunables[name].String())
+	}
+}
+
+// ValidatePositiveInt returns an error if n isn't positive.
+//
+// It can be passed to NewTunableInt for flags, which accept only positive values.
+func ValidatePositiveInt(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("the value must be positive; got %d", n)
+	}
+	return nil
+}
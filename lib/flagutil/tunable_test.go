@@ -0,0 +1,116 @@
+package flagutil
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var (
+	testTunableInt = NewTunableInt("testTunableInt", 42, "test int flag", func(n int) error {
+		if n < 0 {
+			return fmt.Errorf("value cannot be negative")
+		}
+		return nil
+	})
+	testTunableString = NewTunableString("testTunableString", "foo", "test string flag", func(value string) error {
+		if value == "" {
+			return fmt.Errorf("value cannot be empty")
+		}
+		return nil
+	})
+	testTunableBytes = NewTunableBytes("testTunableBytes", 1024, "test bytes flag")
+)
+
+func TestSetRuntimeTunablesSuccess(t *testing.T) {
+	var changes int
+	testTunableInt.OnChange(func() {
+		changes++
+	})
+	defer testTunableInt.OnChange(func() {})
+
+	prevValues, err := SetRuntimeTunables(map[string]string{
+		"testTunableInt":    "123",
+		"testTunableString": "bar",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	prevValuesExpected := map[string]string{
+		"testTunableInt":    "42",
+		"testTunableString": "foo",
+	}
+	if !reflect.DeepEqual(prevValues, prevValuesExpected) {
+		t.Fatalf("unexpected previous values; got %v; want %v", prevValues, prevValuesExpected)
+	}
+	if n := testTunableInt.Get(); n != 123 {
+		t.Fatalf("unexpected value; got %d; want %d", n, 123)
+	}
+	if s := testTunableString.Get(); s != "bar" {
+		t.Fatalf("unexpected value; got %q; want %q", s, "bar")
+	}
+	if changes != 1 {
+		t.Fatalf("unexpected number of OnChange calls; got %d; want 1", changes)
+	}
+
+	prevValues, err = SetRuntimeTunables(map[string]string{
+		"testTunableBytes": "2KiB",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if prevValues["testTunableBytes"] != "1024" {
+		t.Fatalf("unexpected previous value; got %q; want %q", prevValues["testTunableBytes"], "1024")
+	}
+	if n := testTunableBytes.IntN(); n != 2048 {
+		t.Fatalf("unexpected value; got %d; want %d", n, 2048)
+	}
+
+	var bb bytes.Buffer
+	WriteRuntimeTunables(&bb)
+	resultExpected := "-testTunableBytes=\"2KiB\"\n-testTunableInt=\"123\"\n-testTunableString=\"bar\"\n"
+	if s := bb.String(); s != resultExpected {
+		t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", s, resultExpected)
+	}
+}
+
+func TestSetRuntimeTunablesFailure(t *testing.T) {
+	f := func(values map[string]string) {
+		t.Helper()
+
+		prevInt := testTunableInt.Get()
+		prevString := testTunableString.Get()
+		prevBytes := testTunableBytes.IntN()
+		if _, err := SetRuntimeTunables(values); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if n := testTunableInt.Get(); n != prevInt {
+			t.Fatalf("unexpected change of -testTunableInt; got %d; want %d", n, prevInt)
+		}
+		if s := testTunableString.Get(); s != prevString {
+			t.Fatalf("unexpected change of -testTunableString; got %q; want %q", s, prevString)
+		}
+		if n := testTunableBytes.IntN(); n != prevBytes {
+			t.Fatalf("unexpected change of -testTunableBytes; got %d; want %d", n, prevBytes)
+		}
+	}
+
+	// unknown flag
+	f(map[string]string{"unknownFlag": "foo"})
+	f(map[string]string{"testTunableInt": "1", "unknownFlag": "foo"})
+
+	// invalid int
+	f(map[string]string{"testTunableInt": "foo"})
+	f(map[string]string{"testTunableInt": ""})
+
+	// invalid bytes
+	f(map[string]string{"testTunableBytes": "foo"})
+
+	// validation error
+	f(map[string]string{"testTunableInt": "-1"})
+	f(map[string]string{"testTunableString": ""})
+
+	// valid values mustn't be applied if some value is invalid
+	f(map[string]string{"testTunableInt": "1", "testTunableBytes": "1MB", "testTunableString": ""})
+}
@@ -9,12 +9,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"net/url"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,6 +46,10 @@ var (
 	tlsCipherSuites = flagutil.NewArrayString("tlsCipherSuites", "Optional list of TLS cipher suites for incoming requests over HTTPS if -tls is set. See the list of supported cipher suites at https://pkg.go.dev/crypto/tls#pkg-constants")
 	tlsMinVersion   = flagutil.NewArrayString("tlsMinVersion", "Optional minimum TLS version to use for the corresponding -httpListenAddr if -tls is set. "+
 		"Supported values: TLS10, TLS11, TLS12, TLS13")
+	mtlsEnable = flagutil.NewArrayBool("mtls", "Whether to require valid client certificate for https requests to the corresponding -httpListenAddr . "+
+		"This flag works only if -tls flag is set. See also -mtlsCAFile")
+	mtlsCAFile = flagutil.NewArrayString("mtlsCAFile", "Optional path to TLS Root CA for verifying client certificates at the corresponding -httpListenAddr when -mtls is enabled. "+
+		"By default the host system TLS Root CA is used for client certificate verification")
 
 	pathPrefix = flag.String("http.pathPrefix", "", "An optional prefix to add to all the paths handled by http server. For example, if '-http.pathPrefix=/foo/bar' is set, "+
 		"then all the http requests will be handled on '/foo/bar/*' paths. This may be useful for proxied requests. "+
@@ -54,6 +60,9 @@ var (
 	flagsAuthKey     = flagutil.NewPassword("flagsAuthKey", "Auth key for /flags endpoint. It must be passed via authKey query arg. It overrides -httpAuth.*")
 	pprofAuthKey     = flagutil.NewPassword("pprofAuthKey", "Auth key for /debug/pprof/* endpoints. It must be passed via authKey query arg. It overrides -httpAuth.*")
 
+	flagsWriteAuthKey = flagutil.NewPassword("flagsWriteAuthKey", "Auth key for changing flags at runtime via POST requests to /-/flags endpoint. It must be passed via authKey query arg. "+
+		"Flags cannot be changed at runtime if this flag isn't set. See https://docs.victoriametrics.com/#changing-flags-at-runtime")
+
 	disableResponseCompression  = flag.Bool("http.disableResponseCompression", false, "Disable compression of HTTP responses to save CPU resources. By default, compression is enabled to save network bandwidth")
 	maxGracefulShutdownDuration = flag.Duration("http.maxGracefulShutdownDuration", 7*time.Second, `The maximum duration for a graceful shutdown of the HTTP server. A highly loaded server may require increased value for a graceful shutdown`)
 	shutdownDelay               = flag.Duration("http.shutdownDelay", 0, `Optional delay before http server shutdown. During this delay, the server returns non-OK responses from /health page, so load balancers can route new requests to other servers`)
@@ -161,7 +170,15 @@ func serve(addr string, rh RequestHandler, idx int, opts ServeOptions) {
 		if err != nil {
 			logger.Fatalf("cannot load TLS cert from -tlsCertFile=%q, -tlsKeyFile=%q, -tlsMinVersion=%q, -tlsCipherSuites=%q: %s", certFile, keyFile, minVersion, *tlsCipherSuites, err)
 		}
+		if mtlsEnable.GetOptionalArg(idx) {
+			caFile := mtlsCAFile.GetOptionalArg(idx)
+			if err := netutil.SetServerMTLSConfig(tc, caFile); err != nil {
+				logger.Fatalf("cannot enable mTLS with -mtlsCAFile=%q: %s", caFile, err)
+			}
+		}
 		tlsConfig = tc
+	} else if mtlsEnable.GetOptionalArg(idx) {
+		logger.Fatalf("-mtls flag requires -tls flag to be set for -httpListenAddr=%q", addr)
 	}
 	ln, err := netutil.NewTCPListener(scheme, addr, useProxyProto, tlsConfig)
 	if err != nil {
@@ -442,6 +459,10 @@ func builtinRoutesHandler(s *server, r *http.Request, w http.ResponseWriter, rh
 		h.Set("Content-Type", "text/plain; charset=utf-8")
 		flagutil.WriteFlags(w)
 		return true
+	case "/-/flags":
+		runtimeFlagsRequests.Inc()
+		handleRuntimeFlags(w, r)
+		return true
 	case "/-/healthy":
 		// This is needed for Prometheus compatibility
 		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1833
@@ -474,6 +495,77 @@ func builtinRoutesHandler(s *server, r *http.Request, w http.ResponseWriter, rh
 	return rh(w, r)
 }
 
+// handleRuntimeFlags handles /-/flags requests.
+//
+// GET request returns the current values for flags, which can be changed at runtime.
+// POST request changes the flags passed via query args or via request body, e.g. /-/flags?authKey=...&loggerLevel=WARN
+func handleRuntimeFlags(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !CheckAuthFlag(w, r, flagsAuthKey) {
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		flagutil.WriteRuntimeTunables(w)
+	case http.MethodPost:
+		if flagsWriteAuthKey.Get() == "" {
+			// Do not allow changing flags by anyone who can reach the http server or who can read flags.
+			http.Error(w, "-flagsWriteAuthKey command-line flag must be set for changing flags at runtime", http.StatusForbidden)
+			return
+		}
+		if !CheckAuthFlag(w, r, flagsWriteAuthKey) {
+			return
+		}
+		changeRuntimeFlags(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, fmt.Sprintf("unsupported method %s; use GET or POST", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+func changeRuntimeFlags(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		Errorf(w, r, "cannot parse request form: %s", err)
+		return
+	}
+	values := make(map[string]string)
+	for name := range r.Form {
+		if name == "authKey" {
+			continue
+		}
+		if !flagutil.IsRuntimeTunable(name) {
+			Errorf(w, r, "-%s cannot be changed at runtime; see the list of such flags at /-/flags", name)
+			return
+		}
+		values[name] = r.Form.Get(name)
+	}
+	if len(values) == 0 {
+		Errorf(w, r, "missing flags to change; pass them via query args or via request body, e.g. /-/flags?loggerLevel=WARN")
+		return
+	}
+
+	// All the flags are validated before applying any of them, so invalid request doesn't leave flags partially changed.
+	prevValues, err := flagutil.SetRuntimeTunables(values)
+	if err != nil {
+		Errorf(w, r, "%s", err)
+		return
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, name := range names {
+		prevValue := prevValues[name]
+		value := values[name]
+		logger.Auditf("flag -%s is changed from %q to %q via /-/flags by %s", name, prevValue, value, GetQuotedRemoteAddr(r))
+		metrics.GetOrCreateCounter(fmt.Sprintf(`vm_runtime_flag_changes_total{flag=%q}`, name)).Inc()
+		fmt.Fprintf(w, "-%s is changed from %q to %q\n", name, prevValue, value)
+	}
+}
+
 func isProtectedByAuthFlag(path string) bool {
 	// These paths must explicitly call CheckAuthFlag().
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/6329
@@ -564,6 +656,7 @@ func pprofHandler(profileName string, w http.ResponseWriter, r *http.Request) {
 var (
 	metricsRequests      = metrics.NewCounter(`vm_http_requests_total{path="/metrics"}`)
 	pprofRequests        = metrics.NewCounter(`vm_http_requests_total{path="/debug/pprof/"}`)
+	runtimeFlagsRequests = metrics.NewCounter(`vm_http_requests_total{path="/-/flags"}`)
 	pprofCmdlineRequests = metrics.NewCounter(`vm_http_requests_total{path="/debug/pprof/cmdline"}`)
 	pprofProfileRequests = metrics.NewCounter(`vm_http_requests_total{path="/debug/pprof/profile"}`)
 	pprofSymbolRequests  = metrics.NewCounter(`vm_http_requests_total{path="/debug/pprof/symbol"}`)
@@ -670,6 +763,25 @@ func (rwa *responseWriterWithAbort) abort() {
 	rwa.aborted = true
 }
 
+// getResponseWriterWithAbort returns responseWriterWithAbort from w.
+//
+// w may wrap responseWriterWithAbort if it implements Unwrap() http.ResponseWriter method
+// in the same way as expected by net/http.ResponseController.
+//
+// nil is returned if w doesn't contain responseWriterWithAbort.
+func getResponseWriterWithAbort(w http.ResponseWriter) *responseWriterWithAbort {
+	for {
+		switch t := w.(type) {
+		case *responseWriterWithAbort:
+			return t
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
 // Errorf writes formatted error message to w and to logger.
 func Errorf(w http.ResponseWriter, r *http.Request, format string, args ...any) {
 	errStr := fmt.Sprintf(format, args...)
@@ -680,30 +792,38 @@ func Errorf(w http.ResponseWriter, r *http.Request, format string, args ...any)
 
 	// Extract statusCode from args
 	statusCode := http.StatusBadRequest
+	var retryAfter time.Duration
 	var esc *ErrorWithStatusCode
 	for _, arg := range args {
 		if err, ok := arg.(error); ok && errors.As(err, &esc) {
 			statusCode = esc.StatusCode
+			retryAfter = esc.RetryAfter
 			break
 		}
 	}
 
-	if rwa, ok := w.(*responseWriterWithAbort); ok && rwa.sentHeaders {
+	if rwa := getResponseWriterWithAbort(w); rwa != nil && rwa.sentHeaders {
 		// HTTP status code has been already sent to client, so it cannot be sent again.
 		// Just write errStr to the response and abort the client connection, so the client could notice the error.
 		fmt.Fprintf(w, "\n%s\n", errStr)
 		rwa.abort()
 		return
 	}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
 	http.Error(w, errStr, statusCode)
 }
 
 // ErrorWithStatusCode is error with HTTP status code.
 //
 // The given StatusCode is sent to client when the error is passed to Errorf.
+// If RetryAfter is positive, then it is sent to client in Retry-After header.
 type ErrorWithStatusCode struct {
 	Err        error
 	StatusCode int
+	RetryAfter time.Duration
 }
 
 // Unwrap returns e.Err.
@@ -180,3 +180,112 @@ func TestHandlerWrapper(t *testing.T) {
 		t.Fatalf("unexpected CSP header; got %q; want %q", got, cspHeader)
 	}
 }
+
+func TestHandleRuntimeFlags(t *testing.T) {
+	origAuthKey := flagsAuthKey.Get()
+	origWriteAuthKey := flagsWriteAuthKey.Get()
+	defer func() {
+		if err := flagsAuthKey.Set(origAuthKey); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := flagsWriteAuthKey.Set(origWriteAuthKey); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := flagutil.SetRuntimeTunables(map[string]string{"loggerLevel": "INFO", "loggerWarnsPerSecondLimit": "0"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}()
+
+	f := func(method, requestURI string, statusCodeExpected int, bodyExpected string) {
+		t.Helper()
+
+		req := httptest.NewRequest(method, requestURI, nil)
+		w := httptest.NewRecorder()
+		handleRuntimeFlags(w, req)
+
+		res := w.Result()
+		_ = res.Body.Close()
+		if res.StatusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d; response body: %q", res.StatusCode, statusCodeExpected, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), bodyExpected) {
+			t.Fatalf("missing %q in the response body %q", bodyExpected, w.Body.String())
+		}
+	}
+
+	if err := flagsAuthKey.Set("secret"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// flags cannot be changed without -flagsWriteAuthKey even if -flagsAuthKey is set
+	f(http.MethodPost, "/-/flags?authKey=secret&loggerLevel=WARN", http.StatusForbidden, "-flagsWriteAuthKey")
+
+	if err := flagsWriteAuthKey.Set("write-secret"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// missing or invalid authKey
+	f(http.MethodGet, "/-/flags", http.StatusUnauthorized, "")
+	f(http.MethodPost, "/-/flags?authKey=foo&loggerLevel=WARN", http.StatusUnauthorized, "")
+
+	// -flagsAuthKey cannot be used for changing flags
+	f(http.MethodPost, "/-/flags?authKey=secret&loggerLevel=WARN", http.StatusUnauthorized, "")
+
+	// flags, which cannot be changed at runtime
+	f(http.MethodPost, "/-/flags?authKey=write-secret&httpListenAddr=:1234", http.StatusBadRequest, "-httpListenAddr cannot be changed at runtime")
+	f(http.MethodPost, "/-/flags?authKey=write-secret", http.StatusBadRequest, "missing flags to change")
+
+	// invalid flag value
+	f(http.MethodPost, "/-/flags?authKey=write-secret&loggerLevel=foo", http.StatusBadRequest, "unsupported `-loggerLevel` value")
+
+	// valid flags mustn't be changed if some flag is invalid
+	f(http.MethodPost, "/-/flags?authKey=write-secret&loggerWarnsPerSecondLimit=10&loggerLevel=foo", http.StatusBadRequest, "unsupported `-loggerLevel` value")
+	f(http.MethodGet, "/-/flags?authKey=secret", http.StatusOK, `-loggerWarnsPerSecondLimit="0"`)
+
+	// successful change
+	f(http.MethodPost, "/-/flags?authKey=write-secret&loggerLevel=WARN&loggerWarnsPerSecondLimit=10", http.StatusOK, `-loggerLevel is changed from "INFO" to "WARN"`)
+	f(http.MethodGet, "/-/flags?authKey=secret", http.StatusOK, `-loggerLevel="WARN"`)
+	f(http.MethodGet, "/-/flags?authKey=secret", http.StatusOK, `-loggerWarnsPerSecondLimit="10"`)
+
+	// unsupported method
+	f(http.MethodDelete, "/-/flags?authKey=secret", http.StatusMethodNotAllowed, "")
+}
+
+type unwrappableResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *unwrappableResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestGetResponseWriterWithAbort(t *testing.T) {
+	rwa := &responseWriterWithAbort{
+		ResponseWriter: httptest.NewRecorder(),
+	}
+
+	if got := getResponseWriterWithAbort(rwa); got != rwa {
+		t.Fatalf("unexpected responseWriterWithAbort; got %p; want %p", got, rwa)
+	}
+
+	// wrapped writers must be unwrapped
+	w := &unwrappableResponseWriter{
+		ResponseWriter: &unwrappableResponseWriter{
+			ResponseWriter: rwa,
+		},
+	}
+	if got := getResponseWriterWithAbort(w); got != rwa {
+		t.Fatalf("unexpected responseWriterWithAbort for wrapped writer; got %p; want %p", got, rwa)
+	}
+
+	// writers without responseWriterWithAbort
+	if got := getResponseWriterWithAbort(httptest.NewRecorder()); got != nil {
+		t.Fatalf("expecting nil responseWriterWithAbort; got %p", got)
+	}
+	w = &unwrappableResponseWriter{
+		ResponseWriter: httptest.NewRecorder(),
+	}
+	if got := getResponseWriterWithAbort(w); got != nil {
+		t.Fatalf("expecting nil responseWriterWithAbort for wrapped writer; got %p", got)
+	}
+}
@@ -2,14 +2,18 @@ package influx
 
 import (
 	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
@@ -17,20 +21,46 @@ import (
 )
 
 var (
-	writeRequestsTCP = metrics.NewCounter(`vm_ingestserver_requests_total{type="influx", name="write", net="tcp"}`)
-	writeErrorsTCP   = metrics.NewCounter(`vm_ingestserver_request_errors_total{type="influx", name="write", net="tcp"}`)
-
-	writeRequestsUDP = metrics.NewCounter(`vm_ingestserver_requests_total{type="influx", name="write", net="udp"}`)
-	writeErrorsUDP   = metrics.NewCounter(`vm_ingestserver_request_errors_total{type="influx", name="write", net="udp"}`)
+	datagramBatchSize = flagutil.NewBytes("influx.datagramBatchSize", 256*1024, "The maximum size of a batch of InfluxDB line protocol datagrams "+
+		"received via UDP or unixgram sockets before it is processed. See also -influx.datagramFlushInterval")
+	datagramFlushInterval = flag.Duration("influx.datagramFlushInterval", 100*time.Millisecond, "The maximum duration for collecting "+
+		"InfluxDB line protocol datagrams received via UDP or unixgram sockets into a batch before it is processed. See also -influx.datagramBatchSize")
 )
 
-// Server accepts InfluxDB line protocol over TCP and UDP.
+// serverMetrics contains metrics for the given network of InfluxDB server.
+type serverMetrics struct {
+	writeRequests *metrics.Counter
+	writeErrors   *metrics.Counter
+
+	// datagrams and datagramsDropped are used only for datagram networks such as udp and unixgram.
+	datagrams        *metrics.Counter
+	datagramsDropped *metrics.Counter
+}
+
+func newServerMetrics(network string) *serverMetrics {
+	return &serverMetrics{
+		writeRequests:    metrics.GetOrCreateCounter(fmt.Sprintf(`vm_ingestserver_requests_total{type="influx", name="write", net=%q}`, network)),
+		writeErrors:      metrics.GetOrCreateCounter(fmt.Sprintf(`vm_ingestserver_request_errors_total{type="influx", name="write", net=%q}`, network)),
+		datagrams:        metrics.GetOrCreateCounter(fmt.Sprintf(`vm_ingestserver_datagrams_total{type="influx", net=%q}`, network)),
+		datagramsDropped: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_ingestserver_datagrams_dropped_total{type="influx", net=%q}`, network)),
+	}
+}
+
+// Server accepts InfluxDB line protocol over TCP and UDP or over unix sockets.
 type Server struct {
-	addr  string
-	lnTCP net.Listener
-	lnUDP net.PacketConn
-	wg    sync.WaitGroup
-	cm    ingestserver.ConnsMap
+	addr string
+
+	// lnStream and lnPacket may be nil if the server doesn't listen for the corresponding network.
+	lnStream      net.Listener
+	lnPacket      net.PacketConn
+	streamNetwork string
+	packetNetwork string
+
+	// packetPath is the path to unixgram socket, which must be removed on server stop.
+	packetPath string
+
+	wg sync.WaitGroup
+	cm ingestserver.ConnsMap
 }
 
 // MustStart starts InfluxDB server on the given addr.
@@ -55,59 +85,140 @@ func MustStart(addr string, useProxyProtocol bool, insertHandler func(r io.Reade
 	}
 
 	s := &Server{
-		addr:  addr,
-		lnTCP: lnTCP,
-		lnUDP: lnUDP,
+		addr:          addr,
+		lnStream:      lnTCP,
+		lnPacket:      lnUDP,
+		streamNetwork: "tcp",
+		packetNetwork: "udp",
 	}
-	s.cm.Init("influx")
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		s.serveTCP(insertHandler)
-		logger.Infof("stopped TCP InfluxDB server at %q", addr)
-	}()
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		s.serveUDP(insertHandler)
-		logger.Infof("stopped UDP InfluxDB server at %q", addr)
-	}()
+	s.start(insertHandler)
+	return s
+}
+
+// MustStartUnix starts InfluxDB server on the unix stream socket at the given path.
+//
+// The incoming connections are processed with insertHandler.
+//
+// MustStop must be called on the returned server when it is no longer needed.
+func MustStartUnix(path string, insertHandler func(r io.Reader) error) *Server {
+	logger.Infof("starting unix InfluxDB server at %q", path)
+	mustRemoveStaleUnixSocket(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		logger.Fatalf("cannot start unix InfluxDB server at %q: %s", path, err)
+	}
+	s := &Server{
+		addr:          path,
+		lnStream:      ln,
+		streamNetwork: "unix",
+	}
+	s.start(insertHandler)
 	return s
 }
 
+// MustStartUnixgram starts InfluxDB server on the unix datagram socket at the given path.
+//
+// The incoming datagrams are processed with insertHandler.
+//
+// MustStop must be called on the returned server when it is no longer needed.
+func MustStartUnixgram(path string, insertHandler func(r io.Reader) error) *Server {
+	logger.Infof("starting unixgram InfluxDB server at %q", path)
+	mustRemoveStaleUnixSocket(path)
+	ln, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		logger.Fatalf("cannot start unixgram InfluxDB server at %q: %s", path, err)
+	}
+	s := &Server{
+		addr:          path,
+		lnPacket:      ln,
+		packetNetwork: "unixgram",
+		packetPath:    path,
+	}
+	s.start(insertHandler)
+	return s
+}
+
+// mustRemoveStaleUnixSocket removes unix socket at the given path left after unclean shutdown.
+func mustRemoveStaleUnixSocket(path string) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		logger.Fatalf("cannot access %q: %s", path, err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		logger.Fatalf("cannot listen on unix socket at %q, since this path is already occupied by non-socket file", path)
+	}
+	if err := os.Remove(path); err != nil {
+		logger.Fatalf("cannot remove stale unix socket at %q: %s", path, err)
+	}
+}
+
+func (s *Server) start(insertHandler func(r io.Reader) error) {
+	s.cm.Init("influx")
+	if s.lnStream != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveStream(insertHandler)
+			logger.Infof("stopped %s InfluxDB server at %q", s.streamNetwork, s.addr)
+		}()
+	}
+	if s.lnPacket != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.servePacket(insertHandler)
+			logger.Infof("stopped %s InfluxDB server at %q", s.packetNetwork, s.addr)
+		}()
+	}
+}
+
 // MustStop stops the server.
 func (s *Server) MustStop() {
-	logger.Infof("stopping TCP InfluxDB server at %q...", s.addr)
-	if err := s.lnTCP.Close(); err != nil {
-		logger.Errorf("cannot close TCP InfluxDB server: %s", err)
+	if s.lnStream != nil {
+		logger.Infof("stopping %s InfluxDB server at %q...", s.streamNetwork, s.addr)
+		if err := s.lnStream.Close(); err != nil {
+			logger.Errorf("cannot close %s InfluxDB server: %s", s.streamNetwork, err)
+		}
 	}
-	logger.Infof("stopping UDP InfluxDB server at %q...", s.addr)
-	if err := s.lnUDP.Close(); err != nil {
-		logger.Errorf("cannot close UDP InfluxDB server: %s", err)
+	if s.lnPacket != nil {
+		logger.Infof("stopping %s InfluxDB server at %q...", s.packetNetwork, s.addr)
+		if err := s.lnPacket.Close(); err != nil {
+			logger.Errorf("cannot close %s InfluxDB server: %s", s.packetNetwork, err)
+		}
 	}
 	s.cm.CloseAll(0)
 	s.wg.Wait()
-	logger.Infof("TCP and UDP InfluxDB servers at %q have been stopped", s.addr)
+	if s.packetPath != "" {
+		// Unlike unix stream listener, unixgram socket file isn't removed on close.
+		if err := os.Remove(s.packetPath); err != nil && !os.IsNotExist(err) {
+			logger.Errorf("cannot remove unixgram socket at %q: %s", s.packetPath, err)
+		}
+	}
+	logger.Infof("InfluxDB servers at %q have been stopped", s.addr)
 }
 
-func (s *Server) serveTCP(insertHandler func(r io.Reader) error) {
+func (s *Server) serveStream(insertHandler func(r io.Reader) error) {
+	sm := newServerMetrics(s.streamNetwork)
 	var wg sync.WaitGroup
 	for {
-		c, err := s.lnTCP.Accept()
+		c, err := s.lnStream.Accept()
 		if err != nil {
 			var ne net.Error
 			if errors.As(err, &ne) {
 				if ne.Temporary() {
-					logger.Errorf("influx: temporary error when listening for TCP addr %q: %s", s.lnTCP.Addr(), err)
+					logger.Errorf("influx: temporary error when listening for %s addr %q: %s", s.streamNetwork, s.lnStream.Addr(), err)
 					time.Sleep(time.Second)
 					continue
 				}
 				if strings.Contains(err.Error(), "use of closed network connection") {
 					break
 				}
-				logger.Fatalf("unrecoverable error when accepting TCP InfluxDB connections: %s", err)
+				logger.Fatalf("unrecoverable error when accepting %s InfluxDB connections: %s", s.streamNetwork, err)
 			}
-			logger.Fatalf("unexpected error when accepting TCP InfluxDB connections: %s", err)
+			logger.Fatalf("unexpected error when accepting %s InfluxDB connections: %s", s.streamNetwork, err)
 		}
 		if !s.cm.Add(c) {
 			_ = c.Close()
@@ -120,54 +231,164 @@ func (s *Server) serveTCP(insertHandler func(r io.Reader) error) {
 				_ = c.Close()
 				wg.Done()
 			}()
-			writeRequestsTCP.Inc()
+			sm.writeRequests.Inc()
 			if err := insertHandler(c); err != nil {
-				writeErrorsTCP.Inc()
-				logger.Errorf("error in TCP InfluxDB conn %q<->%q: %s", c.LocalAddr(), c.RemoteAddr(), err)
+				sm.writeErrors.Inc()
+				logger.Errorf("error in %s InfluxDB conn %q<->%q: %s", s.streamNetwork, c.LocalAddr(), c.RemoteAddr(), err)
 			}
 		}()
 	}
 	wg.Wait()
 }
 
-func (s *Server) serveUDP(insertHandler func(r io.Reader) error) {
+// servePacket reads datagrams from s.lnPacket, collects them into batches and processes the batches with insertHandler.
+//
+// Batches are dropped if insertHandler cannot keep up with the incoming datagrams.
+// This is better than blocking datagrams reading, since the kernel silently drops datagrams in this case.
+func (s *Server) servePacket(insertHandler func(r io.Reader) error) {
+	sm := newServerMetrics(s.packetNetwork)
 	gomaxprocs := cgroup.AvailableCPUs()
-	var wg sync.WaitGroup
+
+	b := newDatagramBatcher(datagramBatchSize.IntN(), gomaxprocs*4, sm)
+
+	var processWG sync.WaitGroup
 	for i := 0; i < gomaxprocs; i++ {
-		wg.Add(1)
+		processWG.Add(1)
 		go func() {
-			defer wg.Done()
-			var bb bytesutil.ByteBuffer
-			bb.B = bytesutil.ResizeNoCopyNoOverallocate(bb.B, 64*1024)
-			for {
-				bb.Reset()
-				bb.B = bb.B[:cap(bb.B)]
-				n, addr, err := s.lnUDP.ReadFrom(bb.B)
-				if err != nil {
-					writeErrorsUDP.Inc()
-					var ne net.Error
-					if errors.As(err, &ne) {
-						if ne.Temporary() {
-							logger.Errorf("influx: temporary error when listening for UDP addr %q: %s", s.lnUDP.LocalAddr(), err)
-							time.Sleep(time.Second)
-							continue
-						}
-						if strings.Contains(err.Error(), "use of closed network connection") {
-							break
-						}
-					}
-					logger.Errorf("cannot read InfluxDB UDP data: %s", err)
-					continue
-				}
-				bb.B = bb.B[:n]
-				writeRequestsUDP.Inc()
+			defer processWG.Done()
+			for bb := range b.batchCh {
+				sm.writeRequests.Inc()
 				if err := insertHandler(bb.NewReader()); err != nil {
-					writeErrorsUDP.Inc()
-					logger.Errorf("error in UDP InfluxDB conn %q<->%q: %s", s.lnUDP.LocalAddr(), addr, err)
-					continue
+					sm.writeErrors.Inc()
+					logger.Errorf("error in %s InfluxDB server at %q: %s", s.packetNetwork, s.addr, err)
 				}
+				datagramBatchPool.Put(bb)
 			}
 		}()
 	}
-	wg.Wait()
+
+	stopCh := make(chan struct{})
+	var flusherWG sync.WaitGroup
+	flusherWG.Add(1)
+	go func() {
+		defer flusherWG.Done()
+		t := time.NewTicker(*datagramFlushInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-t.C:
+				b.flush()
+			}
+		}
+	}()
+
+	var readWG sync.WaitGroup
+	for i := 0; i < gomaxprocs; i++ {
+		readWG.Add(1)
+		go func() {
+			defer readWG.Done()
+			s.readDatagrams(b, sm)
+		}()
+	}
+	readWG.Wait()
+
+	close(stopCh)
+	flusherWG.Wait()
+	b.flush()
+	close(b.batchCh)
+	processWG.Wait()
+}
+
+func (s *Server) readDatagrams(b *datagramBatcher, sm *serverMetrics) {
+	var bb bytesutil.ByteBuffer
+	bb.B = bytesutil.ResizeNoCopyNoOverallocate(bb.B, 64*1024)
+	for {
+		bb.Reset()
+		bb.B = bb.B[:cap(bb.B)]
+		n, _, err := s.lnPacket.ReadFrom(bb.B)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) {
+				if ne.Temporary() {
+					sm.writeErrors.Inc()
+					logger.Errorf("influx: temporary error when listening for %s addr %q: %s", s.packetNetwork, s.lnPacket.LocalAddr(), err)
+					time.Sleep(time.Second)
+					continue
+				}
+			}
+			if errors.Is(err, net.ErrClosed) || strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			sm.writeErrors.Inc()
+			logger.Errorf("cannot read InfluxDB %s data: %s", s.packetNetwork, err)
+			continue
+		}
+		sm.datagrams.Inc()
+		b.add(bb.B[:n])
+	}
+}
+
+var datagramBatchPool bytesutil.ByteBufferPool
+
+// datagramBatcher collects datagrams into batches.
+type datagramBatcher struct {
+	maxBatchSize int
+	sm           *serverMetrics
+
+	// batchCh contains batches ready for processing.
+	batchCh chan *bytesutil.ByteBuffer
+
+	mu        sync.Mutex
+	bb        *bytesutil.ByteBuffer
+	datagrams int
+}
+
+func newDatagramBatcher(maxBatchSize, maxPendingBatches int, sm *serverMetrics) *datagramBatcher {
+	return &datagramBatcher{
+		maxBatchSize: maxBatchSize,
+		sm:           sm,
+		batchCh:      make(chan *bytesutil.ByteBuffer, maxPendingBatches),
+		bb:           datagramBatchPool.Get(),
+	}
+}
+
+// add adds datagram to the current batch.
+//
+// The batch is sent for processing if its size exceeds maxBatchSize.
+func (b *datagramBatcher) add(datagram []byte) {
+	b.mu.Lock()
+	b.bb.B = append(b.bb.B, datagram...)
+	if len(datagram) > 0 && datagram[len(datagram)-1] != '\n' {
+		// Datagrams may contain lines without the trailing newline.
+		b.bb.B = append(b.bb.B, '\n')
+	}
+	b.datagrams++
+	if len(b.bb.B) >= b.maxBatchSize {
+		b.flushLocked()
+	}
+	b.mu.Unlock()
+}
+
+// flush sends the current batch for processing.
+func (b *datagramBatcher) flush() {
+	b.mu.Lock()
+	b.flushLocked()
+	b.mu.Unlock()
+}
+
+func (b *datagramBatcher) flushLocked() {
+	if len(b.bb.B) == 0 {
+		return
+	}
+	select {
+	case b.batchCh <- b.bb:
+		b.bb = datagramBatchPool.Get()
+	default:
+		// Processing cannot keep up with the incoming datagrams.
+		b.sm.datagramsDropped.Add(b.datagrams)
+		b.bb.Reset()
+	}
+	b.datagrams = 0
 }
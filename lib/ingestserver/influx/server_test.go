@@ -0,0 +1,133 @@
+package influx
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testInsertHandler struct {
+	mu    sync.Mutex
+	calls int
+	lines []string
+}
+
+func (h *testInsertHandler) insert(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.calls++
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *testInsertHandler) waitForLines(t *testing.T, linesExpected []string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mu.Lock()
+		lines := append([]string{}, h.lines...)
+		h.mu.Unlock()
+		sort.Strings(lines)
+		if strings.Join(lines, "\n") == strings.Join(linesExpected, "\n") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected lines\ngot\n%q\nwant\n%q", lines, linesExpected)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "influx.sock")
+	var h testInsertHandler
+	s := MustStartUnix(path, h.insert)
+
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("cannot connect to unix socket: %s", err)
+	}
+	if _, err := c.Write([]byte("foo value=1\nbar value=2\n")); err != nil {
+		t.Fatalf("cannot write data: %s", err)
+	}
+	_ = c.Close()
+	h.waitForLines(t, []string{"bar value=2", "foo value=1"})
+	s.MustStop()
+
+	// The server must start at the path with stale socket left after unclean shutdown.
+	ln, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("cannot create stale socket: %s", err)
+	}
+	_ = ln.Close()
+	s = MustStartUnix(path, h.insert)
+	s.MustStop()
+}
+
+func TestServerUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "influx.sock")
+	var h testInsertHandler
+	s := MustStartUnixgram(path, h.insert)
+	defer s.MustStop()
+
+	c, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatalf("cannot connect to unixgram socket: %s", err)
+	}
+	defer c.Close()
+	datagrams := []string{"foo value=1", "bar value=2\n", "baz value=3\nqux value=4"}
+	for _, d := range datagrams {
+		if _, err := c.Write([]byte(d)); err != nil {
+			t.Fatalf("cannot write datagram: %s", err)
+		}
+	}
+	h.waitForLines(t, []string{"bar value=2", "baz value=3", "foo value=1", "qux value=4"})
+}
+
+func TestDatagramBatcher(t *testing.T) {
+	sm := newServerMetrics("test")
+	droppedBefore := sm.datagramsDropped.Get()
+	b := newDatagramBatcher(10, 1, sm)
+
+	// The batch isn't sent until it reaches the max size or until flush.
+	b.add([]byte("foo 1"))
+	if n := len(b.batchCh); n != 0 {
+		t.Fatalf("unexpected number of pending batches; got %d; want 0", n)
+	}
+	b.add([]byte("bar 2\n"))
+	if n := len(b.batchCh); n != 1 {
+		t.Fatalf("unexpected number of pending batches; got %d; want 1", n)
+	}
+
+	// The batch must be dropped if there is no room for it.
+	b.add([]byte("baz 3"))
+	b.flush()
+	if n := sm.datagramsDropped.Get() - droppedBefore; n != 1 {
+		t.Fatalf("unexpected number of dropped datagrams; got %d; want 1", n)
+	}
+
+	bb := <-b.batchCh
+	if s := string(bb.B); s != "foo 1\nbar 2\n" {
+		t.Fatalf("unexpected batch; got %q; want %q", s, "foo 1\nbar 2\n")
+	}
+
+	// Empty batch mustn't be sent.
+	b.flush()
+	if n := len(b.batchCh); n != 0 {
+		t.Fatalf("unexpected number of pending batches; got %d; want 0", n)
+	}
+}
@@ -13,12 +13,13 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
 	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
-	loggerLevel    = flag.String("loggerLevel", "INFO", "Minimum level of errors to log. Possible values: INFO, WARN, ERROR, FATAL, PANIC")
+	loggerLevel    = flagutil.NewTunableString("loggerLevel", "INFO", "Minimum level of errors to log. Possible values: INFO, WARN, ERROR, FATAL, PANIC", validateLoggerLevel)
 	loggerFormat   = flag.String("loggerFormat", "default", "Format for logs. Possible values: default, json")
 	loggerOutput   = flag.String("loggerOutput", "stderr", "Output for the logs. Supported values: stderr, stdout")
 	loggerTimezone = flag.String("loggerTimezone", "UTC", "Timezone to use for timestamps in logs. Timezone must be a valid IANA Time Zone. "+
@@ -27,8 +28,8 @@ var (
 	maxLogArgLen      = flag.Int("loggerMaxArgLen", 5000, "The maximum length of a single logged argument. Longer arguments are replaced with 'arg_start..arg_end', "+
 		"where 'arg_start' and 'arg_end' is prefix and suffix of the arg with the length not exceeding -loggerMaxArgLen / 2")
 
-	errorsPerSecondLimit = flag.Int("loggerErrorsPerSecondLimit", 0, `Per-second limit on the number of ERROR messages. If more than the given number of errors are emitted per second, the remaining errors are suppressed. Zero values disable the rate limit`)
-	warnsPerSecondLimit  = flag.Int("loggerWarnsPerSecondLimit", 0, `Per-second limit on the number of WARN messages. If more than the given number of warns are emitted per second, then the remaining warns are suppressed. Zero values disable the rate limit`)
+	errorsPerSecondLimit = flagutil.NewTunableInt("loggerErrorsPerSecondLimit", 0, `Per-second limit on the number of ERROR messages. If more than the given number of errors are emitted per second, the remaining errors are suppressed. Zero values disable the rate limit`, nil)
+	warnsPerSecondLimit  = flagutil.NewTunableInt("loggerWarnsPerSecondLimit", 0, `Per-second limit on the number of WARN messages. If more than the given number of warns are emitted per second, then the remaining warns are suppressed. Zero values disable the rate limit`, nil)
 )
 
 // Init initializes the logger.
@@ -50,7 +51,6 @@ func InitNoLogFlags() {
 func initInternal(logFlags bool) {
 	setLoggerJSONFields()
 	setLoggerOutput()
-	validateLoggerLevel()
 	validateLoggerFormat()
 	initTimezone()
 	go logLimiterCleaner()
@@ -83,12 +83,12 @@ func setLoggerOutput() {
 
 var output io.Writer = os.Stderr
 
-func validateLoggerLevel() {
-	switch *loggerLevel {
+func validateLoggerLevel(level string) error {
+	switch level {
 	case "INFO", "WARN", "ERROR", "FATAL", "PANIC":
+		return nil
 	default:
-		// We cannot use logger.Panicf here, since the logger isn't initialized yet.
-		panic(fmt.Errorf("FATAL: unsupported `-loggerLevel` value: %q; supported values are: INFO, WARN, ERROR, FATAL, PANIC", *loggerLevel))
+		return fmt.Errorf("unsupported `-loggerLevel` value: %q; supported values are: INFO, WARN, ERROR, FATAL, PANIC", level)
 	}
 }
 
@@ -113,6 +113,13 @@ func Infof(format string, args ...any) {
 	logLevel("INFO", format, args)
 }
 
+// Auditf logs audit message.
+//
+// Audit messages are logged regardless of -loggerLevel, so they cannot be hidden by changing -loggerLevel.
+func Auditf(format string, args ...any) {
+	logLevel("AUDIT", format, args)
+}
+
 // Warnf logs warn message.
 func Warnf(format string, args ...any) {
 	logLevel("WARN", format, args)
@@ -254,9 +261,9 @@ func logMessage(level, msg string, skipframes int) {
 
 	// rate limit ERROR and WARN log messages with given limit.
 	if level == "ERROR" || level == "WARN" {
-		limit := uint64(*errorsPerSecondLimit)
+		limit := uint64(errorsPerSecondLimit.Get())
 		if level == "WARN" {
-			limit = uint64(*warnsPerSecondLimit)
+			limit = uint64(warnsPerSecondLimit.Get())
 		}
 		ok, suppressMessage := logLimiter.needSuppress(location, limit)
 		if ok {
@@ -321,7 +328,10 @@ func logMessage(level, msg string, skipframes int) {
 var mu sync.Mutex
 
 func shouldSkipLog(level string) bool {
-	switch *loggerLevel {
+	if level == "AUDIT" {
+		return false
+	}
+	switch loggerLevel.Get() {
 	case "WARN":
 		switch level {
 		case "WARN", "ERROR", "FATAL", "PANIC":
@@ -23,3 +23,34 @@ func TestFormatLogMessage(t *testing.T) {
 	// Format args exceeding the maxArgLen
 	f("foo: %s, %q, %s", []any{"abcde", fmt.Errorf("foo bar baz"), "xx"}, 4, `foo: a..e, "f..z", xx`)
 }
+
+func TestShouldSkipLog(t *testing.T) {
+	f := func(loggerLevelValue, level string, resultExpected bool) {
+		t.Helper()
+
+		prevValue := loggerLevel.Get()
+		if err := loggerLevel.Set(loggerLevelValue); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer func() {
+			if err := loggerLevel.Set(prevValue); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}()
+
+		result := shouldSkipLog(level)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for -loggerLevel=%s and level=%s; got %v; want %v", loggerLevelValue, level, result, resultExpected)
+		}
+	}
+
+	f("INFO", "INFO", false)
+	f("WARN", "INFO", true)
+	f("WARN", "ERROR", false)
+	f("PANIC", "ERROR", true)
+
+	// audit messages are never skipped
+	f("INFO", "AUDIT", false)
+	f("ERROR", "AUDIT", false)
+	f("PANIC", "AUDIT", false)
+}
@@ -149,8 +149,8 @@ func (c *column) resizeValues(valuesLen int) []string {
 
 // mustWriteTo writes c to sw and updates ch accordingly.
 //
-// ch is valid until c is changed.
-func (c *column) mustWriteTo(ch *columnHeader, sw *streamWriters) {
+// Values are encrypted with bc if it isn't nil. ch is valid until c is changed.
+func (c *column) mustWriteTo(ch *columnHeader, sw *streamWriters, bc *blockCipher) {
 	ch.reset()
 
 	ch.name = c.name
@@ -167,6 +167,12 @@ func (c *column) mustWriteTo(ch *columnHeader, sw *streamWriters) {
 	// marshal values
 	bb.B = marshalStringsBlock(bb.B[:0], ve.values)
 	putValuesEncoder(ve)
+	if bc != nil {
+		bbEncrypted := longTermBufPool.Get()
+		bbEncrypted.B = bc.encrypt(bbEncrypted.B[:0], bb.B)
+		bb.B, bbEncrypted.B = bbEncrypted.B, bb.B
+		longTermBufPool.Put(bbEncrypted)
+	}
 	ch.valuesSize = uint64(len(bb.B))
 	if ch.valuesSize > maxValuesBlockSize {
 		logger.Panicf("BUG: too valuesSize: %d bytes; mustn't exceed %d bytes", ch.valuesSize, maxValuesBlockSize)
@@ -178,7 +184,7 @@ func (c *column) mustWriteTo(ch *columnHeader, sw *streamWriters) {
 	if ch.valueType != valueTypeDict {
 		hashesBuf := encoding.GetUint64s(0)
 		hashesBuf.A = tokenizeHashes(hashesBuf.A[:0], c.values)
-		bb.B = bloomFilterMarshalHashes(bb.B[:0], hashesBuf.A)
+		bb.B = bloomFilterMarshalHashesWithBitsPerItem(bb.B[:0], hashesBuf.A, sw.bloomFilterBitsPerItem)
 		encoding.PutUint64s(hashesBuf)
 	} else {
 		// there is no need in ecoding bloom filter for dictionary type,
@@ -484,6 +490,8 @@ func (b *block) mustWriteTo(sid *streamID, bh *blockHeader, sw *streamWriters) {
 	// Marshal timestamps
 	mustWriteTimestampsTo(&bh.timestampsHeader, b.timestamps, sw)
 
+	bc := newBlockCipherForWrite(sw.encryptionConfig, bh)
+
 	// Marshal columns
 
 	csh := getColumnsHeader()
@@ -491,12 +499,12 @@ func (b *block) mustWriteTo(sid *streamID, bh *blockHeader, sw *streamWriters) {
 	cs := b.columns
 	chs := csh.resizeColumnHeaders(len(cs))
 	for i := range cs {
-		cs[i].mustWriteTo(&chs[i], sw)
+		cs[i].mustWriteTo(&chs[i], sw, bc)
 	}
 
 	csh.constColumns = append(csh.constColumns[:0], b.constColumns...)
 
-	csh.mustWriteTo(bh, sw)
+	csh.mustWriteTo(bh, sw, bc)
 
 	putColumnsHeader(csh)
 }
@@ -103,6 +103,8 @@ func (bd *blockData) mustWriteTo(bh *blockHeader, sw *streamWriters) {
 	// Marshal timestamps
 	bd.timestampsData.mustWriteTo(&bh.timestampsHeader, sw)
 
+	bc := newBlockCipherForWrite(sw.encryptionConfig, bh)
+
 	// Marshal columns
 	cds := bd.columnsData
 
@@ -110,11 +112,11 @@ func (bd *blockData) mustWriteTo(bh *blockHeader, sw *streamWriters) {
 
 	chs := csh.resizeColumnHeaders(len(cds))
 	for i := range cds {
-		cds[i].mustWriteTo(&chs[i], sw)
+		cds[i].mustWriteTo(&chs[i], sw, bc)
 	}
 	csh.constColumns = append(csh.constColumns[:0], bd.constColumns...)
 
-	csh.mustWriteTo(bh, sw)
+	csh.mustWriteTo(bh, sw, bc)
 
 	putColumnsHeader(csh)
 }
@@ -132,6 +134,12 @@ func (bd *blockData) mustReadFrom(a *arena, bh *blockHeader, sr *streamReaders)
 	// Read timestamps
 	bd.timestampsData.mustReadFrom(a, &bh.timestampsHeader, sr)
 
+	bc, err := newBlockCipherForRead(sr.encryptionConfig, bh)
+	if err != nil {
+		// Parts with blocks encrypted with missing keys aren't merged.
+		logger.Panicf("BUG: %s: %s", sr.columnsHeaderReader.Path(), err)
+	}
+
 	// Read columns
 	if bh.columnsHeaderOffset != sr.columnsHeaderReader.bytesRead {
 		logger.Panicf("FATAL: %s: unexpected columnsHeaderOffset=%d; must equal to the number of bytes read: %d",
@@ -144,6 +152,16 @@ func (bd *blockData) mustReadFrom(a *arena, bh *blockHeader, sr *streamReaders)
 	bb := longTermBufPool.Get()
 	bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, int(columnsHeaderSize))
 	sr.columnsHeaderReader.MustReadFull(bb.B)
+	if bc != nil {
+		bbEncrypted := bb
+		bb = longTermBufPool.Get()
+		var err error
+		bb.B, err = bc.decrypt(bb.B[:0], bbEncrypted.B)
+		if err != nil {
+			logger.Panicf("FATAL: %s: cannot decrypt columnsHeader: %s", sr.columnsHeaderReader.Path(), err)
+		}
+		longTermBufPool.Put(bbEncrypted)
+	}
 
 	csh := getColumnsHeader()
 	if err := csh.unmarshalInplace(bb.B, sr.partFormatVersion); err != nil {
@@ -156,7 +174,7 @@ func (bd *blockData) mustReadFrom(a *arena, bh *blockHeader, sr *streamReaders)
 	chs := csh.columnHeaders
 	cds := bd.resizeColumnsData(len(chs))
 	for i := range chs {
-		cds[i].mustReadFrom(a, &chs[i], sr)
+		cds[i].mustReadFrom(a, &chs[i], sr, bc)
 	}
 	bd.constColumns = appendFields(a, bd.constColumns[:0], csh.constColumns)
 	putColumnsHeader(csh)
@@ -320,8 +338,8 @@ func (cd *columnData) copyFrom(a *arena, src *columnData) {
 
 // mustWriteTo writes cd to sw and updates ch accordingly.
 //
-// ch is valid until cd is changed.
-func (cd *columnData) mustWriteTo(ch *columnHeader, sw *streamWriters) {
+// Values are encrypted with bc if it isn't nil. ch is valid until cd is changed.
+func (cd *columnData) mustWriteTo(ch *columnHeader, sw *streamWriters, bc *blockCipher) {
 	ch.reset()
 
 	ch.name = cd.name
@@ -334,12 +352,19 @@ func (cd *columnData) mustWriteTo(ch *columnHeader, sw *streamWriters) {
 	bloomValuesWriter := sw.getBloomValuesWriterForColumnName(ch.name)
 
 	// marshal values
-	ch.valuesSize = uint64(len(cd.valuesData))
+	valuesData := cd.valuesData
+	if bc != nil {
+		bb := longTermBufPool.Get()
+		defer longTermBufPool.Put(bb)
+		bb.B = bc.encrypt(bb.B[:0], valuesData)
+		valuesData = bb.B
+	}
+	ch.valuesSize = uint64(len(valuesData))
 	if ch.valuesSize > maxValuesBlockSize {
 		logger.Panicf("BUG: too big valuesSize: %d bytes; mustn't exceed %d bytes", ch.valuesSize, maxValuesBlockSize)
 	}
 	ch.valuesOffset = bloomValuesWriter.values.bytesWritten
-	bloomValuesWriter.values.MustWrite(cd.valuesData)
+	bloomValuesWriter.values.MustWrite(valuesData)
 
 	// marshal bloom filter
 	ch.bloomFilterSize = uint64(len(cd.bloomFilterData))
@@ -352,8 +377,8 @@ func (cd *columnData) mustWriteTo(ch *columnHeader, sw *streamWriters) {
 
 // mustReadFrom reads columns data associated with ch from sr to cd.
 //
-// cd is valid until a.reset() is called.
-func (cd *columnData) mustReadFrom(a *arena, ch *columnHeader, sr *streamReaders) {
+// Values are decrypted with bc if it isn't nil. cd is valid until a.reset() is called.
+func (cd *columnData) mustReadFrom(a *arena, ch *columnHeader, sr *streamReaders, bc *blockCipher) {
 	cd.reset()
 
 	cd.name = a.copyString(ch.name)
@@ -374,8 +399,24 @@ func (cd *columnData) mustReadFrom(a *arena, ch *columnHeader, sr *streamReaders
 	if valuesSize > maxValuesBlockSize {
 		logger.Panicf("FATAL: %s: values block size cannot exceed %d bytes; got %d bytes", bloomValuesReader.values.Path(), maxValuesBlockSize, valuesSize)
 	}
-	cd.valuesData = a.newBytes(int(valuesSize))
-	bloomValuesReader.values.MustReadFull(cd.valuesData)
+	if bc == nil {
+		cd.valuesData = a.newBytes(int(valuesSize))
+		bloomValuesReader.values.MustReadFull(cd.valuesData)
+	} else {
+		bb := longTermBufPool.Get()
+		bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, int(valuesSize))
+		bloomValuesReader.values.MustReadFull(bb.B)
+		n := len(bb.B) - bc.encryptionOverhead()
+		if n < 0 {
+			logger.Panicf("FATAL: %s: too small encrypted values block for column %q; got %d bytes", bloomValuesReader.values.Path(), ch.name, len(bb.B))
+		}
+		data, err := bc.decrypt(a.newBytes(n)[:0], bb.B)
+		if err != nil {
+			logger.Panicf("FATAL: %s: cannot decrypt values for column %q: %s", bloomValuesReader.values.Path(), ch.name, err)
+		}
+		cd.valuesData = data
+		longTermBufPool.Put(bb)
+	}
 
 	// read bloom filter
 	// bloom filter is missing in valueTypeDict.
@@ -38,6 +38,14 @@ type blockHeader struct {
 
 	// columnsHeaderSize is the size of columnsHeader at columnsHeaderFilename
 	columnsHeaderSize uint64
+
+	// encryptionKeyID is the id of the tenant key used for encrypting the block.
+	//
+	// It is zero if the block isn't encrypted. See EncryptionConfig.
+	encryptionKeyID uint32
+
+	// encryptionSalt is the salt for deriving the block key from the tenant key.
+	encryptionSalt [encryptionSaltSize]byte
 }
 
 // reset resets bh, so it can be reused.
@@ -50,6 +58,8 @@ func (bh *blockHeader) reset() {
 	bh.columnsHeaderIndexSize = 0
 	bh.columnsHeaderOffset = 0
 	bh.columnsHeaderSize = 0
+	bh.encryptionKeyID = 0
+	bh.encryptionSalt = [encryptionSaltSize]byte{}
 }
 
 func (bh *blockHeader) copyFrom(src *blockHeader) {
@@ -63,10 +73,14 @@ func (bh *blockHeader) copyFrom(src *blockHeader) {
 	bh.columnsHeaderIndexSize = src.columnsHeaderIndexSize
 	bh.columnsHeaderOffset = src.columnsHeaderOffset
 	bh.columnsHeaderSize = src.columnsHeaderSize
+	bh.encryptionKeyID = src.encryptionKeyID
+	bh.encryptionSalt = src.encryptionSalt
 }
 
 // marshal appends the marshaled bh to dst and returns the result.
-func (bh *blockHeader) marshal(dst []byte) []byte {
+//
+// partFormatVersion is the format version of the part bh belongs to.
+func (bh *blockHeader) marshal(dst []byte, partFormatVersion uint) []byte {
 	dst = bh.streamID.marshal(dst)
 	dst = encoding.MarshalVarUint64(dst, bh.uncompressedSizeBytes)
 	dst = encoding.MarshalVarUint64(dst, bh.rowsCount)
@@ -75,6 +89,12 @@ func (bh *blockHeader) marshal(dst []byte) []byte {
 	dst = encoding.MarshalVarUint64(dst, bh.columnsHeaderIndexSize)
 	dst = encoding.MarshalVarUint64(dst, bh.columnsHeaderOffset)
 	dst = encoding.MarshalVarUint64(dst, bh.columnsHeaderSize)
+	if partFormatVersion >= 4 {
+		dst = encoding.MarshalVarUint64(dst, uint64(bh.encryptionKeyID))
+		if bh.encryptionKeyID != 0 {
+			dst = append(dst, bh.encryptionSalt[:]...)
+		}
+	}
 
 	return dst
 }
@@ -155,6 +175,28 @@ func (bh *blockHeader) unmarshal(src []byte, partFormatVersion uint) ([]byte, er
 	}
 	bh.columnsHeaderSize = n
 
+	if partFormatVersion >= 4 {
+		// unmarshal encryptionKeyID
+		n, nSize = encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return srcOrig, fmt.Errorf("cannot unmarshal encryptionKeyID")
+		}
+		src = src[nSize:]
+		if n > math.MaxUint32 {
+			return srcOrig, fmt.Errorf("too big value for encryptionKeyID: %d; mustn't exceed %d", n, uint64(math.MaxUint32))
+		}
+		bh.encryptionKeyID = uint32(n)
+
+		// unmarshal encryptionSalt
+		if bh.encryptionKeyID != 0 {
+			if len(src) < encryptionSaltSize {
+				return srcOrig, fmt.Errorf("cannot unmarshal encryptionSalt from %d bytes; need at least %d bytes", len(src), encryptionSaltSize)
+			}
+			copy(bh.encryptionSalt[:], src)
+			src = src[encryptionSaltSize:]
+		}
+	}
+
 	return src, nil
 }
 
@@ -422,7 +464,10 @@ func (csh *columnsHeader) setColumnNames(cshIndex *columnsHeaderIndex, columnNam
 	return nil
 }
 
-func (csh *columnsHeader) mustWriteTo(bh *blockHeader, sw *streamWriters) {
+// mustWriteTo writes csh to sw and updates bh accordingly.
+//
+// columnsHeader is encrypted with bc if it isn't nil, while columnsHeaderIndex is always written as is.
+func (csh *columnsHeader) mustWriteTo(bh *blockHeader, sw *streamWriters, bc *blockCipher) {
 	bb := longTermBufPool.Get()
 	defer longTermBufPool.Put(bb)
 
@@ -436,6 +481,13 @@ func (csh *columnsHeader) mustWriteTo(bh *blockHeader, sw *streamWriters) {
 
 	putColumnsHeaderIndex(cshIndex)
 
+	if bc != nil {
+		bbEncrypted := longTermBufPool.Get()
+		defer longTermBufPool.Put(bbEncrypted)
+		bbEncrypted.B = bc.encrypt(bbEncrypted.B[:0], columnsHeaderData)
+		columnsHeaderData = bbEncrypted.B
+	}
+
 	bh.columnsHeaderIndexOffset = sw.columnsHeaderIndexWriter.bytesWritten
 	bh.columnsHeaderIndexSize = uint64(len(columnsHeaderIndexData))
 	if bh.columnsHeaderIndexSize > maxColumnsHeaderIndexSize {
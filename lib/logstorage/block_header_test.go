@@ -8,14 +8,14 @@ import (
 )
 
 func TestBlockHeaderMarshalUnmarshal(t *testing.T) {
-	f := func(bh *blockHeader, marshaledLen int) {
+	f := func(bh *blockHeader, partFormatVersion uint, marshaledLen int) {
 		t.Helper()
-		data := bh.marshal(nil)
+		data := bh.marshal(nil, partFormatVersion)
 		if len(data) != marshaledLen {
 			t.Fatalf("unexpected lengths of the marshaled blockHeader; got %d; want %d", len(data), marshaledLen)
 		}
 		bh2 := &blockHeader{}
-		tail, err := bh2.unmarshal(data, partFormatLatestVersion)
+		tail, err := bh2.unmarshal(data, partFormatVersion)
 		if err != nil {
 			t.Fatalf("unexpected error in unmarshal: %s", err)
 		}
@@ -26,7 +26,8 @@ func TestBlockHeaderMarshalUnmarshal(t *testing.T) {
 			t.Fatalf("unexpected blockHeader unmarshaled\ngot\n%v\nwant\n%v", bh2, bh)
 		}
 	}
-	f(&blockHeader{}, 63)
+	f(&blockHeader{}, partFormatUnencryptedVersion, 63)
+	f(&blockHeader{}, partFormatLatestVersion, 64)
 	f(&blockHeader{
 		streamID: streamID{
 			tenantID: TenantID{
@@ -51,7 +52,36 @@ func TestBlockHeaderMarshalUnmarshal(t *testing.T) {
 		columnsHeaderIndexSize:   8989832,
 		columnsHeaderOffset:      4384,
 		columnsHeaderSize:        894,
-	}, 73)
+	}, partFormatUnencryptedVersion, 73)
+
+	// encrypted block
+	f(&blockHeader{
+		streamID: streamID{
+			tenantID: TenantID{
+				AccountID: 123,
+				ProjectID: 456,
+			},
+			id: u128{
+				lo: 3443,
+				hi: 23434,
+			},
+		},
+		uncompressedSizeBytes: 4344,
+		rowsCount:             1234,
+		timestampsHeader: timestampsHeader{
+			blockOffset:  13234,
+			blockSize:    8843,
+			minTimestamp: -4334,
+			maxTimestamp: 23434,
+			marshalType:  encoding.MarshalTypeNearestDelta2,
+		},
+		columnsHeaderIndexOffset: 8923481,
+		columnsHeaderIndexSize:   8989832,
+		columnsHeaderOffset:      4384,
+		columnsHeaderSize:        894,
+		encryptionKeyID:          0x12345678,
+		encryptionSalt:           [encryptionSaltSize]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}, partFormatLatestVersion, 94)
 }
 
 func TestColumnsHeaderIndexMarshalUnmarshal(t *testing.T) {
@@ -190,7 +220,7 @@ func TestBlockHeaderUnmarshalFailure(t *testing.T) {
 		columnsHeaderOffset:      4384,
 		columnsHeaderSize:        894,
 	}
-	data := bh.marshal(nil)
+	data := bh.marshal(nil, partFormatLatestVersion)
 	for len(data) > 0 {
 		data = data[:len(data)-1]
 		f(data)
@@ -394,12 +424,12 @@ func TestMarshalUnmarshalBlockHeaders(t *testing.T) {
 		t.Helper()
 		var data []byte
 		for i := range bhs {
-			data = bhs[i].marshal(data)
+			data = bhs[i].marshal(data, partFormatUnencryptedVersion)
 		}
 		if len(data) != marshaledLen {
 			t.Fatalf("unexpected length for marshaled blockHeader entries; got %d; want %d", len(data), marshaledLen)
 		}
-		bhs2, err := unmarshalBlockHeaders(nil, data, partFormatLatestVersion)
+		bhs2, err := unmarshalBlockHeaders(nil, data, partFormatUnencryptedVersion)
 		if err != nil {
 			t.Fatalf("unexpected error when unmarshaling blockHeader entries: %s", err)
 		}
@@ -108,6 +108,12 @@ type blockSearch struct {
 	// bloomFilterCache contains cached bloom filters for requested columns in the given block
 	bloomFilterCache map[string]*bloomFilter
 
+	// bloomFilterChecks is the number of bloom filter checks performed for the given block
+	bloomFilterChecks int
+
+	// bloomFilterMisses is the number of bloom filter checks for the given block, which didn't find the needed tokens
+	bloomFilterMisses int
+
 	// valuesCache contains cached values for requested columns in the given block
 	valuesCache map[string]*stringBucket
 
@@ -125,6 +131,12 @@ type blockSearch struct {
 	cshBlockCache       []byte
 	cshBlockInitialized bool
 
+	// bc is the cipher for decrypting the given block.
+	//
+	// It is initialized lazily by calling getBlockCipher(). It is nil for unencrypted blocks.
+	bc            *blockCipher
+	bcInitialized bool
+
 	// ccsCache is the cache for accessed const columns
 	ccsCache []Field
 
@@ -161,6 +173,8 @@ func (bs *blockSearch) reset() {
 		putBloomFilter(bf)
 		delete(bloomFilterCache, k)
 	}
+	bs.bloomFilterChecks = 0
+	bs.bloomFilterMisses = 0
 
 	valuesCache := bs.valuesCache
 	for k, values := range valuesCache {
@@ -175,6 +189,9 @@ func (bs *blockSearch) reset() {
 	bs.cshBlockCache = bs.cshBlockCache[:0]
 	bs.cshBlockInitialized = false
 
+	bs.bc = nil
+	bs.bcInitialized = false
+
 	ccsCache := bs.ccsCache
 	for i := range ccsCache {
 		ccsCache[i].Reset()
@@ -200,6 +217,17 @@ func (bs *blockSearch) reset() {
 	// Do not reset seenStreams, since its' lifetime is managed by blockResult.addStreamColumn() code.
 }
 
+// addBloomFilterCheck registers the result of bloom filter check for the given block.
+//
+// matched must be set to false if the bloom filter check allows skipping the block.
+func (bs *blockSearch) addBloomFilterCheck(matched bool) {
+	bs.bloomFilterChecks++
+	if !matched {
+		bs.bloomFilterMisses++
+	}
+	bs.bsw.so.stats.addBloomFilterCheck(matched)
+}
+
 func (bs *blockSearch) partPath() string {
 	return bs.bsw.p.path
 }
@@ -214,6 +242,13 @@ func (bs *blockSearch) search(bsw *blockSearchWork, bm *bitmap) {
 	bm.setBits()
 	bs.bsw.so.filter.applyToBlockSearch(bs, bm)
 
+	if stats := bsw.so.stats; stats != nil {
+		stats.addBlockScanned(int(bsw.bh.rowsCount), bm.onesCount())
+	}
+	if pt := bsw.p.pt; pt != nil {
+		pt.bloomFilterStats.addBlock(bs.bloomFilterChecks, bs.bloomFilterMisses, !bm.isZero())
+	}
+
 	if bm.isZero() {
 		// The filter doesn't match any logs in the current block.
 		return
@@ -383,12 +418,41 @@ func (bs *blockSearch) getColumnsHeader() *columnsHeader {
 
 func (bs *blockSearch) getColumnsHeaderBlock() []byte {
 	if !bs.cshBlockInitialized {
-		bs.cshBlockCache = readColumnsHeaderBlock(bs.cshBlockCache[:0], bs.bsw.p, &bs.bsw.bh)
+		bc := bs.getBlockCipher()
+		if bc == nil {
+			bs.cshBlockCache = readColumnsHeaderBlock(bs.cshBlockCache[:0], bs.bsw.p, &bs.bsw.bh)
+		} else {
+			bb := longTermBufPool.Get()
+			bb.B = readColumnsHeaderBlock(bb.B[:0], bs.bsw.p, &bs.bsw.bh)
+			var err error
+			bs.cshBlockCache, err = bc.decrypt(bs.cshBlockCache[:0], bb.B)
+			if err != nil {
+				logger.Panicf("FATAL: %s: cannot decrypt columns header: %s", bs.partPath(), err)
+			}
+			longTermBufPool.Put(bb)
+		}
 		bs.cshBlockInitialized = true
 	}
 	return bs.cshBlockCache
 }
 
+// getBlockCipher returns the cipher for decrypting the given block.
+//
+// nil is returned if the block isn't encrypted.
+func (bs *blockSearch) getBlockCipher() *blockCipher {
+	if !bs.bcInitialized {
+		p := bs.bsw.p
+		bc, err := newBlockCipherForRead(p.getEncryptionConfig(), &bs.bsw.bh)
+		if err != nil {
+			// Parts with blocks encrypted with missing keys are skipped during the search.
+			logger.Panicf("BUG: %s: %s", p.path, err)
+		}
+		bs.bc = bc
+		bs.bcInitialized = true
+	}
+	return bs.bc
+}
+
 func readColumnsHeaderIndexBlock(dst []byte, p *part, bh *blockHeader) []byte {
 	n := bh.columnsHeaderIndexSize
 	if n > maxColumnsHeaderIndexSize {
@@ -465,6 +529,16 @@ func (bs *blockSearch) getValuesForColumn(ch *columnHeader) []string {
 	}
 	bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, int(valuesSize))
 	bloomValuesFile.values.MustReadAt(bb.B, int64(ch.valuesOffset))
+	if bc := bs.getBlockCipher(); bc != nil {
+		bbEncrypted := bb
+		bb = longTermBufPool.Get()
+		var err error
+		bb.B, err = bc.decrypt(bb.B[:0], bbEncrypted.B)
+		if err != nil {
+			logger.Panicf("FATAL: %s: cannot decrypt values for column %q: %s", bs.partPath(), ch.name, err)
+		}
+		longTermBufPool.Put(bbEncrypted)
+	}
 
 	values = getStringBucket()
 	var err error
@@ -11,10 +11,12 @@ import (
 
 // mustMergeBlockStreams merges bsrs to bsw and updates ph accordingly.
 //
+// Log entries are sampled with ls during the merge if ls isn't nil.
+//
 // Finalize() is guaranteed to be called on bsrs and bsw before returning from the func.
-func mustMergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*blockStreamReader, stopCh <-chan struct{}) {
+func mustMergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*blockStreamReader, ls *logSampler, stopCh <-chan struct{}) {
 	bsm := getBlockStreamMerger()
-	bsm.mustInit(bsw, bsrs)
+	bsm.mustInit(bsw, bsrs, ls)
 	for len(bsm.readersHeap) > 0 {
 		if needStop(stopCh) {
 			break
@@ -46,6 +48,9 @@ type blockStreamMerger struct {
 	// readersHeap contains a heap of readers to read blocks to merge.
 	readersHeap blockStreamReadersHeap
 
+	// ls is an optional sampler for the merged log entries.
+	ls *logSampler
+
 	// streamID is the stream ID for the pending data.
 	streamID streamID
 
@@ -88,6 +93,8 @@ func (bsm *blockStreamMerger) reset() {
 	}
 	bsm.readersHeap = rhs[:0]
 
+	bsm.ls = nil
+
 	bsm.streamID.reset()
 	bsm.resetRows()
 }
@@ -111,11 +118,12 @@ func (bsm *blockStreamMerger) resetRows() {
 	bsm.uniqueFields = 0
 }
 
-func (bsm *blockStreamMerger) mustInit(bsw *blockStreamWriter, bsrs []*blockStreamReader) {
+func (bsm *blockStreamMerger) mustInit(bsw *blockStreamWriter, bsrs []*blockStreamReader, ls *logSampler) {
 	bsm.reset()
 
 	bsm.bsw = bsw
 	bsm.bsrs = bsrs
+	bsm.ls = ls
 
 	rsh := bsm.readersHeap[:0]
 	for _, bsr := range bsrs {
@@ -139,7 +147,7 @@ func (bsm *blockStreamMerger) mustWriteBlock(bd *blockData, bsw *blockStreamWrit
 		bsm.streamID = bd.streamID
 		if bd.uncompressedSizeBytes >= maxUncompressedBlockSize {
 			// Fast path - write full bd to the output without extracting log entries from it.
-			bsw.MustWriteBlockData(bd)
+			bsm.mustWriteBlockData(bd, bsw)
 		} else {
 			// Slow path - copy the bd to the curr bd.
 			bsm.a.reset()
@@ -153,7 +161,7 @@ func (bsm *blockStreamMerger) mustWriteBlock(bd *blockData, bsw *blockStreamWrit
 		// Flush bsm.rows and copy the bd to the curr bd.
 		bsm.mustFlushRows()
 		if uniqueFields >= maxColumnsPerBlock {
-			bsw.MustWriteBlockData(bd)
+			bsm.mustWriteBlockData(bd, bsw)
 		} else {
 			bsm.a.reset()
 			bsm.bd.copyFrom(&bsm.a, bd)
@@ -165,7 +173,7 @@ func (bsm *blockStreamMerger) mustWriteBlock(bd *blockData, bsw *blockStreamWrit
 		// without the need to merge the bd with the current log entries.
 		// Write the current log entries and then the bd.
 		bsm.mustFlushRows()
-		bsw.MustWriteBlockData(bd)
+		bsm.mustWriteBlockData(bd, bsw)
 	default:
 		// The bd contains the same streamID and it isn't full,
 		// so it must be merged with the current log entries.
@@ -259,13 +267,36 @@ func (bsm *blockStreamMerger) mustUnmarshalRows(bd *blockData) {
 
 func (bsm *blockStreamMerger) mustFlushRows() {
 	if len(bsm.rows.timestamps) == 0 {
-		bsm.bsw.MustWriteBlockData(&bsm.bd)
+		bsm.mustWriteBlockData(&bsm.bd, bsm.bsw)
 	} else {
-		bsm.bsw.MustWriteRows(&bsm.streamID, bsm.rows.timestamps, bsm.rows.rows)
+		timestamps, rows := bsm.ls.filterRows(&bsm.streamID, bsm.rows.timestamps, bsm.rows.rows)
+		bsm.bsw.MustWriteRows(&bsm.streamID, timestamps, rows)
 	}
 	bsm.resetRows()
 }
 
+// mustWriteBlockData writes bd to bsw.
+//
+// The bd is unpacked into log entries if some of them must be dropped by bsm.ls.
+func (bsm *blockStreamMerger) mustWriteBlockData(bd *blockData, bsw *blockStreamWriter) {
+	if !bsm.ls.needSampling(bd) {
+		bsw.MustWriteBlockData(bd)
+		return
+	}
+
+	sbu := getStringsBlockUnmarshaler()
+	vd := getValuesDecoder()
+	rs := &bsm.rowsTmp
+	if err := bd.unmarshalRows(rs, sbu, vd); err != nil {
+		logger.Panicf("FATAL: cannot merge %s: cannot unmarshal log entries from blockData: %s", bsm.ReadersPaths(), err)
+	}
+	timestamps, rows := bsm.ls.filterRows(&bd.streamID, rs.timestamps, rs.rows)
+	bsw.MustWriteRows(&bd.streamID, timestamps, rows)
+	rs.reset()
+	putValuesDecoder(vd)
+	putStringsBlockUnmarshaler(sbu)
+}
+
 func getBlockStreamMerger() *blockStreamMerger {
 	v := blockStreamMergerPool.Get()
 	if v == nil {
@@ -74,6 +74,9 @@ type streamReaders struct {
 
 	// columnNames contains id->columnName mapping for all the columns seen in the part
 	columnNames []string
+
+	// encryptionConfig contains keys for decrypting encrypted blocks
+	encryptionConfig *EncryptionConfig
 }
 
 type bloomValuesReader struct {
@@ -125,6 +128,8 @@ func (sr *streamReaders) reset() {
 
 	sr.columnIdxs = nil
 	sr.columnNames = nil
+
+	sr.encryptionConfig = nil
 }
 
 func (sr *streamReaders) init(partFormatVersion uint, columnNamesReader, columnIdxsReader, metaindexReader, indexReader,
@@ -298,6 +303,13 @@ func (bsr *blockStreamReader) Path() string {
 	return filepath.Dir(path)
 }
 
+// setEncryptionConfig sets the config with keys for decrypting encrypted blocks read from bsr.
+//
+// It must be called after bsr initialization.
+func (bsr *blockStreamReader) setEncryptionConfig(ec *EncryptionConfig) {
+	bsr.streamReaders.encryptionConfig = ec
+}
+
 // MustInitFromInmemoryPart initializes bsr from mp.
 func (bsr *blockStreamReader) MustInitFromInmemoryPart(mp *inmemoryPart) {
 	bsr.reset()
@@ -2,6 +2,7 @@ package logstorage
 
 import (
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -63,6 +64,12 @@ type streamWriters struct {
 
 	columnIdxs    map[uint64]uint64
 	nextColumnIdx uint64
+
+	// bloomFilterBitsPerItem is the number of bits per token for bloom filters of the written columns
+	bloomFilterBitsPerItem int
+
+	// encryptionConfig contains keys for encrypting the written blocks
+	encryptionConfig *EncryptionConfig
 }
 
 type bloomValuesWriter struct {
@@ -115,6 +122,10 @@ func (sw *streamWriters) reset() {
 	sw.columnNameIDGenerator.reset()
 	sw.columnIdxs = nil
 	sw.nextColumnIdx = 0
+
+	sw.bloomFilterBitsPerItem = 0
+
+	sw.encryptionConfig = nil
 }
 
 func (sw *streamWriters) init(columnNamesWriter, columnIdxsWriter, metaindexWriter, indexWriter,
@@ -133,6 +144,8 @@ func (sw *streamWriters) init(columnNamesWriter, columnIdxsWriter, metaindexWrit
 
 	sw.createBloomValuesWriter = createBloomValuesWriter
 	sw.maxShards = maxShards
+
+	sw.bloomFilterBitsPerItem = bloomFilterBitsPerItem
 }
 
 func (sw *streamWriters) totalBytesWritten() uint64 {
@@ -245,6 +258,9 @@ type blockStreamWriter struct {
 
 	// indexBlockHeader is used for marshaling the data to metaindexData
 	indexBlockHeader indexBlockHeader
+
+	// encryptionKeys contains the keys used for encrypting the written blocks
+	encryptionKeys []partEncryptionKey
 }
 
 // reset resets bsw for subsequent reuse.
@@ -272,6 +288,8 @@ func (bsw *blockStreamWriter) reset() {
 	}
 
 	bsw.indexBlockHeader.reset()
+
+	bsw.encryptionKeys = bsw.encryptionKeys[:0]
 }
 
 // MustInitForInmemoryPart initializes bsw from mp
@@ -286,6 +304,34 @@ func (bsw *blockStreamWriter) MustInitForInmemoryPart(mp *inmemoryPart) {
 	bsw.streamWriters.init(&mp.columnNames, &mp.columnIdxs, &mp.metaindex, &mp.index, &mp.columnsHeaderIndex, &mp.columnsHeader, &mp.timestamps, messageBloomValues, createBloomValuesWriter, 1)
 }
 
+// setBloomFilterBitsPerItem sets the number of bits per token for bloom filters written to bsw.
+//
+// It must be called after bsw initialization. The default bloomFilterBitsPerItem is used if bitsPerItem isn't positive.
+func (bsw *blockStreamWriter) setBloomFilterBitsPerItem(bitsPerItem int) {
+	if bitsPerItem <= 0 {
+		bitsPerItem = bloomFilterBitsPerItem
+	}
+	bsw.streamWriters.bloomFilterBitsPerItem = bitsPerItem
+}
+
+// setEncryptionConfig sets the config with keys for encrypting blocks written to bsw.
+//
+// It must be called after bsw initialization. Blocks aren't encrypted if ec is nil.
+func (bsw *blockStreamWriter) setEncryptionConfig(ec *EncryptionConfig) {
+	bsw.streamWriters.encryptionConfig = ec
+}
+
+// partFormatVersion returns the format version for the part written by bsw.
+//
+// Parts are written in the format readable by older releases unless encryption is enabled via setEncryptionConfig,
+// so it is possible to downgrade if -storage.encryptionConfig isn't set.
+func (bsw *blockStreamWriter) partFormatVersion() uint {
+	if bsw.streamWriters.encryptionConfig != nil {
+		return partFormatLatestVersion
+	}
+	return partFormatUnencryptedVersion
+}
+
 // MustInitForFilePart initializes bsw for writing data to file part located at path.
 //
 // if nocache is true, then the written data doesn't go to OS page cache.
@@ -419,8 +465,12 @@ func (bsw *blockStreamWriter) mustWriteBlockInternal(sid *streamID, b *block, bd
 	bsw.globalRowsCount += bh.rowsCount
 	bsw.globalBlocksCount++
 
+	if bh.encryptionKeyID != 0 {
+		bsw.addEncryptionKey(bh.streamID.tenantID, bh.encryptionKeyID)
+	}
+
 	// Marshal bh
-	bsw.indexBlockData = bh.marshal(bsw.indexBlockData)
+	bsw.indexBlockData = bh.marshal(bsw.indexBlockData, bsw.partFormatVersion())
 	putBlockHeader(bh)
 	if len(bsw.indexBlockData) > maxUncompressedIndexBlockSize {
 		bsw.mustFlushIndexBlock(bsw.indexBlockData)
@@ -428,6 +478,20 @@ func (bsw *blockStreamWriter) mustWriteBlockInternal(sid *streamID, b *block, bd
 	}
 }
 
+func (bsw *blockStreamWriter) addEncryptionKey(tenantID TenantID, keyID uint32) {
+	k := partEncryptionKey{
+		TenantID: tenantID,
+		KeyID:    keyID,
+	}
+	// Blocks are written in the order of their streamIDs, so the key is usually the last added one.
+	for i := len(bsw.encryptionKeys) - 1; i >= 0; i-- {
+		if bsw.encryptionKeys[i] == k {
+			return
+		}
+	}
+	bsw.encryptionKeys = append(bsw.encryptionKeys, k)
+}
+
 func (bsw *blockStreamWriter) mustFlushIndexBlock(data []byte) {
 	if len(data) > 0 {
 		bsw.indexBlockHeader.mustWriteIndexBlock(data, bsw.sidFirst, bsw.minTimestamp, bsw.maxTimestamp, &bsw.streamWriters)
@@ -445,13 +509,20 @@ func (bsw *blockStreamWriter) mustFlushIndexBlock(data []byte) {
 //
 // bsw can be reused after calling Finalize().
 func (bsw *blockStreamWriter) Finalize(ph *partHeader) {
-	ph.FormatVersion = partFormatLatestVersion
+	ph.FormatVersion = bsw.partFormatVersion()
 	ph.UncompressedSizeBytes = bsw.globalUncompressedSizeBytes
 	ph.RowsCount = bsw.globalRowsCount
 	ph.BlocksCount = bsw.globalBlocksCount
 	ph.MinTimestamp = bsw.globalMinTimestamp
 	ph.MaxTimestamp = bsw.globalMaxTimestamp
 	ph.BloomValuesShardsCount = uint64(len(bsw.streamWriters.bloomValuesShards))
+	if len(bsw.encryptionKeys) > 0 {
+		keys := append([]partEncryptionKey{}, bsw.encryptionKeys...)
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].less(&keys[j])
+		})
+		ph.EncryptionKeys = keys
+	}
 
 	bsw.mustFlushIndexBlock(bsw.indexBlockData)
 
@@ -15,9 +15,20 @@ import (
 // bloomFilterHashesCount is the number of different hashes to use for bloom filter.
 const bloomFilterHashesCount = 6
 
-// bloomFilterBitsPerItem is the number of bits to use per each token.
+// bloomFilterBitsPerItem is the default number of bits to use per each token.
+//
+// The number of bits per token may differ per partition if bloom filter auto-tuning is enabled.
+// This doesn't break searching, since the bloom filter size is stored in columnHeader,
+// while the number of hashes per token remains bloomFilterHashesCount.
 const bloomFilterBitsPerItem = 16
 
+// minBloomFilterBitsPerItem and maxBloomFilterBitsPerItem are the limits for the number of bits per token
+// selected by bloom filter auto-tuning.
+const (
+	minBloomFilterBitsPerItem = 8
+	maxBloomFilterBitsPerItem = 32
+)
+
 // bloomFilterMarshalTokens appends marshaled bloom filter for tokens to dst and returns the result.
 func bloomFilterMarshalTokens(dst []byte, tokens []string) []byte {
 	bf := getBloomFilter()
@@ -29,8 +40,15 @@ func bloomFilterMarshalTokens(dst []byte, tokens []string) []byte {
 
 // bloomFilterMarshalHashes appends marshaled bloom filter for hashes to dst and returns the result.
 func bloomFilterMarshalHashes(dst []byte, hashes []uint64) []byte {
+	return bloomFilterMarshalHashesWithBitsPerItem(dst, hashes, bloomFilterBitsPerItem)
+}
+
+// bloomFilterMarshalHashesWithBitsPerItem appends marshaled bloom filter for hashes to dst and returns the result.
+//
+// bitsPerItem is the number of bits to use per each hash.
+func bloomFilterMarshalHashesWithBitsPerItem(dst []byte, hashes []uint64, bitsPerItem int) []byte {
 	bf := getBloomFilter()
-	bf.mustInitHashes(hashes)
+	bf.mustInitHashes(hashes, bitsPerItem)
 	dst = bf.marshal(dst)
 	putBloomFilter(bf)
 	return dst
@@ -79,9 +97,9 @@ func (bf *bloomFilter) mustInitTokens(tokens []string) {
 	bf.bits = bits
 }
 
-// mustInitHashes initializes bf with the given hashes
-func (bf *bloomFilter) mustInitHashes(hashes []uint64) {
-	bitsCount := len(hashes) * bloomFilterBitsPerItem
+// mustInitHashes initializes bf with the given hashes, by using bitsPerItem bits per each hash
+func (bf *bloomFilter) mustInitHashes(hashes []uint64, bitsPerItem int) {
+	bitsCount := len(hashes) * bitsPerItem
 	wordsCount := (bitsCount + 63) / 64
 	bits := slicesutil.SetLength(bf.bits, wordsCount)
 	bloomFilterAddHashes(bits, hashes)
@@ -3,7 +3,12 @@ package logstorage
 // partFormatLatestVersion is the latest format version for parts.
 //
 // See partHeader.FormatVersion for details.
-const partFormatLatestVersion = 3
+const partFormatLatestVersion = 4
+
+// partFormatUnencryptedVersion is the format version for parts written without encryption.
+//
+// It is smaller than partFormatLatestVersion, so parts written without -storage.encryptionConfig can be read by older releases.
+const partFormatUnencryptedVersion = 3
 
 // bloomValuesMaxShardsCount is the number of shards for bloomFilename and valuesFilename files.
 //
@@ -427,7 +427,7 @@ func (ddb *datadb) bigPartsMerger() {
 func getPartsToMergeLocked(pws []*partWrapper, maxOutBytes uint64) []*partWrapper {
 	pwsRemaining := make([]*partWrapper, 0, len(pws))
 	for _, pw := range pws {
-		if !pw.isInMerge {
+		if !pw.isInMerge && isMergeablePart(pw) {
 			pwsRemaining = append(pwsRemaining, pw)
 		}
 	}
@@ -541,6 +541,8 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		nocache := dstPartType == partBig
 		bsw.MustInitForFilePart(dstPartPath, nocache)
 	}
+	bsw.setBloomFilterBitsPerItem(ddb.getBloomFilterBitsPerItem())
+	bsw.setEncryptionConfig(ddb.getEncryptionConfig())
 
 	// Merge source parts to destination part.
 	var ph partHeader
@@ -549,7 +551,8 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		// The final merge shouldn't be stopped even if ddb.stopCh is closed.
 		stopCh = nil
 	}
-	mustMergeBlockStreams(&ph, bsw, bsrs, stopCh)
+	ls := ddb.newLogSampler()
+	mustMergeBlockStreams(&ph, bsw, bsrs, ls, stopCh)
 	putBlockStreamWriter(bsw)
 	for _, bsr := range bsrs {
 		putBlockStreamReader(bsr)
@@ -563,6 +566,9 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		// Make sure the created part directory listing is synced.
 		fs.MustSyncPath(dstPartPath)
 	}
+	if ls != nil && ls.rowsDropped > 0 {
+		ddb.pt.s.rowsDroppedBySampling.Add(ls.rowsDropped)
+	}
 	if needStop(stopCh) {
 		// Remove incomplete destination part
 		if dstPartType != partInmemory {
@@ -598,6 +604,17 @@ func (ddb *datadb) mustMergeParts(pws []*partWrapper, isFinal bool) {
 		len(pws), srcRowsCount, srcBlocksCount, srcSize, dstRowsCount, dstBlocksCount, dstSize, durationSecs, rowsPerSec, dstPartPath)
 }
 
+// newLogSampler returns logSampler for the merge of ddb parts.
+//
+// nil is returned if log sampling isn't configured.
+func (ddb *datadb) newLogSampler() *logSampler {
+	pt := ddb.pt
+	if pt == nil || pt.s == nil {
+		return nil
+	}
+	return newLogSampler(pt.s.samplingConfig, pt.idb, time.Now().UnixNano())
+}
+
 func (ddb *datadb) nextMergeIdx() uint64 {
 	return ddb.mergeIdx.Add(1)
 }
@@ -657,6 +674,22 @@ func (ddb *datadb) openCreatedPart(ph *partHeader, pws []*partWrapper, mpNew *in
 	return newPartWrapper(p, mpNew, flushDeadline)
 }
 
+// getBloomFilterBitsPerItem returns the number of bits per token for bloom filters in the newly created parts at ddb.
+func (ddb *datadb) getBloomFilterBitsPerItem() int {
+	if ddb.pt == nil {
+		return bloomFilterBitsPerItem
+	}
+	return ddb.pt.bloomFilterBitsPerItem
+}
+
+// getEncryptionConfig returns the config with keys for encrypting blocks in the newly created parts at ddb.
+func (ddb *datadb) getEncryptionConfig() *EncryptionConfig {
+	if ddb.pt == nil || ddb.pt.s == nil {
+		return nil
+	}
+	return ddb.pt.s.encryptionConfig
+}
+
 func (ddb *datadb) mustAddRows(lr *LogRows) {
 	if len(lr.streamIDs) == 0 {
 		return
@@ -664,7 +697,7 @@ func (ddb *datadb) mustAddRows(lr *LogRows) {
 
 	inmemoryPartsConcurrencyCh <- struct{}{}
 	mp := getInmemoryPart()
-	mp.mustInitFromRows(lr)
+	mp.mustInitFromRowsWithConfig(lr, ddb.getBloomFilterBitsPerItem(), ddb.getEncryptionConfig())
 	p := mustOpenInmemoryPart(ddb.pt, mp)
 	<-inmemoryPartsConcurrencyCh
 
@@ -786,6 +819,27 @@ func (ddb *datadb) updateStats(s *DatadbStats) {
 	ddb.partsLock.Unlock()
 }
 
+// getRowsCount returns the number of rows for the given tenantIDs in ddb.
+func (ddb *datadb) getRowsCount(tenantIDs []TenantID) uint64 {
+	ddb.partsLock.Lock()
+	pws := append([]*partWrapper{}, ddb.inmemoryParts...)
+	pws = append(pws, ddb.smallParts...)
+	pws = append(pws, ddb.bigParts...)
+	for _, pw := range pws {
+		pw.incRef()
+	}
+	ddb.partsLock.Unlock()
+
+	n := uint64(0)
+	for _, pw := range pws {
+		for _, tenantID := range tenantIDs {
+			n += pw.p.getTenantRowsCount(tenantID)
+		}
+		pw.decRef()
+	}
+	return n
+}
+
 // debugFlush() makes sure that the recently ingested data is available for search.
 func (ddb *datadb) debugFlush() {
 	// Nothing to do, since all the ingested data is available for search via ddb.inmemoryParts.
@@ -877,6 +931,7 @@ func mustOpenBlockStreamReaders(pws []*partWrapper) []*blockStreamReader {
 		} else {
 			bsr.MustInitFromFilePart(pw.p.path)
 		}
+		bsr.setEncryptionConfig(pw.p.getEncryptionConfig())
 		bsrs = append(bsrs, bsr)
 	}
 	return bsrs
@@ -1262,10 +1317,21 @@ func (ddb *datadb) mustForceMergeAllParts() {
 
 func appendAllPartsForMergeLocked(dst, src []*partWrapper) []*partWrapper {
 	for _, pw := range src {
-		if !pw.isInMerge {
+		if !pw.isInMerge && isMergeablePart(pw) {
 			pw.isInMerge = true
 			dst = append(dst, pw)
 		}
 	}
 	return dst
 }
+
+// isMergeablePart returns false if pw contains blocks, which cannot be decrypted because of missing encryption keys.
+//
+// Such parts are left as is until the missing keys are added to -storage.encryptionConfig.
+func isMergeablePart(pw *partWrapper) bool {
+	if err := pw.p.checkEncryptionKeys(); err != nil {
+		missingEncryptionKeyLogger.Errorf("skipping the part %s during background merge: %s", pw.p.path, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,336 @@
+package logstorage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// EncryptionConfig contains per-tenant keys for encryption of stored log blocks.
+//
+// See https://docs.victoriametrics.com/victorialogs/#encryption-at-rest
+type EncryptionConfig struct {
+	tenants map[TenantID]*tenantEncryptionKeys
+}
+
+// encryptionKeyYAML is YAML representation for a single encryption key.
+type encryptionKeyYAML struct {
+	Key     string `yaml:"key,omitempty"`
+	KeyFile string `yaml:"key_file,omitempty"`
+
+	// KMSEncryptedKey is base64-encoded key encrypted with AWS KMS. It is decrypted via the KMS config.
+	KMSEncryptedKey string `yaml:"kms_encrypted_key,omitempty"`
+}
+
+// tenantEncryptionKeysYAML is YAML representation for encryption keys of a single tenant.
+type tenantEncryptionKeysYAML struct {
+	Tenant string              `yaml:"tenant"`
+	Keys   []encryptionKeyYAML `yaml:"keys"`
+}
+
+type encryptionConfigYAML struct {
+	KMS     *kmsConfigYAML             `yaml:"kms,omitempty"`
+	Tenants []tenantEncryptionKeysYAML `yaml:"tenants"`
+}
+
+// tenantEncryptionKeys contains encryption keys for a single tenant.
+type tenantEncryptionKeys struct {
+	// keys contains the keys for the tenant. The first key is used for encrypting new blocks,
+	// while the remaining keys are used only for decrypting blocks encrypted with them.
+	keys []*encryptionKey
+}
+
+// encryptionKey is a key for encrypting blocks of a single tenant.
+type encryptionKey struct {
+	// id is the id of the key stored in blockHeader. It is always non-zero.
+	id uint32
+
+	key []byte
+}
+
+// ParseEncryptionConfig parses encryption config from data.
+func ParseEncryptionConfig(data []byte) (*EncryptionConfig, error) {
+	var cfgYAML encryptionConfigYAML
+	if err := yaml.UnmarshalStrict(data, &cfgYAML); err != nil {
+		return nil, err
+	}
+	var kc *kmsClient
+	if cfgYAML.KMS != nil {
+		c, err := newKMSClient(cfgYAML.KMS)
+		if err != nil {
+			return nil, fmt.Errorf("cannot initialize kms: %w", err)
+		}
+		kc = c
+	}
+	tenants := make(map[TenantID]*tenantEncryptionKeys, len(cfgYAML.Tenants))
+	for i := range cfgYAML.Tenants {
+		ty := &cfgYAML.Tenants[i]
+		tenantID, err := ParseTenantID(ty.Tenant)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse tenant at tenants #%d: %w", i+1, err)
+		}
+		if _, ok := tenants[tenantID]; ok {
+			return nil, fmt.Errorf("duplicate tenant %q at tenants #%d", ty.Tenant, i+1)
+		}
+		tks, err := newTenantEncryptionKeys(ty.Keys, kc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse keys for tenant %q: %w", ty.Tenant, err)
+		}
+		tenants[tenantID] = tks
+	}
+	return &EncryptionConfig{
+		tenants: tenants,
+	}, nil
+}
+
+func newTenantEncryptionKeys(kys []encryptionKeyYAML, kc *kmsClient) (*tenantEncryptionKeys, error) {
+	if len(kys) == 0 {
+		return nil, fmt.Errorf("missing keys")
+	}
+	keys := make([]*encryptionKey, 0, len(kys))
+	for i := range kys {
+		ek, err := newEncryptionKey(&kys[i], kc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse key #%d: %w", i+1, err)
+		}
+		for _, k := range keys {
+			if k.id == ek.id {
+				return nil, fmt.Errorf("key #%d duplicates the previous key", i+1)
+			}
+		}
+		keys = append(keys, ek)
+	}
+	return &tenantEncryptionKeys{
+		keys: keys,
+	}, nil
+}
+
+func newEncryptionKey(ky *encryptionKeyYAML, kc *kmsClient) (*encryptionKey, error) {
+	n := 0
+	for _, v := range []string{ky.Key, ky.KeyFile, ky.KMSEncryptedKey} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("exactly one of key, key_file or kms_encrypted_key must be set")
+	}
+	key, err := getEncryptionKeyBytes(ky, kc)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("unexpected key length; got %d bytes; want 32 bytes", len(key))
+	}
+
+	h := sha256.Sum256(key)
+	id := binary.BigEndian.Uint32(h[:4])
+	if id == 0 {
+		id = 1
+	}
+	return &encryptionKey{
+		id:  id,
+		key: key,
+	}, nil
+}
+
+func getEncryptionKeyBytes(ky *encryptionKeyYAML, kc *kmsClient) ([]byte, error) {
+	if ky.KMSEncryptedKey != "" {
+		if kc == nil {
+			return nil, fmt.Errorf("missing kms section for decrypting kms_encrypted_key")
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(ky.KMSEncryptedKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode base64-encoded kms_encrypted_key: %w", err)
+		}
+		key, err := kc.decrypt(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt kms_encrypted_key: %w", err)
+		}
+		return key, nil
+	}
+
+	keyHex := []byte(ky.Key)
+	if ky.KeyFile != "" {
+		data, err := fscore.ReadFileOrHTTP(ky.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read key_file: %w", err)
+		}
+		keyHex = bytes.TrimSpace(data)
+	}
+	key := make([]byte, hex.DecodedLen(len(keyHex)))
+	if _, err := hex.Decode(key, keyHex); err != nil {
+		return nil, fmt.Errorf("cannot decode hex-encoded key: %w", err)
+	}
+	return key, nil
+}
+
+// getKeyForEncryption returns the key for encrypting new blocks for the given tenantID.
+//
+// nil is returned if the blocks for the given tenantID mustn't be encrypted.
+func (ec *EncryptionConfig) getKeyForEncryption(tenantID TenantID) *encryptionKey {
+	if ec == nil {
+		return nil
+	}
+	tks := ec.tenants[tenantID]
+	if tks == nil {
+		return nil
+	}
+	return tks.keys[0]
+}
+
+// getKeyForDecryption returns the key with the given keyID for the given tenantID.
+//
+// nil is returned if there is no such key.
+func (ec *EncryptionConfig) getKeyForDecryption(tenantID TenantID, keyID uint32) *encryptionKey {
+	if ec == nil {
+		return nil
+	}
+	tks := ec.tenants[tenantID]
+	if tks == nil {
+		return nil
+	}
+	for _, k := range tks.keys {
+		if k.id == keyID {
+			return k
+		}
+	}
+	return nil
+}
+
+// encryptionSaltSize is the size of the random salt used for deriving the per-block key.
+const encryptionSaltSize = 16
+
+// blockCipher encrypts and decrypts data for a single block with AES-256-GCM.
+//
+// Every block is encrypted with its own key derived from the tenant key and the random salt stored in blockHeader.
+// This avoids hitting the limit on the number of random nonces per key, since the tenant key is used for many blocks.
+type blockCipher struct {
+	aead cipher.AEAD
+
+	// ad is additional authenticated data, which binds the encrypted data to the tenant.
+	ad []byte
+}
+
+// newBlockCipherForWrite initializes bh for encrypting the block according to ec and returns cipher for the block.
+//
+// nil is returned if the block mustn't be encrypted.
+func newBlockCipherForWrite(ec *EncryptionConfig, bh *blockHeader) *blockCipher {
+	ek := ec.getKeyForEncryption(bh.streamID.tenantID)
+	if ek == nil {
+		return nil
+	}
+	bh.encryptionKeyID = ek.id
+	if _, err := rand.Read(bh.encryptionSalt[:]); err != nil {
+		logger.Panicf("FATAL: cannot generate random salt for block encryption: %s", err)
+	}
+	return newBlockCipher(ek, bh)
+}
+
+// newBlockCipherForRead returns cipher for decrypting the block with the given bh.
+//
+// nil is returned if the block isn't encrypted.
+// An error is returned if ec doesn't contain the key for decrypting the block.
+func newBlockCipherForRead(ec *EncryptionConfig, bh *blockHeader) (*blockCipher, error) {
+	if bh.encryptionKeyID == 0 {
+		return nil, nil
+	}
+	tenantID := bh.streamID.tenantID
+	ek := ec.getKeyForDecryption(tenantID, bh.encryptionKeyID)
+	if ek == nil {
+		return nil, newMissingEncryptionKeyError(tenantID, bh.encryptionKeyID)
+	}
+	return newBlockCipher(ek, bh), nil
+}
+
+// checkKeysForDecryption verifies whether ec contains all the keys needed for decrypting blocks encrypted with the given keys.
+func (ec *EncryptionConfig) checkKeysForDecryption(keys []partEncryptionKey) error {
+	for _, k := range keys {
+		if ec.getKeyForDecryption(k.TenantID, k.KeyID) == nil {
+			return newMissingEncryptionKeyError(k.TenantID, k.KeyID)
+		}
+	}
+	return nil
+}
+
+func newMissingEncryptionKeyError(tenantID TenantID, keyID uint32) error {
+	return fmt.Errorf("cannot find the key with id=%d for decrypting blocks for tenant %s; make sure the key is present at -storage.encryptionConfig",
+		keyID, tenantID.String())
+}
+
+// partEncryptionKey identifies the key used for encrypting blocks in a part.
+type partEncryptionKey struct {
+	// TenantID is the tenant the blocks belong to.
+	TenantID TenantID
+
+	// KeyID is the id of the key used for encrypting the blocks.
+	KeyID uint32
+}
+
+func (k *partEncryptionKey) less(other *partEncryptionKey) bool {
+	if !k.TenantID.equal(&other.TenantID) {
+		return k.TenantID.less(&other.TenantID)
+	}
+	return k.KeyID < other.KeyID
+}
+
+func newBlockCipher(ek *encryptionKey, bh *blockHeader) *blockCipher {
+	mac := hmac.New(sha256.New, ek.key)
+	mac.Write(bh.encryptionSalt[:])
+	blockKey := mac.Sum(nil)
+
+	c, err := aes.NewCipher(blockKey)
+	if err != nil {
+		logger.Panicf("BUG: cannot create AES cipher: %s", err)
+	}
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		logger.Panicf("BUG: cannot create GCM cipher: %s", err)
+	}
+	return &blockCipher{
+		aead: aead,
+		ad:   bh.streamID.tenantID.marshal(nil),
+	}
+}
+
+// encryptionOverhead returns the number of bytes added to the encrypted data by bc.
+func (bc *blockCipher) encryptionOverhead() int {
+	return bc.aead.NonceSize() + bc.aead.Overhead()
+}
+
+// encrypt appends the encrypted src to dst and returns the result.
+func (bc *blockCipher) encrypt(dst, src []byte) []byte {
+	nonceSize := bc.aead.NonceSize()
+	dstLen := len(dst)
+	dst = append(dst, make([]byte, nonceSize)...)
+	nonce := dst[dstLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		logger.Panicf("FATAL: cannot generate random nonce for block encryption: %s", err)
+	}
+	return bc.aead.Seal(dst, nonce, src, bc.ad)
+}
+
+// decrypt appends the decrypted src to dst and returns the result.
+func (bc *blockCipher) decrypt(dst, src []byte) ([]byte, error) {
+	nonceSize := bc.aead.NonceSize()
+	if len(src) < nonceSize {
+		return dst, fmt.Errorf("too short encrypted data; got %d bytes; want at least %d bytes", len(src), nonceSize)
+	}
+	dst, err := bc.aead.Open(dst, src[:nonceSize], src[nonceSize:], bc.ad)
+	if err != nil {
+		return dst, fmt.Errorf("cannot decrypt data: %w", err)
+	}
+	return dst, nil
+}
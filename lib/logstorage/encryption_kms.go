@@ -0,0 +1,99 @@
+package logstorage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+)
+
+// kmsConfigYAML is YAML representation for the AWS KMS config used for decrypting tenant keys.
+//
+// See https://docs.victoriametrics.com/victorialogs/#encryption-at-rest
+type kmsConfigYAML struct {
+	Region    string `yaml:"region,omitempty"`
+	Endpoint  string `yaml:"endpoint,omitempty"`
+	RoleARN   string `yaml:"role_arn,omitempty"`
+	AccessKey string `yaml:"access_key,omitempty"`
+	SecretKey string `yaml:"secret_key,omitempty"`
+}
+
+// kmsClient decrypts tenant keys encrypted with AWS KMS.
+type kmsClient struct {
+	cfg    *awsapi.Config
+	apiURL string
+	c      *http.Client
+}
+
+func newKMSClient(ky *kmsConfigYAML) (*kmsClient, error) {
+	cfg, err := awsapi.NewConfig("", "", ky.Region, ky.RoleARN, ky.AccessKey, ky.SecretKey, "kms")
+	if err != nil {
+		return nil, err
+	}
+	apiURL := ky.Endpoint
+	if apiURL == "" {
+		apiURL = fmt.Sprintf("https://kms.%s.amazonaws.com/", cfg.GetRegion())
+	}
+	if !strings.Contains(apiURL, "://") {
+		apiURL = "https://" + apiURL
+	}
+	if !strings.HasSuffix(apiURL, "/") {
+		apiURL += "/"
+	}
+	return &kmsClient{
+		cfg:    cfg,
+		apiURL: apiURL,
+		c: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+// decrypt decrypts the given ciphertext via AWS KMS Decrypt API.
+//
+// See https://docs.aws.amazon.com/kms/latest/APIReference/API_Decrypt.html
+func (kc *kmsClient) decrypt(ciphertext []byte) ([]byte, error) {
+	reqBody, err := json.Marshal(map[string]string{
+		"CiphertextBlob": base64.StdEncoding.EncodeToString(ciphertext),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("BUG: cannot marshal KMS request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, kc.apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %w", kc.apiURL, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	if err := kc.cfg.SignRequest(req, awsapi.HashHex(reqBody)); err != nil {
+		return nil, fmt.Errorf("cannot sign request to %q: %w", kc.apiURL, err)
+	}
+	resp, err := kc.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform request to %q: %w", kc.apiURL, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %q: %w", kc.apiURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for %q; got %d; want %d; response body: %q", kc.apiURL, resp.StatusCode, http.StatusOK, data)
+	}
+	var r struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("cannot parse response from %q: %w", kc.apiURL, err)
+	}
+	if len(r.Plaintext) == 0 {
+		return nil, fmt.Errorf("missing Plaintext in the response from %q", kc.apiURL)
+	}
+	return r.Plaintext, nil
+}
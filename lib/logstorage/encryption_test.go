@@ -0,0 +1,564 @@
+package logstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+const (
+	testEncryptionKey1 = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	testEncryptionKey2 = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+)
+
+func TestParseEncryptionConfig_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := ParseEncryptionConfig([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown field
+	f(`foo: bar`)
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+    foo: bar
+`)
+
+	// invalid tenant
+	f(`
+tenants:
+- tenant: foo
+  keys:
+  - key: ` + testEncryptionKey1 + `
+`)
+
+	// duplicate tenant
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey2 + `
+`)
+
+	// missing keys
+	f(`
+tenants:
+- tenant: "0:0"
+`)
+
+	// missing key and key_file
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - {}
+`)
+
+	// both key and key_file
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+    key_file: /foo/bar
+`)
+
+	// missing key_file
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key_file: /non-existing-file
+`)
+
+	// invalid hex key
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: foobar
+`)
+
+	// too short key
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: 0001020304050607
+`)
+
+	// duplicate key
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+  - key: ` + testEncryptionKey1 + `
+`)
+
+	// kms_encrypted_key without kms section
+	f(`
+tenants:
+- tenant: "0:0"
+  keys:
+  - kms_encrypted_key: Zm9vYmFy
+`)
+
+	// both key and kms_encrypted_key
+	f(`
+kms:
+  region: us-east-1
+  access_key: foo
+  secret_key: bar
+tenants:
+- tenant: "0:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+    kms_encrypted_key: Zm9vYmFy
+`)
+}
+
+func TestParseEncryptionConfig_Success(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(testEncryptionKey2+"\n"), 0600); err != nil {
+		t.Fatalf("cannot write key file: %s", err)
+	}
+	ec, err := ParseEncryptionConfig([]byte(`
+tenants:
+- tenant: "12:34"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+  - key_file: ` + keyFile + `
+- tenant: "0:0"
+  keys:
+  - key_file: ` + keyFile + `
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tenantID := TenantID{AccountID: 12, ProjectID: 34}
+	ek1 := ec.getKeyForEncryption(tenantID)
+	if ek1 == nil {
+		t.Fatalf("missing encryption key for tenant %s", tenantID.String())
+	}
+	ek2 := ec.getKeyForEncryption(TenantID{})
+	if ek2 == nil {
+		t.Fatalf("missing encryption key for tenant %s", (&TenantID{}).String())
+	}
+	if ek1.id == ek2.id {
+		t.Fatalf("distinct keys must have distinct ids; got %d", ek1.id)
+	}
+
+	// The second key for the tenant must be used only for decryption
+	if ek := ec.getKeyForDecryption(tenantID, ek2.id); ek == nil || !bytes.Equal(ek.key, ek2.key) {
+		t.Fatalf("missing decryption key with id=%d for tenant %s", ek2.id, tenantID.String())
+	}
+	if ek := ec.getKeyForDecryption(TenantID{}, ek1.id); ek != nil {
+		t.Fatalf("unexpected decryption key with id=%d for tenant %s", ek1.id, (&TenantID{}).String())
+	}
+
+	// Blocks for tenants without keys mustn't be encrypted
+	if ek := ec.getKeyForEncryption(TenantID{AccountID: 1}); ek != nil {
+		t.Fatalf("unexpected encryption key for the tenant without keys")
+	}
+	var ecNil *EncryptionConfig
+	if ek := ecNil.getKeyForEncryption(tenantID); ek != nil {
+		t.Fatalf("unexpected encryption key for nil config")
+	}
+}
+
+func TestParseEncryptionConfig_KMS(t *testing.T) {
+	key1, err := hex.DecodeString(testEncryptionKey1)
+	if err != nil {
+		t.Fatalf("cannot decode key: %s", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "TrentService.Decrypt" {
+			http.Error(w, fmt.Sprintf("unexpected X-Amz-Target=%q", target), http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=foo/") {
+			http.Error(w, "missing signature", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			CiphertextBlob []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if string(req.CiphertextBlob) != "encrypted-key-1" {
+			http.Error(w, "InvalidCiphertextException", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"Plaintext":%q}`, base64.StdEncoding.EncodeToString(key1))
+	}))
+	defer srv.Close()
+
+	f := func(encryptedKey string, resultExpected bool) {
+		t.Helper()
+
+		ec, err := ParseEncryptionConfig([]byte(`
+kms:
+  endpoint: ` + srv.URL + `
+  region: us-east-1
+  access_key: foo
+  secret_key: bar
+tenants:
+- tenant: "1:2"
+  keys:
+  - kms_encrypted_key: ` + base64.StdEncoding.EncodeToString([]byte(encryptedKey)) + `
+`))
+		if !resultExpected {
+			if err == nil {
+				t.Fatalf("expecting non-nil error")
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ek := ec.getKeyForEncryption(TenantID{AccountID: 1, ProjectID: 2})
+		if ek == nil {
+			t.Fatalf("missing encryption key")
+		}
+		if !bytes.Equal(ek.key, key1) {
+			t.Fatalf("unexpected key; got %X; want %X", ek.key, key1)
+		}
+	}
+
+	f("encrypted-key-1", true)
+	f("unknown-key", false)
+}
+
+func TestBlockCipher(t *testing.T) {
+	ec, err := ParseEncryptionConfig([]byte(`
+tenants:
+- tenant: "1:2"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var bh blockHeader
+	bh.streamID.tenantID = TenantID{AccountID: 1, ProjectID: 2}
+	bcWrite := newBlockCipherForWrite(ec, &bh)
+	if bcWrite == nil {
+		t.Fatalf("missing cipher for encrypted tenant")
+	}
+	if bh.encryptionKeyID == 0 {
+		t.Fatalf("encryptionKeyID must be set for encrypted block")
+	}
+
+	data := []byte("foo bar baz")
+	encrypted := bcWrite.encrypt(nil, data)
+	if bytes.Contains(encrypted, data) {
+		t.Fatalf("encrypted data mustn't contain the original data")
+	}
+	if n := len(encrypted) - len(data); n != bcWrite.encryptionOverhead() {
+		t.Fatalf("unexpected encryption overhead; got %d bytes; want %d bytes", n, bcWrite.encryptionOverhead())
+	}
+
+	bcRead, err := newBlockCipherForRead(ec, &bh)
+	if err != nil {
+		t.Fatalf("cannot create cipher for reading: %s", err)
+	}
+	decrypted, err := bcRead.decrypt(nil, encrypted)
+	if err != nil {
+		t.Fatalf("cannot decrypt data: %s", err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Fatalf("unexpected decrypted data; got %q; want %q", decrypted, data)
+	}
+
+	// Corrupted data mustn't be decrypted
+	encrypted[len(encrypted)-1]++
+	if _, err := bcRead.decrypt(nil, encrypted); err == nil {
+		t.Fatalf("expecting non-nil error when decrypting corrupted data")
+	}
+	encrypted[len(encrypted)-1]--
+
+	// Data encrypted for one block mustn't be decrypted with the cipher for another block
+	var bhOther blockHeader
+	bhOther.copyFrom(&bh)
+	bhOther.encryptionSalt[0]++
+	bcOther, err := newBlockCipherForRead(ec, &bhOther)
+	if err != nil {
+		t.Fatalf("cannot create cipher for reading: %s", err)
+	}
+	if _, err := bcOther.decrypt(nil, encrypted); err == nil {
+		t.Fatalf("expecting non-nil error when decrypting data with the cipher for another block")
+	}
+
+	// Blocks encrypted with missing keys cannot be decrypted
+	ecOther, err := ParseEncryptionConfig([]byte(`
+tenants:
+- tenant: "1:2"
+  keys:
+  - key: ` + testEncryptionKey2 + `
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := newBlockCipherForRead(ecOther, &bh); err == nil {
+		t.Fatalf("expecting non-nil error when the key is missing")
+	}
+	if _, err := newBlockCipherForRead(nil, &bh); err == nil {
+		t.Fatalf("expecting non-nil error when the encryption config is missing")
+	}
+	keys := []partEncryptionKey{{TenantID: bh.streamID.tenantID, KeyID: bh.encryptionKeyID}}
+	if err := ec.checkKeysForDecryption(keys); err != nil {
+		t.Fatalf("unexpected error when checking keys: %s", err)
+	}
+	if err := ecOther.checkKeysForDecryption(keys); err == nil {
+		t.Fatalf("expecting non-nil error when checking missing keys")
+	}
+
+	// Blocks for tenants without keys mustn't be encrypted
+	bh.reset()
+	if bc := newBlockCipherForWrite(ec, &bh); bc != nil {
+		t.Fatalf("unexpected cipher for the tenant without keys")
+	}
+	if bh.encryptionKeyID != 0 {
+		t.Fatalf("unexpected encryptionKeyID for unencrypted block: %d", bh.encryptionKeyID)
+	}
+	if bc, err := newBlockCipherForRead(nil, &bh); err != nil || bc != nil {
+		t.Fatalf("unexpected cipher for unencrypted block; err=%v", err)
+	}
+}
+
+func TestInmemoryPartFormatVersion(t *testing.T) {
+	ec, err := ParseEncryptionConfig([]byte(`
+tenants:
+- tenant: "1:2"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(ec *EncryptionConfig, formatVersionExpected uint) {
+		t.Helper()
+
+		lr := GetLogRows(nil, nil, nil, "")
+		defer PutLogRows(lr)
+		for i := 0; i < 10; i++ {
+			fields := []Field{
+				{
+					Name:  "_msg",
+					Value: fmt.Sprintf("message %d", i),
+				},
+			}
+			lr.MustAdd(TenantID{AccountID: 1, ProjectID: 2}, int64(i), fields, nil)
+		}
+
+		mp := getInmemoryPart()
+		defer putInmemoryPart(mp)
+		mp.mustInitFromRowsWithConfig(lr, bloomFilterBitsPerItem, ec)
+		if mp.ph.FormatVersion != formatVersionExpected {
+			t.Fatalf("unexpected part format version; got %d; want %d", mp.ph.FormatVersion, formatVersionExpected)
+		}
+	}
+
+	// Parts without encryption must be readable by older releases.
+	f(nil, partFormatUnencryptedVersion)
+
+	f(ec, partFormatLatestVersion)
+}
+
+func TestStorageEncryption(t *testing.T) {
+	t.Parallel()
+
+	path := t.Name()
+
+	mustParseEncryptionConfig := func(data string) *EncryptionConfig {
+		t.Helper()
+		ec, err := ParseEncryptionConfig([]byte(data))
+		if err != nil {
+			t.Fatalf("cannot parse encryption config: %s", err)
+		}
+		return ec
+	}
+
+	encryptedTenantID := TenantID{AccountID: 1}
+	plainTenantID := TenantID{AccountID: 2}
+
+	const rowsPerTenant = 1000
+	baseTimestamp := time.Now().UnixNano() - 3600*1e9
+	addRows := func(s *Storage, tenantID TenantID, prefix string, offset int) {
+		lr := GetLogRows([]string{"job"}, nil, nil, "")
+		for i := 0; i < rowsPerTenant; i++ {
+			fields := []Field{
+				{
+					Name:  "job",
+					Value: "app",
+				},
+				{
+					Name:  "_msg",
+					Value: fmt.Sprintf("%s message %d", prefix, offset+i),
+				},
+				{
+					Name:  "const_field",
+					Value: prefix + " const value",
+				},
+			}
+			lr.MustAdd(tenantID, baseTimestamp+int64(offset+i), fields, nil)
+		}
+		s.MustAddRows(lr)
+		PutLogRows(lr)
+	}
+
+	checkRows := func(s *Storage, tenantID TenantID, prefix string, rowsExpected int) {
+		t.Helper()
+
+		q := mustParseQuery(`"` + prefix + ` message" const_field:"` + prefix + ` const value"`)
+		var rowsCount int
+		var mu sync.Mutex
+		writeBlock := func(_ uint, db *DataBlock) {
+			mu.Lock()
+			rowsCount += db.RowsCount()
+			mu.Unlock()
+		}
+		if err := s.RunQuery(context.Background(), nil, []TenantID{tenantID}, q, writeBlock); err != nil {
+			t.Fatalf("unexpected error in query: %s", err)
+		}
+		if rowsCount != rowsExpected {
+			t.Fatalf("unexpected number of rows for tenant %s; got %d; want %d", tenantID.String(), rowsCount, rowsExpected)
+		}
+	}
+
+	// Store logs for encrypted and unencrypted tenants.
+	sc := &StorageConfig{
+		Retention: 24 * time.Hour,
+		Encryption: mustParseEncryptionConfig(`
+tenants:
+- tenant: "1:0"
+  keys:
+  - key: ` + testEncryptionKey1 + `
+`),
+	}
+	s := MustOpenStorage(path, sc)
+	addRows(s, encryptedTenantID, "encrypted", 0)
+	addRows(s, plainTenantID, "plain", 0)
+	s.debugFlush()
+
+	// Logs must be readable from in-memory parts and file parts.
+	checkRows(s, encryptedTenantID, "encrypted", rowsPerTenant)
+	checkRows(s, plainTenantID, "plain", rowsPerTenant)
+	s.MustClose()
+
+	// Stored data mustn't contain logs for the encrypted tenant in plaintext.
+	plainFound := false
+	err := filepath.WalkDir(path, func(filePath string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("encrypted const value")) {
+			return fmt.Errorf("unexpected plaintext log field for encrypted tenant at %s", filePath)
+		}
+		if bytes.Contains(data, []byte("plain const value")) {
+			plainFound = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !plainFound {
+		t.Fatalf("missing plaintext log field for unencrypted tenant in the stored data")
+	}
+
+	// Rotate the key. Previously stored logs must be decrypted with the old key.
+	sc.Encryption = mustParseEncryptionConfig(`
+tenants:
+- tenant: "1:0"
+  keys:
+  - key: ` + testEncryptionKey2 + `
+  - key: ` + testEncryptionKey1 + `
+`)
+	s = MustOpenStorage(path, sc)
+	checkRows(s, encryptedTenantID, "encrypted", rowsPerTenant)
+	addRows(s, encryptedTenantID, "encrypted", rowsPerTenant)
+	s.debugFlush()
+	checkRows(s, encryptedTenantID, "encrypted", 2*rowsPerTenant)
+	checkRows(s, plainTenantID, "plain", rowsPerTenant)
+
+	// Merge the logs encrypted with the old and the new keys.
+	s.MustForceMerge("")
+	checkRows(s, encryptedTenantID, "encrypted", 2*rowsPerTenant)
+	s.MustClose()
+
+	// The merged logs must be encrypted with the new key.
+	sc.Encryption = mustParseEncryptionConfig(`
+tenants:
+- tenant: "1:0"
+  keys:
+  - key: ` + testEncryptionKey2 + `
+`)
+	s = MustOpenStorage(path, sc)
+	checkRows(s, encryptedTenantID, "encrypted", 2*rowsPerTenant)
+	s.MustClose()
+
+	// Parts encrypted with missing keys must be skipped during the search and background merges.
+	sc.Encryption = nil
+	s = MustOpenStorage(path, sc)
+	checkRows(s, encryptedTenantID, "encrypted", 0)
+	addRows(s, plainTenantID, "plain", rowsPerTenant)
+	s.debugFlush()
+	s.MustForceMerge("")
+	checkRows(s, plainTenantID, "plain", 2*rowsPerTenant)
+	var ss StorageStats
+	s.UpdateStats(&ss)
+	if ss.EncryptedPartsSkipped == 0 {
+		t.Fatalf("expecting non-zero EncryptedPartsSkipped")
+	}
+	s.MustClose()
+
+	// The skipped parts must become searchable after adding the missing key.
+	sc.Encryption = mustParseEncryptionConfig(`
+tenants:
+- tenant: "1:0"
+  keys:
+  - key: ` + testEncryptionKey2 + `
+`)
+	s = MustOpenStorage(path, sc)
+	checkRows(s, encryptedTenantID, "encrypted", 2*rowsPerTenant)
+	checkRows(s, plainTenantID, "plain", 2*rowsPerTenant)
+	s.MustClose()
+
+	fs.MustRemoveAll(path)
+}
@@ -15,8 +15,9 @@ const (
 	messageValuesFilename      = "message_values.bin"
 	messageBloomFilename       = "message_bloom.bin"
 
-	metadataFilename = "metadata.json"
-	partsFilename    = "parts.json"
+	metadataFilename          = "metadata.json"
+	partsFilename             = "parts.json"
+	bloomFilterParamsFilename = "bloom_filter_params.json"
 
 	indexdbDirname    = "indexdb"
 	datadbDirname     = "datadb"
@@ -183,6 +183,7 @@ func matchValuesAnyPhrase(bs *blockSearch, ch *columnHeader, bm *bitmap, phrases
 			sb.a = append(sb.a, phrases[i])
 		}
 	}
+	bs.addBloomFilterCheck(len(sb.a) > 0)
 	if len(sb.a) > 0 {
 		values := bs.getValuesForColumn(ch)
 		bm.forEachSetBit(func(idx int) bool {
@@ -209,9 +209,11 @@ func matchBloomFilterAnyTokenSet(bs *blockSearch, ch *columnHeader, commonTokens
 	bf := bs.getBloomFilterForColumn(ch)
 	for _, tokens := range tokenSets {
 		if bf.containsAll(tokens) {
+			bs.addBloomFilterCheck(true)
 			return true
 		}
 	}
+	bs.addBloomFilterCheck(false)
 	return false
 }
 
@@ -302,7 +302,9 @@ func matchBloomFilterAllTokens(bs *blockSearch, ch *columnHeader, tokens []uint6
 		return true
 	}
 	bf := bs.getBloomFilterForColumn(ch)
-	return bf.containsAll(tokens)
+	ok := bf.containsAll(tokens)
+	bs.addBloomFilterCheck(ok)
+	return ok
 }
 
 func quoteFieldNameIfNeeded(s string) string {
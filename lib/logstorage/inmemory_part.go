@@ -68,6 +68,14 @@ func (mp *inmemoryPart) reset() {
 
 // mustInitFromRows initializes mp from lr.
 func (mp *inmemoryPart) mustInitFromRows(lr *LogRows) {
+	mp.mustInitFromRowsWithConfig(lr, bloomFilterBitsPerItem, nil)
+}
+
+// mustInitFromRowsWithConfig initializes mp from lr.
+//
+// bitsPerItem is the number of bits per token for bloom filters at mp.
+// ec is an optional config with keys for encrypting blocks at mp.
+func (mp *inmemoryPart) mustInitFromRowsWithConfig(lr *LogRows, bitsPerItem int, ec *EncryptionConfig) {
 	mp.reset()
 
 	sort.Sort(lr)
@@ -75,6 +83,8 @@ func (mp *inmemoryPart) mustInitFromRows(lr *LogRows) {
 
 	bsw := getBlockStreamWriter()
 	bsw.MustInitForInmemoryPart(mp)
+	bsw.setBloomFilterBitsPerItem(bitsPerItem)
+	bsw.setEncryptionConfig(ec)
 	trs := getTmpRows()
 	var sidPrev *streamID
 	uncompressedBlockSizeBytes := uint64(0)
@@ -2,6 +2,7 @@ package logstorage
 
 import (
 	"fmt"
+	"math"
 	"slices"
 	"sort"
 	"sync"
@@ -429,6 +430,19 @@ func (lr *LogRows) Len() int {
 	return len(lr.streamIDs)
 }
 
+// MinTimestamp returns the minimum timestamp across rows in lr.
+//
+// It returns math.MaxInt64 if lr is empty.
+func (lr *LogRows) MinTimestamp() int64 {
+	minTimestamp := int64(math.MaxInt64)
+	for _, ts := range lr.timestamps {
+		if ts < minTimestamp {
+			minTimestamp = ts
+		}
+	}
+	return minTimestamp
+}
+
 // Less returns true if (streamID, timestamp) for row i is smaller than the (streamID, timestamp) for row j
 func (lr *LogRows) Less(i, j int) bool {
 	a := &lr.streamIDs[i]
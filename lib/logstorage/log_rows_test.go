@@ -1,6 +1,7 @@
 package logstorage
 
 import (
+	"math"
 	"reflect"
 	"testing"
 )
@@ -326,3 +327,27 @@ func TestInsertRow_MarshalUnmarshal(t *testing.T) {
 		t.Fatalf("unexpected tail left after unmarshaling InsertRow; len(tail)=%d; tail=%X", len(tail), tail)
 	}
 }
+
+func TestLogRows_MinTimestamp(t *testing.T) {
+	f := func(timestamps []int64, minTimestampExpected int64) {
+		t.Helper()
+
+		lr := GetLogRows(nil, nil, nil, "")
+		defer PutLogRows(lr)
+
+		for _, timestamp := range timestamps {
+			fields := []Field{
+				{Name: "_msg", Value: "foo"},
+			}
+			lr.MustAdd(TenantID{}, timestamp, fields, nil)
+		}
+		if minTimestamp := lr.MinTimestamp(); minTimestamp != minTimestampExpected {
+			t.Fatalf("unexpected min timestamp; got %d; want %d", minTimestamp, minTimestampExpected)
+		}
+	}
+
+	f(nil, math.MaxInt64)
+	f([]int64{123}, 123)
+	f([]int64{30, 10, 20}, 10)
+	f([]int64{-5, 10}, -5)
+}
@@ -2,8 +2,10 @@ package logstorage
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 // RunNetQueryFunc must run q and pass the query results to writeBlock.
@@ -54,13 +56,29 @@ func NewNetQueryRunner(ctx context.Context, tenantIDs []TenantID, q *Query, runN
 // The concurrency limits the number of concurrent goroutines, which process the query results at the local host.
 //
 // netSearch must execute the given query q at remote storage nodes and pass results to writeBlock.
-func (nqr *NetQueryRunner) Run(ctx context.Context, concurrency int, netSearch func(stopCh <-chan struct{}, q *Query, writeBlock WriteDataBlockFunc) error) error {
+// It must add traces from remote storage nodes to qt if it is enabled.
+//
+// Stats for the locally executed pipes are added to qt if it is enabled.
+func (nqr *NetQueryRunner) Run(ctx context.Context, qt *querytracer.Tracer, concurrency int, netSearch func(stopCh <-chan struct{}, qt *querytracer.Tracer, q *Query, writeBlock WriteDataBlockFunc) error) error {
+	qtChild := qt.NewChild("run query %s at storage nodes and %d pipes locally with concurrency=%d", nqr.qRemote, len(nqr.pipesLocal), concurrency)
+
+	var rowsReceived atomic.Uint64
 	search := func(stopCh <-chan struct{}, writeBlockToPipes writeBlockResultFunc) error {
 		writeNetBlock := writeBlockToPipes.newDataBlockWriter()
-		return netSearch(stopCh, nqr.qRemote, writeNetBlock)
+		if qt.Enabled() {
+			writeNetBlockOrig := writeNetBlock
+			writeNetBlock = func(workerID uint, db *DataBlock) {
+				rowsReceived.Add(uint64(db.RowsCount()))
+				writeNetBlockOrig(workerID, db)
+			}
+		}
+		return netSearch(stopCh, qtChild, nqr.qRemote, writeNetBlock)
 	}
 
-	return runPipes(ctx, nqr.pipesLocal, search, nqr.writeBlock, concurrency)
+	err := runPipes(ctx, qtChild, nqr.pipesLocal, search, nqr.writeBlock, concurrency)
+	qtChild.Printf("rows received from storage nodes: %d", rowsReceived.Load())
+	qtChild.Done()
+	return err
 }
 
 // splitQueryToRemoteAndLocal splits q into remotely executed query and into locally executed pipes.
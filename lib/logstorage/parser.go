@@ -401,6 +401,27 @@ func (q *Query) AddFacetsPipe(limit, maxValuesPerField, maxValueLen int, keepCon
 	q.pipes = append(q.pipes, pf)
 }
 
+// AddFieldStatsPipe adds ' field_stats <limit> max_value_len <maxValueLen>' to the end of q.
+func (q *Query) AddFieldStatsPipe(limit, maxValueLen int) {
+	s := "field_stats"
+	if limit > 0 {
+		s += fmt.Sprintf(" %d", limit)
+	}
+	if maxValueLen > 0 {
+		s += fmt.Sprintf(" max_value_len %d", maxValueLen)
+	}
+	lex := newLexer(s, q.timestamp)
+
+	pf, err := parsePipeFieldStats(lex)
+	if err != nil {
+		logger.Panicf("BUG: unexpected error when parsing [%s]: %w", s, err)
+	}
+	if !lex.isEnd() {
+		logger.Panicf("BUG: unexpected tail left after parsing [%s]: %q", s, lex.s)
+	}
+	q.pipes = append(q.pipes, pf)
+}
+
 // AddCountByTimePipe adds '| stats by (_time:step offset off, field1, ..., fieldN) count() hits' to the end of q.
 func (q *Query) AddCountByTimePipe(step, off int64, fields []string) {
 	{
@@ -441,6 +462,38 @@ func (q *Query) AddCountByTimePipe(step, off int64, fields []string) {
 	}
 }
 
+// AddHistogramByTimePipe adds '| stats by (_time:step offset off) histogram(field) buckets | sort by (_time)' to the end of q.
+func (q *Query) AddHistogramByTimePipe(step, off int64, field string) {
+	{
+		// add 'stats by (_time:step offset off) histogram(field) buckets'
+		stepStr := string(marshalDurationString(nil, step))
+		offsetStr := string(marshalDurationString(nil, off))
+		s := fmt.Sprintf("stats by (_time:%s offset %s) histogram(%s) buckets", stepStr, offsetStr, quoteTokenIfNeeded(field))
+		lex := newLexer(s, q.timestamp)
+
+		ps, err := parsePipeStats(lex, true)
+		if err != nil {
+			logger.Panicf("BUG: unexpected error when parsing [%s]: %s", s, err)
+		}
+		if !lex.isEnd() {
+			logger.Panicf("BUG: unexpected tail left after parsing [%s]: %q", s, lex.s)
+		}
+
+		q.pipes = append(q.pipes, ps)
+	}
+
+	{
+		// Add 'sort by (_time)' in order to get consistent order of the results.
+		s := "sort by (_time)"
+		lex := newLexer(s, q.timestamp)
+		ps, err := parsePipeSort(lex)
+		if err != nil {
+			logger.Panicf("BUG: unexpected error when parsing %q: %s", s, err)
+		}
+		q.pipes = append(q.pipes, ps)
+	}
+}
+
 // Clone returns a copy of q at the given timestamp.
 func (q *Query) Clone(timestamp int64) *Query {
 	qStr := q.String()
@@ -471,6 +524,7 @@ func (q *Query) CanReturnLastNResults() bool {
 			*pipeBlocksCount,
 			*pipeFacets,
 			*pipeFieldNames,
+			*pipeFieldStats,
 			*pipeFieldValues,
 			*pipeFirst,
 			*pipeJoin,
@@ -496,6 +550,81 @@ func (q *Query) CanReturnLastNResults() bool {
 	return true
 }
 
+// CanCacheStatsByTime returns true if results for q with `stats by (_time:step)` can be cached independently per every time bucket.
+//
+// This is the case if q ends with `stats` pipe, it doesn't depend on the query timestamp, it doesn't contain subqueries,
+// and all the other pipes process every log entry independently of other log entries without touching the _time field.
+func (q *Query) CanCacheStatsByTime() bool {
+	if q.opts.ignoreGlobalTimeFilter != nil && *q.opts.ignoreGlobalTimeFilter {
+		return false
+	}
+	if hasFilterTime(q.f) {
+		return false
+	}
+
+	queriesCount := 0
+	q.visitSubqueries(func(_ *Query) {
+		queriesCount++
+	})
+	if queriesCount > 1 {
+		return false
+	}
+
+	if getLastPipeStatsIdx(q.pipes) < 0 {
+		return false
+	}
+	for _, p := range q.pipes {
+		switch t := p.(type) {
+		case *pipeStats:
+			for i := range t.funcs {
+				if iff := t.funcs[i].iff; iff != nil && hasFilterTime(iff.f) {
+					return false
+				}
+			}
+			// The pipe may refer _time in by(...) list, since the _time:step is added there.
+			continue
+		case *pipeFields:
+			if !t.containsStar {
+				return false
+			}
+		case *pipeCollapseNums,
+			*pipeCopy,
+			*pipeDelete,
+			*pipeDropEmptyFields,
+			*pipeExtract,
+			*pipeExtractRegexp,
+			*pipeFilter,
+			*pipeFormat,
+			*pipeHash,
+			*pipeJSONArrayLen,
+			*pipeLen,
+			*pipeMath,
+			*pipePackJSON,
+			*pipePackLogfmt,
+			*pipeRename,
+			*pipeReplace,
+			*pipeReplaceRegexp,
+			*pipeUnpackWords,
+			*pipeUnroll:
+			// These pipes process every log entry independently of other log entries.
+		default:
+			return false
+		}
+		if strings.Contains(p.String(), "_time") {
+			// The pipe may modify the _time field or it may contain time filter, which depends on the query timestamp.
+			return false
+		}
+	}
+	return true
+}
+
+func hasFilterTime(f filter) bool {
+	return visitFilter(f, func(f filter) bool {
+		_, ok := f.(*filterTime)
+		return ok
+	})
+}
+
 // GetFilterTimeRange returns filter time range for the given q.
 func (q *Query) GetFilterTimeRange() (int64, int64) {
 	switch t := q.f.(type) {
@@ -1362,6 +1362,11 @@ func TestParseQuery_Success(t *testing.T) {
 	f(`foo | facets 12`, `foo | facets 12`)
 	f(`foo | facets 12 max_values_per_field 20_000`, `foo | facets 12 max_values_per_field 20000`)
 
+	// field_stats pipe
+	f(`foo | field_stats`, `foo | field_stats`)
+	f(`foo | field_stats 5`, `foo | field_stats`)
+	f(`foo | field_stats 12 max_value_len 1_000`, `foo | field_stats 12 max_value_len 1000`)
+
 	// field_names pipe
 	f(`foo | field_names as x`, `foo | field_names as x`)
 	f(`foo | field_names y`, `foo | field_names as y`)
@@ -2723,6 +2728,7 @@ func TestQueryCanReturnLastNResults(t *testing.T) {
 	f("* | blocks_count", false)
 	f("* | facets", false)
 	f("* | field_names", false)
+	f("* | field_stats", false)
 	f("* | field_values x", false)
 	f("* | top 5 by (x)", false)
 	f("* | join by (x) (foo)", false)
@@ -2743,6 +2749,60 @@ func TestQueryCanReturnLastNResults(t *testing.T) {
 	f("* | delete a, b", true)
 }
 
+func TestQueryCanCacheStatsByTime(t *testing.T) {
+	f := func(qStr string, resultExpected bool) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		result := q.CanCacheStatsByTime()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for CanCacheStatsByTime(%q); got %v; want %v", qStr, result, resultExpected)
+		}
+	}
+
+	f("* | stats count() rows", true)
+	f("error | stats by (host) count() rows, count_uniq(user) users", true)
+	f("* | stats by (_time:1h, host) count() rows", true)
+	f("{app=\"nginx\"} | extract 'status=<status>' | filter status:5* | stats by (status) count() rows", true)
+	f("* | math x*2 as y | stats sum(y) y_sum | filter y_sum:>10", true)
+	f("* | fields * | stats count() if (error) errors", true)
+	f("* | stats by (host) count() rows | stats count() hosts", true)
+	f("_time:day_range[08:00, 18:00) | stats count() rows", true)
+
+	// missing stats pipe
+	f("*", false)
+	f("* | fields foo", false)
+
+	// time filters depend on the query timestamp
+	f("_time:5m | stats count() rows", false)
+	f("error and _time:>1h | stats count() rows", false)
+	f("* | filter _time:5m | stats count() rows", false)
+	f("* | stats count() if (_time:5m) rows", false)
+
+	// subqueries
+	f("user:in(error | fields user) | stats count() rows", false)
+	f("* | join by (x) (foo) | stats count() rows", false)
+	f("* | union (foo) | stats count() rows", false)
+
+	// the global time filter is ignored
+	f("options(ignore_global_time_filter=true) * | stats count() rows", false)
+
+	// pipes, which depend on other log entries
+	f("* | sort by (x) | stats count() rows", false)
+	f("* | limit 10 | stats count() rows", false)
+	f("* | uniq (x) | stats count() rows", false)
+	f("* | stats count() rows | sort by (rows) limit 5", false)
+
+	// _time field may be modified
+	f("* | math _time + 1h as _time | stats count() rows", false)
+	f("* | copy x as _time | stats count() rows", false)
+	f("* | unpack_json | stats count() rows", false)
+	f("* | fields x | stats count() rows", false)
+}
+
 func TestQueryCanLiveTail(t *testing.T) {
 	f := func(qStr string, resultExpected bool) {
 		t.Helper()
@@ -2855,6 +2915,28 @@ func TestQueryGetStatsByFieldsAddGroupingByTime_Success(t *testing.T) {
 	f(`* | by (path) count() requests | by (requests) count() hits | first (hits desc)`, nsecsPerDay, []string{"requests", "_time"}, `* | stats by (path, _time:86400000000000) count(*) as requests | stats by (requests, _time:86400000000000) count(*) as hits | first by (hits desc) partition by (_time)`)
 }
 
+func TestQueryAddHistogramByTimePipe(t *testing.T) {
+	f := func(qStr string, step, offset int64, field, qExpected string) {
+		t.Helper()
+
+		q, err := ParseQuery(qStr)
+		if err != nil {
+			t.Fatalf("cannot parse [%s]: %s", qStr, err)
+		}
+		q.DropAllPipes()
+		q.AddHistogramByTimePipe(step, offset, field)
+
+		qResult := q.String()
+		if qResult != qExpected {
+			t.Fatalf("unexpected query\ngot\n%s\nwant\n%s", qResult, qExpected)
+		}
+	}
+
+	f(`*`, nsecsPerHour, 0, `duration`, `* | stats by (_time:1h offset 0) histogram(duration) as buckets | sort by (_time)`)
+	f(`error | stats count()`, nsecsPerDay, nsecsPerHour, `req.time`, `error | stats by (_time:1d offset 1h) histogram(req.time) as buckets | sort by (_time)`)
+	f(`*`, nsecsPerMinute, 0, `foo bar`, `* | stats by (_time:1m offset 0) histogram("foo bar") as buckets | sort by (_time)`)
+}
+
 func TestQueryGetStatsByFieldsAddGroupingByTime_Failure(t *testing.T) {
 	f := func(qStr string) {
 		t.Helper()
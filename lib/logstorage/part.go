@@ -3,6 +3,7 @@ package logstorage
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/cespare/xxhash/v2"
 
@@ -38,6 +39,12 @@ type part struct {
 	// indexBlockHeaders contains a list of indexBlockHeader entries for the given part.
 	indexBlockHeaders []indexBlockHeader
 
+	// tenantRowsCounts contains the number of rows per tenant in the given part.
+	//
+	// It is lazily initialized on the first call to getTenantRowsCount.
+	tenantRowsCountsOnce sync.Once
+	tenantRowsCounts     map[TenantID]uint64
+
 	indexFile              fs.MustReadAtCloser
 	columnsHeaderIndexFile fs.MustReadAtCloser
 	columnsHeaderFile      fs.MustReadAtCloser
@@ -192,6 +199,24 @@ func mustClosePart(p *part) {
 	p.pt = nil
 }
 
+// getEncryptionConfig returns the config with keys for decrypting encrypted blocks at p.
+func (p *part) getEncryptionConfig() *EncryptionConfig {
+	if p.pt == nil || p.pt.s == nil {
+		return nil
+	}
+	return p.pt.s.encryptionConfig
+}
+
+// checkEncryptionKeys verifies whether all the keys needed for decrypting blocks at p are available.
+//
+// The part cannot be searched or merged if an error is returned.
+func (p *part) checkEncryptionKeys() error {
+	if len(p.ph.EncryptionKeys) == 0 {
+		return nil
+	}
+	return p.getEncryptionConfig().checkKeysForDecryption(p.ph.EncryptionKeys)
+}
+
 func (p *part) getBloomValuesFileForColumnName(name string) *bloomValuesReaderAt {
 	if name == "" {
 		return &p.messageBloomValues
@@ -224,3 +249,25 @@ func getBloomFilePath(partPath string, shardIdx uint64) string {
 func getValuesFilePath(partPath string, shardIdx uint64) string {
 	return filepath.Join(partPath, valuesFilename) + fmt.Sprintf("%d", shardIdx)
 }
+
+// getTenantRowsCount returns the number of rows for the given tenantID in p.
+//
+// The per-tenant row counts are obtained from block headers without reading the block data,
+// and they are cached, since parts are immutable.
+func (p *part) getTenantRowsCount(tenantID TenantID) uint64 {
+	p.tenantRowsCountsOnce.Do(p.initTenantRowsCounts)
+	return p.tenantRowsCounts[tenantID]
+}
+
+func (p *part) initTenantRowsCounts() {
+	m := make(map[TenantID]uint64)
+	var bhs []blockHeader
+	for i := range p.indexBlockHeaders {
+		bhs = p.indexBlockHeaders[i].mustReadBlockHeaders(bhs[:0], p)
+		for j := range bhs {
+			bh := &bhs[j]
+			m[bh.streamID.tenantID] += bh.rowsCount
+		}
+	}
+	p.tenantRowsCounts = m
+}
@@ -37,6 +37,11 @@ type partHeader struct {
 
 	// BloomValuesShardsCount is the number of (bloom, values) shards in the part.
 	BloomValuesShardsCount uint64
+
+	// EncryptionKeys contains the keys used for encrypting blocks in the part.
+	//
+	// The part cannot be searched or merged if some of these keys are missing at -storage.encryptionConfig.
+	EncryptionKeys []partEncryptionKey `json:",omitempty"`
 }
 
 // reset resets ph for subsequent reuse
@@ -49,6 +54,7 @@ func (ph *partHeader) reset() {
 	ph.MinTimestamp = 0
 	ph.MaxTimestamp = 0
 	ph.BloomValuesShardsCount = 0
+	ph.EncryptionKeys = nil
 }
 
 // String returns string representation for ph.
@@ -14,6 +14,7 @@ import (
 type PartitionStats struct {
 	DatadbStats
 	IndexdbStats
+	BloomFilterStats
 }
 
 type partition struct {
@@ -32,6 +33,12 @@ type partition struct {
 
 	// ddb is the datadb used for the given partition
 	ddb *datadb
+
+	// bloomFilterBitsPerItem is the number of bits per token for bloom filters at the newly created parts.
+	bloomFilterBitsPerItem int
+
+	// bloomFilterStats contains stats for bloom filter checks performed during queries over the given partition.
+	bloomFilterStats partitionBloomFilterStats
 }
 
 // mustCreatePartition creates a partition at the given path.
@@ -66,12 +73,16 @@ func mustOpenPartition(s *Storage, path string) *partition {
 	indexdbPath := filepath.Join(path, indexdbDirname)
 	idb := mustOpenIndexdb(indexdbPath, name, s)
 
+	bfp := mustReadBloomFilterParams(path)
+
 	// Start initializing the partition
 	pt := &partition{
 		s:    s,
 		path: path,
 		name: name,
 		idb:  idb,
+
+		bloomFilterBitsPerItem: bfp.BitsPerItem,
 	}
 
 	// Open datadb
@@ -189,6 +200,7 @@ func (pt *partition) debugFlush() {
 func (pt *partition) updateStats(ps *PartitionStats) {
 	pt.ddb.updateStats(&ps.DatadbStats)
 	pt.idb.updateStats(&ps.IndexdbStats)
+	pt.bloomFilterStats.updateStats(&ps.BloomFilterStats)
 }
 
 // mustForceMerge runs forced merge for all the parts in pt.
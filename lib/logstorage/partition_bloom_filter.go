@@ -0,0 +1,162 @@
+package logstorage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// BloomFilterStats contains stats for bloom filter checks performed during queries.
+type BloomFilterStats struct {
+	// BloomFilterChecks is the number of bloom filter checks.
+	BloomFilterChecks uint64
+
+	// BloomFilterMisses is the number of bloom filter checks, which allowed skipping the block, since it doesn't contain the needed tokens.
+	BloomFilterMisses uint64
+
+	// BloomFilterBlocksChecked is the number of blocks with at least a single bloom filter check.
+	BloomFilterBlocksChecked uint64
+
+	// BloomFilterBlocksPassed is the number of blocks, which passed all the bloom filter checks.
+	BloomFilterBlocksPassed uint64
+
+	// BloomFilterFalsePositives is the number of blocks, which passed all the bloom filter checks, but didn't contain matching rows.
+	BloomFilterFalsePositives uint64
+}
+
+// partitionBloomFilterStats collects BloomFilterStats for a single partition.
+type partitionBloomFilterStats struct {
+	checks         atomic.Uint64
+	misses         atomic.Uint64
+	blocksChecked  atomic.Uint64
+	blocksPassed   atomic.Uint64
+	falsePositives atomic.Uint64
+}
+
+// addBlock registers the results of bloom filter checks for a single block.
+//
+// checks is the number of bloom filter checks for the block, misses is the number of checks, which allowed skipping the block,
+// while matched must be set to true if the block contains rows matching the query filter.
+func (ps *partitionBloomFilterStats) addBlock(checks, misses int, matched bool) {
+	if checks <= 0 {
+		return
+	}
+	ps.checks.Add(uint64(checks))
+	ps.blocksChecked.Add(1)
+	if misses > 0 {
+		ps.misses.Add(uint64(misses))
+		return
+	}
+	ps.blocksPassed.Add(1)
+	if !matched {
+		ps.falsePositives.Add(1)
+	}
+}
+
+func (ps *partitionBloomFilterStats) updateStats(s *BloomFilterStats) {
+	s.BloomFilterChecks += ps.checks.Load()
+	s.BloomFilterMisses += ps.misses.Load()
+	s.BloomFilterBlocksChecked += ps.blocksChecked.Load()
+	s.BloomFilterBlocksPassed += ps.blocksPassed.Load()
+	s.BloomFilterFalsePositives += ps.falsePositives.Load()
+}
+
+// bloomFilterParams contains bloom filter params for the partition.
+//
+// They are stored in bloomFilterParamsFilename at the partition directory.
+type bloomFilterParams struct {
+	// BitsPerItem is the number of bits per token for bloom filters at the newly created parts.
+	BitsPerItem int
+}
+
+func mustWriteBloomFilterParams(path string, bfp *bloomFilterParams) {
+	data, err := json.Marshal(bfp)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal bloom filter params to JSON: %s", err)
+	}
+	bfpPath := filepath.Join(path, bloomFilterParamsFilename)
+	fs.MustWriteAtomic(bfpPath, data, true)
+}
+
+// mustReadBloomFilterParams reads bloom filter params for the partition at the given path.
+//
+// Default params are returned if the partition has been created without bloom filter params.
+func mustReadBloomFilterParams(path string) *bloomFilterParams {
+	bfp := &bloomFilterParams{
+		BitsPerItem: bloomFilterBitsPerItem,
+	}
+	bfpPath := filepath.Join(path, bloomFilterParamsFilename)
+	if !fs.IsPathExist(bfpPath) {
+		return bfp
+	}
+	data, err := os.ReadFile(bfpPath)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", bfpPath, err)
+	}
+	if err := json.Unmarshal(data, bfp); err != nil {
+		logger.Panicf("FATAL: cannot parse %s: %s", bfpPath, err)
+	}
+	if bfp.BitsPerItem < minBloomFilterBitsPerItem || bfp.BitsPerItem > maxBloomFilterBitsPerItem {
+		logger.Panicf("FATAL: unexpected BitsPerItem=%d at %s; it must be in the range [%d..%d]", bfp.BitsPerItem, bfpPath, minBloomFilterBitsPerItem, maxBloomFilterBitsPerItem)
+	}
+	return bfp
+}
+
+const (
+	// bloomFilterTuningMinBlocks is the minimum number of blocks, which passed bloom filter checks at the partition,
+	// for using the partition stats for bloom filter auto-tuning.
+	bloomFilterTuningMinBlocks = 1000
+
+	// bloomFilterTuningStep is the step for changing the number of bits per token during bloom filter auto-tuning.
+	bloomFilterTuningStep = 4
+
+	// bloomFilterTuningMaxFalsePositiveRate is the share of false positive blocks, after which the number of bits per token is increased.
+	bloomFilterTuningMaxFalsePositiveRate = 0.01
+
+	// bloomFilterTuningMinFalsePositiveRate is the share of false positive blocks, below which the number of bits per token is decreased.
+	bloomFilterTuningMinFalsePositiveRate = 0.001
+)
+
+// getTunedBloomFilterBitsPerItem returns the number of bits per token for bloom filters at a new partition.
+//
+// ptws must be sorted by time. The number of bits per token is derived from the most recent partition with enough query stats:
+// it is increased if queries frequently read blocks, which pass bloom filter checks without containing matching rows,
+// and it is decreased if such blocks are rare, in order to save disk space.
+func getTunedBloomFilterBitsPerItem(ptws []*partitionWrapper) int {
+	if len(ptws) == 0 {
+		return bloomFilterBitsPerItem
+	}
+	for i := len(ptws) - 1; i >= 0; i-- {
+		pt := ptws[i].pt
+		var bfs BloomFilterStats
+		pt.bloomFilterStats.updateStats(&bfs)
+		if bfs.BloomFilterBlocksPassed < bloomFilterTuningMinBlocks {
+			continue
+		}
+		falsePositiveRate := float64(bfs.BloomFilterFalsePositives) / float64(bfs.BloomFilterBlocksPassed)
+		return tuneBloomFilterBitsPerItem(pt.bloomFilterBitsPerItem, falsePositiveRate)
+	}
+
+	// There are no partitions with enough query stats. Keep the number of bits per token from the most recent partition.
+	return ptws[len(ptws)-1].pt.bloomFilterBitsPerItem
+}
+
+func tuneBloomFilterBitsPerItem(bitsPerItem int, falsePositiveRate float64) int {
+	switch {
+	case falsePositiveRate > bloomFilterTuningMaxFalsePositiveRate:
+		bitsPerItem += bloomFilterTuningStep
+	case falsePositiveRate < bloomFilterTuningMinFalsePositiveRate:
+		bitsPerItem -= bloomFilterTuningStep
+	}
+	if bitsPerItem < minBloomFilterBitsPerItem {
+		bitsPerItem = minBloomFilterBitsPerItem
+	}
+	if bitsPerItem > maxBloomFilterBitsPerItem {
+		bitsPerItem = maxBloomFilterBitsPerItem
+	}
+	return bitsPerItem
+}
@@ -0,0 +1,156 @@
+package logstorage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestBloomFilterMarshalHashesWithBitsPerItem(t *testing.T) {
+	f := func(bitsPerItem int) {
+		t.Helper()
+
+		tokens := make([]string, 1000)
+		for i := range tokens {
+			tokens[i] = fmt.Sprintf("token_%d", i)
+		}
+		hashes := tokenizeHashes(nil, tokens)
+		data := bloomFilterMarshalHashesWithBitsPerItem(nil, hashes, bitsPerItem)
+
+		bf := getBloomFilter()
+		defer putBloomFilter(bf)
+		if err := bf.unmarshal(data); err != nil {
+			t.Fatalf("unexpected error when unmarshaling bloom filter: %s", err)
+		}
+		bitsExpected := (len(hashes)*bitsPerItem + 63) / 64 * 64
+		if bits := len(bf.bits) * 64; bits != bitsExpected {
+			t.Fatalf("unexpected number of bits in bloom filter; got %d; want %d", bits, bitsExpected)
+		}
+		if !bf.containsAll(appendTokensHashes(nil, tokens)) {
+			t.Fatalf("containsAll must return true for the added tokens")
+		}
+	}
+
+	f(minBloomFilterBitsPerItem)
+	f(bloomFilterBitsPerItem)
+	f(maxBloomFilterBitsPerItem)
+}
+
+func TestPartitionBloomFilterStats(t *testing.T) {
+	var ps partitionBloomFilterStats
+
+	// block without bloom filter checks
+	ps.addBlock(0, 0, false)
+
+	// block skipped by bloom filter
+	ps.addBlock(2, 1, false)
+
+	// block passed bloom filter checks with matching rows
+	ps.addBlock(3, 0, true)
+
+	// block passed bloom filter checks without matching rows
+	ps.addBlock(1, 0, false)
+
+	var bfs BloomFilterStats
+	ps.updateStats(&bfs)
+	bfsExpected := BloomFilterStats{
+		BloomFilterChecks:         6,
+		BloomFilterMisses:         1,
+		BloomFilterBlocksChecked:  3,
+		BloomFilterBlocksPassed:   2,
+		BloomFilterFalsePositives: 1,
+	}
+	if bfs != bfsExpected {
+		t.Fatalf("unexpected stats\ngot\n%#v\nwant\n%#v", bfs, bfsExpected)
+	}
+}
+
+func TestTuneBloomFilterBitsPerItem(t *testing.T) {
+	f := func(bitsPerItem int, falsePositiveRate float64, resultExpected int) {
+		t.Helper()
+
+		result := tuneBloomFilterBitsPerItem(bitsPerItem, falsePositiveRate)
+		if result != resultExpected {
+			t.Fatalf("unexpected bits per item; got %d; want %d", result, resultExpected)
+		}
+	}
+
+	// the number of false positives is in the allowed range
+	f(16, 0.005, 16)
+
+	// too many false positives
+	f(16, 0.1, 20)
+	f(30, 0.1, maxBloomFilterBitsPerItem)
+	f(maxBloomFilterBitsPerItem, 0.1, maxBloomFilterBitsPerItem)
+
+	// too small number of false positives
+	f(16, 0, 12)
+	f(10, 0.0001, minBloomFilterBitsPerItem)
+	f(minBloomFilterBitsPerItem, 0, minBloomFilterBitsPerItem)
+}
+
+func TestGetTunedBloomFilterBitsPerItem(t *testing.T) {
+	newPartitionWrapper := func(bitsPerItem, blocksPassed, falsePositives int) *partitionWrapper {
+		pt := &partition{
+			bloomFilterBitsPerItem: bitsPerItem,
+		}
+		for i := 0; i < blocksPassed; i++ {
+			pt.bloomFilterStats.addBlock(1, 0, i >= falsePositives)
+		}
+		return &partitionWrapper{
+			pt: pt,
+		}
+	}
+
+	f := func(ptws []*partitionWrapper, resultExpected int) {
+		t.Helper()
+
+		result := getTunedBloomFilterBitsPerItem(ptws)
+		if result != resultExpected {
+			t.Fatalf("unexpected bits per item; got %d; want %d", result, resultExpected)
+		}
+	}
+
+	// no partitions
+	f(nil, bloomFilterBitsPerItem)
+
+	// partitions without enough stats
+	f([]*partitionWrapper{
+		newPartitionWrapper(20, 0, 0),
+		newPartitionWrapper(24, 10, 5),
+	}, 24)
+
+	// the most recent partition with enough stats has too many false positives
+	f([]*partitionWrapper{
+		newPartitionWrapper(16, 2000, 0),
+		newPartitionWrapper(20, 2000, 100),
+		newPartitionWrapper(24, 10, 5),
+	}, 24)
+
+	// the most recent partition with enough stats has too small number of false positives
+	f([]*partitionWrapper{
+		newPartitionWrapper(20, 2000, 100),
+		newPartitionWrapper(16, 2000, 0),
+	}, 12)
+}
+
+func TestBloomFilterParamsReadWrite(t *testing.T) {
+	path := t.Name()
+	fs.MustMkdirFailIfExist(path)
+	defer fs.MustRemoveAll(path)
+
+	// missing params file
+	bfp := mustReadBloomFilterParams(path)
+	if bfp.BitsPerItem != bloomFilterBitsPerItem {
+		t.Fatalf("unexpected default BitsPerItem; got %d; want %d", bfp.BitsPerItem, bloomFilterBitsPerItem)
+	}
+
+	mustWriteBloomFilterParams(path, &bloomFilterParams{
+		BitsPerItem: 24,
+	})
+	bfp = mustReadBloomFilterParams(path)
+	if bfp.BitsPerItem != 24 {
+		t.Fatalf("unexpected BitsPerItem; got %d; want %d", bfp.BitsPerItem, 24)
+	}
+}
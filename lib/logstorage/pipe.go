@@ -176,6 +176,12 @@ func parsePipe(lex *lexer) (pipe, error) {
 			return nil, fmt.Errorf("cannot parse 'field_names' pipe: %w", err)
 		}
 		return pf, nil
+	case lex.isKeyword("field_stats", "field_stats_remote"):
+		pf, err := parsePipeFieldStats(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse 'field_stats' pipe: %w", err)
+		}
+		return pf, nil
 	case lex.isKeyword("field_values"):
 		pf, err := parsePipeFieldValues(lex)
 		if err != nil {
@@ -401,6 +407,7 @@ var pipeNames = func() map[string]struct{} {
 		"extract_regexp",
 		"facets",
 		"field_names",
+		"field_stats", "field_stats_remote",
 		"field_values",
 		"fields", "keep",
 		"filter", "where",
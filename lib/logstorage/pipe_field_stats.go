@@ -0,0 +1,841 @@
+package logstorage
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/valyala/quicktemplate"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/atomicutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+)
+
+// pipeFieldStatsDefaultLimit is the default number of top values pipeFieldStats returns per each log field.
+const pipeFieldStatsDefaultLimit = 5
+
+// pipeFieldStatsDefaultMaxValueLen is the default length of values, which are ignored when tracking top values per each field.
+const pipeFieldStatsDefaultMaxValueLen = 128
+
+// pipeFieldStats processes '| field_stats ...' queries.
+//
+// See https://docs.victoriametrics.com/victorialogs/logsql/#field_stats-pipe
+type pipeFieldStats struct {
+	// limit is the maximum number of top values to return per each field.
+	limit uint64
+
+	// values longer than maxValueLen aren't tracked in top values, since it is hard to use them in faceted search.
+	maxValueLen uint64
+
+	// mode is the mode of pipeFieldStats execution.
+	//
+	// The remote mode returns the exported sketches per each field, while the local mode merges the exported sketches
+	// into the final results. This allows calculating field stats in VictoriaLogs cluster.
+	mode pipeFieldStatsMode
+}
+
+type pipeFieldStatsMode int
+
+const (
+	pipeFieldStatsModeDefault = pipeFieldStatsMode(0)
+	pipeFieldStatsModeRemote  = pipeFieldStatsMode(1)
+	pipeFieldStatsModeLocal   = pipeFieldStatsMode(2)
+)
+
+func (pf *pipeFieldStats) String() string {
+	s := ""
+	switch pf.mode {
+	case pipeFieldStatsModeDefault:
+		s = "field_stats"
+	case pipeFieldStatsModeRemote:
+		s = "field_stats_remote"
+	case pipeFieldStatsModeLocal:
+		s = "field_stats_local"
+	default:
+		logger.Panicf("BUG: unknown mode: %d", pf.mode)
+	}
+	if pf.limit != pipeFieldStatsDefaultLimit {
+		s += fmt.Sprintf(" %d", pf.limit)
+	}
+	if pf.maxValueLen != pipeFieldStatsDefaultMaxValueLen {
+		s += fmt.Sprintf(" max_value_len %d", pf.maxValueLen)
+	}
+	return s
+}
+
+func (pf *pipeFieldStats) splitToRemoteAndLocal(_ int64) (pipe, []pipe) {
+	pRemote := *pf
+	pRemote.mode = pipeFieldStatsModeRemote
+
+	pLocal := *pf
+	pLocal.mode = pipeFieldStatsModeLocal
+
+	return &pRemote, []pipe{&pLocal}
+}
+
+func (pf *pipeFieldStats) canLiveTail() bool {
+	return false
+}
+
+func (pf *pipeFieldStats) updateNeededFields(neededFields, unneededFields fieldsSet) {
+	neededFields.reset()
+	unneededFields.reset()
+
+	if pf.mode == pipeFieldStatsModeLocal {
+		neededFields.add("field_name")
+		neededFields.add("state")
+		return
+	}
+	neededFields.add("*")
+}
+
+func (pf *pipeFieldStats) hasFilterInWithQuery() bool {
+	return false
+}
+
+func (pf *pipeFieldStats) initFilterInValues(_ *inValuesCache, _ getFieldValuesFunc, _ bool) (pipe, error) {
+	return pf, nil
+}
+
+func (pf *pipeFieldStats) visitSubqueries(_ func(q *Query)) {
+	// nothing to do
+}
+
+func (pf *pipeFieldStats) newPipeProcessor(concurrency int, stopCh <-chan struct{}, cancel func(), ppNext pipeProcessor) pipeProcessor {
+	maxStateSize := int64(float64(memory.Allowed()) * 0.2)
+
+	pfp := &pipeFieldStatsProcessor{
+		pf:     pf,
+		stopCh: stopCh,
+		cancel: cancel,
+		ppNext: ppNext,
+
+		maxStateSize: maxStateSize,
+	}
+	pfp.shards.Init = func(shard *pipeFieldStatsProcessorShard) {
+		shard.pfp = pfp
+	}
+	pfp.stateSizeBudget.Store(maxStateSize)
+
+	return pfp
+}
+
+type pipeFieldStatsProcessor struct {
+	pf     *pipeFieldStats
+	stopCh <-chan struct{}
+	cancel func()
+	ppNext pipeProcessor
+
+	shards atomicutil.Slice[pipeFieldStatsProcessorShard]
+
+	maxStateSize    int64
+	stateSizeBudget atomic.Int64
+
+	// errLock protects err.
+	errLock sync.Mutex
+
+	// err is the error occurred when importing the state in local mode.
+	err error
+}
+
+type pipeFieldStatsProcessorShard struct {
+	// pfp points to the parent pipeFieldStatsProcessor.
+	pfp *pipeFieldStatsProcessor
+
+	// m holds stats per every field name.
+	m map[string]*fieldStatsState
+
+	// rowsTotal contains the total number of selected logs.
+	rowsTotal uint64
+
+	// stateSizeBudget is the remaining budget for the whole state size for the shard.
+	// The per-shard budget is provided in chunks from the parent pipeFieldStatsProcessor.
+	stateSizeBudget int
+}
+
+// writeBlock writes br to shard.
+func (shard *pipeFieldStatsProcessorShard) writeBlock(br *blockResult) {
+	cs := br.getColumns()
+	for _, c := range cs {
+		shard.updateStatsForColumn(br, c)
+	}
+	shard.rowsTotal += uint64(br.rowsLen)
+}
+
+func (shard *pipeFieldStatsProcessorShard) updateStatsForColumn(br *blockResult, c *blockResultColumn) {
+	fs := shard.getFieldStats(c.name)
+
+	if c.isConst {
+		v := c.valuesEncoded[0]
+		shard.updateState(fs, v, uint64(br.rowsLen))
+		return
+	}
+	if c.valueType == valueTypeDict {
+		c.forEachDictValueWithHits(br, func(v string, hits uint64) {
+			shard.updateState(fs, v, hits)
+		})
+		return
+	}
+
+	values := c.getValues(br)
+	for i := 0; i < len(values); {
+		// Count the number of identical consecutive values in order to reduce the number of sketch updates.
+		v := values[i]
+		n := i + 1
+		for n < len(values) && values[n] == v {
+			n++
+		}
+		shard.updateState(fs, v, uint64(n-i))
+		i = n
+	}
+}
+
+func (shard *pipeFieldStatsProcessorShard) updateState(fs *fieldStatsState, v string, hits uint64) {
+	if v == "" {
+		// Empty values are equivalent to missing fields.
+		return
+	}
+	fs.hits += hits
+	h := xxhash.Sum64(bytesutil.ToUnsafeBytes(v))
+	shard.stateSizeBudget -= fs.uniq.add(h)
+	if uint64(len(v)) <= shard.pfp.pf.maxValueLen {
+		shard.stateSizeBudget -= fs.top.add(v, hits, shard.pfp.pf.topCapacity())
+	}
+}
+
+func (shard *pipeFieldStatsProcessorShard) getFieldStats(fieldName string) *fieldStatsState {
+	if shard.m == nil {
+		shard.m = make(map[string]*fieldStatsState)
+	}
+	fs, ok := shard.m[fieldName]
+	if !ok {
+		fs = &fieldStatsState{}
+		fieldNameCopy := strings.Clone(fieldName)
+		shard.m[fieldNameCopy] = fs
+		shard.stateSizeBudget -= len(fieldNameCopy) + int(unsafe.Sizeof(fs)+unsafe.Sizeof(*fs))
+	}
+	return fs
+}
+
+// importStates imports states exported by pipeFieldStats in remote mode.
+func (shard *pipeFieldStatsProcessorShard) importStates(br *blockResult) error {
+	cFieldNames := br.getColumnByName("field_name")
+	cStates := br.getColumnByName("state")
+	fieldNames := cFieldNames.getValues(br)
+	states := cStates.getValues(br)
+
+	for i, fieldName := range fieldNames {
+		src := bytesutil.ToUnsafeBytes(states[i])
+		if fieldName == "" {
+			// The row with empty field_name contains the number of selected logs.
+			rowsTotal, n := encoding.UnmarshalVarUint64(src)
+			if n <= 0 || n != len(src) {
+				return fmt.Errorf("cannot unmarshal the number of selected logs from %q", src)
+			}
+			shard.rowsTotal += rowsTotal
+			continue
+		}
+		fs := shard.getFieldStats(fieldName)
+		stateSize, err := fs.importState(src, shard.pfp.pf.topCapacity())
+		if err != nil {
+			return fmt.Errorf("cannot import state for field %q: %w", fieldName, err)
+		}
+		shard.stateSizeBudget -= stateSize
+	}
+	return nil
+}
+
+func (pfp *pipeFieldStatsProcessor) writeBlock(workerID uint, br *blockResult) {
+	if br.rowsLen == 0 {
+		return
+	}
+
+	shard := pfp.shards.Get(workerID)
+
+	for shard.stateSizeBudget < 0 {
+		// steal some budget for the state size from the global budget.
+		remaining := pfp.stateSizeBudget.Add(-stateSizeBudgetChunk)
+		if remaining < 0 {
+			// The state size is too big. Stop processing data in order to avoid OOM crash.
+			if remaining+stateSizeBudgetChunk >= 0 {
+				// Notify worker goroutines to stop calling writeBlock() in order to save CPU time.
+				pfp.cancel()
+			}
+			return
+		}
+		shard.stateSizeBudget += stateSizeBudgetChunk
+	}
+
+	if pfp.pf.mode == pipeFieldStatsModeLocal {
+		if err := shard.importStates(br); err != nil {
+			pfp.setError(err)
+		}
+		return
+	}
+	shard.writeBlock(br)
+}
+
+func (pfp *pipeFieldStatsProcessor) setError(err error) {
+	pfp.errLock.Lock()
+	if pfp.err == nil {
+		pfp.err = err
+	}
+	pfp.errLock.Unlock()
+
+	pfp.cancel()
+}
+
+func (pfp *pipeFieldStatsProcessor) flush() error {
+	if pfp.err != nil {
+		return pfp.err
+	}
+	if n := pfp.stateSizeBudget.Load(); n <= 0 {
+		return fmt.Errorf("cannot calculate [%s], since it requires more than %dMB of memory", pfp.pf.String(), pfp.maxStateSize/(1<<20))
+	}
+
+	// merge state across shards
+	shards := pfp.shards.GetSlice()
+	if len(shards) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*fieldStatsState)
+	rowsTotal := uint64(0)
+	topCapacity := pfp.pf.topCapacity()
+	for _, shard := range shards {
+		if needStop(pfp.stopCh) {
+			return nil
+		}
+		for fieldName, fs := range shard.m {
+			fsDst, ok := m[fieldName]
+			if !ok {
+				m[fieldName] = fs
+				continue
+			}
+			fsDst.mergeState(fs, topCapacity)
+		}
+		rowsTotal += shard.rowsTotal
+	}
+
+	// sort fieldNames
+	fieldNames := make([]string, 0, len(m))
+	for fieldName, fs := range m {
+		if fs.hits == 0 {
+			// Skip fields with empty values only.
+			continue
+		}
+		fieldNames = append(fieldNames, fieldName)
+	}
+	sort.Strings(fieldNames)
+
+	wctx := &pipeFieldStatsWriteContext{
+		pfp: pfp,
+	}
+	if pfp.pf.mode == pipeFieldStatsModeRemote {
+		wctx.writeRow("", encoding.MarshalVarUint64(nil, rowsTotal))
+		for _, fieldName := range fieldNames {
+			if needStop(pfp.stopCh) {
+				return nil
+			}
+			fs := m[fieldName]
+			fs.top.prune(topCapacity)
+			wctx.writeRow(fieldName, fs.exportState(nil))
+		}
+		wctx.flush()
+		return nil
+	}
+
+	limit := pfp.pf.limit
+	for _, fieldName := range fieldNames {
+		if needStop(pfp.stopCh) {
+			return nil
+		}
+		fs := m[fieldName]
+
+		hits := marshalUint64String(nil, fs.hits)
+
+		nullRatio := float64(0)
+		if rowsTotal > fs.hits {
+			nullRatio = float64(rowsTotal-fs.hits) / float64(rowsTotal)
+		}
+		nullRatioStr := marshalFloat64String(nil, nullRatio)
+
+		// The number of distinct values cannot exceed the number of non-empty values.
+		distinctValues := min(fs.uniq.estimate(), fs.hits)
+		distinctValuesStr := marshalUint64String(nil, distinctValues)
+
+		topValues := fs.top.marshalJSON(nil, limit)
+
+		wctx.writeRow(fieldName, hits, nullRatioStr, distinctValuesStr, topValues)
+	}
+	wctx.flush()
+
+	return nil
+}
+
+// topCapacity returns the number of values to track per each field for determining top values.
+//
+// It is bigger than the number of returned top values in order to improve the accuracy of the returned hits.
+func (pf *pipeFieldStats) topCapacity() int {
+	return int(max(pf.limit*10, 100))
+}
+
+type pipeFieldStatsWriteContext struct {
+	pfp *pipeFieldStatsProcessor
+	rcs []resultColumn
+	br  blockResult
+
+	// rowsCount is the number of rows in the current block
+	rowsCount int
+
+	// valuesLen is the total length of values in the current block
+	valuesLen int
+}
+
+func (wctx *pipeFieldStatsWriteContext) writeRow(fieldName string, values ...[]byte) {
+	rcs := wctx.rcs
+
+	if len(rcs) == 0 {
+		rcs = appendResultColumnWithName(rcs, "field_name")
+		if wctx.pfp.pf.mode == pipeFieldStatsModeRemote {
+			rcs = appendResultColumnWithName(rcs, "state")
+		} else {
+			rcs = appendResultColumnWithName(rcs, "hits")
+			rcs = appendResultColumnWithName(rcs, "null_ratio")
+			rcs = appendResultColumnWithName(rcs, "distinct_values")
+			rcs = appendResultColumnWithName(rcs, "top_values")
+		}
+		wctx.rcs = rcs
+	}
+	if len(values) != len(rcs)-1 {
+		logger.Panicf("BUG: unexpected number of values; got %d; want %d", len(values), len(rcs)-1)
+	}
+
+	rcs[0].addValue(fieldName)
+	wctx.valuesLen += len(fieldName)
+
+	for i, v := range values {
+		rcs[i+1].addValue(bytesutil.ToUnsafeString(v))
+		wctx.valuesLen += len(v)
+	}
+
+	wctx.rowsCount++
+
+	// The 64_000 limit provides the best performance results.
+	if wctx.valuesLen >= 64_000 {
+		wctx.flush()
+	}
+}
+
+func (wctx *pipeFieldStatsWriteContext) flush() {
+	rcs := wctx.rcs
+	br := &wctx.br
+
+	wctx.valuesLen = 0
+
+	// Flush rcs to ppNext
+	br.setResultColumns(rcs, wctx.rowsCount)
+	wctx.rowsCount = 0
+	wctx.pfp.ppNext.writeBlock(0, br)
+	br.reset()
+	for i := range rcs {
+		rcs[i].resetValues()
+	}
+}
+
+// fieldStatsState holds stats for a single field.
+type fieldStatsState struct {
+	// hits is the number of logs with non-empty values for the field.
+	hits uint64
+
+	// uniq is used for estimating the number of distinct values for the field.
+	uniq fieldStatsUniqSketch
+
+	// top is used for tracking the values with the biggest number of hits for the field.
+	top fieldStatsTopSketch
+}
+
+func (fs *fieldStatsState) mergeState(src *fieldStatsState, topCapacity int) {
+	fs.hits += src.hits
+	fs.uniq.mergeState(&src.uniq)
+	fs.top.mergeState(&src.top, topCapacity)
+}
+
+func (fs *fieldStatsState) exportState(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, fs.hits)
+	dst = fs.uniq.exportState(dst)
+	dst = fs.top.exportState(dst)
+	return dst
+}
+
+// importState merges the state exported via exportState from src into fs.
+//
+// It returns the state size increase.
+func (fs *fieldStatsState) importState(src []byte, topCapacity int) (int, error) {
+	hits, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return 0, fmt.Errorf("cannot unmarshal hits")
+	}
+	src = src[n:]
+	fs.hits += hits
+
+	tail, uniqStateSize, err := fs.uniq.importState(src)
+	if err != nil {
+		return 0, fmt.Errorf("cannot import distinct values state: %w", err)
+	}
+	src = tail
+
+	tail, topStateSize, err := fs.top.importState(src, topCapacity)
+	if err != nil {
+		return 0, fmt.Errorf("cannot import top values state: %w", err)
+	}
+	if len(tail) > 0 {
+		return 0, fmt.Errorf("unexpected tail left after importing the state; len(tail)=%d", len(tail))
+	}
+	return uniqStateSize + topStateSize, nil
+}
+
+// fieldStatsUniqPrecision is the precision for HyperLogLog sketch used for estimating the number of distinct values.
+//
+// It provides ~1.6% standard error for the estimated number of distinct values.
+const fieldStatsUniqPrecision = 12
+
+// fieldStatsUniqRegistersCount is the number of registers in HyperLogLog sketch.
+const fieldStatsUniqRegistersCount = 1 << fieldStatsUniqPrecision
+
+// fieldStatsUniqMaxHashes is the maximum number of value hashes to track before switching to HyperLogLog registers.
+//
+// This allows returning exact number of distinct values for fields with small number of distinct values
+// without the need to allocate HyperLogLog registers for them.
+const fieldStatsUniqMaxHashes = 256
+
+// fieldStatsUniqSketch estimates the number of distinct values.
+type fieldStatsUniqSketch struct {
+	// hashes contains hashes for distinct values until their number exceeds fieldStatsUniqMaxHashes.
+	hashes map[uint64]struct{}
+
+	// registers contains HyperLogLog registers. It is non-nil after the number of hashes exceeds fieldStatsUniqMaxHashes.
+	registers []uint8
+}
+
+// add adds the hash h to us and returns the state size increase.
+func (us *fieldStatsUniqSketch) add(h uint64) int {
+	if us.registers != nil {
+		us.addRegister(h)
+		return 0
+	}
+	if us.hashes == nil {
+		us.hashes = make(map[uint64]struct{})
+	}
+	if _, ok := us.hashes[h]; ok {
+		return 0
+	}
+	us.hashes[h] = struct{}{}
+	if len(us.hashes) <= fieldStatsUniqMaxHashes {
+		return int(unsafe.Sizeof(h)) * 2
+	}
+
+	// Switch to HyperLogLog registers.
+	stateSize := fieldStatsUniqRegistersCount - len(us.hashes)*int(unsafe.Sizeof(h))*2
+	us.registers = make([]uint8, fieldStatsUniqRegistersCount)
+	for h := range us.hashes {
+		us.addRegister(h)
+	}
+	us.hashes = nil
+	return stateSize
+}
+
+func (us *fieldStatsUniqSketch) addRegister(h uint64) {
+	idx := h >> (64 - fieldStatsUniqPrecision)
+	rank := uint8(min(bits.LeadingZeros64(h<<fieldStatsUniqPrecision), 64-fieldStatsUniqPrecision) + 1)
+	if rank > us.registers[idx] {
+		us.registers[idx] = rank
+	}
+}
+
+func (us *fieldStatsUniqSketch) mergeState(src *fieldStatsUniqSketch) {
+	if src.registers == nil {
+		for h := range src.hashes {
+			us.add(h)
+		}
+		return
+	}
+	if us.registers == nil {
+		hashes := us.hashes
+		us.hashes = nil
+		us.registers = make([]uint8, fieldStatsUniqRegistersCount)
+		for h := range hashes {
+			us.addRegister(h)
+		}
+	}
+	for i, rank := range src.registers {
+		if rank > us.registers[i] {
+			us.registers[i] = rank
+		}
+	}
+}
+
+// estimate returns the estimated number of distinct values.
+func (us *fieldStatsUniqSketch) estimate() uint64 {
+	if us.registers == nil {
+		return uint64(len(us.hashes))
+	}
+
+	m := float64(len(us.registers))
+	sum := float64(0)
+	zeros := 0
+	for _, rank := range us.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Use linear counting for small cardinalities.
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+func (us *fieldStatsUniqSketch) exportState(dst []byte) []byte {
+	if us.registers == nil {
+		dst = append(dst, 0)
+		dst = encoding.MarshalVarUint64(dst, uint64(len(us.hashes)))
+		for h := range us.hashes {
+			dst = encoding.MarshalUint64(dst, h)
+		}
+		return dst
+	}
+	dst = append(dst, 1)
+	dst = append(dst, us.registers...)
+	return dst
+}
+
+// importState merges the state exported via exportState from src into us.
+//
+// It returns the tail left after importing the state and the state size increase.
+func (us *fieldStatsUniqSketch) importState(src []byte) ([]byte, int, error) {
+	if len(src) == 0 {
+		return src, 0, fmt.Errorf("missing sketch type")
+	}
+	sketchType := src[0]
+	src = src[1:]
+
+	var tmp fieldStatsUniqSketch
+	switch sketchType {
+	case 0:
+		hashesLen, n := encoding.UnmarshalVarUint64(src)
+		if n <= 0 {
+			return src, 0, fmt.Errorf("cannot unmarshal the number of hashes")
+		}
+		src = src[n:]
+		if uint64(len(src)) < 8*hashesLen {
+			return src, 0, fmt.Errorf("cannot unmarshal %d hashes from %d bytes", hashesLen, len(src))
+		}
+		tmp.hashes = make(map[uint64]struct{}, hashesLen)
+		for i := uint64(0); i < hashesLen; i++ {
+			tmp.hashes[encoding.UnmarshalUint64(src)] = struct{}{}
+			src = src[8:]
+		}
+	case 1:
+		if len(src) < fieldStatsUniqRegistersCount {
+			return src, 0, fmt.Errorf("cannot unmarshal %d registers from %d bytes", fieldStatsUniqRegistersCount, len(src))
+		}
+		tmp.registers = src[:fieldStatsUniqRegistersCount]
+		src = src[fieldStatsUniqRegistersCount:]
+	default:
+		return src, 0, fmt.Errorf("unexpected sketch type: %d", sketchType)
+	}
+
+	stateSizePrev := us.stateSize()
+	us.mergeState(&tmp)
+	return src, us.stateSize() - stateSizePrev, nil
+}
+
+func (us *fieldStatsUniqSketch) stateSize() int {
+	if us.registers != nil {
+		return len(us.registers)
+	}
+	return len(us.hashes) * 16
+}
+
+// fieldStatsTopSketch tracks values with the biggest number of hits.
+//
+// It keeps up to 2*capacity values with the biggest hits and drops the remaining values,
+// so the returned hits are lower bounds for the real hits of the top values.
+type fieldStatsTopSketch struct {
+	m map[string]uint64
+}
+
+// add adds hits for the value v to ts and returns the state size increase.
+func (ts *fieldStatsTopSketch) add(v string, hits uint64, capacity int) int {
+	if ts.m == nil {
+		ts.m = make(map[string]uint64)
+	}
+	if _, ok := ts.m[v]; ok {
+		ts.m[v] += hits
+		return 0
+	}
+	ts.m[strings.Clone(v)] = hits
+	stateSize := fieldStatsTopEntrySize(v)
+	if len(ts.m) > 2*capacity {
+		stateSize -= ts.prune(capacity)
+	}
+	return stateSize
+}
+
+// prune leaves up to capacity values with the biggest hits at ts and returns the state size decrease.
+func (ts *fieldStatsTopSketch) prune(capacity int) int {
+	if len(ts.m) <= capacity {
+		return 0
+	}
+	entries := ts.getSortedEntries()
+	stateSize := 0
+	for _, e := range entries[capacity:] {
+		delete(ts.m, e.k)
+		stateSize += fieldStatsTopEntrySize(e.k)
+	}
+	return stateSize
+}
+
+func (ts *fieldStatsTopSketch) getSortedEntries() []pipeTopEntry {
+	entries := make([]pipeTopEntry, 0, len(ts.m))
+	for k, hits := range ts.m {
+		entries = append(entries, pipeTopEntry{
+			k:    k,
+			hits: hits,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if a.hits != b.hits {
+			return a.hits > b.hits
+		}
+		return a.k < b.k
+	})
+	return entries
+}
+
+func fieldStatsTopEntrySize(v string) int {
+	return len(v) + int(unsafe.Sizeof(v)) + 8
+}
+
+func (ts *fieldStatsTopSketch) mergeState(src *fieldStatsTopSketch, capacity int) {
+	if ts.m == nil {
+		ts.m = make(map[string]uint64, len(src.m))
+	}
+	for k, hits := range src.m {
+		ts.m[k] += hits
+	}
+	ts.prune(2 * capacity)
+}
+
+// marshalJSON appends up to limit top values with their hits in JSON to dst and returns the result.
+func (ts *fieldStatsTopSketch) marshalJSON(dst []byte, limit uint64) []byte {
+	entries := ts.getSortedEntries()
+	if uint64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+	dst = append(dst, '[')
+	for i, e := range entries {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `{"value":`...)
+		dst = quicktemplate.AppendJSONString(dst, e.k, true)
+		dst = append(dst, `,"hits":`...)
+		dst = marshalUint64String(dst, e.hits)
+		dst = append(dst, '}')
+	}
+	dst = append(dst, ']')
+	return dst
+}
+
+func (ts *fieldStatsTopSketch) exportState(dst []byte) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(ts.m)))
+	for k, hits := range ts.m {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(k))
+		dst = encoding.MarshalVarUint64(dst, hits)
+	}
+	return dst
+}
+
+// importState merges the state exported via exportState from src into ts.
+//
+// It returns the tail left after importing the state and the state size increase.
+func (ts *fieldStatsTopSketch) importState(src []byte, capacity int) ([]byte, int, error) {
+	entriesLen, n := encoding.UnmarshalVarUint64(src)
+	if n <= 0 {
+		return src, 0, fmt.Errorf("cannot unmarshal the number of entries")
+	}
+	src = src[n:]
+
+	stateSize := 0
+	for i := uint64(0); i < entriesLen; i++ {
+		k, n := encoding.UnmarshalBytes(src)
+		if n <= 0 {
+			return src, 0, fmt.Errorf("cannot unmarshal value")
+		}
+		src = src[n:]
+
+		hits, n := encoding.UnmarshalVarUint64(src)
+		if n <= 0 {
+			return src, 0, fmt.Errorf("cannot unmarshal hits")
+		}
+		src = src[n:]
+
+		stateSize += ts.add(bytesutil.ToUnsafeString(k), hits, capacity)
+	}
+	return src, stateSize, nil
+}
+
+func parsePipeFieldStats(lex *lexer) (pipe, error) {
+	var pf pipeFieldStats
+	switch {
+	case lex.isKeyword("field_stats"):
+		lex.nextToken()
+	case lex.isKeyword("field_stats_remote"):
+		lex.nextToken()
+		pf.mode = pipeFieldStatsModeRemote
+	default:
+		return nil, fmt.Errorf("expecting 'field_stats' or 'field_stats_remote'; got %q", lex.token)
+	}
+
+	pf.limit = pipeFieldStatsDefaultLimit
+	if isNumberPrefix(lex.token) {
+		limitF, s, err := parseNumber(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse N in 'field_stats': %w", err)
+		}
+		if limitF < 1 {
+			return nil, fmt.Errorf("N in 'field_stats %s' must be integer bigger than 0", s)
+		}
+		pf.limit = uint64(limitF)
+	}
+
+	pf.maxValueLen = pipeFieldStatsDefaultMaxValueLen
+	if lex.isKeyword("max_value_len") {
+		lex.nextToken()
+		n, s, err := parseNumber(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse max_value_len: %w", err)
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("max_value_len must be integer bigger than 0; got %s", s)
+		}
+		pf.maxValueLen = uint64(n)
+	}
+
+	return &pf, nil
+}
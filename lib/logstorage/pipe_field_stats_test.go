@@ -0,0 +1,278 @@
+package logstorage
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+func TestParsePipeFieldStatsSuccess(t *testing.T) {
+	f := func(pipeStr string) {
+		t.Helper()
+		expectParsePipeSuccess(t, pipeStr)
+	}
+
+	f(`field_stats`)
+	f(`field_stats 15`)
+	f(`field_stats max_value_len 30`)
+	f(`field_stats 15 max_value_len 30`)
+	f(`field_stats_remote`)
+	f(`field_stats_remote 15 max_value_len 30`)
+}
+
+func TestParsePipeFieldStatsFailure(t *testing.T) {
+	f := func(pipeStr string) {
+		t.Helper()
+		expectParsePipeFailure(t, pipeStr)
+	}
+
+	f(`field_stats foo`)
+	f(`field_stats 0`)
+	f(`field_stats 5 foo`)
+	f(`field_stats max_value_len`)
+	f(`field_stats max_value_len bar`)
+	f(`field_stats max_value_len 0`)
+}
+
+func TestPipeFieldStats(t *testing.T) {
+	f := func(pipeStr string, rows, rowsExpected [][]Field) {
+		t.Helper()
+		expectPipeResults(t, pipeStr, rows, rowsExpected)
+	}
+
+	rows := [][]Field{
+		{
+			{"a", `2`},
+			{"b", `3`},
+		},
+		{
+			{"a", "2"},
+			{"b", "3"},
+			{"c", ""},
+		},
+		{
+			{"a", `2`},
+			{"b", `54`},
+			{"c", "d"},
+			{"e", ""},
+		},
+		{
+			{"a", `2`},
+			{"b", `foobar`},
+		},
+	}
+
+	f("field_stats", rows, [][]Field{
+		{
+			{"field_name", "a"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"2","hits":4}]`},
+		},
+		{
+			{"field_name", "b"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "3"},
+			{"top_values", `[{"value":"3","hits":2},{"value":"54","hits":1},{"value":"foobar","hits":1}]`},
+		},
+		{
+			{"field_name", "c"},
+			{"hits", "1"},
+			{"null_ratio", "0.75"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"d","hits":1}]`},
+		},
+	})
+
+	// limit the number of top values and the length of values
+	f("field_stats 1 max_value_len 2", rows, [][]Field{
+		{
+			{"field_name", "a"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"2","hits":4}]`},
+		},
+		{
+			{"field_name", "b"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "3"},
+			{"top_values", `[{"value":"3","hits":2}]`},
+		},
+		{
+			{"field_name", "c"},
+			{"hits", "1"},
+			{"null_ratio", "0.75"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"d","hits":1}]`},
+		},
+	})
+}
+
+func TestPipeFieldStatsRemoteLocal(t *testing.T) {
+	// The remote and local parts of the pipe must return the same results as the original pipe.
+	pf, err := parsePipeFieldStats(newLexer("field_stats 2", 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pRemote, pLocals := pf.splitToRemoteAndLocal(0)
+	if len(pLocals) != 1 {
+		t.Fatalf("unexpected number of local pipes; got %d; want 1", len(pLocals))
+	}
+
+	workersCount := 5
+	stopCh := make(chan struct{})
+	cancel := func() {}
+	ppTest := newTestPipeProcessor()
+	ppLocal := pLocals[0].newPipeProcessor(workersCount, stopCh, cancel, ppTest)
+
+	// Simulate two remote storage nodes.
+	rowsPerNode := [][][]Field{
+		{
+			{
+				{"a", "2"},
+				{"b", "3"},
+			},
+			{
+				{"a", "2"},
+				{"b", "3"},
+			},
+		},
+		{
+			{
+				{"a", "2"},
+				{"b", "54"},
+				{"c", "d"},
+			},
+			{
+				{"a", "2"},
+				{"b", "foobar"},
+			},
+		},
+	}
+	for _, rows := range rowsPerNode {
+		ppRemote := pRemote.newPipeProcessor(workersCount, stopCh, cancel, ppLocal)
+		brw := newTestBlockResultWriter(workersCount, ppRemote)
+		for _, row := range rows {
+			brw.writeRow(row)
+		}
+		brw.flush()
+		if err := ppRemote.flush(); err != nil {
+			t.Fatalf("unexpected error in remote pipe: %s", err)
+		}
+	}
+	if err := ppLocal.flush(); err != nil {
+		t.Fatalf("unexpected error in local pipe: %s", err)
+	}
+
+	ppTest.expectRows(t, [][]Field{
+		{
+			{"field_name", "a"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"2","hits":4}]`},
+		},
+		{
+			{"field_name", "b"},
+			{"hits", "4"},
+			{"null_ratio", "0"},
+			{"distinct_values", "3"},
+			{"top_values", `[{"value":"3","hits":2},{"value":"54","hits":1}]`},
+		},
+		{
+			{"field_name", "c"},
+			{"hits", "1"},
+			{"null_ratio", "0.75"},
+			{"distinct_values", "1"},
+			{"top_values", `[{"value":"d","hits":1}]`},
+		},
+	})
+}
+
+func TestPipeFieldStatsUpdateNeededFields(t *testing.T) {
+	f := func(s string, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected string) {
+		t.Helper()
+		expectPipeNeededFields(t, s, neededFields, unneededFields, neededFieldsExpected, unneededFieldsExpected)
+	}
+
+	// all the needed fields
+	f("field_stats", "*", "", "*", "")
+
+	// all the needed fields, unneeded fields do not intersect with src
+	f("field_stats", "*", "f1,f2", "*", "")
+
+	// needed fields do not intersect with src
+	f("field_stats", "f1,f2", "", "*", "")
+}
+
+func TestFieldStatsUniqSketchEstimate(t *testing.T) {
+	f := func(n int) {
+		t.Helper()
+
+		var us fieldStatsUniqSketch
+		for i := 0; i < n; i++ {
+			h := xxhash.Sum64String(fmt.Sprintf("value_%d", i))
+			us.add(h)
+
+			// Duplicate values mustn't change the estimate.
+			us.add(h)
+		}
+
+		// Verify that the exported state is properly imported.
+		var usImported fieldStatsUniqSketch
+		tail, _, err := usImported.importState(us.exportState(nil))
+		if err != nil {
+			t.Fatalf("unexpected error when importing state: %s", err)
+		}
+		if len(tail) > 0 {
+			t.Fatalf("unexpected non-empty tail after importing state; len(tail)=%d", len(tail))
+		}
+
+		for _, sketch := range []*fieldStatsUniqSketch{&us, &usImported} {
+			estimate := sketch.estimate()
+			if n <= fieldStatsUniqMaxHashes {
+				if estimate != uint64(n) {
+					t.Fatalf("unexpected estimate for %d distinct values; got %d", n, estimate)
+				}
+				continue
+			}
+			relativeError := math.Abs(float64(estimate)-float64(n)) / float64(n)
+			if relativeError > 0.05 {
+				t.Fatalf("too big relative error for the estimate of %d distinct values: %.3f; estimate=%d", n, relativeError, estimate)
+			}
+		}
+	}
+
+	f(0)
+	f(1)
+	f(fieldStatsUniqMaxHashes)
+	f(fieldStatsUniqMaxHashes + 1)
+	f(1_000)
+	f(10_000)
+	f(100_000)
+}
+
+func TestFieldStatsTopSketch(t *testing.T) {
+	var ts fieldStatsTopSketch
+	capacity := 10
+
+	// Add values with hits proportional to their number, so the values with the biggest numbers must be returned.
+	for i := 1; i <= 100; i++ {
+		ts.add(fmt.Sprintf("v%03d", i), uint64(i), capacity)
+	}
+	if n := len(ts.m); n > 2*capacity {
+		t.Fatalf("too many tracked values; got %d; want up to %d", n, 2*capacity)
+	}
+
+	result := string(ts.marshalJSON(nil, 3))
+	resultExpected := `[{"value":"v100","hits":100},{"value":"v099","hits":99},{"value":"v098","hits":98}]`
+	if result != resultExpected {
+		t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
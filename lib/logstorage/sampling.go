@@ -0,0 +1,279 @@
+package logstorage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+)
+
+// SamplingConfig contains rules for sampling aged log entries during background merges.
+//
+// See https://docs.victoriametrics.com/victorialogs/#log-sampling
+type SamplingConfig struct {
+	rules []*samplingRule
+}
+
+// samplingRuleYAML is YAML representation for a single sampling rule.
+type samplingRuleYAML struct {
+	Tenant       string             `yaml:"tenant,omitempty"`
+	StreamFilter string             `yaml:"stream_filter,omitempty"`
+	LevelField   string             `yaml:"level_field,omitempty"`
+	Levels       []string           `yaml:"levels,omitempty"`
+	OlderThan    *promutil.Duration `yaml:"older_than"`
+	KeepRatio    *float64           `yaml:"keep_ratio"`
+}
+
+type samplingConfigYAML struct {
+	Rules []samplingRuleYAML `yaml:"rules"`
+}
+
+// samplingRule keeps only keepRatio share of log entries with the given levels, which are older than olderThan.
+type samplingRule struct {
+	// tenantID is the tenant the rule applies to. The rule applies to all the tenants if tenantID is nil.
+	tenantID *TenantID
+
+	// sf is an optional filter for log streams the rule applies to.
+	sf *StreamFilter
+
+	levelField string
+	levels     []string
+
+	olderThan time.Duration
+
+	// keepThreshold is the maximum hash value for the sampled log entries to keep.
+	keepThreshold uint64
+}
+
+// ParseSamplingConfig parses sampling config from data.
+func ParseSamplingConfig(data []byte) (*SamplingConfig, error) {
+	var cfgYAML samplingConfigYAML
+	if err := yaml.UnmarshalStrict(data, &cfgYAML); err != nil {
+		return nil, err
+	}
+	rules := make([]*samplingRule, 0, len(cfgYAML.Rules))
+	for i := range cfgYAML.Rules {
+		r, err := newSamplingRule(&cfgYAML.Rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse rule #%d: %w", i+1, err)
+		}
+		rules = append(rules, r)
+	}
+	return &SamplingConfig{
+		rules: rules,
+	}, nil
+}
+
+func newSamplingRule(ry *samplingRuleYAML) (*samplingRule, error) {
+	var r samplingRule
+
+	if ry.Tenant != "" {
+		tenantID, err := ParseTenantID(ry.Tenant)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse tenant: %w", err)
+		}
+		r.tenantID = &tenantID
+	}
+
+	if ry.StreamFilter != "" {
+		lex := newLexer(ry.StreamFilter, 0)
+		sf, err := parseStreamFilter(lex)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse stream_filter=%q: %w", ry.StreamFilter, err)
+		}
+		if !lex.isEnd() {
+			return nil, fmt.Errorf("unexpected tail after stream_filter: %q", lex.s)
+		}
+		r.sf = sf
+	}
+
+	r.levelField = ry.LevelField
+	if r.levelField == "" {
+		r.levelField = "level"
+	}
+	r.levels = ry.Levels
+	if len(r.levels) == 0 {
+		r.levels = []string{"debug"}
+	}
+
+	if ry.OlderThan == nil || ry.OlderThan.Duration() <= 0 {
+		return nil, fmt.Errorf("older_than must be set to positive duration")
+	}
+	r.olderThan = ry.OlderThan.Duration()
+
+	if ry.KeepRatio == nil {
+		return nil, fmt.Errorf("missing keep_ratio")
+	}
+	keepRatio := *ry.KeepRatio
+	if keepRatio < 0 || keepRatio > 1 {
+		return nil, fmt.Errorf("keep_ratio must be in the range [0..1]; got %v", keepRatio)
+	}
+	if keepRatio == 1 {
+		r.keepThreshold = math.MaxUint64
+	} else {
+		r.keepThreshold = uint64(keepRatio * math.MaxUint64)
+	}
+
+	return &r, nil
+}
+
+func (r *samplingRule) matchLevel(fields []Field) bool {
+	for _, f := range fields {
+		if f.Name != r.levelField {
+			continue
+		}
+		for _, level := range r.levels {
+			if strings.EqualFold(f.Value, level) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// logSampler drops log entries according to SamplingConfig during a single merge.
+type logSampler struct {
+	cfg *SamplingConfig
+	idb *indexdb
+
+	// currentTimestamp is the timestamp in nanoseconds at the start of the merge.
+	currentTimestamp int64
+
+	// rowsDropped is the number of log entries dropped by the logSampler.
+	rowsDropped uint64
+
+	// streamID is the last seen streamID.
+	streamID streamID
+
+	// rule is the rule for the streamID. It is nil if no rules match the streamID.
+	rule *samplingRule
+
+	// maxTimestamp is the maximum timestamp for log entries, which can be sampled by rule.
+	maxTimestamp int64
+
+	// hasStreamID is set to true after the first call to initStream().
+	hasStreamID bool
+
+	buf []byte
+}
+
+// newLogSampler returns logSampler for the given cfg.
+//
+// nil is returned if cfg has no rules.
+func newLogSampler(cfg *SamplingConfig, idb *indexdb, currentTimestamp int64) *logSampler {
+	if cfg == nil || len(cfg.rules) == 0 {
+		return nil
+	}
+	return &logSampler{
+		cfg:              cfg,
+		idb:              idb,
+		currentTimestamp: currentTimestamp,
+	}
+}
+
+func (ls *logSampler) initStream(sid *streamID) {
+	if ls.hasStreamID && sid.equal(&ls.streamID) {
+		return
+	}
+	ls.hasStreamID = true
+	ls.streamID = *sid
+	ls.rule = nil
+	ls.maxTimestamp = 0
+
+	streamStr := ""
+	hasStreamStr := false
+	for _, r := range ls.cfg.rules {
+		if r.tenantID != nil && !r.tenantID.equal(&sid.tenantID) {
+			continue
+		}
+		if r.sf != nil {
+			if !hasStreamStr {
+				streamStr = ls.getStreamStr(sid)
+				hasStreamStr = true
+			}
+			if !r.sf.matchStreamName(streamStr) {
+				continue
+			}
+		}
+		ls.rule = r
+		ls.maxTimestamp = ls.currentTimestamp - r.olderThan.Nanoseconds()
+		return
+	}
+}
+
+func (ls *logSampler) getStreamStr(sid *streamID) string {
+	if ls.idb == nil {
+		return ""
+	}
+	ls.buf = ls.idb.appendStreamTagsByStreamID(ls.buf[:0], sid)
+	if len(ls.buf) == 0 {
+		return ""
+	}
+	return getStreamTagsString(bytesutil.ToUnsafeString(ls.buf))
+}
+
+// needSampling returns true if some log entries at bd may be dropped by ls.
+func (ls *logSampler) needSampling(bd *blockData) bool {
+	if ls == nil || bd.rowsCount == 0 {
+		return false
+	}
+	ls.initStream(&bd.streamID)
+	return ls.rule != nil && bd.timestampsData.minTimestamp < ls.maxTimestamp
+}
+
+// filterRows drops log entries for the given sid from timestamps and rows according to ls and returns the remaining log entries.
+//
+// The decision for every log entry depends only on its contents, so the same log entries are kept on repeated merges.
+func (ls *logSampler) filterRows(sid *streamID, timestamps []int64, rows [][]Field) ([]int64, [][]Field) {
+	if ls == nil || len(timestamps) == 0 {
+		return timestamps, rows
+	}
+	ls.initStream(sid)
+	r := ls.rule
+	if r == nil || timestamps[0] >= ls.maxTimestamp {
+		return timestamps, rows
+	}
+
+	dstTimestamps := timestamps[:0]
+	dstRows := rows[:0]
+	for i, timestamp := range timestamps {
+		fields := rows[i]
+		if timestamp < ls.maxTimestamp && r.matchLevel(fields) && getRowHash(timestamp, fields) > r.keepThreshold {
+			ls.rowsDropped++
+			continue
+		}
+		dstTimestamps = append(dstTimestamps, timestamp)
+		dstRows = append(dstRows, fields)
+	}
+	return dstTimestamps, dstRows
+}
+
+// getRowHash returns hash for the log entry with the given timestamp and fields.
+//
+// The hash doesn't depend on the order of fields and on fields with empty values,
+// since they may change when the log entry is re-written during merges.
+func getRowHash(timestamp int64, fields []Field) uint64 {
+	var b [8]byte
+	h := uint64(0)
+	for _, f := range fields {
+		if f.Value == "" {
+			continue
+		}
+		bb := bbPool.Get()
+		bb.B = append(bb.B[:0], f.Name...)
+		bb.B = append(bb.B, 0)
+		bb.B = append(bb.B, f.Value...)
+		h += xxhash.Sum64(bb.B)
+		bbPool.Put(bb)
+	}
+	binary.BigEndian.PutUint64(b[:], h^uint64(timestamp))
+	return xxhash.Sum64(b[:])
+}
@@ -0,0 +1,247 @@
+package logstorage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseSamplingConfig_Failure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := ParseSamplingConfig([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unknown field
+	f(`foo: bar`)
+	f(`
+rules:
+- older_than: 7d
+  keep_ratio: 0.1
+  foo: bar
+`)
+
+	// missing older_than
+	f(`
+rules:
+- keep_ratio: 0.1
+`)
+
+	// invalid older_than
+	f(`
+rules:
+- older_than: foo
+  keep_ratio: 0.1
+`)
+
+	// missing keep_ratio
+	f(`
+rules:
+- older_than: 7d
+`)
+
+	// keep_ratio out of range
+	f(`
+rules:
+- older_than: 7d
+  keep_ratio: 1.5
+`)
+
+	// invalid tenant
+	f(`
+rules:
+- tenant: foo
+  older_than: 7d
+  keep_ratio: 0.1
+`)
+
+	// invalid stream_filter
+	f(`
+rules:
+- stream_filter: 'foo'
+  older_than: 7d
+  keep_ratio: 0.1
+`)
+	f(`
+rules:
+- stream_filter: '{app="foo"} bar'
+  older_than: 7d
+  keep_ratio: 0.1
+`)
+}
+
+func TestParseSamplingConfig_Success(t *testing.T) {
+	cfg, err := ParseSamplingConfig([]byte(`
+rules:
+- tenant: "12:34"
+  stream_filter: '{app="nginx"}'
+  level_field: severity
+  levels: [debug, trace]
+  older_than: 7d
+  keep_ratio: 0.1
+- older_than: 30d
+  keep_ratio: 0
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cfg.rules) != 2 {
+		t.Fatalf("unexpected number of rules; got %d; want 2", len(cfg.rules))
+	}
+
+	r := cfg.rules[0]
+	tenantIDExpected := TenantID{AccountID: 12, ProjectID: 34}
+	if r.tenantID == nil || !r.tenantID.equal(&tenantIDExpected) {
+		t.Fatalf("unexpected tenantID; got %v; want %s", r.tenantID, &tenantIDExpected)
+	}
+	if s := r.sf.String(); s != `{app="nginx"}` {
+		t.Fatalf("unexpected stream_filter; got %s; want %s", s, `{app="nginx"}`)
+	}
+	if r.levelField != "severity" {
+		t.Fatalf("unexpected level_field; got %q; want %q", r.levelField, "severity")
+	}
+	if r.olderThan != 7*24*time.Hour {
+		t.Fatalf("unexpected older_than; got %s; want %s", r.olderThan, 7*24*time.Hour)
+	}
+
+	r = cfg.rules[1]
+	if r.tenantID != nil || r.sf != nil {
+		t.Fatalf("unexpected non-nil filters for the second rule")
+	}
+	if r.levelField != "level" {
+		t.Fatalf("unexpected default level_field; got %q; want %q", r.levelField, "level")
+	}
+	if len(r.levels) != 1 || r.levels[0] != "debug" {
+		t.Fatalf("unexpected default levels; got %q; want %q", r.levels, []string{"debug"})
+	}
+}
+
+func TestLogSamplerFilterRows(t *testing.T) {
+	cfg, err := ParseSamplingConfig([]byte(`
+rules:
+- tenant: "0:0"
+  older_than: 1d
+  keep_ratio: 0.1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	currentTimestamp := time.Now().UnixNano()
+	oldTimestamp := currentTimestamp - 2*nsecsPerDay
+	newTimestamp := currentTimestamp - nsecsPerHour
+
+	const rowsCount = 1000
+	var timestamps []int64
+	var rows [][]Field
+	addRows := func(timestamp int64, level string) {
+		for i := 0; i < rowsCount; i++ {
+			timestamps = append(timestamps, timestamp+int64(i))
+			rows = append(rows, []Field{
+				{Name: "_msg", Value: fmt.Sprintf("message %d", i)},
+				{Name: "level", Value: level},
+			})
+		}
+	}
+	addRows(oldTimestamp, "DEBUG")
+	addRows(oldTimestamp+rowsCount, "info")
+	addRows(newTimestamp, "debug")
+
+	// The rule doesn't match other tenants
+	ls := newLogSampler(cfg, nil, currentTimestamp)
+	sid := streamID{
+		tenantID: TenantID{AccountID: 1},
+	}
+	timestampsResult, _ := ls.filterRows(&sid, append([]int64{}, timestamps...), append([][]Field{}, rows...))
+	if len(timestampsResult) != len(timestamps) {
+		t.Fatalf("unexpected number of log entries for other tenant; got %d; want %d", len(timestampsResult), len(timestamps))
+	}
+	if ls.rowsDropped != 0 {
+		t.Fatalf("unexpected number of dropped log entries for other tenant; got %d; want 0", ls.rowsDropped)
+	}
+
+	// Only old debug logs must be sampled
+	ls = newLogSampler(cfg, nil, currentTimestamp)
+	sid.tenantID.AccountID = 0
+	timestampsResult, rowsResult := ls.filterRows(&sid, append([]int64{}, timestamps...), append([][]Field{}, rows...))
+	if n := len(timestamps) - len(timestampsResult); uint64(n) != ls.rowsDropped {
+		t.Fatalf("unexpected number of dropped log entries; got %d; want %d", ls.rowsDropped, n)
+	}
+	if ls.rowsDropped < 0.8*rowsCount || ls.rowsDropped > 0.95*rowsCount {
+		t.Fatalf("unexpected number of dropped log entries; got %d; want approximately %d", ls.rowsDropped, int(0.9*rowsCount))
+	}
+	notSampled := 0
+	for _, fields := range rowsResult {
+		if fields[1].Value != "DEBUG" {
+			notSampled++
+		}
+	}
+	if notSampled != 2*rowsCount {
+		t.Fatalf("unexpected number of log entries, which mustn't be sampled; got %d; want %d", notSampled, 2*rowsCount)
+	}
+
+	// Repeated sampling must keep the same log entries
+	ls = newLogSampler(cfg, nil, currentTimestamp)
+	timestampsSampled := append([]int64{}, timestampsResult...)
+	timestampsResult, _ = ls.filterRows(&sid, timestampsResult, rowsResult)
+	if ls.rowsDropped != 0 {
+		t.Fatalf("unexpected number of dropped log entries on repeated sampling; got %d; want 0", ls.rowsDropped)
+	}
+	if len(timestampsResult) != len(timestampsSampled) {
+		t.Fatalf("unexpected number of log entries on repeated sampling; got %d; want %d", len(timestampsResult), len(timestampsSampled))
+	}
+}
+
+func TestMergeBlockStreamsWithSampling(t *testing.T) {
+	cfg, err := ParseSamplingConfig([]byte(`
+rules:
+- older_than: 1d
+  keep_ratio: 0.5
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	currentTimestamp := time.Now().UnixNano()
+	oldTimestamp := currentTimestamp - 2*nsecsPerDay
+
+	const rowsCount = 1000
+	lr := GetLogRows(nil, nil, nil, "")
+	defer PutLogRows(lr)
+	for i := 0; i < rowsCount; i++ {
+		level := "debug"
+		if i%2 == 0 {
+			level = "info"
+		}
+		fields := []Field{
+			{Name: "_msg", Value: fmt.Sprintf("message %d", i)},
+			{Name: "level", Value: level},
+		}
+		lr.MustAdd(TenantID{}, oldTimestamp+int64(i), fields, nil)
+	}
+
+	mpSrc := getInmemoryPart()
+	defer putInmemoryPart(mpSrc)
+	mpSrc.mustInitFromRows(lr)
+	bsr := getBlockStreamReader()
+	defer putBlockStreamReader(bsr)
+	bsr.MustInitFromInmemoryPart(mpSrc)
+
+	mpDst := getInmemoryPart()
+	defer putInmemoryPart(mpDst)
+	bsw := getBlockStreamWriter()
+	bsw.MustInitForInmemoryPart(mpDst)
+	ls := newLogSampler(cfg, nil, currentTimestamp)
+	mustMergeBlockStreams(&mpDst.ph, bsw, []*blockStreamReader{bsr}, ls, nil)
+	putBlockStreamWriter(bsw)
+
+	if ls.rowsDropped == 0 || ls.rowsDropped >= rowsCount/2 {
+		t.Fatalf("unexpected number of dropped log entries; got %d; want in the range (0..%d)", ls.rowsDropped, rowsCount/2)
+	}
+	if rowsCountExpected := uint64(rowsCount) - ls.rowsDropped; mpDst.ph.RowsCount != rowsCountExpected {
+		t.Fatalf("unexpected number of log entries after the merge; got %d; want %d", mpDst.ph.RowsCount, rowsCountExpected)
+	}
+}
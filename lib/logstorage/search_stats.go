@@ -0,0 +1,152 @@
+package logstorage
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// searchStats contains stats collected during the search.
+//
+// It is used for query tracing. It is safe calling searchStats methods on nil searchStats - they are no-op then.
+// This allows avoiding the overhead of collecting the stats when query tracing is disabled.
+type searchStats struct {
+	// partsScanned is the number of parts scanned during the search.
+	partsScanned atomic.Uint64
+
+	// blocksScanned is the number of blocks scanned during the search.
+	blocksScanned atomic.Uint64
+
+	// blocksMatched is the number of scanned blocks with at least a single row matching the filter.
+	blocksMatched atomic.Uint64
+
+	// rowsScanned is the number of rows in the scanned blocks.
+	rowsScanned atomic.Uint64
+
+	// rowsMatched is the number of rows matching the filter.
+	rowsMatched atomic.Uint64
+
+	// bloomFilterChecks is the number of bloom filter checks.
+	bloomFilterChecks atomic.Uint64
+
+	// bloomFilterMisses is the number of bloom filter checks, which allowed skipping the block,
+	// since it doesn't contain the needed tokens.
+	bloomFilterMisses atomic.Uint64
+}
+
+func (ss *searchStats) addPartsScanned(n int) {
+	if ss == nil {
+		return
+	}
+	ss.partsScanned.Add(uint64(n))
+}
+
+func (ss *searchStats) addBlockScanned(rowsScanned, rowsMatched int) {
+	if ss == nil {
+		return
+	}
+	ss.blocksScanned.Add(1)
+	ss.rowsScanned.Add(uint64(rowsScanned))
+	if rowsMatched > 0 {
+		ss.blocksMatched.Add(1)
+		ss.rowsMatched.Add(uint64(rowsMatched))
+	}
+}
+
+func (ss *searchStats) addBloomFilterCheck(matched bool) {
+	if ss == nil {
+		return
+	}
+	ss.bloomFilterChecks.Add(1)
+	if !matched {
+		ss.bloomFilterMisses.Add(1)
+	}
+}
+
+func (ss *searchStats) add(src *searchStats) {
+	ss.partsScanned.Add(src.partsScanned.Load())
+	ss.blocksScanned.Add(src.blocksScanned.Load())
+	ss.blocksMatched.Add(src.blocksMatched.Load())
+	ss.rowsScanned.Add(src.rowsScanned.Load())
+	ss.rowsMatched.Add(src.rowsMatched.Load())
+	ss.bloomFilterChecks.Add(src.bloomFilterChecks.Load())
+	ss.bloomFilterMisses.Add(src.bloomFilterMisses.Load())
+}
+
+func (ss *searchStats) String() string {
+	bloomFilterChecks := ss.bloomFilterChecks.Load()
+	bloomFilterMisses := ss.bloomFilterMisses.Load()
+	bloomFilterSkipPercent := 0.0
+	if bloomFilterChecks > 0 {
+		bloomFilterSkipPercent = 100 * float64(bloomFilterMisses) / float64(bloomFilterChecks)
+	}
+	return fmt.Sprintf("parts=%d, blocks scanned=%d, blocks matched=%d, rows scanned=%d, rows matched=%d, "+
+		"bloom filter checks=%d, blocks skipped by bloom filter=%d (%.2f%%)",
+		ss.partsScanned.Load(), ss.blocksScanned.Load(), ss.blocksMatched.Load(), ss.rowsScanned.Load(), ss.rowsMatched.Load(),
+		bloomFilterChecks, bloomFilterMisses, bloomFilterSkipPercent)
+}
+
+// pipeTraceStats contains stats for the pipe execution.
+//
+// It is used for query tracing.
+type pipeTraceStats struct {
+	// rowsIn is the number of rows passed to the pipe.
+	rowsIn atomic.Uint64
+
+	// duration is the total duration of writeBlock and flush calls for the pipe.
+	//
+	// It includes the duration of writeBlock calls for the next pipes, which are called from the pipe.
+	// It is summed across all the worker goroutines, so it may exceed the query duration.
+	duration atomic.Int64
+}
+
+// tracingPipeProcessor collects pipeTraceStats for the wrapped pipeProcessor.
+type tracingPipeProcessor struct {
+	pp    pipeProcessor
+	stats *pipeTraceStats
+}
+
+func newTracingPipeProcessor(pp pipeProcessor, stats *pipeTraceStats) pipeProcessor {
+	return &tracingPipeProcessor{
+		pp:    pp,
+		stats: stats,
+	}
+}
+
+func (tpp *tracingPipeProcessor) writeBlock(workerID uint, br *blockResult) {
+	// Read br.rowsLen before calling writeBlock, since the pipe may modify br.
+	tpp.stats.rowsIn.Add(uint64(br.rowsLen))
+	startTime := time.Now()
+	tpp.pp.writeBlock(workerID, br)
+	tpp.stats.duration.Add(int64(time.Since(startTime)))
+}
+
+func (tpp *tracingPipeProcessor) flush() error {
+	startTime := time.Now()
+	err := tpp.pp.flush()
+	tpp.stats.duration.Add(int64(time.Since(startTime)))
+	return err
+}
+
+// tracePipeStats adds per-pipe stats to qt.
+//
+// pss must contain stats for every pipe from pipes plus the stats for the final writer of the query results.
+func tracePipeStats(qt *querytracer.Tracer, pipes []pipe, pss []pipeTraceStats) {
+	for i, p := range pipes {
+		ps := &pss[i]
+		psNext := &pss[i+1]
+
+		// Subtract the duration of the next pipe, since it is called by the current pipe.
+		duration := time.Duration(ps.duration.Load() - psNext.duration.Load())
+		if duration < 0 {
+			duration = 0
+		}
+		rowsIn := ps.rowsIn.Load()
+		rowsOut := psNext.rowsIn.Load()
+		qt.Printf("pipe `%s`: rows in=%d, rows out=%d, duration=%.3fms", p, rowsIn, rowsOut, duration.Seconds()*1e3)
+	}
+	psLast := &pss[len(pss)-1]
+	qt.Printf("write results: rows=%d, duration=%.3fms", psLast.rowsIn.Load(), time.Duration(psLast.duration.Load()).Seconds()*1e3)
+}
@@ -242,6 +242,10 @@ func (shp *statsHistogramProcessor) finalizeStats(_ statsFunc, dst []byte, _ <-c
 		return stringsutil.LessNatural(vmranges[i], vmranges[j])
 	})
 
+	if len(vmranges) == 0 {
+		return append(dst, "[]"...)
+	}
+
 	dst = append(dst, '[')
 	for _, vmrange := range vmranges {
 		dst = append(dst, `{"vmrange":"`...)
@@ -23,6 +23,12 @@ type StorageStats struct {
 	// RowsDroppedTooSmallTimestamp is the number of rows dropped during data ingestion because their timestamp is bigger than the maximum allowed
 	RowsDroppedTooSmallTimestamp uint64
 
+	// RowsDroppedBySampling is the number of rows dropped during background merges according to StorageConfig.Sampling
+	RowsDroppedBySampling uint64
+
+	// EncryptedPartsSkipped is the number of times parts were skipped during the search because of missing encryption keys
+	EncryptedPartsSkipped uint64
+
 	// PartitionsCount is the number of partitions in the storage
 	PartitionsCount uint64
 
@@ -72,12 +78,24 @@ type StorageConfig struct {
 	//
 	// This can be useful for debugging of data ingestion.
 	LogIngestedRows bool
+
+	// Sampling is an optional config for sampling aged log entries during background merges.
+	Sampling *SamplingConfig
+
+	// BloomFilterAutoTuning enables automatic tuning of bloom filter size for new per-day partitions
+	// based on the bloom filter stats collected during queries over the previous partitions.
+	BloomFilterAutoTuning bool
+
+	// Encryption is an optional config with per-tenant keys for encrypting stored log blocks.
+	Encryption *EncryptionConfig
 }
 
 // Storage is the storage for log entries.
 type Storage struct {
 	rowsDroppedTooBigTimestamp   atomic.Uint64
 	rowsDroppedTooSmallTimestamp atomic.Uint64
+	rowsDroppedBySampling        atomic.Uint64
+	encryptedPartsSkipped        atomic.Uint64
 
 	// path is the path to the Storage directory
 	path string
@@ -107,6 +125,15 @@ type Storage struct {
 	// logIngestedRows instructs to log all the ingested log entries if it is set to true
 	logIngestedRows bool
 
+	// samplingConfig is an optional config for sampling aged log entries during background merges
+	samplingConfig *SamplingConfig
+
+	// bloomFilterAutoTuning enables automatic tuning of bloom filter size for new partitions
+	bloomFilterAutoTuning bool
+
+	// encryptionConfig is an optional config with per-tenant keys for encrypting stored log blocks
+	encryptionConfig *EncryptionConfig
+
 	// flockF is a file, which makes sure that the Storage is opened by a single process
 	flockF *os.File
 
@@ -254,6 +281,9 @@ func MustOpenStorage(path string, cfg *StorageConfig) *Storage {
 		minFreeDiskSpaceBytes:  minFreeDiskSpaceBytes,
 		logNewStreams:          cfg.LogNewStreams,
 		logIngestedRows:        cfg.LogIngestedRows,
+		samplingConfig:         cfg.Sampling,
+		bloomFilterAutoTuning:  cfg.BloomFilterAutoTuning,
+		encryptionConfig:       cfg.Encryption,
 		flockF:                 flockF,
 		stopCh:                 make(chan struct{}),
 
@@ -515,6 +545,25 @@ func (s *Storage) MustForceMerge(partitionNamePrefix string) {
 	}
 }
 
+// GetRowsCount returns the number of rows stored in s for the given tenantIDs.
+//
+// The number is obtained from per-part block headers, so it doesn't scan the stored logs.
+func (s *Storage) GetRowsCount(tenantIDs []TenantID) uint64 {
+	s.partitionsLock.Lock()
+	ptws := append([]*partitionWrapper{}, s.partitions...)
+	for _, ptw := range ptws {
+		ptw.incRef()
+	}
+	s.partitionsLock.Unlock()
+
+	n := uint64(0)
+	for _, ptw := range ptws {
+		n += ptw.pt.ddb.getRowsCount(tenantIDs)
+		ptw.decRef()
+	}
+	return n
+}
+
 // MustAddRows adds lr to s.
 //
 // It is recommended checking whether the s is in read-only mode by calling IsReadOnly()
@@ -579,6 +628,7 @@ func (s *Storage) MustAddRows(lr *LogRows) {
 }
 
 var tooSmallTimestampLogger = logger.WithThrottler("too_small_timestamp", 5*time.Second)
+var missingEncryptionKeyLogger = logger.WithThrottler("missing_encryption_key", 5*time.Second)
 var tooBigTimestampLogger = logger.WithThrottler("too_big_timestamp", 5*time.Second)
 
 // TimeFormatter implements fmt.Stringer for timestamp in nanoseconds
@@ -611,6 +661,15 @@ func (s *Storage) getPartitionForDay(day int64) *partitionWrapper {
 		fname := time.Unix(0, day*nsecsPerDay).UTC().Format(partitionNameFormat)
 		partitionPath := filepath.Join(s.path, partitionsDirname, fname)
 		mustCreatePartition(partitionPath)
+		if s.bloomFilterAutoTuning {
+			bitsPerItem := getTunedBloomFilterBitsPerItem(ptws)
+			mustWriteBloomFilterParams(partitionPath, &bloomFilterParams{
+				BitsPerItem: bitsPerItem,
+			})
+			if bitsPerItem != bloomFilterBitsPerItem {
+				logger.Infof("using %d bits per token for bloom filters at the partition %s according to bloom filter auto-tuning", bitsPerItem, partitionPath)
+			}
+		}
 
 		pt := mustOpenPartition(s, partitionPath)
 		ptw = newPartitionWrapper(pt, day)
@@ -635,6 +694,8 @@ func (s *Storage) getPartitionForDay(day int64) *partitionWrapper {
 func (s *Storage) UpdateStats(ss *StorageStats) {
 	ss.RowsDroppedTooBigTimestamp += s.rowsDroppedTooBigTimestamp.Load()
 	ss.RowsDroppedTooSmallTimestamp += s.rowsDroppedTooSmallTimestamp.Load()
+	ss.RowsDroppedBySampling += s.rowsDroppedBySampling.Load()
+	ss.EncryptedPartsSkipped += s.encryptedPartsSkipped.Load()
 
 	s.partitionsLock.Lock()
 	ss.PartitionsCount += uint64(len(s.partitions))
@@ -646,6 +707,35 @@ func (s *Storage) UpdateStats(ss *StorageStats) {
 	ss.IsReadOnly = s.IsReadOnly()
 }
 
+// PartitionInfo contains information about a single per-day partition.
+type PartitionInfo struct {
+	// Name is the partition name in the form YYYYMMDD.
+	Name string
+
+	// BloomFilterBitsPerItem is the number of bits per token for bloom filters at the newly created parts in the partition.
+	BloomFilterBitsPerItem int
+
+	// PartitionStats contains partition stats.
+	PartitionStats
+}
+
+// GetPartitionInfos returns information about partitions at s.
+//
+// The returned partitions are sorted by time.
+func (s *Storage) GetPartitionInfos() []PartitionInfo {
+	s.partitionsLock.Lock()
+	pis := make([]PartitionInfo, len(s.partitions))
+	for i, ptw := range s.partitions {
+		pi := &pis[i]
+		pi.Name = ptw.pt.name
+		pi.BloomFilterBitsPerItem = ptw.pt.bloomFilterBitsPerItem
+		ptw.pt.updateStats(&pi.PartitionStats)
+	}
+	s.partitionsLock.Unlock()
+
+	return pis
+}
+
 // IsReadOnly returns true if s is in read-only mode.
 func (s *Storage) IsReadOnly() bool {
 	available := fs.MustGetFreeSpace(s.path)
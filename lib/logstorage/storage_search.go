@@ -14,6 +14,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
 )
 
@@ -45,6 +46,18 @@ type genericSearchOptions struct {
 
 	// needAllColumns is set to true when all the columns except of unneededColumnNames must be returned in the result
 	needAllColumns bool
+
+	// stats is an optional stats for the search. It is set when query tracing is enabled.
+	stats *searchStats
+
+	// partitionStats contains per-partition stats for the search. It is filled by Storage.search when stats is set.
+	partitionStats []partitionSearchStats
+}
+
+// partitionSearchStats contains search stats for the partition with the given name.
+type partitionSearchStats struct {
+	name  string
+	stats *searchStats
 }
 
 type searchOptions struct {
@@ -75,6 +88,9 @@ type searchOptions struct {
 
 	// needAllColumns is set to true when all the columns except of unneededColumnNames must be returned in the result
 	needAllColumns bool
+
+	// stats is an optional stats for the search. It is set when query tracing is enabled.
+	stats *searchStats
 }
 
 // WriteDataBlockFunc must process the db.
@@ -112,15 +128,21 @@ func (f writeBlockResultFunc) newDataBlockWriter() WriteDataBlockFunc {
 }
 
 // RunQuery runs the given q and calls writeBlock for results.
-func (s *Storage) RunQuery(ctx context.Context, tenantIDs []TenantID, q *Query, writeBlock WriteDataBlockFunc) error {
+//
+// Query execution stats are added to qt if it is enabled.
+func (s *Storage) RunQuery(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, writeBlock WriteDataBlockFunc) error {
 	writeBlockResult := writeBlock.newBlockResultWriter()
-	return s.runQuery(ctx, tenantIDs, q, writeBlockResult)
+	return s.runQueryTraced(ctx, qt, tenantIDs, q, writeBlockResult)
 }
 
 // runQueryFunc must run the given q and pass query results to writeBlock
 type runQueryFunc func(ctx context.Context, tenantIDs []TenantID, q *Query, writeBlock writeBlockResultFunc) error
 
 func (s *Storage) runQuery(ctx context.Context, tenantIDs []TenantID, q *Query, writeBlock writeBlockResultFunc) error {
+	return s.runQueryTraced(ctx, nil, tenantIDs, q, writeBlock)
+}
+
+func (s *Storage) runQueryTraced(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, writeBlock writeBlockResultFunc) error {
 	qNew, err := initSubqueries(ctx, tenantIDs, q, s.runQuery, true)
 	if err != nil {
 		return err
@@ -145,21 +167,37 @@ func (s *Storage) runQuery(ctx context.Context, tenantIDs []TenantID, q *Query,
 		unneededColumnNames: unneededColumnNames,
 		needAllColumns:      slices.Contains(neededColumnNames, "*"),
 	}
+	if qt.Enabled() {
+		so.stats = &searchStats{}
+	}
 
 	workersCount := q.GetConcurrency()
 
+	qtChild := qt.NewChild("run query %s with concurrency=%d", q, workersCount)
+
 	search := func(stopCh <-chan struct{}, writeBlockToPipes writeBlockResultFunc) error {
 		s.search(workersCount, so, stopCh, writeBlockToPipes)
+		for _, ps := range so.partitionStats {
+			qtChild.Printf("partition %s: %s", ps.name, ps.stats)
+		}
+		if so.stats != nil {
+			qtChild.Printf("search across %d partitions: %s", len(so.partitionStats), so.stats)
+		}
 		return nil
 	}
 
-	return runPipes(ctx, q.pipes, search, writeBlock, workersCount)
+	err = runPipes(ctx, qtChild, q.pipes, search, writeBlock, workersCount)
+	qtChild.Done()
+	return err
 }
 
 // searchFunc must perform search and pass its results to writeBlock.
 type searchFunc func(stopCh <-chan struct{}, writeBlock writeBlockResultFunc) error
 
-func runPipes(ctx context.Context, pipes []pipe, search searchFunc, writeBlock writeBlockResultFunc, concurrency int) error {
+// runPipes passes search results through the given pipes and then to writeBlock.
+//
+// Per-pipe stats are added to qt if it is enabled.
+func runPipes(ctx context.Context, qt *querytracer.Tracer, pipes []pipe, search searchFunc, writeBlock writeBlockResultFunc, concurrency int) error {
 	stopCh := ctx.Done()
 	if len(pipes) == 0 {
 		// Fast path when there are no pipes
@@ -170,10 +208,20 @@ func runPipes(ctx context.Context, pipes []pipe, search searchFunc, writeBlock w
 	cancels := make([]func(), len(pipes))
 	pps := make([]pipeProcessor, len(pipes))
 
+	var pss []pipeTraceStats
+	if qt.Enabled() {
+		pss = make([]pipeTraceStats, len(pipes)+1)
+		defer tracePipeStats(qt, pipes, pss)
+		pp = newTracingPipeProcessor(pp, &pss[len(pipes)])
+	}
+
 	for i := len(pipes) - 1; i >= 0; i-- {
 		p := pipes[i]
 		ctxChild, cancel := context.WithCancel(ctx)
 		pp = p.newPipeProcessor(concurrency, stopCh, cancel, pp)
+		if pss != nil {
+			pp = newTracingPipeProcessor(pp, &pss[i])
+		}
 		cancels[i] = cancel
 		pps[i] = pp
 
@@ -200,7 +248,7 @@ func runPipes(ctx context.Context, pipes []pipe, search searchFunc, writeBlock w
 }
 
 // GetFieldNames returns field names from q results for the given tenantIDs.
-func (s *Storage) GetFieldNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+func (s *Storage) GetFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
 	pipes := append([]pipe{}, q.pipes...)
 	pipeStr := "field_names"
 	lex := newLexer(pipeStr, q.timestamp)
@@ -221,7 +269,7 @@ func (s *Storage) GetFieldNames(ctx context.Context, tenantIDs []TenantID, q *Qu
 	qNew := q.cloneShallow()
 	qNew.pipes = pipes
 
-	return s.runValuesWithHitsQuery(ctx, tenantIDs, qNew)
+	return s.runValuesWithHitsQuery(ctx, qt, tenantIDs, qNew)
 }
 
 func getJoinMapGeneric(ctx context.Context, tenantIDs []TenantID, q *Query, runQuery runQueryFunc, byFields []string, prefix string) (map[string][][]Field, error) {
@@ -367,7 +415,7 @@ func isLastPipeUniq(pipes []pipe) bool {
 // GetFieldValues returns unique values with the number of hits for the given fieldName returned by q for the given tenantIDs.
 //
 // If limit > 0, then up to limit unique values are returned.
-func (s *Storage) GetFieldValues(ctx context.Context, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
+func (s *Storage) GetFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
 	pipes := append([]pipe{}, q.pipes...)
 	quotedFieldName := quoteTokenIfNeeded(fieldName)
 	pipeStr := fmt.Sprintf("field_values %s limit %d", quotedFieldName, limit)
@@ -387,7 +435,7 @@ func (s *Storage) GetFieldValues(ctx context.Context, tenantIDs []TenantID, q *Q
 	qNew := q.cloneShallow()
 	qNew.pipes = pipes
 
-	return s.runValuesWithHitsQuery(ctx, tenantIDs, qNew)
+	return s.runValuesWithHitsQuery(ctx, qt, tenantIDs, qNew)
 }
 
 // ValueWithHits contains value and hits.
@@ -438,8 +486,8 @@ func toValuesWithHits(m map[string]*uint64) []ValueWithHits {
 }
 
 // GetStreamFieldNames returns stream field names from q results for the given tenantIDs.
-func (s *Storage) GetStreamFieldNames(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
-	streams, err := s.GetStreams(ctx, tenantIDs, q, math.MaxUint64)
+func (s *Storage) GetStreamFieldNames(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+	streams, err := s.GetStreams(ctx, qt, tenantIDs, q, math.MaxUint64)
 	if err != nil {
 		return nil, err
 	}
@@ -462,8 +510,8 @@ func (s *Storage) GetStreamFieldNames(ctx context.Context, tenantIDs []TenantID,
 // GetStreamFieldValues returns stream field values for the given fieldName from q results for the given tenantIDs.
 //
 // If limit > 0, then up to limit unique values are returned.
-func (s *Storage) GetStreamFieldValues(ctx context.Context, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
-	streams, err := s.GetStreams(ctx, tenantIDs, q, math.MaxUint64)
+func (s *Storage) GetStreamFieldValues(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, fieldName string, limit uint64) ([]ValueWithHits, error) {
+	streams, err := s.GetStreams(ctx, qt, tenantIDs, q, math.MaxUint64)
 	if err != nil {
 		return nil, err
 	}
@@ -493,18 +541,18 @@ func (s *Storage) GetStreamFieldValues(ctx context.Context, tenantIDs []TenantID
 // GetStreams returns streams from q results for the given tenantIDs.
 //
 // If limit > 0, then up to limit unique streams are returned.
-func (s *Storage) GetStreams(ctx context.Context, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
-	return s.GetFieldValues(ctx, tenantIDs, q, "_stream", limit)
+func (s *Storage) GetStreams(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
+	return s.GetFieldValues(ctx, qt, tenantIDs, q, "_stream", limit)
 }
 
 // GetStreamIDs returns stream_id field values from q results for the given tenantIDs.
 //
 // If limit > 0, then up to limit unique streams are returned.
-func (s *Storage) GetStreamIDs(ctx context.Context, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
-	return s.GetFieldValues(ctx, tenantIDs, q, "_stream_id", limit)
+func (s *Storage) GetStreamIDs(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query, limit uint64) ([]ValueWithHits, error) {
+	return s.GetFieldValues(ctx, qt, tenantIDs, q, "_stream_id", limit)
 }
 
-func (s *Storage) runValuesWithHitsQuery(ctx context.Context, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
+func (s *Storage) runValuesWithHitsQuery(ctx context.Context, qt *querytracer.Tracer, tenantIDs []TenantID, q *Query) ([]ValueWithHits, error) {
 	var results []ValueWithHits
 	var resultsLock sync.Mutex
 	writeBlockResult := func(_ uint, br *blockResult) {
@@ -533,7 +581,7 @@ func (s *Storage) runValuesWithHitsQuery(ctx context.Context, tenantIDs []Tenant
 		resultsLock.Unlock()
 	}
 
-	err := s.runQuery(ctx, tenantIDs, q, writeBlockResult)
+	err := s.runQueryTraced(ctx, qt, tenantIDs, q, writeBlockResult)
 	if err != nil {
 		return nil, err
 	}
@@ -1105,6 +1153,12 @@ func (s *Storage) search(workersCount int, so *genericSearchOptions, stopCh <-ch
 	// Obtain common filterStream from f
 	sf, f := getCommonStreamFilter(so.filter)
 
+	// Collect per-partition stats if needed.
+	var ptStats []searchStats
+	if so.stats != nil {
+		ptStats = make([]searchStats, len(ptws))
+	}
+
 	// Schedule concurrent search across matching partitions.
 	psfs := make([]partitionSearchFinalizer, len(ptws))
 	var wgSearchers sync.WaitGroup
@@ -1112,7 +1166,11 @@ func (s *Storage) search(workersCount int, so *genericSearchOptions, stopCh <-ch
 		partitionSearchConcurrencyLimitCh <- struct{}{}
 		wgSearchers.Add(1)
 		go func(idx int, pt *partition) {
-			psfs[idx] = pt.search(sf, f, so, workCh, stopCh)
+			var stats *searchStats
+			if ptStats != nil {
+				stats = &ptStats[idx]
+			}
+			psfs[idx] = pt.search(sf, f, so, stats, workCh, stopCh)
 			wgSearchers.Done()
 			<-partitionSearchConcurrencyLimitCh
 		}(i, ptw.pt)
@@ -1123,6 +1181,15 @@ func (s *Storage) search(workersCount int, so *genericSearchOptions, stopCh <-ch
 	close(workCh)
 	wgWorkers.Wait()
 
+	for i := range ptStats {
+		stats := &ptStats[i]
+		so.stats.add(stats)
+		so.partitionStats = append(so.partitionStats, partitionSearchStats{
+			name:  ptws[i].pt.name,
+			stats: stats,
+		})
+	}
+
 	// Finalize partition search
 	for _, psf := range psfs {
 		psf()
@@ -1141,7 +1208,7 @@ var partitionSearchConcurrencyLimitCh = make(chan struct{}, cgroup.AvailableCPUs
 
 type partitionSearchFinalizer func()
 
-func (pt *partition) search(sf *StreamFilter, f filter, so *genericSearchOptions, workCh chan<- *blockSearchWorkBatch, stopCh <-chan struct{}) partitionSearchFinalizer {
+func (pt *partition) search(sf *StreamFilter, f filter, so *genericSearchOptions, stats *searchStats, workCh chan<- *blockSearchWorkBatch, stopCh <-chan struct{}) partitionSearchFinalizer {
 	if needStop(stopCh) {
 		// Do not spend CPU time on search, since it is already stopped.
 		return func() {}
@@ -1171,6 +1238,7 @@ func (pt *partition) search(sf *StreamFilter, f filter, so *genericSearchOptions
 		neededColumnNames:   so.neededColumnNames,
 		unneededColumnNames: so.unneededColumnNames,
 		needAllColumns:      so.needAllColumns,
+		stats:               stats,
 	}
 	return pt.ddb.search(soInternal, workCh, stopCh)
 }
@@ -1248,8 +1316,15 @@ func (ddb *datadb) search(so *searchOptions, workCh chan<- *blockSearchWorkBatch
 	}
 	ddb.partsLock.Unlock()
 
+	so.stats.addPartsScanned(len(pws))
+
 	// Apply search to matching parts
 	for _, pw := range pws {
+		if err := pw.p.checkEncryptionKeysForSearch(so); err != nil {
+			ddb.pt.s.encryptedPartsSkipped.Add(1)
+			missingEncryptionKeyLogger.Errorf("skipping the part %s during the search: %s", pw.p.path, err)
+			continue
+		}
 		pw.p.search(so, workCh, stopCh)
 	}
 
@@ -1260,6 +1335,33 @@ func (ddb *datadb) search(so *searchOptions, workCh chan<- *blockSearchWorkBatch
 	}
 }
 
+// checkEncryptionKeysForSearch verifies whether all the keys needed for decrypting the blocks for the searched tenants at p are available.
+func (p *part) checkEncryptionKeysForSearch(so *searchOptions) error {
+	for _, k := range p.ph.EncryptionKeys {
+		if !so.hasTenantID(k.TenantID) {
+			continue
+		}
+		if err := p.getEncryptionConfig().checkKeysForDecryption([]partEncryptionKey{k}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasTenantID returns true if so may select logs for the given tenantID.
+func (so *searchOptions) hasTenantID(tenantID TenantID) bool {
+	if len(so.tenantIDs) > 0 {
+		n := sort.Search(len(so.tenantIDs), func(i int) bool {
+			return !so.tenantIDs[i].less(&tenantID)
+		})
+		return n < len(so.tenantIDs) && so.tenantIDs[n].equal(&tenantID)
+	}
+	n := sort.Search(len(so.streamIDs), func(i int) bool {
+		return !so.streamIDs[i].tenantID.less(&tenantID)
+	})
+	return n < len(so.streamIDs) && so.streamIDs[n].tenantID.equal(&tenantID)
+}
+
 func (p *part) search(so *searchOptions, workCh chan<- *blockSearchWorkBatch, stopCh <-chan struct{}) {
 	bhss := getBlockHeaders()
 	if len(so.tenantIDs) > 0 {
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 )
 
 func TestStorageRunQuery(t *testing.T) {
@@ -86,7 +87,7 @@ func TestStorageRunQuery(t *testing.T) {
 
 	mustRunQuery := func(t *testing.T, tenantIDs []TenantID, q *Query, writeBlock WriteDataBlockFunc) {
 		t.Helper()
-		err := s.RunQuery(context.Background(), tenantIDs, q, writeBlock)
+		err := s.RunQuery(context.Background(), nil, tenantIDs, q, writeBlock)
 		if err != nil {
 			t.Fatalf("unexpected error returned from the query [%s]: %s", q, err)
 		}
@@ -318,7 +319,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("field_names-all", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetFieldNames(context.Background(), allTenantIDs, q)
+		results, err := s.GetFieldNames(context.Background(), nil, allTenantIDs, q)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -340,7 +341,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("field_names-some", func(t *testing.T) {
 		q := mustParseQuery(`_stream:{instance=~"host-1:.+"}`)
-		results, err := s.GetFieldNames(context.Background(), allTenantIDs, q)
+		results, err := s.GetFieldNames(context.Background(), nil, allTenantIDs, q)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -362,7 +363,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("field_values-nolimit", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetFieldValues(context.Background(), allTenantIDs, q, "_stream", 0)
+		results, err := s.GetFieldValues(context.Background(), nil, allTenantIDs, q, "_stream", 0)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -378,7 +379,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("field_values-limit", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetFieldValues(context.Background(), allTenantIDs, q, "_stream", 3)
+		results, err := s.GetFieldValues(context.Background(), nil, allTenantIDs, q, "_stream", 3)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -394,7 +395,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("field_values-limit", func(t *testing.T) {
 		q := mustParseQuery("instance:='host-1:234'")
-		results, err := s.GetFieldValues(context.Background(), allTenantIDs, q, "_stream", 4)
+		results, err := s.GetFieldValues(context.Background(), nil, allTenantIDs, q, "_stream", 4)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -408,7 +409,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("stream_field_names", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetStreamFieldNames(context.Background(), allTenantIDs, q)
+		results, err := s.GetStreamFieldNames(context.Background(), nil, allTenantIDs, q)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -423,7 +424,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("stream_field_values-nolimit", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetStreamFieldValues(context.Background(), allTenantIDs, q, "instance", 0)
+		results, err := s.GetStreamFieldValues(context.Background(), nil, allTenantIDs, q, "instance", 0)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -439,7 +440,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("stream_field_values-limit", func(t *testing.T) {
 		q := mustParseQuery("*")
-		values, err := s.GetStreamFieldValues(context.Background(), allTenantIDs, q, "instance", 3)
+		values, err := s.GetStreamFieldValues(context.Background(), nil, allTenantIDs, q, "instance", 3)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -455,7 +456,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("streams", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetStreams(context.Background(), allTenantIDs, q, 0)
+		results, err := s.GetStreams(context.Background(), nil, allTenantIDs, q, 0)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -471,7 +472,7 @@ func TestStorageRunQuery(t *testing.T) {
 	})
 	t.Run("stream_ids", func(t *testing.T) {
 		q := mustParseQuery("*")
-		results, err := s.GetStreamIDs(context.Background(), allTenantIDs, q, 0)
+		results, err := s.GetStreamIDs(context.Background(), nil, allTenantIDs, q, 0)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
@@ -531,6 +532,26 @@ func TestStorageRunQuery(t *testing.T) {
 			},
 		})
 	})
+	t.Run("query-trace", func(t *testing.T) {
+		q := mustParseQuery(`"message 3" | stats count() rows`)
+		qt := querytracer.New(true, "test")
+		writeBlock := func(_ uint, _ *DataBlock) {}
+		if err := s.RunQuery(context.Background(), qt, allTenantIDs, q, writeBlock); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		qt.Done()
+
+		trace := qt.String()
+		for _, substr := range []string{
+			"rows scanned=1155, rows matched=165,",
+			"pipe `stats count(*) as rows`: rows in=165, rows out=1,",
+			"write results: rows=1,",
+		} {
+			if !strings.Contains(trace, substr) {
+				t.Fatalf("missing %q in the trace:\n%s", substr, trace)
+			}
+		}
+	})
 	t.Run("_stream_id-filter", func(t *testing.T) {
 		f(t, `_stream_id:in(tenant.id:2 | fields _stream_id) | stats count() rows`, [][]Field{
 			{
@@ -1,6 +1,7 @@
 package logstorage
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -20,6 +21,72 @@ func TestStorageLifecycle(t *testing.T) {
 	fs.MustRemoveAll(path)
 }
 
+func TestStorageGetRowsCount(t *testing.T) {
+	t.Parallel()
+
+	path := t.Name()
+
+	cfg := &StorageConfig{}
+	s := MustOpenStorage(path, cfg)
+
+	tenantIDs := []TenantID{
+		{AccountID: 1, ProjectID: 2},
+		{AccountID: 3, ProjectID: 4},
+	}
+	tenantIDMissing := TenantID{AccountID: 5, ProjectID: 6}
+
+	f := func(tenantIDs []TenantID, rowsCountExpected uint64) {
+		t.Helper()
+		if n := s.GetRowsCount(tenantIDs); n != rowsCountExpected {
+			t.Fatalf("unexpected rows count for tenantIDs %v; got %d; want %d", tenantIDs, n, rowsCountExpected)
+		}
+	}
+
+	f(tenantIDs, 0)
+
+	// Add rows in multiple batches, so they are spread among multiple parts.
+	for i := 0; i < 10; i++ {
+		lr := GetLogRows([]string{"job"}, nil, nil, "")
+		now := time.Now().UTC().UnixNano()
+		for j, tenantID := range tenantIDs {
+			for k := 0; k < (j+1)*100; k++ {
+				fields := []Field{
+					{
+						Name:  "job",
+						Value: fmt.Sprintf("job-%d", k%3),
+					},
+					{
+						Name:  "_msg",
+						Value: fmt.Sprintf("message %d", k),
+					},
+				}
+				lr.MustAdd(tenantID, now, fields, nil)
+			}
+		}
+		s.MustAddRows(lr)
+		PutLogRows(lr)
+	}
+
+	f(tenantIDs[:1], 1000)
+	f(tenantIDs[1:], 2000)
+	f(tenantIDs, 3000)
+	f([]TenantID{tenantIDMissing}, 0)
+
+	// Verify that the rows count remains the same after the merge and re-opening the storage.
+	s.MustForceMerge("")
+	f(tenantIDs, 3000)
+
+	s.MustClose()
+	s = MustOpenStorage(path, cfg)
+
+	f(tenantIDs[:1], 1000)
+	f(tenantIDs[1:], 2000)
+	f([]TenantID{tenantIDMissing}, 0)
+
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
+
 func TestStorageMustAddRows(t *testing.T) {
 	t.Parallel()
 
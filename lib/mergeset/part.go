@@ -2,7 +2,7 @@ package mergeset
 
 import (
 	"path/filepath"
-	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/blockcache"
@@ -17,58 +17,51 @@ var ibCache = blockcache.NewCache(getMaxInmemoryBlocksCacheSize)
 var ibSparseCache = blockcache.NewCache(getMaxInmemoryBlocksSparseCacheSize)
 
 // SetIndexBlocksCacheSize overrides the default size of indexdb/indexBlocks cache
+//
+// It may be called at any time. Zero or negative size resets the cache size to the default value.
 func SetIndexBlocksCacheSize(size int) {
-	maxIndexBlockCacheSize = size
+	maxIndexBlockCacheSize.Store(int64(size))
 }
 
 func getMaxIndexBlocksCacheSize() int {
-	maxIndexBlockCacheSizeOnce.Do(func() {
-		if maxIndexBlockCacheSize <= 0 {
-			maxIndexBlockCacheSize = int(0.10 * float64(memory.Allowed()))
-		}
-	})
-	return maxIndexBlockCacheSize
+	if n := maxIndexBlockCacheSize.Load(); n > 0 {
+		return int(n)
+	}
+	return int(0.10 * float64(memory.Allowed()))
 }
 
-var (
-	maxIndexBlockCacheSize     int
-	maxIndexBlockCacheSizeOnce sync.Once
-)
-
 // SetDataBlocksCacheSize overrides the default size of indexdb/dataBlocks cache
+//
+// It may be called at any time. Zero or negative size resets the cache size to the default value.
 func SetDataBlocksCacheSize(size int) {
-	maxInmemoryBlockCacheSize = size
+	maxInmemoryBlockCacheSize.Store(int64(size))
 }
 
 func getMaxInmemoryBlocksCacheSize() int {
-	maxInmemoryBlockCacheSizeOnce.Do(func() {
-		if maxInmemoryBlockCacheSize <= 0 {
-			maxInmemoryBlockCacheSize = int(0.25 * float64(memory.Allowed()))
-		}
-	})
-	return maxInmemoryBlockCacheSize
+	if n := maxInmemoryBlockCacheSize.Load(); n > 0 {
+		return int(n)
+	}
+	return int(0.25 * float64(memory.Allowed()))
 }
 
 // SetDataBlocksSparseCacheSize overrides the default size of indexdb/dataBlocksSparse cache
+//
+// It may be called at any time. Zero or negative size resets the cache size to the default value.
 func SetDataBlocksSparseCacheSize(size int) {
-	maxInmemorySparseMergeCacheSize = size
+	maxInmemorySparseMergeCacheSize.Store(int64(size))
 }
 
 func getMaxInmemoryBlocksSparseCacheSize() int {
-	maxInmemoryBlockSparseCacheSizeOnce.Do(func() {
-		if maxInmemorySparseMergeCacheSize <= 0 {
-			maxInmemorySparseMergeCacheSize = int(0.05 * float64(memory.Allowed()))
-		}
-	})
-	return maxInmemorySparseMergeCacheSize
+	if n := maxInmemorySparseMergeCacheSize.Load(); n > 0 {
+		return int(n)
+	}
+	return int(0.05 * float64(memory.Allowed()))
 }
 
 var (
-	maxInmemoryBlockCacheSize     int
-	maxInmemoryBlockCacheSizeOnce sync.Once
-
-	maxInmemorySparseMergeCacheSize     int
-	maxInmemoryBlockSparseCacheSizeOnce sync.Once
+	maxIndexBlockCacheSize          atomic.Int64
+	maxInmemoryBlockCacheSize       atomic.Int64
+	maxInmemorySparseMergeCacheSize atomic.Int64
 )
 
 type part struct {
@@ -2,7 +2,9 @@ package netutil
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,6 +40,26 @@ func GetServerTLSConfig(tlsCertFile, tlsKeyFile, tlsMinVersion string, tlsCipher
 	return cfg, nil
 }
 
+// SetServerMTLSConfig configures cfg to require and verify client certificates.
+//
+// Client certificates are verified with the TLS Root CA from caFile. The host system TLS Root CA is used if caFile is empty.
+func SetServerMTLSConfig(cfg *tls.Config, caFile string) error {
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if caFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return fmt.Errorf("cannot read TLS Root CA file: %w", err)
+	}
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM(data) {
+		return fmt.Errorf("cannot parse TLS Root CA from %q", caFile)
+	}
+	cfg.ClientCAs = cp
+	return nil
+}
+
 func newGetCertificateFunc(tlsCertFile, tlsKeyFile string) func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	var certLock sync.Mutex
 	var certDeadline uint64
@@ -0,0 +1,38 @@
+package persistentqueue
+
+// BlockCodec encodes blocks before writing them to chunk files and decodes them after reading from chunk files.
+//
+// Encoded blocks may refer to the state built from the previously encoded blocks in the same chunk file,
+// e.g. a dictionary of repeated values. The state never crosses chunk file boundaries, so the chunk file
+// can be decoded after restart by replaying it from the beginning.
+type BlockCodec interface {
+	// NewEncoder returns new encoder for blocks written to a chunk file.
+	NewEncoder() BlockEncoder
+
+	// NewDecoder returns new decoder for blocks read from a chunk file.
+	NewDecoder() BlockDecoder
+}
+
+// BlockEncoder encodes blocks written to a chunk file.
+//
+// The encoder may start writing into the middle of the chunk file after restart,
+// so the first block encoded by a new encoder must reset the state of the decoder.
+type BlockEncoder interface {
+	// EncodeBlock appends the encoded block to dst and returns the result.
+	//
+	// It may return false if the block cannot be encoded or if the encoding doesn't reduce the block size.
+	// The encoder state mustn't change in this case, since the block is written to the chunk file as is.
+	// The encoded block size mustn't exceed the maximum block size for the queue.
+	EncodeBlock(dst, block []byte) ([]byte, bool)
+}
+
+// BlockDecoder decodes blocks read from a chunk file in the order they were written.
+type BlockDecoder interface {
+	// DecodeBlock appends the decoded block from src to dst and returns the result.
+	DecodeBlock(dst, src []byte) ([]byte, error)
+}
+
+// encodedBlockFlag is set in the block header for blocks encoded with BlockCodec.
+//
+// The block size is limited by MaxBlockSize, so the highest bit of the block header is always zero for blocks written as is.
+const encodedBlockFlag = 1 << 63
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
@@ -11,6 +12,52 @@ import (
 	"github.com/VictoriaMetrics/metrics"
 )
 
+// DropPolicy defines which data must be dropped when the file-based queue reaches the maximum size.
+type DropPolicy int
+
+const (
+	// DropOldest drops the oldest data from the file-based queue in order to free space for new data.
+	DropOldest DropPolicy = iota
+
+	// DropNewest drops new data when there is no free space left in the file-based queue.
+	DropNewest
+
+	// BlockWithTimeout blocks writers until there is free space in the file-based queue.
+	// New data is dropped if there is no free space in the queue after the configured timeout.
+	BlockWithTimeout
+)
+
+// ParseDropPolicy parses DropPolicy from s.
+//
+// Supported values: drop-oldest, drop-newest, block-with-timeout.
+// DropOldest is returned for an empty s.
+func ParseDropPolicy(s string) (DropPolicy, error) {
+	switch s {
+	case "", "drop-oldest":
+		return DropOldest, nil
+	case "drop-newest":
+		return DropNewest, nil
+	case "block-with-timeout":
+		return BlockWithTimeout, nil
+	default:
+		return DropOldest, fmt.Errorf("unsupported drop policy %q; supported values: drop-oldest, drop-newest, block-with-timeout", s)
+	}
+}
+
+// String returns string representation for dp.
+func (dp DropPolicy) String() string {
+	switch dp {
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	case BlockWithTimeout:
+		return "block-with-timeout"
+	default:
+		return fmt.Sprintf("DropPolicy(%d)", int(dp))
+	}
+}
+
 // FastQueue is fast persistent queue, which prefers sending data via memory.
 //
 // It falls back to sending data via file when readers don't catch up with writers.
@@ -20,14 +67,25 @@ type FastQueue struct {
 
 	// cond is used for notifying blocked readers when new data has been added
 	// or when MustClose is called.
+	//
+	// It is also used for notifying writers blocked in waitForFreeSpaceLocked when readers free up space.
 	cond sync.Cond
 
+	// blockedWriters is the number of writers waiting for free space in waitForFreeSpaceLocked.
+	blockedWriters int
+
 	// isPQDisabled is set to true when pq is disabled.
 	isPQDisabled bool
 
 	// pq is file-based queue
 	pq *queue
 
+	// dropPolicy defines which data to drop when pq reaches the maximum size.
+	dropPolicy DropPolicy
+
+	// blockTimeout is the maximum duration for waiting for free space in pq when dropPolicy is BlockWithTimeout.
+	blockTimeout time.Duration
+
 	// ch is in-memory queue
 	ch chan *bytesutil.ByteBuffer
 
@@ -43,15 +101,19 @@ type FastQueue struct {
 // It holds up to maxInmemoryBlocks in memory before falling back to file-based persistence.
 //
 // if maxPendingBytes is 0, then the queue size is unlimited.
-// Otherwise its size is limited by maxPendingBytes. The oldest data is dropped when the queue
-// reaches maxPendingSize.
+// Otherwise its size is limited by maxPendingBytes. The data is dropped according to dropPolicy when the queue
+// reaches maxPendingSize. blockTimeout is used only if dropPolicy is BlockWithTimeout.
 // if isPQDisabled is set to true, then write requests that exceed in-memory buffer capacity are rejected.
 // in-memory queue part can be stored on disk during graceful shutdown.
-func MustOpenFastQueue(path, name string, maxInmemoryBlocks int, maxPendingBytes int64, isPQDisabled bool) *FastQueue {
-	pq := mustOpen(path, name, maxPendingBytes)
+// if codec isn't nil, then it is used for encoding blocks stored on disk. See BlockCodec.
+func MustOpenFastQueue(path, name string, maxInmemoryBlocks int, maxPendingBytes int64, isPQDisabled bool, dropPolicy DropPolicy, blockTimeout time.Duration, codec BlockCodec) *FastQueue {
+	pq := mustOpen(path, name, maxPendingBytes, codec)
+	pq.dropNewest = dropPolicy != DropOldest
 	fq := &FastQueue{
 		pq:           pq,
 		isPQDisabled: isPQDisabled,
+		dropPolicy:   dropPolicy,
+		blockTimeout: blockTimeout,
 		ch:           make(chan *bytesutil.ByteBuffer, maxInmemoryBlocks),
 	}
 	fq.cond.L = &fq.mu
@@ -68,7 +130,8 @@ func MustOpenFastQueue(path, name string, maxInmemoryBlocks int, maxPendingBytes
 	if isPQDisabled {
 		persistenceStatus = "disabled"
 	}
-	logger.Infof("opened fast queue at %q with maxInmemoryBlocks=%d, it contains %d pending bytes, persistence is %s", path, maxInmemoryBlocks, pendingBytes, persistenceStatus)
+	logger.Infof("opened fast queue at %q with maxInmemoryBlocks=%d, dropPolicy=%s, it contains %d pending bytes, persistence is %s",
+		path, maxInmemoryBlocks, dropPolicy, pendingBytes, persistenceStatus)
 	return fq
 }
 
@@ -87,6 +150,17 @@ func (fq *FastQueue) IsWriteBlocked() bool {
 	return len(fq.ch) == cap(fq.ch) || fq.pq.GetPendingBytes() > 0
 }
 
+// GetInmemoryQueueUtilization returns the fraction of in-memory queue capacity occupied by pending blocks.
+//
+// The returned value is in the range [0..1].
+func (fq *FastQueue) GetInmemoryQueueUtilization() float64 {
+	n := cap(fq.ch)
+	if n == 0 {
+		return 0
+	}
+	return float64(len(fq.ch)) / float64(n)
+}
+
 // UnblockAllReaders unblocks all the readers.
 func (fq *FastQueue) UnblockAllReaders() {
 	fq.mu.Lock()
@@ -117,6 +191,18 @@ func (fq *FastQueue) MustClose() {
 	logger.Infof("closed fast persistent queue at %q", fq.pq.dir)
 }
 
+// FlushInmemoryBlocksToFile moves all the pending in-memory blocks to the file-based queue.
+//
+// It is a no-op if the file-based queue is disabled.
+func (fq *FastQueue) FlushInmemoryBlocksToFile() {
+	if fq.isPQDisabled {
+		return
+	}
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	fq.flushInmemoryBlocksToFileLocked()
+}
+
 func (fq *FastQueue) flushInmemoryBlocksToFileIfNeededLocked() {
 	if len(fq.ch) == 0 || fq.isPQDisabled {
 		return
@@ -180,6 +266,9 @@ func (fq *FastQueue) tryWriteBlock(block []byte, ignoreDisabledPQ bool) bool {
 	defer fq.mu.Unlock()
 
 	isPQWriteAllowed := !fq.isPQDisabled || ignoreDisabledPQ
+	if isPQWriteAllowed && !ignoreDisabledPQ {
+		fq.waitForFreeSpaceLocked(uint64(len(block)))
+	}
 
 	fq.flushInmemoryBlocksToFileIfNeededLocked()
 	if n := fq.pq.GetPendingBytes(); n > 0 {
@@ -211,10 +300,65 @@ func (fq *FastQueue) tryWriteBlock(block []byte, ignoreDisabledPQ bool) bool {
 
 	// Notify potentially blocked reader.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/484 for the context.
-	fq.cond.Signal()
+	if fq.blockedWriters > 0 {
+		// Signal() may wake up a blocked writer instead of a reader, so wake up all the waiters.
+		fq.cond.Broadcast()
+	} else {
+		fq.cond.Signal()
+	}
 	return true
 }
 
+// waitForFreeSpaceLocked waits until the file-based queue has free space for the block with the given size
+// if fq.dropPolicy is BlockWithTimeout.
+//
+// It waits for up to fq.blockTimeout. The block is dropped by fq.pq if there is no free space after the timeout.
+func (fq *FastQueue) waitForFreeSpaceLocked(blockSize uint64) {
+	// fq.mu must be locked by the caller.
+	if fq.dropPolicy != BlockWithTimeout {
+		return
+	}
+	deadline := time.Now().Add(fq.blockTimeout)
+	var t *time.Timer
+	for fq.stopDeadline == 0 {
+		if fq.pq.GetPendingBytes() == 0 && len(fq.ch) < cap(fq.ch) {
+			// The block is going to be put into the in-memory queue.
+			break
+		}
+		// The block and the in-memory blocks are going to be written to the file-based queue.
+		size := fq.pendingInmemoryBytes + 8*uint64(len(fq.ch)) + blockSize + 8
+		if fq.pq.hasFreeSpace(size) {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		if t == nil {
+			// Wake up the writer when the timeout expires, since sync.Cond doesn't support waiting with timeout.
+			t = time.AfterFunc(fq.blockTimeout, func() {
+				fq.mu.Lock()
+				fq.cond.Broadcast()
+				fq.mu.Unlock()
+			})
+		}
+		// Wait until readers free up space in the queue.
+		fq.blockedWriters++
+		fq.cond.Wait()
+		fq.blockedWriters--
+	}
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// notifyBlockedWritersLocked notifies writers blocked in waitForFreeSpaceLocked after the space in fq has been freed.
+func (fq *FastQueue) notifyBlockedWritersLocked() {
+	// fq.mu must be locked by the caller.
+	if fq.blockedWriters > 0 {
+		fq.cond.Broadcast()
+	}
+}
+
 // MustReadBlock reads the next block from fq to dst and returns it.
 func (fq *FastQueue) MustReadBlock(dst []byte) ([]byte, bool) {
 	fq.mu.Lock()
@@ -233,11 +377,13 @@ func (fq *FastQueue) MustReadBlock(dst []byte) ([]byte, bool) {
 			fq.lastInmemoryBlockReadTime = fasttime.UnixTimestamp()
 			dst = append(dst, bb.B...)
 			blockBufPool.Put(bb)
+			fq.notifyBlockedWritersLocked()
 			return dst, true
 		}
 		if n := fq.pq.GetPendingBytes(); n > 0 {
 			data, ok := fq.pq.MustReadBlockNonblocking(dst)
 			if ok {
+				fq.notifyBlockedWritersLocked()
 				return data, true
 			}
 			dst = data
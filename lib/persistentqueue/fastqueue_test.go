@@ -11,7 +11,7 @@ func TestFastQueueOpenClose(_ *testing.T) {
 	path := "fast-queue-open-close"
 	mustDeleteDir(path)
 	for i := 0; i < 10; i++ {
-		fq := MustOpenFastQueue(path, "foobar", 100, 0, false)
+		fq := MustOpenFastQueue(path, "foobar", 100, 0, false, DropOldest, 0, nil)
 		fq.MustClose()
 	}
 	mustDeleteDir(path)
@@ -22,10 +22,13 @@ func TestFastQueueWriteReadInmemory(t *testing.T) {
 	mustDeleteDir(path)
 
 	capacity := 100
-	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false)
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
 	if n := fq.GetInmemoryQueueLen(); n != 0 {
 		t.Fatalf("unexpected non-zero inmemory queue size:  %d", n)
 	}
+	if n := fq.GetInmemoryQueueUtilization(); n != 0 {
+		t.Fatalf("unexpected non-zero inmemory queue utilization: %v", n)
+	}
 	var blocks []string
 	for i := 0; i < capacity; i++ {
 		block := fmt.Sprintf("block %d", i)
@@ -37,6 +40,9 @@ func TestFastQueueWriteReadInmemory(t *testing.T) {
 	if n := fq.GetInmemoryQueueLen(); n != capacity {
 		t.Fatalf("unexpected size of inmemory queue; got %d; want %d", n, capacity)
 	}
+	if n := fq.GetInmemoryQueueUtilization(); n != 1 {
+		t.Fatalf("unexpected inmemory queue utilization; got %v; want 1", n)
+	}
 	for _, block := range blocks {
 		buf, ok := fq.MustReadBlock(nil)
 		if !ok {
@@ -55,7 +61,7 @@ func TestFastQueueWriteReadMixed(t *testing.T) {
 	mustDeleteDir(path)
 
 	capacity := 100
-	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false)
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
 	if n := fq.GetPendingBytes(); n != 0 {
 		t.Fatalf("the number of pending bytes must be 0; got %d", n)
 	}
@@ -91,7 +97,7 @@ func TestFastQueueWriteReadWithCloses(t *testing.T) {
 	mustDeleteDir(path)
 
 	capacity := 100
-	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false)
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
 	if n := fq.GetPendingBytes(); n != 0 {
 		t.Fatalf("the number of pending bytes must be 0; got %d", n)
 	}
@@ -104,7 +110,7 @@ func TestFastQueueWriteReadWithCloses(t *testing.T) {
 
 		blocks = append(blocks, block)
 		fq.MustClose()
-		fq = MustOpenFastQueue(path, "foobar", capacity, 0, false)
+		fq = MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
 	}
 	if n := fq.GetPendingBytes(); n == 0 {
 		t.Fatalf("the number of pending bytes must be greater than 0")
@@ -118,7 +124,7 @@ func TestFastQueueWriteReadWithCloses(t *testing.T) {
 			t.Fatalf("unexpected block read; got %q; want %q", buf, block)
 		}
 		fq.MustClose()
-		fq = MustOpenFastQueue(path, "foobar", capacity, 0, false)
+		fq = MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
 	}
 	if n := fq.GetPendingBytes(); n != 0 {
 		t.Fatalf("the number of pending bytes must be 0; got %d", n)
@@ -131,7 +137,7 @@ func TestFastQueueReadUnblockByClose(t *testing.T) {
 	path := "fast-queue-read-unblock-by-close"
 	mustDeleteDir(path)
 
-	fq := MustOpenFastQueue(path, "foorbar", 123, 0, false)
+	fq := MustOpenFastQueue(path, "foorbar", 123, 0, false, DropOldest, 0, nil)
 	resultCh := make(chan error)
 	go func() {
 		data, ok := fq.MustReadBlock(nil)
@@ -161,7 +167,7 @@ func TestFastQueueReadUnblockByWrite(t *testing.T) {
 	path := "fast-queue-read-unblock-by-write"
 	mustDeleteDir(path)
 
-	fq := MustOpenFastQueue(path, "foobar", 13, 0, false)
+	fq := MustOpenFastQueue(path, "foobar", 13, 0, false, DropOldest, 0, nil)
 	block := "foodsafdsaf sdf"
 	resultCh := make(chan error)
 	go func() {
@@ -195,7 +201,7 @@ func TestFastQueueReadWriteConcurrent(t *testing.T) {
 	path := "fast-queue-read-write-concurrent"
 	mustDeleteDir(path)
 
-	fq := MustOpenFastQueue(path, "foobar", 5, 0, false)
+	fq := MustOpenFastQueue(path, "foobar", 5, 0, false, DropOldest, 0, nil)
 
 	var blocks []string
 	blocksMap := make(map[string]bool)
@@ -261,7 +267,7 @@ func TestFastQueueReadWriteConcurrent(t *testing.T) {
 	readersWG.Wait()
 
 	// Collect the remaining data
-	fq = MustOpenFastQueue(path, "foobar", 5, 0, false)
+	fq = MustOpenFastQueue(path, "foobar", 5, 0, false, DropOldest, 0, nil)
 	resultCh := make(chan error)
 	go func() {
 		for len(blocksMap) > 0 {
@@ -295,7 +301,7 @@ func TestFastQueueWriteReadWithDisabledPQ(t *testing.T) {
 	mustDeleteDir(path)
 
 	capacity := 20
-	fq := MustOpenFastQueue(path, "foobar", capacity, 0, true)
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, true, DropOldest, 0, nil)
 	if n := fq.GetInmemoryQueueLen(); n != 0 {
 		t.Fatalf("unexpected non-zero inmemory queue size:  %d", n)
 	}
@@ -312,7 +318,7 @@ func TestFastQueueWriteReadWithDisabledPQ(t *testing.T) {
 	}
 
 	fq.MustClose()
-	fq = MustOpenFastQueue(path, "foobar", capacity, 0, true)
+	fq = MustOpenFastQueue(path, "foobar", capacity, 0, true, DropOldest, 0, nil)
 	for _, block := range blocks {
 		buf, ok := fq.MustReadBlock(nil)
 		if !ok {
@@ -326,12 +332,117 @@ func TestFastQueueWriteReadWithDisabledPQ(t *testing.T) {
 	mustDeleteDir(path)
 }
 
+func TestFastQueueBlockWithTimeout(t *testing.T) {
+	path := "fast-queue-block-with-timeout"
+	mustDeleteDir(path)
+
+	const blockTimeout = 100 * time.Millisecond
+	fq := MustOpenFastQueue(path, "foobar", 1, 1000, false, BlockWithTimeout, blockTimeout, nil)
+	defer func() {
+		fq.MustClose()
+		mustDeleteDir(path)
+	}()
+
+	block := make([]byte, 400)
+	for i := 0; i < 2; i++ {
+		if !fq.TryWriteBlock(block) {
+			t.Fatalf("TryWriteBlock must return true in this context")
+		}
+	}
+
+	// The next block must be blocked until the timeout, since there is no free space in the queue.
+	startTime := time.Now()
+	if !fq.TryWriteBlock(block) {
+		t.Fatalf("TryWriteBlock must return true in this context")
+	}
+	if d := time.Since(startTime); d < blockTimeout {
+		t.Fatalf("TryWriteBlock must be blocked for at least %s; it was blocked for %s", blockTimeout, d)
+	}
+
+	// The block must be dropped after the timeout
+	for i := 0; i < 2; i++ {
+		if _, ok := fq.MustReadBlock(nil); !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+	}
+	if n := fq.GetPendingBytes(); n != 0 {
+		t.Fatalf("unexpected non-empty queue; it contains %d pending bytes", n)
+	}
+}
+
+func TestFastQueueBlockWithTimeout_UnblockOnRead(t *testing.T) {
+	path := "fast-queue-block-with-timeout-unblock-on-read"
+	mustDeleteDir(path)
+
+	fq := MustOpenFastQueue(path, "foobar", 1, 1000, false, BlockWithTimeout, time.Hour, nil)
+	defer func() {
+		fq.MustClose()
+		mustDeleteDir(path)
+	}()
+
+	block := make([]byte, 400)
+	for i := 0; i < 2; i++ {
+		if !fq.TryWriteBlock(block) {
+			t.Fatalf("TryWriteBlock must return true in this context")
+		}
+	}
+
+	// The blocked writer must be unblocked as soon as the reader frees up space in the queue.
+	doneCh := make(chan struct{})
+	go func() {
+		if !fq.TryWriteBlock(block) {
+			panic(fmt.Errorf("TryWriteBlock must return true in this context"))
+		}
+		close(doneCh)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := fq.MustReadBlock(nil); !ok {
+		t.Fatalf("unexpected ok=false")
+	}
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout when waiting for the blocked writer")
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := fq.MustReadBlock(nil); !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+	}
+	if n := fq.GetPendingBytes(); n != 0 {
+		t.Fatalf("unexpected non-empty queue; it contains %d pending bytes", n)
+	}
+}
+
+func TestParseDropPolicy(t *testing.T) {
+	f := func(s string, dpExpected DropPolicy) {
+		t.Helper()
+
+		dp, err := ParseDropPolicy(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if dp != dpExpected {
+			t.Fatalf("unexpected drop policy; got %s; want %s", dp, dpExpected)
+		}
+	}
+
+	f("", DropOldest)
+	f("drop-oldest", DropOldest)
+	f("drop-newest", DropNewest)
+	f("block-with-timeout", BlockWithTimeout)
+
+	if _, err := ParseDropPolicy("foobar"); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
 func TestFastQueueWriteReadWithIgnoreDisabledPQ(t *testing.T) {
 	path := "fast-queue-write-read-inmemory-disabled-pq-force-write"
 	mustDeleteDir(path)
 
 	capacity := 20
-	fq := MustOpenFastQueue(path, "foobar", capacity, 0, true)
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, true, DropOldest, 0, nil)
 	if n := fq.GetInmemoryQueueLen(); n != 0 {
 		t.Fatalf("unexpected non-zero inmemory queue size:  %d", n)
 	}
@@ -353,7 +464,49 @@ func TestFastQueueWriteReadWithIgnoreDisabledPQ(t *testing.T) {
 	}
 
 	fq.MustClose()
-	fq = MustOpenFastQueue(path, "foobar", capacity, 0, true)
+	fq = MustOpenFastQueue(path, "foobar", capacity, 0, true, DropOldest, 0, nil)
+	for _, block := range blocks {
+		buf, ok := fq.MustReadBlock(nil)
+		if !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+		if string(buf) != block {
+			t.Fatalf("unexpected block read; got %q; want %q", buf, block)
+		}
+	}
+	fq.MustClose()
+	mustDeleteDir(path)
+}
+
+func TestFastQueueFlushInmemoryBlocksToFile(t *testing.T) {
+	path := "fast-queue-flush-inmemory-blocks-to-file"
+	mustDeleteDir(path)
+
+	capacity := 100
+	fq := MustOpenFastQueue(path, "foobar", capacity, 0, false, DropOldest, 0, nil)
+	var blocks []string
+	for i := 0; i < 10; i++ {
+		block := fmt.Sprintf("block %d", i)
+		if !fq.TryWriteBlock([]byte(block)) {
+			t.Fatalf("TryWriteBlock must return true in this context")
+		}
+		blocks = append(blocks, block)
+	}
+	pendingBytes := fq.GetPendingBytes()
+	fq.FlushInmemoryBlocksToFile()
+	if n := fq.GetInmemoryQueueLen(); n != 0 {
+		t.Fatalf("unexpected non-zero inmemory queue size after the flush: %d", n)
+	}
+	if n := fq.GetPendingBytes(); n < pendingBytes {
+		t.Fatalf("the number of pending bytes mustn't decrease after the flush; got %d; want at least %d", n, pendingBytes)
+	}
+
+	// New blocks must be written to the file-based queue after the previously flushed blocks.
+	block := "block after flush"
+	if !fq.TryWriteBlock([]byte(block)) {
+		t.Fatalf("TryWriteBlock must return true in this context")
+	}
+	blocks = append(blocks, block)
 	for _, block := range blocks {
 		buf, ok := fq.MustReadBlock(nil)
 		if !ok {
@@ -35,6 +35,10 @@ type queue struct {
 	maxBlockSize    uint64
 	maxPendingBytes uint64
 
+	// dropNewest is set to true if new blocks must be dropped instead of the oldest blocks
+	// when the queue size reaches maxPendingBytes.
+	dropNewest bool
+
 	dir  string
 	name string
 
@@ -53,8 +57,18 @@ type queue struct {
 
 	lastMetainfoFlushTime uint64
 
-	blocksDropped *metrics.Counter
-	bytesDropped  *metrics.Counter
+	// codec is used for encoding and decoding blocks if it isn't nil.
+	codec BlockCodec
+
+	// encoder encodes blocks for the chunk file at writerPath. It is reset when switching to the next chunk file.
+	encoder BlockEncoder
+
+	// decoder decodes blocks from the chunk file at readerPath. It is reset when switching to the next chunk file.
+	decoder BlockDecoder
+
+	blocksDropped  *metrics.Counter
+	bytesDropped   *metrics.Counter
+	droppedDataAge *metrics.Histogram
 
 	blocksWritten *metrics.Counter
 	bytesWritten  *metrics.Counter
@@ -101,6 +115,9 @@ func (q *queue) mustResetFiles() {
 	r := filestream.MustOpen(q.readerPath, true)
 	q.reader = r
 
+	q.encoder = nil
+	q.decoder = nil
+
 	if err := q.flushMetainfo(); err != nil {
 		logger.Panicf("FATAL: cannot flush metainfo: %s", err)
 	}
@@ -119,11 +136,15 @@ func (q *queue) GetPendingBytes() uint64 {
 //
 // If maxPendingBytes is greater than 0, then the max queue size is limited by this value.
 // The oldest data is deleted when queue size exceeds maxPendingBytes.
-func mustOpen(path, name string, maxPendingBytes int64) *queue {
+//
+// If codec isn't nil, then it is used for encoding blocks written to the queue.
+func mustOpen(path, name string, maxPendingBytes int64, codec BlockCodec) *queue {
 	if maxPendingBytes < 0 {
 		maxPendingBytes = 0
 	}
-	return mustOpenInternal(path, name, DefaultChunkFileSize, MaxBlockSize, uint64(maxPendingBytes))
+	q := mustOpenInternal(path, name, DefaultChunkFileSize, MaxBlockSize, uint64(maxPendingBytes))
+	q.codec = codec
+	return q
 }
 
 func mustOpenInternal(path, name string, chunkFileSize, maxBlockSize, maxPendingBytes uint64) *queue {
@@ -156,6 +177,7 @@ func tryOpeningQueue(path, name string, chunkFileSize, maxBlockSize, maxPendingB
 
 	q.blocksDropped = metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_blocks_dropped_total{path=%q}`, path))
 	q.bytesDropped = metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_bytes_dropped_total{path=%q}`, path))
+	q.droppedDataAge = metrics.GetOrCreateHistogram(fmt.Sprintf(`vm_persistentqueue_dropped_data_age_seconds{path=%q}`, path))
 	q.blocksWritten = metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_blocks_written_total{path=%q}`, path))
 	q.bytesWritten = metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_bytes_written_total{path=%q}`, path))
 	q.blocksRead = metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_blocks_read_total{path=%q}`, path))
@@ -352,8 +374,16 @@ func (q *queue) MustWriteBlock(block []byte) {
 		logger.Panicf("BUG: readerOffset=%d shouldn't exceed writerOffset=%d", q.readerOffset, q.writerOffset)
 	}
 	if q.maxPendingBytes > 0 {
-		// Drain the oldest blocks until the number of pending bytes becomes enough for the block.
 		blockSize := uint64(len(block) + 8)
+		if q.dropNewest && !q.hasFreeSpace(blockSize) {
+			// There is no space left for the block. Drop it.
+			q.blocksDropped.Inc()
+			q.bytesDropped.Add(len(block))
+			q.droppedDataAge.Update(0)
+			return
+		}
+
+		// Drain the oldest blocks until the number of pending bytes becomes enough for the block.
 		maxPendingBytes := q.maxPendingBytes
 		if blockSize < maxPendingBytes {
 			maxPendingBytes -= blockSize
@@ -361,6 +391,8 @@ func (q *queue) MustWriteBlock(block []byte) {
 			maxPendingBytes = 0
 		}
 		bb := blockBufPool.Get()
+		droppedDataAge := float64(0)
+		droppedDataPath := ""
 		for q.writerOffset-q.readerOffset > maxPendingBytes {
 			var err error
 			bb.B, err = q.readBlock(bb.B[:0])
@@ -372,6 +404,12 @@ func (q *queue) MustWriteBlock(block []byte) {
 			}
 			q.blocksDropped.Inc()
 			q.bytesDropped.Add(len(bb.B))
+			if q.readerPath != droppedDataPath {
+				// Calculate the age only once per chunk file, since it requires os.Stat call.
+				droppedDataAge = q.getReaderChunkAge()
+				droppedDataPath = q.readerPath
+			}
+			q.droppedDataAge.Update(droppedDataAge)
 		}
 		blockBufPool.Put(bb)
 		if blockSize > q.maxPendingBytes {
@@ -386,6 +424,30 @@ func (q *queue) MustWriteBlock(block []byte) {
 
 var blockBufPool bytesutil.ByteBufferPool
 
+// hasFreeSpace returns true if q has enough free space for storing blocks with the given size in bytes.
+func (q *queue) hasFreeSpace(size uint64) bool {
+	if q.maxPendingBytes == 0 {
+		return true
+	}
+	return q.writerOffset-q.readerOffset+size <= q.maxPendingBytes
+}
+
+// getReaderChunkAge returns the age in seconds of the chunk file, which is currently read.
+//
+// The age is estimated by the last modification time of the chunk file,
+// so the returned value is the lower bound for the age of the data in this file.
+func (q *queue) getReaderChunkAge() float64 {
+	fi, err := os.Stat(q.readerPath)
+	if err != nil {
+		return 0
+	}
+	d := time.Since(fi.ModTime()).Seconds()
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
 func (q *queue) writeBlock(block []byte) error {
 	startTime := time.Now()
 	defer func() {
@@ -397,10 +459,28 @@ func (q *queue) writeBlock(block []byte) error {
 		}
 	}
 
+	// Encode the block if needed.
+	blockHeader := uint64(len(block))
+	if q.codec != nil {
+		if q.encoder == nil {
+			q.encoder = q.codec.NewEncoder()
+		}
+		bb := blockBufPool.Get()
+		defer blockBufPool.Put(bb)
+		var ok bool
+		bb.B, ok = q.encoder.EncodeBlock(bb.B[:0], block)
+		if ok {
+			if uint64(len(bb.B)) > q.maxBlockSize {
+				logger.Panicf("BUG: too big encoded block: %d bytes; it mustn't exceed %d bytes", len(bb.B), q.maxBlockSize)
+			}
+			block = bb.B
+			blockHeader = uint64(len(block)) | encodedBlockFlag
+		}
+	}
+
 	// Write block len.
-	blockLen := uint64(len(block))
 	header := headerBufPool.Get()
-	header.B = encoding.MarshalUint64(header.B, blockLen)
+	header.B = encoding.MarshalUint64(header.B, blockHeader)
 	err := q.write(header.B)
 	headerBufPool.Put(header)
 	if err != nil {
@@ -431,6 +511,7 @@ func (q *queue) nextChunkFileForWrite() error {
 	q.writerPath = q.chunkFilePath(q.writerOffset)
 	w := filestream.MustCreate(q.writerPath, false)
 	q.writer = w
+	q.encoder = nil
 	if err := q.flushMetainfo(); err != nil {
 		return fmt.Errorf("cannot flush metainfo: %w", err)
 	}
@@ -471,12 +552,16 @@ func (q *queue) readBlock(dst []byte) ([]byte, error) {
 	}
 
 again:
+	blockLocalOffset := q.readerLocalOffset
+
 	// Read block len.
 	header := headerBufPool.Get()
 	header.B = bytesutil.ResizeNoCopyMayOverallocate(header.B, 8)
 	err := q.readFull(header.B)
-	blockLen := encoding.UnmarshalUint64(header.B)
+	blockHeader := encoding.UnmarshalUint64(header.B)
 	headerBufPool.Put(header)
+	isEncoded := blockHeader&encodedBlockFlag != 0
+	blockLen := blockHeader &^ encodedBlockFlag
 	if err != nil {
 		logger.Errorf("skipping corrupted %q, since header with size 8 bytes cannot be read from it: %s", q.readerPath, err)
 		if err := q.skipBrokenChunkFile(); err != nil {
@@ -492,16 +577,40 @@ again:
 		goto again
 	}
 
-	// Read block contents.
-	dstLen := len(dst)
-	dst = bytesutil.ResizeWithCopyMayOverallocate(dst, dstLen+int(blockLen))
-	if err := q.readFull(dst[dstLen:]); err != nil {
-		logger.Errorf("skipping corrupted %q, since contents with size %d bytes cannot be read from it: %s", q.readerPath, blockLen, err)
+	if isEncoded && q.codec == nil {
+		logger.Errorf("skipping %q, since it contains encoded blocks, which cannot be decoded without codec", q.readerPath)
 		if err := q.skipBrokenChunkFile(); err != nil {
-			return dst[:dstLen], err
+			return dst, err
 		}
 		goto again
 	}
+	if blockLocalOffset == 0 && q.codec != nil {
+		// Start decoding the chunk file from scratch.
+		q.decoder = q.codec.NewDecoder()
+	}
+
+	// Read block contents.
+	dstLen := len(dst)
+	if isEncoded {
+		var err error
+		dst, err = q.readEncodedBlock(dst, blockLocalOffset, blockLen)
+		if err != nil {
+			logger.Errorf("skipping corrupted %q, since encoded block with size %d bytes cannot be read from it: %s", q.readerPath, blockLen, err)
+			if err := q.skipBrokenChunkFile(); err != nil {
+				return dst[:dstLen], err
+			}
+			goto again
+		}
+	} else {
+		dst = bytesutil.ResizeWithCopyMayOverallocate(dst, dstLen+int(blockLen))
+		if err := q.readFull(dst[dstLen:]); err != nil {
+			logger.Errorf("skipping corrupted %q, since contents with size %d bytes cannot be read from it: %s", q.readerPath, blockLen, err)
+			if err := q.skipBrokenChunkFile(); err != nil {
+				return dst[:dstLen], err
+			}
+			goto again
+		}
+	}
 	q.blocksRead.Inc()
 	q.bytesRead.Add(int(blockLen))
 	if err := q.flushReaderMetainfoIfNeeded(); err != nil {
@@ -512,6 +621,81 @@ again:
 
 var readDurationSeconds = metrics.NewFloatCounter(`vm_persistentqueue_read_duration_seconds_total`)
 
+// readEncodedBlock reads the encoded block with the given size, decodes it, appends the result to dst and returns it.
+//
+// blockLocalOffset must point to the block header in the chunk file at readerPath.
+func (q *queue) readEncodedBlock(dst []byte, blockLocalOffset, blockLen uint64) ([]byte, error) {
+	bb := blockBufPool.Get()
+	defer blockBufPool.Put(bb)
+
+	bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, int(blockLen))
+	if err := q.readFull(bb.B); err != nil {
+		return dst, err
+	}
+	if q.decoder == nil {
+		// The queue has been re-opened in the middle of the chunk file.
+		// Restore the decoder state from the preceding blocks in the chunk file.
+		d, err := q.replayChunkFile(blockLocalOffset)
+		if err != nil {
+			return dst, fmt.Errorf("cannot restore decoder state: %w", err)
+		}
+		q.decoder = d
+	}
+	dstLen := len(dst)
+	dst, err := q.decoder.DecodeBlock(dst, bb.B)
+	if err != nil {
+		return dst[:dstLen], fmt.Errorf("cannot decode block: %w", err)
+	}
+	if uint64(len(dst)-dstLen) > q.maxBlockSize {
+		return dst[:dstLen], fmt.Errorf("too big decoded block size: %d bytes; cannot exceed %d bytes", len(dst)-dstLen, q.maxBlockSize)
+	}
+	return dst, nil
+}
+
+// replayChunkFile returns new decoder, which has decoded all the blocks from the chunk file at readerPath up to the given localOffset.
+func (q *queue) replayChunkFile(localOffset uint64) (BlockDecoder, error) {
+	d := q.codec.NewDecoder()
+
+	r, err := filestream.OpenReaderAt(q.readerPath, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	defer r.MustClose()
+
+	bb := blockBufPool.Get()
+	defer blockBufPool.Put(bb)
+	decoded := blockBufPool.Get()
+	defer blockBufPool.Put(decoded)
+
+	offset := uint64(0)
+	for offset < localOffset {
+		bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, 8)
+		if _, err := io.ReadFull(r, bb.B); err != nil {
+			return nil, fmt.Errorf("cannot read block header at offset %d: %w", offset, err)
+		}
+		blockHeader := encoding.UnmarshalUint64(bb.B)
+		blockLen := blockHeader &^ encodedBlockFlag
+		if blockLen > q.maxBlockSize {
+			return nil, fmt.Errorf("too big block size at offset %d: %d bytes; cannot exceed %d bytes", offset, blockLen, q.maxBlockSize)
+		}
+		bb.B = bytesutil.ResizeNoCopyMayOverallocate(bb.B, int(blockLen))
+		if _, err := io.ReadFull(r, bb.B); err != nil {
+			return nil, fmt.Errorf("cannot read block contents with size %d bytes at offset %d: %w", blockLen, offset, err)
+		}
+		if blockHeader&encodedBlockFlag != 0 {
+			decoded.B, err = d.DecodeBlock(decoded.B[:0], bb.B)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode block at offset %d: %w", offset, err)
+			}
+		}
+		offset += 8 + blockLen
+	}
+	if offset != localOffset {
+		return nil, fmt.Errorf("unexpected block boundary; got %d; want %d", offset, localOffset)
+	}
+	return d, nil
+}
+
 func (q *queue) skipBrokenChunkFile() error {
 	// Try to recover from broken chunk file by skipping it.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1030
@@ -539,6 +723,7 @@ func (q *queue) nextChunkFileForRead() error {
 	q.readerPath = q.chunkFilePath(q.readerOffset)
 	r := filestream.MustOpen(q.readerPath, true)
 	q.reader = r
+	q.decoder = nil
 	if err := q.flushMetainfo(); err != nil {
 		return fmt.Errorf("cannot flush metainfo: %w", err)
 	}
@@ -12,7 +12,7 @@ func TestQueueOpenClose(t *testing.T) {
 	path := "queue-open-close"
 	mustDeleteDir(path)
 	for i := 0; i < 3; i++ {
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		if n := q.GetPendingBytes(); n > 0 {
 			t.Fatalf("pending bytes must be 0; got %d", n)
 		}
@@ -26,7 +26,7 @@ func TestQueueOpen(t *testing.T) {
 		path := "queue-open-invalid-metainfo"
 		mustCreateDir(path)
 		mustCreateFile(filepath.Join(path, metainfoFilename), "foobarbaz")
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -36,7 +36,7 @@ func TestQueueOpen(t *testing.T) {
 		mustCreateEmptyMetainfo(path, "foobar")
 		mustCreateFile(filepath.Join(path, "junk-file"), "foobar")
 		mustCreateDir(filepath.Join(path, "junk-dir"))
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -45,7 +45,7 @@ func TestQueueOpen(t *testing.T) {
 		mustCreateDir(path)
 		mustCreateEmptyMetainfo(path, "foobar")
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 1234)), "qwere")
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -54,7 +54,7 @@ func TestQueueOpen(t *testing.T) {
 		mustCreateDir(path)
 		mustCreateEmptyMetainfo(path, "foobar")
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 100*uint64(DefaultChunkFileSize))), "asdf")
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -70,7 +70,7 @@ func TestQueueOpen(t *testing.T) {
 			t.Fatalf("unexpected error: %s", err)
 		}
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 0)), "adfsfd")
-		q := mustOpen(path, mi.Name, 0)
+		q := mustOpen(path, mi.Name, 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -84,7 +84,7 @@ func TestQueueOpen(t *testing.T) {
 		if err := mi.WriteToFile(filepath.Join(path, metainfoFilename)); err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
-		q := mustOpen(path, mi.Name, 0)
+		q := mustOpen(path, mi.Name, 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -92,7 +92,7 @@ func TestQueueOpen(t *testing.T) {
 		path := "queue-open-metainfo-dir"
 		mustCreateDir(path)
 		mustCreateDir(filepath.Join(path, metainfoFilename))
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -108,7 +108,7 @@ func TestQueueOpen(t *testing.T) {
 			t.Fatalf("unexpected error: %s", err)
 		}
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 0)), "sdf")
-		q := mustOpen(path, mi.Name, 0)
+		q := mustOpen(path, mi.Name, 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -117,7 +117,7 @@ func TestQueueOpen(t *testing.T) {
 		mustCreateDir(path)
 		mustCreateEmptyMetainfo(path, "foobar")
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 0)), "sdfdsf")
-		q := mustOpen(path, "foobar", 0)
+		q := mustOpen(path, "foobar", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -131,7 +131,7 @@ func TestQueueOpen(t *testing.T) {
 			t.Fatalf("unexpected error: %s", err)
 		}
 		mustCreateFile(filepath.Join(path, fmt.Sprintf("%016X", 0)), "sdf")
-		q := mustOpen(path, "baz", 0)
+		q := mustOpen(path, "baz", 0, nil)
 		q.MustClose()
 		mustDeleteDir(path)
 	})
@@ -140,7 +140,7 @@ func TestQueueOpen(t *testing.T) {
 func TestQueueResetIfEmpty(t *testing.T) {
 	path := "queue-reset-if-empty"
 	mustDeleteDir(path)
-	q := mustOpen(path, "foobar", 0)
+	q := mustOpen(path, "foobar", 0, nil)
 	defer func() {
 		q.MustClose()
 		mustDeleteDir(path)
@@ -171,7 +171,7 @@ func TestQueueResetIfEmpty(t *testing.T) {
 func TestQueueWriteRead(t *testing.T) {
 	path := "queue-write-read"
 	mustDeleteDir(path)
-	q := mustOpen(path, "foobar", 0)
+	q := mustOpen(path, "foobar", 0, nil)
 	defer func() {
 		q.MustClose()
 		mustDeleteDir(path)
@@ -207,7 +207,7 @@ func TestQueueWriteRead(t *testing.T) {
 func TestQueueWriteCloseRead(t *testing.T) {
 	path := "queue-write-close-read"
 	mustDeleteDir(path)
-	q := mustOpen(path, "foobar", 0)
+	q := mustOpen(path, "foobar", 0, nil)
 	defer func() {
 		q.MustClose()
 		mustDeleteDir(path)
@@ -224,7 +224,7 @@ func TestQueueWriteCloseRead(t *testing.T) {
 			t.Fatalf("pending bytes must be greater than 0; got %d", n)
 		}
 		q.MustClose()
-		q = mustOpen(path, "foobar", 0)
+		q = mustOpen(path, "foobar", 0, nil)
 		if n := q.GetPendingBytes(); n <= 0 {
 			t.Fatalf("pending bytes must be greater than 0; got %d", n)
 		}
@@ -317,7 +317,7 @@ func TestQueueLimitedSize(t *testing.T) {
 	const maxPendingBytes = 1000
 	path := "queue-limited-size"
 	mustDeleteDir(path)
-	q := mustOpen(path, "foobar", maxPendingBytes)
+	q := mustOpen(path, "foobar", maxPendingBytes, nil)
 	defer func() {
 		q.MustClose()
 		mustDeleteDir(path)
@@ -370,6 +370,47 @@ func TestQueueLimitedSize(t *testing.T) {
 	}
 }
 
+func TestQueueLimitedSizeDropNewest(t *testing.T) {
+	const maxPendingBytes = 1000
+	path := "queue-limited-size-drop-newest"
+	mustDeleteDir(path)
+	q := mustOpen(path, "foobar", maxPendingBytes, nil)
+	q.dropNewest = true
+	defer func() {
+		q.MustClose()
+		mustDeleteDir(path)
+	}()
+
+	// Make sure that new blocks are dropped on queue size overflow
+	for i := 0; i < maxPendingBytes; i++ {
+		block := fmt.Sprintf("%d", i)
+		q.MustWriteBlock([]byte(block))
+	}
+	if n := q.GetPendingBytes(); n > maxPendingBytes {
+		t.Fatalf("too many pending bytes; got %d; mustn't exceed %d", n, maxPendingBytes)
+	}
+	var buf []byte
+	var ok bool
+	lastBlockNum := -1
+	for {
+		buf, ok = q.MustReadBlockNonblocking(buf[:0])
+		if !ok {
+			break
+		}
+		blockNum, err := strconv.Atoi(string(buf))
+		if err != nil {
+			t.Fatalf("cannot parse block contents: %s", err)
+		}
+		if blockNum != lastBlockNum+1 {
+			t.Fatalf("unexpected block number; got %d; want %d", blockNum, lastBlockNum+1)
+		}
+		lastBlockNum = blockNum
+	}
+	if lastBlockNum < 0 || lastBlockNum >= maxPendingBytes-1 {
+		t.Fatalf("unexpected last block number: %d; it looks like new blocks weren't dropped", lastBlockNum)
+	}
+}
+
 func mustCreateFile(path, contents string) {
 	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
 		panic(fmt.Errorf("cannot create file %q with %d bytes contents: %w", path, len(contents), err))
@@ -396,3 +437,135 @@ func mustCreateEmptyMetainfo(path, name string) {
 		panic(fmt.Errorf("cannot create metainfo: %w", err))
 	}
 }
+
+func TestQueueCodecPeriodicClose(t *testing.T) {
+	path := "queue-codec-periodic-close"
+	mustDeleteDir(path)
+	const chunkFileSize = 200
+	const maxBlockSize = 20
+	mustOpenWithCodec := func() *queue {
+		q := mustOpenInternal(path, "foobar", chunkFileSize, maxBlockSize, 0)
+		q.codec = prefixCodec{}
+		return q
+	}
+	q := mustOpenWithCodec()
+	defer func() {
+		q.MustClose()
+		mustDeleteDir(path)
+	}()
+	var blocks []string
+	for i := 0; i < 100; i++ {
+		block := fmt.Sprintf("block %d", i)
+		q.MustWriteBlock([]byte(block))
+		blocks = append(blocks, block)
+		if i%7 == 0 {
+			q.MustClose()
+			q = mustOpenWithCodec()
+		}
+	}
+	if n := q.GetPendingBytes(); n == 0 {
+		t.Fatalf("unexpected zero number of bytes pending")
+	}
+	if n := q.blocksWritten.Get(); n == 0 {
+		t.Fatalf("unexpected zero number of blocks written")
+	}
+	for i, block := range blocks {
+		data, ok := q.MustReadBlockNonblocking(nil)
+		if !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+		if block != string(data) {
+			t.Fatalf("unexpected block read; got %q; want %q", data, block)
+		}
+		if i%5 == 0 {
+			// Re-open the queue in the middle of the chunk file in order to verify the decoder state is restored.
+			q.MustClose()
+			q = mustOpenWithCodec()
+		}
+	}
+	if n := q.GetPendingBytes(); n != 0 {
+		t.Fatalf("unexpected non-zero number of pending bytes: %d", n)
+	}
+}
+
+func TestQueueCodecReducesSize(t *testing.T) {
+	f := func(codec BlockCodec) uint64 {
+		t.Helper()
+
+		path := "queue-codec-reduces-size"
+		mustDeleteDir(path)
+		q := mustOpen(path, "foobar", 0, codec)
+		for i := 0; i < 100; i++ {
+			q.MustWriteBlock([]byte(fmt.Sprintf("the same long prefix for all the blocks %d", i)))
+		}
+		n := q.GetPendingBytes()
+		q.MustClose()
+		mustDeleteDir(path)
+		return n
+	}
+
+	plainSize := f(nil)
+	encodedSize := f(prefixCodec{})
+	if encodedSize >= plainSize {
+		t.Fatalf("expecting smaller queue size with codec; got %d bytes; want less than %d bytes", encodedSize, plainSize)
+	}
+}
+
+// prefixCodec encodes every block as the length of the common prefix with the previous block plus the remaining suffix.
+type prefixCodec struct{}
+
+func (prefixCodec) NewEncoder() BlockEncoder {
+	return &prefixEncoder{
+		needReset: true,
+	}
+}
+
+func (prefixCodec) NewDecoder() BlockDecoder {
+	return &prefixDecoder{}
+}
+
+type prefixEncoder struct {
+	prev      []byte
+	needReset bool
+}
+
+func (e *prefixEncoder) EncodeBlock(dst, block []byte) ([]byte, bool) {
+	n := 0
+	if !e.needReset {
+		for n < len(block) && n < len(e.prev) && n < 255 && block[n] == e.prev[n] {
+			n++
+		}
+		if n < 3 {
+			// The encoded block isn't smaller than the original block.
+			return dst, false
+		}
+	}
+	reset := byte(0)
+	if e.needReset {
+		reset = 1
+	}
+	dst = append(dst, reset, byte(n))
+	dst = append(dst, block[n:]...)
+	e.prev = append(e.prev[:0], block...)
+	e.needReset = false
+	return dst, true
+}
+
+type prefixDecoder struct {
+	prev []byte
+}
+
+func (d *prefixDecoder) DecodeBlock(dst, src []byte) ([]byte, error) {
+	if len(src) < 2 {
+		return dst, fmt.Errorf("too short block")
+	}
+	if src[0] == 1 {
+		d.prev = d.prev[:0]
+	}
+	n := int(src[1])
+	if n > len(d.prev) {
+		return dst, fmt.Errorf("too big prefix length %d; previous block length is %d", n, len(d.prev))
+	}
+	d.prev = append(d.prev[:n], src[2:]...)
+	return append(dst, d.prev...), nil
+}
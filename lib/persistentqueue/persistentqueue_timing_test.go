@@ -17,7 +17,7 @@ func BenchmarkQueueThroughputSerial(b *testing.B) {
 			b.SetBytes(int64(blockSize) * iterationsCount)
 			path := fmt.Sprintf("bench-queue-throughput-serial-%d", blockSize)
 			mustDeleteDir(path)
-			q := mustOpen(path, "foobar", 0)
+			q := mustOpen(path, "foobar", 0, nil)
 			defer func() {
 				q.MustClose()
 				mustDeleteDir(path)
@@ -38,7 +38,7 @@ func BenchmarkQueueThroughputConcurrent(b *testing.B) {
 			b.SetBytes(int64(blockSize) * iterationsCount)
 			path := fmt.Sprintf("bench-queue-throughput-concurrent-%d", blockSize)
 			mustDeleteDir(path)
-			q := mustOpen(path, "foobar", 0)
+			q := mustOpen(path, "foobar", 0, nil)
 			var qLock sync.Mutex
 			defer func() {
 				q.MustClose()
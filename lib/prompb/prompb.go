@@ -11,6 +11,9 @@ type WriteRequest struct {
 	// Timeseries is a list of time series in the given WriteRequest
 	Timeseries []TimeSeries
 
+	// Metadata is a list of metric metadata in the given WriteRequest
+	Metadata []MetricMetadata
+
 	labelsPool  []Label
 	samplesPool []Sample
 }
@@ -20,6 +23,9 @@ func (wr *WriteRequest) Reset() {
 	clear(wr.Timeseries)
 	wr.Timeseries = wr.Timeseries[:0]
 
+	clear(wr.Metadata)
+	wr.Metadata = wr.Metadata[:0]
+
 	clear(wr.labelsPool)
 	wr.labelsPool = wr.labelsPool[:0]
 
@@ -54,6 +60,57 @@ type Label struct {
 	Value string
 }
 
+// MetricMetadata contains metadata for the metric family.
+type MetricMetadata struct {
+	// Type is the metric type. See MetricType* constants.
+	Type uint32
+
+	// MetricFamilyName is the name of the metric family, which the metadata applies to.
+	MetricFamilyName string
+
+	// Help is the metric description.
+	Help string
+
+	// Unit is the metric unit.
+	Unit string
+}
+
+// Metric types for MetricMetadata.Type.
+//
+// See https://github.com/prometheus/prometheus/blob/main/prompb/types.proto
+const (
+	MetricTypeUnknown        = 0
+	MetricTypeCounter        = 1
+	MetricTypeGauge          = 2
+	MetricTypeHistogram      = 3
+	MetricTypeGaugeHistogram = 4
+	MetricTypeSummary        = 5
+	MetricTypeInfo           = 6
+	MetricTypeStateset       = 7
+)
+
+// MetricTypeString returns the string representation for the given metric type as used by Prometheus API.
+func MetricTypeString(metricType uint32) string {
+	switch metricType {
+	case MetricTypeCounter:
+		return "counter"
+	case MetricTypeGauge:
+		return "gauge"
+	case MetricTypeHistogram:
+		return "histogram"
+	case MetricTypeGaugeHistogram:
+		return "gaugehistogram"
+	case MetricTypeSummary:
+		return "summary"
+	case MetricTypeInfo:
+		return "info"
+	case MetricTypeStateset:
+		return "stateset"
+	default:
+		return "unknown"
+	}
+}
+
 // UnmarshalProtobuf unmarshals wr from src.
 //
 // src mustn't change while wr is in use, since wr points to src.
@@ -62,8 +119,11 @@ func (wr *WriteRequest) UnmarshalProtobuf(src []byte) (err error) {
 
 	// message WriteRequest {
 	//    repeated TimeSeries timeseries = 1;
+	//    reserved 2;
+	//    repeated MetricMetadata metadata = 3;
 	// }
 	tss := wr.Timeseries
+	mms := wr.Metadata
 	labelsPool := wr.labelsPool
 	samplesPool := wr.samplesPool
 	var fc easyproto.FieldContext
@@ -88,9 +148,24 @@ func (wr *WriteRequest) UnmarshalProtobuf(src []byte) (err error) {
 			if err != nil {
 				return fmt.Errorf("cannot unmarshal timeseries: %w", err)
 			}
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read metadata data")
+			}
+			if len(mms) < cap(mms) {
+				mms = mms[:len(mms)+1]
+			} else {
+				mms = append(mms, MetricMetadata{})
+			}
+			mm := &mms[len(mms)-1]
+			if err := mm.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal metadata: %w", err)
+			}
 		}
 	}
 	wr.Timeseries = tss
+	wr.Metadata = mms
 	wr.labelsPool = labelsPool
 	wr.samplesPool = samplesPool
 	return nil
@@ -203,3 +278,47 @@ func (s *Sample) unmarshalProtobuf(src []byte) (err error) {
 	}
 	return nil
 }
+
+func (mm *MetricMetadata) unmarshalProtobuf(src []byte) (err error) {
+	// message MetricMetadata {
+	//   MetricType type           = 1;
+	//   string metric_family_name = 2;
+	//   string help               = 4;
+	//   string unit               = 5;
+	// }
+	*mm = MetricMetadata{}
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the next field: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			metricType, ok := fc.Uint32()
+			if !ok {
+				return fmt.Errorf("cannot read metric type")
+			}
+			mm.Type = metricType
+		case 2:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read metric family name")
+			}
+			mm.MetricFamilyName = name
+		case 4:
+			help, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read help")
+			}
+			mm.Help = help
+		case 5:
+			unit, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read unit")
+			}
+			mm.Unit = unit
+		}
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package prompb_test
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
+	"github.com/VictoriaMetrics/easyproto"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
@@ -171,3 +174,47 @@ func TestWriteRequestUnmarshalProtobuf(t *testing.T) {
 	data = wrm.MarshalProtobuf(data[:0])
 	f(data)
 }
+
+func TestWriteRequestUnmarshalProtobufMetadata(t *testing.T) {
+	f := func(mmsExpected []prompb.MetricMetadata) {
+		t.Helper()
+
+		var m easyproto.Marshaler
+		mm := m.MessageMarshaler()
+		mm.AppendMessage(1).AppendMessage(1).AppendString(1, "foo")
+		for _, md := range mmsExpected {
+			mdm := mm.AppendMessage(3)
+			mdm.AppendUint32(1, md.Type)
+			mdm.AppendString(2, md.MetricFamilyName)
+			mdm.AppendString(4, md.Help)
+			mdm.AppendString(5, md.Unit)
+		}
+		data := m.Marshal(nil)
+
+		var wr prompb.WriteRequest
+		if err := wr.UnmarshalProtobuf(data); err != nil {
+			t.Fatalf("cannot unmarshal protobuf: %s", err)
+		}
+		if len(wr.Timeseries) != 1 {
+			t.Fatalf("unexpected number of timeseries; got %d; want 1", len(wr.Timeseries))
+		}
+		if !reflect.DeepEqual(wr.Metadata, mmsExpected) {
+			t.Fatalf("unexpected metadata\ngot\n%+v\nwant\n%+v", wr.Metadata, mmsExpected)
+		}
+	}
+
+	f(nil)
+	f([]prompb.MetricMetadata{
+		{
+			Type:             prompb.MetricTypeCounter,
+			MetricFamilyName: "http_requests_total",
+			Help:             "The total number of HTTP requests",
+		},
+		{
+			Type:             prompb.MetricTypeGauge,
+			MetricFamilyName: "process_resident_memory_bytes",
+			Help:             "Resident memory size",
+			Unit:             "bytes",
+		},
+	})
+}
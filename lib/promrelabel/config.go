@@ -10,6 +10,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 	"gopkg.in/yaml.v2"
 )
@@ -42,6 +43,30 @@ type RelabelConfig struct {
 	//     job: '$1'
 	//     instance: '${2}:8080'
 	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Ratio is used for `action: keep_ratio`. It contains the share of series to keep in the range [0..1]. For example:
+	// - action: keep_ratio
+	//   if: 'http_request_duration_seconds_bucket{path=~"/health.*"}'
+	//   ratio: 0.1
+	Ratio *float64 `yaml:"ratio,omitempty"`
+
+	// Salt is used for `action: hash`. It is mixed into the hash of `source_labels`, so the original values cannot be recovered
+	// by hashing well-known values. For example:
+	// - action: hash
+	//   source_labels: [email]
+	//   target_label: email
+	//   salt: 'secret'
+	//   hash_length: 16
+	Salt *promauth.Secret `yaml:"salt,omitempty"`
+
+	// HashLength is used for `action: hash`. It contains the number of hex chars in the hash stored at `target_label`.
+	HashLength int `yaml:"hash_length,omitempty"`
+
+	// RuleSet is the name of rule set from -relabelRuleSets file. The rule is substituted with the rules from the rule set. For example:
+	// - rule_set: drop_debug_metrics
+	// - action: labeldrop
+	//   regex: 'tmp_.*'
+	RuleSet string `yaml:"rule_set,omitempty"`
 }
 
 // MultiLineRegex contains a regex, which can be split into multiple lines.
@@ -182,6 +207,16 @@ func ParseRelabelConfigsData(data []byte) (*ParsedConfigs, error) {
 
 // ParseRelabelConfigs parses rcs to dst.
 func ParseRelabelConfigs(rcs []RelabelConfig) (*ParsedConfigs, error) {
+	if hasRuleSetRefs(rcs) {
+		rss, err := getRuleSets()
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain rule sets: %w", err)
+		}
+		rcs, err = expandRuleSets(rcs, rss, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if len(rcs) == 0 {
 		return nil, nil
 	}
@@ -388,6 +423,41 @@ func parseRelabelConfig(rc *RelabelConfig) (*parsedRelabelConfig, error) {
 		if targetLabel == "" {
 			return nil, fmt.Errorf("missing `target_label` for `action=%s`", action)
 		}
+	case "keep_ratio":
+		if rc.Ratio == nil {
+			return nil, fmt.Errorf("missing `ratio` for `action=keep_ratio`")
+		}
+		if *rc.Ratio < 0 || *rc.Ratio > 1 {
+			return nil, fmt.Errorf("unexpected `ratio` for `action=keep_ratio`: %v; must be in the range [0..1]", *rc.Ratio)
+		}
+		if targetLabel != "" {
+			return nil, fmt.Errorf("`target_label` cannot be used for `action=keep_ratio`")
+		}
+		if rc.Regex != nil {
+			return nil, fmt.Errorf("`regex` cannot be used for `action=keep_ratio`")
+		}
+		if rc.Replacement != nil {
+			return nil, fmt.Errorf("`replacement` cannot be used for `action=keep_ratio`")
+		}
+	case "hash":
+		if len(sourceLabels) == 0 {
+			return nil, fmt.Errorf("missing `source_labels` for `action=hash`")
+		}
+		if targetLabel == "" {
+			return nil, fmt.Errorf("missing `target_label` for `action=hash`")
+		}
+		if rc.Salt.String() == "" {
+			return nil, fmt.Errorf("missing `salt` for `action=hash`")
+		}
+		if rc.HashLength < 0 || rc.HashLength > maxHashLength {
+			return nil, fmt.Errorf("unexpected `hash_length` for `action=hash`: %d; must be in the range [1..%d]", rc.HashLength, maxHashLength)
+		}
+		if rc.Regex != nil {
+			return nil, fmt.Errorf("`regex` cannot be used for `action=hash`")
+		}
+		if rc.Replacement != nil {
+			return nil, fmt.Errorf("`replacement` cannot be used for `action=hash`")
+		}
 	case "labelmap":
 	case "labelmap_all":
 	case "labeldrop":
@@ -403,6 +473,29 @@ func parseRelabelConfig(rc *RelabelConfig) (*parsedRelabelConfig, error) {
 			return nil, fmt.Errorf("`labels` config cannot be applied to `action=%s`; it is applied only to `action=graphite`", action)
 		}
 	}
+	if action != "keep_ratio" && rc.Ratio != nil {
+		return nil, fmt.Errorf("`ratio` cannot be applied to `action=%s`; it is applied only to `action=keep_ratio`", action)
+	}
+	var keepRatioMaxHash uint64
+	if rc.Ratio != nil {
+		keepRatioMaxHash = getKeepRatioMaxHash(*rc.Ratio)
+	}
+	var hashSalt []byte
+	hashLength := 0
+	if action == "hash" {
+		hashSalt = []byte(rc.Salt.String())
+		hashLength = rc.HashLength
+		if hashLength == 0 {
+			hashLength = defaultHashLength
+		}
+	} else {
+		if rc.Salt != nil {
+			return nil, fmt.Errorf("`salt` cannot be applied to `action=%s`; it is applied only to `action=hash`", action)
+		}
+		if rc.HashLength != 0 {
+			return nil, fmt.Errorf("`hash_length` cannot be applied to `action=%s`; it is applied only to `action=hash`", action)
+		}
+	}
 	ruleOriginal, err := yaml.Marshal(rc)
 	if err != nil {
 		logger.Panicf("BUG: cannot marshal RelabelConfig: %s", err)
@@ -422,6 +515,11 @@ func parseRelabelConfig(rc *RelabelConfig) (*parsedRelabelConfig, error) {
 		graphiteMatchTemplate: graphiteMatchTemplate,
 		graphiteLabelRules:    graphiteLabelRules,
 
+		keepRatioMaxHash: keepRatioMaxHash,
+
+		hashSalt:   hashSalt,
+		hashLength: hashLength,
+
 		regex:         promRegex,
 		regexOriginal: regexOriginalCompiled,
 
@@ -431,6 +529,9 @@ func parseRelabelConfig(rc *RelabelConfig) (*parsedRelabelConfig, error) {
 	}
 	prc.stringReplacer = bytesutil.NewFastStringTransformer(prc.replaceFullStringSlow)
 	prc.submatchReplacer = bytesutil.NewFastStringTransformer(prc.replaceStringSubmatchesSlow)
+	if action == "hash" {
+		prc.hasher = bytesutil.NewFastStringTransformer(prc.hashStringSlow)
+	}
 	return prc, nil
 }
 
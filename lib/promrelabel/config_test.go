@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 )
 
 func TestMultiLineRegexUnmarshalMarshal(t *testing.T) {
@@ -154,6 +156,17 @@ func TestParsedConfigsString(t *testing.T) {
 			Replacement: &replacement,
 		},
 	}, "- if: '{foo=~''bar''}'\n  action: replace\n  source_labels: [foo, bar]\n  target_label: x\n- target_label: x\n  replacement: foo\n")
+
+	// salt mustn't be exposed
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"email"},
+			TargetLabel:  "email",
+			Salt:         promauth.NewSecret("secret"),
+			HashLength:   8,
+		},
+	}, "- action: hash\n  source_labels: [email]\n  target_label: email\n  salt: <secret>\n  hash_length: 8\n")
 }
 
 func TestParseRelabelConfigsSuccess(t *testing.T) {
@@ -603,6 +616,136 @@ func TestParseRelabelConfigsFailure(t *testing.T) {
 		},
 	})
 
+	// keep_ratio-missing-ratio
+	f([]RelabelConfig{
+		{
+			Action: "keep_ratio",
+		},
+	})
+
+	// keep_ratio-invalid-ratio
+	f([]RelabelConfig{
+		{
+			Action: "keep_ratio",
+			Ratio:  ptrFloat64(1.5),
+		},
+	})
+	f([]RelabelConfig{
+		{
+			Action: "keep_ratio",
+			Ratio:  ptrFloat64(-0.1),
+		},
+	})
+
+	// keep_ratio-superflouos-target-label
+	f([]RelabelConfig{
+		{
+			Action:      "keep_ratio",
+			Ratio:       ptrFloat64(0.5),
+			TargetLabel: "foo",
+		},
+	})
+
+	// keep_ratio-superflouos-regex
+	f([]RelabelConfig{
+		{
+			Action: "keep_ratio",
+			Ratio:  ptrFloat64(0.5),
+			Regex: &MultiLineRegex{
+				S: "foo",
+			},
+		},
+	})
+
+	// non-keep_ratio-superflouos-ratio
+	f([]RelabelConfig{
+		{
+			Action:       "drop",
+			SourceLabels: []string{"foo"},
+			Ratio:        ptrFloat64(0.5),
+		},
+	})
+
+	// hash-missing-source-labels
+	f([]RelabelConfig{
+		{
+			Action:      "hash",
+			TargetLabel: "foo",
+			Salt:        promauth.NewSecret("secret"),
+		},
+	})
+
+	// hash-missing-target-label
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"foo"},
+			Salt:         promauth.NewSecret("secret"),
+		},
+	})
+
+	// hash-missing-salt
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+		},
+	})
+
+	// hash-invalid-hash-length
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+			Salt:         promauth.NewSecret("secret"),
+			HashLength:   65,
+		},
+	})
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+			Salt:         promauth.NewSecret("secret"),
+			HashLength:   -1,
+		},
+	})
+
+	// hash-superflouos-regex
+	f([]RelabelConfig{
+		{
+			Action:       "hash",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+			Salt:         promauth.NewSecret("secret"),
+			Regex: &MultiLineRegex{
+				S: "foo",
+			},
+		},
+	})
+
+	// non-hash-superflouos-salt
+	f([]RelabelConfig{
+		{
+			Action:       "uppercase",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+			Salt:         promauth.NewSecret("secret"),
+		},
+	})
+
+	// non-hash-superflouos-hash-length
+	f([]RelabelConfig{
+		{
+			Action:       "uppercase",
+			SourceLabels: []string{"foo"},
+			TargetLabel:  "foo",
+			HashLength:   8,
+		},
+	})
+
 	// non-graphite-superflouos-labels
 	f([]RelabelConfig{
 		{
@@ -633,3 +776,7 @@ func TestIsDefaultRegex(t *testing.T) {
 	f("^.*$", true)
 	f("(?:.*)", true)
 }
+
+func ptrFloat64(f float64) *float64 {
+	return &f
+}
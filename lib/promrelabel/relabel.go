@@ -1,7 +1,11 @@
 package promrelabel
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -33,6 +37,13 @@ type parsedRelabelConfig struct {
 	graphiteMatchTemplate *graphiteMatchTemplate
 	graphiteLabelRules    []graphiteLabelRule
 
+	// keepRatioMaxHash is the maximum series hash for series kept by `action: keep_ratio`.
+	keepRatioMaxHash uint64
+
+	// hashSalt and hashLength are used by `action: hash`.
+	hashSalt   []byte
+	hashLength int
+
 	regex         *regexutil.PromRegex
 	regexOriginal *regexp.Regexp
 
@@ -42,6 +53,7 @@ type parsedRelabelConfig struct {
 
 	stringReplacer   *bytesutil.FastStringTransformer
 	submatchReplacer *bytesutil.FastStringTransformer
+	hasher           *bytesutil.FastStringTransformer
 }
 
 // DebugStep contains debug information about a single relabeling rule step
@@ -145,6 +157,28 @@ func removeEmptyLabels(labels []prompbmarshal.Label, labelsOffset int) []prompbm
 	return dst
 }
 
+// getLabelsHash returns hash for the given labels, which doesn't depend on the order of labels.
+func getLabelsHash(labels []prompbmarshal.Label) uint64 {
+	bb := relabelBufPool.Get()
+	h := uint64(0)
+	for _, label := range labels {
+		bb.B = append(bb.B[:0], label.Name...)
+		bb.B = append(bb.B, '\xff')
+		bb.B = append(bb.B, label.Value...)
+		h += xxhash.Sum64(bb.B)
+	}
+	relabelBufPool.Put(bb)
+	return h
+}
+
+// getKeepRatioMaxHash returns the maximum series hash for keeping the given ratio of series.
+func getKeepRatioMaxHash(ratio float64) uint64 {
+	if ratio >= 1 {
+		return math.MaxUint64
+	}
+	return uint64(ratio * math.MaxUint64)
+}
+
 // FinalizeLabels removes labels with "__" in the beginning (except of "__name__").
 func FinalizeLabels(dst, src []prompbmarshal.Label) []prompbmarshal.Label {
 	for _, label := range src {
@@ -372,6 +406,34 @@ func (prc *parsedRelabelConfig) apply(labels []prompbmarshal.Label, labelsOffset
 		value := strconv.Itoa(int(h))
 		relabelBufPool.Put(bb)
 		return setLabelValue(labels, labelsOffset, prc.TargetLabel, value)
+	case "hash":
+		// Replace `target_label` with the salted hash of `source_labels` joined with `separator`.
+		// Empty value isn't hashed, so missing labels remain missing.
+		bb := relabelBufPool.Get()
+		bb.B = concatLabelValues(bb.B[:0], src, prc.SourceLabels, prc.Separator)
+		value := ""
+		if len(bb.B) > 0 {
+			value = prc.hasher.Transform(bytesutil.ToUnsafeString(bb.B))
+		}
+		relabelBufPool.Put(bb)
+		return setLabelValue(labels, labelsOffset, prc.TargetLabel, value)
+	case "keep_ratio":
+		// Keep the given share of series according to the hash of `source_labels` joined with `separator`.
+		// The hash of all the labels is used if `source_labels` are missing.
+		// This guarantees that the same series are kept over time.
+		var h uint64
+		if len(prc.SourceLabels) > 0 {
+			bb := relabelBufPool.Get()
+			bb.B = concatLabelValues(bb.B[:0], src, prc.SourceLabels, prc.Separator)
+			h = xxhash.Sum64(bb.B)
+			relabelBufPool.Put(bb)
+		} else {
+			h = getLabelsHash(src)
+		}
+		if h > prc.keepRatioMaxHash {
+			return labels[:labelsOffset]
+		}
+		return labels
 	case "labelmap":
 		// Replace label names with the `replacement` if they match `regex`
 		for _, label := range src {
@@ -430,6 +492,23 @@ func (prc *parsedRelabelConfig) apply(labels []prompbmarshal.Label, labelsOffset
 	}
 }
 
+const (
+	// defaultHashLength is the default number of hex chars in the hash generated by `action: hash`.
+	defaultHashLength = 16
+
+	// maxHashLength is the maximum number of hex chars in the hash generated by `action: hash`.
+	maxHashLength = 2 * sha256.Size
+)
+
+// hashStringSlow returns hex-encoded HMAC-SHA256 of s with prc.hashSalt truncated to prc.hashLength chars.
+func (prc *parsedRelabelConfig) hashStringSlow(s string) string {
+	mac := hmac.New(sha256.New, prc.hashSalt)
+	mac.Write([]byte(s))
+	var buf [2 * sha256.Size]byte
+	hex.Encode(buf[:], mac.Sum(nil))
+	return string(buf[:prc.hashLength])
+}
+
 // replaceFullStringFast replaces s with the replacement if s matches '^regex$'.
 //
 // s is returned as is if it doesn't match '^regex$'.
@@ -2,6 +2,7 @@ package promrelabel
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 
@@ -683,6 +684,57 @@ func TestParsedRelabelConfigsApply(t *testing.T) {
   modulus: 123
 `, `{xxx="yyy"}`, false, `{aaa="81",xxx="yyy"}`)
 
+	// hash-miss
+	f(`
+- action: hash
+  source_labels: [email]
+  target_label: email
+  salt: secret
+`, `{xxx="yyy"}`, true, `{xxx="yyy"}`)
+
+	// hash-hit
+	f(`
+- action: hash
+  source_labels: [email]
+  target_label: email
+  salt: secret
+`, `{email="user@example.com",xxx="yyy"}`, true, `{email="febca656b1fa2234",xxx="yyy"}`)
+
+	// hash-hit-different-salt
+	f(`
+- action: hash
+  source_labels: [email]
+  target_label: email
+  salt: other
+`, `{email="user@example.com",xxx="yyy"}`, true, `{email="f0b48ddfa9a08555",xxx="yyy"}`)
+
+	// hash-hit-hash-length
+	f(`
+- action: hash
+  source_labels: [email]
+  target_label: user
+  salt: secret
+  hash_length: 8
+`, `{email="user@example.com"}`, false, `{email="user@example.com",user="febca656"}`)
+
+	// hash-hit-multiple-source-labels
+	f(`
+- action: hash
+  source_labels: [email, id]
+  target_label: user
+  salt: secret
+  hash_length: 64
+`, `{email="user@example.com",id="42"}`, false, `{email="user@example.com",id="42",user="b31a90a4f78d21246ca8cf8be19178db9af7a0c0771045b50ce52ce66433f4ba"}`)
+
+	// hash-if-miss
+	f(`
+- action: hash
+  if: '{foo="bar"}'
+  source_labels: [email]
+  target_label: email
+  salt: secret
+`, `{email="user@example.com"}`, true, `{email="user@example.com"}`)
+
 	// hashmod-if-miss
 	f(`
 - action: hashmod
@@ -709,6 +761,49 @@ func TestParsedRelabelConfigsApply(t *testing.T) {
   modulus: 123
 `, `{foo="yyy"}`, true, `{aaa="73",foo="yyy"}`)
 
+	// keep_ratio-keep-all
+	f(`
+- action: keep_ratio
+  ratio: 1
+`, `{foo="yyy"}`, true, `{foo="yyy"}`)
+
+	// keep_ratio-drop-all
+	f(`
+- action: keep_ratio
+  ratio: 0
+`, `{foo="yyy"}`, true, `{}`)
+
+	// keep_ratio-if-miss
+	f(`
+- action: keep_ratio
+  if: '{foo="bar"}'
+  ratio: 0
+`, `{foo="yyy"}`, true, `{foo="yyy"}`)
+
+	// keep_ratio-if-hit
+	f(`
+- action: keep_ratio
+  if: '{foo="yyy"}'
+  ratio: 0
+`, `{foo="yyy"}`, true, `{}`)
+
+	// keep_ratio-source-labels
+	f(`
+- action: keep_ratio
+  source_labels: [foo]
+  ratio: 0.5
+`, `{foo="xxx",bar="a"}`, true, `{bar="a",foo="xxx"}`)
+	f(`
+- action: keep_ratio
+  source_labels: [foo]
+  ratio: 0.5
+`, `{foo="xxx",bar="b"}`, true, `{bar="b",foo="xxx"}`)
+	f(`
+- action: keep_ratio
+  source_labels: [foo]
+  ratio: 0.5
+`, `{foo="yyy",bar="a"}`, true, `{}`)
+
 	// labelmap-copy-label-if-miss
 	f(`
 - action: labelmap
@@ -947,6 +1042,50 @@ func TestParsedRelabelConfigsApply(t *testing.T) {
 		`{container_label_com_docker_swarm_task_name="myservice:subdomain",instance="subdomain.domain.com"}`)
 }
 
+func TestKeepRatio(t *testing.T) {
+	f := func(ratio float64) {
+		t.Helper()
+
+		pcs, err := ParseRelabelConfigsData([]byte(fmt.Sprintf(`
+- action: keep_ratio
+  ratio: %v
+`, ratio)))
+		if err != nil {
+			t.Fatalf("cannot parse relabel configs: %s", err)
+		}
+
+		const seriesCount = 10000
+		kept := 0
+		for i := 0; i < seriesCount; i++ {
+			labels := []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+				{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+				{Name: "job", Value: "bar"},
+			}
+			isKept := len(pcs.Apply(labels, 0)) > 0
+			if isKept {
+				kept++
+			}
+
+			// The result mustn't depend on the order of labels
+			labelsReversed := []prompbmarshal.Label{labels[2], labels[1], labels[0]}
+			if isKeptReversed := len(pcs.Apply(labelsReversed, 0)) > 0; isKeptReversed != isKept {
+				t.Fatalf("unexpected result for labels %s in reversed order; got %v; want %v", LabelsToString(labels), isKeptReversed, isKept)
+			}
+		}
+		keptRatio := float64(kept) / seriesCount
+		if math.Abs(keptRatio-ratio) > 0.02 {
+			t.Fatalf("unexpected share of kept series; got %.3f; want %.3f", keptRatio, ratio)
+		}
+	}
+
+	f(0)
+	f(0.1)
+	f(0.5)
+	f(0.9)
+	f(1)
+}
+
 func TestFinalizeLabels(t *testing.T) {
 	f := func(metric, resultExpected string) {
 		t.Helper()
@@ -0,0 +1,164 @@
+package promrelabel
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+)
+
+var ruleSetsPath = flag.String("relabelRuleSets", "", "Optional path to a file with named sets of relabeling rules, which can be referenced via rule_set option "+
+	"from any relabel configs such as relabel_configs and metric_relabel_configs at -promscrape.config or -remoteWrite.urlRelabelConfig. "+
+	"The path can point either to local file or to http url. The file is re-read on config reloads. See https://docs.victoriametrics.com/vmagent/#relabel-rule-sets")
+
+// maxRuleSetsDepth is the maximum nesting depth for rule sets, which reference other rule sets.
+const maxRuleSetsDepth = 16
+
+// ruleSetsCheckInterval is the interval in seconds for re-reading -relabelRuleSets file.
+//
+// This prevents from re-reading the file per every parsed relabel config on config reload.
+const ruleSetsCheckInterval = 1
+
+type ruleSetsCache struct {
+	mu sync.Mutex
+
+	// lastCheckTime is the last time in unix seconds when rss was obtained from path.
+	lastCheckTime uint64
+
+	path string
+	data []byte
+	rss  map[string][]RelabelConfig
+	err  error
+}
+
+var ruleSetsGlobal ruleSetsCache
+
+// getRuleSets returns named rule sets from -relabelRuleSets file.
+func getRuleSets() (map[string][]RelabelConfig, error) {
+	if *ruleSetsPath == "" {
+		return nil, fmt.Errorf("missing -relabelRuleSets command-line flag")
+	}
+	return ruleSetsGlobal.get(*ruleSetsPath)
+}
+
+func (rsc *ruleSetsCache) get(path string) (map[string][]RelabelConfig, error) {
+	rsc.mu.Lock()
+	defer rsc.mu.Unlock()
+
+	ct := fasttime.UnixTimestamp()
+	if path == rsc.path && ct < rsc.lastCheckTime+ruleSetsCheckInterval {
+		return rsc.rss, rsc.err
+	}
+	rsc.lastCheckTime = ct
+	if path != rsc.path {
+		rsc.path = path
+		rsc.data = nil
+		rsc.rss = nil
+	}
+
+	data, err := fscore.ReadFileOrHTTP(path)
+	if err != nil {
+		rsc.err = fmt.Errorf("cannot read -relabelRuleSets=%q: %w", path, err)
+		return nil, rsc.err
+	}
+	if rsc.rss != nil && string(data) == string(rsc.data) {
+		// Fast path - the file contents didn't change.
+		rsc.err = nil
+		return rsc.rss, nil
+	}
+	rss, err := parseRuleSetsData(data)
+	if err != nil {
+		rsc.err = fmt.Errorf("cannot parse -relabelRuleSets=%q: %w", path, err)
+		return nil, rsc.err
+	}
+	rsc.data = data
+	rsc.rss = rss
+	rsc.err = nil
+	return rss, nil
+}
+
+// parseRuleSetsData parses named rule sets from data and verifies that references between rule sets are valid.
+func parseRuleSetsData(data []byte) (map[string][]RelabelConfig, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var rss map[string][]RelabelConfig
+	if err := yaml.UnmarshalStrict(data, &rss); err != nil {
+		return nil, err
+	}
+	for name := range rss {
+		if name == "" {
+			return nil, fmt.Errorf("rule set name cannot be empty")
+		}
+		if _, err := expandRuleSets(rss[name], rss, []string{name}); err != nil {
+			return nil, fmt.Errorf("invalid rule set %q: %w", name, err)
+		}
+	}
+	return rss, nil
+}
+
+// hasRuleSetRefs returns true if rcs contain references to rule sets.
+func hasRuleSetRefs(rcs []RelabelConfig) bool {
+	for i := range rcs {
+		if rcs[i].RuleSet != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// expandRuleSets returns rcs with `rule_set: <name>` entries substituted with the rules from the referenced rule sets at rss.
+//
+// path contains the names of rule sets, which are being expanded. It is used for detecting cycles.
+func expandRuleSets(rcs []RelabelConfig, rss map[string][]RelabelConfig, path []string) ([]RelabelConfig, error) {
+	if !hasRuleSetRefs(rcs) {
+		return rcs, nil
+	}
+	if len(path) > maxRuleSetsDepth {
+		return nil, fmt.Errorf("too deep nesting of rule sets: %s; the maximum supported depth is %d", strings.Join(path, " -> "), maxRuleSetsDepth)
+	}
+	var dst []RelabelConfig
+	for i := range rcs {
+		rc := &rcs[i]
+		if rc.RuleSet == "" {
+			dst = append(dst, *rc)
+			continue
+		}
+		if !rc.isRuleSetRefOnly() {
+			return nil, fmt.Errorf("`rule_set: %s` cannot be mixed with other options at the same relabeling rule", rc.RuleSet)
+		}
+		for _, name := range path {
+			if name == rc.RuleSet {
+				return nil, fmt.Errorf("cyclic reference to rule set: %s -> %s", strings.Join(path, " -> "), rc.RuleSet)
+			}
+		}
+		rs, ok := rss[rc.RuleSet]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule set %q", rc.RuleSet)
+		}
+		rsExpanded, err := expandRuleSets(rs, rss, append(path, rc.RuleSet))
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, rsExpanded...)
+	}
+	return dst, nil
+}
+
+func (rc *RelabelConfig) isRuleSetRefOnly() bool {
+	rcCopy := *rc
+	rcCopy.RuleSet = ""
+	return rcCopy.isEmpty()
+}
+
+func (rc *RelabelConfig) isEmpty() bool {
+	return rc.If == nil && rc.Action == "" && len(rc.SourceLabels) == 0 && rc.Separator == nil && rc.TargetLabel == "" && rc.Regex == nil &&
+		rc.Modulus == 0 && rc.Replacement == nil && rc.Match == "" && len(rc.Labels) == 0 && rc.Ratio == nil && rc.Salt == nil && rc.HashLength == 0
+}
@@ -0,0 +1,139 @@
+package promrelabel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRuleSetsDataFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseRuleSetsData([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown option
+	f(`
+foo:
+- action: drop
+  foo: bar
+`)
+
+	// unknown rule set
+	f(`
+foo:
+- rule_set: bar
+`)
+
+	// rule_set mixed with other options
+	f(`
+foo:
+- action: drop
+  source_labels: [x]
+bar:
+- rule_set: foo
+  action: keep
+`)
+
+	// self-reference
+	f(`
+foo:
+- action: drop
+  source_labels: [x]
+- rule_set: foo
+`)
+
+	// cyclic references
+	f(`
+foo:
+- rule_set: bar
+bar:
+- rule_set: baz
+baz:
+- rule_set: foo
+`)
+}
+
+func TestParseRelabelConfigsWithRuleSets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rule_sets.yml")
+	data := `
+drop_debug:
+- action: drop
+  source_labels: [__name__]
+  regex: 'debug_.+'
+common:
+- action: labeldrop
+  regex: 'tmp_.+'
+- rule_set: drop_debug
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("cannot write rule sets: %s", err)
+	}
+	ruleSetsPathOrig := *ruleSetsPath
+	*ruleSetsPath = path
+	defer func() {
+		*ruleSetsPath = ruleSetsPathOrig
+	}()
+
+	f := func(config, resultExpected string) {
+		t.Helper()
+		pcs, err := ParseRelabelConfigsData([]byte(config))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := pcs.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// rule set at the beginning
+	f(`
+- rule_set: drop_debug
+- target_label: foo
+  replacement: bar
+`, `- action: drop
+  source_labels: [__name__]
+  regex: debug_.+
+- target_label: foo
+  replacement: bar
+`)
+
+	// rule set at the end
+	f(`
+- target_label: foo
+  replacement: bar
+- rule_set: drop_debug
+`, `- target_label: foo
+  replacement: bar
+- action: drop
+  source_labels: [__name__]
+  regex: debug_.+
+`)
+
+	// nested rule sets
+	f(`
+- rule_set: common
+`, `- action: labeldrop
+  regex: tmp_.+
+- action: drop
+  source_labels: [__name__]
+  regex: debug_.+
+`)
+
+	// unknown rule set
+	if _, err := ParseRelabelConfigsData([]byte(`[{rule_set: foobar}]`)); err == nil {
+		t.Fatalf("expecting non-nil error for unknown rule set")
+	}
+}
+
+func TestParseRelabelConfigsWithRuleSetsMissingFlag(t *testing.T) {
+	if _, err := ParseRelabelConfigsData([]byte(`[{rule_set: foobar}]`)); err == nil {
+		t.Fatalf("expecting non-nil error when -relabelRuleSets isn't set")
+	}
+}
@@ -160,8 +160,8 @@ func (c *client) ReadData(dst *chunkedbuffer.Buffer) (bool, error) {
 		if err != nil {
 			respBody = []byte(err.Error())
 		}
-		return false, fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
-			c.scrapeURL, resp.StatusCode, http.StatusOK, respBody)
+		return false, newScrapeError("http_status", fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
+			c.scrapeURL, resp.StatusCode, http.StatusOK, respBody))
 	}
 	scrapesOK.Inc()
 
@@ -176,9 +176,9 @@ func (c *client) ReadData(dst *chunkedbuffer.Buffer) (bool, error) {
 	}
 	if int64(dst.Len()) >= c.maxScrapeSize {
 		maxScrapeSizeExceeded.Inc()
-		return false, fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize or max_scrape_size in the scrape config (%d bytes). "+
+		return false, newScrapeError("response_too_large", fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize or max_scrape_size in the scrape config (%d bytes). "+
 			"Possible solutions are: reduce the response size for the target, increase -promscrape.maxScrapeSize command-line flag, "+
-			"increase max_scrape_size value in scrape config for the given target", c.scrapeURL, c.maxScrapeSize)
+			"increase max_scrape_size value in scrape config for the given target", c.scrapeURL, c.maxScrapeSize))
 	}
 
 	isGzipped := resp.Header.Get("Content-Encoding") == "gzip"
@@ -55,7 +55,11 @@ var (
 	noStaleMarkers       = flag.Bool("promscrape.noStaleMarkers", false, "Whether to disable sending Prometheus stale markers for metrics when scrape target disappears. This option may reduce memory usage if stale markers aren't needed for your setup. This option also disables populating the scrape_series_added metric. See https://prometheus.io/docs/concepts/jobs_instances/#automatically-generated-labels-and-time-series")
 	seriesLimitPerTarget = flag.Int("promscrape.seriesLimitPerTarget", 0, "Optional limit on the number of unique time series a single scrape target can expose. See https://docs.victoriametrics.com/vmagent/#cardinality-limiter for more info")
 	strictParse          = flag.Bool("promscrape.config.strictParse", true, "Whether to deny unsupported fields in -promscrape.config . Set to false in order to silently skip unsupported fields")
-	dryRun               = flag.Bool("promscrape.config.dryRun", false, "Checks -promscrape.config file for errors and unsupported fields and then exits. "+
+	envSubst             = flagutil.NewArrayString("promscrape.config.envSubst", "Optional list of environment variable names, which can be referred via ${VAR} "+
+		"and ${VAR:-default} placeholders in -promscrape.config and in files referred by scrape_config_files. "+
+		"Config loading fails if the referred variable isn't set and has no default value. Placeholders for other names are left as is. "+
+		"See https://docs.victoriametrics.com/vmagent/#environment-variables-in-scrape-configs")
+	dryRun = flag.Bool("promscrape.config.dryRun", false, "Checks -promscrape.config file for errors and unsupported fields and then exits. "+
 		"Returns non-zero exit code on parsing errors and emits these errors to stderr. "+
 		"See also -promscrape.config.strictParse command-line flag. "+
 		"Pass -loggerLevel=ERROR if you don't need to see info messages in the output.")
@@ -118,11 +122,16 @@ type Config struct {
 
 	// This is set to the directory from where the config has been loaded.
 	baseDir string
+
+	// namespace is the name of vmagent namespace the config belongs to.
+	//
+	// It is empty for -promscrape.config.
+	namespace string
 }
 
 func (cfg *Config) unmarshal(data []byte, isStrict bool) error {
 	var err error
-	data, err = envtemplate.ReplaceBytes(data)
+	data, err = expandEnvVars(data)
 	if err != nil {
 		return fmt.Errorf("cannot expand environment variables: %w", err)
 	}
@@ -136,6 +145,15 @@ func (cfg *Config) unmarshal(data []byte, isStrict bool) error {
 	return err
 }
 
+// expandEnvVars expands `%{ENV_VAR}` placeholders and `${ENV_VAR}` placeholders for env vars from -promscrape.config.envSubst in data.
+func expandEnvVars(data []byte) ([]byte, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return envtemplate.ReplaceAllowedBytes(data, *envSubst)
+}
+
 func (cfg *Config) marshal() []byte {
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
@@ -151,7 +169,7 @@ func (cfg *Config) mustStart() {
 		sc.mustStart(cfg.baseDir)
 	}
 	jobNames := cfg.getJobNames()
-	tsmGlobal.registerJobNames(jobNames)
+	tsmGlobal.registerJobNames(cfg.namespace, jobNames)
 	logger.Infof("started %d service discovery routines in %.3f seconds", len(cfg.ScrapeConfigs), time.Since(startTime).Seconds())
 }
 
@@ -201,7 +219,7 @@ func (cfg *Config) mustRestart(prevCfg *Config) bool {
 		}
 	}
 	jobNames := cfg.getJobNames()
-	tsmGlobal.registerJobNames(jobNames)
+	tsmGlobal.registerJobNames(cfg.namespace, jobNames)
 	updated := started + stopped + restarted
 	if updated == 0 {
 		return false
@@ -220,7 +238,19 @@ func areEqualGlobalConfigs(a, b *GlobalConfig) bool {
 func areEqualScrapeConfigs(a, b *ScrapeConfig) bool {
 	sa := a.marshalJSON()
 	sb := b.marshalJSON()
-	return string(sa) == string(sb)
+	if string(sa) != string(sb) {
+		return false
+	}
+	// Relabel configs may reference rule sets from -relabelRuleSets, which could change independently of the scrape config.
+	// So compare the parsed relabel configs with the substituted rule sets.
+	return areEqualParsedRelabelConfigs(a.swc, b.swc)
+}
+
+func areEqualParsedRelabelConfigs(a, b *scrapeWorkConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.relabelConfigs.String() == b.relabelConfigs.String() && a.metricRelabelConfigs.String() == b.metricRelabelConfigs.String()
 }
 
 func (sc *ScrapeConfig) unmarshalJSON(data []byte) error {
@@ -249,14 +279,17 @@ func (cfg *Config) mustStop() {
 	for _, sc := range cfg.ScrapeConfigs {
 		sc.mustStop()
 	}
+	if cfg.namespace != "" {
+		tsmGlobal.registerJobNames(cfg.namespace, nil)
+	}
 	logger.Infof("stopped %d service discovery routines in %.3f seconds", len(cfg.ScrapeConfigs), time.Since(startTime).Seconds())
 }
 
-// getJobNames returns all the scrape job names from the cfg.
+// getJobNames returns all the scrape pool names from the cfg.
 func (cfg *Config) getJobNames() []string {
 	a := make([]string, 0, len(cfg.ScrapeConfigs))
 	for _, sc := range cfg.ScrapeConfigs {
-		a = append(a, sc.JobName)
+		a = append(a, sc.swc.scrapePool)
 	}
 	return a
 }
@@ -267,6 +300,8 @@ func (cfg *Config) getJobNames() []string {
 type GlobalConfig struct {
 	ScrapeInterval       *promutil.Duration          `yaml:"scrape_interval,omitempty"`
 	ScrapeTimeout        *promutil.Duration          `yaml:"scrape_timeout,omitempty"`
+	ScrapeAlignInterval  *promutil.Duration          `yaml:"scrape_align_interval,omitempty"`
+	ScrapeOffset         *promutil.Duration          `yaml:"scrape_offset,omitempty"`
 	ExternalLabels       *promutil.Labels            `yaml:"external_labels,omitempty"`
 	RelabelConfigs       []promrelabel.RelabelConfig `yaml:"relabel_configs,omitempty"`
 	MetricRelabelConfigs []promrelabel.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
@@ -333,9 +368,17 @@ type ScrapeConfig struct {
 	ScrapeAlignInterval *promutil.Duration         `yaml:"scrape_align_interval,omitempty"`
 	ScrapeOffset        *promutil.Duration         `yaml:"scrape_offset,omitempty"`
 	SeriesLimit         *int                       `yaml:"series_limit,omitempty"`
+	SampleGrowthLimit   float64                    `yaml:"sample_growth_limit,omitempty"`
+	SampleGrowthAction  string                     `yaml:"sample_growth_action,omitempty"`
+	SampleGrowthMargin  int                        `yaml:"sample_growth_margin,omitempty"`
+	SampleGrowthDrops   int                        `yaml:"sample_growth_max_drops,omitempty"`
 	NoStaleMarkers      *bool                      `yaml:"no_stale_markers,omitempty"`
 	ProxyClientConfig   promauth.ProxyClientConfig `yaml:",inline"`
 
+	// KubernetesAnnotations enables configuring scrape targets from Kubernetes annotations.
+	// See https://docs.victoriametrics.com/sd_configs/#scraping-targets-via-kubernetes-annotations
+	KubernetesAnnotations *KubernetesAnnotationsConfig `yaml:"kubernetes_annotations,omitempty"`
+
 	// This is set in loadConfig
 	swc *scrapeWorkConfig
 }
@@ -451,8 +494,10 @@ func loadStaticConfigs(path string) ([]StaticConfig, error) {
 }
 
 // loadConfig loads Prometheus config from the given path.
+//
+// The config is verified according to -promscrape.config.minisignPublicKey and -promscrape.config.sha256Manifest before parsing.
 func loadConfig(path string) (*Config, error) {
-	data, err := fscore.ReadFileOrHTTP(path)
+	data, err := readVerifiedConfig(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read Prometheus config from %q: %w", path, err)
 	}
@@ -463,6 +508,26 @@ func loadConfig(path string) (*Config, error) {
 	return &c, nil
 }
 
+// loadNamespaceConfig loads Prometheus config for the given vmagent namespace from the given path.
+//
+// Scrape pools for the loaded config are prefixed with `namespace/` if namespace isn't empty,
+// so they do not clash with scrape pools from -promscrape.config and from other namespaces.
+//
+// The config is verified in the same way as `scrape_config_files` entries - see ReadVerifiedConfigFile.
+func loadNamespaceConfig(namespace, path string) (*Config, error) {
+	data, err := ReadVerifiedConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read Prometheus config from %q: %w", path, err)
+	}
+	c := Config{
+		namespace: namespace,
+	}
+	if err := c.parseData(data, path); err != nil {
+		return nil, fmt.Errorf("cannot parse Prometheus config from %q: %w", path, err)
+	}
+	return &c, nil
+}
+
 func loadScrapeConfigFiles(baseDir string, scrapeConfigFiles []string, isStrict bool) ([]*ScrapeConfig, error) {
 	var scrapeConfigs []*ScrapeConfig
 	for _, filePath := range scrapeConfigFiles {
@@ -478,12 +543,12 @@ func loadScrapeConfigFiles(baseDir string, scrapeConfigFiles []string, isStrict
 			paths = ps
 		}
 		for _, path := range paths {
-			data, err := fscore.ReadFileOrHTTP(path)
+			data, err := ReadVerifiedConfigFile(path)
 			if err != nil {
 				logger.Errorf("skipping %q at `scrape_config_files` because of error: %s", path, err)
 				continue
 			}
-			data, err = envtemplate.ReplaceBytes(data)
+			data, err = expandEnvVars(data)
 			if err != nil {
 				logger.Errorf("skipping %q at `scrape_config_files` because of failure to expand environment vars: %s", path, err)
 				continue
@@ -554,6 +619,9 @@ func (cfg *Config) parseData(data []byte, path string) error {
 			logger.Errorf("skipping `scrape_config` for job_name=%s because of error: %s", sc.JobName, err)
 			continue
 		}
+		if cfg.namespace != "" {
+			swc.scrapePool = cfg.namespace + "/" + swc.jobName
+		}
 		sc.swc = swc
 		validScrapeConfigs = append(validScrapeConfigs, sc)
 	}
@@ -853,7 +921,7 @@ func (cfg *Config) getScrapeWorkGeneric(visitConfigs func(sc *ScrapeConfig, visi
 }
 
 func (sc *ScrapeConfig) appendPrevTargets(dst []*ScrapeWork, swsPrevByJob map[string][]*ScrapeWork, discoveryType string) []*ScrapeWork {
-	swsPrev := swsPrevByJob[sc.swc.jobName]
+	swsPrev := swsPrevByJob[sc.swc.scrapePool]
 	if len(swsPrev) == 0 {
 		return dst
 	}
@@ -963,10 +1031,44 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 	if sc.SeriesLimit != nil {
 		seriesLimit = *sc.SeriesLimit
 	}
+	if sc.SampleGrowthLimit != 0 && sc.SampleGrowthLimit <= 1 {
+		return nil, fmt.Errorf("`sample_growth_limit` for `job_name` %q must be bigger than 1; got %v", jobName, sc.SampleGrowthLimit)
+	}
+	switch sc.SampleGrowthAction {
+	case "", "drop", "cap", "alert":
+	default:
+		return nil, fmt.Errorf("unexpected `sample_growth_action` for `job_name` %q: %q; supported values: drop, cap or alert", jobName, sc.SampleGrowthAction)
+	}
+	if sc.SampleGrowthMargin < 0 {
+		return nil, fmt.Errorf("`sample_growth_margin` for `job_name` %q cannot be negative; got %d", jobName, sc.SampleGrowthMargin)
+	}
+	if sc.SampleGrowthDrops < 0 {
+		return nil, fmt.Errorf("`sample_growth_max_drops` for `job_name` %q cannot be negative; got %d", jobName, sc.SampleGrowthDrops)
+	}
 	disableCompression := sc.DisableCompression
 	if sc.EnableCompression != nil {
 		disableCompression = !*sc.EnableCompression
 	}
+	// scrape_align_interval and scrape_offset from the `global` section are applied to jobs without these options.
+	// Jobs may opt out of the global alignment by setting these options to zero.
+	scrapeAlignInterval := globalCfg.ScrapeAlignInterval
+	if sc.ScrapeAlignInterval != nil {
+		scrapeAlignInterval = sc.ScrapeAlignInterval
+	}
+	scrapeOffset := globalCfg.ScrapeOffset
+	if sc.ScrapeOffset != nil {
+		scrapeOffset = sc.ScrapeOffset
+	}
+	var ka *kubernetesAnnotations
+	if sc.KubernetesAnnotations != nil {
+		if len(sc.KubernetesSDConfigs) == 0 {
+			return nil, fmt.Errorf("`kubernetes_annotations` for `job_name` %q can be set only together with `kubernetes_sd_configs`", jobName)
+		}
+		ka, err = newKubernetesAnnotations(sc.KubernetesAnnotations, mrcs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `kubernetes_annotations` for `job_name` %q: %w", jobName, err)
+		}
+	}
 	swc := &scrapeWorkConfig{
 		scrapeInterval:       scrapeInterval,
 		scrapeIntervalString: scrapeInterval.String(),
@@ -974,6 +1076,7 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 		scrapeTimeoutString:  scrapeTimeout.String(),
 		maxScrapeSize:        mss,
 		jobName:              jobName,
+		scrapePool:           jobName,
 		metricsPath:          metricsPath,
 		scheme:               scheme,
 		params:               params,
@@ -990,10 +1093,16 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 		disableCompression:   disableCompression,
 		disableKeepAlive:     sc.DisableKeepAlive,
 		streamParse:          sc.StreamParse,
-		scrapeAlignInterval:  sc.ScrapeAlignInterval.Duration(),
-		scrapeOffset:         sc.ScrapeOffset.Duration(),
+		scrapeAlignInterval:  scrapeAlignInterval.Duration(),
+		scrapeOffset:         scrapeOffset.Duration(),
 		seriesLimit:          seriesLimit,
+		sampleGrowthLimit:    sc.SampleGrowthLimit,
+		sampleGrowthAction:   sc.SampleGrowthAction,
+		sampleGrowthMargin:   sc.SampleGrowthMargin,
+		sampleGrowthDrops:    sc.SampleGrowthDrops,
 		noStaleMarkers:       noStaleTracking,
+
+		kubernetesAnnotations: ka,
 	}
 	return swc, nil
 }
@@ -1005,6 +1114,7 @@ type scrapeWorkConfig struct {
 	scrapeTimeoutString  string
 	maxScrapeSize        int64
 	jobName              string
+	scrapePool           string
 	metricsPath          string
 	scheme               string
 	params               map[string][]string
@@ -1024,7 +1134,14 @@ type scrapeWorkConfig struct {
 	scrapeAlignInterval  time.Duration
 	scrapeOffset         time.Duration
 	seriesLimit          int
+	sampleGrowthLimit    float64
+	sampleGrowthAction   string
+	sampleGrowthMargin   int
+	sampleGrowthDrops    int
 	noStaleMarkers       bool
+
+	// kubernetesAnnotations is set if targets must be configured from Kubernetes annotations.
+	kubernetesAnnotations *kubernetesAnnotations
 }
 
 func appendScrapeWorkForTargetLabels(dst []*ScrapeWork, swc *scrapeWorkConfig, targetLabels []*promutil.Labels, discoveryType string) []*ScrapeWork {
@@ -1171,6 +1288,26 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 	if !*dropOriginalLabels {
 		originalLabels = labels.Clone()
 	}
+	metricRelabelConfigs := swc.metricRelabelConfigs
+	if swc.kubernetesAnnotations != nil {
+		// Apply Kubernetes annotations before relabeling, so they could be overridden via relabel_configs.
+		// See https://docs.victoriametrics.com/sd_configs/#scraping-targets-via-kubernetes-annotations
+		ok, pcs, err := swc.kubernetesAnnotations.apply(labels)
+		if err != nil {
+			// Register the target with invalid annotations as dropped, so the error is visible at /targets page.
+			originalLabels = sortOriginalLabelsIfNeeded(originalLabels)
+			droppedTargetsMap.Register(originalLabels, swc.relabelConfigs, targetDropReason("invalid annotations: "+err.Error()), nil)
+			return nil, fmt.Errorf("invalid Kubernetes annotations for target %q in job=%q: %w", target, swc.jobName, err)
+		}
+		if !ok {
+			originalLabels = sortOriginalLabelsIfNeeded(originalLabels)
+			droppedTargetsMap.Register(originalLabels, swc.relabelConfigs, targetDropReasonAnnotations, nil)
+			return nil, nil
+		}
+		if pcs != nil {
+			metricRelabelConfigs = pcs
+		}
+	}
 	labels.Labels = swc.relabelConfigs.Apply(labels.Labels, 0)
 	// Remove labels starting from "__meta_" prefix according to https://www.robustperception.io/life-of-a-label/
 	labels.RemoveMetaLabels()
@@ -1299,7 +1436,7 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 		ProxyAuthConfig:      swc.proxyAuthConfig,
 		AuthConfig:           swc.authConfig,
 		RelabelConfigs:       swc.relabelConfigs,
-		MetricRelabelConfigs: swc.metricRelabelConfigs,
+		MetricRelabelConfigs: metricRelabelConfigs,
 		SampleLimit:          sampleLimit,
 		DisableCompression:   swc.disableCompression,
 		DisableKeepAlive:     swc.disableKeepAlive,
@@ -1307,10 +1444,14 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 		ScrapeAlignInterval:  swc.scrapeAlignInterval,
 		ScrapeOffset:         swc.scrapeOffset,
 		SeriesLimit:          seriesLimit,
+		SampleGrowthLimit:    swc.sampleGrowthLimit,
+		SampleGrowthAction:   swc.sampleGrowthAction,
+		SampleGrowthMargin:   swc.sampleGrowthMargin,
+		SampleGrowthDrops:    swc.sampleGrowthDrops,
 		NoStaleMarkers:       swc.noStaleMarkers,
 		AuthToken:            at,
 
-		jobNameOriginal: swc.jobName,
+		jobNameOriginal: swc.scrapePool,
 	}
 	return sw, nil
 }
@@ -212,6 +212,75 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadNamespaceConfig(t *testing.T) {
+	cfg, err := loadNamespaceConfig("team-a", "testdata/prometheus.yml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	jobNames := cfg.getJobNames()
+	jobNamesExpected := []string{"team-a/foo", "team-a/file-job", "team-a/service-kubernetes"}
+	if !reflect.DeepEqual(jobNames, jobNamesExpected) {
+		t.Fatalf("unexpected scrape pools; got %q; want %q", jobNames, jobNamesExpected)
+	}
+
+	// The job label must remain unchanged.
+	sws := cfg.getStaticScrapeWork()
+	if len(sws) == 0 {
+		t.Fatalf("expecting non-empty static scrape work")
+	}
+	for _, sw := range sws {
+		if sw.jobNameOriginal != "team-a/foo" {
+			t.Fatalf("unexpected scrape pool; got %q; want %q", sw.jobNameOriginal, "team-a/foo")
+		}
+		if job := sw.Labels.Get("job"); job != "foo" {
+			t.Fatalf("unexpected job label; got %q; want %q", job, "foo")
+		}
+	}
+}
+
+func TestConfigUnmarshalEnvSubst(t *testing.T) {
+	origEnvSubst := *envSubst
+	*envSubst = []string{"VM_PROMSCRAPE_TEST_MISSING_ENV"}
+	defer func() {
+		*envSubst = origEnvSubst
+	}()
+
+	data := `
+scrape_configs:
+- job_name: ${VM_PROMSCRAPE_TEST_MISSING_ENV:-foo}
+  static_configs:
+  - targets: ["${VM_PROMSCRAPE_TEST_MISSING_ENV:-localhost:9100}"]
+  relabel_configs:
+  - source_labels: [__address__]
+    regex: "(.+):.+"
+    target_label: host
+    replacement: "${1}"
+`
+	var cfg Config
+	if err := cfg.unmarshal([]byte(data), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sc := cfg.ScrapeConfigs[0]
+	if sc.JobName != "foo" {
+		t.Fatalf("unexpected job_name; got %q; want %q", sc.JobName, "foo")
+	}
+	if target := sc.StaticConfigs[0].Targets[0]; target != "localhost:9100" {
+		t.Fatalf("unexpected target; got %q; want %q", target, "localhost:9100")
+	}
+	if replacement := *sc.RelabelConfigs[0].Replacement; replacement != "${1}" {
+		t.Fatalf("unexpected replacement; got %q; want %q", replacement, "${1}")
+	}
+
+	// missing env var without the default value
+	data = `
+scrape_configs:
+- job_name: ${VM_PROMSCRAPE_TEST_MISSING_ENV}
+`
+	if err := cfg.unmarshal([]byte(data), true); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
 func TestAddressWithFullURL(t *testing.T) {
 	data := `
 scrape_configs:
@@ -442,6 +511,71 @@ scrape_configs:
 `)
 }
 
+func TestGetScrapeWorkConfigFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		var cfg Config
+		if err := cfg.unmarshal([]byte(data), true); err != nil {
+			t.Fatalf("cannot parse config: %s", err)
+		}
+		sc := cfg.ScrapeConfigs[0]
+		if _, err := getScrapeWorkConfig(sc, "", &cfg.Global); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Invalid sample_growth_limit
+	f(`
+scrape_configs:
+- job_name: foo
+  sample_growth_limit: 0.5
+`)
+
+	// Unsupported sample_growth_action
+	f(`
+scrape_configs:
+- job_name: foo
+  sample_growth_limit: 5
+  sample_growth_action: foo
+`)
+
+	// Negative sample_growth_margin
+	f(`
+scrape_configs:
+- job_name: foo
+  sample_growth_limit: 5
+  sample_growth_action: cap
+  sample_growth_margin: -1
+`)
+
+	// Negative sample_growth_max_drops
+	f(`
+scrape_configs:
+- job_name: foo
+  sample_growth_limit: 5
+  sample_growth_max_drops: -1
+`)
+
+	// kubernetes_annotations without kubernetes_sd_configs
+	f(`
+scrape_configs:
+- job_name: foo
+  kubernetes_annotations: {}
+`)
+
+	// Invalid metric relabel snippet in kubernetes_annotations
+	f(`
+scrape_configs:
+- job_name: foo
+  kubernetes_sd_configs:
+  - role: pod
+  kubernetes_annotations:
+    metric_relabel_snippets:
+      foo:
+      - action: unsupported
+`)
+}
+
 // String returns human-readable representation for sw.
 func (sw *ScrapeWork) String() string {
 	return stringsutil.JSONString(sw.key())
@@ -1237,6 +1371,47 @@ scrape_configs:
 		},
 	})
 
+	// scrape_align_interval and scrape_offset from global section
+	f(`
+global:
+  scrape_align_interval: 1m
+  scrape_offset: 5s
+scrape_configs:
+- job_name: aligned
+  static_configs:
+  - targets: ["foo.bar:1234"]
+- job_name: spread
+  scrape_align_interval: 0s
+  scrape_offset: 0s
+  static_configs:
+  - targets: ["foo.bar:1234"]
+`, []*ScrapeWork{
+		{
+			ScrapeURL:           "http://foo.bar:1234/metrics",
+			ScrapeInterval:      defaultScrapeInterval,
+			ScrapeTimeout:       defaultScrapeTimeout,
+			ScrapeAlignInterval: time.Minute,
+			ScrapeOffset:        5 * time.Second,
+			MaxScrapeSize:       maxScrapeSize.N,
+			Labels: promutil.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:1234",
+				"job":      "aligned",
+			}),
+			jobNameOriginal: "aligned",
+		},
+		{
+			ScrapeURL:      "http://foo.bar:1234/metrics",
+			ScrapeInterval: defaultScrapeInterval,
+			ScrapeTimeout:  defaultScrapeTimeout,
+			MaxScrapeSize:  maxScrapeSize.N,
+			Labels: promutil.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:1234",
+				"job":      "spread",
+			}),
+			jobNameOriginal: "spread",
+		},
+	})
+
 	defaultSeriesLimitPerTarget := *seriesLimitPerTarget
 	*seriesLimitPerTarget = 1e3
 	f(`
@@ -1260,6 +1435,33 @@ scrape_configs:
 		},
 	})
 	*seriesLimitPerTarget = defaultSeriesLimitPerTarget
+
+	f(`
+scrape_configs:
+- job_name: foo
+  sample_growth_limit: 5
+  sample_growth_action: cap
+  sample_growth_margin: 100
+  sample_growth_max_drops: 10
+  static_configs:
+  - targets: ["foo.bar:1234"]
+`, []*ScrapeWork{
+		{
+			ScrapeURL:       "http://foo.bar:1234/metrics",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			MaxScrapeSize:   maxScrapeSize.N,
+			jobNameOriginal: "foo",
+			Labels: promutil.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:1234",
+				"job":      "foo",
+			}),
+			SampleGrowthLimit:  5,
+			SampleGrowthAction: "cap",
+			SampleGrowthMargin: 100,
+			SampleGrowthDrops:  10,
+		},
+	})
 }
 
 func equalStaticConfigForScrapeWorks(a, b []*ScrapeWork) bool {
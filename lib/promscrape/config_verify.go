@@ -0,0 +1,183 @@
+package promscrape
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"golang.org/x/crypto/blake2b"
+)
+
+var (
+	configMinisignPublicKey = flag.String("promscrape.config.minisignPublicKey", "", "Optional minisign public key for verifying -promscrape.config contents "+
+		"before applying them. Files referred via scrape_config_files, -namespaces.config and namespace scrape configs are verified with the same key. "+
+		"The key must be in the format used at the second line of minisign.pub file. "+
+		"The detached signature is read from -promscrape.config.signatureURL. "+
+		"See https://docs.victoriametrics.com/vmagent/#scrape-config-verification")
+	configSignatureURL = flag.String("promscrape.config.signatureURL", "", "Optional path or http url to detached minisign signature for -promscrape.config . "+
+		"By default, the signature is read from -promscrape.config with .minisig suffix. "+
+		"The signature is verified only if -promscrape.config.minisignPublicKey is set")
+	configSHA256Manifest = flag.String("promscrape.config.sha256Manifest", "", "Optional path or http url to a manifest with sha256 checksum for -promscrape.config contents "+
+		"in the format generated by sha256sum command. The -promscrape.config contents must match the checksum before applying them. "+
+		"The manifest must also contain checksums for files referred via scrape_config_files, -namespaces.config and namespace scrape configs. "+
+		"See https://docs.victoriametrics.com/vmagent/#scrape-config-verification")
+)
+
+// readVerifiedConfig reads -promscrape.config contents from the given path.
+//
+// The contents are verified according to -promscrape.config.minisignPublicKey and -promscrape.config.sha256Manifest
+// command-line flags, so tampered config isn't applied.
+func readVerifiedConfig(path string) ([]byte, error) {
+	return readVerifiedFile(path, *configSignatureURL, true)
+}
+
+// ReadVerifiedConfigFile reads the contents of additional scrape config file from the given path.
+//
+// The file may be `scrape_config_files` entry, -namespaces.config or namespace scrape config. It is verified in the same way as -promscrape.config,
+// except of the signature, which is always read from the path with .minisig suffix,
+// and the checksum, which must be present in -promscrape.config.sha256Manifest under the file name.
+func ReadVerifiedConfigFile(path string) ([]byte, error) {
+	return readVerifiedFile(path, "", false)
+}
+
+func readVerifiedFile(path, sigPath string, allowUnnamedChecksum bool) ([]byte, error) {
+	data, err := fscore.ReadFileOrHTTP(path)
+	if err != nil {
+		return nil, err
+	}
+	if *configSHA256Manifest != "" {
+		manifest, err := fscore.ReadFileOrHTTP(*configSHA256Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read sha256 manifest: %w", err)
+		}
+		if err := verifySHA256Manifest(data, manifest, configFileName(path), allowUnnamedChecksum); err != nil {
+			return nil, fmt.Errorf("cannot verify sha256 checksum from %q: %w", *configSHA256Manifest, err)
+		}
+	}
+	if *configMinisignPublicKey != "" {
+		if sigPath == "" {
+			sigPath = path + ".minisig"
+		}
+		sig, err := fscore.ReadFileOrHTTP(sigPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read minisign signature: %w", err)
+		}
+		if err := verifyMinisign(data, sig, *configMinisignPublicKey); err != nil {
+			return nil, fmt.Errorf("cannot verify minisign signature from %q: %w", sigPath, err)
+		}
+	}
+	return data, nil
+}
+
+// configFileName returns the file name for the given path, which may be an http url.
+func configFileName(p string) string {
+	if u, err := url.Parse(p); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return path.Base(u.Path)
+	}
+	return filepath.Base(p)
+}
+
+// verifySHA256Manifest verifies that data has sha256 checksum from manifest for the given fileName.
+//
+// The manifest must be in the format generated by sha256sum command.
+// The manifest may contain a single checksum without file name if allowUnnamedChecksum is set.
+func verifySHA256Manifest(data, manifest []byte, fileName string, allowUnnamedChecksum bool) error {
+	var lines []string
+	for _, line := range strings.Split(string(manifest), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	expectedChecksum := ""
+	for _, line := range lines {
+		checksum, name, ok := strings.Cut(line, " ")
+		if !ok {
+			if len(lines) > 1 || !allowUnnamedChecksum {
+				return fmt.Errorf("missing file name in manifest line %q", line)
+			}
+			expectedChecksum = checksum
+			break
+		}
+		// sha256sum marks files read in binary mode with `*` prefix.
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if path.Base(name) == fileName {
+			expectedChecksum = checksum
+			break
+		}
+	}
+	if expectedChecksum == "" {
+		return fmt.Errorf("missing checksum for %q", fileName)
+	}
+	checksum := sha256.Sum256(data)
+	if s := hex.EncodeToString(checksum[:]); !strings.EqualFold(s, expectedChecksum) {
+		return fmt.Errorf("unexpected sha256 checksum; got %s; want %s", s, expectedChecksum)
+	}
+	return nil
+}
+
+// verifyMinisign verifies data with minisign signature sig and the given public key.
+//
+// See https://jedisct1.github.io/minisign/ for details on minisign signature format.
+func verifyMinisign(data, sig []byte, publicKey string) error {
+	pk, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return fmt.Errorf("cannot decode public key: %w", err)
+	}
+	if len(pk) != 2+8+ed25519.PublicKeySize || string(pk[:2]) != "Ed" {
+		return fmt.Errorf("unsupported public key format; it must be minisign ed25519 public key")
+	}
+	keyID := pk[2:10]
+	pk = pk[10:]
+
+	// The signature file contains untrusted comment, signature, trusted comment and global signature lines.
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) != 4 {
+		return fmt.Errorf("unexpected number of lines in signature; got %d; want 4", len(lines))
+	}
+	s, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return fmt.Errorf("cannot decode signature: %w", err)
+	}
+	if len(s) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("unexpected signature length; got %d bytes; want %d bytes", len(s), 2+8+ed25519.SignatureSize)
+	}
+	if !bytes.Equal(s[2:10], keyID) {
+		return fmt.Errorf("signature key id %X doesn't match public key id %X", s[2:10], keyID)
+	}
+	switch string(s[:2]) {
+	case "Ed":
+		// legacy signature over the data
+	case "ED":
+		// signature over blake2b hash of the data
+		h := blake2b.Sum512(data)
+		data = h[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", s[:2])
+	}
+	if !ed25519.Verify(pk, data, s[10:]) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	trustedComment, ok := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("missing trusted comment")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return fmt.Errorf("cannot decode global signature: %w", err)
+	}
+	signedComment := append(append([]byte{}, s[10:]...), trustedComment...)
+	if !ed25519.Verify(pk, signedComment, globalSig) {
+		return fmt.Errorf("invalid global signature for trusted comment %q", trustedComment)
+	}
+	return nil
+}
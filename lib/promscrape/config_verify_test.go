@@ -0,0 +1,178 @@
+package promscrape
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestVerifySHA256Manifest(t *testing.T) {
+	data := []byte("scrape_configs: []\n")
+	checksum := sha256.Sum256(data)
+	hash := hex.EncodeToString(checksum[:])
+
+	f := func(manifest, fileName string, allowUnnamedChecksum, resultExpected bool) {
+		t.Helper()
+
+		err := verifySHA256Manifest(data, []byte(manifest), fileName, allowUnnamedChecksum)
+		if resultExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !resultExpected && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// single checksum without file name
+	f(hash, "prometheus.yml", true, true)
+	f(hash+"\n", "prometheus.yml", true, true)
+	f(strings.ToUpper(hash), "prometheus.yml", true, true)
+
+	// single checksum without file name isn't allowed
+	f(hash, "prometheus.yml", false, false)
+
+	// sha256sum output
+	f(hash+"  prometheus.yml\n", "prometheus.yml", true, true)
+	f(hash+" *prometheus.yml\n", "prometheus.yml", true, true)
+	f(strings.Repeat("0", 64)+"  other.yml\n"+hash+"  configs/prometheus.yml\n", "prometheus.yml", true, true)
+	f(hash+"  prometheus.yml\n", "prometheus.yml", false, true)
+
+	// missing checksum
+	f("", "prometheus.yml", true, false)
+	f(hash+"  other.yml\n", "prometheus.yml", true, false)
+	f(hash+"\n"+hash+"\n", "prometheus.yml", true, false)
+
+	// checksum mismatch
+	f(strings.Repeat("0", 64), "prometheus.yml", true, false)
+	f(strings.Repeat("0", 64)+"  prometheus.yml", "prometheus.yml", true, false)
+}
+
+func TestConfigFileName(t *testing.T) {
+	f := func(path, resultExpected string) {
+		t.Helper()
+
+		result := configFileName(path)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for configFileName(%q); got %q; want %q", path, result, resultExpected)
+		}
+	}
+
+	f("prometheus.yml", "prometheus.yml")
+	f("/etc/vmagent/prometheus.yml", "prometheus.yml")
+	f("https://config-server/vmagent/prometheus.yml?env=prod", "prometheus.yml")
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	pk, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("cannot generate key: %s", err)
+	}
+	_, skOther, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("cannot generate key: %s", err)
+	}
+	keyID := []byte("12345678")
+	publicKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pk...))
+
+	data := []byte("scrape_configs: []\n")
+	signMinisign := func(alg string, keyID []byte, sk ed25519.PrivateKey, data []byte, trustedComment string) []byte {
+		msg := data
+		if alg == "ED" {
+			h := blake2b.Sum512(data)
+			msg = h[:]
+		}
+		sig := ed25519.Sign(sk, msg)
+		globalSig := ed25519.Sign(sk, append(append([]byte{}, sig...), trustedComment...))
+		s := fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(append(append([]byte(alg), keyID...), sig...)),
+			trustedComment, base64.StdEncoding.EncodeToString(globalSig))
+		return []byte(s)
+	}
+
+	f := func(sig []byte, publicKey string, resultExpected bool) {
+		t.Helper()
+
+		err := verifyMinisign(data, sig, publicKey)
+		if resultExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !resultExpected && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// prehashed and legacy signatures
+	f(signMinisign("ED", keyID, sk, data, "timestamp:1700000000"), publicKey, true)
+	f(signMinisign("Ed", keyID, sk, data, "timestamp:1700000000"), publicKey, true)
+
+	// signature for other data
+	f(signMinisign("ED", keyID, sk, []byte("foo"), "timestamp:1700000000"), publicKey, false)
+
+	// signature with other key
+	f(signMinisign("ED", keyID, skOther, data, "timestamp:1700000000"), publicKey, false)
+	f(signMinisign("ED", []byte("87654321"), sk, data, "timestamp:1700000000"), publicKey, false)
+
+	// unsupported algorithm
+	f(signMinisign("XX", keyID, sk, data, "timestamp:1700000000"), publicKey, false)
+
+	// tampered trusted comment
+	sig := signMinisign("ED", keyID, sk, data, "timestamp:1700000000")
+	f([]byte(strings.Replace(string(sig), "timestamp:1700000000", "timestamp:1800000000", 1)), publicKey, false)
+
+	// invalid signature format
+	f(nil, publicKey, false)
+	f([]byte("foobar"), publicKey, false)
+
+	// invalid public key
+	f(signMinisign("ED", keyID, sk, data, "timestamp:1700000000"), "foobar", false)
+	f(signMinisign("ED", keyID, sk, data, "timestamp:1700000000"), base64.StdEncoding.EncodeToString(pk), false)
+}
+
+func TestLoadConfig_VerifyScrapeConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("cannot write %q: %s", path, err)
+		}
+		return path
+	}
+	sha256Line := func(name, data string) string {
+		checksum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(checksum[:]) + "  " + name + "\n"
+	}
+
+	mainData := "scrape_config_files: [verified.yml, tampered.yml]\n"
+	verifiedData := "- job_name: verified\n  static_configs: [{targets: [foo]}]\n"
+	tamperedData := "- job_name: tampered\n  static_configs: [{targets: [bar]}]\n"
+	configPath := writeFile("prometheus.yml", mainData)
+	writeFile("verified.yml", verifiedData)
+	writeFile("tampered.yml", tamperedData)
+	manifest := sha256Line("prometheus.yml", mainData) + sha256Line("verified.yml", verifiedData) + sha256Line("tampered.yml", "- job_name: other\n")
+	manifestPath := writeFile("SHA256SUMS", manifest)
+
+	origManifest := *configSHA256Manifest
+	*configSHA256Manifest = manifestPath
+	defer func() {
+		*configSHA256Manifest = origManifest
+	}()
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	jobNames := cfg.getJobNames()
+	if !reflect.DeepEqual(jobNames, []string{"verified"}) {
+		t.Fatalf("unexpected job names; got %q; want %q", jobNames, []string{"verified"})
+	}
+}
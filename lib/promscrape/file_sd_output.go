@@ -0,0 +1,245 @@
+package promscrape
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/stringsutil"
+)
+
+var (
+	fileSDOutputDir = flag.String("promscrape.fileSDOutputDir", "", "Optional path to directory where the active scrape targets are written per each job "+
+		"in file_sd_configs format after the relabeling. The files can be used by other scrapers for mirroring the targets discovered by vmagent. "+
+		"See https://docs.victoriametrics.com/vmagent/#writing-discovered-targets-to-files")
+	fileSDOutputInterval = flag.Duration("promscrape.fileSDOutputInterval", 30*time.Second, "Interval for writing the active scrape targets to -promscrape.fileSDOutputDir")
+)
+
+// fileSDOutputFileSuffix is the suffix for files written to -promscrape.fileSDOutputDir
+const fileSDOutputFileSuffix = ".json"
+
+func runFileSDOutputWriter(stopCh <-chan struct{}) {
+	if *fileSDOutputDir == "" {
+		return
+	}
+	fsw := newFileSDOutputWriter(*fileSDOutputDir)
+	fsw.writeTargets(tsmGlobal)
+
+	ticker := time.NewTicker(*fileSDOutputInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			fsw.writeTargets(tsmGlobal)
+		}
+	}
+}
+
+// fileSDOutputWriter writes active scrape targets per job into file_sd_configs files.
+type fileSDOutputWriter struct {
+	dir string
+
+	// written contains the last contents written per each file name.
+	written map[string][]byte
+}
+
+func newFileSDOutputWriter(dir string) *fileSDOutputWriter {
+	return &fileSDOutputWriter{
+		dir:     dir,
+		written: make(map[string][]byte),
+	}
+}
+
+func (fsw *fileSDOutputWriter) writeTargets(tsm *targetStatusMap) {
+	if err := os.MkdirAll(fsw.dir, 0755); err != nil {
+		fileSDOutputErrors.Inc()
+		logger.Errorf("cannot create -promscrape.fileSDOutputDir=%q: %s", fsw.dir, err)
+		return
+	}
+
+	m := getFileSDTargetGroupsByJob(tsm)
+	fileNames := make(map[string]struct{}, len(m))
+	for jobName, data := range m {
+		fileName := getFileSDOutputFileName(jobName)
+		fileNames[fileName] = struct{}{}
+		if bytes.Equal(fsw.written[fileName], data) {
+			continue
+		}
+		path := filepath.Join(fsw.dir, fileName)
+		if err := writeFileAtomic(path, data); err != nil {
+			fileSDOutputErrors.Inc()
+			logger.Errorf("cannot write scrape targets for job %q: %s", jobName, err)
+			continue
+		}
+		fsw.written[fileName] = data
+		fileSDOutputWrites.Inc()
+	}
+
+	// Remove files for jobs, which no longer exist.
+	// Files left from the previous runs of vmagent are removed too, so the directory is listed instead of relying on fsw.written.
+	for fileName := range fsw.written {
+		if _, ok := fileNames[fileName]; !ok {
+			delete(fsw.written, fileName)
+		}
+	}
+	des, err := os.ReadDir(fsw.dir)
+	if err != nil {
+		fileSDOutputErrors.Inc()
+		logger.Errorf("cannot read -promscrape.fileSDOutputDir=%q: %s", fsw.dir, err)
+		return
+	}
+	for _, de := range des {
+		fileName := de.Name()
+		if de.IsDir() || !isFileSDOutputFileName(fileName) {
+			continue
+		}
+		if _, ok := fileNames[fileName]; ok {
+			continue
+		}
+		path := filepath.Join(fsw.dir, fileName)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fileSDOutputErrors.Inc()
+			logger.Errorf("cannot remove obsolete scrape targets file %q: %s", path, err)
+		}
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// getFileSDOutputFileName returns file name for the given jobName.
+//
+// Characters, which may be unsafe in file names, are replaced with '_'.
+// The hash of jobName is appended to the file name, so jobs with distinct names,
+// which are sanitized to the same string, do not overwrite files of each other.
+func getFileSDOutputFileName(jobName string) string {
+	b := []byte(jobName)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' {
+			continue
+		}
+		b[i] = '_'
+	}
+	if len(b) == 0 || b[0] == '.' {
+		b = append([]byte{'_'}, b...)
+	}
+	return fmt.Sprintf("%s_%016x%s", b, xxhash.Sum64String(jobName), fileSDOutputFileSuffix)
+}
+
+// isFileSDOutputFileName returns true if fileName has been generated by getFileSDOutputFileName.
+//
+// This allows removing obsolete files from -promscrape.fileSDOutputDir without touching files, which were put there by other means.
+func isFileSDOutputFileName(fileName string) bool {
+	name, ok := strings.CutSuffix(fileName, fileSDOutputFileSuffix)
+	if !ok || len(name) < 17 {
+		return false
+	}
+	name = name[len(name)-17:]
+	if name[0] != '_' {
+		return false
+	}
+	for _, c := range name[1:] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// getFileSDTargetGroupsByJob returns file_sd_configs-formatted JSON with active targets per each registered job.
+func getFileSDTargetGroupsByJob(tsm *targetStatusMap) map[string][]byte {
+	tsm.mu.Lock()
+	jobNames := append([]string{}, tsm.jobNames...)
+	tsm.mu.Unlock()
+
+	targetsByJob := make(map[string][]string)
+	for _, jobName := range jobNames {
+		targetsByJob[jobName] = nil
+	}
+	tss := tsm.getActiveTargetStatuses()
+	for _, ts := range tss {
+		jobName := ts.sw.Config.jobNameOriginal
+		s := marshalFileSDTargetGroup(ts.sw.Config.ScrapeURL, ts.sw.Config.Labels)
+		targetsByJob[jobName] = append(targetsByJob[jobName], s)
+	}
+
+	m := make(map[string][]byte, len(targetsByJob))
+	for jobName, tgs := range targetsByJob {
+		// Sort target groups, so the file contents stays stable across calls.
+		sort.Strings(tgs)
+		var bb bytes.Buffer
+		bb.WriteString("[")
+		for i, tg := range tgs {
+			if i > 0 {
+				bb.WriteString(",")
+			}
+			bb.WriteString("\n  ")
+			bb.WriteString(tg)
+		}
+		if len(tgs) > 0 {
+			bb.WriteString("\n")
+		}
+		bb.WriteString("]\n")
+		m[jobName] = bb.Bytes()
+	}
+	return m
+}
+
+// marshalFileSDTargetGroup returns file_sd_configs target group for the given scrapeURL and labels.
+//
+// The scheme, path and query args from scrapeURL are put into __scheme__, __metrics_path__ and __param_* labels,
+// so the scraper, which reads the target group, could scrape exactly the same url.
+func marshalFileSDTargetGroup(scrapeURL string, labels *promutil.Labels) string {
+	target := scrapeURL
+	lbls := promutil.NewLabels(labels.Len() + 2)
+	if u, err := url.Parse(scrapeURL); err == nil && u.Host != "" {
+		target = u.Host
+		lbls.Add("__scheme__", u.Scheme)
+		lbls.Add("__metrics_path__", u.EscapedPath())
+		for k, vs := range u.Query() {
+			if len(vs) > 0 {
+				lbls.Add("__param_"+k, vs[0])
+			}
+		}
+	}
+	lbls.AddFrom(labels)
+	lbls.Sort()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `{"targets":[%s],"labels":{`, stringsutil.JSONString(target))
+	for i, label := range lbls.GetLabels() {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "%s:%s", stringsutil.JSONString(label.Name), stringsutil.JSONString(label.Value))
+	}
+	sb.WriteString("}}")
+	return sb.String()
+}
+
+var (
+	fileSDOutputWrites = metrics.NewCounter(`vm_promscrape_file_sd_output_writes_total`)
+	fileSDOutputErrors = metrics.NewCounter(`vm_promscrape_file_sd_output_errors_total`)
+)
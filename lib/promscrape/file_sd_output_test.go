@@ -0,0 +1,138 @@
+package promscrape
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+)
+
+func TestGetFileSDOutputFileName(t *testing.T) {
+	f := func(jobName, resultExpected string) {
+		t.Helper()
+
+		result := getFileSDOutputFileName(jobName)
+		if result != resultExpected {
+			t.Fatalf("unexpected file name; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f("", "__ef46db3751d8e999.json")
+	f("node-exporter", "node-exporter_ca2ce674c3fd5bed.json")
+	f("..", "_.._b34d11ac0cb06a6f.json")
+	f("job.v1_2", "job.v1_2_a0dfd9fadc3c9f03.json")
+
+	// job names, which are sanitized to the same string, must result in distinct file names
+	f("foo/bar baz", "foo_bar_baz_7beb7b1d583868be.json")
+	f("foo bar/baz", "foo_bar_baz_86a519ab8f4f3916.json")
+}
+
+func TestIsFileSDOutputFileName(t *testing.T) {
+	f := func(fileName string, resultExpected bool) {
+		t.Helper()
+
+		result := isFileSDOutputFileName(fileName)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", fileName, result, resultExpected)
+		}
+	}
+
+	f(getFileSDOutputFileName(""), true)
+	f(getFileSDOutputFileName("node-exporter"), true)
+	f("node-exporter_ca2ce674c3fd5bed.json", true)
+
+	f("", false)
+	f("node-exporter.json", false)
+	f("node-exporter_ca2ce674c3fd5bed.json.tmp", false)
+	f("node-exporter_ca2ce674c3fd5beX.json", false)
+	f("node-exporter-ca2ce674c3fd5bed.json", false)
+}
+
+func TestMarshalFileSDTargetGroup(t *testing.T) {
+	f := func(scrapeURL string, labels map[string]string, resultExpected string) {
+		t.Helper()
+
+		result := marshalFileSDTargetGroup(scrapeURL, promutil.NewLabelsFromMap(labels))
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f("http://foo:1234/metrics", map[string]string{
+		"job":      "bar",
+		"instance": "foo:1234",
+	}, `{"targets":["foo:1234"],"labels":{"__metrics_path__":"/metrics","__scheme__":"http","instance":"foo:1234","job":"bar"}}`)
+
+	f("https://foo/federate?match[]=up", map[string]string{
+		"job": "federate",
+	}, `{"targets":["foo"],"labels":{"__metrics_path__":"/federate","__param_match[]":"up","__scheme__":"https","job":"federate"}}`)
+}
+
+func TestFileSDOutputWriter(t *testing.T) {
+	dir := t.TempDir()
+
+	tsm := newTargetStatusMap()
+	tsm.registerJobNames("", []string{"foo", "bar"})
+	sw := &scrapeWork{
+		Config: &ScrapeWork{
+			ScrapeURL:       "http://host1:9100/metrics",
+			Labels:          promutil.NewLabelsFromMap(map[string]string{"job": "foo", "instance": "host1:9100"}),
+			OriginalLabels:  promutil.NewLabelsFromMap(map[string]string{"__address__": "host1:9100"}),
+			jobNameOriginal: "foo",
+		},
+	}
+	tsm.Register(sw)
+
+	fsw := newFileSDOutputWriter(dir)
+	fsw.writeTargets(tsm)
+
+	checkFile := func(fileName, dataExpected string) {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, fileName))
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", fileName, err)
+		}
+		if string(data) != dataExpected {
+			t.Fatalf("unexpected contents for %q\ngot\n%s\nwant\n%s", fileName, data, dataExpected)
+		}
+	}
+	fooFileName := getFileSDOutputFileName("foo")
+	barFileName := getFileSDOutputFileName("bar")
+	checkFile(fooFileName, `[
+  {"targets":["host1:9100"],"labels":{"__metrics_path__":"/metrics","__scheme__":"http","instance":"host1:9100","job":"foo"}}
+]
+`)
+	checkFile(barFileName, "[]\n")
+
+	checkRemoved := func(fileName string) {
+		t.Helper()
+		if _, err := os.Stat(filepath.Join(dir, fileName)); !os.IsNotExist(err) {
+			t.Fatalf("expecting %q to be removed; got err=%v", fileName, err)
+		}
+	}
+
+	// Remove the job and verify the file is removed
+	tsm.Unregister(sw)
+	tsm.registerJobNames("", []string{"foo"})
+	fsw.writeTargets(tsm)
+
+	checkFile(fooFileName, "[]\n")
+	checkRemoved(barFileName)
+
+	// Verify that files for removed jobs left from the previous run are removed,
+	// while files, which weren't written by the writer, are left untouched.
+	staleFileName := getFileSDOutputFileName("stale")
+	if err := os.WriteFile(filepath.Join(dir, staleFileName), []byte("[]\n"), 0644); err != nil {
+		t.Fatalf("cannot write %q: %s", staleFileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte("[]\n"), 0644); err != nil {
+		t.Fatalf("cannot write other.json: %s", err)
+	}
+	fsw = newFileSDOutputWriter(dir)
+	fsw.writeTargets(tsm)
+
+	checkFile(fooFileName, "[]\n")
+	checkFile("other.json", "[]\n")
+	checkRemoved(staleFileName)
+}
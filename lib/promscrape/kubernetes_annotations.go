@@ -0,0 +1,214 @@
+package promscrape
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
+)
+
+// KubernetesAnnotationsConfig represents `kubernetes_annotations` section of `scrape_config`.
+//
+// See https://docs.victoriametrics.com/sd_configs/#scraping-targets-via-kubernetes-annotations
+type KubernetesAnnotationsConfig struct {
+	// Prefix is the prefix for annotation names. It is set to `prometheus.io` by default.
+	Prefix string `yaml:"prefix,omitempty"`
+
+	// MetricRelabelSnippets contains named lists of metric relabeling rules,
+	// which can be referred by Kubernetes objects via `<prefix>/metric-relabel` annotation.
+	MetricRelabelSnippets map[string][]promrelabel.RelabelConfig `yaml:"metric_relabel_snippets,omitempty"`
+}
+
+const defaultKubernetesAnnotationsPrefix = "prometheus.io"
+
+// kubernetesAnnotationRoles contains Kubernetes object types, which annotations are checked by kubernetesAnnotations.
+//
+// The first object type with the `<prefix>/scrape` annotation is used.
+// This allows overriding Service annotations with Pod annotations for role: endpoints and role: endpointslice.
+var kubernetesAnnotationRoles = []string{"pod", "service", "ingress", "node"}
+
+// kubernetesAnnotations configures scrape targets from Kubernetes annotations.
+type kubernetesAnnotations struct {
+	// prefix is the sanitized annotation prefix.
+	prefix string
+
+	// metricRelabelConfigs contains `metric_relabel_configs` for the job.
+	//
+	// Snippets referred via `<prefix>/metric-relabel` annotation are appended to them.
+	metricRelabelConfigs []promrelabel.RelabelConfig
+
+	// snippets contains metric relabel snippets from KubernetesAnnotationsConfig.MetricRelabelSnippets.
+	snippets map[string][]promrelabel.RelabelConfig
+
+	// pcsCache contains parsed metric relabel configs keyed by `<prefix>/metric-relabel` annotation values.
+	pcsCacheLock sync.Mutex
+	pcsCache     map[string]*promrelabel.ParsedConfigs
+}
+
+func newKubernetesAnnotations(kac *KubernetesAnnotationsConfig, metricRelabelConfigs []promrelabel.RelabelConfig) (*kubernetesAnnotations, error) {
+	prefix := kac.Prefix
+	if prefix == "" {
+		prefix = defaultKubernetesAnnotationsPrefix
+	}
+	for name, rcs := range kac.MetricRelabelSnippets {
+		if name == "" {
+			return nil, fmt.Errorf("metric relabel snippet name cannot be empty")
+		}
+		if strings.Contains(name, ",") {
+			return nil, fmt.Errorf("metric relabel snippet name cannot contain commas; got %q", name)
+		}
+		if _, err := promrelabel.ParseRelabelConfigs(rcs); err != nil {
+			return nil, fmt.Errorf("cannot parse metric relabel snippet %q: %w", name, err)
+		}
+	}
+	return &kubernetesAnnotations{
+		prefix:               discoveryutil.SanitizeLabelName(prefix),
+		metricRelabelConfigs: metricRelabelConfigs,
+		snippets:             kac.MetricRelabelSnippets,
+		pcsCache:             make(map[string]*promrelabel.ParsedConfigs),
+	}, nil
+}
+
+// apply updates labels according to Kubernetes annotations found in labels.
+//
+// It returns false if the target isn't annotated for scraping.
+// It returns metric relabel configs for the target if `<prefix>/metric-relabel` annotation is set. Otherwise nil is returned.
+func (ka *kubernetesAnnotations) apply(labels *promutil.Labels) (bool, *promrelabel.ParsedConfigs, error) {
+	getAnnotation := ka.getAnnotationGetter(labels)
+	if getAnnotation == nil {
+		return false, nil, nil
+	}
+
+	scrape, err := strconv.ParseBool(getAnnotation("scrape"))
+	if err != nil {
+		return false, nil, fmt.Errorf("cannot parse `scrape` annotation: %w", err)
+	}
+	if !scrape {
+		return false, nil, nil
+	}
+
+	if s := getAnnotation("scheme"); s != "" {
+		scheme := strings.ToLower(s)
+		if scheme != "http" && scheme != "https" {
+			return false, nil, fmt.Errorf("unexpected `scheme` annotation: %q; supported values: http or https", s)
+		}
+		labels.Set("__scheme__", scheme)
+	}
+	if s := getAnnotation("port"); s != "" {
+		port, err := strconv.ParseUint(s, 10, 16)
+		if err != nil || port == 0 {
+			return false, nil, fmt.Errorf("cannot parse `port` annotation: %q; it must be an integer in the range [1..65535]", s)
+		}
+		host := labels.Get("__address__")
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		labels.Set("__address__", discoveryutil.JoinHostPort(host, int(port)))
+	}
+	if s := getAnnotation("path"); s != "" {
+		if !strings.HasPrefix(s, "/") {
+			return false, nil, fmt.Errorf("unexpected `path` annotation: %q; it must start with /", s)
+		}
+		labels.Set("__metrics_path__", s)
+	}
+	if s := getAnnotation("params"); s != "" {
+		params, err := url.ParseQuery(s)
+		if err != nil {
+			return false, nil, fmt.Errorf("cannot parse `params` annotation: %w", err)
+		}
+		for k, vs := range params {
+			labels.Set("__param_"+k, vs[0])
+		}
+	}
+	if s := getAnnotation("interval"); s != "" {
+		if _, err := timeutil.ParseDuration(s); err != nil {
+			return false, nil, fmt.Errorf("cannot parse `interval` annotation: %w", err)
+		}
+		labels.Set("__scrape_interval__", s)
+	}
+	if s := getAnnotation("timeout"); s != "" {
+		if _, err := timeutil.ParseDuration(s); err != nil {
+			return false, nil, fmt.Errorf("cannot parse `timeout` annotation: %w", err)
+		}
+		labels.Set("__scrape_timeout__", s)
+	}
+	if s := getAnnotation("sample_limit"); s != "" {
+		if n, err := strconv.Atoi(s); err != nil || n < 0 {
+			return false, nil, fmt.Errorf("cannot parse `sample-limit` annotation: %q; it must be non-negative integer", s)
+		}
+		labels.Set("__sample_limit__", s)
+	}
+	if s := getAnnotation("series_limit"); s != "" {
+		if n, err := strconv.Atoi(s); err != nil || n < 0 {
+			return false, nil, fmt.Errorf("cannot parse `series-limit` annotation: %q; it must be non-negative integer", s)
+		}
+		labels.Set("__series_limit__", s)
+	}
+
+	var pcs *promrelabel.ParsedConfigs
+	if s := getAnnotation("metric_relabel"); s != "" {
+		pcs, err = ka.getMetricRelabelConfigs(s)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+	return true, pcs, nil
+}
+
+// getAnnotationGetter returns a function for obtaining annotation values by sanitized names without prefix.
+//
+// nil is returned if labels have no `<prefix>/scrape` annotation.
+func (ka *kubernetesAnnotations) getAnnotationGetter(labels *promutil.Labels) func(name string) string {
+	for _, role := range kubernetesAnnotationRoles {
+		labelPrefix := "__meta_kubernetes_" + role + "_annotation_" + ka.prefix + "_"
+		if labels.Get(labelPrefix+"scrape") == "" {
+			continue
+		}
+		return func(name string) string {
+			return labels.Get(labelPrefix + name)
+		}
+	}
+	return nil
+}
+
+func (ka *kubernetesAnnotations) getMetricRelabelConfigs(s string) (*promrelabel.ParsedConfigs, error) {
+	ka.pcsCacheLock.Lock()
+	defer ka.pcsCacheLock.Unlock()
+
+	if pcs, ok := ka.pcsCache[s]; ok {
+		return pcs, nil
+	}
+
+	rcs := append([]promrelabel.RelabelConfig{}, ka.metricRelabelConfigs...)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		snippet, ok := ka.snippets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric relabel snippet %q in `metric-relabel` annotation; supported snippets: %s", name, ka.getSnippetNames())
+		}
+		rcs = append(rcs, snippet...)
+	}
+	pcs, err := promrelabel.ParseRelabelConfigs(rcs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse metric relabel configs for `metric-relabel` annotation %q: %w", s, err)
+	}
+	ka.pcsCache[s] = pcs
+	return pcs, nil
+}
+
+func (ka *kubernetesAnnotations) getSnippetNames() string {
+	names := make([]string, 0, len(ka.snippets))
+	for name := range ka.snippets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, ",") + "]"
+}
@@ -0,0 +1,203 @@
+package promscrape
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+)
+
+func newTestKubernetesAnnotations(t *testing.T) *kubernetesAnnotations {
+	t.Helper()
+
+	kac := &KubernetesAnnotationsConfig{
+		MetricRelabelSnippets: map[string][]promrelabel.RelabelConfig{
+			"drop_go": {{
+				Action:       "drop",
+				SourceLabels: []string{"__name__"},
+				Regex: &promrelabel.MultiLineRegex{
+					S: "go_.*",
+				},
+			}},
+			"add_foo": {{
+				TargetLabel: "foo",
+				Replacement: ptrString("bar"),
+			}},
+		},
+	}
+	ka, err := newKubernetesAnnotations(kac, nil)
+	if err != nil {
+		t.Fatalf("cannot create kubernetesAnnotations: %s", err)
+	}
+	return ka
+}
+
+func ptrString(s string) *string {
+	return &s
+}
+
+func TestKubernetesAnnotationsApplySuccess(t *testing.T) {
+	f := func(labels map[string]string, okExpected bool, labelsExpected map[string]string, metricRelabelConfigsExpected string) {
+		t.Helper()
+
+		ka := newTestKubernetesAnnotations(t)
+		ls := promutil.NewLabelsFromMap(labels)
+		ok, pcs, err := ka.apply(ls)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ok != okExpected {
+			t.Fatalf("unexpected ok; got %v; want %v", ok, okExpected)
+		}
+		if !ok {
+			return
+		}
+		ls.Sort()
+		lsExpected := promutil.NewLabelsFromMap(labelsExpected)
+		lsExpected.Sort()
+		if s, sExpected := ls.String(), lsExpected.String(); s != sExpected {
+			t.Fatalf("unexpected labels\ngot\n%s\nwant\n%s", s, sExpected)
+		}
+		if s := pcs.String(); s != metricRelabelConfigsExpected {
+			t.Fatalf("unexpected metric relabel configs\ngot\n%s\nwant\n%s", s, metricRelabelConfigsExpected)
+		}
+	}
+
+	// missing scrape annotation
+	f(map[string]string{
+		"__address__": "10.0.0.1:8080",
+	}, false, nil, "")
+
+	// disabled scrape annotation
+	f(map[string]string{
+		"__address__": "10.0.0.1:8080",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape": "false",
+	}, false, nil, "")
+
+	// scrape annotation without other annotations
+	f(map[string]string{
+		"__address__": "10.0.0.1:8080",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape": "true",
+	}, true, map[string]string{
+		"__address__": "10.0.0.1:8080",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape": "true",
+	}, "")
+
+	// all the supported annotations
+	f(map[string]string{
+		"__address__":      "10.0.0.1:8080",
+		"__scheme__":       "http",
+		"__metrics_path__": "/metrics",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape":         "true",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scheme":         "HTTPS",
+		"__meta_kubernetes_pod_annotation_prometheus_io_port":           "9100",
+		"__meta_kubernetes_pod_annotation_prometheus_io_path":           "/federate",
+		"__meta_kubernetes_pod_annotation_prometheus_io_params":         "match[]=up&format=text",
+		"__meta_kubernetes_pod_annotation_prometheus_io_interval":       "15s",
+		"__meta_kubernetes_pod_annotation_prometheus_io_timeout":        "5s",
+		"__meta_kubernetes_pod_annotation_prometheus_io_sample_limit":   "1000",
+		"__meta_kubernetes_pod_annotation_prometheus_io_series_limit":   "500",
+		"__meta_kubernetes_pod_annotation_prometheus_io_metric_relabel": "drop_go, add_foo",
+	}, true, map[string]string{
+		"__address__":         "10.0.0.1:9100",
+		"__scheme__":          "https",
+		"__metrics_path__":    "/federate",
+		"__param_match[]":     "up",
+		"__param_format":      "text",
+		"__scrape_interval__": "15s",
+		"__scrape_timeout__":  "5s",
+		"__sample_limit__":    "1000",
+		"__series_limit__":    "500",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape":         "true",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scheme":         "HTTPS",
+		"__meta_kubernetes_pod_annotation_prometheus_io_port":           "9100",
+		"__meta_kubernetes_pod_annotation_prometheus_io_path":           "/federate",
+		"__meta_kubernetes_pod_annotation_prometheus_io_params":         "match[]=up&format=text",
+		"__meta_kubernetes_pod_annotation_prometheus_io_interval":       "15s",
+		"__meta_kubernetes_pod_annotation_prometheus_io_timeout":        "5s",
+		"__meta_kubernetes_pod_annotation_prometheus_io_sample_limit":   "1000",
+		"__meta_kubernetes_pod_annotation_prometheus_io_series_limit":   "500",
+		"__meta_kubernetes_pod_annotation_prometheus_io_metric_relabel": "drop_go, add_foo",
+	}, `- action: drop
+  source_labels: [__name__]
+  regex: go_.*
+- target_label: foo
+  replacement: bar
+`)
+
+	// port annotation for address without port
+	f(map[string]string{
+		"__address__": "foo.svc",
+		"__meta_kubernetes_service_annotation_prometheus_io_scrape": "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_port":   "8080",
+	}, true, map[string]string{
+		"__address__": "foo.svc:8080",
+		"__meta_kubernetes_service_annotation_prometheus_io_scrape": "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_port":   "8080",
+	}, "")
+
+	// pod annotations take precedence over service annotations
+	f(map[string]string{
+		"__address__": "10.0.0.1:8080",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape":     "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_scrape": "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_port":   "9100",
+	}, true, map[string]string{
+		"__address__": "10.0.0.1:8080",
+		"__meta_kubernetes_pod_annotation_prometheus_io_scrape":     "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_scrape": "true",
+		"__meta_kubernetes_service_annotation_prometheus_io_port":   "9100",
+	}, "")
+}
+
+func TestKubernetesAnnotationsApplyFailure(t *testing.T) {
+	f := func(labels map[string]string) {
+		t.Helper()
+
+		ka := newTestKubernetesAnnotations(t)
+		ls := promutil.NewLabelsFromMap(labels)
+		if _, _, err := ka.apply(ls); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	const prefix = "__meta_kubernetes_pod_annotation_prometheus_io_"
+	newLabels := func(name, value string) map[string]string {
+		return map[string]string{
+			"__address__":     "10.0.0.1:8080",
+			prefix + "scrape": "true",
+			prefix + name:     value,
+		}
+	}
+
+	// invalid scrape annotation
+	f(map[string]string{
+		"__address__":     "10.0.0.1:8080",
+		prefix + "scrape": "yes please",
+	})
+
+	// invalid scheme
+	f(newLabels("scheme", "ftp"))
+
+	// invalid port
+	f(newLabels("port", "foo"))
+	f(newLabels("port", "0"))
+	f(newLabels("port", "65536"))
+
+	// invalid path
+	f(newLabels("path", "metrics"))
+
+	// invalid params
+	f(newLabels("params", "foo=%zz"))
+
+	// invalid interval and timeout
+	f(newLabels("interval", "foo"))
+	f(newLabels("timeout", "1x"))
+
+	// invalid limits
+	f(newLabels("sample_limit", "-1"))
+	f(newLabels("series_limit", "foo"))
+
+	// unknown metric relabel snippet
+	f(newLabels("metric_relabel", "drop_go,unknown"))
+}
@@ -0,0 +1,135 @@
+package promscrape
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// NamespaceScraper scrapes targets from the scrape config file owned by a single vmagent namespace.
+//
+// It works independently of the scraper for -promscrape.config.
+//
+// See https://docs.victoriametrics.com/vmagent/#namespaces
+type NamespaceScraper struct {
+	namespace  string
+	configFile string
+	pushData   func(at *auth.Token, wr *prompbmarshal.WriteRequest)
+
+	ms           *metrics.Set
+	reloads      *metrics.Counter
+	reloadErrors *metrics.Counter
+	success      *metrics.Gauge
+	timestamp    *metrics.Counter
+
+	reloadCh chan struct{}
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// CheckNamespaceConfig checks the scrape config file for the given vmagent namespace for errors and unsupported options.
+func CheckNamespaceConfig(namespace, configFile string) error {
+	_, err := loadNamespaceConfig(namespace, configFile)
+	return err
+}
+
+// StartNamespaceScraper starts scraping targets from configFile for the given vmagent namespace.
+//
+// Scraped data is passed to pushData. Job names from configFile are exposed as `namespace/job_name` scrape pools at /targets page.
+//
+// MustStop must be called when the returned scraper is no longer needed.
+func StartNamespaceScraper(namespace, configFile string, pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)) (*NamespaceScraper, error) {
+	logger.Infof("reading scrape configs for namespace %q from %q", namespace, configFile)
+	cfg, err := loadNamespaceConfig(namespace, configFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", configFile, err)
+	}
+
+	ms := metrics.NewSet()
+	ns := &NamespaceScraper{
+		namespace:  namespace,
+		configFile: configFile,
+		pushData:   pushData,
+
+		ms:           ms,
+		reloads:      ms.NewCounter(fmt.Sprintf(`vm_promscrape_config_reloads_total{namespace=%q}`, namespace)),
+		reloadErrors: ms.NewCounter(fmt.Sprintf(`vm_promscrape_config_reloads_errors_total{namespace=%q}`, namespace)),
+		success:      ms.NewGauge(fmt.Sprintf(`vm_promscrape_config_last_reload_successful{namespace=%q}`, namespace), nil),
+		timestamp:    ms.NewCounter(fmt.Sprintf(`vm_promscrape_config_last_reload_success_timestamp_seconds{namespace=%q}`, namespace)),
+
+		reloadCh: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+	metrics.RegisterSet(ms)
+
+	cfg.mustStart()
+	ns.success.Set(1)
+	ns.timestamp.Set(fasttime.UnixTimestamp())
+
+	ns.wg.Add(1)
+	go func() {
+		defer ns.wg.Done()
+		ns.run(cfg)
+	}()
+	return ns, nil
+}
+
+// ConfigFile returns the path to the scrape config file for ns.
+func (ns *NamespaceScraper) ConfigFile() string {
+	return ns.configFile
+}
+
+// Reload schedules re-reading of the scrape config file for ns.
+//
+// Scrape jobs are restarted only if they were changed in the config file.
+func (ns *NamespaceScraper) Reload() {
+	select {
+	case ns.reloadCh <- struct{}{}:
+	default:
+		// Reload is already scheduled.
+	}
+}
+
+// MustStop stops ns and unregisters its metrics.
+func (ns *NamespaceScraper) MustStop() {
+	close(ns.stopCh)
+	ns.wg.Wait()
+	metrics.UnregisterSet(ns.ms, true)
+}
+
+func (ns *NamespaceScraper) run(cfg *Config) {
+	scs := newScrapeConfigs(ns.namespace, ns.pushData, ns.stopCh)
+	scs.addDiscoveryTypes()
+	for {
+		scs.updateConfig(cfg)
+	waitForChans:
+		select {
+		case <-ns.reloadCh:
+			cfgNew, err := loadNamespaceConfig(ns.namespace, ns.configFile)
+			if err != nil {
+				ns.reloadErrors.Inc()
+				ns.success.Set(0)
+				logger.Errorf("cannot read %q for namespace %q: %s; continuing with the previous config", ns.configFile, ns.namespace, err)
+				goto waitForChans
+			}
+			ns.success.Set(1)
+			if !cfgNew.mustRestart(cfg) {
+				goto waitForChans
+			}
+			cfg = cfgNew
+			ns.reloads.Inc()
+			ns.timestamp.Set(fasttime.UnixTimestamp())
+		case <-ns.stopCh:
+			cfg.mustStop()
+			scs.stop()
+			logger.Infof("stopped scrapers for namespace %q", ns.namespace)
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package promscrape
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+var (
+	scrapeErrorsLogFormat = flag.String("promscrape.scrapeErrorsLogFormat", "text", "Format for scrape error log lines. Supported values: text, json. "+
+		"The json format contains job, instance, error_class and duration_seconds fields, so scrape errors can be aggregated by log pipelines without regex parsing. "+
+		"See https://docs.victoriametrics.com/vmagent/#scrape-errors-logging")
+	scrapeErrorsLogSampling = flag.Int("promscrape.scrapeErrorsLogSampling", 1, "Log only every N-th scrape error per each target, which isn't suppressed by -promscrape.suppressScrapeErrorsDelay. "+
+		"The logged line contains the number of failed scrapes since the previous logged line for the target. "+
+		"See https://docs.victoriametrics.com/vmagent/#scrape-errors-logging")
+)
+
+// scrapeError is an error with the class, which is used in structured scrape error logs.
+type scrapeError struct {
+	class string
+	err   error
+}
+
+func newScrapeError(class string, err error) error {
+	return &scrapeError{
+		class: class,
+		err:   err,
+	}
+}
+
+// Error implements error interface.
+func (se *scrapeError) Error() string {
+	return se.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (se *scrapeError) Unwrap() error {
+	return se.err
+}
+
+// getScrapeErrorClass returns the class for the given scrape error.
+func getScrapeErrorClass(err error) string {
+	var se *scrapeError
+	if errors.As(err, &se) {
+		return se.class
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns"
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "connection_refused"
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return "connection_reset"
+	}
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordHeaderErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &recordHeaderErr) {
+		return "tls"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
+
+// scrapeErrorLogEntry is a structured scrape error log line.
+type scrapeErrorLogEntry struct {
+	Job             string  `json:"job"`
+	Instance        string  `json:"instance"`
+	ScrapeURL       string  `json:"scrape_url"`
+	ErrorClass      string  `json:"error_class"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Failures        int     `json:"failures"`
+	Scrapes         int     `json:"scrapes"`
+	Error           string  `json:"error"`
+}
+
+// formatScrapeErrorJSON returns the scrape error log line for sw in JSON format.
+//
+// failures is the number of failed scrapes out of the given number of scrapes since the previous logged line.
+func (sw *scrapeWork) formatScrapeErrorJSON(err error, durationSeconds float64, failures, scrapes int) string {
+	cfg := sw.Config
+	e := &scrapeErrorLogEntry{
+		Job:             cfg.Job(),
+		Instance:        cfg.Labels.Get("instance"),
+		ScrapeURL:       cfg.ScrapeURL,
+		ErrorClass:      getScrapeErrorClass(err),
+		DurationSeconds: durationSeconds,
+		Failures:        failures,
+		Scrapes:         scrapes,
+		Error:           err.Error(),
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		// This shouldn't happen, since scrapeErrorLogEntry contains only strings and numbers.
+		return fmt.Sprintf(`{"error":"cannot marshal scrape error to JSON: %s"}`, err)
+	}
+	return string(data)
+}
+
+func validateScrapeErrorsLogFlags() error {
+	switch *scrapeErrorsLogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unsupported -promscrape.scrapeErrorsLogFormat=%q; supported values: text, json", *scrapeErrorsLogFormat)
+	}
+	if *scrapeErrorsLogSampling < 1 {
+		return fmt.Errorf("-promscrape.scrapeErrorsLogSampling must be positive; got %d", *scrapeErrorsLogSampling)
+	}
+	return nil
+}
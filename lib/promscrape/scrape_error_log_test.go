@@ -0,0 +1,48 @@
+package promscrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
+)
+
+func TestGetScrapeErrorClass(t *testing.T) {
+	f := func(err error, classExpected string) {
+		t.Helper()
+
+		class := getScrapeErrorClass(err)
+		if class != classExpected {
+			t.Fatalf("unexpected error class for %q; got %q; want %q", err, class, classExpected)
+		}
+	}
+
+	f(errors.New("foo"), "other")
+	f(fmt.Errorf("cannot perform request: %w", context.DeadlineExceeded), "timeout")
+	f(fmt.Errorf("cannot perform request: %w", &net.DNSError{Err: "no such host", Name: "foo", IsNotFound: true}), "dns")
+	f(fmt.Errorf("cannot perform request: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}), "connection_refused")
+	f(fmt.Errorf("cannot read data: %w", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), "connection_reset")
+	f(fmt.Errorf("cannot perform request: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network is unreachable")}), "network")
+	f(newScrapeError("http_status", errors.New("unexpected status code")), "http_status")
+	f(fmt.Errorf("cannot scrape: %w", newScrapeError("sample_limit", errors.New("sample_limit exceeded"))), "sample_limit")
+}
+
+func TestFormatScrapeErrorJSON(t *testing.T) {
+	sw := &scrapeWork{
+		Config: &ScrapeWork{
+			ScrapeURL: "http://host1:9100/metrics",
+			Labels:    promutil.NewLabelsFromMap(map[string]string{"job": "foo", "instance": "host1:9100"}),
+		},
+	}
+	err := newScrapeError("http_status", errors.New(`unexpected status code returned: 503; response body: "bar"`))
+	result := sw.formatScrapeErrorJSON(err, 1.5, 2, 5)
+	resultExpected := `{"job":"foo","instance":"host1:9100","scrape_url":"http://host1:9100/metrics","error_class":"http_status",` +
+		`"duration_seconds":1.5,"failures":2,"scrapes":5,"error":"unexpected status code returned: 503; response body: \"bar\""}`
+	if result != resultExpected {
+		t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
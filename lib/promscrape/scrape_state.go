@@ -0,0 +1,233 @@
+package promscrape
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var scrapeStateFile = flag.String("promscrape.stateFile", "", "Optional path to file for handing off scrape state between vmagent instances during restarts and rolling upgrades. "+
+	"The state for scrape targets is written to the file on graceful shutdown and is read from the file on startup, so the new vmagent instance "+
+	"continues scraping targets without gaps and duplicate scrapes, and sends staleness markers for metrics, which disappeared during the restart. "+
+	"See https://docs.victoriametrics.com/vmagent/#scrape-state-handoff")
+
+// scrapeStateFileVersion is the version of the file format at -promscrape.stateFile.
+//
+// It must be incremented on incompatible changes in the file format.
+const scrapeStateFileVersion = 1
+
+// scrapeStateFileData is the contents of -promscrape.stateFile.
+type scrapeStateFileData struct {
+	Version int            `json:"version"`
+	Targets []*scrapeState `json:"targets"`
+}
+
+// scrapeState is the state for a single scrape target, which is handed off between vmagent instances.
+type scrapeState struct {
+	// Key is the hash of ScrapeWork.key() for the target.
+	//
+	// The hash is used instead of the key itself in order to avoid leaking auth secrets to the file.
+	Key string `json:"key"`
+
+	// LastScrapeTimestamp is the timestamp in milliseconds for the last scrape of the target.
+	LastScrapeTimestamp int64 `json:"lastScrapeTimestamp"`
+
+	// Target health as shown at /targets page.
+	Up                 bool   `json:"up"`
+	ScrapeTime         int64  `json:"scrapeTime"`
+	ScrapeDuration     int64  `json:"scrapeDuration"`
+	ScrapeResponseSize int    `json:"scrapeResponseSize"`
+	SamplesScraped     int    `json:"samplesScraped"`
+	ScrapesTotal       int    `json:"scrapesTotal"`
+	ScrapesFailed      int    `json:"scrapesFailed"`
+	LastError          string `json:"lastError,omitempty"`
+
+	// LastScrapeCompressed is the last response from the target, which is used for sending staleness markers.
+	LastScrapeCompressed []byte `json:"lastScrapeCompressed,omitempty"`
+	LastScrapeLen        int    `json:"lastScrapeLen,omitempty"`
+}
+
+var (
+	restoredScrapeStatesLock sync.Mutex
+	restoredScrapeStates     map[string]*scrapeState
+
+	savedScrapeStatesLock sync.Mutex
+	savedScrapeStates     []*scrapeState
+)
+
+func getScrapeStateKey(sw *ScrapeWork) string {
+	h := xxhash.Sum64(bytesutil.ToUnsafeBytes(sw.key()))
+	return fmt.Sprintf("%016x", h)
+}
+
+// mustLoadScrapeState loads scrape state from -promscrape.stateFile.
+//
+// The file is removed after loading, so the state isn't applied again on the next restart,
+// which may occur much later.
+func mustLoadScrapeState() {
+	path := *scrapeStateFile
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Errorf("cannot read scrape state from -promscrape.stateFile=%q: %s; starting with empty scrape state", path, err)
+		}
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		logger.Errorf("cannot remove -promscrape.stateFile=%q after reading: %s", path, err)
+	}
+
+	sss, err := unmarshalScrapeState(data)
+	if err != nil {
+		logger.Errorf("cannot load scrape state from -promscrape.stateFile=%q: %s; starting with empty scrape state", path, err)
+		return
+	}
+	m := make(map[string]*scrapeState, len(sss))
+	for _, ss := range sss {
+		m[ss.Key] = ss
+	}
+	restoredScrapeStatesLock.Lock()
+	restoredScrapeStates = m
+	restoredScrapeStatesLock.Unlock()
+	logger.Infof("loaded scrape state for %d targets from -promscrape.stateFile=%q", len(sss), path)
+}
+
+func unmarshalScrapeState(data []byte) ([]*scrapeState, error) {
+	var fd scrapeStateFileData
+	if err := json.Unmarshal(data, &fd); err != nil {
+		return nil, fmt.Errorf("cannot parse scrape state: %w", err)
+	}
+	if fd.Version != scrapeStateFileVersion {
+		return nil, fmt.Errorf("unsupported scrape state version: %d; want %d", fd.Version, scrapeStateFileVersion)
+	}
+	return fd.Targets, nil
+}
+
+// mustSaveScrapeState saves the scrape state collected via saveScrapeState to -promscrape.stateFile.
+//
+// It must be called after all the scrapers are stopped.
+func mustSaveScrapeState() {
+	path := *scrapeStateFile
+	if path == "" {
+		return
+	}
+
+	savedScrapeStatesLock.Lock()
+	sss := savedScrapeStates
+	savedScrapeStates = nil
+	savedScrapeStatesLock.Unlock()
+
+	data := marshalScrapeState(sss)
+	fs.MustWriteAtomic(path, data, true)
+	logger.Infof("saved scrape state for %d targets to -promscrape.stateFile=%q", len(sss), path)
+}
+
+func marshalScrapeState(sss []*scrapeState) []byte {
+	fd := &scrapeStateFileData{
+		Version: scrapeStateFileVersion,
+		Targets: sss,
+	}
+	data, err := json.Marshal(fd)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal scrape state: %s", err)
+	}
+	return data
+}
+
+// takeRestoredScrapeState returns the restored scrape state for sw and removes it from the restored states.
+//
+// nil is returned if there is no restored scrape state for sw.
+func takeRestoredScrapeState(sw *ScrapeWork) *scrapeState {
+	restoredScrapeStatesLock.Lock()
+	defer restoredScrapeStatesLock.Unlock()
+
+	if len(restoredScrapeStates) == 0 {
+		return nil
+	}
+	key := getScrapeStateKey(sw)
+	ss := restoredScrapeStates[key]
+	delete(restoredScrapeStates, key)
+	return ss
+}
+
+// saveScrapeState saves the state for sw, so it could be written to -promscrape.stateFile on shutdown.
+func (sw *scrapeWork) saveScrapeState() {
+	if *scrapeStateFile == "" || sw.lastScrapeTimestamp <= 0 {
+		return
+	}
+	ss := &scrapeState{
+		Key:                  getScrapeStateKey(sw.Config),
+		LastScrapeTimestamp:  sw.lastScrapeTimestamp,
+		LastScrapeCompressed: sw.lastScrapeCompressed,
+		LastScrapeLen:        sw.lastScrapeLen,
+	}
+	if ts, ok := tsmGlobal.getStatus(sw); ok {
+		ss.Up = ts.up
+		ss.ScrapeTime = ts.scrapeTime
+		ss.ScrapeDuration = ts.scrapeDuration
+		ss.ScrapeResponseSize = ts.scrapeResponseSize
+		ss.SamplesScraped = ts.samplesScraped
+		ss.ScrapesTotal = ts.scrapesTotal
+		ss.ScrapesFailed = ts.scrapesFailed
+		if ts.err != nil {
+			ss.LastError = ts.err.Error()
+		}
+	}
+
+	savedScrapeStatesLock.Lock()
+	savedScrapeStates = append(savedScrapeStates, ss)
+	savedScrapeStatesLock.Unlock()
+}
+
+// restoreScrapeState restores sw state from ss.
+func (sw *scrapeWork) restoreScrapeState(ss *scrapeState) {
+	sw.lastScrapeTimestamp = ss.LastScrapeTimestamp
+	if !sw.Config.NoStaleMarkers {
+		sw.lastScrapeCompressed = ss.LastScrapeCompressed
+		sw.lastScrapeLen = ss.LastScrapeLen
+		sw.prevBodyLen = ss.LastScrapeLen
+	}
+	var err error
+	if ss.LastError != "" {
+		err = errors.New(ss.LastError)
+	}
+	tsmGlobal.restore(sw, &targetStatus{
+		up:                 ss.Up,
+		scrapeTime:         ss.ScrapeTime,
+		scrapeDuration:     ss.ScrapeDuration,
+		scrapeResponseSize: ss.ScrapeResponseSize,
+		samplesScraped:     ss.SamplesScraped,
+		scrapesTotal:       ss.ScrapesTotal,
+		scrapesFailed:      ss.ScrapesFailed,
+		err:                err,
+	})
+}
+
+// getRestoredScrapeDelay returns the delay before the next scrape for the target with the given lastScrapeTimestamp in milliseconds,
+// so the scrape interval is preserved across vmagent restarts.
+//
+// false is returned if the next scrape cannot be scheduled from lastScrapeTimestamp.
+func getRestoredScrapeDelay(lastScrapeTimestamp int64, scrapeInterval time.Duration, now int64) (time.Duration, bool) {
+	if lastScrapeTimestamp <= 0 || lastScrapeTimestamp > now {
+		return 0, false
+	}
+	nextScrapeTimestamp := lastScrapeTimestamp + scrapeInterval.Milliseconds()
+	if nextScrapeTimestamp <= now {
+		// The next scrape is already overdue. Scrape the target immediately in order to minimize the gap.
+		return 0, true
+	}
+	return time.Duration(nextScrapeTimestamp-now) * time.Millisecond, true
+}
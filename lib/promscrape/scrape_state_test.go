@@ -0,0 +1,86 @@
+package promscrape
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalUnmarshalScrapeState(t *testing.T) {
+	f := func(sss []*scrapeState) {
+		t.Helper()
+
+		data := marshalScrapeState(sss)
+		result, err := unmarshalScrapeState(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, sss) {
+			t.Fatalf("unexpected unmarshaled scrape state\ngot\n%#v\nwant\n%#v", result, sss)
+		}
+	}
+
+	f(nil)
+	f([]*scrapeState{
+		{
+			Key:                  "0123456789abcdef",
+			LastScrapeTimestamp:  1700000000000,
+			Up:                   true,
+			ScrapeTime:           1700000000010,
+			ScrapeDuration:       20,
+			ScrapeResponseSize:   1234,
+			SamplesScraped:       56,
+			ScrapesTotal:         10,
+			ScrapesFailed:        1,
+			LastScrapeCompressed: []byte("foo bar"),
+			LastScrapeLen:        123,
+		},
+		{
+			Key:                 "fedcba9876543210",
+			LastScrapeTimestamp: 1700000001000,
+			LastError:           "connection refused",
+		},
+	})
+}
+
+func TestUnmarshalScrapeStateFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		if _, err := unmarshalScrapeState([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("")
+	f("foobar")
+	f(`{"version":2,"targets":[]}`)
+	f(`{"targets":[]}`)
+}
+
+func TestGetRestoredScrapeDelay(t *testing.T) {
+	f := func(lastScrapeTimestamp int64, scrapeInterval time.Duration, now int64, delayExpected time.Duration, okExpected bool) {
+		t.Helper()
+
+		delay, ok := getRestoredScrapeDelay(lastScrapeTimestamp, scrapeInterval, now)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok; got %v; want %v", ok, okExpected)
+		}
+		if delay != delayExpected {
+			t.Fatalf("unexpected delay; got %s; want %s", delay, delayExpected)
+		}
+	}
+
+	// missing last scrape timestamp
+	f(0, 30*time.Second, 1700000000000, 0, false)
+
+	// last scrape timestamp in the future
+	f(1700000001000, 30*time.Second, 1700000000000, 0, false)
+
+	// the next scrape is in the future
+	f(1700000000000, 30*time.Second, 1700000010000, 20*time.Second, true)
+
+	// the next scrape is overdue
+	f(1700000000000, 30*time.Second, 1700000030000, 0, true)
+	f(1700000000000, 30*time.Second, 1700000100000, 0, true)
+}
@@ -66,19 +66,31 @@ func CheckConfig() error {
 //
 // Scraped data is passed to pushData.
 func Init(pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)) {
+	if err := validateScrapeErrorsLogFlags(); err != nil {
+		logger.Fatalf("%s", err)
+	}
 	mustInitClusterMemberID()
+	mustLoadScrapeState()
 	globalStopChan = make(chan struct{})
 	scraperWG.Add(1)
 	go func() {
 		defer scraperWG.Done()
 		runScraper(*promscrapeConfigFile, pushData, globalStopChan)
 	}()
+	if *promscrapeConfigFile != "" {
+		scraperWG.Add(1)
+		go func() {
+			defer scraperWG.Done()
+			runFileSDOutputWriter(globalStopChan)
+		}()
+	}
 }
 
 // Stop stops Prometheus scraper.
 func Stop() {
 	close(globalStopChan)
 	scraperWG.Wait()
+	mustSaveScrapeState()
 }
 
 var (
@@ -127,30 +139,8 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 	configSuccess.Set(1)
 	configTimestamp.Set(fasttime.UnixTimestamp())
 
-	scs := newScrapeConfigs(pushData, globalStopCh)
-	scs.add("azure_sd_configs", *azure.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getAzureSDScrapeWork(swsPrev) })
-	scs.add("consul_sd_configs", *consul.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getConsulSDScrapeWork(swsPrev) })
-	scs.add("consulagent_sd_configs", *consulagent.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getConsulAgentSDScrapeWork(swsPrev) })
-	scs.add("digitalocean_sd_configs", *digitalocean.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDigitalOceanDScrapeWork(swsPrev) })
-	scs.add("dns_sd_configs", *dns.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDNSSDScrapeWork(swsPrev) })
-	scs.add("docker_sd_configs", *docker.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDockerSDScrapeWork(swsPrev) })
-	scs.add("dockerswarm_sd_configs", *dockerswarm.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDockerSwarmSDScrapeWork(swsPrev) })
-	scs.add("ec2_sd_configs", *ec2.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getEC2SDScrapeWork(swsPrev) })
-	scs.add("eureka_sd_configs", *eureka.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getEurekaSDScrapeWork(swsPrev) })
-	scs.add("file_sd_configs", *fileSDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getFileSDScrapeWork(swsPrev) })
-	scs.add("gce_sd_configs", *gce.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getGCESDScrapeWork(swsPrev) })
-	scs.add("hetzner_sd_configs", *hetzner.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getHetznerSDScrapeWork(swsPrev) })
-	scs.add("http_sd_configs", *http.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getHTTPDScrapeWork(swsPrev) })
-	scs.add("kubernetes_sd_configs", *kubernetes.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKubernetesSDScrapeWork(swsPrev) })
-	scs.add("kuma_sd_configs", *kuma.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKumaSDScrapeWork(swsPrev) })
-	scs.add("marathon_sd_configs", *marathon.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getMarathonSDScrapeWork(swsPrev) })
-	scs.add("nomad_sd_configs", *nomad.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getNomadSDScrapeWork(swsPrev) })
-	scs.add("openstack_sd_configs", *openstack.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getOpenStackSDScrapeWork(swsPrev) })
-	scs.add("ovhcloud_sd_configs", *ovhcloud.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getOVHCloudSDScrapeWork(swsPrev) })
-	scs.add("puppetdb_sd_configs", *puppetdb.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getPuppetDBSDScrapeWork(swsPrev) })
-	scs.add("vultr_sd_configs", *vultr.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getVultrSDScrapeWork(swsPrev) })
-	scs.add("yandexcloud_sd_configs", *yandexcloud.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getYandexCloudSDScrapeWork(swsPrev) })
-	scs.add("static_configs", 0, func(cfg *Config, _ []*ScrapeWork) []*ScrapeWork { return cfg.getStaticScrapeWork() })
+	scs := newScrapeConfigs("", pushData, globalStopCh)
+	scs.addDiscoveryTypes()
 
 	var tickerCh <-chan time.Time
 	if *configCheckInterval > 0 {
@@ -218,6 +208,7 @@ var (
 )
 
 type scrapeConfigs struct {
+	namespace    string
 	pushData     func(at *auth.Token, wr *prompbmarshal.WriteRequest)
 	wg           sync.WaitGroup
 	stopCh       chan struct{}
@@ -225,17 +216,46 @@ type scrapeConfigs struct {
 	scfgs        []*scrapeConfig
 }
 
-func newScrapeConfigs(pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest), globalStopCh <-chan struct{}) *scrapeConfigs {
+func newScrapeConfigs(namespace string, pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest), globalStopCh <-chan struct{}) *scrapeConfigs {
 	return &scrapeConfigs{
+		namespace:    namespace,
 		pushData:     pushData,
 		stopCh:       make(chan struct{}),
 		globalStopCh: globalStopCh,
 	}
 }
 
+// addDiscoveryTypes adds scrape configs for all the supported service discovery types to scs.
+func (scs *scrapeConfigs) addDiscoveryTypes() {
+	scs.add("azure_sd_configs", *azure.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getAzureSDScrapeWork(swsPrev) })
+	scs.add("consul_sd_configs", *consul.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getConsulSDScrapeWork(swsPrev) })
+	scs.add("consulagent_sd_configs", *consulagent.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getConsulAgentSDScrapeWork(swsPrev) })
+	scs.add("digitalocean_sd_configs", *digitalocean.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDigitalOceanDScrapeWork(swsPrev) })
+	scs.add("dns_sd_configs", *dns.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDNSSDScrapeWork(swsPrev) })
+	scs.add("docker_sd_configs", *docker.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDockerSDScrapeWork(swsPrev) })
+	scs.add("dockerswarm_sd_configs", *dockerswarm.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getDockerSwarmSDScrapeWork(swsPrev) })
+	scs.add("ec2_sd_configs", *ec2.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getEC2SDScrapeWork(swsPrev) })
+	scs.add("eureka_sd_configs", *eureka.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getEurekaSDScrapeWork(swsPrev) })
+	scs.add("file_sd_configs", *fileSDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getFileSDScrapeWork(swsPrev) })
+	scs.add("gce_sd_configs", *gce.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getGCESDScrapeWork(swsPrev) })
+	scs.add("hetzner_sd_configs", *hetzner.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getHetznerSDScrapeWork(swsPrev) })
+	scs.add("http_sd_configs", *http.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getHTTPDScrapeWork(swsPrev) })
+	scs.add("kubernetes_sd_configs", *kubernetes.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKubernetesSDScrapeWork(swsPrev) })
+	scs.add("kuma_sd_configs", *kuma.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKumaSDScrapeWork(swsPrev) })
+	scs.add("marathon_sd_configs", *marathon.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getMarathonSDScrapeWork(swsPrev) })
+	scs.add("nomad_sd_configs", *nomad.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getNomadSDScrapeWork(swsPrev) })
+	scs.add("openstack_sd_configs", *openstack.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getOpenStackSDScrapeWork(swsPrev) })
+	scs.add("ovhcloud_sd_configs", *ovhcloud.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getOVHCloudSDScrapeWork(swsPrev) })
+	scs.add("puppetdb_sd_configs", *puppetdb.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getPuppetDBSDScrapeWork(swsPrev) })
+	scs.add("vultr_sd_configs", *vultr.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getVultrSDScrapeWork(swsPrev) })
+	scs.add("yandexcloud_sd_configs", *yandexcloud.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getYandexCloudSDScrapeWork(swsPrev) })
+	scs.add("static_configs", 0, func(cfg *Config, _ []*ScrapeWork) []*ScrapeWork { return cfg.getStaticScrapeWork() })
+}
+
 func (scs *scrapeConfigs) add(name string, checkInterval time.Duration, getScrapeWork func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork) {
 	PendingScrapeConfigs.Add(1)
 	scfg := &scrapeConfig{
+		namespace:     scs.namespace,
 		name:          name,
 		pushData:      scs.pushData,
 		getScrapeWork: getScrapeWork,
@@ -243,7 +263,7 @@ func (scs *scrapeConfigs) add(name string, checkInterval time.Duration, getScrap
 		cfgCh:         make(chan *Config, 1),
 		stopCh:        scs.stopCh,
 
-		discoveryDuration: metrics.GetOrCreateHistogram(fmt.Sprintf("vm_promscrape_service_discovery_duration_seconds{type=%q}", name)),
+		discoveryDuration: metrics.GetOrCreateHistogram(fmt.Sprintf("vm_promscrape_service_discovery_duration_seconds{%s}", getScraperGroupLabels(scs.namespace, name))),
 	}
 	scs.wg.Add(1)
 	go func() {
@@ -266,6 +286,7 @@ func (scs *scrapeConfigs) stop() {
 }
 
 type scrapeConfig struct {
+	namespace     string
 	name          string
 	pushData      func(at *auth.Token, wr *prompbmarshal.WriteRequest)
 	getScrapeWork func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork
@@ -277,7 +298,7 @@ type scrapeConfig struct {
 }
 
 func (scfg *scrapeConfig) run(globalStopCh <-chan struct{}) {
-	sg := newScraperGroup(scfg.name, scfg.pushData, globalStopCh)
+	sg := newScraperGroup(scfg.namespace, scfg.name, scfg.pushData, globalStopCh)
 	defer sg.stop()
 
 	var tickerCh <-chan time.Time
@@ -322,6 +343,9 @@ type scraperGroup struct {
 	m        map[string]*scraper
 	pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)
 
+	// ms contains metrics for the scraperGroup, so they could be unregistered when the group is stopped.
+	ms *metrics.Set
+
 	changesCount    *metrics.Counter
 	activeScrapers  *metrics.Counter
 	scrapersStarted *metrics.Counter
@@ -330,28 +354,44 @@ type scraperGroup struct {
 	globalStopCh <-chan struct{}
 }
 
-func newScraperGroup(name string, pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest), globalStopCh <-chan struct{}) *scraperGroup {
+func newScraperGroup(namespace, name string, pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest), globalStopCh <-chan struct{}) *scraperGroup {
+	ms := metrics.NewSet()
+	labels := getScraperGroupLabels(namespace, name)
+	if namespace != "" {
+		// Scraper groups from distinct namespaces must have distinct names, since targets are tracked by group names.
+		name = namespace + "/" + name
+	}
 	sg := &scraperGroup{
 		name:     name,
 		m:        make(map[string]*scraper),
 		pushData: pushData,
 
-		changesCount:    metrics.NewCounter(fmt.Sprintf(`vm_promscrape_config_changes_total{type=%q}`, name)),
-		activeScrapers:  metrics.NewCounter(fmt.Sprintf(`vm_promscrape_active_scrapers{type=%q}`, name)),
-		scrapersStarted: metrics.NewCounter(fmt.Sprintf(`vm_promscrape_scrapers_started_total{type=%q}`, name)),
-		scrapersStopped: metrics.NewCounter(fmt.Sprintf(`vm_promscrape_scrapers_stopped_total{type=%q}`, name)),
+		ms:              ms,
+		changesCount:    ms.NewCounter(fmt.Sprintf(`vm_promscrape_config_changes_total{%s}`, labels)),
+		activeScrapers:  ms.NewCounter(fmt.Sprintf(`vm_promscrape_active_scrapers{%s}`, labels)),
+		scrapersStarted: ms.NewCounter(fmt.Sprintf(`vm_promscrape_scrapers_started_total{%s}`, labels)),
+		scrapersStopped: ms.NewCounter(fmt.Sprintf(`vm_promscrape_scrapers_stopped_total{%s}`, labels)),
 
 		globalStopCh: globalStopCh,
 	}
-	metrics.NewGauge(fmt.Sprintf(`vm_promscrape_targets{type=%q, status="up"}`, name), func() float64 {
+	ms.NewGauge(fmt.Sprintf(`vm_promscrape_targets{%s, status="up"}`, labels), func() float64 {
 		return float64(tsmGlobal.StatusByGroup(sg.name, true))
 	})
-	metrics.NewGauge(fmt.Sprintf(`vm_promscrape_targets{type=%q, status="down"}`, name), func() float64 {
+	ms.NewGauge(fmt.Sprintf(`vm_promscrape_targets{%s, status="down"}`, labels), func() float64 {
 		return float64(tsmGlobal.StatusByGroup(sg.name, false))
 	})
+	metrics.RegisterSet(ms)
 	return sg
 }
 
+// getScraperGroupLabels returns labels for metrics of the scraper group with the given name in the given vmagent namespace.
+func getScraperGroupLabels(namespace, name string) string {
+	if namespace == "" {
+		return fmt.Sprintf("type=%q", name)
+	}
+	return fmt.Sprintf("type=%q, namespace=%q", name, namespace)
+}
+
 func (sg *scraperGroup) stop() {
 	sg.mLock.Lock()
 	for _, sc := range sg.m {
@@ -360,6 +400,7 @@ func (sg *scraperGroup) stop() {
 	sg.m = nil
 	sg.mLock.Unlock()
 	sg.wg.Wait()
+	metrics.UnregisterSet(sg.ms, true)
 }
 
 func (sg *scraperGroup) update(sws []*ScrapeWork) {
@@ -16,7 +16,7 @@ func TestScraperReload(t *testing.T) {
 		defer close(globalStopChan)
 
 		randName := rand.Int()
-		sg := newScraperGroup(fmt.Sprintf("static_configs_%d", randName), pushData, globalStopChan)
+		sg := newScraperGroup("", fmt.Sprintf("static_configs_%d", randName), pushData, globalStopChan)
 		defer sg.stop()
 
 		scrapeConfigPath := "test-scrape.yaml"
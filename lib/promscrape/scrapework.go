@@ -145,6 +145,21 @@ type ScrapeWork struct {
 	// Optional limit on the number of unique series the scrape target can expose.
 	SeriesLimit int
 
+	// Optional limit on the growth of the number of samples after relabeling compared to the previous scrape.
+	// For example, 5 means that the target may expose up to 5x more samples than at the previous scrape.
+	// See https://docs.victoriametrics.com/vmagent/#sample-growth-limiter
+	SampleGrowthLimit float64
+
+	// The action to perform when SampleGrowthLimit is exceeded: drop, cap or alert. Empty value means drop.
+	SampleGrowthAction string
+
+	// The number of samples, which can be added to the number of samples at the previous scrape when SampleGrowthAction is cap.
+	SampleGrowthMargin int
+
+	// The number of consecutive scrapes dropped because of SampleGrowthLimit, after which the number of samples
+	// at the last dropped scrape becomes the new baseline. Zero value means defaultSampleGrowthDrops.
+	SampleGrowthDrops int
+
 	// Whether to process stale markers for the given target.
 	// See https://docs.victoriametrics.com/vmagent/#prometheus-staleness-markers
 	NoStaleMarkers bool
@@ -157,9 +172,9 @@ type ScrapeWork struct {
 }
 
 func (sw *ScrapeWork) canSwitchToStreamParseMode() bool {
-	// Deny switching to stream parse mode if `sample_limit` or `series_limit` options are set,
+	// Deny switching to stream parse mode if `sample_limit`, `series_limit` or `sample_growth_limit` options are set,
 	// since these limits cannot be applied in stream parsing mode.
-	return sw.SampleLimit <= 0 && sw.SeriesLimit <= 0
+	return sw.SampleLimit <= 0 && sw.SeriesLimit <= 0 && sw.SampleGrowthLimit <= 0
 }
 
 // key returns unique identifier for the given sw.
@@ -173,12 +188,14 @@ func (sw *ScrapeWork) key() string {
 		"HonorTimestamps=%v, DenyRedirects=%v, Labels=%s, ExternalLabels=%s, MaxScrapeSize=%d, "+
 		"ProxyURL=%s, ProxyAuthConfig=%s, AuthConfig=%s, MetricRelabelConfigs=%q, "+
 		"SampleLimit=%d, DisableCompression=%v, DisableKeepAlive=%v, StreamParse=%v, "+
-		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, NoStaleMarkers=%v",
+		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, SampleGrowthLimit=%v, SampleGrowthAction=%s, SampleGrowthMargin=%d, "+
+		"SampleGrowthDrops=%d, NoStaleMarkers=%v",
 		sw.jobNameOriginal, sw.ScrapeURL, sw.ScrapeInterval, sw.ScrapeTimeout, sw.HonorLabels,
 		sw.HonorTimestamps, sw.DenyRedirects, sw.Labels.String(), sw.ExternalLabels.String(), sw.MaxScrapeSize,
 		sw.ProxyURL.String(), sw.ProxyAuthConfig.String(), sw.AuthConfig.String(), sw.MetricRelabelConfigs.String(),
 		sw.SampleLimit, sw.DisableCompression, sw.DisableKeepAlive, sw.StreamParse,
-		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.NoStaleMarkers)
+		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.SampleGrowthLimit, sw.SampleGrowthAction, sw.SampleGrowthMargin,
+		sw.SampleGrowthDrops, sw.NoStaleMarkers)
 	return key
 }
 
@@ -222,6 +239,14 @@ type scrapeWork struct {
 	// It is used as a hint in order to reduce memory usage when parsing scrape responses.
 	prevLabelsLen int
 
+	// prevSamplesPostRelabeling contains the number of samples accepted after relabeling during the previous successful scrape.
+	// It is used for applying sample_growth_limit.
+	prevSamplesPostRelabeling int
+
+	// sampleGrowthDrops contains the number of consecutive scrapes dropped because of sample_growth_limit.
+	// It is used for re-baselining prevSamplesPostRelabeling, so the target doesn't remain unhealthy forever.
+	sampleGrowthDrops int
+
 	// lastScrapeCompressed holds the last response from scrape target in the compressed form.
 	// It is used for staleness tracking and for populating scrape_series_added metric.
 	// The lastScrapeCompressed isn't populated if -promscrape.noStaleMarkers is set. This reduces memory usage.
@@ -239,6 +264,18 @@ type scrapeWork struct {
 
 	// successRequestsCount is the number of success requests during the last suppressScrapeErrorsDelay
 	successRequestsCount int
+
+	// errorLogsSkipped is the number of scrape error log lines skipped because of -promscrape.scrapeErrorsLogSampling
+	// since the last logged line.
+	errorLogsSkipped int
+
+	// lastScrapeDurationSeconds is the duration of the last scrape in seconds.
+	lastScrapeDurationSeconds float64
+
+	// lastScrapeTimestamp is the timestamp in milliseconds for the last scrape.
+	//
+	// It is used for handing off scrape state via -promscrape.stateFile.
+	lastScrapeTimestamp int64
 }
 
 // loadLastScrape appends last scrape response to dst and returns the result.
@@ -271,7 +308,16 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 	if scrapeOffset > 0 {
 		scrapeAlignInterval = scrapeInterval
 	}
-	if scrapeAlignInterval <= 0 {
+	restoredDelay, isRestored := time.Duration(0), false
+	if ss := takeRestoredScrapeState(sw.Config); ss != nil {
+		sw.restoreScrapeState(ss)
+		restoredDelay, isRestored = getRestoredScrapeDelay(ss.LastScrapeTimestamp, scrapeInterval, time.Now().UnixMilli())
+	}
+	if isRestored {
+		// Continue scraping the target at the same interval as the previous vmagent instance did.
+		// See https://docs.victoriametrics.com/vmagent/#scrape-state-handoff
+		randSleep = uint64(restoredDelay)
+	} else if scrapeAlignInterval <= 0 {
 		// Calculate start time for the first scrape from ScrapeURL and labels.
 		// This should spread load when scraping many targets with different
 		// scrape urls and labels.
@@ -309,6 +355,12 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 	select {
 	case <-stopCh:
 		timerpool.Put(timer)
+		select {
+		case <-globalStopCh:
+			// Hand off the restored state to the next vmagent instance.
+			sw.saveScrapeState()
+		default:
+		}
 		return
 	case <-timer.C:
 		timerpool.Put(timer)
@@ -326,6 +378,7 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 			case <-globalStopCh:
 				// Do not send staleness markers on graceful shutdown as Prometheus does.
 				// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/2013#issuecomment-1006994079
+				sw.saveScrapeState()
 			default:
 				// The code below is CPU-bound, while it may allocate big amounts of memory.
 				// That's why it is a good idea to limit the number of concurrent goroutines,
@@ -371,6 +424,7 @@ func (sw *scrapeWork) logError(s string) {
 
 func (sw *scrapeWork) scrapeAndLogError(scrapeTimestamp, realTimestamp int64) {
 	err := sw.scrapeInternal(scrapeTimestamp, realTimestamp)
+	sw.lastScrapeTimestamp = scrapeTimestamp
 	if *suppressScrapeErrors {
 		return
 	}
@@ -385,12 +439,26 @@ func (sw *scrapeWork) scrapeAndLogError(scrapeTimestamp, realTimestamp int64) {
 	if realTimestamp < sw.nextErrorLogTime {
 		return
 	}
+	sw.nextErrorLogTime = realTimestamp + suppressScrapeErrorsDelay.Milliseconds()
+	if errors.Is(err, context.Canceled) {
+		sw.failureRequestsCount = 0
+		sw.successRequestsCount = 0
+		return
+	}
+	if sw.errorLogsSkipped+1 < *scrapeErrorsLogSampling {
+		// Skip the log line because of -promscrape.scrapeErrorsLogSampling.
+		// The number of failed scrapes is reported at the next logged line.
+		sw.errorLogsSkipped++
+		return
+	}
 	totalRequests := sw.failureRequestsCount + sw.successRequestsCount
-	if !errors.Is(err, context.Canceled) {
+	if *scrapeErrorsLogFormat == "json" {
+		logger.Warnf("%s", sw.formatScrapeErrorJSON(err, sw.lastScrapeDurationSeconds, sw.failureRequestsCount, totalRequests))
+	} else {
 		logger.Warnf("cannot scrape target %q (%s) %d out of %d times during -promscrape.suppressScrapeErrorsDelay=%s; the last error: %s",
 			sw.Config.ScrapeURL, sw.Config.Labels.String(), sw.failureRequestsCount, totalRequests, *suppressScrapeErrorsDelay, err)
 	}
-	sw.nextErrorLogTime = realTimestamp + suppressScrapeErrorsDelay.Milliseconds()
+	sw.errorLogsSkipped = 0
 	sw.failureRequestsCount = 0
 	sw.successRequestsCount = 0
 }
@@ -402,6 +470,11 @@ var (
 	scrapesSkippedBySampleLimit = metrics.NewCounter("vm_promscrape_scrapes_skipped_by_sample_limit_total")
 	scrapesFailed               = metrics.NewCounter("vm_promscrape_scrapes_failed_total")
 	pushDataDuration            = metrics.NewHistogram("vm_promscrape_push_data_duration_seconds")
+
+	scrapesExceededSampleGrowthLimit  = metrics.NewCounter("vm_promscrape_scrapes_exceeded_sample_growth_limit_total")
+	scrapesSkippedBySampleGrowthLimit = metrics.NewCounter("vm_promscrape_scrapes_skipped_by_sample_growth_limit_total")
+	samplesDroppedBySampleGrowthLimit = metrics.NewCounter("vm_promscrape_samples_dropped_by_sample_growth_limit_total")
+	sampleGrowthRebaselines           = metrics.NewCounter("vm_promscrape_sample_growth_limit_rebaselines_total")
 )
 
 func (sw *scrapeWork) needStreamParseMode(responseSize int) bool {
@@ -443,6 +516,7 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	endTimestamp := time.Now().UnixMilli()
 	scrapeDurationSeconds := float64(endTimestamp-realTimestamp) / 1e3
 	scrapeDuration.Update(scrapeDurationSeconds)
+	sw.lastScrapeDurationSeconds = scrapeDurationSeconds
 
 	// The code below is CPU-bound, while it may allocate big amounts of memory.
 	// That's why it is a good idea to limit the number of concurrent goroutines,
@@ -528,8 +602,31 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 		wc.reset()
 		up = 0
 		scrapesSkippedBySampleLimit.Inc()
-		err = fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
-			"either reduce the sample count for the target or increase sample_limit", cfg.ScrapeURL, cfg.SampleLimit)
+		err = newScrapeError("sample_limit", fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
+			"either reduce the sample count for the target or increase sample_limit", cfg.ScrapeURL, cfg.SampleLimit))
+	}
+	sampleGrowthLimitExceeded := false
+	if up == 1 {
+		if maxSamples := sw.getSampleGrowthMaxSamples(); maxSamples > 0 && samplesPostRelabeling > maxSamples {
+			sampleGrowthLimitExceeded = true
+			scrapesExceededSampleGrowthLimit.Inc()
+			switch cfg.SampleGrowthAction {
+			case "alert":
+			case "cap":
+				samplesDropped := wc.capSamples(sw.getSampleGrowthCapSamples())
+				samplesDroppedBySampleGrowthLimit.Add(samplesDropped)
+			default:
+				wc.reset()
+				up = 0
+				scrapesSkippedBySampleGrowthLimit.Inc()
+				err = sw.newSampleGrowthLimitError(samplesPostRelabeling)
+				sw.registerSampleGrowthDrop(samplesPostRelabeling)
+			}
+		}
+		if up == 1 {
+			sw.prevSamplesPostRelabeling = len(wc.writeRequest.Timeseries)
+			sw.sampleGrowthDrops = 0
+		}
 	}
 	if up == 0 {
 		bodyString = ""
@@ -555,6 +652,7 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 		samplesPostRelabeling:     samplesPostRelabeling,
 		seriesAdded:               seriesAdded,
 		seriesLimitSamplesDropped: samplesDropped,
+		sampleGrowthLimitExceeded: sampleGrowthLimitExceeded,
 	}
 	wc.addAutoMetrics(sw, am, scrapeTimestamp)
 
@@ -579,6 +677,8 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 	var samplesScraped atomic.Int64
 	var samplesPostRelabeling atomic.Int64
 	var samplesDroppedTotal atomic.Int64
+	var samplesDroppedBySampleGrowth atomic.Int64
+	var sampleGrowthLimitExceeded atomic.Bool
 	var maxLabelsLen atomic.Int64
 
 	maxLabelsLen.Store(int64(sw.prevLabelsLen))
@@ -590,6 +690,8 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 	bodyString := bytesutil.ToUnsafeString(body.B)
 	cfg := sw.Config
 	areIdenticalSeries := areIdenticalSeries(cfg, lastScrapeStr, bodyString)
+	maxSamples := sw.getSampleGrowthMaxSamples()
+	capSamples := sw.getSampleGrowthCapSamples()
 
 	r := body.NewReader()
 	err := stream.Parse(r, scrapeTimestamp, "", false, func(rows []parser.Row) error {
@@ -615,8 +717,20 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 		n := samplesPostRelabeling.Add(int64(len(wc.writeRequest.Timeseries)))
 		if cfg.SampleLimit > 0 && int(n) > cfg.SampleLimit {
 			scrapesSkippedBySampleLimit.Inc()
-			return fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
-				"either reduce the sample count for the target or increase sample_limit", cfg.ScrapeURL, cfg.SampleLimit)
+			return newScrapeError("sample_limit", fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
+				"either reduce the sample count for the target or increase sample_limit", cfg.ScrapeURL, cfg.SampleLimit))
+		}
+		if maxSamples > 0 && int(n) > maxSamples {
+			sampleGrowthLimitExceeded.Store(true)
+			switch cfg.SampleGrowthAction {
+			case "alert":
+			case "cap":
+				// Keep only samples, which fit capSamples, in the order they were counted in n.
+				samplesDropped := wc.capSamples(capSamples - (int(n) - len(wc.writeRequest.Timeseries)))
+				samplesDroppedBySampleGrowth.Add(int64(samplesDropped))
+			default:
+				return sw.newSampleGrowthLimitError(int(n))
+			}
 		}
 
 		if sw.seriesLimitExceeded.Load() || !areIdenticalSeries {
@@ -630,6 +744,12 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 
 	sw.prevLabelsLen = int(maxLabelsLen.Load())
 	scrapedSamples.Update(float64(samplesScraped.Load()))
+	if sampleGrowthLimitExceeded.Load() {
+		scrapesExceededSampleGrowthLimit.Inc()
+		if cfg.SampleGrowthAction == "cap" {
+			samplesDroppedBySampleGrowthLimit.Add(int(samplesDroppedBySampleGrowth.Load()))
+		}
+	}
 	up := 1
 	if err != nil {
 		// Mark the scrape as failed even if it already read and pushed some samples
@@ -637,6 +757,15 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 		up = 0
 		bodyString = ""
 		scrapesFailed.Inc()
+		if sampleGrowthLimitExceeded.Load() && (cfg.SampleGrowthAction == "" || cfg.SampleGrowthAction == "drop") {
+			scrapesSkippedBySampleGrowthLimit.Inc()
+			// The parsing stops as soon as the limit is exceeded, so the number of samples may be lower than the real one.
+			// This is OK, since the baseline continues growing on subsequent drops until it reaches the real number of samples.
+			sw.registerSampleGrowthDrop(int(samplesPostRelabeling.Load()))
+		}
+	} else {
+		sw.prevSamplesPostRelabeling = int(samplesPostRelabeling.Load() - samplesDroppedBySampleGrowth.Load())
+		sw.sampleGrowthDrops = 0
 	}
 	seriesAdded := 0
 	if !areIdenticalSeries {
@@ -655,6 +784,7 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 		samplesPostRelabeling:     int(samplesPostRelabeling.Load()),
 		seriesAdded:               seriesAdded,
 		seriesLimitSamplesDropped: int(samplesDroppedTotal.Load()),
+		sampleGrowthLimitExceeded: sampleGrowthLimitExceeded.Load(),
 	}
 	sw.pushAutoMetrics(am, scrapeTimestamp)
 
@@ -774,6 +904,64 @@ func getSeriesAdded(lastScrape, currScrape string) int {
 	return strings.Count(bodyString, "\n")
 }
 
+// getSampleGrowthMaxSamples returns the maximum number of samples the target may expose at the current scrape according to sample_growth_limit.
+//
+// 0 is returned if there is no limit.
+func (sw *scrapeWork) getSampleGrowthMaxSamples() int {
+	limit := sw.Config.SampleGrowthLimit
+	if limit <= 0 || sw.prevSamplesPostRelabeling <= 0 {
+		return 0
+	}
+	return int(float64(sw.prevSamplesPostRelabeling) * limit)
+}
+
+// defaultSampleGrowthDrops is the default number of consecutive scrapes dropped because of sample_growth_limit,
+// after which the number of samples at the last dropped scrape becomes the new baseline.
+const defaultSampleGrowthDrops = 5
+
+// registerSampleGrowthDrop registers the scrape with the given number of samples dropped because of sample_growth_limit.
+//
+// If the number of consecutive drops reaches sample_growth_max_drops, then the given number of samples becomes the new baseline.
+// This prevents from marking the target as unhealthy forever after the legitimate growth of the number of samples.
+func (sw *scrapeWork) registerSampleGrowthDrop(samples int) {
+	maxDrops := sw.Config.SampleGrowthDrops
+	if maxDrops <= 0 {
+		maxDrops = defaultSampleGrowthDrops
+	}
+	sw.sampleGrowthDrops++
+	if sw.sampleGrowthDrops < maxDrops {
+		return
+	}
+	sw.prevSamplesPostRelabeling = samples
+	sw.sampleGrowthDrops = 0
+	sampleGrowthRebaselines.Inc()
+}
+
+// getSampleGrowthCapSamples returns the number of samples to keep when sample_growth_limit is exceeded and sample_growth_action is cap.
+func (sw *scrapeWork) getSampleGrowthCapSamples() int {
+	return sw.prevSamplesPostRelabeling + sw.Config.SampleGrowthMargin
+}
+
+func (sw *scrapeWork) newSampleGrowthLimitError(samples int) error {
+	cfg := sw.Config
+	return newScrapeError("sample_growth_limit", fmt.Errorf("the response from %q contains %d samples, which exceeds sample_growth_limit=%v compared to %d samples at the previous scrape; "+
+		"either fix the target or increase sample_growth_limit", cfg.ScrapeURL, samples, cfg.SampleGrowthLimit, sw.prevSamplesPostRelabeling))
+}
+
+// capSamples leaves up to maxSamples samples at wc and returns the number of dropped samples.
+func (wc *writeRequestCtx) capSamples(maxSamples int) int {
+	tss := wc.writeRequest.Timeseries
+	if maxSamples < 0 {
+		maxSamples = 0
+	}
+	if len(tss) <= maxSamples {
+		return 0
+	}
+	clear(tss[maxSamples:])
+	wc.writeRequest.Timeseries = tss[:maxSamples]
+	return len(tss) - maxSamples
+}
+
 func (sw *scrapeWork) initSeriesLimiter() {
 	if sw.Config.SeriesLimit > 0 {
 		sw.seriesLimiter = bloomfilter.NewLimiter(sw.Config.SeriesLimit, 24*time.Hour)
@@ -907,6 +1095,7 @@ type autoMetrics struct {
 	samplesPostRelabeling     int
 	seriesAdded               int
 	seriesLimitSamplesDropped int
+	sampleGrowthLimitExceeded bool
 }
 
 func isAutoMetric(s string) bool {
@@ -919,6 +1108,7 @@ func isAutoMetric(s string) bool {
 	switch s {
 	case "scrape_duration_seconds",
 		"scrape_response_size_bytes",
+		"scrape_sample_growth_limit_exceeded",
 		"scrape_samples_limit",
 		"scrape_samples_post_metric_relabeling",
 		"scrape_samples_scraped",
@@ -940,10 +1130,19 @@ func isAutoMetric(s string) bool {
 // sw is used as read-only config source.
 func (wc *writeRequestCtx) addAutoMetrics(sw *scrapeWork, am *autoMetrics, timestamp int64) {
 	rows := getAutoRows()
-	dst := slicesutil.SetLength(rows.Rows, 11)[:0]
+	dst := slicesutil.SetLength(rows.Rows, 12)[:0]
 
 	dst = appendRow(dst, "scrape_duration_seconds", am.scrapeDurationSeconds, timestamp)
 	dst = appendRow(dst, "scrape_response_size_bytes", float64(am.scrapeResponseSize), timestamp)
+	if sw.Config.SampleGrowthLimit > 0 {
+		// Expose scrape_sample_growth_limit_exceeded metric if sample_growth_limit config is set for the target,
+		// so alerts could be built on top of it.
+		exceeded := 0
+		if am.sampleGrowthLimitExceeded {
+			exceeded = 1
+		}
+		dst = appendRow(dst, "scrape_sample_growth_limit_exceeded", float64(exceeded), timestamp)
+	}
 
 	if sampleLimit := sw.Config.SampleLimit; sampleLimit > 0 {
 		// Expose scrape_samples_limit metric if sample_limit config is set for the target.
@@ -10,6 +10,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/chunkedbuffer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutil"
@@ -35,6 +36,7 @@ func TestIsAutoMetric(t *testing.T) {
 	f("scrape_series_limit_samples_dropped", true)
 	f("scrape_series_limit", true)
 	f("scrape_series_current", true)
+	f("scrape_sample_growth_limit_exceeded", true)
 
 	f("foobar", false)
 	f("exported_up", false)
@@ -623,6 +625,134 @@ func TestScrapeWorkScrapeInternalStreamConcurrency(t *testing.T) {
 	}, 3, 4015, 2)
 }
 
+func TestScrapeWorkScrapeInternalSampleGrowthLimit(t *testing.T) {
+	t.Run("OneShot", func(t *testing.T) {
+		testScrapeWorkScrapeInternalSampleGrowthLimit(t, false)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		testScrapeWorkScrapeInternalSampleGrowthLimit(t, true)
+	})
+}
+
+func testScrapeWorkScrapeInternalSampleGrowthLimit(t *testing.T, streamParse bool) {
+	generateScrape := func(n int) string {
+		w := strings.Builder{}
+		for i := 0; i < n; i++ {
+			w.WriteString(fmt.Sprintf("foo_%d 1\n", i))
+		}
+		return w.String()
+	}
+
+	f := func(action string, samplesExpected int, upExpected float64) {
+		t.Helper()
+
+		var sw scrapeWork
+		sw.Config = &ScrapeWork{
+			StreamParse:        streamParse,
+			ScrapeTimeout:      time.Second * 42,
+			SampleGrowthLimit:  5,
+			SampleGrowthAction: action,
+			SampleGrowthMargin: 3,
+			SampleGrowthDrops:  3,
+		}
+
+		data := generateScrape(2)
+		sw.ReadData = func(dst *chunkedbuffer.Buffer) (bool, error) {
+			dst.MustWrite([]byte(data))
+			return false, nil
+		}
+
+		var pushDataMu sync.Mutex
+		samples := 0
+		up := float64(-1)
+		exceeded := float64(-1)
+		sw.PushData = func(_ *auth.Token, wr *prompbmarshal.WriteRequest) {
+			pushDataMu.Lock()
+			defer pushDataMu.Unlock()
+
+			for _, ts := range wr.Timeseries {
+				name := ts.Labels[0].Value
+				switch name {
+				case "up":
+					up = ts.Samples[0].Value
+				case "scrape_sample_growth_limit_exceeded":
+					exceeded = ts.Samples[0].Value
+				default:
+					if !isAutoMetric(name) && !decimal.IsStaleNaN(ts.Samples[0].Value) {
+						samples++
+					}
+				}
+			}
+		}
+
+		// Unmarshal workers are needed for sending stale markers when the scrape is dropped.
+		protoparserutil.StartUnmarshalWorkers()
+		defer protoparserutil.StopUnmarshalWorkers()
+
+		timestamp := int64(123000)
+		tsmGlobal.Register(&sw)
+		defer tsmGlobal.Unregister(&sw)
+
+		// The first scrape sets the baseline
+		if err := sw.scrapeInternal(timestamp, timestamp); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if samples != 2 || up != 1 || exceeded != 0 {
+			t.Fatalf("unexpected result for the first scrape; got samples=%d, up=%v, exceeded=%v; want samples=2, up=1, exceeded=0", samples, up, exceeded)
+		}
+
+		// The second scrape exceeds sample_growth_limit
+		data = generateScrape(20)
+		samples = 0
+		err := sw.scrapeInternal(timestamp+1000, timestamp+1000)
+		if upExpected == 0 {
+			if err == nil || !strings.Contains(err.Error(), "sample_growth_limit") {
+				t.Fatalf("expecting sample_growth_limit error; got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if samples != samplesExpected {
+			t.Fatalf("unexpected number of samples; got %d; want %d", samples, samplesExpected)
+		}
+		if up != upExpected {
+			t.Fatalf("unexpected up; got %v; want %v", up, upExpected)
+		}
+		if exceeded != 1 {
+			t.Fatalf("unexpected scrape_sample_growth_limit_exceeded; got %v; want 1", exceeded)
+		}
+		if upExpected == 1 {
+			return
+		}
+
+		// The number of samples at the last dropped scrape becomes the new baseline after sample_growth_max_drops consecutive drops
+		for i := 2; i <= 3; i++ {
+			samples = 0
+			timestamp += 1000
+			if err := sw.scrapeInternal(timestamp+1000, timestamp+1000); err == nil {
+				t.Fatalf("expecting sample_growth_limit error at scrape #%d", i)
+			}
+			if samples != 0 || up != 0 {
+				t.Fatalf("unexpected result for dropped scrape #%d; got samples=%d, up=%v; want samples=0, up=0", i, samples, up)
+			}
+		}
+		samples = 0
+		timestamp += 1000
+		if err := sw.scrapeInternal(timestamp+1000, timestamp+1000); err != nil {
+			t.Fatalf("unexpected error after re-baselining: %s", err)
+		}
+		if samples != 20 || up != 1 || exceeded != 0 {
+			t.Fatalf("unexpected result after re-baselining; got samples=%d, up=%v, exceeded=%v; want samples=20, up=1, exceeded=0", samples, up, exceeded)
+		}
+	}
+
+	f("", 0, 0)
+	f("drop", 0, 0)
+	f("cap", 5, 1)
+	f("alert", 20, 1)
+}
+
 func TestWriteRequestCtx_AddRowNoRelabeling(t *testing.T) {
 	f := func(row string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
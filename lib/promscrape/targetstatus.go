@@ -92,6 +92,9 @@ type targetStatusMap struct {
 	m        map[*scrapeWork]*targetStatus
 	jobNames []string
 
+	// jobNamesByNamespace contains job names per each vmagent namespace. Job names for -promscrape.config are stored under empty namespace.
+	jobNamesByNamespace map[string][]string
+
 	// the current number of `up` targets in the given jobName
 	upByJob map[string]int
 
@@ -107,10 +110,30 @@ func newTargetStatusMap() *targetStatusMap {
 	}
 }
 
-func (tsm *targetStatusMap) registerJobNames(jobNames []string) {
+// registerJobNames registers jobNames for the given vmagent namespace.
+//
+// The namespace must be empty for -promscrape.config.
+func (tsm *targetStatusMap) registerJobNames(namespace string, jobNames []string) {
 	tsm.mu.Lock()
-	tsm.registerJobsMetricsLocked(tsm.jobNames, jobNames)
-	tsm.jobNames = append(tsm.jobNames[:0], jobNames...)
+	if tsm.jobNamesByNamespace == nil {
+		tsm.jobNamesByNamespace = make(map[string][]string)
+	}
+	if len(jobNames) == 0 {
+		delete(tsm.jobNamesByNamespace, namespace)
+	} else {
+		tsm.jobNamesByNamespace[namespace] = append([]string{}, jobNames...)
+	}
+	namespaces := make([]string, 0, len(tsm.jobNamesByNamespace))
+	for ns := range tsm.jobNamesByNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	var allJobNames []string
+	for _, ns := range namespaces {
+		allJobNames = append(allJobNames, tsm.jobNamesByNamespace[ns]...)
+	}
+	tsm.registerJobsMetricsLocked(tsm.jobNames, allJobNames)
+	tsm.jobNames = allJobNames
 	tsm.mu.Unlock()
 }
 
@@ -207,6 +230,39 @@ func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDu
 	tsm.mu.Unlock()
 }
 
+// restore restores the status for sw from ts, which has been handed off from the previous vmagent instance.
+func (tsm *targetStatusMap) restore(sw *scrapeWork, ts *targetStatus) {
+	jobName := sw.Config.jobNameOriginal
+
+	tsm.mu.Lock()
+	tsPrev, ok := tsm.m[sw]
+	if !ok {
+		logger.Panicf("BUG: missing Register() call for the target %q", jobName)
+	}
+	if ts.up && !tsPrev.up {
+		tsm.upByJob[jobName]++
+		tsm.downByJob[jobName]--
+	} else if !ts.up && tsPrev.up {
+		tsm.upByJob[jobName]--
+		tsm.downByJob[jobName]++
+	}
+	ts.sw = sw
+	tsm.m[sw] = ts
+	tsm.mu.Unlock()
+}
+
+// getStatus returns a copy of the status for sw.
+func (tsm *targetStatusMap) getStatus(sw *scrapeWork) (targetStatus, bool) {
+	tsm.mu.Lock()
+	defer tsm.mu.Unlock()
+
+	ts, ok := tsm.m[sw]
+	if !ok {
+		return targetStatus{}, false
+	}
+	return *ts, true
+}
+
 func (tsm *targetStatusMap) getScrapeWorkByTargetID(targetID string) *scrapeWork {
 	tsm.mu.Lock()
 	defer tsm.mu.Unlock()
@@ -352,6 +408,7 @@ const (
 	targetDropReasonMissingScrapeURL = targetDropReason("missing scrape URL") // target dropped because of missing scrape URL
 	targetDropReasonDuplicate        = targetDropReason("duplicate")          // target with the given set of labels already exists
 	targetDropReasonSharding         = targetDropReason("sharding")           // target is dropped because of sharding https://docs.victoriametrics.com/vmagent/#scraping-big-number-of-targets
+	targetDropReasonAnnotations      = targetDropReason("annotations")        // target isn't annotated for scraping https://docs.victoriametrics.com/sd_configs/#scraping-targets-via-kubernetes-annotations
 )
 
 func (dt *droppedTargets) getTargetsList() []droppedTarget {
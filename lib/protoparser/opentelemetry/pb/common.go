@@ -47,6 +47,62 @@ func (r *Resource) unmarshalProtobuf(src []byte) (err error) {
 	return nil
 }
 
+// InstrumentationScope represents the corresponding OTEL protobuf message
+type InstrumentationScope struct {
+	Name       string
+	Version    string
+	Attributes []*KeyValue
+}
+
+func (is *InstrumentationScope) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	mm.AppendString(1, is.Name)
+	mm.AppendString(2, is.Version)
+	for _, a := range is.Attributes {
+		a.marshalProtobuf(mm.AppendMessage(3))
+	}
+}
+
+// unmarshalProtobuf unmarshals is from protobuf message at src.
+func (is *InstrumentationScope) unmarshalProtobuf(src []byte) (err error) {
+	// message InstrumentationScope {
+	//   string name = 1;
+	//   string version = 2;
+	//   repeated KeyValue attributes = 3;
+	// }
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in InstrumentationScope: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read Name")
+			}
+			is.Name = strings.Clone(name)
+		case 2:
+			version, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read Version")
+			}
+			is.Version = strings.Clone(version)
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Attribute data")
+			}
+			is.Attributes = append(is.Attributes, &KeyValue{})
+			a := is.Attributes[len(is.Attributes)-1]
+			if err := a.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Attribute: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
 // KeyValue represents the corresponding OTEL protobuf message
 type KeyValue struct {
 	Key   string
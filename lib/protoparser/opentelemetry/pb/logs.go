@@ -107,10 +107,12 @@ func (rl *ResourceLogs) unmarshalProtobuf(src []byte) (err error) {
 
 // ScopeLogs represents the corresponding OTEL protobuf message
 type ScopeLogs struct {
+	Scope      InstrumentationScope
 	LogRecords []LogRecord
 }
 
 func (sl *ScopeLogs) marshalProtobuf(mm *easyproto.MessageMarshaler) {
+	sl.Scope.marshalProtobuf(mm.AppendMessage(1))
 	for _, m := range sl.LogRecords {
 		m.marshalProtobuf(mm.AppendMessage(2))
 	}
@@ -118,6 +120,7 @@ func (sl *ScopeLogs) marshalProtobuf(mm *easyproto.MessageMarshaler) {
 
 func (sl *ScopeLogs) unmarshalProtobuf(src []byte) (err error) {
 	// message ScopeLogs {
+	//   InstrumentationScope scope = 1;
 	//   repeated LogRecord log_records = 2;
 	// }
 	var fc easyproto.FieldContext
@@ -127,6 +130,14 @@ func (sl *ScopeLogs) unmarshalProtobuf(src []byte) (err error) {
 			return fmt.Errorf("cannot read next field in ScopeLogs: %w", err)
 		}
 		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read Scope data")
+			}
+			if err := sl.Scope.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal Scope: %w", err)
+			}
 		case 2:
 			data, ok := fc.MessageData()
 			if !ok {
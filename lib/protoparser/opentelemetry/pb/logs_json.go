@@ -0,0 +1,303 @@
+package pb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fastjson"
+)
+
+// UnmarshalJSON unmarshals r from OTLP/HTTP JSON message at src.
+//
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+func (r *ExportLogsServiceRequest) UnmarshalJSON(src []byte) error {
+	p := jsonParserPool.Get()
+	defer jsonParserPool.Put(p)
+
+	v, err := p.ParseBytes(src)
+	if err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	if v.Type() != fastjson.TypeObject {
+		return fmt.Errorf("unexpected JSON type; got %s; want object", v.Type())
+	}
+	rls, err := getJSONArray(v, "resourceLogs")
+	if err != nil {
+		return err
+	}
+	for _, rlv := range rls {
+		var rl ResourceLogs
+		if err := rl.unmarshalJSON(rlv); err != nil {
+			return fmt.Errorf("cannot unmarshal ResourceLogs: %w", err)
+		}
+		r.ResourceLogs = append(r.ResourceLogs, rl)
+	}
+	return nil
+}
+
+var jsonParserPool fastjson.ParserPool
+
+func (rl *ResourceLogs) unmarshalJSON(v *fastjson.Value) error {
+	if rv := v.Get("resource"); rv != nil {
+		attrs, err := unmarshalJSONAttributes(rv)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal Resource: %w", err)
+		}
+		rl.Resource.Attributes = attrs
+	}
+	sls, err := getJSONArray(v, "scopeLogs")
+	if err != nil {
+		return err
+	}
+	for _, slv := range sls {
+		var sl ScopeLogs
+		if err := sl.unmarshalJSON(slv); err != nil {
+			return fmt.Errorf("cannot unmarshal ScopeLogs: %w", err)
+		}
+		rl.ScopeLogs = append(rl.ScopeLogs, sl)
+	}
+	return nil
+}
+
+func (sl *ScopeLogs) unmarshalJSON(v *fastjson.Value) error {
+	if sv := v.Get("scope"); sv != nil {
+		if err := sl.Scope.unmarshalJSON(sv); err != nil {
+			return fmt.Errorf("cannot unmarshal Scope: %w", err)
+		}
+	}
+	lrs, err := getJSONArray(v, "logRecords")
+	if err != nil {
+		return err
+	}
+	for _, lrv := range lrs {
+		var lr LogRecord
+		if err := lr.unmarshalJSON(lrv); err != nil {
+			return fmt.Errorf("cannot unmarshal LogRecord: %w", err)
+		}
+		sl.LogRecords = append(sl.LogRecords, lr)
+	}
+	return nil
+}
+
+func (is *InstrumentationScope) unmarshalJSON(v *fastjson.Value) (err error) {
+	if is.Name, err = getJSONString(v, "name"); err != nil {
+		return err
+	}
+	if is.Version, err = getJSONString(v, "version"); err != nil {
+		return err
+	}
+	is.Attributes, err = unmarshalJSONAttributes(v)
+	return err
+}
+
+func (lr *LogRecord) unmarshalJSON(v *fastjson.Value) (err error) {
+	if lr.TimeUnixNano, err = getJSONUint64(v, "timeUnixNano"); err != nil {
+		return err
+	}
+	if lr.ObservedTimeUnixNano, err = getJSONUint64(v, "observedTimeUnixNano"); err != nil {
+		return err
+	}
+	if sv := v.Get("severityNumber"); sv != nil {
+		n, err := sv.Int()
+		if err != nil {
+			return fmt.Errorf("cannot parse severityNumber: %w", err)
+		}
+		lr.SeverityNumber = int32(n)
+	}
+	if lr.SeverityText, err = getJSONString(v, "severityText"); err != nil {
+		return err
+	}
+	if bv := v.Get("body"); bv != nil {
+		if err := lr.Body.unmarshalJSON(bv); err != nil {
+			return fmt.Errorf("cannot unmarshal body: %w", err)
+		}
+	}
+	if lr.Attributes, err = unmarshalJSONAttributes(v); err != nil {
+		return err
+	}
+	// traceId and spanId are hex-encoded in OTLP/HTTP JSON instead of base64 encoding used for other bytes fields.
+	if lr.TraceID, err = getJSONString(v, "traceId"); err != nil {
+		return err
+	}
+	lr.TraceID = strings.ToLower(lr.TraceID)
+	if lr.SpanID, err = getJSONString(v, "spanId"); err != nil {
+		return err
+	}
+	lr.SpanID = strings.ToLower(lr.SpanID)
+	return nil
+}
+
+func (av *AnyValue) unmarshalJSON(v *fastjson.Value) error {
+	o, err := v.Object()
+	if err != nil {
+		return fmt.Errorf("cannot read AnyValue: %w", err)
+	}
+	o.Visit(func(k []byte, fv *fastjson.Value) {
+		if err != nil {
+			return
+		}
+		switch string(k) {
+		case "stringValue":
+			var b []byte
+			b, err = fv.StringBytes()
+			if err != nil {
+				err = fmt.Errorf("cannot read stringValue: %w", err)
+				return
+			}
+			s := string(b)
+			av.StringValue = &s
+		case "boolValue":
+			var b bool
+			b, err = fv.Bool()
+			if err != nil {
+				err = fmt.Errorf("cannot read boolValue: %w", err)
+				return
+			}
+			av.BoolValue = &b
+		case "intValue":
+			var n int64
+			n, err = parseJSONInt64(fv)
+			if err != nil {
+				err = fmt.Errorf("cannot read intValue: %w", err)
+				return
+			}
+			av.IntValue = &n
+		case "doubleValue":
+			var f float64
+			f, err = parseJSONFloat64(fv)
+			if err != nil {
+				err = fmt.Errorf("cannot read doubleValue: %w", err)
+				return
+			}
+			av.DoubleValue = &f
+		case "arrayValue":
+			var values []*fastjson.Value
+			values, err = getJSONArray(fv, "values")
+			if err != nil {
+				return
+			}
+			av.ArrayValue = &ArrayValue{}
+			for _, vv := range values {
+				a := &AnyValue{}
+				if err = a.unmarshalJSON(vv); err != nil {
+					return
+				}
+				av.ArrayValue.Values = append(av.ArrayValue.Values, a)
+			}
+		case "kvlistValue":
+			var kvs []*KeyValue
+			kvs, err = unmarshalJSONKeyValues(fv, "values")
+			if err != nil {
+				return
+			}
+			av.KeyValueList = &KeyValueList{
+				Values: kvs,
+			}
+		case "bytesValue":
+			var b []byte
+			b, err = fv.StringBytes()
+			if err != nil {
+				err = fmt.Errorf("cannot read bytesValue: %w", err)
+				return
+			}
+			var data []byte
+			data, err = base64.StdEncoding.DecodeString(string(b))
+			if err != nil {
+				err = fmt.Errorf("cannot decode bytesValue: %w", err)
+				return
+			}
+			av.BytesValue = &data
+		}
+	})
+	return err
+}
+
+func unmarshalJSONAttributes(v *fastjson.Value) ([]*KeyValue, error) {
+	return unmarshalJSONKeyValues(v, "attributes")
+}
+
+func unmarshalJSONKeyValues(v *fastjson.Value, key string) ([]*KeyValue, error) {
+	items, err := getJSONArray(v, key)
+	if err != nil {
+		return nil, err
+	}
+	kvs := make([]*KeyValue, 0, len(items))
+	for _, item := range items {
+		k, err := getJSONString(item, "key")
+		if err != nil {
+			return nil, err
+		}
+		kv := &KeyValue{
+			Key:   k,
+			Value: &AnyValue{},
+		}
+		if vv := item.Get("value"); vv != nil {
+			if err := kv.Value.unmarshalJSON(vv); err != nil {
+				return nil, fmt.Errorf("cannot unmarshal value for key %q: %w", k, err)
+			}
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, nil
+}
+
+func getJSONArray(v *fastjson.Value, key string) ([]*fastjson.Value, error) {
+	av := v.Get(key)
+	if av == nil || av.Type() == fastjson.TypeNull {
+		return nil, nil
+	}
+	a, err := av.Array()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", key, err)
+	}
+	return a, nil
+}
+
+func getJSONString(v *fastjson.Value, key string) (string, error) {
+	sv := v.Get(key)
+	if sv == nil || sv.Type() == fastjson.TypeNull {
+		return "", nil
+	}
+	b, err := sv.StringBytes()
+	if err != nil {
+		return "", fmt.Errorf("cannot read %q: %w", key, err)
+	}
+	return string(b), nil
+}
+
+func getJSONUint64(v *fastjson.Value, key string) (uint64, error) {
+	nv := v.Get(key)
+	if nv == nil || nv.Type() == fastjson.TypeNull {
+		return 0, nil
+	}
+	// 64-bit integers may be encoded either as JSON strings or as JSON numbers.
+	if nv.Type() == fastjson.TypeString {
+		n, err := strconv.ParseUint(string(nv.GetStringBytes()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse %q: %w", key, err)
+		}
+		return n, nil
+	}
+	n, err := nv.Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q: %w", key, err)
+	}
+	return n, nil
+}
+
+func parseJSONInt64(v *fastjson.Value) (int64, error) {
+	if v.Type() == fastjson.TypeString {
+		return strconv.ParseInt(string(v.GetStringBytes()), 10, 64)
+	}
+	return v.Int64()
+}
+
+func parseJSONFloat64(v *fastjson.Value) (float64, error) {
+	if v.Type() == fastjson.TypeString {
+		// Special values such as NaN and Infinity are encoded as strings.
+		return strconv.ParseFloat(string(v.GetStringBytes()), 64)
+	}
+	return v.Float64()
+}
@@ -18,10 +18,10 @@ import (
 
 var maxInsertRequestSize = flagutil.NewBytes("maxInsertRequestSize", 32*1024*1024, "The maximum size in bytes of a single Prometheus remote_write API request")
 
-// Parse parses Prometheus remote_write message from reader and calls callback for the parsed timeseries.
+// Parse parses Prometheus remote_write message from reader and calls callback for the parsed timeseries and metric metadata.
 //
-// callback shouldn't hold tss after returning.
-func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSeries) error) error {
+// callback shouldn't hold tss and mms after returning.
+func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
@@ -87,8 +87,9 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 		rows += len(tss[i].Samples)
 	}
 	rowsRead.Add(rows)
+	metadataRead.Add(len(wr.Metadata))
 
-	if err := callback(tss); err != nil {
+	if err := callback(tss, wr.Metadata); err != nil {
 		return fmt.Errorf("error when processing imported data: %w", err)
 	}
 	return nil
@@ -126,6 +127,7 @@ var (
 	readCalls       = metrics.NewCounter(`vm_protoparser_read_calls_total{type="promremotewrite"}`)
 	readErrors      = metrics.NewCounter(`vm_protoparser_read_errors_total{type="promremotewrite"}`)
 	rowsRead        = metrics.NewCounter(`vm_protoparser_rows_read_total{type="promremotewrite"}`)
+	metadataRead    = metrics.NewCounter(`vm_protoparser_metadata_read_total{type="promremotewrite"}`)
 	unmarshalErrors = metrics.NewCounter(`vm_protoparser_unmarshal_errors_total{type="promremotewrite"}`)
 )
 
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
@@ -13,7 +14,7 @@ import (
 // Call Register() for registering the given amounts of resources.
 type RateLimiter struct {
 	// perSecondLimit is the per-second limit of resources.
-	perSecondLimit int64
+	perSecondLimit atomic.Int64
 
 	// stopCh is used for unbloking rate limiting.
 	stopCh <-chan struct{}
@@ -35,11 +36,19 @@ type RateLimiter struct {
 //
 // stopCh is used for unblocking Register() calls when the rate limiter is no longer needed.
 func New(perSecondLimit int64, limitReached *metrics.Counter, stopCh <-chan struct{}) *RateLimiter {
-	return &RateLimiter{
-		perSecondLimit: perSecondLimit,
-		stopCh:         stopCh,
-		limitReached:   limitReached,
+	rl := &RateLimiter{
+		stopCh:       stopCh,
+		limitReached: limitReached,
 	}
+	rl.perSecondLimit.Store(perSecondLimit)
+	return rl
+}
+
+// SetLimit sets the per-second limit to perSecondLimit.
+//
+// The rate limiting is disabled if perSecondLimit <= 0.
+func (rl *RateLimiter) SetLimit(perSecondLimit int64) {
+	rl.perSecondLimit.Store(perSecondLimit)
 }
 
 // Register registers count resources.
@@ -51,7 +60,7 @@ func (rl *RateLimiter) Register(count int) {
 		return
 	}
 
-	limit := rl.perSecondLimit
+	limit := rl.perSecondLimit.Load()
 	if limit <= 0 {
 		return
 	}
@@ -0,0 +1,125 @@
+package remotecache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memcachedClient is a client for memcached, which uses text protocol.
+//
+// See https://github.com/memcached/memcached/blob/master/doc/protocol.txt
+type memcachedClient struct {
+	cp connPool
+}
+
+// maxMemcachedKeyLen is the maximum key length supported by memcached.
+const maxMemcachedKeyLen = 250
+
+// maxMemcachedRelativeExpiration is the maximum expiration time in seconds, which is treated by memcached as relative to the current time.
+const maxMemcachedRelativeExpiration = 30 * 24 * 3600
+
+func checkMemcachedKey(key string) error {
+	if len(key) == 0 || len(key) > maxMemcachedKeyLen {
+		return fmt.Errorf("memcached key length must be in the range [1..%d]; got %d", maxMemcachedKeyLen, len(key))
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return fmt.Errorf("memcached key cannot contain whitespace or control chars; got %q", key)
+		}
+	}
+	return nil
+}
+
+// Get implements Client interface.
+func (c *memcachedClient) Get(dst []byte, key string) ([]byte, bool, error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return dst, false, err
+	}
+	dstLen := len(dst)
+	ok := false
+	err := c.cp.do(func(bc *bufferedConn) error {
+		if _, err := fmt.Fprintf(bc.bw, "get %s\r\n", key); err != nil {
+			return fmt.Errorf("cannot write get request: %w", err)
+		}
+		if err := bc.bw.Flush(); err != nil {
+			return fmt.Errorf("cannot send get request: %w", err)
+		}
+		line, err := readLine(bc.br)
+		if err != nil {
+			return fmt.Errorf("cannot read get response: %w", err)
+		}
+		if line == "END" {
+			return nil
+		}
+		// The response must be in the form `VALUE <key> <flags> <bytes>`
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" || fields[1] != key {
+			return fmt.Errorf("unexpected get response: %q", line)
+		}
+		n, err := strconv.Atoi(fields[3])
+		if err != nil || n < 0 {
+			return fmt.Errorf("cannot parse value size from get response %q", line)
+		}
+		dst, err = readValue(dst, bc.br, n)
+		if err != nil {
+			return err
+		}
+		line, err = readLine(bc.br)
+		if err != nil {
+			return fmt.Errorf("cannot read the end of get response: %w", err)
+		}
+		if line != "END" {
+			return fmt.Errorf("unexpected end of get response: %q; want END", line)
+		}
+		ok = true
+		return nil
+	})
+	if err != nil {
+		return dst[:dstLen], false, err
+	}
+	return dst, ok, nil
+}
+
+// Set implements Client interface.
+func (c *memcachedClient) Set(key string, value []byte, ttl time.Duration) error {
+	if err := checkMemcachedKey(key); err != nil {
+		return err
+	}
+	exptime := int64(ttl.Seconds())
+	if exptime <= 0 {
+		exptime = 1
+	}
+	if exptime > maxMemcachedRelativeExpiration {
+		// Larger values are treated by memcached as unix timestamps.
+		exptime = time.Now().Unix() + exptime
+	}
+	return c.cp.do(func(bc *bufferedConn) error {
+		if _, err := fmt.Fprintf(bc.bw, "set %s 0 %d %d\r\n", key, exptime, len(value)); err != nil {
+			return fmt.Errorf("cannot write set request: %w", err)
+		}
+		if _, err := bc.bw.Write(value); err != nil {
+			return fmt.Errorf("cannot write value for set request: %w", err)
+		}
+		if _, err := bc.bw.WriteString("\r\n"); err != nil {
+			return fmt.Errorf("cannot write set request: %w", err)
+		}
+		if err := bc.bw.Flush(); err != nil {
+			return fmt.Errorf("cannot send set request: %w", err)
+		}
+		line, err := readLine(bc.br)
+		if err != nil {
+			return fmt.Errorf("cannot read set response: %w", err)
+		}
+		if line != "STORED" {
+			return fmt.Errorf("unexpected set response: %q; want STORED", line)
+		}
+		return nil
+	})
+}
+
+// MustStop implements Client interface.
+func (c *memcachedClient) MustStop() {
+	c.cp.mustStop()
+}
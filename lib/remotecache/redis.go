@@ -0,0 +1,134 @@
+package remotecache
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// redisClient is a client for Redis, which uses RESP protocol.
+//
+// See https://redis.io/docs/latest/develop/reference/protocol-spec/
+type redisClient struct {
+	cp connPool
+}
+
+func redisInitConn(bc *bufferedConn, password string, db int) error {
+	if password != "" {
+		if err := redisSimpleCommand(bc, "AUTH", password); err != nil {
+			return fmt.Errorf("cannot authenticate: %w", err)
+		}
+	}
+	if db > 0 {
+		if err := redisSimpleCommand(bc, "SELECT", strconv.Itoa(db)); err != nil {
+			return fmt.Errorf("cannot select db %d: %w", db, err)
+		}
+	}
+	return nil
+}
+
+// redisSimpleCommand sends the given command with args to Redis and expects +OK response.
+func redisSimpleCommand(bc *bufferedConn, args ...string) error {
+	writeRedisCommand(bc, len(args))
+	for _, arg := range args {
+		writeRedisBulkString(bc, arg)
+	}
+	if err := bc.bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send %s command: %w", args[0], err)
+	}
+	line, err := readLine(bc.br)
+	if err != nil {
+		return fmt.Errorf("cannot read response for %s command: %w", args[0], err)
+	}
+	if line != "+OK" {
+		return fmt.Errorf("unexpected response for %s command: %q; want +OK", args[0], line)
+	}
+	return nil
+}
+
+func writeRedisCommand(bc *bufferedConn, argsCount int) {
+	// Errors are checked by the caller at bc.bw.Flush()
+	_, _ = fmt.Fprintf(bc.bw, "*%d\r\n", argsCount)
+}
+
+func writeRedisBulkString(bc *bufferedConn, s string) {
+	_, _ = fmt.Fprintf(bc.bw, "$%d\r\n", len(s))
+	_, _ = bc.bw.WriteString(s)
+	_, _ = bc.bw.WriteString("\r\n")
+}
+
+func writeRedisBulkBytes(bc *bufferedConn, b []byte) {
+	_, _ = fmt.Fprintf(bc.bw, "$%d\r\n", len(b))
+	_, _ = bc.bw.Write(b)
+	_, _ = bc.bw.WriteString("\r\n")
+}
+
+// Get implements Client interface.
+func (c *redisClient) Get(dst []byte, key string) ([]byte, bool, error) {
+	dstLen := len(dst)
+	ok := false
+	err := c.cp.do(func(bc *bufferedConn) error {
+		writeRedisCommand(bc, 2)
+		writeRedisBulkString(bc, "GET")
+		writeRedisBulkString(bc, key)
+		if err := bc.bw.Flush(); err != nil {
+			return fmt.Errorf("cannot send GET command: %w", err)
+		}
+		line, err := readLine(bc.br)
+		if err != nil {
+			return fmt.Errorf("cannot read GET response: %w", err)
+		}
+		if line == "$-1" {
+			return nil
+		}
+		if len(line) < 2 || line[0] != '$' {
+			return fmt.Errorf("unexpected GET response: %q", line)
+		}
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return fmt.Errorf("cannot parse value size from GET response %q", line)
+		}
+		dst, err = readValue(dst, bc.br, n)
+		if err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+	if err != nil {
+		return dst[:dstLen], false, err
+	}
+	return dst, ok, nil
+}
+
+// Set implements Client interface.
+func (c *redisClient) Set(key string, value []byte, ttl time.Duration) error {
+	ttlMsecs := ttl.Milliseconds()
+	if ttlMsecs <= 0 {
+		ttlMsecs = 1
+	}
+	return c.cp.do(func(bc *bufferedConn) error {
+		writeRedisCommand(bc, 5)
+		writeRedisBulkString(bc, "SET")
+		writeRedisBulkString(bc, key)
+		writeRedisBulkBytes(bc, value)
+		writeRedisBulkString(bc, "PX")
+		writeRedisBulkString(bc, strconv.FormatInt(ttlMsecs, 10))
+		if err := bc.bw.Flush(); err != nil {
+			return fmt.Errorf("cannot send SET command: %w", err)
+		}
+		line, err := readLine(bc.br)
+		if err != nil {
+			return fmt.Errorf("cannot read SET response: %w", err)
+		}
+		if line != "+OK" {
+			return fmt.Errorf("unexpected SET response: %q; want +OK", line)
+		}
+		return nil
+	})
+}
+
+// MustStop implements Client interface.
+func (c *redisClient) MustStop() {
+	c.cp.mustStop()
+}
@@ -0,0 +1,205 @@
+// Package remotecache provides clients for remote key-value caches such as memcached and Redis.
+package remotecache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client is a client for remote key-value cache.
+type Client interface {
+	// Get appends the value for the given key to dst and returns the result.
+	//
+	// false is returned if the key is missing in the cache.
+	Get(dst []byte, key string) ([]byte, bool, error)
+
+	// Set stores the given value under the given key for the given ttl.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// MustStop stops the client.
+	MustStop()
+}
+
+// NewClient returns new client for the remote cache at the given cacheURL.
+//
+// The following urls are supported:
+//
+//   - memcached://host:port
+//   - redis://host:port/db - db is optional
+//
+// password is used for authenticating at Redis if it is non-empty.
+// timeout is used as a deadline for every request to the remote cache.
+func NewClient(cacheURL, password string, timeout time.Duration) (Client, error) {
+	u, err := url.Parse(cacheURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse remote cache url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in remote cache url %q", cacheURL)
+	}
+	addr := u.Host
+	switch u.Scheme {
+	case "memcached":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "11211")
+		}
+		if password != "" {
+			return nil, fmt.Errorf("password isn't supported for memcached")
+		}
+		c := &memcachedClient{}
+		c.cp.init(addr, timeout, nil)
+		return c, nil
+	case "redis":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "6379")
+		}
+		db := 0
+		if dbStr := strings.Trim(u.Path, "/"); dbStr != "" {
+			db, err = strconv.Atoi(dbStr)
+			if err != nil || db < 0 {
+				return nil, fmt.Errorf("cannot parse Redis db number from %q", dbStr)
+			}
+		}
+		c := &redisClient{}
+		c.cp.init(addr, timeout, func(bc *bufferedConn) error {
+			return redisInitConn(bc, password, db)
+		})
+		return c, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme in remote cache url %q; supported schemes: memcached, redis", cacheURL)
+	}
+}
+
+// bufferedConn is a connection to remote cache.
+type bufferedConn struct {
+	net.Conn
+
+	br *bufio.Reader
+	bw *bufio.Writer
+}
+
+// connPool is a pool of connections to remote cache.
+type connPool struct {
+	addr     string
+	timeout  time.Duration
+	initConn func(bc *bufferedConn) error
+
+	mu    sync.Mutex
+	conns []*bufferedConn
+}
+
+// maxIdleConns is the maximum number of idle connections to remote cache.
+const maxIdleConns = 64
+
+func (cp *connPool) init(addr string, timeout time.Duration, initConn func(bc *bufferedConn) error) {
+	cp.addr = addr
+	cp.timeout = timeout
+	cp.initConn = initConn
+}
+
+// do calls f with connection to remote cache.
+//
+// The connection is closed if f returns error, since it may be in inconsistent state.
+func (cp *connPool) do(f func(bc *bufferedConn) error) error {
+	bc, err := cp.getConn()
+	if err != nil {
+		return err
+	}
+	if err := bc.SetDeadline(time.Now().Add(cp.timeout)); err != nil {
+		_ = bc.Close()
+		return fmt.Errorf("cannot set deadline for connection to %s: %w", cp.addr, err)
+	}
+	if err := f(bc); err != nil {
+		_ = bc.Close()
+		return err
+	}
+	cp.putConn(bc)
+	return nil
+}
+
+func (cp *connPool) getConn() (*bufferedConn, error) {
+	cp.mu.Lock()
+	if n := len(cp.conns); n > 0 {
+		bc := cp.conns[n-1]
+		cp.conns[n-1] = nil
+		cp.conns = cp.conns[:n-1]
+		cp.mu.Unlock()
+		return bc, nil
+	}
+	cp.mu.Unlock()
+
+	c, err := net.DialTimeout("tcp", cp.addr, cp.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %s: %w", cp.addr, err)
+	}
+	bc := &bufferedConn{
+		Conn: c,
+		br:   bufio.NewReader(c),
+		bw:   bufio.NewWriter(c),
+	}
+	if cp.initConn != nil {
+		if err := c.SetDeadline(time.Now().Add(cp.timeout)); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("cannot set deadline for connection to %s: %w", cp.addr, err)
+		}
+		if err := cp.initConn(bc); err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("cannot initialize connection to %s: %w", cp.addr, err)
+		}
+	}
+	return bc, nil
+}
+
+func (cp *connPool) putConn(bc *bufferedConn) {
+	cp.mu.Lock()
+	if len(cp.conns) < maxIdleConns {
+		cp.conns = append(cp.conns, bc)
+		bc = nil
+	}
+	cp.mu.Unlock()
+	if bc != nil {
+		_ = bc.Close()
+	}
+}
+
+func (cp *connPool) mustStop() {
+	cp.mu.Lock()
+	conns := cp.conns
+	cp.conns = nil
+	cp.mu.Unlock()
+	for _, bc := range conns {
+		_ = bc.Close()
+	}
+}
+
+// readLine reads a line ending with \r\n from br and returns it without the ending.
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", fmt.Errorf("missing \\r\\n at the end of line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// readValue appends n bytes followed by \r\n from br to dst and returns the result.
+func readValue(dst []byte, br *bufio.Reader, n int) ([]byte, error) {
+	dstLen := len(dst)
+	dst = append(dst, make([]byte, n+2)...)
+	if _, err := io.ReadFull(br, dst[dstLen:]); err != nil {
+		return dst[:dstLen], fmt.Errorf("cannot read value with size %d bytes: %w", n, err)
+	}
+	if string(dst[len(dst)-2:]) != "\r\n" {
+		return dst[:dstLen], fmt.Errorf("missing \\r\\n after the value")
+	}
+	return dst[:len(dst)-2], nil
+}
@@ -0,0 +1,282 @@
+package remotecache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewClient_Failure(t *testing.T) {
+	f := func(cacheURL, password string) {
+		t.Helper()
+
+		c, err := NewClient(cacheURL, password, time.Second)
+		if err == nil {
+			c.MustStop()
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("", "")
+	f("http://localhost:1234", "")
+	f("memcached://", "")
+	f("memcached://localhost:11211", "password")
+	f("redis://localhost:6379/foo", "")
+}
+
+func TestClient(t *testing.T) {
+	f := func(scheme, password string) {
+		t.Helper()
+
+		s := newFakeServer(t, scheme, password)
+		defer s.stop()
+
+		c, err := NewClient(fmt.Sprintf("%s://%s", scheme, s.ln.Addr()), password, time.Second)
+		if err != nil {
+			t.Fatalf("cannot create client: %s", err)
+		}
+		defer c.MustStop()
+
+		// missing key
+		dst, ok, err := c.Get([]byte("prefix"), "foo")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ok {
+			t.Fatalf("expecting missing key")
+		}
+		if string(dst) != "prefix" {
+			t.Fatalf("unexpected dst; got %q; want %q", dst, "prefix")
+		}
+
+		// existing keys
+		values := []string{"", "bar", "baz\r\nEND\r\n", strings.Repeat("x", 100*1024)}
+		for i, value := range values {
+			key := fmt.Sprintf("key_%d", i)
+			if err := c.Set(key, []byte(value), time.Hour); err != nil {
+				t.Fatalf("cannot set value for key %q: %s", key, err)
+			}
+		}
+		for i, value := range values {
+			key := fmt.Sprintf("key_%d", i)
+			dst, ok, err := c.Get([]byte("prefix"), key)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !ok {
+				t.Fatalf("missing value for key %q", key)
+			}
+			if string(dst) != "prefix"+value {
+				t.Fatalf("unexpected value for key %q; got %q; want %q", key, dst, "prefix"+value)
+			}
+		}
+	}
+
+	f("memcached", "")
+	f("redis", "")
+	f("redis", "secret")
+}
+
+func TestClientAuthFailure(t *testing.T) {
+	s := newFakeServer(t, "redis", "secret")
+	defer s.stop()
+
+	c, err := NewClient(fmt.Sprintf("redis://%s", s.ln.Addr()), "invalid", time.Second)
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	defer c.MustStop()
+
+	if _, _, err := c.Get(nil, "foo"); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestMemcachedClientInvalidKey(t *testing.T) {
+	c, err := NewClient("memcached://localhost:11211", "", time.Second)
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	defer c.MustStop()
+
+	f := func(key string) {
+		t.Helper()
+
+		if _, _, err := c.Get(nil, key); err == nil {
+			t.Fatalf("expecting non-nil error for Get")
+		}
+		if err := c.Set(key, nil, time.Hour); err == nil {
+			t.Fatalf("expecting non-nil error for Set")
+		}
+	}
+
+	f("")
+	f("foo bar")
+	f("foo\nbar")
+	f(strings.Repeat("x", maxMemcachedKeyLen+1))
+}
+
+// fakeServer is a minimal in-memory server, which supports a subset of memcached and Redis protocols.
+type fakeServer struct {
+	ln       net.Listener
+	scheme   string
+	password string
+	wg       sync.WaitGroup
+
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func newFakeServer(t *testing.T, scheme, password string) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start listener: %s", err)
+	}
+	s := &fakeServer{
+		ln:       ln,
+		scheme:   scheme,
+		password: password,
+		m:        make(map[string]string),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer c.Close()
+				s.serveConn(c)
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) stop() {
+	_ = s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *fakeServer) serveConn(c net.Conn) {
+	br := bufio.NewReader(c)
+	bw := bufio.NewWriter(c)
+	authenticated := s.password == ""
+	for {
+		var resp string
+		var ok bool
+		if s.scheme == "memcached" {
+			resp, ok = s.handleMemcachedRequest(br)
+		} else {
+			resp, ok = s.handleRedisRequest(br, &authenticated)
+		}
+		if !ok {
+			return
+		}
+		if _, err := bw.WriteString(resp); err != nil {
+			return
+		}
+		if err := bw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) handleMemcachedRequest(br *bufio.Reader) (string, bool) {
+	line, err := readLine(br)
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 2 && fields[0] == "get":
+		s.mu.Lock()
+		value, ok := s.m[fields[1]]
+		s.mu.Unlock()
+		if !ok {
+			return "END\r\n", true
+		}
+		return fmt.Sprintf("VALUE %s 0 %d\r\n%s\r\nEND\r\n", fields[1], len(value), value), true
+	case len(fields) == 5 && fields[0] == "set":
+		n, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return "", false
+		}
+		value, err := readValue(nil, br, n)
+		if err != nil {
+			return "", false
+		}
+		s.mu.Lock()
+		s.m[fields[1]] = string(value)
+		s.mu.Unlock()
+		return "STORED\r\n", true
+	default:
+		return "ERROR\r\n", true
+	}
+}
+
+func (s *fakeServer) handleRedisRequest(br *bufio.Reader, authenticated *bool) (string, bool) {
+	line, err := readLine(br)
+	if err != nil || !strings.HasPrefix(line, "*") {
+		return "", false
+	}
+	argsCount, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return "", false
+	}
+	var args []string
+	for i := 0; i < argsCount; i++ {
+		line, err := readLine(br)
+		if err != nil || !strings.HasPrefix(line, "$") {
+			return "", false
+		}
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", false
+		}
+		arg, err := readValue(nil, br, n)
+		if err != nil {
+			return "", false
+		}
+		args = append(args, string(arg))
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	if args[0] == "AUTH" {
+		if len(args) != 2 || args[1] != s.password {
+			return "-WRONGPASS invalid password\r\n", true
+		}
+		*authenticated = true
+		return "+OK\r\n", true
+	}
+	if !*authenticated {
+		return "-NOAUTH Authentication required.\r\n", true
+	}
+	switch {
+	case args[0] == "GET" && len(args) == 2:
+		s.mu.Lock()
+		value, ok := s.m[args[1]]
+		s.mu.Unlock()
+		if !ok {
+			return "$-1\r\n", true
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value), true
+	case args[0] == "SET" && len(args) == 5 && args[3] == "PX":
+		s.mu.Lock()
+		s.m[args[1]] = args[2]
+		s.mu.Unlock()
+		return "+OK\r\n", true
+	default:
+		return "-ERR unknown command\r\n", true
+	}
+}
@@ -186,7 +186,9 @@ func (b *Block) rowsCount() int {
 }
 
 // MarshalData marshals the block into binary representation.
-func (b *Block) MarshalData(timestampsBlockOffset, valuesBlockOffset uint64) ([]byte, []byte, []byte) {
+//
+// The block header is marshaled in the given formatVersion.
+func (b *Block) MarshalData(timestampsBlockOffset, valuesBlockOffset uint64, formatVersion uint) ([]byte, []byte, []byte) {
 	if len(b.values) == 0 {
 		// The data has been already marshaled.
 
@@ -209,7 +211,7 @@ func (b *Block) MarshalData(timestampsBlockOffset, valuesBlockOffset uint64) ([]
 		// headerData must be always recreated, since it contains timestampsBlockOffset and valuesBlockOffset.
 		b.bh.TimestampsBlockOffset = timestampsBlockOffset
 		b.bh.ValuesBlockOffset = valuesBlockOffset
-		b.headerData = b.bh.Marshal(b.headerData[:0])
+		b.headerData = b.bh.marshal(b.headerData[:0], formatVersion)
 
 		return b.headerData, b.timestampsData, b.valuesData
 	}
@@ -227,6 +229,7 @@ func (b *Block) MarshalData(timestampsBlockOffset, valuesBlockOffset uint64) ([]
 		logger.Panicf("BUG: the number of values must match the number of timestamps; got %d vs %d", len(values), len(timestamps))
 	}
 
+	b.bh.updateSummary(values)
 	b.valuesData, b.bh.ValuesMarshalType, b.bh.FirstValue = encoding.MarshalValues(b.valuesData[:0], values, b.bh.PrecisionBits)
 	b.bh.ValuesBlockOffset = valuesBlockOffset
 	b.bh.ValuesBlockSize = uint32(len(b.valuesData))
@@ -239,7 +242,7 @@ func (b *Block) MarshalData(timestampsBlockOffset, valuesBlockOffset uint64) ([]
 	b.timestamps = b.timestamps[:0]
 
 	b.bh.RowsCount = uint32(len(values))
-	b.headerData = b.bh.Marshal(b.headerData[:0])
+	b.headerData = b.bh.marshal(b.headerData[:0], formatVersion)
 
 	b.nextIdx = 0
 
@@ -353,7 +356,7 @@ func (b *Block) filterTimestamps(tr TimeRange) ([]int64, []int64) {
 //
 // The marshaled value must be unmarshaled with UnmarshalPortable function.
 func (b *Block) MarshalPortable(dst []byte) []byte {
-	b.MarshalData(0, 0)
+	b.MarshalData(0, 0, partFormatVersion)
 	dst = b.bh.marshalPortable(dst)
 	dst = encoding.MarshalBytes(dst, b.timestampsData)
 	dst = encoding.MarshalBytes(dst, b.valuesData)
@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
@@ -79,6 +80,27 @@ type blockHeader struct {
 	//
 	// Lower PrecisionBits give better block compression and speed.
 	PrecisionBits uint8
+
+	// HasSummary is set to true if MinValue, MaxValue and SumValue contain the summary for all the values in the block.
+	//
+	// The summary is missing for blocks with Inf values or staleness marks, for blocks with lossy compressed values
+	// and for blocks from parts created before the introduction of block summaries.
+	HasSummary bool
+
+	// MinValue is the minimum value in the block.
+	//
+	// It must be multiplied by 10^Scale in order to get the real value.
+	MinValue int64
+
+	// MaxValue is the maximum value in the block.
+	//
+	// It must be multiplied by 10^Scale in order to get the real value.
+	MaxValue int64
+
+	// SumValue is the sum of values in the block.
+	//
+	// It must be multiplied by 10^Scale in order to get the real value.
+	SumValue float64
 }
 
 // Less returns true if b is less than src.
@@ -99,8 +121,27 @@ var marshaledBlockHeaderSize = func() int {
 	return len(data)
 }()
 
+// marshaledBlockSummarySize is the size of marshaled block summary at the end of marshaled block header.
+const marshaledBlockSummarySize = 1 + 8 + 8 + 8
+
+// marshaledBlockHeaderSizeLegacy is the size of marshaled block header for parts with partFormatVersionLegacy.
+var marshaledBlockHeaderSizeLegacy = marshaledBlockHeaderSize - marshaledBlockSummarySize
+
+// getMarshaledBlockHeaderSize returns the size of marshaled block header for parts with the given formatVersion.
+func getMarshaledBlockHeaderSize(formatVersion uint) int {
+	if formatVersion == partFormatVersionLegacy {
+		return marshaledBlockHeaderSizeLegacy
+	}
+	return marshaledBlockHeaderSize
+}
+
 // Marshal appends marshaled bh to dst and returns the result.
 func (bh *blockHeader) Marshal(dst []byte) []byte {
+	return bh.marshal(dst, partFormatVersion)
+}
+
+// marshal appends bh marshaled in the given formatVersion to dst and returns the result.
+func (bh *blockHeader) marshal(dst []byte, formatVersion uint) []byte {
 	dst = bh.TSID.Marshal(dst)
 	dst = encoding.MarshalInt64(dst, bh.MinTimestamp)
 	dst = encoding.MarshalInt64(dst, bh.MaxTimestamp)
@@ -112,13 +153,30 @@ func (bh *blockHeader) Marshal(dst []byte) []byte {
 	dst = encoding.MarshalUint32(dst, bh.RowsCount)
 	dst = encoding.MarshalInt16(dst, bh.Scale)
 	dst = append(dst, byte(bh.TimestampsMarshalType), byte(bh.ValuesMarshalType), bh.PrecisionBits)
+	if formatVersion == partFormatVersionLegacy {
+		return dst
+	}
+	hasSummary := byte(0)
+	if bh.HasSummary {
+		hasSummary = 1
+	}
+	dst = append(dst, hasSummary)
+	dst = encoding.MarshalInt64(dst, bh.MinValue)
+	dst = encoding.MarshalInt64(dst, bh.MaxValue)
+	dst = encoding.MarshalUint64(dst, math.Float64bits(bh.SumValue))
 	return dst
 }
 
 // Unmarshal unmarshals bh from src and returns the rest of src.
 func (bh *blockHeader) Unmarshal(src []byte) ([]byte, error) {
-	if len(src) < marshaledBlockHeaderSize {
-		return src, fmt.Errorf("too short block header; got %d bytes; want %d bytes", len(src), marshaledBlockHeaderSize)
+	return bh.unmarshal(src, partFormatVersion)
+}
+
+// unmarshal unmarshals bh in the given formatVersion from src and returns the rest of src.
+func (bh *blockHeader) unmarshal(src []byte, formatVersion uint) ([]byte, error) {
+	bhSize := getMarshaledBlockHeaderSize(formatVersion)
+	if len(src) < bhSize {
+		return src, fmt.Errorf("too short block header; got %d bytes; want %d bytes", len(src), bhSize)
 	}
 
 	tail, err := bh.TSID.Unmarshal(src)
@@ -152,10 +210,65 @@ func (bh *blockHeader) Unmarshal(src []byte) ([]byte, error) {
 	bh.PrecisionBits = uint8(src[0])
 	src = src[1:]
 
+	bh.HasSummary = false
+	bh.MinValue = 0
+	bh.MaxValue = 0
+	bh.SumValue = 0
+	if formatVersion != partFormatVersionLegacy {
+		switch src[0] {
+		case 0:
+		case 1:
+			bh.HasSummary = true
+		default:
+			return src, fmt.Errorf("unexpected value for hasSummary flag; got %d; want 0 or 1", src[0])
+		}
+		src = src[1:]
+		bh.MinValue = encoding.UnmarshalInt64(src)
+		src = src[8:]
+		bh.MaxValue = encoding.UnmarshalInt64(src)
+		src = src[8:]
+		bh.SumValue = math.Float64frombits(encoding.UnmarshalUint64(src))
+		src = src[8:]
+	}
+
 	err = bh.validate()
 	return src, err
 }
 
+// updateSummary updates the summary at bh for the given values, which are going to be stored in the block.
+func (bh *blockHeader) updateSummary(values []int64) {
+	bh.HasSummary = false
+	bh.MinValue = 0
+	bh.MaxValue = 0
+	bh.SumValue = 0
+
+	if len(values) == 0 || bh.PrecisionBits < 64 {
+		// Lossy compression may change the stored values, so the summary may become inconsistent with them.
+		return
+	}
+	minValue := values[0]
+	maxValue := values[0]
+	sumValue := float64(0)
+	for _, v := range values {
+		if v < minValue {
+			minValue = v
+		}
+		if v > maxValue {
+			maxValue = v
+		}
+		sumValue += float64(v)
+	}
+	if decimal.IsSpecialValueInt64(minValue) || decimal.IsSpecialValueInt64(maxValue) {
+		// Special values are located at the edges of int64 range, so it is enough to check minValue and maxValue.
+		// Do not store the summary for blocks with Inf values and staleness marks, since they must be handled by rollup functions.
+		return
+	}
+	bh.HasSummary = true
+	bh.MinValue = minValue
+	bh.MaxValue = maxValue
+	bh.SumValue = sumValue
+}
+
 func (bh *blockHeader) marshalPortable(dst []byte) []byte {
 	dst = encoding.MarshalVarInt64(dst, bh.MinTimestamp)
 	dst = encoding.MarshalVarInt64(dst, bh.MaxTimestamp)
@@ -258,7 +371,7 @@ func (bh *blockHeader) validate() error {
 // appends them to dst and returns the appended result.
 //
 // Block headers must be sorted by bh.TSID.
-func unmarshalBlockHeaders(dst []blockHeader, src []byte, blockHeadersCount int) ([]blockHeader, error) {
+func unmarshalBlockHeaders(dst []blockHeader, src []byte, blockHeadersCount int, formatVersion uint) ([]blockHeader, error) {
 	if blockHeadersCount <= 0 {
 		logger.Panicf("BUG: blockHeadersCount must be greater than zero; got %d", blockHeadersCount)
 	}
@@ -266,7 +379,7 @@ func unmarshalBlockHeaders(dst []blockHeader, src []byte, blockHeadersCount int)
 	dst = slicesutil.ExtendCapacity(dst, blockHeadersCount)
 	var bh blockHeader
 	for len(src) > 0 {
-		tmp, err := bh.Unmarshal(src)
+		tmp, err := bh.unmarshal(src, formatVersion)
 		if err != nil {
 			return dst, fmt.Errorf("cannot unmarshal block header: %w", err)
 		}
@@ -1,9 +1,11 @@
 package storage
 
 import (
+	"math"
 	"reflect"
 	"testing"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 )
 
@@ -11,10 +13,14 @@ func TestMarshaledBlockHeaderSize(t *testing.T) {
 	// This test makes sure marshaled format isn't changed.
 	// If this test breaks then the storage format has been changed,
 	// so it may become incompatible with the previously written data.
-	expectedSize := 81
+	expectedSize := 106
 	if marshaledBlockHeaderSize != expectedSize {
 		t.Fatalf("unexpected marshaledBlockHeaderSize; got %d; want %d", marshaledBlockHeaderSize, expectedSize)
 	}
+	expectedSizeLegacy := 81
+	if marshaledBlockHeaderSizeLegacy != expectedSizeLegacy {
+		t.Fatalf("unexpected marshaledBlockHeaderSizeLegacy; got %d; want %d", marshaledBlockHeaderSizeLegacy, expectedSizeLegacy)
+	}
 }
 
 func TestBlockHeaderMarshalUnmarshal(t *testing.T) {
@@ -32,11 +38,98 @@ func TestBlockHeaderMarshalUnmarshal(t *testing.T) {
 		bh.TimestampsMarshalType = encoding.MarshalType((i + 10) % 7)
 		bh.ValuesMarshalType = encoding.MarshalType((i + 11) % 7)
 		bh.PrecisionBits = 1 + uint8((i+12)%64)
+		bh.HasSummary = i%2 == 0
+		bh.MinValue = int64(i*13 - 100)
+		bh.MaxValue = int64(i*17 + 14)
+		bh.SumValue = float64(i) * 1.5
 
 		testBlockHeaderMarshalUnmarshal(t, &bh)
 	}
 }
 
+func TestBlockHeaderUnmarshalLegacy(t *testing.T) {
+	var bh blockHeader
+	bh.TSID.MetricID = 123
+	bh.MinTimestamp = 10
+	bh.MaxTimestamp = 20
+	bh.RowsCount = 3
+	bh.Scale = -2
+	bh.PrecisionBits = 64
+	bh.HasSummary = true
+	bh.MinValue = 1
+	bh.MaxValue = 5
+	bh.SumValue = 9
+
+	// Legacy block headers have no summary at the end.
+	data := bh.marshal(nil, partFormatVersionLegacy)
+	if len(data) != marshaledBlockHeaderSizeLegacy {
+		t.Fatalf("unexpected size of legacy block header; got %d bytes; want %d bytes", len(data), marshaledBlockHeaderSizeLegacy)
+	}
+	data = append(data, "foo"...)
+
+	var bhLegacy blockHeader
+	tail, err := bhLegacy.unmarshal(data, partFormatVersionLegacy)
+	if err != nil {
+		t.Fatalf("cannot unmarshal legacy block header: %s", err)
+	}
+	if string(tail) != "foo" {
+		t.Fatalf("unexpected tail; got %q; want %q", tail, "foo")
+	}
+	bhExpected := bh
+	bhExpected.HasSummary = false
+	bhExpected.MinValue = 0
+	bhExpected.MaxValue = 0
+	bhExpected.SumValue = 0
+	if !reflect.DeepEqual(&bhLegacy, &bhExpected) {
+		t.Fatalf("unexpected block header; got\n%+v; want\n%+v", &bhLegacy, &bhExpected)
+	}
+
+	// The legacy block header cannot be unmarshaled in the current format.
+	if _, err := bhLegacy.unmarshal(data[:marshaledBlockHeaderSizeLegacy], partFormatVersion); err == nil {
+		t.Fatalf("expecting non-nil error when unmarshaling legacy block header in the current format")
+	}
+}
+
+func TestBlockHeaderUpdateSummary(t *testing.T) {
+	f := func(values []int64, precisionBits uint8, hasSummaryExpected bool, minExpected, maxExpected int64, sumExpected float64) {
+		t.Helper()
+
+		var bh blockHeader
+		bh.PrecisionBits = precisionBits
+		bh.updateSummary(values)
+		if bh.HasSummary != hasSummaryExpected {
+			t.Fatalf("unexpected HasSummary; got %v; want %v", bh.HasSummary, hasSummaryExpected)
+		}
+		if bh.MinValue != minExpected {
+			t.Fatalf("unexpected MinValue; got %d; want %d", bh.MinValue, minExpected)
+		}
+		if bh.MaxValue != maxExpected {
+			t.Fatalf("unexpected MaxValue; got %d; want %d", bh.MaxValue, maxExpected)
+		}
+		if bh.SumValue != sumExpected {
+			t.Fatalf("unexpected SumValue; got %v; want %v", bh.SumValue, sumExpected)
+		}
+	}
+
+	// empty values
+	f(nil, 64, false, 0, 0, 0)
+
+	// regular values
+	f([]int64{5}, 64, true, 5, 5, 5)
+	f([]int64{3, -2, 10, 4}, 64, true, -2, 10, 15)
+
+	// lossy compression
+	f([]int64{3, -2, 10, 4}, 20, false, 0, 0, 0)
+
+	// staleness mark and infs
+	staleNaN, _ := decimal.FromFloat(decimal.StaleNaN)
+	f([]int64{3, staleNaN, 4}, 64, false, 0, 0, 0)
+	infPos, _ := decimal.FromFloat(math.Inf(1))
+	f([]int64{3, infPos}, 64, false, 0, 0, 0)
+	infNeg, _ := decimal.FromFloat(math.Inf(-1))
+	f([]int64{infNeg, 3}, 64, false, 0, 0, 0)
+}
+
 func testBlockHeaderMarshalUnmarshal(t *testing.T, bh *blockHeader) {
 	t.Helper()
 
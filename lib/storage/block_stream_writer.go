@@ -134,13 +134,13 @@ func (bsw *blockStreamWriter) MustClose() {
 func (bsw *blockStreamWriter) WriteExternalBlock(b *Block, ph *partHeader, rowsMerged *atomic.Uint64) {
 	rowsMerged.Add(uint64(b.rowsCount()))
 	b.deduplicateSamplesDuringMerge()
-	headerData, timestampsData, valuesData := b.MarshalData(bsw.timestampsBlockOffset, bsw.valuesBlockOffset)
+	headerData, timestampsData, valuesData := b.MarshalData(bsw.timestampsBlockOffset, bsw.valuesBlockOffset, ph.FormatVersion)
 
 	usePrevTimestamps := len(bsw.prevTimestampsData) > 0 && bytes.Equal(timestampsData, bsw.prevTimestampsData)
 	if usePrevTimestamps {
 		// The current timestamps block equals to the previous timestamps block.
 		// Update headerData so it points to the previous timestamps block. This saves disk space.
-		headerData, timestampsData, valuesData = b.MarshalData(bsw.prevTimestampsBlockOffset, bsw.valuesBlockOffset)
+		headerData, timestampsData, valuesData = b.MarshalData(bsw.prevTimestampsBlockOffset, bsw.valuesBlockOffset, ph.FormatVersion)
 		timestampsBlocksMerged.Add(1)
 		timestampsBytesSaved.Add(uint64(len(timestampsData)))
 	}
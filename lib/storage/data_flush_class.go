@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
+)
+
+// DataFlushClass contains the interval for guaranteed flush of recently ingested data from memory to disk
+// for time series with metric names matching MetricNameRegex.
+type DataFlushClass struct {
+	// MetricNameRegex is the regular expression for metric names, which belong to the class.
+	MetricNameRegex *regexutil.PromRegex
+
+	// FlushInterval is the interval for guaranteed flush of recently ingested data for the class from memory to disk.
+	FlushInterval time.Duration
+}
+
+// The minimum supported DataFlushClass.FlushInterval.
+const minDataFlushClassInterval = time.Second
+
+// Data flush classes set via SetDataFlushClasses.
+var dataFlushClasses []DataFlushClass
+
+// SetDataFlushClasses sets data flush classes for time series, which must be flushed from memory to disk
+// with an interval different from the interval set via SetDataFlushInterval.
+//
+// The first class with the matching metric name is used for every time series.
+// Time series, which do not match any class, are flushed to disk with the interval set via SetDataFlushInterval.
+//
+// This function must be called before initializing the storage.
+func SetDataFlushClasses(classes []DataFlushClass) {
+	dataFlushClasses = dataFlushClasses[:0]
+	for _, dfc := range classes {
+		if dfc.FlushInterval < minDataFlushClassInterval {
+			dfc.FlushInterval = minDataFlushClassInterval
+		}
+		dataFlushClasses = append(dataFlushClasses, dfc)
+	}
+}
+
+// getMinDataFlushInterval returns the minimum interval for guaranteed flush of recently ingested data across all the data flush classes.
+func getMinDataFlushInterval() time.Duration {
+	d := dataFlushInterval
+	for _, dfc := range dataFlushClasses {
+		if dfc.FlushInterval < d {
+			d = dfc.FlushInterval
+		}
+	}
+	return d
+}
+
+// getMinPendingRowsFlushInterval returns the minimum interval for flushing pending rows across all the data flush classes.
+func getMinPendingRowsFlushInterval() time.Duration {
+	d := getMinDataFlushInterval()
+	if d > pendingRowsFlushInterval {
+		d = pendingRowsFlushInterval
+	}
+	return d
+}
+
+// getDataFlushClassInterval returns data flush interval for the given metricGroup.
+//
+// Zero is returned if metricGroup doesn't match any data flush class.
+func getDataFlushClassInterval(metricGroup []byte) time.Duration {
+	s := bytesutil.ToUnsafeString(metricGroup)
+	for _, dfc := range dataFlushClasses {
+		if dfc.MetricNameRegex.MatchString(s) {
+			return dfc.FlushInterval
+		}
+	}
+	return 0
+}
+
+// getMetricGroupFromMetricNameRaw returns metric group from metricNameRaw generated via MarshalMetricNameRaw.
+//
+// nil is returned if metricNameRaw doesn't contain metric group or if it cannot be parsed.
+func getMetricGroupFromMetricNameRaw(metricNameRaw []byte) []byte {
+	src := metricNameRaw
+	for len(src) > 0 {
+		tail, key, err := unmarshalBytesFast(src)
+		if err != nil {
+			return nil
+		}
+		tail, value, err := unmarshalBytesFast(tail)
+		if err != nil {
+			return nil
+		}
+		if len(key) == 0 {
+			return value
+		}
+		src = tail
+	}
+	return nil
+}
+
+// registerDataFlushClasses registers data flush intervals for the given rows, which belong to data flush classes.
+//
+// mrs must contain MetricRow entries for the corresponding rows.
+func (s *Storage) registerDataFlushClasses(rows []rawRow, mrs []*MetricRow) {
+	c := s.dataFlushIntervalsCache
+	if c == nil {
+		return
+	}
+	var key, value [8]byte
+	prevMetricID := uint64(0)
+	for i := range rows {
+		metricID := rows[i].TSID.MetricID
+		if metricID == prevMetricID {
+			continue
+		}
+		prevMetricID = metricID
+		encoding.MarshalUint64(key[:0], metricID)
+		if c.Has(key[:]) {
+			continue
+		}
+		// Cache zero intervals for time series outside data flush classes too,
+		// so the metric name isn't matched against data flush classes on every ingested row.
+		metricGroup := getMetricGroupFromMetricNameRaw(mrs[i].MetricNameRaw)
+		d := getDataFlushClassInterval(metricGroup)
+		encoding.MarshalUint64(value[:0], uint64(d))
+		c.Set(key[:], value[:])
+	}
+}
+
+// getDataFlushInterval returns the interval for guaranteed flush of the given rows from memory to disk.
+//
+// The default interval is used for rows, which are missing in the data flush intervals cache.
+func (s *Storage) getDataFlushInterval(rows []rawRow) time.Duration {
+	d := dataFlushInterval
+	if s == nil || s.dataFlushIntervalsCache == nil {
+		return d
+	}
+	var key [8]byte
+	var buf []byte
+	prevMetricID := uint64(0)
+	for i := range rows {
+		metricID := rows[i].TSID.MetricID
+		if metricID == prevMetricID {
+			continue
+		}
+		prevMetricID = metricID
+		encoding.MarshalUint64(key[:0], metricID)
+		buf = s.dataFlushIntervalsCache.Get(buf[:0], key[:])
+		if len(buf) != 8 {
+			continue
+		}
+		if dc := time.Duration(encoding.UnmarshalUint64(buf)); dc > 0 && dc < d {
+			d = dc
+		}
+	}
+	return d
+}
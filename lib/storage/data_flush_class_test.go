@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
+)
+
+func TestGetMetricGroupFromMetricNameRaw(t *testing.T) {
+	f := func(labels []prompbmarshal.Label, metricGroupExpected string) {
+		t.Helper()
+
+		metricNameRaw := MarshalMetricNameRaw(nil, labels)
+		metricGroup := getMetricGroupFromMetricNameRaw(metricNameRaw)
+		if string(metricGroup) != metricGroupExpected {
+			t.Fatalf("unexpected metric group; got %q; want %q", metricGroup, metricGroupExpected)
+		}
+	}
+
+	f(nil, "")
+	f([]prompbmarshal.Label{{Name: "job", Value: "foo"}}, "")
+	f([]prompbmarshal.Label{{Name: "__name__", Value: "foo"}}, "foo")
+	f([]prompbmarshal.Label{{Name: "job", Value: "bar"}, {Name: "__name__", Value: "foo"}, {Name: "instance", Value: "baz"}}, "foo")
+
+	// invalid metricNameRaw
+	if metricGroup := getMetricGroupFromMetricNameRaw([]byte("a")); metricGroup != nil {
+		t.Fatalf("unexpected metric group for invalid metricNameRaw; got %q; want nil", metricGroup)
+	}
+}
+
+func TestSetDataFlushClasses(t *testing.T) {
+	defer SetDataFlushClasses(nil)
+
+	SetDataFlushClasses([]DataFlushClass{
+		{
+			MetricNameRegex: mustNewPromRegex("billing_.+"),
+			FlushInterval:   time.Millisecond,
+		},
+		{
+			MetricNameRegex: mustNewPromRegex("billing_.+|important_.+"),
+			FlushInterval:   3 * time.Second,
+		},
+	})
+
+	f := func(metricGroup string, dExpected time.Duration) {
+		t.Helper()
+
+		d := getDataFlushClassInterval([]byte(metricGroup))
+		if d != dExpected {
+			t.Fatalf("unexpected data flush interval for %q; got %s; want %s", metricGroup, d, dExpected)
+		}
+	}
+
+	// The interval smaller than minDataFlushClassInterval must be adjusted.
+	f("billing_total", minDataFlushClassInterval)
+	f("important_total", 3*time.Second)
+	f("other_total", 0)
+	f("", 0)
+
+	if d := getMinDataFlushInterval(); d != minDataFlushClassInterval {
+		t.Fatalf("unexpected min data flush interval; got %s; want %s", d, minDataFlushClassInterval)
+	}
+	if d := getMinPendingRowsFlushInterval(); d != minDataFlushClassInterval {
+		t.Fatalf("unexpected min pending rows flush interval; got %s; want %s", d, minDataFlushClassInterval)
+	}
+}
+
+func TestStorageDataFlushClasses(t *testing.T) {
+	defer testRemoveAll(t)
+	defer SetDataFlushClasses(nil)
+
+	SetDataFlushClasses([]DataFlushClass{
+		{
+			MetricNameRegex: mustNewPromRegex("billing_.+"),
+			FlushInterval:   time.Second,
+		},
+	})
+
+	s := MustOpenStorage(t.Name(), OpenOptions{})
+	defer s.MustClose()
+
+	newMetricRow := func(metricGroup string) MetricRow {
+		mn := MetricName{
+			MetricGroup: []byte(metricGroup),
+		}
+		return MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     time.Now().UnixMilli(),
+			Value:         1,
+		}
+	}
+	mrs := []MetricRow{
+		newMetricRow("infra_cpu_seconds_total"),
+		newMetricRow("billing_requests_total"),
+	}
+	s.AddRows(mrs, defaultPrecisionBits)
+	s.DebugFlush()
+
+	getRows := func(metricGroup string) []rawRow {
+		t.Helper()
+
+		var genTSID generationTSID
+		mr := newMetricRow(metricGroup)
+		if !s.getTSIDFromCache(&genTSID, mr.MetricNameRaw) {
+			t.Fatalf("cannot find TSID for %q", metricGroup)
+		}
+		return []rawRow{{TSID: genTSID.TSID}}
+	}
+
+	infraRows := getRows("infra_cpu_seconds_total")
+	if d := s.getDataFlushInterval(infraRows); d != dataFlushInterval {
+		t.Fatalf("unexpected data flush interval for rows outside data flush classes; got %s; want %s", d, dataFlushInterval)
+	}
+	billingRows := getRows("billing_requests_total")
+	if d := s.getDataFlushInterval(billingRows); d != time.Second {
+		t.Fatalf("unexpected data flush interval for rows in data flush class; got %s; want %s", d, time.Second)
+	}
+	allRows := append(infraRows, billingRows...)
+	if d := s.getDataFlushInterval(allRows); d != time.Second {
+		t.Fatalf("unexpected data flush interval for mixed rows; got %s; want %s", d, time.Second)
+	}
+}
+
+func mustNewPromRegex(expr string) *regexutil.PromRegex {
+	pr, err := regexutil.NewPromRegex(expr)
+	if err != nil {
+		panic(err)
+	}
+	return pr
+}
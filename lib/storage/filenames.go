@@ -10,6 +10,7 @@ const (
 
 	appliedRetentionFilename    = "appliedRetention.txt"
 	resetCacheOnStartupFilename = "reset_cache_on_startup"
+	metricMetadataFilename      = "metric_metadata.json"
 )
 
 const (
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+)
+
+// ingestionDeduplicator drops samples with the same series and timestamp received during the configured window.
+//
+// Every sample is registered in the current generation, while generations are rotated every window.
+// So the sample is remembered for the duration in the range [window ... 2*window].
+// This guarantees that the duplicate sample is dropped if it is received in less than window after the original sample.
+//
+// The number of registered samples is limited by maxItems. If the limit is reached, then the shard generations
+// are rotated earlier, so the oldest samples are evicted.
+//
+// It is safe using the ingestionDeduplicator from concurrent goroutines.
+type ingestionDeduplicator struct {
+	shards []ingestionDedupShard
+
+	// evictions is the number of early generation rotations because of the maxItems limit.
+	evictions atomic.Uint64
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// ingestionDedupKey identifies the sample by the MetricID of its series and by its timestamp.
+//
+// MetricID is unique per the canonical metric name, so it doesn't depend on the order of labels in the ingested sample.
+type ingestionDedupKey struct {
+	metricID  uint64
+	timestamp int64
+}
+
+// ingestionDedupItemSize is the approximate memory usage in bytes per every registered sample, including map overhead.
+const ingestionDedupItemSize = 48
+
+type ingestionDedupShard struct {
+	mu   sync.Mutex
+	curr map[ingestionDedupKey]struct{}
+	prev map[ingestionDedupKey]struct{}
+
+	// maxItems is the maximum number of items in curr and prev.
+	maxItems int
+}
+
+// isDuplicate returns true if k is already registered at shard. Otherwise k is registered and false is returned.
+//
+// The second returned value is set to true if the oldest items were evicted because of shard.maxItems limit.
+func (shard *ingestionDedupShard) isDuplicate(k ingestionDedupKey) (bool, bool) {
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, ok := shard.curr[k]; ok {
+		return true, false
+	}
+	if _, ok := shard.prev[k]; ok {
+		// Do not move k to shard.curr, since this would prolong its lifetime beyond 2*window.
+		return true, false
+	}
+	evicted := false
+	if len(shard.curr)+len(shard.prev) >= shard.maxItems {
+		shard.rotateLocked()
+		evicted = true
+	}
+	shard.curr[k] = struct{}{}
+	return false, evicted
+}
+
+func (shard *ingestionDedupShard) rotate() {
+	shard.mu.Lock()
+	shard.rotateLocked()
+	shard.mu.Unlock()
+}
+
+func (shard *ingestionDedupShard) rotateLocked() {
+	shard.prev = shard.curr
+	shard.curr = make(map[ingestionDedupKey]struct{}, len(shard.prev))
+}
+
+func (shard *ingestionDedupShard) itemsCount() int {
+	shard.mu.Lock()
+	n := len(shard.curr) + len(shard.prev)
+	shard.mu.Unlock()
+	return n
+}
+
+// newIngestionDeduplicator creates new ingestionDeduplicator, which drops duplicate samples received during the given window.
+//
+// The returned ingestionDeduplicator keeps up to maxItems samples.
+//
+// MustStop must be called when the returned ingestionDeduplicator is no longer needed.
+func newIngestionDeduplicator(window time.Duration, maxItems int) *ingestionDeduplicator {
+	shards := make([]ingestionDedupShard, cgroup.AvailableCPUs())
+	maxShardItems := max(maxItems/len(shards), 1)
+	for i := range shards {
+		shards[i].curr = make(map[ingestionDedupKey]struct{})
+		shards[i].maxItems = maxShardItems
+	}
+	d := &ingestionDeduplicator{
+		shards: shards,
+		stopCh: make(chan struct{}),
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		t := time.NewTicker(window)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				d.rotate()
+			case <-d.stopCh:
+				return
+			}
+		}
+	}()
+	return d
+}
+
+// MustStop stops d.
+// It is expected that nobody accesses d at MustStop call.
+func (d *ingestionDeduplicator) MustStop() {
+	close(d.stopCh)
+	d.wg.Wait()
+}
+
+// isDuplicate returns true if the sample with the given metricID and timestamp has been already registered in d.
+//
+// Otherwise the sample is registered in d and false is returned.
+func (d *ingestionDeduplicator) isDuplicate(metricID uint64, timestamp int64) bool {
+	k := ingestionDedupKey{
+		metricID:  metricID,
+		timestamp: timestamp,
+	}
+	shard := &d.shards[metricID%uint64(len(d.shards))]
+	isDuplicate, evicted := shard.isDuplicate(k)
+	if evicted {
+		d.evictions.Add(1)
+	}
+	return isDuplicate
+}
+
+// filterRows removes duplicate samples from rows and the corresponding entries from mrs.
+//
+// rows must contain only samples accepted for storing, e.g. with already populated TSID,
+// so samples rejected by the storage aren't registered in d and their retries aren't dropped.
+//
+// It returns the filtered rows and mrs plus the number of removed samples.
+func (d *ingestionDeduplicator) filterRows(rows []rawRow, mrs []*MetricRow) ([]rawRow, []*MetricRow, int) {
+	j := 0
+	for i := range rows {
+		r := &rows[i]
+		if d.isDuplicate(r.TSID.MetricID, r.Timestamp) {
+			continue
+		}
+		rows[j] = *r
+		mrs[j] = mrs[i]
+		j++
+	}
+	return rows[:j], mrs[:j], len(rows) - j
+}
+
+func (d *ingestionDeduplicator) rotate() {
+	for i := range d.shards {
+		d.shards[i].rotate()
+	}
+}
+
+// itemsCount returns the number of samples registered in d.
+func (d *ingestionDeduplicator) itemsCount() int {
+	n := 0
+	for i := range d.shards {
+		n += d.shards[i].itemsCount()
+	}
+	return n
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+)
+
+func TestIngestionDeduplicator(t *testing.T) {
+	// Use big window in order to rotate generations manually.
+	d := newIngestionDeduplicator(time.Hour, 1e6)
+	defer d.MustStop()
+
+	f := func(metricID uint64, timestamp int64, resultExpected bool) {
+		t.Helper()
+		result := d.isDuplicate(metricID, timestamp)
+		if result != resultExpected {
+			t.Fatalf("unexpected isDuplicate(%d, %d); got %v; want %v", metricID, timestamp, result, resultExpected)
+		}
+	}
+
+	f(1, 1000, false)
+	f(1, 1000, true)
+	f(1, 1001, false)
+	f(2, 1000, false)
+	f(2, 1000, true)
+	if n := d.itemsCount(); n != 3 {
+		t.Fatalf("unexpected itemsCount; got %d; want 3", n)
+	}
+
+	// The samples from the previous generation must be still detected as duplicates
+	d.rotate()
+	f(1, 1000, true)
+	f(3, 1000, false)
+
+	// The samples must be forgotten after the second rotation.
+	// The sample from the previous generation mustn't be moved to the current generation on lookup.
+	d.rotate()
+	f(3, 1000, true)
+	d.rotate()
+	f(1, 1000, false)
+	f(2, 1000, false)
+	f(3, 1000, false)
+	if n := d.itemsCount(); n != 3 {
+		t.Fatalf("unexpected itemsCount; got %d; want 3", n)
+	}
+	if n := d.evictions.Load(); n != 0 {
+		t.Fatalf("unexpected evictions; got %d; want 0", n)
+	}
+}
+
+func TestIngestionDeduplicatorMaxItems(t *testing.T) {
+	d := newIngestionDeduplicator(time.Hour, 2*cgroup.AvailableCPUs())
+	defer d.MustStop()
+
+	// All the metricIDs below belong to the first shard, which may contain up to 2 items.
+	shards := uint64(len(d.shards))
+	for i := uint64(0); i < 4; i++ {
+		if d.isDuplicate(i*shards, 1000) {
+			t.Fatalf("unexpected duplicate for metricID=%d", i*shards)
+		}
+	}
+	if n := d.evictions.Load(); n != 2 {
+		t.Fatalf("unexpected evictions; got %d; want 2", n)
+	}
+	if n := d.itemsCount(); n != 2 {
+		t.Fatalf("unexpected itemsCount; got %d; want 2", n)
+	}
+
+	// The oldest samples must be evicted, while the newest samples must be kept.
+	if d.isDuplicate(0, 1000) {
+		t.Fatalf("the oldest sample must be evicted")
+	}
+	if !d.isDuplicate(3*shards, 1000) {
+		t.Fatalf("the newest sample must be kept")
+	}
+}
+
+func TestStorageAddRows_IngestionDedup(t *testing.T) {
+	defer testRemoveAll(t)
+
+	const numRows = 1000
+	rng := rand.New(rand.NewSource(1))
+	minTimestamp := time.Now().UnixMilli()
+	maxTimestamp := minTimestamp + 1000
+	mrs := testGenerateMetricRows(rng, numRows, minTimestamp, maxTimestamp)
+	for i := range mrs {
+		mrs[i].Value = float64(i)
+	}
+
+	s := MustOpenStorage(t.Name(), OpenOptions{
+		IngestionDedupWindow: time.Hour,
+	})
+	defer s.MustClose()
+
+	// Add the same rows twice with distinct values, like redundant pipelines may do.
+	s.AddRows(mrs, defaultPrecisionBits)
+	mrsDup := append([]MetricRow{}, mrs...)
+	for i := range mrsDup {
+		mrsDup[i].Value++
+	}
+	s.AddRows(mrsDup, defaultPrecisionBits)
+	s.DebugFlush()
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.RowsReceivedTotal != 2*numRows {
+		t.Fatalf("unexpected Metrics.RowsReceivedTotal: got %d, want %d", m.RowsReceivedTotal, 2*numRows)
+	}
+	if m.RowsAddedTotal != numRows {
+		t.Fatalf("unexpected Metrics.RowsAddedTotal: got %d, want %d", m.RowsAddedTotal, numRows)
+	}
+	if m.IngestionDedupRowsDropped != numRows {
+		t.Fatalf("unexpected Metrics.IngestionDedupRowsDropped: got %d, want %d", m.IngestionDedupRowsDropped, numRows)
+	}
+	if m.IngestionDedupCurrentItems != numRows {
+		t.Fatalf("unexpected Metrics.IngestionDedupCurrentItems: got %d, want %d", m.IngestionDedupCurrentItems, numRows)
+	}
+
+	// The first received samples must win.
+	tfs := NewTagFilters()
+	if err := tfs.Add(nil, []byte(`metric_\d*`), false, true); err != nil {
+		t.Fatalf("unexpected error in TagFilters.Add: %v", err)
+	}
+	if err := testAssertSearchResult(s, TimeRange{minTimestamp, maxTimestamp}, tfs, mrs); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestStorageAddRows_IngestionDedupLabelsOrder(t *testing.T) {
+	defer testRemoveAll(t)
+
+	s := MustOpenStorage(t.Name(), OpenOptions{
+		IngestionDedupWindow: time.Hour,
+	})
+	defer s.MustClose()
+
+	// The same series with distinct order of labels must be deduplicated.
+	timestamp := time.Now().UnixMilli()
+	newMetricRow := func(tags ...string) MetricRow {
+		var mn MetricName
+		mn.MetricGroup = []byte("metric")
+		for i := 0; i < len(tags); i += 2 {
+			mn.AddTag(tags[i], tags[i+1])
+		}
+		return MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         1,
+		}
+	}
+	s.AddRows([]MetricRow{newMetricRow("job", "a", "instance", "b")}, defaultPrecisionBits)
+	s.AddRows([]MetricRow{newMetricRow("instance", "b", "job", "a")}, defaultPrecisionBits)
+	s.DebugFlush()
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.RowsAddedTotal != 1 {
+		t.Fatalf("unexpected Metrics.RowsAddedTotal: got %d, want 1", m.RowsAddedTotal)
+	}
+	if m.IngestionDedupRowsDropped != 1 {
+		t.Fatalf("unexpected Metrics.IngestionDedupRowsDropped: got %d, want 1", m.IngestionDedupRowsDropped)
+	}
+}
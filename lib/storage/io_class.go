@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ratelimiter"
+)
+
+// IOClass is the class of disk IO for search queries.
+//
+// Disk reads for queries with IOClassBatch may be throttled via SetBatchIOReadLimiter,
+// so they do not evict page cache needed by interactive queries.
+type IOClass uint8
+
+const (
+	// IOClassInteractive is the IO class for interactive queries such as dashboards and alerting rules.
+	//
+	// Disk reads for such queries are never throttled.
+	IOClassInteractive IOClass = iota
+
+	// IOClassBatch is the IO class for background and batch queries such as bulk exports.
+	IOClassBatch
+)
+
+// ParseIOClass parses IO class from s.
+//
+// Empty s is parsed as IOClassInteractive.
+func ParseIOClass(s string) (IOClass, error) {
+	switch s {
+	case "", "interactive":
+		return IOClassInteractive, nil
+	case "batch", "background":
+		return IOClassBatch, nil
+	default:
+		return IOClassInteractive, fmt.Errorf("unsupported IO class %q; supported values: interactive, batch, background", s)
+	}
+}
+
+// String returns string representation of c.
+func (c IOClass) String() string {
+	switch c {
+	case IOClassInteractive:
+		return "interactive"
+	case IOClassBatch:
+		return "batch"
+	default:
+		return fmt.Sprintf("IOClass(%d)", uint8(c))
+	}
+}
+
+// SetBatchIOReadLimiter sets the limiter for the number of bytes per second read from disk by queries with IOClassBatch.
+//
+// Disk reads for queries with IOClassBatch aren't throttled if rl is nil.
+func SetBatchIOReadLimiter(rl *ratelimiter.RateLimiter) {
+	batchIOReadLimiter.Store(rl)
+}
+
+var batchIOReadLimiter atomic.Pointer[ratelimiter.RateLimiter]
+
+// throttleRead blocks if the given number of bytes to read exceeds the read limit for the given ioClass.
+func throttleRead(ioClass IOClass, bytes uint64) {
+	if ioClass != IOClassBatch {
+		return
+	}
+	batchIOReadLimiter.Load().Register(int(bytes))
+}
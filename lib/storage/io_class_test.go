@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ratelimiter"
+)
+
+func TestParseIOClassSuccess(t *testing.T) {
+	f := func(s string, classExpected IOClass) {
+		t.Helper()
+
+		ioClass, err := ParseIOClass(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ioClass != classExpected {
+			t.Fatalf("unexpected IO class; got %s; want %s", ioClass, classExpected)
+		}
+	}
+
+	f("", IOClassInteractive)
+	f("interactive", IOClassInteractive)
+	f("batch", IOClassBatch)
+	f("background", IOClassBatch)
+}
+
+func TestParseIOClassFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		if _, err := ParseIOClass(s); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("foo")
+	f("Batch")
+	f("interactive,batch")
+}
+
+func TestThrottleRead(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	limitReached := metrics.NewCounter(`vm_batch_io_read_limit_reached_total{test="TestThrottleRead"}`)
+	SetBatchIOReadLimiter(ratelimiter.New(100, limitReached, stopCh))
+	defer SetBatchIOReadLimiter(nil)
+
+	// interactive reads mustn't be throttled
+	for i := 0; i < 10; i++ {
+		throttleRead(IOClassInteractive, 1000)
+	}
+	if n := limitReached.Get(); n != 0 {
+		t.Fatalf("unexpected throttling for interactive reads; limitReached=%d", n)
+	}
+
+	// batch reads must be throttled after exceeding the limit
+	startTime := time.Now()
+	throttleRead(IOClassBatch, 100)
+	throttleRead(IOClassBatch, 1)
+	if n := limitReached.Get(); n != 1 {
+		t.Fatalf("unexpected number of throttled batch reads; got %d; want 1", n)
+	}
+	if d := time.Since(startTime); d < 500*time.Millisecond {
+		t.Fatalf("batch reads weren't throttled; duration=%s", d)
+	}
+}
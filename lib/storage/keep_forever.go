@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+// newKeepForeverMatcher returns matcher for metric names of series, which must be excluded from retention.
+//
+// nil is returned if regexes is empty.
+func newKeepForeverMatcher(regexes []*regexutil.PromRegex) *bytesutil.FastStringMatcher {
+	if len(regexes) == 0 {
+		return nil
+	}
+	return bytesutil.NewFastStringMatcher(func(s string) bool {
+		for _, re := range regexes {
+			if re.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// isKeepForeverMetricGroup returns true if series with the given metricGroup must be excluded from retention.
+func (s *Storage) isKeepForeverMetricGroup(metricGroup []byte) bool {
+	if s.keepForeverMatcher == nil {
+		return false
+	}
+	return s.keepForeverMatcher.Match(bytesutil.ToUnsafeString(metricGroup))
+}
+
+// isKeepForeverMetricNameRaw returns true if series with the given metricNameRaw must be excluded from retention.
+func (s *Storage) isKeepForeverMetricNameRaw(metricNameRaw []byte) bool {
+	if s.keepForeverMatcher == nil {
+		return false
+	}
+	return s.isKeepForeverMetricGroup(getMetricGroupFromMetricNameRaw(metricNameRaw))
+}
+
+// splitKeepForeverRows splits rows into rows for regular series and rows for series excluded from retention.
+//
+// mrs must contain MetricRow entries for the corresponding rows.
+// The returned regular rows re-use rows.
+func (s *Storage) splitKeepForeverRows(rows []rawRow, mrs []*MetricRow) ([]rawRow, []rawRow) {
+	if s.keepForeverMatcher == nil {
+		// Fast path - there are no series excluded from retention.
+		return rows, nil
+	}
+
+	var keepForeverRows []rawRow
+	dst := rows[:0]
+	for i := range rows {
+		if s.isKeepForeverMetricNameRaw(mrs[i].MetricNameRaw) {
+			keepForeverRows = append(keepForeverRows, rows[i])
+		} else {
+			dst = append(dst, rows[i])
+		}
+	}
+	return dst, keepForeverRows
+}
+
+// mustCopyKeepForeverSeries copies index entries for series excluded from retention from the previous indexdb to idbNext.
+//
+// This function must be called during indexdb rotation before the previous indexdb is dropped,
+// so series excluded from retention remain searchable after the rotation.
+func (s *Storage) mustCopyKeepForeverSeries(idbCurr, idbNext *indexDB) {
+	if s.keepForeverMatcher == nil {
+		return
+	}
+	idbCurr.doExtDB(func(idbPrev *indexDB) {
+		startTime := time.Now()
+		n, err := idbPrev.copyKeepForeverSeries(idbNext, s.isKeepForeverMetricGroup, s.getDeletedMetricIDs())
+		if err != nil {
+			logger.Panicf("FATAL: cannot copy series excluded from retention from indexdb %q to %q: %s", idbPrev.name, idbNext.name, err)
+		}
+		if n > 0 {
+			invalidateTagFiltersCache()
+		}
+		logger.Infof("copied %d series excluded from retention from indexdb %q to %q in %.3f seconds",
+			n, idbPrev.name, idbNext.name, time.Since(startTime).Seconds())
+	})
+}
+
+// copyKeepForeverSeries copies global and per-day index entries for series with metric names matching isKeepForever from db to dst.
+//
+// Series with metricIDs from dmis are skipped. The number of copied series is returned.
+func (db *indexDB) copyKeepForeverSeries(dst *indexDB, isKeepForever func(metricGroup []byte) bool, dmis *uint64set.Set) (int, error) {
+	is := db.getIndexSearch(noDeadline)
+	defer db.putIndexSearch(is)
+
+	mn := GetMetricName()
+	defer PutMetricName(mn)
+
+	// Collect metric names for series excluded from retention.
+	metricNames := make(map[uint64][]byte)
+	ts := &is.ts
+	kb := &is.kb
+	kb.B = is.marshalCommonPrefix(kb.B[:0], nsPrefixMetricIDToMetricName)
+	ts.Seek(kb.B)
+	for ts.NextItem() {
+		item := ts.Item
+		if !bytes.HasPrefix(item, kb.B) {
+			break
+		}
+		tail := item[len(kb.B):]
+		if len(tail) < 8 {
+			return 0, fmt.Errorf("unexpected metricID->metricName item len; got %d bytes; want at least 8 bytes", len(tail))
+		}
+		metricID := encoding.UnmarshalUint64(tail)
+		if dmis.Has(metricID) {
+			continue
+		}
+		metricName := tail[8:]
+		if err := mn.Unmarshal(metricName); err != nil {
+			return 0, fmt.Errorf("cannot unmarshal metricName for metricID=%d: %w", metricID, err)
+		}
+		if !isKeepForever(mn.MetricGroup) {
+			continue
+		}
+		metricNames[metricID] = append([]byte{}, metricName...)
+	}
+	if err := ts.Error(); err != nil {
+		return 0, fmt.Errorf("error when searching for metricID->metricName entries: %w", err)
+	}
+	if len(metricNames) == 0 {
+		return 0, nil
+	}
+
+	// Collect dates for per-day index entries of the found series.
+	dates := make(map[uint64][]uint64)
+	if !db.s.disablePerDayIndex {
+		kb.B = is.marshalCommonPrefix(kb.B[:0], nsPrefixDateToMetricID)
+		ts.Seek(kb.B)
+		for ts.NextItem() {
+			item := ts.Item
+			if !bytes.HasPrefix(item, kb.B) {
+				break
+			}
+			tail := item[len(kb.B):]
+			if len(tail) != 16 {
+				return 0, fmt.Errorf("unexpected date->metricID item len; got %d bytes; want 16 bytes", len(tail))
+			}
+			date := encoding.UnmarshalUint64(tail)
+			metricID := encoding.UnmarshalUint64(tail[8:])
+			if _, ok := metricNames[metricID]; ok {
+				dates[metricID] = append(dates[metricID], date)
+			}
+		}
+		if err := ts.Error(); err != nil {
+			return 0, fmt.Errorf("error when searching for date->metricID entries: %w", err)
+		}
+	}
+
+	// Create index entries for the found series in dst.
+	dstIs := dst.getIndexSearch(noDeadline)
+	defer dst.putIndexSearch(dstIs)
+
+	n := 0
+	var tsid TSID
+	for metricID, metricName := range metricNames {
+		if !is.getTSIDByMetricID(&tsid, metricID) {
+			// Skip series without metricID->TSID entry. This may be the case after unclean shutdown.
+			continue
+		}
+		if err := mn.Unmarshal(metricName); err != nil {
+			logger.Panicf("BUG: cannot unmarshal the previously unmarshaled metricName for metricID=%d: %s", metricID, err)
+		}
+		dstIs.createGlobalIndexes(&tsid, mn)
+		for _, date := range dates[metricID] {
+			dstIs.createPerDayIndexes(date, &tsid, mn)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
+)
+
+func TestStorageKeepForeverSeries(t *testing.T) {
+	defer testRemoveAll(t)
+
+	re, err := regexutil.NewPromRegex("kpi_.*")
+	if err != nil {
+		t.Fatalf("cannot compile regex: %s", err)
+	}
+	opts := OpenOptions{
+		Retention:                    24 * time.Hour,
+		KeepForeverMetricNameRegexes: []*regexutil.PromRegex{re},
+	}
+
+	now := time.Now().UnixMilli()
+	oldTimestamp := now - 10*msecPerDay
+	newMetricRow := func(metricName string, timestamp int64) MetricRow {
+		mn := MetricName{
+			MetricGroup: []byte(metricName),
+		}
+		return MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         1,
+		}
+	}
+	mrs := []MetricRow{
+		newMetricRow("kpi_revenue", oldTimestamp),
+		newMetricRow("kpi_revenue", now),
+		newMetricRow("regular_metric", oldTimestamp),
+		newMetricRow("regular_metric", now),
+	}
+
+	s := MustOpenStorage(t.Name(), opts)
+	s.AddRows(mrs, defaultPrecisionBits)
+	s.DebugFlush()
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.TooSmallTimestampRows != 1 {
+		t.Fatalf("unexpected TooSmallTimestampRows; got %d; want 1", m.TooSmallTimestampRows)
+	}
+	if !s.tb.hasKeepForeverPartitions.Load() {
+		t.Fatalf("expecting keep-forever partitions in the table")
+	}
+
+	trAll := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: now + msecPerDay,
+	}
+	trOld := TimeRange{
+		MinTimestamp: oldTimestamp - msecPerHour,
+		MaxTimestamp: oldTimestamp + msecPerHour,
+	}
+	checkSamples := func(want map[string]int) {
+		t.Helper()
+		got := testCountSamplesPerMetricName(s, trAll)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("unexpected samples; got %v; want %v", got, want)
+		}
+	}
+	checkSamples(map[string]int{
+		"kpi_revenue":    2,
+		"regular_metric": 1,
+	})
+	if n := testCountAllMetricNames(s, trOld); n != 1 {
+		t.Fatalf("unexpected number of series outside the retention; got %d; want 1", n)
+	}
+
+	// Series excluded from retention must survive indexdb rotations.
+	s.mustRotateIndexDB(time.Now())
+	s.mustRotateIndexDB(time.Now())
+	s.DebugFlush()
+	checkSamples(map[string]int{
+		"kpi_revenue": 2,
+	})
+	if n := testCountAllMetricNames(s, trOld); n != 1 {
+		t.Fatalf("unexpected number of series outside the retention after indexdb rotation; got %d; want 1", n)
+	}
+
+	// Keep-forever partitions must be detected after the storage re-opening.
+	s.MustClose()
+	s = MustOpenStorage(t.Name(), opts)
+	if !s.tb.hasKeepForeverPartitions.Load() {
+		t.Fatalf("expecting keep-forever partitions in the table after re-opening the storage")
+	}
+	checkSamples(map[string]int{
+		"kpi_revenue": 2,
+	})
+	s.MustClose()
+}
+
+func TestPartitionGetRetentionDeadline(t *testing.T) {
+	s := &Storage{
+		retentionMsecs: msecPerDay,
+	}
+	pt := &partition{
+		s: s,
+	}
+	currentTimestamp := int64(10 * msecPerDay)
+	if deadline := pt.getRetentionDeadline(currentTimestamp); deadline != 9*msecPerDay {
+		t.Fatalf("unexpected retention deadline; got %d; want %d", deadline, 9*msecPerDay)
+	}
+
+	pt.keepForever = true
+	if deadline := pt.getRetentionDeadline(currentTimestamp); deadline != 0 {
+		t.Fatalf("unexpected retention deadline for keep-forever partition; got %d; want 0", deadline)
+	}
+}
+
+func testCountSamplesPerMetricName(s *Storage, tr TimeRange) map[string]int {
+	tfs := NewTagFilters()
+	if err := tfs.Add([]byte("__name__"), []byte(".*"), false, true); err != nil {
+		panic(fmt.Sprintf("unexpected error in TagFilters.Add: %v", err))
+	}
+	var search Search
+	search.Init(nil, s, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+	defer search.MustClose()
+
+	var mn MetricName
+	var b Block
+	result := make(map[string]int)
+	for search.NextMetricBlock() {
+		if err := mn.Unmarshal(search.MetricBlockRef.MetricName); err != nil {
+			panic(fmt.Sprintf("cannot unmarshal metric name: %v", err))
+		}
+		search.MetricBlockRef.BlockRef.MustReadBlock(&b)
+		result[string(mn.MetricGroup)] += b.RowsCount()
+	}
+	if err := search.Error(); err != nil {
+		panic(fmt.Sprintf("search error: %v", err))
+	}
+	return result
+}
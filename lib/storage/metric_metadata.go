@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// MetricMetadata contains metadata for the metric family.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+type MetricMetadata struct {
+	// MetricFamilyName is the name of the metric family, which the metadata applies to.
+	MetricFamilyName string `json:"metric"`
+
+	// Type is the metric type such as counter, gauge, histogram or summary.
+	Type string `json:"type"`
+
+	// Help is the metric description.
+	Help string `json:"help"`
+
+	// Unit is the metric unit.
+	Unit string `json:"unit"`
+}
+
+func (mm *MetricMetadata) clone() MetricMetadata {
+	return MetricMetadata{
+		MetricFamilyName: strings.Clone(mm.MetricFamilyName),
+		Type:             strings.Clone(mm.Type),
+		Help:             strings.Clone(mm.Help),
+		Unit:             strings.Clone(mm.Unit),
+	}
+}
+
+// metricMetadataStore holds metric metadata received during the last ttlSecs.
+//
+// Metadata is kept in memory and is persisted to a file at MustClose call.
+type metricMetadataStore struct {
+	path    string
+	ttlSecs uint64
+
+	mu sync.Mutex
+
+	// m maps metadata to the last time in unix seconds when it has been received.
+	m map[MetricMetadata]uint64
+
+	// nextCleanupTime is the unix timestamp in seconds for the next removal of expired metadata.
+	nextCleanupTime uint64
+}
+
+type metricMetadataEntry struct {
+	MetricMetadata
+	LastSeen uint64 `json:"lastSeen"`
+}
+
+// mustLoadMetricMetadataStore loads metricMetadataStore from the given path.
+//
+// Metadata received more than ttlSecs ago is dropped.
+func mustLoadMetricMetadataStore(path string, ttlSecs uint64) *metricMetadataStore {
+	mms := &metricMetadataStore{
+		path:    path,
+		ttlSecs: ttlSecs,
+		m:       make(map[MetricMetadata]uint64),
+	}
+	if !fs.IsPathExist(path) {
+		return mms
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read metric metadata from %q: %s", path, err)
+	}
+	var entries []metricMetadataEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Errorf("cannot parse metric metadata from %q; resetting it: %s", path, err)
+		return mms
+	}
+	minLastSeen := mms.getMinLastSeen(fasttime.UnixTimestamp())
+	for _, e := range entries {
+		if e.LastSeen >= minLastSeen {
+			mms.m[e.MetricMetadata] = e.LastSeen
+		}
+	}
+	return mms
+}
+
+// mustSave persists mms to the path passed to mustLoadMetricMetadataStore.
+func (mms *metricMetadataStore) mustSave() {
+	mms.mu.Lock()
+	entries := make([]metricMetadataEntry, 0, len(mms.m))
+	for mm, lastSeen := range mms.m {
+		entries = append(entries, metricMetadataEntry{
+			MetricMetadata: mm,
+			LastSeen:       lastSeen,
+		})
+	}
+	mms.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal metric metadata: %s", err)
+	}
+	fs.MustMkdirIfNotExist(filepath.Dir(mms.path))
+	fs.MustWriteAtomic(mms.path, data, true)
+}
+
+func (mms *metricMetadataStore) getMinLastSeen(currentTime uint64) uint64 {
+	if currentTime < mms.ttlSecs {
+		return 0
+	}
+	return currentTime - mms.ttlSecs
+}
+
+// add registers the given metadata in mms.
+//
+// src may refer to a buffer, which is re-used after returning, so it is cloned before storing in mms.
+func (mms *metricMetadataStore) add(src []MetricMetadata) {
+	currentTime := fasttime.UnixTimestamp()
+
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+
+	for _, mm := range src {
+		if mm.MetricFamilyName == "" {
+			continue
+		}
+		// Clone mm unconditionally, since Go replaces string keys in the map on assignment to existing keys.
+		mms.m[mm.clone()] = currentTime
+	}
+
+	if currentTime >= mms.nextCleanupTime {
+		minLastSeen := mms.getMinLastSeen(currentTime)
+		for mm, lastSeen := range mms.m {
+			if lastSeen < minLastSeen {
+				delete(mms.m, mm)
+			}
+		}
+		mms.nextCleanupTime = currentTime + 60
+	}
+}
+
+// search returns metadata for the given metricFamilyName.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+// The number of returned metric families is limited by limit, while the number of metadata entries per metric family
+// is limited by limitPerMetric. Zero or negative limits mean no limit.
+func (mms *metricMetadataStore) search(metricFamilyName string, limit, limitPerMetric int) map[string][]MetricMetadata {
+	minLastSeen := mms.getMinLastSeen(fasttime.UnixTimestamp())
+
+	m := make(map[string][]MetricMetadata)
+	mms.mu.Lock()
+	for mm, lastSeen := range mms.m {
+		if lastSeen < minLastSeen {
+			continue
+		}
+		if metricFamilyName != "" && mm.MetricFamilyName != metricFamilyName {
+			continue
+		}
+		m[mm.MetricFamilyName] = append(m[mm.MetricFamilyName], mm)
+	}
+	mms.mu.Unlock()
+
+	// Sort metric family names and metadata entries, so the results are consistent across calls.
+	names := make([]string, 0, len(m))
+	for name, a := range m {
+		names = append(names, name)
+		sort.Slice(a, func(i, j int) bool {
+			if a[i].Type != a[j].Type {
+				return a[i].Type < a[j].Type
+			}
+			if a[i].Help != a[j].Help {
+				return a[i].Help < a[j].Help
+			}
+			return a[i].Unit < a[j].Unit
+		})
+		if limitPerMetric > 0 && len(a) > limitPerMetric {
+			m[name] = a[:limitPerMetric]
+		}
+	}
+	if limit > 0 && len(names) > limit {
+		sort.Strings(names)
+		for _, name := range names[limit:] {
+			delete(m, name)
+		}
+	}
+	return m
+}
+
+// itemsCount returns the number of metadata entries in mms.
+func (mms *metricMetadataStore) itemsCount() int {
+	mms.mu.Lock()
+	n := len(mms.m)
+	mms.mu.Unlock()
+	return n
+}
+
+// AddMetricMetadata registers the given metric metadata in s.
+//
+// The metadata is ignored if s has been opened without OpenOptions.MetricMetadataTTL.
+func (s *Storage) AddMetricMetadata(mms []MetricMetadata) {
+	if s.metricMetadata == nil {
+		return
+	}
+	s.metricMetadata.add(mms)
+}
+
+// SearchMetricMetadata returns metric metadata for the given metricFamilyName grouped by metric family names.
+//
+// Metadata for all the metric families is returned if metricFamilyName is empty.
+// limit limits the number of returned metric families, while limitPerMetric limits the number of metadata entries per metric family.
+func (s *Storage) SearchMetricMetadata(metricFamilyName string, limit, limitPerMetric int) map[string][]MetricMetadata {
+	if s.metricMetadata == nil {
+		return nil
+	}
+	return s.metricMetadata.search(metricFamilyName, limit, limitPerMetric)
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestMetricMetadataStoreSearch(t *testing.T) {
+	mms := mustLoadMetricMetadataStore(filepath.Join(t.TempDir(), metricMetadataFilename), 3600)
+	mms.add([]MetricMetadata{
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help"},
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help"},
+		{MetricFamilyName: "foo", Type: "gauge", Help: "another foo help", Unit: "seconds"},
+		{MetricFamilyName: "bar", Type: "histogram", Help: "bar help"},
+		{MetricFamilyName: "baz", Type: "summary"},
+		// Metadata without metric family name must be ignored
+		{Type: "gauge", Help: "ignored"},
+	})
+
+	f := func(metricFamilyName string, limit, limitPerMetric int, resultExpected map[string][]MetricMetadata) {
+		t.Helper()
+		result := mms.search(metricFamilyName, limit, limitPerMetric)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+	}
+
+	fooMetadata := []MetricMetadata{
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help"},
+		{MetricFamilyName: "foo", Type: "gauge", Help: "another foo help", Unit: "seconds"},
+	}
+	barMetadata := []MetricMetadata{
+		{MetricFamilyName: "bar", Type: "histogram", Help: "bar help"},
+	}
+	bazMetadata := []MetricMetadata{
+		{MetricFamilyName: "baz", Type: "summary"},
+	}
+
+	// all the metadata
+	f("", 0, 0, map[string][]MetricMetadata{
+		"foo": fooMetadata,
+		"bar": barMetadata,
+		"baz": bazMetadata,
+	})
+
+	// metadata for the given metric family
+	f("foo", 0, 0, map[string][]MetricMetadata{
+		"foo": fooMetadata,
+	})
+
+	// missing metric family
+	f("missing", 0, 0, map[string][]MetricMetadata{})
+
+	// limit
+	f("", 2, 0, map[string][]MetricMetadata{
+		"bar": barMetadata,
+		"baz": bazMetadata,
+	})
+
+	// limit_per_metric
+	f("foo", 0, 1, map[string][]MetricMetadata{
+		"foo": fooMetadata[:1],
+	})
+}
+
+func TestMetricMetadataStoreSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata", metricMetadataFilename)
+
+	mms := mustLoadMetricMetadataStore(path, 3600)
+	metadata := []MetricMetadata{
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help", Unit: "bytes"},
+		{MetricFamilyName: "bar", Type: "gauge"},
+	}
+	mms.add(metadata)
+	mms.mustSave()
+	if !fs.IsPathExist(path) {
+		t.Fatalf("missing metric metadata file at %q", path)
+	}
+
+	// Load the saved metadata
+	mms = mustLoadMetricMetadataStore(path, 3600)
+	if n := mms.itemsCount(); n != len(metadata) {
+		t.Fatalf("unexpected number of items after load; got %d; want %d", n, len(metadata))
+	}
+	result := mms.search("foo", 0, 0)
+	resultExpected := map[string][]MetricMetadata{
+		"foo": metadata[:1],
+	}
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected result after load\ngot\n%v\nwant\n%v", result, resultExpected)
+	}
+
+	// Load the metadata with expired entries
+	for mm := range mms.m {
+		mms.m[mm] = 1
+	}
+	mms.mustSave()
+	mms = mustLoadMetricMetadataStore(path, 3600)
+	if n := mms.itemsCount(); n != 0 {
+		t.Fatalf("expecting zero items after loading expired metadata; got %d", n)
+	}
+}
@@ -416,6 +416,31 @@ func (mt *Tracker) GetStats(limit, le int, matchPattern string) StatsResult {
 	return result
 }
 
+// GetUnusedStats returns stats for the tracked metric names, which weren't queried since the given unusedSince unix timestamp in seconds.
+//
+// Metric names, which were never queried, have zero LastRequestTs.
+func (mt *Tracker) GetUnusedStats(limit int, unusedSince uint64, matchPattern string) StatsResult {
+	var result StatsResult
+	if mt == nil {
+		return result
+	}
+	mt.mu.RLock()
+
+	result = mt.getStatsLocked(limit, func(sk *statKey, si *statItem) bool {
+		if si.lastRequestTs.Load() >= unusedSince {
+			return false
+		}
+		if len(matchPattern) > 0 && !strings.Contains(sk.metricName, matchPattern) {
+			return false
+		}
+		return true
+	})
+	mt.mu.RUnlock()
+
+	result.sort()
+	return result
+}
+
 func (mt *Tracker) getStatsLocked(limit int, predicate func(sk *statKey, si *statItem) bool) StatsResult {
 	var result StatsResult
 
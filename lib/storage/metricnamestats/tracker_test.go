@@ -268,6 +268,50 @@ func TestMetricsTrackerMaxSize(t *testing.T) {
 	}
 }
 
+func TestMetricsTrackerUnusedStats(t *testing.T) {
+	umt, err := loadFrom(t.TempDir()+t.Name(), 100_000)
+	if err != nil {
+		t.Fatalf("cannot load tracker: %s", err)
+	}
+	register := func(ts uint64, o byte, metricName string) {
+		umt.getCurrentTs = func() uint64 { return ts }
+		switch o {
+		case 'i':
+			umt.RegisterIngestRequest(0, 0, []byte(metricName))
+		case 'r':
+			umt.RegisterQueryRequest(0, 0, []byte(metricName))
+		}
+	}
+	register(10, 'i', "never_queried")
+	register(10, 'i', "queried_long_ago")
+	register(10, 'i', "queried_recently")
+	register(10, 'i', "foo_never_queried")
+	register(20, 'r', "queried_long_ago")
+	register(20, 'r', "queried_recently")
+	register(100, 'r', "queried_recently")
+
+	f := func(limit int, unusedSince uint64, matchPattern string, metricNamesExpected []string) {
+		t.Helper()
+		got := umt.GetUnusedStats(limit, unusedSince, matchPattern)
+		var metricNames []string
+		for _, r := range got.Records {
+			metricNames = append(metricNames, r.MetricName)
+		}
+		if !cmp.Equal(metricNames, metricNamesExpected) {
+			t.Fatalf("unexpected unused metric names: %s", cmp.Diff(metricNamesExpected, metricNames))
+		}
+	}
+
+	// metric names, which weren't queried since the given timestamp
+	f(100, 50, "", []string{"foo_never_queried", "never_queried", "queried_long_ago"})
+	f(100, 20, "", []string{"foo_never_queried", "never_queried"})
+	f(100, 101, "", []string{"foo_never_queried", "never_queried", "queried_long_ago", "queried_recently"})
+
+	// match pattern
+	f(100, 50, "foo", []string{"foo_never_queried"})
+	f(100, 50, "bar", nil)
+}
+
 func TestDeduplicateRecords(t *testing.T) {
 	f := func(result StatsResult, expected StatsResult) {
 		t.Helper()
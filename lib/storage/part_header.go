@@ -31,6 +31,42 @@ type partHeader struct {
 
 	// MinDedupInterval is minimal dedup interval in milliseconds across all the blocks in the part.
 	MinDedupInterval int64
+
+	// FormatVersion is the format version for the part data.
+	//
+	// It is used for determining the format of block headers in the part.
+	FormatVersion uint
+}
+
+const (
+	// partFormatVersionLegacy is the format version for parts created before block summaries were introduced.
+	partFormatVersionLegacy = 0
+
+	// partFormatVersionBlockSummary is the format version for parts with values summaries in block headers.
+	partFormatVersionBlockSummary = 1
+
+	// partFormatVersion is the latest supported format version.
+	partFormatVersion = partFormatVersionBlockSummary
+)
+
+// SetBlockSummariesEnabled enables storing block summaries in newly created parts.
+//
+// Parts with block summaries cannot be read by releases without block summaries support,
+// so they are stored only if explicitly enabled.
+//
+// This function must be called before any calling any storage functions.
+func SetBlockSummariesEnabled(ok bool) {
+	blockSummariesEnabled = ok
+}
+
+var blockSummariesEnabled = false
+
+// getNewPartFormatVersion returns the format version for newly created parts.
+func getNewPartFormatVersion() uint {
+	if blockSummariesEnabled {
+		return partFormatVersionBlockSummary
+	}
+	return partFormatVersionLegacy
 }
 
 // String returns string representation of ph.
@@ -45,6 +81,7 @@ func (ph *partHeader) Reset() {
 	ph.MinTimestamp = (1 << 63) - 1
 	ph.MaxTimestamp = -1 << 63
 	ph.MinDedupInterval = 0
+	ph.FormatVersion = getNewPartFormatVersion()
 }
 
 func (ph *partHeader) readMinDedupInterval(partPath string) error {
@@ -80,6 +117,9 @@ const userReadableTimeFormat = "20060102150405.000"
 func (ph *partHeader) ParseFromPath(path string) error {
 	ph.Reset()
 
+	// Parts without metadata file are created before the introduction of block summaries.
+	ph.FormatVersion = partFormatVersionLegacy
+
 	path = filepath.Clean(path)
 
 	// Extract encoded part name.
@@ -134,6 +174,9 @@ func (ph *partHeader) ParseFromPath(path string) error {
 func (ph *partHeader) MustReadMetadata(partPath string) {
 	ph.Reset()
 
+	// Parts created before the introduction of block summaries have no FormatVersion in metadata.
+	ph.FormatVersion = partFormatVersionLegacy
+
 	metadataPath := filepath.Join(partPath, metadataFilename)
 	if !fs.IsPathExist(metadataPath) {
 		// This is a part created before v1.90.0.
@@ -164,6 +207,10 @@ func (ph *partHeader) MustReadMetadata(partPath string) {
 	if ph.BlocksCount > ph.RowsCount {
 		logger.Panicf("FATAL: blocksCount cannot be bigger than rowsCount at %q; got blocksCount=%d, rowsCount=%d", metadataPath, ph.BlocksCount, ph.RowsCount)
 	}
+	if ph.FormatVersion > partFormatVersion {
+		logger.Panicf("FATAL: unsupported formatVersion at %q; got %d; want %d or lower; make sure the part was created by the same or older VictoriaMetrics release",
+			metadataPath, ph.FormatVersion, partFormatVersion)
+	}
 }
 
 func (ph *partHeader) MustWriteMetadata(partPath string) {
@@ -102,11 +102,18 @@ type partition struct {
 	s *Storage
 
 	// Name is the name of the partition in the form YYYY_MM.
+	//
+	// Keep-forever partitions have keepForeverPartitionSuffix at the end of the name.
 	name string
 
 	// The time range for the partition. Usually this is a whole month.
 	tr TimeRange
 
+	// keepForever is set to true if the partition contains samples for series, which must be excluded from retention.
+	//
+	// Such partitions are never deleted by retention.
+	keepForever bool
+
 	// rawRows contains recently added rows that haven't been converted into parts yet.
 	//
 	// rawRows are converted into inmemoryParts on every pendingRowsFlushInterval or when rawRows becomes full.
@@ -191,10 +198,18 @@ func (pw *partWrapper) decRef() {
 	}
 }
 
+// keepForeverPartitionSuffix is the suffix for names of partitions, which are excluded from retention.
+const keepForeverPartitionSuffix = "_keep"
+
 // mustCreatePartition creates new partition for the given timestamp and the given paths
 // to small and big partitions.
-func mustCreatePartition(timestamp int64, smallPartitionsPath, bigPartitionsPath string, s *Storage) *partition {
+//
+// If keepForever is set, then the created partition is excluded from retention.
+func mustCreatePartition(timestamp int64, smallPartitionsPath, bigPartitionsPath string, s *Storage, keepForever bool) *partition {
 	name := timestampToPartitionName(timestamp)
+	if keepForever {
+		name += keepForeverPartitionSuffix
+	}
 	smallPartsPath := filepath.Join(filepath.Clean(smallPartitionsPath), name)
 	bigPartsPath := filepath.Join(filepath.Clean(bigPartitionsPath), name)
 	logger.Infof("creating a partition %q with smallPartsPath=%q, bigPartsPath=%q", name, smallPartsPath, bigPartsPath)
@@ -206,6 +221,7 @@ func mustCreatePartition(timestamp int64, smallPartitionsPath, bigPartitionsPath
 	tr.fromPartitionTimestamp(timestamp)
 
 	pt := newPartition(name, smallPartsPath, bigPartsPath, tr, s)
+	pt.keepForever = keepForever
 
 	pt.startBackgroundWorkers()
 
@@ -242,8 +258,9 @@ func mustOpenPartition(smallPartsPath, bigPartsPath string, s *Storage) *partiti
 	bigPartsPath = filepath.Clean(bigPartsPath)
 
 	name := filepath.Base(smallPartsPath)
+	trName, keepForever := strings.CutSuffix(name, keepForeverPartitionSuffix)
 	var tr TimeRange
-	if err := tr.fromPartitionName(name); err != nil {
+	if err := tr.fromPartitionName(trName); err != nil {
 		logger.Panicf("FATAL: cannot obtain partition time range from smallPartsPath %q: %s", smallPartsPath, err)
 	}
 	if !strings.HasSuffix(bigPartsPath, name) {
@@ -264,6 +281,7 @@ func mustOpenPartition(smallPartsPath, bigPartsPath string, s *Storage) *partiti
 	}
 
 	pt := newPartition(name, smallPartsPath, bigPartsPath, tr, s)
+	pt.keepForever = keepForever
 	pt.smallParts = smallParts
 	pt.bigParts = bigParts
 
@@ -434,7 +452,8 @@ func (pt *partition) AddRows(rows []rawRow) {
 		}
 	}
 
-	pt.rawRows.addRows(pt, rows)
+	d := pt.s.getDataFlushInterval(rows)
+	pt.rawRows.addRows(pt, rows, d)
 }
 
 var isDebug = false
@@ -455,19 +474,22 @@ func (rrss *rawRowsShards) init() {
 	rrss.shards = make([]rawRowsShard, rawRowsShardsPerPartition)
 }
 
-func (rrss *rawRowsShards) addRows(pt *partition, rows []rawRow) {
+// addRows adds rows to rrss.
+//
+// flushInterval is the interval for guaranteed flush of rows from memory to disk. See SetDataFlushClasses.
+func (rrss *rawRowsShards) addRows(pt *partition, rows []rawRow, flushInterval time.Duration) {
 	shards := rrss.shards
 	shardsLen := uint32(len(shards))
 	for len(rows) > 0 {
 		n := rrss.shardIdx.Add(1)
 		idx := n % shardsLen
-		tailRows, rowsToFlush := shards[idx].addRows(rows)
-		rrss.addRowsToFlush(pt, rowsToFlush)
+		tailRows, rowsToFlush := shards[idx].addRows(rows, flushInterval)
+		rrss.addRowsToFlush(pt, rowsToFlush, flushInterval)
 		rows = tailRows
 	}
 }
 
-func (rrss *rawRowsShards) addRowsToFlush(pt *partition, rowsToFlush []rawRow) {
+func (rrss *rawRowsShards) addRowsToFlush(pt *partition, rowsToFlush []rawRow, flushInterval time.Duration) {
 	if len(rowsToFlush) == 0 {
 		return
 	}
@@ -478,6 +500,7 @@ func (rrss *rawRowsShards) addRowsToFlush(pt *partition, rowsToFlush []rawRow) {
 	if len(rrss.rowssToFlush) == 0 {
 		rrss.updateFlushDeadline()
 	}
+	lowerFlushDeadline(&rrss.flushDeadlineMs, flushInterval)
 	rrss.rowssToFlush = append(rrss.rowssToFlush, rowsToFlush)
 	if len(rrss.rowssToFlush) >= defaultPartsToMerge {
 		rowssToMerge = rrss.rowssToFlush
@@ -529,7 +552,7 @@ func (rrs *rawRowsShard) Len() int {
 	return n
 }
 
-func (rrs *rawRowsShard) addRows(rows []rawRow) ([]rawRow, []rawRow) {
+func (rrs *rawRowsShard) addRows(rows []rawRow, flushInterval time.Duration) ([]rawRow, []rawRow) {
 	var rowsToFlush []rawRow
 
 	rrs.mu.Lock()
@@ -550,6 +573,7 @@ func (rrs *rawRowsShard) addRows(rows []rawRow) ([]rawRow, []rawRow) {
 		rrs.rows = rrs.rows[:n]
 		rows = rows[n:]
 	}
+	lowerFlushDeadline(&rrs.flushDeadlineMs, flushInterval)
 	rrs.mu.Unlock()
 
 	return rows, rowsToFlush
@@ -841,7 +865,7 @@ func (pt *partition) createInmemoryPart(rows []rawRow) *partWrapper {
 		logger.Panicf("BUG: the part %q cannot be added to partition %q because of too big MaxTimestamp; got %d; want at least %d",
 			&mp.ph, pt.smallPartsPath, mp.ph.MaxTimestamp, pt.tr.MaxTimestamp)
 	}
-	flushToDiskDeadline := time.Now().Add(dataFlushInterval)
+	flushToDiskDeadline := time.Now().Add(pt.s.getDataFlushInterval(rows))
 	return newPartWrapperFromInmemoryPart(mp, flushToDiskDeadline)
 }
 
@@ -856,6 +880,16 @@ func newPartWrapperFromInmemoryPart(mp *inmemoryPart, flushToDiskDeadline time.T
 	return pw
 }
 
+// getRetentionDeadline returns the minimum timestamp for samples in pt, which must be kept at currentTimestamp.
+//
+// Samples in keep-forever partitions are never deleted by retention.
+func (pt *partition) getRetentionDeadline(currentTimestamp int64) int64 {
+	if pt.keepForever {
+		return 0
+	}
+	return currentTimestamp - pt.s.retentionMsecs
+}
+
 // HasTimestamp returns true if the pt contains the given timestamp.
 func (pt *partition) HasTimestamp(timestamp int64) bool {
 	return timestamp >= pt.tr.MinTimestamp && timestamp <= pt.tr.MaxTimestamp
@@ -1061,7 +1095,7 @@ func getBigPartsConcurrency() int {
 
 func (pt *partition) inmemoryPartsFlusher() {
 	// Do not add jitter to d in order to guarantee the flush interval
-	d := dataFlushInterval
+	d := getMinDataFlushInterval()
 	ticker := time.NewTicker(d)
 	defer ticker.Stop()
 	for {
@@ -1076,7 +1110,7 @@ func (pt *partition) inmemoryPartsFlusher() {
 
 func (pt *partition) pendingRowsFlusher() {
 	// Do not add jitter to d in order to guarantee the flush interval
-	d := pendingRowsFlushInterval
+	d := getMinPendingRowsFlushInterval()
 	ticker := time.NewTicker(d)
 	defer ticker.Stop()
 	for {
@@ -1155,6 +1189,22 @@ func (rrs *rawRowsShard) updateFlushDeadline() {
 	rrs.flushDeadlineMs.Store(time.Now().Add(pendingRowsFlushInterval).UnixMilli())
 }
 
+// lowerFlushDeadline lowers the pending rows flush deadline at flushDeadlineMs if flushInterval is smaller than pendingRowsFlushInterval.
+//
+// This guarantees that rows for data flush classes with small flush intervals do not stay in memory for longer than flushInterval.
+func lowerFlushDeadline(flushDeadlineMs *atomic.Int64, flushInterval time.Duration) {
+	if flushInterval >= pendingRowsFlushInterval {
+		return
+	}
+	deadlineMs := time.Now().Add(flushInterval).UnixMilli()
+	for {
+		n := flushDeadlineMs.Load()
+		if n <= deadlineMs || flushDeadlineMs.CompareAndSwap(n, deadlineMs) {
+			return
+		}
+	}
+}
+
 func appendRawRowss(dst [][]rawRow, src []rawRow) [][]rawRow {
 	if len(src) == 0 {
 		return dst
@@ -1572,7 +1622,7 @@ func (pt *partition) mergePartsInternal(dstPartPath string, bsw *blockStreamWrit
 	default:
 		logger.Panicf("BUG: unknown partType=%d", dstPartType)
 	}
-	retentionDeadline := currentTimestamp - pt.s.retentionMsecs
+	retentionDeadline := pt.getRetentionDeadline(currentTimestamp)
 	activeMerges.Add(1)
 	dmis := pt.s.getDeletedMetricIDs()
 	err := mergeBlockStreams(&ph, bsw, bsrs, stopCh, dmis, retentionDeadline, rowsMerged, rowsDeleted, useSparseCache)
@@ -1725,7 +1775,7 @@ func (pt *partition) stalePartsRemover() {
 
 func (pt *partition) removeStaleParts() {
 	startTime := time.Now()
-	retentionDeadline := timestampFromTime(startTime) - pt.s.retentionMsecs
+	retentionDeadline := pt.getRetentionDeadline(timestampFromTime(startTime))
 
 	var pws []*partWrapper
 	pt.partsLock.Lock()
@@ -169,7 +169,7 @@ func testPartitionSearchEx(t *testing.T, ptt int64, tr TimeRange, partsCount, ma
 	// Create partition from rowss and test search on it.
 	strg := newTestStorage()
 	strg.retentionMsecs = timestampFromTime(time.Now()) - ptr.MinTimestamp + 3600*1000
-	pt := mustCreatePartition(ptt, "small-table", "big-table", strg)
+	pt := mustCreatePartition(ptt, "small-table", "big-table", strg, false)
 	smallPartsPath := pt.smallPartsPath
 	bigPartsPath := pt.bigPartsPath
 	for _, rows := range rowss {
@@ -205,7 +205,7 @@ func TestMustCreatePartition(t *testing.T) {
 	}
 	s := &Storage{}
 
-	got := mustCreatePartition(ts, smallPath, bigPath, s)
+	got := mustCreatePartition(ts, smallPath, bigPath, s, false)
 	defer got.MustClose()
 
 	wantSmallPartsPath := filepath.Join(smallPath, "2025_03")
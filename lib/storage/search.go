@@ -3,9 +3,11 @@ package storage
 import (
 	"fmt"
 	"io"
+	"math"
 	"strings"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -56,6 +58,56 @@ func (br *BlockRef) RowsCount() int {
 	return int(br.bh.RowsCount)
 }
 
+// BlockSummary contains pre-aggregated stats for all the samples in a block.
+type BlockSummary struct {
+	// MinTimestamp is the minimum timestamp for samples in the block.
+	MinTimestamp int64
+
+	// MaxTimestamp is the maximum timestamp for samples in the block.
+	MaxTimestamp int64
+
+	// Count is the number of samples in the block.
+	Count int
+
+	// Min is the minimum value across samples in the block.
+	Min float64
+
+	// Max is the maximum value across samples in the block.
+	Max float64
+
+	// Sum is the sum of values across samples in the block.
+	Sum float64
+}
+
+// Summary returns the summary for the block referred by br.
+//
+// false is returned if the summary isn't available for the block.
+// The summary is never available for blocks with Inf values and Prometheus staleness marks.
+func (br *BlockRef) Summary() (BlockSummary, bool) {
+	bh := &br.bh
+	if !bh.HasSummary {
+		return BlockSummary{}, false
+	}
+	bs := BlockSummary{
+		MinTimestamp: bh.MinTimestamp,
+		MaxTimestamp: bh.MaxTimestamp,
+		Count:        int(bh.RowsCount),
+		Min:          decimal.ToFloat(bh.MinValue, bh.Scale),
+		Max:          decimal.ToFloat(bh.MaxValue, bh.Scale),
+		Sum:          scaleFloat64(bh.SumValue, bh.Scale),
+	}
+	return bs, true
+}
+
+// scaleFloat64 returns f*10^scale.
+func scaleFloat64(f float64, scale int16) float64 {
+	// Divide by 10^-scale for negative scale in the same way as decimal.ToFloat does for better precision.
+	if scale < 0 {
+		return f / math.Pow10(int(-scale))
+	}
+	return f * math.Pow10(int(scale))
+}
+
 // PartRef returns PartRef from br.
 func (br *BlockRef) PartRef() PartRef {
 	return PartRef{
@@ -70,9 +122,18 @@ type PartRef struct {
 
 // MustReadBlock reads block from br to dst.
 func (br *BlockRef) MustReadBlock(dst *Block) {
+	br.MustReadBlockWithIOClass(dst, IOClassInteractive)
+}
+
+// MustReadBlockWithIOClass reads block from br to dst.
+//
+// The read may be throttled depending on the given ioClass. See SetBatchIOReadLimiter.
+func (br *BlockRef) MustReadBlockWithIOClass(dst *Block, ioClass IOClass) {
 	dst.Reset()
 	dst.bh = br.bh
 
+	throttleRead(ioClass, uint64(br.bh.TimestampsBlockSize)+uint64(br.bh.ValuesBlockSize))
+
 	dst.timestampsData = bytesutil.ResizeNoCopyMayOverallocate(dst.timestampsData, int(br.bh.TimestampsBlockSize))
 	br.p.timestampsFile.MustReadAt(dst.timestampsData, int64(br.bh.TimestampsBlockOffset))
 
@@ -161,6 +222,11 @@ func (s *Search) Init(qt *querytracer.Tracer, storage *Storage, tfss []*TagFilte
 		logger.Panicf("BUG: missing MustClose call before the next call to Init")
 	}
 	retentionDeadline := int64(fasttime.UnixTimestamp()*1e3) - storage.retentionMsecs
+	if storage.tb.hasKeepForeverPartitions.Load() {
+		// Blocks for keep-forever series may be located outside the configured retention.
+		// Blocks outside the retention for other series are skipped by tableSearch.
+		retentionDeadline = 0
+	}
 
 	s.reset()
 	s.idb, s.putIndexDB = storage.getCurrIndexDB()
@@ -271,6 +337,11 @@ type SearchQuery struct {
 
 	// The maximum number of time series the search query can return.
 	MaxMetrics int
+
+	// IOClass is the class of disk IO for the search query.
+	//
+	// It is used for throttling disk reads for batch queries. See SetBatchIOReadLimiter.
+	IOClass IOClass
 }
 
 // GetTimeRange returns time range for the given sq.
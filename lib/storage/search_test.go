@@ -11,6 +11,8 @@ import (
 	"testing"
 	"testing/quick"
 	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 )
 
 func TestSearchQueryMarshalUnmarshal(t *testing.T) {
@@ -264,3 +266,95 @@ func mrsToString(mrs []MetricRow) string {
 	}
 	return bb.String()
 }
+
+func TestSearchBlockSummary(t *testing.T) {
+	testSearchBlockSummary(t, true)
+}
+
+func TestSearchBlockSummaryDisabled(t *testing.T) {
+	testSearchBlockSummary(t, false)
+}
+
+func testSearchBlockSummary(t *testing.T, summariesEnabled bool) {
+	SetBlockSummariesEnabled(summariesEnabled)
+	defer SetBlockSummariesEnabled(false)
+
+	path := "TestSearchBlockSummary"
+	st := MustOpenStorage(path, OpenOptions{})
+	defer func() {
+		st.MustClose()
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove storage %q: %s", path, err)
+		}
+	}()
+
+	startTimestamp := timestampFromTime(time.Now())
+	startTimestamp -= startTimestamp % (1e3 * 60 * 30)
+	addRows := func(metricGroup string, values []float64) {
+		var mn MetricName
+		mn.MetricGroup = []byte(metricGroup)
+		metricNameRaw := mn.marshalRaw(nil)
+		mrs := make([]MetricRow, len(values))
+		for i, v := range values {
+			mrs[i] = MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     startTimestamp + int64(i)*1000,
+				Value:         v,
+			}
+		}
+		// Block summaries are stored only for lossless compression.
+		st.AddRows(mrs, 64)
+	}
+	addRows("regular", []float64{1.5, -2.25, 10, 4})
+	addRows("stale", []float64{1, decimal.StaleNaN, 3})
+
+	// Re-open the storage in order to flush all the pending cached data.
+	st.MustClose()
+	st = MustOpenStorage(path, OpenOptions{})
+
+	f := func(metricGroup string, hasSummaryExpected bool, bsExpected BlockSummary) {
+		t.Helper()
+
+		tfs := NewTagFilters()
+		if err := tfs.Add(nil, []byte(metricGroup), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		tr := TimeRange{
+			MinTimestamp: startTimestamp,
+			MaxTimestamp: startTimestamp + 1e5,
+		}
+		var s Search
+		s.Init(nil, st, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+		defer s.MustClose()
+		if !s.NextMetricBlock() {
+			t.Fatalf("cannot find block for %q: %v", metricGroup, s.Error())
+		}
+		bs, ok := s.MetricBlockRef.BlockRef.Summary()
+		if ok != hasSummaryExpected {
+			t.Fatalf("unexpected summary availability for %q; got %v; want %v", metricGroup, ok, hasSummaryExpected)
+		}
+		if !reflect.DeepEqual(bs, bsExpected) {
+			t.Fatalf("unexpected summary for %q;\ngot\n%+v\nwant\n%+v", metricGroup, bs, bsExpected)
+		}
+		if s.NextMetricBlock() {
+			t.Fatalf("unexpected additional block for %q", metricGroup)
+		}
+	}
+
+	if !summariesEnabled {
+		// Block summaries mustn't be stored, so the data remains readable by releases without block summaries support.
+		f("regular", false, BlockSummary{})
+		f("stale", false, BlockSummary{})
+		return
+	}
+
+	f("regular", true, BlockSummary{
+		MinTimestamp: startTimestamp,
+		MaxTimestamp: startTimestamp + 3000,
+		Count:        4,
+		Min:          -2.25,
+		Max:          10,
+		Sum:          13.25,
+	})
+	f("stale", false, BlockSummary{})
+}
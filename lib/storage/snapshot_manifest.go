@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/snapshot/snapshotutil"
+)
+
+// SnapshotManifest describes the contents of a snapshot.
+//
+// It allows inspecting the snapshot contents without walking the snapshot directory.
+type SnapshotManifest struct {
+	// Name is the snapshot name.
+	Name string `json:"name"`
+
+	// CreatedAt is the snapshot creation time.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// SizeBytes is the total size of data and indexdb parts in the snapshot.
+	SizeBytes uint64 `json:"sizeBytes"`
+
+	// RowsCount is the total number of rows in data parts of the snapshot.
+	RowsCount uint64 `json:"rowsCount"`
+
+	// MinTimestamp is the minimum timestamp in milliseconds across data parts of the snapshot.
+	MinTimestamp int64 `json:"minTimestamp"`
+
+	// MaxTimestamp is the maximum timestamp in milliseconds across data parts of the snapshot.
+	MaxTimestamp int64 `json:"maxTimestamp"`
+
+	// Partitions contains per-month partitions with data parts.
+	Partitions []SnapshotPartition `json:"partitions"`
+
+	// IndexDBParts contains indexdb parts.
+	IndexDBParts []SnapshotIndexDBPart `json:"indexdbParts"`
+}
+
+// SnapshotPartition describes a per-month partition in a snapshot.
+type SnapshotPartition struct {
+	// Name is the partition name in the YYYY_MM format.
+	Name string `json:"name"`
+
+	SizeBytes    uint64 `json:"sizeBytes"`
+	RowsCount    uint64 `json:"rowsCount"`
+	MinTimestamp int64  `json:"minTimestamp"`
+	MaxTimestamp int64  `json:"maxTimestamp"`
+
+	// Parts contains small and big parts of the partition.
+	Parts []SnapshotPart `json:"parts"`
+}
+
+// SnapshotPart describes a data part in a snapshot.
+type SnapshotPart struct {
+	// Path is the part path relative to the snapshot directory.
+	Path string `json:"path"`
+
+	// Type is the part type - small or big.
+	Type string `json:"type"`
+
+	SizeBytes    uint64 `json:"sizeBytes"`
+	RowsCount    uint64 `json:"rowsCount"`
+	BlocksCount  uint64 `json:"blocksCount"`
+	MinTimestamp int64  `json:"minTimestamp"`
+	MaxTimestamp int64  `json:"maxTimestamp"`
+}
+
+// SnapshotIndexDBPart describes an indexdb part in a snapshot.
+type SnapshotIndexDBPart struct {
+	// Path is the part path relative to the snapshot directory.
+	Path string `json:"path"`
+
+	SizeBytes uint64 `json:"sizeBytes"`
+}
+
+// GetSnapshotManifest returns the manifest for the snapshot with the given snapshotName.
+func (s *Storage) GetSnapshotManifest(snapshotName string) (*SnapshotManifest, error) {
+	if err := snapshotutil.Validate(snapshotName); err != nil {
+		return nil, fmt.Errorf("invalid snapshotName %q: %w", snapshotName, err)
+	}
+	createdAt, err := snapshotutil.Time(snapshotName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain creation time for snapshot %q: %w", snapshotName, err)
+	}
+	snapshotPath := filepath.Join(s.path, snapshotsDirname, snapshotName)
+	if !fs.IsPathExist(snapshotPath) {
+		return nil, fmt.Errorf("cannot find snapshot %q", snapshotName)
+	}
+
+	sm := &SnapshotManifest{
+		Name:         snapshotName,
+		CreatedAt:    createdAt,
+		MinTimestamp: math.MaxInt64,
+		MaxTimestamp: math.MinInt64,
+		Partitions:   []SnapshotPartition{},
+		IndexDBParts: []SnapshotIndexDBPart{},
+	}
+
+	// Every partition has both small and big parts directories, so it is enough to list partitions at the small directory.
+	smallPath := filepath.Join(snapshotPath, dataDirname, smallDirname)
+	for _, ptName := range mustReadPartNamesFromDir(smallPath) {
+		pt := getSnapshotPartition(snapshotPath, ptName)
+		sm.Partitions = append(sm.Partitions, pt)
+		sm.SizeBytes += pt.SizeBytes
+		sm.RowsCount += pt.RowsCount
+		sm.MinTimestamp = min(sm.MinTimestamp, pt.MinTimestamp)
+		sm.MaxTimestamp = max(sm.MaxTimestamp, pt.MaxTimestamp)
+	}
+	sort.Slice(sm.Partitions, func(i, j int) bool {
+		return sm.Partitions[i].Name < sm.Partitions[j].Name
+	})
+	if sm.RowsCount == 0 {
+		sm.MinTimestamp = 0
+		sm.MaxTimestamp = 0
+	}
+
+	idbPath := filepath.Join(snapshotPath, indexdbDirname)
+	for _, idbName := range mustReadPartNamesFromDir(idbPath) {
+		for _, partName := range mustReadPartNamesFromDir(filepath.Join(idbPath, idbName)) {
+			partPath := filepath.Join(indexdbDirname, idbName, partName)
+			size := mustGetPartSize(filepath.Join(snapshotPath, partPath))
+			sm.IndexDBParts = append(sm.IndexDBParts, SnapshotIndexDBPart{
+				Path:      partPath,
+				SizeBytes: size,
+			})
+			sm.SizeBytes += size
+		}
+	}
+	sort.Slice(sm.IndexDBParts, func(i, j int) bool {
+		return sm.IndexDBParts[i].Path < sm.IndexDBParts[j].Path
+	})
+
+	return sm, nil
+}
+
+func getSnapshotPartition(snapshotPath, ptName string) SnapshotPartition {
+	smallPartsPath := filepath.Join(dataDirname, smallDirname, ptName)
+	bigPartsPath := filepath.Join(dataDirname, bigDirname, ptName)
+	partsFile := filepath.Join(snapshotPath, smallPartsPath, partsFilename)
+	partNamesSmall, partNamesBig := mustReadPartNames(partsFile, filepath.Join(snapshotPath, smallPartsPath), filepath.Join(snapshotPath, bigPartsPath))
+
+	pt := SnapshotPartition{
+		Name:         ptName,
+		MinTimestamp: math.MaxInt64,
+		MaxTimestamp: math.MinInt64,
+		Parts:        []SnapshotPart{},
+	}
+	addParts := func(partsPath, partType string, partNames []string) {
+		for _, partName := range partNames {
+			partPath := filepath.Join(partsPath, partName)
+			p := getSnapshotPart(snapshotPath, partPath, partType)
+			pt.Parts = append(pt.Parts, p)
+			pt.SizeBytes += p.SizeBytes
+			pt.RowsCount += p.RowsCount
+			pt.MinTimestamp = min(pt.MinTimestamp, p.MinTimestamp)
+			pt.MaxTimestamp = max(pt.MaxTimestamp, p.MaxTimestamp)
+		}
+	}
+	addParts(smallPartsPath, "small", partNamesSmall)
+	addParts(bigPartsPath, "big", partNamesBig)
+	if pt.RowsCount == 0 {
+		pt.MinTimestamp = 0
+		pt.MaxTimestamp = 0
+	}
+	return pt
+}
+
+func getSnapshotPart(snapshotPath, partPath, partType string) SnapshotPart {
+	path := filepath.Join(snapshotPath, partPath)
+	var ph partHeader
+	ph.MustReadMetadata(path)
+	return SnapshotPart{
+		Path:         partPath,
+		Type:         partType,
+		SizeBytes:    mustGetPartSize(path),
+		RowsCount:    ph.RowsCount,
+		BlocksCount:  ph.BlocksCount,
+		MinTimestamp: ph.MinTimestamp,
+		MaxTimestamp: ph.MaxTimestamp,
+	}
+}
+
+// mustGetPartSize returns the total size of files in the part directory at partPath.
+func mustGetPartSize(partPath string) uint64 {
+	n := uint64(0)
+	for _, de := range fs.MustReadDir(partPath) {
+		if !de.Type().IsRegular() {
+			continue
+		}
+		fi, err := de.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			logger.Panicf("FATAL: cannot stat %q: %s", filepath.Join(partPath, de.Name()), err)
+		}
+		n += uint64(fi.Size())
+	}
+	return n
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/snapshot/snapshotutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage/metricnamestats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timeutil"
@@ -58,6 +59,8 @@ type Storage struct {
 	hourlySeriesLimitRowsDropped atomic.Uint64
 	dailySeriesLimitRowsDropped  atomic.Uint64
 
+	ingestionDedupRowsDropped atomic.Uint64
+
 	// nextRotationTimestamp is a timestamp in seconds of the next indexdb rotation.
 	//
 	// It is used for gradual pre-population of the idbNext during the last hour before the indexdb rotation.
@@ -69,6 +72,11 @@ type Storage struct {
 	cachePath      string
 	retentionMsecs int64
 
+	// keepForeverMatcher matches metric names for series, which must be excluded from retention.
+	//
+	// It is nil if all the series are subject to retention.
+	keepForeverMatcher *bytesutil.FastStringMatcher
+
 	// lock file for exclusive access to the storage on the given path.
 	flockF *os.File
 
@@ -95,6 +103,14 @@ type Storage struct {
 	hourlySeriesLimiter *bloomfilter.Limiter
 	dailySeriesLimiter  *bloomfilter.Limiter
 
+	// ingestionDeduplicator drops duplicate samples during data ingestion.
+	// It is nil if OpenOptions.IngestionDedupWindow isn't set.
+	ingestionDeduplicator *ingestionDeduplicator
+
+	// metricMetadata holds metric metadata received via Prometheus remote write protocol.
+	// It is nil if OpenOptions.MetricMetadataTTL isn't set.
+	metricMetadata *metricMetadataStore
+
 	// tsidCache is MetricName -> TSID cache.
 	tsidCache *workingsetcache.Cache
 
@@ -168,6 +184,10 @@ type Storage struct {
 	missingMetricIDs              map[uint64]uint64
 	missingMetricIDsResetDeadline uint64
 
+	// dataFlushIntervalsCache maps metricID to the data flush interval for time series matching data flush classes.
+	// It is nil if data flush classes aren't set. See SetDataFlushClasses for details.
+	dataFlushIntervalsCache *workingsetcache.Cache
+
 	// isReadOnly is set to true when the storage is in read-only mode.
 	isReadOnly atomic.Bool
 
@@ -181,6 +201,23 @@ type OpenOptions struct {
 	MaxDailySeries        int
 	DisablePerDayIndex    bool
 	TrackMetricNamesStats bool
+
+	// IngestionDedupWindow enables dropping samples with the same series and timestamp
+	// received during the given window. It is disabled if set to 0.
+	IngestionDedupWindow time.Duration
+
+	// IngestionDedupMaxItems is the maximum number of samples tracked for IngestionDedupWindow.
+	// By default it is limited by 5% of allowed memory.
+	IngestionDedupMaxItems int
+
+	// MetricMetadataTTL enables storing metric metadata. Metadata is dropped if it isn't received during the given TTL.
+	// Metric metadata isn't stored if it is set to 0.
+	MetricMetadataTTL time.Duration
+
+	// KeepForeverMetricNameRegexes contains regexes for metric names of series, which must be excluded from retention.
+	//
+	// Samples for such series are stored in dedicated partitions, which are never deleted by retention.
+	KeepForeverMetricNameRegexes []*regexutil.PromRegex
 }
 
 // MustOpenStorage opens storage on the given path with the given retentionMsecs.
@@ -198,6 +235,8 @@ func MustOpenStorage(path string, opts OpenOptions) *Storage {
 		cachePath:      filepath.Join(path, cacheDirname),
 		retentionMsecs: retention.Milliseconds(),
 		stopCh:         make(chan struct{}),
+
+		keepForeverMatcher: newKeepForeverMatcher(opts.KeepForeverMetricNameRegexes),
 	}
 	fs.MustMkdirIfNotExist(path)
 
@@ -235,12 +274,24 @@ func MustOpenStorage(path string, opts OpenOptions) *Storage {
 		s.dailySeriesLimiter = bloomfilter.NewLimiter(opts.MaxDailySeries, 24*time.Hour)
 	}
 
+	// Initialize ingestion deduplicator.
+	if opts.IngestionDedupWindow > 0 {
+		maxItems := opts.IngestionDedupMaxItems
+		if maxItems <= 0 {
+			maxItems = memory.Allowed() / 20 / ingestionDedupItemSize
+		}
+		s.ingestionDeduplicator = newIngestionDeduplicator(opts.IngestionDedupWindow, maxItems)
+	}
+
 	// Load caches.
 	mem := memory.Allowed()
 	s.tsidCache = s.mustLoadCache("metricName_tsid", getTSIDCacheSize())
 	s.metricIDCache = s.mustLoadCache("metricID_tsid", mem/16)
 	s.metricNameCache = s.mustLoadCache("metricID_metricName", mem/10)
 	s.dateMetricIDCache = newDateMetricIDCache()
+	if len(dataFlushClasses) > 0 {
+		s.dataFlushIntervalsCache = workingsetcache.New(mem / 64)
+	}
 
 	hour := fasttime.UnixHour()
 	hmCurr := s.mustLoadHourMetricIDs(hour, "curr_hour_metric_ids")
@@ -268,6 +319,10 @@ func MustOpenStorage(path string, opts OpenOptions) *Storage {
 	isEmptyDB := !fs.IsPathExist(filepath.Join(path, indexdbDirname))
 	fs.MustMkdirIfNotExist(metadataDir)
 	s.minTimestampForCompositeIndex = mustGetMinTimestampForCompositeIndex(metadataDir, isEmptyDB)
+	if opts.MetricMetadataTTL > 0 {
+		ttlSecs := uint64(opts.MetricMetadataTTL.Seconds())
+		s.metricMetadata = mustLoadMetricMetadataStore(filepath.Join(metadataDir, metricMetadataFilename), ttlSecs)
+	}
 
 	s.disablePerDayIndex = opts.DisablePerDayIndex
 
@@ -576,6 +631,12 @@ type Metrics struct {
 	DailySeriesLimitMaxSeries     uint64
 	DailySeriesLimitCurrentSeries uint64
 
+	IngestionDedupRowsDropped  uint64
+	IngestionDedupCurrentItems uint64
+	IngestionDedupEvictions    uint64
+
+	MetricMetadataItems uint64
+
 	TimestampsBlocksMerged uint64
 	TimestampsBytesSaved   uint64
 
@@ -659,6 +720,16 @@ func (s *Storage) UpdateMetrics(m *Metrics) {
 		m.DailySeriesLimitCurrentSeries += uint64(sl.CurrentItems())
 	}
 
+	if d := s.ingestionDeduplicator; d != nil {
+		m.IngestionDedupRowsDropped += s.ingestionDedupRowsDropped.Load()
+		m.IngestionDedupCurrentItems += uint64(d.itemsCount())
+		m.IngestionDedupEvictions += d.evictions.Load()
+	}
+
+	if mms := s.metricMetadata; mms != nil {
+		m.MetricMetadataItems += uint64(mms.itemsCount())
+	}
+
 	m.TimestampsBlocksMerged = timestampsBlocksMerged.Load()
 	m.TimestampsBytesSaved = timestampsBytesSaved.Load()
 
@@ -883,6 +954,9 @@ func (s *Storage) mustRotateIndexDB(currentTime time.Time) {
 	nextRotationTimestamp := currentTime.Unix() + s.retentionMsecs/1000
 	s.nextRotationTimestamp.Store(nextRotationTimestamp)
 
+	// Copy series excluded from retention from idbPrev to idbNext, since idbPrev is dropped below.
+	s.mustCopyKeepForeverSeries(s.idbCurr.Load(), s.idbNext.Load())
+
 	s.idbLock.Lock()
 
 	// Set idbNext to idbNew
@@ -968,6 +1042,9 @@ func (s *Storage) MustClose() {
 	s.metricIDCache.Stop()
 	s.mustSaveCache(s.metricNameCache, "metricID_metricName")
 	s.metricNameCache.Stop()
+	if s.dataFlushIntervalsCache != nil {
+		s.dataFlushIntervalsCache.Stop()
+	}
 
 	hmCurr := s.currHourMetricIDs.Load()
 	s.mustSaveHourMetricIDs(hmCurr, "curr_hour_metric_ids")
@@ -977,6 +1054,10 @@ func (s *Storage) MustClose() {
 	nextDayMetricIDs := s.nextDayMetricIDs.Load()
 	s.mustSaveNextDayMetricIDs(nextDayMetricIDs)
 
+	if mms := s.metricMetadata; mms != nil {
+		mms.mustSave()
+	}
+
 	s.metricsTracker.MustClose()
 	// Release lock file.
 	fs.MustClose(s.flockF)
@@ -989,6 +1070,11 @@ func (s *Storage) MustClose() {
 	if sl := s.dailySeriesLimiter; sl != nil {
 		sl.MustStop()
 	}
+
+	// Stop ingestion deduplicator.
+	if d := s.ingestionDeduplicator; d != nil {
+		d.MustStop()
+	}
 }
 
 func (s *Storage) mustLoadNextDayMetricIDs(generation, date uint64) *byDateMetricIDEntry {
@@ -1866,6 +1952,11 @@ func (s *Storage) adjustTimeRange(tr TimeRange) TimeRange {
 	return tr
 }
 
+// IsGlobalIndexSearch returns true if series for the given tr are searched in the global index instead of the per-day index.
+func (s *Storage) IsGlobalIndexSearch(tr TimeRange) bool {
+	return s.adjustTimeRange(tr) == globalIndexTimeRange
+}
+
 // RegisterMetricNames registers all the metric names from mrs in the indexdb, so they can be queried later.
 //
 // The the MetricRow.Timestamp is used for registering the metric name at the given day according to the timestamp.
@@ -1950,6 +2041,11 @@ func (s *Storage) RegisterMetricNames(qt *querytracer.Tracer, mrs []MetricRow) {
 		if is.getTSIDByMetricName(&genTSID, metricNameBuf, date) {
 			// Slower path - the TSID has been found in indexdb.
 
+			if isSeriesDenied(mr.MetricNameRaw) {
+				// Skip the row, since the series is denied by the filter set via SetDeniedSeriesFilter.
+				continue
+			}
+
 			if !s.registerSeriesCardinality(genTSID.TSID.MetricID, mr.MetricNameRaw) {
 				// Skip the row, since it exceeds the configured cardinality limit.
 				continue
@@ -1966,6 +2062,10 @@ func (s *Storage) RegisterMetricNames(qt *querytracer.Tracer, mrs []MetricRow) {
 		}
 
 		// Slowest path - there is no TSID in indexdb for the given mr.MetricNameRaw. Create it.
+		if !isNewSeriesAllowed(mr.MetricNameRaw) {
+			// Skip the row, since the new series is denied by the filter set via SetNewSeriesFilter.
+			continue
+		}
 		generateTSID(&genTSID.TSID, mn)
 
 		if !s.registerSeriesCardinality(genTSID.TSID.MetricID, mr.MetricNameRaw) {
@@ -2034,7 +2134,7 @@ func (s *Storage) add(rows []rawRow, dstMrs []*MetricRow, mrs []MetricRow, preci
 				continue
 			}
 		}
-		if mr.Timestamp < minTimestamp {
+		if mr.Timestamp < minTimestamp && !s.isKeepForeverMetricNameRaw(mr.MetricNameRaw) {
 			// Skip rows with too small timestamps outside the retention.
 			if firstWarn == nil {
 				metricName := getUserReadableMetricName(mr.MetricNameRaw)
@@ -2135,6 +2235,12 @@ func (s *Storage) add(rows []rawRow, dstMrs []*MetricRow, mrs []MetricRow, preci
 		if is.getTSIDByMetricName(&genTSID, metricNameBuf, date) {
 			// Slower path - the TSID has been found in indexdb.
 
+			if isSeriesDenied(mr.MetricNameRaw) {
+				// Skip the row, since the series is denied by the filter set via SetDeniedSeriesFilter.
+				j--
+				continue
+			}
+
 			if !s.registerSeriesCardinality(genTSID.TSID.MetricID, mr.MetricNameRaw) {
 				// Skip the row, since it exceeds the configured cardinality limit.
 				j--
@@ -2161,6 +2267,11 @@ func (s *Storage) add(rows []rawRow, dstMrs []*MetricRow, mrs []MetricRow, preci
 		}
 
 		// Slowest path - the TSID for the given mr.MetricNameRaw isn't found in indexdb. Create it.
+		if !isNewSeriesAllowed(mr.MetricNameRaw) {
+			// Skip the row, since the new series is denied by the filter set via SetNewSeriesFilter.
+			j--
+			continue
+		}
 		generateTSID(&genTSID.TSID, mn)
 
 		if !s.registerSeriesCardinality(genTSID.TSID.MetricID, mr.MetricNameRaw) {
@@ -2194,6 +2305,15 @@ func (s *Storage) add(rows []rawRow, dstMrs []*MetricRow, mrs []MetricRow, preci
 	dstMrs = dstMrs[:j]
 	rows = rows[:j]
 
+	if d := s.ingestionDeduplicator; d != nil {
+		// Drop samples with the same series and timestamp as the already received samples.
+		// This must be performed after the rows are accepted by the storage, so rows dropped above
+		// because of cardinality limits or invalid metric names aren't registered as received.
+		var n int
+		rows, dstMrs, n = d.filterRows(rows, dstMrs)
+		s.ingestionDedupRowsDropped.Add(uint64(n))
+	}
+
 	if len(pendingHourEntries) > 0 {
 		s.pendingHourEntriesLock.Lock()
 		s.pendingHourEntries.AddMulti(pendingHourEntries)
@@ -2216,9 +2336,13 @@ func (s *Storage) add(rows []rawRow, dstMrs []*MetricRow, mrs []MetricRow, preci
 		storageAddRowsLogger.Warnf("warn occurred during rows addition: %s", firstWarn)
 	}
 
+	s.registerDataFlushClasses(rows, dstMrs)
+	rowsAdded := len(rows)
+	rows, keepForeverRows := s.splitKeepForeverRows(rows, dstMrs)
 	s.tb.MustAddRows(rows)
+	s.tb.MustAddKeepForeverRows(keepForeverRows)
 
-	return len(rows)
+	return rowsAdded
 }
 
 var storageAddRowsLogger = logger.WithThrottler("storageAddRows", 5*time.Second)
@@ -2232,6 +2356,59 @@ func SetLogNewSeries(ok bool) {
 
 var logNewSeries = false
 
+// SetNewSeriesFilter sets the filter, which is called before creating every new time series.
+//
+// The filter must return false if the time series with the given metricNameRaw mustn't be created. Samples for such time series are skipped.
+// The filter is called only for time series missing in the storage, so it is called once per every accepted time series.
+// The filter is called in the data ingestion path, so it must be fast.
+//
+// Pass nil in order to remove the filter.
+func SetNewSeriesFilter(f func(metricNameRaw []byte) bool) {
+	if f == nil {
+		newSeriesFilter.Store(nil)
+		return
+	}
+	newSeriesFilter.Store(&f)
+}
+
+var newSeriesFilter atomic.Pointer[func(metricNameRaw []byte) bool]
+
+func isNewSeriesAllowed(metricNameRaw []byte) bool {
+	f := newSeriesFilter.Load()
+	return f == nil || (*f)(metricNameRaw)
+}
+
+// SetDeniedSeriesFilter sets the filter, which is called for existing time series missing in the TSID cache.
+//
+// The filter must return true if samples for the time series with the given metricNameRaw must be skipped.
+// It allows dropping samples for time series, which have been denied after their creation.
+// Such time series must be removed from the TSID cache via Storage.DropSeriesFromCache,
+// so the filter is called for their samples.
+// The filter is called in the data ingestion path, so it must be fast.
+//
+// Pass nil in order to remove the filter.
+func SetDeniedSeriesFilter(f func(metricNameRaw []byte) bool) {
+	if f == nil {
+		deniedSeriesFilter.Store(nil)
+		return
+	}
+	deniedSeriesFilter.Store(&f)
+}
+
+var deniedSeriesFilter atomic.Pointer[func(metricNameRaw []byte) bool]
+
+func isSeriesDenied(metricNameRaw []byte) bool {
+	f := deniedSeriesFilter.Load()
+	return f != nil && (*f)(metricNameRaw)
+}
+
+// DropSeriesFromCache removes the time series with the given metricNameRaw from the TSID cache.
+//
+// Samples for such time series are ingested via slower path, which calls the filter set via SetDeniedSeriesFilter.
+func (s *Storage) DropSeriesFromCache(metricNameRaw []byte) {
+	s.tsidCache.Del(metricNameRaw)
+}
+
 func createAllIndexesForMetricName(is *indexSearch, mn *MetricName, tsid *TSID, date uint64) {
 	is.createGlobalIndexes(tsid, mn)
 	is.createPerDayIndexes(date, tsid, mn)
@@ -2962,6 +3139,11 @@ func (s *Storage) GetMetricNamesStats(_ *querytracer.Tracer, limit, le int, matc
 	return s.metricsTracker.GetStats(limit, le, matchPattern)
 }
 
+// GetUnusedMetricNamesStats returns stats for metric names, which weren't queried since the given unusedSince unix timestamp in seconds.
+func (s *Storage) GetUnusedMetricNamesStats(_ *querytracer.Tracer, limit int, unusedSince uint64, matchPattern string) MetricNamesStatsResponse {
+	return s.metricsTracker.GetUnusedStats(limit, unusedSince, matchPattern)
+}
+
 // ResetMetricNamesStats resets state for metric names usage tracker
 func (s *Storage) ResetMetricNamesStats(_ *querytracer.Tracer) {
 	s.metricsTracker.Reset(s.tsidCache.Reset)
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/quick"
 	"time"
@@ -3289,3 +3290,176 @@ func TestStorageMetricTracker(t *testing.T) {
 		t.Fatalf("unexpected Stats records count=%d, want %d records", len(mus.Records), numRows)
 	}
 }
+
+func TestStorageGetSnapshotManifest(t *testing.T) {
+	defer testRemoveAll(t)
+
+	rng := rand.New(rand.NewSource(1))
+	s := MustOpenStorage(t.Name(), OpenOptions{})
+	defer s.MustClose()
+
+	const rowsCount = 1000
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 3*msecPerDay
+	mrs := testGenerateMetricRows(rng, rowsCount, minTimestamp, maxTimestamp)
+	s.AddRows(mrs, defaultPrecisionBits)
+	snapshotName := s.MustCreateSnapshot()
+
+	sm, err := s.GetSnapshotManifest(snapshotName)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sm.Name != snapshotName {
+		t.Fatalf("unexpected snapshot name; got %q; want %q", sm.Name, snapshotName)
+	}
+	if sm.RowsCount != rowsCount {
+		t.Fatalf("unexpected rows count; got %d; want %d", sm.RowsCount, rowsCount)
+	}
+	if sm.MinTimestamp < minTimestamp || sm.MaxTimestamp > maxTimestamp || sm.MinTimestamp > sm.MaxTimestamp {
+		t.Fatalf("unexpected time range [%d..%d]; must be within [%d..%d]", sm.MinTimestamp, sm.MaxTimestamp, minTimestamp, maxTimestamp)
+	}
+	if len(sm.Partitions) == 0 {
+		t.Fatalf("expecting non-empty partitions")
+	}
+	if len(sm.IndexDBParts) == 0 {
+		t.Fatalf("expecting non-empty indexdb parts")
+	}
+
+	// Verify that the summary matches the parts.
+	var partsRows, partsSize uint64
+	for _, pt := range sm.Partitions {
+		for _, p := range pt.Parts {
+			if p.SizeBytes == 0 {
+				t.Fatalf("unexpected zero size for part %q", p.Path)
+			}
+			if !fs.IsPathExist(filepath.Join(s.path, snapshotsDirname, snapshotName, p.Path)) {
+				t.Fatalf("missing part %q in the snapshot", p.Path)
+			}
+			partsRows += p.RowsCount
+			partsSize += p.SizeBytes
+		}
+	}
+	for _, p := range sm.IndexDBParts {
+		partsSize += p.SizeBytes
+	}
+	if partsRows != sm.RowsCount {
+		t.Fatalf("unexpected rows count in parts; got %d; want %d", partsRows, sm.RowsCount)
+	}
+	if partsSize != sm.SizeBytes {
+		t.Fatalf("unexpected size of parts; got %d; want %d", partsSize, sm.SizeBytes)
+	}
+
+	// Missing and invalid snapshots
+	if _, err := s.GetSnapshotManifest("20240101000000-0000000000000000"); err == nil {
+		t.Fatalf("expecting non-nil error for missing snapshot")
+	}
+	if _, err := s.GetSnapshotManifest("../foo"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid snapshot name")
+	}
+}
+
+func TestStorageAddRows_NewSeriesFilter(t *testing.T) {
+	defer testRemoveAll(t)
+
+	var filterCalls []string
+	SetNewSeriesFilter(func(metricNameRaw []byte) bool {
+		name := getUserReadableMetricName(metricNameRaw)
+		filterCalls = append(filterCalls, name)
+		return name != "bad{}"
+	})
+	defer SetNewSeriesFilter(nil)
+
+	s := MustOpenStorage(t.Name(), OpenOptions{})
+	defer s.MustClose()
+
+	timestamp := time.Now().UnixMilli()
+	newMetricRows := func(names ...string) []MetricRow {
+		var mrs []MetricRow
+		for i, name := range names {
+			var mn MetricName
+			mn.MetricGroup = []byte(name)
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: mn.marshalRaw(nil),
+				Timestamp:     timestamp + int64(i),
+				Value:         1,
+			})
+		}
+		return mrs
+	}
+
+	// The filter must be called only for new series.
+	s.AddRows(newMetricRows("good", "bad"), defaultPrecisionBits)
+	s.AddRows(newMetricRows("good", "bad"), defaultPrecisionBits)
+	s.DebugFlush()
+
+	filterCallsExpected := []string{"good{}", "bad{}", "bad{}"}
+	if !reflect.DeepEqual(filterCalls, filterCallsExpected) {
+		t.Fatalf("unexpected filter calls; got %q; want %q", filterCalls, filterCallsExpected)
+	}
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.NewTimeseriesCreated != 1 {
+		t.Fatalf("unexpected Metrics.NewTimeseriesCreated: got %d, want 1", m.NewTimeseriesCreated)
+	}
+	if m.RowsAddedTotal != 2 {
+		t.Fatalf("unexpected Metrics.RowsAddedTotal: got %d, want 2", m.RowsAddedTotal)
+	}
+}
+
+func TestStorageAddRows_DeniedSeriesFilter(t *testing.T) {
+	defer testRemoveAll(t)
+
+	var denied atomic.Bool
+	SetDeniedSeriesFilter(func(metricNameRaw []byte) bool {
+		return denied.Load() && getUserReadableMetricName(metricNameRaw) == "bad{}"
+	})
+	defer SetDeniedSeriesFilter(nil)
+
+	s := MustOpenStorage(t.Name(), OpenOptions{})
+	defer s.MustClose()
+
+	timestamp := time.Now().UnixMilli()
+	newMetricRow := func(name string) MetricRow {
+		var mn MetricName
+		mn.MetricGroup = []byte(name)
+		timestamp++
+		return MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         1,
+		}
+	}
+	rowsAddedExpected := func(n uint64) {
+		t.Helper()
+		var m Metrics
+		s.UpdateMetrics(&m)
+		if m.RowsAddedTotal != n {
+			t.Fatalf("unexpected Metrics.RowsAddedTotal: got %d, want %d", m.RowsAddedTotal, n)
+		}
+	}
+
+	s.AddRows([]MetricRow{newMetricRow("good"), newMetricRow("bad")}, defaultPrecisionBits)
+	rowsAddedExpected(2)
+
+	// Make the created series searchable in indexdb.
+	s.DebugFlush()
+
+	// The filter isn't called for series in the TSID cache.
+	denied.Store(true)
+	s.AddRows([]MetricRow{newMetricRow("good"), newMetricRow("bad")}, defaultPrecisionBits)
+	rowsAddedExpected(4)
+
+	// The filter is called for existing series after they are dropped from the TSID cache.
+	bad := newMetricRow("bad")
+	s.DropSeriesFromCache(bad.MetricNameRaw)
+	s.AddRows([]MetricRow{newMetricRow("good"), bad}, defaultPrecisionBits)
+	rowsAddedExpected(5)
+
+	// Samples for the denied series are dropped until the filter allows them.
+	s.AddRows([]MetricRow{newMetricRow("bad")}, defaultPrecisionBits)
+	rowsAddedExpected(5)
+	denied.Store(false)
+	s.AddRows([]MetricRow{newMetricRow("bad")}, defaultPrecisionBits)
+	rowsAddedExpected(6)
+}
@@ -34,6 +34,9 @@ type table struct {
 	ptws     []*partitionWrapper
 	ptwsLock sync.Mutex
 
+	// hasKeepForeverPartitions is set to true if tb contains at least a single keep-forever partition.
+	hasKeepForeverPartitions atomic.Bool
+
 	stopCh chan struct{}
 
 	retentionWatcherWG  sync.WaitGroup
@@ -170,6 +173,9 @@ func (tb *table) addPartitionNolock(pt *partition) {
 	}
 	ptw.incRef()
 	tb.ptws = append(tb.ptws, ptw)
+	if pt.keepForever {
+		tb.hasKeepForeverPartitions.Store(true)
+	}
 }
 
 // MustClose closes the table.
@@ -239,13 +245,17 @@ func (tb *table) UpdateMetrics(m *TableMetrics) {
 	}
 
 	// Collect separate metrics for the last partition.
-	if len(ptws) > 0 {
-		ptwLast := ptws[0]
-		for _, ptw := range ptws[1:] {
-			if ptw.pt.tr.MinTimestamp > ptwLast.pt.tr.MinTimestamp {
-				ptwLast = ptw
-			}
+	// Keep-forever partitions are skipped, since they usually contain small share of data.
+	var ptwLast *partitionWrapper
+	for _, ptw := range ptws {
+		if ptw.pt.keepForever {
+			continue
+		}
+		if ptwLast == nil || ptw.pt.tr.MinTimestamp > ptwLast.pt.tr.MinTimestamp {
+			ptwLast = ptw
 		}
+	}
+	if ptwLast != nil {
 		ptwLast.pt.UpdateMetrics(&m.LastPartition)
 	}
 }
@@ -276,6 +286,17 @@ func (tb *table) ForceMergePartitions(partitionNamePrefix string) error {
 
 // MustAddRows adds the given rows to the table tb.
 func (tb *table) MustAddRows(rows []rawRow) {
+	tb.mustAddRows(rows, false)
+}
+
+// MustAddKeepForeverRows adds the given rows to keep-forever partitions of the table tb.
+//
+// Such rows are excluded from retention.
+func (tb *table) MustAddKeepForeverRows(rows []rawRow) {
+	tb.mustAddRows(rows, true)
+}
+
+func (tb *table) mustAddRows(rows []rawRow, keepForever bool) {
 	if len(rows) == 0 {
 		return
 	}
@@ -287,6 +308,9 @@ func (tb *table) MustAddRows(rows []rawRow) {
 	ptwsX.a = tb.GetPartitions(ptwsX.a[:0])
 	ptws := ptwsX.a
 	for i, ptw := range ptws {
+		if ptw.pt.keepForever != keepForever {
+			continue
+		}
 		singlePt := true
 		for j := range rows {
 			if !ptw.pt.HasTimestamp(rows[j].Timestamp) {
@@ -324,7 +348,7 @@ func (tb *table) MustAddRows(rows []rawRow) {
 		r := &rows[i]
 		ptFound := false
 		for _, ptw := range ptws {
-			if ptw.pt.HasTimestamp(r.Timestamp) {
+			if ptw.pt.keepForever == keepForever && ptw.pt.HasTimestamp(r.Timestamp) {
 				ptBuckets[ptw] = append(ptBuckets[ptw], *r)
 				ptFound = true
 				break
@@ -347,6 +371,10 @@ func (tb *table) MustAddRows(rows []rawRow) {
 	// Create new partitions for these rows.
 	// Do this under tb.ptwsLock.
 	minTimestamp, maxTimestamp := tb.getMinMaxTimestamps()
+	if keepForever {
+		// Rows outside retention are allowed for keep-forever partitions.
+		minTimestamp = 0
+	}
 	tb.ptwsLock.Lock()
 	for i := range missingRows {
 		r := &missingRows[i]
@@ -359,7 +387,7 @@ func (tb *table) MustAddRows(rows []rawRow) {
 		// Make sure the partition for the r hasn't been added by another goroutines.
 		ptFound := false
 		for _, ptw := range tb.ptws {
-			if ptw.pt.HasTimestamp(r.Timestamp) {
+			if ptw.pt.keepForever == keepForever && ptw.pt.HasTimestamp(r.Timestamp) {
 				ptFound = true
 				ptw.pt.AddRows(missingRows[i : i+1])
 				break
@@ -369,7 +397,7 @@ func (tb *table) MustAddRows(rows []rawRow) {
 			continue
 		}
 
-		pt := mustCreatePartition(r.Timestamp, tb.smallPartitionsPath, tb.bigPartitionsPath, tb.s)
+		pt := mustCreatePartition(r.Timestamp, tb.smallPartitionsPath, tb.bigPartitionsPath, tb.s, keepForever)
 		pt.AddRows(missingRows[i : i+1])
 		tb.addPartitionNolock(pt)
 	}
@@ -414,7 +442,7 @@ func (tb *table) retentionWatcher() {
 		tb.ptwsLock.Lock()
 		dst := tb.ptws[:0]
 		for _, ptw := range tb.ptws {
-			if ptw.pt.tr.MaxTimestamp < minTimestamp {
+			if !ptw.pt.keepForever && ptw.pt.tr.MaxTimestamp < minTimestamp {
 				ptwsDrop = append(ptwsDrop, ptw)
 			} else {
 				dst = append(dst, ptw)
@@ -455,10 +483,9 @@ func (tb *table) finalDedupWatcher() {
 		ptws := tb.GetPartitions(nil)
 		defer tb.PutPartitions(ptws)
 		timestamp := timestampFromTime(time.Now())
-		currentPartitionName := timestampToPartitionName(timestamp)
 		var ptwsToDedup []*partitionWrapper
 		for _, ptw := range ptws {
-			if ptw.pt.name == currentPartitionName {
+			if ptw.pt.HasTimestamp(timestamp) {
 				// Do not run final dedup for the current month.
 				// For the current month, the samples are countinously
 				// deduplicated by the background in-memory, small, and big part
@@ -65,11 +65,12 @@ func (ts *tableSearch) Init(tb *table, tsids []TSID, tr TimeRange) {
 	}
 
 	// Adjust tr.MinTimestamp, so it doesn't obtain data older
-	// than the tb retention.
+	// than the tb retention. Keep-forever partitions are searched on the original tr.
 	now := int64(fasttime.UnixTimestamp() * 1000)
 	minTimestamp := now - tb.s.retentionMsecs
-	if tr.MinTimestamp < minTimestamp {
-		tr.MinTimestamp = minTimestamp
+	retentionTR := tr
+	if retentionTR.MinTimestamp < minTimestamp {
+		retentionTR.MinTimestamp = minTimestamp
 	}
 
 	ts.reset()
@@ -87,7 +88,11 @@ func (ts *tableSearch) Init(tb *table, tsids []TSID, tr TimeRange) {
 	// Initialize the ptsPool.
 	ts.ptsPool = slicesutil.SetLength(ts.ptsPool, len(ts.ptws))
 	for i, ptw := range ts.ptws {
-		ts.ptsPool[i].Init(ptw.pt, tsids, tr)
+		ptTR := retentionTR
+		if ptw.pt.keepForever {
+			ptTR = tr
+		}
+		ts.ptsPool[i].Init(ptw.pt, tsids, ptTR)
 	}
 
 	// Initialize the ptsHeap.
@@ -412,6 +412,17 @@ func (c *Cache) Set(key, value []byte) {
 	curr.Set(key, value)
 }
 
+// Del deletes the entry for the given key from the cache.
+func (c *Cache) Del(key []byte) {
+	curr := c.curr.Load()
+	curr.Del(key)
+	if c.mode.Load() == whole {
+		return
+	}
+	prev := c.prev.Load()
+	prev.Del(key)
+}
+
 // GetBig appends the found value for the given key to dst and returns the result.
 func (c *Cache) GetBig(dst, key []byte) []byte {
 	curr := c.curr.Load()
@@ -7,19 +7,21 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
-	maxConcurrentInserts = flag.Int("maxConcurrentInserts", 2*cgroup.AvailableCPUs(), "The maximum number of concurrent insert requests. "+
+	maxConcurrentInserts = flagutil.NewTunableInt("maxConcurrentInserts", 2*cgroup.AvailableCPUs(), "The maximum number of concurrent insert requests. "+
 		"Set higher value when clients send data over slow networks. "+
 		"Default value depends on the number of available CPU cores. It should work fine in most cases since it minimizes resource usage. "+
-		"See also -insert.maxQueueDuration")
+		"The limit can be changed at runtime via /-/flags endpoint. See also -insert.maxQueueDuration", flagutil.ValidatePositiveInt)
 	maxQueueDuration = flag.Duration("insert.maxQueueDuration", time.Minute, "The maximum duration to wait in the queue when -maxConcurrentInserts "+
 		"concurrent insert requests are executed")
 )
@@ -29,8 +31,11 @@ var (
 // The concurrency can be reduced by calling DecConcurrency().
 // Then the concurrency is increased after the next Read() call.
 type Reader struct {
-	r                    io.Reader
-	increasedConcurrency bool
+	r io.Reader
+
+	// concurrencyCh is the channel the concurrency was increased at.
+	// It is nil if the concurrency isn't increased.
+	concurrencyCh chan struct{}
 }
 
 // GetReader returns the Reader for r.
@@ -64,18 +69,19 @@ var readerPool sync.Pool
 // It increases concurrency after the first call or after the next call after DecConcurrency() call.
 func (r *Reader) Read(p []byte) (int, error) {
 	n, err := r.r.Read(p)
-	if !r.increasedConcurrency {
-		if !incConcurrency() {
+	if r.concurrencyCh == nil {
+		ch := getConcurrencyLimitCh()
+		if !incConcurrency(ch) {
 			err = &httpserver.ErrorWithStatusCode{
 				Err: fmt.Errorf("cannot process insert request for %.3f seconds because %d concurrent insert requests are executed. "+
 					"Possible solutions: to reduce workload; to increase compute resources at the server; "+
 					"to increase -insert.maxQueueDuration; to increase -maxConcurrentInserts",
-					maxQueueDuration.Seconds(), *maxConcurrentInserts),
+					maxQueueDuration.Seconds(), cap(ch)),
 				StatusCode: http.StatusServiceUnavailable,
 			}
 			return 0, err
 		}
-		r.increasedConcurrency = true
+		r.concurrencyCh = ch
 	}
 	if errors.Is(err, io.ErrUnexpectedEOF) {
 		// See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/8704
@@ -89,26 +95,39 @@ func (r *Reader) Read(p []byte) (int, error) {
 
 // DecConcurrency decreases the concurrency, so it could be increased again after the next Read() call.
 func (r *Reader) DecConcurrency() {
-	if r.increasedConcurrency {
-		decConcurrency()
-		r.increasedConcurrency = false
+	if r.concurrencyCh != nil {
+		<-r.concurrencyCh
+		r.concurrencyCh = nil
 	}
 }
 
-func initConcurrencyLimitCh() {
-	concurrencyLimitCh = make(chan struct{}, *maxConcurrentInserts)
-}
-
+// concurrencyLimitCh limits the number of concurrently executed insert requests.
+//
+// It is replaced with a new channel when -maxConcurrentInserts is changed at runtime.
+// Readers, which already increased the concurrency, decrease it at the channel they used.
 var (
-	concurrencyLimitCh     chan struct{}
+	concurrencyLimitCh     atomic.Pointer[chan struct{}]
 	concurrencyLimitChOnce sync.Once
 )
 
-func incConcurrency() bool {
-	concurrencyLimitChOnce.Do(initConcurrencyLimitCh)
+func getConcurrencyLimitCh() chan struct{} {
+	concurrencyLimitChOnce.Do(func() {
+		setConcurrencyLimit(maxConcurrentInserts.Get())
+		maxConcurrentInserts.OnChange(func() {
+			setConcurrencyLimit(maxConcurrentInserts.Get())
+		})
+	})
+	return *concurrencyLimitCh.Load()
+}
+
+func setConcurrencyLimit(n int) {
+	ch := make(chan struct{}, n)
+	concurrencyLimitCh.Store(&ch)
+}
 
+func incConcurrency(ch chan struct{}) bool {
 	select {
-	case concurrencyLimitCh <- struct{}{}:
+	case ch <- struct{}{}:
 		return true
 	default:
 	}
@@ -116,7 +135,7 @@ func incConcurrency() bool {
 	concurrencyLimitReached.Inc()
 	t := timerpool.Get(*maxQueueDuration)
 	select {
-	case concurrencyLimitCh <- struct{}{}:
+	case ch <- struct{}{}:
 		timerpool.Put(t)
 		return true
 	case <-t.C:
@@ -126,20 +145,14 @@ func incConcurrency() bool {
 	}
 }
 
-func decConcurrency() {
-	<-concurrencyLimitCh
-}
-
 var (
 	concurrencyLimitReached = metrics.NewCounter(`vm_concurrent_insert_limit_reached_total`)
 	concurrencyLimitTimeout = metrics.NewCounter(`vm_concurrent_insert_limit_timeout_total`)
 
 	_ = metrics.NewGauge(`vm_concurrent_insert_capacity`, func() float64 {
-		concurrencyLimitChOnce.Do(initConcurrencyLimitCh)
-		return float64(cap(concurrencyLimitCh))
+		return float64(cap(getConcurrencyLimitCh()))
 	})
 	_ = metrics.NewGauge(`vm_concurrent_insert_current`, func() float64 {
-		concurrencyLimitChOnce.Do(initConcurrencyLimitCh)
-		return float64(len(concurrencyLimitCh))
+		return float64(len(getConcurrencyLimitCh()))
 	})
 )
@@ -0,0 +1,8 @@
+module github.com/VictoriaMetrics/metricsql
+
+go 1.13
+
+require (
+	github.com/VictoriaMetrics/metrics v1.34.0
+	golang.org/x/sys v0.21.0 // indirect
+)